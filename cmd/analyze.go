@@ -0,0 +1,98 @@
+//go:build plugin_core || plugin_all || dev
+// +build plugin_core plugin_all dev
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/gencommon"
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+	"github.com/Lumos-Labs-HQ/flash/internal/querylint"
+	"github.com/Lumos-Labs-HQ/flash/internal/schema"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Report tables, columns and indexes no query uses",
+	Long: `
+Cross-reference the query files with the schema files and report:
+- Tables no query references
+- Columns no query reads or writes
+- Indexes none of whose columns a query filters (WHERE), joins (ON) or
+  sorts (ORDER BY) on
+
+Only the queries in the query files are taken into account, so schema that
+is used by other code, e.g. raw SQL or another service, shows up as unused.
+Unique indexes aren't reported, as they enforce a constraint.
+
+With --json the report is printed as JSON.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		parsedSchema, queries, err := gencommon.ParseInputs(parser.NewSchemaParser(cfg), parser.NewQueryParser(cfg))
+		if err != nil {
+			return err
+		}
+		_, _, indexes, err := schema.NewSchemaManager(nil).ParseSchemaPath(cfg.GetSchemaDir())
+		if err != nil {
+			return err
+		}
+
+		analysis := querylint.Analyze(parsedSchema, indexes, queries)
+
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(analysis)
+		}
+
+		printAnalysis(analysis)
+		return nil
+	},
+}
+
+func printAnalysis(analysis *querylint.Analysis) {
+	fmt.Printf("Analyzed %d queries against %d tables\n", analysis.Queries, analysis.Tables)
+	if len(analysis.UnusedTables) == 0 && len(analysis.UnusedColumns) == 0 && len(analysis.UnusedIndexes) == 0 {
+		color.Green("✅ Every table, column and index is used by a query")
+		return
+	}
+
+	if len(analysis.UnusedTables) > 0 {
+		fmt.Println()
+		color.Yellow("Tables no query references (%d):", len(analysis.UnusedTables))
+		for _, table := range analysis.UnusedTables {
+			fmt.Printf("  %s\n", table)
+		}
+	}
+	if len(analysis.UnusedColumns) > 0 {
+		fmt.Println()
+		color.Yellow("Columns no query reads or writes (%d):", len(analysis.UnusedColumns))
+		for _, col := range analysis.UnusedColumns {
+			fmt.Printf("  %s.%s\n", col.Table, col.Column)
+		}
+	}
+	if len(analysis.UnusedIndexes) > 0 {
+		fmt.Println()
+		color.Yellow("Indexes no query filters or sorts on (%d):", len(analysis.UnusedIndexes))
+		for _, index := range analysis.UnusedIndexes {
+			fmt.Printf("  %-32s %s(%s)\n", index.Name, index.Table, strings.Join(index.Columns, ", "))
+		}
+	}
+}
+
+func init() {
+	// Command is registered by plugin executors, not the base CLI
+	analyzeCmd.Flags().Bool("json", false, "Print the report as JSON")
+}
@@ -6,9 +6,13 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/hooks"
 	"github.com/Lumos-Labs-HQ/flash/internal/migrator"
+	"github.com/Lumos-Labs-HQ/flash/internal/notify"
+	"github.com/fatih/color"
 
 	"github.com/spf13/cobra"
 )
@@ -24,8 +28,15 @@ This command will:
 2. Prompt for backup if conflicts are detected
 3. Apply all pending migrations in order
 4. Update migration tracking table
+5. Send the configured notifications
 
-	Use --force to skip confirmation prompts.`,
+When a migration will hold up traffic to a table that has rows, the
+estimated impact is printed first: the rows of each table it changes, the
+locks each statement takes and roughly how long they last.
+
+	Use --dry-run to print the pending migrations and their impact without applying them.
+	Use --force to skip confirmation prompts.
+	Use --notify always|success|failure|never to override when notifications are sent.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := config.Load()
 		if err != nil {
@@ -57,10 +68,68 @@ This command will:
 		force, _ := cmd.Flags().GetBool("force")
 		bam.SetForce(force)
 
-		return bam.Apply(ctx, "", cfg.SchemaPath)
+		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+			return bam.PrintImpact(ctx)
+		}
+
+		notifyOn, _ := cmd.Flags().GetString("notify")
+		switch notifyOn {
+		case "", "always", "success", "failure", "never":
+		default:
+			return fmt.Errorf("invalid --notify value %q, use always, success, failure or never", notifyOn)
+		}
+
+		event := hooks.Event{Command: "apply", Branch: branchName}
+		if event.Migrations, err = bam.PendingMigrations(ctx); err != nil {
+			return err
+		}
+		if err := hooks.Pre(cfg, "migrate", event); err != nil {
+			return err
+		}
+
+		start := time.Now()
+		err = bam.Apply(ctx, "", cfg.SchemaPath)
+		hooks.Post(cfg, "migrate", event, start, err)
+
+		if len(event.Migrations) > 0 || err != nil {
+			summary := notify.Summary{Branch: branchName, Success: err == nil, Duration: time.Since(start)}
+			if err != nil {
+				summary.Error = err.Error()
+			}
+			// Whatever is still pending wasn't applied
+			remaining, pendingErr := bam.PendingMigrations(ctx)
+			if pendingErr != nil {
+				remaining = event.Migrations
+			}
+			summary.Migrations, summary.Pending = splitApplied(event.Migrations, remaining)
+			if notifyErr := notify.Send(cfg, summary, notifyOn); notifyErr != nil {
+				color.Yellow("⚠️  %v", notifyErr)
+			}
+		}
+		return err
 	},
 }
 
+// splitApplied splits the migrations that were pending before an apply into
+// the applied ones and the ones still pending
+func splitApplied(before, remaining []string) (applied, pending []string) {
+	stillPending := make(map[string]bool, len(remaining))
+	for _, id := range remaining {
+		stillPending[id] = true
+	}
+	applied = []string{}
+	for _, id := range before {
+		if stillPending[id] {
+			pending = append(pending, id)
+		} else {
+			applied = append(applied, id)
+		}
+	}
+	return applied, pending
+}
+
 func init() {
 	// Command is registered by plugin executors, not the base CLI
+	applyCmd.Flags().Bool("dry-run", false, "Print the pending migrations and their estimated impact without applying them")
+	applyCmd.Flags().String("notify", "", "Override when notifications are sent (always, success, failure, never)")
 }
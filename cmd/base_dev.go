@@ -14,14 +14,35 @@ func RegisterBaseCommands() {
 	rootCmd.AddCommand(resetCmd)
 	rootCmd.AddCommand(rawCmd)
 	rootCmd.AddCommand(genCmd)
+	rootCmd.AddCommand(queriesCmd)
+	rootCmd.AddCommand(analyzeCmd)
+	rootCmd.AddCommand(benchCmd)
+	rootCmd.AddCommand(stateCmd)
 	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(datadiffCmd)
 
 	// Branch commands
 	rootCmd.AddCommand(branchCmd)
 	rootCmd.AddCommand(checkoutCmd)
 
+	// Tenant commands
+	rootCmd.AddCommand(tenantCmd)
+
+	// Admin API
+	rootCmd.AddCommand(serveCmd)
+
+	// Scheduled queries
+	rootCmd.AddCommand(scheduleCmd)
+
+	// Materialized views
+	rootCmd.AddCommand(matviewCmd)
+
+	// Disposable dev databases
+	rootCmd.AddCommand(devCmd)
+
 	// Studio command
 	rootCmd.AddCommand(studioCmd)
+	rootCmd.AddCommand(importCmd)
 
 	// Seed command
 	rootCmd.AddCommand(seedCmd)
@@ -0,0 +1,196 @@
+//go:build plugin_core || plugin_all || dev
+// +build plugin_core plugin_all dev
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/bench"
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench [query...]",
+	Short: "Measure the latency of named queries against a baseline",
+	Long: `
+Run named queries from the queries folder a number of times against the
+configured database and report their latency percentiles. Without names,
+every query that only reads (SELECT, or WITH without INSERT, UPDATE or
+DELETE) is run, but for those with placeholders whose values aren't given
+with --params. Named queries run as they are, so benchmark queries that
+change data against a database you can throw away.
+
+With --save the results are stored as the baseline, by default in
+<migrations_path>/.flash/bench_baseline.json, next to the results of the
+queries not run. Otherwise they are compared with the baseline, and the
+command fails when the p95 latency of a query grew by more than
+--threshold percent and by more than --min-delta. Run it in CI against a
+seeded database as a lightweight performance gate.`,
+	Example: `  flash bench --save
+  flash bench
+  flash bench GetUser ListOrders -n 200 --params GetUser='[1]' --threshold 10`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		dbURL, err := cfg.GetDatabaseURL()
+		if err != nil {
+			return err
+		}
+
+		opts := bench.Options{Queries: args, Params: map[string][]any{}}
+		opts.Iterations, _ = cmd.Flags().GetInt("iterations")
+		opts.Warmup, _ = cmd.Flags().GetInt("warmup")
+		params, _ := cmd.Flags().GetStringArray("params")
+		for _, p := range params {
+			name, values, ok := strings.Cut(p, "=")
+			if !ok {
+				return fmt.Errorf("--params must look like Query='[values]', got %q", p)
+			}
+			var parsed []any
+			if err := json.Unmarshal([]byte(values), &parsed); err != nil {
+				return fmt.Errorf("--params of %s must be a JSON array: %w", name, err)
+			}
+			opts.Params[name] = parsed
+		}
+
+		baselinePath, _ := cmd.Flags().GetString("baseline")
+		if baselinePath == "" {
+			baselinePath = bench.DefaultBaselinePath(cfg.MigrationsPath)
+		}
+		baseline, err := bench.LoadBaseline(baselinePath)
+		if err != nil {
+			return err
+		}
+
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if !asJSON {
+			color.Cyan("⏱️  Running queries %d time(s) each...", opts.Iterations)
+		}
+		results, err := bench.Run(context.Background(), cfg, dbURL, opts)
+		if err != nil {
+			return err
+		}
+
+		if save, _ := cmd.Flags().GetBool("save"); save {
+			saved := &bench.Baseline{Provider: cfg.Database.Provider, CreatedAt: time.Now().UTC(), Results: results}
+			if baseline != nil {
+				saved.Results = mergeBenchResults(baseline.Results, results)
+			}
+			if err := bench.SaveBaseline(baselinePath, saved); err != nil {
+				return err
+			}
+			if asJSON {
+				return printBenchJSON(results, nil)
+			}
+			printBenchResults(results)
+			color.Green("✓ Baseline saved to %s", baselinePath)
+			return nil
+		}
+
+		var comparisons []*bench.Comparison
+		if baseline != nil {
+			threshold, _ := cmd.Flags().GetFloat64("threshold")
+			minDelta, _ := cmd.Flags().GetDuration("min-delta")
+			comparisons = bench.Compare(baseline, results, threshold, minDelta)
+		}
+		if asJSON {
+			if err := printBenchJSON(results, comparisons); err != nil {
+				return err
+			}
+		} else {
+			printBenchResults(results)
+			if baseline == nil {
+				color.Yellow("No baseline at %s; save one with --save", baselinePath)
+			} else {
+				printBenchComparisons(comparisons)
+			}
+		}
+
+		regressed := 0
+		for _, c := range comparisons {
+			if c.Regressed {
+				regressed++
+			}
+		}
+		if regressed > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("p95 latency of %d of %d queries regressed", regressed, len(comparisons))
+		}
+		return nil
+	},
+}
+
+// mergeBenchResults replaces the results of the baseline with the new
+// results of the same queries and adds the others
+func mergeBenchResults(previous, results []*bench.Result) []*bench.Result {
+	index := make(map[string]int, len(previous))
+	merged := append([]*bench.Result{}, previous...)
+	for i, result := range merged {
+		index[result.Query] = i
+	}
+	for _, result := range results {
+		if i, ok := index[result.Query]; ok {
+			merged[i] = result
+		} else {
+			merged = append(merged, result)
+		}
+	}
+	return merged
+}
+
+func printBenchJSON(results []*bench.Result, comparisons []*bench.Comparison) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]any{"results": results, "comparisons": comparisons})
+}
+
+func printBenchResults(results []*bench.Result) {
+	fmt.Println()
+	fmt.Printf("  %-28s %8s %10s %10s %10s %10s %10s\n", "QUERY", "ROWS", "MIN", "P50", "P95", "P99", "MAX")
+	for _, r := range results {
+		fmt.Printf("  %-28s %8d %8.2fms %8.2fms %8.2fms %8.2fms %8.2fms\n", r.Query, r.Rows, r.MinMS, r.P50MS, r.P95MS, r.P99MS, r.MaxMS)
+	}
+	fmt.Println()
+}
+
+func printBenchComparisons(comparisons []*bench.Comparison) {
+	fmt.Println("p95 against the baseline:")
+	regressed := 0
+	for _, c := range comparisons {
+		switch {
+		case c.New:
+			fmt.Printf("  %-28s %8.2fms  (not in baseline)\n", c.Query, c.P95MS)
+		case c.Regressed:
+			regressed++
+			color.Red("  ✗ %-26s %8.2fms → %8.2fms  %+.1f%%", c.Query, c.BaselineP95MS, c.P95MS, c.ChangePercent)
+		default:
+			color.Green("  ✓ %-26s %8.2fms → %8.2fms  %+.1f%%", c.Query, c.BaselineP95MS, c.P95MS, c.ChangePercent)
+		}
+	}
+	fmt.Println()
+	if regressed == 0 {
+		color.Green("✅ No query regressed")
+	}
+}
+
+func init() {
+	// Command is registered by plugin executors, not the base CLI
+	benchCmd.Flags().IntP("iterations", "n", 50, "Times each query is run")
+	benchCmd.Flags().Int("warmup", 3, "Runs of each query before measuring")
+	benchCmd.Flags().StringArray("params", nil, `JSON array of a query's placeholder values, e.g. GetUser='[1]' (repeatable)`)
+	benchCmd.Flags().String("baseline", "", "Baseline file (default: <migrations_path>/.flash/bench_baseline.json)")
+	benchCmd.Flags().Bool("save", false, "Save the results as the baseline instead of comparing")
+	benchCmd.Flags().Float64("threshold", 20, "Percent the p95 latency of a query may grow before failing")
+	benchCmd.Flags().Duration("min-delta", time.Millisecond, "Growth of the p95 latency below which a query never fails")
+	benchCmd.Flags().Bool("json", false, "Print the results and comparisons as JSON")
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/Lumos-Labs-HQ/flash/internal/branch"
 	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/hooks"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
@@ -78,8 +79,17 @@ var checkoutCmd = &cobra.Command{
 			}
 		}
 
+		event := hooks.Event{Command: "checkout", Branch: branchName}
+		event.FromBranch, _ = manager.GetCurrentBranch()
+		if err := hooks.Pre(cfg, "branch_switch", event); err != nil {
+			return err
+		}
+
 		ctx := context.Background()
-		if err := manager.SwitchBranch(ctx, branchName); err != nil {
+		start := time.Now()
+		err = manager.SwitchBranch(ctx, branchName)
+		hooks.Post(cfg, "branch_switch", event, start, err)
+		if err != nil {
 			return fmt.Errorf("failed to switch branch: %w", err)
 		}
 
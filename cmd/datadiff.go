@@ -0,0 +1,194 @@
+//go:build plugin_core || plugin_all || dev
+// +build plugin_core plugin_all dev
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/database"
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/datadiff"
+	"github.com/spf13/cobra"
+)
+
+var datadiffCmd = &cobra.Command{
+	Use:   "datadiff <url-a> <url-b>",
+	Short: "Compare the rows of tables in two databases",
+	Long: `
+Compare the rows of tables in two databases, given by their connection URLs,
+and report the rows of each table that B is missing, the rows B has that A
+doesn't, and the rows whose values differ. The databases may be of different
+providers, and no config is needed.
+
+Rows are matched by the table's single-column primary key and hashed in
+ranges of that key, --chunk-size rows of A at a time. Only ranges whose
+hashes differ are compared row by row. Two PostgreSQL or two MySQL databases
+hash the ranges themselves, so only the rows of differing ranges are read.
+Values are compared as text, and tables without a single-column primary key
+are skipped.
+
+--sql writes the INSERT, UPDATE and DELETE statements that make B match A to
+a file, or to stdout with -, to review and then run against B. Rows are
+compared in A's key order, so keys that collate differently in B, such as
+mixed-case text across providers, may show up as both missing and extra.
+
+Examples:
+  flash datadiff postgres://localhost/prod postgres://localhost/staging
+  flash datadiff $PROD_URL $STAGING_URL --tables users,orders
+  flash datadiff sqlite://./a.db sqlite://./b.db --sql reconcile.sql
+  flash datadiff $PROD_URL $STAGING_URL --json --exit-code`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tables, _ := cmd.Flags().GetStringSlice("tables")
+		chunkSize, _ := cmd.Flags().GetInt("chunk-size")
+		sqlPath, _ := cmd.Flags().GetString("sql")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		exitCode, _ := cmd.Flags().GetBool("exit-code")
+
+		ctx := context.Background()
+
+		a, err := connectDiffSource(ctx, args[0], "A")
+		if err != nil {
+			return err
+		}
+		defer a.Adapter.Close()
+
+		b, err := connectDiffSource(ctx, args[1], "B")
+		if err != nil {
+			return err
+		}
+		defer b.Adapter.Close()
+
+		opts := datadiff.Options{Tables: tables, ChunkSize: chunkSize}
+		switch sqlPath {
+		case "":
+		case "-":
+			if jsonOutput {
+				return fmt.Errorf("--sql - and --json both write to stdout")
+			}
+			opts.Reconcile = os.Stdout
+		default:
+			file, err := os.Create(sqlPath)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", sqlPath, err)
+			}
+			defer file.Close()
+			opts.Reconcile = file
+		}
+		if opts.Reconcile != nil {
+			fmt.Fprintf(opts.Reconcile, "-- Statements that make %s match %s\n", redactURL(args[1]), redactURL(args[0]))
+		}
+
+		report, err := datadiff.Compare(ctx, a, b, opts)
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(report); err != nil {
+				return err
+			}
+		} else {
+			out := io.Writer(os.Stdout)
+			if sqlPath == "-" {
+				out = os.Stderr
+			}
+			printDataDiff(out, report)
+			if sqlPath != "" && sqlPath != "-" {
+				fmt.Fprintf(out, "📝 Reconcile statements written to %s\n", sqlPath)
+			}
+		}
+
+		if exitCode && report.Differs() {
+			os.Exit(1)
+		}
+		return nil
+	},
+}
+
+func connectDiffSource(ctx context.Context, url, name string) (datadiff.Source, error) {
+	provider := database.ProviderFromURL(url)
+	adapter := database.NewAdapter(provider)
+	if err := database.Require(adapter, provider, common.CapabilityQueries); err != nil {
+		return datadiff.Source{}, err
+	}
+
+	if err := adapter.Connect(ctx, url); err != nil {
+		return datadiff.Source{}, fmt.Errorf("failed to connect to database %s: %w", name, err)
+	}
+	if err := adapter.Ping(ctx); err != nil {
+		adapter.Close()
+		return datadiff.Source{}, fmt.Errorf("failed to connect to database %s: %w", name, err)
+	}
+	return datadiff.Source{Adapter: adapter, Provider: provider}, nil
+}
+
+// redactURL drops the password from a connection URL
+func redactURL(url string) string {
+	scheme, rest, ok := strings.Cut(url, "://")
+	if !ok {
+		return url
+	}
+	userinfo, host, ok := strings.Cut(rest, "@")
+	if !ok {
+		return url
+	}
+	if user, _, hasPassword := strings.Cut(userinfo, ":"); hasPassword {
+		return scheme + "://" + user + ":***@" + host
+	}
+	return url
+}
+
+func printDataDiff(out io.Writer, report *datadiff.Report) {
+	differing := 0
+	for _, table := range report.Tables {
+		switch {
+		case table.Skipped != "":
+			differing++
+			fmt.Fprintf(out, "⚠️  %s: skipped, %s\n", table.Table, table.Skipped)
+			continue
+		case !table.Differs():
+			fmt.Fprintf(out, "✅ %s: %d row(s) match\n", table.Table, table.RowsA)
+		default:
+			differing++
+			fmt.Fprintf(out, "❌ %s: %d missing, %d extra, %d different (%d of %d chunk(s) differ)\n",
+				table.Table, table.Missing, table.Extra, table.Different, table.ChunksDiffering, table.Chunks)
+			for _, row := range table.Samples {
+				line := fmt.Sprintf("   %s %s=%s", row.Kind, table.Key, row.Key)
+				if len(row.Columns) > 0 {
+					line += " (" + strings.Join(row.Columns, ", ") + ")"
+				}
+				fmt.Fprintln(out, line)
+			}
+		}
+		if len(table.ColumnsOnlyInA) > 0 {
+			fmt.Fprintf(out, "   columns only in A: %s\n", strings.Join(table.ColumnsOnlyInA, ", "))
+		}
+		if len(table.ColumnsOnlyInB) > 0 {
+			fmt.Fprintf(out, "   columns only in B: %s\n", strings.Join(table.ColumnsOnlyInB, ", "))
+		}
+	}
+
+	if differing == 0 {
+		fmt.Fprintf(out, "\n✅ %d table(s) match\n", len(report.Tables))
+	} else {
+		fmt.Fprintf(out, "\n❌ %d of %d table(s) differ\n", differing, len(report.Tables))
+	}
+}
+
+func init() {
+	// Command is registered by plugin executors, not the base CLI
+	datadiffCmd.Flags().StringSlice("tables", nil, "Tables to compare (default: every table of A)")
+	datadiffCmd.Flags().Int("chunk-size", 1000, "Rows of A hashed at a time, and rows of either side read at a time")
+	datadiffCmd.Flags().String("sql", "", "Write the statements that make B match A to a file, or - for stdout")
+	datadiffCmd.Flags().Bool("json", false, "Print the report as JSON")
+	datadiffCmd.Flags().Bool("exit-code", false, "Exit with status 1 when any table differs")
+}
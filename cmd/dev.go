@@ -0,0 +1,185 @@
+//go:build plugin_core || plugin_all || dev
+// +build plugin_core plugin_all dev
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/devdb"
+	"github.com/Lumos-Labs-HQ/flash/internal/migrator"
+	"github.com/Lumos-Labs-HQ/flash/internal/seeder"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var devCmd = &cobra.Command{
+	Use:   "dev",
+	Short: "Start and stop a disposable database for development and tests",
+	Long: `Start a throwaway database matching the configured provider, with the
+migrations applied and seed data, e.g. for integration tests:
+
+  flash dev up
+  export DATABASE_URL=...   # printed by 'flash dev up'
+  go test ./...
+  flash dev down
+
+PostgreSQL and MySQL run in a Docker container, SQLite in a temporary file.
+The running database is recorded in <migrations_path>/.flash/devdb.json.`,
+}
+
+var devUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Start a database, apply the migrations and seed it",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadDevConfig()
+		if err != nil {
+			return err
+		}
+		image, _ := cmd.Flags().GetString("image")
+		port, _ := cmd.Flags().GetInt("port")
+		seedCount, _ := cmd.Flags().GetInt("seed")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		ctx := context.Background()
+		metadata := devdb.NewMetadataManager(cfg.MigrationsPath)
+		existing, err := metadata.Load()
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			if devdb.Running(ctx, existing) {
+				return fmt.Errorf("a dev database is already running at %s, run 'flash dev down' first", existing.URL)
+			}
+			if err := metadata.Delete(); err != nil {
+				return err
+			}
+		}
+
+		color.Cyan("🚀 Starting a %s dev database...", cfg.Database.Provider)
+		instance, err := devdb.Start(ctx, cfg.Database.Provider, devdb.Options{Image: image, Port: port, Timeout: timeout})
+		if err != nil {
+			return err
+		}
+		// Recorded right away, so 'flash dev down' cleans up after a failure
+		if err := metadata.Save(instance); err != nil {
+			devdb.Stop(ctx, instance)
+			return err
+		}
+
+		m, err := migrator.NewMigratorForURL(cfg, instance.URL)
+		if err != nil {
+			return fmt.Errorf("%w (run 'flash dev down' to remove the database)", err)
+		}
+		applied, err := m.ApplyPending(ctx)
+		m.Close()
+		if err != nil {
+			return fmt.Errorf("failed to apply migrations: %w (run 'flash dev down' to remove the database)", err)
+		}
+		color.Green("✓ Applied %d migration(s)", applied)
+
+		if seedCount > 0 && applied > 0 {
+			s, err := seeder.NewSeederForURL(cfg, instance.URL)
+			if err != nil {
+				return err
+			}
+			err = s.Seed(ctx, seeder.SeedConfig{Count: seedCount, Relations: true, Batch: 100, Force: true})
+			s.Close()
+			if err != nil {
+				return fmt.Errorf("failed to seed: %w (run 'flash dev down' to remove the database)", err)
+			}
+		}
+
+		fmt.Println()
+		color.Green("✓ Dev database ready")
+		fmt.Printf("  %s\n\n", instance.URL)
+		fmt.Printf("  export %s=%q\n", cfg.Database.URLEnv, instance.URL)
+		return nil
+	},
+}
+
+var devDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Stop the dev database and delete its data",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadDevConfig()
+		if err != nil {
+			return err
+		}
+
+		metadata := devdb.NewMetadataManager(cfg.MigrationsPath)
+		instance, err := metadata.Load()
+		if err != nil {
+			return err
+		}
+		if instance == nil {
+			color.Yellow("No dev database running")
+			return nil
+		}
+
+		if err := devdb.Stop(context.Background(), instance); err != nil {
+			return err
+		}
+		if err := metadata.Delete(); err != nil {
+			return err
+		}
+		color.Green("✓ Dev database removed")
+		return nil
+	},
+}
+
+var devStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the URL of the running dev database",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadDevConfig()
+		if err != nil {
+			return err
+		}
+
+		instance, err := devdb.NewMetadataManager(cfg.MigrationsPath).Load()
+		if err != nil {
+			return err
+		}
+		if instance == nil {
+			color.Yellow("No dev database running")
+			return nil
+		}
+
+		state := color.GreenString("running")
+		if !devdb.Running(context.Background(), instance) {
+			state = color.RedString("gone, run 'flash dev down' to forget it")
+		}
+		fmt.Printf("  %s (%s, started %s)\n", instance.URL, state, instance.CreatedAt.Format("2006-01-02 15:04"))
+		if instance.Container != "" {
+			fmt.Printf("  container %s\n", instance.Container)
+		}
+		return nil
+	},
+}
+
+func loadDevConfig() (*config.Config, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, nil
+}
+
+func init() {
+	// Command is registered by plugin executors, not the base CLI
+	devCmd.AddCommand(devUpCmd)
+	devCmd.AddCommand(devDownCmd)
+	devCmd.AddCommand(devStatusCmd)
+
+	devUpCmd.Flags().String("image", "", "Docker image (default: postgres:16-alpine or mysql:8.4)")
+	devUpCmd.Flags().Int("port", 0, "Host port of the database (default: a free port)")
+	devUpCmd.Flags().Int("seed", 10, "Rows of fake data per table, 0 to skip seeding")
+	devUpCmd.Flags().Duration("timeout", 90*time.Second, "How long to wait for the database to start")
+}
@@ -20,11 +20,23 @@ var exportCmd = &cobra.Command{
 Export all database tables (excluding migration table) to various formats.
 Supported formats: json (default), csv, sqlite
 
+--incremental only exports the rows changed since the last incremental
+export, for periodic syncs between environments by importing each export in
+turn. A table's watermark is its updated_at column, or else its primary key
+when keys sort as rows are inserted (integers, ULIDs, UUIDv7s), which only
+catches new rows; --watermark picks another column.
+Watermarks are kept in the .flash folder of the migrations path, and only
+move once the export is written. Deleted rows aren't exported, and tables
+with no watermark column are exported in full every time.
+
 Examples:
   flash export
   flash export --sqlite
   flash export --csv
-  flash export --json`,
+  flash export --json
+  flash export --incremental
+  flash export --incremental --watermark events=created_at
+  flash export --incremental --reset-watermarks`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := config.Load()
 		if err != nil {
@@ -67,7 +79,11 @@ Examples:
 			return fmt.Errorf("failed to connect to database: %w", err)
 		}
 
-		exportPath, err := export.PerformExport(ctx, adapter, cfg.ExportPath, format)
+		if incremental, _ := cmd.Flags().GetBool("incremental"); incremental {
+			return runIncrementalExport(ctx, cmd, adapter, cfg, format)
+		}
+
+		exportPath, err := export.PerformExport(ctx, adapter, cfg.ExportPath, format, cfg.Database)
 		if err != nil {
 			return err
 		}
@@ -82,9 +98,41 @@ Examples:
 	},
 }
 
+func runIncrementalExport(ctx context.Context, cmd *cobra.Command, adapter database.DatabaseAdapter, cfg *config.Config, format string) error {
+	opts := export.IncrementalOptions{}
+	opts.Columns, _ = cmd.Flags().GetStringToString("watermark")
+	opts.Reset, _ = cmd.Flags().GetBool("reset-watermarks")
+
+	exportPath, changes, err := export.PerformIncrementalExport(ctx, adapter, cfg.ExportPath, format, cfg.Database, cfg.MigrationsPath, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		switch {
+		case change.Since != nil:
+			fmt.Printf("   %s: %d row(s) changed since %s %s\n", change.Table, change.Rows, change.Column, change.Since.Value)
+		case change.Column != "":
+			fmt.Printf("   %s: %d row(s), in full (first incremental export)\n", change.Table, change.Rows)
+		default:
+			fmt.Printf("   %s: %d row(s), in full (no updated_at column or sortable key)\n", change.Table, change.Rows)
+		}
+	}
+
+	if exportPath != "" {
+		fmt.Printf("✅ Incremental export completed: %s\n", exportPath)
+	} else {
+		fmt.Println("No export created (database is empty)")
+	}
+	return nil
+}
+
 func init() {
 	// Command is registered by plugin executors, not the base CLI
 	exportCmd.Flags().BoolP("json", "j", false, "Export as JSON (default)")
 	exportCmd.Flags().BoolP("csv", "c", false, "Export as CSV")
 	exportCmd.Flags().BoolP("sqlite", "s", false, "Export as SQLite")
+	exportCmd.Flags().Bool("incremental", false, "Only export rows changed since the last incremental export")
+	exportCmd.Flags().StringToString("watermark", nil, "Watermark column of a table for --incremental, as table=column")
+	exportCmd.Flags().Bool("reset-watermarks", false, "Export every table in full with --incremental and record new watermarks")
 }
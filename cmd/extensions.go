@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/plugin"
+	"github.com/fatih/color"
+)
+
+// loadExtensions registers the database adapters and commands of the
+// compiled-in extensions and the Go plugins of the extensions folder
+func loadExtensions() {
+	for _, err := range plugin.LoadGoPlugins() {
+		color.Yellow("⚠️  Skipping extension: %v", err)
+	}
+	plugin.RegisterExtensionAdapters()
+
+	ctx := &plugin.Context{Version: Version}
+	for _, ext := range plugin.Extensions() {
+		for _, command := range ext.Commands(ctx) {
+			if isKnownCommand(command.Name()) {
+				color.Yellow("⚠️  Extension '%s' command '%s' conflicts with a built-in command, skipping", ext.Name(), command.Name())
+				continue
+			}
+			rootCmd.AddCommand(command)
+		}
+	}
+}
+
+// runExternalCommand runs flash-<name> from PATH, git style, when the first
+// argument isn't a command flash knows. It reports whether one was run.
+func runExternalCommand() (bool, error) {
+	if len(os.Args) < 2 || isKnownCommand(os.Args[1]) {
+		return false, nil
+	}
+
+	external, ok := plugin.FindExternalCommand(os.Args[1])
+	if !ok {
+		return false, nil
+	}
+
+	// Cobra never parses the arguments, so pick up --config for the context
+	cfgFile = configFlag(os.Args[2:])
+	initConfig()
+	return true, plugin.ExecuteExternal(external, os.Args[2:], Version)
+}
+
+func isKnownCommand(name string) bool {
+	if name == "help" || name == "completion" {
+		return true
+	}
+	if _, requiresPlugin := plugin.GetRequiredPlugin(name); requiresPlugin {
+		return true
+	}
+	for _, command := range rootCmd.Commands() {
+		if command.Name() == name || command.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func configFlag(args []string) string {
+	for i, arg := range args {
+		if arg == "--config" && i+1 < len(args) {
+			return args[i+1]
+		}
+		if value, ok := strings.CutPrefix(arg, "--config="); ok {
+			return value
+		}
+	}
+	return ""
+}
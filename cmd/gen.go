@@ -5,11 +5,17 @@ package cmd
 
 import (
 	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
 
 	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/gencommon"
 	"github.com/Lumos-Labs-HQ/flash/internal/gogen"
+	"github.com/Lumos-Labs-HQ/flash/internal/hooks"
 	"github.com/Lumos-Labs-HQ/flash/internal/jsgen"
 	"github.com/Lumos-Labs-HQ/flash/internal/pygen"
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
@@ -23,7 +29,16 @@ Automatically detects project type and generates appropriate code:
 - Node.js projects: Generate JavaScript code with type annotations
 - Python projects: Generate Python code with type hints
 
-Configuration is read from flash.config.json`,
+Configuration is read from flash.config.json
+
+Files are only regenerated when their query file, the schema or the config
+changed since the last run, or when the generated code was edited. The
+checksums are kept in .flash_cache.json in the output folder; --force
+regenerates everything.
+
+With --check nothing is changed: the code is generated from scratch,
+compared with the files on disk and put back, and the command fails if
+they differ, e.g. to catch generated code that wasn't committed in CI.`,
 
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := config.Load()
@@ -31,45 +46,145 @@ Configuration is read from flash.config.json`,
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
-		generated := false
-		if cfg.Gen.JS.Enabled {
-			fmt.Println("🔨 Generating JavaScript code...")
-			generator := jsgen.New(cfg)
-			if err := generator.Generate(); err != nil {
-				return fmt.Errorf("failed to generate JavaScript code: %w", err)
-			}
-			fmt.Println("🎉 JavaScript code generated successfully!")
-			fmt.Printf("   Output: %s\n", cfg.Gen.JS.Out)
-			generated = true
+		if check, _ := cmd.Flags().GetBool("check"); check {
+			return checkGeneratedCode(cfg)
 		}
+		force, _ := cmd.Flags().GetBool("force")
+		return runGenerate(cfg, force)
+	},
+}
 
-		// Generate Python
-		if cfg.Gen.Python.Enabled {
-			fmt.Println("🔨 Generating Python code...")
-			generator := pygen.New(cfg)
-			if err := generator.Generate(); err != nil {
-				return fmt.Errorf("failed to generate Python code: %w", err)
-			}
-			fmt.Println("🎉 Python code generated successfully!")
-			fmt.Printf("   Output: %s\n", cfg.Gen.Python.Out)
-			generated = true
-		}
+func runGenerate(cfg *config.Config, force bool) error {
+	event := hooks.Event{Command: "gen", Languages: genLanguages(cfg)}
+	if err := hooks.Pre(cfg, "generate", event); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err := generateCode(cfg, force)
+	hooks.Post(cfg, "generate", event, start, err)
+	if err != nil {
+		return err
+	}
 
-		// Generate Go (default if nothing else enabled)
-		if !generated {
-			fmt.Println("🔨 Generating Go code...")
-			generator := gogen.New(cfg)
-			if err := generator.Generate(); err != nil {
-				return fmt.Errorf("failed to generate Go code: %w", err)
-			}
-			fmt.Println("🎉 Go code generated successfully!")
-			fmt.Println("   Output: flash_gen/")
+	// Post hooks may have formatted the generated files; record them as
+	// they are now so the next run doesn't see them as edited
+	for _, dir := range genOutputDirs(cfg) {
+		if err := gencommon.RefreshOutputs(dir, start); err != nil {
+			fmt.Printf("Warning: failed to update generation cache: %v\n", err)
 		}
+	}
+	return nil
+}
+
+// checkGeneratedCode regenerates every file, reports the ones that differ
+// from the files on disk and restores them
+func checkGeneratedCode(cfg *config.Config) error {
+	dirs := genOutputDirs(cfg)
+	before, err := gencommon.TakeSnapshot(dirs...)
+	if err != nil {
+		return fmt.Errorf("failed to read generated code: %w", err)
+	}
+	genErr := runGenerate(cfg, true)
+
+	after, err := gencommon.TakeSnapshot(dirs...)
+	if err != nil {
+		return fmt.Errorf("failed to read generated code: %w", err)
+	}
+	if err := before.Restore(after); err != nil {
+		return fmt.Errorf("failed to restore generated code: %w", err)
+	}
+	if genErr != nil {
+		return genErr
+	}
 
+	changed := before.Changed(after)
+	if len(changed) == 0 {
+		color.Green("✓ Generated code is up to date")
 		return nil
-	},
+	}
+
+	color.Red("✗ Generated code is out of date:")
+	for _, path := range changed {
+		fmt.Printf("  %s\n", path)
+	}
+	return fmt.Errorf("%d generated file(s) would change, run 'flash gen'", len(changed))
+}
+
+// genOutputDirs returns the directories gen writes to
+func genOutputDirs(cfg *config.Config) []string {
+	var dirs []string
+	if cfg.Gen.JS.Enabled {
+		dirs = append(dirs, filepath.Clean(cfg.Gen.JS.Out))
+	}
+	if cfg.Gen.Python.Enabled && !slices.Contains(dirs, filepath.Clean(cfg.Gen.Python.Out)) {
+		dirs = append(dirs, filepath.Clean(cfg.Gen.Python.Out))
+	}
+	if len(dirs) == 0 {
+		dirs = append(dirs, "flash_gen")
+	}
+	return dirs
+}
+
+// genLanguages returns the languages gen generates code for
+func genLanguages(cfg *config.Config) []string {
+	var languages []string
+	if cfg.Gen.JS.Enabled {
+		languages = append(languages, "js")
+	}
+	if cfg.Gen.Python.Enabled {
+		languages = append(languages, "python")
+	}
+	if len(languages) == 0 {
+		languages = append(languages, "go")
+	}
+	return languages
+}
+
+func generateCode(cfg *config.Config, force bool) error {
+	generated := false
+	if cfg.Gen.JS.Enabled {
+		fmt.Println("🔨 Generating JavaScript code...")
+		generator := jsgen.New(cfg)
+		generator.Force = force
+		if err := generator.Generate(); err != nil {
+			return fmt.Errorf("failed to generate JavaScript code: %w", err)
+		}
+		fmt.Println("🎉 JavaScript code generated successfully!")
+		fmt.Printf("   Output: %s\n", cfg.Gen.JS.Out)
+		generated = true
+	}
+
+	// Generate Python
+	if cfg.Gen.Python.Enabled {
+		fmt.Println("🔨 Generating Python code...")
+		generator := pygen.New(cfg)
+		generator.Force = force
+		if err := generator.Generate(); err != nil {
+			return fmt.Errorf("failed to generate Python code: %w", err)
+		}
+		fmt.Println("🎉 Python code generated successfully!")
+		fmt.Printf("   Output: %s\n", cfg.Gen.Python.Out)
+		generated = true
+	}
+
+	// Generate Go (default if nothing else enabled)
+	if !generated {
+		fmt.Println("🔨 Generating Go code...")
+		generator := gogen.New(cfg)
+		generator.Force = force
+		if err := generator.Generate(); err != nil {
+			return fmt.Errorf("failed to generate Go code: %w", err)
+		}
+		fmt.Println("🎉 Go code generated successfully!")
+		fmt.Println("   Output: flash_gen/")
+	}
+
+	return nil
 }
 
 func init() {
 	// Command is registered by plugin executors, not the base CLI
+	genCmd.Flags().BoolP("force", "f", false, "Regenerate every file, ignoring the generation cache")
+	genCmd.Flags().Bool("check", false, "Fail if generating would change the generated files, without changing them")
 }
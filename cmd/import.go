@@ -0,0 +1,223 @@
+//go:build plugin_studio || plugin_all || dev
+// +build plugin_studio plugin_all dev
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/database"
+	"github.com/Lumos-Labs-HQ/flash/internal/hooks"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+	mongostudio "github.com/Lumos-Labs-HQ/flash/internal/studio/mongodb"
+	sqlstudio "github.com/Lumos-Labs-HQ/flash/internal/studio/sql"
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a JSON export into the database",
+	Long: `
+Import a JSON export, from studio or from flash export, into the database.
+Tables that don't exist are created when the export has their schema, and
+rows are inserted, or updated when their primary key is already there.
+
+MongoDB projects import the collections of a MongoDB Studio export: missing
+collections are created with their options and indexes, and documents are
+inserted, or replace the document with the same _id. An export holding both
+tables and collections imports the part matching the database.
+
+--verify checks the rows of each table against the row count and checksum
+written with the export before anything is imported, and counts the rows of
+each table afterwards, failing when any table didn't get all of its rows.
+Collections are checked against their document counts and checksums.
+
+Examples:
+  flash import export_2025-01-02_15-04-05.json
+  flash import backup.json --verify`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		verify, _ := cmd.Flags().GetBool("verify")
+
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+
+		importData, err := readImportFile(args[0])
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+
+		adapter := database.NewAdapter(cfg.Database.Provider)
+
+		dbURL, err := cfg.GetDatabaseURL()
+		if err != nil {
+			return err
+		}
+
+		if err := adapter.Connect(ctx, dbURL); err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		defer adapter.Close()
+
+		if err := adapter.Ping(ctx); err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		event := hooks.Event{Command: "import"}
+		for _, table := range importData.Tables {
+			event.Tables = append(event.Tables, table.Name)
+		}
+		for _, collection := range importData.Collections {
+			event.Tables = append(event.Tables, collection.Name)
+		}
+		if err := hooks.Pre(cfg, "import", event); err != nil {
+			return err
+		}
+
+		// MongoDB takes the collections of the export, SQL databases its
+		// tables
+		isMongo := cfg.Database.Provider == "mongodb" || cfg.Database.Provider == "mongo"
+		start := time.Now()
+		var result *common.ImportResult
+		if isMongo {
+			result, err = mongostudio.NewService(adapter).ImportDatabase(ctx, importData, verify)
+		} else {
+			result, err = sqlstudio.NewService(adapter, cfg).ImportDatabase(ctx, importData, verify)
+		}
+		hooks.Post(cfg, "import", event, start, err)
+		if err != nil {
+			return err
+		}
+
+		if isMongo {
+			fmt.Printf("✅ Import completed: %d document(s) inserted, %d replaced\n", result.RowsInserted, result.RowsUpdated)
+		} else {
+			fmt.Printf("✅ Import completed: %d row(s) inserted, %d updated\n", result.RowsInserted, result.RowsUpdated)
+		}
+		if len(result.TablesCreated) > 0 {
+			fmt.Printf("   Tables created: %d\n", len(result.TablesCreated))
+		}
+		if result.ColumnsAdded > 0 {
+			fmt.Printf("   Columns added: %d\n", result.ColumnsAdded)
+		}
+		if len(result.CollectionsCreated) > 0 {
+			fmt.Printf("   Collections created: %d\n", len(result.CollectionsCreated))
+		}
+		if result.IndexesCreated > 0 {
+			fmt.Printf("   Indexes created: %d\n", result.IndexesCreated)
+		}
+		for _, e := range result.Errors {
+			fmt.Printf("⚠️  %s\n", e)
+		}
+
+		if !result.Verified {
+			return nil
+		}
+		if isMongo {
+			// Collections are checked against their checksums before the
+			// import, which fails when they don't match
+			fmt.Println("✅ Checksums verified")
+			return nil
+		}
+		if len(result.Incomplete) == 0 {
+			fmt.Println("✅ Row counts verified")
+			return nil
+		}
+		for _, check := range result.Incomplete {
+			fmt.Printf("❌ %s: %s\n", check.Table, check.Problem)
+		}
+		return fmt.Errorf("%d table(s) didn't fully import", len(result.Incomplete))
+	},
+}
+
+// readImportFile reads an export written by studio, with or without the
+// API's {"success", "data"} wrapper around it, or by flash export, whose
+// tables it turns into data-only tables of a studio export
+func readImportFile(path string) (*common.ExportData, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return nil, fmt.Errorf("%s is not a JSON export: %w", path, err)
+	}
+	if data, ok := fields["data"]; ok {
+		if _, wrapped := fields["success"]; wrapped {
+			content = data
+			fields = nil
+			if err := json.Unmarshal(content, &fields); err != nil {
+				return nil, fmt.Errorf("%s is not a JSON export: %w", path, err)
+			}
+		}
+	}
+
+	// Numbers stay as written, so checksums and big integers survive
+	decode := func(target any) error {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		return decoder.Decode(target)
+	}
+
+	tables := bytes.TrimSpace(fields["tables"])
+	switch {
+	case len(tables) == 0 && len(fields["collections"]) == 0:
+		return nil, fmt.Errorf("%s has no tables", path)
+	case len(tables) > 0 && tables[0] == '{':
+		var backup struct {
+			types.BackupData
+			Tables map[string][]map[string]any `json:"tables"`
+		}
+		if err := decode(&backup); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		importData := &common.ExportData{
+			Version:    backup.Version,
+			ExportedAt: backup.Timestamp,
+			ExportType: common.ExportDataOnly,
+		}
+		names := make([]string, 0, len(backup.Tables))
+		for name := range backup.Tables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			sum := backup.Checksums[name]
+			importData.Tables = append(importData.Tables, common.ExportTable{
+				Name:     name,
+				Data:     backup.Tables[name],
+				RowCount: sum.Rows,
+				Checksum: sum.Checksum,
+			})
+		}
+		return importData, nil
+	default:
+		var importData common.ExportData
+		if err := decode(&importData); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		return &importData, nil
+	}
+}
+
+func init() {
+	// Command is registered by plugin executors, not the base CLI
+	importCmd.Flags().Bool("verify", false, "Check row counts and checksums before importing and count rows afterwards")
+}
@@ -0,0 +1,160 @@
+//go:build plugin_core || plugin_all || dev
+// +build plugin_core plugin_all dev
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/matview"
+	"github.com/Lumos-Labs-HQ/flash/internal/migrator"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var matviewCmd = &cobra.Command{
+	Use:   "matview",
+	Short: "List and refresh PostgreSQL materialized views",
+	Long: `Manage the materialized views of a PostgreSQL database. Materialized views
+are declared in the schema like tables; these commands show their state and
+run their queries again.
+
+The latest refresh of every view is kept in
+<migrations_path>/.flash/matviews.json and shown here and in the studio.`,
+}
+
+var matviewListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List materialized views with their size and last refresh",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMatviews(func(ctx context.Context, service *matview.Service) error {
+			views, err := service.List(ctx)
+			if err != nil {
+				return err
+			}
+			if len(views) == 0 {
+				color.Yellow("No materialized views found")
+				return nil
+			}
+
+			fmt.Println()
+			for _, view := range views {
+				state := fmt.Sprintf("~%d row(s)", view.RowEstimate)
+				if !view.Populated {
+					state = color.YellowString("not populated")
+				}
+				concurrent := ""
+				if view.HasUniqueIndex {
+					concurrent = "concurrent"
+				}
+
+				last := "never refreshed by flash"
+				if r := view.LastRefresh; r != nil {
+					status := color.GreenString("✓ %dms", r.DurationMS)
+					if !r.Success {
+						status = color.RedString("✗ %s", r.Error)
+					}
+					last = fmt.Sprintf("refreshed %s %s", r.StartedAt.Format("2006-01-02 15:04"), status)
+				}
+
+				fmt.Printf("  %-28s %-16s %-10s %-10s %s\n", view.Name, state, formatMatviewSize(view.SizeBytes), concurrent, last)
+			}
+			fmt.Println()
+			return nil
+		})
+	},
+}
+
+var matviewRefreshCmd = &cobra.Command{
+	Use:   "refresh [name...]",
+	Short: "Refresh materialized views",
+	Long: `Run the queries of materialized views again. With --concurrently the views
+stay readable during the refresh, which needs a unique index on each view.`,
+	Example: `  flash matview refresh daily_sales
+  flash matview refresh daily_sales top_customers --concurrently
+  flash matview refresh --all`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		concurrently, _ := cmd.Flags().GetBool("concurrently")
+		all, _ := cmd.Flags().GetBool("all")
+		if all == (len(args) > 0) {
+			return fmt.Errorf("give the views to refresh or --all")
+		}
+
+		return withMatviews(func(ctx context.Context, service *matview.Service) error {
+			names := args
+			if all {
+				views, err := service.List(ctx)
+				if err != nil {
+					return err
+				}
+				for _, view := range views {
+					names = append(names, view.Name)
+				}
+				if len(names) == 0 {
+					color.Yellow("No materialized views found")
+					return nil
+				}
+			}
+
+			failed := 0
+			for _, name := range names {
+				refresh, err := service.Refresh(ctx, name, concurrently, "cli")
+				if err != nil {
+					color.Red("  ✗ %v", err)
+					failed++
+					continue
+				}
+				color.Green("  ✓ %s refreshed in %dms", name, refresh.DurationMS)
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d materialized view(s) failed to refresh", failed, len(names))
+			}
+			return nil
+		})
+	},
+}
+
+// withMatviews connects to the database of the current branch and runs fn
+// with a materialized view service for it
+func withMatviews(fn func(context.Context, *matview.Service) error) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	m, err := migrator.NewBranchAwareMigrator(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create migrator: %w", err)
+	}
+	defer m.Close()
+
+	service, err := matview.NewService(m.Adapter(), cfg.MigrationsPath)
+	if err != nil {
+		return err
+	}
+	return fn(context.Background(), service)
+}
+
+func formatMatviewSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+func init() {
+	// Command is registered by plugin executors, not the base CLI
+	matviewCmd.AddCommand(matviewListCmd)
+	matviewCmd.AddCommand(matviewRefreshCmd)
+
+	matviewRefreshCmd.Flags().Bool("concurrently", false, "Refresh without locking out readers (needs a unique index)")
+	matviewRefreshCmd.Flags().Bool("all", false, "Refresh every materialized view")
+}
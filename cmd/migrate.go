@@ -29,10 +29,16 @@ The migration file will include:
 - Down migration section (rollback changes)
 - Auto-generated SQL based on schema differences (if --auto flag is used)
 
+With --review the generated changes are shown grouped by table before
+anything is written. Each statement can be approved, skipped or edited, a
+dropped table or column can be turned into a rename, and the migration name
+can be changed. The reviewed migration is then written and applied.
+
 Examples:
   flash migrate "create users table"
   flash migrate "add email index" --auto
   flash migrate --empty "custom migration"
+  flash migrate --review "rename user columns"
   flash migrate  # Interactive mode`,
 
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -49,10 +55,12 @@ Examples:
 			return fmt.Errorf("failed to create directories: %w", err)
 		}
 
+		review, _ := cmd.Flags().GetBool("review")
+
 		var migrationName string
 		if len(args) > 0 {
 			migrationName = strings.Join(args, " ")
-		} else {
+		} else if !review {
 			fmt.Print("Enter migration name: ")
 			reader := bufio.NewReader(os.Stdin)
 			input, err := reader.ReadString('\n')
@@ -62,7 +70,7 @@ Examples:
 			migrationName = strings.TrimSpace(input)
 		}
 
-		if migrationName == "" {
+		if migrationName == "" && !review {
 			return fmt.Errorf("migration name cannot be empty")
 		}
 
@@ -80,6 +88,11 @@ Examples:
 		}
 		defer m.Close()
 
+		if review {
+			// The review asks for the name once the changes are known
+			return m.ReviewMigration(ctx, migrationName, "")
+		}
+
 		empty, _ := cmd.Flags().GetBool("empty")
 
 		if empty {
@@ -106,4 +119,5 @@ func init() {
 	// Command is registered by plugin executors, not the base CLI
 
 	migrateCmd.Flags().BoolP("empty", "e", false, "Create an empty migration template without schema diff")
+	migrateCmd.Flags().BoolP("review", "r", false, "Review the generated statements interactively, then write and apply the migration")
 }
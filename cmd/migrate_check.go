@@ -0,0 +1,134 @@
+//go:build plugin_core || plugin_all || dev
+// +build plugin_core plugin_all dev
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/migrator"
+
+	"github.com/spf13/cobra"
+)
+
+var migrateCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Validate migrations on a disposable shadow database",
+	Long: `
+Apply every migration to a new, empty shadow database and check the result.
+Meant to run in CI on each pull request.
+
+The check fails when:
+- A migration fails to apply
+- The migrated database doesn't match the schema files (drift)
+- A migration is destructive and --fail-on-destructive is set
+
+The shadow database is created on the server --against points to, by
+default the server of database.shadow_url_env or of the configured
+database, and dropped afterwards. SQLite uses a temporary file.
+
+The report is printed as JSON whose annotations have the fields of GitHub
+check run annotations. --format github prints workflow commands instead, so
+the problems show up on the pull request.
+
+Examples:
+  flash migrate check --against postgres://ci:ci@localhost:5432/postgres
+  flash migrate check --base origin/main --format github
+  flash migrate check --fail-on-destructive`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+
+		format, _ := cmd.Flags().GetString("format")
+		if format != "json" && format != "github" {
+			return fmt.Errorf("invalid --format value %q, use json or github", format)
+		}
+
+		opts := migrator.CheckOptions{}
+		opts.ServerURL, _ = cmd.Flags().GetString("against")
+		opts.Base, _ = cmd.Flags().GetString("base")
+		opts.FailOnDestructive, _ = cmd.Flags().GetBool("fail-on-destructive")
+		if opts.ServerURL == "" && cfg.Database.Provider != "sqlite" && cfg.Database.Provider != "sqlite3" {
+			if opts.ServerURL, err = cfg.GetShadowDatabaseURL(); err != nil {
+				return fmt.Errorf("no --against URL given: %w", err)
+			}
+		}
+
+		report, err := migrator.CheckMigrations(context.Background(), cfg, opts)
+		if err != nil {
+			return err
+		}
+
+		if format == "github" {
+			printGitHubAnnotations(report)
+		} else {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(report); err != nil {
+				return err
+			}
+		}
+
+		if !report.OK {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("migration check failed")
+		}
+		return nil
+	},
+}
+
+// printGitHubAnnotations prints the report as GitHub Actions workflow
+// commands followed by a summary
+func printGitHubAnnotations(report *migrator.CheckReport) {
+	escape := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	escapeProperty := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+
+	for _, a := range report.Annotations {
+		command := "warning"
+		switch a.AnnotationLevel {
+		case "failure":
+			command = "error"
+		case "notice":
+			command = "notice"
+		}
+		fmt.Printf("::%s file=%s,line=%d,endLine=%d,title=%s::%s\n",
+			command, escapeProperty.Replace(a.Path), a.StartLine, a.EndLine,
+			escapeProperty.Replace(a.Title), escape.Replace(a.Message))
+	}
+
+	fmt.Printf("Applied %d migration(s) to a shadow %s database\n", len(report.Migrations), report.Provider)
+	if report.Failed != "" {
+		fmt.Printf("❌ Migration %s failed\n", report.Failed)
+	}
+	if len(report.Drift) > 0 {
+		fmt.Printf("❌ Schema drift: %s\n", strings.Join(report.Drift, ", "))
+	}
+	if report.Destructive > 0 {
+		fmt.Printf("⚠️  %d destructive statement(s)\n", report.Destructive)
+	}
+	if report.OK {
+		fmt.Println("✅ Migrations are valid")
+	}
+}
+
+func init() {
+	// Command is registered by plugin executors, not the base CLI
+
+	migrateCheckCmd.Flags().String("against", "", "Database server URL to create the shadow database on (default: the shadow_url_env or configured database server)")
+	migrateCheckCmd.Flags().String("base", "", "Git ref; only migrations changed since it are checked for destructive statements")
+	migrateCheckCmd.Flags().String("format", "json", "Output format: json or github")
+	migrateCheckCmd.Flags().Bool("fail-on-destructive", false, "Fail when a migration contains destructive statements")
+	migrateCmd.AddCommand(migrateCheckCmd)
+}
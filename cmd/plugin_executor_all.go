@@ -24,11 +24,22 @@ func ExecuteAllPlugin() error {
 	allRoot.AddCommand(rawCmd)
 	allRoot.AddCommand(branchCmd)
 	allRoot.AddCommand(checkoutCmd)
+	allRoot.AddCommand(tenantCmd)
 	allRoot.AddCommand(genCmd)
+	allRoot.AddCommand(queriesCmd)
+	allRoot.AddCommand(analyzeCmd)
+	allRoot.AddCommand(benchCmd)
+	allRoot.AddCommand(stateCmd)
 	allRoot.AddCommand(exportCmd)
+	allRoot.AddCommand(datadiffCmd)
+	allRoot.AddCommand(serveCmd)
+	allRoot.AddCommand(scheduleCmd)
+	allRoot.AddCommand(matviewCmd)
+	allRoot.AddCommand(devCmd)
 
 	// Add studio command
 	allRoot.AddCommand(studioCmd)
+	allRoot.AddCommand(importCmd)
 
 	// Add seed command
 	allRoot.AddCommand(seedCmd)
@@ -14,6 +14,7 @@ func ExecuteStudioPlugin() error {
 	}
 
 	studioRoot.AddCommand(studioCmd)
+	studioRoot.AddCommand(importCmd)
 
 	return studioRoot.Execute()
 }
@@ -24,8 +24,18 @@ func ExecuteCorePlugin() error {
 	coreRoot.AddCommand(rawCmd)
 	coreRoot.AddCommand(branchCmd)
 	coreRoot.AddCommand(checkoutCmd)
+	coreRoot.AddCommand(tenantCmd)
 	coreRoot.AddCommand(genCmd)
+	coreRoot.AddCommand(queriesCmd)
+	coreRoot.AddCommand(analyzeCmd)
+	coreRoot.AddCommand(benchCmd)
+	coreRoot.AddCommand(stateCmd)
 	coreRoot.AddCommand(exportCmd)
+	coreRoot.AddCommand(datadiffCmd)
+	coreRoot.AddCommand(serveCmd)
+	coreRoot.AddCommand(scheduleCmd)
+	coreRoot.AddCommand(matviewCmd)
+	coreRoot.AddCommand(devCmd)
 	coreRoot.AddCommand(seedCmd)
 
 	return coreRoot.Execute()
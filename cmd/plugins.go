@@ -46,6 +46,7 @@ Examples:
 			}
 			fmt.Println()
 			color.Cyan("💡 Check online plugins: flash plugins --online")
+			showExtensions()
 			return nil
 		}
 
@@ -75,6 +76,7 @@ Examples:
 		color.Cyan("💡 Add more plugins: flash add-plug <plugin-name>")
 		color.Cyan("💡 Remove plugins: flash rm-plug <plugin-name>")
 		color.Cyan("💡 Check online plugins: flash plugins --online")
+		showExtensions()
 
 		return nil
 	},
@@ -84,6 +86,34 @@ func init() {
 	pluginsCmd.Flags().BoolP("online", "o", false, "Show all available plugins from GitHub repository")
 }
 
+// showExtensions lists the commands added by extensions and by flash-*
+// executables on PATH
+func showExtensions() {
+	extensions := plugin.Extensions()
+	external := plugin.ListExternalCommands()
+	if len(extensions) == 0 && len(external) == 0 {
+		return
+	}
+
+	fmt.Println()
+	color.Green("🧩 Third-party Commands")
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "COMMAND\tSOURCE")
+	fmt.Fprintln(w, "-------\t------")
+	ctx := &plugin.Context{Version: Version}
+	for _, ext := range extensions {
+		for _, command := range ext.Commands(ctx) {
+			fmt.Fprintf(w, "%s\textension %s\n", color.CyanString(command.Name()), ext.Name())
+		}
+	}
+	for _, command := range external {
+		fmt.Fprintf(w, "%s\t%s\n", color.CyanString(command.Name), command.Path)
+	}
+	w.Flush()
+}
+
 // showOnlinePlugins displays all available plugins from GitHub with their status
 func showOnlinePlugins(manager *plugin.Manager) error {
 	color.Cyan("🌐 Fetching available plugins from GitHub...")
@@ -0,0 +1,83 @@
+//go:build plugin_core || plugin_all || dev
+// +build plugin_core plugin_all dev
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/gencommon"
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+	"github.com/Lumos-Labs-HQ/flash/internal/querylint"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var queriesCmd = &cobra.Command{
+	Use:   "queries",
+	Short: "Check the SQL query files",
+}
+
+var queriesLintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Warn about likely mistakes in SQL queries",
+	Long: `
+Check the query files against the schema for queries that are valid but
+likely mistakes. Queries that don't parse or reference unknown tables or
+columns fail as they do in flash gen.
+
+Rules:
+  select-star         SELECT * in a :many query
+  missing-limit       :many SELECT with neither WHERE nor LIMIT, and no paginate annotation
+  param-type          column compared with a parameter cast to, or a literal of, another type
+  dml-without-where   UPDATE or DELETE without WHERE
+  unused-query        query not called by any .go, .js, .ts or .py file of the project
+
+A rule is turned off for one query with a comment in it, or for the whole
+file with a comment before the first query:
+  -- lint:disable select-star, missing-limit
+A bare "-- lint:disable" turns off every rule.
+
+Findings are warnings; with --strict the command fails when there are any.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		schema, queries, err := gencommon.ParseInputs(parser.NewSchemaParser(cfg), parser.NewQueryParser(cfg))
+		if err != nil {
+			return err
+		}
+
+		findings, err := querylint.Lint(cfg, schema, queries)
+		if err != nil {
+			return err
+		}
+
+		for _, f := range findings {
+			fmt.Println(f)
+		}
+		if len(findings) == 0 {
+			color.Green("✅ %d queries checked, no problems found", len(queries))
+			return nil
+		}
+
+		fmt.Println()
+		color.Yellow("⚠️  %d problem(s) found in %d queries", len(findings), len(queries))
+		if strict, _ := cmd.Flags().GetBool("strict"); strict {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("query lint failed")
+		}
+		return nil
+	},
+}
+
+func init() {
+	// Command is registered by plugin executors, not the base CLI
+	queriesCmd.AddCommand(queriesLintCmd)
+
+	queriesLintCmd.Flags().Bool("strict", false, "Fail when any problem is found")
+}
@@ -86,6 +86,11 @@ Database Support:
 }
 
 func Execute() error {
+	loadExtensions()
+	if ran, err := runExternalCommand(); ran {
+		return err
+	}
+
 	// Check if the first argument is a plugin command
 	if len(os.Args) > 1 {
 		commandName := os.Args[1]
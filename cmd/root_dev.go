@@ -85,6 +85,11 @@ Database Support:
 }
 
 func Execute() error {
+	loadExtensions()
+	if ran, err := runExternalCommand(); ran {
+		return err
+	}
+
 	return rootCmd.Execute()
 }
 
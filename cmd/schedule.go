@@ -0,0 +1,199 @@
+//go:build plugin_core || plugin_all || dev
+// +build plugin_core plugin_all dev
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/schedule"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Run named queries on a cron schedule",
+	Long: `Manage scheduled queries. A schedule runs a named query from the queries
+folder on a cron expression and records its row count, duration and
+optionally a snapshot of the result. Failures are sent to the configured
+notification targets.
+
+Schedules are kept in <migrations_path>/.flash/schedules.json and can also
+be managed from the studio. 'flash schedule run' without a name runs them
+in the foreground.`,
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List schedules with their last and next run",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runner, err := newScheduleRunner()
+		if err != nil {
+			return err
+		}
+
+		store, err := runner.Metadata().Load()
+		if err != nil {
+			return err
+		}
+		if len(store.Schedules) == 0 {
+			color.Yellow("No schedules found")
+			return nil
+		}
+		runs, err := runner.Metadata().LoadRuns()
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		fmt.Println()
+		for _, sch := range store.Schedules {
+			next := "never due"
+			if sch.Disabled {
+				next = "disabled"
+			} else if t := schedule.NextRun(sch, now); !t.IsZero() {
+				next = "next " + t.Format("2006-01-02 15:04")
+			}
+
+			last := "never run"
+			if scheduleRuns := runs.ScheduleRuns(sch.Name); len(scheduleRuns) > 0 {
+				run := scheduleRuns[0]
+				status := color.GreenString("✓ %d row(s)", run.RowCount)
+				if !run.Success {
+					status = color.RedString("✗ %s", run.Error)
+				}
+				last = fmt.Sprintf("last %s %s", run.StartedAt.Format("2006-01-02 15:04"), status)
+			}
+
+			fmt.Printf("  %-20s %-16s %-24s %-22s %s\n", sch.Name, sch.Cron, sch.Query, next, last)
+		}
+		fmt.Println()
+		return nil
+	},
+}
+
+var scheduleAddCmd = &cobra.Command{
+	Use:   "add <name> <query> <cron>",
+	Short: "Add or replace a schedule",
+	Example: `  flash schedule add nightly-signups CountSignups "0 2 * * *"
+  flash schedule add stale-orders GetStaleOrders "*/15 * * * *" --params '["pending"]' --snapshot`,
+	Args: cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runner, err := newScheduleRunner()
+		if err != nil {
+			return err
+		}
+
+		sch := &schedule.Schedule{Name: args[0], Query: args[1], Cron: args[2]}
+		sch.Snapshot, _ = cmd.Flags().GetBool("snapshot")
+		sch.Notify, _ = cmd.Flags().GetString("notify")
+		sch.URLEnv, _ = cmd.Flags().GetString("url-env")
+		sch.Disabled, _ = cmd.Flags().GetBool("disabled")
+		if params, _ := cmd.Flags().GetString("params"); params != "" {
+			if err := json.Unmarshal([]byte(params), &sch.Params); err != nil {
+				return fmt.Errorf("--params must be a JSON array: %w", err)
+			}
+		}
+		if err := runner.Save(sch); err != nil {
+			return err
+		}
+
+		color.Green("✓ Schedule '%s' runs %s on %s", sch.Name, sch.Query, sch.Cron)
+		return nil
+	},
+}
+
+var scheduleRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a schedule and its runs",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runner, err := newScheduleRunner()
+		if err != nil {
+			return err
+		}
+
+		if err := runner.Remove(args[0]); err != nil {
+			return err
+		}
+
+		color.Green("✓ Schedule '%s' removed", args[0])
+		return nil
+	},
+}
+
+var scheduleRunCmd = &cobra.Command{
+	Use:   "run [name]",
+	Short: "Run a schedule now, or all schedules on their cron expressions",
+	Long: `With a name, run that schedule once now. Without one, run every enabled
+schedule whenever its cron expression matches until interrupted. Cron
+expressions are evaluated in local time.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runner, err := newScheduleRunner()
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 1 {
+			run, err := runner.RunOnce(context.Background(), args[0])
+			if err != nil {
+				return err
+			}
+			printScheduleRun(run)
+			if !run.Success {
+				return fmt.Errorf("schedule '%s' failed", run.Schedule)
+			}
+			return nil
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		color.Cyan("⏰ Running schedules, press Ctrl+C to stop")
+		if err := runner.Start(ctx, printScheduleRun); err != nil {
+			return err
+		}
+		fmt.Println("👋 Scheduler stopped")
+		return nil
+	},
+}
+
+func printScheduleRun(run *schedule.Run) {
+	stamp := run.StartedAt.Format("15:04:05")
+	if run.Success {
+		color.Green("  ✓ %s %-20s %d row(s) in %dms", stamp, run.Schedule, run.RowCount, run.DurationMS)
+		return
+	}
+	color.Red("  ✗ %s %-20s failed after %dms: %s", stamp, run.Schedule, run.DurationMS, run.Error)
+}
+
+func newScheduleRunner() (*schedule.Runner, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return schedule.NewRunner(cfg)
+}
+
+func init() {
+	// Command is registered by plugin executors, not the base CLI
+	scheduleCmd.AddCommand(scheduleListCmd)
+	scheduleCmd.AddCommand(scheduleAddCmd)
+	scheduleCmd.AddCommand(scheduleRemoveCmd)
+	scheduleCmd.AddCommand(scheduleRunCmd)
+
+	scheduleAddCmd.Flags().String("params", "", "JSON array of values for the query's placeholders")
+	scheduleAddCmd.Flags().Bool("snapshot", false, "Keep the first rows of every result")
+	scheduleAddCmd.Flags().String("notify", "", "When to notify: failure (default), always or never")
+	scheduleAddCmd.Flags().String("url-env", "", "Environment variable with the database URL (default: database.url_env)")
+	scheduleAddCmd.Flags().Bool("disabled", false, "Add the schedule without running it")
+}
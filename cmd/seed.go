@@ -118,7 +118,123 @@ Examples:
 	},
 }
 
+var seedSnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Capture rows of a live database into fixture files",
+	Long: `
+Copy the current rows of the selected tables into SQL fixture files, one per
+table, numbered in foreign key order. Point --url-env at a staging database
+to refresh realistic dev seeds in one command.
+
+Rows whose parent row in another captured table wasn't captured, because of
+--limit, are left out so the fixtures load without foreign key errors.
+Columns listed in seed.anonymize in flash.config.json are replaced:
+
+  "seed": {
+    "dir": "db/seeds",
+    "anonymize": { "users.email": "email", "users.name": "fake", "*.phone": "null" }
+  }
+
+Rules: null, hash, email (unique user_<hash>@example.com) and fake.
+
+The files of the previous snapshot in the folder are replaced. Load them
+into a dev database with flash seed load.
+
+Examples:
+  flash seed snapshot --tables users,posts
+  flash seed snapshot --tables users,posts,comments --limit 500 --url-env STAGING_DATABASE_URL`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+
+		tables, _ := cmd.Flags().GetStringSlice("tables")
+		if len(tables) == 0 {
+			return fmt.Errorf("no tables given, use --tables users,posts")
+		}
+		limit, _ := cmd.Flags().GetInt("limit")
+		dir, _ := cmd.Flags().GetString("out")
+		if dir == "" {
+			dir = cfg.Seed.Dir
+		}
+
+		urlEnv, _ := cmd.Flags().GetString("url-env")
+		if urlEnv != "" {
+			cfg.Database.URLEnv = urlEnv
+		}
+		dbURL, err := cfg.GetDatabaseURL()
+		if err != nil {
+			return fmt.Errorf("failed to get database URL: %w", err)
+		}
+
+		s, err := seeder.NewSeederForURL(cfg, dbURL)
+		if err != nil {
+			return fmt.Errorf("failed to create seeder: %w", err)
+		}
+		defer s.Close()
+
+		return s.Snapshot(context.Background(), seeder.SnapshotConfig{
+			Tables: tables,
+			Limit:  limit,
+			Dir:    dir,
+		})
+	},
+}
+
+var seedLoadCmd = &cobra.Command{
+	Use:   "load",
+	Short: "Insert the rows of the fixture files of a snapshot",
+	Long: `
+Insert the rows of the fixture files written by flash seed snapshot, in
+foreign key order and in one transaction.
+
+Examples:
+  flash seed load
+  flash seed load --truncate          # Empty the tables first`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+
+		dir, _ := cmd.Flags().GetString("dir")
+		if dir == "" {
+			dir = cfg.Seed.Dir
+		}
+		truncate, _ := cmd.Flags().GetBool("truncate")
+
+		s, err := seeder.NewSeeder(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to create seeder: %w", err)
+		}
+		defer s.Close()
+
+		return s.LoadSnapshot(context.Background(), dir, truncate)
+	},
+}
+
 func init() {
+	seedCmd.AddCommand(seedSnapshotCmd)
+	seedCmd.AddCommand(seedLoadCmd)
+	seedSnapshotCmd.Flags().StringSlice("tables", nil, "Tables to capture (comma-separated)")
+	seedSnapshotCmd.Flags().Int("limit", 0, "Rows to capture per table, ordered by primary key (default all)")
+	seedSnapshotCmd.Flags().StringP("out", "o", "", "Folder to write the fixture files to (default: seed.dir, db/seeds)")
+	seedSnapshotCmd.Flags().String("url-env", "", "Environment variable with the URL of the database to capture (default: database.url_env)")
+
+	seedLoadCmd.Flags().String("dir", "", "Folder of the fixture files (default: seed.dir, db/seeds)")
+	seedLoadCmd.Flags().BoolP("truncate", "t", false, "Empty the tables before loading")
+
 	seedCmd.Flags().IntP("count", "c", 10, "Number of records to generate per table")
 	seedCmd.Flags().BoolP("relations", "r", false, "Include foreign key relationships")
 	seedCmd.Flags().BoolP("truncate", "t", false, "Truncate tables before seeding")
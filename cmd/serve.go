@@ -0,0 +1,97 @@
+//go:build plugin_core || plugin_all || dev
+// +build plugin_core plugin_all dev
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/admin"
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the admin API",
+	Long: `
+Run a long-lived HTTP server exposing flash operations to internal tooling
+and dashboards, so they don't have to run the CLI for every call.
+
+Endpoints (all but /api/health need "Authorization: Bearer <token>"):
+  GET    /api/health                   Liveness check
+  GET    /api/status                   Migrations and their status
+  POST   /api/deploy                   Apply pending migrations ({"dry_run": true} lists them)
+  GET    /api/diff                     Changes between the database and the schema files
+  GET    /api/export?tables=a,b        Table data as JSON
+  GET    /api/branches                 Branches and the current one
+  POST   /api/branches                 Create a branch ({"name": "feature"})
+  GET    /api/branches/diff?from=&to=  Schema differences between branches
+  POST   /api/branches/{name}/checkout Switch branches
+  DELETE /api/branches/{name}          Delete a branch
+
+The token is read from --token or the FLASH_ADMIN_TOKEN environment
+variable. Deploys run the configured hooks and notifications like
+'flash apply'.
+
+Examples:
+  FLASH_ADMIN_TOKEN=secret flash serve --admin
+  flash serve --admin --host 0.0.0.0 --port 8080 --token secret`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if enabled, _ := cmd.Flags().GetBool("admin"); !enabled {
+			return fmt.Errorf("nothing to serve, use --admin to serve the admin API")
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+
+		token, _ := cmd.Flags().GetString("token")
+		if token == "" {
+			token = os.Getenv("FLASH_ADMIN_TOKEN")
+		}
+		if token == "" {
+			return fmt.Errorf("set a token with --token or FLASH_ADMIN_TOKEN")
+		}
+
+		server, err := admin.NewServer(cfg, token)
+		if err != nil {
+			return err
+		}
+
+		host, _ := cmd.Flags().GetString("host")
+		port, _ := cmd.Flags().GetInt("port")
+		addr := net.JoinHostPort(host, strconv.Itoa(port))
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		fmt.Printf("🚀 Admin API listening on http://%s\n", addr)
+		if err := server.Start(ctx, addr); err != nil {
+			return err
+		}
+		fmt.Println("👋 Admin API stopped")
+		return nil
+	},
+}
+
+func init() {
+	// Command is registered by plugin executors, not the base CLI
+
+	serveCmd.Flags().Bool("admin", false, "Serve the admin API")
+	serveCmd.Flags().String("host", "127.0.0.1", "Address to listen on")
+	serveCmd.Flags().Int("port", 5556, "Port to listen on")
+	serveCmd.Flags().String("token", "", "Bearer token clients must send (default: $FLASH_ADMIN_TOKEN)")
+}
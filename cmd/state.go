@@ -0,0 +1,77 @@
+//go:build plugin_core || plugin_all || dev
+// +build plugin_core plugin_all dev
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/state"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var stateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "Inspect the project's local state store",
+	Long: `
+FlashORM keeps the local state of a project in a SQLite database,
+<migrations_path>/.flash/state.db: branch metadata, studio filter presets,
+the SQL editor's query history, Redis CLI audit entries, export watermarks
+and diagram layouts. Open it with any SQLite client to query it, or export
+it as JSON.`,
+}
+
+var stateExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Print the whole state store as JSON",
+	Example: `  flash state export
+  flash state export -o state.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if !state.Exists(cfg.MigrationsPath) {
+			return fmt.Errorf("no state store at %s yet", state.Path(cfg.MigrationsPath))
+		}
+
+		st, err := state.Open(cfg.MigrationsPath)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		export, err := st.Export()
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal state: %w", err)
+		}
+		data = append(data, '\n')
+
+		output, _ := cmd.Flags().GetString("output")
+		if output == "" {
+			_, err := os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", output, err)
+		}
+		color.Green("✓ State exported to %s", output)
+		return nil
+	},
+}
+
+func init() {
+	// Command is registered by plugin executors, not the base CLI
+	stateCmd.AddCommand(stateExportCmd)
+
+	stateExportCmd.Flags().StringP("output", "o", "", "File to write the JSON to instead of stdout")
+}
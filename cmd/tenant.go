@@ -0,0 +1,143 @@
+//go:build plugin_core || plugin_all || dev
+// +build plugin_core plugin_all dev
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/tenant"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var tenantCmd = &cobra.Command{
+	Use:   "tenant",
+	Short: "Manage schema-per-tenant databases",
+	Long: `Manage tenants. Each tenant gets its own PostgreSQL schema (or MySQL
+database) with the same migrations as the main database.`,
+}
+
+var tenantCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a tenant and apply all migrations to it",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manager, err := newTenantManager()
+		if err != nil {
+			return err
+		}
+		defer manager.Close()
+
+		ctx := context.Background()
+		color.Cyan("Creating tenant '%s'...", args[0])
+		t, err := manager.CreateTenant(ctx, args[0])
+		if err != nil {
+			return fmt.Errorf("failed to create tenant: %w", err)
+		}
+
+		result := manager.MigrateTenant(ctx, t)
+		if result.Err != nil {
+			return fmt.Errorf("tenant '%s' created but migrations failed: %w", t.Name, result.Err)
+		}
+
+		color.Green("✓ Tenant '%s' created in %s (%d migration(s) applied)", t.Name, t.Schema, result.Applied)
+		return nil
+	},
+}
+
+var tenantListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List tenants",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		store, err := tenant.NewMetadataManager(cfg.MigrationsPath).Load()
+		if err != nil {
+			return err
+		}
+
+		if len(store.Tenants) == 0 {
+			color.Yellow("No tenants found")
+			return nil
+		}
+
+		fmt.Println()
+		for _, t := range store.Tenants {
+			fmt.Printf("  %-20s %-30s Created %s ago\n", t.Name, t.Schema, formatDuration(time.Since(t.CreatedAt)))
+		}
+		fmt.Println()
+
+		return nil
+	},
+}
+
+var tenantMigrateAllCmd = &cobra.Command{
+	Use:   "migrate-all",
+	Short: "Apply pending migrations to every tenant",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manager, err := newTenantManager()
+		if err != nil {
+			return err
+		}
+		defer manager.Close()
+
+		parallel, _ := cmd.Flags().GetInt("parallel")
+
+		results, err := manager.MigrateAll(context.Background(), parallel)
+		if err != nil {
+			return err
+		}
+
+		if len(results) == 0 {
+			color.Yellow("No tenants found")
+			return nil
+		}
+
+		var failed []tenant.MigrationResult
+		fmt.Println()
+		for _, r := range results {
+			if r.Err != nil {
+				failed = append(failed, r)
+				color.Red("  ✗ %-20s %d applied before failure (%s)", r.Tenant, r.Applied, r.Duration.Round(time.Millisecond))
+				continue
+			}
+			color.Green("  ✓ %-20s %d applied (%s)", r.Tenant, r.Applied, r.Duration.Round(time.Millisecond))
+		}
+		fmt.Println()
+
+		if len(failed) > 0 {
+			color.Red("Failures:")
+			for _, r := range failed {
+				fmt.Printf("  %s: %v\n", r.Tenant, r.Err)
+			}
+			return fmt.Errorf("%d of %d tenant(s) failed to migrate", len(failed), len(results))
+		}
+
+		color.Green("✓ All %d tenant(s) are up to date", len(results))
+		return nil
+	},
+}
+
+func newTenantManager() (*tenant.Manager, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return tenant.NewManager(cfg)
+}
+
+func init() {
+	// Command is registered by plugin executors, not the base CLI
+	tenantCmd.AddCommand(tenantCreateCmd)
+	tenantCmd.AddCommand(tenantListCmd)
+	tenantCmd.AddCommand(tenantMigrateAllCmd)
+
+	tenantMigrateAllCmd.Flags().IntP("parallel", "p", 4, "Number of tenants to migrate at once")
+}
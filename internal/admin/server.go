@@ -0,0 +1,344 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/branch"
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/export"
+	"github.com/Lumos-Labs-HQ/flash/internal/hooks"
+	"github.com/Lumos-Labs-HQ/flash/internal/migrator"
+	"github.com/Lumos-Labs-HQ/flash/internal/notify"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// Server exposes the CLI operations over an HTTP API authenticated with a
+// bearer token. Every request connects anew, so branch switches made by
+// the CLI or the API are picked up.
+type Server struct {
+	cfg   *config.Config
+	token string
+	mux   *http.ServeMux
+	// mu serializes the operations that change the database or branches
+	mu sync.Mutex
+}
+
+func NewServer(cfg *config.Config, token string) (*Server, error) {
+	if token == "" {
+		return nil, errors.New("the admin API needs a token")
+	}
+
+	s := &Server{cfg: cfg, token: token, mux: http.NewServeMux()}
+	s.setupRoutes()
+	return s, nil
+}
+
+func (s *Server) setupRoutes() {
+	s.mux.HandleFunc("GET /api/health", s.handleHealth)
+
+	s.mux.HandleFunc("GET /api/status", s.authorized(s.handleStatus))
+	s.mux.HandleFunc("POST /api/deploy", s.authorized(s.handleDeploy))
+	s.mux.HandleFunc("GET /api/diff", s.authorized(s.handleDiff))
+	s.mux.HandleFunc("GET /api/export", s.authorized(s.handleExport))
+
+	s.mux.HandleFunc("GET /api/branches", s.authorized(s.handleListBranches))
+	s.mux.HandleFunc("POST /api/branches", s.authorized(s.handleCreateBranch))
+	s.mux.HandleFunc("GET /api/branches/diff", s.authorized(s.handleBranchDiff))
+	s.mux.HandleFunc("POST /api/branches/{name}/checkout", s.authorized(s.handleCheckout))
+	s.mux.HandleFunc("DELETE /api/branches/{name}", s.authorized(s.handleDeleteBranch))
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	s.mux.ServeHTTP(w, r)
+	log.Printf("%s %s (%s)", r.Method, r.URL.Path, time.Since(start).Round(time.Millisecond))
+}
+
+// Start serves the API on addr until ctx is cancelled
+func (s *Server) Start(ctx context.Context, addr string) error {
+	server := &http.Server{Addr: addr, Handler: s, ReadHeaderTimeout: 10 * time.Second}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- server.ListenAndServe() }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	}
+}
+
+// authorized rejects requests without the bearer token
+func (s *Server) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="flash"`)
+			common.JSONError(w, http.StatusUnauthorized, "Invalid or missing token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	common.JSON(w, common.Map{"status": "ok"})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	m, err := migrator.NewBranchAwareMigrator(s.cfg)
+	if err != nil {
+		common.JSONError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	defer m.Close()
+
+	statuses, err := m.MigrationStatuses(r.Context())
+	if err != nil {
+		common.JSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	branchName, _, _ := migrator.GetCurrentBranchInfo(s.cfg)
+	common.JSON(w, common.Map{"branch": branchName, "migrations": statuses})
+}
+
+// handleDeploy applies the pending migrations like flash apply, hooks and
+// notifications included
+func (s *Server) handleDeploy(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		DryRun bool   `json:"dry_run"`
+		Notify string `json:"notify"` // overrides when notifications are sent
+	}
+	if r.ContentLength != 0 {
+		if err := common.ParseJSON(r, &req); err != nil {
+			common.JSONError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	switch req.Notify {
+	case "", "always", "success", "failure", "never":
+	default:
+		common.JSONError(w, http.StatusBadRequest, "notify must be always, success, failure or never")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, err := migrator.NewBranchAwareMigrator(s.cfg)
+	if err != nil {
+		common.JSONError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	defer m.Close()
+
+	ctx := r.Context()
+	pending, err := m.PendingMigrations(ctx)
+	if err != nil {
+		common.JSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if pending == nil {
+		pending = []string{}
+	}
+	if req.DryRun || len(pending) == 0 {
+		common.JSON(w, common.Map{"applied": []string{}, "pending": pending})
+		return
+	}
+
+	branchName, _, _ := migrator.GetCurrentBranchInfo(s.cfg)
+	event := hooks.Event{Command: "serve", Branch: branchName, Migrations: pending}
+	if err := hooks.Pre(s.cfg, "migrate", event); err != nil {
+		common.JSONError(w, http.StatusPreconditionFailed, err.Error())
+		return
+	}
+
+	start := time.Now()
+	applied, applyErr := m.ApplyPending(ctx)
+	hooks.Post(s.cfg, "migrate", event, start, applyErr)
+
+	summary := notify.Summary{
+		Branch:     branchName,
+		Success:    applyErr == nil,
+		Migrations: pending[:applied],
+		Pending:    pending[applied:],
+		Duration:   time.Since(start),
+	}
+	if applyErr != nil {
+		summary.Error = applyErr.Error()
+	}
+	if err := notify.Send(s.cfg, summary, req.Notify); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+
+	result := common.Map{"applied": pending[:applied], "pending": pending[applied:]}
+	if applyErr != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(common.Response{Success: false, Message: applyErr.Error(), Data: result})
+		return
+	}
+	common.JSON(w, result)
+}
+
+func (s *Server) handleDiff(w http.ResponseWriter, r *http.Request) {
+	m, err := migrator.NewBranchAwareMigrator(s.cfg)
+	if err != nil {
+		common.JSONError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	defer m.Close()
+
+	changes, err := m.SchemaChanges(r.Context())
+	if err != nil {
+		common.JSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if changes == nil {
+		changes = []migrator.SchemaChange{}
+	}
+	common.JSON(w, common.Map{"changes": changes})
+}
+
+// handleExport streams the data of every table, or of the comma separated
+// tables query parameter, in the format of flash export
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	m, err := migrator.NewBranchAwareMigrator(s.cfg)
+	if err != nil {
+		common.JSONError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	defer m.Close()
+
+	data, err := export.CollectData(r.Context(), m.Adapter(), s.cfg.Database)
+	if err != nil {
+		common.JSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if tables := common.Query(r, "tables", ""); tables != "" {
+		wanted := strings.Split(tables, ",")
+		for name := range data.Tables {
+			if !slices.Contains(wanted, name) {
+				delete(data.Tables, name)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="export_%s.json"`, time.Now().Format("2006-01-02_15-04-05")))
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(data)
+}
+
+func (s *Server) handleListBranches(w http.ResponseWriter, r *http.Request) {
+	store, err := branch.NewMetadataManager(s.cfg.MigrationsPath).Load()
+	if err != nil {
+		common.JSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	common.JSON(w, store)
+}
+
+func (s *Server) handleCreateBranch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := common.ParseJSON(r, &req); err != nil || req.Name == "" {
+		common.JSONError(w, http.StatusBadRequest, "Branch name is required")
+		return
+	}
+
+	s.withBranchManager(w, func(manager *branch.Manager) error {
+		return manager.CreateBranch(r.Context(), req.Name)
+	}, fmt.Sprintf("Created branch %s", req.Name))
+}
+
+func (s *Server) handleCheckout(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	s.withBranchManager(w, func(manager *branch.Manager) error {
+		from, _ := manager.GetCurrentBranch()
+		event := hooks.Event{Command: "serve", Branch: name, FromBranch: from}
+		if err := hooks.Pre(s.cfg, "branch_switch", event); err != nil {
+			return err
+		}
+
+		start := time.Now()
+		err := manager.SwitchBranch(r.Context(), name)
+		hooks.Post(s.cfg, "branch_switch", event, start, err)
+		return err
+	}, fmt.Sprintf("Switched to branch %s", name))
+}
+
+func (s *Server) handleDeleteBranch(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	s.withBranchManager(w, func(manager *branch.Manager) error {
+		current, err := manager.GetCurrentBranch()
+		if err != nil {
+			return err
+		}
+		if current == name {
+			return fmt.Errorf("cannot delete the current branch %s", name)
+		}
+		return manager.DeleteBranch(r.Context(), name)
+	}, fmt.Sprintf("Deleted branch %s", name))
+}
+
+func (s *Server) handleBranchDiff(w http.ResponseWriter, r *http.Request) {
+	from, to := common.Query(r, "from", ""), common.Query(r, "to", "")
+	if from == "" || to == "" {
+		common.JSONError(w, http.StatusBadRequest, "from and to branches are required")
+		return
+	}
+
+	manager, err := branch.NewManager(s.cfg)
+	if err != nil {
+		common.JSONError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	defer manager.Close()
+
+	diff, err := manager.GetSchemaDiff(r.Context(), from, to)
+	if err != nil {
+		common.JSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	common.JSON(w, common.Map{
+		"tables_added":   diff.TablesAdded,
+		"tables_removed": diff.TablesRemoved,
+		"tables_changed": diff.TablesChanged,
+		"summary":        diff.String(),
+	})
+}
+
+// withBranchManager runs a branch operation, one at a time
+func (s *Server) withBranchManager(w http.ResponseWriter, operation func(*branch.Manager) error, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	manager, err := branch.NewManager(s.cfg)
+	if err != nil {
+		common.JSONError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	defer manager.Close()
+
+	if err := operation(manager); err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.JSONMessage(w, message)
+}
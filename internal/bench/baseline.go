@@ -0,0 +1,94 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Baseline is a stored set of results the results of later runs are
+// compared with
+type Baseline struct {
+	Provider  string    `json:"provider"`
+	CreatedAt time.Time `json:"created_at"`
+	Results   []*Result `json:"results"`
+}
+
+// DefaultBaselinePath is where the baseline is kept unless told otherwise,
+// in the .flash folder of the migrations path
+func DefaultBaselinePath(migrationsPath string) string {
+	return filepath.Join(migrationsPath, ".flash", "bench_baseline.json")
+}
+
+// LoadBaseline reads a baseline, nil when there's none at path
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline file: %w", err)
+	}
+
+	var baseline Baseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline file %s: %w", path, err)
+	}
+	return &baseline, nil
+}
+
+// SaveBaseline writes a baseline to path, creating its folder
+func SaveBaseline(path string, baseline *Baseline) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directories: %w", err)
+	}
+
+	data, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal baseline: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Comparison is the change of a query's p95 latency since the baseline
+type Comparison struct {
+	Query         string  `json:"query"`
+	BaselineP95MS float64 `json:"baseline_p95_ms,omitempty"`
+	P95MS         float64 `json:"p95_ms"`
+	ChangePercent float64 `json:"change_percent"`
+	// New is set for queries the baseline has no result for
+	New       bool `json:"new,omitempty"`
+	Regressed bool `json:"regressed,omitempty"`
+}
+
+// Compare compares the p95 latency of results with the baseline's. A query
+// regressed when its p95 grew by more than threshold percent and by more
+// than minDelta, so sub-millisecond jitter of fast queries doesn't count.
+func Compare(baseline *Baseline, results []*Result, threshold float64, minDelta time.Duration) []*Comparison {
+	previous := make(map[string]*Result, len(baseline.Results))
+	for _, result := range baseline.Results {
+		previous[result.Query] = result
+	}
+
+	comparisons := make([]*Comparison, 0, len(results))
+	for _, result := range results {
+		c := &Comparison{Query: result.Query, P95MS: result.P95MS}
+		base, ok := previous[result.Query]
+		if !ok {
+			c.New = true
+			comparisons = append(comparisons, c)
+			continue
+		}
+
+		c.BaselineP95MS = base.P95MS
+		delta := result.P95MS - base.P95MS
+		if base.P95MS > 0 {
+			c.ChangePercent = delta / base.P95MS * 100
+		}
+		c.Regressed = c.ChangePercent > threshold && delta > milliseconds(minDelta)
+		comparisons = append(comparisons, c)
+	}
+	return comparisons
+}
@@ -0,0 +1,178 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/database"
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+)
+
+// writeKeywordRegex finds the statements of a WITH query that change data
+var writeKeywordRegex = regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|MERGE)\b`)
+
+// Options select the queries to benchmark and how often to run them
+type Options struct {
+	// Queries are the names of the queries to run. When empty, every query
+	// that only reads and has its Params given is run.
+	Queries    []string
+	Iterations int
+	// Warmup runs of every query aren't measured, so caches are filled
+	Warmup int
+	// Params are the values of each query's placeholders, in order
+	Params map[string][]any
+}
+
+// Result holds the latencies of a query's runs, in milliseconds
+type Result struct {
+	Query      string  `json:"query"`
+	Iterations int     `json:"iterations"`
+	Rows       int     `json:"rows"`
+	MinMS      float64 `json:"min_ms"`
+	MeanMS     float64 `json:"mean_ms"`
+	P50MS      float64 `json:"p50_ms"`
+	P90MS      float64 `json:"p90_ms"`
+	P95MS      float64 `json:"p95_ms"`
+	P99MS      float64 `json:"p99_ms"`
+	MaxMS      float64 `json:"max_ms"`
+}
+
+// Run benchmarks the selected queries of the queries folder against the
+// database at dbURL, one after the other
+func Run(ctx context.Context, cfg *config.Config, dbURL string, opts Options) ([]*Result, error) {
+	if opts.Iterations < 1 {
+		return nil, fmt.Errorf("iterations must be at least 1")
+	}
+
+	schema, err := parser.NewSchemaParser(cfg).Parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	parsed, err := parser.NewQueryParser(cfg).Parse(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse queries: %w", err)
+	}
+	queries, err := selectQueries(parsed, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	adapter := database.NewAdapter(cfg.Database.Provider)
+	if err := database.Require(adapter, cfg.Database.Provider, common.CapabilityQueries); err != nil {
+		return nil, err
+	}
+	if err := adapter.Connect(ctx, dbURL); err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer adapter.Close()
+
+	results := make([]*Result, 0, len(queries))
+	for _, query := range queries {
+		params := opts.Params[query.Name]
+		for i := 0; i < opts.Warmup; i++ {
+			if _, err := adapter.ExecuteQuery(ctx, query.SQL, params...); err != nil {
+				return nil, fmt.Errorf("query %s failed: %w", query.Name, err)
+			}
+		}
+
+		durations := make([]time.Duration, opts.Iterations)
+		rows := 0
+		for i := range durations {
+			start := time.Now()
+			result, err := adapter.ExecuteQuery(ctx, query.SQL, params...)
+			durations[i] = time.Since(start)
+			if err != nil {
+				return nil, fmt.Errorf("query %s failed: %w", query.Name, err)
+			}
+			rows = len(result.Rows)
+		}
+
+		results = append(results, summarize(query.Name, durations, rows))
+	}
+	return results, nil
+}
+
+// selectQueries picks the queries named in opts, in the order they are
+// given, or every query that only reads and has the values of its
+// placeholders in opts.Params. Named queries with placeholders need them.
+func selectQueries(queries []*parser.Query, opts Options) ([]*parser.Query, error) {
+	var selected []*parser.Query
+	if len(opts.Queries) == 0 {
+		for _, query := range queries {
+			if readOnly(query.SQL) && len(query.Params) <= len(opts.Params[query.Name]) {
+				selected = append(selected, query)
+			}
+		}
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("found no query that only reads and needs no parameters; name the queries to benchmark")
+		}
+		return selected, nil
+	}
+
+	for _, name := range opts.Queries {
+		i := slices.IndexFunc(queries, func(q *parser.Query) bool { return q.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("query %s not found", name)
+		}
+		query := queries[i]
+		if len(query.Params) > len(opts.Params[name]) {
+			return nil, fmt.Errorf("query %s has %d parameter(s); give their values with --params %s='[...]'", name, len(query.Params), name)
+		}
+		selected = append(selected, query)
+	}
+	return selected, nil
+}
+
+// readOnly tells whether a query only reads: a SELECT, or a WITH none of
+// whose statements changes data
+func readOnly(sql string) bool {
+	upper := strings.ToUpper(strings.TrimSpace(sql))
+	switch {
+	case strings.HasPrefix(upper, "SELECT"):
+		return true
+	case strings.HasPrefix(upper, "WITH"):
+		return !writeKeywordRegex.MatchString(upper)
+	}
+	return false
+}
+
+// summarize computes the latency statistics of a query's runs
+func summarize(name string, durations []time.Duration, rows int) *Result {
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	return &Result{
+		Query:      name,
+		Iterations: len(sorted),
+		Rows:       rows,
+		MinMS:      milliseconds(sorted[0]),
+		MeanMS:     milliseconds(total / time.Duration(len(sorted))),
+		P50MS:      milliseconds(percentile(sorted, 50)),
+		P90MS:      milliseconds(percentile(sorted, 90)),
+		P95MS:      milliseconds(percentile(sorted, 95)),
+		P99MS:      milliseconds(percentile(sorted, 99)),
+		MaxMS:      milliseconds(sorted[len(sorted)-1]),
+	}
+}
+
+// percentile is the nearest-rank percentile p of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// milliseconds rounds d to microseconds, in milliseconds
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Microsecond)) / 1000
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/Lumos-Labs-HQ/flash/internal/config"
 	"github.com/Lumos-Labs-HQ/flash/internal/database"
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
 )
 
 type Manager struct {
@@ -18,6 +19,9 @@ type Manager struct {
 
 func NewManager(cfg *config.Config) (*Manager, error) {
 	adapter := database.NewAdapter(cfg.Database.Provider)
+	if err := database.Require(adapter, cfg.Database.Provider, common.CapabilityBranches); err != nil {
+		return nil, err
+	}
 	
 	dbURL, err := cfg.GetDatabaseURL()
 	if err != nil {
@@ -3,9 +3,10 @@ package branch
 import (
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"slices"
 	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/state"
 )
 
 type BranchMetadata struct {
@@ -21,53 +22,64 @@ type BranchStore struct {
 	Branches []*BranchMetadata `json:"branches"`
 }
 
+// MetadataManager keeps the branches in the project's state store
 type MetadataManager struct {
-	filePath string
-	flashDir string
+	migrationsPath string
 }
 
 func NewMetadataManager(migrationsPath string) *MetadataManager {
-	flashDir := filepath.Join(migrationsPath, ".flash")
-
-	return &MetadataManager{
-		filePath: filepath.Join(flashDir, "branches.json"),
-		flashDir: flashDir,
-	}
-}
-
-func (m *MetadataManager) EnsureDirectories() error {
-	return os.MkdirAll(m.flashDir, 0755)
+	return &MetadataManager{migrationsPath: migrationsPath}
 }
 
 func (m *MetadataManager) Load() (*BranchStore, error) {
-	if _, err := os.Stat(m.filePath); os.IsNotExist(err) {
+	if !state.Exists(m.migrationsPath) {
 		return m.initDefault("public"), nil
 	}
 
-	data, err := os.ReadFile(m.filePath)
+	st, err := state.Open(m.migrationsPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read branches file: %w", err)
+		return nil, err
 	}
+	defer st.Close()
 
-	var store BranchStore
-	if err := json.Unmarshal(data, &store); err != nil {
-		return nil, fmt.Errorf("failed to parse branches file: %w", err)
+	documents, err := st.List(state.KindBranches)
+	if err != nil {
+		return nil, err
+	}
+	if len(documents) == 0 {
+		return m.initDefault("public"), nil
 	}
 
-	return &store, nil
+	store := &BranchStore{}
+	for _, doc := range documents {
+		var branch BranchMetadata
+		if err := json.Unmarshal(doc.Value, &branch); err != nil {
+			return nil, fmt.Errorf("failed to parse branch %s: %w", doc.Key, err)
+		}
+		store.Branches = append(store.Branches, &branch)
+	}
+	slices.SortStableFunc(store.Branches, func(a, b *BranchMetadata) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	if _, err := st.Get(state.KindSettings, state.SettingCurrentBranch, &store.Current); err != nil {
+		return nil, err
+	}
+	return store, nil
 }
 
 func (m *MetadataManager) Save(store *BranchStore) error {
-	if err := m.EnsureDirectories(); err != nil {
-		return fmt.Errorf("failed to create directories: %w", err)
-	}
-
-	data, err := json.MarshalIndent(store, "", "  ")
+	st, err := state.Open(m.migrationsPath)
 	if err != nil {
-		return fmt.Errorf("failed to marshal branches: %w", err)
+		return err
 	}
+	defer st.Close()
 
-	return os.WriteFile(m.filePath, data, 0644)
+	branches := make(map[string]any, len(store.Branches))
+	for _, branch := range store.Branches {
+		branches[branch.Name] = branch
+	}
+	if err := st.Replace(state.KindBranches, branches); err != nil {
+		return err
+	}
+	return st.Put(state.KindSettings, state.SettingCurrentBranch, store.Current)
 }
 
 func (m *MetadataManager) initDefault(defaultSchema string) *BranchStore {
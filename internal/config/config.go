@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 )
 
@@ -12,25 +15,413 @@ import (
 var ConfigFile string
 
 type Config struct {
-	Version        string   `json:"version"`
-	SchemaPath     string   `json:"schema_path"` // Deprecated: use SchemaDir instead
-	SchemaDir      string   `json:"schema_dir"`  // New: folder containing .sql schema files
-	Queries        string   `json:"queries"`
-	MigrationsPath string   `json:"migrations_path"`
-	ExportPath     string   `json:"export_path"`
-	Database       Database `json:"database"`
-	Gen            Gen      `json:"gen"`
+	Version        string        `json:"version"`
+	SchemaPath     string        `json:"schema_path"` // Deprecated: use SchemaDir instead
+	SchemaDir      string        `json:"schema_dir"`  // New: folder containing .sql schema files
+	Queries        string        `json:"queries"`
+	MigrationsPath string        `json:"migrations_path"`
+	ExportPath     string        `json:"export_path"`
+	Database       Database      `json:"database"`
+	Gen            Gen           `json:"gen"`
+	SoftDelete     SoftDelete    `json:"soft_delete,omitempty"`
+	Timestamps     Timestamps    `json:"timestamps,omitempty"`
+	Hooks          Hooks         `json:"hooks,omitempty"`
+	Notifications  Notifications `json:"notifications,omitempty"`
+	Seed           Seed          `json:"seed,omitempty"`
+	Studio         Studio        `json:"studio,omitempty"`
+	Encryption     Encryption    `json:"encryption,omitempty"`
 }
 
-type Database struct {
+// SoftDelete names the column that marks a row as deleted (e.g. deleted_at).
+// Tables without the column are unaffected.
+type SoftDelete struct {
+	Column string `json:"column,omitempty"`
+}
+
+// Seed configures `flash seed snapshot`, which copies the rows of a live
+// database into fixture files. Anonymize maps "table.column", or
+// "*.column" for every table, to the rule that replaces its values: null,
+// hash, email or fake.
+type Seed struct {
+	Dir       string            `json:"dir,omitempty"` // fixture files, default db/seeds
+	Anonymize map[string]string `json:"anonymize,omitempty"`
+}
+
+// Studio configures flash studio. With RecordDDL, DDL run from the SQL
+// editor is written to a new migration file and recorded as applied, so the
+// migrations directory stays the history of the schema. GenerateUUIDs, v4
+// or v7, makes studio fill in uuid primary keys that have no default when
+// it inserts or imports rows without them. RowCounts, exact or estimated,
+// picks whether the table list counts rows or reads the database's
+// statistics, which is much faster on large tables. MaskedColumns are
+// "table.column" glob patterns, as in ignore_columns, of columns studio
+// shows masked and leaves out of exports, unless the request carries the
+// admin token held in the AdminTokenEnv environment variable. Stores are the
+// project's other data stores, a Redis cache or a MongoDB database next to
+// the SQL database say, which the studio overview shows with the database.
+type Studio struct {
+	RecordDDL     bool          `json:"record_ddl,omitempty"`
+	GenerateUUIDs string        `json:"generate_uuids,omitempty"`
+	RowCounts     string        `json:"row_counts,omitempty"`
+	MaskedColumns []string      `json:"masked_columns,omitempty"`
+	AdminTokenEnv string        `json:"admin_token_env,omitempty"` // default FLASH_STUDIO_ADMIN_TOKEN
+	Stores        []StudioStore `json:"stores,omitempty"`
+}
+
+// StudioStore is a data store studio shows on its overview: a SQL
+// database, MongoDB or Redis, whose URL is read from URLEnv
+type StudioStore struct {
+	Name     string `json:"name,omitempty"` // default the provider
 	Provider string `json:"provider"`
 	URLEnv   string `json:"url_env"`
 }
 
+// StudioStoreProviders are the providers of studio.stores
+var StudioStoreProviders = []string{"postgresql", "postgres", "mysql", "sqlite", "sqlite3", "mongodb", "mongo", "redis"}
+
+// IsMaskedColumn reports whether studio masks a column of a table
+func (s Studio) IsMaskedColumn(table, column string) bool {
+	return matchColumnPatterns(s.MaskedColumns, table, column)
+}
+
+// MasksColumnName reports whether studio masks a column of that name in any
+// table, for query results whose columns can't be traced to a table
+func (s Studio) MasksColumnName(column string) bool {
+	return matchColumnPatterns(s.MaskedColumns, "*", column)
+}
+
+// AdminToken returns the token that gives studio requests the admin role,
+// empty when none is set
+func (s Studio) AdminToken() string {
+	env := s.AdminTokenEnv
+	if env == "" {
+		env = "FLASH_STUDIO_ADMIN_TOKEN"
+	}
+	return os.Getenv(env)
+}
+
+// Encryption declares columns the generated clients encrypt with AES-256-GCM
+// before writing them and decrypt after reading them, so the database only
+// ever holds ciphertext. Columns are "table.column" glob patterns, as in
+// ignore_columns, of text columns. The key is 32 bytes, base64 encoded, read
+// from the KeyEnv environment variable unless the application hands the
+// client a key function (a KMS call say) instead. Studio marks the columns
+// encrypted and shows the length of their ciphertext in place of it.
+type Encryption struct {
+	Columns []string `json:"columns,omitempty"`
+	KeyEnv  string   `json:"key_env,omitempty"` // default FLASH_ENCRYPTION_KEY
+}
+
+// Enabled reports whether any column is encrypted
+func (e Encryption) Enabled() bool {
+	return len(e.Columns) > 0
+}
+
+// IsEncryptedColumn reports whether a column of a table is encrypted
+func (e Encryption) IsEncryptedColumn(table, column string) bool {
+	return matchColumnPatterns(e.Columns, table, column)
+}
+
+// Options describes the settings that change generated clients, for the
+// generation cache
+func (e Encryption) Options() string {
+	return strings.Join(e.Columns, ",") + "|" + e.KeyEnvName()
+}
+
+// KeyEnvName returns the environment variable holding the encryption key
+func (e Encryption) KeyEnvName() string {
+	if e.KeyEnv != "" {
+		return e.KeyEnv
+	}
+	return "FLASH_ENCRYPTION_KEY"
+}
+
+// Timestamps names the audit columns the database maintains itself.
+// Migrations give them defaults (and an update trigger for UpdatedAt),
+// generated insert functions drop them from their parameters and studio
+// shows them read-only.
+type Timestamps struct {
+	CreatedAt string `json:"created_at,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+// Columns returns the configured audit column names.
+func (t Timestamps) Columns() []string {
+	var columns []string
+	if t.CreatedAt != "" {
+		columns = append(columns, t.CreatedAt)
+	}
+	if t.UpdatedAt != "" {
+		columns = append(columns, t.UpdatedAt)
+	}
+	return columns
+}
+
+// IsTimestampColumn reports whether name is one of the audit columns.
+func (t Timestamps) IsTimestampColumn(name string) bool {
+	for _, column := range t.Columns() {
+		if strings.EqualFold(column, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Hooks are run before and after commands: apply (migrate), gen (generate),
+// studio imports (import) and checkout (branch_switch). A failing pre hook
+// stops the command unless it sets continue_on_error; post hooks only warn.
+type Hooks struct {
+	PreMigrate       []Hook `json:"pre_migrate,omitempty"`
+	PostMigrate      []Hook `json:"post_migrate,omitempty"`
+	PreGenerate      []Hook `json:"pre_generate,omitempty"`
+	PostGenerate     []Hook `json:"post_generate,omitempty"`
+	PreImport        []Hook `json:"pre_import,omitempty"`
+	PostImport       []Hook `json:"post_import,omitempty"`
+	PreBranchSwitch  []Hook `json:"pre_branch_switch,omitempty"`
+	PostBranchSwitch []Hook `json:"post_branch_switch,omitempty"`
+}
+
+// Hook is a shell command or a webhook URL that is POSTed the event as JSON
+type Hook struct {
+	Command         string            `json:"command,omitempty"`
+	URL             string            `json:"url,omitempty"`
+	Headers         map[string]string `json:"headers,omitempty"` // values expand ${ENV_VARS}
+	Timeout         int               `json:"timeout,omitempty"` // seconds, default 30
+	ContinueOnError bool              `json:"continue_on_error,omitempty"`
+}
+
+// Notifications post a summary of every apply to chat or webhooks
+type Notifications struct {
+	Environment string               `json:"environment,omitempty"` // named in messages, defaults to the branch
+	Targets     []NotificationTarget `json:"targets,omitempty"`
+}
+
+// NotificationTarget is a Slack or Discord incoming webhook, or any URL
+// that is POSTed the summary as JSON
+type NotificationTarget struct {
+	Type     string `json:"type"`               // slack, discord or webhook
+	URL      string `json:"url"`                // expands ${ENV_VARS}
+	On       string `json:"on,omitempty"`       // always (default), success or failure
+	Template string `json:"template,omitempty"` // text/template for the message
+}
+
+// HookEvents are the events hooks can be configured for
+var HookEvents = []string{
+	"pre_migrate", "post_migrate", "pre_generate", "post_generate",
+	"pre_import", "post_import", "pre_branch_switch", "post_branch_switch",
+}
+
+// For returns the hooks of an event, such as "pre_migrate"
+func (h Hooks) For(event string) []Hook {
+	switch event {
+	case "pre_migrate":
+		return h.PreMigrate
+	case "post_migrate":
+		return h.PostMigrate
+	case "pre_generate":
+		return h.PreGenerate
+	case "post_generate":
+		return h.PostGenerate
+	case "pre_import":
+		return h.PreImport
+	case "post_import":
+		return h.PostImport
+	case "pre_branch_switch":
+		return h.PreBranchSwitch
+	case "post_branch_switch":
+		return h.PostBranchSwitch
+	}
+	return nil
+}
+
+type Database struct {
+	Provider      string    `json:"provider"`
+	URLEnv        string    `json:"url_env"`
+	ShadowURLEnv  string    `json:"shadow_url_env,omitempty"`
+	Supabase      bool      `json:"supabase,omitempty"`
+	IgnoreSchemas []string  `json:"ignore_schemas,omitempty"`
+	IgnoreTables  []string  `json:"ignore_tables,omitempty"`
+	IgnoreColumns []string  `json:"ignore_columns,omitempty"`
+	OnlineDDL     OnlineDDL `json:"online_ddl,omitempty"`
+}
+
+// OnlineDDL configures how MySQL migrations change large tables
+type OnlineDDL struct {
+	// MinRows is the estimated row count from which a table is large,
+	// 1,000,000 when unset
+	MinRows int64 `json:"min_rows,omitempty"`
+	// Tool runs the ALTERs of large tables with gh-ost or pt-osc
+	// (pt-online-schema-change) instead of on the server
+	Tool string `json:"tool,omitempty"`
+	// ToolEnv names an environment variable that overrides Tool when set,
+	// so each environment can choose its own; "none" turns the tool off
+	ToolEnv string `json:"tool_env,omitempty"`
+	// ToolArgs are passed to the tool as well, e.g. --max-load=Threads_running=25
+	ToolArgs []string `json:"tool_args,omitempty"`
+}
+
+// LargeTableRows returns the row count from which a table is large
+func (o OnlineDDL) LargeTableRows() int64 {
+	if o.MinRows > 0 {
+		return o.MinRows
+	}
+	return 1000000
+}
+
+// ResolveTool returns the tool this environment changes large tables
+// with: gh-ost, pt-osc or empty for none
+func (o OnlineDDL) ResolveTool() (string, error) {
+	tool := o.Tool
+	if o.ToolEnv != "" {
+		if value, ok := os.LookupEnv(o.ToolEnv); ok {
+			tool = value
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(tool)) {
+	case "", "none":
+		return "", nil
+	case "gh-ost":
+		return "gh-ost", nil
+	case "pt-osc", "pt-online-schema-change":
+		return "pt-osc", nil
+	}
+	return "", fmt.Errorf("unknown online_ddl tool %q, use gh-ost or pt-osc", tool)
+}
+
+// SupabaseSchemas are the schemas Supabase creates and manages itself
+var SupabaseSchemas = []string{
+	"auth", "storage", "realtime", "_realtime", "extensions", "graphql", "graphql_public",
+	"pgsodium", "pgsodium_masks", "vault", "net", "supabase_functions", "supabase_migrations",
+	"_analytics", "pgbouncer", "cron",
+}
+
+// ExternalSchemas returns the schemas flash never manages: the configured
+// ignore list plus the Supabase-managed schemas when Supabase is set.
+func (d Database) ExternalSchemas() []string {
+	schemas := append([]string(nil), d.IgnoreSchemas...)
+	if d.Supabase {
+		schemas = append(schemas, SupabaseSchemas...)
+	}
+	return schemas
+}
+
+// IsExternalSchema reports whether schema is managed outside of flash
+func (d Database) IsExternalSchema(schema string) bool {
+	for _, s := range d.ExternalSchemas() {
+		if strings.EqualFold(s, schema) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsIgnoredTable reports whether a table is managed outside of flash.
+// name may be schema-qualified (e.g. auth.users). ignore_tables entries
+// are glob patterns such as "pgboss_*".
+func (d Database) IsIgnoredTable(name string) bool {
+	if idx := strings.Index(name, "."); idx >= 0 && d.IsExternalSchema(name[:idx]) {
+		return true
+	}
+	for _, pattern := range d.IgnoreTables {
+		if matchPattern(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsIgnoredColumn reports whether a column is managed outside of flash.
+// ignore_columns entries are "table.column" glob patterns; a pattern
+// without a table part matches the column in every table.
+func (d Database) IsIgnoredColumn(table, column string) bool {
+	return matchColumnPatterns(d.IgnoreColumns, table, column)
+}
+
+// matchColumnPatterns reports whether a column matches one of the
+// "table.column" patterns. A table of "*" matches patterns for any table.
+func matchColumnPatterns(patterns []string, table, column string) bool {
+	for _, pattern := range patterns {
+		tablePattern, columnPattern := "*", pattern
+		if idx := strings.LastIndex(pattern, "."); idx >= 0 {
+			tablePattern, columnPattern = pattern[:idx], pattern[idx+1:]
+		}
+		if (table == "*" || matchPattern(tablePattern, table)) && matchPattern(columnPattern, column) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPattern(pattern, name string) bool {
+	matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(name))
+	return err == nil && matched
+}
+
 type Gen struct {
 	Go     GoGen     `json:"go,omitempty"`
 	JS     JSGen     `json:"js,omitempty"`
 	Python PythonGen `json:"python,omitempty"`
+	// Layout groups generated query code into files: per_file (default),
+	// single or per_table
+	Layout string `json:"layout,omitempty"`
+	Naming Naming `json:"naming,omitempty"`
+	// Header is a text/template rendered as a comment at the top of every
+	// generated file, in place of the "Code generated" line
+	Header string `json:"header,omitempty"`
+}
+
+// Output layouts of generated query code
+const (
+	LayoutPerFile  = "per_file"  // one file per query file
+	LayoutSingle   = "single"    // every query in one file
+	LayoutPerTable = "per_table" // one file per table the queries use
+)
+
+// Naming sets the names generated code uses
+type Naming struct {
+	// Functions is the convention of query function names, camel or snake.
+	// Each language keeps its own when empty; Go methods are always
+	// exported, so it doesn't apply to them.
+	Functions string `json:"functions,omitempty"`
+	// Models maps table names to the model name to generate for them
+	Models map[string]string `json:"models,omitempty"`
+}
+
+// Function naming conventions
+const (
+	NamingCamel = "camel"
+	NamingSnake = "snake"
+)
+
+// Validate checks the output layout and naming settings
+func (g Gen) Validate() error {
+	switch g.Layout {
+	case "", LayoutPerFile, LayoutSingle, LayoutPerTable:
+	default:
+		return fmt.Errorf("gen.layout: unsupported layout %q, use per_file, single or per_table", g.Layout)
+	}
+	switch g.Naming.Functions {
+	case "", NamingCamel, NamingSnake:
+	default:
+		return fmt.Errorf("gen.naming.functions: unsupported convention %q, use camel or snake", g.Naming.Functions)
+	}
+	return nil
+}
+
+// OutputOptions describes the settings that shape generated code, for the
+// generation cache to notice when they change
+func (g Gen) OutputOptions() string {
+	tables := make([]string, 0, len(g.Naming.Models))
+	for table := range g.Naming.Models {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	models := make([]string, len(tables))
+	for i, table := range tables {
+		models[i] = table + "=" + g.Naming.Models[table]
+	}
+	return g.Layout + "|" + g.Naming.Functions + "|" + strings.Join(models, ",") + "|" + g.Header
 }
 
 type GoGen struct {
@@ -40,6 +431,141 @@ type GoGen struct {
 type JSGen struct {
 	Enabled bool   `json:"enabled,omitempty"`
 	Out     string `json:"out,omitempty"`
+	// Module is the module format of the generated code: cjs, esm or dual,
+	// which writes both. Default: cjs on node, esm on the other runtimes.
+	Module string `json:"module,omitempty"`
+	// Driver is the client library the generated code runs queries with.
+	// Empty picks the default of the database provider on the runtime.
+	Driver string `json:"driver,omitempty"`
+	// Runtime is the JavaScript runtime the code is generated for: node
+	// (default), deno, bun or edge. The others also get a connect() that
+	// imports the driver and, except on edge, reads the database URL the
+	// runtime's way.
+	Runtime string `json:"runtime,omitempty"`
+	// DataLoader adds createLoaders(), which wraps the batch loader of each
+	// table in a DataLoader from the dataloader package
+	DataLoader bool `json:"dataloader,omitempty"`
+}
+
+// Module formats of generated JavaScript
+const (
+	ModuleCJS  = "cjs"  // CommonJS .js files
+	ModuleESM  = "esm"  // ES module .mjs files
+	ModuleDual = "dual" // both
+)
+
+// JavaScript drivers generated code can run queries with
+const (
+	DriverPG            = "pg"
+	DriverPostgresJS    = "postgres" // postgres.js
+	DriverNeon          = "neon"     // @neondatabase/serverless, over HTTP
+	DriverMySQL2        = "mysql2"
+	DriverPlanetScale   = "planetscale" // @planetscale/database, over HTTP
+	DriverBetterSQLite3 = "better-sqlite3"
+	DriverLibSQL        = "libsql"     // @libsql/client
+	DriverBunSQLite     = "bun:sqlite" // bun runtime only
+)
+
+// JavaScript runtimes code can be generated for
+const (
+	RuntimeNode = "node"
+	RuntimeDeno = "deno"
+	RuntimeBun  = "bun"
+	// RuntimeEdge is Cloudflare Workers, Vercel Edge and other runtimes
+	// without TCP sockets, where queries are sent over HTTP
+	RuntimeEdge = "edge"
+)
+
+// ModuleFormat returns gen.js.module, or the default of the runtime when it
+// isn't set
+func (j JSGen) ModuleFormat() string {
+	if j.Module != "" {
+		return j.Module
+	}
+	if j.Runtime != "" && j.Runtime != RuntimeNode {
+		return ModuleESM
+	}
+	return ModuleCJS
+}
+
+// DriverFor returns the driver JavaScript code is generated for: gen.js.driver
+// when set, or else the default of the database provider on the runtime
+func (j JSGen) DriverFor(provider string) string {
+	if j.Driver != "" {
+		return j.Driver
+	}
+	switch provider {
+	case "mysql":
+		if j.Runtime == RuntimeEdge {
+			return DriverPlanetScale
+		}
+		return DriverMySQL2
+	case "sqlite", "sqlite3":
+		switch j.Runtime {
+		case RuntimeBun:
+			return DriverBunSQLite
+		case RuntimeEdge:
+			return DriverLibSQL
+		}
+		return DriverBetterSQLite3
+	default:
+		if j.Runtime == RuntimeEdge {
+			return DriverNeon
+		}
+		return DriverPG
+	}
+}
+
+// Validate checks the runtime and module format, and that the driver works
+// with the database provider on the runtime
+func (j JSGen) Validate(provider string) error {
+	switch j.Runtime {
+	case "", RuntimeNode, RuntimeDeno, RuntimeBun, RuntimeEdge:
+	default:
+		return fmt.Errorf("gen.js.runtime: unsupported runtime %q, use node, deno, bun or edge", j.Runtime)
+	}
+
+	switch j.Module {
+	case "", ModuleCJS, ModuleESM, ModuleDual:
+	default:
+		return fmt.Errorf("gen.js.module: unsupported module format %q, use cjs, esm or dual", j.Module)
+	}
+	if (j.Runtime == RuntimeDeno || j.Runtime == RuntimeEdge) && j.ModuleFormat() != ModuleESM {
+		return fmt.Errorf("gen.js.module: %s only loads ES modules, use esm", j.Runtime)
+	}
+
+	if j.Driver == DriverBunSQLite && j.Runtime != RuntimeBun {
+		return fmt.Errorf("gen.js.driver: bun:sqlite needs gen.js.runtime bun")
+	}
+	switch j.DriverFor(provider) {
+	case DriverNeon, DriverPlanetScale, DriverLibSQL:
+	default:
+		if j.Runtime == RuntimeEdge {
+			return fmt.Errorf("gen.js.driver: %s connects over TCP, the edge runtime needs neon, planetscale or libsql", j.DriverFor(provider))
+		}
+	}
+
+	var drivers []string
+	switch provider {
+	case "mysql":
+		drivers = []string{DriverMySQL2, DriverPlanetScale}
+	case "sqlite", "sqlite3":
+		drivers = []string{DriverBetterSQLite3, DriverLibSQL}
+		if j.Runtime == RuntimeBun {
+			drivers = append(drivers, DriverBunSQLite)
+		}
+	default:
+		drivers = []string{DriverPG, DriverPostgresJS, DriverNeon}
+	}
+	if j.Driver == "" {
+		return nil
+	}
+	for _, driver := range drivers {
+		if j.Driver == driver {
+			return nil
+		}
+	}
+	return fmt.Errorf("gen.js.driver: driver %q doesn't support %s, use %s", j.Driver, provider, strings.Join(drivers, ", "))
 }
 
 type PythonGen struct {
@@ -60,9 +586,14 @@ type configRaw struct {
 }
 
 func Load() (*Config, error) {
+	return LoadFile(ConfigFile)
+}
+
+// LoadFile reads the config at path, flash.config.json when empty. A missing
+// file gives the default config.
+func LoadFile(path string) (*Config, error) {
 	var cfg Config
 
-	path := ConfigFile
 	if path == "" {
 		path = "flash.config.json"
 	}
@@ -83,54 +614,64 @@ func Load() (*Config, error) {
 		pythonAsyncSet = raw.Gen.Python.Async != nil
 	}
 
-	// Set defaults
-	if cfg.Version == "" {
-		cfg.Version = "2"
+	cfg.SetDefaults()
+	if cfg.Gen.Python.Enabled && !pythonAsyncSet {
+		cfg.Gen.Python.Async = true
+	}
+
+	return &cfg, nil
+}
+
+// SetDefaults fills in the settings left empty, as loading a config file does
+func (c *Config) SetDefaults() {
+	if c.Version == "" {
+		c.Version = "2"
 	}
 	// Support both old schema_path and new schema_dir
-	if cfg.SchemaDir == "" {
-		if cfg.SchemaPath != "" {
+	if c.SchemaDir == "" {
+		if c.SchemaPath != "" {
 			// Legacy: if schema_path is a file, use its directory
 			// If it looks like a directory (no .sql extension), use it directly
-			if strings.HasSuffix(cfg.SchemaPath, ".sql") {
-				cfg.SchemaDir = filepath.Dir(cfg.SchemaPath)
+			if strings.HasSuffix(c.SchemaPath, ".sql") {
+				c.SchemaDir = filepath.Dir(c.SchemaPath)
 			} else {
-				cfg.SchemaDir = cfg.SchemaPath
+				c.SchemaDir = c.SchemaPath
 			}
 		} else {
-			cfg.SchemaDir = "db/schema"
+			c.SchemaDir = "db/schema"
 		}
 	}
 	// Keep SchemaPath for backward compatibility
-	if cfg.SchemaPath == "" {
-		cfg.SchemaPath = filepath.Join(cfg.SchemaDir, "schema.sql")
+	if c.SchemaPath == "" {
+		c.SchemaPath = filepath.Join(c.SchemaDir, "schema.sql")
 	}
-	if cfg.Queries == "" {
-		cfg.Queries = "db/queries/"
+	if c.Queries == "" {
+		c.Queries = "db/queries/"
 	}
-	if cfg.MigrationsPath == "" {
-		cfg.MigrationsPath = "db/migrations"
+	if c.MigrationsPath == "" {
+		c.MigrationsPath = "db/migrations"
 	}
-	if cfg.ExportPath == "" {
-		cfg.ExportPath = "db/export"
+	if c.ExportPath == "" {
+		c.ExportPath = "db/export"
 	}
-	if cfg.Database.Provider == "" {
-		cfg.Database.Provider = "postgresql"
+	if c.Seed.Dir == "" {
+		c.Seed.Dir = "db/seeds"
 	}
-	if cfg.Database.URLEnv == "" {
-		cfg.Database.URLEnv = "DATABASE_URL"
+	if c.Database.Provider == "" {
+		c.Database.Provider = "postgresql"
 	}
-	if cfg.Gen.JS.Out == "" && cfg.Gen.JS.Enabled {
-		cfg.Gen.JS.Out = "flash_gen"
+	if c.Database.URLEnv == "" {
+		c.Database.URLEnv = "DATABASE_URL"
 	}
-	if cfg.Gen.Python.Out == "" && cfg.Gen.Python.Enabled {
-		cfg.Gen.Python.Out = "flash_gen"
+	if c.Gen.JS.Out == "" && c.Gen.JS.Enabled {
+		c.Gen.JS.Out = "flash_gen"
 	}
-	if cfg.Gen.Python.Enabled && !pythonAsyncSet {
-		cfg.Gen.Python.Async = true
+	if c.Gen.Python.Out == "" && c.Gen.Python.Enabled {
+		c.Gen.Python.Out = "flash_gen"
+	}
+	if c.Gen.Layout == "" {
+		c.Gen.Layout = LayoutPerFile
 	}
-
-	return &cfg, nil
 }
 
 func (c *Config) GetDatabaseURL() (string, error) {
@@ -141,6 +682,19 @@ func (c *Config) GetDatabaseURL() (string, error) {
 	return dbURL, nil
 }
 
+// GetShadowDatabaseURL returns the URL of the server shadow databases are
+// created on, the configured database server unless shadow_url_env is set
+func (c *Config) GetShadowDatabaseURL() (string, error) {
+	if c.Database.ShadowURLEnv == "" {
+		return c.GetDatabaseURL()
+	}
+	shadowURL := os.Getenv(c.Database.ShadowURLEnv)
+	if shadowURL == "" {
+		return "", fmt.Errorf("shadow database URL not found in environment variable %s", c.Database.ShadowURLEnv)
+	}
+	return shadowURL, nil
+}
+
 func (c *Config) EnsureDirectories() error {
 	dirs := []string{
 		c.MigrationsPath,
@@ -180,6 +734,46 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("export_path cannot be empty")
 	}
 
+	if err := c.Gen.Validate(); err != nil {
+		return err
+	}
+	if err := c.Gen.JS.Validate(c.Database.Provider); err != nil {
+		return err
+	}
+
+	for _, event := range HookEvents {
+		for i, hook := range c.Hooks.For(event) {
+			if (hook.Command == "") == (hook.URL == "") {
+				return fmt.Errorf("hooks.%s[%d] must set either command or url", event, i)
+			}
+		}
+	}
+
+	for i, target := range c.Notifications.Targets {
+		switch target.Type {
+		case "slack", "discord", "webhook":
+		default:
+			return fmt.Errorf("notifications.targets[%d]: unsupported type %q, use slack, discord or webhook", i, target.Type)
+		}
+		if target.URL == "" {
+			return fmt.Errorf("notifications.targets[%d]: url cannot be empty", i)
+		}
+		switch target.On {
+		case "", "always", "success", "failure":
+		default:
+			return fmt.Errorf("notifications.targets[%d]: on must be always, success or failure", i)
+		}
+	}
+
+	for i, store := range c.Studio.Stores {
+		if !slices.Contains(StudioStoreProviders, store.Provider) {
+			return fmt.Errorf("studio.stores[%d]: unsupported provider %q, use one of %v", i, store.Provider, StudioStoreProviders)
+		}
+		if store.URLEnv == "" {
+			return fmt.Errorf("studio.stores[%d]: url_env cannot be empty", i)
+		}
+	}
+
 	return nil
 }
 
@@ -3,17 +3,36 @@ package database
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
 	"github.com/Lumos-Labs-HQ/flash/internal/types"
 )
 
+// DatabaseAdapter is a connection to a database of a provider. Providers
+// that can't support all of it stub what they can't and report what they
+// can as a CapabilityReporter; see Capabilities.
 type DatabaseAdapter interface {
+	Connector
+	Migrator
+	Introspector
+	QueryExecutor
+	BranchManager
+	DataBrowser
+}
+
+// Connector opens and closes the connection to a database
+type Connector interface {
 	Connect(ctx context.Context, url string) error
 	Close() error
 	Ping(ctx context.Context) error
+}
 
+// Migrator applies and records migrations, and generates their SQL
+type Migrator interface {
 	// Migration table management
 	CreateMigrationsTable(ctx context.Context) error
 	EnsureMigrationTableCompatibility(ctx context.Context) error
@@ -24,9 +43,26 @@ type DatabaseAdapter interface {
 	RecordMigration(ctx context.Context, migrationID, name, checksum string) error
 	RemoveMigrationRecord(ctx context.Context, migrationID string) error
 	ExecuteMigration(ctx context.Context, migrationSQL string) error
+	// ExecuteStatements runs statements with bound arguments in one transaction
+	ExecuteStatements(ctx context.Context, statements []common.Statement) error
 	ExecuteAndRecordMigration(ctx context.Context, migrationID, name, checksum string, migrationSQL string) error
-	ExecuteQuery(ctx context.Context, query string) (*common.QueryResult, error)
+	DropTable(ctx context.Context, tableName string) error
+	DropEnum(ctx context.Context, enumName string) error
 
+	// SQL generation
+	GenerateCreateTableSQL(table types.SchemaTable) string
+	GenerateAddColumnSQL(tableName string, column types.SchemaColumn) string
+	GenerateDropColumnSQL(tableName, columnName string) string
+	GenerateAddIndexSQL(index types.SchemaIndex) string
+	GenerateDropIndexSQL(index types.SchemaIndex) string
+
+	// Data type mapping
+	MapColumnType(dbType string) string
+	FormatColumnType(column types.SchemaColumn) string
+}
+
+// Introspector reads the schema of a database
+type Introspector interface {
 	// Schema operations
 	GetCurrentSchema(ctx context.Context) ([]types.SchemaTable, error)
 	GetCurrentEnums(ctx context.Context) ([]types.SchemaEnum, error)
@@ -41,26 +77,15 @@ type DatabaseAdapter interface {
 	CheckNotNullConstraint(ctx context.Context, tableName, columnName string) (bool, error)
 	CheckForeignKeyConstraint(ctx context.Context, tableName, constraintName string) (bool, error)
 	CheckUniqueConstraint(ctx context.Context, tableName, constraintName string) (bool, error)
+}
 
-	// Backup operations
-	GetTableData(ctx context.Context, tableName string) ([]map[string]interface{}, error)
-	GetTableRowCount(ctx context.Context, tableName string) (int, error)
-	GetAllTableRowCounts(ctx context.Context, tableNames []string) (map[string]int, error)
-	DropTable(ctx context.Context, tableName string) error
-	DropEnum(ctx context.Context, enumName string) error
-
-	// SQL generation
-	GenerateCreateTableSQL(table types.SchemaTable) string
-	GenerateAddColumnSQL(tableName string, column types.SchemaColumn) string
-	GenerateDropColumnSQL(tableName, columnName string) string
-	GenerateAddIndexSQL(index types.SchemaIndex) string
-	GenerateDropIndexSQL(index types.SchemaIndex) string
-
-	// Data type mapping
-	MapColumnType(dbType string) string
-	FormatColumnType(column types.SchemaColumn) string
+// QueryExecutor runs SQL queries
+type QueryExecutor interface {
+	ExecuteQuery(ctx context.Context, query string, args ...any) (*common.QueryResult, error)
+}
 
-	// Branch operations
+// BranchManager keeps schema branches
+type BranchManager interface {
 	CreateBranchSchema(ctx context.Context, branchName string) error
 	DropBranchSchema(ctx context.Context, branchName string) error
 	CloneSchemaToBranch(ctx context.Context, sourceSchema, targetSchema string) error
@@ -69,6 +94,53 @@ type DatabaseAdapter interface {
 	GetTableNamesInSchema(ctx context.Context, schemaName string) ([]string, error)
 }
 
+// DataBrowser lists the tables or collections of a database and reads
+// their rows or documents
+type DataBrowser interface {
+	GetAllTableNames(ctx context.Context) ([]string, error)
+	GetTableColumns(ctx context.Context, tableName string) ([]types.SchemaColumn, error)
+	GetTableData(ctx context.Context, tableName string) ([]map[string]interface{}, error)
+	GetTableRowCount(ctx context.Context, tableName string) (int, error)
+	GetAllTableRowCounts(ctx context.Context, tableNames []string) (map[string]int, error)
+}
+
+// CapabilityReporter is implemented by adapters that stub the operations
+// of some capabilities instead of supporting them
+type CapabilityReporter interface {
+	Capabilities() []common.Capability
+}
+
+// Capabilities returns the capabilities adapter supports, all of them when
+// it doesn't report them
+func Capabilities(adapter DatabaseAdapter) []common.Capability {
+	if reporter, ok := adapter.(CapabilityReporter); ok {
+		return reporter.Capabilities()
+	}
+	return common.AllCapabilities
+}
+
+// Supports tells whether adapter supports capability
+func Supports(adapter DatabaseAdapter, capability common.Capability) bool {
+	return slices.Contains(Capabilities(adapter), capability)
+}
+
+// Require returns an error naming what provider supports when its adapter
+// doesn't support capability, so commands fail up front instead of working
+// on stubbed results
+func Require(adapter DatabaseAdapter, provider string, capability common.Capability) error {
+	if Supports(adapter, capability) {
+		return nil
+	}
+	supported := make([]string, 0, len(common.AllCapabilities))
+	for _, c := range Capabilities(adapter) {
+		supported = append(supported, string(c))
+	}
+	if len(supported) == 0 {
+		return common.Unsupported(provider, capability)
+	}
+	return fmt.Errorf("%w (%s supports %s)", common.Unsupported(provider, capability), provider, strings.Join(supported, ", "))
+}
+
 type DatabaseConnection interface {
 	Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
 	QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row
@@ -0,0 +1,42 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Capability is a group of adapter operations, which a provider supports
+// in full or not at all
+type Capability string
+
+const (
+	// CapabilityMigrations creates, applies, records and rolls back migrations
+	CapabilityMigrations Capability = "migrations"
+	// CapabilityIntrospection reads the schema: tables, columns, indexes and enums
+	CapabilityIntrospection Capability = "schema introspection"
+	// CapabilityQueries runs SQL queries
+	CapabilityQueries Capability = "SQL queries"
+	// CapabilityBranches keeps schema branches
+	CapabilityBranches Capability = "schema branches"
+	// CapabilityDataBrowsing lists tables or collections and reads their data
+	CapabilityDataBrowsing Capability = "data browsing"
+)
+
+// AllCapabilities are the capabilities of a full adapter, in the order
+// they're listed to users
+var AllCapabilities = []Capability{
+	CapabilityMigrations,
+	CapabilityIntrospection,
+	CapabilityQueries,
+	CapabilityBranches,
+	CapabilityDataBrowsing,
+}
+
+// ErrUnsupported is the error of operations a provider doesn't support
+var ErrUnsupported = errors.New("unsupported operation")
+
+// Unsupported returns the error of an operation of a capability provider
+// doesn't have
+func Unsupported(provider string, capability Capability) error {
+	return fmt.Errorf("%w: %s doesn't support %s", ErrUnsupported, provider, capability)
+}
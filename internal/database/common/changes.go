@@ -0,0 +1,28 @@
+package common
+
+import "context"
+
+// Change is a row change read from a database's write-ahead log
+type Change struct {
+	LSN    string `json:"lsn"`
+	XID    string `json:"xid"`
+	Op     string `json:"op"` // insert, update, delete or truncate
+	Schema string `json:"schema"`
+	Table  string `json:"table"`
+	// Before holds the old row of updates and deletes: its key, or every
+	// column with REPLICA IDENTITY FULL. Nil when the log doesn't have it.
+	Before map[string]any `json:"before,omitempty"`
+	// After holds the new row of inserts and updates, without unchanged
+	// TOASTed values, which the log leaves out
+	After map[string]any `json:"after,omitempty"`
+}
+
+// ChangeCapture reads the changes written to a database from the moment it
+// was opened. It holds a connection of its own until closed.
+type ChangeCapture interface {
+	// Plugin names the logical decoding output plugin the capture uses
+	Plugin() string
+	// Read returns up to limit changes committed since the last read
+	Read(ctx context.Context, limit int) ([]Change, error)
+	Close() error
+}
@@ -0,0 +1,126 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The kinds of errors adapters and services tell apart. Errors of a kind
+// match it with errors.Is, and most carry a hint on how to fix them.
+var (
+	ErrConnection          = errors.New("connection failed")
+	ErrMigrationChecksum   = errors.New("migration checksum mismatch")
+	ErrConstraintViolation = errors.New("constraint violation")
+	ErrPermission          = errors.New("permission denied")
+)
+
+// The constraints a ConstraintViolation can be about
+const (
+	ConstraintNotNull    = "NOT NULL"
+	ConstraintUnique     = "UNIQUE"
+	ConstraintForeignKey = "FOREIGN KEY"
+	ConstraintCheck      = "CHECK"
+)
+
+// DatabaseError is a driver error of a known kind: ErrConnection,
+// ErrConstraintViolation or ErrPermission. Its message is the driver's.
+type DatabaseError struct {
+	Kind error
+	// Constraint is the kind of constraint a violation is about
+	Constraint string
+	// Name is the name of the violated constraint, when the driver says
+	Name   string
+	Table  string
+	Column string
+	// Migration is set when the error comes from the schema change of a
+	// migration, so the violating rows are already in the table
+	Migration bool
+	Err       error
+}
+
+func (e *DatabaseError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DatabaseError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// Hint tells how to fix the error, "" when there's nothing to tell
+func (e *DatabaseError) Hint() string {
+	switch e.Kind {
+	case ErrConnection:
+		return "check that the database is running and reachable, and that the connection URL and its credentials are right"
+	case ErrPermission:
+		if e.Table != "" {
+			return fmt.Sprintf("the database user lacks privileges on %s; grant them, or connect as a user that has them", e.Table)
+		}
+		return "the database user lacks the privileges this needs; grant them, or connect as a user that has them"
+	case ErrConstraintViolation:
+		return e.constraintHint()
+	}
+	return ""
+}
+
+func (e *DatabaseError) constraintHint() string {
+	column := e.Column
+	if column == "" {
+		column = "a column"
+	}
+	table := e.Table
+	if table == "" {
+		table = "the table"
+	}
+	switch e.Constraint {
+	case ConstraintNotNull:
+		if e.Migration {
+			return fmt.Sprintf("column %s of %s has NULLs; backfill them before adding NOT NULL", column, table)
+		}
+		return fmt.Sprintf("column %s of %s can't be NULL; give it a value, or the column a default", column, table)
+	case ConstraintUnique:
+		if e.Migration {
+			return fmt.Sprintf("%s has duplicate values in %s; remove the duplicates before adding the unique constraint", table, column)
+		}
+		return fmt.Sprintf("another row of %s has the same %s; use a value of its own", table, column)
+	case ConstraintForeignKey:
+		if e.Migration {
+			return fmt.Sprintf("rows of %s reference rows that don't exist; fix or delete them before adding the foreign key", table)
+		}
+		return fmt.Sprintf("the row of %s references a row that doesn't exist, or rows still reference it; change or delete those first", table)
+	case ConstraintCheck:
+		if e.Name != "" {
+			return fmt.Sprintf("a value of %s breaks the check constraint %s", table, e.Name)
+		}
+		return fmt.Sprintf("a value of %s breaks one of its check constraints", table)
+	}
+	return ""
+}
+
+// ChecksumError is the error of a migration whose file changed after it
+// was applied
+type ChecksumError struct {
+	Migration string
+	Applied   string
+	Current   string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("migration %s changed after it was applied (checksum %s, was %s)", e.Migration, e.Current, e.Applied)
+}
+
+func (e *ChecksumError) Unwrap() error {
+	return ErrMigrationChecksum
+}
+
+func (e *ChecksumError) Hint() string {
+	return "restore the file as it was applied and put the change in a new migration, or apply with --force to skip the check"
+}
+
+// Hint returns the hint of the first error of err's chain that has one
+func Hint(err error) string {
+	var hinted interface{ Hint() string }
+	if errors.As(err, &hinted) {
+		return hinted.Hint()
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package common
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// KeyOrder tells whether the values of a primary key sort in the order
+// rows were inserted, so rows can be paged through by key instead of by
+// offset
+type KeyOrder string
+
+const (
+	KeyOrderNone    KeyOrder = ""        // no useful order, such as random UUIDs
+	KeyOrderInteger KeyOrder = "integer" // sequences, auto increments and snowflake ids
+	KeyOrderULID    KeyOrder = "ulid"    // ULIDs, which sort as text by their time
+	KeyOrderUUIDv7  KeyOrder = "uuidv7"  // version 7 UUIDs, which lead with their time
+)
+
+var (
+	ulidPattern   = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Za-hjkmnp-tv-z]{25}$`)
+	uuidv7Pattern = regexp.MustCompile(`^[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-7[0-9A-Fa-f]{3}-[89ABab][0-9A-Fa-f]{3}-[0-9A-Fa-f]{12}$`)
+)
+
+// Ordered tells whether keys of the order can be paged through
+func (o KeyOrder) Ordered() bool {
+	return o != KeyOrderNone
+}
+
+// DetectKeyOrder tells how a primary key column sorts from its type, its
+// default and a sample of its values. Text keys are ULIDs or UUIDv7s only
+// when every sampled value is one, in the same letter case, since mixed
+// case doesn't sort by time; an empty sample falls back on the default,
+// such as uuidv7() or gen_ulid().
+func DetectKeyOrder(colType, defaultValue string, sample []any) KeyOrder {
+	lowerType := strings.ToLower(colType)
+	if strings.Contains(lowerType, "int") || strings.Contains(lowerType, "serial") {
+		return KeyOrderInteger
+	}
+
+	values := make([]string, 0, len(sample))
+	for _, v := range sample {
+		if v == nil {
+			continue
+		}
+		values = append(values, KeyString(v))
+	}
+	if len(values) == 0 {
+		lowerDefault := strings.ReplaceAll(strings.ToLower(defaultValue), "_", "")
+		switch {
+		case strings.Contains(lowerDefault, "ulid"):
+			return KeyOrderULID
+		case strings.Contains(lowerDefault, "uuidv7"), strings.Contains(lowerDefault, "uuidgeneratev7"):
+			return KeyOrderUUIDv7
+		}
+		return KeyOrderNone
+	}
+
+	switch {
+	case matchesAll(values, ulidPattern):
+		return KeyOrderULID
+	case matchesAll(values, uuidv7Pattern):
+		return KeyOrderUUIDv7
+	}
+	return KeyOrderNone
+}
+
+// matchesAll tells whether every value matches pattern, all in upper or
+// all in lower case
+func matchesAll(values []string, pattern *regexp.Regexp) bool {
+	upper, lower := true, true
+	for _, v := range values {
+		if !pattern.MatchString(v) {
+			return false
+		}
+		upper = upper && v == strings.ToUpper(v)
+		lower = lower && v == strings.ToLower(v)
+	}
+	return upper || lower
+}
+
+// KeyString is a key value as text, with UUIDs drivers return as bytes in
+// their usual form
+func KeyString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case [16]byte:
+		s := hex.EncodeToString(v[:])
+		return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+	}
+	return fmt.Sprint(v)
+}
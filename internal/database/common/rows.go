@@ -0,0 +1,66 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrStopRows is returned by a row callback to stop reading rows early
+var ErrStopRows = errors.New("stop reading rows")
+
+// StreamRows passes the columns of rows and their types to onColumns, then
+// the values of each row to onRow as they are read. []byte values are
+// converted to strings like ExecuteQuery does.
+func StreamRows(rows *sql.Rows, onColumns func(columns, types []string) error, onRow func([]any) error) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to get columns: %w", err)
+	}
+	if err := onColumns(columns, ColumnTypes(rows, len(columns))); err != nil {
+		return err
+	}
+
+	values := make([]any, len(columns))
+	valuePtrs := make([]any, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+		for i, val := range values {
+			if b, ok := val.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		if err := onRow(values); err != nil {
+			if errors.Is(err, ErrStopRows) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+	return nil
+}
+
+// ColumnTypes returns the database type names of the n columns of rows, ""
+// where the driver doesn't know one, as for expressions on SQLite
+func ColumnTypes(rows *sql.Rows, n int) []string {
+	types := make([]string, n)
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return types
+	}
+	for i, ct := range columnTypes {
+		if i < n {
+			types[i] = ct.DatabaseTypeName()
+		}
+	}
+	return types
+}
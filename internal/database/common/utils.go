@@ -8,12 +8,26 @@ import (
 // Pre-compiled regex patterns for SQL parsing (performance optimization)
 var (
 	commentRegex = regexp.MustCompile(`(?m)^\s*--.*$`)
-	stringRegex  = regexp.MustCompile(`'(?:[^']|'')*'|"(?:[^"]|"")*"|` + "`(?:[^`]|``)*`")
+	stringRegex  = regexp.MustCompile(`'(?:[^']|'')*'|"(?:[^"]|"")*"|` + "`(?:[^`]|``)*`" + `|\$\$(?s:.*?)\$\$`)
+	// Trigger bodies contain semicolons; the statement only ends after END
+	triggerStartRegex = regexp.MustCompile(`(?i)^CREATE\s+(?:TEMP\s+|TEMPORARY\s+)?TRIGGER\b`)
+	triggerBeginRegex = regexp.MustCompile(`(?i)\bBEGIN\b`)
+	triggerEndRegex   = regexp.MustCompile(`(?i)\bEND\s*$`)
 )
 
 type QueryResult struct {
 	Columns []string
-	Rows    []map[string]interface{}
+	// ColumnTypes are the database type names of Columns, "" where the
+	// driver doesn't know one
+	ColumnTypes []string
+	Rows        []map[string]interface{}
+}
+
+// Statement is a statement with the arguments of its placeholders, $N on
+// PostgreSQL and ? elsewhere, which the driver binds with their Go types
+type Statement struct {
+	SQL  string
+	Args []any
 }
 
 // ParseSQLStatements uses regex-based parsing for 40-50% performance improvement on large migrations
@@ -37,6 +51,10 @@ func ParseSQLStatements(sql string) []string {
 	for i, char := range sql {
 		if char == ';' && !stringPositions[i] {
 			stmt := strings.TrimSpace(currentStatement.String())
+			if triggerStartRegex.MatchString(stmt) && triggerBeginRegex.MatchString(stmt) && !triggerEndRegex.MatchString(stmt) {
+				currentStatement.WriteRune(char)
+				continue
+			}
 			if stmt != "" && !strings.HasPrefix(stmt, "/*") {
 				statements = append(statements, stmt)
 			}
@@ -1,13 +1,36 @@
 package database
 
 import (
+	"strings"
+	"sync"
+
 	"github.com/Lumos-Labs-HQ/flash/internal/database/mongodb"
 	"github.com/Lumos-Labs-HQ/flash/internal/database/mysql"
 	"github.com/Lumos-Labs-HQ/flash/internal/database/postgres"
 	"github.com/Lumos-Labs-HQ/flash/internal/database/sqlite"
 )
 
+var (
+	adaptersMu sync.RWMutex
+	adapters   = make(map[string]func() DatabaseAdapter)
+)
+
+// RegisterAdapter makes NewAdapter return adapters built by factory for a
+// provider, so plugins can add databases or replace a built-in adapter
+func RegisterAdapter(provider string, factory func() DatabaseAdapter) {
+	adaptersMu.Lock()
+	defer adaptersMu.Unlock()
+	adapters[provider] = factory
+}
+
 func NewAdapter(provider string) DatabaseAdapter {
+	adaptersMu.RLock()
+	factory := adapters[provider]
+	adaptersMu.RUnlock()
+	if factory != nil {
+		return factory()
+	}
+
 	switch provider {
 	case "postgresql", "postgres":
 		return postgres.New()
@@ -21,3 +44,17 @@ func NewAdapter(provider string) DatabaseAdapter {
 		return postgres.New()
 	}
 }
+
+// ProviderFromURL tells the provider of a database from the scheme of its
+// connection URL, PostgreSQL when the scheme doesn't say
+func ProviderFromURL(url string) string {
+	switch {
+	case strings.HasPrefix(url, "mongodb://"), strings.HasPrefix(url, "mongodb+srv://"):
+		return "mongodb"
+	case strings.HasPrefix(url, "mysql://"):
+		return "mysql"
+	case strings.HasPrefix(url, "sqlite://"):
+		return "sqlite"
+	}
+	return "postgresql"
+}
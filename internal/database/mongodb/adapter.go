@@ -293,29 +293,8 @@ func (a *Adapter) FindDocuments(ctx context.Context, collection string, filter b
 
 // FindDocumentsInDB finds documents in a specific database and collection with pagination
 func (a *Adapter) FindDocumentsInDB(ctx context.Context, database, collection string, filter bson.M, skip, limit int64) ([]map[string]interface{}, error) {
-	db := a.client.Database(database)
-	coll := db.Collection(collection)
-	opts := options.Find().SetSkip(skip).SetLimit(limit)
-
-	cursor, err := coll.Find(ctx, filter, opts)
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-
-	var results []map[string]interface{}
-	for cursor.Next(ctx) {
-		var doc bson.M
-		if err := cursor.Decode(&doc); err != nil {
-			continue
-		}
-		converted := make(map[string]interface{})
-		for k, v := range doc {
-			converted[k] = convertBSONValue(v)
-		}
-		results = append(results, converted)
-	}
-	return results, nil
+	results, _, err := a.FindDocumentsWithIDsInDB(ctx, database, collection, filter, skip, limit)
+	return results, err
 }
 
 // CountDocuments counts documents in a collection
@@ -474,6 +453,14 @@ func (a *Adapter) GetDatabaseStats(ctx context.Context) (map[string]interface{},
 	return stats, nil
 }
 
+// GetServerStatus returns the serverStatus of the server: its version,
+// connections and operation counters among others
+func (a *Adapter) GetServerStatus(ctx context.Context) (bson.M, error) {
+	var result bson.M
+	err := a.client.Database("admin").RunCommand(ctx, bson.D{{Key: "serverStatus", Value: 1}}).Decode(&result)
+	return result, err
+}
+
 // ListDatabases lists all databases
 func (a *Adapter) ListDatabases(ctx context.Context) ([]map[string]interface{}, error) {
 	databases, err := a.client.ListDatabases(ctx, bson.M{})
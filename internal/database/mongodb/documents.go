@@ -0,0 +1,121 @@
+package mongodb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// documentID writes a document's _id as canonical Extended JSON, which keeps
+// its exact type (ObjectId, UUID, int or long, a compound key's field order)
+// and which parseObjectID reads back
+func documentID(doc bson.Raw) (string, error) {
+	id, err := doc.LookupErr("_id")
+	if err != nil {
+		return "", fmt.Errorf("document has no _id")
+	}
+	out, err := bson.MarshalExtJSON(bson.D{{Key: "_id", Value: id}}, true, false)
+	if err != nil {
+		return "", err
+	}
+	var wrapper struct {
+		ID json.RawMessage `json:"_id"`
+	}
+	if err := json.Unmarshal(out, &wrapper); err != nil {
+		return "", err
+	}
+	return string(wrapper.ID), nil
+}
+
+// FindDocumentsWithIDsInDB finds documents like FindDocumentsInDB, along
+// with each document's _id as documentID writes it
+func (a *Adapter) FindDocumentsWithIDsInDB(ctx context.Context, database, collection string, filter bson.M, skip, limit int64) ([]map[string]interface{}, []string, error) {
+	coll := a.client.Database(database).Collection(collection)
+	opts := options.Find().SetSkip(skip).SetLimit(limit)
+
+	cursor, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []map[string]interface{}
+	var ids []string
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			continue
+		}
+		id, _ := documentID(cursor.Current)
+		converted := make(map[string]interface{})
+		for k, v := range doc {
+			converted[k] = convertBSONValue(v)
+		}
+		results = append(results, converted)
+		ids = append(ids, id)
+	}
+	return results, ids, cursor.Err()
+}
+
+// FindDocumentByID returns a document as stored, mongo.ErrNoDocuments when
+// there's none with the ID
+func (a *Adapter) FindDocumentByID(ctx context.Context, collection string, id string) (bson.Raw, error) {
+	objectID, err := parseObjectID(id)
+	if err != nil {
+		return nil, err
+	}
+	return a.database.Collection(collection).FindOne(ctx, bson.M{"_id": objectID}).Raw()
+}
+
+// UpdateDocumentByID applies an update to a document, returning whether a
+// document has the ID
+func (a *Adapter) UpdateDocumentByID(ctx context.Context, collection string, id string, update interface{}) (bool, error) {
+	objectID, err := parseObjectID(id)
+	if err != nil {
+		return false, err
+	}
+	result, err := a.database.Collection(collection).UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// GetCollectionOptions returns the options a collection was created with,
+// among them its validator, validationLevel and validationAction; nil when
+// there's no such collection
+func (a *Adapter) GetCollectionOptions(ctx context.Context, collection string) (bson.Raw, error) {
+	cursor, err := a.database.ListCollections(ctx, bson.M{"name": collection})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if !cursor.Next(ctx) {
+		return nil, cursor.Err()
+	}
+	opts, ok := cursor.Current.Lookup("options").DocumentOK()
+	if !ok {
+		return nil, nil
+	}
+	return opts, nil
+}
+
+// SampleDocuments returns up to size documents of a collection as stored,
+// picked at random by the server
+func (a *Adapter) SampleDocuments(ctx context.Context, collection string, size int) ([]bson.Raw, error) {
+	cursor, err := a.database.Collection(collection).Aggregate(ctx, bson.A{bson.M{"$sample": bson.M{"size": size}}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []bson.Raw
+	for cursor.Next(ctx) {
+		docs = append(docs, bson.Raw(append([]byte(nil), cursor.Current...)))
+	}
+	return docs, cursor.Err()
+}
@@ -0,0 +1,95 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ForEachDocument calls each with every document of a collection as stored,
+// in _id order, stopping at the first error
+func (a *Adapter) ForEachDocument(ctx context.Context, collection string, each func(doc bson.Raw) error) error {
+	cursor, err := a.database.Collection(collection).Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		// The cursor reuses its buffer
+		if err := each(bson.Raw(append([]byte(nil), cursor.Current...))); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// ListIndexSpecs returns the indexes of a collection as the server
+// describes them: key, name and options such as unique or sparse
+func (a *Adapter) ListIndexSpecs(ctx context.Context, collection string) ([]bson.Raw, error) {
+	cursor, err := a.database.Collection(collection).Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var specs []bson.Raw
+	for cursor.Next(ctx) {
+		specs = append(specs, bson.Raw(append([]byte(nil), cursor.Current...)))
+	}
+	return specs, cursor.Err()
+}
+
+// CreateCollectionWithOptions creates a collection with the options
+// GetCollectionOptions returns, such as a validator or capped size
+func (a *Adapter) CreateCollectionWithOptions(ctx context.Context, name string, opts bson.Raw) error {
+	command := bson.D{{Key: "create", Value: name}}
+	if opts != nil {
+		elements, err := opts.Elements()
+		if err != nil {
+			return err
+		}
+		for _, element := range elements {
+			command = append(command, bson.E{Key: element.Key(), Value: element.Value()})
+		}
+	}
+	return a.database.RunCommand(ctx, command).Err()
+}
+
+// CreateIndexSpecs creates indexes described as ListIndexSpecs returns
+// them. Indexes that already exist as described are left alone.
+func (a *Adapter) CreateIndexSpecs(ctx context.Context, collection string, specs []bson.D) error {
+	if len(specs) == 0 {
+		return nil
+	}
+	return a.database.RunCommand(ctx, bson.D{
+		{Key: "createIndexes", Value: collection},
+		{Key: "indexes", Value: specs},
+	}).Err()
+}
+
+// UpsertDocuments writes documents, replacing those whose _id is already
+// there, and returns how many were inserted and how many replaced.
+// Documents without an _id are inserted.
+func (a *Adapter) UpsertDocuments(ctx context.Context, collection string, docs []bson.Raw) (int64, int64, error) {
+	if len(docs) == 0 {
+		return 0, 0, nil
+	}
+	models := make([]mongo.WriteModel, len(docs))
+	for i, doc := range docs {
+		id, err := doc.LookupErr("_id")
+		if err != nil {
+			models[i] = mongo.NewInsertOneModel().SetDocument(doc)
+			continue
+		}
+		models[i] = mongo.NewReplaceOneModel().SetFilter(bson.D{{Key: "_id", Value: id}}).SetReplacement(doc).SetUpsert(true)
+	}
+
+	result, err := a.database.Collection(collection).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if result == nil {
+		return 0, 0, err
+	}
+	return result.InsertedCount + result.UpsertedCount, result.MatchedCount, err
+}
@@ -75,8 +75,16 @@ func extractBetween(str, start, end string) string {
 	return strings.TrimSpace(str[startIdx:endIdx])
 }
 
-// parseObjectID parses a string ID to ObjectID or returns the string as-is
+// parseObjectID parses a string ID to ObjectID or returns the string as-is.
+// An ID in Extended JSON, as documentID writes them, is read as the value it
+// stands for, so UUID, numeric and compound IDs can be given too.
 func parseObjectID(id string) (interface{}, error) {
+	if strings.HasPrefix(id, "{") || strings.HasPrefix(id, `"`) {
+		var doc bson.D
+		if err := bson.UnmarshalExtJSON([]byte(`{"_id":`+id+`}`), true, &doc); err == nil && len(doc) == 1 {
+			return doc[0].Value, nil
+		}
+	}
 	if len(id) == 24 {
 		oid, err := primitive.ObjectIDFromHex(id)
 		if err != nil {
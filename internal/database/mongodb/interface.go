@@ -20,6 +20,7 @@ type MongoDBAdapter interface {
 	DropDatabase(ctx context.Context, dbName string) error
 	CreateDatabase(ctx context.Context, dbName string) error
 	GetDatabaseStats(ctx context.Context) (map[string]interface{}, error)
+	GetServerStatus(ctx context.Context) (bson.M, error)
 
 	// Collection operations
 	ListCollections(ctx context.Context) ([]string, error)
@@ -36,6 +37,18 @@ type MongoDBAdapter interface {
 	InsertDocument(ctx context.Context, collection string, document interface{}) (string, error)
 	UpdateDocument(ctx context.Context, collection string, id string, update interface{}) error
 	DeleteDocument(ctx context.Context, collection string, id string) error
+	FindDocumentsWithIDsInDB(ctx context.Context, database, collection string, filter bson.M, skip, limit int64) ([]map[string]interface{}, []string, error)
+	FindDocumentByID(ctx context.Context, collection string, id string) (bson.Raw, error)
+	UpdateDocumentByID(ctx context.Context, collection string, id string, update interface{}) (bool, error)
+	GetCollectionOptions(ctx context.Context, collection string) (bson.Raw, error)
+	SampleDocuments(ctx context.Context, collection string, size int) ([]bson.Raw, error)
+
+	// Export and import
+	ForEachDocument(ctx context.Context, collection string, each func(doc bson.Raw) error) error
+	ListIndexSpecs(ctx context.Context, collection string) ([]bson.Raw, error)
+	CreateCollectionWithOptions(ctx context.Context, name string, opts bson.Raw) error
+	CreateIndexSpecs(ctx context.Context, collection string, specs []bson.D) error
+	UpsertDocuments(ctx context.Context, collection string, docs []bson.Raw) (int64, int64, error)
 
 	// Index operations
 	ListIndexes(ctx context.Context, collection string) ([]map[string]interface{}, error)
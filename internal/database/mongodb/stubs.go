@@ -9,76 +9,99 @@ import (
 	"github.com/Lumos-Labs-HQ/flash/internal/types"
 )
 
-func (a *Adapter) CreateMigrationsTable(ctx context.Context) error             { return nil }
-func (a *Adapter) EnsureMigrationTableCompatibility(ctx context.Context) error { return nil }
-func (a *Adapter) CleanupBrokenMigrationRecords(ctx context.Context) error     { return nil }
+// MongoDB only has the data browsing of the generic adapter. The operations
+// of the other capabilities fail, but for SQL generation and type mapping,
+// which only migrations use.
+func (a *Adapter) Capabilities() []common.Capability {
+	return []common.Capability{common.CapabilityDataBrowsing}
+}
+
+func unsupported(capability common.Capability) error {
+	return common.Unsupported("mongodb", capability)
+}
+
+func (a *Adapter) CreateMigrationsTable(ctx context.Context) error {
+	return unsupported(common.CapabilityMigrations)
+}
+
+func (a *Adapter) EnsureMigrationTableCompatibility(ctx context.Context) error {
+	return unsupported(common.CapabilityMigrations)
+}
+
+func (a *Adapter) CleanupBrokenMigrationRecords(ctx context.Context) error {
+	return unsupported(common.CapabilityMigrations)
+}
 
 func (a *Adapter) GetAppliedMigrations(ctx context.Context) (map[string]*time.Time, error) {
-	return nil, nil
+	return nil, unsupported(common.CapabilityMigrations)
 }
 
 func (a *Adapter) RecordMigration(ctx context.Context, migrationID, name, checksum string) error {
-	return nil
+	return unsupported(common.CapabilityMigrations)
 }
 
 func (a *Adapter) RemoveMigrationRecord(ctx context.Context, migrationID string) error {
-	return nil
+	return unsupported(common.CapabilityMigrations)
 }
 
 func (a *Adapter) ExecuteMigration(ctx context.Context, migrationSQL string) error {
-	return nil
+	return unsupported(common.CapabilityMigrations)
+}
+
+func (a *Adapter) ExecuteStatements(ctx context.Context, statements []common.Statement) error {
+	return unsupported(common.CapabilityMigrations)
 }
 
 func (a *Adapter) ExecuteAndRecordMigration(ctx context.Context, migrationID, name, checksum string, migrationSQL string) error {
-	return nil
+	return unsupported(common.CapabilityMigrations)
 }
 
-func (a *Adapter) ExecuteQuery(ctx context.Context, query string) (*common.QueryResult, error) {
-	return nil, nil
+func (a *Adapter) ExecuteQuery(ctx context.Context, query string, args ...any) (*common.QueryResult, error) {
+	return nil, unsupported(common.CapabilityQueries)
 }
 
 func (a *Adapter) GetCurrentSchema(ctx context.Context) ([]types.SchemaTable, error) {
-	return nil, nil
+	return nil, unsupported(common.CapabilityIntrospection)
 }
 
 func (a *Adapter) GetCurrentEnums(ctx context.Context) ([]types.SchemaEnum, error) {
-	return nil, nil
+	return nil, unsupported(common.CapabilityIntrospection)
 }
 
 func (a *Adapter) GetTableIndexes(ctx context.Context, tableName string) ([]types.SchemaIndex, error) {
-	return nil, nil
+	return nil, unsupported(common.CapabilityIntrospection)
 }
 
 func (a *Adapter) PullCompleteSchema(ctx context.Context) ([]types.SchemaTable, error) {
-	return nil, nil
+	return nil, unsupported(common.CapabilityIntrospection)
 }
 
 func (a *Adapter) CheckTableExists(ctx context.Context, tableName string) (bool, error) {
-	return false, nil
+	return false, unsupported(common.CapabilityIntrospection)
 }
 
 func (a *Adapter) CheckColumnExists(ctx context.Context, tableName, columnName string) (bool, error) {
-	return false, nil
+	return false, unsupported(common.CapabilityIntrospection)
 }
 
 func (a *Adapter) CheckNotNullConstraint(ctx context.Context, tableName, columnName string) (bool, error) {
-	return false, nil
+	return false, unsupported(common.CapabilityIntrospection)
 }
 
 func (a *Adapter) CheckForeignKeyConstraint(ctx context.Context, tableName, constraintName string) (bool, error) {
-	return false, nil
+	return false, unsupported(common.CapabilityIntrospection)
 }
 
 func (a *Adapter) CheckUniqueConstraint(ctx context.Context, tableName, constraintName string) (bool, error) {
-	return false, nil
+	return false, unsupported(common.CapabilityIntrospection)
 }
 
 func (a *Adapter) DropTable(ctx context.Context, tableName string) error {
-	return nil
+	return unsupported(common.CapabilityMigrations)
 }
 
 func (a *Adapter) DropEnum(ctx context.Context, enumName string) error {
-	return nil
+	return unsupported(common.CapabilityMigrations)
 }
 
 func (a *Adapter) GenerateCreateTableSQL(table types.SchemaTable) string {
@@ -110,31 +133,31 @@ func (a *Adapter) FormatColumnType(column types.SchemaColumn) string {
 }
 
 func (a *Adapter) CreateBranchSchema(ctx context.Context, branchName string) error {
-	return nil
+	return unsupported(common.CapabilityBranches)
 }
 
 func (a *Adapter) DropBranchSchema(ctx context.Context, branchName string) error {
-	return nil
+	return unsupported(common.CapabilityBranches)
 }
 
 func (a *Adapter) CloneSchemaToBranch(ctx context.Context, sourceSchema, targetSchema string) error {
-	return nil
+	return unsupported(common.CapabilityBranches)
 }
 
 func (a *Adapter) GetSchemaForBranch(ctx context.Context, branchSchema string) ([]types.SchemaTable, error) {
-	return nil, nil
+	return nil, unsupported(common.CapabilityBranches)
 }
 
 func (a *Adapter) SetActiveSchema(ctx context.Context, schemaName string) error {
-	return nil
+	return unsupported(common.CapabilityBranches)
 }
 
 func (a *Adapter) GetTableNamesInSchema(ctx context.Context, schemaName string) ([]string, error) {
-	return nil, nil
+	return nil, unsupported(common.CapabilityBranches)
 }
 
 func (a *Adapter) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	return nil, nil
+	return nil, unsupported(common.CapabilityQueries)
 }
 
 func (a *Adapter) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
@@ -142,9 +165,9 @@ func (a *Adapter) QueryRow(ctx context.Context, query string, args ...interface{
 }
 
 func (a *Adapter) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	return nil, nil
+	return nil, unsupported(common.CapabilityQueries)
 }
 
 func (a *Adapter) Begin(ctx context.Context) (*sql.Tx, error) {
-	return nil, nil
+	return nil, unsupported(common.CapabilityQueries)
 }
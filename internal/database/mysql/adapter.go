@@ -35,7 +35,9 @@ func New() *Adapter {
 	}
 }
 
-func (m *Adapter) Connect(ctx context.Context, url string) error {
+// DSN converts a mysql:// URL to the DSN format of the MySQL driver. Other
+// strings are returned as they are.
+func DSN(url string) string {
 	dsn := url
 	if strings.HasPrefix(url, "mysql://") {
 		dsn = strings.TrimPrefix(url, "mysql://")
@@ -63,7 +65,11 @@ func (m *Adapter) Connect(ctx context.Context, url string) error {
 			}
 		}
 	}
+	return dsn
+}
 
+func (m *Adapter) Connect(ctx context.Context, url string) error {
+	dsn := DSN(url)
 	m.originalDSN = dsn
 
 	if idx := strings.Index(dsn, "/"); idx > 0 {
@@ -130,7 +136,7 @@ func (m *Adapter) Close() error {
 }
 
 func (m *Adapter) Ping(ctx context.Context) error {
-	return m.db.PingContext(ctx)
+	return classifyError(m.db.PingContext(ctx), false)
 }
 
 func (m *Adapter) CreateMigrationsTable(ctx context.Context) error {
@@ -233,7 +239,7 @@ func (m *Adapter) RemoveMigrationRecord(ctx context.Context, migrationID string)
 func (m *Adapter) ExecuteAndRecordMigration(ctx context.Context, migrationID, name, checksum string, migrationSQL string) error {
 	tx, err := m.db.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return classifyError(err, false)
 	}
 	defer tx.Rollback()
 
@@ -253,7 +259,7 @@ func (m *Adapter) ExecuteAndRecordMigration(ctx context.Context, migrationID, na
 				continue
 			}
 			if _, err := tx.ExecContext(ctx, stmt); err != nil {
-				return fmt.Errorf("failed to execute statement %d: %w", i+1, err)
+				return fmt.Errorf("failed to execute statement %d: %w", i+1, classifyError(err, true))
 			}
 		}
 	}
@@ -273,7 +279,7 @@ func (m *Adapter) ExecuteAndRecordMigration(ctx context.Context, migrationID, na
 func (m *Adapter) ExecuteMigration(ctx context.Context, migrationSQL string) error {
 	tx, err := m.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", classifyError(err, false))
 	}
 	defer tx.Rollback()
 
@@ -294,7 +300,7 @@ func (m *Adapter) ExecuteMigration(ctx context.Context, migrationSQL string) err
 
 		_, err := tx.ExecContext(ctx, stmt)
 		if err != nil {
-			return fmt.Errorf("failed to execute statement '%s': %w", stmt, err)
+			return fmt.Errorf("failed to execute statement '%s': %w", stmt, classifyError(err, true))
 		}
 	}
 
@@ -305,16 +311,31 @@ func (m *Adapter) ExecuteMigration(ctx context.Context, migrationSQL string) err
 	return nil
 }
 
-func (m *Adapter) ExecuteQuery(ctx context.Context, query string) (*common.QueryResult, error) {
+func (m *Adapter) ExecuteStatements(ctx context.Context, statements []common.Statement) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", classifyError(err, false))
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt.SQL, stmt.Args...); err != nil {
+			return fmt.Errorf("failed to execute statement '%s': %w", stmt.SQL, classifyError(err, false))
+		}
+	}
+	return classifyError(tx.Commit(), false)
+}
+
+func (m *Adapter) ExecuteQuery(ctx context.Context, query string, args ...any) (*common.QueryResult, error) {
 	trimmedQuery := strings.TrimSpace(strings.ToUpper(query))
 	if strings.HasPrefix(trimmedQuery, "USE ") ||
 		strings.HasPrefix(trimmedQuery, "SET ") ||
 		strings.HasPrefix(trimmedQuery, "CREATE ") ||
 		strings.HasPrefix(trimmedQuery, "DROP ") ||
 		strings.HasPrefix(trimmedQuery, "ALTER ") {
-		_, err := m.db.ExecContext(ctx, query)
+		_, err := m.db.ExecContext(ctx, query, args...)
 		if err != nil {
-			return nil, fmt.Errorf("failed to execute command: %w", err)
+			return nil, fmt.Errorf("failed to execute command: %w", classifyError(err, false))
 		}
 		return &common.QueryResult{
 			Columns: []string{},
@@ -322,9 +343,9 @@ func (m *Adapter) ExecuteQuery(ctx context.Context, query string) (*common.Query
 		}, nil
 	}
 
-	rows, err := m.db.QueryContext(ctx, query)
+	rows, err := m.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %w", err)
+		return nil, fmt.Errorf("failed to execute query: %w", classifyError(err, false))
 	}
 	defer rows.Close()
 
@@ -332,6 +353,7 @@ func (m *Adapter) ExecuteQuery(ctx context.Context, query string) (*common.Query
 	if err != nil {
 		return nil, fmt.Errorf("failed to get columns: %w", err)
 	}
+	types := common.ColumnTypes(rows, len(columns))
 
 	results := make([]map[string]interface{}, 0, 64)
 	for rows.Next() {
@@ -358,15 +380,28 @@ func (m *Adapter) ExecuteQuery(ctx context.Context, query string) (*common.Query
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
+		return nil, fmt.Errorf("error iterating rows: %w", classifyError(err, false))
 	}
 
 	return &common.QueryResult{
-		Columns: columns,
-		Rows:    results,
+		Columns:     columns,
+		ColumnTypes: types,
+		Rows:        results,
 	}, nil
 }
 
+// StreamQuery runs a query and passes its rows to onRow one at a time
+// instead of loading them all
+func (m *Adapter) StreamQuery(ctx context.Context, query string, args []any, onColumns func(columns, types []string) error, onRow func([]any) error) error {
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	return common.StreamRows(rows, onColumns, onRow)
+}
+
 func (m *Adapter) MapColumnType(dbType string) string {
 	if mapped, exists := typeMap[strings.ToLower(dbType)]; exists {
 		return mapped
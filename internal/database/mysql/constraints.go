@@ -0,0 +1,95 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+// attachConstraints sets the foreign keys and unique constraints over more
+// than one column of tables; single-column ones are read with the columns
+func (m *Adapter) attachConstraints(ctx context.Context, tables []types.SchemaTable) error {
+	if len(tables) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(tables))
+	args := make([]interface{}, len(tables))
+	for i, table := range tables {
+		placeholders[i] = "?"
+		args[i] = table.Name
+	}
+
+	query := fmt.Sprintf(`
+		SELECT tc.TABLE_NAME, tc.CONSTRAINT_NAME, tc.CONSTRAINT_TYPE, k.COLUMN_NAME,
+			k.REFERENCED_TABLE_NAME, k.REFERENCED_COLUMN_NAME, r.DELETE_RULE, r.UPDATE_RULE
+		FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc
+		JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE k
+			ON k.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA
+			AND k.TABLE_NAME = tc.TABLE_NAME
+			AND k.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+		LEFT JOIN INFORMATION_SCHEMA.REFERENTIAL_CONSTRAINTS r
+			ON r.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA
+			AND r.TABLE_NAME = tc.TABLE_NAME
+			AND r.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+		WHERE tc.TABLE_SCHEMA = DATABASE()
+		  AND tc.TABLE_NAME IN (%s)
+		  AND tc.CONSTRAINT_TYPE IN ('FOREIGN KEY', 'UNIQUE')
+		ORDER BY tc.TABLE_NAME, tc.CONSTRAINT_NAME, k.ORDINAL_POSITION
+	`, strings.Join(placeholders, ","))
+
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type constraintKey struct {
+		tableName, name string
+	}
+	var order []constraintKey
+	constraints := make(map[constraintKey]*types.SchemaConstraint)
+
+	for rows.Next() {
+		var tableName, name, constraintType, column string
+		var refTable, refColumn, onDelete, onUpdate sql.NullString
+		if err := rows.Scan(&tableName, &name, &constraintType, &column, &refTable, &refColumn, &onDelete, &onUpdate); err != nil {
+			return err
+		}
+
+		key := constraintKey{tableName, name}
+		constraint, exists := constraints[key]
+		if !exists {
+			constraint = &types.SchemaConstraint{
+				Name:     name,
+				Type:     constraintType,
+				RefTable: refTable.String,
+				OnDelete: onDelete.String,
+				OnUpdate: onUpdate.String,
+			}
+			constraints[key] = constraint
+			order = append(order, key)
+		}
+		constraint.Columns = append(constraint.Columns, column)
+		if refColumn.Valid {
+			constraint.RefColumns = append(constraint.RefColumns, refColumn.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	byTable := make(map[string][]types.SchemaConstraint)
+	for _, key := range order {
+		if constraint := constraints[key]; len(constraint.Columns) > 1 {
+			byTable[key.tableName] = append(byTable[key.tableName], *constraint)
+		}
+	}
+	for i := range tables {
+		tables[i].Constraints = byTable[tables[i].Name]
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package mysql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net"
+	"regexp"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	// Column 'email' cannot be null / Field 'email' doesn't have a default value
+	nullColumnRegex = regexp.MustCompile("^(?:Column|Field) '([^']+)'")
+	// Duplicate entry 'a@b.c' for key 'users.email'
+	duplicateKeyRegex = regexp.MustCompile(`for key '(?:([^'.]+)\.)?([^']+)'$`)
+	// (`shop`.`orders`, CONSTRAINT `orders_user_id_fkey` FOREIGN KEY (`user_id`) REFERENCES ...
+	foreignKeyRegex = regexp.MustCompile("\\(`[^`]*`\\.`([^`]+)`, CONSTRAINT `([^`]+)` FOREIGN KEY \\(`([^`]+)`")
+	// Check constraint 'price_positive' is violated.
+	checkConstraintRegex = regexp.MustCompile(`^Check constraint '([^']+)'`)
+	// INSERT command denied to user 'app'@'%' for table 'users'
+	deniedTableRegex = regexp.MustCompile(`for table '([^']+)'`)
+)
+
+// classifyError turns the errors of MySQL into a common.DatabaseError of
+// their kind, leaving others as they are. migration tells whether err comes
+// from the statements of a migration.
+func classifyError(err error, migration bool) error {
+	if err == nil {
+		return nil
+	}
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) {
+		var netErr net.Error
+		if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) || errors.As(err, &netErr) {
+			return &common.DatabaseError{Kind: common.ErrConnection, Err: err}
+		}
+		return err
+	}
+
+	dbErr := &common.DatabaseError{Migration: migration, Err: err}
+	switch myErr.Number {
+	case 1048, 1364:
+		dbErr.Kind, dbErr.Constraint = common.ErrConstraintViolation, common.ConstraintNotNull
+		if m := nullColumnRegex.FindStringSubmatch(myErr.Message); m != nil {
+			dbErr.Column = m[1]
+		}
+	case 1138:
+		// Invalid use of NULL value, from a MODIFY to NOT NULL
+		dbErr.Kind, dbErr.Constraint, dbErr.Migration = common.ErrConstraintViolation, common.ConstraintNotNull, true
+	case 1062:
+		dbErr.Kind, dbErr.Constraint = common.ErrConstraintViolation, common.ConstraintUnique
+		if m := duplicateKeyRegex.FindStringSubmatch(myErr.Message); m != nil {
+			dbErr.Table, dbErr.Name = m[1], m[2]
+		}
+	case 1451, 1452:
+		dbErr.Kind, dbErr.Constraint = common.ErrConstraintViolation, common.ConstraintForeignKey
+		if m := foreignKeyRegex.FindStringSubmatch(myErr.Message); m != nil {
+			dbErr.Table, dbErr.Name, dbErr.Column = m[1], m[2], m[3]
+		}
+	case 3819:
+		dbErr.Kind, dbErr.Constraint = common.ErrConstraintViolation, common.ConstraintCheck
+		if m := checkConstraintRegex.FindStringSubmatch(myErr.Message); m != nil {
+			dbErr.Name = m[1]
+		}
+	case 1044, 1142, 1143, 1227:
+		dbErr.Kind = common.ErrPermission
+		if m := deniedTableRegex.FindStringSubmatch(myErr.Message); m != nil {
+			dbErr.Table = m[1]
+		}
+	case 1045, 1049:
+		// Access denied for the credentials, unknown database
+		dbErr.Kind = common.ErrConnection
+	default:
+		return err
+	}
+	return dbErr
+}
@@ -147,7 +147,7 @@ func (m *Adapter) DropEnum(ctx context.Context, enumName string) error {
 
 func (m *Adapter) GenerateCreateTableSQL(table types.SchemaTable) string {
 	var lines []string
-	var foreignKeys []string
+	var constraints []string
 
 	for _, column := range table.Columns {
 		if column.ForeignKeyTable != "" && column.ForeignKeyColumn != "" {
@@ -156,23 +156,31 @@ func (m *Adapter) GenerateCreateTableSQL(table types.SchemaTable) string {
 			if column.OnDeleteAction != "" {
 				fk += fmt.Sprintf(" ON DELETE %s", column.OnDeleteAction)
 			}
-			foreignKeys = append(foreignKeys, fk)
+			if column.OnUpdateAction != "" {
+				fk += fmt.Sprintf(" ON UPDATE %s", column.OnUpdateAction)
+			}
+			constraints = append(constraints, fk)
 		}
 	}
 
+	for _, constraint := range table.Constraints {
+		constraint.Deferrable = "" // PostgreSQL only
+		constraints = append(constraints, "  "+constraint.Definition(func(name string) string { return "`" + name + "`" }))
+	}
+
 	lines = append(lines, fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` (", table.Name))
 
 	for i, column := range table.Columns {
 		comma := ","
-		if i == len(table.Columns)-1 && len(foreignKeys) == 0 {
+		if i == len(table.Columns)-1 && len(constraints) == 0 {
 			comma = ""
 		}
 		lines = append(lines, fmt.Sprintf("  `%s` %s%s", column.Name, m.FormatColumnType(column), comma))
 	}
 
-	for i, fk := range foreignKeys {
+	for i, fk := range constraints {
 		comma := ","
-		if i == len(foreignKeys)-1 {
+		if i == len(constraints)-1 {
 			comma = ""
 		}
 		lines = append(lines, fmt.Sprintf("%s%s", fk, comma))
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/Lumos-Labs-HQ/flash/internal/types"
@@ -44,6 +45,9 @@ func (m *Adapter) GetCurrentSchema(ctx context.Context) ([]types.SchemaTable, er
 			Indexes: allIndexes[name],
 		})
 	}
+	if err := m.attachConstraints(ctx, tables); err != nil {
+		return nil, err
+	}
 	return tables, nil
 }
 
@@ -134,13 +138,17 @@ func (m *Adapter) GetAllTablesColumns(ctx context.Context, tableNames []string)
 			c.ordinal_position,
 			k.REFERENCED_TABLE_NAME,
 			k.REFERENCED_COLUMN_NAME,
-			r.DELETE_RULE
+			r.DELETE_RULE,
+			r.UPDATE_RULE
 		FROM information_schema.columns c
 		LEFT JOIN information_schema.key_column_usage k
 			ON c.table_schema = k.table_schema
 			AND c.table_name = k.table_name
 			AND c.column_name = k.column_name
 			AND k.referenced_table_name IS NOT NULL
+			AND (SELECT COUNT(*) FROM information_schema.key_column_usage k2
+				WHERE k2.constraint_schema = k.constraint_schema AND k2.table_name = k.table_name
+				AND k2.constraint_name = k.constraint_name) = 1
 		LEFT JOIN information_schema.referential_constraints r
 			ON k.constraint_name = r.constraint_name
 			AND k.table_schema = r.constraint_schema
@@ -159,7 +167,7 @@ func (m *Adapter) GetAllTablesColumns(ctx context.Context, tableNames []string)
 		var tableName string
 		var column types.SchemaColumn
 		var dataType, isNullable, columnType, extra string
-		var columnDefault, referencedTable, referencedColumn, onDeleteAction sql.NullString
+		var columnDefault, referencedTable, referencedColumn, onDeleteAction, onUpdateAction sql.NullString
 		var charMaxLength, numericPrecision, numericScale sql.NullInt64
 		var isPrimary, isUnique int
 		var ordinalPosition int
@@ -181,6 +189,7 @@ func (m *Adapter) GetAllTablesColumns(ctx context.Context, tableNames []string)
 			&referencedTable,
 			&referencedColumn,
 			&onDeleteAction,
+			&onUpdateAction,
 		)
 		if err != nil {
 			return nil, err
@@ -201,6 +210,9 @@ func (m *Adapter) GetAllTablesColumns(ctx context.Context, tableNames []string)
 			if onDeleteAction.Valid {
 				column.OnDeleteAction = onDeleteAction.String
 			}
+			if onUpdateAction.Valid {
+				column.OnUpdateAction = onUpdateAction.String
+			}
 		}
 
 		result[tableName] = append(result[tableName], column)
@@ -281,6 +293,9 @@ func (m *Adapter) GetAllTablesIndexes(ctx context.Context, tableNames []string)
 	for key, idx := range indexMap {
 		result[key.tableName] = append(result[key.tableName], *idx)
 	}
+	for _, indexes := range result {
+		sort.Slice(indexes, func(i, j int) bool { return indexes[i].Name < indexes[j].Name })
+	}
 
 	return result, nil
 }
@@ -340,13 +355,17 @@ func (m *Adapter) PullCompleteSchema(ctx context.Context) ([]types.SchemaTable,
 		CASE WHEN c.COLUMN_KEY = 'UNI' THEN 'UNIQUE' ELSE NULL END as is_unique,
 		k.REFERENCED_TABLE_NAME AS REFERENCES_TABLE,
 		k.REFERENCED_COLUMN_NAME AS REFERENCES_COLUMN,
-		r.DELETE_RULE AS ON_DELETE
+		r.DELETE_RULE AS ON_DELETE,
+		r.UPDATE_RULE AS ON_UPDATE
 	FROM INFORMATION_SCHEMA.COLUMNS c
 	LEFT JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE k
 		ON c.TABLE_SCHEMA = k.TABLE_SCHEMA
 		AND c.TABLE_NAME = k.TABLE_NAME
 		AND c.COLUMN_NAME = k.COLUMN_NAME
 		AND k.REFERENCED_TABLE_NAME IS NOT NULL
+		AND (SELECT COUNT(*) FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE k2
+			WHERE k2.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA AND k2.TABLE_NAME = k.TABLE_NAME
+			AND k2.CONSTRAINT_NAME = k.CONSTRAINT_NAME) = 1
 	LEFT JOIN INFORMATION_SCHEMA.REFERENTIAL_CONSTRAINTS r
 		ON k.CONSTRAINT_NAME = r.CONSTRAINT_NAME
 		AND k.TABLE_SCHEMA = r.CONSTRAINT_SCHEMA
@@ -366,10 +385,10 @@ func (m *Adapter) PullCompleteSchema(ctx context.Context) ([]types.SchemaTable,
 	for rows.Next() {
 		var tableName, columnName, columnType, isNullable string
 		var ordinalPosition int
-		var columnDefault, extra, isPrimary, isUnique, referencesTable, referencesColumn, onDelete sql.NullString
+		var columnDefault, extra, isPrimary, isUnique, referencesTable, referencesColumn, onDelete, onUpdate sql.NullString
 
 		err := rows.Scan(&tableName, &columnName, &columnType, &isNullable, &columnDefault,
-			&extra, &ordinalPosition, &isPrimary, &isUnique, &referencesTable, &referencesColumn, &onDelete)
+			&extra, &ordinalPosition, &isPrimary, &isUnique, &referencesTable, &referencesColumn, &onDelete, &onUpdate)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
@@ -404,6 +423,9 @@ func (m *Adapter) PullCompleteSchema(ctx context.Context) ([]types.SchemaTable,
 			if onDelete.Valid {
 				column.OnDeleteAction = onDelete.String
 			}
+			if onUpdate.Valid {
+				column.OnUpdateAction = onUpdate.String
+			}
 		}
 
 		tableMap[tableName].Columns = append(tableMap[tableName].Columns, column)
@@ -413,6 +435,11 @@ func (m *Adapter) PullCompleteSchema(ctx context.Context) ([]types.SchemaTable,
 	for _, table := range tableMap {
 		tables = append(tables, *table)
 	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+
+	if err := m.attachConstraints(ctx, tables); err != nil {
+		return nil, fmt.Errorf("failed to query constraints: %w", err)
+	}
 
 	return tables, nil
 }
@@ -471,7 +498,7 @@ func (m *Adapter) formatMySQLDefault(defaultValue, columnType string) string {
 		return ""
 	}
 
-	if strings.Contains(strings.ToLower(defaultValue), "current_timestamp") {
+	if strings.EqualFold(strings.TrimSpace(defaultValue), "current_timestamp") {
 		return "CURRENT_TIMESTAMP"
 	}
 
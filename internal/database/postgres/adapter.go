@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -9,6 +10,8 @@ import (
 	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
 	"github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -66,7 +69,7 @@ func (p *Adapter) Close() error {
 }
 
 func (p *Adapter) Ping(ctx context.Context) error {
-	return p.pool.Ping(ctx)
+	return classifyError(p.pool.Ping(ctx), false)
 }
 
 func (p *Adapter) CreateMigrationsTable(ctx context.Context) error {
@@ -154,7 +157,7 @@ func (p *Adapter) RemoveMigrationRecord(ctx context.Context, migrationID string)
 func (p *Adapter) ExecuteAndRecordMigration(ctx context.Context, migrationID, name, checksum string, migrationSQL string) error {
 	tx, err := p.pool.Begin(ctx)
 	if err != nil {
-		return err
+		return classifyError(err, false)
 	}
 	defer tx.Rollback(ctx)
 
@@ -175,7 +178,7 @@ func (p *Adapter) ExecuteAndRecordMigration(ctx context.Context, migrationID, na
 				continue
 			}
 			if _, err := tx.Exec(ctx, stmt); err != nil {
-				return fmt.Errorf("failed to execute statement %d: %w", i+1, err)
+				return fmt.Errorf("failed to execute statement %d: %w", i+1, classifyError(err, true))
 			}
 		}
 	}
@@ -196,7 +199,7 @@ func (p *Adapter) ExecuteAndRecordMigration(ctx context.Context, migrationID, na
 func (p *Adapter) ExecuteMigration(ctx context.Context, migrationSQL string) error {
 	tx, err := p.pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", classifyError(err, false))
 	}
 	defer tx.Rollback(ctx)
 
@@ -210,7 +213,7 @@ func (p *Adapter) ExecuteMigration(ctx context.Context, migrationSQL string) err
 
 		_, err := tx.Exec(ctx, stmt)
 		if err != nil {
-			return fmt.Errorf("failed to execute statement '%s': %w", stmt, err)
+			return fmt.Errorf("failed to execute statement '%s': %w", stmt, classifyError(err, true))
 		}
 	}
 
@@ -221,10 +224,25 @@ func (p *Adapter) ExecuteMigration(ctx context.Context, migrationSQL string) err
 	return nil
 }
 
-func (p *Adapter) ExecuteQuery(ctx context.Context, query string) (*common.QueryResult, error) {
-	rows, err := p.pool.Query(ctx, query)
+func (p *Adapter) ExecuteStatements(ctx context.Context, statements []common.Statement) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", classifyError(err, false))
+	}
+	defer tx.Rollback(ctx)
+
+	for _, stmt := range statements {
+		if _, err := tx.Exec(ctx, stmt.SQL, stmt.Args...); err != nil {
+			return fmt.Errorf("failed to execute statement '%s': %w", stmt.SQL, classifyError(err, false))
+		}
+	}
+	return classifyError(tx.Commit(ctx), false)
+}
+
+func (p *Adapter) ExecuteQuery(ctx context.Context, query string, args ...any) (*common.QueryResult, error) {
+	rows, err := p.pool.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %w", err)
+		return nil, fmt.Errorf("failed to execute query: %w", classifyError(err, false))
 	}
 	defer rows.Close()
 
@@ -233,6 +251,7 @@ func (p *Adapter) ExecuteQuery(ctx context.Context, query string) (*common.Query
 	for i, fd := range fieldDescriptions {
 		columns[i] = string(fd.Name)
 	}
+	types, unknown := p.fieldTypes(rows)
 
 	results := make([]map[string]interface{}, 0, 64)
 	for rows.Next() {
@@ -249,15 +268,110 @@ func (p *Adapter) ExecuteQuery(ctx context.Context, query string) (*common.Query
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
+		return nil, fmt.Errorf("error iterating rows: %w", classifyError(err, false))
+	}
+	rows.Close()
+	if len(unknown) > 0 {
+		p.lookUpTypes(ctx, fieldDescriptions, types, unknown)
 	}
 
 	return &common.QueryResult{
-		Columns: columns,
-		Rows:    results,
+		Columns:     columns,
+		ColumnTypes: types,
+		Rows:        results,
 	}, nil
 }
 
+// StreamQuery runs a query and passes its rows to onRow one at a time
+// instead of loading them all
+func (p *Adapter) StreamQuery(ctx context.Context, query string, args []any, onColumns func(columns, types []string) error, onRow func([]any) error) error {
+	rows, err := p.pool.Query(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	fieldDescriptions := rows.FieldDescriptions()
+	columns := make([]string, len(fieldDescriptions))
+	for i, fd := range fieldDescriptions {
+		columns[i] = string(fd.Name)
+	}
+	types, _ := p.fieldTypes(rows)
+	if err := onColumns(columns, types); err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+		if err := onRow(values); err != nil {
+			if errors.Is(err, common.ErrStopRows) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+	return nil
+}
+
+// fieldTypes returns the type names of the fields of rows from the types
+// the connection knows, and the indexes of those of other types, like enums
+// and domains
+func (p *Adapter) fieldTypes(rows pgx.Rows) ([]string, []int) {
+	fieldDescriptions := rows.FieldDescriptions()
+	types := make([]string, len(fieldDescriptions))
+	var unknown []int
+	conn := rows.Conn()
+	for i, fd := range fieldDescriptions {
+		var t *pgtype.Type
+		ok := false
+		if conn != nil {
+			t, ok = conn.TypeMap().TypeForOID(fd.DataTypeOID)
+		}
+		if !ok {
+			unknown = append(unknown, i)
+			continue
+		}
+		if element, isArray := strings.CutPrefix(t.Name, "_"); isArray {
+			types[i] = p.MapColumnType(element) + "[]"
+		} else {
+			types[i] = p.MapColumnType(t.Name)
+		}
+	}
+	return types, unknown
+}
+
+// lookUpTypes names the fields of unknown types from pg_type. Their names
+// are left empty when the lookup fails.
+func (p *Adapter) lookUpTypes(ctx context.Context, fieldDescriptions []pgconn.FieldDescription, types []string, unknown []int) {
+	oids := make([]uint32, len(unknown))
+	for i, field := range unknown {
+		oids[i] = fieldDescriptions[field].DataTypeOID
+	}
+	rows, err := p.pool.Query(ctx, "SELECT oid::int8, format_type(oid, NULL) FROM pg_type WHERE oid = ANY($1::oid[])", oids)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	names := make(map[uint32]string)
+	for rows.Next() {
+		var oid int64
+		var name string
+		if rows.Scan(&oid, &name) == nil {
+			names[uint32(oid)] = name
+		}
+	}
+	for _, field := range unknown {
+		types[field] = names[fieldDescriptions[field].DataTypeOID]
+	}
+}
+
 func (p *Adapter) MapColumnType(dbType string) string {
 	if mapped, exists := typeMap[strings.ToLower(dbType)]; exists {
 		return mapped
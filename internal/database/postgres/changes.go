@@ -0,0 +1,359 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// changePlugins are the logical decoding output plugins tried for change
+// capture, in order. wal2json is an extension; test_decoding ships with
+// PostgreSQL's contrib modules.
+var changePlugins = []string{"wal2json", "test_decoding"}
+
+// OpenChangeCapture creates a temporary logical replication slot to read the
+// changes written from now on. It needs wal_level = logical and a role with
+// REPLICATION. The slot holds a connection taken out of the pool, and is
+// dropped by the server when the capture closes it.
+func (p *Adapter) OpenChangeCapture(ctx context.Context) (common.ChangeCapture, error) {
+	pooled, err := p.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+
+	var walLevel string
+	if err := pooled.QueryRow(ctx, "SHOW wal_level").Scan(&walLevel); err != nil {
+		pooled.Release()
+		return nil, fmt.Errorf("failed to read wal_level: %w", err)
+	}
+	if walLevel != "logical" {
+		pooled.Release()
+		return nil, fmt.Errorf("change capture needs wal_level = logical, the server runs with %s; set it in postgresql.conf and restart", walLevel)
+	}
+
+	// The connection never goes back to the pool, so closing it ends the
+	// session and the temporary slot with it
+	conn := pooled.Hijack()
+	slot := fmt.Sprintf("flash_changes_%d", time.Now().UnixNano())
+	for _, plugin := range changePlugins {
+		_, err = conn.Exec(ctx, "SELECT pg_create_logical_replication_slot($1, $2, true)", slot, plugin)
+		if err == nil {
+			return &changeCapture{conn: conn, slot: slot, plugin: plugin}, nil
+		}
+		// undefined_file: the plugin isn't installed
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || pgErr.Code != "58P01" {
+			break
+		}
+	}
+	conn.Close(context.Background())
+	return nil, fmt.Errorf("failed to create replication slot: %w", err)
+}
+
+type changeCapture struct {
+	conn   *pgx.Conn
+	slot   string
+	plugin string
+}
+
+func (c *changeCapture) Plugin() string {
+	return c.plugin
+}
+
+// Read consumes the changes of the slot, whole transactions at a time, so
+// it may return a few more than limit
+func (c *changeCapture) Read(ctx context.Context, limit int) ([]common.Change, error) {
+	options := `'skip-empty-xacts', '1', 'include-xids', '0'`
+	if c.plugin == "wal2json" {
+		options = `'format-version', '2', 'include-transaction', 'false', 'include-types', 'false'`
+	}
+	query := fmt.Sprintf("SELECT lsn::text, xid::text, data FROM pg_logical_slot_get_changes($1, NULL, $2, %s)", options)
+
+	rows, err := c.conn.Query(ctx, query, c.slot, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []common.Change
+	for rows.Next() {
+		var lsn, xid, data string
+		if err := rows.Scan(&lsn, &xid, &data); err != nil {
+			return nil, fmt.Errorf("failed to scan change: %w", err)
+		}
+
+		var change *common.Change
+		if c.plugin == "wal2json" {
+			change, err = parseWal2JSON(data)
+		} else {
+			change, err = parseTestDecoding(data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode change at %s: %w", lsn, err)
+		}
+		if change == nil {
+			continue
+		}
+		change.LSN = lsn
+		change.XID = xid
+		changes = append(changes, *change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read changes: %w", err)
+	}
+	return changes, nil
+}
+
+func (c *changeCapture) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return c.conn.Close(ctx)
+}
+
+var changeOps = map[string]string{
+	"I": "insert", "U": "update", "D": "delete", "T": "truncate",
+	"INSERT": "insert", "UPDATE": "update", "DELETE": "delete", "TRUNCATE": "truncate",
+}
+
+// parseWal2JSON decodes a change written by wal2json's format version 2,
+// nil for messages that aren't row changes
+func parseWal2JSON(data string) (*common.Change, error) {
+	var msg struct {
+		Action  string `json:"action"`
+		Schema  string `json:"schema"`
+		Table   string `json:"table"`
+		Columns []struct {
+			Name  string `json:"name"`
+			Value any    `json:"value"`
+		} `json:"columns"`
+		Identity []struct {
+			Name  string `json:"name"`
+			Value any    `json:"value"`
+		} `json:"identity"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(data)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&msg); err != nil {
+		return nil, err
+	}
+
+	op, ok := changeOps[msg.Action]
+	if !ok {
+		return nil, nil
+	}
+	change := &common.Change{Op: op, Schema: msg.Schema, Table: msg.Table}
+	if len(msg.Columns) > 0 {
+		change.After = make(map[string]any, len(msg.Columns))
+		for _, col := range msg.Columns {
+			change.After[col.Name] = col.Value
+		}
+	}
+	if len(msg.Identity) > 0 {
+		change.Before = make(map[string]any, len(msg.Identity))
+		for _, col := range msg.Identity {
+			change.Before[col.Name] = col.Value
+		}
+	}
+	return change, nil
+}
+
+// parseTestDecoding decodes a change written by test_decoding, like
+//
+//	table public.users: UPDATE: old-key: id[integer]:1 new-tuple: id[integer]:2 name[text]:'ann'
+//
+// nil for BEGIN, COMMIT and messages. Values are the text of the columns.
+func parseTestDecoding(data string) (*common.Change, error) {
+	if !strings.HasPrefix(data, "table ") {
+		return nil, nil
+	}
+	p := &tupleParser{s: data, i: len("table ")}
+
+	change := &common.Change{}
+	var tables []string
+	for {
+		schema, table, err := p.qualifiedName()
+		if err != nil {
+			return nil, err
+		}
+		change.Schema = schema
+		tables = append(tables, table)
+		// A TRUNCATE of several tables lists them all
+		if !p.consume(", ") {
+			break
+		}
+	}
+	change.Table = strings.Join(tables, ", ")
+	if !p.consume(": ") {
+		return nil, fmt.Errorf("expected ': ' after the table name")
+	}
+
+	end := strings.Index(p.s[p.i:], ":")
+	if end < 0 {
+		return nil, fmt.Errorf("missing operation")
+	}
+	op, ok := changeOps[p.s[p.i:p.i+end]]
+	if !ok {
+		return nil, nil
+	}
+	change.Op = op
+	p.i += end + 1
+
+	target := &change.After
+	if op == "delete" {
+		target = &change.Before
+	}
+	for {
+		p.skipSpaces()
+		switch {
+		case p.i >= len(p.s):
+			return change, nil
+		case p.consume("(no-tuple-data)"), p.consume("(no-flags)"), op == "truncate":
+			return change, nil
+		case p.consume("old-key:"):
+			target = &change.Before
+			continue
+		case p.consume("new-tuple:"):
+			target = &change.After
+			continue
+		}
+
+		column, value, unchanged, err := p.column()
+		if err != nil {
+			return nil, err
+		}
+		if unchanged {
+			continue
+		}
+		if *target == nil {
+			*target = make(map[string]any)
+		}
+		(*target)[column] = value
+	}
+}
+
+// tupleParser reads the columns of a change written by test_decoding
+type tupleParser struct {
+	s string
+	i int
+}
+
+func (p *tupleParser) consume(prefix string) bool {
+	if strings.HasPrefix(p.s[p.i:], prefix) {
+		p.i += len(prefix)
+		return true
+	}
+	return false
+}
+
+func (p *tupleParser) skipSpaces() {
+	for p.i < len(p.s) && p.s[p.i] == ' ' {
+		p.i++
+	}
+}
+
+// qualifiedName reads a table name and its schema, when it has one
+func (p *tupleParser) qualifiedName() (string, string, error) {
+	name, err := p.ident()
+	if err != nil {
+		return "", "", err
+	}
+	if !p.consume(".") {
+		return "", name, nil
+	}
+	table, err := p.ident()
+	return name, table, err
+}
+
+// ident reads an identifier, unquoting it when it's quoted
+func (p *tupleParser) ident() (string, error) {
+	if p.i < len(p.s) && p.s[p.i] == '"' {
+		var b strings.Builder
+		for p.i++; p.i < len(p.s); p.i++ {
+			if p.s[p.i] == '"' {
+				if p.i+1 < len(p.s) && p.s[p.i+1] == '"' {
+					b.WriteByte('"')
+					p.i++
+					continue
+				}
+				p.i++
+				return b.String(), nil
+			}
+			b.WriteByte(p.s[p.i])
+		}
+		return "", fmt.Errorf("unterminated identifier")
+	}
+
+	start := p.i
+	for p.i < len(p.s) && !strings.ContainsRune(".[:, ", rune(p.s[p.i])) {
+		p.i++
+	}
+	if p.i == start {
+		return "", fmt.Errorf("expected an identifier at %d", start)
+	}
+	return p.s[start:p.i], nil
+}
+
+// column reads a name[type]:value column. unchanged reports a TOASTed
+// value the update didn't change, which the log leaves out.
+func (p *tupleParser) column() (string, any, bool, error) {
+	name, err := p.ident()
+	if err != nil {
+		return "", nil, false, err
+	}
+	if !p.consume("[") {
+		return "", nil, false, fmt.Errorf("expected the type of %s", name)
+	}
+	// Array types nest brackets, like integer[]
+	for depth := 1; depth > 0; p.i++ {
+		if p.i >= len(p.s) {
+			return "", nil, false, fmt.Errorf("unterminated type of %s", name)
+		}
+		switch p.s[p.i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		}
+	}
+	if !p.consume(":") {
+		return "", nil, false, fmt.Errorf("expected the value of %s", name)
+	}
+
+	if p.i < len(p.s) && p.s[p.i] == '\'' {
+		var b strings.Builder
+		for p.i++; p.i < len(p.s); p.i++ {
+			if p.s[p.i] == '\'' {
+				if p.i+1 < len(p.s) && p.s[p.i+1] == '\'' {
+					b.WriteByte('\'')
+					p.i++
+					continue
+				}
+				p.i++
+				return name, b.String(), false, nil
+			}
+			b.WriteByte(p.s[p.i])
+		}
+		return "", nil, false, fmt.Errorf("unterminated value of %s", name)
+	}
+
+	start := p.i
+	for p.i < len(p.s) && p.s[p.i] != ' ' {
+		p.i++
+	}
+	switch value := p.s[start:p.i]; value {
+	case "null":
+		return name, nil, false, nil
+	case "unchanged-toast-datum":
+		return name, nil, true, nil
+	default:
+		return name, value, false, nil
+	}
+}
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+// attachConstraints sets the foreign keys and unique constraints over more
+// than one column of tables; single-column ones are read with the columns
+func (p *Adapter) attachConstraints(ctx context.Context, tables []types.SchemaTable) error {
+	if len(tables) == 0 {
+		return nil
+	}
+	tableNames := make([]string, len(tables))
+	for i, table := range tables {
+		tableNames[i] = table.Name
+	}
+
+	rows, err := p.pool.Query(ctx, `
+		SELECT
+			src_table.relname AS table_name,
+			con.conname,
+			CASE con.contype WHEN 'f' THEN 'FOREIGN KEY' ELSE 'UNIQUE' END AS constraint_type,
+			ARRAY(
+				SELECT a.attname FROM UNNEST(con.conkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			)::text[] AS columns,
+			CASE WHEN tgt_ns.nspname IN (current_schema(), 'public') THEN tgt_table.relname
+				ELSE tgt_ns.nspname || '.' || tgt_table.relname END AS foreign_table_name,
+			ARRAY(
+				SELECT a.attname FROM UNNEST(con.confkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			)::text[] AS foreign_columns,
+			CASE con.confdeltype
+				WHEN 'a' THEN 'NO ACTION'
+				WHEN 'r' THEN 'RESTRICT'
+				WHEN 'c' THEN 'CASCADE'
+				WHEN 'n' THEN 'SET NULL'
+				WHEN 'd' THEN 'SET DEFAULT'
+			END AS on_delete_action,
+			CASE con.confupdtype
+				WHEN 'a' THEN 'NO ACTION'
+				WHEN 'r' THEN 'RESTRICT'
+				WHEN 'c' THEN 'CASCADE'
+				WHEN 'n' THEN 'SET NULL'
+				WHEN 'd' THEN 'SET DEFAULT'
+			END AS on_update_action,
+			CASE WHEN con.condeferred THEN 'DEFERRABLE INITIALLY DEFERRED'
+				WHEN con.condeferrable THEN 'DEFERRABLE' END AS deferrable
+		FROM pg_constraint con
+		JOIN pg_class src_table ON con.conrelid = src_table.oid
+		JOIN pg_namespace ns ON src_table.relnamespace = ns.oid
+		LEFT JOIN pg_class tgt_table ON con.confrelid = tgt_table.oid
+		LEFT JOIN pg_namespace tgt_ns ON tgt_table.relnamespace = tgt_ns.oid
+		WHERE src_table.relname = ANY($1)
+		  AND ns.nspname IN (current_schema(), 'public')
+		  AND con.contype IN ('f', 'u')
+		  AND array_length(con.conkey, 1) > 1
+		ORDER BY src_table.relname, con.conname
+	`, tableNames)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	constraints := make(map[string][]types.SchemaConstraint)
+	for rows.Next() {
+		var tableName string
+		var constraint types.SchemaConstraint
+		var foreignTable, onDelete, onUpdate, deferrable sql.NullString
+
+		if err := rows.Scan(&tableName, &constraint.Name, &constraint.Type, &constraint.Columns,
+			&foreignTable, &constraint.RefColumns, &onDelete, &onUpdate, &deferrable); err != nil {
+			return err
+		}
+		constraint.RefTable = foreignTable.String
+		constraint.OnDelete = onDelete.String
+		constraint.OnUpdate = onUpdate.String
+		constraint.Deferrable = deferrable.String
+		constraints[tableName] = append(constraints[tableName], constraint)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range tables {
+		tables[i].Constraints = constraints[tables[i].Name]
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// Key (email)=(a@b.c) already exists.
+	keyDetailRegex = regexp.MustCompile(`^Key \(([^)]+)\)=`)
+	// permission denied for table users
+	permissionObjectRegex = regexp.MustCompile(`^permission denied for (?:table|relation|view|sequence) (\S+)`)
+)
+
+// classifyError turns the errors of PostgreSQL into a common.DatabaseError
+// of their kind, leaving others as they are. migration tells whether err
+// comes from the statements of a migration.
+func classifyError(err error, migration bool) error {
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		var connectErr *pgconn.ConnectError
+		var netErr net.Error
+		if errors.As(err, &connectErr) || errors.As(err, &netErr) {
+			return &common.DatabaseError{Kind: common.ErrConnection, Err: err}
+		}
+		return err
+	}
+
+	dbErr := &common.DatabaseError{
+		Name:      pgErr.ConstraintName,
+		Table:     pgErr.TableName,
+		Column:    pgErr.ColumnName,
+		Migration: migration,
+		Err:       err,
+	}
+	switch {
+	case pgErr.Code == "23502":
+		dbErr.Kind, dbErr.Constraint = common.ErrConstraintViolation, common.ConstraintNotNull
+		// ALTER ... SET NOT NULL: column "x" of relation "t" contains null values
+		dbErr.Migration = migration || strings.Contains(pgErr.Message, "contains null values")
+	case pgErr.Code == "23505":
+		dbErr.Kind, dbErr.Constraint = common.ErrConstraintViolation, common.ConstraintUnique
+		dbErr.Migration = migration || strings.HasPrefix(pgErr.Message, "could not create unique index")
+	case pgErr.Code == "23503":
+		dbErr.Kind, dbErr.Constraint = common.ErrConstraintViolation, common.ConstraintForeignKey
+	case pgErr.Code == "23514":
+		dbErr.Kind, dbErr.Constraint = common.ErrConstraintViolation, common.ConstraintCheck
+	case pgErr.Code == "42501":
+		dbErr.Kind = common.ErrPermission
+		if m := permissionObjectRegex.FindStringSubmatch(pgErr.Message); m != nil && dbErr.Table == "" {
+			dbErr.Table = m[1]
+		}
+	case strings.HasPrefix(pgErr.Code, "08"), strings.HasPrefix(pgErr.Code, "28"), pgErr.Code == "3D000":
+		// Connection exceptions, failed authentication and unknown databases
+		dbErr.Kind = common.ErrConnection
+	default:
+		return err
+	}
+	if dbErr.Column == "" {
+		if m := keyDetailRegex.FindStringSubmatch(pgErr.Detail); m != nil {
+			dbErr.Column = m[1]
+		}
+	}
+	return dbErr
+}
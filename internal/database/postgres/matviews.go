@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+// GetCurrentMaterializedViews returns the materialized views of the current
+// schema with their indexes. Definitions are as PostgreSQL deparses them.
+func (p *Adapter) GetCurrentMaterializedViews(ctx context.Context) ([]types.SchemaMaterializedView, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT matviewname, definition, ispopulated
+		FROM pg_matviews
+		WHERE schemaname = current_schema()
+		ORDER BY matviewname
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var views []types.SchemaMaterializedView
+	var names []string
+	for rows.Next() {
+		var view types.SchemaMaterializedView
+		var populated bool
+		if err := rows.Scan(&view.Name, &view.Definition, &populated); err != nil {
+			return nil, err
+		}
+		view.Definition = trimViewDefinition(view.Definition)
+		view.NoData = !populated
+		views = append(views, view)
+		names = append(names, view.Name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(views) == 0 {
+		return views, nil
+	}
+
+	indexes, err := p.GetAllTablesIndexes(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	for i := range views {
+		views[i].Indexes = indexes[views[i].Name]
+	}
+	return views, nil
+}
+
+// GetMaterializedViewInfo returns the size, population and indexing of the
+// materialized views of the current schema
+func (p *Adapter) GetMaterializedViewInfo(ctx context.Context) ([]types.MaterializedViewInfo, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT
+			m.matviewname,
+			m.ispopulated,
+			pg_total_relation_size(c.oid),
+			GREATEST(c.reltuples, 0)::bigint,
+			EXISTS (
+				SELECT 1 FROM pg_index i
+				WHERE i.indrelid = c.oid AND i.indisunique AND i.indpred IS NULL
+			),
+			m.definition
+		FROM pg_matviews m
+		JOIN pg_class c ON c.relname = m.matviewname
+		JOIN pg_namespace n ON n.oid = c.relnamespace AND n.nspname = m.schemaname
+		WHERE m.schemaname = current_schema()
+		ORDER BY m.matviewname
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	views := []types.MaterializedViewInfo{}
+	for rows.Next() {
+		var view types.MaterializedViewInfo
+		if err := rows.Scan(&view.Name, &view.Populated, &view.SizeBytes, &view.RowEstimate, &view.HasUniqueIndex, &view.Definition); err != nil {
+			return nil, err
+		}
+		view.Definition = trimViewDefinition(view.Definition)
+		views = append(views, view)
+	}
+	return views, rows.Err()
+}
+
+// NormalizeViewDefinition deparses a view query the way PostgreSQL reports
+// the definitions of existing views, so schema files can be compared with
+// the database. The query is planned as a temporary view in a transaction
+// that is rolled back; it fails when the query doesn't fit the current
+// tables.
+func (p *Adapter) NormalizeViewDefinition(ctx context.Context, definition string) (string, error) {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "CREATE TEMP VIEW flash_view_definition AS "+definition); err != nil {
+		return "", err
+	}
+	var normalized string
+	if err := tx.QueryRow(ctx, "SELECT pg_get_viewdef('flash_view_definition'::regclass)").Scan(&normalized); err != nil {
+		return "", err
+	}
+	return trimViewDefinition(normalized), nil
+}
+
+// RefreshMaterializedView runs the query of a materialized view again.
+// Concurrently keeps the view readable during the refresh, which needs a
+// unique index on the view and a populated view.
+func (p *Adapter) RefreshMaterializedView(ctx context.Context, name string, concurrently bool) error {
+	stmt := "REFRESH MATERIALIZED VIEW "
+	if concurrently {
+		stmt += "CONCURRENTLY "
+	}
+	stmt += fmt.Sprintf(`"%s"`, strings.ReplaceAll(name, `"`, `""`))
+
+	_, err := p.pool.Exec(ctx, stmt)
+	return err
+}
+
+func trimViewDefinition(definition string) string {
+	return strings.TrimSuffix(strings.TrimSpace(definition), ";")
+}
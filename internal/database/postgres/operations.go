@@ -225,38 +225,52 @@ func (p *Adapter) DropEnum(ctx context.Context, enumName string) error {
 
 func (p *Adapter) GenerateCreateTableSQL(table types.SchemaTable) string {
 	var lines []string
-	var foreignKeys []string
+	var constraints []string
 
 	for _, column := range table.Columns {
 		if column.ForeignKeyTable != "" && column.ForeignKeyColumn != "" {
-			fk := fmt.Sprintf("  FOREIGN KEY (\"%s\") REFERENCES \"%s\"(\"%s\")",
-				column.Name, column.ForeignKeyTable, column.ForeignKeyColumn)
+			fk := fmt.Sprintf("  FOREIGN KEY (\"%s\") REFERENCES %s(\"%s\")",
+				column.Name, quoteTableName(column.ForeignKeyTable), column.ForeignKeyColumn)
 			if column.OnDeleteAction != "" {
 				fk += fmt.Sprintf(" ON DELETE %s", column.OnDeleteAction)
 			}
-			foreignKeys = append(foreignKeys, fk)
+			if column.OnUpdateAction != "" {
+				fk += fmt.Sprintf(" ON UPDATE %s", column.OnUpdateAction)
+			}
+			if column.ForeignKeyDeferrable != "" {
+				fk += " " + column.ForeignKeyDeferrable
+			}
+			constraints = append(constraints, fk)
 		}
 	}
 
+	for _, constraint := range table.Constraints {
+		constraints = append(constraints, "  "+constraint.Definition(func(name string) string { return "\"" + name + "\"" }))
+	}
+
 	lines = append(lines, fmt.Sprintf("CREATE TABLE IF NOT EXISTS \"%s\" (", table.Name))
 
 	for i, column := range table.Columns {
 		comma := ","
-		if i == len(table.Columns)-1 && len(foreignKeys) == 0 {
+		if i == len(table.Columns)-1 && len(constraints) == 0 {
 			comma = ""
 		}
 		lines = append(lines, fmt.Sprintf("  \"%s\" %s%s", column.Name, p.FormatColumnType(column), comma))
 	}
 
-	for i, fk := range foreignKeys {
+	for i, fk := range constraints {
 		comma := ","
-		if i == len(foreignKeys)-1 {
+		if i == len(constraints)-1 {
 			comma = ""
 		}
 		lines = append(lines, fmt.Sprintf("%s%s", fk, comma))
 	}
 
-	lines = append(lines, ");")
+	if table.PartitionBy != "" {
+		lines = append(lines, fmt.Sprintf(") PARTITION BY %s;", table.PartitionBy))
+	} else {
+		lines = append(lines, ");")
+	}
 	return strings.Join(lines, "\n")
 }
 
@@ -292,6 +306,9 @@ func (p *Adapter) FormatColumnType(column types.SchemaColumn) string {
 
 	if column.IsUnique && !column.IsPrimary {
 		parts = append(parts, "UNIQUE")
+		if column.UniqueDeferrable != "" {
+			parts = append(parts, column.UniqueDeferrable)
+		}
 	}
 
 	if !column.Nullable && !column.IsPrimary {
@@ -299,10 +316,16 @@ func (p *Adapter) FormatColumnType(column types.SchemaColumn) string {
 	}
 
 	if column.ForeignKeyTable != "" && column.ForeignKeyColumn != "" {
-		parts = append(parts, fmt.Sprintf("REFERENCES \"%s\"(\"%s\")", column.ForeignKeyTable, column.ForeignKeyColumn))
+		parts = append(parts, fmt.Sprintf("REFERENCES %s(\"%s\")", quoteTableName(column.ForeignKeyTable), column.ForeignKeyColumn))
 		if column.OnDeleteAction != "" {
 			parts = append(parts, fmt.Sprintf("ON DELETE %s", column.OnDeleteAction))
 		}
+		if column.OnUpdateAction != "" {
+			parts = append(parts, fmt.Sprintf("ON UPDATE %s", column.OnUpdateAction))
+		}
+		if column.ForeignKeyDeferrable != "" {
+			parts = append(parts, column.ForeignKeyDeferrable)
+		}
 	}
 
 	if column.Default != "" && !strings.Contains(column.Default, "nextval") {
@@ -311,3 +334,13 @@ func (p *Adapter) FormatColumnType(column types.SchemaColumn) string {
 
 	return strings.Join(parts, " ")
 }
+
+// quoteTableName quotes a table name that may be schema-qualified
+// (auth.users becomes "auth"."users")
+func quoteTableName(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = fmt.Sprintf("\"%s\"", part)
+	}
+	return strings.Join(parts, ".")
+}
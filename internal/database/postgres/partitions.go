@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+// GetCurrentPartitions returns the partitions of the partitioned tables in
+// the current schema. Partitions of partitions have the partition they
+// belong to as parent.
+func (p *Adapter) GetCurrentPartitions(ctx context.Context) ([]types.SchemaPartition, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT c.relname, parent.relname, pg_get_expr(c.relpartbound, c.oid)
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_class parent ON parent.oid = i.inhparent
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relispartition
+			AND c.relkind IN ('r', 'p')
+			AND n.nspname = current_schema()
+		ORDER BY parent.relname, c.relname
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var partitions []types.SchemaPartition
+	for rows.Next() {
+		var partition types.SchemaPartition
+		if err := rows.Scan(&partition.Name, &partition.Parent, &partition.Bound); err != nil {
+			return nil, err
+		}
+		partitions = append(partitions, partition)
+	}
+	return partitions, rows.Err()
+}
+
+// GetPartitionKeys returns the partition key of every partitioned table in
+// the current schema, e.g. "RANGE (created_at)"
+func (p *Adapter) GetPartitionKeys(ctx context.Context) (map[string]string, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT c.relname, pg_get_partkeydef(c.oid)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind = 'p' AND n.nspname = current_schema()
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make(map[string]string)
+	for rows.Next() {
+		var table, key string
+		if err := rows.Scan(&table, &key); err != nil {
+			return nil, err
+		}
+		keys[table] = key
+	}
+	return keys, rows.Err()
+}
+
+// attachPartitions sets the partition key and partitions of the partitioned
+// tables among tables
+func (p *Adapter) attachPartitions(ctx context.Context, tables []types.SchemaTable) error {
+	keys, err := p.GetPartitionKeys(ctx)
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+	partitions, err := p.GetCurrentPartitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range tables {
+		tables[i].PartitionBy = keys[tables[i].Name]
+		for _, partition := range partitions {
+			if partition.Parent == tables[i].Name {
+				tables[i].Partitions = append(tables[i].Partitions, partition)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+// GetCurrentPolicies returns the row-level security policies of the tables
+// in the current schema
+func (p *Adapter) GetCurrentPolicies(ctx context.Context) ([]types.SchemaPolicy, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT
+			policyname,
+			tablename,
+			permissive,
+			roles::text[],
+			cmd,
+			COALESCE(qual, ''),
+			COALESCE(with_check, '')
+		FROM pg_policies
+		WHERE schemaname = current_schema()
+		ORDER BY tablename, policyname
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var policies []types.SchemaPolicy
+	for rows.Next() {
+		var policy types.SchemaPolicy
+		var roles []string
+		if err := rows.Scan(&policy.Name, &policy.Table, &policy.Permissive, &roles,
+			&policy.Command, &policy.Using, &policy.WithCheck); err != nil {
+			return nil, err
+		}
+		for _, role := range roles {
+			if !strings.EqualFold(role, "public") {
+				policy.Roles = append(policy.Roles, role)
+			}
+		}
+		policies = append(policies, policy)
+	}
+
+	return policies, rows.Err()
+}
+
+// GetRLSEnabledTables returns the tables in the current schema that have
+// row-level security enabled
+func (p *Adapter) GetRLSEnabledTables(ctx context.Context) ([]string, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT c.relname
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = current_schema()
+			AND c.relkind = 'r'
+			AND c.relrowsecurity
+		ORDER BY c.relname
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+
+	return tables, rows.Err()
+}
@@ -4,11 +4,20 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/Lumos-Labs-HQ/flash/internal/types"
 )
 
+var (
+	// A cast of a whole default, e.g. ::character varying(20) or ::text[]
+	defaultCastRegex = regexp.MustCompile(`(?i)^\s*"?[a-z_][\w .]*"?(?:\(\s*\d+(?:\s*,\s*\d+)?\s*\))?(?:\[\])*\s*$`)
+	// The current time on its own, but not now() + '1 day'::interval
+	currentTimestampRegex = regexp.MustCompile(`(?i)^(?:now\s*\(\s*\)|current_timestamp(?:\s*\(\s*\))?)$`)
+)
+
 func (p *Adapter) GetCurrentSchema(ctx context.Context) ([]types.SchemaTable, error) {
 	tableNames, err := p.GetAllTableNames(ctx)
 	if err != nil {
@@ -44,6 +53,9 @@ func (p *Adapter) GetCurrentSchema(ctx context.Context) ([]types.SchemaTable, er
 			Indexes: allIndexes[name],
 		})
 	}
+	if err := p.attachConstraints(ctx, tables); err != nil {
+		return nil, err
+	}
 	return tables, nil
 }
 
@@ -79,6 +91,7 @@ func (p *Adapter) GetCurrentEnums(ctx context.Context) ([]types.SchemaEnum, erro
 			Values: values,
 		})
 	}
+	sort.Slice(enums, func(i, j int) bool { return enums[i].Name < enums[j].Name })
 
 	return enums, nil
 }
@@ -166,14 +179,17 @@ func (p *Adapter) GetAllTablesColumns(ctx context.Context, tableNames []string)
 	}
 
 	// Query 2: Get all constraints (PK, UNIQUE, FK) using pg_constraint directly
-	// Using UNNEST with ordinality for proper FK column matching
+	// Using UNNEST with ordinality for proper FK column matching. UNIQUE and
+	// FK constraints over several columns are table constraints, read by
+	// attachConstraints
 	constraintsQuery := `
 		WITH fk_columns AS (
 			SELECT
 				con.oid as constraint_oid,
 				src_table.relname AS table_name,
 				src_attr.attname AS column_name,
-				tgt_table.relname AS foreign_table_name,
+				CASE WHEN tgt_ns.nspname IN (current_schema(), 'public') THEN tgt_table.relname
+					ELSE tgt_ns.nspname || '.' || tgt_table.relname END AS foreign_table_name,
 				tgt_attr.attname AS foreign_column_name,
 				CASE con.confdeltype
 					WHEN 'a' THEN 'NO ACTION'
@@ -181,24 +197,37 @@ func (p *Adapter) GetAllTablesColumns(ctx context.Context, tableNames []string)
 					WHEN 'c' THEN 'CASCADE'
 					WHEN 'n' THEN 'SET NULL'
 					WHEN 'd' THEN 'SET DEFAULT'
-				END AS on_delete_action
+				END AS on_delete_action,
+				CASE con.confupdtype
+					WHEN 'a' THEN 'NO ACTION'
+					WHEN 'r' THEN 'RESTRICT'
+					WHEN 'c' THEN 'CASCADE'
+					WHEN 'n' THEN 'SET NULL'
+					WHEN 'd' THEN 'SET DEFAULT'
+				END AS on_update_action,
+				CASE WHEN con.condeferred THEN 'DEFERRABLE INITIALLY DEFERRED'
+					WHEN con.condeferrable THEN 'DEFERRABLE' END AS deferrable
 			FROM pg_constraint con
 			JOIN pg_class src_table ON con.conrelid = src_table.oid
 			JOIN pg_namespace ns ON src_table.relnamespace = ns.oid
 			CROSS JOIN LATERAL UNNEST(con.conkey, con.confkey) WITH ORDINALITY AS cols(src_col, tgt_col, ord)
 			JOIN pg_attribute src_attr ON src_attr.attrelid = src_table.oid AND src_attr.attnum = cols.src_col
 			JOIN pg_class tgt_table ON con.confrelid = tgt_table.oid
+			JOIN pg_namespace tgt_ns ON tgt_table.relnamespace = tgt_ns.oid
 			JOIN pg_attribute tgt_attr ON tgt_attr.attrelid = tgt_table.oid AND tgt_attr.attnum = cols.tgt_col
 			WHERE src_table.relname = ANY($1)
 			  AND ns.nspname IN (current_schema(), 'public')
 			  AND con.contype = 'f'
+			  AND array_length(con.conkey, 1) = 1
 		),
 		pk_uk_columns AS (
 			SELECT
 				con.oid as constraint_oid,
 				src_table.relname AS table_name,
 				src_attr.attname AS column_name,
-				CASE con.contype WHEN 'p' THEN 'PRIMARY KEY' ELSE 'UNIQUE' END AS constraint_type
+				CASE con.contype WHEN 'p' THEN 'PRIMARY KEY' ELSE 'UNIQUE' END AS constraint_type,
+				CASE WHEN con.condeferred THEN 'DEFERRABLE INITIALLY DEFERRED'
+					WHEN con.condeferrable THEN 'DEFERRABLE' END AS deferrable
 			FROM pg_constraint con
 			JOIN pg_class src_table ON con.conrelid = src_table.oid
 			JOIN pg_namespace ns ON src_table.relnamespace = ns.oid
@@ -206,12 +235,12 @@ func (p *Adapter) GetAllTablesColumns(ctx context.Context, tableNames []string)
 			JOIN pg_attribute src_attr ON src_attr.attrelid = src_table.oid AND src_attr.attnum = cols.src_col
 			WHERE src_table.relname = ANY($1)
 			  AND ns.nspname IN (current_schema(), 'public')
-			  AND con.contype IN ('p', 'u')
+			  AND (con.contype = 'p' OR (con.contype = 'u' AND array_length(con.conkey, 1) = 1))
 		)
-		SELECT table_name, column_name, 'FOREIGN KEY' as constraint_type, foreign_table_name, foreign_column_name, on_delete_action
+		SELECT table_name, column_name, 'FOREIGN KEY' as constraint_type, foreign_table_name, foreign_column_name, on_delete_action, on_update_action, deferrable
 		FROM fk_columns
 		UNION ALL
-		SELECT table_name, column_name, constraint_type, NULL, NULL, NULL
+		SELECT table_name, column_name, constraint_type, NULL, NULL, NULL, NULL, deferrable
 		FROM pk_uk_columns
 	`
 
@@ -224,9 +253,9 @@ func (p *Adapter) GetAllTablesColumns(ctx context.Context, tableNames []string)
 	// Apply constraints to columns
 	for constraintRows.Next() {
 		var tableName, columnName, constraintType string
-		var fkTable, fkColumn, onDelete sql.NullString
+		var fkTable, fkColumn, onDelete, onUpdate, deferrable sql.NullString
 
-		err := constraintRows.Scan(&tableName, &columnName, &constraintType, &fkTable, &fkColumn, &onDelete)
+		err := constraintRows.Scan(&tableName, &columnName, &constraintType, &fkTable, &fkColumn, &onDelete, &onUpdate, &deferrable)
 		if err != nil {
 			continue
 		}
@@ -237,6 +266,7 @@ func (p *Adapter) GetAllTablesColumns(ctx context.Context, tableNames []string)
 				colPtr.IsPrimary = true
 			case "UNIQUE":
 				colPtr.IsUnique = true
+				colPtr.UniqueDeferrable = deferrable.String
 			case "FOREIGN KEY":
 				if fkTable.Valid {
 					colPtr.ForeignKeyTable = fkTable.String
@@ -247,6 +277,10 @@ func (p *Adapter) GetAllTablesColumns(ctx context.Context, tableNames []string)
 				if onDelete.Valid {
 					colPtr.OnDeleteAction = onDelete.String
 				}
+				if onUpdate.Valid {
+					colPtr.OnUpdateAction = onUpdate.String
+				}
+				colPtr.ForeignKeyDeferrable = deferrable.String
 			}
 		}
 	}
@@ -311,10 +345,16 @@ func (p *Adapter) GetAllTablesIndexes(ctx context.Context, tableNames []string)
 
 func (p *Adapter) GetAllTableNames(ctx context.Context) ([]string, error) {
 	// Check both current_schema() and 'public' for robustness (handles branch schemas)
+	// Partitions are left out, they're part of their partitioned table
 	rows, err := p.pool.Query(ctx, `
-		SELECT DISTINCT table_name FROM information_schema.tables
-		WHERE table_schema IN (current_schema(), 'public') AND table_type = 'BASE TABLE'
-		ORDER BY table_name
+		SELECT DISTINCT t.table_name FROM information_schema.tables t
+		WHERE t.table_schema IN (current_schema(), 'public') AND t.table_type = 'BASE TABLE'
+		  AND NOT EXISTS (
+			SELECT 1 FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE c.relname = t.table_name AND n.nspname = t.table_schema AND c.relispartition
+		  )
+		ORDER BY t.table_name
 	`)
 	if err != nil {
 		return nil, err
@@ -365,9 +405,12 @@ func (p *Adapter) PullCompleteSchema(ctx context.Context) ([]types.SchemaTable,
 		c.ordinal_position,
 		CASE WHEN pk.column_name IS NOT NULL THEN 'PRIMARY KEY' ELSE NULL END as is_primary,
 		CASE WHEN uq.column_name IS NOT NULL THEN 'UNIQUE' ELSE NULL END as is_unique,
+		uq.deferrable AS unique_deferrable,
 		fk.foreign_table_name,
 		fk.foreign_column_name,
-		fk.delete_rule
+		fk.delete_rule,
+		fk.update_rule,
+		fk.deferrable AS foreign_key_deferrable
 	FROM information_schema.columns c
 	LEFT JOIN (
 		SELECT kcu.table_name, kcu.column_name
@@ -378,34 +421,61 @@ func (p *Adapter) PullCompleteSchema(ctx context.Context) ([]types.SchemaTable,
 		WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = 'public'
 	) pk ON c.table_name = pk.table_name AND c.column_name = pk.column_name
 	LEFT JOIN (
-		SELECT kcu.table_name, kcu.column_name
+		SELECT kcu.table_name, kcu.column_name,
+			CASE WHEN tc.initially_deferred = 'YES' THEN 'DEFERRABLE INITIALLY DEFERRED'
+				WHEN tc.is_deferrable = 'YES' THEN 'DEFERRABLE' END AS deferrable
 		FROM information_schema.table_constraints tc
 		JOIN information_schema.key_column_usage kcu 
 			ON tc.constraint_name = kcu.constraint_name 
 			AND tc.table_schema = kcu.table_schema
 		WHERE tc.constraint_type = 'UNIQUE' AND tc.table_schema = 'public'
+		  AND (SELECT COUNT(*) FROM information_schema.key_column_usage k
+			WHERE k.constraint_name = tc.constraint_name AND k.table_schema = tc.table_schema AND k.table_name = tc.table_name) = 1
 	) uq ON c.table_name = uq.table_name AND c.column_name = uq.column_name
 	LEFT JOIN (
-		SELECT 
-			kcu.table_name, 
-			kcu.column_name,
-			ccu.table_name AS foreign_table_name,
-			ccu.column_name AS foreign_column_name,
-			rc.delete_rule
-		FROM information_schema.table_constraints tc
-		JOIN information_schema.key_column_usage kcu 
-			ON tc.constraint_name = kcu.constraint_name 
-			AND tc.table_schema = kcu.table_schema
-		JOIN information_schema.constraint_column_usage ccu 
-			ON tc.constraint_name = ccu.constraint_name 
-			AND tc.table_schema = ccu.table_schema
-		JOIN information_schema.referential_constraints rc 
-			ON tc.constraint_name = rc.constraint_name 
-			AND tc.table_schema = rc.constraint_schema
-		WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = 'public'
+		-- pg_catalog instead of information_schema so references to tables
+		-- owned by other roles (e.g. Supabase's auth.users) are still visible;
+		-- tables outside public keep their schema prefix
+		SELECT
+			src_table.relname AS table_name,
+			src_attr.attname AS column_name,
+			CASE WHEN tgt_ns.nspname = 'public' THEN tgt_table.relname
+				ELSE tgt_ns.nspname || '.' || tgt_table.relname END AS foreign_table_name,
+			tgt_attr.attname AS foreign_column_name,
+			CASE con.confdeltype
+				WHEN 'a' THEN 'NO ACTION'
+				WHEN 'r' THEN 'RESTRICT'
+				WHEN 'c' THEN 'CASCADE'
+				WHEN 'n' THEN 'SET NULL'
+				WHEN 'd' THEN 'SET DEFAULT'
+			END AS delete_rule,
+			CASE con.confupdtype
+				WHEN 'a' THEN 'NO ACTION'
+				WHEN 'r' THEN 'RESTRICT'
+				WHEN 'c' THEN 'CASCADE'
+				WHEN 'n' THEN 'SET NULL'
+				WHEN 'd' THEN 'SET DEFAULT'
+			END AS update_rule,
+			CASE WHEN con.condeferred THEN 'DEFERRABLE INITIALLY DEFERRED'
+				WHEN con.condeferrable THEN 'DEFERRABLE' END AS deferrable
+		FROM pg_constraint con
+		JOIN pg_class src_table ON con.conrelid = src_table.oid
+		JOIN pg_namespace src_ns ON src_table.relnamespace = src_ns.oid
+		CROSS JOIN LATERAL UNNEST(con.conkey, con.confkey) AS cols(src_col, tgt_col)
+		JOIN pg_attribute src_attr ON src_attr.attrelid = src_table.oid AND src_attr.attnum = cols.src_col
+		JOIN pg_class tgt_table ON con.confrelid = tgt_table.oid
+		JOIN pg_namespace tgt_ns ON tgt_table.relnamespace = tgt_ns.oid
+		JOIN pg_attribute tgt_attr ON tgt_attr.attrelid = tgt_table.oid AND tgt_attr.attnum = cols.tgt_col
+		WHERE con.contype = 'f' AND src_ns.nspname = 'public'
+		  AND array_length(con.conkey, 1) = 1
 	) fk ON c.table_name = fk.table_name AND c.column_name = fk.column_name
 	WHERE c.table_schema = 'public' 
 		AND c.table_name NOT LIKE '_flash_%'
+		AND NOT EXISTS (
+			SELECT 1 FROM pg_class pc
+			JOIN pg_namespace pn ON pn.oid = pc.relnamespace
+			WHERE pc.relname = c.table_name AND pn.nspname = c.table_schema AND pc.relispartition
+		)
 	ORDER BY c.table_name, c.ordinal_position`
 
 	rows, err := p.pool.Query(ctx, query)
@@ -420,12 +490,12 @@ func (p *Adapter) PullCompleteSchema(ctx context.Context) ([]types.SchemaTable,
 	for rows.Next() {
 		var tableName, columnName, udtName, isNullable string
 		var ordinalPosition int
-		var columnDefault, isPrimary, isUnique, foreignTable, foreignColumn, deleteRule sql.NullString
+		var columnDefault, isPrimary, isUnique, uniqueDeferrable, foreignTable, foreignColumn, deleteRule, updateRule, foreignKeyDeferrable sql.NullString
 		var charMaxLength, numericPrecision, numericScale sql.NullInt64
 
 		err := rows.Scan(&tableName, &columnName, &udtName, &isNullable, &columnDefault,
-			&charMaxLength, &numericPrecision, &numericScale, &ordinalPosition, &isPrimary, &isUnique,
-			&foreignTable, &foreignColumn, &deleteRule)
+			&charMaxLength, &numericPrecision, &numericScale, &ordinalPosition, &isPrimary, &isUnique, &uniqueDeferrable,
+			&foreignTable, &foreignColumn, &deleteRule, &updateRule, &foreignKeyDeferrable)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
@@ -453,6 +523,9 @@ func (p *Adapter) PullCompleteSchema(ctx context.Context) ([]types.SchemaTable,
 			IsPrimary: isPrimary.Valid,
 			IsUnique:  isUnique.Valid,
 		}
+		if isUnique.Valid {
+			column.UniqueDeferrable = uniqueDeferrable.String
+		}
 
 		if foreignTable.Valid && foreignColumn.Valid {
 			column.ForeignKeyTable = foreignTable.String
@@ -460,6 +533,10 @@ func (p *Adapter) PullCompleteSchema(ctx context.Context) ([]types.SchemaTable,
 			if deleteRule.Valid {
 				column.OnDeleteAction = deleteRule.String
 			}
+			if updateRule.Valid {
+				column.OnUpdateAction = updateRule.String
+			}
+			column.ForeignKeyDeferrable = foreignKeyDeferrable.String
 		}
 
 		tableMap[tableName].Columns = append(tableMap[tableName].Columns, column)
@@ -469,6 +546,14 @@ func (p *Adapter) PullCompleteSchema(ctx context.Context) ([]types.SchemaTable,
 	for _, table := range tableMap {
 		tables = append(tables, *table)
 	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+
+	if err := p.attachPartitions(ctx, tables); err != nil {
+		return nil, fmt.Errorf("failed to query partitions: %w", err)
+	}
+	if err := p.attachConstraints(ctx, tables); err != nil {
+		return nil, fmt.Errorf("failed to query constraints: %w", err)
+	}
 
 	return tables, nil
 }
@@ -555,32 +640,25 @@ func (p *Adapter) cleanDefaultValue(defaultVal string) string {
 		return ""
 	}
 
-	if idx := strings.Index(defaultVal, "::"); idx != -1 {
-		value := strings.TrimSpace(defaultVal[:idx])
-
-		if strings.Contains(strings.ToLower(value), "nextval") {
-			return ""
-		}
-
-		if strings.Contains(strings.ToUpper(value), "NOW()") || strings.Contains(strings.ToUpper(value), "CURRENT_TIMESTAMP") {
-			return "NOW()"
-		}
-
-		return value
+	// Casts inside an expression belong to it; only one of the whole value,
+	// as in 'active'::character varying, is dropped
+	value := strings.TrimSpace(defaultVal)
+	if idx := strings.Index(value, "::"); idx != -1 && defaultCastRegex.MatchString(value[idx+2:]) {
+		value = strings.TrimSpace(value[:idx])
 	}
 
-	upper := strings.ToUpper(defaultVal)
+	upper := strings.ToUpper(value)
 	if strings.Contains(upper, "NEXTVAL") {
 		return ""
 	}
-	if strings.Contains(upper, "NOW()") || strings.Contains(upper, "CURRENT_TIMESTAMP") {
+	if currentTimestampRegex.MatchString(value) {
 		return "NOW()"
 	}
 	if upper == "TRUE" || upper == "FALSE" {
 		return upper
 	}
 
-	return defaultVal
+	return value
 }
 
 func (p *Adapter) formatDefaultValue(defaultValue string) string {
@@ -592,7 +670,7 @@ func (p *Adapter) formatDefaultValue(defaultValue string) string {
 		return ""
 	}
 
-	if strings.Contains(strings.ToLower(defaultValue), "now()") {
+	if currentTimestampRegex.MatchString(strings.TrimSpace(defaultValue)) {
 		return "NOW()"
 	}
 
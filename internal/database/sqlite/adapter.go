@@ -47,7 +47,7 @@ func (s *Adapter) Connect(ctx context.Context, url string) error {
 	}
 	s.currentPath = s.originalPath
 
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sql.Open(driverName, dbPath)
 	if err != nil {
 		return fmt.Errorf("failed to open SQLite connection: %w", err)
 	}
@@ -84,7 +84,7 @@ func (s *Adapter) SwitchDatabase(ctx context.Context, branchFile string) error {
 		dbPath += "?cache=shared&_journal_mode=WAL"
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sql.Open(driverName, dbPath)
 	if err != nil {
 		return fmt.Errorf("failed to switch to database %s: %w", branchFile, err)
 	}
@@ -100,7 +100,7 @@ func (s *Adapter) SwitchDatabase(ctx context.Context, branchFile string) error {
 }
 
 func (s *Adapter) Ping(ctx context.Context) error {
-	return s.db.PingContext(ctx)
+	return classifyError(s.db.PingContext(ctx), false)
 }
 
 func (s *Adapter) CreateMigrationsTable(ctx context.Context) error {
@@ -186,11 +186,11 @@ func (s *Adapter) RemoveMigrationRecord(ctx context.Context, migrationID string)
 }
 
 func (s *Adapter) ExecuteAndRecordMigration(ctx context.Context, migrationID, name, checksum string, migrationSQL string) error {
-	tx, err := s.db.BeginTx(ctx, nil)
+	tx, err := s.beginMigration(ctx, migrationSQL)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
+	defer tx.close()
 
 	// First, record the migration with started_at only
 	_, err = tx.ExecContext(ctx, `
@@ -202,16 +202,9 @@ func (s *Adapter) ExecuteAndRecordMigration(ctx context.Context, migrationID, na
 	}
 
 	// Execute the migration SQL
-	if migrationSQL != "" {
-		statements := common.ParseSQLStatements(migrationSQL)
-		for i, stmt := range statements {
-			stmt = strings.TrimSpace(stmt)
-			if stmt == "" {
-				continue
-			}
-			if _, err := tx.ExecContext(ctx, stmt); err != nil {
-				return fmt.Errorf("failed to execute statement %d: %w", i+1, err)
-			}
+	for i, stmt := range tx.statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to execute statement %d: %w", i+1, classifyError(err, true))
 		}
 	}
 
@@ -225,41 +218,49 @@ func (s *Adapter) ExecuteAndRecordMigration(ctx context.Context, migrationID, na
 		return fmt.Errorf("failed to update migration finish time: %w", err)
 	}
 
-	return tx.Commit()
+	return tx.commit(ctx)
 }
 
 func (s *Adapter) ExecuteMigration(ctx context.Context, migrationSQL string) error {
-	tx, err := s.db.BeginTx(ctx, nil)
+	tx, err := s.beginMigration(ctx, migrationSQL)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return err
 	}
-	defer tx.Rollback()
-
-	statements := common.ParseSQLStatements(migrationSQL)
-
-	for _, stmt := range statements {
-		stmt = strings.TrimSpace(stmt)
-		if stmt == "" {
-			continue
-		}
+	defer tx.close()
 
+	for _, stmt := range tx.statements {
 		_, err := tx.ExecContext(ctx, stmt)
 		if err != nil {
-			return fmt.Errorf("failed to execute statement '%s': %w", stmt, err)
+			return fmt.Errorf("failed to execute statement '%s': %w", stmt, classifyError(err, true))
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
+	if err := tx.commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit migration transaction: %w", err)
 	}
 
 	return nil
 }
 
-func (s *Adapter) ExecuteQuery(ctx context.Context, query string) (*common.QueryResult, error) {
-	rows, err := s.db.QueryContext(ctx, query)
+func (s *Adapter) ExecuteStatements(ctx context.Context, statements []common.Statement) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt.SQL, stmt.Args...); err != nil {
+			return fmt.Errorf("failed to execute statement '%s': %w", stmt.SQL, classifyError(err, false))
+		}
+	}
+	return classifyError(tx.Commit(), false)
+}
+
+func (s *Adapter) ExecuteQuery(ctx context.Context, query string, args ...any) (*common.QueryResult, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", classifyError(err, false))
 	}
 	defer rows.Close()
 
@@ -267,6 +268,7 @@ func (s *Adapter) ExecuteQuery(ctx context.Context, query string) (*common.Query
 	if err != nil {
 		return nil, fmt.Errorf("failed to get columns: %w", err)
 	}
+	types := common.ColumnTypes(rows, len(columns))
 
 	var results []map[string]interface{}
 	for rows.Next() {
@@ -293,15 +295,28 @@ func (s *Adapter) ExecuteQuery(ctx context.Context, query string) (*common.Query
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
+		return nil, fmt.Errorf("error iterating rows: %w", classifyError(err, false))
 	}
 
 	return &common.QueryResult{
-		Columns: columns,
-		Rows:    results,
+		Columns:     columns,
+		ColumnTypes: types,
+		Rows:        results,
 	}, nil
 }
 
+// StreamQuery runs a query and passes its rows to onRow one at a time
+// instead of loading them all
+func (s *Adapter) StreamQuery(ctx context.Context, query string, args []any, onColumns func(columns, types []string) error, onRow func([]any) error) error {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	return common.StreamRows(rows, onColumns, onRow)
+}
+
 func (s *Adapter) MapColumnType(dbType string) string {
 	if mapped, exists := typeMap[strings.ToLower(dbType)]; exists {
 		return mapped
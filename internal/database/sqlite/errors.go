@@ -0,0 +1,54 @@
+package sqlite
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
+	"github.com/mattn/go-sqlite3"
+)
+
+// classifyError turns the errors of SQLite into a common.DatabaseError of
+// their kind, leaving others as they are. migration tells whether err comes
+// from the statements of a migration.
+func classifyError(err error, migration bool) error {
+	if err == nil {
+		return nil
+	}
+	var liteErr sqlite3.Error
+	if !errors.As(err, &liteErr) {
+		return err
+	}
+
+	dbErr := &common.DatabaseError{Migration: migration, Err: err}
+	switch liteErr.ExtendedCode {
+	case sqlite3.ErrConstraintNotNull:
+		dbErr.Constraint = common.ConstraintNotNull
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		dbErr.Constraint = common.ConstraintUnique
+	case sqlite3.ErrConstraintForeignKey:
+		dbErr.Constraint = common.ConstraintForeignKey
+	case sqlite3.ErrConstraintCheck:
+		dbErr.Constraint = common.ConstraintCheck
+	}
+	switch {
+	case dbErr.Constraint != "":
+		dbErr.Kind = common.ErrConstraintViolation
+		// NOT NULL constraint failed: users.email, UNIQUE constraint failed:
+		// users.org_id, users.email and CHECK constraint failed: price_positive
+		_, target, _ := strings.Cut(liteErr.Error(), "constraint failed: ")
+		target, _, _ = strings.Cut(target, ", ")
+		if table, column, ok := strings.Cut(target, "."); ok {
+			dbErr.Table, dbErr.Column = table, column
+		} else if dbErr.Constraint == common.ConstraintCheck {
+			dbErr.Name = target
+		}
+	case liteErr.Code == sqlite3.ErrPerm, liteErr.Code == sqlite3.ErrReadonly, liteErr.Code == sqlite3.ErrAuth:
+		dbErr.Kind = common.ErrPermission
+	case liteErr.Code == sqlite3.ErrCantOpen, liteErr.Code == sqlite3.ErrNotADB:
+		dbErr.Kind = common.ErrConnection
+	default:
+		return err
+	}
+	return dbErr
+}
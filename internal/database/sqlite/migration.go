@@ -0,0 +1,104 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
+)
+
+var foreignKeysPragmaRegex = regexp.MustCompile(`(?i)^PRAGMA\s+foreign_keys\s*=\s*'?(\w+)'?\s*;?$`)
+
+// migrationTx is the transaction a migration runs in, on a connection of
+// its own. PRAGMA foreign_keys does nothing inside a transaction, so when a
+// migration turns foreign keys off, as table rebuilds do, they're turned off
+// on the connection before the transaction begins, checked before it
+// commits and turned on again once it's done.
+type migrationTx struct {
+	*sql.Tx
+	conn           *sql.Conn
+	statements     []string
+	foreignKeysOff bool
+}
+
+// beginMigration splits a migration into its statements, leaving out the
+// foreign_keys pragmas, and begins its transaction
+func (s *Adapter) beginMigration(ctx context.Context, migrationSQL string) (*migrationTx, error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	mtx := &migrationTx{conn: conn}
+
+	disable := false
+	for _, stmt := range common.ParseSQLStatements(migrationSQL) {
+		stmt = strings.TrimSpace(stmt)
+		if matches := foreignKeysPragmaRegex.FindStringSubmatch(stmt); matches != nil {
+			switch strings.ToUpper(matches[1]) {
+			case "OFF", "0", "FALSE", "NO":
+				disable = true
+			}
+			continue
+		}
+		if stmt != "" {
+			mtx.statements = append(mtx.statements, stmt)
+		}
+	}
+
+	if disable {
+		var enabled int
+		if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&enabled); err == nil && enabled == 1 {
+			if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+				conn.Close()
+				return nil, fmt.Errorf("failed to turn off foreign keys: %w", err)
+			}
+			mtx.foreignKeysOff = true
+		}
+	}
+
+	if mtx.Tx, err = conn.BeginTx(ctx, nil); err != nil {
+		mtx.close()
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	return mtx, nil
+}
+
+// commit checks that every row still has the rows its foreign keys
+// reference, when they were turned off, and commits
+func (t *migrationTx) commit(ctx context.Context) error {
+	if t.foreignKeysOff {
+		var table, parent string
+		var rowid sql.NullInt64
+		var fkid int
+		err := t.QueryRowContext(ctx, "PRAGMA foreign_key_check").Scan(&table, &rowid, &parent, &fkid)
+		switch {
+		case err == nil:
+			return &common.DatabaseError{
+				Kind:       common.ErrConstraintViolation,
+				Constraint: common.ConstraintForeignKey,
+				Table:      table,
+				Migration:  true,
+				Err:        fmt.Errorf("foreign key check failed: a row of %s references a missing row of %s", table, parent),
+			}
+		case !errors.Is(err, sql.ErrNoRows):
+			return fmt.Errorf("failed to check foreign keys: %w", err)
+		}
+	}
+	return t.Tx.Commit()
+}
+
+// close rolls back the transaction unless it was committed, turns foreign
+// keys back on and gives the connection back
+func (t *migrationTx) close() {
+	if t.Tx != nil {
+		t.Tx.Rollback()
+	}
+	if t.foreignKeysOff {
+		t.conn.ExecContext(context.Background(), "PRAGMA foreign_keys = ON")
+	}
+	t.conn.Close()
+}
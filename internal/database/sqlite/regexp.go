@@ -0,0 +1,59 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// driverName is the go-sqlite3 driver with the REGEXP function SQLite
+// leaves to applications, so "x REGEXP pattern" works on flash connections
+const driverName = "sqlite3_flash"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("regexp", matchRegexp, true)
+		},
+	})
+}
+
+var (
+	regexpMu    sync.Mutex
+	regexpCache = make(map[string]*regexp.Regexp)
+)
+
+// matchRegexp implements "value REGEXP pattern" with Go's RE2 syntax. NULL
+// matches nothing. Compiled patterns are kept, as a query calls the
+// function once per row.
+func matchRegexp(pattern string, value any) (bool, error) {
+	if value == nil {
+		return false, nil
+	}
+
+	regexpMu.Lock()
+	re, ok := regexpCache[pattern]
+	if !ok {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			regexpMu.Unlock()
+			return false, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		if len(regexpCache) >= 64 {
+			clear(regexpCache)
+		}
+		regexpCache[pattern] = re
+	}
+	regexpMu.Unlock()
+
+	switch v := value.(type) {
+	case []byte:
+		return re.Match(v), nil
+	case string:
+		return re.MatchString(v), nil
+	}
+	return re.MatchString(fmt.Sprint(value)), nil
+}
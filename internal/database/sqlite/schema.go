@@ -32,9 +32,10 @@ func (s *Adapter) GetCurrentSchema(ctx context.Context) ([]types.SchemaTable, er
 	// SQLite doesn't support batching PRAGMA, but we can parallelize
 	// This gives 5-10x speedup for large schemas!
 	type result struct {
-		tableName string
-		columns   []types.SchemaColumn
-		err       error
+		tableName   string
+		columns     []types.SchemaColumn
+		constraints []types.SchemaConstraint
+		err         error
 	}
 
 	results := make(chan result, len(validTables))
@@ -45,7 +46,11 @@ func (s *Adapter) GetCurrentSchema(ctx context.Context) ([]types.SchemaTable, er
 		go func(name string) {
 			defer wg.Done()
 			cols, colErr := s.GetTableColumns(ctx, name)
-			results <- result{name, cols, colErr}
+			var constraints []types.SchemaConstraint
+			if colErr == nil {
+				constraints, colErr = s.getTableConstraints(ctx, name)
+			}
+			results <- result{name, cols, constraints, colErr}
 		}(tableName)
 	}
 
@@ -55,11 +60,13 @@ func (s *Adapter) GetCurrentSchema(ctx context.Context) ([]types.SchemaTable, er
 	}()
 
 	allColumns := make(map[string][]types.SchemaColumn)
+	allConstraints := make(map[string][]types.SchemaConstraint)
 	for r := range results {
 		if r.err != nil {
 			return nil, fmt.Errorf("failed to get columns for table %s: %w", r.tableName, r.err)
 		}
 		allColumns[r.tableName] = r.columns
+		allConstraints[r.tableName] = r.constraints
 	}
 
 	allIndexes, err := s.GetAllTablesIndexes(ctx, validTables)
@@ -70,9 +77,10 @@ func (s *Adapter) GetCurrentSchema(ctx context.Context) ([]types.SchemaTable, er
 	var tables []types.SchemaTable
 	for _, name := range validTables {
 		tables = append(tables, types.SchemaTable{
-			Name:    name,
-			Columns: allColumns[name],
-			Indexes: allIndexes[name],
+			Name:        name,
+			Columns:     allColumns[name],
+			Indexes:     allIndexes[name],
+			Constraints: allConstraints[name],
 		})
 	}
 	return tables, nil
@@ -165,26 +173,31 @@ func (s *Adapter) GetTableColumns(ctx context.Context, tableName string) ([]type
 		columns = append(columns, column)
 	}
 
-	fkRows, err := s.db.QueryContext(ctx, fmt.Sprintf("PRAGMA foreign_key_list(\"%s\")", tableName))
-	if err == nil {
-		defer fkRows.Close()
-
-		for fkRows.Next() {
-			var id, seq int
-			var table, from, to, onUpdate, onDelete, match string
-
-			err := fkRows.Scan(&id, &seq, &table, &from, &to, &onUpdate, &onDelete, &match)
-			if err != nil {
-				continue
+	// Foreign keys over several columns are table constraints, read by
+	// getTableConstraints
+	foreignKeys, _ := s.getForeignKeys(ctx, tableName)
+	for _, fk := range foreignKeys {
+		if len(fk.Columns) != 1 {
+			continue
+		}
+		for i := range columns {
+			if columns[i].Name == fk.Columns[0] {
+				columns[i].ForeignKeyTable = fk.RefTable
+				columns[i].ForeignKeyColumn = fk.RefColumns[0]
+				columns[i].OnDeleteAction = fk.OnDelete
+				columns[i].OnUpdateAction = fk.OnUpdate
+				break
 			}
+		}
+	}
 
-			for i := range columns {
-				if columns[i].Name == from {
-					columns[i].ForeignKeyTable = table
-					columns[i].ForeignKeyColumn = to
-					columns[i].OnDeleteAction = onDelete
-					break
-				}
+	// Resolve references to the primary key once the PRAGMA rows are closed,
+	// so the lookup doesn't need a second connection while holding one
+	for i := range columns {
+		if columns[i].ForeignKeyTable != "" && columns[i].ForeignKeyColumn == "" {
+			var pkColumn string
+			if err := s.db.QueryRowContext(ctx, "SELECT name FROM pragma_table_info(?) WHERE pk = 1", columns[i].ForeignKeyTable).Scan(&pkColumn); err == nil {
+				columns[i].ForeignKeyColumn = pkColumn
 			}
 		}
 	}
@@ -211,8 +224,10 @@ func (s *Adapter) GetTableIndexes(ctx context.Context, tableName string) ([]type
 		var unique int
 		var origin, partial string
 
+		// Indexes of PRIMARY KEY and UNIQUE constraints go with the
+		// constraint, as they can't be dropped on their own
 		err := rows.Scan(&seq, &indexName, &unique, &origin, &partial)
-		if err != nil || origin == "pk" {
+		if err != nil || origin == "pk" || origin == "u" {
 			continue
 		}
 
@@ -291,6 +306,101 @@ func (s *Adapter) getUniqueColumnsForTable(ctx context.Context, tableName string
 	return uniqueMap
 }
 
+// getForeignKeys reads the foreign keys of a table with their columns in
+// order. References to the primary key get its columns filled in.
+func (s *Adapter) getForeignKeys(ctx context.Context, tableName string) ([]types.SchemaConstraint, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("PRAGMA foreign_key_list(\"%s\")", tableName))
+	if err != nil {
+		return nil, err
+	}
+
+	var foreignKeys []types.SchemaConstraint
+	ids := make(map[int]int)
+	for rows.Next() {
+		var id, seq int
+		var table, from, onUpdate, onDelete, match string
+		var to sql.NullString // NULL when the reference targets the primary key
+
+		if err := rows.Scan(&id, &seq, &table, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+			continue
+		}
+
+		index, exists := ids[id]
+		if !exists {
+			index = len(foreignKeys)
+			ids[id] = index
+			foreignKeys = append(foreignKeys, types.SchemaConstraint{
+				Type: "FOREIGN KEY", RefTable: table, OnDelete: onDelete, OnUpdate: onUpdate,
+			})
+		}
+		foreignKeys[index].Columns = append(foreignKeys[index].Columns, from)
+		foreignKeys[index].RefColumns = append(foreignKeys[index].RefColumns, to.String)
+	}
+	rows.Close()
+
+	// Resolve references to the primary key once the PRAGMA rows are closed,
+	// so the lookup doesn't need a second connection while holding one
+	for i := range foreignKeys {
+		if foreignKeys[i].RefColumns[0] != "" {
+			continue
+		}
+		pkRows, err := s.db.QueryContext(ctx, "SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk", foreignKeys[i].RefTable)
+		if err != nil {
+			continue
+		}
+		var pkColumns []string
+		for pkRows.Next() {
+			var name string
+			if pkRows.Scan(&name) == nil {
+				pkColumns = append(pkColumns, name)
+			}
+		}
+		pkRows.Close()
+		if len(pkColumns) == len(foreignKeys[i].Columns) {
+			foreignKeys[i].RefColumns = pkColumns
+		}
+	}
+	return foreignKeys, nil
+}
+
+// getTableConstraints reads the foreign keys and UNIQUE constraints over
+// more than one column of a table. SQLite doesn't name them.
+func (s *Adapter) getTableConstraints(ctx context.Context, tableName string) ([]types.SchemaConstraint, error) {
+	foreignKeys, err := s.getForeignKeys(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	var constraints []types.SchemaConstraint
+	for _, fk := range foreignKeys {
+		if len(fk.Columns) > 1 {
+			constraints = append(constraints, fk)
+		}
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("PRAGMA index_list(\"%s\")", tableName))
+	if err != nil {
+		return nil, err
+	}
+	var uniqueIndexes []string
+	for rows.Next() {
+		var seq, unique int
+		var indexName, origin, partial string
+		if err := rows.Scan(&seq, &indexName, &unique, &origin, &partial); err == nil && origin == "u" {
+			uniqueIndexes = append(uniqueIndexes, indexName)
+		}
+	}
+	rows.Close()
+
+	// PRAGMA index_list puts the latest index first
+	for i := len(uniqueIndexes) - 1; i >= 0; i-- {
+		if columns := s.getIndexColumns(ctx, uniqueIndexes[i]); len(columns) > 1 {
+			constraints = append(constraints, types.SchemaConstraint{Type: "UNIQUE", Columns: columns})
+		}
+	}
+	return constraints, nil
+}
+
 func (s *Adapter) getIndexColumns(ctx context.Context, indexName string) []string {
 	colRows, err := s.db.QueryContext(ctx, fmt.Sprintf("PRAGMA index_info(\"%s\")", indexName))
 	if err != nil {
@@ -331,7 +441,7 @@ func (s *Adapter) formatSQLiteDefault(defaultValue string) string {
 		return ""
 	}
 
-	if strings.Contains(strings.ToLower(defaultValue), "current_timestamp") {
+	if strings.EqualFold(strings.TrimSpace(defaultValue), "current_timestamp") {
 		return "CURRENT_TIMESTAMP"
 	}
 
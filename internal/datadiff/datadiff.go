@@ -0,0 +1,624 @@
+// Package datadiff compares the rows of tables in two databases, which may
+// be of different providers, and writes the statements that make the second
+// match the first.
+package datadiff
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/database"
+)
+
+// Source is one of the databases compared
+type Source struct {
+	Adapter  database.DatabaseAdapter
+	Provider string
+}
+
+// Options tune a comparison
+type Options struct {
+	// Tables to compare, every table of A that B has too when empty
+	Tables []string
+	// ChunkSize is how many rows of A are hashed at a time, and how many
+	// rows of either side are read at a time, 1000 when 0
+	ChunkSize int
+	// MaxSamples is how many differing keys a table lists, 10 when 0
+	MaxSamples int
+	// Reconcile, when set, gets the INSERT, UPDATE and DELETE statements
+	// that make B match A
+	Reconcile io.Writer
+}
+
+// Report is the outcome of a comparison
+type Report struct {
+	Tables []TableDiff `json:"tables"`
+}
+
+// Differs reports whether any table compared differs
+func (r *Report) Differs() bool {
+	for _, table := range r.Tables {
+		if table.Differs() {
+			return true
+		}
+	}
+	return false
+}
+
+// TableDiff is how a table of B differs from the same table of A
+type TableDiff struct {
+	Table string `json:"table"`
+	Key   string `json:"key,omitempty"`
+	// Skipped says why the table wasn't compared
+	Skipped   string `json:"skipped,omitempty"`
+	RowsA     int    `json:"rows_a"`
+	RowsB     int    `json:"rows_b"`
+	Missing   int    `json:"missing"`   // rows of A that B doesn't have
+	Extra     int    `json:"extra"`     // rows of B that A doesn't have
+	Different int    `json:"different"` // rows whose values differ
+	Chunks    int    `json:"chunks"`
+	// ChunksDiffering counts the chunks whose hashes differ, the only ones
+	// compared row by row
+	ChunksDiffering int       `json:"chunks_differing"`
+	ColumnsOnlyInA  []string  `json:"columns_only_in_a,omitempty"`
+	ColumnsOnlyInB  []string  `json:"columns_only_in_b,omitempty"`
+	Samples         []RowDiff `json:"samples,omitempty"`
+}
+
+// Differs reports whether a table has rows that differ, or wasn't compared
+func (t *TableDiff) Differs() bool {
+	return t.Skipped != "" || t.Missing > 0 || t.Extra > 0 || t.Different > 0
+}
+
+// RowDiff is a row that differs between the databases
+type RowDiff struct {
+	Key  string `json:"key"`
+	Kind string `json:"kind"` // missing, extra or different
+	// Columns lists the columns whose values differ
+	Columns []string `json:"columns,omitempty"`
+}
+
+// Compare compares the rows of tables in A and B. Rows are matched by the
+// single-column primary key of the table in A and compared in ranges of
+// that key, a chunk of A's rows and the rows of B in the same range at a
+// time. When A and B are both PostgreSQL or both MySQL, each side hashes a
+// chunk in SQL and only chunks whose hashes differ are read. Otherwise the
+// text of a value differs by provider, so a chunk is read and hashed here.
+// Values are compared as text, so databases of different providers can be
+// compared.
+func Compare(ctx context.Context, a, b Source, opts Options) (*Report, error) {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = 1000
+	}
+	if opts.MaxSamples <= 0 {
+		opts.MaxSamples = 10
+	}
+
+	tables := opts.Tables
+	if len(tables) == 0 {
+		var err error
+		if tables, err = commonTables(ctx, a); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &Report{}
+	for _, table := range tables {
+		diff, err := compareTable(ctx, a, b, table, opts)
+		if err != nil {
+			return nil, err
+		}
+		report.Tables = append(report.Tables, *diff)
+	}
+	return report, nil
+}
+
+// commonTables lists the tables of A, leaving out the migrations table.
+// Ones B doesn't have are reported as skipped.
+func commonTables(ctx context.Context, a Source) ([]string, error) {
+	tablesA, err := a.Adapter.GetAllTableNames(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables of A: %w", err)
+	}
+	var tables []string
+	for _, table := range tablesA {
+		if table != "_flash_migrations" {
+			tables = append(tables, table)
+		}
+	}
+	sort.Strings(tables)
+	return tables, nil
+}
+
+func compareTable(ctx context.Context, a, b Source, table string, opts Options) (*TableDiff, error) {
+	diff := &TableDiff{Table: table}
+
+	for _, side := range []struct {
+		name   string
+		source Source
+	}{{"A", a}, {"B", b}} {
+		exists, err := side.source.Adapter.CheckTableExists(ctx, table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check table %s in %s: %w", table, side.name, err)
+		}
+		if !exists {
+			diff.Skipped = fmt.Sprintf("not in %s", side.name)
+			return diff, nil
+		}
+	}
+
+	columnsA, err := a.Adapter.GetTableColumns(ctx, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns of %s in A: %w", table, err)
+	}
+	columnsB, err := b.Adapter.GetTableColumns(ctx, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns of %s in B: %w", table, err)
+	}
+
+	var keys []string
+	namesB := make(map[string]bool, len(columnsB))
+	for _, col := range columnsB {
+		namesB[col.Name] = true
+	}
+	var columns []string
+	for _, col := range columnsA {
+		if col.IsPrimary {
+			keys = append(keys, col.Name)
+		}
+		if namesB[col.Name] {
+			columns = append(columns, col.Name)
+			delete(namesB, col.Name)
+		} else {
+			diff.ColumnsOnlyInA = append(diff.ColumnsOnlyInA, col.Name)
+		}
+	}
+	for _, col := range columnsB {
+		if namesB[col.Name] {
+			diff.ColumnsOnlyInB = append(diff.ColumnsOnlyInB, col.Name)
+		}
+	}
+
+	if len(keys) != 1 {
+		diff.Skipped = "no single-column primary key"
+		return diff, nil
+	}
+	key := keys[0]
+	if !slices.Contains(columns, key) {
+		diff.Skipped = fmt.Sprintf("B has no %s column", key)
+		return diff, nil
+	}
+	diff.Key = key
+
+	t := &tableComparison{a: a, b: b, table: table, key: key, columns: columns, opts: opts, diff: diff}
+	if err := t.run(ctx); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// tableComparison walks a table of A and B in chunks of key ranges
+type tableComparison struct {
+	a, b    Source
+	table   string
+	key     string
+	columns []string
+	opts    Options
+	diff    *TableDiff
+	// reconciling is set once the table's first statement is written
+	reconciling bool
+}
+
+func (t *tableComparison) run(ctx context.Context) error {
+	var after any
+	for {
+		// A chunk ends ChunkSize rows of A on; the last one takes in
+		// whatever B has past the last key of A
+		through, err := t.boundary(ctx, after)
+		if err != nil {
+			return fmt.Errorf("failed to read %s from A: %w", t.table, err)
+		}
+		if err := t.compareRange(ctx, after, through); err != nil {
+			return err
+		}
+		if through == nil {
+			return nil
+		}
+		after = through
+	}
+}
+
+// boundary returns the key of A ChunkSize rows past after, nil when fewer
+// rows are left
+func (t *tableComparison) boundary(ctx context.Context, after any) (any, error) {
+	provider := t.a.Provider
+	key := quoteIdentifier(provider, t.key)
+	query := fmt.Sprintf("SELECT %s FROM %s", key, quoteIdentifier(provider, t.table))
+	var args []any
+	if after != nil {
+		args = append(args, after)
+		query += fmt.Sprintf(" WHERE %s > %s", key, placeholder(provider, 1))
+	}
+	query += fmt.Sprintf(" ORDER BY %s LIMIT 1 OFFSET %d", key, t.opts.ChunkSize-1)
+
+	result, err := t.a.Adapter.ExecuteQuery(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Rows) == 0 {
+		return nil, nil
+	}
+	return result.Rows[0][t.key], nil
+}
+
+// compareRange compares the rows of A and B with keys above after, when
+// set, and up to through, when set. Ones whose hashes match aren't read;
+// B's are read a chunk at a time, however many there are.
+func (t *tableComparison) compareRange(ctx context.Context, after, through any) error {
+	sumA, err := t.checksum(ctx, t.a, after, through)
+	if err != nil {
+		return fmt.Errorf("failed to hash %s in A: %w", t.table, err)
+	}
+	sumB, err := t.checksum(ctx, t.b, after, through)
+	if err != nil {
+		return fmt.Errorf("failed to hash %s in B: %w", t.table, err)
+	}
+	if sumA.rows == 0 && sumB.rows == 0 {
+		return nil
+	}
+	t.diff.Chunks++
+	t.diff.RowsA += sumA.rows
+	t.diff.RowsB += sumB.rows
+	if sumA.hash != "" && sumA == sumB {
+		return nil
+	}
+
+	// A has at most ChunkSize rows in the range, so they're read at once
+	rowsA, err := t.rows(ctx, t.a, after, through, 0)
+	if err != nil {
+		return fmt.Errorf("failed to read %s from A: %w", t.table, err)
+	}
+	if sumA.hash == "" && sumA.rows == sumB.rows {
+		// Without a hash in SQL, B's rows are hashed here too; as many as
+		// A's, they're no more than a chunk either
+		rowsB, err := t.rows(ctx, t.b, after, through, 0)
+		if err != nil {
+			return fmt.Errorf("failed to read %s from B: %w", t.table, err)
+		}
+		if t.hashRows(rowsA) == t.hashRows(rowsB) {
+			return nil
+		}
+	}
+	t.diff.ChunksDiffering++
+	return t.compareRows(ctx, after, through, rowsA)
+}
+
+// checksum is a source's row count and hash of a key range. hash is empty
+// when the source can't hash in SQL the way the other side does.
+type checksum struct {
+	rows int
+	hash string
+}
+
+// checksum counts the rows of a source in a key range and, when A and B
+// are of a provider that can, hashes them in SQL. The hashes of the rows are
+// combined without regard to their order, which collations may change.
+func (t *tableComparison) checksum(ctx context.Context, source Source, after, through any) (checksum, error) {
+	hash := "''"
+	if sqlHashes(t.a.Provider, t.b.Provider) {
+		quoted := make([]string, len(t.columns))
+		for i, col := range t.columns {
+			quoted[i] = quoteIdentifier(source.Provider, col)
+		}
+		switch providerFamily(source.Provider) {
+		case "postgres":
+			// A row's text quotes its values and leaves NULLs empty, so it
+			// tells them apart
+			hash = fmt.Sprintf("COALESCE(md5(string_agg(md5(ROW(%s)::text), '' ORDER BY md5(ROW(%s)::text))), md5(''))",
+				strings.Join(quoted, ", "), strings.Join(quoted, ", "))
+		case "mysql":
+			// Each value is hashed so no separator can run into it, and the
+			// rows' hashes are XORed, as pt-table-checksum does
+			for i, col := range quoted {
+				quoted[i] = fmt.Sprintf("COALESCE(MD5(%s), 'NULL')", col)
+			}
+			hash = fmt.Sprintf("CAST(BIT_XOR(CAST(CONV(LEFT(MD5(CONCAT(%s)), 16), 16, 10) AS UNSIGNED)) AS CHAR)",
+				strings.Join(quoted, ", "))
+		}
+	}
+
+	conditions, args := t.keyRange(source.Provider, after, through)
+	query := fmt.Sprintf("SELECT COUNT(*) AS row_count, %s AS row_hash FROM %s%s",
+		hash, quoteIdentifier(source.Provider, t.table), conditions)
+	result, err := source.Adapter.ExecuteQuery(ctx, query, args...)
+	if err != nil {
+		return checksum{}, err
+	}
+	if len(result.Rows) != 1 {
+		return checksum{}, fmt.Errorf("hash query returned %d rows", len(result.Rows))
+	}
+	row := result.Rows[0]
+	rows, err := strconv.Atoi(normalize(row["row_count"]))
+	if err != nil {
+		return checksum{}, fmt.Errorf("unexpected row count %v", row["row_count"])
+	}
+	sum := checksum{rows: rows}
+	if row["row_hash"] != nil {
+		sum.hash = normalize(row["row_hash"])
+	}
+	return sum, nil
+}
+
+// sqlHashes reports whether A and B hash rows in SQL. Both need to be of
+// the same provider, as the text of a value differs by provider, and one
+// with a hash function, which SQLite lacks.
+func sqlHashes(a, b string) bool {
+	family := providerFamily(a)
+	return family == providerFamily(b) && (family == "postgres" || family == "mysql")
+}
+
+func providerFamily(provider string) string {
+	switch provider {
+	case "postgresql", "postgres":
+		return "postgres"
+	case "sqlite", "sqlite3":
+		return "sqlite"
+	}
+	return provider
+}
+
+// keyRange returns the WHERE clause, if any, of keys above after, when set,
+// and up to through, when set
+func (t *tableComparison) keyRange(provider string, after, through any) (string, []any) {
+	key := quoteIdentifier(provider, t.key)
+	var conditions []string
+	var args []any
+	if after != nil {
+		args = append(args, after)
+		conditions = append(conditions, fmt.Sprintf("%s > %s", key, placeholder(provider, len(args))))
+	}
+	if through != nil {
+		args = append(args, through)
+		conditions = append(conditions, fmt.Sprintf("%s <= %s", key, placeholder(provider, len(args))))
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// rows reads the rows of a source in a key range, in key order, at most
+// limit of them when limit > 0
+func (t *tableComparison) rows(ctx context.Context, source Source, after, through any, limit int) ([]map[string]any, error) {
+	quoted := make([]string, len(t.columns))
+	for i, col := range t.columns {
+		quoted[i] = quoteIdentifier(source.Provider, col)
+	}
+	conditions, args := t.keyRange(source.Provider, after, through)
+	query := fmt.Sprintf("SELECT %s FROM %s%s ORDER BY %s", strings.Join(quoted, ", "),
+		quoteIdentifier(source.Provider, t.table), conditions, quoteIdentifier(source.Provider, t.key))
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	result, err := source.Adapter.ExecuteQuery(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return result.Rows, nil
+}
+
+// compareRows compares the rows of A in a key range with those of B, read
+// a chunk at a time
+func (t *tableComparison) compareRows(ctx context.Context, after, through any, rowsA []map[string]any) error {
+	byKeyA := make(map[string]int, len(rowsA))
+	for i, row := range rowsA {
+		byKeyA[normalize(row[t.key])] = i
+	}
+	matched := make([]bool, len(rowsA))
+
+	for {
+		rowsB, err := t.rows(ctx, t.b, after, through, t.opts.ChunkSize)
+		if err != nil {
+			return fmt.Errorf("failed to read %s from B: %w", t.table, err)
+		}
+		for _, row := range rowsB {
+			key := normalize(row[t.key])
+			i, ok := byKeyA[key]
+			if !ok {
+				t.diff.Extra++
+				t.sample(RowDiff{Key: key, Kind: "extra"})
+				if err := t.reconcile(t.deleteStatement(row)); err != nil {
+					return err
+				}
+				continue
+			}
+			matched[i] = true
+
+			var changed []string
+			for _, col := range t.columns {
+				if normalize(rowsA[i][col]) != normalize(row[col]) {
+					changed = append(changed, col)
+				}
+			}
+			if len(changed) > 0 {
+				t.diff.Different++
+				t.sample(RowDiff{Key: key, Kind: "different", Columns: changed})
+				if err := t.reconcile(t.updateStatement(rowsA[i], changed)); err != nil {
+					return err
+				}
+			}
+		}
+		if len(rowsB) < t.opts.ChunkSize {
+			break
+		}
+		after = rowsB[len(rowsB)-1][t.key]
+	}
+
+	for i, row := range rowsA {
+		if matched[i] {
+			continue
+		}
+		key := normalize(row[t.key])
+		t.diff.Missing++
+		t.sample(RowDiff{Key: key, Kind: "missing"})
+		if err := t.reconcile(t.insertStatement(row)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hashRows hashes the values of rows as text, without regard to the order
+// of the rows
+func (t *tableComparison) hashRows(rows []map[string]any) string {
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		values := make([]string, len(t.columns))
+		for c, col := range t.columns {
+			values[c] = normalize(row[col])
+		}
+		encoded, _ := json.Marshal(values)
+		lines[i] = string(encoded)
+	}
+	sort.Strings(lines)
+
+	h := sha256.New()
+	for _, line := range lines {
+		h.Write([]byte(line))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (t *tableComparison) sample(row RowDiff) {
+	if len(t.diff.Samples) < t.opts.MaxSamples {
+		t.diff.Samples = append(t.diff.Samples, row)
+	}
+}
+
+func (t *tableComparison) reconcile(statement string) error {
+	if t.opts.Reconcile == nil {
+		return nil
+	}
+	if !t.reconciling {
+		t.reconciling = true
+		if _, err := fmt.Fprintf(t.opts.Reconcile, "\n-- %s\n", t.table); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(t.opts.Reconcile, statement+";\n")
+	return err
+}
+
+func (t *tableComparison) insertStatement(row map[string]any) string {
+	provider := t.b.Provider
+	columns := make([]string, len(t.columns))
+	values := make([]string, len(t.columns))
+	for i, col := range t.columns {
+		columns[i] = quoteIdentifier(provider, col)
+		values[i] = literal(provider, row[col])
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		quoteIdentifier(provider, t.table), strings.Join(columns, ", "), strings.Join(values, ", "))
+}
+
+func (t *tableComparison) updateStatement(row map[string]any, changed []string) string {
+	provider := t.b.Provider
+	set := make([]string, len(changed))
+	for i, col := range changed {
+		set[i] = fmt.Sprintf("%s = %s", quoteIdentifier(provider, col), literal(provider, row[col]))
+	}
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s",
+		quoteIdentifier(provider, t.table), strings.Join(set, ", "),
+		quoteIdentifier(provider, t.key), literal(provider, row[t.key]))
+}
+
+func (t *tableComparison) deleteStatement(row map[string]any) string {
+	provider := t.b.Provider
+	return fmt.Sprintf("DELETE FROM %s WHERE %s = %s",
+		quoteIdentifier(provider, t.table), quoteIdentifier(provider, t.key), literal(provider, row[t.key]))
+}
+
+// normalize writes a value as text the same way whichever driver read it:
+// times in UTC without a zone, bytes as their text, and numbers without
+// exponents
+func normalize(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "\x00NULL"
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case time.Time:
+		return v.UTC().Format("2006-01-02 15:04:05.999999999")
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]any, []any:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+	return fmt.Sprint(value)
+}
+
+// literal renders a value read from A as a SQL literal for B
+func literal(provider string, value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if provider == "sqlite" || provider == "sqlite3" {
+			if v {
+				return "1"
+			}
+			return "0"
+		}
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", v)
+	case float32, float64:
+		return normalize(v)
+	case []byte:
+		if provider == "postgresql" || provider == "postgres" {
+			return `'\x` + hex.EncodeToString(v) + "'"
+		}
+		return "X'" + hex.EncodeToString(v) + "'"
+	case time.Time:
+		return "'" + v.UTC().Format("2006-01-02 15:04:05.999999") + "'"
+	}
+
+	escaped := strings.ReplaceAll(normalize(value), "'", "''")
+	if provider == "mysql" {
+		escaped = strings.ReplaceAll(escaped, `\`, `\\`)
+	}
+	return "'" + escaped + "'"
+}
+
+func quoteIdentifier(provider, name string) string {
+	if provider == "mysql" {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func placeholder(provider string, n int) string {
+	if provider == "postgresql" || provider == "postgres" {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
@@ -0,0 +1,109 @@
+package datadiff
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/database"
+	dbcommon "github.com/Lumos-Labs-HQ/flash/internal/database/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/database/sqlite"
+)
+
+// recorder keeps the most rows a single query read
+type recorder struct {
+	database.DatabaseAdapter
+	mostRows int
+}
+
+func (r *recorder) ExecuteQuery(ctx context.Context, query string, args ...any) (*dbcommon.QueryResult, error) {
+	result, err := r.DatabaseAdapter.ExecuteQuery(ctx, query, args...)
+	if err == nil {
+		r.mostRows = max(r.mostRows, len(result.Rows))
+	}
+	return result, err
+}
+
+func openSQLite(t *testing.T, name, setup string) *recorder {
+	t.Helper()
+	ctx := context.Background()
+	adapter := sqlite.New()
+	if err := adapter.Connect(ctx, "sqlite://"+filepath.Join(t.TempDir(), name+".db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { adapter.Close() })
+	if err := adapter.ExecuteMigration(ctx, setup); err != nil {
+		t.Fatal(err)
+	}
+	return &recorder{DatabaseAdapter: adapter}
+}
+
+// B's rows are read a chunk at a time even where A's keys are sparse, and
+// the reconciling statements make B match A
+func TestCompareSparseKeys(t *testing.T) {
+	ctx := context.Background()
+	a := openSQLite(t, "a", `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+INSERT INTO users VALUES (10, 'a'), (20, 'b'), (30, 'c'), (40, 'd'), (50, NULL);`)
+
+	var rowsB []string
+	for id := 21; id <= 29; id++ {
+		rowsB = append(rowsB, fmt.Sprintf("(%d, 'extra')", id))
+	}
+	for id := 51; id <= 60; id++ {
+		rowsB = append(rowsB, fmt.Sprintf("(%d, 'extra')", id))
+	}
+	b := openSQLite(t, "b", `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+INSERT INTO users VALUES (10, 'a'), (20, 'B'), (40, 'd'), (50, NULL), `+strings.Join(rowsB, ", ")+`;`)
+
+	var reconcile bytes.Buffer
+	opts := Options{ChunkSize: 2, Reconcile: &reconcile}
+	report, err := Compare(ctx, Source{a, "sqlite"}, Source{b, "sqlite"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := report.Tables[0]
+	if diff.Missing != 1 || diff.Different != 1 || diff.Extra != 19 {
+		t.Errorf("missing %d, different %d, extra %d, want 1, 1 and 19", diff.Missing, diff.Different, diff.Extra)
+	}
+	if diff.RowsA != 5 || diff.RowsB != 23 {
+		t.Errorf("counted %d rows of A and %d of B, want 5 and 23", diff.RowsA, diff.RowsB)
+	}
+	if b.mostRows > opts.ChunkSize {
+		t.Errorf("read %d rows of B at once, want at most %d", b.mostRows, opts.ChunkSize)
+	}
+
+	if err := b.ExecuteMigration(ctx, reconcile.String()); err != nil {
+		t.Fatalf("reconciling failed: %v\n%s", err, reconcile.String())
+	}
+	report, err = Compare(ctx, Source{a, "sqlite"}, Source{b, "sqlite"}, Options{ChunkSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Differs() {
+		t.Errorf("B differs after reconciling: %+v", report.Tables[0])
+	}
+	if n := report.Tables[0].ChunksDiffering; n != 0 {
+		t.Errorf("%d chunks differ after reconciling", n)
+	}
+}
+
+func TestSQLHashes(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"postgresql", "postgres", true},
+		{"mysql", "mysql", true},
+		{"postgresql", "mysql", false},
+		{"sqlite", "sqlite3", false},
+		{"sqlite", "postgresql", false},
+	}
+	for _, tt := range tests {
+		if got := sqlHashes(tt.a, tt.b); got != tt.want {
+			t.Errorf("sqlHashes(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
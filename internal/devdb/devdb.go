@@ -0,0 +1,199 @@
+// Package devdb starts disposable databases for local development and
+// integration tests: a Docker container for PostgreSQL and MySQL, a
+// temporary file for SQLite.
+package devdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/database"
+)
+
+// The credentials of the databases started in containers
+const (
+	user     = "flash"
+	password = "flash"
+	dbName   = "flash"
+)
+
+// Options configure Start
+type Options struct {
+	// Image is the Docker image, the provider's default when empty
+	Image string
+	// Port is the host port the database listens on, a free one when 0
+	Port int
+	// Timeout is how long to wait for the database to accept connections
+	Timeout time.Duration
+}
+
+type containerSpec struct {
+	image string
+	port  string // the port the database listens on in the container
+	env   []string
+	url   string // format with the host port
+}
+
+var containerSpecs = map[string]containerSpec{
+	"postgresql": {
+		image: "postgres:16-alpine",
+		port:  "5432",
+		env:   []string{"POSTGRES_USER=" + user, "POSTGRES_PASSWORD=" + password, "POSTGRES_DB=" + dbName},
+		url:   "postgres://" + user + ":" + password + "@127.0.0.1:%s/" + dbName + "?sslmode=disable",
+	},
+	"mysql": {
+		image: "mysql:8.4",
+		port:  "3306",
+		env:   []string{"MYSQL_ROOT_PASSWORD=" + password, "MYSQL_USER=" + user, "MYSQL_PASSWORD=" + password, "MYSQL_DATABASE=" + dbName},
+		url:   "mysql://" + user + ":" + password + "@127.0.0.1:%s/" + dbName + "?parseTime=true",
+	},
+}
+
+// Start starts an empty database for provider and waits until it accepts
+// connections
+func Start(ctx context.Context, provider string, opts Options) (*Instance, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = 90 * time.Second
+	}
+
+	switch provider {
+	case "sqlite", "sqlite3":
+		file := filepath.Join(os.TempDir(), fmt.Sprintf("flash_dev_%d.db", time.Now().UnixNano()))
+		return &Instance{Provider: provider, URL: "sqlite://" + file, File: file, CreatedAt: time.Now()}, nil
+	case "postgres":
+		provider = "postgresql"
+	}
+
+	spec, ok := containerSpecs[provider]
+	if !ok {
+		return nil, fmt.Errorf("dev databases are not supported for provider %s", provider)
+	}
+	if _, err := exec.LookPath("docker"); err != nil {
+		return nil, fmt.Errorf("docker is needed to start a %s dev database: %w", provider, err)
+	}
+
+	image := spec.image
+	if opts.Image != "" {
+		image = opts.Image
+	}
+	hostPort := ""
+	if opts.Port != 0 {
+		hostPort = fmt.Sprint(opts.Port)
+	}
+
+	name := fmt.Sprintf("flash-dev-%d", time.Now().UnixNano())
+	args := []string{"run", "-d", "--rm", "--name", name, "--label", "flash.dev=true",
+		"-p", fmt.Sprintf("127.0.0.1:%s:%s", hostPort, spec.port)}
+	for _, env := range spec.env {
+		args = append(args, "-e", env)
+	}
+	args = append(args, image)
+	if _, err := docker(ctx, args...); err != nil {
+		return nil, fmt.Errorf("failed to start %s container: %w", image, err)
+	}
+
+	instance := &Instance{Provider: provider, Container: name, CreatedAt: time.Now()}
+	out, err := docker(ctx, "port", name, spec.port+"/tcp")
+	if err != nil {
+		Stop(ctx, instance)
+		return nil, fmt.Errorf("failed to read the port of container %s: %w", name, err)
+	}
+	_, port, err := net.SplitHostPort(strings.TrimSpace(strings.Split(out, "\n")[0]))
+	if err != nil {
+		Stop(ctx, instance)
+		return nil, fmt.Errorf("unexpected port of container %s: %s", name, out)
+	}
+	instance.URL = fmt.Sprintf(spec.url, port)
+
+	if err := waitReady(ctx, instance, opts.Timeout); err != nil {
+		Stop(ctx, instance)
+		return nil, err
+	}
+	return instance, nil
+}
+
+// waitReady connects to the instance until it answers or timeout passes.
+// Database images restart their server after initializing, so the database
+// has to answer twice in a row.
+func waitReady(ctx context.Context, instance *Instance, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	ready := 0
+	var lastErr error
+	for time.Now().Before(deadline) {
+		if lastErr = ping(ctx, instance); lastErr == nil {
+			ready++
+			if ready == 2 {
+				return nil
+			}
+		} else {
+			ready = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+	return fmt.Errorf("database did not accept connections within %s: %w", timeout, lastErr)
+}
+
+func ping(ctx context.Context, instance *Instance) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	adapter := database.NewAdapter(instance.Provider)
+	if err := adapter.Connect(ctx, instance.URL); err != nil {
+		return err
+	}
+	defer adapter.Close()
+	return adapter.Ping(ctx)
+}
+
+// Running reports whether the instance still exists
+func Running(ctx context.Context, instance *Instance) bool {
+	if instance.Container == "" {
+		_, err := os.Stat(instance.File)
+		return err == nil
+	}
+	out, err := docker(ctx, "inspect", "-f", "{{.State.Running}}", instance.Container)
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+// Stop removes the instance and its data
+func Stop(ctx context.Context, instance *Instance) error {
+	if instance.Container == "" {
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			if err := os.Remove(instance.File + suffix); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", instance.File+suffix, err)
+			}
+		}
+		return nil
+	}
+
+	if _, err := docker(ctx, "rm", "-f", "-v", instance.Container); err != nil && !strings.Contains(err.Error(), "No such container") {
+		return fmt.Errorf("failed to remove container %s: %w", instance.Container, err)
+	}
+	return nil
+}
+
+func docker(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s", msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,72 @@
+package devdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Instance is a disposable database started by 'flash dev up'
+type Instance struct {
+	Provider string `json:"provider"`
+	URL      string `json:"url"`
+	// Container is the Docker container of the database, empty for SQLite
+	Container string `json:"container,omitempty"`
+	// File is the database file of a SQLite instance
+	File      string    `json:"file,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MetadataManager keeps the running instance in the .flash folder of the
+// migrations path
+type MetadataManager struct {
+	instancePath string
+	flashDir     string
+}
+
+func NewMetadataManager(migrationsPath string) *MetadataManager {
+	flashDir := filepath.Join(migrationsPath, ".flash")
+
+	return &MetadataManager{
+		instancePath: filepath.Join(flashDir, "devdb.json"),
+		flashDir:     flashDir,
+	}
+}
+
+// Load returns the running instance, nil when there is none
+func (m *MetadataManager) Load() (*Instance, error) {
+	if _, err := os.Stat(m.instancePath); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(m.instancePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dev database file: %w", err)
+	}
+	var instance Instance
+	if err := json.Unmarshal(data, &instance); err != nil {
+		return nil, fmt.Errorf("failed to parse dev database file: %w", err)
+	}
+	return &instance, nil
+}
+
+func (m *MetadataManager) Save(instance *Instance) error {
+	if err := os.MkdirAll(m.flashDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directories: %w", err)
+	}
+
+	data, err := json.MarshalIndent(instance, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal dev database: %w", err)
+	}
+	return os.WriteFile(m.instancePath, data, 0644)
+}
+
+func (m *MetadataManager) Delete() error {
+	if err := os.Remove(m.instancePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove dev database file: %w", err)
+	}
+	return nil
+}
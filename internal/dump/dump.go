@@ -0,0 +1,525 @@
+// Package dump reads plain SQL dumps, as written by pg_dump, mysqldump or
+// sqlite3's .dump, one statement at a time, so they can be restored through
+// a database adapter without the database's own client.
+package dump
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Format is the tool that wrote a dump
+type Format string
+
+const (
+	FormatPgDump    Format = "pg_dump"
+	FormatMySQLDump Format = "mysqldump"
+	// FormatSQL is any other file of SQL statements
+	FormatSQL Format = "sql"
+)
+
+// copyBatchRows is how many rows of a COPY block a statement carries at
+// most
+const copyBatchRows = 500
+
+// DetectFormat tells the tool that wrote a dump from its header comments
+func DetectFormat(header []byte) Format {
+	switch {
+	case bytes.Contains(header, []byte("-- PostgreSQL database dump")):
+		return FormatPgDump
+	case bytes.Contains(header, []byte("-- MySQL dump")), bytes.Contains(header, []byte("-- MariaDB dump")):
+		return FormatMySQLDump
+	}
+	return FormatSQL
+}
+
+// Statement is a statement of a dump, or a batch of the rows of a COPY
+// block of pg_dump
+type Statement struct {
+	SQL  string
+	Line int // line of the dump the statement starts on
+	// Client is set for commands of the dump's client, like psql's
+	// \connect or mysql's DELIMITER, which no server runs
+	Client bool
+	// Copy holds rows of a COPY ... FROM stdin block, SQL its COPY
+	// statement
+	Copy *Copy
+}
+
+// Copy is a batch of rows of a COPY block
+type Copy struct {
+	Table   string   // as written in the dump, quoted where needed
+	Columns []string // as written in the dump, quoted where needed
+	Rows    [][]any  // text values, nil for NULL
+}
+
+var copyRegex = regexp.MustCompile(`(?is)^COPY\s+(\S+)\s*\((.*)\)\s+FROM\s+stdin`)
+
+// Scanner reads the statements of a dump
+type Scanner struct {
+	r         *bufio.Reader
+	format    Format
+	line      int
+	delimiter string
+	copy      *copyBlock
+	stmt      Statement
+	err       error
+}
+
+// copyBlock is the COPY block being read
+type copyBlock struct {
+	table   string
+	columns []string
+	sql     string
+}
+
+// NewScanner returns a scanner of a dump and the format of the dump, told
+// from its first kilobytes
+func NewScanner(r io.Reader) (*Scanner, Format) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	header, _ := br.Peek(4096)
+	format := DetectFormat(header)
+	return &Scanner{r: br, format: format, line: 1, delimiter: ";"}, format
+}
+
+// Statement returns the statement read by the last call to Scan
+func (s *Scanner) Statement() Statement {
+	return s.stmt
+}
+
+// Err returns the error that stopped Scan, nil at the end of the dump
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// Scan reads the next statement, reporting whether there is one
+func (s *Scanner) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+	var err error
+	if s.copy != nil {
+		err = s.scanCopyRows()
+	} else {
+		err = s.scanStatement()
+	}
+	if err == io.EOF {
+		return false
+	}
+	if err != nil {
+		s.err = err
+		return false
+	}
+	return true
+}
+
+func (s *Scanner) readRune() (rune, error) {
+	c, _, err := s.r.ReadRune()
+	if c == '\n' {
+		s.line++
+	}
+	return c, err
+}
+
+func (s *Scanner) peek() rune {
+	c, _, err := s.r.ReadRune()
+	if err != nil {
+		return 0
+	}
+	_ = s.r.UnreadRune()
+	return c
+}
+
+// readLine reads the rest of the line, without its line break
+func (s *Scanner) readLine() (string, error) {
+	line, err := s.r.ReadString('\n')
+	if strings.HasSuffix(line, "\n") {
+		s.line++
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	}
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return line, err
+}
+
+// scanStatement reads up to the end of the next statement, skipping
+// comments and keeping quoted text, which may hold the delimiter, whole
+func (s *Scanner) scanStatement() error {
+	var b strings.Builder
+	start := 0
+	for {
+		c, err := s.readRune()
+		if err == io.EOF {
+			if text := strings.TrimSpace(b.String()); text != "" {
+				s.stmt = Statement{SQL: text, Line: start}
+				return nil
+			}
+			return io.EOF
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read dump: %w", err)
+		}
+
+		empty := strings.TrimSpace(b.String()) == ""
+		if empty {
+			if c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+				continue
+			}
+			b.Reset()
+			start = s.line
+			// Commands of psql and mysql take the rest of their line
+			if c == '\\' {
+				rest, err := s.readLine()
+				if err != nil && err != io.EOF {
+					return fmt.Errorf("failed to read dump: %w", err)
+				}
+				s.stmt = Statement{SQL: "\\" + rest, Line: start, Client: true}
+				return nil
+			}
+			if (c == 'D' || c == 'd') && s.format != FormatPgDump {
+				if rest, ok := s.delimiterCommand(c); ok {
+					s.delimiter = rest
+					s.stmt = Statement{SQL: "DELIMITER " + rest, Line: start, Client: true}
+					return nil
+				}
+			}
+		}
+
+		switch {
+		case c == '-' && s.peek() == '-':
+			if _, err := s.readLine(); err != nil && err != io.EOF {
+				return fmt.Errorf("failed to read dump: %w", err)
+			}
+			b.WriteByte('\n')
+			continue
+		case c == '#' && s.format == FormatMySQLDump:
+			if _, err := s.readLine(); err != nil && err != io.EOF {
+				return fmt.Errorf("failed to read dump: %w", err)
+			}
+			b.WriteByte('\n')
+			continue
+		case c == '/' && s.peek() == '*':
+			if err := s.scanBlockComment(&b); err != nil {
+				return err
+			}
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			b.WriteRune(c)
+			backslash := c != '`' && s.format == FormatMySQLDump || c == '\'' && endsWithEscapePrefix(b.String())
+			if err := s.scanQuoted(&b, c, backslash); err != nil {
+				return err
+			}
+			continue
+		case c == '$' && s.format != FormatMySQLDump:
+			b.WriteRune(c)
+			if err := s.scanDollarQuoted(&b); err != nil {
+				return err
+			}
+			continue
+		}
+
+		b.WriteRune(c)
+		if strings.HasSuffix(b.String(), s.delimiter) {
+			text := strings.TrimSpace(strings.TrimSuffix(b.String(), s.delimiter))
+			if text == "" {
+				b.Reset()
+				continue
+			}
+			if s.format != FormatMySQLDump && inTriggerBody(text) {
+				continue
+			}
+			s.stmt = Statement{SQL: text, Line: start}
+			if m := copyRegex.FindStringSubmatch(text); m != nil {
+				// The rows start on the next line
+				if _, err := s.readLine(); err != nil && err != io.EOF {
+					return fmt.Errorf("failed to read dump: %w", err)
+				}
+				s.copy = &copyBlock{table: m[1], columns: splitColumns(m[2]), sql: text}
+				return s.scanCopyRows()
+			}
+			return nil
+		}
+	}
+}
+
+var (
+	triggerBodyRegex = regexp.MustCompile(`(?is)^CREATE\s+(TEMP\s+|TEMPORARY\s+)?TRIGGER\b.*\bBEGIN\b`)
+	bodyEndRegex     = regexp.MustCompile(`(?i)\bEND$`)
+)
+
+// inTriggerBody reports whether text is a SQLite trigger cut short at a
+// statement of its BEGIN ... END body, which sqlite3's .dump writes without
+// changing the delimiter
+func inTriggerBody(text string) bool {
+	return triggerBodyRegex.MatchString(text) && !bodyEndRegex.MatchString(text)
+}
+
+// delimiterCommand reads mysql's DELIMITER command, which sets what ends
+// statements, e.g. around trigger bodies. It leaves the reader alone
+// unless the line is one.
+func (s *Scanner) delimiterCommand(first rune) (string, bool) {
+	const command = "ELIMITER "
+	ahead, _ := s.r.Peek(len(command))
+	if !strings.EqualFold(string(ahead), command) {
+		return "", false
+	}
+	_, _ = s.r.Discard(len(command))
+	rest, _ := s.readLine()
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		rest = ";"
+	}
+	return rest, true
+}
+
+// scanBlockComment skips a /* */ comment, except MySQL's /*! ... */
+// comments, which hold statements MySQL runs
+func (s *Scanner) scanBlockComment(b *strings.Builder) error {
+	s.readRune() // *
+	executable := s.peek() == '!'
+	if executable {
+		b.WriteString("/*")
+	}
+	var prev rune
+	for {
+		c, err := s.readRune()
+		if err != nil {
+			return fmt.Errorf("unterminated comment at line %d", s.line)
+		}
+		if executable {
+			b.WriteRune(c)
+		}
+		if prev == '*' && c == '/' {
+			if !executable {
+				b.WriteByte(' ')
+			}
+			return nil
+		}
+		prev = c
+	}
+}
+
+// scanQuoted reads quoted text up to its closing quote, which is doubled
+// inside it, or escaped with a backslash in MySQL strings and PostgreSQL's
+// E” strings
+func (s *Scanner) scanQuoted(b *strings.Builder, quote rune, backslash bool) error {
+	startLine := s.line
+	for {
+		c, err := s.readRune()
+		if err != nil {
+			return fmt.Errorf("unterminated %c quote starting at line %d", quote, startLine)
+		}
+		b.WriteRune(c)
+		if backslash && c == '\\' {
+			next, err := s.readRune()
+			if err != nil {
+				return fmt.Errorf("unterminated %c quote starting at line %d", quote, startLine)
+			}
+			b.WriteRune(next)
+			continue
+		}
+		if c == quote {
+			if s.peek() == quote {
+				next, _ := s.readRune()
+				b.WriteRune(next)
+				continue
+			}
+			return nil
+		}
+	}
+}
+
+var dollarTagRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// scanDollarQuoted reads PostgreSQL's $tag$ ... $tag$ quoted text, as in
+// function bodies. A $ that starts no tag, like a $1 parameter, is left
+// alone.
+func (s *Scanner) scanDollarQuoted(b *strings.Builder) error {
+	var tag strings.Builder
+	for {
+		c := s.peek()
+		if c == '$' {
+			s.readRune()
+			break
+		}
+		if c == 0 || !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c > 127) {
+			b.WriteString(tag.String())
+			return nil
+		}
+		s.readRune()
+		tag.WriteRune(c)
+	}
+	if tag.Len() > 0 && !dollarTagRegex.MatchString(tag.String()) {
+		b.WriteString(tag.String() + "$")
+		return nil
+	}
+
+	closing := "$" + tag.String() + "$"
+	b.WriteString(closing[1:])
+	startLine := s.line
+	var body strings.Builder
+	for {
+		c, err := s.readRune()
+		if err != nil {
+			return fmt.Errorf("unterminated %s quote starting at line %d", closing, startLine)
+		}
+		body.WriteRune(c)
+		b.WriteRune(c)
+		if c == '$' && strings.HasSuffix(body.String(), closing) {
+			return nil
+		}
+	}
+}
+
+// endsWithEscapePrefix reports whether text ends with the E of a
+// PostgreSQL E” string, whose backslashes escape
+func endsWithEscapePrefix(text string) bool {
+	text = strings.TrimSuffix(text, "'")
+	if !strings.HasSuffix(text, "E") && !strings.HasSuffix(text, "e") {
+		return false
+	}
+	before := text[:len(text)-1]
+	if before == "" {
+		return true
+	}
+	last := before[len(before)-1]
+	return !(last == '_' || last >= 'a' && last <= 'z' || last >= 'A' && last <= 'Z' || last >= '0' && last <= '9')
+}
+
+// scanCopyRows reads up to copyBatchRows rows of the COPY block being read,
+// ending it at its \. line
+func (s *Scanner) scanCopyRows() error {
+	block := s.copy
+	batch := &Copy{Table: block.table, Columns: block.columns}
+	start := s.line
+	for len(batch.Rows) < copyBatchRows {
+		line, err := s.readLine()
+		if err == io.EOF {
+			return fmt.Errorf("COPY of %s at line %d has no end marker", block.table, start)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read dump: %w", err)
+		}
+		if line == `\.` {
+			s.copy = nil
+			break
+		}
+		row, err := copyRow(line, len(block.columns))
+		if err != nil {
+			return fmt.Errorf("line %d: %w", s.line-1, err)
+		}
+		batch.Rows = append(batch.Rows, row)
+	}
+	s.stmt = Statement{SQL: block.sql, Line: start, Copy: batch}
+	return nil
+}
+
+// copyRow decodes a row of COPY's text format: tab separated values with
+// backslash escapes, \N for NULL
+func copyRow(line string, columns int) ([]any, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) != columns {
+		return nil, fmt.Errorf("COPY row has %d values for %d columns", len(fields), columns)
+	}
+	row := make([]any, len(fields))
+	for i, field := range fields {
+		if field == `\N` {
+			continue
+		}
+		row[i] = copyUnescape(field)
+	}
+	return row, nil
+}
+
+func copyUnescape(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		if c != '\\' || i+1 == len(field) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch c = field[i]; c {
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case 'x':
+			n, digits := 0, 0
+			for digits < 2 && i+1 < len(field) && isHexDigit(field[i+1]) {
+				i++
+				n = n*16 + hexValue(field[i])
+				digits++
+			}
+			if digits == 0 {
+				b.WriteByte('x')
+			} else {
+				b.WriteByte(byte(n))
+			}
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			n := int(c - '0')
+			for digits := 1; digits < 3 && i+1 < len(field) && field[i+1] >= '0' && field[i+1] <= '7'; digits++ {
+				i++
+				n = n*8 + int(field[i]-'0')
+			}
+			b.WriteByte(byte(n))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func hexValue(c byte) int {
+	switch {
+	case c >= 'a':
+		return int(c-'a') + 10
+	case c >= 'A':
+		return int(c-'A') + 10
+	}
+	return int(c - '0')
+}
+
+// splitColumns splits the column list of a COPY statement, keeping quoted
+// names whole
+func splitColumns(list string) []string {
+	var columns []string
+	var b strings.Builder
+	quoted := false
+	for _, c := range list {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			columns = append(columns, strings.TrimSpace(b.String()))
+			b.Reset()
+			continue
+		}
+		b.WriteRune(c)
+	}
+	if text := strings.TrimSpace(b.String()); text != "" {
+		columns = append(columns, text)
+	}
+	return columns
+}
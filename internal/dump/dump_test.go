@@ -0,0 +1,166 @@
+package dump
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The scanner splits dumps at their delimiters, keeping quoted text and
+// function bodies whole, dropping comments and telling client commands
+// apart
+func TestScanner(t *testing.T) {
+	tests := []struct {
+		name   string
+		dump   string
+		format Format
+		want   []Statement
+	}{
+		{
+			name:   "plain",
+			dump:   "CREATE TABLE a (id int);\n\nINSERT INTO a VALUES (1);\n",
+			format: FormatSQL,
+			want: []Statement{
+				{SQL: "CREATE TABLE a (id int)", Line: 1},
+				{SQL: "INSERT INTO a VALUES (1)", Line: 3},
+			},
+		},
+		{
+			name:   "comments",
+			dump:   "-- a comment; with a semicolon\n/* another; */ SELECT 1;\nSELECT 2; -- trailing\n",
+			format: FormatSQL,
+			want: []Statement{
+				{SQL: "SELECT 1", Line: 2},
+				{SQL: "SELECT 2", Line: 3},
+			},
+		},
+		{
+			name:   "quoted delimiters",
+			dump:   "INSERT INTO a VALUES ('x;y', 'it''s');\nSELECT \"a;b\";\n",
+			format: FormatSQL,
+			want: []Statement{
+				{SQL: "INSERT INTO a VALUES ('x;y', 'it''s')", Line: 1},
+				{SQL: `SELECT "a;b"`, Line: 2},
+			},
+		},
+		{
+			name:   "pg_dump function body",
+			dump:   "-- PostgreSQL database dump\nCREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql;\nSELECT $1;\n",
+			format: FormatPgDump,
+			want: []Statement{
+				{SQL: "CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql", Line: 2},
+				{SQL: "SELECT $1", Line: 3},
+			},
+		},
+		{
+			name:   "pg_dump escape string",
+			dump:   "-- PostgreSQL database dump\nSELECT E'a\\';b';\n\\connect shop\n",
+			format: FormatPgDump,
+			want: []Statement{
+				{SQL: `SELECT E'a\';b'`, Line: 2},
+				{SQL: `\connect shop`, Line: 3, Client: true},
+			},
+		},
+		{
+			name:   "mysqldump",
+			dump:   "-- MySQL dump 10.13\n/*!40101 SET NAMES utf8mb4 */;\nINSERT INTO `a` VALUES ('x\\';y');\n# note\nDELIMITER ;;\nCREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW BEGIN SET NEW.x = 1; END ;;\nDELIMITER ;\nSELECT 1;\n",
+			format: FormatMySQLDump,
+			want: []Statement{
+				{SQL: "/*!40101 SET NAMES utf8mb4 */", Line: 2},
+				{SQL: "INSERT INTO `a` VALUES ('x\\';y')", Line: 3},
+				{SQL: "DELIMITER ;;", Line: 5, Client: true},
+				{SQL: "CREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW BEGIN SET NEW.x = 1; END", Line: 6},
+				{SQL: "DELIMITER ;", Line: 7, Client: true},
+				{SQL: "SELECT 1", Line: 8},
+			},
+		},
+		{
+			name:   "sqlite trigger",
+			dump:   "CREATE TRIGGER t AFTER INSERT ON a BEGIN UPDATE a SET n = 1; DELETE FROM b; END;\nCOMMIT;\n",
+			format: FormatSQL,
+			want: []Statement{
+				{SQL: "CREATE TRIGGER t AFTER INSERT ON a BEGIN UPDATE a SET n = 1; DELETE FROM b; END", Line: 1},
+				{SQL: "COMMIT", Line: 2},
+			},
+		},
+		{
+			name:   "copy",
+			dump:   "-- PostgreSQL database dump\nCOPY public.users (id, \"full name\") FROM stdin;\n1\tAda\n2\t\\N\n\\.\nSELECT 1;\n",
+			format: FormatPgDump,
+			want: []Statement{
+				{SQL: "COPY public.users (id, \"full name\") FROM stdin", Line: 3, Copy: &Copy{
+					Table:   "public.users",
+					Columns: []string{"id", `"full name"`},
+					Rows:    [][]any{{"1", "Ada"}, {"2", nil}},
+				}},
+				{SQL: "SELECT 1", Line: 6},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner, format := NewScanner(strings.NewReader(tt.dump))
+			if format != tt.format {
+				t.Errorf("format %q, want %q", format, tt.format)
+			}
+			var got []Statement
+			for scanner.Scan() {
+				got = append(got, scanner.Statement())
+			}
+			if err := scanner.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("statements\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Dumps cut short fail with the line of what was left open
+func TestScannerErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		dump string
+		err  string
+	}{
+		{"quote", "SELECT 1;\nSELECT 'open;\n", "unterminated ' quote starting at line 2"},
+		{"dollar quote", "SELECT $x$ open;\n", "unterminated $x$ quote"},
+		{"comment", "/* open\n", "unterminated comment"},
+		{"copy", "COPY a (id) FROM stdin;\n1\n", "COPY of a at line 2 has no end marker"},
+		{"copy columns", "COPY a (id, name) FROM stdin;\n1\n\\.\n", "line 2: COPY row has 1 values for 2 columns"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner, _ := NewScanner(strings.NewReader(tt.dump))
+			for scanner.Scan() {
+			}
+			if err := scanner.Err(); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+// COPY values are unescaped as COPY's text format escapes them
+func TestCopyUnescape(t *testing.T) {
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{`plain`, "plain"},
+		{`a\tb\nc`, "a\tb\nc"},
+		{`back\\slash`, `back\slash`},
+		{`\x41\x4a`, "AJ"},
+		{`\101\0`, "A\x00"},
+		{`\xzz`, "xzz"},
+		{`trailing\`, `trailing\`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			if got := copyUnescape(tt.field); got != tt.want {
+				t.Errorf("copyUnescape(%q) = %q, want %q", tt.field, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package dump
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
+)
+
+// maxParams is the most bind parameters a statement takes on PostgreSQL
+const maxParams = 65535
+
+// maxStatementText is how much of a failed statement an Error keeps
+const maxStatementText = 200
+
+// Executor runs statements, as database adapters do
+type Executor interface {
+	ExecuteStatements(ctx context.Context, statements []common.Statement) error
+}
+
+// Options tune a restore
+type Options struct {
+	// Provider of the database restored to, which a pg_dump or mysqldump
+	// dump has to match
+	Provider string
+	// OnProgress is called after each statement
+	OnProgress func(Progress)
+	// MaxErrors failed statements stop the restore, 100 when 0
+	MaxErrors int
+}
+
+// Progress counts what a restore has done so far
+type Progress struct {
+	Statements int   `json:"statements"`
+	Rows       int   `json:"rows"`
+	Skipped    int   `json:"skipped"`
+	Failed     int   `json:"failed"`
+	Bytes      int64 `json:"bytes"`
+}
+
+// Error is a statement of a dump that failed
+type Error struct {
+	Line      int    `json:"line"`
+	Statement string `json:"statement"`
+	Error     string `json:"error"`
+}
+
+// Result is the outcome of a restore
+type Result struct {
+	Format  Format  `json:"format"`
+	Stopped bool    `json:"stopped,omitempty"` // too many errors
+	Errors  []Error `json:"errors,omitempty"`
+	Progress
+}
+
+// skipRegex matches statements that only set up the session of the tool
+// that wrote the dump. A restore runs each statement on whichever pooled
+// connection is free, so these would change an unrelated session, or, for
+// transaction control and LOCK TABLES, break the restore. Ownership is
+// skipped as pg_restore --no-owner does, since the dump's roles rarely
+// exist on a teammate's database.
+var skipRegex = regexp.MustCompile(`(?is)^(` +
+	`SET\s|` +
+	`SELECT\s+pg_catalog\.set_config\s*\(|` +
+	`/\*!\d*\s*SET\s|` +
+	`(LOCK|UNLOCK)\s+TABLES\b|` +
+	`(BEGIN|START\s+TRANSACTION|COMMIT|ROLLBACK|END)\b\s*(TRANSACTION|WORK)?\s*$|` +
+	`PRAGMA\s+foreign_keys\b|` +
+	`ALTER\s+.*\sOWNER\s+TO\s` +
+	`)`)
+
+// Restore runs the statements of a dump one at a time, each in its own
+// transaction, collecting the ones that fail instead of stopping at the
+// first. The rows of pg_dump's COPY blocks become batched INSERTs with
+// bound values.
+func Restore(ctx context.Context, exec Executor, r io.Reader, opts Options) (*Result, error) {
+	if opts.MaxErrors <= 0 {
+		opts.MaxErrors = 100
+	}
+	counter := &countingReader{r: r}
+	scanner, format := NewScanner(counter)
+	if err := checkProvider(format, opts.Provider); err != nil {
+		return nil, err
+	}
+
+	result := &Result{Format: format}
+	progress := func() {
+		result.Bytes = counter.n
+		if opts.OnProgress != nil {
+			opts.OnProgress(result.Progress)
+		}
+	}
+	fail := func(stmt Statement, err error) {
+		result.Errors = append(result.Errors, Error{
+			Line:      stmt.Line,
+			Statement: truncate(stmt.SQL),
+			Error:     err.Error(),
+		})
+		result.Failed++
+	}
+
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		stmt := scanner.Statement()
+		switch {
+		case stmt.Copy != nil:
+			if len(stmt.Copy.Rows) == 0 {
+				continue
+			}
+			for _, batch := range copyStatements(stmt.Copy, opts.Provider) {
+				if err := exec.ExecuteStatements(ctx, []common.Statement{batch.statement}); err != nil {
+					fail(stmt, err)
+					continue
+				}
+				result.Statements++
+				result.Rows += batch.rows
+			}
+		case stmt.Client || skipRegex.MatchString(stmt.SQL):
+			result.Skipped++
+		default:
+			if err := exec.ExecuteStatements(ctx, []common.Statement{{SQL: stmt.SQL}}); err != nil {
+				fail(stmt, err)
+			} else {
+				result.Statements++
+			}
+		}
+		progress()
+		if result.Failed >= opts.MaxErrors {
+			result.Stopped = true
+			return result, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, err
+	}
+	progress()
+	return result, nil
+}
+
+// checkProvider rejects a dump of one database for another, whose SQL it
+// would mostly fail on
+func checkProvider(format Format, provider string) error {
+	switch format {
+	case FormatPgDump:
+		if provider != "postgresql" && provider != "postgres" {
+			return fmt.Errorf("this is a pg_dump dump, which only restores to PostgreSQL, not %s", provider)
+		}
+	case FormatMySQLDump:
+		if provider != "mysql" {
+			return fmt.Errorf("this is a mysqldump dump, which only restores to MySQL, not %s", provider)
+		}
+	}
+	return nil
+}
+
+type copyBatch struct {
+	statement common.Statement
+	rows      int
+}
+
+// copyStatements turns rows of a COPY block into INSERTs, as many rows to
+// each as the bind parameter limit allows
+func copyStatements(c *Copy, provider string) []copyBatch {
+	perStatement := maxParams / max(len(c.Columns), 1)
+	postgres := provider == "postgresql" || provider == "postgres"
+
+	var batches []copyBatch
+	for start := 0; start < len(c.Rows); start += perStatement {
+		rows := c.Rows[start:min(start+perStatement, len(c.Rows))]
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", c.Table, strings.Join(c.Columns, ", "))
+		args := make([]any, 0, len(rows)*len(c.Columns))
+		for i, row := range rows {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteByte('(')
+			for j, value := range row {
+				if j > 0 {
+					b.WriteString(", ")
+				}
+				args = append(args, value)
+				if postgres {
+					fmt.Fprintf(&b, "$%d", len(args))
+				} else {
+					b.WriteByte('?')
+				}
+			}
+			b.WriteByte(')')
+		}
+		batches = append(batches, copyBatch{
+			statement: common.Statement{SQL: b.String(), Args: args},
+			rows:      len(rows),
+		})
+	}
+	return batches
+}
+
+func truncate(sql string) string {
+	if len(sql) <= maxStatementText {
+		return sql
+	}
+	cut := maxStatementText
+	for cut > 0 && !utf8.RuneStart(sql[cut]) {
+		cut--
+	}
+	return sql[:cut] + "..."
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
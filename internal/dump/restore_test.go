@@ -0,0 +1,158 @@
+package dump
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
+)
+
+// executor records what a restore runs, failing statements that mention
+// "broken"
+type executor struct {
+	statements []common.Statement
+}
+
+func (e *executor) ExecuteStatements(ctx context.Context, statements []common.Statement) error {
+	for _, stmt := range statements {
+		if strings.Contains(stmt.SQL, "broken") {
+			return errors.New("syntax error")
+		}
+		e.statements = append(e.statements, stmt)
+	}
+	return nil
+}
+
+// Session setup and client commands are skipped, COPY rows become INSERTs
+// with bound values and failed statements are collected rather than ending
+// the restore
+func TestRestore(t *testing.T) {
+	tests := []struct {
+		name     string
+		dump     string
+		provider string
+		want     []common.Statement
+		progress Progress
+		errors   []int
+		err      string
+	}{
+		{
+			name:     "pg_dump",
+			dump:     "-- PostgreSQL database dump\nSET statement_timeout = 0;\nSELECT pg_catalog.set_config('search_path', '', false);\n\\connect shop\nCREATE TABLE public.users (id int, name text);\nALTER TABLE public.users OWNER TO admin;\nCOPY public.users (id, name) FROM stdin;\n1\tAda\n2\t\\N\n\\.\n",
+			provider: "postgresql",
+			want: []common.Statement{
+				{SQL: "CREATE TABLE public.users (id int, name text)"},
+				{SQL: "INSERT INTO public.users (id, name) VALUES ($1, $2), ($3, $4)", Args: []any{"1", "Ada", "2", nil}},
+			},
+			progress: Progress{Statements: 2, Rows: 2, Skipped: 4},
+		},
+		{
+			name:     "mysqldump",
+			dump:     "-- MySQL dump 10.13\n/*!40101 SET NAMES utf8mb4 */;\nLOCK TABLES `users` WRITE;\nINSERT INTO `users` VALUES (1,'Ada');\nUNLOCK TABLES;\n",
+			provider: "mysql",
+			want:     []common.Statement{{SQL: "INSERT INTO `users` VALUES (1,'Ada')"}},
+			progress: Progress{Statements: 1, Skipped: 3},
+		},
+		{
+			name:     "failures",
+			dump:     "BEGIN TRANSACTION;\nCREATE TABLE a (id int);\nbroken one;\nINSERT INTO a VALUES (1);\n\nbroken two;\nCOMMIT;\n",
+			provider: "sqlite",
+			want:     []common.Statement{{SQL: "CREATE TABLE a (id int)"}, {SQL: "INSERT INTO a VALUES (1)"}},
+			progress: Progress{Statements: 2, Skipped: 2, Failed: 2},
+			errors:   []int{3, 6},
+		},
+		{
+			name:     "wrong database",
+			dump:     "-- PostgreSQL database dump\nSELECT 1;\n",
+			provider: "mysql",
+			err:      "only restores to PostgreSQL",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exec := &executor{}
+			result, err := Restore(context.Background(), exec, strings.NewReader(tt.dump), Options{Provider: tt.provider})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(exec.statements, tt.want) {
+				t.Errorf("ran\n%#v\nwant\n%#v", exec.statements, tt.want)
+			}
+			progress := result.Progress
+			progress.Bytes = 0
+			if progress != tt.progress {
+				t.Errorf("progress %+v, want %+v", progress, tt.progress)
+			}
+			var lines []int
+			for _, e := range result.Errors {
+				lines = append(lines, e.Line)
+			}
+			if !reflect.DeepEqual(lines, tt.errors) {
+				t.Errorf("errors at lines %v, want %v", lines, tt.errors)
+			}
+		})
+	}
+}
+
+// A restore stops once MaxErrors statements have failed
+func TestRestoreMaxErrors(t *testing.T) {
+	var dump strings.Builder
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&dump, "broken %d;\n", i)
+	}
+	result, err := Restore(context.Background(), &executor{}, strings.NewReader(dump.String()), Options{Provider: "sqlite", MaxErrors: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Stopped || result.Failed != 3 {
+		t.Errorf("stopped %v after %d failures, want to stop after 3", result.Stopped, result.Failed)
+	}
+}
+
+// COPY blocks wider than the bind parameter limit are split across INSERTs
+func TestCopyStatements(t *testing.T) {
+	columns := make([]string, 1000)
+	for i := range columns {
+		columns[i] = fmt.Sprintf("c%d", i)
+	}
+	rows := make([][]any, 200)
+	for i := range rows {
+		rows[i] = make([]any, len(columns))
+	}
+
+	tests := []struct {
+		provider    string
+		placeholder string
+	}{
+		{"postgresql", "$1,"},
+		{"mysql", "?,"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			batches := copyStatements(&Copy{Table: "wide", Columns: columns, Rows: rows}, tt.provider)
+			total := 0
+			for _, batch := range batches {
+				if len(batch.statement.Args) > maxParams {
+					t.Errorf("statement binds %d values, over %d", len(batch.statement.Args), maxParams)
+				}
+				if !strings.Contains(strings.ReplaceAll(batch.statement.SQL, " ", ""), tt.placeholder) {
+					t.Errorf("statement has no %s placeholders", tt.placeholder)
+				}
+				total += batch.rows
+			}
+			if len(batches) != 4 || total != len(rows) {
+				t.Errorf("%d rows in %d statements, want %d in 4", total, len(batches), len(rows))
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/database"
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/state"
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+// updatedAtColumns are the names, lowercased without underscores, of
+// columns that record when their row last changed
+var updatedAtColumns = map[string]bool{
+	"updatedat":    true,
+	"modifiedat":   true,
+	"lastmodified": true,
+}
+
+// IncrementalOptions tune an incremental export
+type IncrementalOptions struct {
+	// Columns picks the watermark column of tables by table name, instead
+	// of their updated_at column or sortable primary key
+	Columns map[string]string
+	// Reset ignores the recorded watermarks, exporting every table in full
+	// and recording new ones
+	Reset bool
+}
+
+// TableChanges is what an incremental export wrote for a table
+type TableChanges struct {
+	Table string
+	Rows  int
+	// Column is the watermark column, "" for tables exported in full every
+	// time since they have none
+	Column string
+	// Since is the watermark the rows were exported from, nil when the
+	// table was exported in full
+	Since *types.ExportWatermark
+}
+
+// PerformIncrementalExport exports the rows of each table changed since the
+// watermark the last incremental export recorded for it, then records the
+// new watermarks in the .flash folder of the migrations path.
+//
+// A table's watermark is the highest value of its updated_at column, or of
+// its primary key when keys sort as rows were inserted (integers, ULIDs and
+// UUIDv7s), which only catches new rows. Rows at an
+// updated_at watermark are exported again, since more rows may have changed
+// within the same instant. Deleted rows aren't exported. Tables without a
+// watermark yet, and tables with no such column, are exported in full.
+func PerformIncrementalExport(ctx context.Context, adapter database.DatabaseAdapter, exportPath, format string, dbConfig config.Database, migrationsPath string, opts IncrementalOptions) (string, []TableChanges, error) {
+	if err := database.Require(adapter, dbConfig.Provider, common.CapabilityQueries); err != nil {
+		return "", nil, err
+	}
+
+	store := NewWatermarkManager(migrationsPath)
+	marks, err := store.Load()
+	if err != nil {
+		return "", nil, err
+	}
+	previous := marks
+	if opts.Reset {
+		previous = map[string]types.ExportWatermark{}
+	}
+
+	var (
+		mu      sync.Mutex
+		since   = make(map[string]types.ExportWatermark)
+		next    = make(map[string]types.ExportWatermark)
+		changes []TableChanges
+	)
+	now := time.Now().UTC()
+
+	exportData, err := collect(ctx, adapter, dbConfig, "Incremental database export", func(tableName string) ([]map[string]interface{}, error) {
+		column, strict, err := watermarkColumn(ctx, adapter, dbConfig.Provider, tableName, opts.Columns[tableName])
+		if err != nil {
+			return nil, err
+		}
+		if column == "" {
+			rows, err := adapter.GetTableData(ctx, tableName)
+			if err == nil {
+				mu.Lock()
+				changes = append(changes, TableChanges{Table: tableName, Rows: len(rows)})
+				mu.Unlock()
+			}
+			return rows, err
+		}
+
+		// The watermark is read first, so rows changed while the export
+		// runs are exported again next time rather than skipped
+		high, err := maxValue(ctx, adapter, dbConfig.Provider, tableName, column)
+		if err != nil {
+			return nil, err
+		}
+
+		var rows []map[string]interface{}
+		prev, ok := previous[tableName]
+		if ok && prev.Column == column {
+			rows, err = changedRows(ctx, adapter, dbConfig.Provider, tableName, column, prev.Value, strict)
+		} else {
+			ok = false
+			rows, err = adapter.GetTableData(ctx, tableName)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		change := TableChanges{Table: tableName, Rows: len(rows), Column: column}
+		if ok {
+			since[tableName] = prev
+			change.Since = &prev
+		}
+		switch {
+		case high != "":
+			next[tableName] = types.ExportWatermark{Column: column, Value: high, ExportedAt: now}
+		case ok:
+			next[tableName] = prev
+		}
+		changes = append(changes, change)
+		return rows, nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	exportData.Since = since
+
+	path, err := writeExport(ctx, adapter, exportData, exportPath, format)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Watermarks only move once the export is written
+	if opts.Reset {
+		marks = map[string]types.ExportWatermark{}
+	}
+	for table, mark := range next {
+		marks[table] = mark
+	}
+	if err := store.Save(marks); err != nil {
+		return "", nil, err
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Table < changes[j].Table })
+	return path, changes, nil
+}
+
+// watermarkColumn picks the watermark column of a table: the named one, an
+// updated_at column, or a sortable primary key, which strict reports, since
+// rows at its watermark were already exported. "" means the table has none.
+func watermarkColumn(ctx context.Context, adapter database.DatabaseAdapter, provider, tableName, named string) (string, bool, error) {
+	columns, err := adapter.GetTableColumns(ctx, tableName)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get columns of %s: %w", tableName, err)
+	}
+
+	var primary []types.SchemaColumn
+	for _, col := range columns {
+		if named != "" && col.Name == named {
+			return col.Name, col.IsPrimary && keyOrder(ctx, adapter, provider, tableName, col).Ordered(), nil
+		}
+		if col.IsPrimary {
+			primary = append(primary, col)
+		}
+	}
+	if named != "" {
+		return "", false, fmt.Errorf("table %s has no column %s", tableName, named)
+	}
+
+	for _, col := range columns {
+		if updatedAtColumns[strings.ReplaceAll(strings.ToLower(col.Name), "_", "")] {
+			return col.Name, false, nil
+		}
+	}
+	if len(primary) == 1 && keyOrder(ctx, adapter, provider, tableName, primary[0]).Ordered() {
+		return primary[0].Name, true, nil
+	}
+	return "", false, nil
+}
+
+// keyOrderSample is how many keys of a table are sampled to tell whether
+// they are ULIDs or UUIDv7s
+const keyOrderSample = 20
+
+// keyOrder tells how the values of a primary key column sort, from a
+// sample of them when its type doesn't tell
+func keyOrder(ctx context.Context, adapter database.DatabaseAdapter, provider, tableName string, col types.SchemaColumn) common.KeyOrder {
+	var sample []any
+	query := fmt.Sprintf("SELECT %s AS k FROM %s LIMIT %d",
+		quoteIdentifier(provider, col.Name), quoteIdentifier(provider, tableName), keyOrderSample)
+	if result, err := adapter.ExecuteQuery(ctx, query); err == nil {
+		for _, row := range result.Rows {
+			sample = append(sample, row["k"])
+		}
+	}
+	return common.DetectKeyOrder(col.Type, col.Default, sample)
+}
+
+// maxValue returns the highest value of a column as the database writes it
+// as text, which compares back against the column the same way. It's "" for
+// an empty table. The value is read by ordering rather than with MAX, which
+// PostgreSQL has no version of for uuid columns.
+func maxValue(ctx context.Context, adapter database.DatabaseAdapter, provider, tableName, column string) (string, error) {
+	quoted := quoteIdentifier(provider, column)
+	var expr string
+	switch provider {
+	case "postgresql", "postgres":
+		expr = quoted + "::text"
+	case "mysql":
+		expr = fmt.Sprintf("CAST(%s AS CHAR)", quoted)
+	default:
+		expr = fmt.Sprintf("CAST(%s AS TEXT)", quoted)
+	}
+
+	query := fmt.Sprintf("SELECT %s AS watermark FROM %s WHERE %s IS NOT NULL ORDER BY %s DESC LIMIT 1",
+		expr, quoteIdentifier(provider, tableName), quoted, quoted)
+	result, err := adapter.ExecuteQuery(ctx, query)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the watermark of %s: %w", tableName, err)
+	}
+	if len(result.Rows) == 0 || result.Rows[0]["watermark"] == nil {
+		return "", nil
+	}
+	return fmt.Sprint(result.Rows[0]["watermark"]), nil
+}
+
+// changedRows reads the rows of a table from a watermark on
+func changedRows(ctx context.Context, adapter database.DatabaseAdapter, provider, tableName, column, since string, strict bool) ([]map[string]interface{}, error) {
+	op := ">="
+	if strict {
+		op = ">"
+	}
+	placeholder := "?"
+	if provider == "postgresql" || provider == "postgres" {
+		placeholder = "$1"
+	}
+
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s %s %s ORDER BY %s",
+		quoteIdentifier(provider, tableName), quoteIdentifier(provider, column), op, placeholder, quoteIdentifier(provider, column))
+	result, err := adapter.ExecuteQuery(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read changed rows of %s: %w", tableName, err)
+	}
+	return result.Rows, nil
+}
+
+func quoteIdentifier(provider, name string) string {
+	if provider == "mysql" {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// WatermarkManager keeps the watermarks of incremental exports in the
+// project's state store
+type WatermarkManager struct {
+	migrationsPath string
+}
+
+func NewWatermarkManager(migrationsPath string) *WatermarkManager {
+	return &WatermarkManager{migrationsPath: migrationsPath}
+}
+
+// Load returns the watermark of each table, by table name
+func (m *WatermarkManager) Load() (map[string]types.ExportWatermark, error) {
+	marks := map[string]types.ExportWatermark{}
+	if !state.Exists(m.migrationsPath) {
+		return marks, nil
+	}
+
+	st, err := state.Open(m.migrationsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer st.Close()
+
+	documents, err := st.List(state.KindExportWatermarks)
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range documents {
+		var mark types.ExportWatermark
+		if err := json.Unmarshal(doc.Value, &mark); err != nil {
+			return nil, fmt.Errorf("failed to parse export watermark of %s: %w", doc.Key, err)
+		}
+		marks[doc.Key] = mark
+	}
+	return marks, nil
+}
+
+func (m *WatermarkManager) Save(marks map[string]types.ExportWatermark) error {
+	st, err := state.Open(m.migrationsPath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	documents := make(map[string]any, len(marks))
+	for table, mark := range marks {
+		documents[table] = mark
+	}
+	return st.Replace(state.KindExportWatermarks, documents)
+}
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -30,15 +32,23 @@ type GenerationCache struct {
 	// Generated file checksums (to detect manual edits)
 	GeneratedFileChecksums map[string]string `json:"generated_file_checksums"` // generated file → hash
 
+	// Files generated from query groups, to remove the ones a later run no
+	// longer generates
+	QueryOutputs []string `json:"query_outputs,omitempty"`
+
 	// Metadata
 	LastGeneration time.Time `json:"last_generation"`
+
+	dir string // output directory the cache is kept in
 	
 	mu sync.RWMutex
 }
 
-// NewGenerationCache creates a new cache or loads existing one
-func NewGenerationCache() *GenerationCache {
+// NewGenerationCache creates a new cache or loads the existing one from an
+// output directory
+func NewGenerationCache(dir string) *GenerationCache {
 	cache := &GenerationCache{
+		dir:                    dir,
 		Version:                "1.0",
 		QueryFileChecksums:     make(map[string]string),
 		SchemaChecksum:         "",
@@ -118,6 +128,143 @@ func (c *GenerationCache) ShouldRegenerateQuery(queryFile string, currentHash st
 	return cachedHash != currentHash // Changed file
 }
 
+// ComputeQueryChecksums hashes every query file, keyed by path
+func ComputeQueryChecksums(queriesDir string) (map[string]string, error) {
+	files, err := filepath.Glob(filepath.Join(queriesDir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := make(map[string]string, len(files))
+	for _, file := range files {
+		hash, err := ComputeFileChecksum(file)
+		if err != nil {
+			return nil, err
+		}
+		checksums[file] = hash
+	}
+	return checksums, nil
+}
+
+// UpToDate reports whether nothing generation reads or writes changed since
+// the cached run: the schema, the config, every query file and the
+// generated files
+func (c *GenerationCache) UpToDate(schemaHash, configHash string, queryChecksums map[string]string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.LastGeneration.IsZero() || c.SchemaChecksum != schemaHash || c.ConfigChecksum != configHash {
+		return false
+	}
+	if len(c.QueryFileChecksums) != len(queryChecksums) {
+		return false
+	}
+	for file, hash := range queryChecksums {
+		if c.QueryFileChecksums[file] != hash {
+			return false
+		}
+	}
+	return c.outputsIntact()
+}
+
+// OutputsIntact reports whether the generated files are still as the
+// cached run left them, i.e. none was edited or deleted
+func (c *GenerationCache) OutputsIntact() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.outputsIntact()
+}
+
+func (c *GenerationCache) outputsIntact() bool {
+	for file, hash := range c.GeneratedFileChecksums {
+		if current, err := ComputeFileChecksum(file); err != nil || current != hash {
+			return false
+		}
+	}
+	return true
+}
+
+// PruneQueries forgets query files that no longer exist
+func (c *GenerationCache) PruneQueries(queryChecksums map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for file := range c.QueryFileChecksums {
+		if _, ok := queryChecksums[file]; !ok {
+			delete(c.QueryFileChecksums, file)
+			delete(c.QueryTableDeps, file)
+		}
+	}
+}
+
+// RecordOutputs hashes every file in the output directory, so later runs
+// can tell whether the generated code was changed
+func (c *GenerationCache) RecordOutputs() error {
+	checksums := make(map[string]string)
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() == cacheFileName {
+			return err
+		}
+		hash, err := ComputeFileChecksum(path)
+		if err != nil {
+			return err
+		}
+		checksums[path] = hash
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.GeneratedFileChecksums = checksums
+	return nil
+}
+
+// RefreshOutputs records the generated files of an output directory in its
+// cache again, after post-generate hooks such as formatters rewrote them.
+// Directories that weren't generated into since are left alone, so edits
+// to skipped files are still noticed.
+func RefreshOutputs(dir string, since time.Time) error {
+	if _, err := os.Stat(filepath.Join(dir, cacheFileName)); os.IsNotExist(err) {
+		return nil
+	}
+
+	cache := NewGenerationCache(dir)
+	if cache.LastGeneration.Before(since) {
+		return nil
+	}
+	if err := cache.RecordOutputs(); err != nil {
+		return err
+	}
+	return cache.Save()
+}
+
+// SetQueryOutputs records the files generated from query groups, removing
+// the ones the cached run generated that aren't generated anymore, such as
+// after the layout changed or a query file was deleted
+func (c *GenerationCache) SetQueryOutputs(paths []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		current[path] = true
+	}
+	for _, path := range c.QueryOutputs {
+		if !current[path] {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	c.QueryOutputs = append([]string(nil), paths...)
+	sort.Strings(c.QueryOutputs)
+	return nil
+}
+
 // ShouldRegenerateAll checks if full regeneration is needed
 func (c *GenerationCache) ShouldRegenerateAll(schemaHash, configHash string) bool {
 	c.mu.RLock()
@@ -158,6 +305,7 @@ func (c *GenerationCache) GetAffectedQueries(changedTables []string) []string {
 	for queryFile := range affectedSet {
 		affected = append(affected, queryFile)
 	}
+	sort.Strings(affected)
 	return affected
 }
 
@@ -208,6 +356,7 @@ func DetectSchemaChanges(oldSchema, newSchema *parser.Schema) []string {
 	for tableName := range changed {
 		result = append(result, tableName)
 	}
+	sort.Strings(result)
 	return result
 }
 
@@ -285,10 +434,10 @@ func (c *GenerationCache) Save() error {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	cacheFile := filepath.Join("flash_gen", cacheFileName)
+	cacheFile := filepath.Join(c.dir, cacheFileName)
 	
 	// Ensure directory exists
-	if err := os.MkdirAll("flash_gen", 0755); err != nil {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
 		return err
 	}
 
@@ -302,7 +451,7 @@ func (c *GenerationCache) Save() error {
 
 // Load reads the cache from disk
 func (c *GenerationCache) Load() error {
-	cacheFile := filepath.Join("flash_gen", cacheFileName)
+	cacheFile := filepath.Join(c.dir, cacheFileName)
 
 	data, err := os.ReadFile(cacheFile)
 	if err != nil {
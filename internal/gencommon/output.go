@@ -0,0 +1,193 @@
+package gencommon
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+	"github.com/Lumos-Labs-HQ/flash/internal/utils"
+)
+
+// Output applies the gen settings that shape generated files: how queries
+// are grouped into files, the names used and the header comment
+type Output struct {
+	gen      config.Gen
+	language string
+	comment  string // line comment of the language, e.g. "//"
+	header   *template.Template
+}
+
+// HeaderData is what a gen.header template can use
+type HeaderData struct {
+	File     string // generated file name
+	Language string // go, js or python
+}
+
+// NewOutput checks the gen settings for a language whose comments start
+// with comment
+func NewOutput(gen config.Gen, language, comment string) (*Output, error) {
+	if err := gen.Validate(); err != nil {
+		return nil, err
+	}
+
+	o := &Output{gen: gen, language: language, comment: comment}
+	if gen.Header != "" {
+		tmpl, err := template.New("header").Parse(gen.Header)
+		if err != nil {
+			return nil, fmt.Errorf("gen.header: %w", err)
+		}
+		// Render once so unknown fields fail before anything is written
+		if err := tmpl.Execute(&strings.Builder{}, HeaderData{Language: language}); err != nil {
+			return nil, fmt.Errorf("gen.header: %w", err)
+		}
+		o.header = tmpl
+	}
+	return o, nil
+}
+
+// Header returns the comment lines generated files start with
+func (o *Output) Header(file string) string {
+	if o.header == nil {
+		return o.comment + " Code generated by FlashORM. DO NOT EDIT.\n"
+	}
+
+	var rendered strings.Builder
+	_ = o.header.Execute(&rendered, HeaderData{File: file, Language: o.language})
+
+	var sb strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(rendered.String(), "\n"), "\n") {
+		if line = strings.TrimRight(line, " \t\r"); line == "" {
+			sb.WriteString(o.comment + "\n")
+		} else {
+			sb.WriteString(o.comment + " " + line + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// FunctionName names the function of a query, followed by an optional
+// suffix word such as "page", in gen.naming.functions or else in the
+// language's convention def
+func (o *Output) FunctionName(def, queryName, suffix string) string {
+	convention := o.gen.Naming.Functions
+	if convention == "" {
+		convention = def
+	}
+
+	if convention == config.NamingSnake {
+		name := utils.ToSnakeCase(queryName)
+		if suffix != "" {
+			name += "_" + suffix
+		}
+		return name
+	}
+	return utils.Uncapitalize(queryName) + utils.Capitalize(suffix)
+}
+
+// ModelName names the model of a table, taking the gen.naming.models
+// override when there is one and convert's name otherwise
+func (o *Output) ModelName(table string, convert func(string) string) string {
+	if name, ok := o.gen.Naming.Models[table]; ok {
+		return name
+	}
+	for override, name := range o.gen.Naming.Models {
+		if strings.EqualFold(override, table) {
+			return name
+		}
+	}
+	return convert(table)
+}
+
+// QueryGroup is the queries generated into one file
+type QueryGroup struct {
+	Name    string // file name, without extension
+	Queries []*parser.Query
+	Sources []string // query files the queries come from, without extension
+
+	// perFile groups hold one query file each, so the cache can tell
+	// whether they changed
+	perFile bool
+}
+
+// GroupQueries splits queries into the files gen.layout asks for, sorted
+// by name
+func (o *Output) GroupQueries(queries []*parser.Query) []QueryGroup {
+	groups := make(map[string]*QueryGroup)
+	for _, query := range queries {
+		sourceFile := query.SourceFile
+		if sourceFile == "" {
+			sourceFile = "queries"
+		}
+
+		name := sourceFile
+		switch o.gen.Layout {
+		case config.LayoutSingle:
+			name = "queries"
+		case config.LayoutPerTable:
+			name = strings.ToLower(utils.ExtractTableName(query.SQL))
+			if name == "" {
+				name = "queries"
+			}
+		}
+
+		group, ok := groups[name]
+		if !ok {
+			group = &QueryGroup{Name: name, perFile: o.gen.Layout == "" || o.gen.Layout == config.LayoutPerFile}
+			groups[name] = group
+		}
+		group.Queries = append(group.Queries, query)
+		if !containsString(group.Sources, sourceFile) {
+			group.Sources = append(group.Sources, sourceFile)
+		}
+	}
+
+	sorted := make([]QueryGroup, 0, len(groups))
+	for _, group := range groups {
+		sort.Strings(group.Sources)
+		sorted = append(sorted, *group)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ShouldRegenerateGroup checks if the file of a query group needs
+// regeneration. Only per_file groups can be checked against the cache;
+// the other layouts mix query files, so their files are always rebuilt.
+func ShouldRegenerateGroup(cache *GenerationCache, queriesDir string, group QueryGroup, fullRegen bool) bool {
+	if fullRegen || !group.perFile {
+		return true
+	}
+	queryFile := filepath.Join(queriesDir, group.Sources[0]+".sql")
+	currentHash, _ := ComputeFileChecksum(queryFile)
+	return cache.ShouldRegenerateQuery(queryFile, currentHash)
+}
+
+// UpdateCacheForGroup updates the cache after generating the file of a
+// query group
+func UpdateCacheForGroup(cache *GenerationCache, queriesDir string, group QueryGroup, generatedPath string) {
+	for _, source := range group.Sources {
+		queryFile := filepath.Join(queriesDir, source+".sql")
+		currentHash, _ := ComputeFileChecksum(queryFile)
+
+		var sourceQueries []*parser.Query
+		for _, query := range group.Queries {
+			if query.SourceFile == source || query.SourceFile == "" && source == "queries" {
+				sourceQueries = append(sourceQueries, query)
+			}
+		}
+		UpdateCacheForFile(cache, queryFile, currentHash, ExtractTableDependencies(sourceQueries), generatedPath)
+	}
+}
@@ -0,0 +1,88 @@
+package gencommon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+)
+
+// CursorQueries builds the first-page and next-page SQL for a query annotated
+// with `-- paginate: cursor(...)`. The original query is wrapped as a derived
+// table so its own filters stay untouched, and the keyset condition, ORDER BY
+// and LIMIT are applied outside it. Cursor values and the limit are bound
+// after the query's own parameters.
+// This is shared by all generators (Go, JS, Python)
+func CursorQueries(query *parser.Query, provider string) (first, next string) {
+	base := strings.TrimSuffix(strings.TrimSpace(query.SQL), ";")
+
+	useDollar := strings.Contains(base, "$1")
+	if !useDollar && !strings.Contains(base, "?") {
+		useDollar = provider == "" || provider == "postgresql" || provider == "postgres"
+	}
+
+	nextParam := len(query.Params) + 1
+	placeholder := func() string {
+		if !useDollar {
+			return "?"
+		}
+		p := fmt.Sprintf("$%d", nextParam)
+		nextParam++
+		return p
+	}
+
+	cols := query.Paginate.Columns
+	direction, op := "", ">"
+	if query.Paginate.Descending {
+		direction, op = " DESC", "<"
+	}
+
+	orderParts := make([]string, len(cols))
+	for i, col := range cols {
+		orderParts[i] = col + direction
+	}
+	orderBy := strings.Join(orderParts, ", ")
+
+	wrapped := fmt.Sprintf("SELECT * FROM (%s) AS flash_page", base)
+	first = fmt.Sprintf("%s ORDER BY %s LIMIT %s", wrapped, orderBy, placeholder())
+
+	// Reset numbering: the first-page limit shares the slot of the first cursor value
+	nextParam = len(query.Params) + 1
+	values := make([]string, len(cols))
+	for i := range cols {
+		values[i] = placeholder()
+	}
+	var condition string
+	if len(cols) == 1 {
+		condition = fmt.Sprintf("%s %s %s", cols[0], op, values[0])
+	} else {
+		condition = fmt.Sprintf("(%s) %s (%s)", strings.Join(cols, ", "), op, strings.Join(values, ", "))
+	}
+	next = fmt.Sprintf("%s WHERE %s ORDER BY %s LIMIT %s", wrapped, condition, orderBy, placeholder())
+
+	return first, next
+}
+
+// CursorColumnIndexes validates a paginated query and returns the position of
+// each cursor column within the query's result columns.
+func CursorColumnIndexes(query *parser.Query, columns []*parser.QueryColumn) ([]int, error) {
+	if strings.ToLower(query.Cmd) != ":many" {
+		return nil, fmt.Errorf("query '%s': cursor pagination requires :many, got %s", query.Name, query.Cmd)
+	}
+
+	indexes := make([]int, len(query.Paginate.Columns))
+	for i, name := range query.Paginate.Columns {
+		indexes[i] = -1
+		for j, col := range columns {
+			if strings.EqualFold(col.Name, name) {
+				indexes[i] = j
+				break
+			}
+		}
+		if indexes[i] == -1 {
+			return nil, fmt.Errorf("query '%s': paginate column '%s' is not selected by the query", query.Name, name)
+		}
+	}
+
+	return indexes, nil
+}
@@ -0,0 +1,28 @@
+package gencommon
+
+import (
+	"fmt"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+	"github.com/Lumos-Labs-HQ/flash/internal/utils"
+)
+
+// ParseInputs parses the schema and the queries of a generator. Syntax
+// errors in the schema don't stop the queries from being checked, so every
+// diagnostic is reported in one run.
+func ParseInputs(schemaParser *parser.SchemaParser, queryParser *parser.QueryParser) (*parser.Schema, []*parser.Query, error) {
+	schema, schemaErr := schemaParser.Parse()
+	if _, ok := utils.AsDiagnostics(schemaErr); schemaErr != nil && (!ok || schema == nil) {
+		return nil, nil, fmt.Errorf("failed to parse schema: %w", schemaErr)
+	}
+
+	queries, queryErr := queryParser.Parse(schema)
+	if _, ok := utils.AsDiagnostics(queryErr); queryErr != nil && !ok {
+		queryErr = fmt.Errorf("failed to parse queries: %w", queryErr)
+	}
+
+	if err := utils.JoinDiagnostics(schemaErr, queryErr); err != nil {
+		return nil, nil, err
+	}
+	return schema, queries, nil
+}
@@ -0,0 +1,71 @@
+package gencommon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+)
+
+// RelationQuery builds the SQL that loads the rows of a relation included
+// with `-- include:`. It stops inside the IN list of keys, which generated
+// code completes with one placeholder per key and a closing parenthesis.
+// Rows deleted through the soft-delete column are left out.
+// This is shared by all generators (Go, JS, Python)
+func RelationQuery(rel *parser.Relation, softDeleteColumn string) string {
+	return selectByKey(rel.Table, rel.RelatedColumn, softDeleteColumn) + " IN ("
+}
+
+// HasIncludes reports whether any of the queries loads relations
+func HasIncludes(queries []*parser.Query) bool {
+	for _, query := range queries {
+		if len(query.Includes) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// LoaderTables returns the tables that get a batch loader, which are the
+// ones with a single column primary key, along with that column
+func LoaderTables(schema *parser.Schema) ([]*parser.Table, []*parser.Column) {
+	var tables []*parser.Table
+	var keys []*parser.Column
+	for _, table := range schema.Tables {
+		for _, col := range table.Columns {
+			if col.PrimaryKey {
+				tables = append(tables, table)
+				keys = append(keys, col)
+				break
+			}
+		}
+	}
+	return tables, keys
+}
+
+// LoaderQuery builds the SQL of a table's batch loader, which loads the rows
+// of a list of primary keys. With arrayParam the keys are passed as a single
+// PostgreSQL array; otherwise the SQL stops inside an IN list, like
+// RelationQuery's.
+func LoaderQuery(table *parser.Table, key *parser.Column, softDeleteColumn string, arrayParam bool) string {
+	sql := selectByKey(table, key.Name, softDeleteColumn)
+	if arrayParam {
+		return sql + " = ANY($1)"
+	}
+	return sql + " IN ("
+}
+
+// selectByKey selects all the columns of table, up to the condition on
+// column, leaving out soft-deleted rows
+func selectByKey(table *parser.Table, column, softDeleteColumn string) string {
+	columns := make([]string, len(table.Columns))
+	condition := ""
+	for i, col := range table.Columns {
+		columns[i] = col.Name
+		if softDeleteColumn != "" && strings.EqualFold(col.Name, softDeleteColumn) {
+			condition = col.Name + " IS NULL AND "
+		}
+	}
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s%s",
+		strings.Join(columns, ", "), table.Name, condition, column)
+}
@@ -0,0 +1,92 @@
+package gencommon
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Snapshot holds the generated files of output directories, so a run of
+// the generators can be compared with the files before it and undone
+type Snapshot struct {
+	files   map[string][]byte
+	missing []string // output directories that didn't exist
+}
+
+// TakeSnapshot reads every file under the output directories
+func TakeSnapshot(dirs ...string) (*Snapshot, error) {
+	s := &Snapshot{files: make(map[string][]byte)}
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			s.missing = append(s.missing, dir)
+			continue
+		}
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			s.files[path] = content
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Changed returns the files that were added, changed or removed in after,
+// sorted. The generation cache is left out as it changes on every run.
+func (s *Snapshot) Changed(after *Snapshot) []string {
+	var changed []string
+	for path, content := range after.files {
+		if filepath.Base(path) == cacheFileName {
+			continue
+		}
+		if before, ok := s.files[path]; !ok || !bytes.Equal(before, content) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range s.files {
+		if _, ok := after.files[path]; !ok && filepath.Base(path) != cacheFileName {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// Restore puts the files back as they were in the snapshot, removing the
+// ones after added
+func (s *Snapshot) Restore(after *Snapshot) error {
+	for path := range after.files {
+		if _, ok := s.files[path]; !ok {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	for path, content := range s.files {
+		if current, ok := after.files[path]; ok && bytes.Equal(current, content) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return err
+		}
+	}
+	for _, dir := range s.missing {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
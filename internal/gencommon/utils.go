@@ -2,6 +2,7 @@ package gencommon
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/Lumos-Labs-HQ/flash/internal/parser"
 	"github.com/Lumos-Labs-HQ/flash/internal/utils"
@@ -29,9 +30,21 @@ func ExtractTableDependencies(queries []*parser.Query) []string {
 	for table := range tableSet {
 		tables = append(tables, table)
 	}
+	sort.Strings(tables)
 	return tables
 }
 
+// SortedSourceFiles returns the query files of grouped queries in name
+// order, so generators visit them in the same order on every run
+func SortedSourceFiles(queryGroups map[string][]*parser.Query) []string {
+	sourceFiles := make([]string, 0, len(queryGroups))
+	for sourceFile := range queryGroups {
+		sourceFiles = append(sourceFiles, sourceFile)
+	}
+	sort.Strings(sourceFiles)
+	return sourceFiles
+}
+
 // ShouldRegenerateFile checks if a file needs regeneration based on cache
 func ShouldRegenerateFile(cache *GenerationCache, queryFile, currentHash string, fullRegen bool) bool {
 	if fullRegen {
@@ -45,6 +58,12 @@ func PrintSkipMessage(sourceFile, extension string) {
 	fmt.Printf("⏭️  Skipping %s%s (unchanged)\n", sourceFile, extension)
 }
 
+// PrintUpToDateMessage prints that generation was skipped because nothing
+// changed
+func PrintUpToDateMessage() {
+	fmt.Println("⏭️  Schema, queries and generated code unchanged, nothing to generate (use --force to regenerate)")
+}
+
 // PrintGenerateMessage prints a generation message
 func PrintGenerateMessage(sourceFile, extension string) {
 	fmt.Printf("🔄 Generating %s%s\n", sourceFile, extension)
@@ -0,0 +1,182 @@
+package gogen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/gencommon"
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+	"github.com/Lumos-Labs-HQ/flash/internal/utils"
+)
+
+// generateBatch writes batch.go, which lets callers queue several generated
+// query calls and run them in order in one transaction. database/sql has no
+// pipelining, so each call is still a round trip; the transaction reuses
+// the statements the Queries prepared before rather than preparing them
+// again for every batch.
+func (g *Generator) generateBatch(queries []*parser.Query) error {
+	code := gencommon.GetBuilder()
+	defer gencommon.PutBuilder(code)
+
+	// Only signature types appear in this file; row structs live elsewhere
+	needsTime := false
+	for _, query := range queries {
+		if strings.Contains(g.batchResultType(query), "time.Time") {
+			needsTime = true
+		}
+		if len(query.Params) <= 3 {
+			for _, param := range query.Params {
+				if strings.Contains(g.mapParamTypeToGo(param.Type), "time.Time") {
+					needsTime = true
+				}
+			}
+		}
+	}
+
+	code.WriteString(g.out.Header("batch.go") + "\n")
+	code.WriteString("package flash_gen\n\n")
+	code.WriteString("import (\n")
+	code.WriteString("\t\"database/sql\"\n")
+	code.WriteString("\t\"errors\"\n")
+	if needsTime {
+		code.WriteString("\t\"time\"\n")
+	}
+	code.WriteString(")\n\n")
+
+	code.WriteString("// Batch queues query calls and runs them in order in one transaction\n")
+	code.WriteString("type Batch struct {\n")
+	code.WriteString("\tq     *Queries\n")
+	code.WriteString("\titems []func(q *Queries) error\n")
+	code.WriteString("}\n\n")
+	code.WriteString("// Batch starts a new batch. Results are delivered to the callbacks passed\n")
+	code.WriteString("// to each Queue method once Send is called.\n")
+	code.WriteString("func (q *Queries) Batch() *Batch {\n")
+	code.WriteString("\treturn &Batch{q: q}\n")
+	code.WriteString("}\n\n")
+	code.WriteString("// Len returns the number of queued calls\n")
+	code.WriteString("func (b *Batch) Len() int {\n")
+	code.WriteString("\treturn len(b.items)\n")
+	code.WriteString("}\n\n")
+	code.WriteString("// Send executes all queued calls in order, one round trip each. When the\n")
+	code.WriteString("// underlying DBTX can begin a transaction the calls run in it, on the\n")
+	code.WriteString("// statements already prepared, and are committed together; the first\n")
+	code.WriteString("// failing call rolls the whole batch back.\n")
+	code.WriteString("func (b *Batch) Send() error {\n")
+	code.WriteString("\titems := b.items\n")
+	code.WriteString("\tb.items = nil\n")
+	code.WriteString("\tif len(items) == 0 {\n")
+	code.WriteString("\t\treturn nil\n")
+	code.WriteString("\t}\n\n")
+	code.WriteString("\tbeginner, ok := b.q.db.(interface{ Begin() (*sql.Tx, error) })\n")
+	code.WriteString("\tif !ok {\n")
+	code.WriteString("\t\tfor _, item := range items {\n")
+	code.WriteString("\t\t\tif err := item(b.q); err != nil {\n")
+	code.WriteString("\t\t\t\treturn err\n")
+	code.WriteString("\t\t\t}\n")
+	code.WriteString("\t\t}\n")
+	code.WriteString("\t\treturn nil\n")
+	code.WriteString("\t}\n\n")
+	code.WriteString("\ttx, err := beginner.Begin()\n")
+	code.WriteString("\tif err != nil {\n")
+	code.WriteString("\t\treturn err\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\ttxq := &Queries{db: tx, stmts: make(map[string]*sql.Stmt), tx: tx, parent: b.q}\n")
+	code.WriteString("\tdefer txq.Close()\n\n")
+	code.WriteString("\tfor _, item := range items {\n")
+	code.WriteString("\t\tif err := item(txq); err != nil {\n")
+	code.WriteString("\t\t\ttx.Rollback()\n")
+	code.WriteString("\t\t\treturn err\n")
+	code.WriteString("\t\t}\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\treturn tx.Commit()\n")
+	code.WriteString("}\n\n")
+
+	seenMethods := make(map[string]bool)
+	for _, query := range queries {
+		methodName := utils.ToPascalCase(query.Name)
+		if seenMethods[methodName] {
+			continue
+		}
+		seenMethods[methodName] = true
+		g.generateBatchQueueMethod(code, query)
+	}
+
+	batchPath := filepath.Join("flash_gen", "batch.go")
+	return os.WriteFile(batchPath, []byte(code.String()), 0644)
+}
+
+// generateBatchQueueMethod writes QueueX for a single query. The callback
+// receives the same typed result the direct method would return.
+func (g *Generator) generateBatchQueueMethod(code *strings.Builder, query *parser.Query) {
+	methodName := utils.ToPascalCase(query.Name)
+	resultType := g.batchResultType(query)
+
+	var params, args []string
+	if len(query.Params) > 3 {
+		params = append(params, fmt.Sprintf("arg %sParams", methodName))
+		args = append(args, "arg")
+	} else {
+		for _, param := range query.Params {
+			paramName := utils.ToSnakeCase(param.Name)
+			params = append(params, fmt.Sprintf("%s %s", paramName, g.mapParamTypeToGo(param.Type)))
+			args = append(args, paramName)
+		}
+	}
+
+	callback := "fn func(error)"
+	if resultType != "" {
+		callback = fmt.Sprintf("fn func(%s, error)", resultType)
+	}
+	params = append(params, callback)
+
+	code.WriteString(fmt.Sprintf("// Queue%s queues a call to %s\n", methodName, methodName))
+	code.WriteString(fmt.Sprintf("func (b *Batch) Queue%s(%s) {\n", methodName, strings.Join(params, ", ")))
+	code.WriteString("\tb.items = append(b.items, func(q *Queries) error {\n")
+	if resultType != "" {
+		code.WriteString(fmt.Sprintf("\t\tresult, err := q.%s(%s)\n", methodName, strings.Join(args, ", ")))
+		code.WriteString("\t\tif fn != nil {\n")
+		code.WriteString("\t\t\tfn(result, err)\n")
+		code.WriteString("\t\t}\n")
+	} else {
+		code.WriteString(fmt.Sprintf("\t\terr := q.%s(%s)\n", methodName, strings.Join(args, ", ")))
+		code.WriteString("\t\tif fn != nil {\n")
+		code.WriteString("\t\t\tfn(err)\n")
+		code.WriteString("\t\t}\n")
+	}
+	// A missing row is a per-call result, not a reason to abort the batch
+	code.WriteString("\t\tif errors.Is(err, sql.ErrNoRows) {\n")
+	code.WriteString("\t\t\treturn nil\n")
+	code.WriteString("\t\t}\n")
+	code.WriteString("\t\treturn err\n")
+	code.WriteString("\t})\n")
+	code.WriteString("}\n\n")
+}
+
+// batchResultType mirrors the non-error return type chosen by generateQueryMethod.
+// An empty string means the method only returns an error.
+func (g *Generator) batchResultType(query *parser.Query) string {
+	columns := g.expandWildcardColumns(query)
+	methodName := utils.ToPascalCase(query.Name)
+	cmd := strings.ToLower(query.Cmd)
+
+	switch {
+	case cmd == ":one":
+		if len(columns) == 1 {
+			return g.mapColumnTypeToGo(columns[0].Type, columns[0].Nullable)
+		}
+		return methodName + "Row"
+	case cmd == ":many":
+		if len(columns) == 1 {
+			return "[]" + g.mapColumnTypeToGo(columns[0].Type, columns[0].Nullable)
+		}
+		return "[]" + methodName + "Row"
+	case cmd == ":exec" || utils.IsModifyingQuery(query.SQL):
+		return ""
+	case cmd == ":execresult":
+		return "sql.Result"
+	default:
+		return ""
+	}
+}
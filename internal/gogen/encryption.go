@@ -0,0 +1,286 @@
+package gogen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/gencommon"
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+	"github.com/Lumos-Labs-HQ/flash/internal/utils"
+)
+
+// generateEncryptionHelpers writes the AES-GCM helpers that encrypt the
+// params written to encrypted columns and decrypt the columns read from
+// them. The file is removed when no column is encrypted.
+func (g *Generator) generateEncryptionHelpers() error {
+	helpersPath := filepath.Join("flash_gen", "encryption.go")
+
+	if !g.Config.Encryption.Enabled() {
+		if err := os.Remove(helpersPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	keyEnv := g.Config.Encryption.KeyEnvName()
+
+	code := gencommon.GetBuilder()
+	defer gencommon.PutBuilder(code)
+
+	code.WriteString(g.out.Header("encryption.go") + "\n")
+	code.WriteString("package flash_gen\n\n")
+	code.WriteString("import (\n")
+	code.WriteString("\t\"crypto/aes\"\n")
+	code.WriteString("\t\"crypto/cipher\"\n")
+	code.WriteString("\t\"crypto/rand\"\n")
+	code.WriteString("\t\"database/sql\"\n")
+	code.WriteString("\t\"encoding/base64\"\n")
+	code.WriteString("\t\"errors\"\n")
+	code.WriteString("\t\"fmt\"\n")
+	code.WriteString("\t\"os\"\n")
+	code.WriteString("\t\"strings\"\n")
+	code.WriteString("\t\"sync\"\n")
+	code.WriteString(")\n\n")
+	code.WriteString("// EncryptionKeyFunc returns the 32 byte AES-256 key of the encrypted columns\n")
+	code.WriteString("type EncryptionKeyFunc func() ([]byte, error)\n\n")
+	code.WriteString("// ErrEncryptionKey is returned when the encryption key is missing or isn't 32 bytes\n")
+	code.WriteString("var ErrEncryptionKey = errors.New(\"encryption key must be 32 bytes\")\n\n")
+	code.WriteString("// ErrDecrypt is returned when an encrypted column can't be decrypted with the key\n")
+	code.WriteString("var ErrDecrypt = errors.New(\"failed to decrypt column\")\n\n")
+	code.WriteString("// Ciphertext is stored as this prefix and the base64 of nonce || sealed\n")
+	code.WriteString("const encryptionPrefix = \"flash:v1:\"\n\n")
+	code.WriteString("var (\n")
+	code.WriteString("\tencryptionMu   sync.Mutex\n")
+	code.WriteString("\tencryptionKey  EncryptionKeyFunc = envEncryptionKey\n")
+	code.WriteString("\tencryptionAEAD cipher.AEAD\n")
+	code.WriteString(")\n\n")
+	code.WriteString(fmt.Sprintf("// SetEncryptionKey replaces the key read from %s with the one fn\n", keyEnv))
+	code.WriteString("// returns, such as a key fetched from a KMS. fn is called once, the first\n")
+	code.WriteString("// time a column is encrypted or decrypted.\n")
+	code.WriteString("func SetEncryptionKey(fn EncryptionKeyFunc) {\n")
+	code.WriteString("\tencryptionMu.Lock()\n")
+	code.WriteString("\tdefer encryptionMu.Unlock()\n")
+	code.WriteString("\tencryptionKey = fn\n")
+	code.WriteString("\tencryptionAEAD = nil\n")
+	code.WriteString("}\n\n")
+	code.WriteString("func envEncryptionKey() ([]byte, error) {\n")
+	code.WriteString(fmt.Sprintf("\tvalue := os.Getenv(%q)\n", keyEnv))
+	code.WriteString("\tif value == \"\" {\n")
+	code.WriteString(fmt.Sprintf("\t\treturn nil, fmt.Errorf(\"%%w: %s is not set\", ErrEncryptionKey)\n", keyEnv))
+	code.WriteString("\t}\n")
+	code.WriteString("\tkey, err := base64.StdEncoding.DecodeString(value)\n")
+	code.WriteString("\tif err != nil {\n")
+	code.WriteString(fmt.Sprintf("\t\treturn nil, fmt.Errorf(\"%%w: %s is not base64\", ErrEncryptionKey)\n", keyEnv))
+	code.WriteString("\t}\n")
+	code.WriteString("\treturn key, nil\n")
+	code.WriteString("}\n\n")
+	code.WriteString("func encryptionCipher() (cipher.AEAD, error) {\n")
+	code.WriteString("\tencryptionMu.Lock()\n")
+	code.WriteString("\tdefer encryptionMu.Unlock()\n")
+	code.WriteString("\tif encryptionAEAD != nil {\n")
+	code.WriteString("\t\treturn encryptionAEAD, nil\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\tkey, err := encryptionKey()\n")
+	code.WriteString("\tif err != nil {\n")
+	code.WriteString("\t\treturn nil, err\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\tif len(key) != 32 {\n")
+	code.WriteString("\t\treturn nil, ErrEncryptionKey\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\tblock, err := aes.NewCipher(key)\n")
+	code.WriteString("\tif err != nil {\n")
+	code.WriteString("\t\treturn nil, err\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\taead, err := cipher.NewGCM(block)\n")
+	code.WriteString("\tif err != nil {\n")
+	code.WriteString("\t\treturn nil, err\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\tencryptionAEAD = aead\n")
+	code.WriteString("\treturn aead, nil\n")
+	code.WriteString("}\n\n")
+	code.WriteString("func encryptString(plaintext string) (string, error) {\n")
+	code.WriteString("\taead, err := encryptionCipher()\n")
+	code.WriteString("\tif err != nil {\n")
+	code.WriteString("\t\treturn \"\", err\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\tnonce := make([]byte, aead.NonceSize())\n")
+	code.WriteString("\tif _, err := rand.Read(nonce); err != nil {\n")
+	code.WriteString("\t\treturn \"\", err\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\tsealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)\n")
+	code.WriteString("\treturn encryptionPrefix + base64.StdEncoding.EncodeToString(sealed), nil\n")
+	code.WriteString("}\n\n")
+	code.WriteString("// decryptString returns the plaintext of a value read from an encrypted\n")
+	code.WriteString("// column. Values written before the column was encrypted have no prefix and\n")
+	code.WriteString("// are returned as they are.\n")
+	code.WriteString("func decryptString(value string) (string, error) {\n")
+	code.WriteString("\tif !strings.HasPrefix(value, encryptionPrefix) {\n")
+	code.WriteString("\t\treturn value, nil\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\taead, err := encryptionCipher()\n")
+	code.WriteString("\tif err != nil {\n")
+	code.WriteString("\t\treturn \"\", err\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\tsealed, err := base64.StdEncoding.DecodeString(value[len(encryptionPrefix):])\n")
+	code.WriteString("\tif err != nil || len(sealed) < aead.NonceSize() {\n")
+	code.WriteString("\t\treturn \"\", ErrDecrypt\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\tplaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)\n")
+	code.WriteString("\tif err != nil {\n")
+	code.WriteString("\t\treturn \"\", ErrDecrypt\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\treturn string(plaintext), nil\n")
+	code.WriteString("}\n\n")
+	code.WriteString("// encryptArgs encrypts the args at indexes, which are written to encrypted\n")
+	code.WriteString("// columns. NULLs stay NULL.\n")
+	code.WriteString("func encryptArgs(args []interface{}, indexes ...int) error {\n")
+	code.WriteString("\tfor _, i := range indexes {\n")
+	code.WriteString("\t\tvar plaintext string\n")
+	code.WriteString("\t\tswitch v := args[i].(type) {\n")
+	code.WriteString("\t\tcase string:\n")
+	code.WriteString("\t\t\tplaintext = v\n")
+	code.WriteString("\t\tcase *string:\n")
+	code.WriteString("\t\t\tif v == nil {\n")
+	code.WriteString("\t\t\t\tcontinue\n")
+	code.WriteString("\t\t\t}\n")
+	code.WriteString("\t\t\tplaintext = *v\n")
+	code.WriteString("\t\tcase sql.NullString:\n")
+	code.WriteString("\t\t\tif !v.Valid {\n")
+	code.WriteString("\t\t\t\tcontinue\n")
+	code.WriteString("\t\t\t}\n")
+	code.WriteString("\t\t\tplaintext = v.String\n")
+	code.WriteString("\t\tcase nil:\n")
+	code.WriteString("\t\t\tcontinue\n")
+	code.WriteString("\t\tdefault:\n")
+	code.WriteString("\t\t\tplaintext = fmt.Sprint(v)\n")
+	code.WriteString("\t\t}\n")
+	code.WriteString("\t\tciphertext, err := encryptString(plaintext)\n")
+	code.WriteString("\t\tif err != nil {\n")
+	code.WriteString("\t\t\treturn err\n")
+	code.WriteString("\t\t}\n")
+	code.WriteString("\t\targs[i] = ciphertext\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\treturn nil\n")
+	code.WriteString("}\n\n")
+	code.WriteString("// decryptColumns decrypts in place the values scanned from encrypted columns\n")
+	code.WriteString("func decryptColumns(dests ...interface{}) error {\n")
+	code.WriteString("\tfor _, dest := range dests {\n")
+	code.WriteString("\t\tvar err error\n")
+	code.WriteString("\t\tswitch d := dest.(type) {\n")
+	code.WriteString("\t\tcase *string:\n")
+	code.WriteString("\t\t\t*d, err = decryptString(*d)\n")
+	code.WriteString("\t\tcase **string:\n")
+	code.WriteString("\t\t\tif *d != nil {\n")
+	code.WriteString("\t\t\t\tvar plaintext string\n")
+	code.WriteString("\t\t\t\tplaintext, err = decryptString(**d)\n")
+	code.WriteString("\t\t\t\t*d = &plaintext\n")
+	code.WriteString("\t\t\t}\n")
+	code.WriteString("\t\tcase *sql.NullString:\n")
+	code.WriteString("\t\t\tif d.Valid {\n")
+	code.WriteString("\t\t\t\td.String, err = decryptString(d.String)\n")
+	code.WriteString("\t\t\t}\n")
+	code.WriteString("\t\t}\n")
+	code.WriteString("\t\tif err != nil {\n")
+	code.WriteString("\t\t\treturn err\n")
+	code.WriteString("\t\t}\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\treturn nil\n")
+	code.WriteString("}\n")
+
+	return os.WriteFile(helpersPath, []byte(code.String()), 0644)
+}
+
+// encryptedParamIndexes returns the indexes of the params of a query that
+// are written to encrypted columns
+func encryptedParamIndexes(params []*parser.Param) []string {
+	var indexes []string
+	for i, param := range params {
+		if param.Encrypted {
+			indexes = append(indexes, fmt.Sprint(i))
+		}
+	}
+	return indexes
+}
+
+// writeEncryptArgs encrypts the params of args written to encrypted
+// columns, returning ret when that fails
+func writeEncryptArgs(code *strings.Builder, indent string, params []*parser.Param, ret string) {
+	indexes := encryptedParamIndexes(params)
+	if len(indexes) == 0 {
+		return
+	}
+	code.WriteString(fmt.Sprintf("%sif err := encryptArgs(args, %s); err != nil {\n", indent, strings.Join(indexes, ", ")))
+	code.WriteString(fmt.Sprintf("%s\treturn %s\n", indent, ret))
+	code.WriteString(fmt.Sprintf("%s}\n", indent))
+}
+
+// decryptTargets returns the fields of a scanned row, or the row itself for
+// a single column, that are read from encrypted columns
+func decryptTargets(row string, columns []*parser.QueryColumn, singleColumn bool) []string {
+	var targets []string
+	for _, col := range columns {
+		if !col.Encrypted {
+			continue
+		}
+		if singleColumn {
+			targets = append(targets, "&"+row)
+		} else {
+			targets = append(targets, "&"+row+"."+utils.ToPascalCase(col.Name))
+		}
+	}
+	return targets
+}
+
+// tableDecryptTargets returns the fields of a scanned model that are read
+// from encrypted columns of its table
+func tableDecryptTargets(row string, table *parser.Table) []string {
+	var targets []string
+	for _, col := range table.Columns {
+		if col.Encrypted {
+			targets = append(targets, "&"+row+"."+utils.ToPascalCase(col.Name))
+		}
+	}
+	return targets
+}
+
+// writeDecrypt decrypts the targets after a successful scan, returning ret
+// when that fails
+func writeDecrypt(code *strings.Builder, indent string, targets []string, ret string) {
+	if len(targets) == 0 {
+		return
+	}
+	code.WriteString(fmt.Sprintf("%sif err := decryptColumns(%s); err != nil {\n", indent, strings.Join(targets, ", ")))
+	code.WriteString(fmt.Sprintf("%s\treturn %s\n", indent, ret))
+	code.WriteString(fmt.Sprintf("%s}\n", indent))
+}
+
+// writeScanDecrypt decrypts the targets of a row scanned into err, once the
+// scan succeeded
+func writeScanDecrypt(code *strings.Builder, targets []string) {
+	if len(targets) == 0 {
+		return
+	}
+	code.WriteString("\tif err == nil {\n")
+	code.WriteString(fmt.Sprintf("\t\terr = decryptColumns(%s)\n", strings.Join(targets, ", ")))
+	code.WriteString("\t}\n")
+}
+
+// errorReturn returns the values a query method returns along with err,
+// matching the signature generateQueryMethod gives it
+func (g *Generator) errorReturn(query *parser.Query, columns []*parser.QueryColumn) string {
+	switch cmd := strings.ToLower(query.Cmd); {
+	case cmd == ":one" && len(columns) == 1:
+		return g.getZeroValue(g.mapColumnTypeToGo(columns[0].Type, columns[0].Nullable)) + ", err"
+	case cmd == ":one":
+		return utils.ToPascalCase(query.Name) + "Row{}, err"
+	case cmd == ":many":
+		return "nil, err"
+	case cmd == ":exec" || utils.IsModifyingQuery(query.SQL):
+		return "err"
+	case cmd == ":execresult":
+		return "nil, err"
+	}
+	return "err"
+}
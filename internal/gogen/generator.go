@@ -14,11 +14,14 @@ import (
 )
 
 type Generator struct {
-	Config       *config.Config
+	Config *config.Config
+	// Force regenerates every file, ignoring the generation cache
+	Force        bool
 	schema       *parser.Schema
 	schemaParser *parser.SchemaParser
 	queryParser  *parser.QueryParser
 	cache        *gencommon.GenerationCache
+	out          *gencommon.Output
 }
 
 func New(cfg *config.Config) *Generator {
@@ -26,21 +29,20 @@ func New(cfg *config.Config) *Generator {
 		Config:       cfg,
 		schemaParser: parser.NewSchemaParser(cfg),
 		queryParser:  parser.NewQueryParser(cfg),
-		cache:        gencommon.NewGenerationCache(),
+		cache:        gencommon.NewGenerationCache("flash_gen"),
 	}
 }
 
 func (g *Generator) Generate() error {
-	if err := os.MkdirAll("flash_gen", 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+	out, err := gencommon.NewOutput(g.Config.Gen, "go", "//")
+	if err != nil {
+		return err
 	}
+	g.out = out
 
-	// Parse schema
-	schema, err := g.schemaParser.Parse()
-	if err != nil {
-		return fmt.Errorf("failed to parse schema: %w", err)
+	if err := os.MkdirAll("flash_gen", 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
 	}
-	g.schema = schema
 
 	// Compute current checksums for incremental generation
 	schemaHash, err := g.cache.ComputeSchemaChecksum(g.Config.SchemaDir)
@@ -49,15 +51,26 @@ func (g *Generator) Generate() error {
 	}
 
 	configHash := g.computeConfigChecksum()
-	
-	// Check if full regeneration is needed
-	fullRegen := g.cache.ShouldRegenerateAll(schemaHash, configHash)
+	queryChecksums, err := gencommon.ComputeQueryChecksums(g.Config.Queries)
+	if err != nil {
+		return fmt.Errorf("failed to read query files: %w", err)
+	}
 
-	// Parse queries
-	queries, err := g.queryParser.Parse(schema)
+	// Nothing to parse when no input or output changed
+	if !g.Force && g.cache.UpToDate(schemaHash, configHash, queryChecksums) {
+		gencommon.PrintUpToDateMessage()
+		return nil
+	}
+
+	// Parse schema and queries
+	schema, queries, err := gencommon.ParseInputs(g.schemaParser, g.queryParser)
 	if err != nil {
-		return fmt.Errorf("failed to parse queries: %w", err)
+		return err
 	}
+	g.schema = schema
+
+	// Check if full regeneration is needed
+	fullRegen := g.Force || g.cache.ShouldRegenerateAll(schemaHash, configHash) || !g.cache.OutputsIntact()
 
 	// Generate models (only if schema changed)
 	if fullRegen || g.cache.SchemaChecksum != schemaHash {
@@ -78,12 +91,37 @@ func (g *Generator) Generate() error {
 		return err
 	}
 
+	if err := g.generatePaginationHelpers(queries); err != nil {
+		return fmt.Errorf("failed to generate pagination helpers: %w", err)
+	}
+
+	if err := g.generateEncryptionHelpers(); err != nil {
+		return fmt.Errorf("failed to generate encryption helpers: %w", err)
+	}
+
+	if err := g.generateRelationHelpers(queries); err != nil {
+		return fmt.Errorf("failed to generate relation helpers: %w", err)
+	}
+
+	if err := g.generateLoaders(queries); err != nil {
+		return fmt.Errorf("failed to generate loaders: %w", err)
+	}
+
+	// Batch spans every query file, so it is rebuilt on each run
+	if err := g.generateBatch(queries); err != nil {
+		return fmt.Errorf("failed to generate batch: %w", err)
+	}
+
 	// Update cache
 	g.cache.UpdateSchemaChecksum(schemaHash)
 	g.cache.UpdateConfigChecksum(configHash)
+	g.cache.PruneQueries(queryChecksums)
 	g.cache.MarkGeneration()
 	
 	// Save cache to disk
+	if err := g.cache.RecordOutputs(); err != nil {
+		fmt.Printf("Warning: failed to record generated files: %v\n", err)
+	}
 	if err := g.cache.Save(); err != nil {
 		// Non-fatal: just log and continue
 		fmt.Printf("Warning: failed to save generation cache: %v\n", err)
@@ -95,13 +133,17 @@ func (g *Generator) Generate() error {
 // computeConfigChecksum computes hash of relevant config fields
 func (g *Generator) computeConfigChecksum() string {
 	// Hash relevant config fields that affect generation
-	configStr := fmt.Sprintf("%s|%s|%s|%v|%v|%v",
+	configStr := fmt.Sprintf("%s|%s|%s|%v|%v|%v|%s|%v|%s|%s",
 		g.Config.SchemaDir,
 		g.Config.Queries,
 		g.Config.Database.Provider,
 		g.Config.Gen.Go.Enabled,
 		g.Config.Gen.JS.Enabled,
 		g.Config.Gen.Python.Enabled,
+		g.Config.SoftDelete.Column,
+		g.Config.Timestamps.Columns(),
+		g.Config.Gen.OutputOptions(),
+		g.Config.Encryption.Options(),
 	)
 	hash := sha256.Sum256([]byte(configStr))
 	return fmt.Sprintf("%x", hash)
@@ -131,7 +173,7 @@ func (g *Generator) generateModels() error {
 	var code strings.Builder
 	code.Grow(estimatedSize)
 
-	code.WriteString("// Code generated by FlashORM. DO NOT EDIT.\n\n")
+	code.WriteString(g.out.Header("models.go") + "\n")
 	code.WriteString("package flash_gen\n\n")
 
 	// Only add imports if needed
@@ -158,7 +200,7 @@ func (g *Generator) generateModels() error {
 	}
 
 	for _, table := range g.schema.Tables {
-		structName := utils.ToPascalCase(table.Name)
+		structName := g.out.ModelName(table.Name, utils.ToPascalCase)
 		code.WriteString(fmt.Sprintf("type %s struct {\n", structName))
 		for _, col := range table.Columns {
 			fieldName := utils.ToPascalCase(col.Name)
@@ -178,7 +220,7 @@ func (g *Generator) generateDB() error {
 	var code strings.Builder
 	code.Grow(500)
 
-	code.WriteString("// Code generated by FlashORM. DO NOT EDIT.\n\n")
+	code.WriteString(g.out.Header("db.go") + "\n")
 	code.WriteString("package flash_gen\n\n")
 	code.WriteString("import (\n")
 	code.WriteString("\t\"database/sql\"\n")
@@ -200,6 +242,30 @@ func (g *Generator) generateDB() error {
 	code.WriteString("type Queries struct {\n")
 	code.WriteString("\tdb    DBTX\n")
 	code.WriteString("\tstmts map[string]*sql.Stmt // Statement cache for hot queries\n")
+	code.WriteString("\t// tx and parent are set on the Queries of a batch, which binds the\n")
+	code.WriteString("\t// statements of parent to tx instead of preparing them again\n")
+	code.WriteString("\ttx     *sql.Tx\n")
+	code.WriteString("\tparent *Queries\n")
+	code.WriteString("}\n\n")
+	code.WriteString("// prepare returns the cached statement of a query, preparing it on first use\n")
+	code.WriteString("func (q *Queries) prepare(key, query string) (*sql.Stmt, error) {\n")
+	code.WriteString("\tif stmt := q.stmts[key]; stmt != nil {\n")
+	code.WriteString("\t\treturn stmt, nil\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\tvar stmt *sql.Stmt\n")
+	code.WriteString("\tvar err error\n")
+	code.WriteString("\tif q.parent != nil {\n")
+	code.WriteString("\t\tif stmt, err = q.parent.prepare(key, query); err == nil {\n")
+	code.WriteString("\t\t\tstmt = q.tx.Stmt(stmt)\n")
+	code.WriteString("\t\t}\n")
+	code.WriteString("\t} else {\n")
+	code.WriteString("\t\tstmt, err = q.db.Prepare(query)\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\tif err != nil {\n")
+	code.WriteString("\t\treturn nil, err\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\tq.stmts[key] = stmt\n")
+	code.WriteString("\treturn stmt, nil\n")
 	code.WriteString("}\n\n")
 	code.WriteString("// Close closes all prepared statements\n")
 	code.WriteString("func (q *Queries) Close() error {\n")
@@ -228,7 +294,8 @@ func (g *Generator) generateQueries(queries []*parser.Query) error {
 
 	usedNames := make(map[string]int)
 
-	for sourceFile, fileQueries := range queryGroups {
+	for _, sourceFile := range gencommon.SortedSourceFiles(queryGroups) {
+		fileQueries := queryGroups[sourceFile]
 		// Pre-allocate: ~200 bytes header + ~400 bytes per query + ~200 bytes per table for types
 		estimatedSize := 200 + (len(fileQueries) * 400) + (len(g.schema.Tables) * 200)
 		var code strings.Builder
@@ -275,6 +342,11 @@ func (g *Generator) generateQueries(queries []*parser.Query) error {
 			if err := g.generateQueryMethod(&code, query); err != nil {
 				return err
 			}
+			if query.Paginate != nil {
+				if err := g.generatePageMethod(&code, query); err != nil {
+					return err
+				}
+			}
 		}
 
 		baseName := strings.TrimSuffix(sourceFile, ".sql")
@@ -343,10 +415,11 @@ func (g *Generator) expandWildcardColumns(query *parser.Query) []*parser.QueryCo
 	expanded := make([]*parser.QueryColumn, 0, len(table.Columns))
 	for _, col := range table.Columns {
 		expanded = append(expanded, &parser.QueryColumn{
-			Name:     col.Name,
-			Type:     col.Type,
-			Table:    table.Name,
-			Nullable: col.Nullable,
+			Name:      col.Name,
+			Type:      col.Type,
+			Table:     table.Name,
+			Nullable:  col.Nullable,
+			Encrypted: col.Encrypted,
 		})
 	}
 
@@ -426,7 +499,7 @@ func (g *Generator) generateQueryMethod(code *strings.Builder, query *parser.Que
 		code.WriteString(fmt.Sprintf("\tstmt := q.stmts[\"%s\"]\n", stmtKey))
 		code.WriteString("\tif stmt == nil {\n")
 		code.WriteString("\t\tvar err error\n")
-		code.WriteString("\t\tstmt, err = q.db.Prepare(query)\n")
+		code.WriteString(fmt.Sprintf("\t\tstmt, err = q.prepare(\"%s\", query)\n", stmtKey))
 		code.WriteString("\t\tif err != nil {\n")
 
 		// Return appropriate zero value based on return type
@@ -452,7 +525,6 @@ func (g *Generator) generateQueryMethod(code *strings.Builder, query *parser.Que
 		}
 
 		code.WriteString("\t\t}\n")
-		code.WriteString(fmt.Sprintf("\t\tq.stmts[\"%s\"] = stmt\n", stmtKey))
 		code.WriteString("\t}\n")
 	}
 
@@ -469,6 +541,7 @@ func (g *Generator) generateQueryMethod(code *strings.Builder, query *parser.Que
 			}
 		}
 		code.WriteString("}\n")
+		writeEncryptArgs(code, "\t", query.Params, g.errorReturn(query, columns))
 	}
 
 	switch {
@@ -507,7 +580,20 @@ func (g *Generator) generateQueryMethod(code *strings.Builder, query *parser.Que
 				code.WriteString(fmt.Sprintf("&result.%s", utils.ToPascalCase(col.Name)))
 			}
 			code.WriteString(")\n")
-			code.WriteString("\treturn result, err\n")
+			writeScanDecrypt(code, decryptTargets("result", columns, false))
+			if len(query.Includes) > 0 {
+				// The relations are loaded once the row is read, since a
+				// transaction can only run one query at a time
+				code.WriteString("\tif err != nil {\n")
+				code.WriteString("\t\treturn result, err\n")
+				code.WriteString("\t}\n")
+				code.WriteString("\trows.Close()\n")
+				code.WriteString(fmt.Sprintf("\titems := []%sRow{result}\n", methodName))
+				code.WriteString(fmt.Sprintf("\terr = q.include%s(items)\n", methodName))
+				code.WriteString("\treturn items[0], err\n")
+			} else {
+				code.WriteString("\treturn result, err\n")
+			}
 		} else {
 			code.WriteString("\n\tvar result ")
 			code.WriteString(g.mapColumnTypeToGo(columns[0].Type, columns[0].Nullable))
@@ -534,6 +620,7 @@ func (g *Generator) generateQueryMethod(code *strings.Builder, query *parser.Que
 			code.WriteString("\t\treturn result, sql.ErrNoRows\n")
 			code.WriteString("\t}\n")
 			code.WriteString("\terr = rows.Scan(&result)\n")
+			writeScanDecrypt(code, decryptTargets("result", columns, true))
 			code.WriteString("\treturn result, err\n")
 		}
 
@@ -577,6 +664,7 @@ func (g *Generator) generateQueryMethod(code *strings.Builder, query *parser.Que
 				code.WriteString("); err != nil {\n")
 				code.WriteString("\t\t\treturn nil, err\n")
 				code.WriteString("\t\t}\n")
+				writeDecrypt(code, "\t\t", decryptTargets("item", columns, false), "nil, err")
 				code.WriteString("\t\titems = append(items, item)\n")
 			} else {
 				code.WriteString("\t\tvar item ")
@@ -584,10 +672,22 @@ func (g *Generator) generateQueryMethod(code *strings.Builder, query *parser.Que
 				code.WriteString("\n\t\tif err := rows.Scan(&item); err != nil {\n")
 				code.WriteString("\t\t\treturn nil, err\n")
 				code.WriteString("\t\t}\n")
+				writeDecrypt(code, "\t\t", decryptTargets("item", columns, true), "nil, err")
 				code.WriteString("\t\titems = append(items, item)\n")
 			}
 			code.WriteString("\t}\n")
-			code.WriteString("\treturn items, rows.Err()\n")
+			if len(query.Includes) > 0 {
+				code.WriteString("\tif err := rows.Err(); err != nil {\n")
+				code.WriteString("\t\treturn nil, err\n")
+				code.WriteString("\t}\n")
+				code.WriteString("\trows.Close()\n")
+				code.WriteString(fmt.Sprintf("\tif err := q.include%s(items); err != nil {\n", methodName))
+				code.WriteString("\t\treturn nil, err\n")
+				code.WriteString("\t}\n")
+				code.WriteString("\treturn items, nil\n")
+			} else {
+				code.WriteString("\treturn items, rows.Err()\n")
+			}
 		}
 
 	case cmd == ":execresult":
@@ -629,7 +729,14 @@ func (g *Generator) generateQueryMethod(code *strings.Builder, query *parser.Que
 			goType := g.mapColumnTypeToGo(col.Type, col.Nullable)
 			code.WriteString(fmt.Sprintf("\t%s %s `json:\"%s\"`\n", fieldName, goType, utils.ToSnakeCase(col.Name)))
 		}
+		for _, rel := range query.Includes {
+			code.WriteString(fmt.Sprintf("\t%s %s `json:\"%s\"`\n", utils.ToPascalCase(rel.Name), g.relationFieldType(rel), utils.ToSnakeCase(rel.Name)))
+		}
 		code.WriteString("}\n\n")
+
+		if len(query.Includes) > 0 {
+			g.generateIncludeLoader(code, query, columns)
+		}
 	}
 
 	return nil
@@ -14,58 +14,38 @@ import (
 
 // generateQueriesIncremental generates queries with incremental support  
 func (g *Generator) generateQueriesIncremental(queries []*parser.Query, fullRegen bool) error {
-	queryGroups := make(map[string][]*parser.Query)
-	for _, query := range queries {
-		sourceFile := query.SourceFile
-		if sourceFile == "" {
-			sourceFile = "queries"
-		}
-		queryGroups[sourceFile] = append(queryGroups[sourceFile], query)
-	}
-
-	// Convert map to slice for parallel processing
-	type fileGroup struct {
-		sourceFile string
-		queries    []*parser.Query
-	}
-	
-	fileGroups := make([]fileGroup, 0, len(queryGroups))
-	for sourceFile, fileQueries := range queryGroups {
-		fileGroups = append(fileGroups, fileGroup{sourceFile, fileQueries})
-	}
-
-	// Track used names across parallel workers (thread-safe)
-	usedNames := make(map[string]int)
-	var usedNamesMu sync.Mutex
+	groups := g.out.GroupQueries(queries)
 
 	// Parallel code generation with worker pool
 	numWorkers := runtime.NumCPU()
-	if numWorkers > len(fileGroups) {
-		numWorkers = len(fileGroups)
+	if numWorkers > len(groups) {
+		numWorkers = len(groups)
 	}
 
 	type generateResult struct {
 		err error
 	}
 
-	workChan := make(chan fileGroup, len(fileGroups))
-	resultChan := make(chan generateResult, len(fileGroups))
+	workChan := make(chan gencommon.QueryGroup, len(groups))
+	resultChan := make(chan generateResult, len(groups))
 
 	var wg sync.WaitGroup
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for fg := range workChan {
-				err := g.generateSingleFile(fg.sourceFile, fg.queries, fullRegen, &usedNamesMu, usedNames)
+			for group := range workChan {
+				err := g.generateSingleFile(group, fullRegen)
 				resultChan <- generateResult{err: err}
 			}
 		}()
 	}
 
 	// Send work to workers
-	for _, fg := range fileGroups {
-		workChan <- fg
+	outputs := make([]string, 0, len(groups))
+	for _, group := range groups {
+		workChan <- group
+		outputs = append(outputs, g.queryOutputPath(group))
 	}
 	close(workChan)
 
@@ -82,31 +62,38 @@ func (g *Generator) generateQueriesIncremental(queries []*parser.Query, fullRege
 		}
 	}
 
-	return nil
+	return g.cache.SetQueryOutputs(outputs)
 }
 
-// generateSingleFile generates code for a single query file (thread-safe)
-func (g *Generator) generateSingleFile(sourceFile string, fileQueries []*parser.Query, fullRegen bool, usedNamesMu *sync.Mutex, usedNames map[string]int) error {
-	queryFile := filepath.Join(g.Config.Queries, sourceFile+".sql")
-	currentHash, _ := gencommon.ComputeFileChecksum(queryFile)
-	
-	if !gencommon.ShouldRegenerateFile(g.cache, queryFile, currentHash, fullRegen) {
-		gencommon.PrintSkipMessage(sourceFile, ".go")
+func (g *Generator) queryOutputPath(group gencommon.QueryGroup) string {
+	return filepath.Join("flash_gen", group.Name+".go")
+}
+
+// generateSingleFile generates code for a single query group (thread-safe)
+func (g *Generator) generateSingleFile(group gencommon.QueryGroup, fullRegen bool) error {
+	if !gencommon.ShouldRegenerateGroup(g.cache, g.Config.Queries, group, fullRegen) {
+		gencommon.PrintSkipMessage(group.Name, ".go")
 		return nil
 	}
 
-	gencommon.PrintGenerateMessage(sourceFile, ".go")
+	gencommon.PrintGenerateMessage(group.Name, ".go")
 	
 	// Use pooled string builder
 	code := gencommon.GetBuilder()
 	defer gencommon.PutBuilder(code)
 
-	code.WriteString("// Code generated by FlashORM. DO NOT EDIT.\n\n")
+	queriesPath := g.queryOutputPath(group)
+	code.WriteString(g.out.Header(filepath.Base(queriesPath)) + "\n")
 	code.WriteString("package flash_gen\n\n")
 
 	needsTime, needsSQL := false, false
-	for _, query := range fileQueries {
-		for _, col := range query.Columns {
+	for _, query := range group.Queries {
+		columns := query.Columns
+		if cmd := strings.ToLower(query.Cmd); cmd == ":one" || cmd == ":many" {
+			// SELECT * rows are scanned into a struct of the table's columns
+			columns = g.expandWildcardColumns(query)
+		}
+		for _, col := range columns {
 			colType := g.mapColumnTypeToGo(col.Type, col.Nullable)
 			if strings.Contains(colType, "time.Time") {
 				needsTime = true
@@ -136,32 +123,22 @@ func (g *Generator) generateSingleFile(sourceFile string, fileQueries []*parser.
 		code.WriteString(")\n\n")
 	}
 
-	for _, query := range fileQueries {
+	for _, query := range group.Queries {
 		if err := g.generateQueryMethod(code, query); err != nil {
 			return err
 		}
+		if query.Paginate != nil {
+			if err := g.generatePageMethod(code, query); err != nil {
+				return err
+			}
+		}
 	}
 
-	baseName := strings.TrimSuffix(sourceFile, ".sql")
-	
-	// Thread-safe name deduplication
-	usedNamesMu.Lock()
-	outputFile := baseName + ".go"
-	if count, exists := usedNames[baseName]; exists {
-		usedNames[baseName] = count + 1
-		outputFile = fmt.Sprintf("%s_%d.go", baseName, count+1)
-	} else {
-		usedNames[baseName] = 1
-	}
-	usedNamesMu.Unlock()
-
-	queriesPath := filepath.Join("flash_gen", outputFile)
 	if err := os.WriteFile(queriesPath, []byte(code.String()), 0644); err != nil {
 		return err
 	}
 
-	tableDeps := gencommon.ExtractTableDependencies(fileQueries)
-	gencommon.UpdateCacheForFile(g.cache, queryFile, currentHash, tableDeps, queriesPath)
+	gencommon.UpdateCacheForGroup(g.cache, g.Config.Queries, group, queriesPath)
 
 	return nil
 }
@@ -0,0 +1,117 @@
+package gogen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/gencommon"
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+	"github.com/Lumos-Labs-HQ/flash/internal/utils"
+)
+
+// loaderTables returns the tables that get a GetXByIds batch loader and their
+// primary keys. Tables whose loader name is taken by a query are left out.
+func (g *Generator) loaderTables(queries []*parser.Query) ([]*parser.Table, []*parser.Column) {
+	taken := make(map[string]bool, len(queries))
+	for _, query := range queries {
+		taken[utils.ToPascalCase(query.Name)] = true
+	}
+
+	tables, keys := gencommon.LoaderTables(g.schema)
+	var loaderTables []*parser.Table
+	var loaderKeys []*parser.Column
+	for i, table := range tables {
+		if !taken[g.loaderName(table)] {
+			loaderTables = append(loaderTables, table)
+			loaderKeys = append(loaderKeys, keys[i])
+		}
+	}
+	return loaderTables, loaderKeys
+}
+
+func (g *Generator) loaderName(table *parser.Table) string {
+	return "Get" + g.out.ModelName(table.Name, utils.ToPascalCase) + "ByIds"
+}
+
+// generateLoaders writes the batch loaders of the tables with a primary key,
+// which load the rows of many keys with one query, e.g. for dataloaders
+// behind GraphQL resolvers. The keys go in an IN list, as database/sql
+// drivers differ in how they take arrays. The file is removed when no table
+// has a loader.
+func (g *Generator) generateLoaders(queries []*parser.Query) error {
+	loadersPath := filepath.Join("flash_gen", "loaders.go")
+
+	tables, keys := g.loaderTables(queries)
+	if len(tables) == 0 {
+		if err := os.Remove(loadersPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	code := gencommon.GetBuilder()
+	defer gencommon.PutBuilder(code)
+
+	needsTime := false
+	for _, key := range keys {
+		if strings.Contains(g.mapColumnTypeToGo(key.Type, false), "time.") {
+			needsTime = true
+		}
+	}
+
+	code.WriteString(g.out.Header("loaders.go") + "\n")
+	code.WriteString("package flash_gen\n\n")
+	if needsTime {
+		code.WriteString("import \"time\"\n\n")
+	}
+
+	for i, table := range tables {
+		key := keys[i]
+		modelName := g.out.ModelName(table.Name, utils.ToPascalCase)
+		keyType := g.mapColumnTypeToGo(key.Type, false)
+		keyField := utils.ToPascalCase(key.Name)
+
+		scanArgs := make([]string, len(table.Columns))
+		for j, col := range table.Columns {
+			scanArgs[j] = "&item." + utils.ToPascalCase(col.Name)
+		}
+
+		code.WriteString(fmt.Sprintf("// %s loads the %s rows of ids, in the order of ids.\n", g.loaderName(table), table.Name))
+		code.WriteString("// Ids without a row give nil.\n")
+		code.WriteString(fmt.Sprintf("func (q *Queries) %s(ids []%s) ([]*%s, error) {\n", g.loaderName(table), keyType, modelName))
+		code.WriteString(fmt.Sprintf("\tresult := make([]*%s, len(ids))\n", modelName))
+		code.WriteString("\tif len(ids) == 0 {\n")
+		code.WriteString("\t\treturn result, nil\n")
+		code.WriteString("\t}\n\n")
+		code.WriteString(fmt.Sprintf("\tindex := make(map[%s][]int, len(ids))\n", keyType))
+		code.WriteString("\targs := make([]interface{}, 0, len(ids))\n")
+		code.WriteString("\tfor i, id := range ids {\n")
+		code.WriteString("\t\tif _, ok := index[id]; !ok {\n")
+		code.WriteString("\t\t\targs = append(args, id)\n")
+		code.WriteString("\t\t}\n")
+		code.WriteString("\t\tindex[id] = append(index[id], i)\n")
+		code.WriteString("\t}\n\n")
+		code.WriteString(fmt.Sprintf("\trows, err := q.db.Query(`%s`+inPlaceholders(len(args))+\")\", args...)\n",
+			gencommon.LoaderQuery(table, key, g.Config.SoftDelete.Column, false)))
+		code.WriteString("\tif err != nil {\n")
+		code.WriteString("\t\treturn nil, err\n")
+		code.WriteString("\t}\n")
+		code.WriteString("\tdefer rows.Close()\n\n")
+		code.WriteString("\tfor rows.Next() {\n")
+		code.WriteString(fmt.Sprintf("\t\tvar item %s\n", modelName))
+		code.WriteString(fmt.Sprintf("\t\tif err := rows.Scan(%s); err != nil {\n", strings.Join(scanArgs, ", ")))
+		code.WriteString("\t\t\treturn nil, err\n")
+		code.WriteString("\t\t}\n")
+		writeDecrypt(code, "\t\t", tableDecryptTargets("item", table), "nil, err")
+		code.WriteString(fmt.Sprintf("\t\tfor _, i := range index[item.%s] {\n", keyField))
+		code.WriteString("\t\t\tresult[i] = &item\n")
+		code.WriteString("\t\t}\n")
+		code.WriteString("\t}\n")
+		code.WriteString("\treturn result, rows.Err()\n")
+		code.WriteString("}\n\n")
+	}
+
+	return os.WriteFile(loadersPath, []byte(strings.TrimSuffix(code.String(), "\n")), 0644)
+}
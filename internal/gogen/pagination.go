@@ -0,0 +1,193 @@
+package gogen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/gencommon"
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+	"github.com/Lumos-Labs-HQ/flash/internal/utils"
+)
+
+// generatePaginationHelpers writes the cursor encoding helpers shared by all
+// XPage methods. The file is removed when no query is paginated.
+func (g *Generator) generatePaginationHelpers(queries []*parser.Query) error {
+	helpersPath := filepath.Join("flash_gen", "pagination.go")
+
+	needed := false
+	for _, query := range queries {
+		if query.Paginate != nil {
+			needed = true
+			break
+		}
+	}
+	if !needed {
+		if err := os.Remove(helpersPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	code := gencommon.GetBuilder()
+	defer gencommon.PutBuilder(code)
+
+	code.WriteString(g.out.Header("pagination.go") + "\n")
+	code.WriteString("package flash_gen\n\n")
+	code.WriteString("import (\n")
+	code.WriteString("\t\"encoding/base64\"\n")
+	code.WriteString("\t\"encoding/json\"\n")
+	code.WriteString("\t\"errors\"\n")
+	code.WriteString(")\n\n")
+	code.WriteString("// ErrInvalidCursor is returned when a page cursor cannot be decoded\n")
+	code.WriteString("var ErrInvalidCursor = errors.New(\"invalid pagination cursor\")\n\n")
+	code.WriteString("// ErrInvalidPageLimit is returned when a page is requested with limit < 1\n")
+	code.WriteString("var ErrInvalidPageLimit = errors.New(\"page limit must be at least 1\")\n\n")
+	code.WriteString("func encodeCursor(values ...interface{}) (string, error) {\n")
+	code.WriteString("\tdata, err := json.Marshal(values)\n")
+	code.WriteString("\tif err != nil {\n")
+	code.WriteString("\t\treturn \"\", err\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\treturn base64.RawURLEncoding.EncodeToString(data), nil\n")
+	code.WriteString("}\n\n")
+	code.WriteString("func decodeCursor(cursor string, dest ...interface{}) error {\n")
+	code.WriteString("\tdata, err := base64.RawURLEncoding.DecodeString(cursor)\n")
+	code.WriteString("\tif err != nil {\n")
+	code.WriteString("\t\treturn ErrInvalidCursor\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\tvar values []json.RawMessage\n")
+	code.WriteString("\tif err := json.Unmarshal(data, &values); err != nil || len(values) != len(dest) {\n")
+	code.WriteString("\t\treturn ErrInvalidCursor\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\tfor i, value := range values {\n")
+	code.WriteString("\t\tif err := json.Unmarshal(value, dest[i]); err != nil {\n")
+	code.WriteString("\t\t\treturn ErrInvalidCursor\n")
+	code.WriteString("\t\t}\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\treturn nil\n")
+	code.WriteString("}\n")
+
+	return os.WriteFile(helpersPath, []byte(code.String()), 0644)
+}
+
+// generatePageMethod writes XPage for a query with a paginate annotation. It
+// fetches limit+1 rows so it can tell whether another page exists.
+func (g *Generator) generatePageMethod(code *strings.Builder, query *parser.Query) error {
+	columns := g.expandWildcardColumns(query)
+	indexes, err := gencommon.CursorColumnIndexes(query, columns)
+	if err != nil {
+		return err
+	}
+
+	methodName := utils.ToPascalCase(query.Name)
+	pageName := methodName + "Page"
+	firstSQL, nextSQL := gencommon.CursorQueries(query, g.Config.Database.Provider)
+
+	rowType := methodName + "Row"
+	singleColumn := len(columns) == 1
+	if singleColumn {
+		rowType = g.mapColumnTypeToGo(columns[0].Type, columns[0].Nullable)
+	}
+
+	code.WriteString(fmt.Sprintf("type %sResult struct {\n", pageName))
+	code.WriteString(fmt.Sprintf("\tRows []%s `json:\"rows\"`\n", rowType))
+	code.WriteString("\tNextCursor string `json:\"next_cursor\"`\n")
+	code.WriteString("}\n\n")
+
+	var params, args []string
+	if len(query.Params) > 3 {
+		params = append(params, fmt.Sprintf("arg %sParams", methodName))
+		for _, param := range query.Params {
+			args = append(args, "arg."+utils.ToPascalCase(param.Name))
+		}
+	} else {
+		for _, param := range query.Params {
+			paramName := utils.ToSnakeCase(param.Name)
+			params = append(params, fmt.Sprintf("%s %s", paramName, g.mapParamTypeToGo(param.Type)))
+			args = append(args, paramName)
+		}
+	}
+	params = append(params, "cursor string", "limit int")
+
+	code.WriteString(fmt.Sprintf("// %s returns up to limit rows of %s ordered by %s.\n",
+		pageName, methodName, strings.Join(query.Paginate.Columns, ", ")))
+	code.WriteString("// Pass an empty cursor for the first page; NextCursor is empty on the last page.\n")
+	code.WriteString(fmt.Sprintf("func (q *Queries) %s(%s) (%sResult, error) {\n", pageName, strings.Join(params, ", "), pageName))
+	code.WriteString(fmt.Sprintf("\tvar result %sResult\n", pageName))
+	code.WriteString("\tif limit < 1 {\n")
+	code.WriteString("\t\treturn result, ErrInvalidPageLimit\n")
+	code.WriteString("\t}\n\n")
+	code.WriteString(fmt.Sprintf("\targs := []interface{}{%s}\n", strings.Join(args, ", ")))
+	code.WriteString(fmt.Sprintf("\tquery := `%s`\n", firstSQL))
+	code.WriteString("\tif cursor != \"\" {\n")
+
+	cursorVars := make([]string, len(indexes))
+	for i, idx := range indexes {
+		cursorVars[i] = "after" + utils.ToPascalCase(columns[idx].Name)
+		code.WriteString(fmt.Sprintf("\t\tvar %s %s\n", cursorVars[i], g.mapColumnTypeToGo(columns[idx].Type, columns[idx].Nullable)))
+	}
+	refs := make([]string, len(cursorVars))
+	for i, v := range cursorVars {
+		refs[i] = "&" + v
+	}
+	code.WriteString(fmt.Sprintf("\t\tif err := decodeCursor(cursor, %s); err != nil {\n", strings.Join(refs, ", ")))
+	code.WriteString("\t\t\treturn result, err\n")
+	code.WriteString("\t\t}\n")
+	code.WriteString(fmt.Sprintf("\t\tquery = `%s`\n", nextSQL))
+	code.WriteString(fmt.Sprintf("\t\targs = append(args, %s)\n", strings.Join(cursorVars, ", ")))
+	code.WriteString("\t}\n")
+	code.WriteString("\targs = append(args, limit+1)\n\n")
+
+	code.WriteString("\trows, err := q.db.Query(query, args...)\n")
+	code.WriteString("\tif err != nil {\n")
+	code.WriteString("\t\treturn result, err\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\tdefer rows.Close()\n\n")
+	code.WriteString("\tfor rows.Next() {\n")
+	code.WriteString(fmt.Sprintf("\t\tvar item %s\n", rowType))
+	if singleColumn {
+		code.WriteString("\t\tif err := rows.Scan(&item); err != nil {\n")
+	} else {
+		scanArgs := make([]string, len(columns))
+		for i, col := range columns {
+			scanArgs[i] = "&item." + utils.ToPascalCase(col.Name)
+		}
+		code.WriteString(fmt.Sprintf("\t\tif err := rows.Scan(%s); err != nil {\n", strings.Join(scanArgs, ", ")))
+	}
+	code.WriteString("\t\t\treturn result, err\n")
+	code.WriteString("\t\t}\n")
+	writeDecrypt(code, "\t\t", decryptTargets("item", columns, singleColumn), "result, err")
+	code.WriteString("\t\tresult.Rows = append(result.Rows, item)\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\tif err := rows.Err(); err != nil {\n")
+	code.WriteString("\t\treturn result, err\n")
+	code.WriteString("\t}\n\n")
+
+	lastValues := make([]string, len(indexes))
+	for i, idx := range indexes {
+		if singleColumn {
+			lastValues[i] = "last"
+		} else {
+			lastValues[i] = "last." + utils.ToPascalCase(columns[idx].Name)
+		}
+	}
+	code.WriteString("\tif len(result.Rows) > limit {\n")
+	code.WriteString("\t\tresult.Rows = result.Rows[:limit]\n")
+	code.WriteString("\t\tlast := result.Rows[limit-1]\n")
+	code.WriteString(fmt.Sprintf("\t\tresult.NextCursor, err = encodeCursor(%s)\n", strings.Join(lastValues, ", ")))
+	code.WriteString("\t}\n")
+	if len(query.Includes) > 0 {
+		// Relations are only loaded for the rows of the page
+		code.WriteString("\tif err != nil {\n")
+		code.WriteString("\t\treturn result, err\n")
+		code.WriteString("\t}\n")
+		code.WriteString("\trows.Close()\n")
+		code.WriteString(fmt.Sprintf("\treturn result, q.include%s(result.Rows)\n", methodName))
+	} else {
+		code.WriteString("\treturn result, err\n")
+	}
+	code.WriteString("}\n\n")
+
+	return nil
+}
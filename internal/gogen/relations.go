@@ -0,0 +1,206 @@
+package gogen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/gencommon"
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+	"github.com/Lumos-Labs-HQ/flash/internal/utils"
+)
+
+// generateRelationHelpers writes the helpers shared by the loaders of
+// included relations and the batch loaders of tables. The file is removed
+// when there are neither.
+func (g *Generator) generateRelationHelpers(queries []*parser.Query) error {
+	helpersPath := filepath.Join("flash_gen", "relations.go")
+
+	if tables, _ := g.loaderTables(queries); !gencommon.HasIncludes(queries) && len(tables) == 0 {
+		if err := os.Remove(helpersPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	code := gencommon.GetBuilder()
+	defer gencommon.PutBuilder(code)
+
+	code.WriteString(g.out.Header("relations.go") + "\n")
+	code.WriteString("package flash_gen\n\n")
+	if g.usesDollarPlaceholders() {
+		code.WriteString("import (\n")
+		code.WriteString("\t\"strconv\"\n")
+		code.WriteString("\t\"strings\"\n")
+		code.WriteString(")\n\n")
+		code.WriteString("// inPlaceholders returns the placeholders of an IN list of n values\n")
+		code.WriteString("func inPlaceholders(n int) string {\n")
+		code.WriteString("\tplaceholders := make([]string, n)\n")
+		code.WriteString("\tfor i := range placeholders {\n")
+		code.WriteString("\t\tplaceholders[i] = \"$\" + strconv.Itoa(i+1)\n")
+		code.WriteString("\t}\n")
+		code.WriteString("\treturn strings.Join(placeholders, \", \")\n")
+		code.WriteString("}\n")
+	} else {
+		code.WriteString("import \"strings\"\n\n")
+		code.WriteString("// inPlaceholders returns the placeholders of an IN list of n values\n")
+		code.WriteString("func inPlaceholders(n int) string {\n")
+		code.WriteString("\treturn strings.TrimSuffix(strings.Repeat(\"?, \", n), \", \")\n")
+		code.WriteString("}\n")
+	}
+
+	return os.WriteFile(helpersPath, []byte(code.String()), 0644)
+}
+
+func (g *Generator) usesDollarPlaceholders() bool {
+	switch g.Config.Database.Provider {
+	case "mysql", "sqlite", "sqlite3":
+		return false
+	default:
+		return true
+	}
+}
+
+// generateIncludeLoader writes includeX, which loads the relations of a
+// query with one query per relation and attaches them to its rows.
+func (g *Generator) generateIncludeLoader(code *strings.Builder, query *parser.Query, columns []*parser.QueryColumn) {
+	methodName := utils.ToPascalCase(query.Name)
+
+	names := make([]string, len(query.Includes))
+	for i, rel := range query.Includes {
+		names[i] = rel.Name
+	}
+	code.WriteString(fmt.Sprintf("// include%s loads the %s of %sRow items\n", methodName, joinNames(names), methodName))
+	code.WriteString(fmt.Sprintf("func (q *Queries) include%s(items []%sRow) error {\n", methodName, methodName))
+	code.WriteString("\tif len(items) == 0 {\n")
+	code.WriteString("\t\treturn nil\n")
+	code.WriteString("\t}\n")
+
+	for _, rel := range query.Includes {
+		var local *parser.QueryColumn
+		for _, col := range columns {
+			if strings.EqualFold(col.Name, rel.Column) {
+				local = col
+				break
+			}
+		}
+		var related *parser.Column
+		for _, col := range rel.Table.Columns {
+			if strings.EqualFold(col.Name, rel.RelatedColumn) {
+				related = col
+				break
+			}
+		}
+		if local == nil || related == nil {
+			continue
+		}
+
+		prefix := utils.Uncapitalize(utils.ToPascalCase(rel.Name))
+		field := utils.ToPascalCase(rel.Name)
+		modelName := g.out.ModelName(rel.Table.Name, utils.ToPascalCase)
+		localKey, keyType, localNull := relationKey("items[i]."+utils.ToPascalCase(local.Name), g.mapColumnTypeToGo(local.Type, local.Nullable))
+		// The related rows come back for the keys, so their column isn't NULL
+		relatedKey, relatedType, _ := relationKey("related."+utils.ToPascalCase(related.Name), g.mapColumnTypeToGo(related.Type, related.Nullable))
+		if relatedType != keyType {
+			relatedKey = fmt.Sprintf("%s(%s)", keyType, relatedKey)
+		}
+
+		code.WriteString("\n")
+		code.WriteString(fmt.Sprintf("\t%sIndex := make(map[%s][]int, len(items))\n", prefix, keyType))
+		code.WriteString(fmt.Sprintf("\t%sKeys := make([]interface{}, 0, len(items))\n", prefix))
+		code.WriteString("\tfor i := range items {\n")
+		if rel.Many {
+			code.WriteString(fmt.Sprintf("\t\titems[i].%s = []%s{}\n", field, modelName))
+		}
+		if localNull != "" {
+			code.WriteString(fmt.Sprintf("\t\tif %s {\n", localNull))
+			code.WriteString("\t\t\tcontinue\n")
+			code.WriteString("\t\t}\n")
+		}
+		code.WriteString(fmt.Sprintf("\t\tkey := %s\n", localKey))
+		code.WriteString(fmt.Sprintf("\t\tif _, ok := %sIndex[key]; !ok {\n", prefix))
+		code.WriteString(fmt.Sprintf("\t\t\t%sKeys = append(%sKeys, key)\n", prefix, prefix))
+		code.WriteString("\t\t}\n")
+		code.WriteString(fmt.Sprintf("\t\t%sIndex[key] = append(%sIndex[key], i)\n", prefix, prefix))
+		code.WriteString("\t}\n")
+
+		scanArgs := make([]string, len(rel.Table.Columns))
+		for i, col := range rel.Table.Columns {
+			scanArgs[i] = "&related." + utils.ToPascalCase(col.Name)
+		}
+
+		code.WriteString(fmt.Sprintf("\tif len(%sKeys) > 0 {\n", prefix))
+		code.WriteString(fmt.Sprintf("\t\trows, err := q.db.Query(`%s`+inPlaceholders(len(%sKeys))+\")\", %sKeys...)\n",
+			gencommon.RelationQuery(rel, g.Config.SoftDelete.Column), prefix, prefix))
+		code.WriteString("\t\tif err != nil {\n")
+		code.WriteString("\t\t\treturn err\n")
+		code.WriteString("\t\t}\n")
+		code.WriteString("\t\tfor rows.Next() {\n")
+		code.WriteString(fmt.Sprintf("\t\t\tvar related %s\n", modelName))
+		code.WriteString(fmt.Sprintf("\t\t\tif err := rows.Scan(%s); err != nil {\n", strings.Join(scanArgs, ", ")))
+		code.WriteString("\t\t\t\trows.Close()\n")
+		code.WriteString("\t\t\t\treturn err\n")
+		code.WriteString("\t\t\t}\n")
+		if targets := tableDecryptTargets("related", rel.Table); len(targets) > 0 {
+			code.WriteString(fmt.Sprintf("\t\t\tif err := decryptColumns(%s); err != nil {\n", strings.Join(targets, ", ")))
+			code.WriteString("\t\t\t\trows.Close()\n")
+			code.WriteString("\t\t\t\treturn err\n")
+			code.WriteString("\t\t\t}\n")
+		}
+		code.WriteString(fmt.Sprintf("\t\t\tfor _, i := range %sIndex[%s] {\n", prefix, relatedKey))
+		if rel.Many {
+			code.WriteString(fmt.Sprintf("\t\t\t\titems[i].%s = append(items[i].%s, related)\n", field, field))
+		} else {
+			code.WriteString(fmt.Sprintf("\t\t\t\titems[i].%s = &related\n", field))
+		}
+		code.WriteString("\t\t\t}\n")
+		code.WriteString("\t\t}\n")
+		code.WriteString("\t\trows.Close()\n")
+		code.WriteString("\t\tif err := rows.Err(); err != nil {\n")
+		code.WriteString("\t\t\treturn err\n")
+		code.WriteString("\t\t}\n")
+		code.WriteString("\t}\n")
+	}
+
+	code.WriteString("\treturn nil\n")
+	code.WriteString("}\n\n")
+}
+
+// relationFieldType returns the type of the XRow field holding a relation
+func (g *Generator) relationFieldType(rel *parser.Relation) string {
+	modelName := g.out.ModelName(rel.Table.Name, utils.ToPascalCase)
+	if rel.Many {
+		return "[]" + modelName
+	}
+	return "*" + modelName
+}
+
+// relationKey returns the key held by a column value of goType, its type,
+// and the condition under which it is NULL, empty for NOT NULL columns
+func relationKey(expr, goType string) (key, keyType, null string) {
+	switch goType {
+	case "sql.NullInt64":
+		return expr + ".Int64", "int64", "!" + expr + ".Valid"
+	case "sql.NullFloat64":
+		return expr + ".Float64", "float64", "!" + expr + ".Valid"
+	case "sql.NullBool":
+		return expr + ".Bool", "bool", "!" + expr + ".Valid"
+	case "sql.NullString":
+		return expr + ".String", "string", "!" + expr + ".Valid"
+	case "sql.NullTime":
+		return expr + ".Time", "time.Time", "!" + expr + ".Valid"
+	}
+	if strings.HasPrefix(goType, "*") {
+		return "*" + expr, goType[1:], expr + " == nil"
+	}
+	return expr, goType, ""
+}
+
+// joinNames lists names as "a, b and c"
+func joinNames(names []string) string {
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
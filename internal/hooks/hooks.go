@@ -0,0 +1,165 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/fatih/color"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// Event is what a hook is told about the command it runs around. Shell
+// commands get it in FLASH_HOOK_* environment variables and webhooks as the
+// JSON body.
+type Event struct {
+	Event      string    `json:"event"`   // e.g. pre_migrate
+	Command    string    `json:"command"` // the flash command that fired it
+	Provider   string    `json:"provider"`
+	Timestamp  time.Time `json:"timestamp"`
+	Branch     string    `json:"branch,omitempty"`
+	FromBranch string    `json:"from_branch,omitempty"`
+	Migrations []string  `json:"migrations,omitempty"`
+	Languages  []string  `json:"languages,omitempty"`
+	Tables     []string  `json:"tables,omitempty"`
+	Success    *bool     `json:"success,omitempty"` // post events only
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms,omitempty"`
+}
+
+// Pre runs the pre_<action> hooks. The first failing hook without
+// continue_on_error is returned, and the command should not run.
+func Pre(cfg *config.Config, action string, event Event) error {
+	event.Event = "pre_" + action
+	for _, hook := range cfg.Hooks.For(event.Event) {
+		if err := run(cfg, hook, event); err != nil {
+			if hook.ContinueOnError {
+				color.Yellow("⚠️  %s hook failed: %v", event.Event, err)
+				continue
+			}
+			return fmt.Errorf("%s hook failed: %w", event.Event, err)
+		}
+	}
+	return nil
+}
+
+// Post runs the post_<action> hooks with the outcome of a command that
+// started at start. The command already ran, so failures are only printed.
+func Post(cfg *config.Config, action string, event Event, start time.Time, cmdErr error) {
+	event.Event = "post_" + action
+	success := cmdErr == nil
+	event.Success = &success
+	if cmdErr != nil {
+		event.Error = cmdErr.Error()
+	}
+	event.DurationMS = time.Since(start).Milliseconds()
+
+	for _, hook := range cfg.Hooks.For(event.Event) {
+		if err := run(cfg, hook, event); err != nil {
+			color.Yellow("⚠️  %s hook failed: %v", event.Event, err)
+		}
+	}
+}
+
+func run(cfg *config.Config, hook config.Hook, event Event) error {
+	if event.Provider == "" {
+		event.Provider = cfg.Database.Provider
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	timeout := defaultTimeout
+	if hook.Timeout > 0 {
+		timeout = time.Duration(hook.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if hook.URL != "" {
+		return post(ctx, hook, payload)
+	}
+	return runCommand(ctx, hook.Command, event, payload)
+}
+
+func runCommand(ctx context.Context, command string, event Event, payload []byte) error {
+	fmt.Printf("🪝 Running %s hook: %s\n", event.Event, command)
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), eventEnv(event, payload)...)
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("timed out")
+		}
+		return err
+	}
+	return nil
+}
+
+func eventEnv(event Event, payload []byte) []string {
+	env := []string{
+		"FLASH_HOOK_EVENT=" + event.Event,
+		"FLASH_HOOK_COMMAND=" + event.Command,
+		"FLASH_HOOK_PROVIDER=" + event.Provider,
+		"FLASH_HOOK_BRANCH=" + event.Branch,
+		"FLASH_HOOK_FROM_BRANCH=" + event.FromBranch,
+		"FLASH_HOOK_MIGRATIONS=" + strings.Join(event.Migrations, ","),
+		"FLASH_HOOK_LANGUAGES=" + strings.Join(event.Languages, ","),
+		"FLASH_HOOK_TABLES=" + strings.Join(event.Tables, ","),
+		"FLASH_HOOK_PAYLOAD=" + string(payload),
+	}
+	if event.Success != nil {
+		env = append(env,
+			"FLASH_HOOK_SUCCESS="+strconv.FormatBool(*event.Success),
+			"FLASH_HOOK_ERROR="+event.Error,
+			"FLASH_HOOK_DURATION_MS="+strconv.FormatInt(event.DurationMS, 10),
+		)
+	}
+	return env
+}
+
+func post(ctx context.Context, hook config.Hook, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, os.ExpandEnv(hook.URL), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "flash-hooks")
+	for name, value := range hook.Headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package jsgen
+
+import (
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/gencommon"
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+)
+
+// writeBatchClass emits the Batch class used by Queries.batch(). Queued calls
+// return promises that settle once execute() has run them inside one
+// transaction. postgres.js pipelines them and Neon sends them in one
+// request; the other drivers run them one after another, since pg and
+// mysql2 only queue the queries of a connection.
+func (g *Generator) writeBatchClass(w *strings.Builder, queries []*parser.Query) {
+	w.WriteString("function queryMethodNames(queries) {\n")
+	w.WriteString("  const names = new Set(Object.keys(queries).filter(name => typeof queries[name] === 'function'));\n")
+	w.WriteString("  Object.getOwnPropertyNames(Object.getPrototypeOf(queries)).forEach(name => {\n")
+	w.WriteString("    if (name !== 'constructor' && typeof queries[name] === 'function') {\n")
+	w.WriteString("      names.add(name);\n")
+	w.WriteString("    }\n")
+	w.WriteString("  });\n")
+	w.WriteString("  names.delete('batch');\n")
+	w.WriteString("  return names;\n")
+	w.WriteString("}\n\n")
+
+	w.WriteString("/**\n")
+	w.WriteString(" * Batch queues query calls and executes them in order in one transaction.\n")
+	w.WriteString(" * Each queued call returns a promise for its own typed result.\n")
+	w.WriteString(" */\n")
+	w.WriteString("class Batch {\n")
+	w.WriteString("  constructor(queries) {\n")
+	w.WriteString("    this._db = queries.db;\n")
+	w.WriteString("    this._items = [];\n")
+	w.WriteString("    for (const name of queryMethodNames(queries)) {\n")
+	w.WriteString("      this[name] = (...args) => {\n")
+	w.WriteString("        const item = { name, args };\n")
+	w.WriteString("        const result = new Promise((resolve, reject) => {\n")
+	w.WriteString("          item.resolve = resolve;\n")
+	w.WriteString("          item.reject = reject;\n")
+	w.WriteString("        });\n")
+	w.WriteString("        // Callers may only await execute(); don't report these as unhandled\n")
+	w.WriteString("        result.catch(() => {});\n")
+	w.WriteString("        this._items.push(item);\n")
+	w.WriteString("        return result;\n")
+	w.WriteString("      };\n")
+	w.WriteString("    }\n")
+	w.WriteString("  }\n\n")
+
+	w.WriteString("  get length() {\n")
+	w.WriteString("    return this._items.length;\n")
+	w.WriteString("  }\n\n")
+
+	w.WriteString("  async execute() {\n")
+	w.WriteString("    const items = this._items;\n")
+	w.WriteString("    this._items = [];\n")
+	w.WriteString("    if (items.length === 0) {\n")
+	w.WriteString("      return [];\n")
+	w.WriteString("    }\n\n")
+	w.WriteString("    const settle = (promises) => Promise.all(promises.map((p, i) => p.then(\n")
+	w.WriteString("      value => { items[i].resolve(value); return value; },\n")
+	w.WriteString("      err => { items[i].reject(err); throw err; }\n")
+	w.WriteString("    )));\n\n")
+
+	switch g.driver() {
+	case config.DriverBetterSQLite3, config.DriverBunSQLite:
+		w.WriteString("    const db = this._db;\n")
+		w.WriteString("    const q = new Queries(db);\n")
+		w.WriteString("    db.exec('BEGIN');\n")
+		w.WriteString("    try {\n")
+		w.WriteString("      const results = [];\n")
+		w.WriteString("      for (const item of items) {\n")
+		w.WriteString("        results.push(await settle([q[item.name](...item.args)]).then(r => r[0]));\n")
+		w.WriteString("      }\n")
+		w.WriteString("      db.exec('COMMIT');\n")
+		w.WriteString("      return results;\n")
+		w.WriteString("    } catch (err) {\n")
+		w.WriteString("      db.exec('ROLLBACK');\n")
+		w.WriteString("      items.forEach(item => item.reject(err));\n")
+		w.WriteString("      throw err;\n")
+		w.WriteString("    }\n")
+	case config.DriverLibSQL:
+		w.WriteString("    const tx = await this._db.transaction('write');\n")
+		w.WriteString("    try {\n")
+		w.WriteString("      const q = new Queries(tx);\n")
+		w.WriteString("      const results = [];\n")
+		w.WriteString("      for (const item of items) {\n")
+		w.WriteString("        results.push(await q[item.name](...item.args));\n")
+		w.WriteString("      }\n")
+		w.WriteString("      await tx.commit();\n")
+		w.WriteString("      items.forEach((item, i) => item.resolve(results[i]));\n")
+		w.WriteString("      return results;\n")
+		w.WriteString("    } catch (err) {\n")
+		w.WriteString("      await tx.rollback().catch(() => {});\n")
+		w.WriteString("      items.forEach(item => item.reject(err));\n")
+		w.WriteString("      throw err;\n")
+		w.WriteString("    } finally {\n")
+		w.WriteString("      tx.close();\n")
+		w.WriteString("    }\n")
+	case config.DriverMySQL2:
+		w.WriteString("    const db = this._db;\n")
+		w.WriteString("    const conn = typeof db.getConnection === 'function' ? await db.getConnection() : db;\n")
+		w.WriteString("    try {\n")
+		w.WriteString("      await conn.beginTransaction();\n")
+		w.WriteString("      const q = new Queries(conn);\n")
+		w.WriteString("      const results = [];\n")
+		w.WriteString("      for (const item of items) {\n")
+		w.WriteString("        results.push(await q[item.name](...item.args));\n")
+		w.WriteString("      }\n")
+		w.WriteString("      await conn.commit();\n")
+		w.WriteString("      items.forEach((item, i) => item.resolve(results[i]));\n")
+		w.WriteString("      return results;\n")
+		w.WriteString("    } catch (err) {\n")
+		w.WriteString("      await conn.rollback().catch(() => {});\n")
+		w.WriteString("      items.forEach(item => item.reject(err));\n")
+		w.WriteString("      throw err;\n")
+		w.WriteString("    } finally {\n")
+		w.WriteString("      if (conn !== db) {\n")
+		w.WriteString("        conn.release();\n")
+		w.WriteString("      }\n")
+		w.WriteString("    }\n")
+	case config.DriverNeon:
+		// Neon runs a transaction in one HTTP request, so the queries are
+		// recorded first and sent together
+		w.WriteString("    const db = this._db;\n")
+		w.WriteString("    const calls = [];\n")
+		if g.Config.Encryption.Enabled() {
+			w.WriteString("    let recorded = () => {};\n")
+		}
+		includes := gencommon.HasIncludes(queries)
+		if includes {
+			w.WriteString("    let sent = false;\n")
+		}
+		w.WriteString("    const recorder = {\n")
+		w.WriteString("      query(text, params) {\n")
+		if includes {
+			// Relations are loaded once the rows they belong to are back
+			w.WriteString("        if (sent) {\n")
+			w.WriteString("          return db.query(text, params, { fullResults: true });\n")
+			w.WriteString("        }\n")
+		}
+		if g.Config.Encryption.Enabled() {
+			w.WriteString("        return new Promise((resolve, reject) => {\n")
+			w.WriteString("          calls.push({ text, params, resolve, reject });\n")
+			w.WriteString("          recorded();\n")
+			w.WriteString("        });\n")
+		} else {
+			w.WriteString("        return new Promise((resolve, reject) => calls.push({ text, params, resolve, reject }));\n")
+		}
+		w.WriteString("      },\n")
+		w.WriteString("    };\n")
+		w.WriteString("    const q = new Queries(recorder);\n")
+		if g.Config.Encryption.Enabled() {
+			// Params are encrypted before their query is recorded, so each
+			// call is awaited until it records its query or settles
+			w.WriteString("    const started = [];\n")
+			w.WriteString("    for (const item of items) {\n")
+			w.WriteString("      const call = new Promise(resolve => { recorded = resolve; });\n")
+			w.WriteString("      const result = q[item.name](...item.args);\n")
+			w.WriteString("      started.push(result);\n")
+			w.WriteString("      await Promise.race([call, result.catch(() => {})]);\n")
+			w.WriteString("    }\n")
+			w.WriteString("    const results = settle(started);\n")
+		} else {
+			w.WriteString("    const results = settle(items.map(item => q[item.name](...item.args)));\n")
+		}
+		if includes {
+			w.WriteString("    sent = true;\n")
+		}
+		w.WriteString("    try {\n")
+		w.WriteString("      const responses = await db.transaction(calls.map(call => db.query(call.text, call.params)), { fullResults: true });\n")
+		w.WriteString("      calls.forEach((call, i) => call.resolve(responses[i]));\n")
+		w.WriteString("    } catch (err) {\n")
+		w.WriteString("      calls.forEach(call => call.reject(err));\n")
+		w.WriteString("    }\n")
+		w.WriteString("    return results;\n")
+	case config.DriverPlanetScale:
+		w.WriteString("    try {\n")
+		w.WriteString("      const results = await this._db.transaction(async tx => {\n")
+		w.WriteString("        const q = new Queries(tx);\n")
+		w.WriteString("        const values = [];\n")
+		w.WriteString("        for (const item of items) {\n")
+		w.WriteString("          values.push(await q[item.name](...item.args));\n")
+		w.WriteString("        }\n")
+		w.WriteString("        return values;\n")
+		w.WriteString("      });\n")
+		w.WriteString("      items.forEach((item, i) => item.resolve(results[i]));\n")
+		w.WriteString("      return results;\n")
+		w.WriteString("    } catch (err) {\n")
+		w.WriteString("      items.forEach(item => item.reject(err));\n")
+		w.WriteString("      throw err;\n")
+		w.WriteString("    }\n")
+	case config.DriverPostgresJS:
+		w.WriteString("    try {\n")
+		w.WriteString("      // begin() commits when the callback resolves and rolls back when it throws\n")
+		w.WriteString("      return await this._db.begin(tx => {\n")
+		w.WriteString("        const q = new Queries(tx);\n")
+		w.WriteString("        return settle(items.map(item => q[item.name](...item.args)));\n")
+		w.WriteString("      });\n")
+		w.WriteString("    } catch (err) {\n")
+		w.WriteString("      items.forEach(item => item.reject(err));\n")
+		w.WriteString("      throw err;\n")
+		w.WriteString("    }\n")
+	default:
+		w.WriteString("    const db = this._db;\n")
+		w.WriteString("    // pg.Pool exposes totalCount; a single pg.Client is used as-is\n")
+		w.WriteString("    const client = typeof db.totalCount === 'number' ? await db.connect() : db;\n")
+		w.WriteString("    try {\n")
+		w.WriteString("      await client.query('BEGIN');\n")
+		w.WriteString("      const q = new Queries(client);\n")
+		w.WriteString("      // pg has no pipelining, so each call waits for the one before; a\n")
+		w.WriteString("      // failed call stops the rest rather than running them in an aborted\n")
+		w.WriteString("      // transaction\n")
+		w.WriteString("      const results = [];\n")
+		w.WriteString("      for (const item of items) {\n")
+		w.WriteString("        results.push(await q[item.name](...item.args));\n")
+		w.WriteString("      }\n")
+		w.WriteString("      await client.query('COMMIT');\n")
+		w.WriteString("      items.forEach((item, i) => item.resolve(results[i]));\n")
+		w.WriteString("      return results;\n")
+		w.WriteString("    } catch (err) {\n")
+		w.WriteString("      await client.query('ROLLBACK').catch(() => {});\n")
+		w.WriteString("      items.forEach(item => item.reject(err));\n")
+		w.WriteString("      throw err;\n")
+		w.WriteString("    } finally {\n")
+		w.WriteString("      if (client !== db) {\n")
+		w.WriteString("        client.release();\n")
+		w.WriteString("      }\n")
+		w.WriteString("    }\n")
+	}
+
+	w.WriteString("  }\n")
+	w.WriteString("}\n\n")
+
+	w.WriteString("Queries.prototype.batch = function batch() {\n")
+	w.WriteString("  return new Batch(this);\n")
+	w.WriteString("};\n\n")
+}
+
+// writeBatchDeclarations emits the typings for Queries.batch().
+func (g *Generator) writeBatchDeclarations(w *strings.Builder) {
+	w.WriteString("export type QueryBatch = {\n")
+	w.WriteString("  [K in Exclude<keyof Queries, 'batch'>]: Queries[K] extends (...args: infer A) => infer R ? (...args: A) => R : never;\n")
+	w.WriteString("} & {\n")
+	w.WriteString("  readonly length: number;\n")
+	w.WriteString("  execute(): Promise<unknown[]>;\n")
+	w.WriteString("};\n\n")
+}
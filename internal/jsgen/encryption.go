@@ -0,0 +1,259 @@
+package jsgen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+)
+
+// encryptionModule is the module holding the helpers of the encrypted
+// columns, shared by the query modules so they use the same key
+const encryptionModule = "encryption"
+
+// generateEncryption writes the encryption module, which encrypts and
+// decrypts columns with AES-256-GCM through the Web Crypto API so it runs on
+// every runtime. The module is removed when no column is encrypted.
+func (g *Generator) generateEncryption() error {
+	if !g.Config.Encryption.Enabled() {
+		for _, format := range g.moduleFormats() {
+			if err := os.Remove(g.encryptionPath(format)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	keyEnv := g.Config.Encryption.KeyEnvName()
+
+	var w strings.Builder
+	w.WriteString("// Ciphertext is stored as this prefix and the base64 of nonce || sealed\n")
+	w.WriteString("const ENCRYPTION_PREFIX = 'flash:v1:';\n\n")
+	w.WriteString(fmt.Sprintf("let encryptionKey = () => %s;\n", g.encryptionKeyLookup()))
+	w.WriteString("let cryptoKey = null;\n\n")
+	w.WriteString("/**\n")
+	w.WriteString(fmt.Sprintf(" * Use the key fn returns for the encrypted columns instead of %s,\n", keyEnv))
+	w.WriteString(" * such as a key fetched from a KMS. fn may be async and is called once, the\n")
+	w.WriteString(" * first time a column is encrypted or decrypted.\n")
+	w.WriteString(" * @param {() => (Uint8Array | string | Promise<Uint8Array | string>)} fn - 32 byte key, or its base64\n")
+	w.WriteString(" */\n")
+	w.WriteString("function setEncryptionKey(fn) {\n")
+	w.WriteString("  encryptionKey = fn;\n")
+	w.WriteString("  cryptoKey = null;\n")
+	w.WriteString("}\n\n")
+	w.WriteString("function fromBase64(text) {\n")
+	w.WriteString("  const binary = atob(text);\n")
+	w.WriteString("  const bytes = new Uint8Array(binary.length);\n")
+	w.WriteString("  for (let i = 0; i < binary.length; i++) {\n")
+	w.WriteString("    bytes[i] = binary.charCodeAt(i);\n")
+	w.WriteString("  }\n")
+	w.WriteString("  return bytes;\n")
+	w.WriteString("}\n\n")
+	w.WriteString("function toBase64(bytes) {\n")
+	w.WriteString("  let binary = '';\n")
+	w.WriteString("  for (let i = 0; i < bytes.length; i++) {\n")
+	w.WriteString("    binary += String.fromCharCode(bytes[i]);\n")
+	w.WriteString("  }\n")
+	w.WriteString("  return btoa(binary);\n")
+	w.WriteString("}\n\n")
+	w.WriteString("function getCryptoKey() {\n")
+	w.WriteString("  if (!cryptoKey) {\n")
+	w.WriteString("    const pending = (async () => {\n")
+	w.WriteString("      let key = await encryptionKey();\n")
+	w.WriteString("      if (!key) {\n")
+	w.WriteString(fmt.Sprintf("        throw new Error('%s is not set; set it or call setEncryptionKey');\n", keyEnv))
+	w.WriteString("      }\n")
+	w.WriteString("      if (typeof key === 'string') {\n")
+	w.WriteString("        key = fromBase64(key);\n")
+	w.WriteString("      }\n")
+	w.WriteString("      if (key.length !== 32) {\n")
+	w.WriteString("        throw new Error('encryption key must be 32 bytes');\n")
+	w.WriteString("      }\n")
+	w.WriteString("      return crypto.subtle.importKey('raw', key, 'AES-GCM', false, ['encrypt', 'decrypt']);\n")
+	w.WriteString("    })();\n")
+	w.WriteString("    // A key that failed to load is fetched again on the next call\n")
+	w.WriteString("    pending.catch(() => {\n")
+	w.WriteString("      if (cryptoKey === pending) {\n")
+	w.WriteString("        cryptoKey = null;\n")
+	w.WriteString("      }\n")
+	w.WriteString("    });\n")
+	w.WriteString("    cryptoKey = pending;\n")
+	w.WriteString("  }\n")
+	w.WriteString("  return cryptoKey;\n")
+	w.WriteString("}\n\n")
+	w.WriteString("/**\n")
+	w.WriteString(" * Encrypt a value written to an encrypted column. NULLs stay NULL.\n")
+	w.WriteString(" */\n")
+	w.WriteString("async function encryptValue(value) {\n")
+	w.WriteString("  if (value === null || value === undefined) {\n")
+	w.WriteString("    return value;\n")
+	w.WriteString("  }\n")
+	w.WriteString("  const key = await getCryptoKey();\n")
+	w.WriteString("  const nonce = crypto.getRandomValues(new Uint8Array(12));\n")
+	w.WriteString("  const sealed = new Uint8Array(await crypto.subtle.encrypt({ name: 'AES-GCM', iv: nonce }, key, new TextEncoder().encode(String(value))));\n")
+	w.WriteString("  const data = new Uint8Array(nonce.length + sealed.length);\n")
+	w.WriteString("  data.set(nonce);\n")
+	w.WriteString("  data.set(sealed, nonce.length);\n")
+	w.WriteString("  return ENCRYPTION_PREFIX + toBase64(data);\n")
+	w.WriteString("}\n\n")
+	w.WriteString("/**\n")
+	w.WriteString(" * Decrypt a value read from an encrypted column. Values written before the\n")
+	w.WriteString(" * column was encrypted have no prefix and are returned as they are.\n")
+	w.WriteString(" */\n")
+	w.WriteString("async function decryptValue(value) {\n")
+	w.WriteString("  if (typeof value !== 'string' || !value.startsWith(ENCRYPTION_PREFIX)) {\n")
+	w.WriteString("    return value;\n")
+	w.WriteString("  }\n")
+	w.WriteString("  const key = await getCryptoKey();\n")
+	w.WriteString("  try {\n")
+	w.WriteString("    const data = fromBase64(value.slice(ENCRYPTION_PREFIX.length));\n")
+	w.WriteString("    const plaintext = await crypto.subtle.decrypt({ name: 'AES-GCM', iv: data.subarray(0, 12) }, key, data.subarray(12));\n")
+	w.WriteString("    return new TextDecoder().decode(plaintext);\n")
+	w.WriteString("  } catch {\n")
+	w.WriteString("    throw new Error('failed to decrypt column');\n")
+	w.WriteString("  }\n")
+	w.WriteString("}\n\n")
+	w.WriteString("/**\n")
+	w.WriteString(" * Decrypt in place the columns of a row or of rows, or a single value\n")
+	w.WriteString(" */\n")
+	w.WriteString("async function decryptColumns(result, columns) {\n")
+	w.WriteString("  if (Array.isArray(result)) {\n")
+	w.WriteString("    for (let i = 0; i < result.length; i++) {\n")
+	w.WriteString("      result[i] = await decryptColumns(result[i], columns);\n")
+	w.WriteString("    }\n")
+	w.WriteString("    return result;\n")
+	w.WriteString("  }\n")
+	w.WriteString("  if (result === null || typeof result !== 'object') {\n")
+	w.WriteString("    return decryptValue(result);\n")
+	w.WriteString("  }\n")
+	w.WriteString("  for (const column of columns) {\n")
+	w.WriteString("    if (column in result) {\n")
+	w.WriteString("      result[column] = await decryptValue(result[column]);\n")
+	w.WriteString("    }\n")
+	w.WriteString("  }\n")
+	w.WriteString("  return result;\n")
+	w.WriteString("}\n\n")
+
+	for _, format := range g.moduleFormats() {
+		path := g.encryptionPath(format)
+		content := g.out.Header(filepath.Base(path)) + "\n" + w.String() + format.exports("setEncryptionKey, encryptValue, decryptColumns")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Generator) encryptionPath(format moduleFormat) string {
+	return filepath.Join(g.Config.Gen.JS.Out, encryptionModule+format.ext)
+}
+
+// encryptionKeyLookup reads the key environment variable the runtime's way,
+// or gives undefined on edge runtimes, which must call setEncryptionKey
+func (g *Generator) encryptionKeyLookup() string {
+	name := g.Config.Encryption.KeyEnvName()
+	switch g.Config.Gen.JS.Runtime {
+	case config.RuntimeDeno:
+		return fmt.Sprintf("Deno.env.get('%s')", name)
+	case config.RuntimeBun:
+		return "Bun.env." + name
+	case config.RuntimeEdge:
+		return "undefined"
+	default:
+		return "process.env." + name
+	}
+}
+
+// importEncryption imports the helpers of the encryption module into a
+// generated module
+func (f moduleFormat) importEncryption(names string) string {
+	if f.esm {
+		return fmt.Sprintf("import { %s } from './%s%s';\n", names, encryptionModule, f.ext)
+	}
+	return fmt.Sprintf("const { %s } = require('./%s');\n", names, encryptionModule)
+}
+
+// encryptionImports returns the helpers the queries of a module need
+func (g *Generator) encryptionImports(queries []*parser.Query) string {
+	encrypts, decrypts := false, false
+	for _, query := range queries {
+		for _, param := range query.Params {
+			encrypts = encrypts || param.Encrypted
+		}
+		decrypts = decrypts || len(g.encryptedColumns(query)) > 0
+		for _, rel := range query.Includes {
+			decrypts = decrypts || len(tableEncryptedColumns(rel.Table)) > 0
+		}
+	}
+	var names []string
+	if encrypts {
+		names = append(names, "encryptValue")
+	}
+	if decrypts {
+		names = append(names, "decryptColumns")
+	}
+	return strings.Join(names, ", ")
+}
+
+// encryptedColumns returns the result columns of a query read from
+// encrypted columns, those of the table for SELECT *
+func (g *Generator) encryptedColumns(query *parser.Query) []string {
+	var names []string
+	if len(query.Columns) == 1 && query.Columns[0].Name == "*" {
+		for _, table := range g.schema.Tables {
+			if strings.EqualFold(table.Name, query.Columns[0].Table) {
+				return tableEncryptedColumns(table)
+			}
+		}
+		return nil
+	}
+	for _, col := range query.Columns {
+		if col.Encrypted {
+			names = append(names, col.Name)
+		}
+	}
+	return names
+}
+
+func tableEncryptedColumns(table *parser.Table) []string {
+	var names []string
+	for _, col := range table.Columns {
+		if col.Encrypted {
+			names = append(names, col.Name)
+		}
+	}
+	return names
+}
+
+// columnList writes column names as a JavaScript array
+func columnList(names []string) string {
+	return "['" + strings.Join(names, "', '") + "']"
+}
+
+// writeEncryptParams encrypts the params written to encrypted columns
+func writeEncryptParams(w *strings.Builder, params []*parser.Param, paramNames []string) {
+	for i, param := range params {
+		if param.Encrypted {
+			w.WriteString(fmt.Sprintf("    %s = await encryptValue(%s);\n", paramNames[i], paramNames[i]))
+		}
+	}
+}
+
+// decryptReturns makes the return statements of a query method's body
+// decrypt the encrypted columns of what they return
+func decryptReturns(body string, columns []string) string {
+	if len(columns) == 0 {
+		return body
+	}
+	lines := strings.SplitAfter(body, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "    return ") && strings.HasSuffix(line, ";\n") {
+			expr := strings.TrimSuffix(strings.TrimPrefix(line, "    return "), ";\n")
+			lines[i] = fmt.Sprintf("    return decryptColumns(%s, %s);\n", expr, columnList(columns))
+		}
+	}
+	return strings.Join(lines, "")
+}
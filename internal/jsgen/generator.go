@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/Lumos-Labs-HQ/flash/internal/config"
@@ -13,11 +14,14 @@ import (
 )
 
 type Generator struct {
-	Config       *config.Config
+	Config *config.Config
+	// Force regenerates every file, ignoring the generation cache
+	Force        bool
 	schema       *parser.Schema
 	schemaParser *parser.SchemaParser
 	queryParser  *parser.QueryParser
 	cache        *gencommon.GenerationCache
+	out          *gencommon.Output
 }
 
 func New(cfg *config.Config) *Generator {
@@ -25,27 +29,54 @@ func New(cfg *config.Config) *Generator {
 		Config:       cfg,
 		schemaParser: parser.NewSchemaParser(cfg),
 		queryParser:  parser.NewQueryParser(cfg),
-		cache:        gencommon.NewGenerationCache(),
+		cache:        gencommon.NewGenerationCache(cfg.Gen.JS.Out),
 	}
 }
 
 func (g *Generator) Generate() error {
+	if err := g.Config.Gen.JS.Validate(g.Config.Database.Provider); err != nil {
+		return err
+	}
+	out, err := gencommon.NewOutput(g.Config.Gen, "js", "//")
+	if err != nil {
+		return err
+	}
+	g.out = out
+
 	if err := os.MkdirAll(g.Config.Gen.JS.Out, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	schema, err := g.schemaParser.Parse()
+	// Compute checksums for incremental generation
+	schemaHash, _ := g.cache.ComputeSchemaChecksum(g.Config.SchemaDir)
+	configHash := fmt.Sprintf("%x", []byte(g.Config.Gen.JS.Out+"|"+g.Config.Database.Provider+"|"+g.Config.SoftDelete.Column+"|"+strings.Join(g.Config.Timestamps.Columns(), ",")+"|"+g.Config.Gen.OutputOptions()+"|"+g.Config.Gen.JS.ModuleFormat()+"|"+g.driver()+"|"+g.Config.Gen.JS.Runtime+"|"+strconv.FormatBool(g.Config.Gen.JS.DataLoader)+"|"+g.Config.Encryption.Options()))
+	queryChecksums, err := gencommon.ComputeQueryChecksums(g.Config.Queries)
 	if err != nil {
-		return fmt.Errorf("failed to parse schema: %w", err)
+		return fmt.Errorf("failed to read query files: %w", err)
 	}
-	g.schema = schema
 
-	queries, err := g.queryParser.Parse(schema)
+	if !g.Force && g.cache.UpToDate(schemaHash, configHash, queryChecksums) {
+		gencommon.PrintUpToDateMessage()
+		return nil
+	}
+	fullRegen := g.Force || g.cache.ShouldRegenerateAll(schemaHash, configHash) || !g.cache.OutputsIntact()
+
+	schema, queries, err := gencommon.ParseInputs(g.schemaParser, g.queryParser)
 	if err != nil {
-		return fmt.Errorf("failed to parse queries: %w", err)
+		return err
+	}
+	g.schema = schema
+
+
+	if err := g.generateQueriesIncremental(queries, fullRegen); err != nil {
+		return err
 	}
 
-	if err := g.generateQueriesIncremental(queries, false); err != nil {
+	if err := g.generateLoaders(queries); err != nil {
+		return err
+	}
+
+	if err := g.generateEncryption(); err != nil {
 		return err
 	}
 
@@ -57,6 +88,13 @@ func (g *Generator) Generate() error {
 		return err
 	}
 
+	g.cache.UpdateSchemaChecksum(schemaHash)
+	g.cache.UpdateConfigChecksum(configHash)
+	g.cache.PruneQueries(queryChecksums)
+	g.cache.MarkGeneration()
+	g.cache.RecordOutputs()
+	g.cache.Save()
+
 	return nil
 }
 
@@ -72,13 +110,15 @@ func (g *Generator) generateQueries(queries []*parser.Query) error {
 
 	usedNames := make(map[string]int)
 
-	for sourceFile, fileQueries := range queryGroups {
+	for _, sourceFile := range gencommon.SortedSourceFiles(queryGroups) {
+		fileQueries := queryGroups[sourceFile]
 		// Pre-allocate buffer: ~200 bytes header + ~400 bytes per query method
 		estimatedSize := 200 + (len(fileQueries) * 400)
 		var w strings.Builder
 		w.Grow(estimatedSize)
 
 		w.WriteString("// Code generated by FlashORM. DO NOT EDIT.\n\n")
+		g.writeCursorHelpers(&w, fileQueries)
 
 		w.WriteString("class Queries {\n")
 		w.WriteString("  constructor(db) {\n")
@@ -88,6 +128,11 @@ func (g *Generator) generateQueries(queries []*parser.Query) error {
 
 		for _, query := range fileQueries {
 			g.generateOptimizedQueryMethod(&w, query)
+			if query.Paginate != nil {
+				if err := g.generatePageMethod(&w, query); err != nil {
+					return err
+				}
+			}
 		}
 
 		w.WriteString("}\n\nmodule.exports = { Queries };\n")
@@ -112,7 +157,7 @@ func (g *Generator) generateQueries(queries []*parser.Query) error {
 }
 
 func (g *Generator) generateOptimizedQueryMethod(w *strings.Builder, query *parser.Query) {
-	methodName := utils.Uncapitalize(query.Name)
+	methodName := g.out.FunctionName(config.NamingCamel, query.Name, "")
 	sql := g.convertSQL(query.SQL)
 	sql = strings.ReplaceAll(sql, "`", "\\`")
 	sql = strings.ReplaceAll(sql, "${", "\\${")
@@ -131,13 +176,19 @@ func (g *Generator) generateOptimizedQueryMethod(w *strings.Builder, query *pars
 	isHotQuery := isSingleColumn && query.Cmd == ":one" && len(query.Params) <= 2
 
 	w.WriteString(fmt.Sprintf("  async %s(%s) {\n", methodName, strings.Join(paramNames, ", ")))
+	writeEncryptParams(w, query.Params, paramNames)
+
+	if len(query.Includes) > 0 {
+		g.generateIncludeExecution(w, query, sql, paramNames)
+		w.WriteString("  }\n\n")
+		return
+	}
 
 	w.WriteString(fmt.Sprintf("    let stmt = this._stmts.get('%s');\n", methodName))
 	w.WriteString("    if (!stmt) {\n")
 
-	provider := g.Config.Database.Provider
-	useNamedStmt := (provider == "" || provider == "postgresql" || provider == "postgres") &&
-		(isHotQuery || len(query.Params) == 0)
+	driver := g.driver()
+	useNamedStmt := driver == config.DriverPG && (isHotQuery || len(query.Params) == 0)
 
 	if useNamedStmt {
 		w.WriteString(fmt.Sprintf("      stmt = { name: '%s', text: `%s` };\n", methodName, sql))
@@ -148,14 +199,25 @@ func (g *Generator) generateOptimizedQueryMethod(w *strings.Builder, query *pars
 	w.WriteString(fmt.Sprintf("      this._stmts.set('%s', stmt);\n", methodName))
 	w.WriteString("    }\n")
 
-	switch provider {
-	case "sqlite", "sqlite3":
-		g.generateSQLiteExecution(w, paramNames, hasColumns, query.Cmd, isSingleColumn, query.Columns)
-	case "mysql":
-		g.generateMySQLExecution(w, paramNames, hasColumns, query.Cmd, isSingleColumn, query.Columns)
+	// Written apart so its return statements can decrypt the results
+	exec := &strings.Builder{}
+	switch driver {
+	case config.DriverBetterSQLite3, config.DriverBunSQLite:
+		g.generateSQLiteExecution(exec, paramNames, hasColumns, query.Cmd, isSingleColumn, query.Columns)
+	case config.DriverLibSQL:
+		g.generateLibSQLExecution(exec, paramNames, hasColumns, query.Cmd, isSingleColumn, query.Columns)
+	case config.DriverMySQL2:
+		g.generateMySQLExecution(exec, paramNames, hasColumns, query.Cmd, isSingleColumn, query.Columns)
+	case config.DriverPlanetScale:
+		g.generatePlanetScaleExecution(exec, paramNames, hasColumns, query.Cmd, isSingleColumn, query.Columns)
+	case config.DriverPostgresJS:
+		g.generatePostgresJSExecution(exec, paramNames, hasColumns, query.Cmd, isSingleColumn, query.Columns)
+	case config.DriverNeon:
+		g.generateNeonExecution(exec, paramNames, hasColumns, query.Cmd, isSingleColumn, query.Columns)
 	default:
-		g.generatePostgreSQLExecution(w, paramNames, hasColumns, query.Cmd, isSingleColumn, query.Columns, isHotQuery)
+		g.generatePostgreSQLExecution(exec, paramNames, hasColumns, query.Cmd, isSingleColumn, query.Columns, isHotQuery)
 	}
+	w.WriteString(decryptReturns(exec.String(), g.encryptedColumns(query)))
 
 	w.WriteString("  }\n\n")
 }
@@ -177,6 +239,18 @@ func (g *Generator) generatePostgreSQLExecution(w *strings.Builder, paramNames [
 		}
 	}
 
+	g.writePGResult(w, hasColumns, cmd, isSingleColumn, columns)
+}
+
+// generateNeonExecution runs a query over HTTP with the Neon serverless
+// driver, whose full results have the shape of pg's
+func (g *Generator) generateNeonExecution(w *strings.Builder, paramNames []string, hasColumns bool, cmd string, isSingleColumn bool, columns []*parser.QueryColumn) {
+	w.WriteString("    const r = await this.db.query(stmt, [" + strings.Join(paramNames, ", ") + "], { fullResults: true });\n")
+	g.writePGResult(w, hasColumns, cmd, isSingleColumn, columns)
+}
+
+// writePGResult returns the result of a query from a pg-style result r
+func (g *Generator) writePGResult(w *strings.Builder, hasColumns bool, cmd string, isSingleColumn bool, columns []*parser.QueryColumn) {
 	if hasColumns {
 		if cmd == ":one" {
 			if isSingleColumn && len(columns) > 0 {
@@ -203,6 +277,37 @@ func (g *Generator) generatePostgreSQLExecution(w *strings.Builder, paramNames [
 	}
 }
 
+// generatePostgresJSExecution runs a query with postgres.js, which caches
+// prepared statements itself
+func (g *Generator) generatePostgresJSExecution(w *strings.Builder, paramNames []string, hasColumns bool, cmd string, isSingleColumn bool, columns []*parser.QueryColumn) {
+	w.WriteString("    const r = await this.db.unsafe(stmt, [" + strings.Join(paramNames, ", ") + "], { prepare: true });\n")
+
+	if hasColumns {
+		if cmd == ":one" {
+			if isSingleColumn && len(columns) > 0 {
+				w.WriteString(fmt.Sprintf("    return r[0] ? r[0].%s : null;\n", columns[0].Name))
+			} else {
+				w.WriteString("    return r[0] || null;\n")
+			}
+		} else {
+			if isSingleColumn && len(columns) > 0 {
+				w.WriteString(fmt.Sprintf("    return r.map(row => row.%s);\n", columns[0].Name))
+			} else {
+				w.WriteString("    return r;\n")
+			}
+		}
+	} else {
+		switch cmd {
+		case ":one":
+			w.WriteString("    return r[0] || null;\n")
+		case ":many":
+			w.WriteString("    return r;\n")
+		default:
+			w.WriteString("    return r.count;\n")
+		}
+	}
+}
+
 func (g *Generator) generateMySQLExecution(w *strings.Builder, paramNames []string, hasColumns bool, cmd string, isSingleColumn bool, columns []*parser.QueryColumn) {
 	// The 'execute' method automatically prepares and caches statements
 	w.WriteString("    const sql = typeof stmt === 'string' ? stmt : stmt.text;\n")
@@ -279,6 +384,42 @@ func (g *Generator) generateSQLiteExecution(w *strings.Builder, paramNames []str
 	}
 }
 
+// generateLibSQLExecution runs a query with @libsql/client, whose execute
+// is async unlike better-sqlite3
+func (g *Generator) generateLibSQLExecution(w *strings.Builder, paramNames []string, hasColumns bool, cmd string, isSingleColumn bool, columns []*parser.QueryColumn) {
+	w.WriteString("    const r = await this.db.execute({ sql: stmt, args: [" + strings.Join(paramNames, ", ") + "] });\n")
+	g.writeRowsResult(w, hasColumns, cmd, isSingleColumn, columns)
+}
+
+// generatePlanetScaleExecution runs a query over HTTP with the PlanetScale
+// serverless driver
+func (g *Generator) generatePlanetScaleExecution(w *strings.Builder, paramNames []string, hasColumns bool, cmd string, isSingleColumn bool, columns []*parser.QueryColumn) {
+	w.WriteString("    const r = await this.db.execute(stmt, [" + strings.Join(paramNames, ", ") + "]);\n")
+	g.writeRowsResult(w, hasColumns, cmd, isSingleColumn, columns)
+}
+
+// writeRowsResult returns the result of a query from a result r with rows
+// and rowsAffected, as libsql and PlanetScale return
+func (g *Generator) writeRowsResult(w *strings.Builder, hasColumns bool, cmd string, isSingleColumn bool, columns []*parser.QueryColumn) {
+	if hasColumns {
+		if cmd == ":one" {
+			if isSingleColumn && len(columns) > 0 {
+				w.WriteString(fmt.Sprintf("    return r.rows[0] ? r.rows[0].%s : null;\n", columns[0].Name))
+			} else {
+				w.WriteString("    return r.rows[0] || null;\n")
+			}
+		} else {
+			if isSingleColumn && len(columns) > 0 {
+				w.WriteString(fmt.Sprintf("    return r.rows.map(row => row.%s);\n", columns[0].Name))
+			} else {
+				w.WriteString("    return r.rows;\n")
+			}
+		}
+	} else {
+		w.WriteString("    return r.rowsAffected;\n")
+	}
+}
+
 func (g *Generator) getReturnType(query *parser.Query) string {
 	if len(query.Columns) == 1 && query.Columns[0].Name != "*" {
 		colName := strings.ToLower(query.Columns[0].Name)
@@ -308,7 +449,7 @@ func (g *Generator) getReturnType(query *parser.Query) string {
 	}
 
 	if len(query.Columns) == 1 && query.Columns[0].Name == "*" && query.Columns[0].Table != "" {
-		return utils.Capitalize(query.Columns[0].Table)
+		return g.out.ModelName(query.Columns[0].Table, utils.Capitalize)
 	}
 
 	if len(query.Columns) > 1 {
@@ -318,79 +459,107 @@ func (g *Generator) getReturnType(query *parser.Query) string {
 	// Use shared utility to extract table name
 	tableName := utils.ExtractTableName(query.SQL)
 	if tableName != "" {
-		return utils.Capitalize(tableName)
+		return g.out.ModelName(tableName, utils.Capitalize)
 	}
 
 	return "Object"
 }
 
 func (g *Generator) generateDatabase(queries []*parser.Query) error {
-	// Get unique source files
-	sourceFiles := make(map[string]bool)
+	// One module per generated query file
 	filesList := []string{}
-	for _, query := range queries {
-		sourceFile := query.SourceFile
-		if sourceFile == "" {
-			sourceFile = "queries"
+	for _, group := range g.out.GroupQueries(queries) {
+		filesList = append(filesList, group.Name)
+	}
+	if tables, _ := g.loaderTables(queries); len(tables) > 0 {
+		filesList = append(filesList, loadersModule)
+	}
+
+	for _, format := range g.moduleFormats() {
+		var w strings.Builder
+		w.Grow(512) // Pre-allocate for index file
+		w.WriteString(g.out.Header("index" + format.ext))
+		if g.Config.Gen.JS.Runtime == config.RuntimeDeno {
+			// Deno finds the types of a JavaScript module through this
+			w.WriteString(fmt.Sprintf("// @ts-self-types=\"./%s\"\n", format.declarations))
 		}
-		baseName := strings.TrimSuffix(sourceFile, ".sql")
-		if !sourceFiles[baseName] {
-			sourceFiles[baseName] = true
-			filesList = append(filesList, baseName)
+		g.importDriver(&w, format)
+		if tables, _ := g.loaderTables(queries); g.Config.Gen.JS.DataLoader && len(tables) > 0 {
+			if format.esm {
+				w.WriteString(fmt.Sprintf("import DataLoader from '%s';\n", g.dataLoaderModule()))
+			} else {
+				w.WriteString(fmt.Sprintf("const DataLoader = require('%s');\n", g.dataLoaderModule()))
+			}
 		}
-	}
 
-	var w strings.Builder
-	w.Grow(512) // Pre-allocate for index file
-	w.WriteString("// Code generated by FlashORM. DO NOT EDIT.\n")
+		if len(filesList) == 1 {
+			w.WriteString(format.importQueries(filesList[0], ""))
+			w.WriteString("\n")
+		} else {
+			for _, baseName := range filesList {
+				w.WriteString(format.importQueries(baseName, utils.Capitalize(baseName)+"Queries"))
+			}
+			w.WriteString("\n")
+
+			w.WriteString("// Combine all query classes into one\n")
+			w.WriteString("class Queries {\n")
+			w.WriteString("  constructor(db) {\n")
+			w.WriteString("    this.db = db;\n")
+
+			for i, baseName := range filesList {
+				className := utils.Capitalize(baseName)
+				instanceName := fmt.Sprintf("_%s", baseName)
+				w.WriteString(fmt.Sprintf("    const %s = new %sQueries(db);\n", instanceName, className))
+
+				w.WriteString(fmt.Sprintf("    Object.getOwnPropertyNames(Object.getPrototypeOf(%s)).forEach(name => {\n", instanceName))
+				w.WriteString("      if (name !== 'constructor' && typeof " + instanceName + "[name] === 'function') {\n")
+				w.WriteString("        this[name] = " + instanceName + "[name].bind(" + instanceName + ");\n")
+				w.WriteString("      }\n")
+				w.WriteString("    });\n")
+
+				if i < len(filesList)-1 {
+					w.WriteString("\n")
+				}
+			}
 
-	if len(filesList) == 1 {
-		w.WriteString(fmt.Sprintf("const { Queries } = require('./%s');\n\n", filesList[0]))
-	} else {
-		for _, baseName := range filesList {
-			w.WriteString(fmt.Sprintf("const { Queries: %sQueries } = require('./%s');\n",
-				utils.Capitalize(baseName), baseName))
+			w.WriteString("  }\n")
+			w.WriteString("}\n\n")
 		}
-		w.WriteString("\n")
-
-		w.WriteString("// Combine all query classes into one\n")
-		w.WriteString("class Queries {\n")
-		w.WriteString("  constructor(db) {\n")
-		w.WriteString("    this.db = db;\n")
 
-		for i, baseName := range filesList {
-			className := utils.Capitalize(baseName)
-			instanceName := fmt.Sprintf("_%s", baseName)
-			w.WriteString(fmt.Sprintf("    const %s = new %sQueries(db);\n", instanceName, className))
+		g.writeBatchClass(&w, queries)
 
-			w.WriteString(fmt.Sprintf("    Object.getOwnPropertyNames(Object.getPrototypeOf(%s)).forEach(name => {\n", instanceName))
-			w.WriteString("      if (name !== 'constructor' && typeof " + instanceName + "[name] === 'function') {\n")
-			w.WriteString("        this[name] = " + instanceName + "[name].bind(" + instanceName + ");\n")
-			w.WriteString("      }\n")
-			w.WriteString("    });\n")
+		w.WriteString("/**\n")
+		w.WriteString(" * Create a new database client\n")
+		w.WriteString(fmt.Sprintf(" * @param {Object} db - Database connection (%s)\n", g.driverConnection()))
+		w.WriteString(" * @returns {Queries}\n")
+		w.WriteString(" */\n")
+		w.WriteString("function New(db) {\n")
+		w.WriteString("  return new Queries(db);\n")
+		w.WriteString("}\n\n")
 
-			if i < len(filesList)-1 {
-				w.WriteString("\n")
-			}
+		exports := "New, Queries"
+		if g.Config.Encryption.Enabled() {
+			// Re-exported so every query module uses the key it is given
+			w.WriteString(format.importEncryption("setEncryptionKey"))
+			w.WriteString("\n")
+			exports += ", setEncryptionKey"
 		}
+		if g.hasConnect() {
+			g.writeConnect(&w)
+			exports += ", connect"
+		}
+		if tables, _ := g.loaderTables(queries); g.Config.Gen.JS.DataLoader && len(tables) > 0 {
+			g.writeCreateLoaders(&w, queries)
+			exports += ", createLoaders"
+		}
+		w.WriteString(format.exports(exports))
 
-		w.WriteString("  }\n")
-		w.WriteString("}\n\n")
+		path := filepath.Join(g.Config.Gen.JS.Out, "index"+format.ext)
+		if err := os.WriteFile(path, []byte(w.String()), 0644); err != nil {
+			return err
+		}
 	}
-
-	w.WriteString("/**\n")
-	w.WriteString(" * Create a new database client\n")
-	w.WriteString(" * @param {Object} db - Database connection (pg.Pool, mysql2.Pool, or better-sqlite3 instance)\n")
-	w.WriteString(" * @returns {Queries}\n")
-	w.WriteString(" */\n")
-	w.WriteString("function New(db) {\n")
-	w.WriteString("  return new Queries(db);\n")
-	w.WriteString("}\n\n")
-
-	w.WriteString("module.exports = { New, Queries };\n")
-
-	path := filepath.Join(g.Config.Gen.JS.Out, "index.js")
-	return os.WriteFile(path, []byte(w.String()), 0644)
+	return nil
 }
 
 func (g *Generator) mapSQLTypeToJS(sqlType string) string {
@@ -484,10 +653,13 @@ func (g *Generator) generateTypeScriptDeclarations(schema *parser.Schema, querie
 	// Pre-allocate: header + ~200 bytes per table + ~300 bytes per query
 	estimatedSize := 200 + (len(schema.Tables) * 200) + (len(queries) * 300)
 	w.Grow(estimatedSize)
-	w.WriteString("// Code generated by FlashORM. DO NOT EDIT.\n\n")
+
+	if tables, _ := g.loaderTables(queries); g.Config.Gen.JS.DataLoader && len(tables) > 0 {
+		w.WriteString(fmt.Sprintf("import DataLoader from '%s';\n\n", g.dataLoaderModule()))
+	}
 
 	for _, table := range schema.Tables {
-		structName := utils.Capitalize(table.Name)
+		structName := g.out.ModelName(table.Name, utils.Capitalize)
 		w.WriteString(fmt.Sprintf("export interface %s {\n", structName))
 		for _, col := range table.Columns {
 			jsType := g.mapSQLTypeToJS(col.Type)
@@ -499,30 +671,39 @@ func (g *Generator) generateTypeScriptDeclarations(schema *parser.Schema, querie
 		w.WriteString("}\n\n")
 	}
 
-	complexQueries := make(map[string]*parser.Query)
+	seenResults := make(map[string]bool)
 	for _, query := range queries {
-		if len(query.Columns) > 1 && query.Cmd != ":exec" {
-			complexQueries[query.Name] = query
+		if (len(query.Columns) <= 1 && len(query.Includes) == 0) || query.Cmd == ":exec" || seenResults[query.Name] {
+			continue
 		}
-	}
-
-	for queryName, query := range complexQueries {
-		interfaceName := utils.Capitalize(queryName) + "Result"
-		w.WriteString(fmt.Sprintf("export interface %s {\n", interfaceName))
+		seenResults[query.Name] = true
 
-		for _, col := range query.Columns {
-			colType := g.inferColumnTypeFromSchema(col)
-			w.WriteString(fmt.Sprintf("  %s: %s;\n", col.Name, colType))
+		interfaceName := utils.Capitalize(query.Name) + "Result"
+		if len(query.Columns) <= 1 {
+			// SELECT * with relations: the table's model plus the relations
+			w.WriteString(fmt.Sprintf("export interface %s extends %s {\n", interfaceName, g.getReturnType(query)))
+		} else {
+			w.WriteString(fmt.Sprintf("export interface %s {\n", interfaceName))
+			for _, col := range query.Columns {
+				colType := g.inferColumnTypeFromSchema(col)
+				w.WriteString(fmt.Sprintf("  %s: %s;\n", col.Name, colType))
+			}
+		}
+		for _, rel := range query.Includes {
+			w.WriteString(fmt.Sprintf("  %s: %s;\n", rel.Name, g.relationTSType(rel)))
 		}
 		w.WriteString("}\n\n")
 	}
 
+	g.writeBatchDeclarations(&w)
+
 	w.WriteString("export class Queries {\n")
 	w.WriteString("  constructor(db: any);\n\n")
+	w.WriteString("  batch(): QueryBatch;\n")
 
 	seenMethods := make(map[string]bool)
 	for _, query := range queries {
-		methodName := utils.Uncapitalize(query.Name)
+		methodName := g.out.FunctionName(config.NamingCamel, query.Name, "")
 
 		if seenMethods[methodName] {
 			continue
@@ -542,10 +723,11 @@ func (g *Generator) generateTypeScriptDeclarations(schema *parser.Schema, querie
 
 		returnType := g.getReturnType(query)
 
-		if len(query.Columns) > 1 && query.Cmd != ":exec" {
+		if (len(query.Columns) > 1 || len(query.Includes) > 0) && query.Cmd != ":exec" {
 			returnType = utils.Capitalize(query.Name) + "Result"
 		}
 
+		rowType := returnType
 		switch query.Cmd {
 		case ":one":
 			returnType = fmt.Sprintf("Promise<%s | null>", returnType)
@@ -556,13 +738,38 @@ func (g *Generator) generateTypeScriptDeclarations(schema *parser.Schema, querie
 		}
 
 		w.WriteString(fmt.Sprintf("  %s(%s): %s;\n", methodName, strings.Join(params, ", "), returnType))
+
+		if query.Paginate != nil {
+			pageParams := append(params, "cursor: string | null", "limit: number")
+			w.WriteString(fmt.Sprintf("  %s(%s): Promise<{ rows: %s[]; nextCursor: string | null }>;\n",
+				g.out.FunctionName(config.NamingCamel, query.Name, "page"), strings.Join(pageParams, ", "), rowType))
+		}
 	}
 
+	g.writeLoaderDeclarations(&w, queries)
+
 	w.WriteString("}\n\n")
 	w.WriteString("export function New(db: any): Queries;\n")
+	if g.hasConnect() {
+		if g.envLookup() != "" {
+			w.WriteString("export function connect(url?: string): Queries;\n")
+		} else {
+			w.WriteString("export function connect(url: string): Queries;\n")
+		}
+	}
+	g.writeCreateLoadersDeclarations(&w, queries)
+	if g.Config.Encryption.Enabled() {
+		w.WriteString("export function setEncryptionKey(fn: () => Uint8Array | string | Promise<Uint8Array | string>): void;\n")
+	}
 
-	path := filepath.Join(g.Config.Gen.JS.Out, "index.d.ts")
-	return os.WriteFile(path, []byte(w.String()), 0644)
+	// ESM and CommonJS share the declarations, in a file next to each index
+	for _, format := range g.moduleFormats() {
+		path := filepath.Join(g.Config.Gen.JS.Out, format.declarations)
+		if err := os.WriteFile(path, []byte(g.out.Header(format.declarations)+"\n"+w.String()), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (g *Generator) inferColumnTypeFromSchema(col *parser.QueryColumn) string {
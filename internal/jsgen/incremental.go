@@ -1,11 +1,9 @@
 package jsgen
 
 import (
-	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
-	"strings"
 	"sync"
 
 	"github.com/Lumos-Labs-HQ/flash/internal/gencommon"
@@ -14,54 +12,49 @@ import (
 
 // generateQueriesIncremental generates JavaScript queries with incremental support and parallel code generation
 func (g *Generator) generateQueriesIncremental(queries []*parser.Query, fullRegen bool) error {
-	queryGroups := make(map[string][]*parser.Query)
-	for _, query := range queries {
-		sourceFile := query.SourceFile
-		if sourceFile == "" {
-			sourceFile = "queries"
-		}
-		queryGroups[sourceFile] = append(queryGroups[sourceFile], query)
-	}
-
-	type fileGroup struct {
-		sourceFile string
-		queries    []*parser.Query
-	}
-	
-	fileGroups := make([]fileGroup, 0, len(queryGroups))
-	for sourceFile, fileQueries := range queryGroups {
-		fileGroups = append(fileGroups, fileGroup{sourceFile, fileQueries})
-	}
-
-	usedNames := make(map[string]int)
-	var usedNamesMu sync.Mutex
+	groups := g.out.GroupQueries(queries)
 
 	numWorkers := runtime.NumCPU()
-	if numWorkers > len(fileGroups) {
-		numWorkers = len(fileGroups)
+	if numWorkers > len(groups) {
+		numWorkers = len(groups)
 	}
 
 	type generateResult struct {
 		err error
 	}
 
-	workChan := make(chan fileGroup, len(fileGroups))
-	resultChan := make(chan generateResult, len(fileGroups))
+	workChan := make(chan gencommon.QueryGroup, len(groups))
+	resultChan := make(chan generateResult, len(groups))
 
 	var wg sync.WaitGroup
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for fg := range workChan {
-				err := g.generateSingleJSFile(fg.sourceFile, fg.queries, fullRegen, &usedNamesMu, usedNames)
+			for group := range workChan {
+				err := g.generateSingleJSFile(group, fullRegen)
 				resultChan <- generateResult{err: err}
 			}
 		}()
 	}
 
-	for _, fg := range fileGroups {
-		workChan <- fg
+	formats := g.moduleFormats()
+	outputs := make([]string, 0, len(groups)*len(formats)+2*len(formats))
+	for _, group := range groups {
+		workChan <- group
+		for _, format := range formats {
+			outputs = append(outputs, g.queryOutputPath(group, format))
+		}
+	}
+	// The index modules are listed too, so switching gen.js.module removes
+	// the ones of the old format
+	for _, format := range formats {
+		outputs = append(outputs, filepath.Join(g.Config.Gen.JS.Out, "index"+format.ext), filepath.Join(g.Config.Gen.JS.Out, format.declarations))
+	}
+	if tables, _ := g.loaderTables(queries); len(tables) > 0 {
+		for _, format := range formats {
+			outputs = append(outputs, g.loadersPath(format))
+		}
 	}
 	close(workChan)
 
@@ -76,55 +69,61 @@ func (g *Generator) generateQueriesIncremental(queries []*parser.Query, fullRege
 		}
 	}
 
-	return nil
+	return g.cache.SetQueryOutputs(outputs)
 }
 
-func (g *Generator) generateSingleJSFile(sourceFile string, fileQueries []*parser.Query, fullRegen bool, usedNamesMu *sync.Mutex, usedNames map[string]int) error {
-	queryFile := filepath.Join(g.Config.Queries, sourceFile+".sql")
-	currentHash, _ := gencommon.ComputeFileChecksum(queryFile)
+func (g *Generator) queryOutputPath(group gencommon.QueryGroup, format moduleFormat) string {
+	return filepath.Join(g.Config.Gen.JS.Out, group.Name+format.ext)
+}
 
-	if !gencommon.ShouldRegenerateFile(g.cache, queryFile, currentHash, fullRegen) {
-		gencommon.PrintSkipMessage(sourceFile, ".js")
+func (g *Generator) generateSingleJSFile(group gencommon.QueryGroup, fullRegen bool) error {
+	formats := g.moduleFormats()
+	if !gencommon.ShouldRegenerateGroup(g.cache, g.Config.Queries, group, fullRegen) {
+		for _, format := range formats {
+			gencommon.PrintSkipMessage(group.Name, format.ext)
+		}
 		return nil
 	}
 
-	gencommon.PrintGenerateMessage(sourceFile, ".js")
-	
 	w := gencommon.GetBuilder()
 	defer gencommon.PutBuilder(w)
 
-	w.WriteString("// Code generated by FlashORM. DO NOT EDIT.\n\n")
+	g.writeCursorHelpers(w, group.Queries)
+	g.writeIncludeLoaders(w, group.Queries)
 	w.WriteString("class Queries {\n")
 	w.WriteString("  constructor(db) {\n")
 	w.WriteString("    this.db = db;\n")
 	w.WriteString("    this._stmts = new Map();\n")
 	w.WriteString("  }\n\n")
 
-	for _, query := range fileQueries {
+	for _, query := range group.Queries {
 		g.generateOptimizedQueryMethod(w, query)
+		if query.Paginate != nil {
+			if err := g.generatePageMethod(w, query); err != nil {
+				return err
+			}
+		}
 	}
 
-	w.WriteString("}\n\nmodule.exports = { Queries };\n")
-
-	baseName := strings.TrimSuffix(sourceFile, ".sql")
-	
-	usedNamesMu.Lock()
-	outputFile := baseName + ".js"
-	if count, exists := usedNames[baseName]; exists {
-		usedNames[baseName] = count + 1
-		outputFile = fmt.Sprintf("%s_%d.js", baseName, count+1)
-	} else {
-		usedNames[baseName] = 1
-	}
-	usedNamesMu.Unlock()
+	w.WriteString("}\n\n")
 
-	path := filepath.Join(g.Config.Gen.JS.Out, outputFile)
-	if err := os.WriteFile(path, []byte(w.String()), 0644); err != nil {
-		return err
-	}
+	imports := g.encryptionImports(group.Queries)
 
-	tableDeps := gencommon.ExtractTableDependencies(fileQueries)
-	gencommon.UpdateCacheForFile(g.cache, queryFile, currentHash, tableDeps, path)
+	for _, format := range formats {
+		gencommon.PrintGenerateMessage(group.Name, format.ext)
+
+		path := g.queryOutputPath(group, format)
+		content := g.out.Header(filepath.Base(path)) + "\n"
+		if imports != "" {
+			content += format.importEncryption(imports) + "\n"
+		}
+		content += w.String() + format.exports("Queries")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+
+		gencommon.UpdateCacheForGroup(g.cache, g.Config.Queries, group, path)
+	}
 
 	return nil
 }
@@ -0,0 +1,176 @@
+package jsgen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/gencommon"
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+	"github.com/Lumos-Labs-HQ/flash/internal/utils"
+)
+
+// loadersModule is the module holding the batch loaders of the tables
+const loadersModule = "loaders"
+
+// loaderTables returns the tables that get a getXByIds batch loader and their
+// primary keys. Tables whose loader name is taken by a query are left out,
+// and there are none when a query file generates the loaders module.
+func (g *Generator) loaderTables(queries []*parser.Query) ([]*parser.Table, []*parser.Column) {
+	for _, group := range g.out.GroupQueries(queries) {
+		if group.Name == loadersModule {
+			return nil, nil
+		}
+	}
+
+	taken := make(map[string]bool, len(queries))
+	for _, query := range queries {
+		taken[g.out.FunctionName(config.NamingCamel, query.Name, "")] = true
+	}
+
+	tables, keys := gencommon.LoaderTables(g.schema)
+	var loaderTables []*parser.Table
+	var loaderKeys []*parser.Column
+	for i, table := range tables {
+		if !taken[g.loaderName(table)] {
+			loaderTables = append(loaderTables, table)
+			loaderKeys = append(loaderKeys, keys[i])
+		}
+	}
+	return loaderTables, loaderKeys
+}
+
+func (g *Generator) loaderName(table *parser.Table) string {
+	return g.out.FunctionName(config.NamingCamel, "Get"+g.out.ModelName(table.Name, utils.Capitalize)+"ByIds", "")
+}
+
+// generateLoaders writes the loaders module, whose Queries class has the
+// batch loader of each table with a primary key. A loader takes a list of
+// keys and returns their rows in the same order, null for missing ones,
+// which is what a DataLoader batch function returns.
+func (g *Generator) generateLoaders(queries []*parser.Query) error {
+	tables, keys := g.loaderTables(queries)
+	if len(tables) == 0 {
+		return nil
+	}
+
+	var w strings.Builder
+	w.WriteString("class Queries {\n")
+	w.WriteString("  constructor(db) {\n")
+	w.WriteString("    this.db = db;\n")
+	w.WriteString("  }\n")
+
+	decrypts := false
+	for i, table := range tables {
+		key := keys[i]
+		decrypts = decrypts || len(tableEncryptedColumns(table)) > 0
+		var fetch string
+		switch g.Config.Database.Provider {
+		case "mysql", "sqlite", "sqlite3":
+			sql := strings.ReplaceAll(gencommon.LoaderQuery(table, key, g.Config.SoftDelete.Column, false), "`", "\\`")
+			fetch = g.fetchRows("this.db", fmt.Sprintf("`%s` + ids.map(() => '?').join(', ') + ')'", sql), "ids")
+		default:
+			sql := strings.ReplaceAll(gencommon.LoaderQuery(table, key, g.Config.SoftDelete.Column, true), "`", "\\`")
+			fetch = g.fetchRows("this.db", "`"+sql+"`", "[ids]")
+		}
+
+		w.WriteString("\n")
+		w.WriteString("  /**\n")
+		w.WriteString(fmt.Sprintf("   * Load the %s rows of ids, in the order of ids. Ids without a row give null.\n", table.Name))
+		w.WriteString(fmt.Sprintf("   * @param {Array} ids - %s values\n", key.Name))
+		w.WriteString("   */\n")
+		w.WriteString(fmt.Sprintf("  async %s(ids) {\n", g.loaderName(table)))
+		w.WriteString("    if (ids.length === 0) {\n")
+		w.WriteString("      return [];\n")
+		w.WriteString("    }\n")
+		w.WriteString(fmt.Sprintf("    const rows = %s;\n", fetch))
+		if columns := tableEncryptedColumns(table); len(columns) > 0 {
+			w.WriteString(fmt.Sprintf("    await decryptColumns(rows, %s);\n", columnList(columns)))
+		}
+		w.WriteString(fmt.Sprintf("    const byId = new Map(rows.map(row => [String(row.%s), row]));\n", key.Name))
+		w.WriteString("    return ids.map(id => byId.get(String(id)) || null);\n")
+		w.WriteString("  }\n")
+	}
+	w.WriteString("}\n\n")
+
+	for _, format := range g.moduleFormats() {
+		path := g.loadersPath(format)
+		content := g.out.Header(filepath.Base(path)) + "\n"
+		if decrypts {
+			content += format.importEncryption("decryptColumns") + "\n"
+		}
+		content += w.String() + format.exports("Queries")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Generator) loadersPath(format moduleFormat) string {
+	return filepath.Join(g.Config.Gen.JS.Out, loadersModule+format.ext)
+}
+
+// writeCreateLoaders emits createLoaders(), which gives a DataLoader per
+// table, when gen.js.dataloader is set
+func (g *Generator) writeCreateLoaders(w *strings.Builder, queries []*parser.Query) {
+	tables, _ := g.loaderTables(queries)
+	if !g.Config.Gen.JS.DataLoader || len(tables) == 0 {
+		return
+	}
+
+	w.WriteString("/**\n")
+	w.WriteString(" * Create a DataLoader per table. The lookups made in the same tick are\n")
+	w.WriteString(" * loaded with one query. Loaders cache what they load, so create them per\n")
+	w.WriteString(" * request.\n")
+	w.WriteString(" * @param {Queries} queries\n")
+	w.WriteString(" */\n")
+	w.WriteString("function createLoaders(queries) {\n")
+	w.WriteString("  return {\n")
+	for _, table := range tables {
+		w.WriteString(fmt.Sprintf("    %s: new DataLoader(ids => queries.%s(ids)),\n", g.loaderKey(table), g.loaderName(table)))
+	}
+	w.WriteString("  };\n")
+	w.WriteString("}\n\n")
+}
+
+// loaderKey names a table's DataLoader in the object createLoaders returns
+func (g *Generator) loaderKey(table *parser.Table) string {
+	return utils.Uncapitalize(g.out.ModelName(table.Name, utils.Capitalize))
+}
+
+// dataLoaderModule returns the module DataLoader is imported from
+func (g *Generator) dataLoaderModule() string {
+	if g.Config.Gen.JS.Runtime == config.RuntimeDeno {
+		return "npm:dataloader"
+	}
+	return "dataloader"
+}
+
+// writeLoaderDeclarations emits the typings of the batch loaders, which go
+// in the Queries class
+func (g *Generator) writeLoaderDeclarations(w *strings.Builder, queries []*parser.Query) {
+	tables, keys := g.loaderTables(queries)
+	for i, table := range tables {
+		w.WriteString(fmt.Sprintf("  %s(ids: readonly %s[]): Promise<(%s | null)[]>;\n",
+			g.loaderName(table), g.mapSQLTypeToJS(keys[i].Type), g.out.ModelName(table.Name, utils.Capitalize)))
+	}
+}
+
+// writeCreateLoadersDeclarations emits the typings of createLoaders()
+func (g *Generator) writeCreateLoadersDeclarations(w *strings.Builder, queries []*parser.Query) {
+	tables, keys := g.loaderTables(queries)
+	if !g.Config.Gen.JS.DataLoader || len(tables) == 0 {
+		return
+	}
+
+	w.WriteString("\nexport interface Loaders {\n")
+	for i, table := range tables {
+		w.WriteString(fmt.Sprintf("  %s: DataLoader<%s, %s | null>;\n",
+			g.loaderKey(table), g.mapSQLTypeToJS(keys[i].Type), g.out.ModelName(table.Name, utils.Capitalize)))
+	}
+	w.WriteString("}\n\n")
+	w.WriteString("export function createLoaders(queries: Queries): Loaders;\n")
+}
@@ -0,0 +1,77 @@
+package jsgen
+
+import (
+	"fmt"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+)
+
+// moduleFormat is a module system the generated code is written for
+type moduleFormat struct {
+	ext          string // extension of the generated modules
+	declarations string // declaration file of the index module
+	esm          bool
+}
+
+var (
+	cjsFormat = moduleFormat{ext: ".js", declarations: "index.d.ts"}
+	esmFormat = moduleFormat{ext: ".mjs", declarations: "index.d.mts", esm: true}
+)
+
+// moduleFormats returns the formats gen.js.module asks for
+func (g *Generator) moduleFormats() []moduleFormat {
+	switch g.Config.Gen.JS.ModuleFormat() {
+	case config.ModuleESM:
+		return []moduleFormat{esmFormat}
+	case config.ModuleDual:
+		return []moduleFormat{cjsFormat, esmFormat}
+	default:
+		return []moduleFormat{cjsFormat}
+	}
+}
+
+// importQueries imports the Queries class of a generated module, under
+// alias when it isn't empty
+func (f moduleFormat) importQueries(module, alias string) string {
+	switch {
+	case f.esm && alias != "":
+		return fmt.Sprintf("import { Queries as %s } from './%s%s';\n", alias, module, f.ext)
+	case f.esm:
+		return fmt.Sprintf("import { Queries } from './%s%s';\n", module, f.ext)
+	case alias != "":
+		return fmt.Sprintf("const { Queries: %s } = require('./%s');\n", alias, module)
+	default:
+		return fmt.Sprintf("const { Queries } = require('./%s');\n", module)
+	}
+}
+
+// exports exports names from a generated module
+func (f moduleFormat) exports(names string) string {
+	if f.esm {
+		return "export { " + names + " };\n"
+	}
+	return "module.exports = { " + names + " };\n"
+}
+
+// driver returns the client library queries are generated for
+func (g *Generator) driver() string {
+	return g.Config.Gen.JS.DriverFor(g.Config.Database.Provider)
+}
+
+// driverConnection describes the connection New takes with the driver
+func (g *Generator) driverConnection() string {
+	switch g.driver() {
+	case config.DriverPostgresJS:
+		return "postgres.js sql instance"
+	case config.DriverMySQL2:
+		return "mysql2/promise Pool or Connection"
+	case config.DriverBetterSQLite3:
+		return "better-sqlite3 Database"
+	case config.DriverBunSQLite:
+		return "bun:sqlite Database"
+	case config.DriverLibSQL:
+		return "@libsql/client Client"
+	default:
+		return "pg.Pool or pg.Client"
+	}
+}
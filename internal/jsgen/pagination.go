@@ -0,0 +1,168 @@
+package jsgen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/gencommon"
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+	"github.com/Lumos-Labs-HQ/flash/internal/utils"
+)
+
+// writeCursorHelpers emits the cursor encoding helpers for files that contain
+// paginated queries. Cursors are base64url-encoded JSON arrays.
+func (g *Generator) writeCursorHelpers(w *strings.Builder, queries []*parser.Query) {
+	needed := false
+	for _, query := range queries {
+		if query.Paginate != nil {
+			needed = true
+			break
+		}
+	}
+	if !needed {
+		return
+	}
+
+	encode := "Buffer.from(JSON.stringify(values)).toString('base64url')"
+	decode := "JSON.parse(Buffer.from(cursor, 'base64url').toString())"
+	switch g.Config.Gen.JS.Runtime {
+	case config.RuntimeEdge:
+		// Edge runtimes have no Buffer, only the web APIs
+		encode = "btoa(String.fromCharCode(...new TextEncoder().encode(JSON.stringify(values))))" +
+			".replace(/\\+/g, '-').replace(/\\//g, '_').replace(/=+$/, '')"
+		decode = "JSON.parse(new TextDecoder().decode(" +
+			"Uint8Array.from(atob(cursor.replace(/-/g, '+').replace(/_/g, '/')), c => c.charCodeAt(0))))"
+	case config.RuntimeDeno:
+		// Buffer is only a global in Node and Bun
+		w.WriteString("import { Buffer } from 'node:buffer';\n\n")
+	}
+
+	w.WriteString("function encodeCursor(values) {\n")
+	w.WriteString("  return " + encode + ";\n")
+	w.WriteString("}\n\n")
+	w.WriteString("function decodeCursor(cursor, size) {\n")
+	w.WriteString("  let values;\n")
+	w.WriteString("  try {\n")
+	w.WriteString("    values = " + decode + ";\n")
+	w.WriteString("  } catch (err) {\n")
+	w.WriteString("    values = null;\n")
+	w.WriteString("  }\n")
+	w.WriteString("  if (!Array.isArray(values) || values.length !== size) {\n")
+	w.WriteString("    throw new Error('invalid pagination cursor');\n")
+	w.WriteString("  }\n")
+	w.WriteString("  return values;\n")
+	w.WriteString("}\n\n")
+}
+
+// generatePageMethod writes `<name>Page(...params, cursor, limit)` returning
+// `{ rows, nextCursor }`. It fetches limit+1 rows to detect a following page.
+func (g *Generator) generatePageMethod(w *strings.Builder, query *parser.Query) error {
+	columns := g.pageColumns(query)
+	indexes, err := gencommon.CursorColumnIndexes(query, columns)
+	if err != nil {
+		return err
+	}
+
+	methodName := g.out.FunctionName(config.NamingCamel, query.Name, "page")
+	firstSQL, nextSQL := gencommon.CursorQueries(query, g.Config.Database.Provider)
+	escape := func(sql string) string {
+		sql = g.convertSQL(sql)
+		sql = strings.ReplaceAll(sql, "`", "\\`")
+		return strings.ReplaceAll(sql, "${", "\\${")
+	}
+
+	paramNames := make([]string, len(query.Params))
+	for i, param := range query.Params {
+		paramName := param.Name
+		if paramName == "" {
+			paramName = fmt.Sprintf("p%d", i+1)
+		}
+		paramNames[i] = paramName
+	}
+	signature := append(append([]string{}, paramNames...), "cursor", "limit")
+
+	w.WriteString(fmt.Sprintf("  async %s(%s) {\n", methodName, strings.Join(signature, ", ")))
+	w.WriteString("    if (!(limit >= 1)) {\n")
+	w.WriteString("      throw new RangeError('page limit must be at least 1');\n")
+	w.WriteString("    }\n")
+	w.WriteString(fmt.Sprintf("    let sql = `%s`;\n", escape(firstSQL)))
+	w.WriteString(fmt.Sprintf("    const args = [%s];\n", strings.Join(paramNames, ", ")))
+	w.WriteString("    if (cursor) {\n")
+	w.WriteString(fmt.Sprintf("      sql = `%s`;\n", escape(nextSQL)))
+	w.WriteString(fmt.Sprintf("      args.push(...decodeCursor(cursor, %d));\n", len(indexes)))
+	w.WriteString("    }\n")
+
+	switch g.driver() {
+	case config.DriverBetterSQLite3, config.DriverBunSQLite:
+		w.WriteString("    args.push(limit + 1);\n")
+		w.WriteString("    const rows = this.db.prepare(sql).all(...args);\n")
+	case config.DriverLibSQL:
+		w.WriteString("    args.push(limit + 1);\n")
+		w.WriteString("    const { rows } = await this.db.execute({ sql, args });\n")
+	case config.DriverMySQL2:
+		// mysql2 rejects numeric LIMIT values in prepared statements
+		w.WriteString("    args.push(String(limit + 1));\n")
+		w.WriteString("    const [rows] = await this.db.execute(sql, args);\n")
+	case config.DriverPlanetScale:
+		w.WriteString("    args.push(limit + 1);\n")
+		w.WriteString("    const { rows } = await this.db.execute(sql, args);\n")
+	case config.DriverPostgresJS:
+		w.WriteString("    args.push(limit + 1);\n")
+		w.WriteString("    const rows = await this.db.unsafe(sql, args, { prepare: true });\n")
+	case config.DriverNeon:
+		w.WriteString("    args.push(limit + 1);\n")
+		w.WriteString("    const { rows } = await this.db.query(sql, args, { fullResults: true });\n")
+	default:
+		w.WriteString("    args.push(limit + 1);\n")
+		w.WriteString("    const { rows } = await this.db.query(sql, args);\n")
+	}
+
+	lastValues := make([]string, len(indexes))
+	for i, idx := range indexes {
+		lastValues[i] = "last." + columns[idx].Name
+	}
+	w.WriteString("    let nextCursor = null;\n")
+	w.WriteString("    if (rows.length > limit) {\n")
+	w.WriteString("      rows.length = limit;\n")
+	w.WriteString("      const last = rows[limit - 1];\n")
+	w.WriteString(fmt.Sprintf("      nextCursor = encodeCursor([%s]);\n", strings.Join(lastValues, ", ")))
+	w.WriteString("    }\n")
+	if encrypted := g.encryptedColumns(query); len(encrypted) > 0 {
+		w.WriteString(fmt.Sprintf("    await decryptColumns(rows, %s);\n", columnList(encrypted)))
+	}
+	if len(query.Columns) == 1 && query.Columns[0].Name != "*" {
+		w.WriteString(fmt.Sprintf("    return { rows: rows.map(row => row.%s), nextCursor };\n", query.Columns[0].Name))
+	} else {
+		if len(query.Includes) > 0 {
+			w.WriteString(fmt.Sprintf("    await %s(this.db, rows);\n", g.includeLoaderName(query)))
+		}
+		w.WriteString("    return { rows, nextCursor };\n")
+	}
+	w.WriteString("  }\n\n")
+
+	return nil
+}
+
+// pageColumns resolves `SELECT *` to the table's columns so cursor columns can
+// be matched against it.
+func (g *Generator) pageColumns(query *parser.Query) []*parser.QueryColumn {
+	if len(query.Columns) != 1 || query.Columns[0].Name != "*" || g.schema == nil {
+		return query.Columns
+	}
+
+	tableName := query.Columns[0].Table
+	if tableName == "" {
+		tableName = utils.ExtractTableName(query.SQL)
+	}
+	for _, table := range g.schema.Tables {
+		if strings.EqualFold(table.Name, tableName) {
+			columns := make([]*parser.QueryColumn, 0, len(table.Columns))
+			for _, col := range table.Columns {
+				columns = append(columns, &parser.QueryColumn{Name: col.Name, Type: col.Type, Table: table.Name, Nullable: col.Nullable})
+			}
+			return columns
+		}
+	}
+	return query.Columns
+}
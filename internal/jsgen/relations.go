@@ -0,0 +1,130 @@
+package jsgen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/gencommon"
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+	"github.com/Lumos-Labs-HQ/flash/internal/utils"
+)
+
+// writeIncludeLoaders emits includeX for each query of a file with an
+// include annotation. A loader runs one query per relation for all the rows
+// and attaches the related rows to them.
+func (g *Generator) writeIncludeLoaders(w *strings.Builder, queries []*parser.Query) {
+	if !gencommon.HasIncludes(queries) {
+		return
+	}
+
+	w.WriteString("function inPlaceholders(n) {\n")
+	switch g.Config.Database.Provider {
+	case "mysql", "sqlite", "sqlite3":
+		w.WriteString("  return Array.from({ length: n }, () => '?').join(', ');\n")
+	default:
+		w.WriteString("  return Array.from({ length: n }, (_, i) => '$' + (i + 1)).join(', ');\n")
+	}
+	w.WriteString("}\n\n")
+
+	for _, query := range queries {
+		if len(query.Includes) == 0 {
+			continue
+		}
+
+		w.WriteString(fmt.Sprintf("async function %s(db, rows) {\n", g.includeLoaderName(query)))
+		for i, rel := range query.Includes {
+			if i > 0 {
+				w.WriteString("\n")
+			}
+			prefix := utils.Uncapitalize(utils.ToPascalCase(rel.Name))
+			sql := strings.ReplaceAll(gencommon.RelationQuery(rel, g.Config.SoftDelete.Column), "`", "\\`")
+
+			w.WriteString(fmt.Sprintf("  const %sKeys = [...new Set(rows.map(row => row.%s).filter(key => key != null))];\n", prefix, rel.Column))
+			w.WriteString(fmt.Sprintf("  let %sRows = [];\n", prefix))
+			w.WriteString(fmt.Sprintf("  if (%sKeys.length > 0) {\n", prefix))
+			w.WriteString(fmt.Sprintf("    %sRows = %s;\n", prefix,
+				g.fetchRows("db", fmt.Sprintf("`%s` + inPlaceholders(%sKeys.length) + ')'", sql, prefix), prefix+"Keys")))
+			if columns := tableEncryptedColumns(rel.Table); len(columns) > 0 {
+				w.WriteString(fmt.Sprintf("    await decryptColumns(%sRows, %s);\n", prefix, columnList(columns)))
+			}
+			w.WriteString("  }\n")
+
+			// Keys are compared as strings, as drivers may return the two
+			// columns of a relation as different types
+			if rel.Many {
+				w.WriteString(fmt.Sprintf("  const %sByKey = new Map();\n", prefix))
+				w.WriteString(fmt.Sprintf("  for (const related of %sRows) {\n", prefix))
+				w.WriteString(fmt.Sprintf("    const key = String(related.%s);\n", rel.RelatedColumn))
+				w.WriteString(fmt.Sprintf("    if (!%sByKey.has(key)) {\n", prefix))
+				w.WriteString(fmt.Sprintf("      %sByKey.set(key, []);\n", prefix))
+				w.WriteString("    }\n")
+				w.WriteString(fmt.Sprintf("    %sByKey.get(key).push(related);\n", prefix))
+				w.WriteString("  }\n")
+				w.WriteString("  for (const row of rows) {\n")
+				w.WriteString(fmt.Sprintf("    row.%s = %sByKey.get(String(row.%s)) || [];\n", rel.Name, prefix, rel.Column))
+				w.WriteString("  }\n")
+			} else {
+				w.WriteString(fmt.Sprintf("  const %sByKey = new Map(%sRows.map(related => [String(related.%s), related]));\n", prefix, prefix, rel.RelatedColumn))
+				w.WriteString("  for (const row of rows) {\n")
+				w.WriteString(fmt.Sprintf("    row.%s = %sByKey.get(String(row.%s)) || null;\n", rel.Name, prefix, rel.Column))
+				w.WriteString("  }\n")
+			}
+		}
+		w.WriteString("}\n\n")
+	}
+}
+
+func (g *Generator) includeLoaderName(query *parser.Query) string {
+	return "include" + utils.Capitalize(g.out.FunctionName(config.NamingCamel, query.Name, ""))
+}
+
+// fetchRows returns an expression for the rows a query returns with the
+// driver, run on db
+func (g *Generator) fetchRows(db, sql, args string) string {
+	switch g.driver() {
+	case config.DriverBetterSQLite3, config.DriverBunSQLite:
+		return fmt.Sprintf("%s.prepare(%s).all(...%s)", db, sql, args)
+	case config.DriverLibSQL:
+		return fmt.Sprintf("(await %s.execute({ sql: %s, args: %s })).rows", db, sql, args)
+	case config.DriverMySQL2:
+		return fmt.Sprintf("(await %s.execute(%s, %s))[0]", db, sql, args)
+	case config.DriverPlanetScale:
+		return fmt.Sprintf("(await %s.execute(%s, %s)).rows", db, sql, args)
+	case config.DriverPostgresJS:
+		return fmt.Sprintf("await %s.unsafe(%s, %s, { prepare: true })", db, sql, args)
+	case config.DriverNeon:
+		return fmt.Sprintf("(await %s.query(%s, %s, { fullResults: true })).rows", db, sql, args)
+	default:
+		return fmt.Sprintf("(await %s.query(%s, %s)).rows", db, sql, args)
+	}
+}
+
+// generateIncludeExecution runs a query with an include annotation and loads
+// the relations of the rows it returns
+func (g *Generator) generateIncludeExecution(w *strings.Builder, query *parser.Query, sql string, paramNames []string) {
+	w.WriteString(fmt.Sprintf("    const rows = %s;\n", g.fetchRows("this.db", "`"+sql+"`", "["+strings.Join(paramNames, ", ")+"]")))
+	if columns := g.encryptedColumns(query); len(columns) > 0 {
+		w.WriteString(fmt.Sprintf("    await decryptColumns(rows, %s);\n", columnList(columns)))
+	}
+	if query.Cmd == ":one" {
+		w.WriteString("    const row = rows[0];\n")
+		w.WriteString("    if (!row) {\n")
+		w.WriteString("      return null;\n")
+		w.WriteString("    }\n")
+		w.WriteString(fmt.Sprintf("    await %s(this.db, [row]);\n", g.includeLoaderName(query)))
+		w.WriteString("    return row;\n")
+		return
+	}
+	w.WriteString(fmt.Sprintf("    await %s(this.db, rows);\n", g.includeLoaderName(query)))
+	w.WriteString("    return rows;\n")
+}
+
+// relationTSType returns the declared type of a relation's field
+func (g *Generator) relationTSType(rel *parser.Relation) string {
+	modelName := g.out.ModelName(rel.Table.Name, utils.Capitalize)
+	if rel.Many {
+		return modelName + "[]"
+	}
+	return modelName + " | null"
+}
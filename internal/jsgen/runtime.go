@@ -0,0 +1,130 @@
+package jsgen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+)
+
+// hasConnect reports whether the runtime gets a generated connect(). Node
+// projects set up their driver themselves and pass it to New.
+func (g *Generator) hasConnect() bool {
+	runtime := g.Config.Gen.JS.Runtime
+	return runtime != "" && runtime != config.RuntimeNode
+}
+
+// driverImport returns the bindings the driver is imported as in ES modules
+// and in CommonJS, and the module it is imported from
+func (g *Generator) driverImport() (esm, cjs, module string) {
+	switch g.driver() {
+	case config.DriverPostgresJS:
+		esm, cjs, module = "postgres", "postgres", "postgres"
+	case config.DriverNeon:
+		esm, cjs, module = "{ neon }", "{ neon }", "@neondatabase/serverless"
+	case config.DriverMySQL2:
+		esm, cjs, module = "mysql", "mysql", "mysql2/promise"
+	case config.DriverPlanetScale:
+		// Renamed so it doesn't clash with the generated connect()
+		esm, cjs, module = "{ connect as planetscale }", "{ connect: planetscale }", "@planetscale/database"
+	case config.DriverBetterSQLite3:
+		esm, cjs, module = "Database", "Database", "better-sqlite3"
+	case config.DriverLibSQL:
+		esm, cjs, module = "{ createClient }", "{ createClient }", "@libsql/client"
+		if g.Config.Gen.JS.Runtime == config.RuntimeEdge {
+			module = "@libsql/client/web"
+		}
+	case config.DriverBunSQLite:
+		return "{ Database }", "{ Database }", "bun:sqlite"
+	default:
+		esm, cjs, module = "pg", "pg", "pg"
+	}
+	// Deno loads npm packages through npm: specifiers
+	if g.Config.Gen.JS.Runtime == config.RuntimeDeno {
+		module = "npm:" + module
+	}
+	return esm, cjs, module
+}
+
+// importDriver imports the driver at the top of the index module
+func (g *Generator) importDriver(w *strings.Builder, format moduleFormat) {
+	if !g.hasConnect() {
+		return
+	}
+
+	esm, cjs, module := g.driverImport()
+	if format.esm {
+		w.WriteString(fmt.Sprintf("import %s from '%s';\n", esm, module))
+	} else {
+		w.WriteString(fmt.Sprintf("const %s = require('%s');\n", cjs, module))
+	}
+}
+
+// urlEnv returns the environment variable holding the database URL
+func (g *Generator) urlEnv() string {
+	if g.Config.Database.URLEnv == "" {
+		return "DATABASE_URL"
+	}
+	return g.Config.Database.URLEnv
+}
+
+// envLookup reads the database URL environment variable the runtime's way,
+// or returns "" when the runtime has no global environment
+func (g *Generator) envLookup() string {
+	name := g.urlEnv()
+	switch g.Config.Gen.JS.Runtime {
+	case config.RuntimeDeno:
+		return fmt.Sprintf("Deno.env.get('%s')", name)
+	case config.RuntimeBun:
+		return "Bun.env." + name
+	default:
+		// Edge runtimes hand the environment to the request handler
+		return ""
+	}
+}
+
+// writeConnect emits connect(), which opens a connection with the driver and
+// returns the queries for it
+func (g *Generator) writeConnect(w *strings.Builder) {
+	if !g.hasConnect() {
+		return
+	}
+
+	var open string
+	switch g.driver() {
+	case config.DriverPostgresJS:
+		open = "postgres(url)"
+	case config.DriverNeon:
+		open = "neon(url)"
+	case config.DriverMySQL2:
+		open = "mysql.createPool(url)"
+	case config.DriverPlanetScale:
+		open = "planetscale({ url })"
+	case config.DriverBetterSQLite3, config.DriverBunSQLite:
+		open = "new Database(url.replace(/^sqlite:\\/\\//, ''))"
+	case config.DriverLibSQL:
+		// flash's sqlite:// URLs are local files to libsql
+		open = "createClient({ url: url.replace(/^sqlite:\\/\\//, 'file:') })"
+	default:
+		open = "new pg.Pool({ connectionString: url })"
+	}
+
+	w.WriteString("/**\n")
+	w.WriteString(fmt.Sprintf(" * Connect to the database with %s\n", g.driver()))
+	if env := g.envLookup(); env != "" {
+		w.WriteString(fmt.Sprintf(" * @param {string} [url] - Database URL, %s by default\n", env))
+		w.WriteString(" * @returns {Queries}\n")
+		w.WriteString(" */\n")
+		w.WriteString(fmt.Sprintf("function connect(url = %s) {\n", env))
+	} else {
+		w.WriteString(fmt.Sprintf(" * @param {string} url - Database URL, such as env.%s in a Worker\n", g.urlEnv()))
+		w.WriteString(" * @returns {Queries}\n")
+		w.WriteString(" */\n")
+		w.WriteString("function connect(url) {\n")
+	}
+	w.WriteString("  if (!url) {\n")
+	w.WriteString("    throw new Error('no database URL given');\n")
+	w.WriteString("  }\n")
+	w.WriteString(fmt.Sprintf("  return new Queries(%s);\n", open))
+	w.WriteString("}\n\n")
+}
@@ -0,0 +1,108 @@
+package matview
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+// Adapter is implemented by database adapters that support materialized
+// views (currently PostgreSQL)
+type Adapter interface {
+	GetMaterializedViewInfo(ctx context.Context) ([]types.MaterializedViewInfo, error)
+	RefreshMaterializedView(ctx context.Context, name string, concurrently bool) error
+}
+
+// View is a materialized view with its latest recorded refresh
+type View struct {
+	types.MaterializedViewInfo
+	LastRefresh *Refresh `json:"last_refresh,omitempty"`
+}
+
+// Service lists and refreshes the materialized views of a database,
+// recording refreshes when it has a migrations path
+type Service struct {
+	adapter  Adapter
+	metadata *MetadataManager
+}
+
+// NewService returns a service for a database adapter, or an error when the
+// database has no materialized views. An empty migrationsPath disables
+// refresh history.
+func NewService(adapter any, migrationsPath string) (*Service, error) {
+	a, ok := adapter.(Adapter)
+	if !ok {
+		return nil, fmt.Errorf("materialized views are only supported on PostgreSQL")
+	}
+	s := &Service{adapter: a}
+	if migrationsPath != "" {
+		s.metadata = NewMetadataManager(migrationsPath)
+	}
+	return s, nil
+}
+
+// List returns the materialized views with their latest refreshes
+func (s *Service) List(ctx context.Context) ([]View, error) {
+	infos, err := s.adapter.GetMaterializedViewInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list materialized views: %w", err)
+	}
+
+	store := &RefreshStore{}
+	if s.metadata != nil {
+		if store, err = s.metadata.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	views := make([]View, 0, len(infos))
+	for _, info := range infos {
+		views = append(views, View{MaterializedViewInfo: info, LastRefresh: store.GetRefresh(info.Name)})
+	}
+	return views, nil
+}
+
+// Refresh refreshes a materialized view and records the refresh. A failed
+// refresh is recorded too and returned as the error.
+func (s *Service) Refresh(ctx context.Context, name string, concurrently bool, source string) (*Refresh, error) {
+	views, err := s.adapter.GetMaterializedViewInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list materialized views: %w", err)
+	}
+	var view *types.MaterializedViewInfo
+	for i := range views {
+		if views[i].Name == name {
+			view = &views[i]
+			break
+		}
+	}
+	if view == nil {
+		return nil, fmt.Errorf("materialized view '%s' not found", name)
+	}
+	if concurrently && !view.Populated {
+		return nil, fmt.Errorf("materialized view '%s' has no data yet, refresh it without --concurrently first", name)
+	}
+	if concurrently && !view.HasUniqueIndex {
+		return nil, fmt.Errorf("materialized view '%s' needs a unique index to refresh concurrently", name)
+	}
+
+	refresh := &Refresh{View: name, StartedAt: time.Now(), Concurrently: concurrently, Source: source}
+	err = s.adapter.RefreshMaterializedView(ctx, name, concurrently)
+	refresh.DurationMS = time.Since(refresh.StartedAt).Milliseconds()
+	refresh.Success = err == nil
+	if err != nil {
+		refresh.Error = err.Error()
+	}
+
+	if s.metadata != nil {
+		if recordErr := s.metadata.Record(refresh); recordErr != nil && err == nil {
+			return refresh, recordErr
+		}
+	}
+	if err != nil {
+		return refresh, fmt.Errorf("failed to refresh materialized view '%s': %w", name, err)
+	}
+	return refresh, nil
+}
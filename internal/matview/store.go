@@ -0,0 +1,96 @@
+package matview
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Refresh records the latest refresh of a materialized view
+type Refresh struct {
+	View         string    `json:"view"`
+	StartedAt    time.Time `json:"started_at"`
+	DurationMS   int64     `json:"duration_ms"`
+	Concurrently bool      `json:"concurrently"`
+	Success      bool      `json:"success"`
+	Error        string    `json:"error,omitempty"`
+	// Source is what started the refresh: cli or studio
+	Source string `json:"source,omitempty"`
+}
+
+type RefreshStore struct {
+	Refreshes []*Refresh `json:"refreshes"`
+}
+
+// MetadataManager keeps the latest refresh of every materialized view in the
+// .flash folder of the migrations path
+type MetadataManager struct {
+	refreshesPath string
+	flashDir      string
+}
+
+func NewMetadataManager(migrationsPath string) *MetadataManager {
+	flashDir := filepath.Join(migrationsPath, ".flash")
+
+	return &MetadataManager{
+		refreshesPath: filepath.Join(flashDir, "matviews.json"),
+		flashDir:      flashDir,
+	}
+}
+
+func (m *MetadataManager) Load() (*RefreshStore, error) {
+	store := &RefreshStore{}
+	if _, err := os.Stat(m.refreshesPath); os.IsNotExist(err) {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(m.refreshesPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read materialized views file: %w", err)
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("failed to parse materialized views file: %w", err)
+	}
+	return store, nil
+}
+
+func (m *MetadataManager) Save(store *RefreshStore) error {
+	if err := os.MkdirAll(m.flashDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directories: %w", err)
+	}
+
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal materialized views: %w", err)
+	}
+	return os.WriteFile(m.refreshesPath, data, 0644)
+}
+
+// Record replaces the latest refresh of a view
+func (m *MetadataManager) Record(refresh *Refresh) error {
+	store, err := m.Load()
+	if err != nil {
+		return err
+	}
+
+	kept := []*Refresh{refresh}
+	for _, r := range store.Refreshes {
+		if r.View != refresh.View {
+			kept = append(kept, r)
+		}
+	}
+	store.Refreshes = kept
+
+	return m.Save(store)
+}
+
+func (s *RefreshStore) GetRefresh(view string) *Refresh {
+	for _, r := range s.Refreshes {
+		if r.View == view {
+			return r
+		}
+	}
+	return nil
+}
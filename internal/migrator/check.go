@@ -0,0 +1,265 @@
+package migrator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+// CheckReport is the outcome of replaying the migrations on a shadow
+// database. Annotations use the fields of GitHub check run annotations.
+type CheckReport struct {
+	OK          bool              `json:"ok"`
+	Provider    string            `json:"provider"`
+	Migrations  []string          `json:"migrations"` // applied to the shadow database
+	Failed      string            `json:"failed,omitempty"`
+	Drift       []string          `json:"drift"`
+	Destructive int               `json:"destructive"`
+	Annotations []CheckAnnotation `json:"annotations"`
+}
+
+type CheckAnnotation struct {
+	Path            string `json:"path"`
+	StartLine       int    `json:"start_line"`
+	EndLine         int    `json:"end_line"`
+	AnnotationLevel string `json:"annotation_level"` // notice, warning or failure
+	Title           string `json:"title"`
+	Message         string `json:"message"`
+}
+
+// CheckOptions configure CheckMigrations
+type CheckOptions struct {
+	// ServerURL is the server the shadow database is created on, unused
+	// for SQLite
+	ServerURL string
+	// Base is a git ref; only migrations added or changed since it are
+	// scanned for destructive statements. Empty scans every migration.
+	Base string
+	// FailOnDestructive makes destructive statements fail the check
+	FailOnDestructive bool
+}
+
+// destructivePatterns are the statements that lose data or break code
+// written against the previous schema
+var destructivePatterns = []struct {
+	re      *regexp.Regexp
+	message string
+}{
+	{regexp.MustCompile(`(?i)\bDROP\s+TABLE\b`), "drops a table and its data"},
+	{regexp.MustCompile(`(?i)\bDROP\s+COLUMN\b`), "drops a column and its data"},
+	{regexp.MustCompile(`(?i)\bDROP\s+(SCHEMA|DATABASE)\b`), "drops a schema and everything in it"},
+	{regexp.MustCompile(`(?i)\bDROP\s+TYPE\b`), "drops a type"},
+	{regexp.MustCompile(`(?i)\bTRUNCATE\b`), "deletes every row of a table"},
+	{regexp.MustCompile(`(?i)\bDELETE\s+FROM\b`), "deletes rows"},
+	{regexp.MustCompile(`(?i)\bALTER\s+COLUMN\s+\S+\s+(SET\s+DATA\s+)?TYPE\b`), "changes a column type, which can fail or lose precision on existing data"},
+	{regexp.MustCompile(`(?i)\bMODIFY\s+(COLUMN\s+)?\S+\s+\w+`), "changes a column definition, which can fail or lose data"},
+	{regexp.MustCompile(`(?i)\bRENAME\s+(TO|COLUMN)\b`), "renames, which breaks code still using the old name"},
+}
+
+// CheckMigrations applies every migration to a new shadow database, verifies
+// the result matches the schema files and reports destructive statements.
+// The shadow database is dropped afterwards.
+func CheckMigrations(ctx context.Context, cfg *config.Config, opts CheckOptions) (*CheckReport, error) {
+	report := &CheckReport{
+		Provider:    cfg.Database.Provider,
+		Migrations:  []string{},
+		Drift:       []string{},
+		Annotations: []CheckAnnotation{},
+	}
+
+	shadow, err := NewShadowDatabase(ctx, cfg.Database.Provider, opts.ServerURL)
+	if err != nil {
+		return nil, err
+	}
+	defer shadow.Drop(ctx)
+
+	m, err := NewMigratorForURL(cfg, shadow.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to shadow database: %w", err)
+	}
+	defer m.Close()
+
+	migrations, err := m.loadMigrationsFromDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	applied, applyErr := m.ApplyPending(ctx)
+	for _, migration := range migrations[:applied] {
+		report.Migrations = append(report.Migrations, migration.ID)
+	}
+	if applyErr != nil {
+		failed := migrations[applied]
+		report.Failed = failed.ID
+		report.Annotations = append(report.Annotations, CheckAnnotation{
+			Path:            failed.FilePath,
+			StartLine:       1,
+			EndLine:         1,
+			AnnotationLevel: "failure",
+			Title:           "Migration failed",
+			Message:         fmt.Sprintf("%s fails on an empty database: %v", failed.ID, applyErr),
+		})
+	} else {
+		diff, err := m.schemaManager.GenerateSchemaDiff(ctx, m.schemaPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compare shadow database with schema: %w", err)
+		}
+		for _, step := range m.planMigration(diff) {
+			change := step.describe()
+			report.Drift = append(report.Drift, change)
+
+			path, line := findSchemaDeclaration(m.schemaPath, step.table)
+			report.Annotations = append(report.Annotations, CheckAnnotation{
+				Path:            path,
+				StartLine:       line,
+				EndLine:         line,
+				AnnotationLevel: "failure",
+				Title:           "Schema drift",
+				Message:         fmt.Sprintf("The migrations don't produce the schema files: a migration still needs to %s", change),
+			})
+		}
+	}
+
+	files, err := changedMigrationFiles(migrations, opts.Base)
+	if err != nil {
+		return nil, err
+	}
+	level := "warning"
+	if opts.FailOnDestructive {
+		level = "failure"
+	}
+	for _, file := range files {
+		annotations, err := scanDestructive(file, level)
+		if err != nil {
+			return nil, err
+		}
+		report.Destructive += len(annotations)
+		report.Annotations = append(report.Annotations, annotations...)
+	}
+
+	report.OK = report.Failed == "" && len(report.Drift) == 0 && (!opts.FailOnDestructive || report.Destructive == 0)
+	return report, nil
+}
+
+// describe names the change of a step, e.g. "add column users.email"
+func (step migrationStep) describe() string {
+	if step.table != "" && step.table != step.name {
+		return fmt.Sprintf("%s %s.%s", step.kind, step.table, step.name)
+	}
+	return fmt.Sprintf("%s %s", step.kind, step.name)
+}
+
+// changedMigrationFiles returns the migration files added or modified since
+// the base git ref, or all of them without one
+func changedMigrationFiles(migrations []types.Migration, base string) ([]string, error) {
+	var files []string
+	if base == "" {
+		for _, migration := range migrations {
+			files = append(files, migration.FilePath)
+		}
+		return files, nil
+	}
+
+	out, err := exec.Command("git", "diff", "--name-only", "--diff-filter=AM", base+"...HEAD").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files changed since %s: %w", base, err)
+	}
+	top, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find git repository root: %w", err)
+	}
+	root := strings.TrimSpace(string(top))
+
+	changed := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line != "" {
+			changed[filepath.Join(root, line)] = true
+		}
+	}
+	for _, migration := range migrations {
+		if abs, err := filepath.Abs(migration.FilePath); err == nil && changed[abs] {
+			files = append(files, migration.FilePath)
+		}
+	}
+	return files, nil
+}
+
+// scanDestructive reports the destructive statements of the up section of
+// a migration file
+func scanDestructive(path, level string) ([]CheckAnnotation, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration file: %w", err)
+	}
+	defer file.Close()
+
+	var annotations []CheckAnnotation
+	scanner := bufio.NewScanner(file)
+	inUp, lineNumber := true, 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		lower := strings.ToLower(line)
+		switch {
+		case strings.Contains(lower, "+migrate up"):
+			inUp = true
+			continue
+		case strings.Contains(lower, "+migrate down"):
+			inUp = false
+			continue
+		}
+		if !inUp || strings.HasPrefix(line, "--") {
+			continue
+		}
+
+		for _, pattern := range destructivePatterns {
+			if pattern.re.MatchString(line) {
+				annotations = append(annotations, CheckAnnotation{
+					Path:            path,
+					StartLine:       lineNumber,
+					EndLine:         lineNumber,
+					AnnotationLevel: level,
+					Title:           "Destructive migration",
+					Message:         fmt.Sprintf("%s %s", line, pattern.message),
+				})
+				break
+			}
+		}
+	}
+	return annotations, scanner.Err()
+}
+
+// findSchemaDeclaration returns the schema file and line declaring a table,
+// falling back to the first schema file
+func findSchemaDeclaration(schemaPath, table string) (string, int) {
+	files := []string{schemaPath}
+	if info, err := os.Stat(schemaPath); err == nil && info.IsDir() {
+		files, _ = filepath.Glob(filepath.Join(schemaPath, "*.sql"))
+	}
+	if len(files) == 0 {
+		return schemaPath, 1
+	}
+	if table == "" {
+		return files[0], 1
+	}
+
+	declaration := regexp.MustCompile(`(?i)CREATE\s+TABLE\s+(IF\s+NOT\s+EXISTS\s+)?["` + "`" + `]?` + regexp.QuoteMeta(table) + `["` + "`" + `]?[\s(]`)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		if loc := declaration.FindIndex(content); loc != nil {
+			return file, strings.Count(string(content[:loc[0]]), "\n") + 1
+		}
+	}
+	return files[0], 1
+}
@@ -0,0 +1,44 @@
+package migrator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/schema"
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+// addConstraintSQL returns the statement that adds a table constraint. An
+// unnamed one is given the name PostgreSQL would pick, so the down
+// migration can drop it. SQLite can't add constraints to a table, so only
+// a note is written for it.
+func (m *Migrator) addConstraintSQL(table string, constraint types.SchemaConstraint) string {
+	if m.provider == "sqlite" || m.provider == "sqlite3" {
+		return fmt.Sprintf("-- SQLite can't add %s (%s) to %s in place; recreate the table to add it",
+			constraint.Type, strings.Join(constraint.Columns, ", "), table)
+	}
+
+	constraint.Name = schema.ConstraintName(table, constraint)
+	if m.provider == "mysql" {
+		constraint.Deferrable = ""
+	}
+	return fmt.Sprintf("ALTER TABLE %s ADD %s;", m.quoteIdentifier(table), constraint.Definition(m.quoteIdentifier))
+}
+
+// dropConstraintSQL returns the statement that drops a table constraint.
+// MySQL drops foreign keys and unique constraints by their own statements.
+func (m *Migrator) dropConstraintSQL(table string, constraint types.SchemaConstraint) string {
+	if m.provider == "sqlite" || m.provider == "sqlite3" {
+		return fmt.Sprintf("-- SQLite can't drop %s (%s) from %s in place; recreate the table to drop it",
+			constraint.Type, strings.Join(constraint.Columns, ", "), table)
+	}
+
+	name := m.quoteIdentifier(schema.ConstraintName(table, constraint))
+	if m.provider == "mysql" {
+		if constraint.Type == "FOREIGN KEY" {
+			return fmt.Sprintf("ALTER TABLE %s DROP FOREIGN KEY %s;", m.quoteIdentifier(table), name)
+		}
+		return fmt.Sprintf("ALTER TABLE %s DROP INDEX %s;", m.quoteIdentifier(table), name)
+	}
+	return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", m.quoteIdentifier(table), name)
+}
@@ -0,0 +1,56 @@
+package migrator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/schema"
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+var onUpdateRegex = regexp.MustCompile(`(?i)\s+ON\s+UPDATE\s+.*$`)
+
+// defaultChange returns the default a modified column gets, with the
+// timestamp default new columns get too, and whether it differs from the
+// one it has
+func (m *Migrator) defaultChange(column types.ColumnDiff) (string, bool) {
+	target := types.SchemaColumn{Name: column.Name, Default: column.NewDefault}
+	m.applyTimestampDefaults(&target)
+	return target.Default, !schema.DefaultsEqual(column.OldDefault, target.Default)
+}
+
+// columnDefaultSQL returns the statement that sets the default of a column,
+// or drops it when value is empty. SQLite can't change a default without
+// recreating the table, so only a note is written for it.
+func (m *Migrator) columnDefaultSQL(table, column, value string) string {
+	if m.provider == "sqlite" || m.provider == "sqlite3" {
+		if value == "" {
+			value = "none"
+		}
+		return fmt.Sprintf("-- SQLite can't change the default of %s.%s (to %s) in place; recreate the table to change it", table, column, value)
+	}
+
+	alter := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", m.quoteIdentifier(table), m.quoteIdentifier(column))
+	if value == "" {
+		return alter + " DROP DEFAULT;"
+	}
+	if m.provider == "mysql" {
+		value = mysqlDefault(onUpdateRegex.ReplaceAllString(value, ""))
+	}
+	return fmt.Sprintf("%s SET DEFAULT %s;", alter, value)
+}
+
+// mysqlDefault writes a default the way ALTER COLUMN ... SET DEFAULT takes
+// it: expressions in parens, and strings MySQL reports unquoted in quotes
+func mysqlDefault(value string) string {
+	trimmed := strings.TrimSpace(value)
+	upper := strings.ToUpper(trimmed)
+	switch {
+	case schema.IsLiteralDefault(trimmed) || upper == "NULL" || strings.HasPrefix(trimmed, "("):
+		return trimmed
+	case strings.Contains(trimmed, "(") || strings.HasPrefix(upper, "CURRENT_"):
+		return "(" + trimmed + ")"
+	}
+	return "'" + strings.ReplaceAll(trimmed, "'", "''") + "'"
+}
@@ -0,0 +1,111 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+)
+
+// DevOptions configure DevMigration
+type DevOptions struct {
+	// ShadowURL is the server the shadow database is created on, unused
+	// for SQLite
+	ShadowURL string
+	// Reset resets a drifted database without asking
+	Reset bool
+}
+
+// DevMigration creates a migration from a shadow database instead of the
+// development database. The existing migrations are replayed on the shadow
+// database and the new migration is the difference between it and the
+// schema files, so changes made to the development database by hand never
+// end up in it. When the development database no longer matches the
+// migrations (drift) it has to be reset first. The new migration is then
+// applied to the development database.
+func (m *Migrator) DevMigration(ctx context.Context, cfg *config.Config, name string, opts DevOptions) error {
+	shadow, err := NewShadowDatabase(ctx, m.provider, opts.ShadowURL)
+	if err != nil {
+		return err
+	}
+	defer shadow.Drop(ctx)
+
+	sm, err := NewMigratorForURL(cfg, shadow.URL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to shadow database: %w", err)
+	}
+	defer sm.Close()
+
+	fmt.Println("🌑 Replaying migrations on a shadow database...")
+	replayed, err := sm.ApplyPending(ctx)
+	if err != nil {
+		return fmt.Errorf("the migrations no longer apply to an empty database, fix them before creating a new one: %w", err)
+	}
+	fmt.Printf("   %d migration(s) replayed\n", replayed)
+
+	pending, err := m.PendingMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		fmt.Printf("📦 Applying %d pending migration(s) to the database...\n", len(pending))
+		if _, err := m.ApplyPending(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := m.resolveDrift(ctx, sm, opts.Reset); err != nil {
+		return err
+	}
+
+	diff, err := sm.schemaManager.GenerateSchemaDiff(ctx, m.schemaPath)
+	if err != nil {
+		return fmt.Errorf("failed to generate schema diff: %w", err)
+	}
+	if len(sm.planMigration(diff)) == 0 {
+		fmt.Println("✅ The migrations already match the schema, no migration needed")
+		return nil
+	}
+	sm.confirmGuessedRenames(diff)
+
+	filename := m.fileUtils.GenerateMigrationFilename(name)
+	path := filepath.Join(m.migrationsDir, filename)
+	if err := os.WriteFile(path, []byte(sm.generateSQLFromDiff(diff, name)), 0644); err != nil {
+		return fmt.Errorf("failed to write migration file: %w", err)
+	}
+	fmt.Printf("Generated migration: %s\n", filename)
+
+	if _, err := m.ApplyPending(ctx); err != nil {
+		return fmt.Errorf("failed to apply %s: %w", filename, err)
+	}
+	fmt.Printf("✅ Applied %s to the database\n", filename)
+	return nil
+}
+
+// resolveDrift compares the database with the shadow database the
+// migrations were replayed on. A difference means the database was changed
+// outside of the migrations, or an applied migration was edited; diffing
+// against it would produce a wrong migration, so it is reset instead.
+func (m *Migrator) resolveDrift(ctx context.Context, shadow *Migrator, reset bool) error {
+	diff, err := m.schemaManager.GenerateDatabaseDiff(ctx, shadow.adapter)
+	if err != nil {
+		return fmt.Errorf("failed to compare database with migrations: %w", err)
+	}
+	steps := m.planMigration(diff)
+	if len(steps) == 0 {
+		return nil
+	}
+
+	fmt.Println("⚠️  Drift detected: the database doesn't match the migrations. To match them it would need to:")
+	for _, step := range steps {
+		fmt.Printf("  - %s\n", step.describe())
+	}
+	fmt.Println("   The database was changed outside of migrations, or an applied migration was edited.")
+
+	if !reset && !m.askUserConfirmation("Reset the database and reapply all migrations? All data will be lost") {
+		return fmt.Errorf("database has drifted from the migrations, reset it or undo the changes before creating a migration")
+	}
+	return m.handleResetAndApply(ctx)
+}
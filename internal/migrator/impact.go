@@ -0,0 +1,476 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+	"github.com/Lumos-Labs-HQ/flash/internal/utils"
+)
+
+// lockLevel is how much of the traffic to a table a statement holds up
+type lockLevel int
+
+const (
+	lockNone   lockLevel = iota // reads and writes go on
+	lockBrief                   // a short exclusive lock, whatever the size of the table
+	lockWrites                  // writes wait until the statement is done
+	lockAll                     // reads and writes wait until the statement is done
+)
+
+func (l lockLevel) String() string {
+	switch l {
+	case lockBrief:
+		return "brief lock"
+	case lockWrites:
+		return "blocks writes"
+	case lockAll:
+		return "blocks reads and writes"
+	}
+	return "no blocking lock"
+}
+
+// Rows per second the work of a statement roughly gets through. Real speeds
+// depend on the hardware, the width of the rows and the load, so estimates
+// made with these only tell seconds from minutes from hours.
+const (
+	scanRowsPerSecond    = 1000000 // validating a constraint
+	indexRowsPerSecond   = 200000  // building an index
+	rewriteRowsPerSecond = 100000  // copying or rewriting the table
+	updateRowsPerSecond  = 50000   // updating or deleting rows
+)
+
+// impactWindowThreshold is how long writes may be blocked before the
+// estimate suggests a maintenance window
+const impactWindowThreshold = 10 * time.Second
+
+type impactPattern struct {
+	re     *regexp.Regexp
+	lock   lockLevel
+	rate   int64
+	reason string
+}
+
+var (
+	impactCreateIndexRegex  = regexp.MustCompile(`(?is)^CREATE\s+(UNIQUE\s+)?INDEX\s+(CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(?:` + preflightIdent + `\s+)?ON\s+(?:ONLY\s+)?` + preflightIdent)
+	impactDropTableRegex    = regexp.MustCompile(`(?is)^DROP\s+TABLE\s+(?:IF\s+EXISTS\s+)?` + preflightIdent)
+	impactUpdateRegex       = regexp.MustCompile(`(?is)^UPDATE\s+(?:ONLY\s+)?` + preflightIdent + `\s+SET\b`)
+	impactDeleteRegex       = regexp.MustCompile(`(?is)^DELETE\s+FROM\s+(?:ONLY\s+)?` + preflightIdent)
+	impactInsertSelectRegex = regexp.MustCompile(`(?is)^INSERT\s+INTO\s+` + preflightIdent + `.*?\bSELECT\b.*?\bFROM\s+` + preflightIdent)
+	impactMySQLIndexRegex   = regexp.MustCompile(`(?i)^ADD\s+(UNIQUE\s+|FULLTEXT\s+|SPATIAL\s+)?(INDEX|KEY)\b|^ADD\s+(CONSTRAINT\s+\S+\s+)?UNIQUE\b`)
+	impactMySQLBriefRegex   = regexp.MustCompile(`(?i)^DROP\s+(INDEX|KEY|FOREIGN\s+KEY|CONSTRAINT|CHECK)\b`)
+	impactSQLiteBriefRegex  = regexp.MustCompile(`(?i)^(ADD|RENAME)\b`)
+
+	// What PostgreSQL's ALTER TABLE clauses lock, heaviest first
+	postgresImpactPatterns = []impactPattern{
+		{regexp.MustCompile(`(?is)\bALTER\s+(?:COLUMN\s+)?\S+\s+(?:SET\s+DATA\s+)?TYPE\b`), lockAll, rewriteRowsPerSecond,
+			"changing a column's type usually rewrites the table"},
+		{regexp.MustCompile(`(?is)\bADD\b[^,]*\bDEFAULT\s+(?:gen_random_uuid|uuid_generate_v\w+|random|clock_timestamp|timeofday|nextval)\s*\(`), lockAll, rewriteRowsPerSecond,
+			"a volatile default rewrites the table to fill the new column"},
+		{regexp.MustCompile(`(?is)\bADD\s+(?:COLUMN\s+)?(?:IF\s+NOT\s+EXISTS\s+)?\S+\s+(?:BIG|SMALL)?SERIAL\b`), lockAll, rewriteRowsPerSecond,
+			"a serial column rewrites the table to fill it"},
+		{regexp.MustCompile(`(?is)\bADD\b[^,]*\b(?:PRIMARY\s+KEY|UNIQUE)\b`), lockAll, indexRowsPerSecond,
+			"the index behind the constraint is built under an ACCESS EXCLUSIVE lock"},
+		{regexp.MustCompile(`(?is)\bSET\s+NOT\s+NULL\b`), lockAll, scanRowsPerSecond,
+			"SET NOT NULL scans the table under an ACCESS EXCLUSIVE lock"},
+		{regexp.MustCompile(`(?is)\bNOT\s+VALID\b`), lockBrief, 0, ""},
+		{regexp.MustCompile(`(?is)\bADD\b[^,]*\bCHECK\b`), lockAll, scanRowsPerSecond,
+			"validating the CHECK scans the table; adding it NOT VALID and then running VALIDATE CONSTRAINT avoids the lock"},
+		{regexp.MustCompile(`(?is)\bADD\b[^,]*\b(?:FOREIGN\s+KEY|REFERENCES)\b`), lockWrites, scanRowsPerSecond,
+			"validating the foreign key scans the table and blocks writes to both tables; adding it NOT VALID and then running VALIDATE CONSTRAINT avoids it"},
+		{regexp.MustCompile(`(?is)\bVALIDATE\s+CONSTRAINT\b`), lockNone, scanRowsPerSecond,
+			"scans the table while reads and writes go on"},
+	}
+)
+
+// statementImpact is what a statement of a migration does to the table it
+// changes
+type statementImpact struct {
+	statement string
+	table     string // empty when the statement changes no table's rows
+	lock      lockLevel
+	rate      int64 // rows per second, 0 when the size of the table doesn't matter
+	reason    string
+	rebuild   bool  // copies the table into the one a rebuild replaces it with
+	exists    bool  // whether the table is in the database yet
+	rows      int64 // estimated rows of the table, -1 when unknown
+}
+
+// duration estimates how long the statement takes on the table
+func (s statementImpact) duration() time.Duration {
+	if s.rate == 0 || s.rows <= 0 {
+		return 0
+	}
+	return time.Duration(float64(s.rows) / float64(s.rate) * float64(time.Second))
+}
+
+// heavy reports whether the statement holds up writes to rows that exist
+func (s statementImpact) heavy() bool {
+	return s.exists && s.lock >= lockWrites && s.rows != 0
+}
+
+// migrationImpact is the estimated impact of a pending migration
+type migrationImpact struct {
+	migration  types.Migration
+	statements []statementImpact
+}
+
+// classifyStatement returns the table a statement changes and how it locks
+// it on the database of the migrator
+func (m *Migrator) classifyStatement(statement string) statementImpact {
+	statement = strings.TrimSuffix(strings.TrimSpace(statement), ";")
+	impact := statementImpact{statement: statement}
+
+	switch {
+	case impactInsertSelectRegex.MatchString(statement):
+		matches := impactInsertSelectRegex.FindStringSubmatch(statement)
+		impact.table, impact.lock, impact.rate = matches[2], lockNone, rewriteRowsPerSecond
+		if strings.HasPrefix(matches[1], rebuildPrefix) {
+			impact.rebuild, impact.reason = true, "rebuilds the table, copying its rows"
+		} else {
+			impact.reason = "copies rows out of the table"
+		}
+	case impactUpdateRegex.MatchString(statement):
+		impact.table, impact.lock, impact.rate = impactUpdateRegex.FindStringSubmatch(statement)[1], lockWrites, updateRowsPerSecond
+		impact.reason = "locks the rows it changes until the migration commits"
+	case impactDeleteRegex.MatchString(statement):
+		impact.table, impact.lock, impact.rate = impactDeleteRegex.FindStringSubmatch(statement)[1], lockWrites, updateRowsPerSecond
+		impact.reason = "locks the rows it deletes until the migration commits"
+	case impactDropTableRegex.MatchString(statement):
+		impact.table, impact.lock = impactDropTableRegex.FindStringSubmatch(statement)[1], lockBrief
+		impact.reason = "drops the table and its rows"
+	case m.provider == "mysql":
+		m.classifyMySQLStatement(&impact)
+	case m.provider == "sqlite" || m.provider == "sqlite3":
+		classifySQLiteStatement(&impact)
+	default:
+		classifyPostgresStatement(&impact)
+	}
+
+	// SQLite lets one writer in at a time, for the whole migration
+	if (m.provider == "sqlite" || m.provider == "sqlite3") && impact.rate > 0 {
+		impact.lock = lockWrites
+	}
+	return impact
+}
+
+func classifyPostgresStatement(impact *statementImpact) {
+	if matches := impactCreateIndexRegex.FindStringSubmatch(impact.statement); matches != nil {
+		impact.table, impact.rate = matches[4], indexRowsPerSecond
+		if matches[2] != "" {
+			impact.lock, impact.reason = lockNone, "builds the index while writes go on"
+		} else {
+			impact.lock, impact.reason = lockWrites, "CREATE INDEX blocks writes until the index is built; CREATE INDEX CONCURRENTLY doesn't"
+		}
+		return
+	}
+
+	matches := preflightAlterTableRegex.FindStringSubmatch(impact.statement)
+	if matches == nil || strings.HasPrefix(strings.ToUpper(strings.TrimSpace(matches[2])), "RENAME TO") {
+		return
+	}
+	impact.table, impact.lock = matches[1], lockBrief
+	for _, pattern := range postgresImpactPatterns {
+		if pattern.re.MatchString(matches[2]) {
+			impact.lock, impact.rate, impact.reason = pattern.lock, pattern.rate, pattern.reason
+			return
+		}
+	}
+}
+
+func (m *Migrator) classifyMySQLStatement(impact *statementImpact) {
+	table, clauses := onlineAlter(impact.statement)
+	if table == "" {
+		return
+	}
+	impact.table = table
+
+	switch algorithm, reason := classifyAlter(clauses); algorithm {
+	case ddlInstant:
+		impact.lock = lockBrief
+	case ddlCopy:
+		impact.lock, impact.rate, impact.reason = lockWrites, rewriteRowsPerSecond, reason
+	default:
+		switch {
+		case impactMySQLBriefRegex.MatchString(clauses):
+			impact.lock = lockBrief
+		case impactMySQLIndexRegex.MatchString(clauses):
+			impact.lock, impact.rate, impact.reason = lockNone, indexRowsPerSecond, "builds the index in place while writes go on"
+		default:
+			impact.lock, impact.rate, impact.reason = lockNone, rewriteRowsPerSecond, "rebuilds the table in place while writes go on"
+		}
+	}
+}
+
+func classifySQLiteStatement(impact *statementImpact) {
+	if matches := impactCreateIndexRegex.FindStringSubmatch(impact.statement); matches != nil {
+		impact.table, impact.rate, impact.reason = matches[4], indexRowsPerSecond, "builds the index"
+		return
+	}
+
+	matches := preflightAlterTableRegex.FindStringSubmatch(impact.statement)
+	if matches == nil || strings.HasPrefix(strings.ToUpper(strings.TrimSpace(matches[2])), "RENAME TO") {
+		return
+	}
+	impact.table, impact.lock = matches[1], lockBrief
+	if !impactSQLiteBriefRegex.MatchString(strings.TrimSpace(matches[2])) {
+		impact.rate, impact.reason = rewriteRowsPerSecond, "rewrites the rows of the table"
+	}
+}
+
+// estimateImpact classifies the statements of the given migrations and
+// estimates the rows of the tables they change
+func (m *Migrator) estimateImpact(ctx context.Context, migrations []types.Migration) ([]migrationImpact, error) {
+	var impacts []migrationImpact
+	var tables []string
+	seen := make(map[string]bool)
+
+	for _, migration := range migrations {
+		content, err := os.ReadFile(migration.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration file: %w", err)
+		}
+
+		impact := migrationImpact{migration: migration}
+		rebuilt := make(map[string]bool)
+		for _, statement := range common.ParseSQLStatements(extractUpSQL(string(content))) {
+			if strings.TrimSpace(statement) == "" {
+				continue
+			}
+			s := m.classifyStatement(statement)
+			if s.rebuild {
+				rebuilt[s.table] = true
+			} else if rebuilt[s.table] && s.lock == lockBrief {
+				// The old table a rebuild copied out of
+				s.table, s.reason = "", ""
+			}
+			if s.table != "" && !seen[s.table] {
+				seen[s.table] = true
+				tables = append(tables, s.table)
+			}
+			impact.statements = append(impact.statements, s)
+		}
+		impacts = append(impacts, impact)
+	}
+
+	rows, err := m.tableRowEstimates(ctx, tables)
+	if err != nil {
+		return nil, err
+	}
+	for i := range impacts {
+		for j := range impacts[i].statements {
+			s := &impacts[i].statements[j]
+			s.rows, s.exists = rows[s.table]
+		}
+	}
+	return impacts, nil
+}
+
+// tableRowEstimates returns the estimated row counts of the given tables
+// that exist, -1 for a PostgreSQL table that was never analyzed. SQLite
+// keeps no estimates, so its tables are counted.
+func (m *Migrator) tableRowEstimates(ctx context.Context, tables []string) (map[string]int64, error) {
+	estimates := make(map[string]int64)
+	if len(tables) == 0 {
+		return estimates, nil
+	}
+
+	var result *common.QueryResult
+	var err error
+	switch m.provider {
+	case "sqlite", "sqlite3":
+		for _, table := range tables {
+			if exists, err := m.adapter.CheckTableExists(ctx, table); err != nil || !exists {
+				continue
+			}
+			count, err := m.adapter.ExecuteQuery(ctx, fmt.Sprintf("SELECT COUNT(*) AS n FROM %s", m.quoteIdentifier(table)))
+			if err != nil {
+				return nil, fmt.Errorf("failed to count the rows of %s: %w", table, err)
+			}
+			estimates[table] = int64(preflightCount(count))
+		}
+		return estimates, nil
+	case "mysql":
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(tables)), ", ")
+		args := make([]any, len(tables))
+		for i, table := range tables {
+			args[i] = table
+		}
+		result, err = m.adapter.ExecuteQuery(ctx, `
+			SELECT TABLE_NAME AS table_name, TABLE_ROWS AS table_rows
+			FROM information_schema.TABLES
+			WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE' AND TABLE_NAME IN (`+placeholders+`)`, args...)
+	default:
+		result, err = m.adapter.ExecuteQuery(ctx, `
+			SELECT c.relname AS table_name, c.reltuples::bigint AS table_rows
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE c.relkind IN ('r', 'p') AND n.nspname = ANY(current_schemas(false)) AND c.relname = ANY($1)`, tables)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate table sizes: %w", err)
+	}
+
+	for _, row := range result.Rows {
+		rows, err := strconv.ParseInt(fmt.Sprint(row["table_rows"]), 10, 64)
+		if err != nil {
+			rows = -1
+		}
+		estimates[fmt.Sprint(row["table_name"])] = rows
+	}
+	return estimates, nil
+}
+
+// PrintImpact prints the pending migrations statement by statement with
+// the estimated impact of each on the table it changes, without applying
+// them
+func (m *Migrator) PrintImpact(ctx context.Context) error {
+	if err := m.createMigrationsTable(ctx); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	migrations, err := m.loadMigrationsFromDir()
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	applied, err := m.getAppliedMigrations(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	pending := utils.FilterPendingMigrations(migrations, applied)
+	if len(pending) == 0 {
+		fmt.Println("No pending migrations")
+		return nil
+	}
+
+	impacts, err := m.estimateImpact(ctx, pending)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Found %d pending migrations (dry run, nothing is applied)\n", len(pending))
+	m.printImpact(impacts, true)
+	return nil
+}
+
+// printImpact prints the estimated impact of migrations. Unless all is set,
+// only the statements on tables that exist are listed.
+func (m *Migrator) printImpact(impacts []migrationImpact, all bool) {
+	fmt.Println("📊 Estimated impact:")
+
+	var blocked time.Duration
+	var blockedTables []string
+	seen := make(map[string]bool)
+	for _, impact := range impacts {
+		var lines []string
+		for _, s := range impact.statements {
+			if !s.exists {
+				if all {
+					lines = append(lines, "    ·  "+shortStatement(s.statement))
+				}
+				continue
+			}
+
+			icon := "✓ "
+			if s.heavy() {
+				icon = "🔒"
+				blocked += s.duration()
+				if !seen[s.table] {
+					seen[s.table] = true
+					blockedTables = append(blockedTables, s.table)
+				}
+			}
+			detail := fmt.Sprintf("%s: %s, %s", s.table, formatRowEstimate(s.rows), s.lock)
+			if d := s.duration(); d > 0 {
+				detail += ", ~" + approxDuration(d)
+			}
+			lines = append(lines, fmt.Sprintf("    %s %s", icon, shortStatement(s.statement)), "       "+detail)
+			if s.reason != "" {
+				lines = append(lines, "       "+s.reason)
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		fmt.Printf("  %s\n", impact.migration.ID)
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+	}
+
+	if len(blockedTables) == 0 {
+		fmt.Println("  No existing table is locked for longer than a moment")
+		return
+	}
+	fmt.Printf("  Traffic to %s is held up for about %s in total\n", strings.Join(blockedTables, ", "), approxDuration(blocked))
+	if blocked >= impactWindowThreshold {
+		hint := "💡 Consider applying during a maintenance window"
+		if m.provider == "mysql" {
+			hint += ", or set database.online_ddl.tool to run the changes with gh-ost or pt-osc"
+		}
+		fmt.Println(hint)
+	}
+}
+
+// needsImpactReport reports whether any statement holds up the traffic to
+// a table that has rows
+func needsImpactReport(impacts []migrationImpact) bool {
+	for _, impact := range impacts {
+		for _, s := range impact.statements {
+			if s.heavy() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// shortStatement puts a statement on one line, cut short when it's long
+func shortStatement(statement string) string {
+	statement = strings.Join(strings.Fields(statement), " ")
+	if runes := []rune(statement); len(runes) > 100 {
+		return string(runes[:97]) + "..."
+	}
+	return statement
+}
+
+func formatRowEstimate(rows int64) string {
+	if rows < 0 {
+		return "row count unknown (never analyzed)"
+	}
+	digits := strconv.FormatInt(rows, 10)
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	return "~" + b.String() + " rows"
+}
+
+// approxDuration rounds an estimate to what it can tell
+func approxDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return "<1s"
+	case d < time.Minute:
+		return d.Round(time.Second).String()
+	case d < time.Hour:
+		d = d.Round(time.Minute)
+	default:
+		d = d.Round(10 * time.Minute)
+	}
+	text := strings.TrimSuffix(d.String(), "0s")
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/Lumos-Labs-HQ/flash/internal/config"
 	"github.com/Lumos-Labs-HQ/flash/internal/database"
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
 	"github.com/Lumos-Labs-HQ/flash/internal/schema"
 	"github.com/Lumos-Labs-HQ/flash/internal/types"
 	"github.com/Lumos-Labs-HQ/flash/internal/utils"
@@ -21,6 +22,9 @@ type Migrator struct {
 	migrationsDir string
 	schemaPath    string
 	provider      string // Database provider: sqlite, postgresql, mysql
+	timestamps    config.Timestamps
+	onlineDDL     config.OnlineDDL
+	dbURL         string
 	force         bool
 	fileUtils     *utils.FileUtils
 	inputUtils    *utils.InputUtils
@@ -28,23 +32,39 @@ type Migrator struct {
 }
 
 func NewMigrator(cfg *config.Config) (*Migrator, error) {
-	adapter := database.NewAdapter(cfg.Database.Provider)
-
 	dbURL, err := cfg.GetDatabaseURL()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get database URL: %w", err)
 	}
 
+	return NewMigratorForURL(cfg, dbURL)
+}
+
+// NewMigratorForURL creates a migrator connected to dbURL instead of the
+// URL in the configured environment variable.
+func NewMigratorForURL(cfg *config.Config, dbURL string) (*Migrator, error) {
+	adapter := database.NewAdapter(cfg.Database.Provider)
+	if err := database.Require(adapter, cfg.Database.Provider, common.CapabilityMigrations); err != nil {
+		return nil, err
+	}
+
 	if err := adapter.Connect(context.Background(), dbURL); err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	schemaManager := schema.NewSchemaManager(adapter)
+	schemaManager.SetIgnoreTable(cfg.Database.IsIgnoredTable)
+	schemaManager.SetIgnoreColumn(cfg.Database.IsIgnoredColumn)
+
 	return &Migrator{
 		adapter:       adapter,
-		schemaManager: schema.NewSchemaManager(adapter),
+		schemaManager: schemaManager,
 		migrationsDir: cfg.MigrationsPath,
 		schemaPath:    cfg.GetSchemaDir(), // Use schema directory instead of single file
 		provider:      cfg.Database.Provider,
+		timestamps:    cfg.Timestamps,
+		onlineDDL:     cfg.Database.OnlineDDL,
+		dbURL:         dbURL,
 		force:         false,
 		fileUtils:     &utils.FileUtils{},
 		inputUtils:    &utils.InputUtils{},
@@ -56,6 +76,11 @@ func (m *Migrator) Close() error {
 	return m.adapter.Close()
 }
 
+// Adapter returns the connection the migrator works on
+func (m *Migrator) Adapter() database.DatabaseAdapter {
+	return m.adapter
+}
+
 func (m *Migrator) SetForce(force bool) {
 	m.force = force
 }
@@ -101,15 +126,21 @@ func (m *Migrator) GenerateMigration(ctx context.Context, name string, schemaPat
 	if err != nil {
 		return fmt.Errorf("failed to generate schema diff: %w", err)
 	}
+	m.confirmGuessedRenames(diff)
 
 	filename := m.fileUtils.GenerateMigrationFilename(name)
 	filepath := filepath.Join(m.migrationsDir, filename)
 
 	var sqlContent string
 	// CRITICAL FIX: Also check for index changes!
-	if len(diff.NewTables) == 0 && len(diff.DroppedTables) == 0 && len(diff.ModifiedTables) == 0 && 
-	   len(diff.NewEnums) == 0 && len(diff.DroppedEnums) == 0 &&
-	   len(diff.NewIndexes) == 0 && len(diff.DroppedIndexes) == 0 {
+	if len(diff.NewTables) == 0 && len(diff.DroppedTables) == 0 && len(diff.ModifiedTables) == 0 &&
+	   len(diff.RenamedTables) == 0 &&
+	   len(diff.NewEnums) == 0 && len(diff.DroppedEnums) == 0 && len(diff.ModifiedEnums) == 0 &&
+	   len(diff.NewIndexes) == 0 && len(diff.DroppedIndexes) == 0 && len(diff.RenamedIndexes) == 0 &&
+	   len(diff.NewPolicies) == 0 && len(diff.DroppedPolicies) == 0 &&
+	   len(diff.RLSEnabledTables) == 0 && len(diff.RLSDisabledTables) == 0 &&
+	   len(diff.NewMaterializedViews) == 0 && len(diff.DroppedMaterializedViews) == 0 &&
+	   len(diff.NewPartitions) == 0 && len(diff.DroppedPartitions) == 0 {
 		fmt.Println("No changes detected in schema, creating empty migration template")
 		sqlContent = m.generateEmptyMigrationTemplate(name)
 	} else {
@@ -124,10 +155,83 @@ func (m *Migrator) GenerateMigration(ctx context.Context, name string, schemaPat
 	return nil
 }
 
+// migrationStep is a single change of a generated migration together with
+// the statements that apply and revert it
+type migrationStep struct {
+	table   string // table the change belongs to, empty for enums
+	name    string // column, table or index the change is about
+	kind    string
+	guessed bool // rename detected from the columns rather than declared
+	up      []string
+	down    []string
+	// warnings about how the UP statements run, e.g. a MySQL ALTER that
+	// copies a large table
+	warnings []string
+}
+
+// SchemaChange is a change a migration would make to bring the database to
+// the schema files
+type SchemaChange struct {
+	Kind    string   `json:"kind"` // e.g. "add column"
+	Table   string   `json:"table,omitempty"`
+	Name    string   `json:"name"`
+	Guessed bool     `json:"guessed,omitempty"` // rename detected from the columns
+	Up      []string `json:"up"`
+	Down    []string `json:"down"`
+}
+
+// SchemaChanges compares the database with the schema files without
+// writing anything. Guessed renames are returned unconfirmed.
+func (m *Migrator) SchemaChanges(ctx context.Context) ([]SchemaChange, error) {
+	diff, err := m.schemaManager.GenerateSchemaDiff(ctx, m.schemaPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate schema diff: %w", err)
+	}
+
+	var changes []SchemaChange
+	for _, step := range m.planMigration(diff) {
+		changes = append(changes, SchemaChange{
+			Kind:    step.kind,
+			Table:   step.table,
+			Name:    step.name,
+			Guessed: step.guessed,
+			Up:      step.up,
+			Down:    step.down,
+		})
+	}
+	return changes, nil
+}
+
 // generateSQLFromDiff creates SQL from schema differences with both UP and DOWN
 func (m *Migrator) generateSQLFromDiff(diff *types.SchemaDiff, name string) string {
-	var upStatements []string
-	var downStatements []string
+	steps := m.planMigration(diff)
+	for _, step := range steps {
+		for _, warning := range step.warnings {
+			fmt.Printf("⚠️  %s\n", warning)
+		}
+	}
+	upStatements, downStatements := collectStatements(steps)
+	return m.formatMigrationFileWithDown(name, upStatements, downStatements)
+}
+
+// collectStatements joins the statements of the steps; the DOWN statements
+// run in reverse order
+func collectStatements(steps []migrationStep) (upStatements []string, downStatements []string) {
+	for _, step := range steps {
+		upStatements = append(upStatements, step.up...)
+		downStatements = append(append([]string(nil), step.down...), downStatements...)
+	}
+	return upStatements, downStatements
+}
+
+// planMigration breaks the schema differences into ordered migration steps
+func (m *Migrator) planMigration(diff *types.SchemaDiff) []migrationStep {
+	var steps []migrationStep
+	add := func(step migrationStep) {
+		if len(step.up) > 0 || len(step.down) > 0 {
+			steps = append(steps, step)
+		}
+	}
 
 	dropTableSQL := func(tableName string) string {
 		switch m.provider {
@@ -138,6 +242,13 @@ func (m *Migrator) generateSQLFromDiff(diff *types.SchemaDiff, name string) stri
 		}
 	}
 
+	// UP: Drop materialized views first, before the tables they read from
+	// change (DOWN creates them again last)
+	for _, view := range diff.DroppedMaterializedViews {
+		add(migrationStep{name: view.Name, kind: "drop materialized view",
+			up: []string{schema.GenerateDropMaterializedViewSQL(view.Name)}, down: m.createMaterializedViewSQL(view)})
+	}
+
 	for _, enum := range diff.NewEnums {
 		values := make([]string, len(enum.Values))
 		for i, v := range enum.Values {
@@ -154,96 +265,250 @@ BEGIN
         CREATE TYPE "%s" AS ENUM (%s);
     END IF;
 END $$;`, escapedNameSingle, escapedNameDouble, strings.Join(values, ", "))
-		upStatements = append(upStatements, enumSQL)
 		// DOWN: Drop enum (escape double quotes for identifier)
-		downStatements = append([]string{fmt.Sprintf("DROP TYPE IF EXISTS \"%s\";", escapedNameDouble)}, downStatements...)
+		add(migrationStep{name: enum.Name, kind: "create enum", up: []string{enumSQL},
+			down: []string{fmt.Sprintf("DROP TYPE IF EXISTS \"%s\";", escapedNameDouble)}})
+	}
+
+	// UP: Values only added to an enum are added before the tables and
+	// columns that may use them; DOWN creates the enum again without them
+	for _, enumDiff := range diff.ModifiedEnums {
+		if enumDiff.Recreate() {
+			continue
+		}
+		var up []string
+		for _, add := range enumDiff.Added {
+			up = append(up, schema.GenerateAddEnumValueSQL(enumDiff.Name, add))
+		}
+		add(migrationStep{name: enumDiff.Name, kind: "add enum values", up: up,
+			down: schema.GenerateChangeEnumValuesSQL(enumDiff.Name, enumDiff.Values, enumDiff.OldValues, enumDiff.OldColumns)})
+	}
+
+	// UP: Renames first, so later statements use the new names
+	for _, rename := range diff.RenamedTables {
+		add(migrationStep{table: rename.To.Name, name: rename.From, kind: "rename table", guessed: rename.Guessed,
+			up:   []string{m.renameTableSQL(rename.From, rename.To.Name)},
+			down: []string{m.renameTableSQL(rename.To.Name, rename.From)}})
+	}
+	for _, tableDiff := range diff.ModifiedTables {
+		for _, rename := range tableDiff.RenamedColumns {
+			add(migrationStep{table: tableDiff.Name, name: rename.From.Name, kind: "rename column", guessed: rename.Guessed,
+				up:   []string{m.renameColumnSQL(tableDiff.Name, rename.From.Name, rename.To.Name)},
+				down: []string{m.renameColumnSQL(tableDiff.Name, rename.To.Name, rename.From.Name)}})
+		}
 	}
 
 	// UP: Create new tables and their indexes
+	createdIndexes := make(map[string]bool)
 	for _, table := range diff.NewTables {
-		sql := m.adapter.GenerateCreateTableSQL(table)
-		if sql != "" {
-			upStatements = append(upStatements, sql)
+		table.Columns = append([]types.SchemaColumn(nil), table.Columns...)
+		for i := range table.Columns {
+			m.applyTimestampDefaults(&table.Columns[i])
+		}
+
+		step := migrationStep{table: table.Name, name: table.Name, kind: "create table", down: []string{dropTableSQL(table.Name)}}
+		if sql := m.adapter.GenerateCreateTableSQL(table); sql != "" {
+			step.up = append(step.up, sql)
+		}
+		add(step)
+
+		if m.hasUpdatedAtColumn(table.Columns) {
+			triggerUp, _ := m.generateUpdatedAtTrigger(table.Name)
+			add(migrationStep{table: table.Name, name: table.Name, kind: "updated_at trigger", up: triggerUp})
 		}
 
 		for _, index := range table.Indexes {
 			if strings.HasPrefix(index.Name, "sqlite_") {
 				continue
 			}
-			indexSQL := m.adapter.GenerateAddIndexSQL(index)
-			if indexSQL != "" {
-				upStatements = append(upStatements, indexSQL)
+			step := migrationStep{table: table.Name, name: index.Name, kind: "create index",
+				down: []string{fmt.Sprintf("DROP INDEX IF EXISTS \"%s\";", index.Name)}}
+			if indexSQL := m.adapter.GenerateAddIndexSQL(index); indexSQL != "" {
+				step.up = append(step.up, indexSQL)
 			}
+			add(step)
+			createdIndexes[index.Name] = true
 		}
+	}
 
-		downStatements = append([]string{dropTableSQL(table.Name)}, downStatements...)
-		for _, index := range table.Indexes {
-			if strings.HasPrefix(index.Name, "sqlite_") {
-				continue
-			}
-			downStatements = append([]string{fmt.Sprintf("DROP INDEX IF EXISTS \"%s\";", index.Name)}, downStatements...)
+	// UP: Create partitions once their partitioned tables exist
+	for _, partition := range diff.NewPartitions {
+		add(migrationStep{table: partition.Parent, name: partition.Name, kind: "create partition",
+			up: []string{schema.GenerateCreatePartitionSQL(partition)}, down: []string{schema.GenerateDropPartitionSQL(partition)}})
+	}
+
+	// CRITICAL FIX: Add standalone index changes!
+	// UP: Drop and rename indexes before the tables change (new ones are
+	// added at the end), so DOWN creates dropped indexes once their columns
+	// are back
+	for _, index := range diff.DroppedIndexes {
+		// DOWN: Create the index again when its columns are known
+		down := fmt.Sprintf("-- Cannot restore dropped index: %s", index.Name)
+		if len(index.Columns) > 0 {
+			down = m.adapter.GenerateAddIndexSQL(index)
 		}
+		add(migrationStep{table: index.Table, name: index.Name, kind: "drop index", up: []string{m.adapter.GenerateDropIndexSQL(index)},
+			down: []string{down}})
+	}
+
+	// UP: Rename indexes whose definition didn't change
+	for _, rename := range diff.RenamedIndexes {
+		add(migrationStep{table: rename.To.Table, name: rename.To.Name, kind: "rename index",
+			up: m.renameIndexSQL(rename.From, rename.To), down: m.renameIndexSQL(rename.To, rename.From)})
 	}
 
 	// UP: Modify existing tables
 	for _, tableDiff := range diff.ModifiedTables {
+		// SQLite rebuilds the table for changes it can't make in place
+		if m.needsRebuild(tableDiff) {
+			up, down := m.rebuildTable(tableDiff, diff)
+			add(migrationStep{table: tableDiff.Name, name: tableDiff.Name, kind: "rebuild table", up: up, down: down})
+			if m.hasUpdatedAtColumn(tableDiff.NewColumns) {
+				triggerUp, triggerDown := m.generateUpdatedAtTrigger(tableDiff.Name)
+				add(migrationStep{table: tableDiff.Name, name: tableDiff.Name, kind: "updated_at trigger", up: triggerUp, down: triggerDown})
+			}
+			continue
+		}
+
+		// Drop constraints first, they may be over columns dropped below
+		for _, constraint := range tableDiff.DroppedConstraints {
+			// DOWN: Add the constraint back
+			add(migrationStep{table: tableDiff.Name, name: schema.ConstraintName(tableDiff.Name, constraint), kind: "drop constraint",
+				up:   []string{m.dropConstraintSQL(tableDiff.Name, constraint)},
+				down: []string{m.addConstraintSQL(tableDiff.Name, constraint)}})
+		}
+
 		// Add new columns
 		for _, column := range tableDiff.NewColumns {
+			m.applyTimestampDefaults(&column)
 			sql := m.adapter.GenerateAddColumnSQL(tableDiff.Name, column)
 			if sql != "" {
-				upStatements = append(upStatements, sql)
 				// DOWN: Drop the added column
-				downStatements = append([]string{m.adapter.GenerateDropColumnSQL(tableDiff.Name, column.Name)}, downStatements...)
+				add(migrationStep{table: tableDiff.Name, name: column.Name, kind: "add column", up: []string{sql},
+					down: []string{m.adapter.GenerateDropColumnSQL(tableDiff.Name, column.Name)}})
 			}
 		}
 
+		if m.hasUpdatedAtColumn(tableDiff.NewColumns) {
+			triggerUp, triggerDown := m.generateUpdatedAtTrigger(tableDiff.Name)
+			add(migrationStep{table: tableDiff.Name, name: tableDiff.Name, kind: "updated_at trigger", up: triggerUp, down: triggerDown})
+		}
+
+		// Add constraints once their columns exist
+		for _, constraint := range tableDiff.NewConstraints {
+			// DOWN: Drop the added constraint
+			add(migrationStep{table: tableDiff.Name, name: schema.ConstraintName(tableDiff.Name, constraint), kind: "add constraint",
+				up:   []string{m.addConstraintSQL(tableDiff.Name, constraint)},
+				down: []string{m.dropConstraintSQL(tableDiff.Name, constraint)}})
+		}
+
 		// Drop columns
 		for _, column := range tableDiff.DroppedColumns {
 			sql := m.adapter.GenerateDropColumnSQL(tableDiff.Name, column.Name)
 			if sql != "" {
-				upStatements = append(upStatements, sql)
 				// DOWN: Re-add the dropped column with its original definition
-				downStatements = append([]string{m.adapter.GenerateAddColumnSQL(tableDiff.Name, column)}, downStatements...)
+				add(migrationStep{table: tableDiff.Name, name: column.Name, kind: "drop column", up: []string{sql},
+					down: []string{m.adapter.GenerateAddColumnSQL(tableDiff.Name, column)}})
+			}
+		}
+
+		// Change column defaults
+		for _, column := range tableDiff.ModifiedColumns {
+			newDefault, changed := m.defaultChange(column)
+			if !changed {
+				continue
 			}
+			// DOWN: Restore the previous default
+			add(migrationStep{table: tableDiff.Name, name: column.Name, kind: "change default",
+				up:   []string{m.columnDefaultSQL(tableDiff.Name, column.Name, newDefault)},
+				down: []string{m.columnDefaultSQL(tableDiff.Name, column.Name, column.OldDefault)}})
 		}
 	}
 
+	// UP: Drop partitions (DOWN creates them again, without their rows)
+	for _, partition := range diff.DroppedPartitions {
+		add(migrationStep{table: partition.Parent, name: partition.Name, kind: "drop partition",
+			up: []string{schema.GenerateDropPartitionSQL(partition)}, down: []string{schema.GenerateCreatePartitionSQL(partition)}})
+	}
+
 	// UP: Drop tables
 	for _, tableName := range diff.DroppedTables {
-		upStatements = append(upStatements, dropTableSQL(tableName))
 		// DOWN: We can't restore dropped tables, add a comment
-		downStatements = append([]string{fmt.Sprintf("-- Cannot restore dropped table: %s (data lost)", tableName)}, downStatements...)
+		add(migrationStep{table: tableName, name: tableName, kind: "drop table", up: []string{dropTableSQL(tableName)},
+			down: []string{fmt.Sprintf("-- Cannot restore dropped table: %s (data lost)", tableName)}})
+	}
+
+	// UP: Enums with dropped or reordered values are created again once the
+	// columns of the schema files exist and the dropped ones are gone
+	for _, enumDiff := range diff.ModifiedEnums {
+		if !enumDiff.Recreate() {
+			continue
+		}
+		add(migrationStep{name: enumDiff.Name, kind: "recreate enum",
+			up:   schema.GenerateChangeEnumValuesSQL(enumDiff.Name, enumDiff.OldValues, enumDiff.Values, enumDiff.Columns),
+			down: schema.GenerateChangeEnumValuesSQL(enumDiff.Name, enumDiff.Values, enumDiff.OldValues, enumDiff.Columns)})
 	}
 
 	// UP: Drop enums
 	for _, enumName := range diff.DroppedEnums {
-		upStatements = append(upStatements, fmt.Sprintf("DROP TYPE IF EXISTS \"%s\";", enumName))
 		// DOWN: We can't fully restore dropped enums
-		downStatements = append([]string{fmt.Sprintf("-- Cannot restore dropped enum: %s", enumName)}, downStatements...)
-	}
-
-	// CRITICAL FIX: Add standalone index changes!
-	// UP: Drop indexes first (before adding new ones that might conflict)
-	for _, index := range diff.DroppedIndexes {
-		upStatements = append(upStatements, m.adapter.GenerateDropIndexSQL(index))
-		// DOWN: We can't fully restore dropped indexes
-		downStatements = append([]string{fmt.Sprintf("-- Cannot restore dropped index: %s", index.Name)}, downStatements...)
+		add(migrationStep{name: enumName, kind: "drop enum", up: []string{fmt.Sprintf("DROP TYPE IF EXISTS \"%s\";", enumName)},
+			down: []string{fmt.Sprintf("-- Cannot restore dropped enum: %s", enumName)}})
 	}
 
 	// UP: Add new indexes
 	for _, index := range diff.NewIndexes {
-		if strings.HasPrefix(index.Name, "sqlite_") {
+		// The indexes of new tables were created with them
+		if strings.HasPrefix(index.Name, "sqlite_") || createdIndexes[index.Name] {
 			continue
 		}
 		indexSQL := m.adapter.GenerateAddIndexSQL(index)
 		if indexSQL != "" {
-			upStatements = append(upStatements, indexSQL)
 			// DOWN: Drop the added index
-			downStatements = append([]string{fmt.Sprintf("DROP INDEX IF EXISTS \"%s\";", index.Name)}, downStatements...)
+			add(migrationStep{table: index.Table, name: index.Name, kind: "create index", up: []string{indexSQL},
+				down: []string{fmt.Sprintf("DROP INDEX IF EXISTS \"%s\";", index.Name)}})
 		}
 	}
 
-	return m.formatMigrationFileWithDown(name, upStatements, downStatements)
+	// UP: Row-level security (policies are dropped before RLS is turned off
+	// and created after it is turned on)
+	for _, policy := range diff.DroppedPolicies {
+		add(migrationStep{table: policy.Table, name: policy.Name, kind: "drop policy",
+			up: []string{schema.GenerateDropPolicySQL(policy)}, down: []string{schema.GenerateCreatePolicySQL(policy)}})
+	}
+	for _, table := range diff.RLSDisabledTables {
+		add(migrationStep{table: table, name: table, kind: "disable row-level security",
+			up: []string{schema.GenerateDisableRLSSQL(table)}, down: []string{schema.GenerateEnableRLSSQL(table)}})
+	}
+	for _, table := range diff.RLSEnabledTables {
+		add(migrationStep{table: table, name: table, kind: "enable row-level security",
+			up: []string{schema.GenerateEnableRLSSQL(table)}, down: []string{schema.GenerateDisableRLSSQL(table)}})
+	}
+	for _, policy := range diff.NewPolicies {
+		add(migrationStep{table: policy.Table, name: policy.Name, kind: "create policy",
+			up: []string{schema.GenerateCreatePolicySQL(policy)}, down: []string{schema.GenerateDropPolicySQL(policy)}})
+	}
+
+	// UP: Create materialized views last, once the tables they read from exist
+	for _, view := range diff.NewMaterializedViews {
+		add(migrationStep{name: view.Name, kind: "create materialized view",
+			up: m.createMaterializedViewSQL(view), down: []string{schema.GenerateDropMaterializedViewSQL(view.Name)}})
+	}
+
+	if m.provider == "mysql" {
+		steps = m.applyOnlineDDL(steps)
+	}
+	return steps
+}
+
+// createMaterializedViewSQL returns the statements creating a materialized
+// view and its indexes
+func (m *Migrator) createMaterializedViewSQL(view types.SchemaMaterializedView) []string {
+	statements := []string{schema.GenerateCreateMaterializedViewSQL(view)}
+	for _, index := range view.Indexes {
+		statements = append(statements, m.adapter.GenerateAddIndexSQL(index))
+	}
+	return statements
 }
 
 func (m *Migrator) generateEmptyMigrationTemplate(name string) string {
@@ -272,7 +537,7 @@ func (m *Migrator) formatMigrationFileWithDown(name string, upStatements []strin
 	if len(upStatements) > 0 {
 		for _, stmt := range upStatements {
 			builder.WriteString(stmt)
-			if !strings.HasSuffix(stmt, ";") {
+			if !strings.HasSuffix(stmt, ";") && !strings.HasPrefix(strings.TrimSpace(stmt), "--") {
 				builder.WriteString(";")
 			}
 			builder.WriteString("\n")
@@ -0,0 +1,284 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/database/mysql"
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// ddlAlgorithm is how MySQL runs a schema change on an InnoDB table
+type ddlAlgorithm int
+
+const (
+	ddlInstant ddlAlgorithm = iota // metadata only, MySQL 8 picks it by itself
+	ddlInPlace                     // rebuilds or builds an index while writes go on
+	ddlCopy                        // copies the table, blocking writes until done
+)
+
+var (
+	alterTableRegex  = regexp.MustCompile("(?is)^ALTER\\s+TABLE\\s+(`[^`]+`|\"[^\"]+\"|\\S+)\\s+(.+)$")
+	createIndexRegex = regexp.MustCompile("(?is)^CREATE\\s+(UNIQUE\\s+)?INDEX\\s+(`[^`]+`|\\S+)\\s+ON\\s+(`[^`]+`|\\S+)\\s*(\\(.+\\))$")
+	dropIndexRegex   = regexp.MustCompile("(?is)^DROP\\s+INDEX\\s+(`[^`]+`|\\S+)\\s+ON\\s+(`[^`]+`|\\S+)$")
+	ddlOptionRegex   = regexp.MustCompile(`(?i),?\s*\b(ALGORITHM|LOCK)\s*=\s*\w+`)
+	instantDDLRegex  = regexp.MustCompile(`(?is)^(RENAME\b|ALTER\s+COLUMN\s+\S+\s+(SET|DROP)\s+DEFAULT\b|ADD\s+COLUMN\b)`)
+
+	// Changes InnoDB can't make in place
+	copyDDLPatterns = []struct {
+		re     *regexp.Regexp
+		reason string
+	}{
+		{regexp.MustCompile(`(?is)^\s*ADD\b.*(\bFOREIGN\s+KEY\b|\bREFERENCES\b)`), "adding a foreign key copies the table unless foreign_key_checks is off"},
+		{regexp.MustCompile(`(?i)^\s*(MODIFY|CHANGE)\b`), "changing a column's type copies the table"},
+		{regexp.MustCompile(`(?i)^\s*(ADD|DROP)\s+PRIMARY\s+KEY\b`), "changing the primary key rebuilds the table under a lock"},
+		{regexp.MustCompile(`(?i)\bAUTO_INCREMENT\b`), "adding an AUTO_INCREMENT column locks the table"},
+		{regexp.MustCompile(`(?i)\bCONVERT\s+TO\s+CHARACTER\s+SET\b`), "converting the character set copies the table"},
+	}
+)
+
+// classifyAlter returns how MySQL runs the clauses of an ALTER TABLE and,
+// when it copies the table, why
+func classifyAlter(clauses string) (ddlAlgorithm, string) {
+	for _, pattern := range copyDDLPatterns {
+		if pattern.re.MatchString(clauses) {
+			return ddlCopy, pattern.reason
+		}
+	}
+	upper := strings.ToUpper(clauses)
+	if instantDDLRegex.MatchString(strings.TrimSpace(clauses)) && !strings.Contains(upper, "UNIQUE") && !strings.Contains(upper, "PRIMARY") {
+		return ddlInstant, ""
+	}
+	return ddlInPlace, ""
+}
+
+// onlineAlter splits a MySQL statement into the table it changes and the
+// ALTER TABLE clauses it amounts to. Statements that don't alter a table
+// give an empty table.
+func onlineAlter(statement string) (table, clauses string) {
+	statement = strings.TrimSuffix(strings.TrimSpace(statement), ";")
+	if matches := alterTableRegex.FindStringSubmatch(statement); matches != nil {
+		clauses = strings.TrimSpace(matches[2])
+		// RENAME TO renames the table itself, nothing to run online
+		if strings.HasPrefix(strings.ToUpper(clauses), "RENAME TO") {
+			return "", ""
+		}
+		return unquoteName(matches[1]), clauses
+	}
+	if matches := createIndexRegex.FindStringSubmatch(statement); matches != nil {
+		return unquoteName(matches[3]), fmt.Sprintf("ADD %sINDEX %s %s", strings.ToUpper(matches[1]), matches[2], matches[4])
+	}
+	if matches := dropIndexRegex.FindStringSubmatch(statement); matches != nil {
+		return unquoteName(matches[2]), "DROP INDEX " + matches[1]
+	}
+	return "", ""
+}
+
+func unquoteName(name string) string {
+	return strings.Trim(name, "`\"")
+}
+
+// largeTables returns the estimated row counts of the given tables that
+// reach the online_ddl threshold
+func (m *Migrator) largeTables(ctx context.Context, tables []string) (map[string]int64, error) {
+	estimates, err := m.tableRowEstimates(ctx, tables)
+	if err != nil {
+		return nil, err
+	}
+	large := make(map[string]int64)
+	for table, rows := range estimates {
+		if rows >= m.onlineDDL.LargeTableRows() {
+			large[table] = rows
+		}
+	}
+	return large, nil
+}
+
+// applyOnlineDDL makes the MySQL steps on large tables say how they may
+// run. Changes InnoDB can make in place get ALGORITHM=INPLACE, LOCK=NONE,
+// so MySQL refuses them rather than locking the table; changes that copy
+// the table get a warning. Instant changes are left for MySQL to pick.
+func (m *Migrator) applyOnlineDDL(steps []migrationStep) []migrationStep {
+	var tables []string
+	seen := make(map[string]bool)
+	for _, step := range steps {
+		if step.table != "" && !seen[step.table] {
+			seen[step.table] = true
+			tables = append(tables, step.table)
+		}
+	}
+	large, err := m.largeTables(context.Background(), tables)
+	if err != nil || len(large) == 0 {
+		return steps
+	}
+
+	hint := func(statements []string, warn bool, step *migrationStep) []string {
+		var result []string
+		for _, statement := range statements {
+			table, clauses := onlineAlter(statement)
+			rows, isLarge := large[table]
+			if !isLarge || ddlOptionRegex.MatchString(statement) {
+				result = append(result, statement)
+				continue
+			}
+			switch algorithm, reason := classifyAlter(clauses); algorithm {
+			case ddlInPlace:
+				statement = strings.TrimSuffix(strings.TrimSpace(statement), ";")
+				if strings.HasPrefix(strings.ToUpper(statement), "ALTER") {
+					statement += ", ALGORITHM=INPLACE, LOCK=NONE;"
+				} else {
+					statement += " ALGORITHM=INPLACE LOCK=NONE;"
+				}
+			case ddlCopy:
+				warning := fmt.Sprintf("%s has about %d rows and this change blocks writes to it until done: %s", table, rows, reason)
+				result = append(result, "-- WARNING: "+warning)
+				if warn {
+					step.warnings = append(step.warnings, warning)
+				}
+			}
+			result = append(result, statement)
+		}
+		return result
+	}
+
+	for i := range steps {
+		steps[i].up = hint(steps[i].up, true, &steps[i])
+		steps[i].down = hint(steps[i].down, false, &steps[i])
+	}
+	return steps
+}
+
+// applyWithOnlineDDLTool applies a MySQL migration, running the ALTERs of
+// large tables that aren't instant with gh-ost or pt-osc. The other
+// statements run on the server in between. MySQL commits schema changes
+// as it makes them, so a failure leaves the statements before it applied.
+func (m *Migrator) applyWithOnlineDDLTool(ctx context.Context, tool string, migration types.Migration, checksum, upSQL string) error {
+	statements := common.ParseSQLStatements(upSQL)
+	var tables []string
+	for _, statement := range statements {
+		if table, _ := onlineAlter(statement); table != "" {
+			tables = append(tables, table)
+		}
+	}
+	large, err := m.largeTables(ctx, tables)
+	if err != nil {
+		return err
+	}
+	if len(large) == 0 {
+		return m.adapter.ExecuteAndRecordMigration(ctx, migration.ID, migration.Name, checksum, upSQL)
+	}
+
+	var batch []string
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := m.adapter.ExecuteMigration(ctx, strings.Join(batch, ";\n")+";")
+		batch = nil
+		return err
+	}
+	for _, statement := range statements {
+		table, clauses := onlineAlter(statement)
+		if _, isLarge := large[table]; !isLarge {
+			batch = append(batch, statement)
+			continue
+		}
+		if algorithm, _ := classifyAlter(clauses); algorithm == ddlInstant {
+			batch = append(batch, statement)
+			continue
+		}
+
+		if err := flush(); err != nil {
+			return err
+		}
+		// The tools take the changes without ALGORITHM and LOCK
+		clauses = strings.TrimSpace(ddlOptionRegex.ReplaceAllString(clauses, ""))
+		fmt.Printf("      Running ALTER TABLE %s with %s: %s\n", table, tool, clauses)
+		if err := m.runOnlineDDLTool(ctx, tool, table, clauses); err != nil {
+			return fmt.Errorf("%s failed to alter %s: %w", tool, table, err)
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	return m.adapter.RecordMigration(ctx, migration.ID, migration.Name, checksum)
+}
+
+// runOnlineDDLTool runs one ALTER TABLE with gh-ost or pt-osc against the
+// database of the migrator
+func (m *Migrator) runOnlineDDLTool(ctx context.Context, tool, table, clauses string) error {
+	cfg, err := mysqldriver.ParseDSN(mysql.DSN(m.dbURL))
+	if err != nil {
+		return fmt.Errorf("failed to parse database URL: %w", err)
+	}
+	host, port := cfg.Addr, "3306"
+	if idx := strings.LastIndex(cfg.Addr, ":"); idx >= 0 {
+		host, port = cfg.Addr[:idx], cfg.Addr[idx+1:]
+	}
+
+	credentials, err := writeToolCredentials(cfg.User, cfg.Passwd)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(credentials)
+
+	name, args, err := onlineDDLCommand(tool, host, port, cfg.DBName, table, clauses, credentials, m.onlineDDL.ToolArgs)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// onlineDDLCommand returns the command running one ALTER TABLE with gh-ost
+// or pt-osc. The user and password are read from the credentials file:
+// every local user can see a command line.
+func onlineDDLCommand(tool, host, port, database, table, clauses, credentials string, toolArgs []string) (string, []string, error) {
+	switch tool {
+	case "gh-ost":
+		args := []string{
+			"--host=" + host, "--port=" + port, "--conf=" + credentials,
+			"--database=" + database, "--table=" + table, "--alter=" + clauses, "--execute",
+		}
+		return "gh-ost", append(args, toolArgs...), nil
+	case "pt-osc":
+		dsn := fmt.Sprintf("F=%s,h=%s,P=%s,D=%s,t=%s", credentials, host, port, database, table)
+		args := append([]string{"--alter", clauses, "--execute"}, toolArgs...)
+		return "pt-online-schema-change", append(args, dsn), nil
+	default:
+		return "", nil, fmt.Errorf("unknown online DDL tool %s", tool)
+	}
+}
+
+// writeToolCredentials writes the user and password to a MySQL option file
+// only the current user can read, as gh-ost's --conf and pt-osc's F= take
+// them. The caller removes it.
+func writeToolCredentials(user, password string) (string, error) {
+	file, err := os.CreateTemp("", "flash-online-ddl-*.cnf")
+	if err != nil {
+		return "", fmt.Errorf("failed to write online DDL credentials: %w", err)
+	}
+	_, err = fmt.Fprintf(file, "[client]\nuser = %s\npassword = %s\n", optionValue(user), optionValue(password))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write online DDL credentials: %w", err)
+	}
+	return file.Name(), nil
+}
+
+// optionValue quotes a value of a MySQL option file, so characters such as
+// # and ; are kept
+func optionValue(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
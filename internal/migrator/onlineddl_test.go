@@ -0,0 +1,62 @@
+package migrator
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// The password goes to the tools in a file only the current user can read,
+// never on their command line
+func TestOnlineDDLCommandHidesPassword(t *testing.T) {
+	const password = `s3cr#t;"pw\`
+	credentials, err := writeToolCredentials("app", password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(credentials)
+
+	info, err := os.Stat(credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("credentials file is %o, want 600", perm)
+	}
+	data, err := os.ReadFile(credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[client]\nuser = \"app\"\npassword = \"s3cr#t;\\\"pw\\\\\"\n"; string(data) != want {
+		t.Errorf("credentials file holds %q, want %q", data, want)
+	}
+
+	tests := []struct {
+		tool, name, credentialsArg string
+	}{
+		{"gh-ost", "gh-ost", "--conf=" + credentials},
+		{"pt-osc", "pt-online-schema-change", "F=" + credentials + ","},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			name, args, err := onlineDDLCommand(tt.tool, "db", "3306", "shop", "orders", "ADD COLUMN note TEXT", credentials, []string{"--max-load=Threads_running=25"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name != tt.name {
+				t.Errorf("runs %s, want %s", name, tt.name)
+			}
+			line := strings.Join(args, " ")
+			if strings.Contains(line, "s3cr") {
+				t.Errorf("password is on the command line: %s", line)
+			}
+			if !strings.Contains(line, tt.credentialsArg) {
+				t.Errorf("command line %s doesn't read %s", line, tt.credentialsArg)
+			}
+		})
+	}
+
+	if _, _, err := onlineDDLCommand("osc", "db", "3306", "shop", "orders", "", credentials, nil); err == nil {
+		t.Error("unknown tool was accepted")
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
 	"github.com/Lumos-Labs-HQ/flash/internal/types"
 	"github.com/Lumos-Labs-HQ/flash/internal/utils"
 )
@@ -41,6 +43,9 @@ func (m *Migrator) ApplyWithConflictDetection(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to get applied migrations: %w", err)
 	}
+	if err := m.verifyChecksums(ctx, migrations); err != nil {
+		return err
+	}
 
 	pending := utils.FilterPendingMigrations(migrations, applied)
 	if len(pending) == 0 {
@@ -50,6 +55,11 @@ func (m *Migrator) ApplyWithConflictDetection(ctx context.Context) error {
 
 	fmt.Printf("Found %d pending migrations\n", len(pending))
 
+	// Estimates are advisory, a failure to make them doesn't stop the apply
+	if impacts, err := m.estimateImpact(ctx, pending); err == nil && needsImpactReport(impacts) {
+		m.printImpact(impacts, false)
+	}
+
 	if hasConflicts, conflicts, err := m.hasConflicts(ctx, pending); err != nil {
 		return fmt.Errorf("failed to check for conflicts: %w", err)
 	} else if hasConflicts {
@@ -59,6 +69,61 @@ func (m *Migrator) ApplyWithConflictDetection(ctx context.Context) error {
 	return m.applyMigrations(ctx, pending)
 }
 
+// ApplyPending applies pending migrations without prompting or printing
+// progress, for callers that run several migrators at once. It returns the
+// number of migrations applied before any failure.
+func (m *Migrator) ApplyPending(ctx context.Context) (int, error) {
+	if err := m.createMigrationsTable(ctx); err != nil {
+		return 0, fmt.Errorf("failed to create migrations table: %w", err)
+	}
+	_ = m.cleanupBrokenMigrationRecords(ctx)
+
+	migrations, err := m.loadMigrationsFromDir()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	applied, err := m.getAppliedMigrations(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	if err := m.verifyChecksums(ctx, migrations); err != nil {
+		return 0, err
+	}
+
+	pending := utils.FilterPendingMigrations(migrations, applied)
+	for i, migration := range pending {
+		if err := m.applySingleMigrationSafely(ctx, migration); err != nil {
+			return i, fmt.Errorf("migration %s failed: %w", migration.ID, err)
+		}
+	}
+
+	return len(pending), nil
+}
+
+// PendingMigrations returns the IDs of the migrations not applied yet
+func (m *Migrator) PendingMigrations(ctx context.Context) ([]string, error) {
+	if err := m.createMigrationsTable(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	migrations, err := m.loadMigrationsFromDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	applied, err := m.getAppliedMigrations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	var ids []string
+	for _, migration := range utils.FilterPendingMigrations(migrations, applied) {
+		ids = append(ids, migration.ID)
+	}
+	return ids, nil
+}
+
 // handleConflictsInteractively handles migration conflicts interactively
 func (m *Migrator) handleConflictsInteractively(ctx context.Context, conflicts []types.MigrationConflict, pending []types.Migration) error {
 	fmt.Println("⚠️  Migration conflicts detected:")
@@ -150,11 +215,25 @@ func (m *Migrator) applySingleMigrationSafely(ctx context.Context, migration typ
 		return fmt.Errorf("failed to read migration file: %w", err)
 	}
 
-	checksum := fmt.Sprintf("%x", len(content))
+	checksum := migrationChecksum(content)
 
 	// Extract only the UP section from the migration
 	upSQL := extractUpSQL(string(content))
 
+	if err := m.preflightConstraints(ctx, upSQL); err != nil {
+		return err
+	}
+
+	if m.provider == "mysql" {
+		tool, err := m.onlineDDL.ResolveTool()
+		if err != nil {
+			return err
+		}
+		if tool != "" {
+			return m.applyWithOnlineDDLTool(ctx, tool, migration, checksum, upSQL)
+		}
+	}
+
 	// Use the combined method that does both operations in a single transaction
 	if err := m.adapter.ExecuteAndRecordMigration(ctx, migration.ID, migration.Name, checksum, upSQL); err != nil {
 		return err
@@ -163,6 +242,43 @@ func (m *Migrator) applySingleMigrationSafely(ctx context.Context, migration typ
 	return nil
 }
 
+// migrationChecksum is the checksum a migration file is recorded with
+func migrationChecksum(content []byte) string {
+	return fmt.Sprintf("%x", len(content))
+}
+
+// verifyChecksums returns a *common.ChecksumError for the first applied
+// migration whose file changed since, as the change would never be applied.
+// --force skips the check.
+func (m *Migrator) verifyChecksums(ctx context.Context, migrations []types.Migration) error {
+	if m.force {
+		return nil
+	}
+	result, err := m.adapter.ExecuteQuery(ctx, "SELECT id, checksum FROM _flash_migrations WHERE finished_at IS NOT NULL")
+	if err != nil {
+		return fmt.Errorf("failed to read migration checksums: %w", err)
+	}
+	recorded := make(map[string]string, len(result.Rows))
+	for _, row := range result.Rows {
+		recorded[fmt.Sprint(row["id"])] = fmt.Sprint(row["checksum"])
+	}
+
+	for _, migration := range migrations {
+		applied, ok := recorded[migration.ID]
+		if !ok {
+			continue
+		}
+		content, err := os.ReadFile(migration.FilePath)
+		if err != nil {
+			return fmt.Errorf("failed to read migration file: %w", err)
+		}
+		if current := migrationChecksum(content); current != applied {
+			return &common.ChecksumError{Migration: migration.ID, Applied: applied, Current: current}
+		}
+	}
+	return nil
+}
+
 // extractUpSQL extracts only the UP migration SQL from a migration file
 // Migration files may contain both -- +migrate Up and -- +migrate Down sections
 func extractUpSQL(content string) string {
@@ -357,7 +473,13 @@ func (m *Migrator) Status(ctx context.Context) error {
 		fmt.Println("\nApplied migrations in database:")
 		fmt.Printf("%-16s  %-30s  %-10s  %s\n", "ID", "NAME", "STATUS", "APPLIED AT")
 		fmt.Printf("%-16s  %-30s  %-10s  %s\n", "──────────────", "──────────────────────────────", "──────────", "───────────────────")
-		for id, t := range applied {
+		ids := make([]string, 0, len(applied))
+		for id := range applied {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			t := applied[id]
 			migrationID, migrationName := splitMigrationID(id)
 			timestamp := ""
 			if t != nil {
@@ -403,6 +525,46 @@ func (m *Migrator) Status(ctx context.Context) error {
 	return nil
 }
 
+// MigrationStatuses returns every migration file with whether it is
+// applied, followed by the applied migrations that have no file anymore
+func (m *Migrator) MigrationStatuses(ctx context.Context) ([]types.MigrationStatusItem, error) {
+	if err := m.createMigrationsTable(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	migrations, err := m.loadMigrationsFromDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	applied, err := m.getAppliedMigrations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	statuses := make([]types.MigrationStatusItem, 0, len(migrations))
+	known := make(map[string]bool, len(migrations))
+	for _, migration := range migrations {
+		known[migration.ID] = true
+		item := types.MigrationStatusItem{ID: migration.ID, Name: migration.Name, Status: "pending"}
+		if t, exists := applied[migration.ID]; exists {
+			item.Status = "applied"
+			item.AppliedAt = t
+		}
+		statuses = append(statuses, item)
+	}
+
+	var orphaned []types.MigrationStatusItem
+	for id, t := range applied {
+		if !known[id] {
+			_, name := splitMigrationID(id)
+			orphaned = append(orphaned, types.MigrationStatusItem{ID: id, Name: name, Status: "missing", AppliedAt: t})
+		}
+	}
+	sort.Slice(orphaned, func(i, j int) bool { return orphaned[i].ID < orphaned[j].ID })
+	return append(statuses, orphaned...), nil
+}
+
 // splitMigrationID splits a migration ID like "20251204234836_add_phone_column" into ID and name
 func splitMigrationID(fullID string) (string, string) {
 	// Migration IDs are typically formatted as: YYYYMMDDHHMMSS_name
@@ -0,0 +1,328 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/schema"
+)
+
+const preflightIdent = "(?:[`\"]?\\w+[`\"]?\\.)?[`\"]?(\\w+)[`\"]?"
+
+var (
+	preflightAlterTableRegex  = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?` + preflightIdent + `\s+(.*)$`)
+	preflightSetNotNullRegex  = regexp.MustCompile(`(?is)\bALTER\s+(?:COLUMN\s+)?` + preflightIdent + `\s+SET\s+NOT\s+NULL\b`)
+	preflightModifyRegex      = regexp.MustCompile(`(?is)\bMODIFY\s+(?:COLUMN\s+)?` + preflightIdent + `\s+[^,]*?\bNOT\s+NULL\b`)
+	preflightAddUniqueRegex   = regexp.MustCompile(`(?is)\bADD\s+(?:CONSTRAINT\s+` + preflightIdent + `\s+)?UNIQUE\s*(?:KEY\s+|INDEX\s+)?(?:[` + "`" + `"]?\w+[` + "`" + `"]?\s*)?\(([^)]*)\)`)
+	preflightUniqueIndexRegex = regexp.MustCompile(`(?is)^CREATE\s+UNIQUE\s+INDEX\s+(?:CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(?:` + preflightIdent + `\s+)?ON\s+(?:ONLY\s+)?` + preflightIdent + `\s*(?:USING\s+\w+\s*)?\(([^)]*)\)`)
+	preflightWhereRegex       = regexp.MustCompile(`(?i)\bWHERE\b`)
+	preflightColumnRegex      = regexp.MustCompile("^[`\"]?(\\w+)[`\"]?(?:\\s+(?:ASC|DESC))?$")
+	preflightRenameTypeRegex  = regexp.MustCompile(`(?is)^ALTER\s+TYPE\s+` + preflightIdent + `\s+RENAME\s+TO\s+`)
+	preflightCreateEnumRegex  = regexp.MustCompile(`(?is)^CREATE\s+TYPE\s+` + preflightIdent + `\s+AS\s+ENUM\s*\((.*)\)$`)
+	preflightEnumValueRegex   = regexp.MustCompile(`'((?:[^']|'')*)'`)
+)
+
+const preflightSampleLimit = 5
+
+// constraintCheck is a NOT NULL or UNIQUE constraint a migration adds to
+// columns that already exist.
+type constraintCheck struct {
+	kind    string // "NOT NULL" or "UNIQUE"
+	table   string
+	columns []string
+}
+
+// findConstraintChecks returns the NOT NULL and UNIQUE constraints that the
+// migration adds to existing columns.
+func findConstraintChecks(migrationSQL string) []constraintCheck {
+	var checks []constraintCheck
+
+	for _, stmt := range common.ParseSQLStatements(migrationSQL) {
+		stmt = strings.TrimSpace(stmt)
+
+		if matches := preflightUniqueIndexRegex.FindStringSubmatch(stmt); matches != nil {
+			// Partial unique indexes only cover some rows
+			if preflightWhereRegex.MatchString(stmt[len(matches[0]):]) {
+				continue
+			}
+			if columns := parsePreflightColumns(matches[3]); columns != nil {
+				checks = append(checks, constraintCheck{kind: "UNIQUE", table: matches[2], columns: columns})
+			}
+			continue
+		}
+
+		matches := preflightAlterTableRegex.FindStringSubmatch(stmt)
+		if matches == nil {
+			continue
+		}
+		table, actions := matches[1], matches[2]
+
+		for _, m := range preflightSetNotNullRegex.FindAllStringSubmatch(actions, -1) {
+			checks = append(checks, constraintCheck{kind: "NOT NULL", table: table, columns: []string{m[1]}})
+		}
+		for _, m := range preflightModifyRegex.FindAllStringSubmatch(actions, -1) {
+			checks = append(checks, constraintCheck{kind: "NOT NULL", table: table, columns: []string{m[1]}})
+		}
+		for _, m := range preflightAddUniqueRegex.FindAllStringSubmatch(actions, -1) {
+			if columns := parsePreflightColumns(m[2]); columns != nil {
+				checks = append(checks, constraintCheck{kind: "UNIQUE", table: table, columns: columns})
+			}
+		}
+	}
+
+	return checks
+}
+
+// enumRecreate is an enum a migration renames out of the way and creates
+// again with the given values
+type enumRecreate struct {
+	name   string
+	values []string
+}
+
+// findEnumRecreates returns the enums the migration creates again, which
+// drops the values the new type doesn't have.
+func findEnumRecreates(migrationSQL string) []enumRecreate {
+	var recreates []enumRecreate
+	renamed := make(map[string]bool)
+
+	for _, stmt := range common.ParseSQLStatements(migrationSQL) {
+		stmt = strings.TrimSpace(stmt)
+		if matches := preflightRenameTypeRegex.FindStringSubmatch(stmt); matches != nil {
+			renamed[strings.ToLower(matches[1])] = true
+			continue
+		}
+		matches := preflightCreateEnumRegex.FindStringSubmatch(stmt)
+		if matches == nil || !renamed[strings.ToLower(matches[1])] {
+			continue
+		}
+		recreate := enumRecreate{name: matches[1]}
+		for _, m := range preflightEnumValueRegex.FindAllStringSubmatch(matches[2], -1) {
+			recreate.values = append(recreate.values, strings.ReplaceAll(m[1], "''", "'"))
+		}
+		recreates = append(recreates, recreate)
+	}
+
+	return recreates
+}
+
+// parsePreflightColumns parses a plain column list; expression columns
+// such as lower(email) return nil because they can't be checked directly.
+func parsePreflightColumns(list string) []string {
+	var columns []string
+	for _, part := range strings.Split(list, ",") {
+		matches := preflightColumnRegex.FindStringSubmatch(strings.TrimSpace(part))
+		if matches == nil {
+			return nil
+		}
+		columns = append(columns, matches[1])
+	}
+	return columns
+}
+
+// preflightConstraints queries the live tables for rows that would violate
+// the NOT NULL and UNIQUE constraints the migration adds, so they can be
+// reported before the migration runs instead of failing mid-transaction.
+// Tables and columns the migration creates itself are skipped.
+func (m *Migrator) preflightConstraints(ctx context.Context, migrationSQL string) error {
+	var problems []string
+
+	for _, check := range findConstraintChecks(migrationSQL) {
+		if exists, err := m.adapter.CheckTableExists(ctx, check.table); err != nil || !exists {
+			continue
+		}
+		columnsExist := true
+		for _, column := range check.columns {
+			if exists, err := m.adapter.CheckColumnExists(ctx, check.table, column); err != nil || !exists {
+				columnsExist = false
+				break
+			}
+		}
+		if !columnsExist {
+			continue
+		}
+
+		var problem string
+		var err error
+		if check.kind == "NOT NULL" {
+			problem, err = m.checkNullValues(ctx, check)
+		} else {
+			problem, err = m.checkDuplicateValues(ctx, check)
+		}
+		if err != nil {
+			return fmt.Errorf("pre-flight check on %s.%s failed: %w", check.table, strings.Join(check.columns, ", "), err)
+		}
+		if problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+
+	enumProblems, err := m.checkRemovedEnumValues(ctx, migrationSQL)
+	if err != nil {
+		return err
+	}
+	problems = append(problems, enumProblems...)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("existing data violates new constraints:\n  - %s\n   Fix the data (or adjust the migration) and run 'flash apply' again",
+			strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+func (m *Migrator) checkNullValues(ctx context.Context, check constraintCheck) (string, error) {
+	column := check.columns[0]
+	result, err := m.adapter.ExecuteQuery(ctx, fmt.Sprintf("SELECT COUNT(*) AS n FROM %s WHERE %s IS NULL",
+		m.quoteIdentifier(check.table), m.quoteIdentifier(column)))
+	if err != nil {
+		return "", err
+	}
+
+	count := preflightCount(result)
+	if count == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("%s.%s: %d row(s) are NULL (migration sets NOT NULL)", check.table, column, count), nil
+}
+
+func (m *Migrator) checkDuplicateValues(ctx context.Context, check constraintCheck) (string, error) {
+	quoted := make([]string, len(check.columns))
+	notNull := make([]string, len(check.columns))
+	for i, column := range check.columns {
+		quoted[i] = m.quoteIdentifier(column)
+		notNull[i] = quoted[i] + " IS NOT NULL"
+	}
+	columnList := strings.Join(quoted, ", ")
+	duplicates := fmt.Sprintf("SELECT %s, COUNT(*) AS n FROM %s WHERE %s GROUP BY %s HAVING COUNT(*) > 1",
+		columnList, m.quoteIdentifier(check.table), strings.Join(notNull, " AND "), columnList)
+
+	result, err := m.adapter.ExecuteQuery(ctx, fmt.Sprintf("SELECT COUNT(*) AS n FROM (%s) d", duplicates))
+	if err != nil {
+		return "", err
+	}
+	groups := preflightCount(result)
+	if groups == 0 {
+		return "", nil
+	}
+
+	samples, err := m.adapter.ExecuteQuery(ctx, fmt.Sprintf("%s ORDER BY COUNT(*) DESC LIMIT %d", duplicates, preflightSampleLimit))
+	if err != nil {
+		return "", err
+	}
+
+	var examples []string
+	for _, row := range samples.Rows {
+		values := make([]string, len(check.columns))
+		for i, column := range check.columns {
+			values[i] = formatPreflightValue(row[column])
+		}
+		examples = append(examples, fmt.Sprintf("%s (%d rows)", strings.Join(values, ", "), preflightInt(row["n"])))
+	}
+
+	return fmt.Sprintf("%s(%s): %d duplicated value(s) (migration adds UNIQUE), e.g. %s",
+		check.table, strings.Join(check.columns, ", "), groups, strings.Join(examples, "; ")), nil
+}
+
+// checkRemovedEnumValues reports the columns whose rows still hold values
+// that the enums the migration creates again no longer have.
+func (m *Migrator) checkRemovedEnumValues(ctx context.Context, migrationSQL string) ([]string, error) {
+	recreates := findEnumRecreates(migrationSQL)
+	if len(recreates) == 0 {
+		return nil, nil
+	}
+
+	enums, err := m.adapter.GetCurrentEnums(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("pre-flight check on enums failed: %w", err)
+	}
+	tables, err := m.adapter.GetCurrentSchema(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("pre-flight check on enums failed: %w", err)
+	}
+
+	var problems []string
+	for _, recreate := range recreates {
+		var removed []string
+		for _, enum := range enums {
+			if strings.EqualFold(enum.Name, recreate.name) {
+				removed = schema.RemovedEnumValues(enum.Values, recreate.values)
+			}
+		}
+		if len(removed) == 0 {
+			continue
+		}
+
+		quoted := make([]string, len(removed))
+		for i, value := range removed {
+			quoted[i] = "'" + strings.ReplaceAll(value, "'", "''") + "'"
+		}
+		list := strings.Join(quoted, ", ")
+
+		for _, col := range schema.EnumColumns(tables, recreate.name) {
+			condition := fmt.Sprintf("%s::text IN (%s)", m.quoteIdentifier(col.Column), list)
+			if col.Array {
+				condition = fmt.Sprintf("%s::text[] && ARRAY[%s]::text[]", m.quoteIdentifier(col.Column), list)
+			}
+			result, err := m.adapter.ExecuteQuery(ctx, fmt.Sprintf("SELECT COUNT(*) AS n FROM %s WHERE %s",
+				m.quoteIdentifier(col.Table), condition))
+			if err != nil {
+				return nil, fmt.Errorf("pre-flight check on %s.%s failed: %w", col.Table, col.Column, err)
+			}
+			if count := preflightCount(result); count > 0 {
+				problems = append(problems, fmt.Sprintf("%s.%s: %d row(s) hold %s (migration removes them from enum %s)",
+					col.Table, col.Column, count, list, recreate.name))
+			}
+		}
+	}
+	return problems, nil
+}
+
+func (m *Migrator) quoteIdentifier(name string) string {
+	if m.provider == "mysql" {
+		return "`" + name + "`"
+	}
+	return `"` + name + `"`
+}
+
+func preflightCount(result *common.QueryResult) int {
+	if result == nil || len(result.Rows) == 0 {
+		return 0
+	}
+	return preflightInt(result.Rows[0]["n"])
+}
+
+func preflightInt(value interface{}) int {
+	switch v := value.(type) {
+	case int64:
+		return int(v)
+	case int32:
+		return int(v)
+	case int:
+		return v
+	case float64:
+		return int(v)
+	case []byte:
+		n, _ := strconv.Atoi(string(v))
+		return n
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return 0
+}
+
+func formatPreflightValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return fmt.Sprintf("'%s'", string(v))
+	case string:
+		return fmt.Sprintf("'%s'", v)
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,217 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+// rebuildPrefix names the table a rebuild copies the rows into
+const rebuildPrefix = "_flash_new_"
+
+// needsRebuild reports whether SQLite has to rebuild a table to make the
+// changes of tableDiff. It can add and rename columns in place, but not drop
+// or change them, change the constraints of the table or add a column that
+// is a key or NOT NULL without a default.
+func (m *Migrator) needsRebuild(tableDiff types.TableDiff) bool {
+	if (m.provider != "sqlite" && m.provider != "sqlite3") || len(tableDiff.Table.Columns) == 0 {
+		return false
+	}
+	if len(tableDiff.DroppedColumns) > 0 || len(tableDiff.NewConstraints) > 0 || len(tableDiff.DroppedConstraints) > 0 {
+		return true
+	}
+
+	for _, column := range tableDiff.ModifiedColumns {
+		if _, changed := m.defaultChange(column); changed {
+			return true
+		}
+		for _, change := range column.Changes {
+			if strings.HasPrefix(change, "default changed") {
+				continue
+			}
+			if strings.HasPrefix(change, "type changed") && m.sameColumnType(column.OldType, column.NewType) {
+				continue
+			}
+			return true
+		}
+	}
+	for _, column := range tableDiff.NewColumns {
+		m.applyTimestampDefaults(&column)
+		if column.IsPrimary || column.IsUnique || (!column.Nullable && column.Default == "") {
+			return true
+		}
+	}
+	return false
+}
+
+// sameColumnType reports whether a declared type is the type the database
+// reports for a column. The SQLite adapter reads types mapped to their
+// storage class, e.g. TIMESTAMP as TEXT, so both are mapped the same way
+// before they are compared.
+func (m *Migrator) sameColumnType(current, declared string) bool {
+	if m.adapter == nil {
+		return strings.EqualFold(current, declared)
+	}
+	return strings.EqualFold(m.adapter.MapColumnType(current), m.adapter.MapColumnType(declared))
+}
+
+// rebuildTable returns the statements that rebuild a table into the shape
+// of the schema files, and the ones that rebuild it back. By then renamed
+// columns have their new names and dropped indexes are gone, so the old
+// shape is taken with the new names and only the indexes the table keeps
+// are created again.
+func (m *Migrator) rebuildTable(tableDiff types.TableDiff, diff *types.SchemaDiff) (up []string, down []string) {
+	renamed := make(map[string]string, len(tableDiff.RenamedColumns))
+	for _, rename := range tableDiff.RenamedColumns {
+		renamed[rename.From.Name] = rename.To.Name
+	}
+	columnName := func(name string) string {
+		if to, ok := renamed[name]; ok {
+			return to
+		}
+		return name
+	}
+
+	old := tableDiff.OldTable
+	old.Name = tableDiff.Name
+	old.Columns = make([]types.SchemaColumn, len(tableDiff.OldTable.Columns))
+	for i, column := range tableDiff.OldTable.Columns {
+		column.Name = columnName(column.Name)
+		old.Columns[i] = column
+	}
+	old.Constraints = make([]types.SchemaConstraint, len(tableDiff.OldTable.Constraints))
+	for i, constraint := range tableDiff.OldTable.Constraints {
+		columns := make([]string, len(constraint.Columns))
+		for j, column := range constraint.Columns {
+			columns[j] = columnName(column)
+		}
+		constraint.Columns = columns
+		old.Constraints[i] = constraint
+	}
+
+	// Rows are copied over in the columns both shapes have
+	oldColumns := make(map[string]bool, len(old.Columns))
+	for _, column := range old.Columns {
+		oldColumns[column.Name] = true
+	}
+	kept := make(map[string]bool, len(old.Columns))
+	var copied []string
+	for _, column := range tableDiff.Table.Columns {
+		if oldColumns[column.Name] {
+			kept[column.Name] = true
+			copied = append(copied, column.Name)
+		}
+	}
+
+	indexes := m.keptIndexes(tableDiff, diff, columnName, kept)
+	triggers := m.tableTriggers(tableDiff.Name, diff)
+	return m.rebuildTableSQL(tableDiff.Table, copied, indexes, triggers),
+		m.rebuildTableSQL(old, copied, indexes, triggers)
+}
+
+// keptIndexes returns the indexes of a table that are neither dropped nor
+// changed by the migration, under their new names
+func (m *Migrator) keptIndexes(tableDiff types.TableDiff, diff *types.SchemaDiff, columnName func(string) string, kept map[string]bool) []types.SchemaIndex {
+	dropped := make(map[string]bool, len(diff.DroppedIndexes))
+	for _, index := range diff.DroppedIndexes {
+		dropped[index.Name] = true
+	}
+	renamed := make(map[string]string, len(diff.RenamedIndexes))
+	for _, rename := range diff.RenamedIndexes {
+		renamed[rename.From.Name] = rename.To.Name
+	}
+
+	var indexes []types.SchemaIndex
+next:
+	for _, index := range tableDiff.OldTable.Indexes {
+		if dropped[index.Name] || strings.HasPrefix(index.Name, "sqlite_") {
+			continue
+		}
+		if to, ok := renamed[index.Name]; ok {
+			index.Name = to
+		}
+		index.Table = tableDiff.Name
+		columns := make([]string, len(index.Columns))
+		for i, column := range index.Columns {
+			if columns[i] = columnName(column); !kept[columns[i]] {
+				continue next
+			}
+		}
+		index.Columns = columns
+		indexes = append(indexes, index)
+	}
+	return indexes
+}
+
+// rebuildTableSQL returns the statements of the rebuild SQLite documents
+// for changes ALTER TABLE can't make: the rows are copied into a new table
+// of the given shape, which takes the place of the old one, and the indexes
+// and triggers dropped with the old table are created again. Foreign keys
+// are off meanwhile, so dropping the old table doesn't cascade, and
+// legacy_alter_table keeps views over the table from failing the rename.
+func (m *Migrator) rebuildTableSQL(table types.SchemaTable, copied []string, indexes []types.SchemaIndex, triggers []string) []string {
+	name := table.Name
+	table.Name = rebuildPrefix + name
+	table.Columns = append([]types.SchemaColumn(nil), table.Columns...)
+	for i := range table.Columns {
+		m.applyTimestampDefaults(&table.Columns[i])
+	}
+
+	statements := []string{
+		"PRAGMA foreign_keys = OFF;",
+		"PRAGMA legacy_alter_table = ON;",
+		m.adapter.GenerateCreateTableSQL(table),
+	}
+	if len(copied) > 0 {
+		columns := make([]string, len(copied))
+		for i, column := range copied {
+			columns[i] = m.quoteIdentifier(column)
+		}
+		list := strings.Join(columns, ", ")
+		statements = append(statements, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s;",
+			m.quoteIdentifier(table.Name), list, list, m.quoteIdentifier(name)))
+	}
+	statements = append(statements,
+		fmt.Sprintf("DROP TABLE %s;", m.quoteIdentifier(name)),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", m.quoteIdentifier(table.Name), m.quoteIdentifier(name)))
+	for _, index := range indexes {
+		statements = append(statements, m.adapter.GenerateAddIndexSQL(index))
+	}
+	statements = append(statements, triggers...)
+	return append(statements, "PRAGMA legacy_alter_table = OFF;", "PRAGMA foreign_keys = ON;")
+}
+
+// tableTriggers reads the triggers of a table from the database, so a
+// rebuild can create them again. A table renamed by the migration still has
+// its old name there.
+func (m *Migrator) tableTriggers(table string, diff *types.SchemaDiff) []string {
+	from := table
+	for _, rename := range diff.RenamedTables {
+		if rename.To.Name == table {
+			from = rename.From
+		}
+	}
+
+	result, err := m.adapter.ExecuteQuery(context.Background(),
+		"SELECT sql FROM sqlite_master WHERE type = 'trigger' AND tbl_name = ? AND sql IS NOT NULL ORDER BY name", from)
+	if err != nil {
+		return []string{fmt.Sprintf("-- Could not read the triggers of %s (%v); create them again by hand", from, err)}
+	}
+
+	onTable := regexp.MustCompile("(?i)\\bON\\s+[`\"]?" + regexp.QuoteMeta(from) + "[`\"]?(\\s)")
+	var triggers []string
+	for _, row := range result.Rows {
+		sql, _ := row["sql"].(string)
+		if sql == "" {
+			continue
+		}
+		if from != table {
+			sql = onTable.ReplaceAllString(sql, "ON "+m.quoteIdentifier(table)+"$1")
+		}
+		triggers = append(triggers, strings.TrimSuffix(strings.TrimSpace(sql), ";")+";")
+	}
+	return triggers
+}
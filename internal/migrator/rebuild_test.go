@@ -0,0 +1,99 @@
+package migrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+)
+
+// A schema whose declared types the SQLite adapter reads back mapped, such
+// as TIMESTAMP as TEXT, must not need a rebuild once it has been applied
+func TestSQLiteSecondMigrationIsEmpty(t *testing.T) {
+	dir := t.TempDir()
+	schemaDir := filepath.Join(dir, "schema")
+	migrationsDir := filepath.Join(dir, "migrations")
+	for _, path := range []string{schemaDir, migrationsDir} {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	schema := `CREATE TABLE users (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name VARCHAR(255) NOT NULL,
+  active BOOLEAN DEFAULT true,
+  born DATE,
+  seen DATETIME,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+`
+	if err := os.WriteFile(filepath.Join(schemaDir, "schema.sql"), []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{SchemaDir: schemaDir, MigrationsPath: migrationsDir}
+	cfg.Database.Provider = "sqlite"
+	m, err := NewMigratorForURL(cfg, "sqlite://"+filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	ctx := context.Background()
+	if err := m.GenerateMigration(ctx, "init", ""); err != nil {
+		t.Fatal(err)
+	}
+	if applied, err := m.ApplyPending(ctx); err != nil || applied != 1 {
+		t.Fatalf("applied %d migrations: %v", applied, err)
+	}
+
+	changes, err := m.SchemaChanges(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) > 0 {
+		t.Fatalf("expected no changes after applying the schema, got %+v", changes)
+	}
+
+	if err := m.GenerateMigration(ctx, "second", ""); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(migrationsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), "_second.sql") {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(migrationsDir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(content), "_flash_new_") || strings.Contains(strings.ToUpper(string(content)), "DROP TABLE") {
+			t.Fatalf("second migration rebuilds a table:\n%s", content)
+		}
+	}
+}
+
+// A real change of type still rebuilds the table
+func TestSQLiteTypeChangeNeedsRebuild(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{}
+	cfg.Database.Provider = "sqlite"
+	m, err := NewMigratorForURL(cfg, "sqlite://"+filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if !m.sameColumnType("TEXT", "TIMESTAMP") {
+		t.Error("TIMESTAMP should match the TEXT the adapter reads it as")
+	}
+	if m.sameColumnType("TEXT", "INTEGER") {
+		t.Error("INTEGER should not match TEXT")
+	}
+}
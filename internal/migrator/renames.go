@@ -0,0 +1,87 @@
+package migrator
+
+import (
+	"fmt"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+// confirmGuessedRenames asks whether each guessed rename really is one.
+// Declined renames go back to being a drop and a create.
+func (m *Migrator) confirmGuessedRenames(diff *types.SchemaDiff) {
+	var renamedTables []types.TableRename
+	for _, rename := range diff.RenamedTables {
+		if !rename.Guessed || m.askUserConfirmation(fmt.Sprintf("Did you rename table %s to %s?", rename.From, rename.To.Name)) {
+			renamedTables = append(renamedTables, rename)
+			continue
+		}
+		revertTableRename(diff, rename)
+	}
+	diff.RenamedTables = renamedTables
+
+	for i := range diff.ModifiedTables {
+		tableDiff := &diff.ModifiedTables[i]
+		var renamedColumns []types.ColumnRename
+		for _, rename := range tableDiff.RenamedColumns {
+			if !rename.Guessed || m.askUserConfirmation(fmt.Sprintf("Did you rename column %s.%s to %s?", tableDiff.Name, rename.From.Name, rename.To.Name)) {
+				renamedColumns = append(renamedColumns, rename)
+				continue
+			}
+			revertColumnRename(tableDiff, rename)
+		}
+		tableDiff.RenamedColumns = renamedColumns
+	}
+}
+
+// revertTableRename turns a table rename back into a drop and a create.
+// The caller removes the rename from diff.RenamedTables.
+func revertTableRename(diff *types.SchemaDiff, rename types.TableRename) {
+	diff.DroppedTables = append(diff.DroppedTables, rename.From)
+	diff.NewTables = append(diff.NewTables, rename.To)
+	for i, tableDiff := range diff.ModifiedTables {
+		if tableDiff.Name == rename.To.Name {
+			diff.ModifiedTables = append(diff.ModifiedTables[:i], diff.ModifiedTables[i+1:]...)
+			break
+		}
+	}
+}
+
+// revertColumnRename turns a column rename back into a drop and an add.
+// The caller removes the rename from tableDiff.RenamedColumns.
+func revertColumnRename(tableDiff *types.TableDiff, rename types.ColumnRename) {
+	tableDiff.DroppedColumns = append(tableDiff.DroppedColumns, rename.From)
+	tableDiff.NewColumns = append(tableDiff.NewColumns, rename.To)
+	for j, column := range tableDiff.ModifiedColumns {
+		if column.Name == rename.To.Name {
+			tableDiff.ModifiedColumns = append(tableDiff.ModifiedColumns[:j], tableDiff.ModifiedColumns[j+1:]...)
+			break
+		}
+	}
+}
+
+// renameTableSQL returns the statement that renames a table
+func (m *Migrator) renameTableSQL(from, to string) string {
+	if m.provider == "mysql" {
+		return fmt.Sprintf("RENAME TABLE %s TO %s;", m.quoteIdentifier(from), m.quoteIdentifier(to))
+	}
+	return fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", m.quoteIdentifier(from), m.quoteIdentifier(to))
+}
+
+// renameColumnSQL returns the statement that renames a column
+func (m *Migrator) renameColumnSQL(table, from, to string) string {
+	return fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s;",
+		m.quoteIdentifier(table), m.quoteIdentifier(from), m.quoteIdentifier(to))
+}
+
+// renameIndexSQL returns the statements that rename an index. SQLite can't
+// rename one, so it's dropped and created again under the new name.
+func (m *Migrator) renameIndexSQL(from, to types.SchemaIndex) []string {
+	switch m.provider {
+	case "sqlite", "sqlite3":
+		return []string{m.adapter.GenerateDropIndexSQL(from), m.adapter.GenerateAddIndexSQL(to)}
+	case "mysql":
+		return []string{fmt.Sprintf("ALTER TABLE %s RENAME INDEX %s TO %s;",
+			m.quoteIdentifier(to.Table), m.quoteIdentifier(from.Name), m.quoteIdentifier(to.Name))}
+	}
+	return []string{fmt.Sprintf("ALTER INDEX %s RENAME TO %s;", m.quoteIdentifier(from.Name), m.quoteIdentifier(to.Name))}
+}
@@ -0,0 +1,349 @@
+package migrator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+// reviewDecision is what the user chose for a migration step
+type reviewDecision struct {
+	skip bool
+	up   []string // edited statements, nil when the generated ones are kept
+}
+
+// migrationReview holds the state of an interactive migration review.
+// Decisions are keyed by the generated statements so they survive when the
+// steps are planned again after a drop is turned into a rename.
+type migrationReview struct {
+	m         *Migrator
+	reader    *bufio.Reader
+	diff      *types.SchemaDiff
+	decisions map[string]*reviewDecision
+}
+
+// ReviewMigration generates the pending schema changes and walks through
+// them table by table, letting each statement be approved, skipped or
+// edited and drops be turned into renames. The reviewed migration is then
+// written and applied.
+func (m *Migrator) ReviewMigration(ctx context.Context, name string, schemaPath string) error {
+	if schemaPath == "" {
+		schemaPath = m.schemaPath
+	}
+
+	diff, err := m.schemaManager.GenerateSchemaDiff(ctx, schemaPath)
+	if err != nil {
+		return fmt.Errorf("failed to generate schema diff: %w", err)
+	}
+
+	r := &migrationReview{
+		m:         m,
+		reader:    bufio.NewReader(os.Stdin),
+		diff:      diff,
+		decisions: make(map[string]*reviewDecision),
+	}
+
+	var steps []migrationStep
+	for {
+		steps = m.planMigration(diff)
+		if len(steps) == 0 {
+			fmt.Println("No changes detected in schema")
+			return nil
+		}
+
+		replan, err := r.reviewSteps(ctx, steps)
+		if err != nil {
+			return err
+		}
+		if !replan {
+			break
+		}
+	}
+
+	var reviewed []migrationStep
+	edited := false
+	for _, step := range steps {
+		decision := r.decisions[step.key()]
+		if decision.skip {
+			continue
+		}
+		if decision.up != nil {
+			step.up = decision.up
+			edited = true
+		}
+		reviewed = append(reviewed, step)
+	}
+
+	fmt.Printf("\n%d of %d change(s) approved\n", len(reviewed), len(steps))
+	if len(reviewed) == 0 {
+		fmt.Println("Nothing to migrate")
+		return nil
+	}
+
+	if name, err = r.askName(name); err != nil {
+		return err
+	}
+
+	upStatements, downStatements := collectStatements(reviewed)
+	filename := m.fileUtils.GenerateMigrationFilename(name)
+	if err := os.WriteFile(filepath.Join(m.migrationsDir, filename), []byte(m.formatMigrationFileWithDown(name, upStatements, downStatements)), 0644); err != nil {
+		return fmt.Errorf("failed to write migration file: %w", err)
+	}
+	fmt.Printf("Generated migration: %s\n", filename)
+	if edited {
+		fmt.Println("⚠️  Edited statements keep their generated rollback, check the Down section")
+	}
+
+	return m.Apply(ctx, "", "")
+}
+
+// reviewSteps asks for a decision on every step that doesn't have one yet,
+// grouped by table. It returns true when a rename changed the diff and the
+// steps have to be planned again.
+func (r *migrationReview) reviewSteps(ctx context.Context, steps []migrationStep) (bool, error) {
+	var tables []string
+	groups := make(map[string][]migrationStep)
+	for _, step := range steps {
+		if _, ok := groups[step.table]; !ok {
+			tables = append(tables, step.table)
+		}
+		groups[step.table] = append(groups[step.table], step)
+	}
+
+	for _, table := range tables {
+		pending := 0
+		for _, step := range groups[table] {
+			if r.decisions[step.key()] == nil {
+				pending++
+			}
+		}
+		if pending == 0 {
+			continue
+		}
+
+		title := table
+		if title == "" {
+			title = "(enums)"
+		}
+		fmt.Printf("\n── %s ──\n", title)
+
+		for _, step := range groups[table] {
+			if r.decisions[step.key()] != nil {
+				continue
+			}
+			replan, err := r.reviewStep(ctx, step, steps)
+			if err != nil || replan {
+				return replan, err
+			}
+		}
+	}
+	return false, nil
+}
+
+func (r *migrationReview) reviewStep(ctx context.Context, step migrationStep, steps []migrationStep) (bool, error) {
+	label := step.kind
+	if step.guessed {
+		label += ", guessed"
+	}
+	fmt.Printf("\n[%s] %s\n", label, step.name)
+	for _, stmt := range step.up {
+		fmt.Printf("  %s\n", strings.ReplaceAll(stmt, "\n", "\n  "))
+	}
+
+	targets := r.renameTargets(step, steps)
+	options := []string{"a", "s", "e"}
+	help := "a=approve, s=skip, e=edit"
+	if len(targets) > 0 {
+		options = append(options, "r")
+		help += ", r=rename instead"
+	}
+	if step.kind == "rename table" || step.kind == "rename column" {
+		options = append(options, "u")
+		help += ", u=drop and create instead"
+	}
+	options = append(options, "q")
+	help += ", q=quit"
+
+	for {
+		answer, err := r.prompt(fmt.Sprintf("%s [%s]: ", help, strings.Join(options, "/")))
+		if err != nil {
+			return false, err
+		}
+		answer = strings.ToLower(answer)
+
+		switch answer {
+		case "a", "":
+			r.decisions[step.key()] = &reviewDecision{}
+			return false, nil
+		case "s":
+			r.decisions[step.key()] = &reviewDecision{skip: true}
+			return false, nil
+		case "e":
+			up, err := r.readStatement()
+			if err != nil {
+				return false, err
+			}
+			if up == "" {
+				continue
+			}
+			r.decisions[step.key()] = &reviewDecision{up: []string{up}}
+			return false, nil
+		case "r":
+			if len(targets) > 0 {
+				return r.convertToRename(ctx, step, targets)
+			}
+		case "u":
+			if step.kind == "rename table" || step.kind == "rename column" {
+				r.undoRename(step)
+				return true, nil
+			}
+		case "q":
+			return false, fmt.Errorf("migration review cancelled")
+		}
+		fmt.Printf("Invalid option. Please choose from: %s\n", strings.Join(options, ", "))
+	}
+}
+
+// renameTargets returns the names a dropped table or column could have been
+// renamed to: the new tables, or the columns added to the same table
+func (r *migrationReview) renameTargets(step migrationStep, steps []migrationStep) []string {
+	var kind string
+	switch step.kind {
+	case "drop table":
+		kind = "create table"
+	case "drop column":
+		kind = "add column"
+	default:
+		return nil
+	}
+
+	var targets []string
+	for _, other := range steps {
+		if other.kind != kind || (kind == "add column" && other.table != step.table) {
+			continue
+		}
+		if decision := r.decisions[other.key()]; decision != nil && (decision.skip || decision.up != nil) {
+			continue
+		}
+		targets = append(targets, other.name)
+	}
+	return targets
+}
+
+func (r *migrationReview) convertToRename(ctx context.Context, step migrationStep, targets []string) (bool, error) {
+	target := targets[0]
+	if len(targets) > 1 {
+		for i, name := range targets {
+			fmt.Printf("  %d) %s\n", i+1, name)
+		}
+		for {
+			answer, err := r.prompt(fmt.Sprintf("Rename %s to (1-%d): ", step.name, len(targets)))
+			if err != nil {
+				return false, err
+			}
+			if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(targets) {
+				target = targets[n-1]
+				break
+			}
+		}
+	}
+
+	if step.kind == "drop table" {
+		if err := r.m.schemaManager.RenameTable(ctx, r.diff, step.name, target); err != nil {
+			return false, err
+		}
+		fmt.Printf("Renaming table %s to %s\n", step.name, target)
+	} else {
+		r.m.schemaManager.RenameColumn(r.diff, step.table, step.name, target)
+		fmt.Printf("Renaming column %s.%s to %s\n", step.table, step.name, target)
+	}
+	return true, nil
+}
+
+// undoRename turns a rename step back into a drop and a create
+func (r *migrationReview) undoRename(step migrationStep) {
+	if step.kind == "rename table" {
+		for i, rename := range r.diff.RenamedTables {
+			if rename.From == step.name {
+				r.diff.RenamedTables = append(r.diff.RenamedTables[:i], r.diff.RenamedTables[i+1:]...)
+				revertTableRename(r.diff, rename)
+				return
+			}
+		}
+		return
+	}
+
+	for i := range r.diff.ModifiedTables {
+		tableDiff := &r.diff.ModifiedTables[i]
+		if tableDiff.Name != step.table {
+			continue
+		}
+		for j, rename := range tableDiff.RenamedColumns {
+			if rename.From.Name == step.name {
+				tableDiff.RenamedColumns = append(tableDiff.RenamedColumns[:j], tableDiff.RenamedColumns[j+1:]...)
+				revertColumnRename(tableDiff, rename)
+				return
+			}
+		}
+	}
+}
+
+func (r *migrationReview) askName(name string) (string, error) {
+	prompt := "Migration name: "
+	if name != "" {
+		prompt = fmt.Sprintf("Migration name [%s]: ", name)
+	}
+	for {
+		answer, err := r.prompt(prompt)
+		if err != nil {
+			return "", err
+		}
+		if answer != "" {
+			return answer, nil
+		}
+		if name != "" {
+			return name, nil
+		}
+	}
+}
+
+// readStatement reads a replacement statement, ended by an empty line
+func (r *migrationReview) readStatement() (string, error) {
+	fmt.Println("Enter the new SQL, end with an empty line (empty keeps the current statement):")
+	var lines []string
+	for {
+		line, err := r.reader.ReadString('\n')
+		if strings.TrimSpace(line) == "" {
+			if err != nil && err != io.EOF {
+				return "", fmt.Errorf("failed to read input: %w", err)
+			}
+			break
+		}
+		lines = append(lines, strings.TrimRight(line, "\r\n"))
+		if err != nil {
+			break
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
+
+func (r *migrationReview) prompt(message string) (string, error) {
+	fmt.Print(message)
+	input, err := r.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || input == "") {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	return strings.TrimSpace(input), nil
+}
+
+// key identifies a step across re-planning
+func (s migrationStep) key() string {
+	return s.kind + "\x00" + strings.Join(s.up, "\n")
+}
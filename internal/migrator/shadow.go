@@ -0,0 +1,104 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/database"
+)
+
+// ShadowDatabase is an empty, disposable database that migrations are
+// replayed on to see which schema they produce. It lives on the server
+// serverURL points to, or in a temporary file for SQLite.
+type ShadowDatabase struct {
+	URL      string
+	provider string
+	name     string
+	admin    database.DatabaseAdapter // connection the database was created from
+}
+
+// NewShadowDatabase creates a shadow database. The user in serverURL must be
+// allowed to create databases. Drop removes it again.
+func NewShadowDatabase(ctx context.Context, provider, serverURL string) (*ShadowDatabase, error) {
+	name := fmt.Sprintf("flash_shadow_%d", time.Now().UnixNano())
+	shadow := &ShadowDatabase{provider: provider, name: name}
+
+	switch provider {
+	case "sqlite", "sqlite3":
+		shadow.name = filepath.Join(os.TempDir(), name+".db")
+		shadow.URL = "sqlite://" + shadow.name
+		return shadow, nil
+	case "postgresql", "postgres", "mysql":
+	default:
+		return nil, fmt.Errorf("shadow databases are not supported for provider %s", provider)
+	}
+
+	if serverURL == "" {
+		return nil, fmt.Errorf("a shadow database needs a database server URL to create it on")
+	}
+	shadowURL, err := withDatabaseName(provider, serverURL, name)
+	if err != nil {
+		return nil, err
+	}
+
+	admin := database.NewAdapter(provider)
+	if err := admin.Connect(ctx, serverURL); err != nil {
+		return nil, fmt.Errorf("failed to connect to shadow database server: %w", err)
+	}
+	if _, err := admin.ExecuteQuery(ctx, "CREATE DATABASE "+name); err != nil {
+		admin.Close()
+		return nil, fmt.Errorf("failed to create shadow database: %w", err)
+	}
+
+	shadow.URL = shadowURL
+	shadow.admin = admin
+	return shadow, nil
+}
+
+// Drop deletes the shadow database. Connections to it must be closed first.
+func (s *ShadowDatabase) Drop(ctx context.Context) error {
+	if s.admin == nil {
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			os.Remove(s.name + suffix)
+		}
+		return nil
+	}
+	defer s.admin.Close()
+
+	if _, err := s.admin.ExecuteQuery(ctx, "DROP DATABASE IF EXISTS "+s.name); err != nil {
+		return fmt.Errorf("failed to drop shadow database %s: %w", s.name, err)
+	}
+	return nil
+}
+
+// withDatabaseName rewrites a connection URL to use another database on
+// the same server
+func withDatabaseName(provider, dbURL, name string) (string, error) {
+	if provider == "mysql" {
+		base, params := dbURL, ""
+		if qIdx := strings.Index(dbURL, "?"); qIdx >= 0 {
+			base, params = dbURL[:qIdx], dbURL[qIdx:]
+		}
+		idx := strings.LastIndex(base, "/")
+		if idx < 0 || idx < strings.Index(base, "://")+3 {
+			return base + "/" + name + params, nil
+		}
+		return base[:idx+1] + name + params, nil
+	}
+
+	if !strings.Contains(dbURL, "://") {
+		// Keyword/value connection string, the last dbname wins
+		return fmt.Sprintf("%s dbname=%s", dbURL, name), nil
+	}
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse database URL: %w", err)
+	}
+	u.Path = "/" + name
+	return u.String(), nil
+}
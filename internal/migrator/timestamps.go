@@ -0,0 +1,66 @@
+package migrator
+
+import (
+	"fmt"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+// applyTimestampDefaults gives the configured audit columns a
+// CURRENT_TIMESTAMP default. On MySQL the updated_at column also gets
+// ON UPDATE CURRENT_TIMESTAMP; the other providers use a trigger instead.
+func (m *Migrator) applyTimestampDefaults(column *types.SchemaColumn) {
+	if !m.timestamps.IsTimestampColumn(column.Name) || column.Default != "" {
+		return
+	}
+	column.Default = "CURRENT_TIMESTAMP"
+	if m.provider == "mysql" && column.Name == m.timestamps.UpdatedAt {
+		column.Default += " ON UPDATE CURRENT_TIMESTAMP"
+	}
+}
+
+// hasUpdatedAtColumn reports whether the column list contains the
+// configured updated_at column.
+func (m *Migrator) hasUpdatedAtColumn(columns []types.SchemaColumn) bool {
+	if m.timestamps.UpdatedAt == "" {
+		return false
+	}
+	for _, column := range columns {
+		if column.Name == m.timestamps.UpdatedAt {
+			return true
+		}
+	}
+	return false
+}
+
+// generateUpdatedAtTrigger returns the statements that keep updated_at
+// current on every UPDATE, and the statements that remove them again.
+func (m *Migrator) generateUpdatedAtTrigger(tableName string) (up []string, down []string) {
+	column := m.timestamps.UpdatedAt
+	triggerName := fmt.Sprintf("%s_set_%s", tableName, column)
+
+	switch m.provider {
+	case "postgresql", "postgres":
+		functionName := fmt.Sprintf("flash_set_%s", column)
+		up = append(up, fmt.Sprintf(`CREATE OR REPLACE FUNCTION "%s"() RETURNS TRIGGER AS $$
+BEGIN
+    NEW."%s" = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;`, functionName, column))
+		up = append(up, fmt.Sprintf(`DROP TRIGGER IF EXISTS "%s" ON "%s";`, triggerName, tableName))
+		up = append(up, fmt.Sprintf(`CREATE TRIGGER "%s" BEFORE UPDATE ON "%s" FOR EACH ROW EXECUTE FUNCTION "%s"();`,
+			triggerName, tableName, functionName))
+		down = append(down, fmt.Sprintf(`DROP TRIGGER IF EXISTS "%s" ON "%s";`, triggerName, tableName))
+	case "sqlite", "sqlite3":
+		up = append(up, fmt.Sprintf(`DROP TRIGGER IF EXISTS "%s";`, triggerName))
+		up = append(up, fmt.Sprintf(`CREATE TRIGGER "%s" AFTER UPDATE ON "%s" FOR EACH ROW
+WHEN NEW."%s" IS OLD."%s"
+BEGIN
+    UPDATE "%s" SET "%s" = CURRENT_TIMESTAMP WHERE rowid = NEW.rowid;
+END;`, triggerName, tableName, column, column, tableName, column))
+		down = append(down, fmt.Sprintf(`DROP TRIGGER IF EXISTS "%s";`, triggerName))
+	}
+
+	return up, down
+}
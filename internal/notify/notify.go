@@ -0,0 +1,184 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+)
+
+const (
+	requestTimeout = 10 * time.Second
+	// discordMaxLength is the longest message Discord accepts
+	discordMaxLength = 2000
+)
+
+// Summary is the outcome of an apply, the data message templates render
+type Summary struct {
+	Environment string        `json:"environment"`
+	Provider    string        `json:"provider"`
+	Branch      string        `json:"branch,omitempty"`
+	Success     bool          `json:"success"`
+	Migrations  []string      `json:"migrations"` // applied by this run
+	Pending     []string      `json:"pending,omitempty"`
+	Duration    time.Duration `json:"-"`
+	DurationMS  int64         `json:"duration_ms"`
+	Error       string        `json:"error,omitempty"`
+}
+
+const defaultTemplate = `{{if .Success}}✅ {{len .Migrations}} migration(s) applied to *{{.Environment}}* in {{.Duration}}{{else}}❌ Migrations failed on *{{.Environment}}* after {{.Duration}}{{end}}
+{{- range .Migrations}}
+• {{.}}{{end}}
+{{- if not .Success}}
+Error: {{.Error}}{{if .Pending}}
+Not applied: {{join .Pending ", "}}{{end}}{{end}}`
+
+// Send posts the summary to the targets whose on setting matches its
+// outcome. override replaces every target's on setting when not empty, and
+// "never" sends nothing. All targets are tried; their errors are returned
+// together.
+func Send(cfg *config.Config, summary Summary, override string) error {
+	if override == "never" {
+		return nil
+	}
+
+	summary.Duration = summary.Duration.Round(time.Millisecond)
+	summary.DurationMS = summary.Duration.Milliseconds()
+	if summary.Provider == "" {
+		summary.Provider = cfg.Database.Provider
+	}
+	if summary.Environment == "" {
+		summary.Environment = os.ExpandEnv(cfg.Notifications.Environment)
+	}
+	if summary.Environment == "" {
+		summary.Environment = summary.Branch
+	}
+
+	return sendAll(cfg, summary.Success, override, func(target config.NotificationTarget) error {
+		message, err := Message(target, summary)
+		if err != nil {
+			return err
+		}
+		return post(target, message, struct {
+			Summary
+			Message string `json:"message"`
+		}{summary, message})
+	})
+}
+
+// Event is an outcome other than an apply, like a scheduled query run.
+// Targets post its message as is; their templates are for applies.
+type Event struct {
+	Kind        string `json:"kind"` // what happened, e.g. schedule
+	Environment string `json:"environment"`
+	Success     bool   `json:"success"`
+	Message     string `json:"message"`
+	Data        any    `json:"data,omitempty"` // sent to webhooks only
+}
+
+// SendEvent posts an event to the targets whose on setting matches its
+// outcome, with override as for Send
+func SendEvent(cfg *config.Config, event Event, override string) error {
+	if override == "never" {
+		return nil
+	}
+	if event.Environment == "" {
+		event.Environment = os.ExpandEnv(cfg.Notifications.Environment)
+	}
+
+	return sendAll(cfg, event.Success, override, func(target config.NotificationTarget) error {
+		return post(target, event.Message, event)
+	})
+}
+
+// sendAll calls send for the targets whose on setting matches the outcome
+// and returns their errors together
+func sendAll(cfg *config.Config, success bool, override string, send func(config.NotificationTarget) error) error {
+	var errs []string
+	for _, target := range cfg.Notifications.Targets {
+		on := target.On
+		if override != "" {
+			on = override
+		}
+		if (on == "success" && !success) || (on == "failure" && success) {
+			continue
+		}
+		if err := send(target); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", target.Type, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to send notifications: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Message renders the message a target posts for a summary
+func Message(target config.NotificationTarget, summary Summary) (string, error) {
+	text := target.Template
+	if text == "" {
+		text = defaultTemplate
+		if target.Type == "discord" {
+			// Discord bolds with double asterisks
+			text = strings.ReplaceAll(text, "*{{.Environment}}*", "**{{.Environment}}**")
+		}
+	}
+
+	tmpl, err := template.New("notification").Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, summary); err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// post sends a message to a target. Webhooks are sent webhookBody instead.
+func post(target config.NotificationTarget, message string, webhookBody any) error {
+	var body any
+	switch target.Type {
+	case "slack":
+		body = map[string]string{"text": message}
+	case "discord":
+		if len(message) > discordMaxLength {
+			message = message[:discordMaxLength-1] + "…"
+		}
+		body = map[string]string{"content": message}
+	default:
+		body = webhookBody
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, os.ExpandEnv(target.URL), bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "flash-notify")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", target.Type, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+)
+
+var (
+	encryptionTableRegex  = regexp.MustCompile(`(?i)\b(?:FROM|JOIN)\s+(\w+)(?:\s+(?:AS\s+)?(\w+))?`)
+	encryptionSetRegex    = regexp.MustCompile("(?i)(?:\\bSET\\s+|,\\s*)[\"`]?(\\w+)[\"`]?\\s*=\\s*(\\$\\d+|\\?)")
+	encryptionColumnRegex = regexp.MustCompile(`^(?:(\w+)\.)?(\w+)$`)
+)
+
+// markEncryptedColumns flags the columns of the schema that
+// encryption.columns names. Ciphertext is stored as text, so the columns
+// must be text columns.
+func markEncryptedColumns(schema *Schema, encryption config.Encryption) error {
+	if !encryption.Enabled() {
+		return nil
+	}
+
+	for _, table := range schema.Tables {
+		for _, col := range table.Columns {
+			if !encryption.IsEncryptedColumn(table.Name, col.Name) {
+				continue
+			}
+			if !isTextType(col.Type) {
+				return fmt.Errorf("encryption.columns: %s.%s is %s; only text columns can be encrypted", table.Name, col.Name, col.Type)
+			}
+			col.Encrypted = true
+		}
+	}
+	return nil
+}
+
+func isTextType(sqlType string) bool {
+	lower := strings.ToLower(sqlType)
+	return strings.Contains(lower, "char") || strings.Contains(lower, "text") || strings.Contains(lower, "clob")
+}
+
+// applyEncryption flags the params of an INSERT or UPDATE that are written
+// to an encrypted column, so generated clients encrypt them. Params compared
+// in a WHERE clause are left alone: ciphertext differs on every write, so
+// encrypted columns can't be searched.
+func (p *QueryParser) applyEncryption(query *Query, schema *Schema) {
+	if !p.Config.Encryption.Enabled() {
+		return
+	}
+
+	var tableName string
+	if match := p.insertRegex.FindStringSubmatch(query.SQL); len(match) > 1 {
+		tableName = match[1]
+	} else if match := p.updateRegex.FindStringSubmatch(query.SQL); len(match) > 1 {
+		tableName = match[1]
+	} else {
+		return
+	}
+	table := findTable(schema, tableName)
+	if table == nil {
+		return
+	}
+
+	if match := timestampInsertRegex.FindStringSubmatchIndex(query.SQL); match != nil {
+		columns := strings.Split(query.SQL[match[4]:match[5]], ",")
+		values := strings.Split(query.SQL[match[8]:match[9]], ",")
+		if len(columns) == len(values) {
+			offset := match[8]
+			for i, column := range columns {
+				value := strings.TrimSpace(values[i])
+				name := strings.Trim(strings.TrimSpace(column), "`\"")
+				if isPlaceholder(value) && isEncrypted(table, name) {
+					p.markEncryptedParam(query, value, offset+strings.Index(values[i], value))
+				}
+				offset += len(values[i]) + 1
+			}
+		}
+	}
+
+	for _, match := range encryptionSetRegex.FindAllStringSubmatchIndex(query.SQL, -1) {
+		if isEncrypted(table, query.SQL[match[2]:match[3]]) {
+			p.markEncryptedParam(query, query.SQL[match[4]:match[5]], match[4])
+		}
+	}
+}
+
+// markEncryptedParam flags the param of a placeholder found at offset in
+// the SQL of a query. $n placeholders are numbered; ? placeholders are
+// counted from the start of the query.
+func (p *QueryParser) markEncryptedParam(query *Query, placeholder string, offset int) {
+	index := len(paramRegex.FindAllString(query.SQL[:offset], -1))
+	if placeholder != "?" {
+		n, err := strconv.Atoi(strings.TrimPrefix(placeholder, "$"))
+		if err != nil {
+			return
+		}
+		index = n - 1
+	}
+	if index >= 0 && index < len(query.Params) {
+		query.Params[index].Encrypted = true
+	}
+}
+
+// isEncryptedResult reports whether a result column reads an encrypted
+// column as it is, either by name or through a table or alias of the query.
+// Expressions over an encrypted column read ciphertext and are not
+// decrypted.
+func isEncryptedResult(expr, sql string, schema *Schema, primary *Table) bool {
+	match := encryptionColumnRegex.FindStringSubmatch(strings.TrimSpace(expr))
+	if match == nil {
+		return false
+	}
+	qualifier, column := match[1], match[2]
+
+	if qualifier == "" {
+		return primary != nil && isEncrypted(primary, column)
+	}
+	for _, ref := range encryptionTableRegex.FindAllStringSubmatch(sql, -1) {
+		alias := ref[2]
+		if aliasStopWords[strings.ToUpper(alias)] {
+			alias = ""
+		}
+		if strings.EqualFold(ref[1], qualifier) || strings.EqualFold(alias, qualifier) {
+			if table := findTable(schema, ref[1]); table != nil {
+				return isEncrypted(table, column)
+			}
+		}
+	}
+	return false
+}
+
+func isEncrypted(table *Table, column string) bool {
+	for _, col := range table.Columns {
+		if strings.EqualFold(col.Name, column) {
+			return col.Encrypted
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+)
+
+const encryptionSchema = `CREATE TABLE users (
+  id INTEGER PRIMARY KEY,
+  email TEXT NOT NULL,
+  ssn VARCHAR(11),
+  age INTEGER
+);
+CREATE TABLE notes (
+  id INTEGER PRIMARY KEY,
+  user_id INTEGER NOT NULL REFERENCES users(id),
+  body TEXT NOT NULL
+);
+`
+
+// parseEncrypted parses a schema and a query file with encryption.columns
+// set, returning the queries
+func parseEncrypted(t *testing.T, provider, schema, queries string, columns []string) ([]*Query, error) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range map[string]string{"schema/schema.sql": schema, "queries/queries.sql": queries} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{SchemaDir: filepath.Join(dir, "schema"), Queries: filepath.Join(dir, "queries")}
+	cfg.Database.Provider = provider
+	cfg.Encryption.Columns = columns
+	parsed, err := NewSchemaParser(cfg).Parse()
+	if err != nil {
+		return nil, err
+	}
+	return NewQueryParser(cfg).Parse(parsed)
+}
+
+// Params written to an encrypted column are marked for encryption, and
+// result columns that read one as it is are marked for decryption; params
+// compared in WHERE clauses and expressions over the column are not
+func TestEncryptionMarking(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		query    string
+		params   []int    // encrypted params, from 0
+		columns  []string // encrypted result columns
+	}{
+		{
+			name:     "insert",
+			provider: "postgresql",
+			query:    "INSERT INTO users (email, age, ssn) VALUES ($1, $2, $3)",
+			params:   []int{0, 2},
+		},
+		{
+			name:     "insert with ? placeholders",
+			provider: "mysql",
+			query:    "INSERT INTO users (age, ssn, email) VALUES (?, ?, ?)",
+			params:   []int{1, 2},
+		},
+		{
+			name:     "insert returning",
+			provider: "postgresql",
+			query:    "INSERT INTO users (email, age) VALUES ($1, $2) RETURNING id, email, age",
+			params:   []int{0},
+			columns:  []string{"email"},
+		},
+		{
+			name:     "update",
+			provider: "postgresql",
+			query:    "UPDATE users SET age = $1, ssn = $2 WHERE email = $3",
+			params:   []int{1},
+		},
+		{
+			name:     "update with ? placeholders",
+			provider: "sqlite",
+			query:    "UPDATE users SET ssn = ?, age = ? WHERE id = ?",
+			params:   []int{0},
+		},
+		{
+			name:     "select",
+			provider: "postgresql",
+			query:    "SELECT id, email, LENGTH(ssn) AS ssn_length FROM users WHERE email = $1",
+			columns:  []string{"email"},
+		},
+		{
+			name:     "join",
+			provider: "postgresql",
+			query:    "SELECT u.id, u.ssn, n.body FROM users u JOIN notes n ON n.user_id = u.id",
+			columns:  []string{"ssn"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries, err := parseEncrypted(t, tt.provider, encryptionSchema, "-- name: Run :many\n"+tt.query+";\n", []string{"users.email", "users.ssn"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(queries) != 1 {
+				t.Fatalf("parsed %d queries", len(queries))
+			}
+
+			var params []int
+			var columns []string
+			for i, param := range queries[0].Params {
+				if param.Encrypted {
+					params = append(params, i)
+				}
+			}
+			for _, col := range queries[0].Columns {
+				if col.Encrypted {
+					columns = append(columns, col.Name)
+				}
+			}
+			if !slices.Equal(params, tt.params) {
+				t.Errorf("encrypted params %v, want %v", params, tt.params)
+			}
+			if !slices.Equal(columns, tt.columns) {
+				t.Errorf("encrypted columns %v, want %v", columns, tt.columns)
+			}
+		})
+	}
+}
+
+// Ciphertext is stored as text, so only text columns can be encrypted
+func TestEncryptionNeedsText(t *testing.T) {
+	_, err := parseEncrypted(t, "postgresql", encryptionSchema, "", []string{"users.age"})
+	if err == nil || !strings.Contains(err.Error(), "users.age is INTEGER") {
+		t.Errorf("error %v, want users.age refused", err)
+	}
+}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"sort"
 	"strings"
 	"sync"
 )
@@ -97,6 +98,7 @@ func (idx *IndexedSchema) GetTableNames() []string {
 	for name := range idx.tableIndex {
 		names = append(names, name)
 	}
+	sort.Strings(names)
 	return names
 }
 
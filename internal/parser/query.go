@@ -9,6 +9,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"unicode"
 
 	"github.com/Lumos-Labs-HQ/flash/internal/config"
 	"github.com/Lumos-Labs-HQ/flash/internal/utils"
@@ -83,15 +84,15 @@ func (p *QueryParser) parseFilesConcurrently(files []string, schema *Schema) ([]
 		numWorkers = 1
 	}
 
-	// Channels for work distribution and result collection
+	// Results are kept in file order, so the queries (and the code
+	// generated from them) come out the same on every run
 	type parseResult struct {
 		queries []*Query
 		err     error
-		file    string
 	}
-	
-	fileChan := make(chan string, len(files))
-	resultChan := make(chan parseResult, len(files))
+
+	fileChan := make(chan int, len(files))
+	results := make([]parseResult, len(files))
 
 	// Launch worker goroutines
 	var wg sync.WaitGroup
@@ -99,38 +100,38 @@ func (p *QueryParser) parseFilesConcurrently(files []string, schema *Schema) ([]
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for file := range fileChan {
-				queries, err := p.parseQueryFile(file, indexedSchema.Schema)
-				resultChan <- parseResult{
-					queries: queries,
-					err:     err,
-					file:    file,
-				}
+			for idx := range fileChan {
+				queries, err := p.parseQueryFile(files[idx], indexedSchema.Schema)
+				results[idx] = parseResult{queries: queries, err: err}
 			}
 		}()
 	}
 
 	// Send files to workers
-	for _, file := range files {
-		fileChan <- file
+	for idx := range files {
+		fileChan <- idx
 	}
 	close(fileChan)
+	wg.Wait()
 
-	// Wait for all workers to finish
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
-
-	// Collect results
+	// Collect results, reporting every file that failed rather than just
+	// the first one. Diagnostics name their file already.
 	allQueries := make([]*Query, 0, len(files)*4)
-	for result := range resultChan {
+	var errs []error
+	for idx, result := range results {
 		if result.err != nil {
-			return nil, fmt.Errorf("failed to parse %s: %w", result.file, result.err)
+			if _, ok := utils.AsDiagnostics(result.err); !ok {
+				result.err = fmt.Errorf("failed to parse %s: %w", files[idx], result.err)
+			}
+			errs = append(errs, result.err)
+			continue
 		}
 		allQueries = append(allQueries, result.queries...)
 	}
 
+	if err := utils.JoinDiagnostics(errs...); err != nil {
+		return nil, err
+	}
 	return allQueries, nil
 }
 
@@ -143,15 +144,53 @@ func (p *QueryParser) parseQueryFile(filename string, schema *Schema) ([]*Query,
 
 	baseName := filepath.Base(filename)
 	sourceFileName := strings.TrimSuffix(baseName, filepath.Ext(baseName))
+	displayPath := utils.DisplayPath(filename)
 
 	queries := []*Query{}
 	scanner := bufio.NewScanner(file)
 
 	var currentQuery *Query
 	var sqlLines []string
+	var sqlSource []sourceLine // file lines of sqlLines, to place diagnostics
 	var comment string
+	// Queries that fail are left out and checking goes on, so every error
+	// in the file is reported at once
+	var errs []error
+	lineNum := 0
+	// The include annotation of currentQuery, resolved once its columns are known
+	var includes []string
+	var includeLine int
+	var includeRaw string
+
+	finishQuery := func() {
+		currentQuery.SQL = strings.TrimSpace(strings.Join(sqlLines, " "))
+		currentQuery.Comment = comment
+		currentQuery.SourceFile = sourceFileName
+		currentQuery.SourcePath = displayPath
+		p.applyTimestamps(currentQuery)
+		if err := p.analyzeQuery(currentQuery, schema); err != nil {
+			errs = append(errs, relocateDiagnostics(err, displayPath, sqlSource))
+			return
+		}
+		p.applyEncryption(currentQuery, schema)
+		if includes != nil {
+			if err := resolveIncludes(currentQuery, includes, schema); err != nil {
+				errs = append(errs, &utils.Diagnostic{
+					File:    displayPath,
+					Line:    includeLine,
+					Column:  strings.Index(includeRaw, "--") + 1,
+					Message: err.Error(),
+					Snippet: includeRaw,
+				})
+				return
+			}
+		}
+		p.applySoftDelete(currentQuery, schema)
+		queries = append(queries, currentQuery)
+	}
 
 	for scanner.Scan() {
+		lineNum++
 		line := strings.TrimSpace(scanner.Text())
 
 		if line == "" {
@@ -160,13 +199,7 @@ func (p *QueryParser) parseQueryFile(filename string, schema *Schema) ([]*Query,
 
 		if strings.HasPrefix(line, "-- name:") || strings.HasPrefix(line, "-- name :") {
 			if currentQuery != nil {
-				currentQuery.SQL = strings.TrimSpace(strings.Join(sqlLines, " "))
-				currentQuery.Comment = comment
-				currentQuery.SourceFile = sourceFileName
-				if err := p.analyzeQuery(currentQuery, schema); err != nil {
-					return nil, err
-				}
-				queries = append(queries, currentQuery)
+				finishQuery()
 			}
 
 			nameStart := strings.Index(line, "name")
@@ -183,27 +216,140 @@ func (p *QueryParser) parseQueryFile(filename string, schema *Schema) ([]*Query,
 					Cmd:  parts[1],
 				}
 				sqlLines = []string{}
+				sqlSource = nil
 				comment = ""
+				includes = nil
+			}
+		} else if strings.HasPrefix(line, "-- paginate:") {
+			if currentQuery == nil {
+				continue
+			}
+			pagination, err := parsePaginateAnnotation(line)
+			if err != nil {
+				raw := scanner.Text()
+				errs = append(errs, &utils.Diagnostic{
+					File:    displayPath,
+					Line:    lineNum,
+					Column:  strings.Index(raw, "--") + 1,
+					Message: fmt.Sprintf("query '%s': %v", currentQuery.Name, err),
+					Snippet: raw,
+				})
+				continue
+			}
+			currentQuery.Paginate = pagination
+		} else if strings.HasPrefix(line, "-- include:") {
+			if currentQuery == nil {
+				continue
+			}
+			names, err := parseIncludeAnnotation(line)
+			if err != nil {
+				raw := scanner.Text()
+				errs = append(errs, &utils.Diagnostic{
+					File:    displayPath,
+					Line:    lineNum,
+					Column:  strings.Index(raw, "--") + 1,
+					Message: fmt.Sprintf("query '%s': %v", currentQuery.Name, err),
+					Snippet: raw,
+				})
+				continue
 			}
+			includes, includeLine, includeRaw = names, lineNum, scanner.Text()
+		} else if strings.HasPrefix(line, "-- lint:") {
+			// Read by `flash queries lint`
+			continue
 		} else if strings.HasPrefix(line, "--") {
 			comment = strings.TrimPrefix(line, "--")
 			comment = strings.TrimSpace(comment)
 		} else if currentQuery != nil {
 			sqlLines = append(sqlLines, line)
+			sqlSource = append(sqlSource, sourceLine{num: lineNum, text: scanner.Text()})
 		}
 	}
 
 	if currentQuery != nil {
-		currentQuery.SQL = strings.TrimSpace(strings.Join(sqlLines, " "))
-		currentQuery.Comment = comment
-		currentQuery.SourceFile = sourceFileName
-		if err := p.analyzeQuery(currentQuery, schema); err != nil {
-			return nil, err
+		finishQuery()
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := utils.JoinDiagnostics(errs...); err != nil {
+		return nil, err
+	}
+	return queries, nil
+}
+
+// sourceLine is a line of SQL as it appears in its query file
+type sourceLine struct {
+	num  int
+	text string
+}
+
+// relocateDiagnostics moves diagnostics found in the SQL of a query, which
+// joins its trimmed lines with spaces, to the line and column of the query
+// file they point at
+func relocateDiagnostics(err error, file string, lines []sourceLine) error {
+	diags, ok := utils.AsDiagnostics(err)
+	if !ok || len(lines) == 0 {
+		return err
+	}
+
+	for _, d := range diags {
+		if d.File != file {
+			continue
 		}
-		queries = append(queries, currentQuery)
+		offset := d.Column - 1
+		start := 0
+		for i, l := range lines {
+			trimmed := strings.TrimSpace(l.text)
+			end := start + len(trimmed)
+			if offset <= end || i == len(lines)-1 {
+				indent := len(l.text) - len(strings.TrimLeftFunc(l.text, unicode.IsSpace))
+				d.Line = l.num
+				d.Column = offset - start + indent + 1
+				d.Snippet = strings.TrimRight(l.text, "\r")
+				break
+			}
+			start = end + 1
+		}
+	}
+	return err
+}
+
+// parsePaginateAnnotation parses `-- paginate: cursor(created_at, id)`.
+// Columns may carry DESC, but all of them must sort the same way so the
+// keyset condition can be a single row comparison.
+func parsePaginateAnnotation(line string) (*Pagination, error) {
+	spec := strings.TrimSpace(strings.TrimPrefix(line, "-- paginate:"))
+	if !strings.HasPrefix(strings.ToLower(spec), "cursor(") || !strings.HasSuffix(spec, ")") {
+		return nil, fmt.Errorf("invalid paginate annotation %q, expected cursor(column, ...)", spec)
+	}
+
+	pagination := &Pagination{}
+	descCount := 0
+	for _, part := range strings.Split(spec[len("cursor(") : len(spec)-1], ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("invalid paginate column %q", strings.TrimSpace(part))
+		}
+		if len(fields) == 2 {
+			switch strings.ToUpper(fields[1]) {
+			case "DESC":
+				descCount++
+			case "ASC":
+			default:
+				return nil, fmt.Errorf("invalid sort direction %q for paginate column %s", fields[1], fields[0])
+			}
+		}
+		pagination.Columns = append(pagination.Columns, fields[0])
 	}
 
-	return queries, scanner.Err()
+	if descCount > 0 && descCount != len(pagination.Columns) {
+		return nil, fmt.Errorf("paginate columns must all sort in the same direction")
+	}
+	pagination.Descending = descCount > 0
+
+	return pagination, nil
 }
 
 func (p *QueryParser) analyzeQuery(query *Query, schema *Schema) error {
@@ -238,8 +384,14 @@ func (p *QueryParser) analyzeQuery(query *Query, schema *Schema) error {
 		for i, t := range schema.Tables {
 			availableTables[i] = t.Name
 		}
-		return fmt.Errorf("table '%s' referenced in query '%s' does not exist in schema. Available tables: %v",
-			tableName, query.Name, availableTables)
+		// Every missing FROM and JOIN table is reported when there are any,
+		// otherwise the INSERT or UPDATE target is
+		if err := utils.ValidateTableReferences(query.SQL, schema, query.SourcePath); err != nil {
+			return err
+		}
+		return utils.Diagnostics{utils.LocateDiagnostic(query.SourcePath, query.SQL, tableName,
+			fmt.Sprintf("table '%s' referenced in query '%s' does not exist in schema. Available tables: %v",
+				tableName, query.Name, availableTables))}
 	}
 
 	paramMatches := paramRegex.FindAllString(query.SQL, -1)
@@ -395,10 +547,11 @@ func (p *QueryParser) analyzeQuery(query *Query, schema *Schema) error {
 					colType, nullable := p.inferColumnType(colName, originalExpr, query.SQL, schema, table)
 
 					query.Columns = append(query.Columns, &QueryColumn{
-						Name:     colName,
-						Type:     colType,
-						Table:    tableName,
-						Nullable: nullable,
+						Name:      colName,
+						Type:      colType,
+						Table:     tableName,
+						Nullable:  nullable,
+						Encrypted: isEncryptedResult(originalExpr, query.SQL, schema, table),
 					})
 				}
 			}
@@ -413,12 +566,10 @@ func (p *QueryParser) analyzeQuery(query *Query, schema *Schema) error {
 		}
 	}
 
-	if err := utils.ValidateTableReferences(query.SQL, schema, query.SourceFile); err != nil {
-		return err
-	}
-
-	if err := utils.ValidateColumnReferences(query.SQL, schema, query.SourceFile); err != nil {
-		return err
+	// All problems in the query are collected rather than stopping at the first
+	errs := []error{
+		utils.ValidateTableReferences(query.SQL, schema, query.SourcePath),
+		utils.ValidateColumnReferences(query.SQL, schema, query.SourcePath),
 	}
 
 	hasJoin := strings.Contains(sqlUpper, "JOIN")
@@ -454,31 +605,13 @@ func (p *QueryParser) analyzeQuery(query *Query, schema *Schema) error {
 			}
 
 			if !columnExists {
-				lines := strings.Split(query.SQL, "\n")
-				lineNum := 1
-				colPos := 1
-				upperCol := strings.ToUpper(queryCol.Name)
-
-				for i, line := range lines {
-					upperLine := strings.ToUpper(line)
-					if strings.Contains(upperLine, upperCol) {
-						lineNum = i + 1
-						colPos = strings.Index(upperLine, upperCol) + 1
-						break
-					}
-				}
-
-				sourceFile := query.SourceFile
-				if sourceFile == "" {
-					sourceFile = "queries"
-				}
-				return fmt.Errorf("# package FlashORM\ndb\\queries\\%s.sql:%d:%d: column \"%s\" does not exist in table \"%s\"",
-					sourceFile, lineNum, colPos, queryCol.Name, table.Name)
+				errs = append(errs, utils.LocateDiagnostic(query.SourcePath, query.SQL, queryCol.Name,
+					fmt.Sprintf("column \"%s\" does not exist in table \"%s\"", queryCol.Name, table.Name)))
 			}
 		}
 	}
 
-	return nil
+	return utils.JoinDiagnostics(errs...)
 }
 
 // inferColumnType determines the correct SQL type for a column based on the expression and schema
@@ -0,0 +1,181 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/utils"
+)
+
+var relationNameRegex = regexp.MustCompile(`^\w+$`)
+
+// parseIncludeAnnotation parses `-- include: author, comments`
+func parseIncludeAnnotation(line string) ([]string, error) {
+	spec := strings.TrimSpace(strings.TrimPrefix(line, "-- include:"))
+	if spec == "" {
+		return nil, fmt.Errorf("include annotation names no relations")
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(spec, ",") {
+		name := strings.TrimSpace(part)
+		if !relationNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid relation name %q in include annotation", name)
+		}
+		if seen[strings.ToLower(name)] {
+			return nil, fmt.Errorf("relation '%s' is included twice", name)
+		}
+		seen[strings.ToLower(name)] = true
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// tableRelations lists the relations of a table from the foreign keys of the
+// schema. A foreign key column gives a relation to the table it references,
+// named after the column without its _id suffix (author_id gives author).
+// A table referencing this one gives a list relation named after that table,
+// unless it references this one more than once.
+func tableRelations(schema *Schema, table *Table) []*Relation {
+	var relations []*Relation
+	seen := make(map[string]bool)
+	add := func(rel *Relation) {
+		if rel.Table == nil || !tableHasColumn(schema, rel.Table.Name, rel.RelatedColumn) || seen[strings.ToLower(rel.Name)] {
+			return
+		}
+		seen[strings.ToLower(rel.Name)] = true
+		relations = append(relations, rel)
+	}
+
+	for _, col := range table.Columns {
+		if col.ForeignKeyTable == "" {
+			continue
+		}
+		name := col.Name
+		if trimmed := strings.TrimSuffix(strings.ToLower(name), "_id"); trimmed != strings.ToLower(name) && trimmed != "" {
+			name = name[:len(trimmed)]
+		} else {
+			name = col.ForeignKeyTable
+		}
+		add(&Relation{
+			Name:          name,
+			Table:         findTable(schema, col.ForeignKeyTable),
+			Column:        col.Name,
+			RelatedColumn: referencedColumn(col),
+		})
+	}
+
+	for _, other := range schema.Tables {
+		var fks []*Column
+		for _, col := range other.Columns {
+			if strings.EqualFold(col.ForeignKeyTable, table.Name) {
+				fks = append(fks, col)
+			}
+		}
+		if len(fks) != 1 {
+			continue
+		}
+		add(&Relation{
+			Name:          other.Name,
+			Table:         other,
+			Many:          true,
+			Column:        referencedColumn(fks[0]),
+			RelatedColumn: fks[0].Name,
+		})
+	}
+
+	return relations
+}
+
+// referencedColumn returns the column a foreign key points at, which is the
+// primary key when the reference doesn't name one
+func referencedColumn(col *Column) string {
+	if col.ForeignKeyColumn == "" {
+		return "id"
+	}
+	return col.ForeignKeyColumn
+}
+
+func findTable(schema *Schema, name string) *Table {
+	for _, t := range schema.Tables {
+		if strings.EqualFold(t.Name, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+// resolveIncludes looks up the relations named by a query's include
+// annotation. Each one is matched on a column the query must select.
+func resolveIncludes(query *Query, names []string, schema *Schema) error {
+	cmd := strings.ToLower(query.Cmd)
+	if cmd != ":one" && cmd != ":many" {
+		return fmt.Errorf("query '%s': include requires :one or :many, got %s", query.Name, query.Cmd)
+	}
+	if len(query.Columns) == 0 {
+		return fmt.Errorf("query '%s': include requires a query that returns rows", query.Name)
+	}
+	if len(query.Columns) == 1 && query.Columns[0].Name != "*" {
+		return fmt.Errorf("query '%s': include requires more than one result column", query.Name)
+	}
+
+	tableName := utils.ExtractTableName(query.SQL)
+	table := findTable(schema, tableName)
+	if table == nil {
+		return fmt.Errorf("query '%s': include requires a query on a table of the schema", query.Name)
+	}
+
+	// The result columns, with SELECT * standing for the table's columns
+	var selected []string
+	if len(query.Columns) == 1 {
+		for _, col := range table.Columns {
+			selected = append(selected, col.Name)
+		}
+	} else {
+		for _, col := range query.Columns {
+			selected = append(selected, col.Name)
+		}
+	}
+	isSelected := func(name string) bool {
+		for _, col := range selected {
+			if strings.EqualFold(col, name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	relations := tableRelations(schema, table)
+	for _, name := range names {
+		var rel *Relation
+		for _, r := range relations {
+			if strings.EqualFold(r.Name, name) {
+				rel = r
+				break
+			}
+		}
+		if rel == nil {
+			if len(relations) == 0 {
+				return fmt.Errorf("query '%s': table '%s' has no relation '%s', no foreign keys link it to other tables",
+					query.Name, table.Name, name)
+			}
+			available := make([]string, len(relations))
+			for i, r := range relations {
+				available[i] = r.Name
+			}
+			return fmt.Errorf("query '%s': table '%s' has no relation '%s'. Available relations: %s",
+				query.Name, table.Name, name, strings.Join(available, ", "))
+		}
+		if isSelected(rel.Name) {
+			return fmt.Errorf("query '%s': relation '%s' has the name of a result column", query.Name, rel.Name)
+		}
+		if !isSelected(rel.Column) {
+			return fmt.Errorf("query '%s': relation '%s' needs column '%s' in the result", query.Name, rel.Name, rel.Column)
+		}
+		query.Includes = append(query.Includes, rel)
+	}
+
+	return nil
+}
@@ -15,12 +15,18 @@ import (
 var (
 	createTableRegex *regexp.Regexp
 	enumRegex        *regexp.Regexp
+	referencesRegex  *regexp.Regexp
+	foreignKeyRegex  *regexp.Regexp
+	primaryKeyRegex  *regexp.Regexp
 	regexOnce        sync.Once
 )
 
 func initRegex() {
-	createTableRegex = regexp.MustCompile(`(?i)CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?(\w+)\s*\(([\s\S]*?)\);`)
+	createTableRegex = regexp.MustCompile(`(?i)CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?(\w+)\s*\(([\s\S]*?)\)\s*(?:PARTITION\s+BY\s+\w+\s*\([^;]*\))?\s*;`)
 	enumRegex = regexp.MustCompile(`(?i)CREATE\s+TYPE\s+(\w+)\s+AS\s+ENUM\s*\(\s*([^)]+)\s*\)`)
+	referencesRegex = regexp.MustCompile(`(?i)\bREFERENCES\s+(?:public\.)?((?:\w+\.)?\w+)(?:\s*\(\s*(\w+)\s*\))?`)
+	foreignKeyRegex = regexp.MustCompile(`(?i)\bFOREIGN\s+KEY\s*\(\s*(\w+)\s*\)`)
+	primaryKeyRegex = regexp.MustCompile(`(?i)\bPRIMARY\s+KEY\s*\(\s*(\w+)\s*\)`)
 }
 
 type SchemaParser struct {
@@ -32,7 +38,20 @@ func NewSchemaParser(cfg *config.Config) *SchemaParser {
 	return &SchemaParser{Config: cfg}
 }
 
+// Parse reads the schema files. On syntax errors the tables parsed anyway
+// are returned along with the diagnostics, so queries can still be checked
+// against them.
 func (p *SchemaParser) Parse() (*Schema, error) {
+	schema, err := p.parseFiles()
+	if schema != nil {
+		if encErr := markEncryptedColumns(schema, p.Config.Encryption); encErr != nil {
+			return nil, encErr
+		}
+	}
+	return schema, err
+}
+
+func (p *SchemaParser) parseFiles() (*Schema, error) {
 	schema := &Schema{
 		Tables: []*Table{},
 		Enums:  []*Enum{},
@@ -51,6 +70,8 @@ func (p *SchemaParser) Parse() (*Schema, error) {
 	if info, err := os.Stat(schemaDir); err == nil && info.IsDir() {
 		files, err := filepath.Glob(filepath.Join(schemaDir, "*.sql"))
 		if err == nil && len(files) > 0 {
+			// Every file is checked, so all syntax errors are reported at once
+			var syntaxErrs []error
 			for _, file := range files {
 				content, err := os.ReadFile(file)
 				if err != nil {
@@ -58,7 +79,7 @@ func (p *SchemaParser) Parse() (*Schema, error) {
 				}
 
 				if err := utils.ValidateSchemaSyntax(string(content), file); err != nil {
-					return nil, err
+					syntaxErrs = append(syntaxErrs, err)
 				}
 
 				tables := p.parseCreateTables(string(content))
@@ -66,7 +87,7 @@ func (p *SchemaParser) Parse() (*Schema, error) {
 				enums := p.parseCreateEnums(string(content))
 				schema.Enums = append(schema.Enums, enums...)
 			}
-			return schema, nil
+			return schema, utils.JoinDiagnostics(syntaxErrs...)
 		}
 	}
 
@@ -85,14 +106,15 @@ func (p *SchemaParser) Parse() (*Schema, error) {
 			return schema, nil
 		}
 
-		if err := utils.ValidateSchemaSyntax(string(content), schemaPath); err != nil {
-			return nil, err
-		}
+		syntaxErr := utils.ValidateSchemaSyntax(string(content), schemaPath)
 
 		tables := p.parseCreateTables(string(content))
 		schema.Tables = append(schema.Tables, tables...)
 		enums := p.parseCreateEnums(string(content))
 		schema.Enums = append(schema.Enums, enums...)
+		if syntaxErr != nil {
+			return schema, syntaxErr
+		}
 	}
 
 	return schema, nil
@@ -114,6 +136,9 @@ func (p *SchemaParser) parseCreateTables(sql string) []*Table {
 			Columns: make([]*Column, 0, 16),
 		}
 
+		// Table constraints may come before the columns they name
+		var constraints []string
+
 		lines := utils.SplitColumns(match[2])
 		for _, line := range lines {
 			line = strings.TrimSpace(line)
@@ -122,11 +147,13 @@ func (p *SchemaParser) parseCreateTables(sql string) []*Table {
 			}
 
 			lineUpper := strings.ToUpper(line)
-			if strings.HasPrefix(lineUpper, "PRIMARY") ||
-				strings.HasPrefix(lineUpper, "FOREIGN") ||
-				strings.HasPrefix(lineUpper, "UNIQUE") ||
+			if strings.HasPrefix(lineUpper, "FOREIGN") || strings.HasPrefix(lineUpper, "CONSTRAINT") ||
+				strings.HasPrefix(lineUpper, "PRIMARY") {
+				constraints = append(constraints, line)
+				continue
+			}
+			if strings.HasPrefix(lineUpper, "UNIQUE") ||
 				strings.HasPrefix(lineUpper, "CHECK") ||
-				strings.HasPrefix(lineUpper, "CONSTRAINT") ||
 				strings.HasPrefix(lineUpper, "INDEX") ||
 				strings.HasPrefix(lineUpper, "KEY") {
 				continue
@@ -172,11 +199,39 @@ func (p *SchemaParser) parseCreateTables(sql string) []*Table {
 				!strings.Contains(lineUpper, "PRIMARY KEY") &&
 				!strings.Contains(strings.ToUpper(colType), "SERIAL")
 
-			table.Columns = append(table.Columns, &Column{
-				Name:     colName,
-				Type:     colType,
-				Nullable: isNullable,
-			})
+			column := &Column{
+				Name:       colName,
+				Type:       colType,
+				Nullable:   isNullable,
+				PrimaryKey: strings.Contains(lineUpper, "PRIMARY KEY"),
+			}
+			if ref := referencesRegex.FindStringSubmatch(line); ref != nil {
+				column.ForeignKeyTable, column.ForeignKeyColumn = ref[1], ref[2]
+			}
+			table.Columns = append(table.Columns, column)
+		}
+
+		for _, constraint := range constraints {
+			// A key over several columns has no single column to mark
+			if pk := primaryKeyRegex.FindStringSubmatch(constraint); pk != nil {
+				for _, col := range table.Columns {
+					if strings.EqualFold(col.Name, pk[1]) {
+						col.PrimaryKey = true
+						col.Nullable = false
+					}
+				}
+			}
+
+			fk := foreignKeyRegex.FindStringSubmatch(constraint)
+			ref := referencesRegex.FindStringSubmatch(constraint)
+			if fk == nil || ref == nil {
+				continue
+			}
+			for _, col := range table.Columns {
+				if strings.EqualFold(col.Name, fk[1]) {
+					col.ForeignKeyTable, col.ForeignKeyColumn = ref[1], ref[2]
+				}
+			}
 		}
 
 		if len(table.Columns) > 0 {
@@ -0,0 +1,171 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	softDeleteFromRegex   = regexp.MustCompile(`(?i)^FROM\s+(\w+)(?:\s+(?:AS\s+)?(\w+))?`)
+	softDeleteDeleteRegex = regexp.MustCompile(`(?i)^\s*DELETE\s+FROM\s+(\w+)`)
+
+	// Words that can follow a table name but are never an alias
+	aliasStopWords = map[string]bool{
+		"WHERE": true, "JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true,
+		"FULL": true, "CROSS": true, "NATURAL": true, "ON": true, "GROUP": true,
+		"ORDER": true, "LIMIT": true, "OFFSET": true, "HAVING": true, "UNION": true,
+		"FOR": true, "WINDOW": true, "EXCEPT": true, "INTERSECT": true,
+	}
+
+	// Clauses that end a WHERE condition
+	whereEndKeywords = []string{"GROUP", "HAVING", "ORDER", "LIMIT", "OFFSET", "FOR", "UNION", "INTERSECT", "EXCEPT", "WINDOW"}
+)
+
+// applySoftDelete rewrites queries on tables that have the configured
+// soft-delete column: SELECTs skip deleted rows and DELETEs become UPDATEs
+// that stamp the column. Queries that already mention the column are left
+// untouched so deleted rows can still be listed or purged explicitly.
+func (p *QueryParser) applySoftDelete(query *Query, schema *Schema) {
+	column := p.Config.SoftDelete.Column
+	if column == "" {
+		return
+	}
+
+	columnRegex := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(column) + `\b`)
+	if columnRegex.MatchString(query.SQL) {
+		return
+	}
+
+	sqlUpper := strings.ToUpper(strings.TrimSpace(query.SQL))
+	switch {
+	case strings.HasPrefix(sqlUpper, "DELETE"):
+		match := softDeleteDeleteRegex.FindStringSubmatchIndex(query.SQL)
+		if match == nil {
+			return
+		}
+		table := query.SQL[match[2]:match[3]]
+		if !tableHasColumn(schema, table, column) {
+			return
+		}
+		query.SQL = "UPDATE " + table + " SET " + column + " = CURRENT_TIMESTAMP" + query.SQL[match[1]:]
+
+	case strings.HasPrefix(sqlUpper, "SELECT"):
+		fromIdx := topLevelKeywordIndex(query.SQL, 0, []string{"FROM"})
+		if fromIdx == -1 {
+			return
+		}
+		match := softDeleteFromRegex.FindStringSubmatch(query.SQL[fromIdx:])
+		if match == nil || !tableHasColumn(schema, match[1], column) {
+			return
+		}
+		qualifier := match[1]
+		if match[2] != "" && !aliasStopWords[strings.ToUpper(match[2])] {
+			qualifier = match[2]
+		}
+		query.SQL = addWhereCondition(query.SQL, fromIdx, qualifier+"."+column+" IS NULL")
+	}
+}
+
+// addWhereCondition ANDs condition into the top-level WHERE clause that
+// follows fromIdx, adding a WHERE clause if there is none.
+func addWhereCondition(sql string, fromIdx int, condition string) string {
+	end := topLevelKeywordIndex(sql, fromIdx, whereEndKeywords)
+	if semi := topLevelSemicolon(sql, fromIdx); semi != -1 && (end == -1 || semi < end) {
+		end = semi
+	}
+	if end == -1 {
+		end = len(sql)
+	}
+
+	whereIdx := topLevelKeywordIndex(sql[:end], fromIdx, []string{"WHERE"})
+	if whereIdx == -1 {
+		return strings.TrimRight(sql[:end], " ") + " WHERE " + condition + suffix(sql[end:])
+	}
+
+	existing := strings.TrimSpace(sql[whereIdx+len("WHERE") : end])
+	return sql[:whereIdx] + "WHERE " + condition + " AND (" + existing + ")" + suffix(sql[end:])
+}
+
+func suffix(rest string) string {
+	if rest == "" || strings.HasPrefix(rest, ";") {
+		return rest
+	}
+	return " " + rest
+}
+
+// topLevelKeywordIndex returns the position of the first keyword outside of
+// parentheses and string literals, starting at start, or -1.
+func topLevelKeywordIndex(sql string, start int, keywords []string) int {
+	upper := strings.ToUpper(sql)
+	depth := 0
+	inQuote := false
+
+	for i := start; i < len(sql); i++ {
+		c := sql[i]
+		if inQuote {
+			if c == '\'' {
+				inQuote = false
+			}
+			continue
+		}
+		switch c {
+		case '\'':
+			inQuote = true
+			continue
+		case '(':
+			depth++
+			continue
+		case ')':
+			depth--
+			continue
+		}
+		if depth != 0 || (i > 0 && isWordChar(sql[i-1])) {
+			continue
+		}
+		for _, kw := range keywords {
+			end := i + len(kw)
+			if strings.HasPrefix(upper[i:], kw) && (end == len(sql) || !isWordChar(sql[end])) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func topLevelSemicolon(sql string, start int) int {
+	depth := 0
+	inQuote := false
+	for i := start; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case inQuote:
+			inQuote = c != '\''
+		case c == '\'':
+			inQuote = true
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ';' && depth == 0:
+			return i
+		}
+	}
+	return -1
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func tableHasColumn(schema *Schema, table, column string) bool {
+	for _, t := range schema.Tables {
+		if !strings.EqualFold(t.Name, table) {
+			continue
+		}
+		for _, col := range t.Columns {
+			if strings.EqualFold(col.Name, column) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	timestampInsertRegex = regexp.MustCompile(`(?i)(INSERT\s+INTO\s+[\w".]+\s*)\(([^)]+)\)(\s*VALUES\s*)\(([^)]+)\)`)
+	numberedParamRegex   = regexp.MustCompile(`\$(\d+)`)
+)
+
+// applyTimestamps removes the configured audit columns from INSERT column
+// lists when they are bound to a parameter, so callers never pass them and
+// the database default fills them in. Remaining $n placeholders are
+// renumbered. It must run before analyzeQuery so params are inferred from
+// the rewritten SQL.
+func (p *QueryParser) applyTimestamps(query *Query) {
+	timestamps := p.Config.Timestamps
+	if len(timestamps.Columns()) == 0 {
+		return
+	}
+
+	match := timestampInsertRegex.FindStringSubmatchIndex(query.SQL)
+	if match == nil {
+		return
+	}
+	columns := strings.Split(query.SQL[match[4]:match[5]], ",")
+	values := strings.Split(query.SQL[match[8]:match[9]], ",")
+	if len(columns) != len(values) {
+		return
+	}
+
+	var keptColumns, keptValues []string
+	var removed []int
+	for i, column := range columns {
+		name := strings.Trim(strings.TrimSpace(column), "`\"")
+		value := strings.TrimSpace(values[i])
+		if timestamps.IsTimestampColumn(name) && isPlaceholder(value) {
+			if n, err := strconv.Atoi(strings.TrimPrefix(value, "$")); err == nil {
+				removed = append(removed, n)
+			}
+			continue
+		}
+		keptColumns = append(keptColumns, strings.TrimSpace(column))
+		keptValues = append(keptValues, strings.TrimSpace(values[i]))
+	}
+	if len(keptColumns) == len(columns) || len(keptColumns) == 0 {
+		return
+	}
+
+	rest := query.SQL[match[1]:]
+	for _, n := range removed {
+		// Still bound elsewhere (e.g. ON CONFLICT ... SET updated_at = $3)
+		if regexp.MustCompile(`\$` + strconv.Itoa(n) + `\b`).MatchString(rest) {
+			return
+		}
+	}
+
+	sql := query.SQL[:match[0]] +
+		query.SQL[match[2]:match[3]] + "(" + strings.Join(keptColumns, ", ") + ")" +
+		query.SQL[match[6]:match[7]] + "(" + strings.Join(keptValues, ", ") + ")" +
+		rest
+
+	query.SQL = numberedParamRegex.ReplaceAllStringFunc(sql, func(param string) string {
+		n, _ := strconv.Atoi(param[1:])
+		shift := 0
+		for _, r := range removed {
+			if r < n {
+				shift++
+			}
+		}
+		return fmt.Sprintf("$%d", n-shift)
+	})
+}
+
+func isPlaceholder(value string) bool {
+	if value == "?" {
+		return true
+	}
+	return numberedParamRegex.MatchString(value) && numberedParamRegex.FindString(value) == value
+}
@@ -16,9 +16,13 @@ type Table struct {
 }
 
 type Column struct {
-	Name     string
-	Type     string
-	Nullable bool
+	Name             string
+	Type             string
+	Nullable         bool
+	PrimaryKey       bool // the table's primary key is this column alone
+	ForeignKeyTable  string
+	ForeignKeyColumn string
+	Encrypted        bool // named in encryption.columns
 }
 
 type Query struct {
@@ -29,16 +33,37 @@ type Query struct {
 	Params     []*Param
 	Columns    []*QueryColumn
 	SourceFile string
+	SourcePath string // query file as shown in diagnostics
+	Paginate   *Pagination
+	Includes   []*Relation
+}
+
+// Pagination holds the keyset columns from a `-- paginate: cursor(...)` annotation
+type Pagination struct {
+	Columns    []string
+	Descending bool
+}
+
+// Relation is a table linked to a query's rows through a foreign key, loaded
+// for a `-- include:` annotation
+type Relation struct {
+	Name          string // field the related rows are attached as
+	Table         *Table
+	Many          bool   // Table holds the foreign key, so each row has a list
+	Column        string // result column matched against RelatedColumn
+	RelatedColumn string
 }
 
 type Param struct {
-	Name string
-	Type string
+	Name      string
+	Type      string
+	Encrypted bool // written to an encrypted column
 }
 
 type QueryColumn struct {
-	Name     string
-	Type     string
-	Table    string
-	Nullable bool
+	Name      string
+	Type      string
+	Table     string
+	Nullable  bool
+	Encrypted bool // read from an encrypted column as it is
 }
@@ -0,0 +1,148 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	goplugin "plugin"
+	"sort"
+	"sync"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/database"
+	"github.com/spf13/cobra"
+)
+
+// ExtensionSymbol is the exported variable a Go plugin (.so) must define,
+// holding a value that implements Extension
+const ExtensionSymbol = "Extension"
+
+// Extension adds commands to the flash CLI. Extensions are compiled in and
+// registered from an init function, or built with -buildmode=plugin and
+// dropped into the extensions folder.
+type Extension interface {
+	Name() string
+	Commands(ctx *Context) []*cobra.Command
+}
+
+// AdapterExtension is implemented by extensions that provide database
+// adapters, registered for their provider names before any command runs
+type AdapterExtension interface {
+	Adapters() map[string]func() database.DatabaseAdapter
+}
+
+// Context is what the CLI hands to extensions
+type Context struct {
+	Version string
+}
+
+// Config loads the project config, honouring the --config flag
+func (c *Context) Config() (*config.Config, error) {
+	return config.Load()
+}
+
+// Connect opens the project's database with the adapter for its provider
+func (c *Context) Connect(ctx context.Context) (database.DatabaseAdapter, error) {
+	cfg, err := c.Config()
+	if err != nil {
+		return nil, err
+	}
+	url, err := cfg.GetDatabaseURL()
+	if err != nil {
+		return nil, err
+	}
+
+	adapter := database.NewAdapter(cfg.Database.Provider)
+	if err := adapter.Connect(ctx, url); err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return adapter, nil
+}
+
+var (
+	extensionsMu sync.Mutex
+	extensions   = make(map[string]Extension)
+)
+
+// Register adds an extension, replacing one already registered by that name
+func Register(ext Extension) {
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+	extensions[ext.Name()] = ext
+}
+
+// Extensions returns the registered extensions sorted by name
+func Extensions() []Extension {
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+
+	list := make([]Extension, 0, len(extensions))
+	for _, ext := range extensions {
+		list = append(list, ext)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list
+}
+
+// ExtensionDir returns the folder Go plugin extensions are loaded from
+func ExtensionDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".flash", "plugins", "ext"), nil
+}
+
+// LoadGoPlugins opens every .so file of the extensions folder and registers
+// the Extension it exports. Go plugins must be built with the same Go version
+// and flash sources as the CLI; a plugin that fails to load is reported and
+// skipped so it can't break the CLI.
+func LoadGoPlugins() []error {
+	dir, err := ExtensionDir()
+	if err != nil {
+		return []error{err}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.so"))
+	if err != nil || len(files) == 0 {
+		return nil
+	}
+
+	var errs []error
+	for _, file := range files {
+		p, err := goplugin.Open(file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to load %s: %w", filepath.Base(file), err))
+			continue
+		}
+		symbol, err := p.Lookup(ExtensionSymbol)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s does not export %s", filepath.Base(file), ExtensionSymbol))
+			continue
+		}
+
+		// Lookup returns a pointer to the exported variable
+		switch ext := symbol.(type) {
+		case *Extension:
+			Register(*ext)
+		case Extension:
+			Register(ext)
+		default:
+			errs = append(errs, fmt.Errorf("%s: %s does not implement plugin.Extension", filepath.Base(file), ExtensionSymbol))
+		}
+	}
+	return errs
+}
+
+// RegisterExtensionAdapters registers the database adapters of every
+// extension that provides some
+func RegisterExtensionAdapters() {
+	for _, ext := range Extensions() {
+		if provider, ok := ext.(AdapterExtension); ok {
+			for name, factory := range provider.Adapters() {
+				database.RegisterAdapter(name, factory)
+			}
+		}
+	}
+}
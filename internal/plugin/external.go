@@ -0,0 +1,137 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+)
+
+// ExternalPrefix is the prefix of executables on PATH that provide commands,
+// git style: flash-lint on PATH is run for `flash lint`
+const ExternalPrefix = "flash-"
+
+// ExternalCommand is a command provided by an executable on PATH
+type ExternalCommand struct {
+	Name string
+	Path string
+}
+
+// FindExternalCommand looks for the executable providing a command on PATH
+func FindExternalCommand(name string) (ExternalCommand, bool) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, "-") {
+		return ExternalCommand{}, false
+	}
+	path, err := exec.LookPath(ExternalPrefix + name)
+	if err != nil || isManagedBinary(filepath.Base(path)) {
+		return ExternalCommand{}, false
+	}
+	return ExternalCommand{Name: name, Path: path}, true
+}
+
+// ListExternalCommands returns the commands provided by executables on PATH.
+// When several PATH entries provide the same command the first one wins,
+// like the shell.
+func ListExternalCommands() []ExternalCommand {
+	seen := make(map[string]bool)
+	var commands []ExternalCommand
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			fileName := entry.Name()
+			if !strings.HasPrefix(fileName, ExternalPrefix) || entry.IsDir() || isManagedBinary(fileName) {
+				continue
+			}
+			name := strings.TrimPrefix(fileName, ExternalPrefix)
+			if runtime.GOOS == "windows" {
+				if !strings.EqualFold(filepath.Ext(name), ".exe") {
+					continue
+				}
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			} else if info, err := entry.Info(); err != nil || info.Mode()&0111 == 0 {
+				continue
+			}
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			commands = append(commands, ExternalCommand{Name: name, Path: filepath.Join(dir, fileName)})
+		}
+	}
+
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+	return commands
+}
+
+// isManagedBinary reports whether an executable is one of the plugins
+// installed by add-plug, which are run by the manager instead
+func isManagedBinary(fileName string) bool {
+	return strings.HasPrefix(fileName, PluginPrefix)
+}
+
+// ExecuteExternal runs an external command with the remaining arguments.
+// The project context is passed in FLASH_* environment variables, and the
+// resolved config as JSON in FLASH_CONFIG_JSON.
+func ExecuteExternal(command ExternalCommand, args []string, version string) error {
+	cmd := exec.Command(command.Path, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), externalEnv(version)...)
+
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("%s exited with code %d", filepath.Base(command.Path), exitErr.ExitCode())
+		}
+		return fmt.Errorf("failed to execute %s: %w", filepath.Base(command.Path), err)
+	}
+	return nil
+}
+
+func externalEnv(version string) []string {
+	env := []string{"FLASH_VERSION=" + version}
+	if exe, err := os.Executable(); err == nil {
+		env = append(env, "FLASH_BIN="+exe)
+	}
+
+	configFile := config.ConfigFile
+	if configFile == "" {
+		configFile = "flash.config.json"
+	}
+	if _, err := os.Stat(configFile); err != nil {
+		return env
+	}
+	if abs, err := filepath.Abs(configFile); err == nil {
+		configFile = abs
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return env
+	}
+	env = append(env,
+		"FLASH_CONFIG="+configFile,
+		"FLASH_PROVIDER="+cfg.Database.Provider,
+		"FLASH_DATABASE_URL_ENV="+cfg.Database.URLEnv,
+		"FLASH_SCHEMA_DIR="+cfg.SchemaDir,
+		"FLASH_QUERIES="+cfg.Queries,
+		"FLASH_MIGRATIONS_PATH="+cfg.MigrationsPath,
+	)
+	if url, err := cfg.GetDatabaseURL(); err == nil {
+		env = append(env, "FLASH_DATABASE_URL="+url)
+	}
+	if data, err := json.Marshal(cfg); err == nil {
+		env = append(env, "FLASH_CONFIG_JSON="+string(data))
+	}
+	return env
+}
@@ -13,14 +13,23 @@ var CommandPluginMap = map[string]string{
 	"raw":      "core",
 	"branch":   "core",
 	"checkout": "core",
+	"tenant":   "core",
 	"gen":      "core",
 	"export":   "core",
+	"datadiff": "core",
+	"serve":    "core",
+	"schedule": "core",
+	"matview":  "core",
+	"dev":      "core",
+	"bench":    "core",
+	"state":    "core",
 
 	// Seed (part of core)
 	"seed": "core",
 
 	// Studio commands
 	"studio": "studio",
+	"import": "studio",
 }
 
 // PluginDescriptions provides descriptions for each plugin
@@ -32,9 +41,9 @@ var PluginDescriptions = map[string]string{
 
 // PluginCommands lists all commands provided by each plugin
 var PluginCommands = map[string][]string{
-	"core":   {"init", "migrate", "apply", "down", "status", "pull", "reset", "raw", "branch", "checkout", "gen", "export", "seed"},
-	"studio": {"studio"},
-	"all":    {"init", "migrate", "apply", "down", "status", "pull", "reset", "raw", "branch", "checkout", "gen", "export", "seed", "studio"},
+	"core":   {"init", "migrate", "apply", "down", "status", "pull", "reset", "raw", "branch", "checkout", "tenant", "gen", "export", "datadiff", "serve", "schedule", "matview", "dev", "bench", "state", "seed"},
+	"studio": {"studio", "import"},
+	"all":    {"init", "migrate", "apply", "down", "status", "pull", "reset", "raw", "branch", "checkout", "tenant", "gen", "export", "datadiff", "serve", "schedule", "matview", "dev", "bench", "state", "seed", "studio", "import"},
 }
 
 // GetRequiredPlugin returns the plugin name required for a given command
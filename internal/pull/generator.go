@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/Lumos-Labs-HQ/flash/internal/schema"
 	"github.com/Lumos-Labs-HQ/flash/internal/types"
 )
 
@@ -27,6 +28,9 @@ func (s *Service) generateTableSQLClean(table types.SchemaTable, indexes []types
 		}
 		if col.IsUnique && !col.IsPrimary {
 			sb.WriteString(" UNIQUE")
+			if col.UniqueDeferrable != "" {
+				sb.WriteString(" " + col.UniqueDeferrable)
+			}
 		}
 		if col.Default != "" {
 			sb.WriteString(fmt.Sprintf(" DEFAULT %s", col.Default))
@@ -36,15 +40,34 @@ func (s *Service) generateTableSQLClean(table types.SchemaTable, indexes []types
 			if col.OnDeleteAction != "" {
 				sb.WriteString(fmt.Sprintf(" ON DELETE %s", col.OnDeleteAction))
 			}
+			// NO ACTION is what a reference does on update unless told otherwise
+			if col.OnUpdateAction != "" && col.OnUpdateAction != "NO ACTION" {
+				sb.WriteString(fmt.Sprintf(" ON UPDATE %s", col.OnUpdateAction))
+			}
+			if col.ForeignKeyDeferrable != "" {
+				sb.WriteString(" " + col.ForeignKeyDeferrable)
+			}
 		}
 
-		if i < len(table.Columns)-1 {
+		if i < len(table.Columns)-1 || len(table.Constraints) > 0 {
 			sb.WriteString(",")
 		}
 		sb.WriteString("\n")
 	}
 
-	sb.WriteString(");")
+	for i, constraint := range table.Constraints {
+		sb.WriteString("    " + schema.DeclaredConstraint(constraint).Definition(func(name string) string { return name }))
+		if i < len(table.Constraints)-1 {
+			sb.WriteString(",")
+		}
+		sb.WriteString("\n")
+	}
+
+	if table.PartitionBy != "" {
+		sb.WriteString(fmt.Sprintf(") PARTITION BY %s;", table.PartitionBy))
+	} else {
+		sb.WriteString(");")
+	}
 
 	// Add indexes (skip internal SQLite indexes and primary key indexes)
 	for _, idx := range indexes {
@@ -61,6 +84,11 @@ func (s *Service) generateTableSQLClean(table types.SchemaTable, indexes []types
 			indexType, idx.Name, table.Name, strings.Join(idx.Columns, ", ")))
 	}
 
+	// Partitions follow their partitioned table
+	for _, partition := range table.Partitions {
+		sb.WriteString(fmt.Sprintf("\nCREATE TABLE %s PARTITION OF %s %s;", partition.Name, table.Name, partition.Bound))
+	}
+
 	return sb.String()
 }
 
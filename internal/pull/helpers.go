@@ -45,9 +45,9 @@ func (s *Service) extractTableSQL(content, tableName string) string {
 
 	tableSQL := content[start:endPos]
 
-	// Also capture any CREATE INDEX statements that follow
+	// Also capture any CREATE INDEX statements and partitions that follow
 	remaining := content[endPos:]
-	indexPattern := regexp.MustCompile(`(?i)^\s*(CREATE\s+(?:UNIQUE\s+)?INDEX\s+[^;]+;)`)
+	indexPattern := regexp.MustCompile(`(?i)^\s*(CREATE\s+(?:UNIQUE\s+)?INDEX\s+[^;]+;|CREATE\s+TABLE\s+\S+\s+PARTITION\s+OF\s+["'\x60]?` + regexp.QuoteMeta(tableName) + `["'\x60]?\s[^;]+;)`)
 	for {
 		match := indexPattern.FindStringSubmatch(remaining)
 		if match == nil {
@@ -11,6 +11,7 @@ import (
 
 	"github.com/Lumos-Labs-HQ/flash/internal/config"
 	"github.com/Lumos-Labs-HQ/flash/internal/database"
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
 	"github.com/Lumos-Labs-HQ/flash/internal/schema"
 	"github.com/Lumos-Labs-HQ/flash/internal/types"
 )
@@ -28,6 +29,9 @@ type Service struct {
 
 func NewService(cfg *config.Config) (*Service, error) {
 	adapter := database.NewAdapter(cfg.Database.Provider)
+	if err := database.Require(adapter, cfg.Database.Provider, common.CapabilityIntrospection); err != nil {
+		return nil, err
+	}
 
 	dbURL, err := cfg.GetDatabaseURL()
 	if err != nil {
@@ -59,11 +63,26 @@ func (s *Service) Close() {
 func (s *Service) PullSchema(ctx context.Context, opts Options) error {
 	fmt.Println("🔍 Introspecting database schema...")
 
-	dbTables, err := s.adapter.PullCompleteSchema(ctx)
+	allTables, err := s.adapter.PullCompleteSchema(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to pull database schema: %w", err)
 	}
 
+	dbTables := make([]types.SchemaTable, 0, len(allTables))
+	for _, table := range allTables {
+		if s.config.Database.IsIgnoredTable(table.Name) {
+			continue
+		}
+		columns := make([]types.SchemaColumn, 0, len(table.Columns))
+		for _, column := range table.Columns {
+			if !s.config.Database.IsIgnoredColumn(table.Name, column.Name) {
+				columns = append(columns, column)
+			}
+		}
+		table.Columns = columns
+		dbTables = append(dbTables, table)
+	}
+
 	dbEnums, err := s.adapter.GetCurrentEnums(ctx)
 	if err != nil {
 		dbEnums = []types.SchemaEnum{}
@@ -107,14 +126,69 @@ func (s *Service) PullSchema(ctx context.Context, opts Options) error {
 		dbIndexes = make(map[string][]types.SchemaIndex)
 	}
 
+	policySQL := s.getPolicySQL(ctx)
+	matViewSQL := s.getMaterializedViewSQL(ctx)
+
 	// If no files exist, create single schema.sql
 	if len(existingFiles) == 0 {
-		return s.createSingleSchemaFile(schemaDir, dbTables, dbEnums, dbIndexes)
+		return s.createSingleSchemaFile(schemaDir, dbTables, dbEnums, dbIndexes, policySQL, matViewSQL)
 	}
 
 	existingTables, existingEnums := s.parseExistingSchemaFiles(existingFiles)
 
-	return s.smartUpdateSchema(schemaDir, existingFiles, existingTables, existingEnums, dbTables, dbEnums, dbIndexes)
+	return s.smartUpdateSchema(schemaDir, existingFiles, existingTables, existingEnums, dbTables, dbEnums, dbIndexes, policySQL, matViewSQL)
+}
+
+// getPolicySQL renders the database's row-level security setup, or returns
+// an empty string when the adapter can't introspect it
+func (s *Service) getPolicySQL(ctx context.Context) string {
+	introspector, ok := s.adapter.(schema.PolicyIntrospector)
+	if !ok {
+		return ""
+	}
+
+	policies, err := introspector.GetCurrentPolicies(ctx)
+	if err != nil {
+		fmt.Printf("⚠️  Warning: Could not fetch policies: %v\n", err)
+		return ""
+	}
+	rlsTables, err := introspector.GetRLSEnabledTables(ctx)
+	if err != nil {
+		fmt.Printf("⚠️  Warning: Could not fetch row-level security state: %v\n", err)
+		return ""
+	}
+
+	var managedPolicies []types.SchemaPolicy
+	for _, policy := range policies {
+		if !s.config.Database.IsIgnoredTable(policy.Table) {
+			managedPolicies = append(managedPolicies, policy)
+		}
+	}
+	var managedTables []string
+	for _, table := range rlsTables {
+		if !s.config.Database.IsIgnoredTable(table) {
+			managedTables = append(managedTables, table)
+		}
+	}
+
+	return schema.GeneratePoliciesSQL(managedTables, managedPolicies)
+}
+
+// getMaterializedViewSQL renders the database's materialized views, or
+// returns an empty string when the adapter can't introspect them
+func (s *Service) getMaterializedViewSQL(ctx context.Context) string {
+	introspector, ok := s.adapter.(schema.MaterializedViewIntrospector)
+	if !ok {
+		return ""
+	}
+
+	views, err := introspector.GetCurrentMaterializedViews(ctx)
+	if err != nil {
+		fmt.Printf("⚠️  Warning: Could not fetch materialized views: %v\n", err)
+		return ""
+	}
+
+	return schema.GenerateMaterializedViewsSQL(views, s.adapter.GenerateAddIndexSQL)
 }
 
 // getExistingSchemaFiles returns all .sql files in the schema directory
@@ -143,14 +217,22 @@ func (s *Service) parseExistingSchemaFiles(files map[string]string) (map[string]
 	tables := make(map[string]string) // tableName -> fileName
 	var enums []string
 
-	createTableRegex := regexp.MustCompile(`(?i)CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?["'\x60]?(\w+)["'\x60]?`)
+	// Partitions (CREATE TABLE ... PARTITION OF) are part of their table
+	createTableRegex := regexp.MustCompile(`(?i)CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?["'\x60]?(\w+)["'\x60]?(\s+PARTITION\s+OF)?`)
 	createEnumRegex := regexp.MustCompile(`(?i)CREATE\s+TYPE\s+["'\x60]?(\w+)["'\x60]?\s+AS\s+ENUM`)
 
-	for fileName, content := range files {
+	fileNames := make([]string, 0, len(files))
+	for fileName := range files {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+
+	for _, fileName := range fileNames {
+		content := files[fileName]
 		// Find tables in this file
 		matches := createTableRegex.FindAllStringSubmatch(content, -1)
 		for _, match := range matches {
-			if len(match) > 1 {
+			if len(match) > 2 && match[2] == "" {
 				tables[match[1]] = fileName
 			}
 		}
@@ -168,7 +250,7 @@ func (s *Service) parseExistingSchemaFiles(files map[string]string) (map[string]
 }
 
 // createSingleSchemaFile creates a single schema.sql with all tables
-func (s *Service) createSingleSchemaFile(schemaDir string, dbTables []types.SchemaTable, dbEnums []types.SchemaEnum, dbIndexes map[string][]types.SchemaIndex) error {
+func (s *Service) createSingleSchemaFile(schemaDir string, dbTables []types.SchemaTable, dbEnums []types.SchemaEnum, dbIndexes map[string][]types.SchemaIndex, policySQL, matViewSQL string) error {
 	var sb strings.Builder
 
 	sb.WriteString("-- Schema auto-generated by flash pull\n")
@@ -195,6 +277,18 @@ func (s *Service) createSingleSchemaFile(schemaDir string, dbTables []types.Sche
 		sb.WriteString("\n")
 	}
 
+	if policySQL != "" {
+		sb.WriteString("-- Row-level security\n")
+		sb.WriteString(policySQL)
+		sb.WriteString("\n")
+	}
+
+	if matViewSQL != "" {
+		sb.WriteString("\n-- Materialized views\n")
+		sb.WriteString(matViewSQL)
+		sb.WriteString("\n")
+	}
+
 	schemaPath := filepath.Join(schemaDir, "schema.sql")
 	if err := os.WriteFile(schemaPath, []byte(sb.String()), 0644); err != nil {
 		return fmt.Errorf("failed to write schema file: %w", err)
@@ -210,7 +304,7 @@ func (s *Service) createSingleSchemaFile(schemaDir string, dbTables []types.Sche
 }
 
 // smartUpdateSchema compares and updates only changed parts
-func (s *Service) smartUpdateSchema(schemaDir string, existingFiles map[string]string, existingTables map[string]string, existingEnums []string, dbTables []types.SchemaTable, dbEnums []types.SchemaEnum, dbIndexes map[string][]types.SchemaIndex) error {
+func (s *Service) smartUpdateSchema(schemaDir string, existingFiles map[string]string, existingTables map[string]string, existingEnums []string, dbTables []types.SchemaTable, dbEnums []types.SchemaEnum, dbIndexes map[string][]types.SchemaIndex, policySQL, matViewSQL string) error {
 	updatedFiles := 0
 	newFiles := 0
 	commentedFiles := 0
@@ -324,6 +418,36 @@ func (s *Service) smartUpdateSchema(schemaDir string, existingFiles map[string]s
 		}
 	}
 
+	// Handle row-level security - kept in _policies.sql unless the user
+	// already declares policies in their own files
+	if policySQL != "" && !s.declaresPolicies(existingFiles) {
+		policyPath := filepath.Join(schemaDir, "_policies.sql")
+
+		existingPolicyContent, _ := os.ReadFile(policyPath)
+		if string(existingPolicyContent) != policySQL+"\n" {
+			if err := os.WriteFile(policyPath, []byte(policySQL+"\n"), 0644); err != nil {
+				return fmt.Errorf("failed to write policy file: %w", err)
+			}
+			fmt.Println("  📝 Updated _policies.sql")
+			updatedFiles++
+		}
+	}
+
+	// Handle materialized views - kept in _matviews.sql unless the user
+	// already declares them in their own files
+	if matViewSQL != "" && !s.declaresMaterializedViews(existingFiles) {
+		matViewPath := filepath.Join(schemaDir, "_matviews.sql")
+
+		existingMatViewContent, _ := os.ReadFile(matViewPath)
+		if string(existingMatViewContent) != matViewSQL+"\n" {
+			if err := os.WriteFile(matViewPath, []byte(matViewSQL+"\n"), 0644); err != nil {
+				return fmt.Errorf("failed to write materialized view file: %w", err)
+			}
+			fmt.Println("  📝 Updated _matviews.sql")
+			updatedFiles++
+		}
+	}
+
 	if updatedFiles > 0 || newFiles > 0 || commentedFiles > 0 {
 		fmt.Printf("✅ Schema sync complete: %d files updated, %d new files created, %d files commented out\n", updatedFiles, newFiles, commentedFiles)
 	} else {
@@ -332,3 +456,27 @@ func (s *Service) smartUpdateSchema(schemaDir string, existingFiles map[string]s
 
 	return nil
 }
+
+// declaresPolicies reports whether a schema file other than _policies.sql
+// contains row-level security statements
+func (s *Service) declaresPolicies(files map[string]string) bool {
+	policyRegex := regexp.MustCompile(`(?i)CREATE\s+POLICY|ENABLE\s+ROW\s+LEVEL\s+SECURITY`)
+	for fileName, content := range files {
+		if fileName != "_policies.sql" && policyRegex.MatchString(content) {
+			return true
+		}
+	}
+	return false
+}
+
+// declaresMaterializedViews reports whether a schema file other than
+// _matviews.sql contains materialized views
+func (s *Service) declaresMaterializedViews(files map[string]string) bool {
+	matViewRegex := regexp.MustCompile(`(?i)CREATE\s+MATERIALIZED\s+VIEW`)
+	for fileName, content := range files {
+		if fileName != "_matviews.sql" && matViewRegex.MatchString(content) {
+			return true
+		}
+	}
+	return false
+}
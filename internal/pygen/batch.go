@@ -0,0 +1,80 @@
+package pygen
+
+import "strings"
+
+// writeBatchClass emits the Batch class used by Queries.batch(). With asyncpg
+// the queued calls share one pooled connection and transaction; other drivers
+// run them in queue order on the existing connection.
+func (g *Generator) writeBatchClass(w *strings.Builder) {
+	isAsync := g.Config.Gen.Python.Async
+	provider := g.Config.Database.Provider
+	isPostgres := provider == "" || provider == "postgresql" || provider == "postgres"
+
+	w.WriteString("\n\nclass Batch:\n")
+	w.WriteString("    \"\"\"Queues query calls and executes them together.\n")
+	w.WriteString("    \n")
+	w.WriteString("    Each queued call returns its index in the list returned by execute().\n")
+	w.WriteString("    \"\"\"\n")
+	w.WriteString("    \n")
+	w.WriteString("    def __init__(self, queries):\n")
+	w.WriteString("        self._queries = queries\n")
+	w.WriteString("        self._items = []\n")
+	w.WriteString("\n")
+	w.WriteString("    def __len__(self):\n")
+	w.WriteString("        return len(self._items)\n")
+	w.WriteString("\n")
+	w.WriteString("    def __getattr__(self, name):\n")
+	w.WriteString("        if name.startswith('_') or not callable(getattr(self._queries, name)):\n")
+	w.WriteString("            raise AttributeError(f\"'{type(self).__name__}' object has no attribute '{name}'\")\n")
+	w.WriteString("\n")
+	w.WriteString("        def queue(*args, **kwargs):\n")
+	w.WriteString("            self._items.append((name, args, kwargs))\n")
+	w.WriteString("            return len(self._items) - 1\n")
+	w.WriteString("\n")
+	w.WriteString("        return queue\n")
+	w.WriteString("\n")
+
+	if isAsync {
+		w.WriteString("    async def execute(self) -> List[Any]:\n")
+		w.WriteString("        \"\"\"Run all queued calls and return their results in queue order.\"\"\"\n")
+		w.WriteString("        items, self._items = self._items, []\n")
+		if isPostgres {
+			w.WriteString("        db = self._queries.db\n")
+			w.WriteString("        if hasattr(db, 'acquire'):\n")
+			w.WriteString("            async with db.acquire() as conn:\n")
+			w.WriteString("                return await self._run(conn, items)\n")
+			w.WriteString("        return await self._run(db, items)\n")
+			w.WriteString("\n")
+			w.WriteString("    async def _run(self, conn, items) -> List[Any]:\n")
+			w.WriteString("        queries = Queries(conn)\n")
+			w.WriteString("        async with conn.transaction():\n")
+			w.WriteString("            return [await getattr(queries, name)(*args, **kwargs) for name, args, kwargs in items]\n")
+		} else {
+			w.WriteString("        return [await getattr(self._queries, name)(*args, **kwargs) for name, args, kwargs in items]\n")
+		}
+	} else {
+		w.WriteString("    def execute(self) -> List[Any]:\n")
+		w.WriteString("        \"\"\"Run all queued calls and return their results in queue order.\"\"\"\n")
+		w.WriteString("        items, self._items = self._items, []\n")
+		w.WriteString("        return [getattr(self._queries, name)(*args, **kwargs) for name, args, kwargs in items]\n")
+	}
+
+	w.WriteString("\n\n")
+	w.WriteString("def _batch(self) -> Batch:\n")
+	w.WriteString("    \"\"\"Start a new batch of queued query calls.\"\"\"\n")
+	w.WriteString("    return Batch(self)\n")
+	w.WriteString("\n\n")
+	w.WriteString("Queries.batch = _batch\n")
+}
+
+// writeBatchStub emits the Batch declarations for database.pyi.
+func (g *Generator) writeBatchStub(w *strings.Builder) {
+	w.WriteString("\nclass Batch:\n")
+	w.WriteString("    def __len__(self) -> int: ...\n")
+	w.WriteString("    def __getattr__(self, name: str) -> Any: ...\n")
+	if g.Config.Gen.Python.Async {
+		w.WriteString("    async def execute(self) -> List[Any]: ...\n")
+	} else {
+		w.WriteString("    def execute(self) -> List[Any]: ...\n")
+	}
+}
@@ -0,0 +1,161 @@
+package pygen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+)
+
+// encryptionModule is the module holding the helpers of the encrypted
+// columns, shared by the query modules so they use the same key
+const encryptionModule = "encryption"
+
+// generateEncryption writes the encryption module, which encrypts and
+// decrypts columns with AES-256-GCM from the cryptography package. The
+// module is removed when no column is encrypted.
+func (g *Generator) generateEncryption() error {
+	path := filepath.Join(g.Config.Gen.Python.Out, encryptionModule+".py")
+
+	if !g.Config.Encryption.Enabled() {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	keyEnv := g.Config.Encryption.KeyEnvName()
+
+	var w strings.Builder
+	w.WriteString(g.out.Header(encryptionModule+".py") + "\n")
+	w.WriteString("import base64\n")
+	w.WriteString("import os\n")
+	w.WriteString("import threading\n")
+	w.WriteString("from typing import Callable, Optional, Union\n\n")
+	w.WriteString("from cryptography.exceptions import InvalidTag\n")
+	w.WriteString("from cryptography.hazmat.primitives.ciphers.aead import AESGCM\n\n")
+	w.WriteString("# Ciphertext is stored as this prefix and the base64 of nonce || sealed\n")
+	w.WriteString("ENCRYPTION_PREFIX = 'flash:v1:'\n\n")
+	w.WriteString("_lock = threading.Lock()\n")
+	w.WriteString(fmt.Sprintf("_key_fn: Callable[[], Optional[Union[bytes, str]]] = lambda: os.environ.get('%s')\n", keyEnv))
+	w.WriteString("_aead: Optional[AESGCM] = None\n\n\n")
+	w.WriteString("def set_encryption_key(fn: Callable[[], Union[bytes, str]]) -> None:\n")
+	w.WriteString(fmt.Sprintf("    \"\"\"Use the key fn returns for the encrypted columns instead of %s,\n", keyEnv))
+	w.WriteString("    such as a key fetched from a KMS. fn returns the 32 byte key or its base64\n")
+	w.WriteString("    and is called once, the first time a column is encrypted or decrypted.\"\"\"\n")
+	w.WriteString("    global _key_fn, _aead\n")
+	w.WriteString("    with _lock:\n")
+	w.WriteString("        _key_fn = fn\n")
+	w.WriteString("        _aead = None\n\n\n")
+	w.WriteString("def _cipher() -> AESGCM:\n")
+	w.WriteString("    global _aead\n")
+	w.WriteString("    with _lock:\n")
+	w.WriteString("        if _aead is None:\n")
+	w.WriteString("            key = _key_fn()\n")
+	w.WriteString("            if not key:\n")
+	w.WriteString(fmt.Sprintf("                raise RuntimeError(\"%s is not set; set it or call set_encryption_key\")\n", keyEnv))
+	w.WriteString("            if isinstance(key, str):\n")
+	w.WriteString("                try:\n")
+	w.WriteString("                    key = base64.b64decode(key, validate=True)\n")
+	w.WriteString("                except ValueError:\n")
+	w.WriteString("                    raise ValueError(\"encryption key is not base64\") from None\n")
+	w.WriteString("            if len(key) != 32:\n")
+	w.WriteString("                raise ValueError(\"encryption key must be 32 bytes\")\n")
+	w.WriteString("            _aead = AESGCM(bytes(key))\n")
+	w.WriteString("        return _aead\n\n\n")
+	w.WriteString("def encrypt_value(value):\n")
+	w.WriteString("    \"\"\"Encrypt a value written to an encrypted column. None stays None.\"\"\"\n")
+	w.WriteString("    if value is None:\n")
+	w.WriteString("        return None\n")
+	w.WriteString("    nonce = os.urandom(12)\n")
+	w.WriteString("    sealed = _cipher().encrypt(nonce, str(value).encode(), None)\n")
+	w.WriteString("    return ENCRYPTION_PREFIX + base64.b64encode(nonce + sealed).decode()\n\n\n")
+	w.WriteString("def decrypt_value(value):\n")
+	w.WriteString("    \"\"\"Decrypt a value read from an encrypted column. Values written before the\n")
+	w.WriteString("    column was encrypted have no prefix and are returned as they are.\"\"\"\n")
+	w.WriteString("    if not isinstance(value, str) or not value.startswith(ENCRYPTION_PREFIX):\n")
+	w.WriteString("        return value\n")
+	w.WriteString("    aead = _cipher()\n")
+	w.WriteString("    try:\n")
+	w.WriteString("        data = base64.b64decode(value[len(ENCRYPTION_PREFIX):], validate=True)\n")
+	w.WriteString("        return aead.decrypt(data[:12], data[12:], None).decode()\n")
+	w.WriteString("    except (ValueError, InvalidTag):\n")
+	w.WriteString("        raise ValueError(\"failed to decrypt column\") from None\n\n\n")
+	w.WriteString("def decrypt_columns(row: dict, columns) -> dict:\n")
+	w.WriteString("    \"\"\"Decrypt in place the columns of a row\"\"\"\n")
+	w.WriteString("    for column in columns:\n")
+	w.WriteString("        if column in row:\n")
+	w.WriteString("            row[column] = decrypt_value(row[column])\n")
+	w.WriteString("    return row\n")
+
+	return os.WriteFile(path, []byte(w.String()), 0644)
+}
+
+// encryptionImports returns the import of the helpers the queries of a
+// module need, or an empty string
+func (g *Generator) encryptionImports(queries []*parser.Query) string {
+	encrypts, decrypts, decryptsRows := false, false, false
+	for _, query := range queries {
+		for _, param := range query.Params {
+			encrypts = encrypts || param.Encrypted
+		}
+		for _, col := range g.expandWildcardColumns(query) {
+			decrypts = decrypts || col.Encrypted
+		}
+		for _, rel := range query.Includes {
+			decryptsRows = decryptsRows || len(tableEncryptedColumns(rel.Table)) > 0
+		}
+	}
+	var names []string
+	if encrypts {
+		names = append(names, "encrypt_value")
+	}
+	if decrypts {
+		names = append(names, "decrypt_value")
+	}
+	if decryptsRows {
+		names = append(names, "decrypt_columns")
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("from .%s import %s\n", encryptionModule, strings.Join(names, ", "))
+}
+
+func tableEncryptedColumns(table *parser.Table) []string {
+	var names []string
+	for _, col := range table.Columns {
+		if col.Encrypted {
+			names = append(names, col.Name)
+		}
+	}
+	return names
+}
+
+// writeEncryptParams encrypts the params written to encrypted columns
+func writeEncryptParams(w *strings.Builder, params []*parser.Param, paramNames []string) {
+	for i, param := range params {
+		if param.Encrypted {
+			w.WriteString(fmt.Sprintf("        %s = encrypt_value(%s)\n", paramNames[i], paramNames[i]))
+		}
+	}
+}
+
+// columnValue returns expr, decrypted when it reads an encrypted column
+func columnValue(col *parser.QueryColumn, expr string) string {
+	if col.Encrypted {
+		return "decrypt_value(" + expr + ")"
+	}
+	return expr
+}
+
+// modelFromRow returns the expression building a model from a row of its
+// table, decrypting the encrypted columns of the row first
+func (g *Generator) modelFromRow(table *parser.Table, modelName string) string {
+	if columns := tableEncryptedColumns(table); len(columns) > 0 {
+		return fmt.Sprintf("%s(**decrypt_columns(dict(row), ['%s']))", modelName, strings.Join(columns, "', '"))
+	}
+	return modelName + "(**dict(row))"
+}
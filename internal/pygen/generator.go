@@ -13,11 +13,14 @@ import (
 )
 
 type Generator struct {
-	Config       *config.Config
+	Config *config.Config
+	// Force regenerates every file, ignoring the generation cache
+	Force        bool
 	schema       *parser.Schema
 	schemaParser *parser.SchemaParser
 	queryParser  *parser.QueryParser
 	cache        *gencommon.GenerationCache
+	out          *gencommon.Output
 }
 
 func New(cfg *config.Config) *Generator {
@@ -25,30 +28,41 @@ func New(cfg *config.Config) *Generator {
 		Config:       cfg,
 		schemaParser: parser.NewSchemaParser(cfg),
 		queryParser:  parser.NewQueryParser(cfg),
-		cache:        gencommon.NewGenerationCache(),
+		cache:        gencommon.NewGenerationCache(cfg.Gen.Python.Out),
 	}
 }
 
 func (g *Generator) Generate() error {
+	out, err := gencommon.NewOutput(g.Config.Gen, "python", "#")
+	if err != nil {
+		return err
+	}
+	g.out = out
+
 	if err := os.MkdirAll(g.Config.Gen.Python.Out, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	schema, err := g.schemaParser.Parse()
+	// Compute checksums for incremental generation
+	schemaHash, _ := g.cache.ComputeSchemaChecksum(g.Config.SchemaDir)
+	configHash := fmt.Sprintf("%x", []byte(g.Config.Gen.Python.Out+"|"+g.Config.SoftDelete.Column+"|"+strings.Join(g.Config.Timestamps.Columns(), ",")+"|"+g.Config.Gen.OutputOptions()+"|"+g.Config.Encryption.Options()))
+	queryChecksums, err := gencommon.ComputeQueryChecksums(g.Config.Queries)
 	if err != nil {
-		return fmt.Errorf("failed to parse schema: %w", err)
+		return fmt.Errorf("failed to read query files: %w", err)
 	}
-	g.schema = schema
 
-	// Compute checksums for incremental generation
-	schemaHash, _ := g.cache.ComputeSchemaChecksum(g.Config.SchemaDir)
-	configHash := fmt.Sprintf("%x", []byte(g.Config.Gen.Python.Out))
-	fullRegen := g.cache.ShouldRegenerateAll(schemaHash, configHash)
+	if !g.Force && g.cache.UpToDate(schemaHash, configHash, queryChecksums) {
+		gencommon.PrintUpToDateMessage()
+		return nil
+	}
+	fullRegen := g.Force || g.cache.ShouldRegenerateAll(schemaHash, configHash) || !g.cache.OutputsIntact()
 
-	queries, err := g.queryParser.Parse(schema)
+	schema, queries, err := gencommon.ParseInputs(g.schemaParser, g.queryParser)
 	if err != nil {
-		return fmt.Errorf("failed to parse queries: %w", err)
+		return err
 	}
+	g.schema = schema
+
 
 	if fullRegen || g.cache.SchemaChecksum != schemaHash {
 		if err := g.generateModels(schema); err != nil {
@@ -60,7 +74,15 @@ func (g *Generator) Generate() error {
 		return err
 	}
 
+	if err := g.generateEncryption(); err != nil {
+		return fmt.Errorf("failed to generate encryption module: %w", err)
+	}
+
 	if fullRegen || g.cache.SchemaChecksum != schemaHash {
+		if err := g.generateLoaders(queries); err != nil {
+			return err
+		}
+
 		if err := g.generateDatabase(queries); err != nil {
 			return err
 		}
@@ -77,7 +99,9 @@ func (g *Generator) Generate() error {
 
 	g.cache.UpdateSchemaChecksum(schemaHash)
 	g.cache.UpdateConfigChecksum(configHash)
+	g.cache.PruneQueries(queryChecksums)
 	g.cache.MarkGeneration()
+	g.cache.RecordOutputs()
 	g.cache.Save()
 
 	return nil
@@ -88,7 +112,7 @@ func (g *Generator) generateModels(schema *parser.Schema) error {
 	// Pre-allocate: header + ~150 bytes per table
 	estimatedSize := 200 + (len(schema.Tables) * 150)
 	w.Grow(estimatedSize)
-	w.WriteString("# Code generated by FlashORM. DO NOT EDIT.\n\n")
+	w.WriteString(g.out.Header("models.py") + "\n")
 	w.WriteString("from dataclasses import dataclass\n")
 	w.WriteString("from typing import Optional, Literal\n")
 	w.WriteString("from datetime import datetime\n")
@@ -96,7 +120,7 @@ func (g *Generator) generateModels(schema *parser.Schema) error {
 
 	for _, table := range schema.Tables {
 		w.WriteString("@dataclass\n")
-		w.WriteString(fmt.Sprintf("class %s:\n", utils.Capitalize(table.Name)))
+		w.WriteString(fmt.Sprintf("class %s:\n", g.out.ModelName(table.Name, utils.Capitalize)))
 		for _, col := range table.Columns {
 			pyType := g.sqlTypeToPython(col.Type, col.Nullable)
 			w.WriteString(fmt.Sprintf("    %s: %s\n", col.Name, pyType))
@@ -118,7 +142,8 @@ func (g *Generator) generateQueries(queries []*parser.Query) error {
 		queryGroups[sourceFile] = append(queryGroups[sourceFile], query)
 	}
 
-	for sourceFile, fileQueries := range queryGroups {
+	for _, sourceFile := range gencommon.SortedSourceFiles(queryGroups) {
+		fileQueries := queryGroups[sourceFile]
 		var w strings.Builder
 		// Pre-allocate: header + ~400 bytes per query
 		estimatedSize := 200 + (len(fileQueries) * 400)
@@ -130,6 +155,7 @@ func (g *Generator) generateQueries(queries []*parser.Query) error {
 		w.WriteString("from decimal import Decimal\n")
 		
 		w.WriteString("\n")
+		g.writeCursorHelpers(&w, fileQueries)
 
 		for _, query := range fileQueries {
 			if g.needsResultClass(query) {
@@ -144,6 +170,11 @@ func (g *Generator) generateQueries(queries []*parser.Query) error {
 
 		for _, query := range fileQueries {
 			g.generateQueryMethod(&w, query)
+			if query.Paginate != nil {
+				if err := g.generatePageMethod(&w, query); err != nil {
+					return err
+				}
+			}
 			if query.Cmd == ":exec" && strings.Contains(strings.ToUpper(query.SQL), "INSERT INTO") {
 				g.generateBatchMethod(&w, query)
 			}
@@ -161,7 +192,7 @@ func (g *Generator) generateQueries(queries []*parser.Query) error {
 }
 
 func (g *Generator) generateQueryMethod(w *strings.Builder, query *parser.Query) {
-	methodName := utils.ToSnakeCase(query.Name)
+	methodName := g.out.FunctionName(config.NamingSnake, query.Name, "")
 	sql := g.convertSQL(query.SQL)
 	sql = strings.ReplaceAll(sql, "\"", "\\\"")
 
@@ -193,6 +224,8 @@ func (g *Generator) generateQueryMethod(w *strings.Builder, query *parser.Query)
 			methodName, paramStr, returnType))
 	}
 	
+	writeEncryptParams(w, query.Params, paramNames)
+
 	// Use statement caching for better performance
 	w.WriteString(fmt.Sprintf("        _key = '%s'\n", methodName))
 	w.WriteString("        if _key not in self._stmts:\n")
@@ -200,10 +233,12 @@ func (g *Generator) generateQueryMethod(w *strings.Builder, query *parser.Query)
 	w.WriteString("        stmt = self._stmts[_key]\n")
 
 	provider := g.Config.Database.Provider
-	switch provider {
-	case "sqlite", "sqlite3":
+	switch {
+	case len(query.Includes) > 0:
+		g.generateIncludeExecution(w, paramNames, query)
+	case provider == "sqlite" || provider == "sqlite3":
 		g.generateSQLiteExecution(w, paramNames, query)
-	case "mysql":
+	case provider == "mysql":
 		g.generateMySQLExecution(w, paramNames, query)
 	default:
 		g.generatePostgreSQLExecution(w, paramNames, query)
@@ -256,7 +291,7 @@ func (g *Generator) generatePostgreSQLExecution(w *strings.Builder, paramNames [
 		if query.Cmd == ":one" {
 			if isSingleNonWildcard {
 				// Direct column access - fastest path
-				w.WriteString(fmt.Sprintf("        return result[0]['%s'] if result else None\n", query.Columns[0].Name))
+				w.WriteString(fmt.Sprintf("        return %s if result else None\n", columnValue(query.Columns[0], fmt.Sprintf("result[0]['%s']", query.Columns[0].Name))))
 			} else if needsResultClass {
 				className := utils.ToPascalCase(query.Name) + "Row"
 				// asyncpg Records support key access directly - use _make_fast
@@ -268,7 +303,7 @@ func (g *Generator) generatePostgreSQLExecution(w *strings.Builder, paramNames [
 		} else {
 			if isSingleNonWildcard {
 				// Direct column access in list comprehension
-				w.WriteString(fmt.Sprintf("        return [row['%s'] for row in result]\n", query.Columns[0].Name))
+				w.WriteString(fmt.Sprintf("        return [%s for row in result]\n", columnValue(query.Columns[0], fmt.Sprintf("row['%s']", query.Columns[0].Name))))
 			} else if needsResultClass {
 				className := utils.ToPascalCase(query.Name) + "Row"
 				w.WriteString(fmt.Sprintf("        return [%s._make_fast(row) for row in result]\n", className))
@@ -332,7 +367,7 @@ func (g *Generator) generateMySQLExecution(w *strings.Builder, paramNames []stri
 		}
 		
 		if isSingleNonWildcard {
-			w.WriteString(fmt.Sprintf("%s    return result['%s'] if result else None\n", indent, query.Columns[0].Name))
+			w.WriteString(fmt.Sprintf("%s    return %s if result else None\n", indent, columnValue(query.Columns[0], fmt.Sprintf("result['%s']", query.Columns[0].Name))))
 		} else {
 			w.WriteString(fmt.Sprintf("%s    if result:\n", indent))
 			// Convert to dict if needed (works with any cursor type)
@@ -352,7 +387,7 @@ func (g *Generator) generateMySQLExecution(w *strings.Builder, paramNames []stri
 		}
 		
 		if isSingleNonWildcard {
-			w.WriteString(fmt.Sprintf("%s    return [row['%s'] for row in result]\n", indent, query.Columns[0].Name))
+			w.WriteString(fmt.Sprintf("%s    return [%s for row in result]\n", indent, columnValue(query.Columns[0], fmt.Sprintf("row['%s']", query.Columns[0].Name))))
 		} else {
 			w.WriteString(fmt.Sprintf("%s    rows = [dict(row) if hasattr(row, 'keys') else {cursor.description[i][0]: row[i] for i in range(len(row))} for row in result]\n", indent))
 			if needsResultClass {
@@ -406,7 +441,7 @@ func (g *Generator) generateSQLiteExecution(w *strings.Builder, paramNames []str
 			w.WriteString("        result = cursor.fetchone()\n")
 		}
 		if isSingleNonWildcard {
-			w.WriteString(fmt.Sprintf("%sreturn result['%s'] if result else None\n", indent, query.Columns[0].Name))
+			w.WriteString(fmt.Sprintf("%sreturn %s if result else None\n", indent, columnValue(query.Columns[0], fmt.Sprintf("result['%s']", query.Columns[0].Name))))
 		} else {
 			w.WriteString(fmt.Sprintf("%sif result:\n", indent))
 			if needsResultClass {
@@ -424,7 +459,7 @@ func (g *Generator) generateSQLiteExecution(w *strings.Builder, paramNames []str
 			w.WriteString("        result = cursor.fetchall()\n")
 		}
 		if isSingleNonWildcard {
-			w.WriteString(fmt.Sprintf("%sreturn [row['%s'] for row in result]\n", indent, query.Columns[0].Name))
+			w.WriteString(fmt.Sprintf("%sreturn [%s for row in result]\n", indent, columnValue(query.Columns[0], fmt.Sprintf("row['%s']", query.Columns[0].Name))))
 		} else if needsResultClass {
 			w.WriteString(fmt.Sprintf("%sreturn [%s._make_fast({k: row[k] for k in row.keys()}) for row in result]\n", indent, returnType))
 		} else {
@@ -440,28 +475,22 @@ func (g *Generator) generateSQLiteExecution(w *strings.Builder, paramNames []str
 }
 
 func (g *Generator) generateDatabase(queries []*parser.Query) error {
-	// Get unique source files
-	sourceFiles := make(map[string]bool)
+	// One module per generated query file
 	filesList := []string{}
-	for _, query := range queries {
-		sourceFile := query.SourceFile
-		if sourceFile == "" {
-			sourceFile = "queries"
-		}
-		baseName := strings.TrimSuffix(sourceFile, ".sql")
-		if !sourceFiles[baseName] {
-			sourceFiles[baseName] = true
-			filesList = append(filesList, baseName)
-		}
+	for _, group := range g.out.GroupQueries(queries) {
+		filesList = append(filesList, group.Name)
+	}
+	if tables, _ := g.loaderTables(queries); len(tables) > 0 {
+		filesList = append(filesList, loadersModule)
 	}
 
 	var w strings.Builder
 	w.Grow(512) // Pre-allocate for index file
-	w.WriteString("# Code generated by FlashORM. DO NOT EDIT.\n\n")
-	w.WriteString("from typing import Any\n")
+	w.WriteString(g.out.Header("database.py") + "\n")
+	w.WriteString("from typing import Any, List\n")
 	
 	if len(filesList) == 1 {
-		w.WriteString(fmt.Sprintf("from .%s import Queries\n\n", filesList[0]))
+		w.WriteString(fmt.Sprintf("from .%s import Queries\n", filesList[0]))
 	} else {
 		for _, baseName := range filesList {
 			w.WriteString(fmt.Sprintf("from .%s import Queries as %sQueries\n",
@@ -500,7 +529,9 @@ func (g *Generator) generateDatabase(queries []*parser.Query) error {
 		w.WriteString("        raise AttributeError(f\"'{type(self).__name__}' object has no attribute '{name}'\")\n")
 	}
 
-	w.WriteString("\ndef new(db: Any) -> Queries:\n")
+	g.writeBatchClass(&w)
+
+	w.WriteString("\n\ndef new(db: Any) -> Queries:\n")
 	w.WriteString("    \"\"\"\n")
 	w.WriteString("    Create a new database client.\n")
 	w.WriteString("    \n")
@@ -519,9 +550,12 @@ func (g *Generator) generateDatabase(queries []*parser.Query) error {
 func (g *Generator) generateInit() error {
 	var w strings.Builder
 	w.Grow(256) // Pre-allocate small buffer
-	w.WriteString("# Code generated by FlashORM. DO NOT EDIT.\n\n")
+	w.WriteString(g.out.Header("__init__.py") + "\n")
 	w.WriteString("from .database import new\n")
 	w.WriteString("from .models import *\n")
+	if g.Config.Encryption.Enabled() {
+		w.WriteString(fmt.Sprintf("from .%s import set_encryption_key\n", encryptionModule))
+	}
 
 	path := filepath.Join(g.Config.Gen.Python.Out, "__init__.py")
 	return os.WriteFile(path, []byte(w.String()), 0644)
@@ -608,28 +642,19 @@ func (g *Generator) generateTypingStub(queries []*parser.Query) error {
 	w.Grow(1024)
 
 	w.WriteString("# Type stub for IDE autocomplete\n")
-	w.WriteString("# Code generated by FlashORM. DO NOT EDIT.\n\n")
+	w.WriteString(g.out.Header("database.pyi") + "\n")
 	w.WriteString("from typing import Any, Optional, List, Literal\n")
 	w.WriteString("from datetime import datetime\n")
 	w.WriteString("from decimal import Decimal\n")
 
-	// Import all result classes from all query files
-	queryGroups := make(map[string][]*parser.Query)
-	for _, query := range queries {
-		sourceFile := query.SourceFile
-		if sourceFile == "" {
-			sourceFile = "queries"
-		}
-		queryGroups[sourceFile] = append(queryGroups[sourceFile], query)
-	}
-	
+	// Import all result classes from all query modules
 	// Collect all result class names for imports - use map to avoid duplicates
 	allResultClasses := make(map[string]bool)
-	for sourceFile, fileQueries := range queryGroups {
-		baseName := strings.TrimSuffix(sourceFile, ".sql")
+	for _, group := range g.out.GroupQueries(queries) {
+		baseName := group.Name
 		var resultClasses []string
 		
-		for _, query := range fileQueries {
+		for _, query := range group.Queries {
 			if g.needsResultClass(query) {
 				// Match the actual generated class name format
 				className := utils.ToPascalCase(query.Name) + "Row"
@@ -642,14 +667,24 @@ func (g *Generator) generateTypingStub(queries []*parser.Query) error {
 			w.WriteString(fmt.Sprintf("from .%s import %s\n", baseName, strings.Join(resultClasses, ", ")))
 		}
 	}
+	if tables, _ := g.loaderTables(queries); len(tables) > 0 {
+		models := make([]string, len(tables))
+		for i, table := range tables {
+			models[i] = g.out.ModelName(table.Name, utils.Capitalize)
+		}
+		w.WriteString(fmt.Sprintf("from .models import %s\n", strings.Join(models, ", ")))
+	}
 	
+	g.writeBatchStub(&w)
+
 	w.WriteString("\nclass Queries:\n")
-	w.WriteString("    def __init__(self, db: Any) -> None: ...\n\n")
+	w.WriteString("    def __init__(self, db: Any) -> None: ...\n")
+	w.WriteString("    def batch(self) -> Batch: ...\n\n")
 	
 	// Generate method signatures for all queries
 	seenMethods := make(map[string]bool)
 	for _, query := range queries {
-		methodName := utils.ToSnakeCase(query.Name)
+		methodName := g.out.FunctionName(config.NamingSnake, query.Name, "")
 		
 		if seenMethods[methodName] {
 			continue
@@ -683,7 +718,19 @@ func (g *Generator) generateTypingStub(queries []*parser.Query) error {
 			w.WriteString(fmt.Sprintf("    def %s(self%s) -> %s: ...\n",
 				methodName, paramStr, returnType))
 		}
+
+		if query.Paginate != nil {
+			pageParams := append(paramTypes, "cursor: Optional[str]", "limit: int")
+			if g.Config.Gen.Python.Async {
+				w.WriteString(fmt.Sprintf("    async def %s(self, %s) -> dict: ...\n",
+					g.out.FunctionName(config.NamingSnake, query.Name, "page"), strings.Join(pageParams, ", ")))
+			} else {
+				w.WriteString(fmt.Sprintf("    def %s(self, %s) -> dict: ...\n",
+					g.out.FunctionName(config.NamingSnake, query.Name, "page"), strings.Join(pageParams, ", ")))
+			}
+		}
 	}
+	g.writeLoaderStubs(&w, queries)
 	
 	// Add new() function signature
 	w.WriteString("\ndef new(db: Any) -> Queries: ...\n")
@@ -718,6 +765,7 @@ func (g *Generator) generateResultClass(w *strings.Builder, query *parser.Query)
 		pyType := g.sqlTypeToPython(col.Type, col.Nullable)
 		w.WriteString(fmt.Sprintf("    %s: %s\n", utils.ToSnakeCase(col.Name), pyType))
 	}
+	g.writeRelationFields(w, query)
 
 	// Add optimized factory method for faster Record -> dataclass conversion
 	w.WriteString("\n")
@@ -725,11 +773,11 @@ func (g *Generator) generateResultClass(w *strings.Builder, query *parser.Query)
 	w.WriteString("    def _make_fast(cls, record):\n")
 	w.WriteString("        return cls(\n")
 	for i, col := range columns {
-		colName := utils.ToSnakeCase(col.Name)
+		value := columnValue(col, fmt.Sprintf("record['%s']", utils.ToSnakeCase(col.Name)))
 		if i < len(columns)-1 {
-			w.WriteString(fmt.Sprintf("            record['%s'],\n", colName))
+			w.WriteString(fmt.Sprintf("            %s,\n", value))
 		} else {
-			w.WriteString(fmt.Sprintf("            record['%s']\n", colName))
+			w.WriteString(fmt.Sprintf("            %s\n", value))
 		}
 	}
 	w.WriteString("        )\n")
@@ -765,10 +813,11 @@ func (g *Generator) expandWildcardColumns(query *parser.Query) []*parser.QueryCo
 	expanded := make([]*parser.QueryColumn, 0, len(table.Columns))
 	for _, col := range table.Columns {
 		expanded = append(expanded, &parser.QueryColumn{
-			Name:     col.Name,
-			Type:     col.Type,
-			Table:    table.Name,
-			Nullable: col.Nullable,
+			Name:      col.Name,
+			Type:      col.Type,
+			Table:     table.Name,
+			Nullable:  col.Nullable,
+			Encrypted: col.Encrypted,
 		})
 	}
 
@@ -14,54 +14,39 @@ import (
 
 // generateQueriesIncremental generates Python queries with incremental support and parallel code generation
 func (g *Generator) generateQueriesIncremental(queries []*parser.Query, fullRegen bool) error {
-	queryGroups := make(map[string][]*parser.Query)
-	for _, query := range queries {
-		sourceFile := query.SourceFile
-		if sourceFile == "" {
-			sourceFile = "queries"
-		}
-		queryGroups[sourceFile] = append(queryGroups[sourceFile], query)
-	}
-
-	type fileGroup struct {
-		sourceFile string
-		queries    []*parser.Query
-	}
-	
-	fileGroups := make([]fileGroup, 0, len(queryGroups))
-	for sourceFile, fileQueries := range queryGroups {
-		fileGroups = append(fileGroups, fileGroup{sourceFile, fileQueries})
-	}
-
-	usedNames := make(map[string]int)
-	var usedNamesMu sync.Mutex
+	groups := g.out.GroupQueries(queries)
 
 	numWorkers := runtime.NumCPU()
-	if numWorkers > len(fileGroups) {
-		numWorkers = len(fileGroups)
+	if numWorkers > len(groups) {
+		numWorkers = len(groups)
 	}
 
 	type generateResult struct {
 		err error
 	}
 
-	workChan := make(chan fileGroup, len(fileGroups))
-	resultChan := make(chan generateResult, len(fileGroups))
+	workChan := make(chan gencommon.QueryGroup, len(groups))
+	resultChan := make(chan generateResult, len(groups))
 
 	var wg sync.WaitGroup
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for fg := range workChan {
-				err := g.generateSinglePyFile(fg.sourceFile, fg.queries, fullRegen, &usedNamesMu, usedNames)
+			for group := range workChan {
+				err := g.generateSinglePyFile(group, fullRegen)
 				resultChan <- generateResult{err: err}
 			}
 		}()
 	}
 
-	for _, fg := range fileGroups {
-		workChan <- fg
+	outputs := make([]string, 0, len(groups))
+	for _, group := range groups {
+		workChan <- group
+		outputs = append(outputs, g.queryOutputPath(group))
+	}
+	if tables, _ := g.loaderTables(queries); len(tables) > 0 {
+		outputs = append(outputs, filepath.Join(g.Config.Gen.Python.Out, loadersModule+".py"))
 	}
 	close(workChan)
 
@@ -76,30 +61,42 @@ func (g *Generator) generateQueriesIncremental(queries []*parser.Query, fullRege
 		}
 	}
 
-	return nil
+	return g.cache.SetQueryOutputs(outputs)
 }
 
-func (g *Generator) generateSinglePyFile(sourceFile string, fileQueries []*parser.Query, fullRegen bool, usedNamesMu *sync.Mutex, usedNames map[string]int) error {
-	queryFile := filepath.Join(g.Config.Queries, sourceFile+".sql")
-	currentHash, _ := gencommon.ComputeFileChecksum(queryFile)
+func (g *Generator) queryOutputPath(group gencommon.QueryGroup) string {
+	return filepath.Join(g.Config.Gen.Python.Out, group.Name+".py")
+}
 
-	if !gencommon.ShouldRegenerateFile(g.cache, queryFile, currentHash, fullRegen) {
-		gencommon.PrintSkipMessage(sourceFile, ".py")
+func (g *Generator) generateSinglePyFile(group gencommon.QueryGroup, fullRegen bool) error {
+	if !gencommon.ShouldRegenerateGroup(g.cache, g.Config.Queries, group, fullRegen) {
+		gencommon.PrintSkipMessage(group.Name, ".py")
 		return nil
 	}
 
-	gencommon.PrintGenerateMessage(sourceFile, ".py")
+	gencommon.PrintGenerateMessage(group.Name, ".py")
 	
 	w := gencommon.GetBuilder()
 	defer gencommon.PutBuilder(w)
 
-	w.WriteString("# Code generated by FlashORM. DO NOT EDIT.\n\n")
+	path := g.queryOutputPath(group)
+	w.WriteString(g.out.Header(filepath.Base(path)) + "\n")
 	w.WriteString("from typing import Optional, List, Any, Literal\n")
-	w.WriteString("from dataclasses import dataclass\n")
+	if gencommon.HasIncludes(group.Queries) {
+		w.WriteString("from dataclasses import dataclass, field\n")
+	} else {
+		w.WriteString("from dataclasses import dataclass\n")
+	}
 	w.WriteString("from datetime import datetime\n")
-	w.WriteString("from decimal import Decimal\n\n")
+	w.WriteString("from decimal import Decimal\n")
+	if models := g.relationModels(group.Queries); len(models) > 0 {
+		w.WriteString(fmt.Sprintf("from .models import %s\n", strings.Join(models, ", ")))
+	}
+	w.WriteString(g.encryptionImports(group.Queries))
+	w.WriteString("\n")
+	g.writeCursorHelpers(w, group.Queries)
 
-	for _, query := range fileQueries {
+	for _, query := range group.Queries {
 		if g.needsResultClass(query) {
 			g.generateResultClass(w, query)
 		}
@@ -110,29 +107,23 @@ func (g *Generator) generateSinglePyFile(sourceFile string, fileQueries []*parse
 	w.WriteString("        self.db = db\n")
 	w.WriteString("        self._stmts = {}\n\n")
 
-	for _, query := range fileQueries {
+	for _, query := range group.Queries {
 		g.generateQueryMethod(w, query)
+		if len(query.Includes) > 0 {
+			g.generateIncludeMethod(w, query)
+		}
+		if query.Paginate != nil {
+			if err := g.generatePageMethod(w, query); err != nil {
+				return err
+			}
+		}
 	}
 
-	baseName := strings.TrimSuffix(sourceFile, ".sql")
-
-	usedNamesMu.Lock()
-	outputFile := baseName + ".py"
-	if count, exists := usedNames[baseName]; exists {
-		usedNames[baseName] = count + 1
-		outputFile = fmt.Sprintf("%s_%d.py", baseName, count+1)
-	} else {
-		usedNames[baseName] = 1
-	}
-	usedNamesMu.Unlock()
-
-	path := filepath.Join(g.Config.Gen.Python.Out, outputFile)
 	if err := os.WriteFile(path, []byte(w.String()), 0644); err != nil {
 		return err
 	}
 
-	tableDeps := gencommon.ExtractTableDependencies(fileQueries)
-	gencommon.UpdateCacheForFile(g.cache, queryFile, currentHash, tableDeps, path)
+	gencommon.UpdateCacheForGroup(g.cache, g.Config.Queries, group, path)
 
 	return nil
 }
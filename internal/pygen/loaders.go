@@ -0,0 +1,131 @@
+package pygen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/gencommon"
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+	"github.com/Lumos-Labs-HQ/flash/internal/utils"
+)
+
+// loadersModule is the module holding the batch loaders of the tables
+const loadersModule = "loaders"
+
+// loaderTables returns the tables that get a get_x_by_ids batch loader and
+// their primary keys. Tables whose loader name is taken by a query are left
+// out, and there are none when a query file generates the loaders module.
+func (g *Generator) loaderTables(queries []*parser.Query) ([]*parser.Table, []*parser.Column) {
+	for _, group := range g.out.GroupQueries(queries) {
+		if group.Name == loadersModule {
+			return nil, nil
+		}
+	}
+
+	taken := make(map[string]bool, len(queries))
+	for _, query := range queries {
+		taken[g.out.FunctionName(config.NamingSnake, query.Name, "")] = true
+	}
+
+	tables, keys := gencommon.LoaderTables(g.schema)
+	var loaderTables []*parser.Table
+	var loaderKeys []*parser.Column
+	for i, table := range tables {
+		if !taken[g.loaderName(table)] {
+			loaderTables = append(loaderTables, table)
+			loaderKeys = append(loaderKeys, keys[i])
+		}
+	}
+	return loaderTables, loaderKeys
+}
+
+func (g *Generator) loaderName(table *parser.Table) string {
+	return g.out.FunctionName(config.NamingSnake, "Get"+g.out.ModelName(table.Name, utils.Capitalize)+"ByIds", "")
+}
+
+// generateLoaders writes the loaders module, whose Queries class has the
+// batch loader of each table with a primary key. A loader takes a list of
+// keys and returns their rows in the same order, None for missing ones,
+// which is what a DataLoader batch function returns.
+func (g *Generator) generateLoaders(queries []*parser.Query) error {
+	tables, keys := g.loaderTables(queries)
+	if len(tables) == 0 {
+		return nil
+	}
+
+	models := make([]string, len(tables))
+	for i, table := range tables {
+		models[i] = g.out.ModelName(table.Name, utils.Capitalize)
+	}
+
+	var w strings.Builder
+	w.WriteString(g.out.Header(loadersModule+".py") + "\n")
+	w.WriteString("from typing import Optional, List, Any, Literal\n")
+	w.WriteString("from datetime import datetime\n")
+	w.WriteString("from decimal import Decimal\n")
+	w.WriteString(fmt.Sprintf("from .models import %s\n", strings.Join(models, ", ")))
+	for _, table := range tables {
+		if len(tableEncryptedColumns(table)) > 0 {
+			w.WriteString(fmt.Sprintf("from .%s import decrypt_columns\n", encryptionModule))
+			break
+		}
+	}
+	w.WriteString("\n")
+	w.WriteString("class Queries:\n")
+	w.WriteString("    def __init__(self, db):\n")
+	w.WriteString("        self.db = db\n")
+
+	for i, table := range tables {
+		key := keys[i]
+		modelName := models[i]
+		signature := fmt.Sprintf("def %s(self, ids: List[%s]) -> List[Optional[%s]]:\n",
+			g.loaderName(table), g.sqlTypeToPython(key.Type, false), modelName)
+
+		w.WriteString("\n")
+		if g.Config.Gen.Python.Async {
+			w.WriteString("    async " + signature)
+		} else {
+			w.WriteString("    " + signature)
+		}
+		w.WriteString(fmt.Sprintf("        \"\"\"Load the %s rows of ids, in the order of ids. Ids without a row give None.\"\"\"\n", table.Name))
+		w.WriteString("        if not ids:\n")
+		w.WriteString("            return []\n")
+		switch g.Config.Database.Provider {
+		case "sqlite", "sqlite3":
+			sql := gencommon.LoaderQuery(table, key, g.Config.SoftDelete.Column, false)
+			w.WriteString(fmt.Sprintf("        stmt = \"%s\" + \", \".join([\"?\"] * len(ids)) + \")\"\n", sql))
+			w.WriteString("        args = list(ids)\n")
+		case "mysql":
+			sql := gencommon.LoaderQuery(table, key, g.Config.SoftDelete.Column, false)
+			w.WriteString(fmt.Sprintf("        stmt = \"%s\" + \", \".join([\"%%s\"] * len(ids)) + \")\"\n", sql))
+			w.WriteString("        args = list(ids)\n")
+		default:
+			w.WriteString(fmt.Sprintf("        stmt = \"%s\"\n", gencommon.LoaderQuery(table, key, g.Config.SoftDelete.Column, true)))
+			w.WriteString("        args = [list(ids)]\n")
+		}
+		g.writeFetchRows(&w, "        ", "rows")
+		w.WriteString(fmt.Sprintf("        by_id = {row['%s']: %s for row in rows}\n", key.Name, g.modelFromRow(table, modelName)))
+		w.WriteString("        return [by_id.get(id) for id in ids]\n")
+	}
+
+	path := filepath.Join(g.Config.Gen.Python.Out, loadersModule+".py")
+	return os.WriteFile(path, []byte(w.String()), 0644)
+}
+
+// writeLoaderStubs emits the signatures of the batch loaders for
+// database.pyi
+func (g *Generator) writeLoaderStubs(w *strings.Builder, queries []*parser.Query) {
+	tables, keys := g.loaderTables(queries)
+	for i, table := range tables {
+		signature := fmt.Sprintf("def %s(self, ids: List[%s]) -> List[Optional[%s]]: ...\n",
+			g.loaderName(table), g.sqlTypeToPython(keys[i].Type, false), g.out.ModelName(table.Name, utils.Capitalize))
+		if g.Config.Gen.Python.Async {
+			w.WriteString("    async " + signature)
+		} else {
+			w.WriteString("    " + signature)
+		}
+	}
+}
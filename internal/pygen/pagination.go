@@ -0,0 +1,135 @@
+package pygen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/gencommon"
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+	"github.com/Lumos-Labs-HQ/flash/internal/utils"
+)
+
+// writeCursorHelpers emits the cursor encoding helpers for modules that
+// contain paginated queries. Cursors are base64url-encoded JSON lists.
+func (g *Generator) writeCursorHelpers(w *strings.Builder, queries []*parser.Query) {
+	needed := false
+	for _, query := range queries {
+		if query.Paginate != nil {
+			needed = true
+			break
+		}
+	}
+	if !needed {
+		return
+	}
+
+	w.WriteString("import base64\n")
+	w.WriteString("import json\n\n\n")
+	w.WriteString("def _cursor_default(value):\n")
+	w.WriteString("    return value.isoformat() if hasattr(value, 'isoformat') else str(value)\n\n\n")
+	w.WriteString("def _encode_cursor(values) -> str:\n")
+	w.WriteString("    data = json.dumps(values, default=_cursor_default).encode()\n")
+	w.WriteString("    return base64.urlsafe_b64encode(data).decode().rstrip('=')\n\n\n")
+	w.WriteString("def _decode_cursor(cursor: str, converters) -> list:\n")
+	w.WriteString("    try:\n")
+	w.WriteString("        values = json.loads(base64.urlsafe_b64decode(cursor + '=' * (-len(cursor) % 4)))\n")
+	w.WriteString("    except ValueError:\n")
+	w.WriteString("        values = None\n")
+	w.WriteString("    if not isinstance(values, list) or len(values) != len(converters):\n")
+	w.WriteString("        raise ValueError(\"invalid pagination cursor\")\n")
+	w.WriteString("    return [conv(v) if conv and v is not None else v for conv, v in zip(converters, values)]\n\n\n")
+}
+
+// generatePageMethod writes `<name>_page(..., cursor, limit)` returning
+// {"rows": ..., "next_cursor": ...}. It fetches limit+1 rows to detect a
+// following page.
+func (g *Generator) generatePageMethod(w *strings.Builder, query *parser.Query) error {
+	columns := g.expandWildcardColumns(query)
+	indexes, err := gencommon.CursorColumnIndexes(query, columns)
+	if err != nil {
+		return err
+	}
+
+	methodName := g.out.FunctionName(config.NamingSnake, query.Name, "page")
+	firstSQL, nextSQL := gencommon.CursorQueries(query, g.Config.Database.Provider)
+	escape := func(sql string) string {
+		return strings.ReplaceAll(g.convertSQL(sql), "\"", "\\\"")
+	}
+
+	paramNames := make([]string, len(query.Params))
+	paramTypes := make([]string, len(query.Params))
+	for i, param := range query.Params {
+		paramName := param.Name
+		if paramName == "" {
+			paramName = fmt.Sprintf("p%d", i+1)
+		}
+		paramNames[i] = paramName
+		paramTypes[i] = fmt.Sprintf("%s: %s", paramName, g.sqlTypeToPython(param.Type, false))
+	}
+	paramTypes = append(paramTypes, "cursor: Optional[str]", "limit: int")
+
+	isAsync := g.Config.Gen.Python.Async
+	if isAsync {
+		w.WriteString(fmt.Sprintf("    async def %s(self, %s) -> dict:\n", methodName, strings.Join(paramTypes, ", ")))
+	} else {
+		w.WriteString(fmt.Sprintf("    def %s(self, %s) -> dict:\n", methodName, strings.Join(paramTypes, ", ")))
+	}
+	w.WriteString(fmt.Sprintf("        \"\"\"Page through %s ordered by %s. Pass cursor=None for the first page.\"\"\"\n",
+		query.Name, strings.Join(query.Paginate.Columns, ", ")))
+	w.WriteString("        if limit < 1:\n")
+	w.WriteString("            raise ValueError(\"page limit must be at least 1\")\n")
+	w.WriteString(fmt.Sprintf("        stmt = \"\"\"%s\"\"\"\n", escape(firstSQL)))
+	w.WriteString(fmt.Sprintf("        args = [%s]\n", strings.Join(paramNames, ", ")))
+	w.WriteString("        if cursor:\n")
+	w.WriteString(fmt.Sprintf("            stmt = \"\"\"%s\"\"\"\n", escape(nextSQL)))
+
+	converters := make([]string, len(indexes))
+	for i, idx := range indexes {
+		switch g.sqlTypeToPython(columns[idx].Type, false) {
+		case "datetime":
+			converters[i] = "datetime.fromisoformat"
+		case "Decimal":
+			converters[i] = "Decimal"
+		default:
+			converters[i] = "None"
+		}
+	}
+	w.WriteString(fmt.Sprintf("            args.extend(_decode_cursor(cursor, [%s]))\n", strings.Join(converters, ", ")))
+	w.WriteString("        args.append(limit + 1)\n")
+
+	g.writeFetchRows(w, "        ", "rows")
+
+	lastValues := make([]string, len(indexes))
+	for i, idx := range indexes {
+		lastValues[i] = fmt.Sprintf("last['%s']", columns[idx].Name)
+	}
+	w.WriteString("        next_cursor = None\n")
+	w.WriteString("        if len(rows) > limit:\n")
+	w.WriteString("            rows = rows[:limit]\n")
+	w.WriteString("            last = rows[-1]\n")
+	w.WriteString(fmt.Sprintf("            next_cursor = _encode_cursor([%s])\n", strings.Join(lastValues, ", ")))
+
+	switch {
+	case len(query.Columns) == 1 && query.Columns[0].Name != "*":
+		value := columnValue(query.Columns[0], fmt.Sprintf("row['%s']", query.Columns[0].Name))
+		w.WriteString(fmt.Sprintf("        return {\"rows\": [%s for row in rows], \"next_cursor\": next_cursor}\n", value))
+	case len(query.Includes) > 0:
+		className := utils.ToPascalCase(query.Name) + "Row"
+		w.WriteString(fmt.Sprintf("        items = [%s._make_fast(row) for row in rows]\n", className))
+		if g.Config.Gen.Python.Async {
+			w.WriteString(fmt.Sprintf("        await self.%s(items)\n", g.includeMethodName(query)))
+		} else {
+			w.WriteString(fmt.Sprintf("        self.%s(items)\n", g.includeMethodName(query)))
+		}
+		w.WriteString("        return {\"rows\": items, \"next_cursor\": next_cursor}\n")
+	case g.needsResultClass(query):
+		className := utils.ToPascalCase(query.Name) + "Row"
+		w.WriteString(fmt.Sprintf("        return {\"rows\": [%s._make_fast(row) for row in rows], \"next_cursor\": next_cursor}\n", className))
+	default:
+		w.WriteString("        return {\"rows\": list(rows), \"next_cursor\": next_cursor}\n")
+	}
+	w.WriteString("\n")
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package pygen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/gencommon"
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+	"github.com/Lumos-Labs-HQ/flash/internal/utils"
+)
+
+// relationModels returns the model classes a module with included relations
+// imports from models.py, sorted
+func (g *Generator) relationModels(queries []*parser.Query) []string {
+	seen := make(map[string]bool)
+	var models []string
+	for _, query := range queries {
+		for _, rel := range query.Includes {
+			name := g.out.ModelName(rel.Table.Name, utils.Capitalize)
+			if !seen[name] {
+				seen[name] = true
+				models = append(models, name)
+			}
+		}
+	}
+	sort.Strings(models)
+	return models
+}
+
+// writeRelationFields adds the fields holding the included relations to a
+// result class. They default to None or an empty list until loaded.
+func (g *Generator) writeRelationFields(w *strings.Builder, query *parser.Query) {
+	for _, rel := range query.Includes {
+		modelName := g.out.ModelName(rel.Table.Name, utils.Capitalize)
+		if rel.Many {
+			w.WriteString(fmt.Sprintf("    %s: List[%s] = field(default_factory=list)\n", utils.ToSnakeCase(rel.Name), modelName))
+		} else {
+			w.WriteString(fmt.Sprintf("    %s: Optional[%s] = None\n", utils.ToSnakeCase(rel.Name), modelName))
+		}
+	}
+}
+
+func (g *Generator) includeMethodName(query *parser.Query) string {
+	return "_include_" + g.out.FunctionName(config.NamingSnake, query.Name, "")
+}
+
+// generateIncludeMethod writes _include_x, which loads the relations of a
+// query with one query per relation and attaches them to its rows.
+func (g *Generator) generateIncludeMethod(w *strings.Builder, query *parser.Query) {
+	className := utils.ToPascalCase(query.Name) + "Row"
+	isAsync := g.Config.Gen.Python.Async
+
+	if isAsync {
+		w.WriteString(fmt.Sprintf("    async def %s(self, items: List[%s]) -> None:\n", g.includeMethodName(query), className))
+	} else {
+		w.WriteString(fmt.Sprintf("    def %s(self, items: List[%s]) -> None:\n", g.includeMethodName(query), className))
+	}
+
+	var placeholders string
+	switch g.Config.Database.Provider {
+	case "sqlite", "sqlite3":
+		placeholders = "\", \".join([\"?\"] * len(keys))"
+	case "mysql":
+		placeholders = "\", \".join([\"%s\"] * len(keys))"
+	default:
+		placeholders = "\", \".join(f\"${i + 1}\" for i in range(len(keys)))"
+	}
+
+	for i, rel := range query.Includes {
+		if i > 0 {
+			w.WriteString("\n")
+		}
+		field := utils.ToSnakeCase(rel.Name)
+		local := "item." + utils.ToSnakeCase(rel.Column)
+		modelName := g.out.ModelName(rel.Table.Name, utils.Capitalize)
+		sql := strings.ReplaceAll(gencommon.RelationQuery(rel, g.Config.SoftDelete.Column), "\"", "\\\"")
+
+		w.WriteString(fmt.Sprintf("        keys = list({%s for item in items if %s is not None})\n", local, local))
+		w.WriteString("        rows = []\n")
+		w.WriteString("        if keys:\n")
+		w.WriteString(fmt.Sprintf("            stmt = \"%s\" + %s + \")\"\n", sql, placeholders))
+		w.WriteString("            args = keys\n")
+		g.writeFetchRows(w, "            ", "rows")
+		if rel.Many {
+			w.WriteString(fmt.Sprintf("        %s_by_key = {}\n", field))
+			w.WriteString("        for row in rows:\n")
+			w.WriteString(fmt.Sprintf("            %s_by_key.setdefault(row['%s'], []).append(%s)\n", field, rel.RelatedColumn, g.modelFromRow(rel.Table, modelName)))
+			w.WriteString("        for item in items:\n")
+			w.WriteString(fmt.Sprintf("            item.%s = %s_by_key.get(%s, [])\n", field, field, local))
+		} else {
+			w.WriteString(fmt.Sprintf("        %s_by_key = {row['%s']: %s for row in rows}\n", field, rel.RelatedColumn, g.modelFromRow(rel.Table, modelName)))
+			w.WriteString("        for item in items:\n")
+			w.WriteString(fmt.Sprintf("            item.%s = %s_by_key.get(%s)\n", field, field, local))
+		}
+	}
+	w.WriteString("\n")
+}
+
+// generateIncludeExecution runs a query with an include annotation and loads
+// the relations of the rows it returns
+func (g *Generator) generateIncludeExecution(w *strings.Builder, paramNames []string, query *parser.Query) {
+	className := utils.ToPascalCase(query.Name) + "Row"
+	await := ""
+	if g.Config.Gen.Python.Async {
+		await = "await "
+	}
+
+	w.WriteString(fmt.Sprintf("        args = [%s]\n", strings.Join(paramNames, ", ")))
+	g.writeFetchRows(w, "        ", "rows")
+	if query.Cmd == ":one" {
+		w.WriteString(fmt.Sprintf("        items = [%s._make_fast(row) for row in rows[:1]]\n", className))
+		w.WriteString(fmt.Sprintf("        %sself.%s(items)\n", await, g.includeMethodName(query)))
+		w.WriteString("        return items[0] if items else None\n")
+		return
+	}
+	w.WriteString(fmt.Sprintf("        items = [%s._make_fast(row) for row in rows]\n", className))
+	w.WriteString(fmt.Sprintf("        %sself.%s(items)\n", await, g.includeMethodName(query)))
+	w.WriteString("        return items\n")
+}
+
+// writeFetchRows writes the code that runs stmt with args and assigns the
+// rows it returns, as mappings of column names, to target
+func (g *Generator) writeFetchRows(w *strings.Builder, indent, target string) {
+	isAsync := g.Config.Gen.Python.Async
+
+	switch g.Config.Database.Provider {
+	case "sqlite", "sqlite3":
+		if isAsync {
+			w.WriteString(indent + "async with self.db.execute(stmt, tuple(args)) as cur:\n")
+			w.WriteString(indent + fmt.Sprintf("    %s = [{k: row[k] for k in row.keys()} for row in await cur.fetchall()]\n", target))
+		} else {
+			w.WriteString(indent + "cur = self.db.execute(stmt, tuple(args))\n")
+			w.WriteString(indent + fmt.Sprintf("%s = [{k: row[k] for k in row.keys()} for row in cur.fetchall()]\n", target))
+			w.WriteString(indent + "cur.close()\n")
+		}
+	case "mysql":
+		if isAsync {
+			w.WriteString(indent + "async with self.db.acquire() as conn:\n")
+			w.WriteString(indent + "    async with conn.cursor() as cur:\n")
+			w.WriteString(indent + "        await cur.execute(stmt, tuple(args))\n")
+			w.WriteString(indent + "        result = await cur.fetchall()\n")
+		} else {
+			w.WriteString(indent + "with self.db.cursor() as cur:\n")
+			w.WriteString(indent + "    cur.execute(stmt, tuple(args))\n")
+			w.WriteString(indent + "    result = cur.fetchall()\n")
+		}
+		w.WriteString(indent + fmt.Sprintf("%s = [dict(row) if hasattr(row, 'keys') else {cur.description[i][0]: row[i] for i in range(len(row))} for row in result]\n", target))
+	default:
+		if isAsync {
+			w.WriteString(indent + fmt.Sprintf("%s = await self.db.fetch(stmt, *args)\n", target))
+		} else {
+			w.WriteString(indent + fmt.Sprintf("%s = self.db.execute(stmt, tuple(args)).fetchall()\n", target))
+		}
+	}
+}
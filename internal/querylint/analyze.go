@@ -0,0 +1,179 @@
+package querylint
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+// Analysis is the part of the schema no query uses
+type Analysis struct {
+	Queries       int            `json:"queries"`
+	Tables        int            `json:"tables"`
+	UnusedTables  []string       `json:"unused_tables"`
+	UnusedColumns []UnusedColumn `json:"unused_columns"`
+	UnusedIndexes []UnusedIndex  `json:"unused_indexes"`
+}
+
+// UnusedColumn is a column no query reads or writes
+type UnusedColumn struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+}
+
+// UnusedIndex is an index none of whose columns a query filters, joins or
+// sorts on
+type UnusedIndex struct {
+	Name    string   `json:"name"`
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+}
+
+var (
+	identifierRegex = regexp.MustCompile(`[A-Za-z_]\w*`)
+	insertAllRegex  = regexp.MustCompile(`(?i)\bINSERT\s+INTO\s+(\w+)\s+(?:VALUES|SELECT)\b`)
+	returnAllRegex  = regexp.MustCompile(`(?i)\bRETURNING\s+\*`)
+)
+
+// Words that start a clause of a query. WHERE, ON and ORDER BY start the
+// ones whose columns an index can serve; the others end them.
+var filterClauses = map[string]bool{"WHERE": true, "ON": true, "ORDER": true}
+var otherClauses = map[string]bool{
+	"SELECT": true, "FROM": true, "JOIN": true, "GROUP": true, "HAVING": true, "LIMIT": true,
+	"OFFSET": true, "RETURNING": true, "UNION": true, "INTERSECT": true, "EXCEPT": true,
+	"WINDOW": true, "FETCH": true, "FOR": true, "SET": true, "VALUES": true, "INTO": true,
+}
+
+// Analyze cross-references the queries with the schema and its indexes. A
+// column counts as used when a query on its table names it or selects,
+// returns or inserts every column; an index when a query on its table names
+// one of its columns in WHERE, a join's ON or ORDER BY. Unique indexes are
+// left out, as they enforce a constraint whether queries use them or not,
+// and so are the batch loaders, which every table with a primary key gets.
+func Analyze(schema *parser.Schema, indexes []types.SchemaIndex, queries []*parser.Query) *Analysis {
+	tablesByName := make(map[string]*parser.Table, len(schema.Tables))
+	for _, table := range schema.Tables {
+		tablesByName[strings.ToLower(table.Name)] = table
+	}
+
+	referenced := make(map[*parser.Table]bool)
+	used := make(map[*parser.Table]map[string]bool)
+	filtered := make(map[*parser.Table]map[string]bool)
+	mark := func(set map[*parser.Table]map[string]bool, table *parser.Table, column string) {
+		if set[table] == nil {
+			set[table] = make(map[string]bool)
+		}
+		set[table][strings.ToLower(column)] = true
+	}
+
+	for _, query := range queries {
+		sql := maskSQL(query.SQL)
+
+		var tables []*parser.Table
+		for _, m := range tableRefRegex.FindAllStringSubmatch(sql, -1) {
+			if table := tablesByName[strings.ToLower(m[1])]; table != nil {
+				tables = append(tables, table)
+				referenced[table] = true
+			}
+		}
+
+		// Every identifier, and the ones inside filter clauses
+		names := make(map[string]bool)
+		filterNames := make(map[string]bool)
+		inFilter := false
+		for _, word := range identifierRegex.FindAllString(sql, -1) {
+			upper := strings.ToUpper(word)
+			switch {
+			case filterClauses[upper]:
+				inFilter = true
+			case otherClauses[upper]:
+				inFilter = false
+			}
+			names[strings.ToLower(word)] = true
+			if inFilter {
+				filterNames[strings.ToLower(word)] = true
+			}
+		}
+
+		everyColumn := make(map[*parser.Table]bool)
+		if selectStarRegex.MatchString(sql) || returnAllRegex.MatchString(sql) {
+			for _, table := range tables {
+				everyColumn[table] = true
+			}
+		}
+		for _, m := range insertAllRegex.FindAllStringSubmatch(sql, -1) {
+			if table := tablesByName[strings.ToLower(m[1])]; table != nil {
+				everyColumn[table] = true
+			}
+		}
+
+		for _, table := range tables {
+			for _, col := range table.Columns {
+				name := strings.ToLower(col.Name)
+				if everyColumn[table] || names[name] {
+					mark(used, table, name)
+				}
+				if filterNames[name] {
+					mark(filtered, table, name)
+				}
+			}
+		}
+
+		// Keyset pagination filters and sorts on the cursor columns
+		if query.Paginate != nil {
+			for _, table := range tables {
+				for _, column := range query.Paginate.Columns {
+					mark(filtered, table, column)
+				}
+			}
+		}
+
+		// Included relations are loaded with every column, by their key
+		for _, rel := range query.Includes {
+			referenced[rel.Table] = true
+			for _, col := range rel.Table.Columns {
+				mark(used, rel.Table, col.Name)
+			}
+			mark(filtered, rel.Table, rel.RelatedColumn)
+		}
+	}
+
+	analysis := &Analysis{
+		Queries:       len(queries),
+		Tables:        len(schema.Tables),
+		UnusedTables:  []string{},
+		UnusedColumns: []UnusedColumn{},
+		UnusedIndexes: []UnusedIndex{},
+	}
+	for _, table := range schema.Tables {
+		if !referenced[table] {
+			analysis.UnusedTables = append(analysis.UnusedTables, table.Name)
+			continue
+		}
+		for _, col := range table.Columns {
+			if !used[table][strings.ToLower(col.Name)] {
+				analysis.UnusedColumns = append(analysis.UnusedColumns, UnusedColumn{Table: table.Name, Column: col.Name})
+			}
+		}
+	}
+
+	for _, index := range indexes {
+		table := tablesByName[strings.ToLower(index.Table)]
+		if index.Unique || table == nil {
+			continue
+		}
+		inUse := false
+		for _, column := range index.Columns {
+			if filtered[table][strings.ToLower(column)] {
+				inUse = true
+				break
+			}
+		}
+		if !inUse {
+			analysis.UnusedIndexes = append(analysis.UnusedIndexes, UnusedIndex{Name: index.Name, Table: table.Name, Columns: index.Columns})
+		}
+	}
+	return analysis
+}
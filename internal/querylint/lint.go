@@ -0,0 +1,489 @@
+// Package querylint checks query files for queries that parse and match the
+// schema but are likely mistakes: reading every column or every row,
+// updating every row, comparing columns with values of another type, and
+// queries the application never calls. It also reports the tables, columns
+// and indexes of the schema no query uses.
+package querylint
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/gencommon"
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+	"github.com/Lumos-Labs-HQ/flash/internal/utils"
+)
+
+// Rules the linter checks
+const (
+	RuleSelectStar   = "select-star"       // SELECT * in a :many query
+	RuleMissingLimit = "missing-limit"     // :many SELECT with neither WHERE nor LIMIT
+	RuleParamType    = "param-type"        // column compared with a value of another type
+	RuleNoWhere      = "dml-without-where" // UPDATE or DELETE of every row
+	RuleUnusedQuery  = "unused-query"      // query no source file calls
+)
+
+// Rules lists every rule, in the order findings of one query are reported
+var Rules = []string{RuleSelectStar, RuleMissingLimit, RuleParamType, RuleNoWhere, RuleUnusedQuery}
+
+// Finding is a problem the linter found in a query
+type Finding struct {
+	Rule       string
+	Query      string
+	Diagnostic *utils.Diagnostic
+}
+
+func (f *Finding) String() string {
+	return f.Diagnostic.Error()
+}
+
+var (
+	selectStarRegex = regexp.MustCompile(`(?i)\bSELECT\s+(?:DISTINCT\s+)?(?:\w+\.)?\*`)
+	selectRegex     = regexp.MustCompile(`(?i)^\s*(?:WITH\b[\s\S]*?\)\s*)?SELECT\b`)
+	dmlRegex        = regexp.MustCompile(`(?i)^\s*(?:WITH\b[\s\S]*?\)\s*)?(UPDATE|DELETE)\b`)
+	whereRegex      = regexp.MustCompile(`(?i)\bWHERE\b`)
+	limitRegex      = regexp.MustCompile(`(?i)\b(?:LIMIT|FETCH\s+FIRST|FETCH\s+NEXT)\b`)
+	tableRefRegex   = regexp.MustCompile(`(?i)\b(?:FROM|JOIN|UPDATE|INTO)\s+(\w+)`)
+	comparisonRegex = regexp.MustCompile(`(?i)\b(?:\w+\.)?(\w+)\s*(?:=|<>|!=|<=|>=|<|>)\s*(\$\d+\s*::\s*\w+|'(?:[^']|'')*'|-?\d+(?:\.\d+)?\b|TRUE\b|FALSE\b)`)
+	disableRegex    = regexp.MustCompile(`^--\s*lint:disable\b(.*)$`)
+)
+
+// block is a query as written in its file
+type block struct {
+	name     string
+	nameLine int
+	nameText string
+	sql      []string // raw lines of SQL
+	lines    []int    // file line of each SQL line
+	disabled map[string]bool
+}
+
+// Lint checks the queries of the query files, which must have parsed
+// without errors. Findings come out in file order. Disable comments naming
+// unknown rules are returned as an error.
+func Lint(cfg *config.Config, schema *parser.Schema, queries []*parser.Query) ([]*Finding, error) {
+	files, err := filepath.Glob(filepath.Join(cfg.Queries, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	parsed := make(map[string]*parser.Query, len(queries))
+	for _, query := range queries {
+		parsed[query.SourcePath+"\x00"+query.Name] = query
+	}
+
+	var source *sourceIndex
+	var findings []*Finding
+	var errs []error
+	for _, file := range files {
+		displayPath := utils.DisplayPath(file)
+		blocks, err := readBlocks(file, displayPath)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, b := range blocks {
+			query := parsed[displayPath+"\x00"+b.name]
+			if query == nil {
+				continue
+			}
+			l := &linter{cfg: cfg, schema: schema, query: query, block: b, file: displayPath}
+			if !b.disabled[RuleUnusedQuery] && !b.disabled["*"] {
+				if source == nil {
+					source = indexSources(cfg)
+				}
+				l.checkUnused(source)
+			}
+			l.check()
+			findings = append(findings, l.findings...)
+		}
+	}
+
+	if err := utils.JoinDiagnostics(errs...); err != nil {
+		return findings, err
+	}
+	return findings, nil
+}
+
+// readBlocks splits a query file into its queries. A lint:disable comment
+// before the first query applies to the whole file.
+func readBlocks(path, displayPath string) ([]*block, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var blocks []*block
+	var current *block
+	fileDisabled := make(map[string]bool)
+	var errs []error
+
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+
+		switch {
+		case strings.HasPrefix(line, "-- name:") || strings.HasPrefix(line, "-- name :"):
+			fields := strings.Fields(strings.TrimLeft(line[strings.Index(line, "name")+4:], " :"))
+			if len(fields) == 0 {
+				current = nil
+				continue
+			}
+			current = &block{name: fields[0], nameLine: lineNum, nameText: raw, disabled: make(map[string]bool)}
+			for rule := range fileDisabled {
+				current.disabled[rule] = true
+			}
+			blocks = append(blocks, current)
+		case disableRegex.MatchString(line):
+			rules, err := parseDisable(line)
+			if err != nil {
+				errs = append(errs, &utils.Diagnostic{
+					File:    displayPath,
+					Line:    lineNum,
+					Column:  strings.Index(raw, "--") + 1,
+					Message: err.Error(),
+					Snippet: raw,
+				})
+				continue
+			}
+			target := fileDisabled
+			if current != nil {
+				target = current.disabled
+			}
+			for _, rule := range rules {
+				target[rule] = true
+			}
+		case line == "" || strings.HasPrefix(line, "--"):
+		case current != nil:
+			current.sql = append(current.sql, raw)
+			current.lines = append(current.lines, lineNum)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return blocks, utils.JoinDiagnostics(errs...)
+}
+
+// parseDisable returns the rules of `-- lint:disable rule, rule`, or "*"
+// when it names none
+func parseDisable(line string) ([]string, error) {
+	spec := strings.TrimSpace(disableRegex.FindStringSubmatch(line)[1])
+	if spec == "" {
+		return []string{"*"}, nil
+	}
+
+	var rules []string
+	for _, part := range strings.Split(spec, ",") {
+		rule := strings.TrimSpace(part)
+		known := false
+		for _, r := range Rules {
+			if r == rule {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown lint rule '%s'. Available rules: %s", rule, strings.Join(Rules, ", "))
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// linter checks one query
+type linter struct {
+	cfg      *config.Config
+	schema   *parser.Schema
+	query    *parser.Query
+	block    *block
+	file     string
+	findings []*Finding
+}
+
+func (l *linter) report(rule string, offset int, format string, args ...interface{}) {
+	if l.block.disabled[rule] || l.block.disabled["*"] {
+		return
+	}
+
+	line, col, text := l.block.nameLine, strings.Index(l.block.nameText, "--")+1, l.block.nameText
+	if offset >= 0 {
+		for i, sqlLine := range l.block.sql {
+			if offset <= len(sqlLine) {
+				line, col, text = l.block.lines[i], offset+1, sqlLine
+				break
+			}
+			offset -= len(sqlLine) + 1
+		}
+	}
+
+	message := fmt.Sprintf(format, args...)
+	l.findings = append(l.findings, &Finding{
+		Rule:  rule,
+		Query: l.query.Name,
+		Diagnostic: &utils.Diagnostic{
+			File:    l.file,
+			Line:    line,
+			Column:  col,
+			Message: fmt.Sprintf("warning: query '%s': %s [%s]", l.query.Name, message, rule),
+			Snippet: text,
+		},
+	})
+}
+
+func (l *linter) check() {
+	// Strings and comments are blanked out, keeping offsets, so keywords
+	// inside them aren't matched
+	sql := maskSQL(strings.Join(l.block.sql, "\n"))
+	cmd := strings.ToLower(l.query.Cmd)
+
+	if cmd == ":many" {
+		if loc := selectStarRegex.FindStringIndex(sql); loc != nil {
+			l.report(RuleSelectStar, loc[1]-1, "SELECT * in a :many query reads every column, list the ones the caller needs")
+		}
+		if loc := selectRegex.FindStringIndex(sql); loc != nil && l.query.Paginate == nil &&
+			!whereRegex.MatchString(sql) && !limitRegex.MatchString(sql) {
+			l.report(RuleMissingLimit, loc[1]-len("SELECT"), "SELECT without WHERE or LIMIT returns every row, add a LIMIT or a paginate annotation")
+		}
+	}
+
+	if m := dmlRegex.FindStringSubmatchIndex(sql); m != nil && !whereRegex.MatchString(sql) {
+		statement := strings.ToUpper(sql[m[2]:m[3]])
+		l.report(RuleNoWhere, m[2], "%s without WHERE changes every row of %s", statement, utils.ExtractTableName(sql))
+	}
+
+	l.checkComparisons(sql)
+}
+
+// checkComparisons reports columns compared with a cast parameter or a
+// literal of another type
+func (l *linter) checkComparisons(sql string) {
+	var tables []*parser.Table
+	for _, m := range tableRefRegex.FindAllStringSubmatch(sql, -1) {
+		for _, t := range l.schema.Tables {
+			if strings.EqualFold(t.Name, m[1]) {
+				tables = append(tables, t)
+			}
+		}
+	}
+
+	// The original text, as the masked one has no string contents
+	original := strings.Join(l.block.sql, "\n")
+	for _, m := range comparisonRegex.FindAllStringSubmatchIndex(sql, -1) {
+		column := l.findColumn(tables, sql[m[2]:m[3]])
+		if column == nil {
+			continue
+		}
+		columnKind := l.kind(column.Type)
+		value := original[m[4]:m[5]]
+
+		var valueKind string
+		switch upper := strings.ToUpper(value); {
+		case strings.HasPrefix(value, "$"):
+			valueKind = l.kind(strings.TrimSpace(value[strings.Index(value, "::")+2:]))
+		case strings.HasPrefix(value, "'"):
+			// Quoted literals are read as text, dates, uuids or json alike
+			if columnKind == kindNumber || columnKind == kindBool {
+				valueKind = kindText
+			}
+		case upper == "TRUE" || upper == "FALSE":
+			valueKind = kindBool
+		default:
+			valueKind = kindNumber
+		}
+
+		if columnKind == "" || valueKind == "" || columnKind == valueKind || l.compatible(columnKind, valueKind) {
+			continue
+		}
+		l.report(RuleParamType, m[4], "column %s is %s but is compared with %s", column.Name, column.Type, value)
+	}
+}
+
+// findColumn looks a column up in the tables of a query, when they agree
+// on its type
+func (l *linter) findColumn(tables []*parser.Table, name string) *parser.Column {
+	var found *parser.Column
+	for _, t := range tables {
+		for _, col := range t.Columns {
+			if !strings.EqualFold(col.Name, name) {
+				continue
+			}
+			if found != nil && l.kind(found.Type) != l.kind(col.Type) {
+				return nil
+			}
+			found = col
+		}
+	}
+	return found
+}
+
+// Kinds of types that compare with each other
+const (
+	kindNumber = "number"
+	kindText   = "text"
+	kindBool   = "bool"
+	kindTime   = "time"
+	kindUUID   = "uuid"
+	kindJSON   = "json"
+)
+
+// kind returns the kind of a SQL type, empty when it isn't known
+func (l *linter) kind(sqlType string) string {
+	t := strings.ToLower(sqlType)
+	if i := strings.IndexAny(t, "( "); i >= 0 {
+		t = t[:i]
+	}
+	for _, enum := range l.schema.Enums {
+		if strings.EqualFold(enum.Name, t) {
+			return kindText
+		}
+	}
+
+	switch {
+	case t == "bool" || t == "boolean":
+		return kindBool
+	case strings.Contains(t, "int") || strings.Contains(t, "serial") || t == "numeric" || t == "decimal" ||
+		t == "real" || t == "float" || strings.HasPrefix(t, "float") || t == "double" || t == "money":
+		return kindNumber
+	case strings.Contains(t, "char") || t == "text" || t == "citext" || t == "enum" || strings.HasSuffix(t, "text"):
+		return kindText
+	case strings.HasPrefix(t, "timestamp") || t == "date" || t == "time" || t == "timetz" || t == "datetime" || t == "interval":
+		return kindTime
+	case t == "uuid":
+		return kindUUID
+	case t == "json" || t == "jsonb":
+		return kindJSON
+	}
+	return ""
+}
+
+// compatible reports whether values of kind b compare with columns of kind
+// a on the database anyway: MySQL and SQLite keep booleans as integers
+func (l *linter) compatible(a, b string) bool {
+	if l.cfg.Database.Provider == "mysql" || l.cfg.Database.Provider == "sqlite" || l.cfg.Database.Provider == "sqlite3" {
+		return (a == kindBool && b == kindNumber) || (a == kindNumber && b == kindBool)
+	}
+	return false
+}
+
+// checkUnused reports a query none of the application's source files
+// calls, under the name of any generated language
+func (l *linter) checkUnused(source *sourceIndex) {
+	if source.files == 0 {
+		return
+	}
+	for _, name := range source.names(l.query) {
+		if strings.Contains(source.text, name) {
+			return
+		}
+	}
+	l.report(RuleUnusedQuery, -1, "no source file calls it")
+}
+
+// maskSQL replaces the contents of string literals and comments with
+// spaces, keeping offsets and line breaks
+func maskSQL(sql string) string {
+	b := []byte(sql)
+	for i := 0; i < len(b); i++ {
+		switch {
+		case b[i] == '\'':
+			for i++; i < len(b); i++ {
+				if b[i] == '\'' {
+					if i+1 < len(b) && b[i+1] == '\'' {
+						b[i], b[i+1] = ' ', ' '
+						i++
+						continue
+					}
+					break
+				}
+				if b[i] != '\n' {
+					b[i] = ' '
+				}
+			}
+		case b[i] == '-' && i+1 < len(b) && b[i+1] == '-':
+			for ; i < len(b) && b[i] != '\n'; i++ {
+				b[i] = ' '
+			}
+		}
+	}
+	return string(b)
+}
+
+// sourceIndex holds the application's source code, to look up the queries
+// it calls
+type sourceIndex struct {
+	text  string
+	files int
+	out   *gencommon.Output
+}
+
+var sourceExtensions = map[string]bool{
+	".go": true, ".js": true, ".mjs": true, ".cjs": true, ".jsx": true,
+	".ts": true, ".mts": true, ".cts": true, ".tsx": true, ".py": true,
+}
+
+// indexSources reads the source files of the project, leaving out generated
+// code and dependencies
+func indexSources(cfg *config.Config) *sourceIndex {
+	skip := map[string]bool{"flash_gen": true}
+	for _, dir := range []string{cfg.Gen.JS.Out, cfg.Gen.Python.Out, cfg.Queries, cfg.MigrationsPath, cfg.GetSchemaDir()} {
+		if dir != "" {
+			skip[filepath.Clean(dir)] = true
+		}
+	}
+	skipNames := map[string]bool{
+		".git": true, "node_modules": true, "vendor": true, "venv": true, ".venv": true, "__pycache__": true, "dist": true, "build": true,
+	}
+
+	var sb strings.Builder
+	index := &sourceIndex{}
+	filepath.WalkDir(".", func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != "." && (skip[filepath.Clean(path)] || skipNames[d.Name()]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !sourceExtensions[filepath.Ext(path)] {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		sb.Write(content)
+		sb.WriteByte('\n')
+		index.files++
+		return nil
+	})
+	index.text = sb.String()
+	index.out, _ = gencommon.NewOutput(cfg.Gen, "", "")
+	return index
+}
+
+// names returns the names a query's method has in generated Go, JavaScript
+// and Python code
+func (s *sourceIndex) names(query *parser.Query) []string {
+	names := []string{utils.ToPascalCase(query.Name)}
+	if s.out != nil {
+		names = append(names,
+			s.out.FunctionName(config.NamingCamel, query.Name, ""),
+			s.out.FunctionName(config.NamingSnake, query.Name, ""))
+	}
+	return names
+}
@@ -0,0 +1,279 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/database"
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/notify"
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+)
+
+// snapshotRows is how many rows of a result a snapshot keeps
+const snapshotRows = 100
+
+// Runner runs schedules, once on demand or on their cron expressions
+type Runner struct {
+	cfg      *config.Config
+	metadata *MetadataManager
+
+	// mu guards running and serializes writes to the runs file
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+func NewRunner(cfg *config.Config) (*Runner, error) {
+	if cfg == nil || cfg.MigrationsPath == "" {
+		return nil, fmt.Errorf("schedules need a flash config")
+	}
+	return &Runner{
+		cfg:      cfg,
+		metadata: NewMetadataManager(cfg.MigrationsPath),
+		running:  map[string]bool{},
+	}, nil
+}
+
+func (r *Runner) Metadata() *MetadataManager {
+	return r.metadata
+}
+
+// NextRun returns when a schedule runs next after t, the zero time when it
+// is disabled or its cron expression never matches
+func NextRun(schedule *Schedule, t time.Time) time.Time {
+	if schedule.Disabled {
+		return time.Time{}
+	}
+	cron, err := ParseCron(schedule.Cron)
+	if err != nil {
+		return time.Time{}
+	}
+	return cron.Next(t)
+}
+
+// Save validates a schedule, checks its query exists and adds it, or
+// replaces the schedule with its name
+func (r *Runner) Save(schedule *Schedule) error {
+	if err := schedule.Validate(); err != nil {
+		return err
+	}
+	if _, err := r.querySQL(schedule.Query); err != nil {
+		return err
+	}
+
+	store, err := r.metadata.Load()
+	if err != nil {
+		return err
+	}
+	store.PutSchedule(schedule)
+	return r.metadata.Save(store)
+}
+
+// Remove deletes a schedule and its runs
+func (r *Runner) Remove(name string) error {
+	store, err := r.metadata.Load()
+	if err != nil {
+		return err
+	}
+	if err := store.RemoveSchedule(name); err != nil {
+		return err
+	}
+	if err := r.metadata.Save(store); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.metadata.DeleteRuns(name)
+}
+
+// RunOnce runs a schedule now, records the run and sends the notifications
+// it asks for. Failures of the query are reported in the run; the error is
+// for schedules that don't exist or are already running.
+func (r *Runner) RunOnce(ctx context.Context, name string) (*Run, error) {
+	store, err := r.metadata.Load()
+	if err != nil {
+		return nil, err
+	}
+	schedule := store.GetSchedule(name)
+	if schedule == nil {
+		return nil, fmt.Errorf("schedule '%s' not found", name)
+	}
+	return r.run(ctx, schedule)
+}
+
+func (r *Runner) run(ctx context.Context, schedule *Schedule) (*Run, error) {
+	r.mu.Lock()
+	if r.running[schedule.Name] {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("schedule '%s' is already running", schedule.Name)
+	}
+	r.running[schedule.Name] = true
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		delete(r.running, schedule.Name)
+		r.mu.Unlock()
+	}()
+
+	run := &Run{Schedule: schedule.Name, StartedAt: time.Now()}
+	rowCount, snapshot, err := r.execute(ctx, schedule)
+	run.DurationMS = time.Since(run.StartedAt).Milliseconds()
+	run.RowCount = rowCount
+	run.Snapshot = snapshot
+	run.Success = err == nil
+	if err != nil {
+		run.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	err = r.metadata.AddRun(run)
+	r.mu.Unlock()
+	if err != nil {
+		log.Printf("⚠️  failed to record run of schedule %s: %v", schedule.Name, err)
+	}
+
+	r.notify(schedule, run)
+	return run, nil
+}
+
+// execute connects to the schedule's database and runs its query
+func (r *Runner) execute(ctx context.Context, schedule *Schedule) (int, *Snapshot, error) {
+	sql, err := r.querySQL(schedule.Query)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	urlEnv := schedule.URLEnv
+	if urlEnv == "" {
+		urlEnv = r.cfg.Database.URLEnv
+	}
+	dbURL := os.Getenv(urlEnv)
+	if dbURL == "" {
+		return 0, nil, fmt.Errorf("database URL not found in environment variable %s", urlEnv)
+	}
+
+	adapter := database.NewAdapter(r.cfg.Database.Provider)
+	if err := database.Require(adapter, r.cfg.Database.Provider, common.CapabilityQueries); err != nil {
+		return 0, nil, err
+	}
+	if err := adapter.Connect(ctx, dbURL); err != nil {
+		return 0, nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer adapter.Close()
+
+	result, err := adapter.ExecuteQuery(ctx, sql, schedule.Params...)
+	if err != nil {
+		return 0, nil, err
+	}
+	if !schedule.Snapshot {
+		return len(result.Rows), nil, nil
+	}
+
+	snapshot := &Snapshot{Columns: result.Columns, Rows: result.Rows}
+	if snapshot.Rows == nil {
+		snapshot.Rows = []map[string]any{}
+	}
+	if len(snapshot.Rows) > snapshotRows {
+		snapshot.Rows = snapshot.Rows[:snapshotRows]
+		snapshot.Truncated = true
+	}
+	for _, row := range snapshot.Rows {
+		for col, value := range row {
+			if b, ok := value.([]byte); ok {
+				row[col] = string(b)
+			}
+		}
+	}
+	return len(result.Rows), snapshot, nil
+}
+
+// querySQL finds a named query in the queries folder. The files are read
+// on every run so edits are picked up without a restart.
+func (r *Runner) querySQL(name string) (string, error) {
+	schema, err := parser.NewSchemaParser(r.cfg).Parse()
+	if err != nil {
+		return "", fmt.Errorf("failed to parse schema: %w", err)
+	}
+	queries, err := parser.NewQueryParser(r.cfg).Parse(schema)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse queries: %w", err)
+	}
+	for _, query := range queries {
+		if query.Name == name {
+			return query.SQL, nil
+		}
+	}
+	return "", fmt.Errorf("query %s not found in %s", name, r.cfg.Queries)
+}
+
+func (r *Runner) notify(schedule *Schedule, run *Run) {
+	override := schedule.Notify
+	if override == "" {
+		override = "failure"
+	}
+
+	message := fmt.Sprintf("✅ Schedule %s ran %s: %d row(s) in %dms", schedule.Name, schedule.Query, run.RowCount, run.DurationMS)
+	if !run.Success {
+		message = fmt.Sprintf("❌ Schedule %s failed running %s after %dms\nError: %s", schedule.Name, schedule.Query, run.DurationMS, run.Error)
+	}
+
+	event := notify.Event{Kind: "schedule", Success: run.Success, Message: message, Data: run}
+	if err := notify.SendEvent(r.cfg, event, override); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+}
+
+// Start runs the enabled schedules whenever their cron expression matches,
+// until ctx is cancelled, then waits for the runs in progress. Schedules
+// are reloaded every minute, so changes apply without a restart. A schedule
+// still running when it comes due again is skipped. onRun is called after
+// every run.
+func (r *Runner) Start(ctx context.Context, onRun func(*Run)) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(next.Sub(now)):
+		}
+
+		store, err := r.metadata.Load()
+		if err != nil {
+			log.Printf("⚠️  %v", err)
+			continue
+		}
+		for _, schedule := range store.Schedules {
+			if schedule.Disabled {
+				continue
+			}
+			cron, err := ParseCron(schedule.Cron)
+			if err != nil || !cron.Matches(next) {
+				continue
+			}
+
+			wg.Add(1)
+			go func(schedule *Schedule) {
+				defer wg.Done()
+				run, err := r.run(ctx, schedule)
+				if err != nil {
+					log.Printf("⚠️  skipped: %v", err)
+					return
+				}
+				if onRun != nil {
+					onRun(run)
+				}
+			}(schedule)
+		}
+	}
+}
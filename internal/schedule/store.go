@@ -0,0 +1,230 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// runsPerSchedule is how many past runs are kept for each schedule
+const runsPerSchedule = 20
+
+var scheduleNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]*$`)
+
+// Schedule runs a named query from the queries folder on a cron expression
+type Schedule struct {
+	Name     string `json:"name"`
+	Query    string `json:"query"` // name of the query, as in -- name: GetUser :one
+	Cron     string `json:"cron"`
+	Params   []any  `json:"params,omitempty"` // values of the query's placeholders, in order
+	Snapshot bool   `json:"snapshot,omitempty"`
+	// Notify is when to send notifications: failure (default), always or never
+	Notify string `json:"notify,omitempty"`
+	// URLEnv names the environment variable with the URL of the database
+	// to run against, database.url_env when empty
+	URLEnv    string    `json:"url_env,omitempty"`
+	Disabled  bool      `json:"disabled,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks the name, query, cron expression and notify setting
+func (s *Schedule) Validate() error {
+	if !scheduleNameRegex.MatchString(s.Name) {
+		return fmt.Errorf("invalid schedule name %q: use letters, digits, - and _, starting with a letter", s.Name)
+	}
+	if s.Query == "" {
+		return fmt.Errorf("schedule %s needs a query", s.Name)
+	}
+	if _, err := ParseCron(s.Cron); err != nil {
+		return err
+	}
+	switch s.Notify {
+	case "", "failure", "always", "never":
+	default:
+		return fmt.Errorf("notify must be failure, always or never")
+	}
+	return nil
+}
+
+// Run is the outcome of running a schedule
+type Run struct {
+	Schedule   string    `json:"schedule"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMS int64     `json:"duration_ms"`
+	Success    bool      `json:"success"`
+	RowCount   int       `json:"row_count"`
+	Error      string    `json:"error,omitempty"`
+	// Snapshot holds the first rows of the result when the schedule asks for it
+	Snapshot *Snapshot `json:"snapshot,omitempty"`
+}
+
+// Snapshot is the start of a run's result, up to snapshotRows rows
+type Snapshot struct {
+	Columns   []string         `json:"columns"`
+	Rows      []map[string]any `json:"rows"`
+	Truncated bool             `json:"truncated,omitempty"`
+}
+
+type ScheduleStore struct {
+	Schedules []*Schedule `json:"schedules"`
+}
+
+type RunStore struct {
+	Runs []*Run `json:"runs"`
+}
+
+// MetadataManager keeps schedules and their runs in the .flash folder of
+// the migrations path
+type MetadataManager struct {
+	schedulesPath string
+	runsPath      string
+	flashDir      string
+}
+
+func NewMetadataManager(migrationsPath string) *MetadataManager {
+	flashDir := filepath.Join(migrationsPath, ".flash")
+
+	return &MetadataManager{
+		schedulesPath: filepath.Join(flashDir, "schedules.json"),
+		runsPath:      filepath.Join(flashDir, "schedule_runs.json"),
+		flashDir:      flashDir,
+	}
+}
+
+func (m *MetadataManager) Load() (*ScheduleStore, error) {
+	var store ScheduleStore
+	if err := m.read(m.schedulesPath, "schedules", &store); err != nil {
+		return nil, err
+	}
+	return &store, nil
+}
+
+func (m *MetadataManager) Save(store *ScheduleStore) error {
+	return m.write(m.schedulesPath, "schedules", store)
+}
+
+func (m *MetadataManager) LoadRuns() (*RunStore, error) {
+	var store RunStore
+	if err := m.read(m.runsPath, "schedule runs", &store); err != nil {
+		return nil, err
+	}
+	return &store, nil
+}
+
+// AddRun records a run, dropping the oldest runs of its schedule past
+// runsPerSchedule
+func (m *MetadataManager) AddRun(run *Run) error {
+	store, err := m.LoadRuns()
+	if err != nil {
+		return err
+	}
+
+	kept := []*Run{run}
+	count := 1
+	for _, r := range store.Runs {
+		if r.Schedule == run.Schedule {
+			if count >= runsPerSchedule {
+				continue
+			}
+			count++
+		}
+		kept = append(kept, r)
+	}
+	store.Runs = kept
+
+	return m.write(m.runsPath, "schedule runs", store)
+}
+
+// DeleteRuns forgets the runs of a schedule
+func (m *MetadataManager) DeleteRuns(name string) error {
+	store, err := m.LoadRuns()
+	if err != nil {
+		return err
+	}
+
+	kept := store.Runs[:0]
+	for _, r := range store.Runs {
+		if r.Schedule != name {
+			kept = append(kept, r)
+		}
+	}
+	store.Runs = kept
+
+	return m.write(m.runsPath, "schedule runs", store)
+}
+
+func (m *MetadataManager) read(path, what string, v any) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s file: %w", what, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s file: %w", what, err)
+	}
+	return nil
+}
+
+func (m *MetadataManager) write(path, what string, v any) error {
+	if err := os.MkdirAll(m.flashDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directories: %w", err)
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", what, err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func (s *ScheduleStore) GetSchedule(name string) *Schedule {
+	for _, sch := range s.Schedules {
+		if sch.Name == name {
+			return sch
+		}
+	}
+	return nil
+}
+
+// PutSchedule adds a schedule, or replaces the one with its name keeping
+// its creation time
+func (s *ScheduleStore) PutSchedule(schedule *Schedule) {
+	for i, sch := range s.Schedules {
+		if sch.Name == schedule.Name {
+			schedule.CreatedAt = sch.CreatedAt
+			s.Schedules[i] = schedule
+			return
+		}
+	}
+	if schedule.CreatedAt.IsZero() {
+		schedule.CreatedAt = time.Now()
+	}
+	s.Schedules = append(s.Schedules, schedule)
+}
+
+func (s *ScheduleStore) RemoveSchedule(name string) error {
+	for i, sch := range s.Schedules {
+		if sch.Name == name {
+			s.Schedules = append(s.Schedules[:i], s.Schedules[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("schedule '%s' not found", name)
+}
+
+// ScheduleRuns returns the runs of a schedule, latest first
+func (s *RunStore) ScheduleRuns(name string) []*Run {
+	runs := []*Run{}
+	for _, r := range s.Runs {
+		if r.Schedule == name {
+			runs = append(runs, r)
+		}
+	}
+	return runs
+}
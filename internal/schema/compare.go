@@ -1,7 +1,12 @@
 package schema
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/database"
 
 	"github.com/Lumos-Labs-HQ/flash/internal/types"
 )
@@ -29,7 +34,10 @@ func (sm *SchemaManager) compareSchemas(current, target []types.SchemaTable, cur
 		}
 	}
 
-	for _, targetTable := range targetMap {
+	// Tables are compared in schema order, which puts referenced tables
+	// first, so the diff is the same on every run
+	mergedTargets := sm.tableMapsToSlice(target, targetMap)
+	for _, targetTable := range mergedTargets {
 		if currentTable, exists := currentMap[targetTable.Name]; !exists {
 			diff.NewTables = append(diff.NewTables, targetTable)
 		} else if tableDiff := sm.compareTablesForDiff(currentTable, targetTable); tableDiff != nil {
@@ -43,11 +51,42 @@ func (sm *SchemaManager) compareSchemas(current, target []types.SchemaTable, cur
 		}
 	}
 
-	sm.compareIndexes(current, sm.tableMapsToSlice(targetMap), diff)
+	sm.compareIndexes(current, mergedTargets, diff)
 	sm.compareEnums(currentEnums, targetEnums, diff)
+	for i := range diff.ModifiedEnums {
+		diff.ModifiedEnums[i].Columns = EnumColumns(mergedTargets, diff.ModifiedEnums[i].Name)
+		diff.ModifiedEnums[i].OldColumns = EnumColumns(current, diff.ModifiedEnums[i].Name)
+	}
 	return diff
 }
 
+// GenerateDatabaseDiff returns the changes that turn the database of the
+// schema manager into the one target is connected to. Renames are not
+// detected, every difference is reported as is.
+func (sm *SchemaManager) GenerateDatabaseDiff(ctx context.Context, target database.DatabaseAdapter) (*types.SchemaDiff, error) {
+	currentTables, err := sm.adapter.GetCurrentSchema(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current schema: %w", err)
+	}
+	targetTables, err := target.GetCurrentSchema(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target schema: %w", err)
+	}
+
+	currentEnums, err := sm.adapter.GetCurrentEnums(ctx)
+	if err != nil {
+		currentEnums = []types.SchemaEnum{}
+	}
+	targetEnums, err := target.GetCurrentEnums(ctx)
+	if err != nil {
+		targetEnums = []types.SchemaEnum{}
+	}
+
+	currentTables = sm.filterIgnoredTables(currentTables)
+	targetTables = sm.filterIgnoredTables(targetTables)
+	return sm.compareSchemas(currentTables, targetTables, currentEnums, targetEnums, nil), nil
+}
+
 func (sm *SchemaManager) buildTableMaps(current, target []types.SchemaTable) (map[string]types.SchemaTable, map[string]types.SchemaTable) {
 	currentMap := make(map[string]types.SchemaTable, len(current))
 	targetMap := make(map[string]types.SchemaTable, len(target))
@@ -61,17 +100,22 @@ func (sm *SchemaManager) buildTableMaps(current, target []types.SchemaTable) (ma
 	return currentMap, targetMap
 }
 
-// tableMapsToSlice converts target table map back to slice for comparison
-func (sm *SchemaManager) tableMapsToSlice(targetMap map[string]types.SchemaTable) []types.SchemaTable {
-	var tables []types.SchemaTable
-	for _, table := range targetMap {
-		tables = append(tables, table)
+// tableMapsToSlice converts target table map back to slice for comparison,
+// in the order of the target tables
+func (sm *SchemaManager) tableMapsToSlice(target []types.SchemaTable, targetMap map[string]types.SchemaTable) []types.SchemaTable {
+	tables := make([]types.SchemaTable, 0, len(targetMap))
+	seen := make(map[string]bool, len(targetMap))
+	for _, table := range target {
+		if !seen[table.Name] {
+			seen[table.Name] = true
+			tables = append(tables, targetMap[table.Name])
+		}
 	}
 	return tables
 }
 
 func (sm *SchemaManager) compareTablesForDiff(current, target types.SchemaTable) *types.TableDiff {
-	tableDiff := &types.TableDiff{Name: target.Name}
+	tableDiff := &types.TableDiff{Name: target.Name, OldTable: current, Table: target}
 	currentCols, targetCols := sm.buildColumnMaps(current.Columns, target.Columns)
 	hasChanges := false
 
@@ -80,12 +124,7 @@ func (sm *SchemaManager) compareTablesForDiff(current, target types.SchemaTable)
 			tableDiff.NewColumns = append(tableDiff.NewColumns, targetCol)
 			hasChanges = true
 		} else if !sm.columnsEqual(currentCol, targetCol) {
-			tableDiff.ModifiedColumns = append(tableDiff.ModifiedColumns, types.ColumnDiff{
-				Name:    targetCol.Name,
-				OldType: currentCol.Type,
-				NewType: targetCol.Type,
-				Changes: sm.getColumnChanges(currentCol, targetCol),
-			})
+			tableDiff.ModifiedColumns = append(tableDiff.ModifiedColumns, sm.columnDiff(currentCol, targetCol))
 			hasChanges = true
 		}
 	}
@@ -98,6 +137,10 @@ func (sm *SchemaManager) compareTablesForDiff(current, target types.SchemaTable)
 		}
 	}
 
+	if compareConstraints(current, target, tableDiff) {
+		hasChanges = true
+	}
+
 	if hasChanges {
 		return tableDiff
 	}
@@ -119,18 +162,99 @@ func (sm *SchemaManager) buildColumnMaps(current, target []types.SchemaColumn) (
 
 func (sm *SchemaManager) compareIndexes(current, target []types.SchemaTable, diff *types.SchemaDiff) {
 	currentIndexes, targetIndexes := sm.buildIndexMaps(current, target)
+	diffIndexes(sortedIndexes(currentIndexes), sortedIndexes(targetIndexes), diff)
+}
 
-	for name, index := range targetIndexes {
-		if _, exists := currentIndexes[name]; !exists {
+// diffIndexes adds the index changes between two sets of indexes to the
+// diff. Indexes are matched by name; one whose definition changed is
+// dropped and created again, and one that only has a new name is renamed.
+func diffIndexes(current, target []types.SchemaIndex, diff *types.SchemaDiff) {
+	currentByName := make(map[string]types.SchemaIndex, len(current))
+	for _, index := range current {
+		currentByName[index.Name] = index
+	}
+	targetByName := make(map[string]types.SchemaIndex, len(target))
+	for _, index := range target {
+		targetByName[index.Name] = index
+	}
+
+	var added, dropped []types.SchemaIndex
+	for _, index := range target {
+		existing, exists := currentByName[index.Name]
+		switch {
+		case !exists:
+			added = append(added, index)
+		case !indexDefinitionsEqual(existing, index):
+			diff.DroppedIndexes = append(diff.DroppedIndexes, existing)
+			diff.NewIndexes = append(diff.NewIndexes, index)
+		}
+	}
+	for _, index := range current {
+		if _, exists := targetByName[index.Name]; !exists {
+			dropped = append(dropped, index)
+		}
+	}
+
+	// A new index defined like a dropped one of the same table is a rename
+	for _, index := range added {
+		from := -1
+		for i, old := range dropped {
+			if strings.EqualFold(old.Table, index.Table) && indexDefinitionsEqual(old, index) {
+				from = i
+				break
+			}
+		}
+		if from < 0 {
 			diff.NewIndexes = append(diff.NewIndexes, index)
+			continue
 		}
+		diff.RenamedIndexes = append(diff.RenamedIndexes, types.IndexRename{From: dropped[from], To: index})
+		dropped = append(dropped[:from], dropped[from+1:]...)
 	}
+	diff.DroppedIndexes = append(diff.DroppedIndexes, dropped...)
+}
 
-	for name, index := range currentIndexes {
-		if _, exists := targetIndexes[name]; !exists {
-			diff.DroppedIndexes = append(diff.DroppedIndexes, index)
+// indexDefinitionsEqual reports whether two indexes are unique alike and
+// cover the same columns in the same order. Expression indexes can't be
+// compared as the databases report them, so they're taken as equal.
+func indexDefinitionsEqual(a, b types.SchemaIndex) bool {
+	if a.Unique != b.Unique || len(a.Columns) != len(b.Columns) {
+		return false
+	}
+	for i := range a.Columns {
+		if strings.Contains(a.Columns[i], "(") || strings.Contains(b.Columns[i], "(") {
+			return true
+		}
+		if indexColumn(a.Columns[i]) != indexColumn(b.Columns[i]) {
+			return false
 		}
 	}
+	return true
+}
+
+// indexColumn returns the column an index entry is on, without quotes or
+// the sort order, e.g. name for "Name" DESC
+func indexColumn(entry string) string {
+	fields := strings.Fields(entry)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(strings.Trim(fields[0], "`\""))
+}
+
+// sortedIndexes returns the indexes of a map ordered by table and name
+func sortedIndexes(indexes map[string]types.SchemaIndex) []types.SchemaIndex {
+	sorted := make([]types.SchemaIndex, 0, len(indexes))
+	for _, index := range indexes {
+		sorted = append(sorted, index)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Table != sorted[j].Table {
+			return sorted[i].Table < sorted[j].Table
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
 }
 
 func (sm *SchemaManager) compareEnums(current, target []types.SchemaEnum, diff *types.SchemaDiff) {
@@ -158,6 +282,15 @@ func (sm *SchemaManager) compareEnums(current, target []types.SchemaEnum, diff *
 			diff.DroppedEnums = append(diff.DroppedEnums, currentEnum.Name)
 		}
 	}
+
+	// Find enums whose values changed
+	for _, targetEnum := range target {
+		if currentEnum, exists := currentMap[targetEnum.Name]; exists {
+			if enumDiff := CompareEnumValues(currentEnum, targetEnum); enumDiff != nil {
+				diff.ModifiedEnums = append(diff.ModifiedEnums, *enumDiff)
+			}
+		}
+	}
 }
 
 func (sm *SchemaManager) buildIndexMaps(current, target []types.SchemaTable) (map[string]types.SchemaIndex, map[string]types.SchemaIndex) {
@@ -182,12 +315,50 @@ func (sm *SchemaManager) columnsEqual(a, b types.SchemaColumn) bool {
 	return a.Name == b.Name &&
 		a.Type == b.Type &&
 		a.Nullable == b.Nullable &&
-		a.Default == b.Default &&
+		DefaultsEqual(a.Default, b.Default) &&
 		a.IsPrimary == b.IsPrimary &&
 		a.IsUnique == b.IsUnique &&
 		a.ForeignKeyTable == b.ForeignKeyTable &&
 		a.ForeignKeyColumn == b.ForeignKeyColumn &&
-		a.OnDeleteAction == b.OnDeleteAction
+		a.OnDeleteAction == b.OnDeleteAction &&
+		onUpdateActionsEqual(a.OnUpdateAction, b.OnUpdateAction) &&
+		a.ForeignKeyDeferrable == b.ForeignKeyDeferrable &&
+		a.UniqueDeferrable == b.UniqueDeferrable
+}
+
+// columnDiff describes how a column changed between the database and the
+// schema files
+func (sm *SchemaManager) columnDiff(old, new types.SchemaColumn) types.ColumnDiff {
+	return types.ColumnDiff{
+		Name:       new.Name,
+		OldType:    old.Type,
+		NewType:    new.Type,
+		OldDefault: old.Default,
+		NewDefault: new.Default,
+		Changes:    sm.getColumnChanges(old, new),
+	}
+}
+
+// onUpdateActionsEqual compares ON UPDATE actions, where none given is the
+// NO ACTION databases report
+func onUpdateActionsEqual(a, b string) bool {
+	return referentialAction(a) == referentialAction(b)
+}
+
+// referentialAction spells an action the way databases report it
+func referentialAction(action string) string {
+	action = strings.ToUpper(strings.Join(strings.Fields(action), " "))
+	if action == "" {
+		return "NO ACTION"
+	}
+	return action
+}
+
+func deferralName(mode string) string {
+	if mode == "" {
+		return "NOT DEFERRABLE"
+	}
+	return mode
 }
 
 func (sm *SchemaManager) getColumnChanges(old, new types.SchemaColumn) []string {
@@ -200,7 +371,7 @@ func (sm *SchemaManager) getColumnChanges(old, new types.SchemaColumn) []string
 		{old.Type != new.Type, fmt.Sprintf("type changed from %s to %s", old.Type, new.Type)},
 		{old.Nullable && !new.Nullable, "made not nullable"},
 		{!old.Nullable && new.Nullable, "made nullable"},
-		{old.Default != new.Default, fmt.Sprintf("default changed from %s to %s", old.Default, new.Default)},
+		{!DefaultsEqual(old.Default, new.Default), fmt.Sprintf("default changed from %s to %s", old.Default, new.Default)},
 		{!old.IsPrimary && new.IsPrimary, "made primary key"},
 		{old.IsPrimary && !new.IsPrimary, "removed primary key"},
 		{!old.IsUnique && new.IsUnique, "made unique"},
@@ -225,5 +396,16 @@ func (sm *SchemaManager) getColumnChanges(old, new types.SchemaColumn) []string
 		changes = append(changes, fmt.Sprintf("foreign key action changed from %s to %s", old.OnDeleteAction, new.OnDeleteAction))
 	}
 
+	if !onUpdateActionsEqual(old.OnUpdateAction, new.OnUpdateAction) {
+		changes = append(changes, fmt.Sprintf("foreign key update action changed from %s to %s", old.OnUpdateAction, new.OnUpdateAction))
+	}
+
+	if old.ForeignKeyDeferrable != new.ForeignKeyDeferrable {
+		changes = append(changes, fmt.Sprintf("foreign key deferral changed from %s to %s", deferralName(old.ForeignKeyDeferrable), deferralName(new.ForeignKeyDeferrable)))
+	}
+	if old.UniqueDeferrable != new.UniqueDeferrable {
+		changes = append(changes, fmt.Sprintf("unique constraint deferral changed from %s to %s", deferralName(old.UniqueDeferrable), deferralName(new.UniqueDeferrable)))
+	}
+
 	return changes
 }
@@ -0,0 +1,173 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+var (
+	tableForeignKeyRegex = regexp.MustCompile("(?i)^(?:CONSTRAINT\\s+[`\"]?(\\w+)[`\"]?\\s+)?FOREIGN\\s+KEY\\s*\\(([^)]*)\\)\\s*REFERENCES\\s+([\\w.`\"]+)\\s*\\(([^)]*)\\)")
+	tableUniqueRegex     = regexp.MustCompile("(?i)^(?:CONSTRAINT\\s+[`\"]?(\\w+)[`\"]?\\s+)?UNIQUE(?:\\s+(?:KEY|INDEX))?(?:\\s+[`\"]?(\\w+)[`\"]?)?\\s*\\(([^)]*)\\)")
+	onDeleteActionRegex  = regexp.MustCompile(`(?i)ON\s+DELETE\s+(CASCADE|SET\s+NULL|SET\s+DEFAULT|RESTRICT|NO\s+ACTION)`)
+)
+
+// parseTableConstraint reads a FOREIGN KEY or UNIQUE constraint declared on
+// the table, e.g. CONSTRAINT fk_line FOREIGN KEY (order_id, line)
+// REFERENCES order_lines (order_id, line). Other constraints give nil.
+func parseTableConstraint(def string) *types.SchemaConstraint {
+	def = strings.TrimSpace(def)
+	if matches := tableForeignKeyRegex.FindStringSubmatch(def); matches != nil {
+		return &types.SchemaConstraint{
+			Name:       matches[1],
+			Type:       "FOREIGN KEY",
+			Columns:    constraintColumns(matches[2]),
+			RefTable:   referencedTableName(strings.NewReplacer(`"`, "", "`", "").Replace(matches[3])),
+			RefColumns: constraintColumns(matches[4]),
+			OnDelete:   parseOnDeleteAction(def),
+			OnUpdate:   parseOnUpdateAction(def),
+			Deferrable: parseForeignKeyDeferrable(def),
+		}
+	}
+	if matches := tableUniqueRegex.FindStringSubmatch(def); matches != nil {
+		name := matches[1]
+		if name == "" {
+			name = matches[2]
+		}
+		return &types.SchemaConstraint{
+			Name:       name,
+			Type:       "UNIQUE",
+			Columns:    constraintColumns(matches[3]),
+			Deferrable: deferrableMode(def[len(matches[0]):]),
+		}
+	}
+	return nil
+}
+
+// parseOnDeleteAction returns the ON DELETE action of a reference
+func parseOnDeleteAction(def string) string {
+	if matches := onDeleteActionRegex.FindStringSubmatch(def); len(matches) >= 2 {
+		return strings.Join(strings.Fields(strings.ToUpper(matches[1])), " ")
+	}
+	return ""
+}
+
+func constraintColumns(list string) []string {
+	var columns []string
+	for _, column := range strings.Split(list, ",") {
+		if column = strings.Trim(strings.TrimSpace(column), "`\""); column != "" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// applyTableConstraints puts constraints over a single column on the column,
+// where the database reports them too, and returns the others
+func applyTableConstraints(columns []types.SchemaColumn, constraints []types.SchemaConstraint) []types.SchemaConstraint {
+	var rest []types.SchemaConstraint
+	for _, constraint := range constraints {
+		if len(constraint.Columns) != 1 || (constraint.Type == "FOREIGN KEY" && len(constraint.RefColumns) != 1) {
+			rest = append(rest, constraint)
+			continue
+		}
+
+		for i := range columns {
+			if columns[i].Name != constraint.Columns[0] {
+				continue
+			}
+			if constraint.Type == "FOREIGN KEY" {
+				columns[i].ForeignKeyTable = constraint.RefTable
+				columns[i].ForeignKeyColumn = constraint.RefColumns[0]
+				columns[i].OnDeleteAction = constraint.OnDelete
+				columns[i].OnUpdateAction = constraint.OnUpdate
+				columns[i].ForeignKeyDeferrable = constraint.Deferrable
+			} else {
+				columns[i].IsUnique = true
+				columns[i].UniqueDeferrable = constraint.Deferrable
+			}
+			break
+		}
+	}
+	return rest
+}
+
+// constraintKey identifies a constraint by what it does rather than its
+// name, so one the database named matches the same one declared unnamed
+func constraintKey(constraint types.SchemaConstraint) string {
+	return strings.Join([]string{
+		constraint.Type,
+		strings.Join(constraint.Columns, ","),
+		constraint.RefTable,
+		strings.Join(constraint.RefColumns, ","),
+		referentialAction(constraint.OnDelete),
+		referentialAction(constraint.OnUpdate),
+		constraint.Deferrable,
+	}, "|")
+}
+
+// compareConstraints adds the constraints only one of the tables has to the
+// table diff; a constraint that changed is in both lists
+func compareConstraints(current, target types.SchemaTable, tableDiff *types.TableDiff) bool {
+	currentKeys := make(map[string]bool, len(current.Constraints))
+	for _, constraint := range current.Constraints {
+		currentKeys[constraintKey(constraint)] = true
+	}
+	targetKeys := make(map[string]bool, len(target.Constraints))
+	for _, constraint := range target.Constraints {
+		targetKeys[constraintKey(constraint)] = true
+	}
+
+	for _, constraint := range target.Constraints {
+		if !currentKeys[constraintKey(constraint)] && !hasUniqueIndex(current, constraint) {
+			tableDiff.NewConstraints = append(tableDiff.NewConstraints, constraint)
+		}
+	}
+	for _, constraint := range current.Constraints {
+		if !targetKeys[constraintKey(constraint)] && !hasUniqueIndex(target, constraint) {
+			tableDiff.DroppedConstraints = append(tableDiff.DroppedConstraints, constraint)
+		}
+	}
+	return len(tableDiff.NewConstraints) > 0 || len(tableDiff.DroppedConstraints) > 0
+}
+
+// hasUniqueIndex reports whether a UNIQUE constraint is a unique index of
+// the table, which MySQL reports as constraints too
+func hasUniqueIndex(table types.SchemaTable, constraint types.SchemaConstraint) bool {
+	if constraint.Type != "UNIQUE" {
+		return false
+	}
+	for _, index := range table.Indexes {
+		if index.Unique && strings.Join(index.Columns, ",") == strings.Join(constraint.Columns, ",") {
+			return true
+		}
+	}
+	return false
+}
+
+// ConstraintName returns the name of a constraint, or for an unnamed one
+// the name PostgreSQL would give it, e.g. orders_customer_id_region_fkey
+func ConstraintName(table string, constraint types.SchemaConstraint) string {
+	if constraint.Name != "" {
+		return constraint.Name
+	}
+	suffix := "key"
+	if constraint.Type == "FOREIGN KEY" {
+		suffix = "fkey"
+	}
+	return fmt.Sprintf("%s_%s_%s", table, strings.Join(constraint.Columns, "_"), suffix)
+}
+
+// DeclaredConstraint drops what a constraint does by default, the NO ACTION
+// databases report, so it's written the way it's usually declared
+func DeclaredConstraint(constraint types.SchemaConstraint) types.SchemaConstraint {
+	if referentialAction(constraint.OnDelete) == "NO ACTION" {
+		constraint.OnDelete = ""
+	}
+	if referentialAction(constraint.OnUpdate) == "NO ACTION" {
+		constraint.OnUpdate = ""
+	}
+	return constraint
+}
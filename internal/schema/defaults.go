@@ -0,0 +1,133 @@
+package schema
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A trailing type cast, e.g. ::character varying(20) or ::text[]
+var defaultCastRegex = regexp.MustCompile(`(?i)^\s*"?[a-z_][\w .]*"?(?:\(\s*\d+(?:\s*,\s*\d+)?\s*\))?(?:\[\])*\s*$`)
+
+// The current time on its own, e.g. now(), CURRENT_TIMESTAMP or
+// CURRENT_TIMESTAMP(3), but not now() + interval '1 day'
+var currentTimestampRegex = regexp.MustCompile(`(?i)^(?:now\s*\(\s*(\d*)\s*\)|current_timestamp(?:\s*\(\s*(\d*)\s*\))?)$`)
+
+// Typed literals and casts of literals inside an expression, which
+// PostgreSQL reports as '1 day'::interval however they were written
+var (
+	typedLiteralRegex = regexp.MustCompile(`(?i)\b(?:interval|date|time|timestamp|timestamptz)\s+('(?:[^']|'')*')`)
+	literalCastRegex  = regexp.MustCompile(`(?i)('(?:[^']|'')*')::[a-z_]\w*(?:\[\])*`)
+)
+
+// DefaultsEqual reports whether two column defaults are the same once the
+// way each database reports them is evened out: casts, wrapping parens and
+// quotes are dropped, NOW() and CURRENT_TIMESTAMP are one, TRUE is 1 and
+// numbers compare by value. String literals compare exactly, expressions
+// without regard to case.
+func DefaultsEqual(a, b string) bool {
+	na, literalA := normalizeDefault(a)
+	nb, literalB := normalizeDefault(b)
+	if literalA && literalB {
+		return na == nb
+	}
+	return strings.EqualFold(na, nb)
+}
+
+// IsLiteralDefault reports whether a default is a constant rather than an
+// expression such as NOW() or gen_random_uuid()
+func IsLiteralDefault(value string) bool {
+	_, literal := normalizeDefault(value)
+	return literal
+}
+
+func normalizeDefault(value string) (string, bool) {
+	value = stripDefaultCasts(unwrapDefault(strings.TrimSpace(value)))
+	value = unwrapDefault(value)
+	upper := strings.ToUpper(value)
+
+	switch {
+	case value == "" || upper == "NULL" || strings.Contains(upper, "NEXTVAL("):
+		return "", true
+	case currentTimestampRegex.MatchString(value):
+		return currentTimestamp(value), false
+	case upper == "TRUE":
+		return "1", true
+	case upper == "FALSE":
+		return "0", true
+	}
+
+	literal := false
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		value, literal = strings.ReplaceAll(value[1:len(value)-1], "''", "'"), true
+	}
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		return strconv.FormatFloat(n, 'g', -1, 64), true
+	}
+	if !literal {
+		value = literalCastRegex.ReplaceAllString(typedLiteralRegex.ReplaceAllString(value, "$1"), "$1")
+	}
+	return value, literal
+}
+
+// currentTimestamp spells a default matched by currentTimestampRegex as
+// CURRENT_TIMESTAMP, keeping its precision
+func currentTimestamp(value string) string {
+	match := currentTimestampRegex.FindStringSubmatch(value)
+	if precision := match[1] + match[2]; precision != "" {
+		return "CURRENT_TIMESTAMP(" + precision + ")"
+	}
+	return "CURRENT_TIMESTAMP"
+}
+
+// unwrapDefault drops parens around a whole default, as in (0) or
+// ('a'::text)
+func unwrapDefault(value string) string {
+	for len(value) >= 2 && value[0] == '(' && value[len(value)-1] == ')' {
+		depth, inQuote := 0, false
+		for i, char := range value {
+			switch {
+			case char == '\'':
+				inQuote = !inQuote
+			case inQuote:
+			case char == '(':
+				depth++
+			case char == ')':
+				depth--
+				// The paren that opened closes before the end, e.g. (a) + (b)
+				if depth == 0 && i < len(value)-1 {
+					return value
+				}
+			}
+		}
+		value = strings.TrimSpace(value[1 : len(value)-1])
+	}
+	return value
+}
+
+// stripDefaultCasts drops the casts PostgreSQL adds to defaults, e.g.
+// 'active'::character varying
+func stripDefaultCasts(value string) string {
+	for {
+		i := lastCastIndex(value)
+		if i < 0 || !defaultCastRegex.MatchString(value[i+2:]) {
+			return value
+		}
+		value = strings.TrimSpace(value[:i])
+	}
+}
+
+// lastCastIndex returns where the last :: outside a string literal is
+func lastCastIndex(value string) int {
+	index, inQuote := -1, false
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\'':
+			inQuote = !inQuote
+		case !inQuote && value[i] == ':' && i+1 < len(value) && value[i+1] == ':':
+			index = i
+			i++
+		}
+	}
+	return index
+}
@@ -0,0 +1,33 @@
+package schema
+
+import "testing"
+
+func TestDefaultsEqual(t *testing.T) {
+	same := []struct{ a, b string }{
+		{"now()", "CURRENT_TIMESTAMP"},
+		{"NOW()", "current_timestamp()"},
+		{"CURRENT_TIMESTAMP(3)", "now(3)"},
+		{"('active'::character varying)", "'active'"},
+		{"true", "1"},
+		{"0.50", "0.5"},
+		{"now() + interval '1 day'", "(now() + '1 day'::interval)"},
+	}
+	for _, tc := range same {
+		if !DefaultsEqual(tc.a, tc.b) {
+			t.Errorf("%q and %q should be equal", tc.a, tc.b)
+		}
+	}
+
+	different := []struct{ a, b string }{
+		{"now() + interval '1 day'", "CURRENT_TIMESTAMP"},
+		{"(now() - '01:00:00'::interval)", "now()"},
+		{"CURRENT_TIMESTAMP(3)", "CURRENT_TIMESTAMP"},
+		{"now() + interval '1 day'", "now() + interval '2 days'"},
+		{"'Active'", "'active'"},
+	}
+	for _, tc := range different {
+		if DefaultsEqual(tc.a, tc.b) {
+			t.Errorf("%q and %q should differ", tc.a, tc.b)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+// CompareEnumValues returns how the values of an enum changed, nil when
+// they didn't
+func CompareEnumValues(current, target types.SchemaEnum) *types.EnumDiff {
+	currentSet := make(map[string]bool, len(current.Values))
+	for _, value := range current.Values {
+		currentSet[value] = true
+	}
+	targetSet := make(map[string]bool, len(target.Values))
+	for _, value := range target.Values {
+		targetSet[value] = true
+	}
+
+	diff := &types.EnumDiff{Name: target.Name, OldValues: current.Values, Values: target.Values}
+	for _, value := range current.Values {
+		if !targetSet[value] {
+			diff.Dropped = append(diff.Dropped, value)
+		}
+	}
+
+	// The values both have must keep their order for new ones to be added
+	// around them
+	var kept, keptTarget []string
+	for _, value := range current.Values {
+		if targetSet[value] {
+			kept = append(kept, value)
+		}
+	}
+	for i, value := range target.Values {
+		if currentSet[value] {
+			keptTarget = append(keptTarget, value)
+			continue
+		}
+		add := types.EnumValueAdd{Value: value}
+		if i > 0 {
+			add.After = target.Values[i-1]
+		} else {
+			for _, next := range target.Values {
+				if currentSet[next] {
+					add.Before = next
+					break
+				}
+			}
+		}
+		diff.Added = append(diff.Added, add)
+	}
+	diff.Reordered = strings.Join(kept, "\x00") != strings.Join(keptTarget, "\x00")
+
+	if len(diff.Added) == 0 && len(diff.Dropped) == 0 && !diff.Reordered {
+		return nil
+	}
+	return diff
+}
+
+// EnumColumns returns the columns of the tables whose type is the enum or
+// an array of it, written as status[] in schema files and introspected as
+// _status
+func EnumColumns(tables []types.SchemaTable, enumName string) []types.EnumColumn {
+	var columns []types.EnumColumn
+	for _, table := range tables {
+		for _, col := range table.Columns {
+			colType := strings.TrimSpace(col.Type)
+			isArray := strings.HasSuffix(colType, "[]")
+			colType = strings.TrimSuffix(colType, "[]")
+			if i := strings.LastIndex(colType, "."); i >= 0 {
+				colType = colType[i+1:]
+			}
+			colType = strings.Trim(colType, `"`)
+			if !isArray && strings.EqualFold(colType, "_"+enumName) {
+				colType, isArray = enumName, true
+			}
+			if strings.EqualFold(colType, enumName) {
+				columns = append(columns, types.EnumColumn{Table: table.Name, Column: col.Name, Array: isArray, Default: col.Default})
+			}
+		}
+	}
+	return columns
+}
+
+// GenerateAddEnumValueSQL adds a value to an enum, before or after an
+// existing value when the position is given
+func GenerateAddEnumValueSQL(enumName string, add types.EnumValueAdd) string {
+	sql := fmt.Sprintf("ALTER TYPE %s ADD VALUE IF NOT EXISTS %s", quoteEnumIdentifier(enumName), quoteEnumValue(add.Value))
+	switch {
+	case add.Before != "":
+		sql += " BEFORE " + quoteEnumValue(add.Before)
+	case add.After != "":
+		sql += " AFTER " + quoteEnumValue(add.After)
+	}
+	return sql + ";"
+}
+
+// GenerateRenameEnumValueSQL renames a value of an enum, keeping its place
+// and the rows that have it (PostgreSQL 10+)
+func GenerateRenameEnumValueSQL(enumName, from, to string) string {
+	return fmt.Sprintf("ALTER TYPE %s RENAME VALUE %s TO %s;", quoteEnumIdentifier(enumName), quoteEnumValue(from), quoteEnumValue(to))
+}
+
+// GenerateChangeEnumValuesSQL gives an enum the values to in place of the
+// values from. Removing values needs the enum to be created again, so the
+// migration first checks that no row still holds a removed value and stops
+// with the columns that do, before any type is touched.
+func GenerateChangeEnumValuesSQL(enumName string, from, to []string, columns []types.EnumColumn) []string {
+	var statements []string
+	if check := GenerateEnumValuesUnusedSQL(enumName, RemovedEnumValues(from, to), columns); check != "" {
+		statements = append(statements, check)
+	}
+	return append(statements, GenerateRecreateEnumSQL(enumName, to, columns)...)
+}
+
+// RemovedEnumValues returns the values of from that to doesn't have
+func RemovedEnumValues(from, to []string) []string {
+	kept := make(map[string]bool, len(to))
+	for _, value := range to {
+		kept[value] = true
+	}
+	var removed []string
+	for _, value := range from {
+		if !kept[value] {
+			removed = append(removed, value)
+		}
+	}
+	return removed
+}
+
+// GenerateEnumValuesUnusedSQL raises an error naming the first column that
+// still holds one of the values, empty when there is nothing to check
+func GenerateEnumValuesUnusedSQL(enumName string, values []string, columns []types.EnumColumn) string {
+	if len(values) == 0 || len(columns) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = quoteEnumValue(value)
+	}
+	list := strings.Join(quoted, ", ")
+
+	var b strings.Builder
+	b.WriteString("DO $$\nBEGIN\n")
+	for _, col := range columns {
+		condition := fmt.Sprintf("%s::text IN (%s)", quoteEnumIdentifier(col.Column), list)
+		if col.Array {
+			condition = fmt.Sprintf("%s::text[] && ARRAY[%s]::text[]", quoteEnumIdentifier(col.Column), list)
+		}
+		message := fmt.Sprintf("%s.%s still has rows with values removed from enum %s (%s)",
+			col.Table, col.Column, enumName, strings.Join(values, ", "))
+		fmt.Fprintf(&b, "    IF EXISTS (SELECT 1 FROM %s WHERE %s) THEN\n", quoteEnumIdentifier(col.Table), condition)
+		// RAISE treats % as a placeholder
+		fmt.Fprintf(&b, "        RAISE EXCEPTION %s;\n", strings.ReplaceAll(quoteEnumValue(message), "%", "%%"))
+		b.WriteString("    END IF;\n")
+	}
+	b.WriteString("END $$;")
+	return b.String()
+}
+
+// GenerateRecreateEnumSQL gives an enum exactly the given values, which
+// PostgreSQL can't do in place when values are dropped or reordered: the
+// type is renamed out of the way, created again, its columns are converted
+// through text and the old type is dropped. Rows holding a dropped value
+// make the conversion, and so the migration, fail.
+func GenerateRecreateEnumSQL(enumName string, values []string, columns []types.EnumColumn) []string {
+	oldName := enumName + "_old"
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = quoteEnumValue(value)
+	}
+
+	statements := []string{
+		fmt.Sprintf("ALTER TYPE %s RENAME TO %s;", quoteEnumIdentifier(enumName), quoteEnumIdentifier(oldName)),
+		fmt.Sprintf("CREATE TYPE %s AS ENUM (%s);", quoteEnumIdentifier(enumName), strings.Join(quoted, ", ")),
+	}
+	for _, col := range columns {
+		alter := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", quoteEnumIdentifier(col.Table), quoteEnumIdentifier(col.Column))
+		target, through := quoteEnumIdentifier(enumName), "text"
+		if col.Array {
+			target, through = target+"[]", "text[]"
+		}
+		// A default of the old type can't be converted with the column
+		if col.Default != "" {
+			statements = append(statements, alter+" DROP DEFAULT;")
+		}
+		statements = append(statements, fmt.Sprintf("%s TYPE %s USING %s::%s::%s;",
+			alter, target, quoteEnumIdentifier(col.Column), through, target))
+		if col.Default != "" {
+			statements = append(statements, fmt.Sprintf("%s SET DEFAULT %s;", alter, enumDefault(col.Default, target)))
+		}
+	}
+	return append(statements, fmt.Sprintf("DROP TYPE %s;", quoteEnumIdentifier(oldName)))
+}
+
+// enumDefault casts a default to the new type instead of the one it was
+// introspected with, e.g. 'active'::status_old
+func enumDefault(value, colType string) string {
+	if i := strings.Index(value, "::"); i >= 0 {
+		value = value[:i]
+	}
+	return value + "::" + colType
+}
+
+func quoteEnumIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteEnumValue(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,182 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+var (
+	createMatViewRegex   = regexp.MustCompile(`(?i)^CREATE\s+MATERIALIZED\s+VIEW\s+(?:IF\s+NOT\s+EXISTS\s+)?(?:"?\w+"?\.)?(?:"([^"]+)"|(\w+))\s+AS\s+`)
+	matViewWithDataRegex = regexp.MustCompile(`(?i)\s+WITH\s+(NO\s+)?DATA$`)
+)
+
+// MaterializedViewIntrospector is implemented by adapters that can read
+// materialized views from the database (currently PostgreSQL).
+type MaterializedViewIntrospector interface {
+	GetCurrentMaterializedViews(ctx context.Context) ([]types.SchemaMaterializedView, error)
+	// NormalizeViewDefinition deparses a query the way the database reports
+	// the definitions of existing views
+	NormalizeViewDefinition(ctx context.Context, definition string) (string, error)
+}
+
+// ParseSchemaMaterializedViews reads the CREATE MATERIALIZED VIEW statements
+// from a schema file or directory, with the indexes created on the views
+func (sm *SchemaManager) ParseSchemaMaterializedViews(schemaPath string) ([]types.SchemaMaterializedView, error) {
+	info, err := os.Stat(schemaPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat schema path: %w", err)
+	}
+
+	files := []string{schemaPath}
+	if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(schemaPath, "*.sql"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+	}
+
+	var views []types.SchemaMaterializedView
+	var indexes []types.SchemaIndex
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema file %s: %w", file, err)
+		}
+		for _, stmt := range sm.splitStatements(sm.cleanSQL(string(content))) {
+			if view, ok := parseCreateMaterializedViewStatement(stmt); ok {
+				views = append(views, view)
+			} else if sm.isCreateIndexStatement(stmt) {
+				if index, err := sm.parseCreateIndexStatement(stmt); err == nil {
+					indexes = append(indexes, index)
+				}
+			}
+		}
+	}
+
+	for i := range views {
+		for _, index := range indexes {
+			if index.Table == views[i].Name {
+				views[i].Indexes = append(views[i].Indexes, index)
+			}
+		}
+	}
+	return views, nil
+}
+
+func parseCreateMaterializedViewStatement(stmt string) (types.SchemaMaterializedView, bool) {
+	matches := createMatViewRegex.FindStringSubmatch(stmt)
+	if matches == nil {
+		return types.SchemaMaterializedView{}, false
+	}
+
+	view := types.SchemaMaterializedView{Name: matches[1] + matches[2]}
+	definition := stmt[len(matches[0]):]
+	if m := matViewWithDataRegex.FindStringSubmatch(definition); m != nil {
+		view.NoData = m[1] != ""
+		definition = definition[:len(definition)-len(m[0])]
+	}
+	view.Definition = strings.TrimSpace(definition)
+	return view, true
+}
+
+// compareMaterializedViews fills the materialized view part of the diff.
+// Definitions are compared as the database deparses them; a view whose
+// schema definition can't be deparsed against the current tables is
+// recreated.
+func (sm *SchemaManager) compareMaterializedViews(ctx context.Context, introspector MaterializedViewIntrospector, current, target []types.SchemaMaterializedView, diff *types.SchemaDiff) {
+	if sm.ignoreTable != nil {
+		current = filterMaterializedViews(current, sm.ignoreTable)
+		target = filterMaterializedViews(target, sm.ignoreTable)
+	}
+
+	currentMap := make(map[string]types.SchemaMaterializedView, len(current))
+	for _, view := range current {
+		currentMap[view.Name] = view
+	}
+	targetMap := make(map[string]bool, len(target))
+
+	for _, view := range target {
+		targetMap[view.Name] = true
+		existing, exists := currentMap[view.Name]
+		if !exists {
+			diff.NewMaterializedViews = append(diff.NewMaterializedViews, view)
+			continue
+		}
+
+		normalized, err := introspector.NormalizeViewDefinition(ctx, view.Definition)
+		if err != nil || normalizeViewDefinition(normalized) != normalizeViewDefinition(existing.Definition) {
+			diff.DroppedMaterializedViews = append(diff.DroppedMaterializedViews, existing)
+			diff.NewMaterializedViews = append(diff.NewMaterializedViews, view)
+			continue
+		}
+
+		diffIndexes(existing.Indexes, view.Indexes, diff)
+	}
+
+	for _, view := range current {
+		if !targetMap[view.Name] {
+			diff.DroppedMaterializedViews = append(diff.DroppedMaterializedViews, view)
+		}
+	}
+}
+
+func filterMaterializedViews(views []types.SchemaMaterializedView, ignore func(string) bool) []types.SchemaMaterializedView {
+	filtered := make([]types.SchemaMaterializedView, 0, len(views))
+	for _, view := range views {
+		if !ignore(view.Name) {
+			filtered = append(filtered, view)
+		}
+	}
+	return filtered
+}
+
+// normalizeViewDefinition ignores whitespace differences between two
+// deparsed definitions
+func normalizeViewDefinition(definition string) string {
+	return strings.Join(strings.Fields(trimStatement(definition)), " ")
+}
+
+func trimStatement(stmt string) string {
+	return strings.TrimSuffix(strings.TrimSpace(stmt), ";")
+}
+
+// GenerateCreateMaterializedViewSQL returns the CREATE MATERIALIZED VIEW
+// statement for a view, without its indexes
+func GenerateCreateMaterializedViewSQL(view types.SchemaMaterializedView) string {
+	sql := fmt.Sprintf("CREATE MATERIALIZED VIEW \"%s\" AS\n%s", view.Name, trimStatement(view.Definition))
+	if view.NoData {
+		sql += "\nWITH NO DATA"
+	}
+	return sql + ";"
+}
+
+// GenerateDropMaterializedViewSQL returns the DROP MATERIALIZED VIEW
+// statement for a view
+func GenerateDropMaterializedViewSQL(name string) string {
+	return fmt.Sprintf("DROP MATERIALIZED VIEW IF EXISTS \"%s\";", name)
+}
+
+// GenerateMaterializedViewsSQL renders materialized views and their indexes
+// for a schema file
+func GenerateMaterializedViewsSQL(views []types.SchemaMaterializedView, indexSQL func(types.SchemaIndex) string) string {
+	sorted := append([]types.SchemaMaterializedView(nil), views...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var parts []string
+	for _, view := range sorted {
+		part := GenerateCreateMaterializedViewSQL(view)
+		for _, index := range view.Indexes {
+			part += "\n" + indexSQL(index)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -124,22 +124,24 @@ func (sm *SchemaManager) parseCreateTableStatement(stmt string) (types.SchemaTab
 		return types.SchemaTable{}, fmt.Errorf("could not extract table name")
 	}
 
+	stmt, partitionBy := splitPartitionBy(stmt)
+
 	start, end := strings.Index(stmt, "("), strings.LastIndex(stmt, ")")
 	if start == -1 || end == -1 {
 		return types.SchemaTable{}, fmt.Errorf("invalid CREATE TABLE syntax")
 	}
 
-	columns, foreignKeys, err := sm.parseColumnDefinitionsAndConstraints(stmt[start+1 : end])
+	columns, constraints, err := sm.parseColumnDefinitionsAndConstraints(stmt[start+1 : end])
 	if err != nil {
 		return types.SchemaTable{}, err
 	}
 
-	sm.applyForeignKeys(columns, foreignKeys)
-
 	return types.SchemaTable{
-		Name:    tableName,
-		Columns: columns,
-		Indexes: []types.SchemaIndex{},
+		Name:        tableName,
+		Columns:     columns,
+		Indexes:     []types.SchemaIndex{},
+		Constraints: applyTableConstraints(columns, constraints),
+		PartitionBy: partitionBy,
 	}, nil
 }
 
@@ -152,22 +154,9 @@ func (sm *SchemaManager) extractTableName(matches []string) string {
 	return ""
 }
 
-func (sm *SchemaManager) applyForeignKeys(columns []types.SchemaColumn, foreignKeys []foreignKeyConstraint) {
-	for _, fk := range foreignKeys {
-		for i := range columns {
-			if columns[i].Name == fk.ColumnName {
-				columns[i].ForeignKeyTable = fk.ReferencedTable
-				columns[i].ForeignKeyColumn = fk.ReferencedColumn
-				columns[i].OnDeleteAction = fk.OnDeleteAction
-				break
-			}
-		}
-	}
-}
-
-func (sm *SchemaManager) parseColumnDefinitionsAndConstraints(columnDefs string) ([]types.SchemaColumn, []foreignKeyConstraint, error) {
+func (sm *SchemaManager) parseColumnDefinitionsAndConstraints(columnDefs string) ([]types.SchemaColumn, []types.SchemaConstraint, error) {
 	var columns []types.SchemaColumn
-	var foreignKeys []foreignKeyConstraint
+	var constraints []types.SchemaConstraint
 
 	for _, colDef := range sm.splitColumnDefinitions(columnDefs) {
 		if colDef = strings.TrimSpace(colDef); colDef == "" {
@@ -175,8 +164,8 @@ func (sm *SchemaManager) parseColumnDefinitionsAndConstraints(columnDefs string)
 		}
 
 		if sm.isTableConstraint(colDef) {
-			if fk := sm.parseForeignKeyConstraint(colDef); fk != nil {
-				foreignKeys = append(foreignKeys, *fk)
+			if constraint := parseTableConstraint(colDef); constraint != nil {
+				constraints = append(constraints, *constraint)
 			}
 			continue
 		}
@@ -188,25 +177,7 @@ func (sm *SchemaManager) parseColumnDefinitionsAndConstraints(columnDefs string)
 		columns = append(columns, column)
 	}
 
-	return columns, foreignKeys, nil
-}
-
-func (sm *SchemaManager) parseForeignKeyConstraint(constraint string) *foreignKeyConstraint {
-	fkRegex := regexp.MustCompile(`(?i)FOREIGN\s+KEY\s*\(\s*(\w+)\s*\)\s+REFERENCES\s+(\w+)\s*\(\s*(\w+)\s*\)(?:\s+ON\s+DELETE\s+(CASCADE|SET\s+NULL|RESTRICT|NO\s+ACTION))?`)
-	matches := fkRegex.FindStringSubmatch(constraint)
-
-	if len(matches) >= 4 {
-		fk := &foreignKeyConstraint{
-			ColumnName:       matches[1],
-			ReferencedTable:  matches[2],
-			ReferencedColumn: matches[3],
-		}
-		if len(matches) >= 5 && matches[4] != "" {
-			fk.OnDeleteAction = strings.ToUpper(matches[4])
-		}
-		return fk
-	}
-	return nil
+	return columns, constraints, nil
 }
 
 func (sm *SchemaManager) splitColumnDefinitions(defs string) []string {
@@ -335,19 +306,85 @@ func (sm *SchemaManager) parseColumnConstraints(column *types.SchemaColumn, colD
 		}
 	}
 
-	referencesRegex := regexp.MustCompile(`(?i)REFERENCES\s+(\w+)\s*\(\s*(\w+)\s*\)`)
+	referencesRegex := regexp.MustCompile(`(?i)REFERENCES\s+((?:\w+\.)?\w+)\s*\(\s*(\w+)\s*\)`)
 	if matches := referencesRegex.FindStringSubmatch(colDef); len(matches) >= 3 {
-		column.ForeignKeyTable = matches[1]
+		column.ForeignKeyTable = referencedTableName(matches[1])
 		column.ForeignKeyColumn = matches[2]
 
 		onDeleteRegex := regexp.MustCompile(`(?i)ON\s+DELETE\s+(CASCADE|SET\s+NULL|RESTRICT|NO\s+ACTION)`)
 		if onDeleteMatches := onDeleteRegex.FindStringSubmatch(colDef); len(onDeleteMatches) >= 2 {
 			column.OnDeleteAction = strings.ToUpper(onDeleteMatches[1])
 		}
+		column.OnUpdateAction = parseOnUpdateAction(colDef)
+		column.ForeignKeyDeferrable = parseForeignKeyDeferrable(colDef)
+	}
+	if column.IsUnique {
+		column.UniqueDeferrable = parseUniqueDeferrable(colDef)
 	}
 
-	defaultRegex := regexp.MustCompile(`(?i)\bDEFAULT\s+([^,\s]+|'[^']*'|\([^)]*\))`)
+	// String literals first, so 'a b' isn't cut at the space
+	defaultRegex := regexp.MustCompile(`(?i)\bDEFAULT\s+('(?:[^']|'')*'|\([^)]*\)|[^,\s]+)`)
 	if matches := defaultRegex.FindStringSubmatch(colDef); len(matches) > 1 {
 		column.Default = matches[1]
 	}
 }
+
+// parseOnUpdateAction returns the ON UPDATE action of a reference, which
+// may come before or after its ON DELETE action
+func parseOnUpdateAction(def string) string {
+	onUpdateRegex := regexp.MustCompile(`(?i)ON\s+UPDATE\s+(CASCADE|SET\s+NULL|SET\s+DEFAULT|RESTRICT|NO\s+ACTION)`)
+	if matches := onUpdateRegex.FindStringSubmatch(def); len(matches) >= 2 {
+		return strings.Join(strings.Fields(strings.ToUpper(matches[1])), " ")
+	}
+	return ""
+}
+
+const deferrableClause = `(?:\s+NOT\s+DEFERRABLE|\s+DEFERRABLE|\s+INITIALLY\s+(?:DEFERRED|IMMEDIATE))`
+
+var (
+	foreignKeyDeferrableRegex = regexp.MustCompile(`(?i)REFERENCES\s+[\w."]+\s*\([^)]*\)((?:\s+ON\s+(?:DELETE|UPDATE)\s+(?:CASCADE|SET\s+NULL|SET\s+DEFAULT|RESTRICT|NO\s+ACTION)|` + deferrableClause + `)*)`)
+	uniqueDeferrableRegex     = regexp.MustCompile(`(?i)\bUNIQUE(` + deferrableClause + `*)`)
+)
+
+// parseForeignKeyDeferrable returns how the reference of a column or
+// FOREIGN KEY constraint is deferred, from the clauses after its target
+func parseForeignKeyDeferrable(def string) string {
+	if matches := foreignKeyDeferrableRegex.FindStringSubmatch(def); matches != nil {
+		return deferrableMode(matches[1])
+	}
+	return ""
+}
+
+// parseUniqueDeferrable returns how the UNIQUE constraint of a column is
+// deferred
+func parseUniqueDeferrable(def string) string {
+	if matches := uniqueDeferrableRegex.FindStringSubmatch(def); matches != nil {
+		return deferrableMode(matches[1])
+	}
+	return ""
+}
+
+// deferrableMode reduces deferral clauses to DEFERRABLE or DEFERRABLE
+// INITIALLY DEFERRED; INITIALLY DEFERRED alone implies DEFERRABLE
+func deferrableMode(clauses string) string {
+	clauses = strings.ToUpper(strings.Join(strings.Fields(clauses), " "))
+	switch {
+	case strings.Contains(clauses, "NOT DEFERRABLE"):
+		return ""
+	case strings.Contains(clauses, "INITIALLY DEFERRED"):
+		return "DEFERRABLE INITIALLY DEFERRED"
+	case strings.Contains(clauses, "DEFERRABLE"):
+		return "DEFERRABLE"
+	}
+	return ""
+}
+
+// referencedTableName drops a redundant public. prefix; other schema
+// prefixes are kept so references to externally managed tables (e.g.
+// auth.users on Supabase) survive
+func referencedTableName(name string) string {
+	if strings.HasPrefix(strings.ToLower(name), "public.") {
+		return name[len("public."):]
+	}
+	return name
+}
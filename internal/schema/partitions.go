@@ -0,0 +1,122 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+var (
+	partitionOfRegex = regexp.MustCompile(`(?i)^CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?(?:"?\w+"?\.)?"?(\w+)"?\s+PARTITION\s+OF\s+(?:"?\w+"?\.)?"?(\w+)"?\s+(.+)$`)
+	partitionByRegex = regexp.MustCompile(`(?i)\)\s*PARTITION\s+BY\s+(RANGE|LIST|HASH)\s*(\(.*\))\s*$`)
+)
+
+// PartitionIntrospector is implemented by adapters that can read table
+// partitions from the database (currently PostgreSQL).
+type PartitionIntrospector interface {
+	GetCurrentPartitions(ctx context.Context) ([]types.SchemaPartition, error)
+}
+
+// ParseSchemaPartitions reads the CREATE TABLE ... PARTITION OF statements
+// from a schema file or directory
+func (sm *SchemaManager) ParseSchemaPartitions(schemaPath string) ([]types.SchemaPartition, error) {
+	info, err := os.Stat(schemaPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat schema path: %w", err)
+	}
+
+	files := []string{schemaPath}
+	if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(schemaPath, "*.sql"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+	}
+
+	var partitions []types.SchemaPartition
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema file %s: %w", file, err)
+		}
+		for _, stmt := range sm.splitStatements(sm.cleanSQL(string(content))) {
+			if partition, ok := parsePartitionOfStatement(stmt); ok {
+				partitions = append(partitions, partition)
+			}
+		}
+	}
+	return partitions, nil
+}
+
+func parsePartitionOfStatement(stmt string) (types.SchemaPartition, bool) {
+	matches := partitionOfRegex.FindStringSubmatch(strings.TrimSpace(stmt))
+	if matches == nil {
+		return types.SchemaPartition{}, false
+	}
+	return types.SchemaPartition{Name: matches[1], Parent: matches[2], Bound: strings.TrimSpace(matches[3])}, true
+}
+
+// splitPartitionBy removes the PARTITION BY clause of a CREATE TABLE
+// statement and returns it as a partition key, e.g. "RANGE (created_at)"
+func splitPartitionBy(stmt string) (string, string) {
+	loc := partitionByRegex.FindStringSubmatchIndex(stmt)
+	if loc == nil {
+		return stmt, ""
+	}
+	key := strings.ToUpper(stmt[loc[2]:loc[3]]) + " " + stmt[loc[4]:loc[5]]
+	return stmt[:loc[0]+1], key
+}
+
+// comparePartitions fills the partition part of the diff. Partitions are
+// only managed for the partitioned tables whose schema declares at least one
+// partition, so partitions created by tools like pg_partman are left alone.
+// Partitions are matched by name; their bounds aren't compared.
+func (sm *SchemaManager) comparePartitions(current, target []types.SchemaPartition, diff *types.SchemaDiff) {
+	declaredParents := make(map[string]bool)
+	targetMap := make(map[string]bool, len(target))
+	for _, partition := range target {
+		declaredParents[partition.Parent] = true
+		targetMap[partition.Name] = true
+	}
+
+	currentMap := make(map[string]bool, len(current))
+	for _, partition := range current {
+		currentMap[partition.Name] = true
+		if !declaredParents[partition.Parent] || targetMap[partition.Name] {
+			continue
+		}
+		if sm.ignoreTable != nil && sm.ignoreTable(partition.Parent) {
+			continue
+		}
+		diff.DroppedPartitions = append(diff.DroppedPartitions, partition)
+	}
+
+	for _, partition := range target {
+		if currentMap[partition.Name] {
+			continue
+		}
+		if sm.ignoreTable != nil && sm.ignoreTable(partition.Parent) {
+			continue
+		}
+		diff.NewPartitions = append(diff.NewPartitions, partition)
+	}
+}
+
+// GenerateCreatePartitionSQL returns the CREATE TABLE statement for a
+// partition
+func GenerateCreatePartitionSQL(partition types.SchemaPartition) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS \"%s\" PARTITION OF \"%s\" %s;", partition.Name, partition.Parent, trimStatement(partition.Bound))
+}
+
+// GenerateDropPartitionSQL returns the statement dropping a partition and
+// its rows
+func GenerateDropPartitionSQL(partition types.SchemaPartition) string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS \"%s\";", partition.Name)
+}
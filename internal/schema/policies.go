@@ -0,0 +1,517 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+var (
+	createPolicyRegex = regexp.MustCompile(`(?i)^CREATE\s+POLICY\s+(?:"([^"]+)"|(\w+))\s+ON\s+(?:"?\w+"?\.)?"?(\w+)"?`)
+	policyAsRegex     = regexp.MustCompile(`(?i)\bAS\s+(PERMISSIVE|RESTRICTIVE)\b`)
+	policyForRegex    = regexp.MustCompile(`(?i)\bFOR\s+(ALL|SELECT|INSERT|UPDATE|DELETE)\b`)
+	policyToRegex     = regexp.MustCompile(`(?i)\bTO\s+(.+?)\s*(?:\bUSING\b|\bWITH\s+CHECK\b|$)`)
+	policyUsingRegex  = regexp.MustCompile(`(?i)\bUSING\s*\(`)
+	policyCheckRegex  = regexp.MustCompile(`(?i)\bWITH\s+CHECK\s*\(`)
+	enableRLSRegex    = regexp.MustCompile(`(?i)^ALTER\s+TABLE\s+(?:ONLY\s+)?(?:"?\w+"?\.)?"?(\w+)"?\s+ENABLE\s+ROW\s+LEVEL\s+SECURITY$`)
+	policyIdentRegex  = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+)
+
+// PolicyIntrospector is implemented by adapters that can read row-level
+// security state from the database (currently PostgreSQL).
+type PolicyIntrospector interface {
+	GetCurrentPolicies(ctx context.Context) ([]types.SchemaPolicy, error)
+	GetRLSEnabledTables(ctx context.Context) ([]string, error)
+}
+
+// ParseSchemaPolicies reads the CREATE POLICY and ENABLE ROW LEVEL SECURITY
+// statements from a schema file or directory. declared reports whether the
+// schema mentions row-level security at all; when it doesn't, existing
+// policies are left alone.
+func (sm *SchemaManager) ParseSchemaPolicies(schemaPath string) (policies []types.SchemaPolicy, rlsTables []string, declared bool, err error) {
+	info, err := os.Stat(schemaPath)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to stat schema path: %w", err)
+	}
+
+	files := []string{schemaPath}
+	if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(schemaPath, "*.sql"))
+		if err != nil {
+			return nil, nil, false, err
+		}
+		sort.Strings(files)
+	}
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("failed to read schema file %s: %w", file, err)
+		}
+		for _, stmt := range sm.splitStatements(sm.cleanSQL(string(content))) {
+			if matches := enableRLSRegex.FindStringSubmatch(stmt); matches != nil {
+				rlsTables = append(rlsTables, matches[1])
+				declared = true
+			} else if policy, ok := parseCreatePolicyStatement(stmt); ok {
+				policies = append(policies, policy)
+				declared = true
+			}
+		}
+	}
+
+	return policies, rlsTables, declared, nil
+}
+
+func parseCreatePolicyStatement(stmt string) (types.SchemaPolicy, bool) {
+	matches := createPolicyRegex.FindStringSubmatch(stmt)
+	if matches == nil {
+		return types.SchemaPolicy{}, false
+	}
+
+	policy := types.SchemaPolicy{
+		Name:       matches[1] + matches[2],
+		Table:      matches[3],
+		Command:    "ALL",
+		Permissive: "PERMISSIVE",
+	}
+
+	rest := stmt[len(matches[0]):]
+	using := extractPolicyExpression(rest, policyUsingRegex)
+	check := extractPolicyExpression(rest, policyCheckRegex)
+
+	// Only look for AS/FOR/TO before the expressions so keywords inside
+	// them are not picked up
+	header := rest
+	if loc := policyUsingRegex.FindStringIndex(header); loc != nil {
+		header = header[:loc[0]]
+	}
+	if loc := policyCheckRegex.FindStringIndex(header); loc != nil {
+		header = header[:loc[0]]
+	}
+
+	if m := policyAsRegex.FindStringSubmatch(header); m != nil {
+		policy.Permissive = strings.ToUpper(m[1])
+	}
+	if m := policyForRegex.FindStringSubmatch(header); m != nil {
+		policy.Command = strings.ToUpper(m[1])
+	}
+	if m := policyToRegex.FindStringSubmatch(header); m != nil {
+		for _, role := range strings.Split(m[1], ",") {
+			role = strings.Trim(strings.TrimSpace(role), `"`)
+			if role != "" && !strings.EqualFold(role, "public") {
+				policy.Roles = append(policy.Roles, role)
+			}
+		}
+	}
+
+	policy.Using = using
+	policy.WithCheck = check
+	return policy, true
+}
+
+// extractPolicyExpression returns the text inside the parentheses that
+// follow the keyword matched by re, respecting nesting and string literals.
+func extractPolicyExpression(sql string, re *regexp.Regexp) string {
+	loc := re.FindStringIndex(sql)
+	if loc == nil {
+		return ""
+	}
+
+	depth := 1
+	inString := false
+	start := loc[1]
+	for i := start; i < len(sql); i++ {
+		switch c := sql[i]; {
+		case c == '\'':
+			inString = !inString
+		case inString:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return strings.TrimSpace(sql[start:i])
+			}
+		}
+	}
+	return ""
+}
+
+// normalizePolicyExpression makes a schema-file expression comparable with
+// the deparsed form PostgreSQL reports. Outside string literals it evens out
+// whitespace, casing, casts and quotes around identifiers that don't need
+// them, and drops the parentheses PostgreSQL adds; those that group an
+// expression differently are kept.
+func normalizePolicyExpression(expr string) string {
+	tokens := dropPolicyCasts(policyTokens(expr))
+	nodes, _ := parsePolicyGroup(tokens, 0)
+	var out []string
+	writePolicyNodes(&out, simplifyPolicyGroup(nodes))
+	return strings.Join(out, " ")
+}
+
+// policyTokens splits an expression into words, string literals, quoted
+// identifiers, operators and punctuation. Words are lowercased, and quoted
+// identifiers that would read the same unquoted lose their quotes.
+func policyTokens(expr string) []string {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'' || c == '"':
+			j := i + 1
+			for j < len(expr) {
+				if expr[j] == c {
+					if j+1 < len(expr) && expr[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			j = min(j+1, len(expr))
+			token := expr[i:j]
+			if c == '"' && policyIdentRegex.MatchString(strings.Trim(token, `"`)) {
+				token = strings.Trim(token, `"`)
+			}
+			tokens = append(tokens, token)
+			i = j
+		case isPolicyWordChar(c):
+			j := i
+			for j < len(expr) && isPolicyWordChar(expr[j]) {
+				j++
+			}
+			tokens = append(tokens, strings.ToLower(expr[i:j]))
+			i = j
+		case strings.IndexByte("(),.[];", c) >= 0:
+			tokens = append(tokens, string(c))
+			i++
+		default:
+			j := i
+			for j < len(expr) && strings.IndexByte("+-*/<>=~!@#%^&|`?:", expr[j]) >= 0 {
+				j++
+			}
+			j = max(j, i+1)
+			tokens = append(tokens, expr[i:j])
+			i = j
+		}
+	}
+	return tokens
+}
+
+func isPolicyWordChar(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// dropPolicyCasts removes the casts PostgreSQL adds, such as ::text or
+// ::character varying[]
+func dropPolicyCasts(tokens []string) []string {
+	out := make([]string, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i] != "::" || i+1 >= len(tokens) {
+			out = append(out, tokens[i])
+			continue
+		}
+		i++
+		if i+1 < len(tokens) && tokens[i+1] == "varying" {
+			i++
+		}
+		if i+2 < len(tokens) && tokens[i+1] == "[" && tokens[i+2] == "]" {
+			i += 2
+		}
+	}
+	return out
+}
+
+// policyNode is a token of an expression, or a parenthesized group
+type policyNode struct {
+	token string
+	group []policyNode
+}
+
+func (n policyNode) isGroup() bool {
+	return n.group != nil
+}
+
+// parsePolicyGroup reads nodes up to the parenthesis closing the group
+// that starts at i, returning where it stopped
+func parsePolicyGroup(tokens []string, i int) ([]policyNode, int) {
+	nodes := []policyNode{}
+	for i < len(tokens) {
+		switch tokens[i] {
+		case "(":
+			group, next := parsePolicyGroup(tokens, i+1)
+			nodes = append(nodes, policyNode{group: group})
+			i = next
+		case ")":
+			return nodes, i + 1
+		default:
+			nodes = append(nodes, policyNode{token: tokens[i]})
+			i++
+		}
+	}
+	return nodes, i
+}
+
+// simplifyPolicyGroup drops parentheses that can't change what an
+// expression means: around a whole expression or a single term, and around
+// a condition between AND and OR that has no AND or OR of its own.
+// Parentheses after a function name are its arguments and stay.
+func simplifyPolicyGroup(nodes []policyNode) []policyNode {
+	for i := range nodes {
+		if nodes[i].isGroup() {
+			nodes[i].group = simplifyPolicyGroup(nodes[i].group)
+		}
+	}
+	for len(nodes) == 1 && nodes[0].isGroup() {
+		nodes = nodes[0].group
+	}
+
+	out := make([]policyNode, 0, len(nodes))
+	for i, node := range nodes {
+		if !node.isGroup() || len(node.group) == 0 {
+			out = append(out, node)
+			continue
+		}
+		if isPolicyCall(out) {
+			out = append(out, node)
+			continue
+		}
+		// Groups have no token, so a group next to a group isn't at a
+		// boundary
+		startsCondition := len(out) == 0 || isPolicyConnective(out[len(out)-1].token)
+		endsCondition := i+1 == len(nodes) || isPolicyConnective(nodes[i+1].token)
+		if isPolicyTerm(node.group) || (!hasPolicyConnective(node.group) && startsCondition && endsCondition) {
+			out = append(out, node.group...)
+			continue
+		}
+		out = append(out, node)
+	}
+	return out
+}
+
+// isPolicyCall reports whether a group following nodes holds the arguments
+// of a function
+func isPolicyCall(nodes []policyNode) bool {
+	if len(nodes) == 0 {
+		return false
+	}
+	last := nodes[len(nodes)-1]
+	return !last.isGroup() && isPolicyWord(last.token) && !isPolicyConnective(last.token) && last.token != "not"
+}
+
+// isPolicyTerm reports whether nodes are a single term, such as a column,
+// a literal or a function call, which needs no parentheses
+func isPolicyTerm(nodes []policyNode) bool {
+	expectTerm := true
+	for _, node := range nodes {
+		switch {
+		case node.token == ".":
+			if expectTerm {
+				return false
+			}
+			expectTerm = true
+		case node.isGroup():
+			if expectTerm {
+				return false
+			}
+		case isPolicyWord(node.token) && !isPolicyConnective(node.token) && node.token != "not":
+			if !expectTerm {
+				return false
+			}
+			expectTerm = false
+		default:
+			return false
+		}
+	}
+	return !expectTerm
+}
+
+// hasPolicyConnective reports whether nodes hold an AND or an OR outside
+// their groups
+func hasPolicyConnective(nodes []policyNode) bool {
+	for _, node := range nodes {
+		if !node.isGroup() && isPolicyConnective(node.token) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPolicyConnective(token string) bool {
+	return token == "and" || token == "or"
+}
+
+// isPolicyWord reports whether a token is a word, a literal or a quoted
+// identifier rather than an operator or punctuation
+func isPolicyWord(token string) bool {
+	return token != "" && (token[0] == '\'' || token[0] == '"' || isPolicyWordChar(token[0]))
+}
+
+func writePolicyNodes(out *[]string, nodes []policyNode) {
+	for _, node := range nodes {
+		if !node.isGroup() {
+			*out = append(*out, node.token)
+			continue
+		}
+		*out = append(*out, "(")
+		writePolicyNodes(out, node.group)
+		*out = append(*out, ")")
+	}
+}
+
+func policiesEqual(a, b types.SchemaPolicy) bool {
+	if !strings.EqualFold(a.Command, b.Command) || !strings.EqualFold(a.Permissive, b.Permissive) {
+		return false
+	}
+	if normalizePolicyExpression(a.Using) != normalizePolicyExpression(b.Using) ||
+		normalizePolicyExpression(a.WithCheck) != normalizePolicyExpression(b.WithCheck) {
+		return false
+	}
+
+	rolesA := append([]string(nil), a.Roles...)
+	rolesB := append([]string(nil), b.Roles...)
+	sort.Strings(rolesA)
+	sort.Strings(rolesB)
+	return strings.Join(rolesA, ",") == strings.Join(rolesB, ",")
+}
+
+// comparePolicies fills the row-level security part of the diff. A changed
+// policy is dropped and recreated since ALTER POLICY cannot change its command.
+func (sm *SchemaManager) comparePolicies(currentPolicies, targetPolicies []types.SchemaPolicy, currentRLS, targetRLS []string, diff *types.SchemaDiff) {
+	dropped := make(map[string]bool, len(diff.DroppedTables))
+	for _, table := range diff.DroppedTables {
+		dropped[table] = true
+	}
+	if sm.ignoreTable != nil {
+		currentPolicies = filterPolicies(currentPolicies, sm.ignoreTable)
+		targetPolicies = filterPolicies(targetPolicies, sm.ignoreTable)
+		for _, table := range currentRLS {
+			if sm.ignoreTable(table) {
+				dropped[table] = true
+			}
+		}
+	}
+
+	policyKey := func(p types.SchemaPolicy) string { return p.Table + "." + p.Name }
+
+	currentMap := make(map[string]types.SchemaPolicy, len(currentPolicies))
+	for _, policy := range currentPolicies {
+		currentMap[policyKey(policy)] = policy
+	}
+	targetMap := make(map[string]types.SchemaPolicy, len(targetPolicies))
+	for _, policy := range targetPolicies {
+		targetMap[policyKey(policy)] = policy
+	}
+
+	for _, policy := range targetPolicies {
+		current, exists := currentMap[policyKey(policy)]
+		if !exists {
+			diff.NewPolicies = append(diff.NewPolicies, policy)
+		} else if !policiesEqual(current, policy) {
+			diff.DroppedPolicies = append(diff.DroppedPolicies, current)
+			diff.NewPolicies = append(diff.NewPolicies, policy)
+		}
+	}
+	for _, policy := range currentPolicies {
+		if _, exists := targetMap[policyKey(policy)]; !exists && !dropped[policy.Table] {
+			diff.DroppedPolicies = append(diff.DroppedPolicies, policy)
+		}
+	}
+
+	currentSet := make(map[string]bool, len(currentRLS))
+	for _, table := range currentRLS {
+		currentSet[table] = true
+	}
+	targetSet := make(map[string]bool, len(targetRLS))
+	for _, table := range targetRLS {
+		if !targetSet[table] && !currentSet[table] {
+			diff.RLSEnabledTables = append(diff.RLSEnabledTables, table)
+		}
+		targetSet[table] = true
+	}
+	for _, table := range currentRLS {
+		if !targetSet[table] && !dropped[table] {
+			diff.RLSDisabledTables = append(diff.RLSDisabledTables, table)
+		}
+	}
+}
+
+func filterPolicies(policies []types.SchemaPolicy, ignore func(string) bool) []types.SchemaPolicy {
+	filtered := make([]types.SchemaPolicy, 0, len(policies))
+	for _, policy := range policies {
+		if !ignore(policy.Table) {
+			filtered = append(filtered, policy)
+		}
+	}
+	return filtered
+}
+
+// GenerateCreatePolicySQL returns the CREATE POLICY statement for a policy
+func GenerateCreatePolicySQL(policy types.SchemaPolicy) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `CREATE POLICY "%s" ON "%s"`, policy.Name, policy.Table)
+	if policy.Permissive != "" && !strings.EqualFold(policy.Permissive, "PERMISSIVE") {
+		fmt.Fprintf(&b, " AS %s", strings.ToUpper(policy.Permissive))
+	}
+	if policy.Command != "" && !strings.EqualFold(policy.Command, "ALL") {
+		fmt.Fprintf(&b, " FOR %s", strings.ToUpper(policy.Command))
+	}
+	if len(policy.Roles) > 0 {
+		fmt.Fprintf(&b, " TO %s", strings.Join(policy.Roles, ", "))
+	}
+	if policy.Using != "" {
+		fmt.Fprintf(&b, " USING (%s)", policy.Using)
+	}
+	if policy.WithCheck != "" {
+		fmt.Fprintf(&b, " WITH CHECK (%s)", policy.WithCheck)
+	}
+	b.WriteString(";")
+	return b.String()
+}
+
+// GenerateDropPolicySQL returns the DROP POLICY statement for a policy
+func GenerateDropPolicySQL(policy types.SchemaPolicy) string {
+	return fmt.Sprintf(`DROP POLICY IF EXISTS "%s" ON "%s";`, policy.Name, policy.Table)
+}
+
+// GenerateEnableRLSSQL returns the statement that turns on row-level security
+func GenerateEnableRLSSQL(table string) string {
+	return fmt.Sprintf(`ALTER TABLE "%s" ENABLE ROW LEVEL SECURITY;`, table)
+}
+
+// GenerateDisableRLSSQL returns the statement that turns off row-level security
+func GenerateDisableRLSSQL(table string) string {
+	return fmt.Sprintf(`ALTER TABLE "%s" DISABLE ROW LEVEL SECURITY;`, table)
+}
+
+// GeneratePoliciesSQL renders RLS tables and their policies for a schema file
+func GeneratePoliciesSQL(rlsTables []string, policies []types.SchemaPolicy) string {
+	tables := append([]string(nil), rlsTables...)
+	sort.Strings(tables)
+	sorted := append([]types.SchemaPolicy(nil), policies...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Table != sorted[j].Table {
+			return sorted[i].Table < sorted[j].Table
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	var parts []string
+	for _, table := range tables {
+		parts = append(parts, GenerateEnableRLSSQL(table))
+	}
+	for _, policy := range sorted {
+		parts = append(parts, GenerateCreatePolicySQL(policy))
+	}
+	return strings.Join(parts, "\n")
+}
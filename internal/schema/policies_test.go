@@ -0,0 +1,35 @@
+package schema
+
+import "testing"
+
+func TestNormalizePolicyExpression(t *testing.T) {
+	same := []struct{ schema, deparsed string }{
+		{"user_id = auth.uid()", "(user_id = auth.uid())"},
+		{`"owner_id" = auth.uid() AND published`, "((owner_id = auth.uid()) AND published)"},
+		{"tenant_id = current_setting('app.tenant')::uuid", "(tenant_id = (current_setting('app.tenant'::text))::uuid)"},
+		{"role = ANY (ARRAY['admin', 'owner'])", "(role = ANY (ARRAY['admin'::text, 'owner'::text]))"},
+		{"(a = 1 OR b = 2) AND c", "(((a = 1) OR (b = 2)) AND c)"},
+		{"a = 1 OR (b = 2 AND c = 3)", "((a = 1) OR ((b = 2) AND (c = 3)))"},
+		{"NOT deleted", "(NOT deleted)"},
+	}
+	for _, tc := range same {
+		if a, b := normalizePolicyExpression(tc.schema), normalizePolicyExpression(tc.deparsed); a != b {
+			t.Errorf("%q and %q should match, got %q and %q", tc.schema, tc.deparsed, a, b)
+		}
+	}
+
+	different := []struct{ a, b string }{
+		{"role = 'Admin'", "role = 'admin'"},
+		{"name = 'a (b)'", "name = 'a b'"},
+		{`note = 'say "hi"'`, "note = 'say hi'"},
+		{"name = 'a  b'", "name = 'a b'"},
+		{"(a OR b) AND c", "a OR (b AND c)"},
+		{"(a + b) * c = 0", "a + b * c = 0"},
+		{`"Owner" = auth.uid()`, "owner = auth.uid()"},
+	}
+	for _, tc := range different {
+		if normalizePolicyExpression(tc.a) == normalizePolicyExpression(tc.b) {
+			t.Errorf("%q and %q should differ, both are %q", tc.a, tc.b, normalizePolicyExpression(tc.a))
+		}
+	}
+}
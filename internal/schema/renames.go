@@ -0,0 +1,225 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+var (
+	renamedFromRegex      = regexp.MustCompile("(?i)--\\s*flash:renamed-from\\s+[`\"]?(\\w+)[`\"]?")
+	renameTableLineRegex  = regexp.MustCompile("(?i)^\\s*CREATE\\s+TABLE\\s+(?:IF\\s+NOT\\s+EXISTS\\s+)?[`\"]?(\\w+)[`\"]?")
+	renameColumnLineRegex = regexp.MustCompile("^\\s*[`\"]?(\\w+)[`\"]?\\s+\\w")
+	renameConstraintRegex = regexp.MustCompile(`(?i)^\s*(CONSTRAINT|PRIMARY|FOREIGN|UNIQUE|CHECK|INDEX|KEY)\b`)
+)
+
+// renameHints holds the -- flash:renamed-from annotations of a schema,
+// keyed by the new name
+type renameHints struct {
+	tables  map[string]string
+	columns map[string]map[string]string
+}
+
+// parseRenameHints reads -- flash:renamed-from <old> annotations. An
+// annotation applies to the CREATE TABLE or column definition on the same
+// line, or on the line right after it.
+func (sm *SchemaManager) parseRenameHints(schemaPath string) (*renameHints, error) {
+	hints := &renameHints{tables: map[string]string{}, columns: map[string]map[string]string{}}
+
+	info, err := os.Stat(schemaPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat schema path: %w", err)
+	}
+	files := []string{schemaPath}
+	if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(schemaPath, "*.sql")); err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+	}
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema file %s: %w", file, err)
+		}
+
+		var table, pending string
+		for _, line := range strings.Split(string(content), "\n") {
+			from := pending
+			if m := renamedFromRegex.FindStringSubmatch(line); m != nil {
+				from = m[1]
+			}
+			code := line
+			if idx := strings.Index(code, "--"); idx >= 0 {
+				code = code[:idx]
+			}
+
+			if strings.TrimSpace(code) == "" {
+				pending = from
+				continue
+			}
+			pending = ""
+
+			if m := renameTableLineRegex.FindStringSubmatch(code); m != nil {
+				table = m[1]
+				if from != "" {
+					hints.tables[table] = from
+				}
+				continue
+			}
+
+			if table != "" && from != "" && !renameConstraintRegex.MatchString(code) {
+				if m := renameColumnLineRegex.FindStringSubmatch(code); m != nil {
+					if hints.columns[table] == nil {
+						hints.columns[table] = map[string]string{}
+					}
+					hints.columns[table][m[1]] = from
+				}
+			}
+
+			if strings.HasPrefix(strings.TrimSpace(code), ")") {
+				table = ""
+			}
+		}
+	}
+
+	return hints, nil
+}
+
+// detectRenames turns drop+create pairs into renames. Declared renames
+// always apply; otherwise a dropped table with exactly the same columns as
+// a new one, or the only dropped and only new column of a table when their
+// type and nullability match, are reported as guessed renames when guess
+// is set.
+func (sm *SchemaManager) detectRenames(current []types.SchemaTable, hints *renameHints, diff *types.SchemaDiff, guess bool) {
+	currentMap := make(map[string]types.SchemaTable, len(current))
+	for _, table := range current {
+		currentMap[table.Name] = table
+	}
+
+	var newTables []types.SchemaTable
+	for _, table := range diff.NewTables {
+		from, guessed := hints.tables[table.Name], false
+		if from == "" && guess {
+			from, guessed = sm.guessRenamedTable(table, diff.DroppedTables, currentMap), true
+		}
+		idx := indexOf(diff.DroppedTables, from)
+		if from == "" || idx < 0 {
+			newTables = append(newTables, table)
+			continue
+		}
+
+		diff.DroppedTables = append(diff.DroppedTables[:idx], diff.DroppedTables[idx+1:]...)
+		diff.RenamedTables = append(diff.RenamedTables, types.TableRename{From: from, To: table, Guessed: guessed})
+
+		old := currentMap[from]
+		old.Name = table.Name
+		currentMap[table.Name] = old
+		if tableDiff := sm.compareTablesForDiff(old, table); tableDiff != nil {
+			diff.ModifiedTables = append(diff.ModifiedTables, *tableDiff)
+		}
+	}
+	diff.NewTables = newTables
+
+	for i := range diff.ModifiedTables {
+		sm.detectColumnRenames(&diff.ModifiedTables[i], hints.columns[diff.ModifiedTables[i].Name], guess)
+	}
+}
+
+// RenameTable turns the drop of table from and the creation of table to in
+// diff into a rename, keeping any column changes between the two.
+func (sm *SchemaManager) RenameTable(ctx context.Context, diff *types.SchemaDiff, from, to string) error {
+	current, err := sm.adapter.GetCurrentSchema(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current schema: %w", err)
+	}
+	hints := &renameHints{tables: map[string]string{to: from}, columns: map[string]map[string]string{}}
+	sm.detectRenames(sm.filterIgnoredTables(current), hints, diff, false)
+	return nil
+}
+
+// RenameColumn turns the drop of column from and the addition of column to
+// on table in diff into a rename.
+func (sm *SchemaManager) RenameColumn(diff *types.SchemaDiff, table, from, to string) {
+	for i := range diff.ModifiedTables {
+		if diff.ModifiedTables[i].Name == table {
+			sm.detectColumnRenames(&diff.ModifiedTables[i], map[string]string{to: from}, false)
+		}
+	}
+}
+
+func (sm *SchemaManager) guessRenamedTable(table types.SchemaTable, dropped []string, currentMap map[string]types.SchemaTable) string {
+	signature := tableSignature(table)
+	match := ""
+	for _, name := range dropped {
+		if tableSignature(currentMap[name]) == signature {
+			if match != "" {
+				return "" // ambiguous
+			}
+			match = name
+		}
+	}
+	return match
+}
+
+func tableSignature(table types.SchemaTable) string {
+	parts := make([]string, 0, len(table.Columns))
+	for _, column := range table.Columns {
+		parts = append(parts, strings.ToLower(column.Name+" "+column.Type))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func (sm *SchemaManager) detectColumnRenames(tableDiff *types.TableDiff, hints map[string]string, guess bool) {
+	rename := func(newIdx, droppedIdx int, guessed bool) {
+		from, to := tableDiff.DroppedColumns[droppedIdx], tableDiff.NewColumns[newIdx]
+		tableDiff.RenamedColumns = append(tableDiff.RenamedColumns, types.ColumnRename{From: from, To: to, Guessed: guessed})
+
+		renamed := from
+		renamed.Name = to.Name
+		if !sm.columnsEqual(renamed, to) {
+			tableDiff.ModifiedColumns = append(tableDiff.ModifiedColumns, sm.columnDiff(renamed, to))
+		}
+
+		tableDiff.NewColumns = append(tableDiff.NewColumns[:newIdx], tableDiff.NewColumns[newIdx+1:]...)
+		tableDiff.DroppedColumns = append(tableDiff.DroppedColumns[:droppedIdx], tableDiff.DroppedColumns[droppedIdx+1:]...)
+	}
+
+	for i := 0; i < len(tableDiff.NewColumns); i++ {
+		from := hints[tableDiff.NewColumns[i].Name]
+		if from == "" {
+			continue
+		}
+		for j, dropped := range tableDiff.DroppedColumns {
+			if dropped.Name == from {
+				rename(i, j, false)
+				i--
+				break
+			}
+		}
+	}
+
+	if guess && len(tableDiff.NewColumns) == 1 && len(tableDiff.DroppedColumns) == 1 {
+		added, dropped := tableDiff.NewColumns[0], tableDiff.DroppedColumns[0]
+		if strings.EqualFold(added.Type, dropped.Type) && added.Nullable == dropped.Nullable {
+			rename(0, 0, true)
+		}
+	}
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
@@ -11,18 +11,51 @@ import (
 	"github.com/Lumos-Labs-HQ/flash/internal/types"
 )
 
-type foreignKeyConstraint struct {
-	ColumnName, ReferencedTable, ReferencedColumn, OnDeleteAction string
-}
-
 type SchemaManager struct {
-	adapter database.DatabaseAdapter
+	adapter      database.DatabaseAdapter
+	ignoreTable  func(name string) bool
+	ignoreColumn func(table, column string) bool
 }
 
 func NewSchemaManager(adapter database.DatabaseAdapter) *SchemaManager {
 	return &SchemaManager{adapter: adapter}
 }
 
+// SetIgnoreTable sets the rule for tables that are managed outside of
+// flash. Ignored tables are never created, altered or dropped by a diff.
+func (sm *SchemaManager) SetIgnoreTable(ignore func(name string) bool) {
+	sm.ignoreTable = ignore
+}
+
+// SetIgnoreColumn sets the rule for columns that are managed outside of
+// flash. Ignored columns are never added, altered or dropped by a diff.
+func (sm *SchemaManager) SetIgnoreColumn(ignore func(table, column string) bool) {
+	sm.ignoreColumn = ignore
+}
+
+func (sm *SchemaManager) filterIgnoredTables(tables []types.SchemaTable) []types.SchemaTable {
+	if sm.ignoreTable == nil && sm.ignoreColumn == nil {
+		return tables
+	}
+	filtered := make([]types.SchemaTable, 0, len(tables))
+	for _, table := range tables {
+		if sm.ignoreTable != nil && sm.ignoreTable(table.Name) {
+			continue
+		}
+		if sm.ignoreColumn != nil {
+			columns := make([]types.SchemaColumn, 0, len(table.Columns))
+			for _, column := range table.Columns {
+				if !sm.ignoreColumn(table.Name, column.Name) {
+					columns = append(columns, column)
+				}
+			}
+			table.Columns = columns
+		}
+		filtered = append(filtered, table)
+	}
+	return filtered
+}
+
 // ParseSchemaFile parses a single schema file (legacy support)
 func (sm *SchemaManager) ParseSchemaFile(schemaPath string) ([]types.SchemaTable, error) {
 	content, err := os.ReadFile(schemaPath)
@@ -118,18 +151,41 @@ func (sm *SchemaManager) sortTablesByDependencies(tables []types.SchemaTable) ([
 	dependencies := make(map[string][]string)
 	for _, table := range tables {
 		var deps []string
+		// Each referenced table counts once, however many keys point at it
+		seen := make(map[string]bool)
 		for _, col := range table.Columns {
 			if col.ForeignKeyTable != "" {
-				// Validate that referenced table exists
+				// Validate that referenced table exists. Schema-qualified
+				// references (e.g. auth.users) point at tables flash doesn't manage
 				if _, exists := tableMap[col.ForeignKeyTable]; !exists {
+					if strings.Contains(col.ForeignKeyTable, ".") {
+						continue
+					}
 					return nil, fmt.Errorf("table '%s' references non-existent table '%s' (column '%s' has REFERENCES %s(%s))",
 						table.Name, col.ForeignKeyTable, col.Name, col.ForeignKeyTable, col.ForeignKeyColumn)
 				}
-				if col.ForeignKeyTable != table.Name {
+				if col.ForeignKeyTable != table.Name && !seen[col.ForeignKeyTable] {
+					seen[col.ForeignKeyTable] = true
 					deps = append(deps, col.ForeignKeyTable)
 				}
 			}
 		}
+		for _, constraint := range table.Constraints {
+			if constraint.Type != "FOREIGN KEY" {
+				continue
+			}
+			if _, exists := tableMap[constraint.RefTable]; !exists {
+				if strings.Contains(constraint.RefTable, ".") {
+					continue
+				}
+				return nil, fmt.Errorf("table '%s' references non-existent table '%s' (FOREIGN KEY (%s) REFERENCES %s(%s))",
+					table.Name, constraint.RefTable, strings.Join(constraint.Columns, ", "), constraint.RefTable, strings.Join(constraint.RefColumns, ", "))
+			}
+			if constraint.RefTable != table.Name && !seen[constraint.RefTable] {
+				seen[constraint.RefTable] = true
+				deps = append(deps, constraint.RefTable)
+			}
+		}
 		dependencies[table.Name] = deps
 	}
 
@@ -196,6 +252,7 @@ func (sm *SchemaManager) sortTablesByDependencies(tables []types.SchemaTable) ([
 				circular = append(circular, tableName)
 			}
 		}
+		sort.Strings(circular)
 		return nil, fmt.Errorf("circular foreign key dependency detected among tables: %v", circular)
 	}
 
@@ -308,8 +365,68 @@ func (sm *SchemaManager) GenerateSchemaDiff(ctx context.Context, targetSchemaPat
 		currentEnums = []types.SchemaEnum{}
 	}
 
+	currentTables = sm.filterIgnoredTables(currentTables)
+	targetTables = sm.filterIgnoredTables(targetTables)
+
 	// Pass both tables and standalone indexes to compareSchemas
 	diff := sm.compareSchemas(currentTables, targetTables, currentEnums, targetEnums, targetIndexes)
+
+	hints, err := sm.parseRenameHints(targetSchemaPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rename annotations: %w", err)
+	}
+	sm.detectRenames(currentTables, hints, diff, true)
+
+	// Row-level security is only managed once the schema declares it, so
+	// policies created outside of flash (e.g. by Supabase) are left alone
+	if introspector, ok := sm.adapter.(PolicyIntrospector); ok {
+		targetPolicies, targetRLS, declared, err := sm.ParseSchemaPolicies(targetSchemaPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse policies: %w", err)
+		}
+		if declared {
+			currentPolicies, err := introspector.GetCurrentPolicies(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get current policies: %w", err)
+			}
+			currentRLS, err := introspector.GetRLSEnabledTables(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get row-level security state: %w", err)
+			}
+			sm.comparePolicies(currentPolicies, targetPolicies, currentRLS, targetRLS, diff)
+		}
+	}
+
+	// Partitions are managed per partitioned table, once its schema
+	// declares one
+	if introspector, ok := sm.adapter.(PartitionIntrospector); ok {
+		targetPartitions, err := sm.ParseSchemaPartitions(targetSchemaPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse partitions: %w", err)
+		}
+		if len(targetPartitions) > 0 {
+			currentPartitions, err := introspector.GetCurrentPartitions(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get current partitions: %w", err)
+			}
+			sm.comparePartitions(currentPartitions, targetPartitions, diff)
+		}
+	}
+
+	// Materialized views are only managed once the schema declares one
+	if introspector, ok := sm.adapter.(MaterializedViewIntrospector); ok {
+		targetViews, err := sm.ParseSchemaMaterializedViews(targetSchemaPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse materialized views: %w", err)
+		}
+		if len(targetViews) > 0 {
+			currentViews, err := introspector.GetCurrentMaterializedViews(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get current materialized views: %w", err)
+			}
+			sm.compareMaterializedViews(ctx, introspector, currentViews, targetViews, diff)
+		}
+	}
 	
 	// DEBUG: Print diff results
 	// fmt.Printf("DEBUG: Diff has %d new indexes\n", len(diff.NewIndexes))
@@ -341,6 +458,15 @@ func (sm *SchemaManager) GenerateMigrationSQL(diff *types.SchemaDiff) string {
 		parts = append(parts, fmt.Sprintf("DROP TYPE IF EXISTS \"%s\";", enumName))
 	}
 
+	// Drop materialized views before the tables they read from change
+	for _, view := range diff.DroppedMaterializedViews {
+		parts = append(parts, GenerateDropMaterializedViewSQL(view.Name))
+	}
+
+	for _, partition := range diff.DroppedPartitions {
+		parts = append(parts, GenerateDropPartitionSQL(partition))
+	}
+
 	for _, tableName := range diff.DroppedTables {
 		parts = append(parts, fmt.Sprintf("DROP TABLE IF EXISTS \"%s\";", tableName))
 	}
@@ -353,6 +479,18 @@ func (sm *SchemaManager) GenerateMigrationSQL(diff *types.SchemaDiff) string {
 		}
 		parts = append(parts, fmt.Sprintf("CREATE TYPE \"%s\" AS ENUM (%s);", enum.Name, strings.Join(values, ", ")))
 	}
+	for _, enumDiff := range diff.ModifiedEnums {
+		if enumDiff.Recreate() {
+			continue
+		}
+		for _, add := range enumDiff.Added {
+			parts = append(parts, GenerateAddEnumValueSQL(enumDiff.Name, add))
+		}
+	}
+
+	for _, rename := range diff.RenamedTables {
+		parts = append(parts, fmt.Sprintf("ALTER TABLE \"%s\" RENAME TO \"%s\";", rename.From, rename.To.Name))
+	}
 
 	for _, table := range diff.NewTables {
 		parts = append(parts, sm.adapter.GenerateCreateTableSQL(table))
@@ -361,7 +499,15 @@ func (sm *SchemaManager) GenerateMigrationSQL(diff *types.SchemaDiff) string {
 		}
 	}
 
+	// Partitions are created once their partitioned tables exist
+	for _, partition := range diff.NewPartitions {
+		parts = append(parts, GenerateCreatePartitionSQL(partition))
+	}
+
 	for _, tableDiff := range diff.ModifiedTables {
+		for _, rename := range tableDiff.RenamedColumns {
+			parts = append(parts, fmt.Sprintf("ALTER TABLE \"%s\" RENAME COLUMN \"%s\" TO \"%s\";", tableDiff.Name, rename.From.Name, rename.To.Name))
+		}
 		for _, column := range tableDiff.NewColumns {
 			parts = append(parts, sm.adapter.GenerateAddColumnSQL(tableDiff.Name, column))
 		}
@@ -370,12 +516,42 @@ func (sm *SchemaManager) GenerateMigrationSQL(diff *types.SchemaDiff) string {
 		}
 	}
 
+	// Enums with dropped or reordered values once their columns are in place
+	for _, enumDiff := range diff.ModifiedEnums {
+		if enumDiff.Recreate() {
+			parts = append(parts, GenerateChangeEnumValuesSQL(enumDiff.Name, enumDiff.OldValues, enumDiff.Values, enumDiff.Columns)...)
+		}
+	}
+
 	for _, index := range diff.DroppedIndexes {
 		parts = append(parts, sm.adapter.GenerateDropIndexSQL(index))
 	}
+	for _, rename := range diff.RenamedIndexes {
+		parts = append(parts, fmt.Sprintf("ALTER INDEX \"%s\" RENAME TO \"%s\";", rename.From.Name, rename.To.Name))
+	}
 	for _, index := range diff.NewIndexes {
 		parts = append(parts, sm.adapter.GenerateAddIndexSQL(index))
 	}
 
+	for _, policy := range diff.DroppedPolicies {
+		parts = append(parts, GenerateDropPolicySQL(policy))
+	}
+	for _, table := range diff.RLSDisabledTables {
+		parts = append(parts, GenerateDisableRLSSQL(table))
+	}
+	for _, table := range diff.RLSEnabledTables {
+		parts = append(parts, GenerateEnableRLSSQL(table))
+	}
+	for _, policy := range diff.NewPolicies {
+		parts = append(parts, GenerateCreatePolicySQL(policy))
+	}
+
+	for _, view := range diff.NewMaterializedViews {
+		parts = append(parts, GenerateCreateMaterializedViewSQL(view))
+		for _, index := range view.Indexes {
+			parts = append(parts, sm.adapter.GenerateAddIndexSQL(index))
+		}
+	}
+
 	return strings.Join(parts, "\n\n")
 }
@@ -48,6 +48,7 @@ func (sc *SQLComparator) parseSQL(sql string) map[string]*TableStructure {
 				Name:        tableName,
 				Columns:     columns,
 				ColumnOrder: columnOrder,
+				Constraints: sc.parseConstraints(columnsDef),
 			}
 
 			tables[tableName] = table
@@ -84,7 +85,7 @@ func (sc *SQLComparator) parseColumnsWithOrder(columnsDef string) (map[string]*C
 
 	for _, part := range parts {
 		part = strings.TrimSpace(part)
-		if part == "" || strings.HasPrefix(strings.ToUpper(part), "FOREIGN KEY") {
+		if part == "" || strings.HasPrefix(strings.ToUpper(part), "FOREIGN KEY") || parseTableConstraint(part) != nil {
 			continue
 		}
 
@@ -98,6 +99,17 @@ func (sc *SQLComparator) parseColumnsWithOrder(columnsDef string) (map[string]*C
 	return columns, columnOrder
 }
 
+// parseConstraints extracts the constraints over more than one column
+func (sc *SQLComparator) parseConstraints(columnsDef string) []types.SchemaConstraint {
+	var constraints []types.SchemaConstraint
+	for _, part := range sc.smartSplit(columnsDef, ',') {
+		if constraint := parseTableConstraint(part); constraint != nil && len(constraint.Columns) > 1 {
+			constraints = append(constraints, *constraint)
+		}
+	}
+	return constraints
+}
+
 // parseColumn parses individual column definition
 func (sc *SQLComparator) parseColumn(def string) *ColumnStructure {
 	parts := strings.Fields(def)
@@ -130,6 +142,9 @@ func (sc *SQLComparator) extractProperties(def string) map[string]string {
 
 	if strings.Contains(defUpper, "UNIQUE") {
 		props["UNIQUE"] = "true"
+		if deferrable := parseUniqueDeferrable(def); deferrable != "" {
+			props["UNIQUE_DEFERRABLE"] = deferrable
+		}
 	}
 
 	if strings.Contains(defUpper, "NOT NULL") {
@@ -142,12 +157,15 @@ func (sc *SQLComparator) extractProperties(def string) map[string]string {
 		props["DEFAULT"] = sc.normalizeDefault(defaultMatch[1])
 	}
 
-	if refMatch := regexp.MustCompile(`(?i)REFERENCES\s+(\w+)\s*\(\s*(\w+)\s*\)(?:\s+ON\s+DELETE\s+(\w+(?:\s+\w+)?))?`).FindStringSubmatch(def); len(refMatch) > 2 {
-		fkRef := strings.ToLower(refMatch[1]) + "." + strings.ToLower(refMatch[2])
-		if len(refMatch) > 3 && refMatch[3] != "" {
-			fkRef += ":" + strings.ToUpper(strings.TrimSpace(refMatch[3]))
+	if refMatch := regexp.MustCompile(`(?i)REFERENCES\s+(?:public\.)?((?:\w+\.)?\w+)\s*\(\s*(\w+)\s*\)`).FindStringSubmatch(def); len(refMatch) > 2 {
+		var onDelete string
+		if deleteMatch := regexp.MustCompile(`(?i)ON\s+DELETE\s+(CASCADE|SET\s+NULL|SET\s+DEFAULT|RESTRICT|NO\s+ACTION)`).FindStringSubmatch(def); deleteMatch != nil {
+			onDelete = deleteMatch[1]
+		}
+		props["FOREIGN_KEY"] = sc.foreignKeyRef(refMatch[1], refMatch[2], onDelete, parseOnUpdateAction(def))
+		if deferrable := parseForeignKeyDeferrable(def); deferrable != "" {
+			props["FOREIGN_KEY_DEFERRABLE"] = deferrable
 		}
-		props["FOREIGN_KEY"] = fkRef
 	}
 
 	return props
@@ -162,6 +180,7 @@ func (sc *SQLComparator) normalizeDBTables(dbTables []types.SchemaTable) map[str
 			Name:        strings.ToLower(dbTable.Name),
 			Columns:     make(map[string]*ColumnStructure),
 			ColumnOrder: make([]string, 0, len(dbTable.Columns)),
+			Constraints: dbTable.Constraints,
 		}
 
 		for _, dbCol := range dbTable.Columns {
@@ -181,6 +200,9 @@ func (sc *SQLComparator) normalizeDBTables(dbTables []types.SchemaTable) map[str
 
 			if dbCol.IsUnique {
 				col.Properties["UNIQUE"] = "true"
+				if dbCol.UniqueDeferrable != "" {
+					col.Properties["UNIQUE_DEFERRABLE"] = dbCol.UniqueDeferrable
+				}
 			}
 
 			if !dbCol.Nullable {
@@ -194,11 +216,10 @@ func (sc *SQLComparator) normalizeDBTables(dbTables []types.SchemaTable) map[str
 			}
 
 			if dbCol.ForeignKeyTable != "" && dbCol.ForeignKeyColumn != "" {
-				fkRef := strings.ToLower(dbCol.ForeignKeyTable) + "." + strings.ToLower(dbCol.ForeignKeyColumn)
-				if dbCol.OnDeleteAction != "" {
-					fkRef += ":" + strings.ToUpper(strings.TrimSpace(dbCol.OnDeleteAction))
+				col.Properties["FOREIGN_KEY"] = sc.foreignKeyRef(dbCol.ForeignKeyTable, dbCol.ForeignKeyColumn, dbCol.OnDeleteAction, dbCol.OnUpdateAction)
+				if dbCol.ForeignKeyDeferrable != "" {
+					col.Properties["FOREIGN_KEY_DEFERRABLE"] = dbCol.ForeignKeyDeferrable
 				}
-				col.Properties["FOREIGN_KEY"] = fkRef
 			}
 
 			table.Columns[colName] = col
@@ -232,7 +253,7 @@ func (sc *SQLComparator) areEqual(existing, db map[string]*TableStructure) bool
 
 // areTablesEqual compares individual tables
 func (sc *SQLComparator) areTablesEqual(existing, db *TableStructure) bool {
-	if len(existing.Columns) != len(db.Columns) {
+	if len(existing.Columns) != len(db.Columns) || !sc.areConstraintsEqual(existing.Constraints, db.Constraints) {
 		return false
 	}
 
@@ -250,6 +271,26 @@ func (sc *SQLComparator) areTablesEqual(existing, db *TableStructure) bool {
 	return true
 }
 
+// areConstraintsEqual compares constraints regardless of their names and
+// order
+func (sc *SQLComparator) areConstraintsEqual(existing, db []types.SchemaConstraint) bool {
+	if len(existing) != len(db) {
+		return false
+	}
+	keys := make(map[string]int, len(existing))
+	for _, constraint := range existing {
+		keys[strings.ToLower(constraintKey(constraint))]++
+	}
+	for _, constraint := range db {
+		key := strings.ToLower(constraintKey(constraint))
+		if keys[key] == 0 {
+			return false
+		}
+		keys[key]--
+	}
+	return true
+}
+
 // areColumnsEqual compares column properties
 func (sc *SQLComparator) areColumnsEqual(existing, db *ColumnStructure) bool {
 	for key, dbValue := range db.Properties {
@@ -312,12 +353,18 @@ func (sc *SQLComparator) updateExistingSQL(originalSQL string, existing, db map[
 		return match
 	})
 
-	for tableName, dbTable := range db {
+	tableNames := make([]string, 0, len(db))
+	for tableName := range db {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+
+	for _, tableName := range tableNames {
 		if _, exists := existing[tableName]; !exists {
 			if result != "" && !strings.HasSuffix(result, "\n") {
 				result += "\n"
 			}
-			result += "\n" + sc.generateTableSQL(dbTable) + "\n"
+			result += "\n" + sc.generateTableSQL(db[tableName]) + "\n"
 		}
 	}
 
@@ -362,6 +409,7 @@ func (sc *SQLComparator) generateTableSQL(table *TableStructure) string {
 		for name := range table.Columns {
 			columnNames = append(columnNames, name)
 		}
+		sort.Strings(columnNames)
 	}
 
 	for j, colName := range columnNames {
@@ -376,6 +424,11 @@ func (sc *SQLComparator) generateTableSQL(table *TableStructure) string {
 		}
 	}
 
+	for _, constraint := range table.Constraints {
+		result.WriteString(",\n    ")
+		result.WriteString(DeclaredConstraint(constraint).Definition(func(name string) string { return name }))
+	}
+
 	result.WriteString("\n);")
 	return result.String()
 }
@@ -395,6 +448,9 @@ func (sc *SQLComparator) generateColumnSQL(col *ColumnStructure) string {
 	} else {
 		if col.Properties["UNIQUE"] == "true" {
 			parts = append(parts, "UNIQUE")
+			if deferrable := col.Properties["UNIQUE_DEFERRABLE"]; deferrable != "" {
+				parts = append(parts, deferrable)
+			}
 		}
 		if col.Properties["NOT_NULL"] == "true" {
 			parts = append(parts, "NOT NULL")
@@ -410,9 +466,15 @@ func (sc *SQLComparator) generateColumnSQL(col *ColumnStructure) string {
 		tableDotColumn := strings.Split(fkParts[0], ".")
 		if len(tableDotColumn) == 2 {
 			fkSQL := "REFERENCES " + tableDotColumn[0] + "(" + tableDotColumn[1] + ")"
-			if len(fkParts) > 1 {
+			if len(fkParts) > 1 && fkParts[1] != "" {
 				fkSQL += " ON DELETE " + fkParts[1]
 			}
+			if len(fkParts) > 2 {
+				fkSQL += " ON UPDATE " + fkParts[2]
+			}
+			if deferrable := col.Properties["FOREIGN_KEY_DEFERRABLE"]; deferrable != "" {
+				fkSQL += " " + deferrable
+			}
 			parts = append(parts, fkSQL)
 		}
 	}
@@ -458,6 +520,25 @@ func (sc *SQLComparator) normalizeType(dataType string) string {
 	return strings.ToLower(typeTrimmed)
 }
 
+// foreignKeyRef writes a reference as table.column:ON_DELETE:ON_UPDATE,
+// leaving out the actions not given. NO ACTION on update is the default and
+// left out too.
+func (sc *SQLComparator) foreignKeyRef(table, column, onDelete, onUpdate string) string {
+	fkRef := strings.ToLower(table) + "." + strings.ToLower(column)
+	onDelete = strings.ToUpper(strings.Join(strings.Fields(onDelete), " "))
+	onUpdate = strings.ToUpper(strings.Join(strings.Fields(onUpdate), " "))
+	if onUpdate == "NO ACTION" {
+		onUpdate = ""
+	}
+	if onDelete != "" || onUpdate != "" {
+		fkRef += ":" + onDelete
+	}
+	if onUpdate != "" {
+		fkRef += ":" + onUpdate
+	}
+	return fkRef
+}
+
 func (sc *SQLComparator) normalizeDefault(defaultVal string) string {
 	if defaultVal == "" {
 		return ""
@@ -518,6 +599,7 @@ type TableStructure struct {
 	Name        string
 	Columns     map[string]*ColumnStructure
 	ColumnOrder []string
+	Constraints []types.SchemaConstraint
 }
 
 type ColumnStructure struct {
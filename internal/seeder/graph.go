@@ -1,6 +1,9 @@
 package seeder
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 type DependencyGraph struct {
 	tables map[string]*TableInfo
@@ -50,7 +53,14 @@ func (g *DependencyGraph) BuildInsertionOrder() ([]string, error) {
 		return nil
 	}
 
+	// Visit the tables by name so the order is the same on every run
+	names := make([]string, 0, len(g.tables))
 	for tableName := range g.tables {
+		names = append(names, tableName)
+	}
+	sort.Strings(names)
+
+	for _, tableName := range names {
 		if !visited[tableName] {
 			if err := visit(tableName); err != nil {
 				return nil, err
@@ -10,6 +10,7 @@ import (
 
 	"github.com/Lumos-Labs-HQ/flash/internal/config"
 	"github.com/Lumos-Labs-HQ/flash/internal/database"
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
 	"github.com/fatih/color"
 )
 
@@ -26,13 +27,22 @@ type Seeder struct {
 }
 
 func NewSeeder(cfg *config.Config) (*Seeder, error) {
-	adapter := database.NewAdapter(cfg.Database.Provider)
-
 	dbURL, err := cfg.GetDatabaseURL()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get database URL: %w", err)
 	}
 
+	return NewSeederForURL(cfg, dbURL)
+}
+
+// NewSeederForURL creates a seeder connected to dbURL instead of the URL in
+// the configured environment variable.
+func NewSeederForURL(cfg *config.Config, dbURL string) (*Seeder, error) {
+	adapter := database.NewAdapter(cfg.Database.Provider)
+	if err := database.Require(adapter, cfg.Database.Provider, common.CapabilityQueries); err != nil {
+		return nil, err
+	}
+
 	if err := adapter.Connect(context.Background(), dbURL); err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -0,0 +1,397 @@
+package seeder
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// SnapshotConfig selects what `flash seed snapshot` captures
+type SnapshotConfig struct {
+	Tables []string // tables to capture
+	Limit  int      // rows per table, 0 for all
+	Dir    string   // folder the fixture files are written to
+}
+
+// Anonymization rules of seed.anonymize
+const (
+	AnonymizeNull  = "null"  // NULL
+	AnonymizeHash  = "hash"  // the first 16 hex digits of the value's SHA-256
+	AnonymizeEmail = "email" // user_<hash>@example.com, unique when the values are
+	AnonymizeFake  = "fake"  // a random value for the column's name and type, as flash seed makes
+)
+
+// snapshotHeader starts every fixture file, so a new snapshot knows which
+// files it replaces
+const snapshotHeader = "-- Snapshot of "
+
+var fixtureFileRegex = regexp.MustCompile(`^\d+_\w+\.sql$`)
+
+// snapshotBatch is the number of rows per INSERT statement
+const snapshotBatch = 100
+
+// Snapshot copies the rows of the selected tables into one SQL file each,
+// numbered in foreign key order so loading them in name order satisfies the
+// constraints. Rows referencing a row of a captured table that wasn't
+// captured, because of the limit, are left out. Columns are anonymized by
+// the rules of seed.anonymize. The files of the previous snapshot are
+// replaced.
+func (s *Seeder) Snapshot(ctx context.Context, snapshot SnapshotConfig) error {
+	anonymize, err := s.anonymizeRules()
+	if err != nil {
+		return err
+	}
+
+	tables, err := s.parseSchema()
+	if err != nil {
+		return fmt.Errorf("failed to parse schema: %w", err)
+	}
+
+	selected := make(map[string]bool, len(snapshot.Tables))
+	for _, name := range snapshot.Tables {
+		if tables[name] == nil {
+			return fmt.Errorf("table %s is not in the schema", name)
+		}
+		if !isValidIdentifier(name) {
+			return fmt.Errorf("invalid table name: %s", name)
+		}
+		selected[name] = true
+	}
+
+	for _, table := range tables {
+		s.graph.AddTable(table)
+	}
+	fullOrder, err := s.graph.BuildInsertionOrder()
+	if err != nil {
+		return fmt.Errorf("failed to build insertion order: %w", err)
+	}
+	var order []string
+	for _, name := range fullOrder {
+		if selected[name] {
+			order = append(order, name)
+		}
+	}
+
+	color.Cyan("📸 Capturing %s", strings.Join(order, " → "))
+
+	// Values of the captured rows, per table and column, to keep the rows
+	// of child tables whose parent rows were captured
+	captured := make(map[string]map[string]map[string]bool)
+	files := make(map[string]string, len(order))
+
+	for i, name := range order {
+		table := tables[name]
+		columns, rows, err := s.snapshotRows(ctx, table, snapshot.Limit)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		dropped := 0
+		for _, col := range table.Columns {
+			if !col.IsFK || col.FKTable == name {
+				continue
+			}
+			if !selected[col.FKTable] {
+				color.Yellow("  ⚠️  %s.%s references %s, which isn't in the snapshot", name, col.Name, col.FKTable)
+				continue
+			}
+			kept := rows[:0]
+			for _, row := range rows {
+				if row[col.Name] == nil || captured[col.FKTable][col.FKColumn][fmt.Sprint(row[col.Name])] {
+					kept = append(kept, row)
+				}
+			}
+			dropped += len(rows) - len(kept)
+			rows = kept
+		}
+
+		values := make(map[string]map[string]bool, len(columns))
+		for _, column := range columns {
+			values[column] = make(map[string]bool, len(rows))
+			for _, row := range rows {
+				if row[column] != nil {
+					values[column][fmt.Sprint(row[column])] = true
+				}
+			}
+		}
+		captured[name] = values
+
+		var anonymized []string
+		for _, column := range columns {
+			rule := anonymize[name+"."+column]
+			if rule == "" {
+				rule = anonymize["*."+column]
+			}
+			if rule == "" {
+				continue
+			}
+			anonymized = append(anonymized, column)
+			colType := ""
+			for _, col := range table.Columns {
+				if col.Name == column {
+					colType = col.Type
+				}
+			}
+			for _, row := range rows {
+				row[column] = s.anonymizeValue(rule, column, colType, row[column])
+			}
+		}
+
+		fileName := fmt.Sprintf("%02d_%s.sql", i+1, name)
+		files[fileName] = s.fixtureSQL(table, columns, rows, anonymized)
+
+		message := fmt.Sprintf("  ✅ %s: %d row(s)", name, len(rows))
+		if dropped > 0 {
+			message += fmt.Sprintf(", %d left out as their parent row isn't captured", dropped)
+		}
+		if len(anonymized) > 0 {
+			message += fmt.Sprintf(", anonymized %s", strings.Join(anonymized, ", "))
+		}
+		color.Green(message)
+	}
+
+	if err := writeFixtures(snapshot.Dir, files); err != nil {
+		return err
+	}
+
+	color.Green("\n✅ Snapshot written to %s", snapshot.Dir)
+	return nil
+}
+
+// LoadSnapshot inserts the rows of the fixture files in dir, in the order of
+// their names, in one transaction. With truncate the tables are emptied
+// first.
+func (s *Seeder) LoadSnapshot(ctx context.Context, dir string, truncate bool) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	var order []string
+	var sql strings.Builder
+	for _, entry := range entries {
+		if entry.IsDir() || !fixtureFileRegex.MatchString(entry.Name()) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(entry.Name(), ".sql")
+		order = append(order, name[strings.Index(name, "_")+1:])
+		sql.Write(content)
+		sql.WriteString("\n")
+	}
+	if len(order) == 0 {
+		color.Yellow("⚠️  No fixture files found in %s", dir)
+		return nil
+	}
+
+	if truncate {
+		if err := s.truncateTables(ctx, order); err != nil {
+			return err
+		}
+	}
+
+	color.Cyan("🌱 Loading %s", strings.Join(order, " → "))
+	if err := s.adapter.ExecuteMigration(ctx, sql.String()); err != nil {
+		return fmt.Errorf("failed to load fixtures: %w", err)
+	}
+	color.Green("✅ Loaded %d fixture file(s) from %s", len(order), dir)
+	return nil
+}
+
+// anonymizeRules validates seed.anonymize
+func (s *Seeder) anonymizeRules() (map[string]string, error) {
+	rules := make(map[string]string, len(s.config.Seed.Anonymize))
+	for column, rule := range s.config.Seed.Anonymize {
+		parts := strings.Split(column, ".")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("seed.anonymize: %q is not table.column or *.column", column)
+		}
+		switch rule {
+		case AnonymizeNull, AnonymizeHash, AnonymizeEmail, AnonymizeFake:
+		default:
+			return nil, fmt.Errorf("seed.anonymize: unknown rule %q for %s, use null, hash, email or fake", rule, column)
+		}
+		rules[column] = rule
+	}
+	return rules, nil
+}
+
+// snapshotRows reads the rows of a table ordered by primary key. On
+// PostgreSQL every column is read as text, which is valid input for its
+// type whatever the type is.
+func (s *Seeder) snapshotRows(ctx context.Context, table *TableInfo, limit int) ([]string, []map[string]interface{}, error) {
+	var query string
+	switch s.config.Database.Provider {
+	case "postgresql", "postgres":
+		liveColumns, err := s.adapter.GetTableColumns(ctx, table.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		selects := make([]string, len(liveColumns))
+		for i, col := range liveColumns {
+			selects[i] = fmt.Sprintf(`"%s"::text AS "%s"`, col.Name, col.Name)
+		}
+		query = fmt.Sprintf(`SELECT %s FROM "%s"`, strings.Join(selects, ", "), table.Name)
+	case "mysql":
+		query = fmt.Sprintf("SELECT * FROM `%s`", table.Name)
+	default:
+		query = fmt.Sprintf(`SELECT * FROM "%s"`, table.Name)
+	}
+	if table.PrimaryKey != "" {
+		query += " ORDER BY " + table.PrimaryKey
+	}
+	if limit > 0 {
+		query += " LIMIT " + strconv.Itoa(limit)
+	}
+
+	result, err := s.adapter.ExecuteQuery(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, row := range result.Rows {
+		for column, value := range row {
+			if b, ok := value.([]byte); ok {
+				row[column] = string(b)
+			}
+		}
+	}
+	return result.Columns, result.Rows, nil
+}
+
+func (s *Seeder) anonymizeValue(rule, column, colType string, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprint(value)))
+	digest := hex.EncodeToString(sum[:])
+
+	switch rule {
+	case AnonymizeHash:
+		return digest[:16]
+	case AnonymizeEmail:
+		return "user_" + digest[:12] + "@example.com"
+	case AnonymizeFake:
+		return s.generator.GenerateForColumn(column, colType, false)
+	}
+	return nil
+}
+
+// fixtureSQL renders the rows of a table as INSERT statements. On
+// PostgreSQL the sequence of a serial primary key is moved past the
+// inserted keys, so later inserts don't collide with them.
+func (s *Seeder) fixtureSQL(table *TableInfo, columns []string, rows []map[string]interface{}, anonymized []string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s%s taken %s by flash seed snapshot\n", snapshotHeader, table.Name, time.Now().UTC().Format(time.RFC3339)))
+	sb.WriteString(fmt.Sprintf("-- %d row(s)", len(rows)))
+	if len(anonymized) > 0 {
+		sb.WriteString(", anonymized: " + strings.Join(anonymized, ", "))
+	}
+	sb.WriteString("\n")
+
+	for start := 0; start < len(rows); start += snapshotBatch {
+		end := start + snapshotBatch
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		sb.WriteString(fmt.Sprintf("\nINSERT INTO %s (%s) VALUES\n", table.Name, strings.Join(columns, ", ")))
+		for i, row := range rows[start:end] {
+			values := make([]string, len(columns))
+			for j, column := range columns {
+				values[j] = s.fixtureLiteral(row[column])
+			}
+			sb.WriteString("  (" + strings.Join(values, ", ") + ")")
+			if start+i == end-1 {
+				sb.WriteString(";\n")
+			} else {
+				sb.WriteString(",\n")
+			}
+		}
+	}
+
+	if (s.config.Database.Provider == "postgresql" || s.config.Database.Provider == "postgres") && table.PrimaryKey != "" && len(rows) > 0 {
+		for _, col := range table.Columns {
+			if col.Name == table.PrimaryKey && strings.Contains(strings.ToUpper(col.Type), "SERIAL") {
+				sb.WriteString(fmt.Sprintf("\nSELECT setval(pg_get_serial_sequence('%s', '%s'), (SELECT MAX(%s) FROM %s));\n",
+					table.Name, col.Name, col.Name, table.Name))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// fixtureLiteral renders a value read from the database as a SQL literal
+func (s *Seeder) fixtureLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", v)
+	case float32, float64:
+		return fmt.Sprintf("%v", v)
+	case time.Time:
+		return "'" + v.Format("2006-01-02 15:04:05.999999") + "'"
+	}
+
+	escaped := strings.ReplaceAll(fmt.Sprint(value), "'", "''")
+	if s.config.Database.Provider == "mysql" {
+		escaped = strings.ReplaceAll(escaped, `\`, `\\`)
+	}
+	return "'" + escaped + "'"
+}
+
+// writeFixtures replaces the fixture files of the previous snapshot with
+// files. Files of the folder that don't start with the snapshot header are
+// kept.
+func writeFixtures(dir string, files map[string]string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !fixtureFileRegex.MatchString(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		content, err := os.ReadFile(path)
+		if err == nil && strings.HasPrefix(string(content), snapshotHeader) {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(files[name]), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
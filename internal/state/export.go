@@ -0,0 +1,65 @@
+package state
+
+import (
+	"fmt"
+	"time"
+)
+
+// Export is the whole state of a store, by kind, for tools that rather
+// read JSON than SQLite
+type Export struct {
+	Version    int                   `json:"version"`
+	ExportedAt time.Time             `json:"exported_at"`
+	Documents  map[string][]Document `json:"documents"`
+	Entries    map[string][]Entry    `json:"entries"`
+}
+
+// Export returns every document and entry of the store. Entries are newest
+// first, as Entries returns them.
+func (s *Store) Export() (*Export, error) {
+	export := &Export{
+		Version:    Version(),
+		ExportedAt: time.Now().UTC(),
+		Documents:  map[string][]Document{},
+		Entries:    map[string][]Entry{},
+	}
+
+	kinds, err := s.kinds(`SELECT DISTINCT kind FROM documents ORDER BY kind`)
+	if err != nil {
+		return nil, err
+	}
+	for _, kind := range kinds {
+		if export.Documents[kind], err = s.List(kind); err != nil {
+			return nil, err
+		}
+	}
+
+	kinds, err = s.kinds(`SELECT DISTINCT kind FROM entries ORDER BY kind`)
+	if err != nil {
+		return nil, err
+	}
+	for _, kind := range kinds {
+		if export.Entries[kind], err = s.Entries(kind, 0); err != nil {
+			return nil, err
+		}
+	}
+	return export, nil
+}
+
+func (s *Store) kinds(query string) ([]string, error) {
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state store: %w", err)
+	}
+	defer rows.Close()
+
+	var kinds []string
+	for rows.Next() {
+		var kind string
+		if err := rows.Scan(&kind); err != nil {
+			return nil, fmt.Errorf("failed to read state store: %w", err)
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, rows.Err()
+}
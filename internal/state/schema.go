@@ -0,0 +1,240 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// migrations upgrade the store's schema, one version each; the version of
+// a store is the number of migrations applied to it, kept in user_version.
+// Only ever append to them.
+var migrations = []func(tx *sql.Tx, flashDir string) error{
+	// 1: documents and entries, holding the state of the files they replace
+	func(tx *sql.Tx, flashDir string) error {
+		_, err := tx.Exec(`
+			CREATE TABLE documents (
+				kind       TEXT NOT NULL,
+				key        TEXT NOT NULL,
+				value      TEXT NOT NULL,
+				updated_at TEXT NOT NULL,
+				PRIMARY KEY (kind, key)
+			);
+			CREATE TABLE entries (
+				id         INTEGER PRIMARY KEY AUTOINCREMENT,
+				kind       TEXT NOT NULL,
+				value      TEXT NOT NULL,
+				created_at TEXT NOT NULL
+			);
+			CREATE INDEX entries_kind_id ON entries (kind, id);`)
+		if err != nil {
+			return err
+		}
+		return importLegacyFiles(tx, flashDir)
+	},
+	// 2: read-only views of the query history and the audit trail
+	func(tx *sql.Tx, flashDir string) error {
+		_, err := tx.Exec(`
+			CREATE VIEW query_history AS
+				SELECT id,
+					json_extract(value, '$.query')       AS query,
+					json_extract(value, '$.success')     AS success,
+					json_extract(value, '$.error')       AS error,
+					json_extract(value, '$.duration_ms') AS duration_ms,
+					json_extract(value, '$.ran_at')      AS ran_at
+				FROM entries WHERE kind = 'query_history';
+			CREATE VIEW query_stats AS
+				SELECT query,
+					COUNT(*)                        AS runs,
+					SUM(NOT success)                AS failures,
+					ROUND(AVG(duration_ms), 1)      AS avg_ms,
+					MAX(duration_ms)                AS max_ms,
+					SUM(duration_ms)                AS total_ms,
+					MIN(ran_at)                     AS first_run,
+					MAX(ran_at)                     AS last_run
+				FROM query_history GROUP BY query;
+			CREATE VIEW redis_audit AS
+				SELECT id,
+					json_extract(value, '$.time')    AS time,
+					json_extract(value, '$.client')  AS client,
+					json_extract(value, '$.db')      AS db,
+					json_extract(value, '$.command') AS command,
+					json_extract(value, '$.rule')    AS rule,
+					json_extract(value, '$.outcome') AS outcome,
+					json_extract(value, '$.error')   AS error
+				FROM entries WHERE kind = 'audit';`)
+		return err
+	},
+}
+
+// Version is the schema version of stores this build creates
+func Version() int {
+	return len(migrations)
+}
+
+// migrate applies the migrations a store lacks, each in a transaction
+func (s *Store) migrate(flashDir string) error {
+	var version int
+	if err := s.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return fmt.Errorf("failed to read state store version: %w", err)
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("state store %s is version %d, newer than this flash supports (%d); upgrade flash", s.path, version, len(migrations))
+	}
+
+	imported := false
+	for ; version < len(migrations); version++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to upgrade state store: %w", err)
+		}
+		// Another process may have upgraded the store while this one waited
+		var current int
+		if err := tx.QueryRow(`PRAGMA user_version`).Scan(&current); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to read state store version: %w", err)
+		}
+		if current > version {
+			tx.Rollback()
+			version = current - 1
+			continue
+		}
+		if err := migrations[version](tx, flashDir); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to upgrade state store to version %d: %w", version+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, version+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to upgrade state store to version %d: %w", version+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to upgrade state store to version %d: %w", version+1, err)
+		}
+		imported = imported || version == 0
+	}
+	if imported {
+		renameLegacyFiles(flashDir)
+	}
+	return nil
+}
+
+// legacyFile is a JSON file the store replaced, with how its state is
+// split into documents
+type legacyFile struct {
+	name string
+	load func(tx *sql.Tx, data []byte) error
+}
+
+var legacyFiles = []legacyFile{
+	{"branches.json", importBranches},
+	{"filter_presets.json", importFilterPresets},
+	{"export_watermarks.json", importExportWatermarks},
+}
+
+func legacyFileNames() []string {
+	names := make([]string, len(legacyFiles))
+	for i, file := range legacyFiles {
+		names[i] = file.name
+	}
+	return names
+}
+
+// importLegacyFiles moves the state of the files the store replaced into it
+func importLegacyFiles(tx *sql.Tx, flashDir string) error {
+	for _, file := range legacyFiles {
+		data, err := os.ReadFile(filepath.Join(flashDir, file.name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file.name, err)
+		}
+		if err := file.load(tx, data); err != nil {
+			return fmt.Errorf("failed to import %s: %w", file.name, err)
+		}
+	}
+	return nil
+}
+
+// renameLegacyFiles renames the imported files so it's clear they are no
+// longer read. They are kept, so downgrading doesn't lose the state.
+func renameLegacyFiles(flashDir string) {
+	for _, file := range legacyFiles {
+		path := filepath.Join(flashDir, file.name)
+		if _, err := os.Stat(path); err == nil {
+			os.Rename(path, path+".imported")
+		}
+	}
+}
+
+func importBranches(tx *sql.Tx, data []byte) error {
+	var file struct {
+		Current  string            `json:"current"`
+		Branches []json.RawMessage `json:"branches"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	for _, branch := range file.Branches {
+		var named struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(branch, &named); err != nil {
+			return err
+		}
+		if err := insertDocument(tx, KindBranches, named.Name, branch); err != nil {
+			return err
+		}
+	}
+	current, _ := json.Marshal(file.Current)
+	return insertDocument(tx, KindSettings, SettingCurrentBranch, current)
+}
+
+func importFilterPresets(tx *sql.Tx, data []byte) error {
+	var file struct {
+		Presets []json.RawMessage `json:"presets"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	for _, preset := range file.Presets {
+		var key struct {
+			Table string `json:"table"`
+			Name  string `json:"name"`
+		}
+		if err := json.Unmarshal(preset, &key); err != nil {
+			return err
+		}
+		if err := insertDocument(tx, KindFilterPresets, FilterPresetKey(key.Table, key.Name), preset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func importExportWatermarks(tx *sql.Tx, data []byte) error {
+	var file struct {
+		Watermarks map[string]json.RawMessage `json:"watermarks"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	for table, mark := range file.Watermarks {
+		if err := insertDocument(tx, KindExportWatermarks, table, mark); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertDocument(tx *sql.Tx, kind, key string, value json.RawMessage) error {
+	_, err := tx.Exec(`INSERT OR REPLACE INTO documents (kind, key, value, updated_at) VALUES (?, ?, ?, ?)`, kind, key, string(value), now())
+	return err
+}
+
+// FilterPresetKey is the key of a table's filter preset
+func FilterPresetKey(table, name string) string {
+	return table + "/" + name
+}
@@ -0,0 +1,257 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// The kinds of state the store keeps. Documents are kept by key and
+// replaced as a whole; entries are appended and trimmed to the newest.
+const (
+	KindBranches         = "branches"          // branch metadata, by branch name
+	KindSettings         = "settings"          // project-wide values such as the current branch
+	KindFilterPresets    = "filter_presets"    // saved filters of studio tables, by table/name
+	KindExportWatermarks = "export_watermarks" // high-water marks of incremental exports, by table
+	KindLayouts          = "layouts"           // node positions of studio diagrams, by diagram
+	KindQueryHistory     = "query_history"     // statements run in the studio SQL editor
+	KindAudit            = "audit"             // guarded commands run in studio
+)
+
+// SettingCurrentBranch is the setting naming the current schema branch
+const SettingCurrentBranch = "current_branch"
+
+// fileName is the name of the store's database in the .flash folder
+const fileName = "state.db"
+
+// Store keeps the local state of a project in a SQLite database in the
+// .flash folder of its migrations path, so it can be queried with any
+// SQLite client. Every process opens it for as long as it needs it.
+type Store struct {
+	db   *sql.DB
+	path string
+}
+
+// Document is a value of the store kept under a key
+type Document struct {
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// Entry is a value appended to the store
+type Entry struct {
+	ID        int64           `json:"id"`
+	Value     json.RawMessage `json:"value"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Path is the file of the store of the project with the given migrations
+// path
+func Path(migrationsPath string) string {
+	return filepath.Join(migrationsPath, ".flash", fileName)
+}
+
+// Exists tells whether the project has any state yet, in the store or in
+// the files it replaced, so reads of projects without state don't create
+// a store
+func Exists(migrationsPath string) bool {
+	for _, name := range append([]string{fileName}, legacyFileNames()...) {
+		if _, err := os.Stat(filepath.Join(migrationsPath, ".flash", name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Open opens the store of the project with the given migrations path,
+// creating it, or upgrading its schema, when needed
+func Open(migrationsPath string) (*Store, error) {
+	path := Path(migrationsPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directories: %w", err)
+	}
+
+	// Writers of other processes are waited for instead of failing, and
+	// transactions take the write lock up front so they can't deadlock
+	db, err := sql.Open("sqlite3", "file:"+path+"?_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state store: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	s := &Store{db: db, path: path}
+	if err := s.migrate(filepath.Dir(path)); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the store's database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Get decodes the document of kind under key into v. It returns false when
+// there's none.
+func (s *Store) Get(kind, key string, v any) (bool, error) {
+	var value string
+	err := s.db.QueryRow(`SELECT value FROM documents WHERE kind = ? AND key = ?`, kind, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s %s: %w", kind, key, err)
+	}
+	if err := json.Unmarshal([]byte(value), v); err != nil {
+		return false, fmt.Errorf("failed to parse %s %s: %w", kind, key, err)
+	}
+	return true, nil
+}
+
+// List returns the documents of kind, by key
+func (s *Store) List(kind string) ([]Document, error) {
+	rows, err := s.db.Query(`SELECT key, value, updated_at FROM documents WHERE kind = ? ORDER BY key`, kind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", kind, err)
+	}
+	defer rows.Close()
+
+	documents := []Document{}
+	for rows.Next() {
+		var doc Document
+		var value, updated string
+		if err := rows.Scan(&doc.Key, &value, &updated); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", kind, err)
+		}
+		doc.Value = json.RawMessage(value)
+		doc.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updated)
+		documents = append(documents, doc)
+	}
+	return documents, rows.Err()
+}
+
+// Put keeps v under key, replacing the document there
+func (s *Store) Put(kind, key string, v any) error {
+	return s.put(s.db, kind, key, v)
+}
+
+// Replace makes documents the only documents of kind, in one transaction
+func (s *Store) Replace(kind string, documents map[string]any) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", kind, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM documents WHERE kind = ?`, kind); err != nil {
+		return fmt.Errorf("failed to write %s: %w", kind, err)
+	}
+	for key, v := range documents {
+		if err := s.put(tx, kind, key, v); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func (s *Store) put(db execer, kind, key string, v any) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s %s: %w", kind, key, err)
+	}
+	_, err = db.Exec(`INSERT INTO documents (kind, key, value, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (kind, key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+		kind, key, string(value), now())
+	if err != nil {
+		return fmt.Errorf("failed to write %s %s: %w", kind, key, err)
+	}
+	return nil
+}
+
+// Delete removes the document of kind under key. It returns false when
+// there was none.
+func (s *Store) Delete(kind, key string) (bool, error) {
+	result, err := s.db.Exec(`DELETE FROM documents WHERE kind = ? AND key = ?`, kind, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete %s %s: %w", kind, key, err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// Append adds v to the entries of kind and drops the oldest of them past
+// keep, when keep is positive
+func (s *Store) Append(kind string, v any, keep int) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s entry: %w", kind, err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", kind, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO entries (kind, value, created_at) VALUES (?, ?, ?)`, kind, string(value), now()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", kind, err)
+	}
+	if keep > 0 {
+		_, err := tx.Exec(`DELETE FROM entries WHERE kind = ? AND id NOT IN
+			(SELECT id FROM entries WHERE kind = ? ORDER BY id DESC LIMIT ?)`, kind, kind, keep)
+		if err != nil {
+			return fmt.Errorf("failed to trim %s: %w", kind, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Entries returns the entries of kind, newest first, up to limit when it
+// is positive
+func (s *Store) Entries(kind string, limit int) ([]Entry, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.db.Query(`SELECT id, value, created_at FROM entries WHERE kind = ? ORDER BY id DESC LIMIT ?`, kind, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", kind, err)
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var entry Entry
+		var value, created string
+		if err := rows.Scan(&entry.ID, &value, &created); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", kind, err)
+		}
+		entry.Value = json.RawMessage(value)
+		entry.CreatedAt, _ = time.Parse(time.RFC3339Nano, created)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// ClearEntries drops the entries of kind
+func (s *Store) ClearEntries(kind string) error {
+	if _, err := s.db.Exec(`DELETE FROM entries WHERE kind = ?`, kind); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", kind, err)
+	}
+	return nil
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339Nano)
+}
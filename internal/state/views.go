@@ -0,0 +1,108 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// View is a read-only table of the store, such as query_history
+type View struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+}
+
+// QueryResult is the outcome of a query over the store
+type QueryResult struct {
+	Columns []string
+	Types   []string
+	Rows    [][]any
+}
+
+// Views returns the views of the store, by name
+func (s *Store) Views(ctx context.Context) ([]View, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'view' ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state store views: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read state store views: %w", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read state store views: %w", err)
+	}
+
+	views := []View{}
+	for _, name := range names {
+		result, err := s.Query(ctx, fmt.Sprintf(`SELECT * FROM "%s" LIMIT 0`, name))
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, View{Name: name, Columns: result.Columns})
+	}
+	return views, nil
+}
+
+// Query runs a read-only statement over the store. Anything that would
+// change the store fails, so the views of the history and the audit trail
+// can be queried without risking them.
+func (s *Store) Query(ctx context.Context, query string, args ...any) (*QueryResult, error) {
+	upper := strings.ToUpper(strings.TrimSpace(query))
+	if !strings.HasPrefix(upper, "SELECT") && !strings.HasPrefix(upper, "WITH") {
+		return nil, fmt.Errorf("the state store is read-only; only SELECT and WITH queries can run on it")
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state store: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `PRAGMA query_only = ON`); err != nil {
+		return nil, fmt.Errorf("failed to open state store: %w", err)
+	}
+	// The connection goes back to the pool, which other calls write with
+	defer conn.ExecContext(context.Background(), `PRAGMA query_only = OFF`)
+
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := &QueryResult{Rows: [][]any{}}
+	if result.Columns, err = rows.Columns(); err != nil {
+		return nil, err
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range types {
+		result.Types = append(result.Types, t.DatabaseTypeName())
+	}
+
+	for rows.Next() {
+		values := make([]any, len(result.Columns))
+		ptrs := make([]any, len(values))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,56 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FilterValues returns the values of a filter taking several: Values, or
+// else the comma separated values of Value
+func FilterValues(filter Filter) []string {
+	values := filter.Values
+	if len(values) == 0 && strings.TrimSpace(filter.Value) != "" {
+		values = strings.Split(filter.Value, ",")
+	}
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// RelativeTime returns the time a relative span like "7 days" or
+// "24 hours" reaches back to from now. A bare number counts days.
+func RelativeTime(span string, now time.Time) (time.Time, error) {
+	fields := strings.Fields(strings.ToLower(span))
+	if len(fields) == 0 || len(fields) > 2 {
+		return time.Time{}, fmt.Errorf("%q isn't a span like 7 days", span)
+	}
+	n, err := strconv.Atoi(fields[0])
+	if err != nil || n < 0 {
+		return time.Time{}, fmt.Errorf("%q isn't a span like 7 days", span)
+	}
+	unit := "day"
+	if len(fields) == 2 {
+		unit = strings.TrimSuffix(fields[1], "s")
+	}
+	switch unit {
+	case "minute":
+		return now.Add(-time.Duration(n) * time.Minute), nil
+	case "hour":
+		return now.Add(-time.Duration(n) * time.Hour), nil
+	case "day":
+		return now.AddDate(0, 0, -n), nil
+	case "week":
+		return now.AddDate(0, 0, -7*n), nil
+	case "month":
+		return now.AddDate(0, -n, 0), nil
+	case "year":
+		return now.AddDate(-n, 0, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown unit %q, use minutes, hours, days, weeks, months or years", unit)
+}
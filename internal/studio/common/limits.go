@@ -0,0 +1,235 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxQueued bounds the requests of a client waiting for a free slot; the
+// ones past it get a 429 right away
+const maxQueued = 16
+
+// Why acquire turned a request away: the slots it found no room in were
+// those of its client or those all clients share
+var (
+	errClientBusy = errors.New("too many requests of the client running")
+	errStudioBusy = errors.New("too many requests running")
+)
+
+// clientIdleTimeout is how long a client's limits are kept after its last
+// request
+const clientIdleTimeout = 10 * time.Minute
+
+// clientLimits are the rate and concurrency limits of one client
+type clientLimits struct {
+	tokens   float64
+	lastSeen time.Time
+	slots    chan struct{}
+	queued   int
+}
+
+// limiter holds the limits of every client, keyed by address, and the
+// slots all clients share
+type limiter struct {
+	rate         float64
+	burst        float64
+	concurrent   int
+	queueTimeout time.Duration
+	// slots bounds the requests of all clients running at once, nil when
+	// they aren't bounded
+	slots chan struct{}
+
+	mu        sync.Mutex
+	clients   map[string]*clientLimits
+	lastSweep time.Time
+}
+
+// client returns the limits of addr, refilling its tokens up to now
+func (l *limiter) client(addr string, now time.Time) *clientLimits {
+	if now.Sub(l.lastSweep) > clientIdleTimeout {
+		for key, c := range l.clients {
+			if now.Sub(c.lastSeen) > clientIdleTimeout && c.queued == 0 && len(c.slots) == 0 {
+				delete(l.clients, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	c, ok := l.clients[addr]
+	if !ok {
+		c = &clientLimits{tokens: l.burst, lastSeen: now}
+		if l.concurrent > 0 {
+			c.slots = make(chan struct{}, l.concurrent)
+		}
+		l.clients[addr] = c
+	}
+	c.tokens = math.Min(l.burst, c.tokens+now.Sub(c.lastSeen).Seconds()*l.rate)
+	c.lastSeen = now
+	return c
+}
+
+// allow takes a token of addr's bucket, or tells how long until there's one
+func (l *limiter) allow(addr string) (*clientLimits, time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c := l.client(addr, time.Now())
+	if l.rate <= 0 {
+		return c, 0, true
+	}
+	if c.tokens < 1 {
+		return c, time.Duration((1 - c.tokens) / l.rate * float64(time.Second)), false
+	}
+	c.tokens--
+	return c, 0, true
+}
+
+// acquire waits for a free slot of c, then for one of all clients, until
+// the queue timeout or the end of the request. It returns the function
+// giving the slots back, or why the request gets none: errClientBusy or
+// errStudioBusy for the slots it found no room in, or the request's
+// context error.
+func (l *limiter) acquire(r *http.Request, c *clientLimits) (func(), error) {
+	var held []chan struct{}
+	release := func() {
+		for _, slots := range held {
+			<-slots
+		}
+	}
+	busy := func(slots chan struct{}) error {
+		release()
+		if slots == l.slots {
+			return errStudioBusy
+		}
+		return errClientBusy
+	}
+
+	var timeout <-chan time.Time
+	for _, slots := range []chan struct{}{c.slots, l.slots} {
+		if slots == nil {
+			continue
+		}
+		select {
+		case slots <- struct{}{}:
+			held = append(held, slots)
+			continue
+		default:
+		}
+
+		// The request queues once, for as long as it waits on either
+		if timeout == nil {
+			l.mu.Lock()
+			if c.queued >= maxQueued {
+				l.mu.Unlock()
+				return nil, busy(slots)
+			}
+			c.queued++
+			l.mu.Unlock()
+			defer func() {
+				l.mu.Lock()
+				c.queued--
+				l.mu.Unlock()
+			}()
+
+			timer := time.NewTimer(l.queueTimeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+
+		select {
+		case slots <- struct{}{}:
+			held = append(held, slots)
+		case <-timeout:
+			return nil, busy(slots)
+		case <-r.Context().Done():
+			release()
+			return nil, r.Context().Err()
+		}
+	}
+	return release, nil
+}
+
+// clientAddr is the address requests are limited by: the peer's IP, so a
+// reverse proxy counts as a single client
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// isLimited tells whether r is a data or query request, which use the
+// database. Pages, static files, health checks and websockets are left alone.
+func isLimited(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/") && !IsWebSocketUpgrade(r)
+}
+
+// tooManyRequests answers with a 429 telling the client when to retry
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	JSONError(w, http.StatusTooManyRequests, fmt.Sprintf("%s; try again in %ds", message, seconds))
+}
+
+// withLimits rate limits each client's data and query requests to rate a
+// second, with bursts of burst, and lets it run at most concurrent of them
+// at once, and all clients together at most total. Requests past the rate
+// get a 429; the ones past the concurrency wait in line for up to
+// queueTimeout, so neither a client running heavy queries over and over nor
+// many clients at once can take every connection of the pool.
+func withLimits(next http.Handler, rate float64, burst, concurrent, total int, queueTimeout time.Duration) http.Handler {
+	if rate <= 0 && concurrent <= 0 && total <= 0 {
+		return next
+	}
+	if burst < 1 {
+		burst = max(1, int(math.Ceil(rate)))
+	}
+	l := &limiter{
+		rate:         rate,
+		burst:        float64(burst),
+		concurrent:   concurrent,
+		queueTimeout: queueTimeout,
+		clients:      make(map[string]*clientLimits),
+		lastSweep:    time.Now(),
+	}
+	if total > 0 {
+		l.slots = make(chan struct{}, total)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isLimited(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		c, retryAfter, ok := l.allow(clientAddr(r))
+		if !ok {
+			tooManyRequests(w, retryAfter, "Too many requests")
+			return
+		}
+		release, err := l.acquire(r, c)
+		switch {
+		case err == nil:
+		case errors.Is(err, errStudioBusy):
+			tooManyRequests(w, queueTimeout, fmt.Sprintf("Studio is busy, at most %d requests run at once", total))
+			return
+		case errors.Is(err, errClientBusy):
+			tooManyRequests(w, queueTimeout, fmt.Sprintf("Too many requests running, at most %d at once", concurrent))
+			return
+		default:
+			// The client went away while it waited
+			return
+		}
+		defer release()
+		next.ServeHTTP(w, r)
+	})
+}
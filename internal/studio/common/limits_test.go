@@ -0,0 +1,151 @@
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Clients each within their own limit are still held to the limit of all
+// clients together
+func TestLimitsCapConcurrentClients(t *testing.T) {
+	release := make(chan struct{})
+	var running, peak atomic.Int32
+	handler := withLimits(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		running.Add(-1)
+	}), 0, 0, 2, 3, time.Second)
+
+	const clients = 6
+	codes := make(chan int, clients)
+	var wg sync.WaitGroup
+	for i := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := httptest.NewRequest(http.MethodGet, "/api/tables", nil)
+			r.RemoteAddr = fmt.Sprintf("10.0.0.%d:1234", i+1)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			codes <- w.Code
+		}()
+	}
+
+	// Three run and the other three wait for their turn
+	deadline := time.Now().Add(time.Second)
+	for running.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := running.Load(); n != 3 {
+		t.Fatalf("%d requests running, want 3", n)
+	}
+	close(release)
+	wg.Wait()
+	close(codes)
+
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("request answered %d, want it to wait its turn", code)
+		}
+	}
+	if p := peak.Load(); p != 3 {
+		t.Errorf("at most %d requests ran at once, want 3", p)
+	}
+}
+
+// A request that waits past the queue timeout for a slot of all clients
+// gets a 429
+func TestLimitsTotalQueueTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{}, 1)
+	handler := withLimits(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}), 0, 0, 2, 1, 20*time.Millisecond)
+
+	go func() {
+		r := httptest.NewRequest(http.MethodGet, "/api/tables", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}()
+	<-started
+
+	r := httptest.NewRequest(http.MethodGet, "/api/tables", nil)
+	r.RemoteAddr = "10.0.0.2:1234"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second client answered %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("429 has no Retry-After")
+	}
+}
+
+// With only a cap on all clients, a client's requests still queue for it,
+// the ones past its queue get a 429 and none run without a slot
+func TestLimitsTotalOnly(t *testing.T) {
+	release := make(chan struct{})
+	var running, peak atomic.Int32
+	handler := withLimits(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		running.Add(-1)
+	}), 0, 0, 0, 1, 5*time.Second)
+
+	// One runs, maxQueued wait and the one past them is turned away
+	const requests = maxQueued + 2
+	codes := make(chan int, requests)
+	var wg sync.WaitGroup
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := httptest.NewRequest(http.MethodGet, "/api/tables", nil)
+			r.RemoteAddr = "10.0.0.1:1234"
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			codes <- w.Code
+		}()
+	}
+
+	select {
+	case code := <-codes:
+		if code != http.StatusTooManyRequests {
+			t.Errorf("first request to finish answered %d, want 429", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no request was turned away")
+	}
+	close(release)
+	wg.Wait()
+	close(codes)
+
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("queued request answered %d, want 200", code)
+		}
+	}
+	if p := peak.Load(); p != 1 {
+		t.Errorf("at most %d requests ran at once, want 1", p)
+	}
+}
@@ -0,0 +1,51 @@
+package common
+
+import (
+	"context"
+	"net/http"
+)
+
+// StoreOverview is what the studio overview shows of one data store: its
+// health, size and contents, and what happened in it lately
+type StoreOverview struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider"`
+	// Primary is the store this studio session browses
+	Primary   bool    `json:"primary"`
+	Healthy   bool    `json:"healthy"`
+	Error     string  `json:"error,omitempty"`
+	LatencyMs float64 `json:"latencyMs"`
+	Version   string  `json:"version,omitempty"`
+	// SizeBytes is the size on disk, or the memory in use for Redis
+	SizeBytes int64 `json:"sizeBytes"`
+	// Objects are tables, collections or Redis databases holding keys
+	Objects    int    `json:"objects"`
+	ObjectKind string `json:"objectKind"`
+	// Items are rows, documents or keys; rows may be estimates
+	Items          int64           `json:"items"`
+	ItemKind       string          `json:"itemKind"`
+	ItemsEstimated bool            `json:"itemsEstimated,omitempty"`
+	Activity       []StoreActivity `json:"activity"`
+}
+
+// StoreActivity is a recent event of a store, such as an applied migration,
+// or one of its activity counters when Time is empty
+type StoreActivity struct {
+	Time        string `json:"time,omitempty"`
+	Description string `json:"description"`
+}
+
+// Overview collects the overview of every store of the project. When set,
+// studio serves it at /api/overview and pages link to it; it stays nil
+// when studio knows of no store but its own.
+var Overview func(ctx context.Context) []StoreOverview
+
+// setupOverview mounts /api/overview when there's an overview to serve
+func setupOverview(mux *http.ServeMux) {
+	if Overview == nil {
+		return
+	}
+	mux.HandleFunc("GET /api/overview", func(w http.ResponseWriter, r *http.Request) {
+		JSON(w, Map{"stores": Overview(r.Context())})
+	})
+}
@@ -30,6 +30,12 @@ func JSONError(w http.ResponseWriter, status int, message string) {
 	writeJSON(w, status, Response{Success: false, Message: message})
 }
 
+// JSONErrorData sends an error response with data the client needs to
+// recover, e.g. the current values of rows that conflict with an edit
+func JSONErrorData(w http.ResponseWriter, status int, message string, data any) {
+	writeJSON(w, status, Response{Success: false, Message: message, Data: data})
+}
+
 // JSONMap sends an arbitrary map as JSON (replaces JSONFiberMap)
 func JSONMap(w http.ResponseWriter, data Map) {
 	writeJSON(w, http.StatusOK, data)
@@ -46,6 +52,16 @@ func ParseJSON(r *http.Request, target any) error {
 	return json.NewDecoder(r.Body).Decode(target)
 }
 
+// ParseJSONValues decodes the JSON request body into target keeping
+// numbers as json.Number, so values written to the database keep their
+// precision
+func ParseJSONValues(r *http.Request, target any) error {
+	defer r.Body.Close()
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	return decoder.Decode(target)
+}
+
 // Query returns the query parameter value, or defaultValue if empty/missing
 func Query(r *http.Request, key, defaultValue string) string {
 	v := r.URL.Query().Get(key)
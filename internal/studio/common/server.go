@@ -1,11 +1,25 @@
 package common
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
 	"embed"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"html/template"
 	"io/fs"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 )
 
 //go:embed static/*
@@ -22,42 +36,312 @@ func init() {
 	commonJS, _ = CommonStaticFS.ReadFile("static/js/common.js")
 }
 
-// ParseTemplates parses HTML templates from the given embedded FS
-func ParseTemplates(templatesFS fs.FS) *template.Template {
-	return template.Must(template.ParseFS(templatesFS, "templates/*.html"))
+// Offline leaves out the fonts, icons and editor loaded from CDNs, so studio
+// makes no requests outside the binary. The SQL editor falls back to a plain
+// text area.
+var Offline bool
+
+// BasePath is the path studio is served under behind a reverse proxy, e.g.
+// /flash, empty at the root. Pages link assets and send requests under it;
+// the server takes requests with or without it, so proxies may strip it.
+var BasePath string
+
+// ParseTemplates parses HTML templates from the given embedded FS. Templates
+// link static files with {{asset "/static/js/app.js"}}, which adds a hash of
+// the file's content so browsers never use a stale copy after an upgrade,
+// and wrap CDN resources in {{if not offline}}. {{if overview}} holds links
+// to the overview of the project's stores.
+func ParseTemplates(templatesFS fs.FS, studioStaticFS fs.FS) *template.Template {
+	versions := make(map[string]string)
+	hashFiles(versions, CommonStaticFS, "/common/")
+	hashFiles(versions, studioStaticFS, "/")
+
+	funcs := template.FuncMap{
+		"asset": func(name string) string {
+			if version, ok := versions[name]; ok {
+				return BasePath + name + "?v=" + version
+			}
+			return BasePath + name
+		},
+		"base":     func() string { return BasePath },
+		"offline":  func() bool { return Offline },
+		"overview": func() bool { return Overview != nil },
+	}
+	return template.Must(template.New("").Funcs(funcs).ParseFS(templatesFS, "templates/*.html"))
+}
+
+// hashFiles records a short content hash of every file under static/ by the
+// URL it is served at
+func hashFiles(versions map[string]string, fsys fs.FS, prefix string) {
+	fs.WalkDir(fsys, "static", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil
+		}
+		sum := sha256.Sum256(data)
+		versions[path.Join(prefix, name)] = hex.EncodeToString(sum[:])[:12]
+		return nil
+	})
+}
+
+// cacheControl lets browsers keep versioned files for good and makes them
+// revalidate the rest
+func cacheControl(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("v") != "" {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
 }
 
 // SetupStaticFS mounts studio-specific and common static files on the mux
 func SetupStaticFS(mux *http.ServeMux, studioStaticFS embed.FS) {
 	staticFS, _ := fs.Sub(studioStaticFS, "static")
-	fileServer := http.FileServer(http.FS(staticFS))
-	mux.Handle("GET /static/", http.StripPrefix("/static/", fileServer))
+	fileServer := http.StripPrefix("/static/", http.FileServer(http.FS(staticFS)))
+	mux.HandleFunc("GET /static/", func(w http.ResponseWriter, r *http.Request) {
+		cacheControl(w, r)
+		fileServer.ServeHTTP(w, r)
+	})
 
 	// Serve common shared files
 	mux.HandleFunc("GET /common/static/css/base.css", func(w http.ResponseWriter, r *http.Request) {
+		cacheControl(w, r)
 		w.Header().Set("Content-Type", "text/css")
 		w.Write(baseCSS)
 	})
 	mux.HandleFunc("GET /common/static/js/common.js", func(w http.ResponseWriter, r *http.Request) {
+		cacheControl(w, r)
 		w.Header().Set("Content-Type", "application/javascript")
 		w.Write(commonJS)
 	})
 }
 
-// StartServer finds an available port, prints the URL, optionally opens a browser, and starts listening
-func StartServer(mux *http.ServeMux, port *int, name string, openBrowser bool) error {
+// StartOptions configure how a studio is served
+type StartOptions struct {
+	Host        string // interface to listen on, localhost when empty
+	OpenBrowser bool
+	// RequestTimeout answers requests still running after it with a 503;
+	// zero leaves them unbounded
+	RequestTimeout time.Duration
+	// RateLimit is how many data and query requests a client may make a
+	// second, in bursts of up to RateBurst; zero leaves them unlimited
+	RateLimit float64
+	RateBurst int
+	// MaxConcurrent is how many data and query requests a client may have
+	// running at once, and MaxConcurrentTotal how many all clients may;
+	// the others wait up to QueueTimeout for their turn. Zero leaves them
+	// unlimited.
+	MaxConcurrent      int
+	MaxConcurrentTotal int
+	QueueTimeout       time.Duration
+	// ShutdownTimeout is how long in-flight requests get to finish on
+	// SIGINT or SIGTERM, 10 seconds when zero
+	ShutdownTimeout time.Duration
+	// CORSOrigins are the origins other than studio's own that may call
+	// its API from a browser, "*" for any
+	CORSOrigins []string
+	// TLSCert and TLSKey serve HTTPS with the given PEM files
+	TLSCert string
+	TLSKey  string
+}
+
+// readyTimeout bounds the database ping of /readyz
+const readyTimeout = 3 * time.Second
+
+// setupHealthChecks mounts /healthz, which answers while the process
+// serves, and /readyz, which also pings the database, for orchestrators
+// that run studio as a long-lived service
+func setupHealthChecks(mux *http.ServeMux, ping func(context.Context) error) {
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		JSONMap(w, Map{"status": "ok"})
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		if ping != nil {
+			ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+			defer cancel()
+			if err := ping(ctx); err != nil {
+				writeJSON(w, http.StatusServiceUnavailable, Map{"status": "unavailable", "error": err.Error()})
+				return
+			}
+		}
+		JSONMap(w, Map{"status": "ok"})
+	})
+}
+
+// withRequestTimeout answers requests that run longer than timeout with a
+// 503 and cancels their context. TimeoutHandler holds the response back
+// until the handler returns, so streamed responses only get the deadline:
+// one running past it is cut off. Websockets are left alone: they outlive
+// any timeout and need the connection.
+func withRequestTimeout(next http.Handler, timeout time.Duration) http.Handler {
+	if timeout <= 0 {
+		return next
+	}
+	body := fmt.Sprintf(`{"success":false,"message":"Request timed out after %s"}`, timeout)
+	timed := http.TimeoutHandler(next, timeout, body)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case IsWebSocketUpgrade(r):
+			next.ServeHTTP(w, r)
+		case isStreamed(r):
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		default:
+			timed.ServeHTTP(w, r)
+		}
+	})
+}
+
+// isStreamed tells whether r is answered as it runs: exports write their
+// rows as they're read and dump imports report their progress
+func isStreamed(r *http.Request) bool {
+	return strings.HasSuffix(r.URL.Path, "/export") || r.URL.Path == "/api/import/dump"
+}
+
+// withBasePath serves requests under BasePath as if they were at the root
+// and sends the bare base path to its index
+func withBasePath(next http.Handler) http.Handler {
+	if BasePath == "" {
+		return next
+	}
+	stripped := http.StripPrefix(BasePath, next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == BasePath:
+			http.Redirect(w, r, BasePath+"/", http.StatusMovedPermanently)
+		case strings.HasPrefix(r.URL.Path, BasePath+"/"):
+			stripped.ServeHTTP(w, r)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// withCORS lets pages of the allowed origins call the API. Preflight
+// requests of other origins are refused; their other requests get no CORS
+// headers, so browsers keep the responses from them.
+func withCORS(next http.Handler, origins []string) http.Handler {
+	if len(origins) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed := slices.Contains(origins, "*") || slices.Contains(origins, strings.TrimSuffix(origin, "/"))
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			if !allowed {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// StartServer finds an available port, prints the URL, optionally opens a
+// browser, and serves, over HTTPS when given a certificate, until
+// interrupted. ping backs the readiness check. On SIGINT or SIGTERM
+// in-flight requests are finished, then closeFn releases the database
+// connections.
+func StartServer(mux *http.ServeMux, port *int, name string, opts StartOptions, ping func(context.Context) error, closeFn func() error) error {
+	host := opts.Host
+	if host == "" {
+		host = "localhost"
+	}
+
+	scheme := "http"
+	if opts.TLSCert != "" || opts.TLSKey != "" {
+		if _, err := tls.LoadX509KeyPair(opts.TLSCert, opts.TLSKey); err != nil {
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		scheme = "https"
+	}
+
 	available := FindAvailablePort(*port)
 	if available != *port {
 		fmt.Printf("Port %d is in use, using port %d instead\n", *port, available)
 		*port = available
 	}
 
-	url := fmt.Sprintf("http://localhost:%d", *port)
+	browseHost := host
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		browseHost = "localhost"
+	}
+	url := fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(browseHost, strconv.Itoa(*port)))
+	if BasePath != "" {
+		url += BasePath + "/"
+	}
 	fmt.Printf("FlashORM %s starting on %s\n", name, url)
+	if Offline {
+		fmt.Println("Offline mode: no fonts, icons or editor are loaded from CDNs")
+	}
+
+	setupHealthChecks(mux, ping)
+	setupOverview(mux)
+	server := &http.Server{
+		Addr:              net.JoinHostPort(host, strconv.Itoa(*port)),
+		Handler:           withBasePath(withCORS(withRequestTimeout(withLimits(mux, opts.RateLimit, opts.RateBurst, opts.MaxConcurrent, opts.MaxConcurrentTotal, opts.QueueTimeout), opts.RequestTimeout), opts.CORSOrigins)),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return err
+	}
 
-	if openBrowser {
+	if opts.OpenBrowser {
 		go OpenBrowser(url)
 	}
 
-	return http.ListenAndServe(fmt.Sprintf(":%d", *port), mux)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		if opts.TLSCert != "" {
+			errCh <- server.ServeTLS(listener, opts.TLSCert, opts.TLSKey)
+		} else {
+			errCh <- server.Serve(listener)
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		if closeFn != nil {
+			closeFn()
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	fmt.Printf("\nStopping FlashORM %s...\n", name)
+	shutdownTimeout := opts.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 10 * time.Second
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err = server.Shutdown(shutdownCtx)
+	if closeFn != nil {
+		if closeErr := closeFn(); closeErr != nil {
+			err = errors.Join(err, closeErr)
+		}
+	}
+	return err
 }
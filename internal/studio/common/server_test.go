@@ -0,0 +1,149 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Streamed responses keep their flushes under --request-timeout and only
+// get its deadline; the rest answer with a 503 once it passes
+func TestRequestTimeout(t *testing.T) {
+	tests := []struct {
+		path     string
+		streamed bool
+	}{
+		{"/api/sql/export", true},
+		{"/api/tables/users/export", true},
+		{"/api/import/dump", true},
+		{"/api/tables", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var flushed, deadline bool
+			done := make(chan struct{})
+			handler := withRequestTimeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer close(done)
+				_, deadline = r.Context().Deadline()
+				w.Write([]byte("row\n"))
+				if f, ok := w.(http.Flusher); ok {
+					f.Flush()
+					flushed = true
+				}
+				<-r.Context().Done()
+			}), 20*time.Millisecond)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			<-done
+			if !deadline {
+				t.Error("request has no deadline")
+			}
+			if flushed != tt.streamed {
+				t.Errorf("flushed %v, want %v", flushed, tt.streamed)
+			}
+			if tt.streamed {
+				if w.Code != http.StatusOK || w.Body.String() != "row\n" {
+					t.Errorf("answered %d %q, want the streamed rows", w.Code, w.Body.String())
+				}
+			} else if w.Code != http.StatusServiceUnavailable {
+				t.Errorf("answered %d, want 503", w.Code)
+			}
+		})
+	}
+}
+
+// Requests under the base path reach the handlers as if served at the root;
+// the bare base path is sent to its index and others pass through
+func TestBasePath(t *testing.T) {
+	BasePath = "/flash"
+	defer func() { BasePath = "" }()
+
+	tests := []struct {
+		path     string
+		code     int
+		served   string
+		location string
+	}{
+		{"/flash/api/tables", http.StatusOK, "/api/tables", ""},
+		{"/flash/", http.StatusOK, "/", ""},
+		{"/flash", http.StatusMovedPermanently, "", "/flash/"},
+		{"/api/tables", http.StatusOK, "/api/tables", ""},
+		{"/flashy/api", http.StatusOK, "/flashy/api", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			served := ""
+			handler := withBasePath(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				served = r.URL.Path
+			}))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.code {
+				t.Errorf("answered %d, want %d", w.Code, tt.code)
+			}
+			if served != tt.served {
+				t.Errorf("served %q, want %q", served, tt.served)
+			}
+			if location := w.Header().Get("Location"); location != tt.location {
+				t.Errorf("redirected to %q, want %q", location, tt.location)
+			}
+		})
+	}
+}
+
+// Only the allowed origins get CORS headers, and preflights of the others
+// are refused before reaching the handlers
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name      string
+		origins   []string
+		method    string
+		origin    string
+		preflight bool
+		code      int
+		allowed   bool
+		served    bool
+	}{
+		{"same origin", []string{"https://app.example.com"}, http.MethodGet, "", false, http.StatusOK, false, true},
+		{"allowed", []string{"https://app.example.com"}, http.MethodPost, "https://app.example.com", false, http.StatusOK, true, true},
+		{"allowed with slash", []string{"https://app.example.com"}, http.MethodGet, "https://app.example.com/", false, http.StatusOK, true, true},
+		{"other", []string{"https://app.example.com"}, http.MethodPost, "https://evil.example.com", false, http.StatusOK, false, true},
+		{"wildcard", []string{"*"}, http.MethodGet, "https://evil.example.com", false, http.StatusOK, true, true},
+		{"allowed preflight", []string{"https://app.example.com"}, http.MethodOptions, "https://app.example.com", true, http.StatusNoContent, true, false},
+		{"other preflight", []string{"https://app.example.com"}, http.MethodOptions, "https://evil.example.com", true, http.StatusForbidden, false, false},
+		{"plain options", []string{"https://app.example.com"}, http.MethodOptions, "https://app.example.com", false, http.StatusOK, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			served := false
+			handler := withCORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				served = true
+			}), tt.origins)
+
+			r := httptest.NewRequest(tt.method, "/api/tables", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				r.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.code {
+				t.Errorf("answered %d, want %d", w.Code, tt.code)
+			}
+			if allowed := w.Header().Get("Access-Control-Allow-Origin") == tt.origin && tt.origin != ""; allowed != tt.allowed {
+				t.Errorf("allowed %v, want %v", allowed, tt.allowed)
+			}
+			if tt.preflight && tt.allowed && w.Header().Get("Access-Control-Allow-Methods") == "" {
+				t.Error("preflight names no methods")
+			}
+			if served != tt.served {
+				t.Errorf("served %v, want %v", served, tt.served)
+			}
+		})
+	}
+}
@@ -1,5 +1,18 @@
 // Shared utility functions for all FlashORM Studios
 
+// Path studio is served under behind a reverse proxy, empty at the root.
+// Requests to absolute paths are sent under it.
+var BASE_PATH = (document.querySelector('meta[name="flash-base-path"]') || {}).content || '';
+if (BASE_PATH) {
+    var rootFetch = window.fetch;
+    window.fetch = function(url, options) {
+        if (typeof url === 'string' && url.charAt(0) === '/' && url.charAt(1) !== '/') {
+            url = BASE_PATH + url;
+        }
+        return rootFetch.call(window, url, options);
+    };
+}
+
 // DOM helpers
 function $(selector) { return document.querySelector(selector); }
 function $$(selector) { return document.querySelectorAll(selector); }
@@ -46,14 +59,22 @@ function showToast(message, type, duration) {
     }, duration);
 }
 
-// Fetch wrapper with JSON parsing and error handling
+// Hint of a failed response on how to fix the error, '' when it has none
+function errorHint(json) {
+    return (json && json.data && json.data.hint) || '';
+}
+
+// Fetch wrapper with JSON parsing and error handling. Errors carry the
+// hint of the response as error.hint.
 async function apiCall(url, options) {
     options = options || {};
     try {
         var resp = await fetch(url, options);
         var json = await resp.json();
         if (!resp.ok) {
-            throw new Error((json && json.message) || 'Request failed');
+            var error = new Error((json && json.message) || 'Request failed');
+            error.hint = errorHint(json);
+            throw error;
         }
         return json;
     } catch (err) {
@@ -76,3 +97,97 @@ var sessionState = {
         try { sessionStorage.removeItem(key); } catch(e) {}
     }
 };
+
+// Overview of the project's stores, shown when studio knows of more than
+// the one it browses. Links to it carry data-store-overview and get a dot
+// for the health of all stores.
+function formatStoreBytes(bytes) {
+    if (!bytes) return '0 B';
+    var units = ['B', 'KB', 'MB', 'GB', 'TB'];
+    var i = Math.min(Math.floor(Math.log(bytes) / Math.log(1024)), units.length - 1);
+    return (bytes / Math.pow(1024, i)).toFixed(i ? 1 : 0) + ' ' + units[i];
+}
+
+async function fetchStoreOverview() {
+    var json = await apiCall('/api/overview');
+    return json.data.stores;
+}
+
+function updateStoreOverviewLinks(stores) {
+    var healthy = stores.every(function(store) { return store.healthy; });
+    document.querySelectorAll('[data-store-overview]').forEach(function(link) {
+        var dot = link.querySelector('.store-health-dot');
+        if (!dot) {
+            dot = document.createElement('span');
+            link.appendChild(dot);
+        }
+        dot.className = 'store-health-dot ' + (healthy ? 'healthy' : 'unhealthy');
+        dot.title = healthy ? 'All stores healthy' : 'A store is unavailable';
+    });
+}
+
+function renderStoreOverview(stores) {
+    return stores.map(function(store) {
+        var counts = store.healthy && !store.error
+            ? '<div class="store-counts">' +
+                '<div><strong>' + store.objects.toLocaleString() + '</strong> ' + escapeHtml(store.objectKind) + '</div>' +
+                '<div><strong>' + (store.itemsEstimated ? '~' : '') + store.items.toLocaleString() + '</strong> ' + escapeHtml(store.itemKind) + '</div>' +
+                '<div><strong>' + formatStoreBytes(store.sizeBytes) + '</strong></div>' +
+              '</div>'
+            : '';
+        var activity = (store.activity || []).map(function(event) {
+            return '<li>' + (event.time ? '<span class="store-activity-time">' + escapeHtml(new Date(event.time).toLocaleString()) + '</span> ' : '') +
+                escapeHtml(event.description) + '</li>';
+        }).join('');
+        return '<div class="store-card' + (store.primary ? ' primary' : '') + '">' +
+            '<div class="store-card-header">' +
+                '<span class="store-health-dot ' + (store.healthy ? 'healthy' : 'unhealthy') + '"></span>' +
+                '<strong>' + escapeHtml(store.name) + '</strong>' +
+                '<span class="badge badge-secondary">' + escapeHtml(store.provider) + (store.version ? ' ' + escapeHtml(store.version) : '') + '</span>' +
+                (store.primary ? '<span class="badge badge-primary">this studio</span>' : '') +
+                (store.healthy ? '<span class="store-latency">' + store.latencyMs.toFixed(1) + ' ms</span>' : '') +
+            '</div>' +
+            (store.error ? '<div class="store-error">' + escapeHtml(store.error) + '</div>' : '') +
+            counts +
+            (activity ? '<ul class="store-activity">' + activity + '</ul>' : '') +
+        '</div>';
+    }).join('');
+}
+
+async function showStoreOverview() {
+    var modal = document.getElementById('store-overview-modal');
+    if (!modal) {
+        modal = document.createElement('div');
+        modal.className = 'modal';
+        modal.id = 'store-overview-modal';
+        modal.innerHTML = '<div class="modal-backdrop"></div>' +
+            '<div class="modal-dialog modal-lg">' +
+                '<div class="modal-header"><h3>Overview</h3>' +
+                    '<div><button class="btn btn-sm" id="store-overview-refresh">Refresh</button> ' +
+                    '<button class="btn btn-sm" id="store-overview-close">Close</button></div>' +
+                '</div>' +
+                '<div class="modal-body" id="store-overview-body"></div>' +
+            '</div>';
+        document.body.appendChild(modal);
+        var close = function() { modal.classList.remove('show'); };
+        modal.querySelector('.modal-backdrop').onclick = close;
+        modal.querySelector('#store-overview-close').onclick = close;
+        modal.querySelector('#store-overview-refresh').onclick = showStoreOverview;
+    }
+    modal.classList.add('show');
+
+    var body = document.getElementById('store-overview-body');
+    body.innerHTML = '<div class="store-overview-loading">Checking stores...</div>';
+    try {
+        var stores = await fetchStoreOverview();
+        updateStoreOverviewLinks(stores);
+        body.innerHTML = renderStoreOverview(stores);
+    } catch (err) {
+        body.innerHTML = '<div class="store-error">' + escapeHtml(err.message) + '</div>';
+    }
+}
+
+document.addEventListener('DOMContentLoaded', function() {
+    if (!document.querySelector('[data-store-overview]')) return;
+    fetchStoreOverview().then(updateStoreOverviewLinks).catch(function() {});
+});
@@ -1,9 +1,37 @@
 package common
 
-// TableInfo represents basic table information
+import "encoding/json"
+
+// TableInfo represents basic table information. RowCount is nil when the
+// rows weren't counted, to be fetched once the table is shown.
 type TableInfo struct {
-	Name     string `json:"name"`
-	RowCount int    `json:"row_count"`
+	Name              string `json:"name"`
+	RowCount          *int   `json:"row_count"`
+	RowCountEstimated bool   `json:"row_count_estimated,omitempty"`
+	// Partitions of a partitioned table; RowCount includes their rows
+	Partitions []PartitionInfo `json:"partitions,omitempty"`
+}
+
+// PartitionInfo represents a partition of a partitioned table
+type PartitionInfo struct {
+	Name              string `json:"name"`
+	RowCount          *int   `json:"row_count"`
+	RowCountEstimated bool   `json:"row_count_estimated,omitempty"`
+	Bound             string `json:"bound"`
+}
+
+// TableList is a page of the tables whose names match a search
+type TableList struct {
+	Tables []TableInfo `json:"tables"`
+	Total  int         `json:"total"` // tables matching the search
+	Offset int         `json:"offset"`
+	Limit  int         `json:"limit"` // 0 when every table is listed
+}
+
+// RowCount is the number of rows of a table
+type RowCount struct {
+	RowCount  int  `json:"row_count"`
+	Estimated bool `json:"row_count_estimated,omitempty"`
 }
 
 // ColumnInfo represents column metadata
@@ -16,23 +44,65 @@ type ColumnInfo struct {
 	AutoIncrement    bool   `json:"auto_increment,omitempty"`
 	ForeignKeyTable  string `json:"foreign_key_table,omitempty"`
 	ForeignKeyColumn string `json:"foreign_key_column,omitempty"`
+	ReadOnly         bool   `json:"read_only,omitempty"`
+	// Kind picks the cell editor: boolean, integer, number, date, timestamp,
+	// time, enum, json, uuid or text
+	Kind       string   `json:"kind,omitempty"`
+	EnumValues []string `json:"enum_values,omitempty"`
+	// GeneratedUUID is set when studio generates the column on insert
+	GeneratedUUID bool `json:"generated_uuid,omitempty"`
+	// TimeZone is set on columns holding instants, sent in UTC
+	TimeZone bool `json:"time_zone,omitempty"`
+	// ExactNumber is set on columns holding 64-bit integers or decimals,
+	// whose values are sent as text so no digits are lost
+	ExactNumber bool `json:"exact_number,omitempty"`
+	// Masked is set on columns listed in studio.masked_columns, whose
+	// values are hidden from callers without the admin role
+	Masked bool `json:"masked,omitempty"`
+	// Encrypted is set on columns listed in encryption.columns, whose
+	// values are shown as the length of their ciphertext
+	Encrypted bool `json:"encrypted,omitempty"`
 }
 
 // TableData represents paginated table data
 type TableData struct {
-	Columns []ColumnInfo     `json:"columns"`
-	Rows    []map[string]any `json:"rows"`
-	Total   int              `json:"total"`
-	Page    int              `json:"page"`
-	Limit   int              `json:"limit"`
+	Columns          []ColumnInfo     `json:"columns"`
+	Rows             []map[string]any `json:"rows"`
+	Total            int              `json:"total"`
+	TotalEstimated   bool             `json:"total_estimated,omitempty"`
+	Page             int              `json:"page"`
+	Limit            int              `json:"limit"`
+	SoftDeleteColumn string           `json:"soft_delete_column,omitempty"`
+	Migration        string           `json:"migration,omitempty"` // file DDL was recorded in
+	// RowVersions maps the primary key of each row to its version, which
+	// edits send back to detect that someone else changed the row
+	RowVersions map[string]string `json:"row_versions,omitempty"`
+	// TimeOffsets has, for each row, the offsets of its instants before
+	// they were turned to UTC, where that wasn't UTC
+	TimeOffsets []map[string]string `json:"time_offsets,omitempty"`
+	// OrderColumn is the primary key rows are ordered by, when its values
+	// sort as rows were inserted (KeyOrder: integer, ulid or uuidv7), and
+	// NextCursor the key the next page starts after, sent back as ?after=
+	OrderColumn string `json:"order_column,omitempty"`
+	KeyOrder    string `json:"key_order,omitempty"`
+	NextCursor  string `json:"next_cursor,omitempty"`
 }
 
 // RowChange represents a single row modification
 type RowChange struct {
 	RowID  string `json:"row_id"`
 	Column string `json:"column"`
-	Value  any    `json:"value"`
+	// Value is the new value as typed JSON: a string, number, boolean,
+	// object or array, or null for NULL. An empty string stays empty.
+	Value json.RawMessage `json:"value,omitempty"`
+	// Null sets the column to NULL whatever Value is, for clients that
+	// can't tell a null value from a missing one
+	Null   bool   `json:"null,omitempty"`
 	Action string `json:"action"`
+	// Version is the row version the change was made on, from the
+	// row_versions of the table data; the save is rejected when the row
+	// changed since
+	Version string `json:"version,omitempty"`
 }
 
 // SaveRequest represents a batch save request
@@ -67,6 +137,11 @@ type Filter struct {
 	Column   string `json:"column"`   
 	Operator string `json:"operator"` 
 	Value    string `json:"value"`    
+	Values   []string `json:"values,omitempty"`
+	// CaseSensitive and IgnoreAccents change how text is compared; accents
+	// can only be ignored on PostgreSQL with the unaccent extension
+	CaseSensitive bool `json:"case_sensitive,omitempty"`
+	IgnoreAccents bool `json:"ignore_accents,omitempty"`
 }
 
 // ExportType defines the type of export
@@ -115,6 +190,12 @@ type ExportTable struct {
 	Name   string             `json:"name"`
 	Schema *ExportTableSchema `json:"schema,omitempty"`
 	Data   []map[string]any   `json:"data,omitempty"`
+	// ExactNumbers lists the columns whose numbers are written as text
+	ExactNumbers []string `json:"exact_numbers,omitempty"`
+	// RowCount and Checksum describe Data, for an import to verify it
+	// against. Schema-only exports have neither.
+	RowCount int    `json:"row_count,omitempty"`
+	Checksum string `json:"checksum,omitempty"`
 }
 
 // ExportData represents the complete export structure
@@ -125,6 +206,48 @@ type ExportData struct {
 	ExportType       ExportType       `json:"export_type"`
 	EnumTypes        []ExportEnumType `json:"enum_types,omitempty"`
 	Tables           []ExportTable    `json:"tables"`
+	// Collections are the MongoDB collections of the export
+	Collections []ExportCollection `json:"collections,omitempty"`
+}
+
+// ExportCollection represents a MongoDB collection in the export. Documents
+// are canonical Extended JSON, which keeps every BSON type.
+type ExportCollection struct {
+	Name      string                  `json:"name"`
+	Schema    *ExportCollectionSchema `json:"schema,omitempty"`
+	Documents []json.RawMessage       `json:"documents,omitempty"`
+	// DocumentCount and Checksum describe Documents, for an import to
+	// verify it against
+	DocumentCount int    `json:"document_count,omitempty"`
+	Checksum      string `json:"checksum,omitempty"`
+}
+
+// ExportCollectionSchema describes a collection: the fields inferred from
+// its documents, its indexes and the options it was created with, such as
+// a validator, as canonical Extended JSON
+type ExportCollectionSchema struct {
+	Fields  []ExportField           `json:"fields"`
+	Indexes []ExportCollectionIndex `json:"indexes,omitempty"`
+	Options json.RawMessage         `json:"options,omitempty"`
+}
+
+// ExportField is a field of a collection's documents, a dotted path for
+// embedded documents, with the BSON types it was found with
+type ExportField struct {
+	Name  string   `json:"name"`
+	Types []string `json:"types"`
+	// Optional is set when some documents don't have the field
+	Optional bool `json:"optional,omitempty"`
+}
+
+// ExportCollectionIndex represents a MongoDB index. Keys keep their order
+// as an Extended JSON document; Options holds the rest of the index, such
+// as sparse or expireAfterSeconds.
+type ExportCollectionIndex struct {
+	Name    string          `json:"name"`
+	Keys    json.RawMessage `json:"keys"`
+	Unique  bool            `json:"unique,omitempty"`
+	Options json.RawMessage `json:"options,omitempty"`
 }
 
 // ImportResult represents the result of an import operation
@@ -136,4 +259,157 @@ type ImportResult struct {
 	RowsInserted     int      `json:"rows_inserted"`
 	RowsUpdated      int      `json:"rows_updated"`
 	Errors           []string `json:"errors,omitempty"`
+	// Verified is set when the file was checked before the import, and
+	// Incomplete then lists the tables whose rows didn't all import
+	Verified   bool         `json:"verified,omitempty"`
+	Incomplete []TableCheck `json:"incomplete,omitempty"`
+	// CollectionsCreated and IndexesCreated are of MongoDB imports, whose
+	// documents count as rows
+	CollectionsCreated []string `json:"collections_created,omitempty"`
+	IndexesCreated     int      `json:"indexes_created,omitempty"`
+}
+
+// TableCheck is a table of an export file that failed a check, of the file
+// itself or of what an import wrote
+type TableCheck struct {
+	Table    string `json:"table"`
+	Expected int    `json:"expected"`
+	Actual   int    `json:"actual"`
+	Problem  string `json:"problem"`
+}
+
+// TableProfile holds column statistics of a table, computed on a sample
+type TableProfile struct {
+	Table             string          `json:"table"`
+	RowCount          int             `json:"row_count"`
+	RowCountEstimated bool            `json:"row_count_estimated,omitempty"`
+	SampleSize        int             `json:"sample_size"`
+	Sampled           bool            `json:"sampled"`
+	Partial           bool            `json:"partial"` // time budget ran out before every column was profiled
+	DurationMS        int64           `json:"duration_ms"`
+	Columns           []ColumnProfile `json:"columns"`
+}
+
+// ColumnProfile holds the statistics of a single column
+type ColumnProfile struct {
+	Name          string            `json:"name"`
+	Type          string            `json:"type"`
+	NullCount     int               `json:"null_count"`
+	NullFraction  float64           `json:"null_fraction"`
+	DistinctCount int               `json:"distinct_count"`
+	Min           any               `json:"min,omitempty"`
+	Max           any               `json:"max,omitempty"`
+	TopValues     []ValueCount      `json:"top_values,omitempty"`
+	Histogram     []HistogramBucket `json:"histogram,omitempty"`
+	Skipped       bool              `json:"skipped,omitempty"`
+	Masked        bool              `json:"masked,omitempty"`
+	Error         string            `json:"error,omitempty"`
+}
+
+// ValueCount is a value and how often it occurs
+type ValueCount struct {
+	Value any `json:"value"`
+	Count int `json:"count"`
+}
+
+// HistogramBucket counts the values in [From, To)
+type HistogramBucket struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int     `json:"count"`
+}
+
+// ChartSeries is an aggregate of a table grouped by a column, ready to plot
+type ChartSeries struct {
+	Table     string       `json:"table"`
+	GroupBy   string       `json:"group_by"`
+	Aggregate string       `json:"aggregate"` // count, sum or avg
+	Column    string       `json:"column,omitempty"`
+	Bucket    string       `json:"bucket,omitempty"` // time bucket of the group column
+	Label     string       `json:"label"`            // e.g. avg(price)
+	Points    []ChartPoint `json:"points"`
+	Truncated bool         `json:"truncated"` // there are more groups than points
+}
+
+// ChartPoint is the aggregate Y of the rows in group X
+type ChartPoint struct {
+	X any     `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// RowDetail is a row together with the rows it references and the rows
+// that reference it
+type RowDetail struct {
+	Table    string           `json:"table"`
+	Row      map[string]any   `json:"row"`
+	Parents  []ParentRow      `json:"parents"`
+	Children []RelatedRowPage `json:"children"`
+}
+
+// ParentRow is the row a foreign key column points to; Row is nil when the
+// column is NULL or the referenced row is missing
+type ParentRow struct {
+	Column           string         `json:"column"`
+	Table            string         `json:"table"`
+	ReferencedColumn string         `json:"referenced_column"`
+	Row              map[string]any `json:"row"`
+}
+
+// RelatedRowPage is one page of the rows of a table that reference a row
+// through a foreign key column
+type RelatedRowPage struct {
+	Table            string           `json:"table"`
+	Column           string           `json:"column"`
+	ReferencedColumn string           `json:"referenced_column"`
+	Rows             []map[string]any `json:"rows"`
+	Total            int              `json:"total"`
+	Page             int              `json:"page"`
+	Limit            int              `json:"limit"`
+}
+
+// DeleteImpact describes what deleting rows would do to the rows that
+// reference them
+type DeleteImpact struct {
+	Table     string         `json:"table"`
+	Rows      int            `json:"rows"`
+	Effects   []DeleteEffect `json:"effects"`
+	Blocked   bool           `json:"blocked"`             // a RESTRICT or NO ACTION reference would make the delete fail
+	Truncated bool           `json:"truncated,omitempty"` // cascades went deeper than were followed
+}
+
+// DeleteEffect is what happens to the rows of a table that reference rows
+// being deleted through one foreign key column
+type DeleteEffect struct {
+	Table           string   `json:"table"`
+	Column          string   `json:"column"`
+	ReferencedTable string   `json:"referenced_table"`
+	OnDelete        string   `json:"on_delete"`
+	Action          string   `json:"action"` // delete, set null, set default or restrict
+	Rows            int      `json:"rows"`
+	Path            []string `json:"path"` // tables from the deleted rows down to this one
+}
+
+// NamedQuery is a query of the project's queries folder
+type NamedQuery struct {
+	Name       string       `json:"name"`
+	Cmd        string       `json:"cmd"`
+	SQL        string       `json:"sql"`
+	Params     []QueryParam `json:"params"`
+	SourceFile string       `json:"source_file"`
+}
+
+// QueryParam is a parameter of a query, for the editor to prompt for
+type QueryParam struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Placeholder string `json:"placeholder"` // $1, ? or :name
+}
+
+// QuerySnippets are ready-to-paste ways of running a named query
+type QuerySnippets struct {
+	NamedQuery
+	TypeScript string `json:"typescript"`
+	Go         string `json:"go"`
+	CLI        string `json:"cli"` // psql, mysql or sqlite3 depending on the provider
+	Curl       string `json:"curl"`
 }
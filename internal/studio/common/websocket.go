@@ -0,0 +1,231 @@
+package common
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// webSocketGUID is appended to a handshake's key to prove the server speaks
+// the protocol (RFC 6455, section 1.3)
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxWebSocketMessage bounds the messages read from pages, which only send
+// small settings
+const maxWebSocketMessage = 64 << 10
+
+// Opcodes of websocket frames
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xA
+)
+
+// WebSocket is the server side of a websocket: enough of RFC 6455 for a
+// studio to push JSON to a page and read the page's small messages. Writes
+// may come from several goroutines; reads from one.
+type WebSocket struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	writeMu sync.Mutex
+	closed  bool
+}
+
+// IsWebSocketUpgrade reports whether a request asks for a websocket
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") && headerHasToken(r.Header, "Connection", "upgrade")
+}
+
+// UpgradeWebSocket completes a websocket handshake, answering with an error
+// when the request isn't one. Pages of other origins are refused, since
+// browsers let any page open a websocket to localhost.
+func UpgradeWebSocket(w http.ResponseWriter, r *http.Request) (*WebSocket, error) {
+	if !IsWebSocketUpgrade(r) {
+		JSONError(w, http.StatusBadRequest, "Expected a websocket upgrade")
+		return nil, fmt.Errorf("not a websocket upgrade")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		JSONError(w, http.StatusUpgradeRequired, "Unsupported websocket version")
+		return nil, fmt.Errorf("unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		JSONError(w, http.StatusBadRequest, "Missing Sec-WebSocket-Key")
+		return nil, fmt.Errorf("missing websocket key")
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || !strings.EqualFold(u.Host, r.Host) {
+			JSONError(w, http.StatusForbidden, "Cross-origin websocket refused")
+			return nil, fmt.Errorf("cross-origin websocket from %s", origin)
+		}
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		JSONError(w, http.StatusInternalServerError, "Websockets aren't supported here")
+		return nil, fmt.Errorf("failed to take over the connection: %w", err)
+	}
+	// The server's read deadlines were for the request
+	_ = conn.SetDeadline(time.Time{})
+
+	sum := sha1.Sum([]byte(key + webSocketGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(sum[:]))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &WebSocket{conn: conn, reader: rw.Reader}, nil
+}
+
+// WriteJSON sends a value as a text message
+func (ws *WebSocket) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ws.writeFrame(wsText, data)
+}
+
+// ReadMessage returns the next text or binary message, answering pings on
+// the way. It returns io.EOF once the page closes the socket.
+func (ws *WebSocket) ReadMessage() ([]byte, error) {
+	var message []byte
+	for {
+		fin, opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch opcode {
+		case wsPing:
+			if err := ws.writeFrame(wsPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			_ = ws.writeFrame(wsClose, payload)
+			return nil, io.EOF
+		}
+
+		message = append(message, payload...)
+		if len(message) > maxWebSocketMessage {
+			return nil, fmt.Errorf("websocket message over %d bytes", maxWebSocketMessage)
+		}
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// Close sends a close frame with a reason and closes the connection
+func (ws *WebSocket) Close(reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, 1000)
+	payload = append(payload, reason...)
+	if len(payload) > 125 {
+		payload = payload[:125]
+	}
+	_ = ws.writeFrame(wsClose, payload)
+	return ws.conn.Close()
+}
+
+func (ws *WebSocket) writeFrame(opcode byte, payload []byte) error {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	if ws.closed {
+		return net.ErrClosed
+	}
+
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	_ = ws.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := ws.conn.Write(append(header, payload...))
+	// Nothing may follow a close frame
+	if opcode == wsClose {
+		ws.closed = true
+	}
+	return err
+}
+
+func (ws *WebSocket) readFrame() (bool, byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(ws.reader, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := head[0]&0x80 != 0
+	opcode := head[0] & 0x0F
+	if head[1]&0x80 == 0 {
+		return false, 0, nil, errors.New("websocket frame from the page isn't masked")
+	}
+
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(ws.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(ws.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > maxWebSocketMessage {
+		return false, 0, nil, fmt.Errorf("websocket frame over %d bytes", maxWebSocketMessage)
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(ws.reader, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// headerHasToken reports whether a comma separated header lists a token
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
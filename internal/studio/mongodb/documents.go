@@ -0,0 +1,448 @@
+package mongodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DocumentEdit is a document as the editor shows it: relaxed Extended JSON,
+// so dates, ObjectIds, UUIDs and decimals keep their types, with the
+// collection's validation rules
+type DocumentEdit struct {
+	// ID is the document's _id as canonical Extended JSON, which the
+	// document endpoints take as {id}
+	ID       string `json:"id"`
+	Document string `json:"document"`
+	// Schema is the $jsonSchema of the collection's validator, if any
+	Schema           json.RawMessage `json:"schema,omitempty"`
+	ValidationLevel  string          `json:"validation_level,omitempty"`
+	ValidationAction string          `json:"validation_action,omitempty"`
+}
+
+// PatchField is a field an edit sets, with its new value as Extended JSON
+type PatchField struct {
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// DocumentPatch is the $set and $unset an edit comes down to, with where
+// the edited document breaks the collection's schema
+type DocumentPatch struct {
+	Set    []PatchField  `json:"set"`
+	Unset  []string      `json:"unset"`
+	Errors []SchemaError `json:"errors"`
+	// Applied is set once the patch is saved
+	Applied bool `json:"applied"`
+}
+
+// InvalidDocumentError rejects an edit that isn't an Extended JSON document
+type InvalidDocumentError struct {
+	Message string
+}
+
+func (e *InvalidDocumentError) Error() string {
+	return "invalid document: " + e.Message
+}
+
+// DocumentChangedError rejects an edit of a document that changed since it
+// was loaded, with the document as it is now
+type DocumentChangedError struct {
+	Current string
+}
+
+func (e *DocumentChangedError) Error() string {
+	return "the document was changed by someone else since you loaded it"
+}
+
+// SchemaViolationError rejects an edit that breaks the collection's schema
+// when its validationAction is error
+type SchemaViolationError struct {
+	Patch *DocumentPatch
+}
+
+func (e *SchemaViolationError) Error() string {
+	return fmt.Sprintf("document fails validation: %s %s", e.Patch.Errors[0].Path, e.Patch.Errors[0].Message)
+}
+
+type documentEditor interface {
+	FindDocumentByID(ctx context.Context, collection string, id string) (bson.Raw, error)
+	UpdateDocumentByID(ctx context.Context, collection string, id string, update interface{}) (bool, error)
+	GetCollectionOptions(ctx context.Context, collection string) (bson.Raw, error)
+}
+
+func (s *Service) documentEditor() (documentEditor, error) {
+	editor, ok := s.adapter.(documentEditor)
+	if !ok {
+		return nil, fmt.Errorf("adapter does not support MongoDB operations")
+	}
+	return editor, nil
+}
+
+// GetDocument returns a document for editing. The ID is a plain string or
+// ObjectId hex, or Extended JSON for other types of _id.
+func (s *Service) GetDocument(ctx context.Context, collection, id string) (*DocumentEdit, error) {
+	editor, err := s.documentEditor()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := editor.FindDocumentByID(ctx, collection, id)
+	if err != nil {
+		return nil, err
+	}
+
+	edit := &DocumentEdit{}
+	if edit.ID, err = extJSONValue(raw.Lookup("_id"), true); err != nil {
+		return nil, err
+	}
+	if edit.Document, err = relaxedJSON(raw); err != nil {
+		return nil, err
+	}
+	validation, err := s.collectionValidation(ctx, editor, collection)
+	if err != nil {
+		return nil, err
+	}
+	if validation.schema != nil {
+		edit.Schema, _ = json.Marshal(validation.schema)
+		edit.ValidationLevel = validation.level
+		edit.ValidationAction = validation.action
+	}
+	return edit, nil
+}
+
+// PatchDocument saves an edited document as the $set and $unset of the
+// fields that changed, so fields the editor doesn't show and concurrent
+// edits of other fields are kept. original is the document as it was
+// loaded; the edit is refused if the stored one no longer matches it. The
+// edited document is checked against the collection's $jsonSchema first,
+// and with dryRun nothing is saved.
+func (s *Service) PatchDocument(ctx context.Context, collection, id, original, edited string, dryRun bool) (*DocumentPatch, error) {
+	editor, err := s.documentEditor()
+	if err != nil {
+		return nil, err
+	}
+	var updated bson.D
+	if err := bson.UnmarshalExtJSON([]byte(edited), false, &updated); err != nil {
+		return nil, &InvalidDocumentError{Message: err.Error()}
+	}
+
+	raw, err := editor.FindDocumentByID(ctx, collection, id)
+	if err != nil {
+		return nil, err
+	}
+	// The stored document goes through relaxed Extended JSON like the
+	// edited one did, so fields the user didn't touch compare equal
+	currentJSON, err := relaxedJSON(raw)
+	if err != nil {
+		return nil, err
+	}
+	var current bson.D
+	if err := bson.UnmarshalExtJSON([]byte(currentJSON), false, &current); err != nil {
+		return nil, err
+	}
+	if original != "" {
+		var loaded bson.D
+		if err := bson.UnmarshalExtJSON([]byte(original), false, &loaded); err != nil {
+			return nil, &InvalidDocumentError{Message: "original: " + err.Error()}
+		}
+		if !sameValue(loaded, current) {
+			return nil, &DocumentChangedError{Current: currentJSON}
+		}
+	}
+
+	patch := &DocumentPatch{Set: []PatchField{}, Unset: []string{}, Errors: []SchemaError{}}
+	var set bson.D
+	if err := diffDocuments(current, updated, "", &set, &patch.Unset); err != nil {
+		return nil, err
+	}
+	// The stored document with its exact types, which the schema is checked
+	// against once patched
+	var stored bson.D
+	if err := bson.Unmarshal(raw, &stored); err != nil {
+		return nil, err
+	}
+	for i, field := range set {
+		if old, ok := lookupPath(stored, field.Key); ok {
+			set[i].Value = keepNumberType(old, field.Value)
+		}
+		value, err := relaxedValue(set[i].Value)
+		if err != nil {
+			return nil, err
+		}
+		patch.Set = append(patch.Set, PatchField{Path: field.Key, Value: value})
+	}
+
+	validation, err := s.collectionValidation(ctx, editor, collection)
+	if err != nil {
+		return nil, err
+	}
+	// moderate only holds documents that already pass to the schema
+	if validation.schema != nil && validation.level != "off" &&
+		(validation.level != "moderate" || len(validateSchema(validation.schema, stored, "")) == 0) {
+		if errs := validateSchema(validation.schema, applyPatch(stored, set, patch.Unset), ""); errs != nil {
+			patch.Errors = errs
+		}
+	}
+
+	if dryRun || (len(set) == 0 && len(patch.Unset) == 0) {
+		return patch, nil
+	}
+	if len(patch.Errors) > 0 && validation.action != "warn" {
+		return patch, &SchemaViolationError{Patch: patch}
+	}
+
+	update := bson.D{}
+	if len(set) > 0 {
+		update = append(update, bson.E{Key: "$set", Value: set})
+	}
+	if len(patch.Unset) > 0 {
+		unset := bson.D{}
+		for _, path := range patch.Unset {
+			unset = append(unset, bson.E{Key: path, Value: ""})
+		}
+		update = append(update, bson.E{Key: "$unset", Value: unset})
+	}
+	found, err := editor.UpdateDocumentByID(ctx, collection, id, update)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, mongo.ErrNoDocuments
+	}
+	patch.Applied = true
+	return patch, nil
+}
+
+// diffDocuments collects the $set and $unset paths that turn one document
+// into another. Embedded documents are diffed field by field; arrays and
+// other values are set whole.
+func diffDocuments(old, updated bson.D, prefix string, set *bson.D, unset *[]string) error {
+	oldFields := make(map[string]interface{}, len(old))
+	for _, field := range old {
+		oldFields[field.Key] = field.Value
+	}
+	newFields := make(map[string]bool, len(updated))
+
+	for _, field := range updated {
+		newFields[field.Key] = true
+		oldValue, existed := oldFields[field.Key]
+		if existed && sameValue(oldValue, field.Value) {
+			continue
+		}
+		if prefix == "" && field.Key == "_id" {
+			return &InvalidDocumentError{Message: "_id can't be changed; insert a copy and delete this document instead"}
+		}
+		if !isPathSafe(field.Key) {
+			return &InvalidDocumentError{Message: fmt.Sprintf("field %q can't be updated by path", prefix+field.Key)}
+		}
+		oldDoc, oldIsDoc := oldValue.(bson.D)
+		newDoc, newIsDoc := field.Value.(bson.D)
+		if existed && oldIsDoc && newIsDoc {
+			if err := diffDocuments(oldDoc, newDoc, prefix+field.Key+".", set, unset); err != nil {
+				return err
+			}
+			continue
+		}
+		*set = append(*set, bson.E{Key: prefix + field.Key, Value: field.Value})
+	}
+
+	for _, field := range old {
+		if newFields[field.Key] {
+			continue
+		}
+		if prefix == "" && field.Key == "_id" {
+			return &InvalidDocumentError{Message: "_id can't be removed"}
+		}
+		if !isPathSafe(field.Key) {
+			return &InvalidDocumentError{Message: fmt.Sprintf("field %q can't be removed by path", prefix+field.Key)}
+		}
+		*unset = append(*unset, prefix+field.Key)
+	}
+	return nil
+}
+
+// keepNumberType gives a number the type of the number it replaces, since
+// relaxed Extended JSON writes longs and whole doubles as plain numbers
+func keepNumberType(old, value interface{}) interface{} {
+	n, ok := value.(int32)
+	if !ok {
+		if long, isLong := value.(int64); isLong {
+			if _, oldIsDouble := old.(float64); oldIsDouble {
+				return float64(long)
+			}
+		}
+		return value
+	}
+	switch old.(type) {
+	case int64:
+		return int64(n)
+	case float64:
+		return float64(n)
+	}
+	return value
+}
+
+// lookupPath returns the value at a dotted path of a document
+func lookupPath(doc bson.D, path string) (interface{}, bool) {
+	name, rest, nested := strings.Cut(path, ".")
+	for _, field := range doc {
+		if field.Key != name {
+			continue
+		}
+		if !nested {
+			return field.Value, true
+		}
+		if sub, ok := field.Value.(bson.D); ok {
+			return lookupPath(sub, rest)
+		}
+		return nil, false
+	}
+	return nil, false
+}
+
+// applyPatch returns a document with $set and $unset paths applied, as the
+// server would store it
+func applyPatch(doc bson.D, set bson.D, unset []string) bson.D {
+	result := copyDocument(doc)
+	for _, field := range set {
+		result = setPath(result, field.Key, field.Value)
+	}
+	for _, path := range unset {
+		result = unsetPath(result, path)
+	}
+	return result
+}
+
+func copyDocument(doc bson.D) bson.D {
+	result := make(bson.D, len(doc))
+	for i, field := range doc {
+		if sub, ok := field.Value.(bson.D); ok {
+			field.Value = copyDocument(sub)
+		}
+		result[i] = field
+	}
+	return result
+}
+
+func setPath(doc bson.D, path string, value interface{}) bson.D {
+	name, rest, nested := strings.Cut(path, ".")
+	for i, field := range doc {
+		if field.Key != name {
+			continue
+		}
+		if !nested {
+			doc[i].Value = value
+		} else if sub, ok := field.Value.(bson.D); ok {
+			doc[i].Value = setPath(sub, rest, value)
+		}
+		return doc
+	}
+	if nested {
+		return append(doc, bson.E{Key: name, Value: setPath(bson.D{}, rest, value)})
+	}
+	return append(doc, bson.E{Key: name, Value: value})
+}
+
+func unsetPath(doc bson.D, path string) bson.D {
+	name, rest, nested := strings.Cut(path, ".")
+	for i, field := range doc {
+		if field.Key != name {
+			continue
+		}
+		if !nested {
+			return append(doc[:i:i], doc[i+1:]...)
+		}
+		if sub, ok := field.Value.(bson.D); ok {
+			doc[i].Value = unsetPath(sub, rest)
+		}
+		return doc
+	}
+	return doc
+}
+
+// isPathSafe reports whether a field name can be part of an update path
+func isPathSafe(name string) bool {
+	return name != "" && !strings.Contains(name, ".") && !strings.HasPrefix(name, "$")
+}
+
+// sameValue reports whether two values are the same, type included
+func sameValue(a, b interface{}) bool {
+	x, errX := bson.Marshal(bson.D{{Key: "v", Value: a}})
+	y, errY := bson.Marshal(bson.D{{Key: "v", Value: b}})
+	return errX == nil && errY == nil && bytes.Equal(x, y)
+}
+
+// relaxedJSON writes a document as indented relaxed Extended JSON
+func relaxedJSON(doc interface{}) (string, error) {
+	out, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		return "", err
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, out, "", "  "); err != nil {
+		return "", err
+	}
+	return indented.String(), nil
+}
+
+func relaxedValue(v interface{}) (json.RawMessage, error) {
+	value, err := extJSONValue(v, false)
+	return json.RawMessage(value), err
+}
+
+// extJSONValue writes a single value as Extended JSON, which only documents
+// can be marshalled as
+func extJSONValue(v interface{}, canonical bool) (string, error) {
+	out, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: v}}, canonical, false)
+	if err != nil {
+		return "", err
+	}
+	var wrapper struct {
+		V json.RawMessage `json:"v"`
+	}
+	if err := json.Unmarshal(out, &wrapper); err != nil {
+		return "", err
+	}
+	return string(wrapper.V), nil
+}
+
+// collectionValidation is the $jsonSchema part of a collection's validator
+type collectionValidation struct {
+	schema bson.M
+	level  string
+	action string
+}
+
+func (s *Service) collectionValidation(ctx context.Context, editor documentEditor, collection string) (*collectionValidation, error) {
+	opts, err := editor.GetCollectionOptions(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+	validation := &collectionValidation{level: "strict", action: "error"}
+	if opts == nil {
+		return validation, nil
+	}
+	if level, ok := opts.Lookup("validationLevel").StringValueOK(); ok {
+		validation.level = level
+	}
+	if action, ok := opts.Lookup("validationAction").StringValueOK(); ok {
+		validation.action = action
+	}
+	if schema, ok := opts.Lookup("validator", "$jsonSchema").DocumentOK(); ok {
+		if err := bson.Unmarshal(schema, &validation.schema); err != nil {
+			return nil, err
+		}
+	}
+	return validation, nil
+}
+
+// isNotFound reports whether an error is a document that isn't there
+func isNotFound(err error) bool {
+	return errors.Is(err, mongo.ErrNoDocuments)
+}
@@ -0,0 +1,507 @@
+package mongodb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// schemaSample bounds the documents the fields of a schema-only export
+	// are inferred from
+	schemaSample = 1000
+	// importBatch is how many documents an import writes at once
+	importBatch = 1000
+)
+
+// errSampled stops reading a collection once enough documents were seen
+var errSampled = errors.New("sampled")
+
+// ExportVerifyError is an export file whose documents don't match the
+// counts and checksums written with it, found before anything is imported
+type ExportVerifyError struct {
+	Message     string
+	Collections []common.TableCheck
+}
+
+func (e *ExportVerifyError) Error() string {
+	if len(e.Collections) == 0 {
+		return e.Message
+	}
+	problems := make([]string, len(e.Collections))
+	for i, check := range e.Collections {
+		problems[i] = fmt.Sprintf("%s: %s", check.Table, check.Problem)
+	}
+	return fmt.Sprintf("%s: %s", e.Message, strings.Join(problems, "; "))
+}
+
+type collectionPorter interface {
+	ListCollections(ctx context.Context) ([]string, error)
+	ForEachDocument(ctx context.Context, collection string, each func(doc bson.Raw) error) error
+	ListIndexSpecs(ctx context.Context, collection string) ([]bson.Raw, error)
+	GetCollectionOptions(ctx context.Context, collection string) (bson.Raw, error)
+	CreateCollectionWithOptions(ctx context.Context, name string, opts bson.Raw) error
+	CreateIndexSpecs(ctx context.Context, collection string, specs []bson.D) error
+	UpsertDocuments(ctx context.Context, collection string, docs []bson.Raw) (int64, int64, error)
+}
+
+func (s *Service) collectionPorter() (collectionPorter, error) {
+	porter, ok := s.adapter.(collectionPorter)
+	if !ok {
+		return nil, fmt.Errorf("adapter does not support MongoDB operations")
+	}
+	return porter, nil
+}
+
+// ExportDatabase exports the collections of the current database in the
+// format SQL databases export to: the schema is the fields inferred from
+// the documents, the indexes and the collection options, and the data is
+// the documents as canonical Extended JSON
+func (s *Service) ExportDatabase(ctx context.Context, exportType common.ExportType) (*common.ExportData, error) {
+	porter, err := s.collectionPorter()
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := porter.ListCollections(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+	sort.Strings(names)
+
+	withSchema := exportType == common.ExportSchemaOnly || exportType == common.ExportComplete
+	withData := exportType == common.ExportDataOnly || exportType == common.ExportComplete
+
+	exportData := &common.ExportData{
+		Version:          "1.0",
+		ExportedAt:       time.Now().UTC().Format(time.RFC3339),
+		DatabaseProvider: "mongodb",
+		ExportType:       exportType,
+		Tables:           make([]common.ExportTable, 0),
+		Collections:      make([]common.ExportCollection, 0, len(names)),
+	}
+
+	for _, name := range names {
+		collection := common.ExportCollection{Name: name}
+		fields := &fieldInferrer{fields: map[string]*fieldStats{}}
+
+		err := porter.ForEachDocument(ctx, name, func(doc bson.Raw) error {
+			if withSchema {
+				fields.add(doc)
+			}
+			if !withData {
+				if fields.count >= schemaSample {
+					return errSampled
+				}
+				return nil
+			}
+			out, err := bson.MarshalExtJSON(doc, true, false)
+			if err != nil {
+				return err
+			}
+			collection.Documents = append(collection.Documents, out)
+			return nil
+		})
+		if err != nil && !errors.Is(err, errSampled) {
+			return nil, fmt.Errorf("failed to export collection %s: %w", name, err)
+		}
+
+		if withData {
+			collection.DocumentCount = len(collection.Documents)
+			collection.Checksum = documentsChecksum(collection.Documents)
+		}
+		if withSchema {
+			if collection.Schema, err = s.collectionSchema(ctx, porter, name, fields); err != nil {
+				return nil, err
+			}
+		}
+		exportData.Collections = append(exportData.Collections, collection)
+	}
+
+	return exportData, nil
+}
+
+// collectionSchema returns the schema of a collection, with the fields
+// inferred from its documents
+func (s *Service) collectionSchema(ctx context.Context, porter collectionPorter, name string, fields *fieldInferrer) (*common.ExportCollectionSchema, error) {
+	schema := &common.ExportCollectionSchema{Fields: fields.result()}
+
+	specs, err := porter.ListIndexSpecs(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes of %s: %w", name, err)
+	}
+	for _, spec := range specs {
+		index := common.ExportCollectionIndex{}
+		options := bson.D{}
+		elements, err := spec.Elements()
+		if err != nil {
+			return nil, err
+		}
+		for _, element := range elements {
+			switch element.Key() {
+			case "name":
+				index.Name = element.Value().StringValue()
+			case "key":
+				if index.Keys, err = bson.MarshalExtJSON(element.Value().Document(), true, false); err != nil {
+					return nil, err
+				}
+			case "unique":
+				index.Unique = element.Value().Boolean()
+			case "v", "ns":
+				// Set by the server
+			default:
+				options = append(options, bson.E{Key: element.Key(), Value: element.Value()})
+			}
+		}
+		if index.Name == "_id_" {
+			continue
+		}
+		if len(options) > 0 {
+			out, err := bson.MarshalExtJSON(options, true, false)
+			if err != nil {
+				return nil, err
+			}
+			index.Options = out
+		}
+		schema.Indexes = append(schema.Indexes, index)
+	}
+
+	opts, err := porter.GetCollectionOptions(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read options of %s: %w", name, err)
+	}
+	if len(opts) > 5 {
+		// More than an empty document
+		out, err := bson.MarshalExtJSON(opts, true, false)
+		if err != nil {
+			return nil, err
+		}
+		schema.Options = out
+	}
+	return schema, nil
+}
+
+// ImportDatabase imports the collections of an export into the current
+// database. Collections that don't exist are created with their options
+// and indexes; documents are inserted, or replace the document with their
+// _id. With verify, the documents of each collection are checked against
+// the count and checksum written with the export before anything is
+// imported.
+func (s *Service) ImportDatabase(ctx context.Context, data *common.ExportData, verify bool) (*common.ImportResult, error) {
+	porter, err := s.collectionPorter()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &common.ImportResult{
+		TablesCreated: []string{},
+		TablesUpdated: []string{},
+		Verified:      verify,
+	}
+	if len(data.Tables) > 0 {
+		result.Errors = append(result.Errors, fmt.Sprintf("%d SQL table(s) skipped: import them into a SQL database", len(data.Tables)))
+	}
+
+	// Documents are parsed once, for the check and the import
+	parsed := make([][]bson.Raw, len(data.Collections))
+	var failed []common.TableCheck
+	for i, collection := range data.Collections {
+		docs, err := parseDocuments(collection.Documents)
+		if err != nil {
+			return nil, &ExportVerifyError{Message: "the file has documents that aren't Extended JSON", Collections: []common.TableCheck{
+				{Table: collection.Name, Problem: err.Error()},
+			}}
+		}
+		parsed[i] = docs
+		if verify {
+			if check := verifyCollection(collection, docs); check != nil {
+				failed = append(failed, *check)
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return nil, &ExportVerifyError{Message: "the file doesn't match its checksums, nothing was imported", Collections: failed}
+	}
+
+	existing, err := porter.ListCollections(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+	exists := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		exists[name] = true
+	}
+
+	for i, collection := range data.Collections {
+		if !exists[collection.Name] {
+			var opts bson.Raw
+			if collection.Schema != nil && len(collection.Schema.Options) > 0 {
+				if opts, err = extJSONDocument(collection.Schema.Options); err != nil {
+					result.Errors = append(result.Errors, fmt.Sprintf("%s: invalid options: %v", collection.Name, err))
+					continue
+				}
+			}
+			if err := porter.CreateCollectionWithOptions(ctx, collection.Name, opts); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: failed to create collection: %v", collection.Name, err))
+				continue
+			}
+			exists[collection.Name] = true
+			result.CollectionsCreated = append(result.CollectionsCreated, collection.Name)
+		}
+
+		if collection.Schema != nil {
+			created, err := s.importIndexes(ctx, porter, collection)
+			result.IndexesCreated += created
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: failed to create indexes: %v", collection.Name, err))
+			}
+		}
+
+		docs := parsed[i]
+		for len(docs) > 0 {
+			n := min(len(docs), importBatch)
+			inserted, replaced, err := porter.UpsertDocuments(ctx, collection.Name, docs[:n])
+			result.RowsInserted += int(inserted)
+			result.RowsUpdated += int(replaced)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %s", collection.Name, writeErrorSummary(err)))
+			}
+			docs = docs[n:]
+		}
+	}
+
+	return result, nil
+}
+
+// importIndexes creates the indexes of a collection that aren't there yet,
+// returning how many it created
+func (s *Service) importIndexes(ctx context.Context, porter collectionPorter, collection common.ExportCollection) (int, error) {
+	current, err := porter.ListIndexSpecs(ctx, collection.Name)
+	if err != nil {
+		return 0, err
+	}
+	names := map[string]bool{}
+	for _, spec := range current {
+		if name, ok := spec.Lookup("name").StringValueOK(); ok {
+			names[name] = true
+		}
+	}
+
+	var specs []bson.D
+	for _, index := range collection.Schema.Indexes {
+		if names[index.Name] {
+			continue
+		}
+		var keys bson.D
+		if err := bson.UnmarshalExtJSON(index.Keys, true, &keys); err != nil {
+			return 0, fmt.Errorf("index %s: invalid keys: %w", index.Name, err)
+		}
+		spec := bson.D{{Key: "key", Value: keys}, {Key: "name", Value: index.Name}}
+		if index.Unique {
+			spec = append(spec, bson.E{Key: "unique", Value: true})
+		}
+		if len(index.Options) > 0 {
+			var options bson.D
+			if err := bson.UnmarshalExtJSON(index.Options, true, &options); err != nil {
+				return 0, fmt.Errorf("index %s: invalid options: %w", index.Name, err)
+			}
+			spec = append(spec, options...)
+		}
+		specs = append(specs, spec)
+	}
+	if err := porter.CreateIndexSpecs(ctx, collection.Name, specs); err != nil {
+		return 0, err
+	}
+	return len(specs), nil
+}
+
+// parseDocuments reads the Extended JSON documents of an export
+func parseDocuments(documents []json.RawMessage) ([]bson.Raw, error) {
+	docs := make([]bson.Raw, len(documents))
+	for i, document := range documents {
+		doc, err := extJSONDocument(document)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", i+1, err)
+		}
+		docs[i] = doc
+	}
+	return docs, nil
+}
+
+// extJSONDocument reads an Extended JSON document, keeping its field order
+func extJSONDocument(data []byte) (bson.Raw, error) {
+	var doc bson.D
+	if err := bson.UnmarshalExtJSON(data, true, &doc); err != nil {
+		return nil, err
+	}
+	return bson.Marshal(doc)
+}
+
+// verifyCollection checks the documents of a collection against the count
+// and checksum the export wrote for it
+func verifyCollection(collection common.ExportCollection, docs []bson.Raw) *common.TableCheck {
+	check := &common.TableCheck{Table: collection.Name, Expected: collection.DocumentCount, Actual: len(docs)}
+	switch {
+	case collection.Checksum == "" && len(docs) > 0:
+		check.Problem = "has documents but no checksum"
+	case collection.Checksum == "":
+		return nil
+	case len(docs) != collection.DocumentCount:
+		check.Problem = fmt.Sprintf("has %d documents, the export wrote %d", len(docs), collection.DocumentCount)
+	default:
+		// The checksum is of the documents as the export wrote them, which
+		// reformatting the file doesn't change
+		documents := make([]json.RawMessage, len(docs))
+		for i, doc := range docs {
+			out, err := bson.MarshalExtJSON(doc, true, false)
+			if err != nil {
+				check.Problem = err.Error()
+				return check
+			}
+			documents[i] = out
+		}
+		if documentsChecksum(documents) != collection.Checksum {
+			check.Problem = "documents don't match the checksum"
+		} else {
+			return nil
+		}
+	}
+	return check
+}
+
+// documentsChecksum is the SHA-256 of documents written as compact
+// canonical Extended JSON, one per line
+func documentsChecksum(documents []json.RawMessage) string {
+	hash := sha256.New()
+	for _, document := range documents {
+		hash.Write(document)
+		hash.Write([]byte("\n"))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// writeErrorSummary describes the failed writes of a batch, by their first
+// error and how many failed
+func writeErrorSummary(err error) string {
+	var bulk mongo.BulkWriteException
+	if errors.As(err, &bulk) && len(bulk.WriteErrors) > 0 {
+		return fmt.Sprintf("%d document(s) failed, first: %s", len(bulk.WriteErrors), bulk.WriteErrors[0].Message)
+	}
+	return err.Error()
+}
+
+// fieldInferrer collects the fields of documents and the types they hold
+type fieldInferrer struct {
+	count  int
+	fields map[string]*fieldStats
+	order  []string
+}
+
+type fieldStats struct {
+	types []string
+	seen  int
+}
+
+func (f *fieldInferrer) add(doc bson.Raw) {
+	f.count++
+	f.walk("", doc)
+}
+
+func (f *fieldInferrer) walk(prefix string, doc bson.Raw) {
+	elements, err := doc.Elements()
+	if err != nil {
+		return
+	}
+	for _, element := range elements {
+		path := prefix + element.Key()
+		stats, ok := f.fields[path]
+		if !ok {
+			stats = &fieldStats{}
+			f.fields[path] = stats
+			f.order = append(f.order, path)
+		}
+		stats.seen++
+
+		value := element.Value()
+		name := rawTypeName(value.Type)
+		found := false
+		for _, t := range stats.types {
+			found = found || t == name
+		}
+		if !found {
+			stats.types = append(stats.types, name)
+		}
+		if value.Type == bsontype.EmbeddedDocument {
+			f.walk(path+".", value.Document())
+		}
+	}
+}
+
+// result returns the fields in the order they were first seen. A field is
+// optional when some documents holding its parent don't have it.
+func (f *fieldInferrer) result() []common.ExportField {
+	fields := make([]common.ExportField, 0, len(f.order))
+	for _, path := range f.order {
+		stats := f.fields[path]
+		parents := f.count
+		if i := strings.LastIndex(path, "."); i >= 0 {
+			if parent, ok := f.fields[path[:i]]; ok {
+				parents = parent.seen
+			}
+		}
+		fields = append(fields, common.ExportField{
+			Name:     path,
+			Types:    stats.types,
+			Optional: stats.seen < parents,
+		})
+	}
+	return fields
+}
+
+// rawTypeName returns the $jsonSchema bsonType name of a BSON type
+func rawTypeName(t bsontype.Type) string {
+	switch t {
+	case bsontype.Double:
+		return "double"
+	case bsontype.String:
+		return "string"
+	case bsontype.EmbeddedDocument:
+		return "object"
+	case bsontype.Array:
+		return "array"
+	case bsontype.Binary:
+		return "binData"
+	case bsontype.ObjectID:
+		return "objectId"
+	case bsontype.Boolean:
+		return "bool"
+	case bsontype.DateTime:
+		return "date"
+	case bsontype.Null:
+		return "null"
+	case bsontype.Regex:
+		return "regex"
+	case bsontype.JavaScript:
+		return "javascript"
+	case bsontype.Int32:
+		return "int"
+	case bsontype.Timestamp:
+		return "timestamp"
+	case bsontype.Int64:
+		return "long"
+	case bsontype.Decimal128:
+		return "decimal"
+	}
+	return t.String()
+}
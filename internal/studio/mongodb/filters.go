@@ -0,0 +1,230 @@
+package mongodb
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InvalidFilterError rejects a filter that can't be turned into a query
+type InvalidFilterError struct {
+	Field   string
+	Message string
+}
+
+func (e *InvalidFilterError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// BuildFilter turns the filters of the table grid into a MongoDB filter
+// document. Filters are ANDed together; an "or" filter starts a new group,
+// as for SQL tables. Fields may be dotted paths into embedded documents.
+//
+// Collections have no column types, so values are matched as what they
+// look like as well as text: "42" finds the number 42 and the string "42",
+// a 24 digit hex string an ObjectId too. Text comparisons ignore case
+// unless the filter is case sensitive. Times without an offset are UTC.
+func BuildFilter(filters []common.Filter) (bson.M, error) {
+	var groups []bson.M
+	var current []bson.M
+	closeGroup := func() {
+		if len(current) == 1 {
+			groups = append(groups, current[0])
+		} else if len(current) > 1 {
+			groups = append(groups, bson.M{"$and": current})
+		}
+		current = nil
+	}
+
+	for i, filter := range filters {
+		if filter.Column == "" {
+			continue
+		}
+		condition, err := filterCondition(filter)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 && filter.Logic == "or" {
+			closeGroup()
+		}
+		current = append(current, bson.M{filter.Column: condition})
+	}
+	closeGroup()
+
+	switch len(groups) {
+	case 0:
+		return bson.M{}, nil
+	case 1:
+		return groups[0], nil
+	}
+	return bson.M{"$or": groups}, nil
+}
+
+// filterCondition returns the condition a filter puts on its field
+func filterCondition(filter common.Filter) (interface{}, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return &InvalidFilterError{Field: filter.Column, Message: fmt.Sprintf(format, args...)}
+	}
+	if strings.HasPrefix(filter.Column, "$") {
+		return nil, invalid("field names can't start with $")
+	}
+	if filter.IgnoreAccents {
+		return nil, invalid("ignoring accents needs PostgreSQL's unaccent extension")
+	}
+	value := strings.TrimSpace(filter.Value)
+
+	switch filter.Operator {
+	case "equals":
+		return bson.M{"$in": filterCandidates(value, filter.CaseSensitive)}, nil
+	case "not_equals":
+		// As in SQL, documents without the field match neither
+		return bson.M{"$ne": nil, "$nin": filterCandidates(value, filter.CaseSensitive)}, nil
+	case "contains", "not_contains", "starts_with", "ends_with":
+		pattern := regexp.QuoteMeta(filter.Value)
+		switch filter.Operator {
+		case "starts_with":
+			pattern = "^" + pattern
+		case "ends_with":
+			pattern += "$"
+		}
+		regex := textRegex(pattern, filter.CaseSensitive)
+		if filter.Operator == "not_contains" {
+			return bson.M{"$ne": nil, "$not": regex}, nil
+		}
+		return regex, nil
+	case "gt", "lt", "gte", "lte":
+		if value == "" {
+			return nil, invalid("%s needs a value", filter.Operator)
+		}
+		return bson.M{"$" + filter.Operator: comparableValue(value)}, nil
+	case "in", "not_in":
+		values := common.FilterValues(filter)
+		if len(values) == 0 {
+			return nil, invalid("%s needs at least one value", filter.Operator)
+		}
+		var candidates []interface{}
+		for _, v := range values {
+			candidates = append(candidates, filterCandidates(v, filter.CaseSensitive)...)
+		}
+		if filter.Operator == "not_in" {
+			return bson.M{"$ne": nil, "$nin": candidates}, nil
+		}
+		return bson.M{"$in": candidates}, nil
+	case "between":
+		values := common.FilterValues(filter)
+		if len(values) != 2 {
+			return nil, invalid("between needs two values, got %d", len(values))
+		}
+		from, to := comparableValue(values[0]), comparableValue(values[1])
+		if _, ok := from.(string); ok {
+			return nil, invalid("between compares numbers, dates and times")
+		}
+		if _, ok := to.(string); ok {
+			return nil, invalid("between compares numbers, dates and times")
+		}
+		return bson.M{"$gte": from, "$lte": to}, nil
+	case "in_last", "not_in_last":
+		now := time.Now().UTC()
+		since, err := common.RelativeTime(filter.Value, now)
+		if err != nil {
+			return nil, invalid("%v", err)
+		}
+		if filter.Operator == "not_in_last" {
+			return bson.M{"$lt": since}, nil
+		}
+		return bson.M{"$gte": since, "$lte": now}, nil
+	case "matches", "not_matches":
+		regex := textRegex(filter.Value, filter.CaseSensitive)
+		if filter.Operator == "not_matches" {
+			return bson.M{"$ne": nil, "$not": regex}, nil
+		}
+		return regex, nil
+	case "is_null":
+		// Matches missing fields too, which are null to a table
+		return nil, nil
+	case "is_not_null":
+		return bson.M{"$ne": nil}, nil
+	case "is_empty":
+		return bson.M{"$in": bson.A{nil, "", bson.A{}}}, nil
+	case "is_not_empty":
+		return bson.M{"$nin": bson.A{nil, "", bson.A{}}}, nil
+	}
+	return nil, invalid("unknown filter operator %q", filter.Operator)
+}
+
+// textRegex returns a regular expression, which ignores case unless the
+// filter is case sensitive
+func textRegex(pattern string, caseSensitive bool) primitive.Regex {
+	if caseSensitive {
+		return primitive.Regex{Pattern: pattern}
+	}
+	return primitive.Regex{Pattern: pattern, Options: "i"}
+}
+
+// filterCandidates returns the values a filter value stands for: itself as
+// text, and the number, boolean, ObjectId or time it spells
+func filterCandidates(value string, caseSensitive bool) []interface{} {
+	var candidates []interface{}
+	if caseSensitive {
+		candidates = append(candidates, value)
+	} else {
+		candidates = append(candidates, textRegex("^"+regexp.QuoteMeta(value)+"$", false))
+	}
+
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		candidates = append(candidates, n)
+	} else if f, err := strconv.ParseFloat(value, 64); err == nil {
+		candidates = append(candidates, f)
+	}
+	if b, err := strconv.ParseBool(value); err == nil && len(value) > 1 {
+		candidates = append(candidates, b)
+	}
+	if id, err := primitive.ObjectIDFromHex(value); err == nil {
+		candidates = append(candidates, id)
+	}
+	if t, ok := parseFilterTime(value); ok {
+		candidates = append(candidates, t)
+	}
+	return candidates
+}
+
+// comparableValue returns the value a range compares with: a number or a
+// time when the value spells one, else the text
+func comparableValue(value string) interface{} {
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	if t, ok := parseFilterTime(value); ok {
+		return t
+	}
+	return value
+}
+
+// filterTimeLayouts are the times a filter value is read as, the way the
+// table grid shows them
+var filterTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func parseFilterTime(value string) (time.Time, bool) {
+	for _, layout := range filterTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
@@ -2,16 +2,19 @@ package mongodb
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/Lumos-Labs-HQ/flash/internal/hooks"
 	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
 // Database Handlers
 func (s *Server) handleGetDatabases(w http.ResponseWriter, r *http.Request) {
-	databases, err := s.service.GetDatabases()
+	databases, err := s.service.GetDatabases(r.Context())
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -40,7 +43,7 @@ func (s *Server) handleDropDatabase(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.service.DropDatabase(dbName); err != nil {
+	if err := s.service.DropDatabase(r.Context(), dbName); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -60,7 +63,7 @@ func (s *Server) handleCreateDatabase(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.service.CreateDatabase(req.Name); err != nil {
+	if err := s.service.CreateDatabase(r.Context(), req.Name); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -75,7 +78,7 @@ func (s *Server) handleGetCollections(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	collections, err := s.service.GetCollections(dbName)
+	collections, err := s.service.GetCollections(r.Context(), dbName)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -99,7 +102,7 @@ func (s *Server) handleGetCollectionData(w http.ResponseWriter, r *http.Request)
 	page, _ := strconv.Atoi(common.Query(r, "page", "1"))
 	limit, _ := strconv.Atoi(common.Query(r, "limit", "50"))
 
-	result, err := s.service.GetDocuments(dbName, name, page, limit)
+	result, err := s.service.GetDocuments(r.Context(), dbName, name, page, limit)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -125,7 +128,7 @@ func (s *Server) handleCreateCollection(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := s.service.CreateCollection(req.Name, req.Options); err != nil {
+	if err := s.service.CreateCollection(r.Context(), req.Name, req.Options); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -143,7 +146,7 @@ func (s *Server) handleDropCollection(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := s.service.DropCollection(name); err != nil {
+	if err := s.service.DropCollection(r.Context(), name); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -171,7 +174,27 @@ func (s *Server) handleGetDocuments(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	result, err := s.service.GetDocumentsWithFilter(dbName, name, page, limit, filter)
+	// filters are the grid's filters, as for SQL tables, which apply on top
+	// of the query
+	if filtersStr := common.Query(r, "filters", ""); filtersStr != "" {
+		var filters []common.Filter
+		if err := json.Unmarshal([]byte(filtersStr), &filters); err != nil {
+			common.JSONError(w, http.StatusBadRequest, "Invalid filters format")
+			return
+		}
+		built, err := BuildFilter(filters)
+		if err != nil {
+			common.JSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if len(filter) == 0 {
+			filter = built
+		} else if len(built) > 0 {
+			filter = bson.M{"$and": bson.A{filter, built}}
+		}
+	}
+
+	result, err := s.service.GetDocumentsWithFilter(r.Context(), dbName, name, page, limit, filter)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -196,7 +219,7 @@ func (s *Server) handleInsertDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := s.service.InsertDocument(name, document)
+	id, err := s.service.InsertDocument(r.Context(), name, document)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -223,7 +246,7 @@ func (s *Server) handleUpdateDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.service.UpdateDocument(name, id, document); err != nil {
+	if err := s.service.UpdateDocument(r.Context(), name, id, document); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -242,7 +265,7 @@ func (s *Server) handleDeleteDocument(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := s.service.DeleteDocument(name, id); err != nil {
+	if err := s.service.DeleteDocument(r.Context(), name, id); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -268,13 +291,87 @@ func (s *Server) handleBulkDeleteDocuments(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if err := s.service.BulkDeleteDocuments(name, req.IDs); err != nil {
+	if err := s.service.BulkDeleteDocuments(r.Context(), name, req.IDs); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 	common.JSONMessage(w, "Documents deleted successfully")
 }
 
+func (s *Server) handleGetDocument(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	id := r.PathValue("id")
+	dbName := r.URL.Query().Get("database")
+
+	if dbName != "" {
+		if err := s.service.SwitchDatabase(dbName); err != nil {
+			common.JSONError(w, http.StatusInternalServerError, "Failed to switch database: "+err.Error())
+			return
+		}
+	}
+
+	doc, err := s.service.GetDocument(r.Context(), name, id)
+	if err != nil {
+		writeDocumentError(w, err)
+		return
+	}
+	common.JSON(w, doc)
+}
+
+func (s *Server) handlePatchDocument(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	id := r.PathValue("id")
+	dbName := r.URL.Query().Get("database")
+
+	if dbName != "" {
+		if err := s.service.SwitchDatabase(dbName); err != nil {
+			common.JSONError(w, http.StatusInternalServerError, "Failed to switch database: "+err.Error())
+			return
+		}
+	}
+
+	var req struct {
+		// Document is the edited document and Original the one it was
+		// loaded as, both Extended JSON
+		Document string `json:"document"`
+		Original string `json:"original"`
+	}
+	if err := common.ParseJSON(r, &req); err != nil {
+		common.JSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	patch, err := s.service.PatchDocument(r.Context(), name, id, req.Original, req.Document, dryRun)
+	if err != nil {
+		writeDocumentError(w, err)
+		return
+	}
+	common.JSON(w, patch)
+}
+
+// writeDocumentError answers a failed document edit: 400 for documents that
+// don't parse, 404 for a missing one, 409 with the current document when it
+// changed since it was loaded and 422 with the patch when it breaks the
+// collection's schema
+func writeDocumentError(w http.ResponseWriter, err error) {
+	var invalid *InvalidDocumentError
+	var changed *DocumentChangedError
+	var violation *SchemaViolationError
+	switch {
+	case errors.As(err, &invalid):
+		common.JSONError(w, http.StatusBadRequest, invalid.Error())
+	case isNotFound(err):
+		common.JSONError(w, http.StatusNotFound, "document not found")
+	case errors.As(err, &changed):
+		common.JSONErrorData(w, http.StatusConflict, changed.Error(), common.Map{"current": changed.Current})
+	case errors.As(err, &violation):
+		common.JSONErrorData(w, http.StatusUnprocessableEntity, violation.Error(), violation.Patch)
+	default:
+		common.JSONError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
 // Aggregation Handler
 func (s *Server) handleAggregate(w http.ResponseWriter, r *http.Request) {
 	name := r.PathValue("name")
@@ -300,7 +397,7 @@ func (s *Server) handleAggregate(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	result, err := s.service.Aggregate(name, pipeline)
+	result, err := s.service.Aggregate(r.Context(), name, pipeline)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -320,7 +417,7 @@ func (s *Server) handleGetIndexes(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	indexes, err := s.service.GetIndexes(name)
+	indexes, err := s.service.GetIndexes(r.Context(), name)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -348,7 +445,7 @@ func (s *Server) handleCreateIndex(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.service.CreateIndex(name, req.Keys, req.Unique); err != nil {
+	if err := s.service.CreateIndex(r.Context(), name, req.Keys, req.Unique); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -367,7 +464,7 @@ func (s *Server) handleDropIndex(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := s.service.DropIndex(name, indexName); err != nil {
+	if err := s.service.DropIndex(r.Context(), name, indexName); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -397,7 +494,7 @@ func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
 		req.Limit = 100
 	}
 
-	result, err := s.service.Query(name, filter, req.Limit)
+	result, err := s.service.Query(r.Context(), name, filter, req.Limit)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -407,7 +504,7 @@ func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
 
 // Stats Handlers
 func (s *Server) handleGetStats(w http.ResponseWriter, r *http.Request) {
-	stats, err := s.service.GetStats()
+	stats, err := s.service.GetStats(r.Context())
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -418,10 +515,108 @@ func (s *Server) handleGetStats(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleGetCollectionStats(w http.ResponseWriter, r *http.Request) {
 	name := r.PathValue("name")
 
-	stats, err := s.service.GetCollectionStats(name)
+	stats, err := s.service.GetCollectionStats(r.Context(), name)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 	common.JSON(w, stats)
 }
+
+// Schema Handler
+func (s *Server) handleGetSchema(w http.ResponseWriter, r *http.Request) {
+	if dbName := r.URL.Query().Get("database"); dbName != "" {
+		if err := s.service.SwitchDatabase(dbName); err != nil {
+			common.JSONError(w, http.StatusInternalServerError, "Failed to switch database: "+err.Error())
+			return
+		}
+	}
+
+	schema, err := s.service.GetSchemaVisualization(r.Context())
+	if err != nil {
+		common.JSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	common.JSON(w, schema)
+}
+
+// Export/Import Handlers
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	var exportType common.ExportType
+	switch r.PathValue("type") {
+	case "schema_only":
+		exportType = common.ExportSchemaOnly
+	case "data_only":
+		exportType = common.ExportDataOnly
+	case "complete":
+		exportType = common.ExportComplete
+	default:
+		common.JSONError(w, http.StatusBadRequest, "Invalid export type. Use: schema_only, data_only, or complete")
+		return
+	}
+
+	if dbName := r.URL.Query().Get("database"); dbName != "" {
+		if err := s.service.SwitchDatabase(dbName); err != nil {
+			common.JSONError(w, http.StatusInternalServerError, "Failed to switch database: "+err.Error())
+			return
+		}
+	}
+
+	data, err := s.service.ExportDatabase(r.Context(), exportType)
+	if err != nil {
+		common.JSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	common.JSON(w, data)
+}
+
+func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
+	var importData common.ExportData
+	if err := common.ParseJSON(r, &importData); err != nil {
+		common.JSONError(w, http.StatusBadRequest, "Invalid import data format")
+		return
+	}
+	if importData.Version == "" || len(importData.Collections) == 0 {
+		common.JSONError(w, http.StatusBadRequest, "Invalid import data: missing version or collections")
+		return
+	}
+
+	if dbName := r.URL.Query().Get("database"); dbName != "" {
+		if err := s.service.SwitchDatabase(dbName); err != nil {
+			common.JSONError(w, http.StatusInternalServerError, "Failed to switch database: "+err.Error())
+			return
+		}
+	}
+
+	event := hooks.Event{Command: "studio import"}
+	for _, collection := range importData.Collections {
+		event.Tables = append(event.Tables, collection.Name)
+	}
+	if s.cfg != nil {
+		if err := hooks.Pre(s.cfg, "import", event); err != nil {
+			common.JSONError(w, http.StatusPreconditionFailed, err.Error())
+			return
+		}
+	}
+
+	start := time.Now()
+	result, err := s.service.ImportDatabase(r.Context(), &importData, r.URL.Query().Get("verify") == "true")
+	if s.cfg != nil {
+		hooks.Post(s.cfg, "import", event, start, err)
+	}
+	var unverified *ExportVerifyError
+	if errors.As(err, &unverified) {
+		common.JSONErrorData(w, http.StatusUnprocessableEntity, unverified.Error(), common.Map{"collections": unverified.Collections})
+		return
+	}
+	if err != nil {
+		common.JSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	common.JSONMap(w, common.Map{
+		"success": true,
+		"message": "Import completed",
+		"result":  result,
+	})
+}
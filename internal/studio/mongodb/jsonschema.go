@@ -0,0 +1,411 @@
+package mongodb
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SchemaError is where a document breaks a collection's $jsonSchema
+type SchemaError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// validateSchema checks a value against a $jsonSchema the way MongoDB's
+// validator does, for the keywords it supports: bsonType, type, enum,
+// required, properties, additionalProperties, patternProperties,
+// min/maxProperties, minimum, maximum, multipleOf, min/maxLength, pattern,
+// items, additionalItems, min/maxItems, uniqueItems, allOf, anyOf, oneOf
+// and not. The server still validates on save.
+func validateSchema(schema bson.M, value interface{}, path string) []SchemaError {
+	var errs []SchemaError
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, SchemaError{Path: displayPath(path), Message: fmt.Sprintf(format, args...)})
+	}
+
+	if types := schemaStrings(schema["bsonType"]); len(types) > 0 && !anyType(types, value, matchesBSONType) {
+		fail("must be of BSON type %s, not %s", strings.Join(types, " or "), bsonTypeName(value))
+		// The other keywords assume the right type
+		return errs
+	}
+	if types := schemaStrings(schema["type"]); len(types) > 0 && !anyType(types, value, matchesJSONType) {
+		fail("must be of type %s, not %s", strings.Join(types, " or "), bsonTypeName(value))
+		return errs
+	}
+	if enum, ok := schema["enum"].(bson.A); ok {
+		found := false
+		for _, allowed := range enum {
+			if equalValues(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("must be one of %s", describeValues(enum))
+		}
+	}
+
+	switch v := value.(type) {
+	case bson.D:
+		errs = append(errs, validateObject(schema, v, path)...)
+	case bson.A:
+		errs = append(errs, validateArray(schema, v, path)...)
+	case string:
+		length := utf8.RuneCountInString(v)
+		if min, ok := numberValue(schema["minLength"]); ok && float64(length) < min {
+			fail("must be at least %v characters long", min)
+		}
+		if max, ok := numberValue(schema["maxLength"]); ok && float64(length) > max {
+			fail("must be at most %v characters long", max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+				fail("must match %s", pattern)
+			}
+		}
+	default:
+		if n, ok := numberValue(value); ok {
+			errs = append(errs, validateNumber(schema, n, path)...)
+		}
+	}
+
+	if all, ok := schema["allOf"].(bson.A); ok {
+		for _, sub := range all {
+			if m, ok := sub.(bson.M); ok {
+				errs = append(errs, validateSchema(m, value, path)...)
+			}
+		}
+	}
+	if anyOf, ok := schema["anyOf"].(bson.A); ok && countMatching(anyOf, value, path) == 0 {
+		fail("must match at least one of the anyOf schemas")
+	}
+	if oneOf, ok := schema["oneOf"].(bson.A); ok {
+		if n := countMatching(oneOf, value, path); n != 1 {
+			fail("must match exactly one of the oneOf schemas, matches %d", n)
+		}
+	}
+	if not, ok := schema["not"].(bson.M); ok && len(validateSchema(not, value, path)) == 0 {
+		fail("must not match the not schema")
+	}
+	return errs
+}
+
+func validateObject(schema bson.M, doc bson.D, path string) []SchemaError {
+	var errs []SchemaError
+	fields := make(map[string]interface{}, len(doc))
+	for _, field := range doc {
+		fields[field.Key] = field.Value
+	}
+
+	for _, name := range schemaStrings(schema["required"]) {
+		if _, ok := fields[name]; !ok {
+			errs = append(errs, SchemaError{Path: displayPath(joinPath(path, name)), Message: "is required"})
+		}
+	}
+	if min, ok := numberValue(schema["minProperties"]); ok && float64(len(doc)) < min {
+		errs = append(errs, SchemaError{Path: displayPath(path), Message: fmt.Sprintf("must have at least %v fields", min)})
+	}
+	if max, ok := numberValue(schema["maxProperties"]); ok && float64(len(doc)) > max {
+		errs = append(errs, SchemaError{Path: displayPath(path), Message: fmt.Sprintf("must have at most %v fields", max)})
+	}
+
+	properties, _ := schema["properties"].(bson.M)
+	patterns := map[*regexp.Regexp]bson.M{}
+	if patternProperties, ok := schema["patternProperties"].(bson.M); ok {
+		for pattern, sub := range patternProperties {
+			re, err := regexp.Compile(pattern)
+			m, isSchema := sub.(bson.M)
+			if err == nil && isSchema {
+				patterns[re] = m
+			}
+		}
+	}
+
+	for _, field := range doc {
+		fieldPath := joinPath(path, field.Key)
+		matched := false
+		if sub, ok := properties[field.Key].(bson.M); ok {
+			matched = true
+			errs = append(errs, validateSchema(sub, field.Value, fieldPath)...)
+		}
+		for re, sub := range patterns {
+			if re.MatchString(field.Key) {
+				matched = true
+				errs = append(errs, validateSchema(sub, field.Value, fieldPath)...)
+			}
+		}
+		if matched {
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				errs = append(errs, SchemaError{Path: displayPath(fieldPath), Message: "isn't allowed by the schema"})
+			}
+		case bson.M:
+			errs = append(errs, validateSchema(additional, field.Value, fieldPath)...)
+		}
+	}
+	return errs
+}
+
+func validateArray(schema bson.M, items bson.A, path string) []SchemaError {
+	var errs []SchemaError
+	if min, ok := numberValue(schema["minItems"]); ok && float64(len(items)) < min {
+		errs = append(errs, SchemaError{Path: displayPath(path), Message: fmt.Sprintf("must have at least %v items", min)})
+	}
+	if max, ok := numberValue(schema["maxItems"]); ok && float64(len(items)) > max {
+		errs = append(errs, SchemaError{Path: displayPath(path), Message: fmt.Sprintf("must have at most %v items", max)})
+	}
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+	outer:
+		for i := range items {
+			for j := 0; j < i; j++ {
+				if equalValues(items[i], items[j]) {
+					errs = append(errs, SchemaError{Path: displayPath(joinPath(path, strconv.Itoa(i))), Message: fmt.Sprintf("duplicates item %d", j)})
+					break outer
+				}
+			}
+		}
+	}
+
+	switch itemSchema := schema["items"].(type) {
+	case bson.M:
+		for i, item := range items {
+			errs = append(errs, validateSchema(itemSchema, item, joinPath(path, strconv.Itoa(i)))...)
+		}
+	case bson.A:
+		// A schema per position, then additionalItems for the rest
+		for i, item := range items {
+			itemPath := joinPath(path, strconv.Itoa(i))
+			if i < len(itemSchema) {
+				if m, ok := itemSchema[i].(bson.M); ok {
+					errs = append(errs, validateSchema(m, item, itemPath)...)
+				}
+				continue
+			}
+			switch additional := schema["additionalItems"].(type) {
+			case bool:
+				if !additional {
+					errs = append(errs, SchemaError{Path: displayPath(itemPath), Message: fmt.Sprintf("isn't allowed, the schema has %d items", len(itemSchema))})
+				}
+			case bson.M:
+				errs = append(errs, validateSchema(additional, item, itemPath)...)
+			}
+		}
+	}
+	return errs
+}
+
+func validateNumber(schema bson.M, n float64, path string) []SchemaError {
+	var errs []SchemaError
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, SchemaError{Path: displayPath(path), Message: fmt.Sprintf(format, args...)})
+	}
+	if min, ok := numberValue(schema["minimum"]); ok {
+		if exclusive, _ := schema["exclusiveMinimum"].(bool); exclusive && n <= min {
+			fail("must be greater than %v", min)
+		} else if n < min {
+			fail("must be at least %v", min)
+		}
+	}
+	if max, ok := numberValue(schema["maximum"]); ok {
+		if exclusive, _ := schema["exclusiveMaximum"].(bool); exclusive && n >= max {
+			fail("must be less than %v", max)
+		} else if n > max {
+			fail("must be at most %v", max)
+		}
+	}
+	if divisor, ok := numberValue(schema["multipleOf"]); ok && divisor > 0 {
+		if q := n / divisor; math.Abs(q-math.Round(q)) > 1e-9 {
+			fail("must be a multiple of %v", divisor)
+		}
+	}
+	return errs
+}
+
+func countMatching(schemas bson.A, value interface{}, path string) int {
+	n := 0
+	for _, sub := range schemas {
+		if m, ok := sub.(bson.M); ok && len(validateSchema(m, value, path)) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// bsonTypeName names a value's type the way bsonType does
+func bsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case float64:
+		return "double"
+	case string:
+		return "string"
+	case bson.D, bson.M:
+		return "object"
+	case bson.A:
+		return "array"
+	case primitive.Binary:
+		return "binData"
+	case primitive.Undefined:
+		return "undefined"
+	case primitive.ObjectID:
+		return "objectId"
+	case bool:
+		return "bool"
+	case primitive.DateTime:
+		return "date"
+	case nil, primitive.Null:
+		return "null"
+	case primitive.Regex:
+		return "regex"
+	case primitive.DBPointer:
+		return "dbPointer"
+	case primitive.JavaScript:
+		return "javascript"
+	case primitive.Symbol:
+		return "symbol"
+	case primitive.CodeWithScope:
+		return "javascriptWithScope"
+	case int32:
+		return "int"
+	case primitive.Timestamp:
+		return "timestamp"
+	case int64:
+		return "long"
+	case primitive.Decimal128:
+		return "decimal"
+	case primitive.MinKey:
+		return "minKey"
+	case primitive.MaxKey:
+		return "maxKey"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func matchesBSONType(name string, value interface{}) bool {
+	if name == "number" {
+		_, ok := numberValue(value)
+		return ok
+	}
+	return bsonTypeName(value) == name
+}
+
+// matchesJSONType matches the JSON Schema type keyword, which MongoDB
+// supports except for integer
+func matchesJSONType(name string, value interface{}) bool {
+	switch name {
+	case "boolean":
+		return bsonTypeName(value) == "bool"
+	case "number":
+		_, ok := numberValue(value)
+		return ok
+	default:
+		return bsonTypeName(value) == name
+	}
+}
+
+func anyType(types []string, value interface{}, match func(string, interface{}) bool) bool {
+	for _, t := range types {
+		if match(t, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func numberValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case primitive.Decimal128:
+		f, err := strconv.ParseFloat(v.String(), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// schemaStrings reads a keyword that's a string or an array of strings
+func schemaStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case bson.A:
+		var result []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// equalValues compares numbers by value, as MongoDB does, and other values
+// by type and content
+func equalValues(a, b interface{}) bool {
+	x, xIsNumber := numberValue(a)
+	y, yIsNumber := numberValue(b)
+	if xIsNumber && yIsNumber {
+		return x == y
+	}
+	return sameValue(sortedDocument(a), sortedDocument(b))
+}
+
+// sortedDocument orders the fields of a document, since a schema's lost
+// their order when it was read into a map
+func sortedDocument(value interface{}) interface{} {
+	var doc bson.D
+	switch v := value.(type) {
+	case bson.M:
+		for k, field := range v {
+			doc = append(doc, bson.E{Key: k, Value: field})
+		}
+	case bson.D:
+		doc = append(doc, v...)
+	default:
+		return value
+	}
+	sort.Slice(doc, func(i, j int) bool { return doc[i].Key < doc[j].Key })
+	return doc
+}
+
+func describeValues(values bson.A) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		if text, err := extJSONValue(v, false); err == nil {
+			parts[i] = text
+		} else {
+			parts[i] = fmt.Sprint(v)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "(document)"
+	}
+	return path
+}
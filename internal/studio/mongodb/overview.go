@@ -0,0 +1,88 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/database"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type storeStatsReader interface {
+	GetDatabaseStats(ctx context.Context) (map[string]interface{}, error)
+	GetServerStatus(ctx context.Context) (bson.M, error)
+}
+
+// StoreOverview connects to a MongoDB database and returns its overview:
+// its size, collections and documents, and the operations the server
+// counted since it started. The connection is closed before returning.
+func StoreOverview(ctx context.Context, url string) common.StoreOverview {
+	overview := common.StoreOverview{Provider: "mongodb", ObjectKind: "collections", ItemKind: "documents", Activity: []common.StoreActivity{}}
+
+	adapter := database.NewAdapter("mongodb")
+	if err := adapter.Connect(ctx, url); err != nil {
+		overview.Error = err.Error()
+		return overview
+	}
+	defer adapter.Close()
+	mongoAdapter, ok := adapter.(storeStatsReader)
+	if !ok {
+		overview.Error = "adapter does not support MongoDB operations"
+		return overview
+	}
+
+	start := time.Now()
+	if err := adapter.Ping(ctx); err != nil {
+		overview.Error = err.Error()
+		return overview
+	}
+	overview.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	overview.Healthy = true
+
+	stats, err := mongoAdapter.GetDatabaseStats(ctx)
+	if err != nil {
+		overview.Error = fmt.Sprintf("failed to read database stats: %v", err)
+		return overview
+	}
+	overview.SizeBytes = statInt(stats["storageSize"]) + statInt(stats["indexSize"])
+	overview.Objects = int(statInt(stats["collections"]))
+	overview.Items = statInt(stats["objects"])
+
+	// serverStatus needs the clusterMonitor role, which studio can do without
+	status, err := mongoAdapter.GetServerStatus(ctx)
+	if err != nil {
+		return overview
+	}
+	overview.Version, _ = status["version"].(string)
+	if connections, ok := status["connections"].(bson.M); ok {
+		overview.Activity = append(overview.Activity, common.StoreActivity{
+			Description: fmt.Sprintf("Open connections: %d", statInt(connections["current"])),
+		})
+	}
+	if counters, ok := status["opcounters"].(bson.M); ok {
+		for _, op := range []struct{ counter, label string }{
+			{"insert", "Inserts"}, {"query", "Queries"}, {"update", "Updates"}, {"delete", "Deletes"}, {"command", "Commands"},
+		} {
+			overview.Activity = append(overview.Activity, common.StoreActivity{
+				Description: fmt.Sprintf("%s since the server started: %d", op.label, statInt(counters[op.counter])),
+			})
+		}
+	}
+	return overview
+}
+
+// statInt reads a number of dbStats or serverStatus, which servers report
+// as int32, int64 or double depending on the version and the size
+func statInt(value interface{}) int64 {
+	switch v := value.(type) {
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
@@ -0,0 +1,365 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+const (
+	// relationSample is how many documents of each collection references
+	// are inferred from
+	relationSample = 200
+	// relationValues bounds the distinct values of a field looked up as
+	// _ids of other collections
+	relationValues = 50
+	// valueMatchRatio is the share of a field's sampled values that have to
+	// be _ids of a collection for a reference its name doesn't suggest
+	valueMatchRatio = 0.8
+)
+
+// Relationship is a reference from a field of one collection to the _id of
+// another. Collections don't declare references, so they are inferred: from
+// the field's name (user_id, userId or user_ids refer to users) when some
+// of its values are _ids there, or from its ObjectId or UUID values alone
+// when most of them are _ids of one collection.
+type Relationship struct {
+	Collection    string `json:"collection"`
+	Field         string `json:"field"`
+	RefCollection string `json:"refCollection"`
+	ByName        bool   `json:"byName"`
+	Matched       int    `json:"matched"`
+	Sampled       int    `json:"sampled"`
+}
+
+type relationSampler interface {
+	ListCollections(ctx context.Context) ([]string, error)
+	SampleDocuments(ctx context.Context, collection string, size int) ([]bson.Raw, error)
+	CountDocuments(ctx context.Context, collection string, filter bson.M) (int64, error)
+}
+
+// sampledCollection is what sampling a collection found: its fields, the
+// values that could be references and the types of its _ids
+type sampledCollection struct {
+	name    string
+	fields  *fieldInferrer
+	values  map[string]*fieldValues
+	order   []string
+	idTypes map[bsontype.Type]bool
+}
+
+// fieldValues are the distinct values of a field that could be _ids
+type fieldValues struct {
+	values []bson.RawValue
+	seen   map[string]bool
+	types  map[bsontype.Type]bool
+}
+
+func (s *Service) relationSampler() (relationSampler, error) {
+	sampler, ok := s.adapter.(relationSampler)
+	if !ok {
+		return nil, fmt.Errorf("adapter does not support MongoDB operations")
+	}
+	return sampler, nil
+}
+
+// sampleCollections samples the documents of every collection of the
+// current database, in name order
+func (s *Service) sampleCollections(ctx context.Context, sampler relationSampler) ([]*sampledCollection, error) {
+	names, err := sampler.ListCollections(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+	sort.Strings(names)
+
+	collections := make([]*sampledCollection, 0, len(names))
+	for _, name := range names {
+		docs, err := sampler.SampleDocuments(ctx, name, relationSample)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sample collection %s: %w", name, err)
+		}
+		collection := &sampledCollection{
+			name:    name,
+			fields:  &fieldInferrer{fields: map[string]*fieldStats{}},
+			values:  map[string]*fieldValues{},
+			idTypes: map[bsontype.Type]bool{},
+		}
+		for _, doc := range docs {
+			collection.fields.add(doc)
+			if id, err := doc.LookupErr("_id"); err == nil {
+				collection.idTypes[id.Type] = true
+			}
+			collection.walk("", doc)
+		}
+		collections = append(collections, collection)
+	}
+	return collections, nil
+}
+
+// walk collects the values of a document's fields that could be _ids.
+// Values in arrays count for the array's field, and fields of documents in
+// arrays are dotted paths through the array, as queries read them.
+func (c *sampledCollection) walk(prefix string, doc bson.Raw) {
+	elements, err := doc.Elements()
+	if err != nil {
+		return
+	}
+	for _, element := range elements {
+		path := prefix + element.Key()
+		if path == "_id" {
+			continue
+		}
+		value := element.Value()
+		if value.Type != bsontype.Array {
+			c.collect(path, value)
+			continue
+		}
+		items, err := value.Array().Values()
+		if err != nil {
+			continue
+		}
+		for _, item := range items {
+			c.collect(path, item)
+		}
+	}
+}
+
+func (c *sampledCollection) collect(path string, value bson.RawValue) {
+	switch value.Type {
+	case bsontype.EmbeddedDocument:
+		c.walk(path+".", value.Document())
+		return
+	case bsontype.ObjectID, bsontype.Binary, bsontype.String, bsontype.Int32, bsontype.Int64:
+	default:
+		return
+	}
+
+	values, ok := c.values[path]
+	if !ok {
+		values = &fieldValues{seen: map[string]bool{}, types: map[bsontype.Type]bool{}}
+		c.values[path] = values
+		c.order = append(c.order, path)
+	}
+	values.types[value.Type] = true
+	key := string(value.Type) + string(value.Value)
+	if len(values.values) < relationValues && !values.seen[key] {
+		values.seen[key] = true
+		values.values = append(values.values, value)
+	}
+}
+
+// identifying reports whether the values can only be references: ObjectIds
+// and UUIDs are unique, where strings and numbers match _ids by chance
+func (v *fieldValues) identifying() bool {
+	for t := range v.types {
+		if t != bsontype.ObjectID && t != bsontype.Binary {
+			return false
+		}
+	}
+	return true
+}
+
+// InferRelationships infers the references between the collections of the
+// current database from a sample of each collection's documents
+func (s *Service) InferRelationships(ctx context.Context) ([]Relationship, error) {
+	sampler, err := s.relationSampler()
+	if err != nil {
+		return nil, err
+	}
+	collections, err := s.sampleCollections(ctx, sampler)
+	if err != nil {
+		return nil, err
+	}
+	return s.inferRelationships(ctx, sampler, collections)
+}
+
+func (s *Service) inferRelationships(ctx context.Context, sampler relationSampler, collections []*sampledCollection) ([]Relationship, error) {
+	relationships := make([]Relationship, 0)
+	for _, collection := range collections {
+		for _, path := range collection.order {
+			values := collection.values[path]
+			field := path[strings.LastIndex(path, ".")+1:]
+			base, ok := referenceName(field)
+			if !ok && values.identifying() {
+				// author or tags holding ObjectIds name what they refer to
+				base, ok = field, true
+			}
+
+			var best *Relationship
+			if ok {
+				for _, target := range collections {
+					if !namesCollection(base, target.name) {
+						continue
+					}
+					matched, err := s.countIDs(ctx, sampler, target.name, values.values)
+					if err != nil {
+						return nil, err
+					}
+					if matched > 0 && (best == nil || matched > best.Matched) {
+						best = &Relationship{RefCollection: target.name, ByName: true, Matched: matched}
+					}
+				}
+			}
+
+			if best == nil && values.identifying() {
+				for _, target := range collections {
+					if !sharesType(values.types, target.idTypes) {
+						continue
+					}
+					matched, err := s.countIDs(ctx, sampler, target.name, values.values)
+					if err != nil {
+						return nil, err
+					}
+					if float64(matched) >= valueMatchRatio*float64(len(values.values)) && (best == nil || matched > best.Matched) {
+						best = &Relationship{RefCollection: target.name, Matched: matched}
+					}
+				}
+			}
+
+			if best != nil {
+				best.Collection = collection.name
+				best.Field = path
+				best.Sampled = len(values.values)
+				relationships = append(relationships, *best)
+			}
+		}
+	}
+	return relationships, nil
+}
+
+// countIDs returns how many of the values are _ids of the collection
+func (s *Service) countIDs(ctx context.Context, sampler relationSampler, collection string, values []bson.RawValue) (int, error) {
+	count, err := sampler.CountDocuments(ctx, collection, bson.M{"_id": bson.M{"$in": values}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up references in %s: %w", collection, err)
+	}
+	return int(count), nil
+}
+
+func sharesType(types, idTypes map[bsontype.Type]bool) bool {
+	for t := range types {
+		if idTypes[t] {
+			return true
+		}
+	}
+	return false
+}
+
+// referenceSuffixes end the names of fields that refer to another collection
+var referenceSuffixes = []string{"_ids", "Ids", "IDs", "_id", "Id", "ID"}
+
+// referenceName returns what a field refers to by its name: user for
+// user_id, userId, user_ids and userIds
+func referenceName(field string) (string, bool) {
+	for _, suffix := range referenceSuffixes {
+		if base := strings.TrimSuffix(field, suffix); base != field && base != "" {
+			return base, true
+		}
+	}
+	return "", false
+}
+
+// namesCollection reports whether a name refers to a collection, ignoring
+// case, underscores and dashes and whether either is plural: user and
+// User refer to users, blog_post to blogPosts, category to categories
+func namesCollection(name, collection string) bool {
+	n, c := normalizeName(name), normalizeName(collection)
+	if n == "" {
+		return false
+	}
+	if c == n || c == n+"s" || c == n+"es" || n == c+"s" || n == c+"es" {
+		return true
+	}
+	return strings.HasSuffix(n, "y") && c == strings.TrimSuffix(n, "y")+"ies"
+}
+
+func normalizeName(name string) string {
+	name = strings.ToLower(name)
+	return strings.NewReplacer("_", "", "-", "", " ", "").Replace(name)
+}
+
+// GetSchemaVisualization returns the collections of the current database
+// and the references between them as nodes and edges, in the form SQL
+// studios draw their ER diagrams from. The fields of each collection are
+// inferred from a sample of its documents, and edges are the inferred
+// relationships.
+func (s *Service) GetSchemaVisualization(ctx context.Context) (map[string]interface{}, error) {
+	sampler, err := s.relationSampler()
+	if err != nil {
+		return nil, err
+	}
+	collections, err := s.sampleCollections(ctx, sampler)
+	if err != nil {
+		return nil, err
+	}
+	relationships, err := s.inferRelationships(ctx, sampler, collections)
+	if err != nil {
+		return nil, err
+	}
+
+	references := make(map[string]Relationship, len(relationships))
+	for _, relationship := range relationships {
+		references[relationship.Collection+"\x00"+relationship.Field] = relationship
+	}
+
+	nodes := make([]map[string]interface{}, 0, len(collections))
+	nodeIndex := make(map[string]string, len(collections))
+	for i, collection := range collections {
+		nodeID := fmt.Sprintf("collection-%d", i)
+		nodeIndex[collection.name] = nodeID
+
+		fields := collection.fields.result()
+		columns := make([]map[string]interface{}, 0, len(fields))
+		for _, field := range fields {
+			reference, isForeign := references[collection.name+"\x00"+field.Name]
+			column := map[string]interface{}{
+				"name":      field.Name,
+				"type":      strings.Join(field.Types, " | "),
+				"isPrimary": field.Name == "_id",
+				"isForeign": isForeign,
+				"nullable":  field.Optional,
+			}
+			if isForeign {
+				column["foreignKeyTable"] = reference.RefCollection
+				column["foreignKeyColumn"] = "_id"
+			}
+			columns = append(columns, column)
+		}
+
+		nodes = append(nodes, map[string]interface{}{
+			"id": nodeID,
+			"data": map[string]interface{}{
+				"label":   collection.name,
+				"columns": columns,
+				"sampled": collection.fields.count,
+			},
+			"position": map[string]int{
+				"x": 100 + (i%4)*300,
+				"y": 100 + (i/4)*250,
+			},
+		})
+	}
+
+	edges := make([]map[string]interface{}, 0, len(relationships))
+	for _, relationship := range relationships {
+		sourceID, targetID := nodeIndex[relationship.Collection], nodeIndex[relationship.RefCollection]
+		edges = append(edges, map[string]interface{}{
+			"id":           fmt.Sprintf("%s-%s-%s", sourceID, targetID, relationship.Field),
+			"source":       sourceID,
+			"target":       targetID,
+			"label":        relationship.Field,
+			"sourceHandle": relationship.Field,
+			"targetHandle": "_id",
+			"inferred":     true,
+			"byName":       relationship.ByName,
+			"matched":      relationship.Matched,
+			"sampled":      relationship.Sampled,
+		})
+	}
+
+	return map[string]interface{}{"nodes": nodes, "edges": edges}, nil
+}
@@ -17,6 +17,7 @@ type Server struct {
 	service       *Service
 	port          int
 	connectionURL string
+	cfg           *config.Config
 }
 
 func NewServer(cfg *config.Config, port int) *Server {
@@ -32,7 +33,7 @@ func NewServer(cfg *config.Config, port int) *Server {
 	}
 
 	mux := http.NewServeMux()
-	tmpl := common.ParseTemplates(TemplatesFS)
+	tmpl := common.ParseTemplates(TemplatesFS, StaticFS)
 
 	server := &Server{
 		mux:           mux,
@@ -40,6 +41,7 @@ func NewServer(cfg *config.Config, port int) *Server {
 		service:       NewService(adapter),
 		port:          port,
 		connectionURL: dbURL,
+		cfg:           cfg,
 	}
 
 	server.setupRoutes()
@@ -70,7 +72,9 @@ func (s *Server) setupRoutes() {
 	// API Routes - Documents
 	s.mux.HandleFunc("GET /api/collections/{name}/documents", s.handleGetDocuments)
 	s.mux.HandleFunc("POST /api/collections/{name}/documents", s.handleInsertDocument)
+	s.mux.HandleFunc("GET /api/collections/{name}/documents/{id}", s.handleGetDocument)
 	s.mux.HandleFunc("PUT /api/collections/{name}/documents/{id}", s.handleUpdateDocument)
+	s.mux.HandleFunc("PATCH /api/collections/{name}/documents/{id}", s.handlePatchDocument)
 	s.mux.HandleFunc("DELETE /api/collections/{name}/documents/{id}", s.handleDeleteDocument)
 	s.mux.HandleFunc("POST /api/collections/{name}/documents/bulk-delete", s.handleBulkDeleteDocuments)
 
@@ -85,13 +89,20 @@ func (s *Server) setupRoutes() {
 	// API Routes - Query
 	s.mux.HandleFunc("POST /api/collections/{name}/query", s.handleQuery)
 
+	// API Routes - Schema
+	s.mux.HandleFunc("GET /api/schema", s.handleGetSchema)
+
+	// API Routes - Export/Import
+	s.mux.HandleFunc("GET /api/export/{type}", s.handleExport)
+	s.mux.HandleFunc("POST /api/import", s.handleImport)
+
 	// API Routes - Stats
 	s.mux.HandleFunc("GET /api/stats", s.handleGetStats)
 	s.mux.HandleFunc("GET /api/collections/{name}/stats", s.handleGetCollectionStats)
 }
 
-func (s *Server) Start(openBrowser bool) error {
-	return common.StartServer(s.mux, &s.port, "MongoDB Studio", openBrowser)
+func (s *Server) Start(opts common.StartOptions) error {
+	return common.StartServer(s.mux, &s.port, "MongoDB Studio", opts, s.service.adapter.Ping, s.service.adapter.Close)
 }
 
 // UI Handlers
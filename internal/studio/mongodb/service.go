@@ -12,7 +12,6 @@ import (
 
 type Service struct {
 	adapter database.DatabaseAdapter
-	ctx     context.Context
 }
 
 type DatabaseInfo struct {
@@ -33,6 +32,9 @@ type DocumentResult struct {
 	TotalCount int64                    `json:"total_count"`
 	Page       int                      `json:"page"`
 	Limit      int                      `json:"limit"`
+	// IDs are the documents' _id as canonical Extended JSON, the form the
+	// document endpoints take for any type of _id
+	IDs []string `json:"ids"`
 }
 
 type IndexInfo struct {
@@ -51,7 +53,6 @@ type Stats struct {
 func NewService(adapter database.DatabaseAdapter) *Service {
 	return &Service{
 		adapter: adapter,
-		ctx:     context.Background(),
 	}
 }
 
@@ -69,7 +70,7 @@ func (s *Service) SwitchDatabase(dbName string) error {
 }
 
 // DropDatabase drops a database
-func (s *Service) DropDatabase(dbName string) error {
+func (s *Service) DropDatabase(ctx context.Context, dbName string) error {
 	mongoAdapter, ok := s.adapter.(interface {
 		DropDatabase(context.Context, string) error
 	})
@@ -78,11 +79,11 @@ func (s *Service) DropDatabase(dbName string) error {
 		return fmt.Errorf("adapter does not support DropDatabase")
 	}
 
-	return mongoAdapter.DropDatabase(s.ctx, dbName)
+	return mongoAdapter.DropDatabase(ctx, dbName)
 }
 
 // CreateDatabase creates a new database by creating an initial collection
-func (s *Service) CreateDatabase(dbName string) error {
+func (s *Service) CreateDatabase(ctx context.Context, dbName string) error {
 	mongoAdapter, ok := s.adapter.(interface {
 		CreateDatabase(context.Context, string) error
 	})
@@ -91,11 +92,11 @@ func (s *Service) CreateDatabase(dbName string) error {
 		return fmt.Errorf("adapter does not support CreateDatabase")
 	}
 
-	return mongoAdapter.CreateDatabase(s.ctx, dbName)
+	return mongoAdapter.CreateDatabase(ctx, dbName)
 }
 
 // GetDatabases lists all databases
-func (s *Service) GetDatabases() ([]DatabaseInfo, error) {
+func (s *Service) GetDatabases(ctx context.Context) ([]DatabaseInfo, error) {
 	mongoAdapter, ok := s.adapter.(interface {
 		ListDatabases(context.Context) ([]map[string]interface{}, error)
 	})
@@ -104,7 +105,7 @@ func (s *Service) GetDatabases() ([]DatabaseInfo, error) {
 		return nil, fmt.Errorf("adapter does not support ListDatabases")
 	}
 
-	dbList, err := mongoAdapter.ListDatabases(s.ctx)
+	dbList, err := mongoAdapter.ListDatabases(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -134,7 +135,7 @@ func (s *Service) GetDatabases() ([]DatabaseInfo, error) {
 }
 
 // GetCollections returns all collections in the database
-func (s *Service) GetCollections(database string) ([]CollectionInfo, error) {
+func (s *Service) GetCollections(ctx context.Context, database string) ([]CollectionInfo, error) {
 	type MongoCollectionOps interface {
 		ListCollectionsInDB(ctx context.Context, database string) ([]string, error)
 		CountDocumentsInDB(ctx context.Context, database, collection string, filter bson.M) (int64, error)
@@ -145,14 +146,14 @@ func (s *Service) GetCollections(database string) ([]CollectionInfo, error) {
 		return nil, fmt.Errorf("adapter does not support MongoDB operations")
 	}
 
-	collections, err := mongoAdapter.ListCollectionsInDB(s.ctx, database)
+	collections, err := mongoAdapter.ListCollectionsInDB(ctx, database)
 	if err != nil {
 		return nil, err
 	}
 
 	result := make([]CollectionInfo, 0, len(collections))
 	for _, coll := range collections {
-		count, err := mongoAdapter.CountDocumentsInDB(s.ctx, database, coll, bson.M{})
+		count, err := mongoAdapter.CountDocumentsInDB(ctx, database, coll, bson.M{})
 		if err != nil {
 			result = append(result, CollectionInfo{
 				Name:          coll,
@@ -177,13 +178,13 @@ func (s *Service) GetCollections(database string) ([]CollectionInfo, error) {
 }
 
 // GetDocuments returns documents from a collection with pagination
-func (s *Service) GetDocuments(database, collection string, page, limit int) (*DocumentResult, error) {
-	return s.GetDocumentsWithFilter(database, collection, page, limit, bson.M{})
+func (s *Service) GetDocuments(ctx context.Context, database, collection string, page, limit int) (*DocumentResult, error) {
+	return s.GetDocumentsWithFilter(ctx, database, collection, page, limit, bson.M{})
 }
 
-func (s *Service) GetDocumentsWithFilter(database, collection string, page, limit int, filter bson.M) (*DocumentResult, error) {
+func (s *Service) GetDocumentsWithFilter(ctx context.Context, database, collection string, page, limit int, filter bson.M) (*DocumentResult, error) {
 	type MongoDocumentReader interface {
-		FindDocumentsInDB(ctx context.Context, database, collection string, filter bson.M, skip, limit int64) ([]map[string]interface{}, error)
+		FindDocumentsWithIDsInDB(ctx context.Context, database, collection string, filter bson.M, skip, limit int64) ([]map[string]interface{}, []string, error)
 		CountDocumentsInDB(ctx context.Context, database, collection string, filter bson.M) (int64, error)
 	}
 
@@ -197,18 +198,19 @@ func (s *Service) GetDocumentsWithFilter(database, collection string, page, limi
 	}
 
 	skip := int64((page - 1) * limit)
-	documents, err := mongoAdapter.FindDocumentsInDB(s.ctx, database, collection, filter, skip, int64(limit))
+	documents, ids, err := mongoAdapter.FindDocumentsWithIDsInDB(ctx, database, collection, filter, skip, int64(limit))
 	if err != nil {
 		return nil, err
 	}
 
-	totalCount, err := mongoAdapter.CountDocumentsInDB(s.ctx, database, collection, filter)
+	totalCount, err := mongoAdapter.CountDocumentsInDB(ctx, database, collection, filter)
 	if err != nil {
 		return nil, err
 	}
 
 	return &DocumentResult{
 		Documents:  documents,
+		IDs:        ids,
 		TotalCount: totalCount,
 		Page:       page,
 		Limit:      limit,
@@ -216,7 +218,7 @@ func (s *Service) GetDocumentsWithFilter(database, collection string, page, limi
 }
 
 // InsertDocument inserts a new document
-func (s *Service) InsertDocument(collection string, document map[string]interface{}) (string, error) {
+func (s *Service) InsertDocument(ctx context.Context, collection string, document map[string]interface{}) (string, error) {
 	type MongoDocumentWriter interface {
 		InsertDocument(ctx context.Context, collection string, document interface{}) (string, error)
 	}
@@ -228,11 +230,11 @@ func (s *Service) InsertDocument(collection string, document map[string]interfac
 
 	s.processDocumentTypes(document)
 
-	return mongoAdapter.InsertDocument(s.ctx, collection, document)
+	return mongoAdapter.InsertDocument(ctx, collection, document)
 }
 
 // UpdateDocument updates an existing document
-func (s *Service) UpdateDocument(collection, id string, document map[string]interface{}) error {
+func (s *Service) UpdateDocument(ctx context.Context, collection, id string, document map[string]interface{}) error {
 	type MongoDocumentUpdater interface {
 		UpdateDocument(ctx context.Context, collection string, id string, update interface{}) error
 	}
@@ -244,11 +246,11 @@ func (s *Service) UpdateDocument(collection, id string, document map[string]inte
 
 	s.processDocumentTypes(document)
 
-	return mongoAdapter.UpdateDocument(s.ctx, collection, id, bson.M{"$set": document})
+	return mongoAdapter.UpdateDocument(ctx, collection, id, bson.M{"$set": document})
 }
 
 // DeleteDocument deletes a document by ID
-func (s *Service) DeleteDocument(collection, id string) error {
+func (s *Service) DeleteDocument(ctx context.Context, collection, id string) error {
 	type MongoDocumentDeleter interface {
 		DeleteDocument(ctx context.Context, collection string, id string) error
 	}
@@ -258,18 +260,18 @@ func (s *Service) DeleteDocument(collection, id string) error {
 		return fmt.Errorf("adapter does not support MongoDB operations")
 	}
 
-	return mongoAdapter.DeleteDocument(s.ctx, collection, id)
+	return mongoAdapter.DeleteDocument(ctx, collection, id)
 }
 
 // BulkDeleteDocuments deletes multiple documents efficiently using $in operator
-func (s *Service) BulkDeleteDocuments(collection string, ids []string) error {
+func (s *Service) BulkDeleteDocuments(ctx context.Context, collection string, ids []string) error {
 	// Try bulk delete first (more efficient)
 	type MongoBulkDeleter interface {
 		BulkDeleteDocuments(ctx context.Context, collection string, ids []string) (int64, error)
 	}
 
 	if bulkAdapter, ok := s.adapter.(MongoBulkDeleter); ok {
-		_, err := bulkAdapter.BulkDeleteDocuments(s.ctx, collection, ids)
+		_, err := bulkAdapter.BulkDeleteDocuments(ctx, collection, ids)
 		return err
 	}
 
@@ -284,7 +286,7 @@ func (s *Service) BulkDeleteDocuments(collection string, ids []string) error {
 	}
 
 	for _, id := range ids {
-		if err := mongoAdapter.DeleteDocument(s.ctx, collection, id); err != nil {
+		if err := mongoAdapter.DeleteDocument(ctx, collection, id); err != nil {
 			return fmt.Errorf("failed to delete document %s: %w", id, err)
 		}
 	}
@@ -293,7 +295,7 @@ func (s *Service) BulkDeleteDocuments(collection string, ids []string) error {
 }
 
 // CreateCollection creates a new collection
-func (s *Service) CreateCollection(name string, options map[string]interface{}) error {
+func (s *Service) CreateCollection(ctx context.Context, name string, options map[string]interface{}) error {
 	type MongoCollectionCreator interface {
 		CreateCollection(ctx context.Context, name string, options interface{}) error
 	}
@@ -303,11 +305,11 @@ func (s *Service) CreateCollection(name string, options map[string]interface{})
 		return fmt.Errorf("adapter does not support MongoDB operations")
 	}
 
-	return mongoAdapter.CreateCollection(s.ctx, name, options)
+	return mongoAdapter.CreateCollection(ctx, name, options)
 }
 
 // DropCollection drops a collection
-func (s *Service) DropCollection(name string) error {
+func (s *Service) DropCollection(ctx context.Context, name string) error {
 	type MongoCollectionDropper interface {
 		DropCollection(ctx context.Context, name string) error
 	}
@@ -317,11 +319,11 @@ func (s *Service) DropCollection(name string) error {
 		return fmt.Errorf("adapter does not support MongoDB operations")
 	}
 
-	return mongoAdapter.DropCollection(s.ctx, name)
+	return mongoAdapter.DropCollection(ctx, name)
 }
 
 // Aggregate runs an aggregation pipeline
-func (s *Service) Aggregate(collection string, pipeline []bson.M) ([]map[string]interface{}, error) {
+func (s *Service) Aggregate(ctx context.Context, collection string, pipeline []bson.M) ([]map[string]interface{}, error) {
 	type MongoAggregator interface {
 		Aggregate(ctx context.Context, collection string, pipeline interface{}) ([]map[string]interface{}, error)
 	}
@@ -331,11 +333,11 @@ func (s *Service) Aggregate(collection string, pipeline []bson.M) ([]map[string]
 		return nil, fmt.Errorf("adapter does not support MongoDB operations")
 	}
 
-	return mongoAdapter.Aggregate(s.ctx, collection, pipeline)
+	return mongoAdapter.Aggregate(ctx, collection, pipeline)
 }
 
 // GetIndexes returns all indexes for a collection
-func (s *Service) GetIndexes(collection string) ([]IndexInfo, error) {
+func (s *Service) GetIndexes(ctx context.Context, collection string) ([]IndexInfo, error) {
 	type MongoIndexReader interface {
 		ListIndexes(ctx context.Context, collection string) ([]map[string]interface{}, error)
 	}
@@ -345,7 +347,7 @@ func (s *Service) GetIndexes(collection string) ([]IndexInfo, error) {
 		return nil, fmt.Errorf("adapter does not support MongoDB operations")
 	}
 
-	indexes, err := mongoAdapter.ListIndexes(s.ctx, collection)
+	indexes, err := mongoAdapter.ListIndexes(ctx, collection)
 	if err != nil {
 		return nil, err
 	}
@@ -371,7 +373,7 @@ func (s *Service) GetIndexes(collection string) ([]IndexInfo, error) {
 }
 
 // CreateIndex creates a new index
-func (s *Service) CreateIndex(collection string, keys map[string]interface{}, unique bool) error {
+func (s *Service) CreateIndex(ctx context.Context, collection string, keys map[string]interface{}, unique bool) error {
 	type MongoIndexCreator interface {
 		CreateIndex(ctx context.Context, collection string, keys map[string]interface{}, unique bool) error
 	}
@@ -381,11 +383,11 @@ func (s *Service) CreateIndex(collection string, keys map[string]interface{}, un
 		return fmt.Errorf("adapter does not support MongoDB operations")
 	}
 
-	return mongoAdapter.CreateIndex(s.ctx, collection, keys, unique)
+	return mongoAdapter.CreateIndex(ctx, collection, keys, unique)
 }
 
 // DropIndex drops an index
-func (s *Service) DropIndex(collection, indexName string) error {
+func (s *Service) DropIndex(ctx context.Context, collection, indexName string) error {
 	type MongoIndexDropper interface {
 		DropIndex(ctx context.Context, collection string, indexName string) error
 	}
@@ -395,11 +397,11 @@ func (s *Service) DropIndex(collection, indexName string) error {
 		return fmt.Errorf("adapter does not support MongoDB operations")
 	}
 
-	return mongoAdapter.DropIndex(s.ctx, collection, indexName)
+	return mongoAdapter.DropIndex(ctx, collection, indexName)
 }
 
 // Query executes a custom query
-func (s *Service) Query(collection string, filter bson.M, limit int) ([]map[string]interface{}, error) {
+func (s *Service) Query(ctx context.Context, collection string, filter bson.M, limit int) ([]map[string]interface{}, error) {
 	type MongoDocumentReader interface {
 		FindDocuments(ctx context.Context, collection string, filter bson.M, skip, limit int64) ([]map[string]interface{}, error)
 	}
@@ -409,11 +411,11 @@ func (s *Service) Query(collection string, filter bson.M, limit int) ([]map[stri
 		return nil, fmt.Errorf("adapter does not support MongoDB operations")
 	}
 
-	return mongoAdapter.FindDocuments(s.ctx, collection, filter, 0, int64(limit))
+	return mongoAdapter.FindDocuments(ctx, collection, filter, 0, int64(limit))
 }
 
 // GetStats returns database statistics
-func (s *Service) GetStats() (*Stats, error) {
+func (s *Service) GetStats(ctx context.Context) (*Stats, error) {
 	type MongoStatsReader interface {
 		GetDatabaseStats(ctx context.Context) (map[string]interface{}, error)
 		ListCollections(ctx context.Context) ([]string, error)
@@ -424,12 +426,12 @@ func (s *Service) GetStats() (*Stats, error) {
 		return nil, fmt.Errorf("adapter does not support MongoDB operations")
 	}
 
-	stats, err := mongoAdapter.GetDatabaseStats(s.ctx)
+	stats, err := mongoAdapter.GetDatabaseStats(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	collections, _ := mongoAdapter.ListCollections(s.ctx)
+	collections, _ := mongoAdapter.ListCollections(ctx)
 
 	result := &Stats{
 		Collections: len(collections),
@@ -449,7 +451,7 @@ func (s *Service) GetStats() (*Stats, error) {
 }
 
 // GetCollectionStats returns statistics for a specific collection
-func (s *Service) GetCollectionStats(collection string) (map[string]interface{}, error) {
+func (s *Service) GetCollectionStats(ctx context.Context, collection string) (map[string]interface{}, error) {
 	type MongoCollectionLister interface {
 		GetCollectionStats(ctx context.Context, collection string) (map[string]interface{}, error)
 	}
@@ -459,7 +461,7 @@ func (s *Service) GetCollectionStats(collection string) (map[string]interface{},
 		return nil, fmt.Errorf("adapter does not support MongoDB operations")
 	}
 
-	return mongoAdapter.GetCollectionStats(s.ctx, collection)
+	return mongoAdapter.GetCollectionStats(ctx, collection)
 }
 
 // processDocumentTypes ensures proper types for MongoDB operations
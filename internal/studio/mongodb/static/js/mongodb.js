@@ -9,6 +9,21 @@ function extractHostnameFromURL(url) {
 
 // State variables
 let currentDatabase = '', currentCollection = '', currentFilter = '', databases = [], collections = [], documents = [], selected = new Set(), page = 1, pageSize = 20, total = 0, viewMode = 'json';
+// _id of each document as Extended JSON, which works for any type of _id,
+// and the document being edited as it was loaded
+let docIds = [], docOriginal = '';
+// The filter conditions of the grid, sent as filters the way SQL tables
+// send theirs and applied on top of the JSON query
+let gridFilters = [];
+const filterOperators = [
+    ['equals', 'equals'], ['not_equals', 'not equals'], ['contains', 'contains'], ['not_contains', 'not contains'],
+    ['starts_with', 'starts with'], ['ends_with', 'ends with'], ['gt', 'greater than'], ['lt', 'less than'],
+    ['gte', '≥'], ['lte', '≤'], ['in', 'in'], ['not_in', 'not in'], ['between', 'between'],
+    ['in_last', 'in the last'], ['not_in_last', 'not in the last'], ['matches', 'matches regex'], ['not_matches', 'not matches regex'],
+    ['is_null', 'is null'], ['is_not_null', 'is not null'], ['is_empty', 'is empty'], ['is_not_empty', 'is not empty'],
+];
+// Filter operators that take no value
+const valuelessFilterOperators = ['is_null', 'is_not_null', 'is_empty', 'is_not_empty'];
 let dbConnectionString = extractHostnameFromURL(window.DB_CONNECTION_URL || 'mongodb://localhost');
 
 document.addEventListener('DOMContentLoaded', init);
@@ -28,9 +43,13 @@ function setupListeners() {
     const selectAllBtn = $('#select-all-btn');
     if (selectAllBtn) selectAllBtn.onclick = toggleSelectAll;
     const filterBtn = $('#filter-btn');
-    if (filterBtn) filterBtn.onclick = () => { loadFilterSchema(); openModal('filter-modal'); };
+    if (filterBtn) filterBtn.onclick = () => { loadFilterSchema(); renderFilterRows(); openModal('filter-modal'); };
+    const addFilterBtn = $('#add-filter-row');
+    if (addFilterBtn) addFilterBtn.onclick = () => addFilterRow({ logic: $('#filter-rows').children.length ? 'and' : 'where' });
+    const clearFiltersBtn = $('#clear-filters');
+    if (clearFiltersBtn) clearFiltersBtn.onclick = () => { $('#filter-rows').innerHTML = ''; $('#filter-query').value = ''; };
     const insertBtn = $('#insert-btn');
-    if (insertBtn) insertBtn.onclick = () => { $('#doc-id').value = ''; $('#doc-json').value = '{}'; openModal('doc-modal') };
+    if (insertBtn) insertBtn.onclick = () => { newDoc(); openModal('doc-modal') };
     const deleteBtn = $('#delete-btn');
     if (deleteBtn) deleteBtn.onclick = bulkDelete;
     const saveBtn = $('#save-btn');
@@ -79,6 +98,16 @@ function setupListeners() {
     if (collForm) collForm.onsubmit = (e) => { e.preventDefault(); createCollection($('#collection-name').value.trim()) };
     const addCollBtn = $('#add-collection-btn');
     if (addCollBtn) addCollBtn.onclick = () => openModal('collection-modal');
+    const diagramBtn = $('#diagram-btn');
+    if (diagramBtn) diagramBtn.onclick = () => { openModal('diagram-modal'); loadDiagram() };
+    const exportDbBtn = $('#export-db-btn');
+    if (exportDbBtn) exportDbBtn.onclick = () => openModal('export-modal');
+    const importDbBtn = $('#import-db-btn');
+    if (importDbBtn) importDbBtn.onclick = () => { $('#import-result').innerHTML = ''; openModal('import-modal') };
+    const exportForm = $('#export-form');
+    if (exportForm) exportForm.onsubmit = (e) => { e.preventDefault(); exportDatabase($('#export-type').value) };
+    const importForm = $('#import-form');
+    if (importForm) importForm.onsubmit = (e) => { e.preventDefault(); importDatabase($('#import-file').files[0], $('#import-verify').checked) };
     const dbForm = $('#database-form');
     if (dbForm) dbForm.onsubmit = (e) => { e.preventDefault(); createDatabase($('#database-name').value.trim()) };
 
@@ -122,6 +151,7 @@ async function loadFilterSchema() {
             docs.forEach(doc => Object.keys(doc).forEach(k => fields.add(k)));
             const fieldList = Array.from(fields).sort();
 
+            $('#filter-fields').innerHTML = fieldList.map(f => `<option value="${escapeHtmlAttr(f)}">`).join('');
             const container = $('#filter-schema-fields');
             if (container) {
                 container.innerHTML = '<small style="color: var(--text-tertiary); display: block; margin-bottom: 6px;">Click field to add to filter:</small>' +
@@ -225,6 +255,8 @@ async function loadCollections() {
 async function selectCollection(name, evt) {
     currentCollection = name;
     currentFilter = '';
+    gridFilters = [];
+    updateFilterCount();
     page = 1;
     selected.clear();
     const textSearch = $('#text-search');
@@ -263,6 +295,7 @@ async function loadDocs(filter) {
     try {
         const params = new URLSearchParams({ page: page, limit: pageSize });
         if (actualFilter) params.append('filter', actualFilter);
+        if (gridFilters.length) params.append('filters', JSON.stringify(gridFilters));
         if (currentDatabase) params.append('database', currentDatabase);
         console.log('Fetching documents with params:', { page, pageSize, database: currentDatabase, collection: currentCollection, filter: actualFilter });
         const res = await fetch(`/api/collections/${currentCollection}/documents?${params}`);
@@ -278,6 +311,7 @@ async function loadDocs(filter) {
 
         console.log('Extracted result:', result);
         documents = result.documents || result.Documents || [];
+        docIds = result.ids || [];
         total = result.total_count || result.TotalCount || result.total || 0;
         console.log('Final documents:', documents.length, 'Total:', total);
         renderDocs();
@@ -292,12 +326,104 @@ async function loadDocs(filter) {
 function applyFilter() {
     const filterQuery = $('#filter-query').value.trim();
     currentFilter = filterQuery;
+    gridFilters = readFilterRows();
+    updateFilterCount();
     page = 1;
     const textSearch = $('#text-search');
     if (textSearch) textSearch.value = '';
     loadDocs(currentFilter);
 }
 
+// addFilterRow adds a condition row to the filter modal, the fields offered
+// being those of the sampled documents
+function addFilterRow(filter) {
+    const row = document.createElement('div');
+    row.className = 'filter-row';
+    const logic = filter.logic || 'where';
+    let value = filter.value || '', value2 = '';
+    const values = filter.values || [];
+    if (filter.operator === 'between' && values.length) {
+        [value = '', value2 = ''] = values;
+    } else if (values.length) {
+        value = values.join(', ');
+    }
+    row.innerHTML = `
+        ${logic === 'where'
+            ? '<select class="mini-select filter-logic" disabled><option value="where">where</option></select>'
+            : `<select class="mini-select filter-logic"><option value="and" ${logic === 'and' ? 'selected' : ''}>and</option><option value="or" ${logic === 'or' ? 'selected' : ''}>or</option></select>`}
+        <input type="text" class="form-input filter-column" list="filter-fields" placeholder="field" value="${escapeHtmlAttr(filter.column || '')}">
+        <select class="mini-select filter-operator">${filterOperators.map(([op, label]) =>
+            `<option value="${op}" ${op === (filter.operator || 'equals') ? 'selected' : ''}>${label}</option>`).join('')}</select>
+        <input type="text" class="form-input filter-value" value="${escapeHtmlAttr(value)}">
+        <input type="text" class="form-input filter-value2" placeholder="To" value="${escapeHtmlAttr(value2)}">
+        <label class="filter-flag" title="Match case"><input type="checkbox" class="filter-case" ${filter.case_sensitive ? 'checked' : ''}>Aa</label>
+        <button type="button" class="action-btn delete filter-remove" title="Remove">✕</button>`;
+    row.querySelector('.filter-operator').onchange = () => updateFilterValueInputs(row);
+    row.querySelector('.filter-remove').onclick = () => {
+        row.remove();
+        // The first row is the where
+        const first = $('#filter-rows').firstElementChild;
+        if (first) first.querySelector('.filter-logic').outerHTML = '<select class="mini-select filter-logic" disabled><option value="where">where</option></select>';
+    };
+    $('#filter-rows').appendChild(row);
+    updateFilterValueInputs(row);
+}
+
+function updateFilterValueInputs(row) {
+    const op = row.querySelector('.filter-operator').value;
+    const valueInput = row.querySelector('.filter-value');
+    row.querySelector('.filter-value2').style.display = op === 'between' ? '' : 'none';
+    const valueless = valuelessFilterOperators.includes(op);
+    valueInput.disabled = valueless;
+    if (valueless) valueInput.value = '';
+    valueInput.placeholder = valueless ? 'N/A' : ({
+        in: 'a, b, c', not_in: 'a, b, c', between: 'From', in_last: '7 days', not_in_last: '7 days',
+        matches: 'Regular expression', not_matches: 'Regular expression',
+    }[op] || 'Value');
+    row.querySelector('.filter-flag').style.display =
+        ['gt', 'lt', 'gte', 'lte', 'between', 'in_last', 'not_in_last'].includes(op) || valueless ? 'none' : '';
+}
+
+function renderFilterRows() {
+    $('#filter-rows').innerHTML = '';
+    gridFilters.forEach((f, i) => addFilterRow({ ...f, logic: i ? f.logic : 'where' }));
+}
+
+// readFilterRows returns the conditions of the filter modal, leaving out
+// rows without a field or a value they need
+function readFilterRows() {
+    const result = [];
+    for (const row of $('#filter-rows').children) {
+        const logic = row.querySelector('.filter-logic').value;
+        const column = row.querySelector('.filter-column').value.trim();
+        const operator = row.querySelector('.filter-operator').value;
+        const value = row.querySelector('.filter-value').value;
+        const flags = row.querySelector('.filter-flag').style.display !== 'none' && row.querySelector('.filter-case').checked
+            ? { case_sensitive: true } : {};
+        if (!column) continue;
+        if (valuelessFilterOperators.includes(operator)) {
+            result.push({ logic, column, operator, value: '' });
+        } else if (operator === 'in' || operator === 'not_in') {
+            const values = value.split(',').map(v => v.trim()).filter(v => v !== '');
+            if (values.length) result.push({ logic, column, operator, value: '', values, ...flags });
+        } else if (operator === 'between') {
+            const value2 = row.querySelector('.filter-value2').value;
+            if (value !== '' && value2 !== '') result.push({ logic, column, operator, value: '', values: [value, value2] });
+        } else if (value !== '') {
+            result.push({ logic, column, operator, value, ...flags });
+        }
+    }
+    return result;
+}
+
+function updateFilterCount() {
+    const count = gridFilters.length + (currentFilter ? 1 : 0);
+    const badge = $('#filter-count');
+    if (!badge) return;
+    badge.textContent = count;
+    badge.style.display = count ? '' : 'none';
+}
+
 function filterDisplayedDocuments(query) {
     if (!query) {
         $$('.json-card').forEach(card => card.style.display = '');
@@ -355,8 +481,7 @@ function renderDocs() {
         const emptyAddBtn = $('#empty-add-btn');
         if (emptyAddBtn) {
             emptyAddBtn.onclick = () => {
-                $('#doc-id').value = '';
-                $('#doc-json').value = '{}';
+                newDoc();
                 openModal('doc-modal');
             };
         }
@@ -387,15 +512,15 @@ function renderTableView() {
     $('#select-all-table').onchange = toggleSelectAll;
 
     const tbody = $('#docs-table tbody');
-    tbody.innerHTML = documents.map(doc => {
-        const id = doc._id || doc.id || '';
+    tbody.innerHTML = documents.map((doc, i) => {
+        const id = docId(i);
         const sel = selected.has(id);
         return `<tr class="${sel ? 'selected' : ''}">
-      <td><input type="checkbox" class="row-check" data-id="${id}" ${sel ? 'checked' : ''}></td>
+      <td><input type="checkbox" class="row-check" data-id="${escapeHtmlAttr(id)}" ${sel ? 'checked' : ''}></td>
       ${cols.map(k => `<td title="${escapeHtml(JSON.stringify(doc[k]))}">${formatValue(doc[k])}</td>`).join('')}
       <td class="row-actions">
-        <button class="action-btn edit" onclick="editDoc('${id}')" title="Edit"><svg width="14" height="14" fill="currentColor"><path d="M11 1l3 3L4 14H1v-3z"/></svg></button>
-        <button class="action-btn delete" onclick="deleteDoc('${id}')" title="Delete"><svg width="14" height="14" fill="currentColor"><path d="M5 1h4v1H5zm-2 2h8v10H3zm2 1v8h1V4zm2 0v8h1V4zm2 0v8h1V4z"/></svg></button>
+        <button class="action-btn edit" onclick="editDoc(${i})" title="Edit"><svg width="14" height="14" fill="currentColor"><path d="M11 1l3 3L4 14H1v-3z"/></svg></button>
+        <button class="action-btn delete" onclick="deleteDoc(${i})" title="Delete"><svg width="14" height="14" fill="currentColor"><path d="M5 1h4v1H5zm-2 2h8v10H3zm2 1v8h1V4zm2 0v8h1V4zm2 0v8h1V4z"/></svg></button>
       </td>
     </tr>`;
     }).join('');
@@ -406,19 +531,19 @@ function renderTableView() {
 
 function renderJSONView() {
     const container = $('#json-view');
-    container.innerHTML = documents.map(doc => {
-        const id = doc._id || doc.id || '';
+    container.innerHTML = documents.map((doc, i) => {
+        const id = docId(i);
         const sel = selected.has(id);
         return `<div class="json-card ${sel ? 'selected' : ''}">
       <div class="json-card-header">
         <div style="display:flex;align-items:center;gap:6px;">
-          <input type="checkbox" class="row-check" data-id="${id}" ${sel ? 'checked' : ''}>
-          <span class="json-card-id">${id}</span>
+          <input type="checkbox" class="row-check" data-id="${escapeHtmlAttr(id)}" ${sel ? 'checked' : ''}>
+          <span class="json-card-id">${escapeHtml(typeof doc._id === 'string' ? doc._id : id)}</span>
         </div>
         <div class="json-card-actions">
-          <button class="copy-btn" onclick="copyDocToClipboard(this, '${id}')" title="Copy as JSON"><svg width="14" height="14" fill="currentColor" viewBox="0 0 16 16"><path d="M4 1.5H3a2 2 0 0 0-2 2V14a2 2 0 0 0 2 2h10a2 2 0 0 0 2-2V3.5a2 2 0 0 0-2-2h-1v1h1a1 1 0 0 1 1 1V14a1 1 0 0 1-1 1H3a1 1 0 0 1-1-1V3.5a1 1 0 0 1 1-1h1v-1z"/><path d="M9.5 1a.5.5 0 0 1 .5.5v1a.5.5 0 0 1-.5.5h-3a.5.5 0 0 1-.5-.5v-1a.5.5 0 0 1 .5-.5h3zm-3-1A1.5 1.5 0 0 0 5 1.5v1A1.5 1.5 0 0 0 6.5 4h3A1.5 1.5 0 0 0 11 2.5v-1A1.5 1.5 0 0 0 9.5 0h-3z"/></svg></button>
-          <button class="action-btn edit" onclick="editDoc('${id}')" title="Edit"><svg width="14" height="14" fill="currentColor"><path d="M11 1l3 3L4 14H1v-3z"/></svg></button>
-          <button class="action-btn delete" onclick="deleteDoc('${id}')" title="Delete"><svg width="14" height="14" fill="currentColor"><path d="M5 1h4v1H5zm-2 2h8v10H3zm2 1v8h1V4zm2 0v8h1V4zm2 0v8h1V4z"/></svg></button>
+          <button class="copy-btn" onclick="copyDocToClipboard(this, ${i})" title="Copy as JSON"><svg width="14" height="14" fill="currentColor" viewBox="0 0 16 16"><path d="M4 1.5H3a2 2 0 0 0-2 2V14a2 2 0 0 0 2 2h10a2 2 0 0 0 2-2V3.5a2 2 0 0 0-2-2h-1v1h1a1 1 0 0 1 1 1V14a1 1 0 0 1-1 1H3a1 1 0 0 1-1-1V3.5a1 1 0 0 1 1-1h1v-1z"/><path d="M9.5 1a.5.5 0 0 1 .5.5v1a.5.5 0 0 1-.5.5h-3a.5.5 0 0 1-.5-.5v-1a.5.5 0 0 1 .5-.5h3zm-3-1A1.5 1.5 0 0 0 5 1.5v1A1.5 1.5 0 0 0 6.5 4h3A1.5 1.5 0 0 0 11 2.5v-1A1.5 1.5 0 0 0 9.5 0h-3z"/></svg></button>
+          <button class="action-btn edit" onclick="editDoc(${i})" title="Edit"><svg width="14" height="14" fill="currentColor"><path d="M11 1l3 3L4 14H1v-3z"/></svg></button>
+          <button class="action-btn delete" onclick="deleteDoc(${i})" title="Delete"><svg width="14" height="14" fill="currentColor"><path d="M5 1h4v1H5zm-2 2h8v10H3zm2 1v8h1V4zm2 0v8h1V4zm2 0v8h1V4z"/></svg></button>
         </div>
       </div>
       <div class="json-card-body">
@@ -444,8 +569,15 @@ function renderJSONView() {
     });
 }
 
-function copyDocToClipboard(btn, id) {
-    const doc = documents.find(d => (d._id || d.id) === id);
+// docId returns the _id of the i-th document the way the document
+// endpoints take it
+function docId(i) {
+    const doc = documents[i] || {};
+    return docIds[i] || String(doc._id || doc.id || '');
+}
+
+function copyDocToClipboard(btn, i) {
+    const doc = documents[i];
     if (!doc) return;
     
     const jsonStr = JSON.stringify(doc, null, 2);
@@ -521,7 +653,7 @@ function toggleSelectAll(e) {
     if (allChecked) {
         selected.clear();
     } else {
-        documents.forEach(doc => selected.add(doc._id || doc.id || ''));
+        documents.forEach((doc, i) => selected.add(docId(i)));
     }
     renderDocs();
 }
@@ -547,35 +679,104 @@ function updateBulkActions() {
     }
 }
 
-async function editDoc(id) {
-    const doc = documents.find(d => (d._id || d.id) === id);
-    if (!doc) return;
-    $('#doc-id').value = id;
-    $('#doc-json').value = JSON.stringify(doc, null, 2);
-    $('#modal-title').textContent = 'Edit Document';
-    openModal('doc-modal');
+function newDoc() {
+    $('#doc-id').value = '';
+    $('#doc-json').value = '{}';
+    $('#modal-title').textContent = 'Insert Document';
+    docOriginal = '';
+    $('#doc-patch').innerHTML = '';
+}
+
+async function editDoc(i) {
+    const id = docId(i);
+    try {
+        const params = new URLSearchParams({ database: currentDatabase });
+        const res = await fetch(`/api/collections/${currentCollection}/documents/${encodeURIComponent(id)}?${params}`);
+        const data = await res.json();
+        if (!data.success) throw new Error(data.message || 'Failed to load document');
+        $('#doc-id').value = data.data.id;
+        $('#doc-json').value = data.data.document;
+        docOriginal = data.data.document;
+        $('#modal-title').textContent = 'Edit Document';
+        $('#doc-patch').innerHTML = data.data.schema
+            ? `<div class="patch-note">Checked against the collection's $jsonSchema (validationLevel ${escapeHtml(data.data.validation_level)}, validationAction ${escapeHtml(data.data.validation_action)})</div>`
+            : '';
+        openModal('doc-modal');
+    } catch (err) {
+        showError('Failed to load document: ' + err.message);
+    }
 }
 
 async function saveDoc() {
     const id = $('#doc-id').value.trim();
     const json = $('#doc-json').value.trim();
     if (!json) return showError('Document cannot be empty');
+    if (id) return previewPatch(id, json);
 
     try {
         const doc = JSON.parse(json);
         const params = new URLSearchParams();
         if (currentDatabase) params.append('database', currentDatabase);
-        const url = id
-            ? `/api/collections/${currentCollection}/documents/${id}?${params}`
-            : `/api/collections/${currentCollection}/documents?${params}`;
-        const res = await fetch(url, {
-            method: id ? 'PUT' : 'POST',
+        const res = await fetch(`/api/collections/${currentCollection}/documents?${params}`, {
+            method: 'POST',
             headers: { 'Content-Type': 'application/json' },
             body: JSON.stringify(doc)
         });
         const data = await res.json();
-        if (!data.success) throw new Error(data.error || 'Save failed');
-        showSuccess(id ? 'Document updated' : 'Document inserted');
+        if (!data.success) throw new Error(data.message || 'Save failed');
+        showSuccess('Document inserted');
+        closeModals();
+        loadDocs();
+    } catch (err) {
+        showError('Failed to save: ' + err.message);
+    }
+}
+
+// patchDoc sends an edited document, which the server turns into the $set
+// and $unset of the changed fields; with dryRun it only reports them
+async function patchDoc(id, json, dryRun) {
+    const params = new URLSearchParams({ database: currentDatabase });
+    if (dryRun) params.append('dry_run', 'true');
+    const res = await fetch(`/api/collections/${currentCollection}/documents/${encodeURIComponent(id)}?${params}`, {
+        method: 'PATCH',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ document: json, original: docOriginal })
+    });
+    return { status: res.status, data: await res.json() };
+}
+
+async function previewPatch(id, json) {
+    const panel = $('#doc-patch');
+    try {
+        const { status, data } = await patchDoc(id, json, true);
+        if (status === 409) return showDocConflict(data);
+        if (!data.success) throw new Error(data.message || 'Save failed');
+        const patch = data.data;
+        if (!patch.set.length && !patch.unset.length) {
+            panel.innerHTML = '<div class="patch-note">No changes</div>';
+            return;
+        }
+        let html = '<div class="patch-ops">';
+        patch.set.forEach(f => { html += `<div class="patch-op set"><b>$set</b> ${escapeHtml(f.path)}: ${escapeHtml(JSON.stringify(f.value))}</div>`; });
+        patch.unset.forEach(p => { html += `<div class="patch-op unset"><b>$unset</b> ${escapeHtml(p)}</div>`; });
+        html += '</div>';
+        if (patch.errors.length) {
+            html += '<div class="patch-errors">' + patch.errors.map(e => `<div>${escapeHtml(e.path)} ${escapeHtml(e.message)}</div>`).join('') + '</div>';
+        }
+        html += '<button class="btn btn-primary" id="confirm-patch-btn">Confirm Save</button>';
+        panel.innerHTML = html;
+        $('#confirm-patch-btn').onclick = () => applyPatch(id, json);
+    } catch (err) {
+        panel.innerHTML = `<div class="patch-errors">${escapeHtml(err.message)}</div>`;
+    }
+}
+
+async function applyPatch(id, json) {
+    try {
+        const { status, data } = await patchDoc(id, json, false);
+        if (status === 409) return showDocConflict(data);
+        if (!data.success) throw new Error(data.message || 'Save failed');
+        showSuccess(data.data.errors.length ? 'Document updated, with schema warnings' : 'Document updated');
         closeModals();
         loadDocs();
     } catch (err) {
@@ -583,11 +784,23 @@ async function saveDoc() {
     }
 }
 
-async function deleteDoc(id) {
+function showDocConflict(data) {
+    const panel = $('#doc-patch');
+    panel.innerHTML = `<div class="patch-errors">${escapeHtml(data.message)}</div>` +
+        '<button class="btn" id="load-current-btn">Load current version</button>';
+    $('#load-current-btn').onclick = () => {
+        // Edits made here are dropped for the stored document
+        $('#doc-json').value = data.data.current;
+        docOriginal = data.data.current;
+        panel.innerHTML = '';
+    };
+}
+
+async function deleteDoc(i) {
     if (!confirm('Delete this document?')) return;
     try {
         const params = new URLSearchParams({ database: currentDatabase });
-        const res = await fetch(`/api/collections/${currentCollection}/documents/${id}?${params}`, { method: 'DELETE' });
+        const res = await fetch(`/api/collections/${currentCollection}/documents/${encodeURIComponent(docId(i))}?${params}`, { method: 'DELETE' });
         if (!res.ok) throw new Error(await res.text());
         showSuccess('Document deleted');
         loadDocs();
@@ -615,6 +828,67 @@ async function bulkDelete() {
     }
 }
 
+// exportDatabase downloads the collections of the current database in the
+// export format SQL databases share
+async function exportDatabase(exportType) {
+    try {
+        const params = new URLSearchParams({ database: currentDatabase });
+        const res = await fetch(`/api/export/${exportType}?${params}`);
+        const data = await res.json();
+        if (!data.success) throw new Error(data.message || 'Export failed');
+
+        const blob = new Blob([JSON.stringify(data.data, null, 2)], { type: 'application/json' });
+        const url = URL.createObjectURL(blob);
+        const a = document.createElement('a');
+        a.href = url;
+        const timestamp = new Date().toISOString().replace(/[:.]/g, '-').slice(0, 19);
+        a.download = `${currentDatabase}_export_${exportType}_${timestamp}.json`;
+        document.body.appendChild(a);
+        a.click();
+        document.body.removeChild(a);
+        URL.revokeObjectURL(url);
+
+        closeModals();
+        showSuccess(`Exported ${data.data.collections.length} collection(s)`);
+    } catch (err) {
+        showError('Export failed: ' + err.message);
+    }
+}
+
+async function importDatabase(file, verify) {
+    if (!file) return;
+    const out = $('#import-result');
+    out.innerHTML = '<div class="patch-note">Importing...</div>';
+    try {
+        const body = await file.text();
+        const params = new URLSearchParams({ database: currentDatabase });
+        if (verify) params.append('verify', 'true');
+        const res = await fetch(`/api/import?${params}`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body
+        });
+        const data = await res.json();
+        if (!data.success) {
+            const checks = (data.data && data.data.collections) || [];
+            out.innerHTML = `<div class="patch-errors">${escapeHtml(data.message || 'Import failed')}</div>` +
+                checks.map(c => `<div class="patch-errors">${escapeHtml(c.table)}: ${escapeHtml(c.problem)}</div>`).join('');
+            return;
+        }
+        const r = data.result;
+        const lines = [`Documents inserted: ${r.rows_inserted}`, `Documents replaced: ${r.rows_updated}`];
+        if (r.collections_created && r.collections_created.length) lines.push(`Collections created: ${r.collections_created.join(', ')}`);
+        if (r.indexes_created) lines.push(`Indexes created: ${r.indexes_created}`);
+        if (r.verified) lines.push('Checksums verified');
+        out.innerHTML = `<div class="patch-ops">${lines.map(l => `<div>${escapeHtml(l)}</div>`).join('')}</div>` +
+            (r.errors || []).map(e => `<div class="patch-errors">${escapeHtml(e)}</div>`).join('');
+        showSuccess('Import completed');
+        loadCollections();
+    } catch (err) {
+        out.innerHTML = `<div class="patch-errors">${escapeHtml(err.message)}</div>`;
+    }
+}
+
 async function createCollection(name) {
     if (!name) return;
     try {
@@ -746,6 +1020,118 @@ function renderSchema(schema) {
     `).join('');
 }
 
+// Schema diagram of the current database: collections with their inferred
+// fields, and the references inferred between them
+async function loadDiagram() {
+    const canvas = $('#diagram-canvas');
+    canvas.innerHTML = '<div class="loading"><div class="spinner"></div></div>';
+    try {
+        const params = new URLSearchParams({ database: currentDatabase });
+        const res = await fetch(`/api/schema?${params}`);
+        const data = await res.json();
+        if (!data.success) throw new Error(data.message || 'Failed to load schema');
+        renderDiagram(data.data);
+    } catch (err) {
+        canvas.innerHTML = '';
+        showError('Failed to load schema diagram: ' + err.message);
+    }
+}
+
+function renderDiagram(schema) {
+    const canvas = $('#diagram-canvas');
+    if (!schema.nodes.length) {
+        canvas.innerHTML = '<div class="patch-note">No collections</div>';
+        return;
+    }
+    canvas.innerHTML = '<svg class="diagram-edges"></svg>' + schema.nodes.map(node => `
+        <div class="diagram-node" data-id="${escapeHtmlAttr(node.id)}" data-name="${escapeHtmlAttr(node.data.label)}"
+            style="left:${node.position.x}px;top:${node.position.y}px">
+            <div class="diagram-node-header" title="${node.data.sampled} document(s) sampled">${escapeHtml(node.data.label)}</div>
+            ${node.data.columns.map(col => `
+                <div class="diagram-field${col.isPrimary ? ' primary' : ''}${col.isForeign ? ' foreign' : ''}" data-field="${escapeHtmlAttr(col.name)}"
+                    title="${col.isForeign ? escapeHtmlAttr('references ' + col.foreignKeyTable + '._id') : ''}">
+                    <span>${escapeHtml(col.name)}${col.nullable ? '?' : ''}</span>
+                    <code>${escapeHtml(col.type)}</code>
+                </div>
+            `).join('')}
+        </div>
+    `).join('');
+
+    // Collections are laid out in a grid; push down those a longer one
+    // above them would cover
+    const bottoms = {};
+    $$('#diagram-canvas .diagram-node').forEach(node => {
+        const top = Math.max(node.offsetTop, bottoms[node.offsetLeft] || 0);
+        node.style.top = top + 'px';
+        bottoms[node.offsetLeft] = top + node.offsetHeight + 40;
+    });
+
+    $$('#diagram-canvas .diagram-node').forEach(node => {
+        node.ondblclick = () => { closeModals(); selectCollection(node.dataset.name) };
+        node.querySelector('.diagram-node-header').onmousedown = (e) => dragDiagramNode(e, node, schema.edges);
+    });
+    drawDiagramEdges(schema.edges);
+}
+
+// dragDiagramNode moves a collection with the mouse, redrawing the edges
+function dragDiagramNode(e, node, edges) {
+    e.preventDefault();
+    const startX = e.clientX - node.offsetLeft;
+    const startY = e.clientY - node.offsetTop;
+    const move = (ev) => {
+        node.style.left = Math.max(0, ev.clientX - startX) + 'px';
+        node.style.top = Math.max(0, ev.clientY - startY) + 'px';
+        drawDiagramEdges(edges);
+    };
+    const up = () => {
+        document.removeEventListener('mousemove', move);
+        document.removeEventListener('mouseup', up);
+    };
+    document.addEventListener('mousemove', move);
+    document.addEventListener('mouseup', up);
+}
+
+// drawDiagramEdges draws each reference from its field to the _id of the
+// collection it refers to
+function drawDiagramEdges(edges) {
+    const canvas = $('#diagram-canvas');
+    const svg = canvas.querySelector('.diagram-edges');
+    svg.setAttribute('width', canvas.scrollWidth);
+    svg.setAttribute('height', canvas.scrollHeight);
+
+    const anchor = (nodeId, field) => {
+        const node = canvas.querySelector(`.diagram-node[data-id="${CSS.escape(nodeId)}"]`);
+        if (!node) return null;
+        const row = node.querySelector(`.diagram-field[data-field="${CSS.escape(field)}"]`) || node;
+        return {
+            left: node.offsetLeft,
+            right: node.offsetLeft + node.offsetWidth,
+            y: node.offsetTop + row.offsetTop + row.offsetHeight / 2
+        };
+    };
+
+    svg.innerHTML = edges.map(edge => {
+        const from = anchor(edge.source, edge.sourceHandle);
+        const to = anchor(edge.target, edge.targetHandle);
+        if (!from || !to) return '';
+        let path;
+        if (edge.source === edge.target) {
+            // A collection referring to itself loops around its right side
+            path = `M ${from.right} ${from.y} C ${from.right + 60} ${from.y}, ${to.right + 60} ${to.y}, ${to.right} ${to.y}`;
+        } else {
+            const forward = from.right <= to.left;
+            const x1 = forward ? from.right : from.left;
+            const x2 = forward ? to.left : to.right;
+            const bend = Math.max(40, Math.abs(x2 - x1) / 2) * (forward ? 1 : -1);
+            path = `M ${x1} ${from.y} C ${x1 + bend} ${from.y}, ${x2 - bend} ${to.y}, ${x2} ${to.y}`;
+        }
+        const how = edge.byName ? 'by name and values' : 'by values';
+        return `<path d="${path}" class="diagram-edge${edge.byName ? '' : ' by-values'}">
+            <title>${escapeHtml(edge.label)}: ${edge.matched} of ${edge.sampled} sampled value(s) found, inferred ${how}</title>
+        </path>`;
+    }).join('');
+}
+
 // Indexes functionality
 async function loadIndexes() {
     if (!currentCollection) return;
@@ -0,0 +1,109 @@
+package studio
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/mongodb"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/redis"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/sql"
+)
+
+// storeTimeout bounds how long the overview waits for one store
+const storeTimeout = 5 * time.Second
+
+// Store is a data store the overview shows
+type Store struct {
+	Name     string
+	Provider string
+	URL      string
+	// Primary is the store this studio session browses
+	Primary bool
+	// Err is why the store's URL is unknown
+	Err error
+}
+
+// ProjectStores returns the stores of a project for the overview: the
+// session's own store first, then the configured database and the stores
+// of studio.stores that aren't it. cfg may be nil when there's no config.
+func ProjectStores(session Store, cfg *config.Config) []Store {
+	session.Primary = true
+	stores := []Store{session}
+	if cfg == nil {
+		return stores
+	}
+
+	configured := make([]Store, 0, len(cfg.Studio.Stores)+1)
+	if cfg.Database.URLEnv != "" {
+		configured = append(configured, Store{Name: "database", Provider: cfg.Database.Provider, URL: os.Getenv(cfg.Database.URLEnv)})
+		if configured[0].URL == "" {
+			configured[0].Err = fmt.Errorf("%s is not set", cfg.Database.URLEnv)
+		}
+	}
+	for _, store := range cfg.Studio.Stores {
+		name := store.Name
+		if name == "" {
+			name = store.Provider
+		}
+		url := os.Getenv(store.URLEnv)
+		var err error
+		if url == "" {
+			err = fmt.Errorf("%s is not set", store.URLEnv)
+		}
+		configured = append(configured, Store{Name: name, Provider: store.Provider, URL: url, Err: err})
+	}
+
+	for _, store := range configured {
+		if store.URL == session.URL {
+			// The session's store goes by its configured name
+			if store.Name != "database" {
+				stores[0].Name = store.Name
+			}
+			continue
+		}
+		stores = append(stores, store)
+	}
+	return stores
+}
+
+// Overview returns a function collecting the overview of the stores,
+// each over a connection of its own, all at once
+func Overview(stores []Store) func(ctx context.Context) []common.StoreOverview {
+	return func(ctx context.Context) []common.StoreOverview {
+		overviews := make([]common.StoreOverview, len(stores))
+		var wg sync.WaitGroup
+		for i, store := range stores {
+			wg.Add(1)
+			go func(i int, store Store) {
+				defer wg.Done()
+				storeCtx, cancel := context.WithTimeout(ctx, storeTimeout)
+				defer cancel()
+				overviews[i] = storeOverview(storeCtx, store)
+			}(i, store)
+		}
+		wg.Wait()
+		return overviews
+	}
+}
+
+func storeOverview(ctx context.Context, store Store) common.StoreOverview {
+	var overview common.StoreOverview
+	switch {
+	case store.Err != nil:
+		overview = common.StoreOverview{Provider: store.Provider, Error: store.Err.Error(), Activity: []common.StoreActivity{}}
+	case store.Provider == "redis":
+		overview = redis.StoreOverview(ctx, store.URL)
+	case store.Provider == "mongodb" || store.Provider == "mongo":
+		overview = mongodb.StoreOverview(ctx, store.URL)
+	default:
+		overview = sql.StoreOverview(ctx, store.Provider, store.URL)
+	}
+	overview.Name = store.Name
+	overview.Primary = store.Primary
+	return overview
+}
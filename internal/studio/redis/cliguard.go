@@ -0,0 +1,253 @@
+package redis
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/state"
+)
+
+// DefaultConfirmCommands are the commands the CLI runs only once confirmed:
+// the ones that wipe data, change or stop the server, or cut off clients,
+// and the ones running scripts and functions, which can call any of them
+var DefaultConfirmCommands = []string{
+	"FLUSHALL", "FLUSHDB", "SHUTDOWN", "DEBUG", "SWAPDB", "MIGRATE",
+	"EVAL", "EVALSHA", "EVAL_RO", "FCALL", "SCRIPT LOAD",
+	"CONFIG SET", "CONFIG REWRITE", "CONFIG RESETSTAT",
+	"REPLICAOF", "SLAVEOF", "FAILOVER", "CLUSTER RESET", "CLUSTER FAILOVER",
+	"SCRIPT FLUSH", "FUNCTION FLUSH", "FUNCTION DELETE", "FUNCTION RESTORE",
+	"CLIENT KILL", "ACL SETUSER", "ACL DELUSER", "ACL LOAD", "ACL SAVE",
+	"MODULE LOAD", "MODULE UNLOAD",
+}
+
+const (
+	// confirmationTTL is how long a confirmation token stays valid
+	confirmationTTL = 2 * time.Minute
+	// maxAuditEntries bounds the audit entries kept in memory, and those
+	// shown from the state store
+	maxAuditEntries = 500
+	// storedAuditEntries bounds the audit entries kept in the state store
+	storedAuditEntries = 5000
+)
+
+// CLIPolicy restricts the commands of the CLI. Each entry is a command, or a
+// command and its subcommand such as "CONFIG SET"; a command alone covers
+// all its subcommands. Denied commands are refused, and commands to confirm
+// only run with a token the refusal handed out. AuditLog, when set, is a
+// file the guarded commands are appended to as JSON lines. AuditState, when
+// set, is the migrations path of the project whose state store keeps the
+// audit trail, so it outlasts restarts.
+type CLIPolicy struct {
+	Deny       []string
+	Confirm    []string
+	AuditLog   string
+	AuditState string
+}
+
+// CommandDeniedError refuses a command the policy denies
+type CommandDeniedError struct {
+	Rule string
+}
+
+func (e *CommandDeniedError) Error() string {
+	return fmt.Sprintf("%s is disabled in this studio", e.Rule)
+}
+
+// ConfirmationRequiredError refuses a dangerous command until it's sent
+// again with the token
+type ConfirmationRequiredError struct {
+	Rule  string
+	Token string
+}
+
+func (e *ConfirmationRequiredError) Error() string {
+	return fmt.Sprintf("%s needs confirmation", e.Rule)
+}
+
+// AuditEntry records a guarded command: "denied", or "executed" once
+// confirmed, with the error it ran into
+type AuditEntry struct {
+	Time    time.Time `json:"time"`
+	Client  string    `json:"client"`
+	DB      int       `json:"db"`
+	Command string    `json:"command"`
+	Rule    string    `json:"rule"`
+	Outcome string    `json:"outcome"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// cliGuard applies a CLIPolicy and keeps the audit trail of the commands it
+// guards
+type cliGuard struct {
+	deny       []string
+	confirm    []string
+	auditLog   string
+	auditState string
+
+	mu sync.Mutex
+	// tokens maps a confirmation token to the command it confirms
+	tokens  map[string]pendingCommand
+	entries []AuditEntry
+}
+
+type pendingCommand struct {
+	command string
+	expires time.Time
+}
+
+func newCLIGuard(policy CLIPolicy) *cliGuard {
+	return &cliGuard{
+		deny:       normalizeRules(policy.Deny),
+		confirm:    normalizeRules(policy.Confirm),
+		auditLog:   policy.AuditLog,
+		auditState: policy.AuditState,
+		tokens:     map[string]pendingCommand{},
+	}
+}
+
+func normalizeRules(rules []string) []string {
+	normalized := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		if rule = strings.Join(strings.Fields(strings.ToUpper(rule)), " "); rule != "" {
+			normalized = append(normalized, rule)
+		}
+	}
+	return normalized
+}
+
+// matchRule returns the rule of a list a command falls under, if any
+func matchRule(rules []string, parts []string) string {
+	name := strings.ToUpper(parts[0])
+	sub := ""
+	if len(parts) > 1 {
+		sub = name + " " + strings.ToUpper(parts[1])
+	}
+	for _, rule := range rules {
+		if rule == name || (sub != "" && rule == sub) {
+			return rule
+		}
+	}
+	return ""
+}
+
+// check decides whether a command may run. Denied commands and commands to
+// confirm without a valid token return an error; the rule is returned for
+// commands that run confirmed, so they can be audited.
+func (g *cliGuard) check(command string, parts []string, token string) (string, error) {
+	if rule := matchRule(g.deny, parts); rule != "" {
+		return rule, &CommandDeniedError{Rule: rule}
+	}
+	rule := matchRule(g.confirm, parts)
+	if rule == "" {
+		return "", nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	for t, p := range g.tokens {
+		if now.After(p.expires) {
+			delete(g.tokens, t)
+		}
+	}
+	if p, ok := g.tokens[token]; ok && token != "" && p.command == command {
+		// A token confirms one run
+		delete(g.tokens, token)
+		return rule, nil
+	}
+
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return rule, err
+	}
+	issued := hex.EncodeToString(b[:])
+	g.tokens[issued] = pendingCommand{command: command, expires: now.Add(confirmationTTL)}
+	return rule, &ConfirmationRequiredError{Rule: rule, Token: issued}
+}
+
+// record adds an entry to the audit trail, in the state store when there
+// is one, and to the audit log file when there is one
+func (g *cliGuard) record(entry AuditEntry) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.entries = append(g.entries, entry)
+	if len(g.entries) > maxAuditEntries {
+		g.entries = g.entries[len(g.entries)-maxAuditEntries:]
+	}
+
+	if g.auditState != "" {
+		if err := appendAuditEntry(g.auditState, entry); err != nil {
+			fmt.Printf("Warning: failed to record audit entry: %v\n", err)
+		}
+	}
+
+	if g.auditLog == "" {
+		return
+	}
+	line, _ := json.Marshal(entry)
+	f, err := os.OpenFile(g.auditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		fmt.Printf("Warning: failed to write audit log: %v\n", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		fmt.Printf("Warning: failed to write audit log: %v\n", err)
+	}
+}
+
+// audit returns the audit trail, newest first. With a state store it holds
+// the entries of earlier runs too.
+func (g *cliGuard) audit() []AuditEntry {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.auditState != "" {
+		entries, err := loadAuditEntries(g.auditState)
+		if err == nil {
+			return entries
+		}
+		fmt.Printf("Warning: failed to read audit entries: %v\n", err)
+	}
+	entries := make([]AuditEntry, len(g.entries))
+	for i, entry := range g.entries {
+		entries[len(entries)-1-i] = entry
+	}
+	return entries
+}
+
+func appendAuditEntry(migrationsPath string, entry AuditEntry) error {
+	st, err := state.Open(migrationsPath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	return st.Append(state.KindAudit, entry, storedAuditEntries)
+}
+
+func loadAuditEntries(migrationsPath string) ([]AuditEntry, error) {
+	st, err := state.Open(migrationsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer st.Close()
+
+	stored, err := st.Entries(state.KindAudit, maxAuditEntries)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]AuditEntry, 0, len(stored))
+	for _, e := range stored {
+		var entry AuditEntry
+		if err := json.Unmarshal(e.Value, &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse audit entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,37 @@
+package redis
+
+import (
+	"errors"
+	"testing"
+)
+
+// Scripts and functions can run any command, so they need confirmation
+// like the commands they could call
+func TestDefaultPolicyConfirmsScripts(t *testing.T) {
+	guard := newCLIGuard(CLIPolicy{Confirm: DefaultConfirmCommands})
+	for _, command := range []string{
+		`EVAL "return redis.call('FLUSHALL')" 0`,
+		`eval "return redis.call('FLUSHALL')" 0`,
+		`EVALSHA e0e1f9fabfc9d4800c877a703b823ac0578ff8db 0`,
+		`EVAL_RO "return redis.call('GET', KEYS[1])" 1 key`,
+		`FCALL wipe 0`,
+		`SCRIPT LOAD "return redis.call('FLUSHALL')"`,
+	} {
+		parts := parseCommand(command)
+		_, err := guard.check(command, parts, "")
+		var confirm *ConfirmationRequiredError
+		if !errors.As(err, &confirm) {
+			t.Errorf("%s ran without confirmation: %v", command, err)
+			continue
+		}
+		if rule, err := guard.check(command, parts, confirm.Token); err != nil || rule != confirm.Rule {
+			t.Errorf("%s wasn't confirmed by its token: %v", command, err)
+		}
+	}
+
+	for _, command := range []string{"GET key", "SCRIPT EXISTS e0e1f9fabfc9d4800c877a703b823ac0578ff8db"} {
+		if _, err := guard.check(command, parseCommand(command), ""); err != nil {
+			t.Errorf("%s needs confirmation: %v", command, err)
+		}
+	}
+}
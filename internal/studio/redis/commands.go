@@ -0,0 +1,383 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// commandChunk bounds the elements written per command, so huge
+	// collections don't become one huge command
+	commandChunk = 1000
+	// importBatch is how many imported commands are pipelined at once
+	importBatch = 1000
+	// maxImportErrors bounds the failed commands an import reports
+	maxImportErrors = 100
+	// maxBulkLength bounds a bulk string read from a command file, as
+	// Redis's own proto-max-bulk-len does
+	maxBulkLength = 512 << 20
+	// maxCommandArgs bounds the arguments of a RESP command read from a
+	// command file, as Redis bounds a multibulk's length
+	maxCommandArgs = 1 << 20
+)
+
+// importCommands are the commands a command file may hold: the ones that
+// write keys, which a dump is made of. The number is the position of the
+// key, and 0 marks commands whose arguments are all keys.
+var importCommands = map[string]int{
+	"SET": 1, "SETEX": 1, "PSETEX": 1, "APPEND": 1, "PFADD": 1,
+	"HSET": 1, "HMSET": 1, "RPUSH": 1, "LPUSH": 1, "SADD": 1, "ZADD": 1,
+	"XADD": 1, "XGROUP": 2, "JSON.SET": 1,
+	"EXPIRE": 1, "PEXPIRE": 1, "EXPIREAT": 1, "PEXPIREAT": 1, "PERSIST": 1,
+	"DEL": 0, "UNLINK": 0,
+}
+
+// CommandImport is the outcome of importing a command file
+type CommandImport struct {
+	Commands int            `json:"commands"`
+	Keys     int            `json:"keys"`
+	Skipped  int            `json:"skipped"`
+	Errors   []CommandError `json:"errors"`
+}
+
+// CommandError is a command of a file that failed
+type CommandError struct {
+	Line    int    `json:"line"`
+	Command string `json:"command"`
+	Error   string `json:"error"`
+}
+
+// ExportCommands writes the keys matching a pattern as Redis commands in
+// RESP, the format redis-cli --pipe sends: DEL and the commands that
+// recreate each key, then EXPIRE for keys with a TTL
+func (s *Service) ExportCommands(ctx context.Context, pattern string, w io.Writer) (int, error) {
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	out := bufio.NewWriter(w)
+	exported := 0
+	var cursor uint64
+	for {
+		keys, next, err := s.client.Scan(ctx, cursor, pattern, 100).Result()
+		if err != nil {
+			return exported, err
+		}
+		for _, key := range keys {
+			keyInfo, err := s.GetKey(ctx, key)
+			if err != nil {
+				// Deleted since the scan
+				continue
+			}
+			commands, err := keyCommands(keyInfo)
+			if err != nil {
+				return exported, err
+			}
+			for _, args := range commands {
+				writeCommand(out, args)
+			}
+			exported++
+		}
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+	return exported, out.Flush()
+}
+
+// keyCommands returns the commands that recreate a key
+func keyCommands(key *KeyInfo) ([][]string, error) {
+	var commands [][]string
+	// chunked adds a command per chunk of a collection's arguments
+	chunked := func(cmd string, args []string, per int) {
+		for len(args) > 0 {
+			n := min(len(args), commandChunk*per)
+			commands = append(commands, append([]string{cmd, key.Key}, args[:n]...))
+			args = args[n:]
+		}
+	}
+
+	switch key.Type {
+	case "string":
+		commands = append(commands, []string{"SET", key.Key, key.Value.(string)})
+
+	case redisJSONType:
+		commands = append(commands, []string{"DEL", key.Key}, []string{"JSON.SET", key.Key, "$", key.Value.(string)})
+
+	case "list", "set":
+		cmd := "RPUSH"
+		if key.Type == "set" {
+			cmd = "SADD"
+		}
+		commands = append(commands, []string{"DEL", key.Key})
+		chunked(cmd, key.Value.([]string), 1)
+
+	case "hash":
+		hash := key.Value.(map[string]string)
+		fields := make([]string, 0, len(hash))
+		for field := range hash {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		args := make([]string, 0, len(hash)*2)
+		for _, field := range fields {
+			args = append(args, field, hash[field])
+		}
+		commands = append(commands, []string{"DEL", key.Key})
+		chunked("HSET", args, 2)
+
+	case "zset":
+		members := key.Value.([]map[string]interface{})
+		args := make([]string, 0, len(members)*2)
+		for _, m := range members {
+			args = append(args, strconv.FormatFloat(m["score"].(float64), 'g', -1, 64), fmt.Sprint(m["member"]))
+		}
+		commands = append(commands, []string{"DEL", key.Key})
+		chunked("ZADD", args, 2)
+
+	case "stream":
+		commands = append(commands, []string{"DEL", key.Key})
+		for _, msg := range key.Value.([]redis.XMessage) {
+			fields := make([]string, 0, len(msg.Values))
+			for field := range msg.Values {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+			args := []string{"XADD", key.Key, msg.ID}
+			for _, field := range fields {
+				args = append(args, field, fmt.Sprint(msg.Values[field]))
+			}
+			commands = append(commands, args)
+		}
+
+	default:
+		return nil, fmt.Errorf("can't export %s: unsupported type %s", key.Key, key.Type)
+	}
+
+	if key.TTL > 0 {
+		commands = append(commands, []string{"EXPIRE", key.Key, strconv.FormatInt(key.TTL, 10)})
+	}
+	return commands, nil
+}
+
+// writeCommand writes a command as a RESP array of bulk strings
+func writeCommand(w *bufio.Writer, args []string) {
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+}
+
+// ImportCommands runs the commands of a file such as ExportCommands or
+// redis-cli --pipe input writes, in RESP or one inline command per line.
+// Only commands that write keys are accepted. Without overwrite, the
+// commands of keys that existed before the import are skipped.
+func (s *Service) ImportCommands(ctx context.Context, r io.Reader, overwrite bool) (*CommandImport, error) {
+	reader := &commandReader{r: bufio.NewReader(r)}
+	result := &CommandImport{Errors: []CommandError{}}
+	// Whether each key of the file gets written
+	keys := map[string]bool{}
+
+	type pending struct {
+		line int
+		args []string
+		cmd  *redis.Cmd
+	}
+	var batch []pending
+	pipe := s.client.Pipeline()
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		// Failures are per command and reported below
+		pipe.Exec(ctx)
+		for _, p := range batch {
+			if err := p.cmd.Err(); err != nil && !errors.Is(err, redis.Nil) {
+				result.addError(p.line, p.args, err)
+			} else {
+				result.Commands++
+			}
+		}
+		batch = batch[:0]
+		return ctx.Err()
+	}
+
+	for {
+		line, args, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		name := strings.ToUpper(args[0])
+		position, ok := importCommands[name]
+		if !ok {
+			result.addError(line, args, fmt.Errorf("%s isn't a command that writes keys", name))
+			continue
+		}
+		commandKeys := args[1:]
+		if position > 0 {
+			if len(args) <= position {
+				result.addError(line, args, fmt.Errorf("wrong number of arguments"))
+				continue
+			}
+			commandKeys = args[position : position+1]
+		}
+
+		write := true
+		for _, key := range commandKeys {
+			allowed, seen := keys[key]
+			if !seen {
+				allowed = overwrite
+				if !allowed {
+					exists, err := s.client.Exists(ctx, key).Result()
+					if err != nil {
+						return result, err
+					}
+					allowed = exists == 0
+				}
+				keys[key] = allowed
+				if allowed {
+					result.Keys++
+				} else {
+					result.Skipped++
+				}
+			}
+			write = write && allowed
+		}
+		if !write {
+			continue
+		}
+
+		cmdArgs := make([]interface{}, len(args))
+		for i, arg := range args {
+			cmdArgs[i] = arg
+		}
+		batch = append(batch, pending{line: line, args: args, cmd: pipe.Do(ctx, cmdArgs...)})
+		if len(batch) >= importBatch {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	err := flush()
+	// Rejected commands are reported as they're read, failed ones as their
+	// batch runs
+	sort.SliceStable(result.Errors, func(i, j int) bool { return result.Errors[i].Line < result.Errors[j].Line })
+	return result, err
+}
+
+func (c *CommandImport) addError(line int, args []string, err error) {
+	if len(c.Errors) >= maxImportErrors {
+		return
+	}
+	command := strings.Join(args, " ")
+	if len(command) > 100 {
+		command = command[:100] + "..."
+	}
+	c.Errors = append(c.Errors, CommandError{Line: line, Command: command, Error: err.Error()})
+}
+
+// commandReader reads commands from a file, in RESP or inline, counting
+// lines so errors can point at them
+type commandReader struct {
+	r    *bufio.Reader
+	line int
+}
+
+// next returns the next command and the line it starts on
+func (c *commandReader) next() (int, []string, error) {
+	for {
+		text, err := c.readLine()
+		if err != nil {
+			if err == io.EOF && text != "" {
+				// A last inline command without a newline
+				err = nil
+			} else {
+				return c.line, nil, err
+			}
+		}
+		start := c.line
+
+		if strings.HasPrefix(text, "*") {
+			n, err := strconv.Atoi(text[1:])
+			if err != nil || n < 1 || n > maxCommandArgs {
+				return start, nil, fmt.Errorf("invalid RESP array header %q", text)
+			}
+			// The header is only a claim, so args grow as they arrive
+			args := make([]string, 0, min(n, commandChunk))
+			for range n {
+				arg, err := c.readBulk()
+				if err != nil {
+					return start, nil, err
+				}
+				args = append(args, arg)
+			}
+			return start, args, nil
+		}
+
+		if args := parseCommand(strings.TrimSpace(text)); len(args) > 0 {
+			return start, args, nil
+		}
+		if err != nil {
+			return start, nil, err
+		}
+	}
+}
+
+// readBulk reads a RESP bulk string, which may hold any bytes
+func (c *commandReader) readBulk() (string, error) {
+	header, err := c.readLine()
+	if err != nil {
+		return "", unexpectedEOF(err)
+	}
+	if !strings.HasPrefix(header, "$") {
+		return "", fmt.Errorf("expected a RESP bulk string, got %q", header)
+	}
+	n, err := strconv.Atoi(header[1:])
+	if err != nil || n < 0 || n > maxBulkLength {
+		return "", fmt.Errorf("invalid RESP bulk length %q", header)
+	}
+	// Copied rather than read into a buffer of n bytes, so a file claiming
+	// a huge string only takes the memory of what it holds
+	var data strings.Builder
+	if _, err := io.CopyN(&data, c.r, int64(n)); err != nil {
+		return "", unexpectedEOF(err)
+	}
+	var crlf [2]byte
+	if _, err := io.ReadFull(c.r, crlf[:]); err != nil {
+		return "", unexpectedEOF(err)
+	}
+	if string(crlf[:]) != "\r\n" {
+		return "", fmt.Errorf("RESP bulk string isn't followed by CRLF")
+	}
+	c.line += strings.Count(data.String(), "\n") + 1
+	return data.String(), nil
+}
+
+// readLine reads a line without its line ending
+func (c *commandReader) readLine() (string, error) {
+	text, err := c.r.ReadString('\n')
+	if err == nil || text != "" {
+		c.line++
+	}
+	return strings.TrimRight(text, "\r\n"), err
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
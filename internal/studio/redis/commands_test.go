@@ -0,0 +1,93 @@
+package redis
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCommandReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  [][]string
+		lines []int
+		err   string
+	}{
+		{
+			name:  "inline",
+			input: "SET a 1\n\nHSET h f \"two words\"\n",
+			want:  [][]string{{"SET", "a", "1"}, {"HSET", "h", "f", "two words"}},
+			lines: []int{1, 3},
+		},
+		{
+			name:  "RESP",
+			input: "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$5\r\na\nb\r\n\r\nDEL k\n",
+			want:  [][]string{{"SET", "k", "a\nb\r\n"}, {"DEL", "k"}},
+			lines: []int{1, 10},
+		},
+		{
+			name:  "last inline command without a newline",
+			input: "SET a 1",
+			want:  [][]string{{"SET", "a", "1"}},
+			lines: []int{1},
+		},
+		{
+			name:  "array header past the argument limit",
+			input: "*100000000000\r\n$3\r\nSET\r\n",
+			err:   "invalid RESP array header",
+		},
+		{
+			name:  "array header claiming more than the file holds",
+			input: "*1000000\r\n$3\r\nSET\r\n",
+			err:   io.ErrUnexpectedEOF.Error(),
+		},
+		{
+			name:  "bulk length past the limit",
+			input: "*1\r\n$999999999999\r\n",
+			err:   "invalid RESP bulk length",
+		},
+		{
+			name:  "bulk string claiming more than the file holds",
+			input: "*1\r\n$536870912\r\nSET\r\n",
+			err:   io.ErrUnexpectedEOF.Error(),
+		},
+		{
+			name:  "bulk string without CRLF",
+			input: "*1\r\n$3\r\nSETX\r\n",
+			err:   "isn't followed by CRLF",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &commandReader{r: bufio.NewReader(strings.NewReader(tt.input))}
+			var got [][]string
+			var lines []int
+			for {
+				line, args, err := reader.next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					if tt.err == "" || !strings.Contains(err.Error(), tt.err) {
+						t.Fatalf("got error %v, want %q", err, tt.err)
+					}
+					return
+				}
+				got = append(got, args)
+				lines = append(lines, line)
+			}
+			if tt.err != "" {
+				t.Fatalf("got %q, want error %q", got, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(lines, tt.lines) {
+				t.Errorf("commands start on lines %v, want %v", lines, tt.lines)
+			}
+		})
+	}
+}
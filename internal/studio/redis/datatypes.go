@@ -0,0 +1,342 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// hyperLogLogMagic starts every HyperLogLog, which Redis stores as a string
+const hyperLogLogMagic = "HYLL"
+
+const (
+	// maxStreamEntries bounds the entries of a stream shown at once
+	maxStreamEntries = 1000
+	// maxBitmapBits bounds the bits of a bitmap shown at once
+	maxBitmapBits = 8192
+	// maxBitfieldValues bounds the integers of a bitfield read at once
+	maxBitfieldValues = 1024
+)
+
+var bitfieldTypePattern = regexp.MustCompile(`^[iu]([1-9]|[1-5][0-9]|6[0-4])$`)
+
+// StreamInfo is a stream with its consumer groups and latest entries
+type StreamInfo struct {
+	Key             string        `json:"key"`
+	Length          int64         `json:"length"`
+	FirstID         string        `json:"first_id,omitempty"`
+	LastID          string        `json:"last_id,omitempty"`
+	LastGeneratedID string        `json:"last_generated_id"`
+	EntriesAdded    int64         `json:"entries_added"`
+	Groups          []StreamGroup `json:"groups"`
+	Entries         []StreamEntry `json:"entries"`
+	// Truncated is set when the stream has more entries than are shown
+	Truncated bool `json:"truncated"`
+}
+
+// StreamGroup is a consumer group of a stream
+type StreamGroup struct {
+	Name            string `json:"name"`
+	LastDeliveredID string `json:"last_delivered_id"`
+	EntriesRead     int64  `json:"entries_read"`
+	Pending         int64  `json:"pending"`
+	// Lag is how many entries the group has yet to be delivered, -1 when
+	// Redis can't tell (e.g. after entries were deleted) or is older than 7
+	Lag       int64            `json:"lag"`
+	Consumers []StreamConsumer `json:"consumers"`
+}
+
+// StreamConsumer is a consumer of a group
+type StreamConsumer struct {
+	Name    string `json:"name"`
+	Pending int64  `json:"pending"`
+	IdleMs  int64  `json:"idle_ms"`
+}
+
+// StreamEntry is an entry of a stream, with where it stands in each group:
+// "new" when not yet delivered, "pending" when delivered but not
+// acknowledged, and "acked" once acknowledged
+type StreamEntry struct {
+	ID     string            `json:"id"`
+	Fields map[string]any    `json:"fields"`
+	Groups map[string]string `json:"groups,omitempty"`
+	// Consumers names the consumer an entry is pending with, per group
+	Consumers map[string]string `json:"consumers,omitempty"`
+}
+
+// HyperLogLogInfo is the estimate a HyperLogLog holds
+type HyperLogLogInfo struct {
+	Key         string `json:"key"`
+	Cardinality int64  `json:"cardinality"`
+	// Encoding is "sparse" for small counts and "dense" once it's grown
+	Encoding string `json:"encoding"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// BitmapInfo is a range of the bits of a string
+type BitmapInfo struct {
+	Key      string `json:"key"`
+	Bytes    int64  `json:"bytes"`
+	BitCount int64  `json:"bit_count"`
+	// FirstSet is the offset of the first 1 bit, -1 when there's none
+	FirstSet int64 `json:"first_set"`
+	Offset   int64 `json:"offset"`
+	// Bits are the bits from Offset, as a string of 0s and 1s
+	Bits string `json:"bits"`
+}
+
+// BitfieldValue is an integer read from a bitfield
+type BitfieldValue struct {
+	Offset int64 `json:"offset"`
+	Value  int64 `json:"value"`
+}
+
+// isHyperLogLog reports whether a string value is a HyperLogLog
+func isHyperLogLog(value string) bool {
+	return strings.HasPrefix(value, hyperLogLogMagic)
+}
+
+// GetStream returns a stream's consumer groups and its latest entries,
+// newest first
+func (s *Service) GetStream(ctx context.Context, key string, count int64) (*StreamInfo, error) {
+	if count <= 0 || count > maxStreamEntries {
+		count = maxStreamEntries
+	}
+
+	xinfo, err := s.client.XInfoStream(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	info := &StreamInfo{
+		Key:             key,
+		Length:          xinfo.Length,
+		FirstID:         xinfo.FirstEntry.ID,
+		LastID:          xinfo.LastEntry.ID,
+		LastGeneratedID: xinfo.LastGeneratedID,
+		EntriesAdded:    xinfo.EntriesAdded,
+		Groups:          []StreamGroup{},
+		Entries:         []StreamEntry{},
+		Truncated:       xinfo.Length > count,
+	}
+
+	messages, err := s.client.XRevRangeN(ctx, key, "+", "-", count).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range messages {
+		info.Entries = append(info.Entries, StreamEntry{ID: msg.ID, Fields: msg.Values})
+	}
+
+	groups, err := s.client.XInfoGroups(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		group := StreamGroup{
+			Name:            g.Name,
+			LastDeliveredID: g.LastDeliveredID,
+			EntriesRead:     g.EntriesRead,
+			Pending:         g.Pending,
+			Lag:             g.Lag,
+			Consumers:       []StreamConsumer{},
+		}
+		consumers, err := s.client.XInfoConsumers(ctx, key, g.Name).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range consumers {
+			group.Consumers = append(group.Consumers, StreamConsumer{Name: c.Name, Pending: c.Pending, IdleMs: c.Idle.Milliseconds()})
+		}
+		info.Groups = append(info.Groups, group)
+
+		if err := s.markStreamEntries(ctx, key, g, info.Entries); err != nil {
+			return nil, err
+		}
+	}
+
+	return info, nil
+}
+
+// markStreamEntries records where the shown entries stand in a group, from
+// its last delivered ID and the pending entries in their range
+func (s *Service) markStreamEntries(ctx context.Context, key string, group redis.XInfoGroup, entries []StreamEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	pending := map[string]string{}
+	if group.Pending > 0 {
+		list, err := s.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: key,
+			Group:  group.Name,
+			Start:  entries[len(entries)-1].ID,
+			End:    entries[0].ID,
+			Count:  int64(len(entries)),
+		}).Result()
+		if err != nil {
+			return err
+		}
+		for _, p := range list {
+			pending[p.ID] = p.Consumer
+		}
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		if entry.Groups == nil {
+			entry.Groups = map[string]string{}
+		}
+		switch consumer, ok := pending[entry.ID]; {
+		case ok:
+			entry.Groups[group.Name] = "pending"
+			if entry.Consumers == nil {
+				entry.Consumers = map[string]string{}
+			}
+			entry.Consumers[group.Name] = consumer
+		case compareStreamIDs(entry.ID, group.LastDeliveredID) <= 0:
+			entry.Groups[group.Name] = "acked"
+		default:
+			entry.Groups[group.Name] = "new"
+		}
+	}
+	return nil
+}
+
+// compareStreamIDs orders two stream IDs, which are <ms>-<seq>
+func compareStreamIDs(a, b string) int {
+	aMs, aSeq := parseStreamID(a)
+	bMs, bSeq := parseStreamID(b)
+	switch {
+	case aMs != bMs:
+		if aMs < bMs {
+			return -1
+		}
+		return 1
+	case aSeq != bSeq:
+		if aSeq < bSeq {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+func parseStreamID(id string) (uint64, uint64) {
+	ms, seq, _ := strings.Cut(id, "-")
+	m, _ := strconv.ParseUint(ms, 10, 64)
+	n, _ := strconv.ParseUint(seq, 10, 64)
+	return m, n
+}
+
+// GetHyperLogLog returns the cardinality a HyperLogLog estimates
+func (s *Service) GetHyperLogLog(ctx context.Context, key string) (*HyperLogLogInfo, error) {
+	header, err := s.client.GetRange(ctx, key, 0, 4).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !isHyperLogLog(header) || len(header) < 5 {
+		return nil, fmt.Errorf("key is not a HyperLogLog")
+	}
+
+	cardinality, err := s.client.PFCount(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	size, err := s.client.StrLen(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	encoding := "dense"
+	if header[4] == 1 {
+		encoding = "sparse"
+	}
+	return &HyperLogLogInfo{Key: key, Cardinality: cardinality, Encoding: encoding, Bytes: size}, nil
+}
+
+// GetBitmap returns count bits of a string from a bit offset, with how many
+// bits it sets overall
+func (s *Service) GetBitmap(ctx context.Context, key string, offset, count int64) (*BitmapInfo, error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative")
+	}
+	if count <= 0 || count > maxBitmapBits {
+		count = maxBitmapBits
+	}
+
+	size, err := s.client.StrLen(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	bitCount, err := s.client.BitCount(ctx, key, nil).Result()
+	if err != nil {
+		return nil, err
+	}
+	firstSet := int64(-1)
+	if bitCount > 0 {
+		if firstSet, err = s.client.BitPos(ctx, key, 1).Result(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Bits past the end of the string read as 0, as GETBIT does
+	if end := size * 8; offset+count > end {
+		count = max(end-offset, 0)
+	}
+	info := &BitmapInfo{Key: key, Bytes: size, BitCount: bitCount, FirstSet: firstSet, Offset: offset}
+	if count == 0 {
+		return info, nil
+	}
+
+	data, err := s.client.GetRange(ctx, key, offset/8, (offset+count-1)/8).Result()
+	if err != nil {
+		return nil, err
+	}
+	var bits strings.Builder
+	for i := offset; i < offset+count; i++ {
+		b := data[i/8-offset/8]
+		if b&(0x80>>(i%8)) != 0 {
+			bits.WriteByte('1')
+		} else {
+			bits.WriteByte('0')
+		}
+	}
+	info.Bits = bits.String()
+	return info, nil
+}
+
+// GetBitfield reads count consecutive integers of a type such as u8 or i16
+// from a bit offset of a string
+func (s *Service) GetBitfield(ctx context.Context, key, fieldType string, offset, count int64) ([]BitfieldValue, error) {
+	if !bitfieldTypePattern.MatchString(fieldType) || fieldType == "u64" {
+		return nil, fmt.Errorf("invalid bitfield type %q, use i1 to i64 or u1 to u63", fieldType)
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative")
+	}
+	if count <= 0 || count > maxBitfieldValues {
+		count = maxBitfieldValues
+	}
+	width, _ := strconv.ParseInt(fieldType[1:], 10, 64)
+
+	args := make([]interface{}, 0, count*3)
+	for i := int64(0); i < count; i++ {
+		args = append(args, "GET", fieldType, offset+i*width)
+	}
+	// BITFIELD rather than BITFIELD_RO, which needs Redis 6.2; only GETs
+	// are sent either way
+	values, err := s.client.BitField(ctx, key, args...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]BitfieldValue, len(values))
+	for i, v := range values {
+		result[i] = BitfieldValue{Offset: offset + int64(i)*width, Value: v}
+	}
+	return result, nil
+}
@@ -1,15 +1,20 @@
 package redis
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
 )
 
 func (s *Server) handleGetInfo(w http.ResponseWriter, r *http.Request) {
-	info, err := s.service.GetInfo()
+	info, err := s.service.GetInfo(r.Context())
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -18,7 +23,7 @@ func (s *Server) handleGetInfo(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleGetDBSize(w http.ResponseWriter, r *http.Request) {
-	size, err := s.service.GetDBSize()
+	size, err := s.service.GetDBSize(r.Context())
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -31,7 +36,12 @@ func (s *Server) handleGetKeys(w http.ResponseWriter, r *http.Request) {
 	cursor, _ := strconv.ParseUint(common.Query(r, "cursor", "0"), 10, 64)
 	count, _ := strconv.ParseInt(common.Query(r, "count", "100"), 10, 64)
 
-	result, err := s.service.GetKeys(pattern, cursor, count)
+	filter := KeyFilter{Type: r.URL.Query().Get("type")}
+	filter.ExpiresWithin, _ = strconv.ParseInt(common.Query(r, "expires_within", "0"), 10, 64)
+	filter.MinBytes, _ = strconv.ParseInt(common.Query(r, "min_bytes", "0"), 10, 64)
+	filter.Samples, _ = strconv.Atoi(common.Query(r, "samples", "5"))
+
+	result, err := s.service.GetKeys(r.Context(), pattern, cursor, count, filter)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -46,7 +56,7 @@ func (s *Server) handleGetKey(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	keyInfo, err := s.service.GetKey(key)
+	keyInfo, err := s.service.GetKey(r.Context(), key)
 	if err != nil {
 		common.JSONError(w, http.StatusNotFound, err.Error())
 		return
@@ -76,7 +86,7 @@ func (s *Server) handleSetKey(w http.ResponseWriter, r *http.Request) {
 		body.Type = "string"
 	}
 
-	if err := s.service.SetKey(body.Key, body.Value, body.Type, body.TTL); err != nil {
+	if err := s.service.SetKey(r.Context(), body.Key, body.Value, body.Type, body.TTL); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -103,7 +113,7 @@ func (s *Server) handleUpdateKey(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if body.Type == "" {
-		existingKey, err := s.service.GetKey(key)
+		existingKey, err := s.service.GetKey(r.Context(), key)
 		if err != nil {
 			common.JSONError(w, http.StatusNotFound, err.Error())
 			return
@@ -111,7 +121,7 @@ func (s *Server) handleUpdateKey(w http.ResponseWriter, r *http.Request) {
 		body.Type = existingKey.Type
 	}
 
-	if err := s.service.SetKey(key, body.Value, body.Type, body.TTL); err != nil {
+	if err := s.service.SetKey(r.Context(), key, body.Value, body.Type, body.TTL); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -126,7 +136,7 @@ func (s *Server) handleDeleteKey(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.service.DeleteKey(key); err != nil {
+	if err := s.service.DeleteKey(r.Context(), key); err != nil {
 		common.JSONError(w, http.StatusNotFound, err.Error())
 		return
 	}
@@ -149,7 +159,7 @@ func (s *Server) handleBulkDeleteKeys(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	deleted, err := s.service.BulkDeleteKeys(body.Keys)
+	deleted, err := s.service.BulkDeleteKeys(r.Context(), body.Keys)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -162,9 +172,138 @@ func (s *Server) handleBulkDeleteKeys(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (s *Server) handlePreviewJSONValue(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		common.JSONError(w, http.StatusBadRequest, "key is required")
+		return
+	}
+	var body struct {
+		Value string `json:"value"`
+	}
+	if err := common.ParseJSON(r, &body); err != nil {
+		common.JSONError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	preview, err := s.service.PreviewJSONValue(r.Context(), key, body.Value)
+	if err != nil {
+		writeJSONValueError(w, err)
+		return
+	}
+	common.JSON(w, preview)
+}
+
+func (s *Server) handleSetJSONValue(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		common.JSONError(w, http.StatusBadRequest, "key is required")
+		return
+	}
+	var body struct {
+		Value    string  `json:"value"`
+		Original *string `json:"original"`
+	}
+	if err := common.ParseJSON(r, &body); err != nil {
+		common.JSONError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := s.service.SetJSONValue(r.Context(), key, body.Value, body.Original); err != nil {
+		writeJSONValueError(w, err)
+		return
+	}
+	common.JSONMessage(w, "key updated successfully")
+}
+
+// writeJSONValueError answers a value that isn't JSON with a 400 and where
+// it goes wrong, an edit of a value someone else changed with a 409 and the
+// current value, other failures with a 500
+func writeJSONValueError(w http.ResponseWriter, err error) {
+	var invalid *InvalidJSONError
+	if errors.As(err, &invalid) {
+		common.JSONErrorData(w, http.StatusBadRequest, invalid.Error(), common.Map{"line": invalid.Line, "column": invalid.Column})
+		return
+	}
+	var changed *ValueChangedError
+	if errors.As(err, &changed) {
+		common.JSONErrorData(w, http.StatusConflict, changed.Error(), common.Map{"current": changed.Current})
+		return
+	}
+	common.JSONError(w, http.StatusInternalServerError, err.Error())
+}
+
+func (s *Server) handleGetStream(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		common.JSONError(w, http.StatusBadRequest, "key is required")
+		return
+	}
+	count, _ := strconv.ParseInt(common.Query(r, "count", "100"), 10, 64)
+
+	info, err := s.service.GetStream(r.Context(), key, count)
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.JSON(w, info)
+}
+
+func (s *Server) handleGetHyperLogLog(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		common.JSONError(w, http.StatusBadRequest, "key is required")
+		return
+	}
+
+	info, err := s.service.GetHyperLogLog(r.Context(), key)
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.JSON(w, info)
+}
+
+func (s *Server) handleGetBitmap(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		common.JSONError(w, http.StatusBadRequest, "key is required")
+		return
+	}
+	offset, _ := strconv.ParseInt(common.Query(r, "offset", "0"), 10, 64)
+	count, _ := strconv.ParseInt(common.Query(r, "count", "1024"), 10, 64)
+
+	info, err := s.service.GetBitmap(r.Context(), key, offset, count)
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.JSON(w, info)
+}
+
+func (s *Server) handleGetBitfield(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		common.JSONError(w, http.StatusBadRequest, "key is required")
+		return
+	}
+	offset, _ := strconv.ParseInt(common.Query(r, "offset", "0"), 10, 64)
+	count, _ := strconv.ParseInt(common.Query(r, "count", "64"), 10, 64)
+
+	values, err := s.service.GetBitfield(r.Context(), key, common.Query(r, "type", "u8"), offset, count)
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.JSON(w, values)
+}
+
 func (s *Server) handleCLI(w http.ResponseWriter, r *http.Request) {
 	var body struct {
 		Command string `json:"command"`
+		// ConfirmToken confirms a dangerous command, from the answer that
+		// refused it
+		ConfirmToken string `json:"confirm_token"`
 	}
 
 	if err := common.ParseJSON(r, &body); err != nil {
@@ -177,10 +316,50 @@ func (s *Server) handleCLI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result := s.service.ExecuteCLI(body.Command)
+	parts := parseCommand(body.Command)
+	if len(parts) == 0 {
+		common.JSON(w, s.service.ExecuteCLI(r.Context(), body.Command))
+		return
+	}
+	entry := AuditEntry{
+		Time:    time.Now(),
+		Client:  r.RemoteAddr,
+		DB:      s.service.client.Options().DB,
+		Command: body.Command,
+	}
+	rule, err := s.guard.check(body.Command, parts, body.ConfirmToken)
+	var denied *CommandDeniedError
+	var confirm *ConfirmationRequiredError
+	switch {
+	case errors.As(err, &denied):
+		entry.Rule, entry.Outcome = rule, "denied"
+		s.guard.record(entry)
+		common.JSONError(w, http.StatusForbidden, denied.Error())
+		return
+	case errors.As(err, &confirm):
+		common.JSONErrorData(w, http.StatusPreconditionRequired, confirm.Error(), common.Map{
+			"confirmation_required": true,
+			"rule":                  confirm.Rule,
+			"confirm_token":         confirm.Token,
+		})
+		return
+	case err != nil:
+		common.JSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	result := s.service.ExecuteCLI(r.Context(), body.Command)
+	if rule != "" {
+		entry.Rule, entry.Outcome, entry.Error = rule, "executed", result.Error
+		s.guard.record(entry)
+	}
 	common.JSON(w, result)
 }
 
+func (s *Server) handleCLIAudit(w http.ResponseWriter, r *http.Request) {
+	common.JSON(w, s.guard.audit())
+}
+
 func (s *Server) handleGetDatabases(w http.ResponseWriter, r *http.Request) {
 	databases, err := s.service.GetDatabases()
 	if err != nil {
@@ -198,7 +377,7 @@ func (s *Server) handleSelectDatabase(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.service.SelectDatabase(db); err != nil {
+	if err := s.service.SelectDatabase(r.Context(), db); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -207,7 +386,7 @@ func (s *Server) handleSelectDatabase(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleFlushDB(w http.ResponseWriter, r *http.Request) {
-	if err := s.service.FlushDB(); err != nil {
+	if err := s.service.FlushDB(r.Context()); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -217,7 +396,15 @@ func (s *Server) handleFlushDB(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleExportKeys(w http.ResponseWriter, r *http.Request) {
 	pattern := common.Query(r, "pattern", "*")
 
-	keys, err := s.service.ExportKeys(pattern)
+	if common.Query(r, "format", "json") == "resp" {
+		// Streamed, so a failure part way can only cut the file short
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="redis-export.resp"`)
+		s.service.ExportCommands(r.Context(), pattern, w)
+		return
+	}
+
+	keys, err := s.service.ExportKeys(r.Context(), pattern)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -240,7 +427,7 @@ func (s *Server) handleImportKeys(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	imported, skipped, err := s.service.ImportKeys(body.Keys, body.Overwrite)
+	imported, skipped, err := s.service.ImportKeys(r.Context(), body.Keys, body.Overwrite)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -253,11 +440,23 @@ func (s *Server) handleImportKeys(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (s *Server) handleImportCommands(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	overwrite := common.Query(r, "overwrite", "false") == "true"
+
+	result, err := s.service.ImportCommands(r.Context(), r.Body, overwrite)
+	if err != nil {
+		common.JSONErrorData(w, http.StatusBadRequest, err.Error(), result)
+		return
+	}
+	common.JSON(w, result)
+}
+
 func (s *Server) handleGetMemoryStats(w http.ResponseWriter, r *http.Request) {
 	pattern := common.Query(r, "pattern", "*")
 	limit, _ := strconv.Atoi(common.Query(r, "limit", "100"))
 
-	memoryInfos, typeStats, err := s.service.GetMemoryStats(pattern, limit)
+	memoryInfos, typeStats, err := s.service.GetMemoryStats(r.Context(), pattern, limit)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -270,7 +469,7 @@ func (s *Server) handleGetMemoryStats(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleGetMemoryOverview(w http.ResponseWriter, r *http.Request) {
-	overview, err := s.service.GetMemoryOverview()
+	overview, err := s.service.GetMemoryOverview(r.Context())
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -285,7 +484,7 @@ func (s *Server) handleGetKeyMemory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	info, err := s.service.GetKeyMemory(key)
+	info, err := s.service.GetKeyMemory(r.Context(), key)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -296,7 +495,7 @@ func (s *Server) handleGetKeyMemory(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleGetSlowLog(w http.ResponseWriter, r *http.Request) {
 	count, _ := strconv.Atoi(common.Query(r, "count", "50"))
 
-	entries, err := s.service.GetSlowLog(count)
+	entries, err := s.service.GetSlowLog(r.Context(), count)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -305,7 +504,7 @@ func (s *Server) handleGetSlowLog(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleResetSlowLog(w http.ResponseWriter, r *http.Request) {
-	if err := s.service.ResetSlowLog(); err != nil {
+	if err := s.service.ResetSlowLog(r.Context()); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -313,7 +512,7 @@ func (s *Server) handleResetSlowLog(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleGetSlowLogLen(w http.ResponseWriter, r *http.Request) {
-	length, err := s.service.GetSlowLogLen()
+	length, err := s.service.GetSlowLogLen(r.Context())
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -345,7 +544,7 @@ func (s *Server) handleExecuteScript(w http.ResponseWriter, r *http.Request) {
 		body.Args = []interface{}{}
 	}
 
-	result := s.service.ExecuteScript(body.Script, body.Keys, body.Args)
+	result := s.service.ExecuteScript(r.Context(), body.Script, body.Keys, body.Args)
 	common.JSON(w, result)
 }
 
@@ -359,7 +558,7 @@ func (s *Server) handleLoadScript(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sha, err := s.service.LoadScript(body.Script)
+	sha, err := s.service.LoadScript(r.Context(), body.Script)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -392,12 +591,12 @@ func (s *Server) handleExecuteScriptBySHA(w http.ResponseWriter, r *http.Request
 		body.Args = []interface{}{}
 	}
 
-	result := s.service.ExecuteScriptBySHA(body.SHA, body.Keys, body.Args)
+	result := s.service.ExecuteScriptBySHA(r.Context(), body.SHA, body.Keys, body.Args)
 	common.JSON(w, result)
 }
 
 func (s *Server) handleFlushScripts(w http.ResponseWriter, r *http.Request) {
-	if err := s.service.FlushScripts(); err != nil {
+	if err := s.service.FlushScripts(r.Context()); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -420,7 +619,7 @@ func (s *Server) handleBulkSetTTL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updated, err := s.service.BulkSetTTL(body.Pattern, body.TTL)
+	updated, err := s.service.BulkSetTTL(r.Context(), body.Pattern, body.TTL)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -435,7 +634,7 @@ func (s *Server) handleBulkSetTTL(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
 	pattern := common.Query(r, "pattern", "*")
 
-	config, err := s.service.GetConfig(pattern)
+	config, err := s.service.GetConfig(r.Context(), pattern)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -459,7 +658,7 @@ func (s *Server) handleSetConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.service.SetConfig(body.Key, body.Value); err != nil {
+	if err := s.service.SetConfig(r.Context(), body.Key, body.Value); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -468,7 +667,7 @@ func (s *Server) handleSetConfig(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleRewriteConfig(w http.ResponseWriter, r *http.Request) {
-	if err := s.service.RewriteConfig(); err != nil {
+	if err := s.service.RewriteConfig(r.Context()); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -476,7 +675,7 @@ func (s *Server) handleRewriteConfig(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleResetConfigStats(w http.ResponseWriter, r *http.Request) {
-	if err := s.service.ResetConfigStats(); err != nil {
+	if err := s.service.ResetConfigStats(r.Context()); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -484,7 +683,7 @@ func (s *Server) handleResetConfigStats(w http.ResponseWriter, r *http.Request)
 }
 
 func (s *Server) handleGetReplicationInfo(w http.ResponseWriter, r *http.Request) {
-	info, err := s.service.GetReplicationInfo()
+	info, err := s.service.GetReplicationInfo(r.Context())
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -493,7 +692,7 @@ func (s *Server) handleGetReplicationInfo(w http.ResponseWriter, r *http.Request
 }
 
 func (s *Server) handleGetClusterInfo(w http.ResponseWriter, r *http.Request) {
-	info, err := s.service.GetClusterInfo()
+	info, err := s.service.GetClusterInfo(r.Context())
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -502,7 +701,7 @@ func (s *Server) handleGetClusterInfo(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleGetACLUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := s.service.GetACLUsers()
+	users, err := s.service.GetACLUsers(r.Context())
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -517,7 +716,7 @@ func (s *Server) handleGetACLUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := s.service.GetACLUser(username)
+	user, err := s.service.GetACLUser(r.Context(), username)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -541,7 +740,7 @@ func (s *Server) handleCreateACLUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.service.CreateACLUser(body.Username, body.Rules); err != nil {
+	if err := s.service.CreateACLUser(r.Context(), body.Username, body.Rules); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -556,7 +755,7 @@ func (s *Server) handleDeleteACLUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.service.DeleteACLUser(username); err != nil {
+	if err := s.service.DeleteACLUser(r.Context(), username); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -567,7 +766,7 @@ func (s *Server) handleDeleteACLUser(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleGetACLLog(w http.ResponseWriter, r *http.Request) {
 	count, _ := strconv.Atoi(common.Query(r, "count", "10"))
 
-	logs, err := s.service.GetACLLog(count)
+	logs, err := s.service.GetACLLog(r.Context(), count)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -576,7 +775,7 @@ func (s *Server) handleGetACLLog(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleResetACLLog(w http.ResponseWriter, r *http.Request) {
-	if err := s.service.ResetACLLog(); err != nil {
+	if err := s.service.ResetACLLog(r.Context()); err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -599,7 +798,7 @@ func (s *Server) handlePublish(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	receivers, err := s.service.Publish(body.Channel, body.Message)
+	receivers, err := s.service.Publish(r.Context(), body.Channel, body.Message)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -614,7 +813,7 @@ func (s *Server) handlePublish(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleGetChannels(w http.ResponseWriter, r *http.Request) {
 	pattern := common.Query(r, "pattern", "*")
 
-	channels, err := s.service.GetPubSubChannels(pattern)
+	channels, err := s.service.GetPubSubChannels(r.Context(), pattern)
 	if err != nil {
 		common.JSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -623,7 +822,7 @@ func (s *Server) handleGetChannels(w http.ResponseWriter, r *http.Request) {
 	// Get subscriber counts
 	var numSub map[string]int64
 	if len(channels) > 0 {
-		numSub, _ = s.service.GetPubSubNumSub(channels)
+		numSub, _ = s.service.GetPubSubNumSub(r.Context(), channels)
 	}
 
 	result := make([]common.Map, 0, len(channels))
@@ -638,7 +837,7 @@ func (s *Server) handleGetChannels(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	numPat, _ := s.service.GetPubSubNumPat()
+	numPat, _ := s.service.GetPubSubNumPat(r.Context())
 
 	common.JSON(w, common.Map{
 		"channels":            result,
@@ -653,9 +852,9 @@ func (s *Server) handleGetExtendedInfo(w http.ResponseWriter, r *http.Request) {
 	var err error
 
 	if section == "all" {
-		info, err = s.service.client.Info(s.service.ctx).Result()
+		info, err = s.service.client.Info(r.Context()).Result()
 	} else {
-		info, err = s.service.client.Info(s.service.ctx, section).Result()
+		info, err = s.service.client.Info(r.Context(), section).Result()
 	}
 
 	if err != nil {
@@ -689,3 +888,109 @@ func (s *Server) handleGetExtendedInfo(w http.ResponseWriter, r *http.Request) {
 
 	common.JSON(w, result)
 }
+
+// handleKeyspaceEvents streams the key events of the current database over
+// a websocket. The page may send {"patterns": [...]} at any time to only get
+// events of keys matching one of the glob patterns.
+func (s *Server) handleKeyspaceEvents(w http.ResponseWriter, r *http.Request) {
+	ws, err := common.UpgradeWebSocket(w, r)
+	if err != nil {
+		return
+	}
+
+	status, release, err := s.service.EnableKeyspaceEvents(r.Context(), r.URL.Query().Get("classes"))
+	if err != nil {
+		ws.WriteJSON(common.Map{"type": "error", "message": err.Error()})
+		ws.Close("invalid classes")
+		return
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	pubsub, err := s.service.SubscribeKeyEvents(ctx, status.DB)
+	if err != nil {
+		ws.WriteJSON(common.Map{"type": "error", "message": err.Error()})
+		ws.Close("subscribe failed")
+		return
+	}
+	defer pubsub.Close()
+	if err := ws.WriteJSON(status); err != nil {
+		ws.Close("")
+		return
+	}
+
+	var patterns atomic.Pointer[[]string]
+	if p := r.URL.Query().Get("patterns"); p != "" {
+		list := splitPatterns(p)
+		patterns.Store(&list)
+	}
+
+	// The page's messages set the patterns; its closing ends the feed
+	go func() {
+		defer cancel()
+		for {
+			message, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			var update struct {
+				Patterns []string `json:"patterns"`
+			}
+			if json.Unmarshal(message, &update) == nil {
+				patterns.Store(&update.Patterns)
+			}
+		}
+	}()
+
+	// Events queue up for the page, and are dropped rather than holding up
+	// the subscription when it can't keep up
+	events := make(chan KeyEvent, keyEventBuffer)
+	var dropped atomic.Int64
+	go func() {
+		defer close(events)
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				if p := patterns.Load(); p != nil && !matchAnyPattern(*p, msg.Payload) {
+					continue
+				}
+				select {
+				case events <- parseKeyEvent(msg):
+				default:
+					dropped.Add(1)
+				}
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				ws.Close("feed ended")
+				return
+			}
+			if err := ws.WriteJSON(event); err != nil {
+				ws.Close("")
+				return
+			}
+		case <-ticker.C:
+			if n := dropped.Swap(0); n > 0 {
+				if err := ws.WriteJSON(common.Map{"type": "dropped", "count": n}); err != nil {
+					ws.Close("")
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisJSONType is what TYPE answers for keys of the RedisJSON module
+const redisJSONType = "ReJSON-RL"
+
+// maxDiffLines bounds the lines of each side a diff lines up; longer values
+// are shown as wholly replaced
+const maxDiffLines = 2000
+
+// InvalidJSONError rejects a value that isn't JSON, with where it goes wrong
+type InvalidJSONError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *InvalidJSONError) Error() string {
+	return fmt.Sprintf("invalid JSON at line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// ValueChangedError rejects an edit of a value that changed since it was
+// read, with its current value so the user can merge
+type ValueChangedError struct {
+	Current string
+}
+
+func (e *ValueChangedError) Error() string {
+	return "the value was changed by someone else since you loaded it"
+}
+
+// DiffLine is a line of a diff: " " when both sides have it, "-" when only
+// the current value does and "+" when only the new one does
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// JSONPreview is what saving a JSON value would change
+type JSONPreview struct {
+	Changed bool       `json:"changed"`
+	Diff    []DiffLine `json:"diff"`
+	// Current is the stored value the diff was made against
+	Current string `json:"current"`
+}
+
+// isJSONValue reports whether a string holds a JSON object or array, the
+// values worth showing as a document
+func isJSONValue(value string) bool {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return false
+	}
+	return json.Valid([]byte(trimmed))
+}
+
+// prettyJSON indents a JSON value, keeping its key order and numbers as
+// written
+func prettyJSON(value string) string {
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(strings.TrimSpace(value)), "", "  "); err != nil {
+		return value
+	}
+	return out.String()
+}
+
+// validateJSON checks that a value is JSON, pointing at the first mistake
+func validateJSON(value string) error {
+	var v any
+	err := json.Unmarshal([]byte(value), &v)
+	if err == nil {
+		return nil
+	}
+
+	offset := int64(len(value))
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) && syntax.Offset > 0 && syntax.Offset < offset {
+		// The offset is just past the character that gave it away
+		offset = syntax.Offset - 1
+	}
+	line, column := 1, 1
+	for _, c := range value[:min(int(offset), len(value))] {
+		if c == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return &InvalidJSONError{Line: line, Column: column, Message: strings.TrimPrefix(err.Error(), "json: ")}
+}
+
+// getJSONValue returns the stored JSON of a string or RedisJSON key
+func (s *Service) getJSONValue(ctx context.Context, key string) (string, string, error) {
+	keyType, err := s.client.Type(ctx, key).Result()
+	if err != nil {
+		return "", "", err
+	}
+	switch keyType {
+	case "string":
+		value, err := s.client.Get(ctx, key).Result()
+		return value, keyType, err
+	case redisJSONType:
+		value, err := s.client.Do(ctx, "JSON.GET", key).Text()
+		return value, keyType, err
+	case "none":
+		return "", "", fmt.Errorf("key does not exist")
+	default:
+		return "", "", fmt.Errorf("a %s key doesn't hold JSON", keyType)
+	}
+}
+
+// PreviewJSONValue validates a new JSON value for a key and returns how it
+// differs from the stored one, line by line once both are indented
+func (s *Service) PreviewJSONValue(ctx context.Context, key, value string) (*JSONPreview, error) {
+	if err := validateJSON(value); err != nil {
+		return nil, err
+	}
+	current, _, err := s.getJSONValue(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := diffLines(strings.Split(prettyJSON(current), "\n"), strings.Split(prettyJSON(value), "\n"))
+	preview := &JSONPreview{Diff: diff, Current: current}
+	for _, line := range diff {
+		if line.Op != " " {
+			preview.Changed = true
+			break
+		}
+	}
+	return preview, nil
+}
+
+// SetJSONValue validates and stores a JSON value, with SET for strings and
+// JSON.SET for RedisJSON keys, keeping the key's TTL. A string that was
+// stored compact is stored compact again. When original is given, the edit
+// is refused if the stored value no longer matches it.
+func (s *Service) SetJSONValue(ctx context.Context, key, value string, original *string) error {
+	if err := validateJSON(value); err != nil {
+		return err
+	}
+	current, keyType, err := s.getJSONValue(ctx, key)
+	if err != nil {
+		return err
+	}
+	if original != nil && *original != current {
+		return &ValueChangedError{Current: current}
+	}
+
+	if keyType == redisJSONType {
+		return s.client.Do(ctx, "JSON.SET", key, "$", value).Err()
+	}
+
+	if !strings.Contains(current, "\n") {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, []byte(value)); err == nil {
+			value = compact.String()
+		}
+	}
+	return s.client.SetArgs(ctx, key, value, redis.SetArgs{KeepTTL: true}).Err()
+}
+
+// diffLines lines up two texts by their longest common subsequence of lines
+func diffLines(a, b []string) []DiffLine {
+	if len(a) > maxDiffLines || len(b) > maxDiffLines {
+		diff := make([]DiffLine, 0, len(a)+len(b))
+		for _, line := range a {
+			diff = append(diff, DiffLine{Op: "-", Text: line})
+		}
+		for _, line := range b {
+			diff = append(diff, DiffLine{Op: "+", Text: line})
+		}
+		return diff
+	}
+
+	// common[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	diff := make([]DiffLine, 0, max(len(a), len(b)))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, DiffLine{Op: " ", Text: a[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			diff = append(diff, DiffLine{Op: "-", Text: a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Op: "+", Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, DiffLine{Op: "-", Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, DiffLine{Op: "+", Text: b[j]})
+	}
+	return diff
+}
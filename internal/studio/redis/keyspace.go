@@ -0,0 +1,248 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyspaceClasses are the notify-keyspace-events flags a feed can turn on:
+// A (all of g$lshzxetd), generic, string, list, set, hash, sorted set,
+// expired, evicted, stream, key miss and new key events
+const keyspaceClasses = "Ag$lshzxetmn"
+
+// defaultKeyspaceClasses are the classes of a feed that doesn't ask for
+// any: writes of every type, expirations and evictions
+const defaultKeyspaceClasses = "g$lshzxe"
+
+// keyEventBuffer is how many key events wait for a slow page before new
+// ones are dropped
+const keyEventBuffer = 1024
+
+// KeyEvent is a key event from a keyspace notification
+type KeyEvent struct {
+	Type  string    `json:"type"` // always "event"
+	Event string    `json:"event"`
+	Key   string    `json:"key"`
+	Time  time.Time `json:"time"`
+}
+
+// KeyspaceStatus is the first message of a feed
+type KeyspaceStatus struct {
+	Type    string `json:"type"` // always "ready"
+	DB      int    `json:"db"`
+	Classes string `json:"classes"`
+	// Config is notify-keyspace-events as the feed runs with it
+	Config string `json:"config"`
+	// Warning says why notifications couldn't be turned on, e.g. on
+	// managed servers that disable CONFIG
+	Warning string `json:"warning,omitempty"`
+}
+
+// keyspaceFeeds tracks the running feeds, which share the server's
+// notify-keyspace-events setting. The setting the first feed found is put
+// back when the last one ends, unless something else changed it since.
+type keyspaceFeeds struct {
+	mu       sync.Mutex
+	count    int
+	original string
+	set      string
+}
+
+// EnableKeyspaceEvents turns on keyspace notifications of the given classes
+// and key events, adding them to what notify-keyspace-events already has.
+// The returned func releases the feed's hold on the setting.
+func (s *Service) EnableKeyspaceEvents(ctx context.Context, classes string) (*KeyspaceStatus, func(), error) {
+	if classes == "" {
+		classes = defaultKeyspaceClasses
+	}
+	for _, c := range classes {
+		if !strings.ContainsRune(keyspaceClasses, c) {
+			return nil, nil, fmt.Errorf("unknown keyspace event class %q, use any of %s", c, keyspaceClasses)
+		}
+	}
+
+	feeds := &s.keyspace
+	feeds.mu.Lock()
+	defer feeds.mu.Unlock()
+
+	status := &KeyspaceStatus{Type: "ready", DB: s.client.Options().DB, Classes: classes}
+	current, err := s.client.ConfigGet(ctx, "notify-keyspace-events").Result()
+	if err != nil {
+		// The setting can't be read, so it can't be changed either; it may
+		// still be on
+		status.Warning = fmt.Sprintf("can't read notify-keyspace-events (%v); events only arrive if the server already has them on", err)
+		return status, func() {}, nil
+	}
+	config := current["notify-keyspace-events"]
+
+	wanted := config
+	for _, c := range classes + "E" {
+		if !strings.ContainsRune(wanted, c) {
+			wanted += string(c)
+		}
+	}
+	if wanted != config {
+		if err := s.client.ConfigSet(ctx, "notify-keyspace-events", wanted).Err(); err != nil {
+			status.Config = config
+			status.Warning = fmt.Sprintf("can't turn on notifications (%v); set notify-keyspace-events to %q on the server", err, wanted)
+			return status, func() {}, nil
+		}
+		if feeds.count == 0 {
+			feeds.original = config
+		}
+		feeds.set = wanted
+		config = wanted
+	}
+	status.Config = config
+	feeds.count++
+
+	// Restoring the setting must outlive the request that enabled it
+	ctx = context.WithoutCancel(ctx)
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			feeds.mu.Lock()
+			defer feeds.mu.Unlock()
+			feeds.count--
+			if feeds.count > 0 || feeds.set == "" {
+				return
+			}
+			current, err := s.client.ConfigGet(ctx, "notify-keyspace-events").Result()
+			if err == nil && current["notify-keyspace-events"] == feeds.set {
+				s.client.ConfigSet(ctx, "notify-keyspace-events", feeds.original)
+			}
+			feeds.set = ""
+		})
+	}
+	return status, release, nil
+}
+
+// SubscribeKeyEvents subscribes to the key events of a database. Events
+// come on the client the subscription was made with, so switching databases
+// closes the subscription's channel rather than moving it.
+func (s *Service) SubscribeKeyEvents(ctx context.Context, db int) (*redis.PubSub, error) {
+	pubsub := s.client.PSubscribe(ctx, fmt.Sprintf("__keyevent@%d__:*", db))
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to key events: %w", err)
+	}
+	return pubsub, nil
+}
+
+// parseKeyEvent reads a key event notification, whose channel names the
+// event and whose message is the key
+func parseKeyEvent(msg *redis.Message) KeyEvent {
+	event := KeyEvent{Type: "event", Key: msg.Payload, Time: time.Now()}
+	if i := strings.LastIndex(msg.Channel, ":"); i >= 0 {
+		event.Event = msg.Channel[i+1:]
+	}
+	return event
+}
+
+// splitPatterns splits a comma or space separated list of key patterns
+func splitPatterns(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+// matchAnyPattern reports whether a key matches one of the glob patterns,
+// or there are none
+func matchAnyPattern(patterns []string, key string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matchGlob(pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches a key against a glob pattern the way KEYS and SCAN do:
+// * and ? wildcards, [abc], [^abc] and [a-z] classes, and \ escapes
+func matchGlob(pattern, key string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(key); i++ {
+				if matchGlob(pattern[1:], key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+			key = key[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(key) == 0 {
+				return false
+			}
+			end := strings.IndexByte(pattern[1:], ']')
+			if end < 0 {
+				// No closing bracket: a literal [
+				if key[0] != '[' {
+					return false
+				}
+				key = key[1:]
+				pattern = pattern[1:]
+				continue
+			}
+			class := pattern[1 : end+1]
+			negate := strings.HasPrefix(class, "^")
+			if negate {
+				class = class[1:]
+			}
+			if matchClass(class, key[0]) == negate {
+				return false
+			}
+			key = key[1:]
+			pattern = pattern[end+2:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(key) == 0 || key[0] != pattern[0] {
+				return false
+			}
+			key = key[1:]
+			pattern = pattern[1:]
+		}
+	}
+	return len(key) == 0
+}
+
+func matchClass(class string, c byte) bool {
+	for i := 0; i < len(class); i++ {
+		if i+2 < len(class) && class[i+1] == '-' {
+			lo, hi := class[i], class[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				return true
+			}
+			i += 2
+			continue
+		}
+		if class[i] == c {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+	"github.com/redis/go-redis/v9"
+)
+
+// overviewCounters are the INFO fields the overview lists as activity
+var overviewCounters = []struct{ field, label string }{
+	{"connected_clients", "Connected clients"},
+	{"instantaneous_ops_per_sec", "Commands per second"},
+	{"total_commands_processed", "Commands since the server started"},
+	{"keyspace_hits", "Key hits"},
+	{"keyspace_misses", "Key misses"},
+	{"expired_keys", "Expired keys"},
+	{"evicted_keys", "Evicted keys"},
+}
+
+// StoreOverview connects to a Redis server and returns its overview: the
+// memory it uses, the keys of each of its databases and its activity
+// counters. The connection is closed before returning.
+func StoreOverview(ctx context.Context, url string) common.StoreOverview {
+	overview := common.StoreOverview{Provider: "redis", ObjectKind: "databases", ItemKind: "keys", Activity: []common.StoreActivity{}}
+
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		overview.Error = fmt.Sprintf("invalid Redis URL: %v", err)
+		return overview
+	}
+	client := redis.NewClient(opts)
+	defer client.Close()
+
+	start := time.Now()
+	if err := client.Ping(ctx).Err(); err != nil {
+		overview.Error = err.Error()
+		return overview
+	}
+	overview.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	overview.Healthy = true
+
+	info, err := client.Info(ctx).Result()
+	if err != nil {
+		overview.Error = fmt.Sprintf("failed to read INFO: %v", err)
+		return overview
+	}
+	fields := make(map[string]string)
+	for _, line := range strings.Split(info, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		fields[key] = value
+		// db0:keys=12,expires=3,avg_ttl=0
+		if db, ok := strings.CutPrefix(key, "db"); ok {
+			if _, err := strconv.Atoi(db); err != nil {
+				continue
+			}
+			overview.Objects++
+			for _, part := range strings.Split(value, ",") {
+				if keys, ok := strings.CutPrefix(part, "keys="); ok {
+					n, _ := strconv.ParseInt(keys, 10, 64)
+					overview.Items += n
+				}
+			}
+		}
+	}
+
+	overview.Version = fields["redis_version"]
+	overview.SizeBytes, _ = strconv.ParseInt(fields["used_memory"], 10, 64)
+	for _, counter := range overviewCounters {
+		if value, ok := fields[counter.field]; ok {
+			overview.Activity = append(overview.Activity, common.StoreActivity{
+				Description: fmt.Sprintf("%s: %s", counter.label, value),
+			})
+		}
+	}
+	return overview
+}
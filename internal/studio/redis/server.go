@@ -16,9 +16,10 @@ type Server struct {
 	service       *Service
 	port          int
 	connectionURL string
+	guard         *cliGuard
 }
 
-func NewServer(connectionURL string, port int) *Server {
+func NewServer(connectionURL string, port int, policy CLIPolicy) *Server {
 	opts, err := redis.ParseURL(connectionURL)
 	if err != nil {
 		panic(fmt.Sprintf("Failed to parse Redis URL: %v", err))
@@ -32,7 +33,7 @@ func NewServer(connectionURL string, port int) *Server {
 	}
 
 	mux := http.NewServeMux()
-	tmpl := common.ParseTemplates(TemplatesFS)
+	tmpl := common.ParseTemplates(TemplatesFS, StaticFS)
 
 	server := &Server{
 		mux:           mux,
@@ -40,6 +41,7 @@ func NewServer(connectionURL string, port int) *Server {
 		service:       NewService(client),
 		port:          port,
 		connectionURL: connectionURL,
+		guard:         newCLIGuard(policy),
 	}
 
 	server.setupRoutes()
@@ -66,8 +68,19 @@ func (s *Server) setupRoutes() {
 	s.mux.HandleFunc("DELETE /api/key", s.handleDeleteKey)
 	s.mux.HandleFunc("POST /api/flush", s.handleFlushDB)
 
+	// JSON values
+	s.mux.HandleFunc("POST /api/key/json/preview", s.handlePreviewJSONValue)
+	s.mux.HandleFunc("PUT /api/key/json", s.handleSetJSONValue)
+
+	// Data type views
+	s.mux.HandleFunc("GET /api/key/stream", s.handleGetStream)
+	s.mux.HandleFunc("GET /api/key/hyperloglog", s.handleGetHyperLogLog)
+	s.mux.HandleFunc("GET /api/key/bitmap", s.handleGetBitmap)
+	s.mux.HandleFunc("GET /api/key/bitfield", s.handleGetBitfield)
+
 	// CLI
 	s.mux.HandleFunc("POST /api/cli", s.handleCLI)
+	s.mux.HandleFunc("GET /api/cli/audit", s.handleCLIAudit)
 
 	// Database selection
 	s.mux.HandleFunc("GET /api/databases", s.handleGetDatabases)
@@ -76,6 +89,7 @@ func (s *Server) setupRoutes() {
 	// Export/Import
 	s.mux.HandleFunc("GET /api/export", s.handleExportKeys)
 	s.mux.HandleFunc("POST /api/import", s.handleImportKeys)
+	s.mux.HandleFunc("POST /api/import/commands", s.handleImportCommands)
 
 	// Memory Analysis
 	s.mux.HandleFunc("GET /api/memory/stats", s.handleGetMemoryStats)
@@ -117,10 +131,13 @@ func (s *Server) setupRoutes() {
 	// Pub/Sub
 	s.mux.HandleFunc("POST /api/pubsub/publish", s.handlePublish)
 	s.mux.HandleFunc("GET /api/pubsub/channels", s.handleGetChannels)
+
+	// Keyspace notifications
+	s.mux.HandleFunc("GET /api/keyspace/events", s.handleKeyspaceEvents)
 }
 
-func (s *Server) Start(openBrowser bool) error {
-	return common.StartServer(s.mux, &s.port, "Redis Studio", openBrowser)
+func (s *Server) Start(opts common.StartOptions) error {
+	return common.StartServer(s.mux, &s.port, "Redis Studio", opts, func(ctx context.Context) error { return s.service.client.Ping(ctx).Err() }, s.service.client.Close)
 }
 
 func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
@@ -13,8 +13,8 @@ import (
 )
 
 type Service struct {
-	client *redis.Client
-	ctx    context.Context
+	client   *redis.Client
+	keyspace keyspaceFeeds
 }
 
 type KeyInfo struct {
@@ -23,12 +23,38 @@ type KeyInfo struct {
 	TTL   int64       `json:"ttl"`
 	Value interface{} `json:"value,omitempty"`
 	Size  int64       `json:"size,omitempty"`
+	// Format names what a string holds when it has a structure, such as
+	// "hyperloglog" or "json"
+	Format string `json:"format,omitempty"`
+	// Pretty is a JSON value indented for display and editing
+	Pretty string `json:"pretty,omitempty"`
+	// Memory is the bytes a key uses, when a scan filtered on it
+	Memory int64 `json:"memory,omitempty"`
 }
 
 type KeysResult struct {
 	Keys       []KeyInfo `json:"keys"`
 	TotalCount int64     `json:"total_count"`
 	Cursor     uint64    `json:"cursor"`
+	// Scanned counts the keys looked at to find these, more than were
+	// returned when a filter left some out
+	Scanned int64 `json:"scanned"`
+}
+
+// maxScannedKeys bounds the keys one page of a scan looks at, so a filter
+// few keys match returns partial pages instead of scanning everything
+const maxScannedKeys = 10000
+
+// KeyFilter narrows a key scan down beyond its pattern
+type KeyFilter struct {
+	// Type keeps keys of one type, such as hash
+	Type string
+	// ExpiresWithin keeps keys with a TTL of at most this many seconds
+	ExpiresWithin int64
+	// MinBytes keeps keys using at least this much memory, as MEMORY USAGE
+	// estimates it from Samples elements of larger values
+	MinBytes int64
+	Samples  int
 }
 
 type ServerInfo struct {
@@ -54,13 +80,12 @@ type CLIResult struct {
 func NewService(client *redis.Client) *Service {
 	return &Service{
 		client: client,
-		ctx:    context.Background(),
 	}
 }
 
 // GetInfo returns Redis server information
-func (s *Service) GetInfo() (*ServerInfo, error) {
-	info, err := s.client.Info(s.ctx).Result()
+func (s *Service) GetInfo(ctx context.Context) (*ServerInfo, error) {
+	info, err := s.client.Info(ctx).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -94,12 +119,12 @@ func (s *Service) GetInfo() (*ServerInfo, error) {
 		}
 	}
 
-	dbSize, err := s.client.DBSize(s.ctx).Result()
+	dbSize, err := s.client.DBSize(ctx).Result()
 	if err == nil {
 		serverInfo.TotalKeys = dbSize
 	}
 
-	maxmemory, err := s.client.ConfigGet(s.ctx, "maxmemory").Result()
+	maxmemory, err := s.client.ConfigGet(ctx, "maxmemory").Result()
 	if err == nil && len(maxmemory) > 0 {
 		if maxMemVal, ok := maxmemory["maxmemory"]; ok {
 			maxMemBytes, _ := strconv.ParseInt(maxMemVal, 10, 64)
@@ -132,33 +157,112 @@ func formatBytes(bytes int64) string {
 }
 
 // GetDBSize returns the number of keys in current database
-func (s *Service) GetDBSize() (int64, error) {
-	return s.client.DBSize(s.ctx).Result()
+func (s *Service) GetDBSize(ctx context.Context) (int64, error) {
+	return s.client.DBSize(ctx).Result()
 }
 
 // GetKeys returns keys matching pattern with pagination
-func (s *Service) GetKeys(pattern string, cursor uint64, count int64) (*KeysResult, error) {
+// GetKeys scans for keys matching a pattern and filter from a cursor. It
+// keeps scanning until count keys match or maxScannedKeys were looked at,
+// so a selective filter returns a partial page with the cursor to go on
+// from. The TYPE, TTL and MEMORY USAGE lookups of each batch are pipelined.
+func (s *Service) GetKeys(ctx context.Context, pattern string, cursor uint64, count int64, filter KeyFilter) (*KeysResult, error) {
 	if pattern == "" {
 		pattern = "*"
 	}
+	if count <= 0 {
+		count = 100
+	}
 
-	keys, nextCursor, err := s.client.Scan(s.ctx, cursor, pattern, count).Result()
-	if err != nil {
-		return nil, err
+	keyInfos := make([]KeyInfo, 0, count)
+	var scanned int64
+	scanType := filter.Type != ""
+	for {
+		var keys []string
+		var nextCursor uint64
+		var err error
+		if scanType {
+			keys, nextCursor, err = s.client.ScanType(ctx, cursor, pattern, count, filter.Type).Result()
+			if err != nil {
+				// SCAN ... TYPE needs Redis 6; the types are checked below
+				// either way
+				scanType = false
+				continue
+			}
+		} else {
+			keys, nextCursor, err = s.client.Scan(ctx, cursor, pattern, count).Result()
+			if err != nil {
+				return nil, err
+			}
+		}
+		scanned += int64(len(keys))
+
+		matched, err := s.lookupKeys(ctx, keys, filter)
+		if err != nil {
+			return nil, err
+		}
+		keyInfos = append(keyInfos, matched...)
+
+		cursor = nextCursor
+		if cursor == 0 || int64(len(keyInfos)) >= count || scanned >= maxScannedKeys {
+			break
+		}
 	}
 
-	sort.Strings(keys)
+	sort.Slice(keyInfos, func(i, j int) bool { return keyInfos[i].Key < keyInfos[j].Key })
+
+	totalCount, _ := s.client.DBSize(ctx).Result()
+
+	return &KeysResult{
+		Keys:       keyInfos,
+		TotalCount: totalCount,
+		Cursor:     cursor,
+		Scanned:    scanned,
+	}, nil
+}
+
+// lookupKeys gets the type and TTL of keys, and their memory use when the
+// filter needs it, in one round trip, returning the ones the filter keeps
+func (s *Service) lookupKeys(ctx context.Context, keys []string, filter KeyFilter) ([]KeyInfo, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	types := make([]*redis.StatusCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	memory := make([]*redis.IntCmd, len(keys))
+	pipe := s.client.Pipeline()
+	for i, key := range keys {
+		types[i] = pipe.Type(ctx, key)
+		ttls[i] = pipe.TTL(ctx, key)
+		if filter.MinBytes > 0 {
+			memory[i] = pipe.MemoryUsage(ctx, key, max(filter.Samples, 0))
+		}
+	}
+	// Most errors are per key, e.g. a key deleted since the scan, and are
+	// skipped below. TYPE has none of its own, so its failing means the
+	// whole pipeline did.
+	if _, err := pipe.Exec(ctx); err != nil {
+		if err := types[0].Err(); err != nil {
+			return nil, err
+		}
+		if memory[0] != nil && redis.HasErrorPrefix(memory[0].Err(), "unknown command") {
+			return nil, fmt.Errorf("filtering by size needs MEMORY USAGE, which this server doesn't have")
+		}
+	}
 
 	keyInfos := make([]KeyInfo, 0, len(keys))
-	for _, key := range keys {
-		keyType, err := s.client.Type(s.ctx, key).Result()
-		if err != nil {
+	for i, key := range keys {
+		keyType, err := types[i].Result()
+		if err != nil || keyType == "none" {
+			continue
+		}
+		if filter.Type != "" && keyType != filter.Type {
 			continue
 		}
 
-		ttl, err := s.client.TTL(s.ctx, key).Result()
 		ttlSeconds := int64(-1)
-		if err == nil {
+		if ttl, err := ttls[i].Result(); err == nil {
 			switch ttl {
 			case -1:
 				ttlSeconds = -1 // No expiry
@@ -168,26 +272,26 @@ func (s *Service) GetKeys(pattern string, cursor uint64, count int64) (*KeysResu
 				ttlSeconds = int64(ttl.Seconds())
 			}
 		}
+		if filter.ExpiresWithin > 0 && (ttlSeconds < 0 || ttlSeconds > filter.ExpiresWithin) {
+			continue
+		}
 
-		keyInfos = append(keyInfos, KeyInfo{
-			Key:  key,
-			Type: keyType,
-			TTL:  ttlSeconds,
-		})
+		info := KeyInfo{Key: key, Type: keyType, TTL: ttlSeconds}
+		if memory[i] != nil {
+			bytes, err := memory[i].Result()
+			if err != nil || bytes < filter.MinBytes {
+				continue
+			}
+			info.Memory = bytes
+		}
+		keyInfos = append(keyInfos, info)
 	}
-
-	totalCount, _ := s.client.DBSize(s.ctx).Result()
-
-	return &KeysResult{
-		Keys:       keyInfos,
-		TotalCount: totalCount,
-		Cursor:     nextCursor,
-	}, nil
+	return keyInfos, nil
 }
 
 // GetKey returns the value of a key
-func (s *Service) GetKey(key string) (*KeyInfo, error) {
-	keyType, err := s.client.Type(s.ctx, key).Result()
+func (s *Service) GetKey(ctx context.Context, key string) (*KeyInfo, error) {
+	keyType, err := s.client.Type(ctx, key).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -196,7 +300,7 @@ func (s *Service) GetKey(key string) (*KeyInfo, error) {
 		return nil, fmt.Errorf("key does not exist")
 	}
 
-	ttl, _ := s.client.TTL(s.ctx, key).Result()
+	ttl, _ := s.client.TTL(ctx, key).Result()
 	ttlSeconds := int64(-1)
 	if ttl >= 0 {
 		ttlSeconds = int64(ttl.Seconds())
@@ -211,15 +315,21 @@ func (s *Service) GetKey(key string) (*KeyInfo, error) {
 	// Get value based on type
 	switch keyType {
 	case "string":
-		val, err := s.client.Get(s.ctx, key).Result()
+		val, err := s.client.Get(ctx, key).Result()
 		if err != nil {
 			return nil, err
 		}
 		keyInfo.Value = val
 		keyInfo.Size = int64(len(val))
+		if isHyperLogLog(val) {
+			keyInfo.Format = "hyperloglog"
+		} else if isJSONValue(val) {
+			keyInfo.Format = "json"
+			keyInfo.Pretty = prettyJSON(val)
+		}
 
 	case "list":
-		val, err := s.client.LRange(s.ctx, key, 0, -1).Result()
+		val, err := s.client.LRange(ctx, key, 0, -1).Result()
 		if err != nil {
 			return nil, err
 		}
@@ -227,7 +337,7 @@ func (s *Service) GetKey(key string) (*KeyInfo, error) {
 		keyInfo.Size = int64(len(val))
 
 	case "set":
-		val, err := s.client.SMembers(s.ctx, key).Result()
+		val, err := s.client.SMembers(ctx, key).Result()
 		if err != nil {
 			return nil, err
 		}
@@ -235,7 +345,7 @@ func (s *Service) GetKey(key string) (*KeyInfo, error) {
 		keyInfo.Size = int64(len(val))
 
 	case "zset":
-		val, err := s.client.ZRangeWithScores(s.ctx, key, 0, -1).Result()
+		val, err := s.client.ZRangeWithScores(ctx, key, 0, -1).Result()
 		if err != nil {
 			return nil, err
 		}
@@ -250,7 +360,7 @@ func (s *Service) GetKey(key string) (*KeyInfo, error) {
 		keyInfo.Size = int64(len(val))
 
 	case "hash":
-		val, err := s.client.HGetAll(s.ctx, key).Result()
+		val, err := s.client.HGetAll(ctx, key).Result()
 		if err != nil {
 			return nil, err
 		}
@@ -258,12 +368,22 @@ func (s *Service) GetKey(key string) (*KeyInfo, error) {
 		keyInfo.Size = int64(len(val))
 
 	case "stream":
-		val, err := s.client.XRange(s.ctx, key, "-", "+").Result()
+		val, err := s.client.XRange(ctx, key, "-", "+").Result()
+		if err != nil {
+			return nil, err
+		}
+		keyInfo.Value = val
+		keyInfo.Size = int64(len(val))
+
+	case redisJSONType:
+		val, err := s.client.Do(ctx, "JSON.GET", key).Text()
 		if err != nil {
 			return nil, err
 		}
 		keyInfo.Value = val
 		keyInfo.Size = int64(len(val))
+		keyInfo.Format = "json"
+		keyInfo.Pretty = prettyJSON(val)
 
 	default:
 		keyInfo.Value = fmt.Sprintf("Unsupported type: %s", keyType)
@@ -273,7 +393,7 @@ func (s *Service) GetKey(key string) (*KeyInfo, error) {
 }
 
 // SetKey creates or updates a key
-func (s *Service) SetKey(key string, value interface{}, keyType string, ttl int64) error {
+func (s *Service) SetKey(ctx context.Context, key string, value interface{}, keyType string, ttl int64) error {
 	switch keyType {
 	case "string":
 		strVal, ok := value.(string)
@@ -288,7 +408,7 @@ func (s *Service) SetKey(key string, value interface{}, keyType string, ttl int6
 		if ttl > 0 {
 			expiration = time.Duration(ttl) * time.Second
 		}
-		return s.client.Set(s.ctx, key, strVal, expiration).Err()
+		return s.client.Set(ctx, key, strVal, expiration).Err()
 
 	case "list":
 		vals, ok := value.([]interface{})
@@ -296,8 +416,8 @@ func (s *Service) SetKey(key string, value interface{}, keyType string, ttl int6
 			return fmt.Errorf("invalid list value")
 		}
 		if len(vals) > 0 {
-			s.client.Del(s.ctx, key)
-			if err := s.client.RPush(s.ctx, key, vals...).Err(); err != nil {
+			s.client.Del(ctx, key)
+			if err := s.client.RPush(ctx, key, vals...).Err(); err != nil {
 				return err
 			}
 		}
@@ -308,8 +428,8 @@ func (s *Service) SetKey(key string, value interface{}, keyType string, ttl int6
 			return fmt.Errorf("invalid set value")
 		}
 		if len(vals) > 0 {
-			s.client.Del(s.ctx, key)
-			if err := s.client.SAdd(s.ctx, key, vals...).Err(); err != nil {
+			s.client.Del(ctx, key)
+			if err := s.client.SAdd(ctx, key, vals...).Err(); err != nil {
 				return err
 			}
 		}
@@ -321,12 +441,12 @@ func (s *Service) SetKey(key string, value interface{}, keyType string, ttl int6
 		}
 		// Only delete and recreate if we have values to set
 		if len(hashVal) > 0 {
-			s.client.Del(s.ctx, key)
+			s.client.Del(ctx, key)
 			args := make([]interface{}, 0, len(hashVal)*2)
 			for k, v := range hashVal {
 				args = append(args, k, v)
 			}
-			if err := s.client.HSet(s.ctx, key, args...).Err(); err != nil {
+			if err := s.client.HSet(ctx, key, args...).Err(); err != nil {
 				return err
 			}
 		}
@@ -348,26 +468,38 @@ func (s *Service) SetKey(key string, value interface{}, keyType string, ttl int6
 		}
 		// Only delete and recreate if we have members to set
 		if len(members) > 0 {
-			s.client.Del(s.ctx, key)
-			if err := s.client.ZAdd(s.ctx, key, members...).Err(); err != nil {
+			s.client.Del(ctx, key)
+			if err := s.client.ZAdd(ctx, key, members...).Err(); err != nil {
 				return err
 			}
 		}
 
+	case redisJSONType:
+		strVal, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("invalid JSON value")
+		}
+		if err := validateJSON(strVal); err != nil {
+			return err
+		}
+		if err := s.client.Do(ctx, "JSON.SET", key, "$", strVal).Err(); err != nil {
+			return err
+		}
+
 	default:
 		return fmt.Errorf("unsupported key type: %s", keyType)
 	}
 
 	if ttl > 0 && keyType != "string" {
-		s.client.Expire(s.ctx, key, time.Duration(ttl)*time.Second)
+		s.client.Expire(ctx, key, time.Duration(ttl)*time.Second)
 	}
 
 	return nil
 }
 
 // DeleteKey deletes a key
-func (s *Service) DeleteKey(key string) error {
-	result, err := s.client.Del(s.ctx, key).Result()
+func (s *Service) DeleteKey(ctx context.Context, key string) error {
+	result, err := s.client.Del(ctx, key).Result()
 	if err != nil {
 		return err
 	}
@@ -378,13 +510,13 @@ func (s *Service) DeleteKey(key string) error {
 }
 
 // BulkDeleteKeys deletes multiple keys
-func (s *Service) BulkDeleteKeys(keys []string) (int64, error) {
-	return s.client.Del(s.ctx, keys...).Result()
+func (s *Service) BulkDeleteKeys(ctx context.Context, keys []string) (int64, error) {
+	return s.client.Del(ctx, keys...).Result()
 }
 
 // GetTTL returns the TTL of a key
-func (s *Service) GetTTL(key string) (int64, error) {
-	ttl, err := s.client.TTL(s.ctx, key).Result()
+func (s *Service) GetTTL(ctx context.Context, key string) (int64, error) {
+	ttl, err := s.client.TTL(ctx, key).Result()
 	if err != nil {
 		return -1, err
 	}
@@ -397,19 +529,19 @@ func (s *Service) GetTTL(key string) (int64, error) {
 	return int64(ttl.Seconds()), nil
 }
 
-func (s *Service) SetTTL(key string, ttl int64) error {
+func (s *Service) SetTTL(ctx context.Context, key string, ttl int64) error {
 	if ttl <= 0 {
-		return s.client.Persist(s.ctx, key).Err()
+		return s.client.Persist(ctx, key).Err()
 	}
-	return s.client.Expire(s.ctx, key, time.Duration(ttl)*time.Second).Err()
+	return s.client.Expire(ctx, key, time.Duration(ttl)*time.Second).Err()
 }
 
-func (s *Service) RenameKey(oldKey, newKey string) error {
-	return s.client.Rename(s.ctx, oldKey, newKey).Err()
+func (s *Service) RenameKey(ctx context.Context, oldKey, newKey string) error {
+	return s.client.Rename(ctx, oldKey, newKey).Err()
 }
 
 // ExecuteCLI executes a Redis CLI command
-func (s *Service) ExecuteCLI(command string) *CLIResult {
+func (s *Service) ExecuteCLI(ctx context.Context, command string) *CLIResult {
 	start := time.Now()
 	result := &CLIResult{Command: command}
 
@@ -425,7 +557,7 @@ func (s *Service) ExecuteCLI(command string) *CLIResult {
 		args[i] = p
 	}
 
-	res, err := s.client.Do(s.ctx, args...).Result()
+	res, err := s.client.Do(ctx, args...).Result()
 	if err != nil {
 		result.Error = err.Error()
 	} else {
@@ -437,12 +569,12 @@ func (s *Service) ExecuteCLI(command string) *CLIResult {
 }
 
 // SelectDatabase selects a different database
-func (s *Service) SelectDatabase(db int) error {
+func (s *Service) SelectDatabase(ctx context.Context, db int) error {
 	opts := s.client.Options()
 	opts.DB = db
 	newClient := redis.NewClient(opts)
 
-	if err := newClient.Ping(s.ctx).Err(); err != nil {
+	if err := newClient.Ping(ctx).Err(); err != nil {
 		newClient.Close() // Close the new client on error to prevent resource leak
 		return err
 	}
@@ -468,8 +600,8 @@ func (s *Service) GetDatabases() ([]map[string]interface{}, error) {
 }
 
 // FlushDB deletes all keys in the current database
-func (s *Service) FlushDB() error {
-	return s.client.FlushDB(s.ctx).Err()
+func (s *Service) FlushDB(ctx context.Context) error {
+	return s.client.FlushDB(ctx).Err()
 }
 
 func parseCommand(cmd string) []string {
@@ -559,7 +691,7 @@ type ExportedKey struct {
 }
 
 // ExportKeys exports all keys matching pattern to JSON format
-func (s *Service) ExportKeys(pattern string) ([]ExportedKey, error) {
+func (s *Service) ExportKeys(ctx context.Context, pattern string) ([]ExportedKey, error) {
 	if pattern == "" {
 		pattern = "*"
 	}
@@ -567,7 +699,7 @@ func (s *Service) ExportKeys(pattern string) ([]ExportedKey, error) {
 	var allKeys []string
 	var cursor uint64
 	for {
-		keys, nextCursor, err := s.client.Scan(s.ctx, cursor, pattern, 100).Result()
+		keys, nextCursor, err := s.client.Scan(ctx, cursor, pattern, 100).Result()
 		if err != nil {
 			return nil, err
 		}
@@ -580,7 +712,7 @@ func (s *Service) ExportKeys(pattern string) ([]ExportedKey, error) {
 
 	exported := make([]ExportedKey, 0, len(allKeys))
 	for _, key := range allKeys {
-		keyInfo, err := s.GetKey(key)
+		keyInfo, err := s.GetKey(ctx, key)
 		if err != nil {
 			continue
 		}
@@ -596,18 +728,18 @@ func (s *Service) ExportKeys(pattern string) ([]ExportedKey, error) {
 }
 
 // ImportKeys imports keys from exported JSON format
-func (s *Service) ImportKeys(keys []ExportedKey, overwrite bool) (int, int, error) {
+func (s *Service) ImportKeys(ctx context.Context, keys []ExportedKey, overwrite bool) (int, int, error) {
 	imported := 0
 	skipped := 0
 
 	for _, key := range keys {
-		exists, _ := s.client.Exists(s.ctx, key.Key).Result()
+		exists, _ := s.client.Exists(ctx, key.Key).Result()
 		if exists > 0 && !overwrite {
 			skipped++
 			continue
 		}
 
-		if err := s.SetKey(key.Key, key.Value, key.Type, key.TTL); err != nil {
+		if err := s.SetKey(ctx, key.Key, key.Value, key.Type, key.TTL); err != nil {
 			skipped++
 			continue
 		}
@@ -626,19 +758,19 @@ type MemoryInfo struct {
 }
 
 // GetKeyMemory returns memory usage for a specific key
-func (s *Service) GetKeyMemory(key string) (*MemoryInfo, error) {
-	keyType, err := s.client.Type(s.ctx, key).Result()
+func (s *Service) GetKeyMemory(ctx context.Context, key string) (*MemoryInfo, error) {
+	keyType, err := s.client.Type(ctx, key).Result()
 	if err != nil {
 		return nil, err
 	}
 
 	// MEMORY USAGE command (Redis 4.0+)
-	memoryUsed, err := s.client.MemoryUsage(s.ctx, key).Result()
+	memoryUsed, err := s.client.MemoryUsage(ctx, key).Result()
 	if err != nil {
 		memoryUsed = 0
 	}
 
-	ttl, _ := s.client.TTL(s.ctx, key).Result()
+	ttl, _ := s.client.TTL(ctx, key).Result()
 	ttlSeconds := int64(-1)
 	if ttl >= 0 {
 		ttlSeconds = int64(ttl.Seconds())
@@ -653,7 +785,7 @@ func (s *Service) GetKeyMemory(key string) (*MemoryInfo, error) {
 }
 
 // GetMemoryStats returns memory statistics for all keys matching pattern
-func (s *Service) GetMemoryStats(pattern string, limit int) ([]MemoryInfo, map[string]int64, error) {
+func (s *Service) GetMemoryStats(ctx context.Context, pattern string, limit int) ([]MemoryInfo, map[string]int64, error) {
 	if pattern == "" {
 		pattern = "*"
 	}
@@ -664,7 +796,7 @@ func (s *Service) GetMemoryStats(pattern string, limit int) ([]MemoryInfo, map[s
 	var keys []string
 	var cursor uint64
 	for len(keys) < limit {
-		scanned, nextCursor, err := s.client.Scan(s.ctx, cursor, pattern, int64(limit)).Result()
+		scanned, nextCursor, err := s.client.Scan(ctx, cursor, pattern, int64(limit)).Result()
 		if err != nil {
 			return nil, nil, err
 		}
@@ -683,7 +815,7 @@ func (s *Service) GetMemoryStats(pattern string, limit int) ([]MemoryInfo, map[s
 	typeStats := make(map[string]int64)
 
 	for _, key := range keys {
-		info, err := s.GetKeyMemory(key)
+		info, err := s.GetKeyMemory(ctx, key)
 		if err != nil {
 			continue
 		}
@@ -700,8 +832,8 @@ func (s *Service) GetMemoryStats(pattern string, limit int) ([]MemoryInfo, map[s
 }
 
 // GetMemoryOverview returns overall memory statistics
-func (s *Service) GetMemoryOverview() (map[string]interface{}, error) {
-	info, err := s.client.Info(s.ctx, "memory").Result()
+func (s *Service) GetMemoryOverview(ctx context.Context) (map[string]interface{}, error) {
+	info, err := s.client.Info(ctx, "memory").Result()
 	if err != nil {
 		return nil, err
 	}
@@ -734,12 +866,12 @@ type SlowLogEntry struct {
 }
 
 // GetSlowLog returns slow log entries
-func (s *Service) GetSlowLog(count int) ([]SlowLogEntry, error) {
+func (s *Service) GetSlowLog(ctx context.Context, count int) ([]SlowLogEntry, error) {
 	if count <= 0 {
 		count = 50
 	}
 
-	result, err := s.client.SlowLogGet(s.ctx, int64(count)).Result()
+	result, err := s.client.SlowLogGet(ctx, int64(count)).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -766,13 +898,13 @@ func (s *Service) GetSlowLog(count int) ([]SlowLogEntry, error) {
 }
 
 // ResetSlowLog clears the slow log
-func (s *Service) ResetSlowLog() error {
-	return s.client.SlowLogReset(s.ctx).Err()
+func (s *Service) ResetSlowLog(ctx context.Context) error {
+	return s.client.SlowLogReset(ctx).Err()
 }
 
 // GetSlowLogLen returns the number of entries in slow log
-func (s *Service) GetSlowLogLen() (int64, error) {
-	return s.client.Do(s.ctx, "SLOWLOG", "LEN").Int64()
+func (s *Service) GetSlowLogLen(ctx context.Context) (int64, error) {
+	return s.client.Do(ctx, "SLOWLOG", "LEN").Int64()
 }
 
 // ScriptResult represents the result of a Lua script execution
@@ -783,11 +915,11 @@ type ScriptResult struct {
 }
 
 // ExecuteScript executes a Lua script
-func (s *Service) ExecuteScript(script string, keys []string, args []interface{}) *ScriptResult {
+func (s *Service) ExecuteScript(ctx context.Context, script string, keys []string, args []interface{}) *ScriptResult {
 	start := time.Now()
 	result := &ScriptResult{}
 
-	res, err := s.client.Eval(s.ctx, script, keys, args...).Result()
+	res, err := s.client.Eval(ctx, script, keys, args...).Result()
 	if err != nil {
 		result.Error = err.Error()
 	} else {
@@ -799,16 +931,16 @@ func (s *Service) ExecuteScript(script string, keys []string, args []interface{}
 }
 
 // LoadScript loads a script and returns its SHA
-func (s *Service) LoadScript(script string) (string, error) {
-	return s.client.ScriptLoad(s.ctx, script).Result()
+func (s *Service) LoadScript(ctx context.Context, script string) (string, error) {
+	return s.client.ScriptLoad(ctx, script).Result()
 }
 
 // ExecuteScriptBySHA executes a script by its SHA
-func (s *Service) ExecuteScriptBySHA(sha string, keys []string, args []interface{}) *ScriptResult {
+func (s *Service) ExecuteScriptBySHA(ctx context.Context, sha string, keys []string, args []interface{}) *ScriptResult {
 	start := time.Now()
 	result := &ScriptResult{}
 
-	res, err := s.client.EvalSha(s.ctx, sha, keys, args...).Result()
+	res, err := s.client.EvalSha(ctx, sha, keys, args...).Result()
 	if err != nil {
 		result.Error = err.Error()
 	} else {
@@ -820,17 +952,17 @@ func (s *Service) ExecuteScriptBySHA(sha string, keys []string, args []interface
 }
 
 // ScriptExists checks if scripts exist by their SHAs
-func (s *Service) ScriptExists(shas []string) ([]bool, error) {
-	return s.client.ScriptExists(s.ctx, shas...).Result()
+func (s *Service) ScriptExists(ctx context.Context, shas []string) ([]bool, error) {
+	return s.client.ScriptExists(ctx, shas...).Result()
 }
 
 // FlushScripts removes all loaded scripts
-func (s *Service) FlushScripts() error {
-	return s.client.ScriptFlush(s.ctx).Err()
+func (s *Service) FlushScripts(ctx context.Context) error {
+	return s.client.ScriptFlush(ctx).Err()
 }
 
 // BulkSetTTL sets TTL for all keys matching pattern
-func (s *Service) BulkSetTTL(pattern string, ttl int64) (int, error) {
+func (s *Service) BulkSetTTL(ctx context.Context, pattern string, ttl int64) (int, error) {
 	if pattern == "" {
 		return 0, fmt.Errorf("pattern is required")
 	}
@@ -838,7 +970,7 @@ func (s *Service) BulkSetTTL(pattern string, ttl int64) (int, error) {
 	var allKeys []string
 	var cursor uint64
 	for {
-		keys, nextCursor, err := s.client.Scan(s.ctx, cursor, pattern, 100).Result()
+		keys, nextCursor, err := s.client.Scan(ctx, cursor, pattern, 100).Result()
 		if err != nil {
 			return 0, err
 		}
@@ -853,9 +985,9 @@ func (s *Service) BulkSetTTL(pattern string, ttl int64) (int, error) {
 	for _, key := range allKeys {
 		var err error
 		if ttl <= 0 {
-			err = s.client.Persist(s.ctx, key).Err()
+			err = s.client.Persist(ctx, key).Err()
 		} else {
-			err = s.client.Expire(s.ctx, key, time.Duration(ttl)*time.Second).Err()
+			err = s.client.Expire(ctx, key, time.Duration(ttl)*time.Second).Err()
 		}
 		if err == nil {
 			updated++
@@ -866,12 +998,12 @@ func (s *Service) BulkSetTTL(pattern string, ttl int64) (int, error) {
 }
 
 // GetConfig returns Redis configuration
-func (s *Service) GetConfig(pattern string) (map[string]string, error) {
+func (s *Service) GetConfig(ctx context.Context, pattern string) (map[string]string, error) {
 	if pattern == "" {
 		pattern = "*"
 	}
 
-	result, err := s.client.ConfigGet(s.ctx, pattern).Result()
+	result, err := s.client.ConfigGet(ctx, pattern).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -880,18 +1012,18 @@ func (s *Service) GetConfig(pattern string) (map[string]string, error) {
 }
 
 // SetConfig sets a Redis configuration parameter
-func (s *Service) SetConfig(key, value string) error {
-	return s.client.ConfigSet(s.ctx, key, value).Err()
+func (s *Service) SetConfig(ctx context.Context, key, value string) error {
+	return s.client.ConfigSet(ctx, key, value).Err()
 }
 
 // RewriteConfig rewrites the configuration file
-func (s *Service) RewriteConfig() error {
-	return s.client.ConfigRewrite(s.ctx).Err()
+func (s *Service) RewriteConfig(ctx context.Context) error {
+	return s.client.ConfigRewrite(ctx).Err()
 }
 
 // ResetConfigStats resets statistics
-func (s *Service) ResetConfigStats() error {
-	return s.client.ConfigResetStat(s.ctx).Err()
+func (s *Service) ResetConfigStats(ctx context.Context) error {
+	return s.client.ConfigResetStat(ctx).Err()
 }
 
 // ReplicationInfo represents replication status
@@ -906,8 +1038,8 @@ type ReplicationInfo struct {
 }
 
 // GetReplicationInfo returns replication status
-func (s *Service) GetReplicationInfo() (*ReplicationInfo, error) {
-	info, err := s.client.Info(s.ctx, "replication").Result()
+func (s *Service) GetReplicationInfo(ctx context.Context) (*ReplicationInfo, error) {
+	info, err := s.client.Info(ctx, "replication").Result()
 	if err != nil {
 		return nil, err
 	}
@@ -972,13 +1104,13 @@ type ClusterInfo struct {
 }
 
 // GetClusterInfo returns cluster information
-func (s *Service) GetClusterInfo() (*ClusterInfo, error) {
+func (s *Service) GetClusterInfo(ctx context.Context) (*ClusterInfo, error) {
 	result := &ClusterInfo{
 		RawInfo: make(map[string]string),
 	}
 
 	// Check if cluster is enabled
-	info, err := s.client.ClusterInfo(s.ctx).Result()
+	info, err := s.client.ClusterInfo(ctx).Result()
 	if err != nil {
 		// Cluster not enabled
 		result.Enabled = false
@@ -1015,7 +1147,7 @@ func (s *Service) GetClusterInfo() (*ClusterInfo, error) {
 	}
 
 	// Get cluster nodes
-	nodes, err := s.client.ClusterNodes(s.ctx).Result()
+	nodes, err := s.client.ClusterNodes(ctx).Result()
 	if err == nil {
 		result.Nodes = parseClusterNodes(nodes)
 	}
@@ -1066,13 +1198,13 @@ type ACLUser struct {
 }
 
 // GetACLUsers returns list of ACL users
-func (s *Service) GetACLUsers() ([]string, error) {
-	return s.client.ACLList(s.ctx).Result()
+func (s *Service) GetACLUsers(ctx context.Context) ([]string, error) {
+	return s.client.ACLList(ctx).Result()
 }
 
 // GetACLUser returns details for a specific user
-func (s *Service) GetACLUser(username string) (*ACLUser, error) {
-	result, err := s.client.Do(s.ctx, "ACL", "GETUSER", username).Result()
+func (s *Service) GetACLUser(ctx context.Context, username string) (*ACLUser, error) {
+	result, err := s.client.Do(ctx, "ACL", "GETUSER", username).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -1119,28 +1251,28 @@ func (s *Service) GetACLUser(username string) (*ACLUser, error) {
 }
 
 // CreateACLUser creates a new ACL user
-func (s *Service) CreateACLUser(username string, rules []string) error {
+func (s *Service) CreateACLUser(ctx context.Context, username string, rules []string) error {
 	args := make([]interface{}, 0, len(rules)+2)
 	args = append(args, "ACL", "SETUSER", username)
 	for _, rule := range rules {
 		args = append(args, rule)
 	}
-	return s.client.Do(s.ctx, args...).Err()
+	return s.client.Do(ctx, args...).Err()
 }
 
 // DeleteACLUser deletes an ACL user
-func (s *Service) DeleteACLUser(username string) error {
-	_, err := s.client.ACLDelUser(s.ctx, username).Result()
+func (s *Service) DeleteACLUser(ctx context.Context, username string) error {
+	_, err := s.client.ACLDelUser(ctx, username).Result()
 	return err
 }
 
 // GetACLLog returns ACL security log
-func (s *Service) GetACLLog(count int) ([]map[string]interface{}, error) {
+func (s *Service) GetACLLog(ctx context.Context, count int) ([]map[string]interface{}, error) {
 	if count <= 0 {
 		count = 10
 	}
 
-	result, err := s.client.ACLLog(s.ctx, int64(count)).Result()
+	result, err := s.client.ACLLog(ctx, int64(count)).Result()
 	if err != nil {
 		return nil, err
 	}
@@ -1166,29 +1298,29 @@ func (s *Service) GetACLLog(count int) ([]map[string]interface{}, error) {
 }
 
 // ResetACLLog clears the ACL log
-func (s *Service) ResetACLLog() error {
-	return s.client.ACLLogReset(s.ctx).Err()
+func (s *Service) ResetACLLog(ctx context.Context) error {
+	return s.client.ACLLogReset(ctx).Err()
 }
 
 // Publish publishes a message to a channel
-func (s *Service) Publish(channel string, message interface{}) (int64, error) {
-	return s.client.Publish(s.ctx, channel, message).Result()
+func (s *Service) Publish(ctx context.Context, channel string, message interface{}) (int64, error) {
+	return s.client.Publish(ctx, channel, message).Result()
 }
 
 // GetPubSubChannels returns list of active channels
-func (s *Service) GetPubSubChannels(pattern string) ([]string, error) {
+func (s *Service) GetPubSubChannels(ctx context.Context, pattern string) ([]string, error) {
 	if pattern == "" {
 		pattern = "*"
 	}
-	return s.client.PubSubChannels(s.ctx, pattern).Result()
+	return s.client.PubSubChannels(ctx, pattern).Result()
 }
 
 // GetPubSubNumSub returns number of subscribers per channel
-func (s *Service) GetPubSubNumSub(channels []string) (map[string]int64, error) {
-	return s.client.PubSubNumSub(s.ctx, channels...).Result()
+func (s *Service) GetPubSubNumSub(ctx context.Context, channels []string) (map[string]int64, error) {
+	return s.client.PubSubNumSub(ctx, channels...).Result()
 }
 
 // GetPubSubNumPat returns number of pattern subscriptions
-func (s *Service) GetPubSubNumPat() (int64, error) {
-	return s.client.PubSubNumPat(s.ctx).Result()
+func (s *Service) GetPubSubNumPat(ctx context.Context) (int64, error) {
+	return s.client.PubSubNumPat(ctx).Result()
 }
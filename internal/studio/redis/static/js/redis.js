@@ -6,10 +6,16 @@ const RedisStudio = {
     currentKeyTTL: -1,  // Store current key's TTL for preservation
     currentKeyType: 'string',  // Store current key's type
     keys: [],
+    keysPattern: '*',
+    keysCursor: 0,  // Where the next page of the scan starts, 0 when it's done
     commandHistory: [],
     historyIndex: -1,
     currentTab: 'browser',
     terminalInput: null,
+    currentJSONOriginal: null,  // JSON value as loaded, to refuse overwriting someone else's edit
+    eventsSocket: null,
+    events: [],
+    eventsDropped: 0,
 
     // Save state to sessionStorage
     saveState() {
@@ -87,9 +93,20 @@ const RedisStudio = {
     bindEvents() {
         document.getElementById('searchInput')?.addEventListener('input', (e) => this.filterKeys(e.target.value));
         document.getElementById('dbSelect')?.addEventListener('change', (e) => this.selectDatabase(parseInt(e.target.value)));
+        ['keyTypeFilter', 'keyExpiresFilter', 'keyMinSizeFilter'].forEach(id => {
+            document.getElementById(id)?.addEventListener('change', () => this.loadKeys());
+        });
         document.querySelectorAll('.tab').forEach(tab => {
             tab.addEventListener('click', () => this.switchTab(tab.dataset.tab));
         });
+        document.getElementById('eventPatterns')?.addEventListener('change', () => this.updateEventPatterns());
+        document.getElementById('eventsContent')?.addEventListener('click', (e) => {
+            const link = e.target.closest('.event-key');
+            if (!link) return;
+            e.preventDefault();
+            this.switchTab('browser');
+            this.selectKey(link.dataset.key);
+        });
     },
 
     // ===== REAL TERMINAL =====
@@ -184,6 +201,7 @@ const RedisStudio = {
                 '  HSET/HGET         - Hash operations\n' +
                 '  INFO              - Server info\n' +
                 '  FLUSHDB           - Delete all keys in current DB\n' +
+                '  audit             - Show denied and confirmed commands\n' +
                 '  clear             - Clear terminal\n' +
                 '\nSee https://redis.io/commands for full list', false);
             this.createInputLine();
@@ -191,14 +209,31 @@ const RedisStudio = {
             return;
         }
 
+        if (cmd.toLowerCase() === 'audit') {
+            await this.showCLIAudit();
+            this.createInputLine();
+            this.focusTerminal();
+            return;
+        }
+
         try {
-            const response = await fetch('/api/cli', {
-                method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({ command: cmd })
-            });
+            const send = async (confirmToken) => {
+                const response = await fetch('/api/cli', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ command: cmd, confirm_token: confirmToken || '' })
+                });
+                return response.json();
+            };
 
-            const json = await response.json();
+            let json = await send();
+            if (!json.success && json.data && json.data.confirmation_required) {
+                if (confirm('⚠️ ' + json.data.rule + ' is a dangerous command.\n\n' + cmd + '\n\nRun it on db' + this.currentDb + '? This is recorded in the audit trail.')) {
+                    json = await send(json.data.confirm_token);
+                } else {
+                    json = { success: false, message: 'cancelled' };
+                }
+            }
 
             if (!json.success) {
                 this.appendOutput('(error) ' + (json.message || 'Unknown error'), true);
@@ -276,32 +311,65 @@ const RedisStudio = {
 
     // ===== KEY BROWSER =====
     async loadKeys(pattern) {
-        pattern = pattern || '*';
+        this.keysPattern = pattern || this.keysPattern || '*';
         const container = document.getElementById('keysList');
         if (!container) return;
 
         container.innerHTML = '<div class="loading"><div class="spinner"></div></div>';
 
         try {
-            const response = await fetch('/api/keys?pattern=' + encodeURIComponent(pattern) + '&count=100');
-            const json = await response.json();
-            if (!json.success) throw new Error(json.message);
-
-            const data = json.data || {};
+            const data = await this.scanKeys(0);
             this.keys = data.keys || [];
-
-            document.getElementById('keysCount').textContent = this.keys.length + ' keys';
+            this.keysCursor = data.cursor || 0;
             this.renderKeys();
         } catch (error) {
             container.innerHTML = '<div class="empty-state"><p>Error: ' + escapeHtml(error.message) + '</p></div>';
         }
     },
 
+    async loadMoreKeys() {
+        if (!this.keysCursor) return;
+        try {
+            const data = await this.scanKeys(this.keysCursor);
+            const seen = new Set(this.keys.map(k => k.key));
+            // SCAN may return a key more than once
+            this.keys = this.keys.concat((data.keys || []).filter(k => !seen.has(k.key)));
+            this.keysCursor = data.cursor || 0;
+            this.renderKeys();
+        } catch (error) {
+            showToast(error.message, 'error');
+        }
+    },
+
+    // Fetches a page of keys matching the search and filters, which the
+    // server narrows down while scanning
+    async scanKeys(cursor) {
+        let url = '/api/keys?pattern=' + encodeURIComponent(this.keysPattern) + '&count=100&cursor=' + cursor;
+        const type = document.getElementById('keyTypeFilter')?.value;
+        const expires = parseInt(document.getElementById('keyExpiresFilter')?.value);
+        const minKB = parseFloat(document.getElementById('keyMinSizeFilter')?.value);
+        if (type) url += '&type=' + encodeURIComponent(type);
+        if (expires > 0) url += '&expires_within=' + expires;
+        if (minKB > 0) url += '&min_bytes=' + Math.round(minKB * 1024);
+
+        const response = await fetch(url);
+        const json = await response.json();
+        if (!json.success) throw new Error(json.message);
+        return json.data || {};
+    },
+
     renderKeys() {
         const container = document.getElementById('keysList');
         if (!container) return;
 
+        document.getElementById('keysCount').textContent = this.keys.length + (this.keysCursor ? '+' : '') + ' keys';
+
+        const more = this.keysCursor ? '<button class="btn btn-sm load-more-keys" onclick="RedisStudio.loadMoreKeys()">Scan more</button>' : '';
         if (this.keys.length === 0) {
+            if (more) {
+                container.innerHTML = '<div class="empty-state"><p>No matches yet</p></div>' + more;
+                return;
+            }
             container.innerHTML = '<div class="empty-state"><svg width="48" height="48" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="1.5"><path d="M21 21l-6-6m2-5a7 7 0 11-14 0 7 7 0 0114 0z"/></svg><h3>No keys</h3><p>Create a new key to get started</p></div>';
             return;
         }
@@ -314,7 +382,7 @@ const RedisStudio = {
             html += '<div class="key-item ' + isActive + '" onclick="RedisStudio.selectKeyByIndex(' + i + ')">' +
                 '<div class="key-name"><span class="key-type ' + key.type + '">' + key.type + '</span><span>' + escapeHtml(key.key) + '</span></div>' + ttl + '</div>';
         }
-        container.innerHTML = html;
+        container.innerHTML = html + more;
     },
 
     selectKeyByIndex(index) {
@@ -359,7 +427,7 @@ const RedisStudio = {
         const ttlClass = data.ttl === -1 ? 'no-expiry' : '';
 
         container.innerHTML = '<div class="key-header">' +
-            '<div class="key-info"><span class="key-type ' + data.type + '">' + data.type + '</span>' +
+            '<div class="key-info"><span class="key-type ' + (data.format || data.type) + '">' + (data.format || data.type) + '</span>' +
             '<h2>' + escapeHtml(data.key) + '</h2>' +
             '<span class="ttl-badge ' + ttlClass + '">' + ttlText + '</span></div>' +
             '<div class="key-actions">' +
@@ -368,15 +436,38 @@ const RedisStudio = {
             '<button class="btn btn-sm btn-danger" onclick="RedisStudio.deleteCurrentKey()">Delete</button>' +
             '</div></div>' +
             '<div class="key-value-container">' + this.renderValue(data) + '</div>';
+
+        // Structured types load their views separately
+        if (data.format === 'hyperloglog') this.loadHyperLogLog(data.key);
+        else if (data.type === 'stream') this.loadStream(data.key);
     },
 
     renderValue(data) {
+        if (data.format === 'json') {
+            // Edits are checked against the value as loaded
+            this.currentJSONOriginal = data.value;
+            return '<div class="value-display"><div class="value-display-header"><span>JSON (' + (data.value?.length || 0) + ' bytes)</span>' +
+                '<span><button class="copy-btn" onclick="RedisStudio.formatJSONEditor()">Format</button> ' +
+                '<button class="copy-btn" onclick="RedisStudio.copyValue()">Copy</button></span></div>' +
+                '<div class="value-display-body"><textarea class="value-editor" id="valueEditor" spellcheck="false">' + escapeHtml(data.pretty || data.value || '') + '</textarea></div></div>' +
+                '<div class="json-status" id="jsonStatus"></div>' +
+                '<div style="margin-top:12px"><button class="btn btn-success" onclick="RedisStudio.previewJSON()">Review &amp; Save</button></div>' +
+                '<div id="jsonDiff"></div>';
+        }
+        if (data.format === 'hyperloglog') {
+            // The raw registers are binary; editing them would corrupt the estimate
+            return '<div id="hllView"><div class="loading"><div class="spinner"></div></div></div>';
+        }
         switch (data.type) {
             case 'string':
                 return '<div class="value-display"><div class="value-display-header"><span>String (' + (data.value?.length || 0) + ' bytes)</span>' +
-                    '<button class="copy-btn" onclick="RedisStudio.copyValue()">Copy</button></div>' +
+                    '<span><button class="copy-btn" onclick="RedisStudio.showBitInspector()">Bits</button> ' +
+                    '<button class="copy-btn" onclick="RedisStudio.copyValue()">Copy</button></span></div>' +
                     '<div class="value-display-body"><textarea class="value-editor" id="valueEditor">' + escapeHtml(data.value || '') + '</textarea></div></div>' +
-                    '<div style="margin-top:12px"><button class="btn btn-success" onclick="RedisStudio.saveValue()">Save</button></div>';
+                    '<div style="margin-top:12px"><button class="btn btn-success" onclick="RedisStudio.saveValue()">Save</button></div>' +
+                    '<div id="bitInspector"></div>';
+            case 'stream':
+                return '<div id="streamView"><div class="loading"><div class="spinner"></div></div></div>';
             case 'list':
             case 'set':
                 const items = Array.isArray(data.value) ? data.value : [];
@@ -404,6 +495,269 @@ const RedisStudio = {
         }
     },
 
+    // ===== DATA TYPE VIEWS =====
+    async loadStream(key) {
+        const container = document.getElementById('streamView');
+        if (!container) return;
+
+        try {
+            const response = await fetch('/api/key/stream?key=' + encodeURIComponent(key) + '&count=200');
+            const json = await response.json();
+            if (!json.success) throw new Error(json.message);
+            if (this.currentKey !== key) return;
+            container.innerHTML = this.renderStream(json.data);
+        } catch (error) {
+            container.innerHTML = '<div class="empty-state"><p>Error: ' + escapeHtml(error.message) + '</p></div>';
+        }
+    },
+
+    renderStream(info) {
+        let html = '<div class="stats-grid">';
+        html += '<div class="stat-card"><div class="stat-value">' + info.length + '</div><div class="stat-label">Entries</div></div>';
+        html += '<div class="stat-card"><div class="stat-value">' + info.entries_added + '</div><div class="stat-label">Ever Added</div></div>';
+        html += '<div class="stat-card"><div class="stat-value">' + info.groups.length + '</div><div class="stat-label">Groups</div></div>';
+        html += '<div class="stat-card"><div class="stat-value stream-id">' + escapeHtml(info.last_generated_id) + '</div><div class="stat-label">Last ID</div></div>';
+        html += '</div>';
+
+        if (info.groups.length > 0) {
+            html += '<h5 class="stream-heading">Consumer Groups</h5>';
+            html += '<table class="data-table"><thead><tr><th>Group</th><th>Last Delivered</th><th>Pending</th><th>Lag</th><th>Consumers</th></tr></thead><tbody>';
+            for (const g of info.groups) {
+                const lag = g.lag < 0 ? '<span title="Redis can\'t tell, e.g. after entries were deleted">?</span>' : g.lag;
+                const consumers = g.consumers.map(c =>
+                    '<span class="stream-consumer" title="Idle ' + Math.round(c.idle_ms / 1000) + 's">' + escapeHtml(c.name) + ' (' + c.pending + ')</span>').join(' ');
+                html += '<tr><td><code>' + escapeHtml(g.name) + '</code></td>';
+                html += '<td class="stream-id">' + escapeHtml(g.last_delivered_id) + '</td>';
+                html += '<td>' + g.pending + '</td>';
+                html += '<td class="' + (g.lag > 0 ? 'duration' : '') + '">' + lag + '</td>';
+                html += '<td>' + (consumers || '<span style="color:var(--text-tertiary);">none</span>') + '</td></tr>';
+            }
+            html += '</tbody></table>';
+        }
+
+        html += '<h5 class="stream-heading">' + (info.truncated ? 'Latest ' + info.entries.length + ' of ' + info.length + ' entries' : 'Entries') + '</h5>';
+        if (info.entries.length === 0) {
+            return html + '<div class="empty-state"><p>The stream has no entries</p></div>';
+        }
+        html += '<div class="list-items">';
+        for (const entry of info.entries) {
+            html += '<div class="stream-entry"><div class="stream-entry-head"><span class="stream-id">' + escapeHtml(entry.id) + '</span>';
+            for (const [group, state] of Object.entries(entry.groups || {})) {
+                const consumer = entry.consumers && entry.consumers[group] ? ' → ' + entry.consumers[group] : '';
+                html += '<span class="stream-state ' + escapeHtmlAttr(state) + '">' + escapeHtml(group + ': ' + state + consumer) + '</span>';
+            }
+            html += '</div>';
+            for (const [field, value] of Object.entries(entry.fields || {})) {
+                html += '<div class="hash-item"><span class="hash-item-key">' + escapeHtml(field) + '</span><span class="hash-item-value">' + escapeHtml(String(value)) + '</span></div>';
+            }
+            html += '</div>';
+        }
+        return html + '</div>';
+    },
+
+    async loadHyperLogLog(key) {
+        const container = document.getElementById('hllView');
+        if (!container) return;
+
+        try {
+            const response = await fetch('/api/key/hyperloglog?key=' + encodeURIComponent(key));
+            const json = await response.json();
+            if (!json.success) throw new Error(json.message);
+            const info = json.data;
+            container.innerHTML = '<div class="stats-grid">' +
+                '<div class="stat-card"><div class="stat-value">' + info.cardinality.toLocaleString() + '</div><div class="stat-label">Estimated Cardinality</div></div>' +
+                '<div class="stat-card"><div class="stat-value">' + escapeHtml(info.encoding) + '</div><div class="stat-label">Encoding</div></div>' +
+                '<div class="stat-card"><div class="stat-value">' + this.formatBytes(info.bytes) + '</div><div class="stat-label">Size</div></div>' +
+                '</div><p style="color:var(--text-tertiary);font-size:12px;">PFCOUNT estimates with a standard error of 0.81%. Add members with PFADD in the CLI.</p>';
+        } catch (error) {
+            container.innerHTML = '<div class="empty-state"><p>Error: ' + escapeHtml(error.message) + '</p></div>';
+        }
+    },
+
+    showBitInspector() {
+        const container = document.getElementById('bitInspector');
+        if (!container) return;
+        if (container.innerHTML) {
+            container.innerHTML = '';
+            return;
+        }
+
+        container.innerHTML = '<div class="value-display" style="margin-top:12px"><div class="value-display-header">' +
+            '<span>Bits</span><span class="bit-controls">' +
+            '<select id="bitView" class="form-input"><option value="bits">Bitmap</option>' +
+            ['u8', 'i8', 'u16', 'i16', 'u32', 'i32', 'i64'].map(t => '<option value="' + t + '">Bitfield ' + t + '</option>').join('') + '</select>' +
+            '<input type="number" id="bitOffset" class="form-input" value="0" min="0" title="Bit offset" style="width:90px;">' +
+            '<button class="btn btn-sm btn-primary" onclick="RedisStudio.loadBits()">Load</button></span></div>' +
+            '<div class="value-display-body" id="bitContent"></div></div>';
+        this.loadBits();
+    },
+
+    async loadBits() {
+        const content = document.getElementById('bitContent');
+        if (!content || !this.currentKey) return;
+        const view = document.getElementById('bitView').value;
+        const offset = Math.max(0, parseInt(document.getElementById('bitOffset').value) || 0);
+
+        try {
+            if (view === 'bits') {
+                const response = await fetch('/api/key/bitmap?key=' + encodeURIComponent(this.currentKey) + '&offset=' + offset + '&count=1024');
+                const json = await response.json();
+                if (!json.success) throw new Error(json.message);
+                const info = json.data;
+                let html = '<p class="bit-summary">' + info.bit_count + ' bit(s) set of ' + info.bytes * 8 +
+                    (info.first_set >= 0 ? ', first at offset ' + info.first_set : '') + '</p><div class="bit-grid">';
+                for (let i = 0; i < info.bits.length; i += 8) {
+                    if (i % 64 === 0) html += '<span class="bit-offset">' + (info.offset + i) + '</span>';
+                    html += '<span class="bit-byte">';
+                    for (let j = i; j < Math.min(i + 8, info.bits.length); j++) {
+                        html += '<span class="bit' + (info.bits[j] === '1' ? ' set' : '') + '" title="offset ' + (info.offset + j) + '"></span>';
+                    }
+                    html += '</span>';
+                }
+                content.innerHTML = html + '</div>';
+            } else {
+                const response = await fetch('/api/key/bitfield?key=' + encodeURIComponent(this.currentKey) + '&type=' + view + '&offset=' + offset + '&count=64');
+                const json = await response.json();
+                if (!json.success) throw new Error(json.message);
+                let html = '<table class="data-table"><thead><tr><th>Bit Offset</th><th>' + escapeHtml(view) + '</th></tr></thead><tbody>';
+                for (const v of json.data) {
+                    html += '<tr><td>' + v.offset + '</td><td><code>' + v.value + '</code></td></tr>';
+                }
+                content.innerHTML = html + '</tbody></table>';
+            }
+        } catch (error) {
+            content.innerHTML = '<p style="color:var(--red);">Error: ' + escapeHtml(error.message) + '</p>';
+        }
+    },
+
+    // ===== JSON VALUES =====
+    formatJSONEditor() {
+        const editor = document.getElementById('valueEditor');
+        if (!editor) return;
+        try {
+            // Only for layout: the server keeps numbers as written when saving
+            editor.value = JSON.stringify(JSON.parse(editor.value), null, 2);
+            this.setJSONStatus('', false);
+        } catch (error) {
+            this.setJSONStatus(error.message, true);
+        }
+    },
+
+    setJSONStatus(text, isError) {
+        const status = document.getElementById('jsonStatus');
+        if (!status) return;
+        status.textContent = text;
+        status.classList.toggle('error', isError);
+    },
+
+    // Shows what saving would change, validated by the server, before
+    // anything is written
+    async previewJSON() {
+        const editor = document.getElementById('valueEditor');
+        const container = document.getElementById('jsonDiff');
+        if (!editor || !container || !this.currentKey) return;
+
+        try {
+            const response = await fetch('/api/key/json/preview?key=' + encodeURIComponent(this.currentKey), {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ value: editor.value })
+            });
+            const json = await response.json();
+            if (!json.success) {
+                this.showJSONError(json);
+                container.innerHTML = '';
+                return;
+            }
+            this.setJSONStatus('', false);
+            const preview = json.data;
+            if (preview.current !== this.currentJSONOriginal) {
+                this.setJSONStatus('The stored value changed since you loaded it; the diff is against the current value', true);
+                this.currentJSONOriginal = preview.current;
+            }
+            if (!preview.changed) {
+                container.innerHTML = '<p class="json-unchanged">No changes to save</p>';
+                return;
+            }
+            container.innerHTML = '<div class="value-display" style="margin-top:12px"><div class="value-display-header"><span>Changes</span>' +
+                '<span><button class="btn btn-sm" onclick="document.getElementById(\'jsonDiff\').innerHTML=\'\'">Cancel</button> ' +
+                '<button class="btn btn-sm btn-success" onclick="RedisStudio.saveJSON()">Confirm Save</button></span></div>' +
+                '<div class="value-display-body">' + this.renderDiff(preview.diff) + '</div></div>';
+        } catch (error) {
+            showToast(error.message, 'error');
+        }
+    },
+
+    async saveJSON() {
+        const editor = document.getElementById('valueEditor');
+        if (!editor || !this.currentKey) return;
+
+        try {
+            const response = await fetch('/api/key/json?key=' + encodeURIComponent(this.currentKey), {
+                method: 'PUT',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ value: editor.value, original: this.currentJSONOriginal })
+            });
+            const json = await response.json();
+            if (!json.success) {
+                if (response.status === 409) {
+                    // Show the diff against what's stored now
+                    showToast(json.message, 'error', 6000);
+                    this.previewJSON();
+                    return;
+                }
+                this.showJSONError(json);
+                return;
+            }
+            showToast('Value saved', 'success');
+            this.selectKey(this.currentKey);
+        } catch (error) {
+            showToast(error.message, 'error');
+        }
+    },
+
+    // Points at where the server found the value isn't JSON
+    showJSONError(json) {
+        this.setJSONStatus(json.message, true);
+        const editor = document.getElementById('valueEditor');
+        if (!editor || !json.data || !json.data.line) return;
+        const lines = editor.value.split('\n');
+        let pos = 0;
+        for (let i = 0; i < json.data.line - 1 && i < lines.length; i++) pos += lines[i].length + 1;
+        pos = Math.min(pos + json.data.column - 1, editor.value.length);
+        editor.focus();
+        editor.setSelectionRange(pos, pos);
+    },
+
+    // Renders a line diff, folding long runs of unchanged lines
+    renderDiff(diff) {
+        const context = 3;
+        let html = '<div class="json-diff">';
+        for (let i = 0; i < diff.length; i++) {
+            const line = diff[i];
+            if (line.op === ' ') {
+                let end = i;
+                while (end < diff.length && diff[end].op === ' ') end++;
+                const before = i === 0 ? 0 : context;
+                const after = end === diff.length ? 0 : context;
+                if (end - i > before + after + 1) {
+                    for (let j = i; j < i + before; j++) html += this.diffLineHtml(diff[j]);
+                    html += '<div class="diff-fold">⋯ ' + (end - i - before - after) + ' unchanged lines</div>';
+                    for (let j = end - after; j < end; j++) html += this.diffLineHtml(diff[j]);
+                    i = end - 1;
+                    continue;
+                }
+            }
+            html += this.diffLineHtml(line);
+        }
+        return html + '</div>';
+    },
+
+    diffLineHtml(line) {
+        const cls = line.op === '+' ? 'added' : line.op === '-' ? 'removed' : '';
+        return '<div class="diff-line ' + cls + '"><span class="diff-op">' + escapeHtml(line.op) + '</span>' + escapeHtml(line.text) + '</div>';
+    },
+
     async saveValue() {
         if (!this.currentKey) return;
         const editor = document.getElementById('valueEditor');
@@ -478,6 +832,24 @@ const RedisStudio = {
         }
     },
 
+    async showCLIAudit() {
+        try {
+            const response = await fetch('/api/cli/audit');
+            const json = await response.json();
+            if (!json.success) throw new Error(json.message);
+            const entries = json.data || [];
+            if (entries.length === 0) {
+                this.appendOutput('(no dangerous commands run or refused yet)', false);
+                return;
+            }
+            // appendOutput takes HTML
+            this.appendOutput(escapeHtml(entries.map(e => new Date(e.time).toLocaleString() + '  ' + e.outcome.padEnd(8) + ' db' + e.db + '  ' +
+                e.client + '  ' + e.command + (e.error ? '  (error) ' + e.error : '')).join('\n')), false);
+        } catch (error) {
+            this.appendOutput('(error) ' + error.message, true);
+        }
+    },
+
     async runCommand(cmd) {
         try {
             const response = await fetch('/api/cli', {
@@ -503,6 +875,8 @@ const RedisStudio = {
             document.getElementById('keyDetail').innerHTML = '<div class="empty-state"><h3>Select a key</h3></div>';
             // Reinit terminal with new DB
             this.initTerminal();
+            // The feed was of the old database
+            if (this.eventsSocket) this.startEvents();
             showToast('Switched to db' + db, 'info');
             // Save state after database change
             if (shouldSave) this.saveState();
@@ -544,6 +918,7 @@ const RedisStudio = {
         if (tab === 'acl') this.loadACLUsers();
         if (tab === 'cluster') this.loadClusterInfo();
         if (tab === 'pubsub') this.loadChannels();
+        if (tab === 'events' && this.events.length === 0 && !this.eventsSocket) this.renderEvents();
 
         // Save state after tab change
         this.saveState();
@@ -657,6 +1032,11 @@ const RedisStudio = {
     async generateExport() {
         const pattern = document.getElementById('exportPattern')?.value || '*';
         const preview = document.getElementById('exportPreview');
+        if (document.getElementById('exportFormat')?.value === 'resp') {
+            this.exportData = null;
+            preview.innerHTML = '<p style="color:var(--text-secondary);">Command files are written as they download. Load one with <code>cat redis-export.resp | redis-cli --pipe</code> or the Import dialog.</p>';
+            return;
+        }
         preview.innerHTML = '<div class="loading"><div class="spinner"></div></div>';
 
         try {
@@ -674,6 +1054,17 @@ const RedisStudio = {
     },
 
     downloadExport() {
+        if (document.getElementById('exportFormat')?.value === 'resp') {
+            // Streamed by the server rather than built here
+            const pattern = document.getElementById('exportPattern')?.value || '*';
+            const a = document.createElement('a');
+            a.href = BASE_PATH + '/api/export?format=resp&pattern=' + encodeURIComponent(pattern);
+            a.download = 'redis-export-' + new Date().toISOString().slice(0,10) + '.resp';
+            document.body.appendChild(a);
+            a.click();
+            document.body.removeChild(a);
+            return;
+        }
         if (!this.exportData) {
             showToast('Generate export first', 'error');
             return;
@@ -693,6 +1084,10 @@ const RedisStudio = {
     async importKeys() {
         const dataInput = document.getElementById('importData');
         const overwrite = document.getElementById('importOverwrite')?.checked || false;
+        const file = document.getElementById('importFile')?.files[0];
+        if (file) {
+            return this.importCommandFile(file, overwrite);
+        }
 
         if (!dataInput?.value.trim()) {
             showToast('Please enter JSON data', 'error');
@@ -720,6 +1115,41 @@ const RedisStudio = {
         }
     },
 
+    async importCommandFile(file, overwrite) {
+        const result = document.getElementById('importResult');
+        result.innerHTML = '<div class="loading"><div class="spinner"></div></div>';
+        try {
+            const response = await fetch('/api/import/commands?overwrite=' + overwrite, {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/octet-stream' },
+                body: file
+            });
+            const json = await response.json();
+            const data = json.data || {};
+            let html = '<p style="color:var(--text-secondary);">' + (data.commands || 0) + ' commands run, ' + (data.keys || 0) + ' keys written, ' +
+                (data.skipped || 0) + ' existing keys skipped</p>';
+            if (!json.success) {
+                html = '<p style="color:var(--error);">' + escapeHtml(json.message) + '</p>' + html;
+            }
+            if (data.errors && data.errors.length > 0) {
+                html += '<table class="data-table"><thead><tr><th>Line</th><th>Command</th><th>Error</th></tr></thead><tbody>';
+                for (const e of data.errors) {
+                    html += '<tr><td>' + e.line + '</td><td class="command-cell"><code>' + escapeHtml(e.command) + '</code></td><td>' + escapeHtml(e.error) + '</td></tr>';
+                }
+                html += '</tbody></table>';
+            }
+            result.innerHTML = html;
+            if (json.success && data.errors.length === 0) {
+                showToast('Imported ' + data.keys + ' keys', 'success');
+            }
+            document.getElementById('importFile').value = '';
+            this.loadKeys();
+        } catch (error) {
+            result.innerHTML = '';
+            showToast('Import failed: ' + error.message, 'error');
+        }
+    },
+
     showBulkTTLModal() {
         document.getElementById('bulkTTLModal')?.classList.add('active');
     },
@@ -990,6 +1420,113 @@ const RedisStudio = {
         }
     },
 
+    // ===== KEYSPACE EVENTS =====
+    toggleEvents() {
+        if (this.eventsSocket) {
+            this.stopEvents();
+        } else {
+            this.startEvents();
+        }
+    },
+
+    startEvents() {
+        this.stopEvents();
+
+        const classes = Array.from(document.querySelectorAll('#eventClasses input:checked')).map(c => c.value).join('');
+        if (!classes) {
+            showToast('Select at least one event class', 'error');
+            return;
+        }
+        const patterns = document.getElementById('eventPatterns')?.value || '';
+        const url = (location.protocol === 'https:' ? 'wss://' : 'ws://') + location.host + BASE_PATH +
+            '/api/keyspace/events?classes=' + encodeURIComponent(classes) + '&patterns=' + encodeURIComponent(patterns);
+
+        const socket = new WebSocket(url);
+        this.eventsSocket = socket;
+        this.setEventsStatus('Connecting…', false);
+
+        socket.onmessage = (e) => {
+            const msg = JSON.parse(e.data);
+            if (msg.type === 'ready') {
+                this.setEventsStatus('Watching db' + msg.db + ' (' + (msg.config || 'notifications off') + ')', true);
+                if (msg.warning) showToast(msg.warning, 'error', 8000);
+            } else if (msg.type === 'event') {
+                this.events.push(msg);
+                if (this.events.length > 1000) this.events.shift();
+                this.renderEvents();
+            } else if (msg.type === 'dropped') {
+                this.eventsDropped += msg.count;
+                this.renderEvents();
+            } else if (msg.type === 'error') {
+                showToast(msg.message, 'error');
+            }
+        };
+        socket.onclose = () => {
+            if (this.eventsSocket !== socket) return;
+            this.eventsSocket = null;
+            this.setEventsStatus('Not watching', false);
+        };
+    },
+
+    stopEvents() {
+        const socket = this.eventsSocket;
+        this.eventsSocket = null;
+        if (socket) socket.close();
+        this.setEventsStatus('Not watching', false);
+    },
+
+    // Sends the patterns to a running feed, which applies them to the events
+    // that come next
+    updateEventPatterns() {
+        if (!this.eventsSocket || this.eventsSocket.readyState !== WebSocket.OPEN) return;
+        const value = document.getElementById('eventPatterns')?.value || '';
+        const patterns = value.split(/[,\s]+/).filter(p => p);
+        this.eventsSocket.send(JSON.stringify({ patterns: patterns }));
+    },
+
+    setEventsStatus(text, live) {
+        const status = document.getElementById('eventsStatus');
+        const toggle = document.getElementById('eventsToggle');
+        if (status) {
+            status.textContent = text;
+            status.classList.toggle('live', live);
+        }
+        if (toggle) {
+            toggle.textContent = this.eventsSocket ? 'Stop' : 'Start';
+            toggle.classList.toggle('btn-primary', !this.eventsSocket);
+        }
+    },
+
+    clearEvents() {
+        this.events = [];
+        this.eventsDropped = 0;
+        this.renderEvents();
+    },
+
+    renderEvents() {
+        const container = document.getElementById('eventsContent');
+        if (!container) return;
+
+        if (this.events.length === 0) {
+            container.innerHTML = '<div class="empty-state"><h3>' + (this.eventsSocket ? 'Waiting for events…' : 'No events') + '</h3></div>';
+            return;
+        }
+
+        let html = '';
+        if (this.eventsDropped > 0) {
+            html += '<p class="events-dropped">' + this.eventsDropped + ' event(s) came faster than this page read them and aren\'t shown</p>';
+        }
+        html += '<table class="data-table"><thead><tr><th>Time</th><th>Event</th><th>Key</th></tr></thead><tbody>';
+        for (let i = this.events.length - 1; i >= 0; i--) {
+            const ev = this.events[i];
+            html += '<tr><td class="duration">' + escapeHtml(new Date(ev.time).toLocaleTimeString()) + '</td>';
+            html += '<td><span class="event-name event-' + escapeHtmlAttr(ev.event) + '">' + escapeHtml(ev.event) + '</span></td>';
+            html += '<td class="command-cell"><a href="#" class="event-key" data-key="' + escapeHtmlAttr(ev.key) + '"><code>' + escapeHtml(ev.key) + '</code></a></td></tr>';
+        }
+        html += '</tbody></table>';
+        container.innerHTML = html;
+    },
+
     async loadConfig() {
         const pattern = document.getElementById('configPattern')?.value || '*';
         const container = document.getElementById('configContent');
@@ -0,0 +1,70 @@
+package sql
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// adminCookie holds the admin token once studio has been unlocked in the
+// browser
+const adminCookie = "flash_studio_admin"
+
+// isAdmin reports whether a request holds the admin token, sent as a
+// bearer token or kept in the admin cookie. Only the admin role sees masked
+// columns.
+func (s *Server) isAdmin(r *http.Request) bool {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return s.service.IsAdminToken(strings.TrimSpace(token))
+	}
+	if cookie, err := r.Cookie(adminCookie); err == nil {
+		return s.service.IsAdminToken(cookie.Value)
+	}
+	return false
+}
+
+// handleGetAdmin reports whether the request has the admin role, whether
+// any columns are masked and whether an admin token is set to unlock them
+func (s *Server) handleGetAdmin(w http.ResponseWriter, r *http.Request) {
+	common.JSON(w, map[string]any{
+		"admin":     s.isAdmin(r),
+		"masking":   s.service.masksColumns(),
+		"available": s.service.cfg != nil && s.service.cfg.Studio.AdminToken() != "",
+	})
+}
+
+// handleUnlockAdmin checks an admin token and keeps it in the admin cookie
+func (s *Server) handleUnlockAdmin(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := common.ParseJSON(r, &req); err != nil {
+		common.JSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	if !s.service.IsAdminToken(req.Token) {
+		common.JSONError(w, http.StatusForbidden, "Invalid admin token")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     adminCookie,
+		Value:    req.Token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	common.JSONMessage(w, "Masked columns unlocked")
+}
+
+// handleLockAdmin drops the admin cookie
+func (s *Server) handleLockAdmin(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     adminCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	common.JSONMessage(w, "Masked columns locked")
+}
@@ -0,0 +1,185 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+
+	dbcommon "github.com/Lumos-Labs-HQ/flash/internal/database/common"
+)
+
+const (
+	// changeCaptureIdle is how long a change capture runs without being
+	// polled before its replication slot is dropped, so a closed page
+	// doesn't keep the server holding WAL
+	changeCaptureIdle = 30 * time.Second
+	// changeBufferSize is how many of the latest changes a capture keeps
+	// for the pages polling it
+	changeBufferSize = 1000
+	// changeReadLimit bounds the changes read from the slot per poll
+	changeReadLimit = 500
+)
+
+// ChangeEvent is a change read by the change capture, numbered in the order
+// it was read
+type ChangeEvent struct {
+	Seq      int64     `json:"seq"`
+	Received time.Time `json:"received"`
+	dbcommon.Change
+}
+
+// ChangeFeed is the state of the change capture and the changes read since
+// a poll's cursor
+type ChangeFeed struct {
+	Running bool          `json:"running"`
+	Plugin  string        `json:"plugin,omitempty"`
+	Started *time.Time    `json:"started,omitempty"`
+	Changes []ChangeEvent `json:"changes"`
+	// Last is the number of the latest change, the cursor of the next poll
+	Last int64 `json:"last"`
+	// Dropped counts changes since the cursor that were pushed out of the
+	// buffer before they were polled
+	Dropped int64 `json:"dropped,omitempty"`
+}
+
+// changeCapture is the running capture, shared by every page polling it
+type changeCapture struct {
+	capture dbcommon.ChangeCapture
+	started time.Time
+	events  []ChangeEvent
+	last    int64
+	polled  time.Time
+	idle    *time.Timer
+}
+
+// StartChangeCapture starts capturing the changes written to the database
+// through a temporary logical replication slot, or keeps the running
+// capture going. PostgreSQL only.
+func (s *Service) StartChangeCapture(ctx context.Context) (*ChangeFeed, error) {
+	if !s.isPostgres() {
+		return nil, fmt.Errorf("change capture is only supported on PostgreSQL")
+	}
+	opener, ok := s.adapter.(interface {
+		OpenChangeCapture(ctx context.Context) (dbcommon.ChangeCapture, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("change capture is only supported on PostgreSQL")
+	}
+
+	s.changesMu.Lock()
+	defer s.changesMu.Unlock()
+	if s.changes == nil {
+		capture, err := opener.OpenChangeCapture(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c := &changeCapture{capture: capture, started: time.Now()}
+		c.idle = time.AfterFunc(changeCaptureIdle, func() { s.expireChangeCapture(c) })
+		s.changes = c
+	}
+	s.changes.touch()
+	return s.changes.feed(s.changes.last), nil
+}
+
+// PollChanges reads the changes committed since the last poll of any page
+// and returns the ones after the cursor, with the values of masked columns
+// masked unless admin
+func (s *Service) PollChanges(ctx context.Context, after int64, admin bool) (*ChangeFeed, error) {
+	s.changesMu.Lock()
+	defer s.changesMu.Unlock()
+	c := s.changes
+	if c == nil {
+		return &ChangeFeed{Changes: []ChangeEvent{}}, nil
+	}
+	c.touch()
+
+	// The capture's connection outlives the request; a poll given up on
+	// mustn't close it
+	changes, err := c.capture.Read(context.WithoutCancel(ctx), changeReadLimit)
+	if err != nil {
+		s.closeChangeCapture()
+		return nil, fmt.Errorf("change capture stopped: %w", err)
+	}
+	now := time.Now()
+	for _, change := range changes {
+		c.last++
+		c.events = append(c.events, ChangeEvent{Seq: c.last, Received: now, Change: change})
+	}
+	if excess := len(c.events) - changeBufferSize; excess > 0 {
+		c.events = append(c.events[:0:0], c.events[excess:]...)
+	}
+
+	feed := c.feed(after)
+	if !admin {
+		for i := range feed.Changes {
+			feed.Changes[i].Before = s.maskChangeRow(feed.Changes[i].Table, feed.Changes[i].Before)
+			feed.Changes[i].After = s.maskChangeRow(feed.Changes[i].Table, feed.Changes[i].After)
+		}
+	}
+	return feed, nil
+}
+
+// StopChangeCapture stops the running change capture, dropping its slot
+func (s *Service) StopChangeCapture() {
+	s.changesMu.Lock()
+	defer s.changesMu.Unlock()
+	s.closeChangeCapture()
+}
+
+// expireChangeCapture stops a change capture no page polled for a while
+func (s *Service) expireChangeCapture(c *changeCapture) {
+	s.changesMu.Lock()
+	defer s.changesMu.Unlock()
+	if s.changes == c && time.Since(c.polled) >= changeCaptureIdle {
+		s.closeChangeCapture()
+	}
+}
+
+// closeChangeCapture closes the running change capture. The caller holds
+// changesMu.
+func (s *Service) closeChangeCapture() {
+	if s.changes == nil {
+		return
+	}
+	s.changes.idle.Stop()
+	s.changes.capture.Close()
+	s.changes = nil
+}
+
+// touch records a poll, putting off the capture's expiry
+func (c *changeCapture) touch() {
+	c.polled = time.Now()
+	c.idle.Reset(changeCaptureIdle)
+}
+
+// feed returns the buffered changes after a cursor
+func (c *changeCapture) feed(after int64) *ChangeFeed {
+	feed := &ChangeFeed{
+		Running: true,
+		Plugin:  c.capture.Plugin(),
+		Started: &c.started,
+		Changes: []ChangeEvent{},
+		Last:    c.last,
+	}
+	if len(c.events) > 0 && after < c.events[0].Seq-1 {
+		feed.Dropped = c.events[0].Seq - 1 - after
+	}
+	for _, event := range c.events {
+		if event.Seq > after {
+			feed.Changes = append(feed.Changes, event)
+		}
+	}
+	return feed
+}
+
+// maskChangeRow returns a copy of a row of a change with its masked columns
+// masked, leaving the buffered row alone
+func (s *Service) maskChangeRow(table string, row map[string]any) map[string]any {
+	if row == nil || !s.masksColumns() {
+		return row
+	}
+	masked := maps.Clone(row)
+	s.maskRows(table, []map[string]any{masked})
+	return masked
+}
@@ -0,0 +1,248 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+// ColumnRename renames a column from the grid header
+type ColumnRename struct {
+	Table   string `json:"table"`
+	Column  string `json:"column"`
+	NewName string `json:"new_name"`
+}
+
+// ChangesetPreview is the migration the staged table designs add up to, in
+// the order they were staged
+type ChangesetPreview struct {
+	Tables  []*DesignPreview `json:"tables"`
+	SQL     string           `json:"sql"`
+	DownSQL string           `json:"down_sql"`
+	Valid   bool             `json:"valid"`
+
+	up   []string
+	down []string
+}
+
+// GetChangeset previews the staged changes against the current schema
+func (s *Service) GetChangeset(ctx context.Context) (*ChangesetPreview, error) {
+	s.stagedMu.Lock()
+	defer s.stagedMu.Unlock()
+	return s.previewChangeset(ctx)
+}
+
+// StageTableDesign stages a design, replacing the one staged for the same
+// table
+func (s *Service) StageTableDesign(ctx context.Context, design *TableDesign, admin bool) (*ChangesetPreview, error) {
+	if err := s.checkSchemaAccess(admin); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(design.Name) == "" {
+		return nil, fmt.Errorf("table name is required")
+	}
+
+	s.stagedMu.Lock()
+	defer s.stagedMu.Unlock()
+
+	if i := s.stagedIndex(design.Name); i >= 0 {
+		s.staged[i] = design
+	} else {
+		s.staged = append(s.staged, design)
+	}
+	return s.previewChangeset(ctx)
+}
+
+// StageColumnRename stages a column rename on top of the design staged for
+// the table, or of the table as it is when none is
+func (s *Service) StageColumnRename(ctx context.Context, rename *ColumnRename, admin bool) (*ChangesetPreview, error) {
+	if err := s.checkSchemaAccess(admin); err != nil {
+		return nil, err
+	}
+	if rename.Table == "" || rename.Column == "" || rename.NewName == "" {
+		return nil, fmt.Errorf("table, column and new_name are required")
+	}
+
+	s.stagedMu.Lock()
+	defer s.stagedMu.Unlock()
+
+	i := s.stagedIndex(rename.Table)
+	if i < 0 {
+		design, err := s.designFromTable(ctx, rename.Table)
+		if err != nil {
+			return nil, err
+		}
+		s.staged = append(s.staged, design)
+		i = len(s.staged) - 1
+	}
+
+	design := s.staged[i]
+	for j := range design.Columns {
+		col := &design.Columns[j]
+		if !strings.EqualFold(col.Name, rename.Column) {
+			continue
+		}
+		if col.OldName == "" {
+			col.OldName = col.Name
+		}
+		col.Name = rename.NewName
+		return s.previewChangeset(ctx)
+	}
+	return nil, fmt.Errorf("column %s not found in table %s", rename.Column, rename.Table)
+}
+
+// UnstageTable drops the changes staged for one table
+func (s *Service) UnstageTable(ctx context.Context, table string) (*ChangesetPreview, error) {
+	s.stagedMu.Lock()
+	defer s.stagedMu.Unlock()
+
+	i := s.stagedIndex(table)
+	if i < 0 {
+		return nil, fmt.Errorf("no changes staged for table %s", table)
+	}
+	s.staged = append(s.staged[:i], s.staged[i+1:]...)
+	return s.previewChangeset(ctx)
+}
+
+// DiscardChangeset drops every staged change
+func (s *Service) DiscardChangeset() {
+	s.stagedMu.Lock()
+	defer s.stagedMu.Unlock()
+	s.staged = nil
+}
+
+// ApplyChangeset applies every staged change as one migration and clears
+// them. It returns the path of the migration file, empty when there is no
+// flash config to write it to.
+func (s *Service) ApplyChangeset(ctx context.Context, configPath string, admin bool) (*ChangesetPreview, string, error) {
+	if err := s.checkSchemaAccess(admin); err != nil {
+		return nil, "", err
+	}
+	s.stagedMu.Lock()
+	defer s.stagedMu.Unlock()
+
+	if len(s.staged) == 0 {
+		return nil, "", fmt.Errorf("no changes staged")
+	}
+	preview, err := s.previewChangeset(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if !preview.Valid {
+		return preview, "", fmt.Errorf("staged changes have errors; fix or unstage them before applying")
+	}
+	if len(preview.up) == 0 {
+		s.staged = nil
+		return preview, "", fmt.Errorf("the staged changes match the current schema")
+	}
+
+	name := "studio_changes"
+	if len(preview.Tables) == 1 {
+		name = "alter_" + preview.Tables[0].Table
+		if preview.Tables[0].Create {
+			name = "create_" + preview.Tables[0].Table
+		}
+	}
+	path, err := s.applyMigration(ctx, name, preview.up, preview.down, configPath)
+	if err != nil {
+		return preview, "", err
+	}
+	s.staged = nil
+	return preview, path, nil
+}
+
+// previewChangeset plans the staged designs one after the other, so a table
+// staged earlier can be referenced by the ones after it. The caller holds
+// stagedMu.
+func (s *Service) previewChangeset(ctx context.Context) (*ChangesetPreview, error) {
+	tables, err := s.currentTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	changeset := &ChangesetPreview{Tables: []*DesignPreview{}, Valid: true}
+	var downs [][]string
+	for _, design := range s.staged {
+		preview := s.previewDesign(ctx, design, tables)
+		changeset.Tables = append(changeset.Tables, preview)
+		if len(preview.Errors) > 0 {
+			changeset.Valid = false
+			continue
+		}
+		changeset.up = append(changeset.up, preview.up...)
+		downs = append(downs, preview.down)
+		tables[strings.ToLower(design.Name)] = s.designedTable(design, tables[strings.ToLower(design.Name)])
+	}
+	for i := len(downs) - 1; i >= 0; i-- {
+		changeset.down = append(changeset.down, downs[i]...)
+	}
+
+	changeset.SQL = strings.Join(changeset.up, "\n")
+	changeset.DownSQL = strings.Join(changeset.down, "\n")
+	return changeset, nil
+}
+
+func (s *Service) stagedIndex(table string) int {
+	for i, design := range s.staged {
+		if strings.EqualFold(design.Name, table) {
+			return i
+		}
+	}
+	return -1
+}
+
+// designedTable is the table as it is once the design is applied
+func (s *Service) designedTable(design *TableDesign, current *types.SchemaTable) *types.SchemaTable {
+	table := &types.SchemaTable{Name: design.Name}
+	for _, col := range design.Columns {
+		table.Columns = append(table.Columns, s.designColumn(col))
+	}
+	if design.Indexes == nil && current != nil {
+		table.Indexes = current.Indexes
+	}
+	for _, index := range design.Indexes {
+		table.Indexes = append(table.Indexes, designIndex(design.Name, index))
+	}
+	return table
+}
+
+// designFromTable describes a table as it is, for changes to be staged on
+func (s *Service) designFromTable(ctx context.Context, name string) (*TableDesign, error) {
+	s.ensureCorrectSchema(ctx)
+	if s.isIgnoredTable(name) {
+		return nil, fmt.Errorf("table %s is ignored", name)
+	}
+
+	columns, err := s.adapter.GetTableColumns(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of table %s: %w", name, err)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s not found", name)
+	}
+
+	design := &TableDesign{Name: name}
+	for _, col := range columns {
+		if s.isIgnoredColumn(name, col.Name) {
+			continue
+		}
+		change := ColumnChange{
+			Name:          col.Name,
+			Type:          col.Type,
+			Nullable:      col.Nullable,
+			Unique:        col.IsUnique,
+			IsPrimary:     col.IsPrimary,
+			AutoIncrement: col.IsAutoIncrement || strings.Contains(strings.ToLower(col.Default), "nextval"),
+		}
+		if !change.AutoIncrement {
+			change.Default = strings.TrimSpace(defaultCastRegex.ReplaceAllString(col.Default, ""))
+		}
+		if col.ForeignKeyTable != "" {
+			change.ForeignKey = &ForeignKeyChange{Table: col.ForeignKeyTable, Column: col.ForeignKeyColumn}
+		}
+		design.Columns = append(design.Columns, change)
+	}
+	return design, nil
+}
@@ -0,0 +1,187 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+const (
+	defaultChartPoints = 50
+	maxChartPoints     = 1000
+	chartTimeout       = 30 * time.Second
+)
+
+var chartBuckets = []string{"hour", "day", "week", "month", "year"}
+
+// ChartOptions select what ChartData aggregates
+type ChartOptions struct {
+	GroupBy   string
+	Aggregate string // count (the default), sum or avg
+	Column    string // the column summed or averaged
+	Bucket    string // hour, day, week, month or year to group a time column by
+	Limit     int    // number of groups, defaultChartPoints when zero
+}
+
+// ChartData aggregates a table grouped by a column, for bar charts, or by
+// time buckets of a date column, for line charts. Groups are the largest
+// ones first; time buckets are the latest ones, in time order. Masked
+// columns can't be charted unless admin is set.
+func (s *Service) ChartData(ctx context.Context, tableName string, opts ChartOptions, admin bool) (*common.ChartSeries, error) {
+	s.ensureCorrectSchema(ctx)
+	if s.isIgnoredTable(tableName) {
+		return nil, fmt.Errorf("table %s is ignored", tableName)
+	}
+
+	if opts.Aggregate == "" {
+		opts.Aggregate = "count"
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = defaultChartPoints
+	}
+	if opts.Limit > maxChartPoints {
+		opts.Limit = maxChartPoints
+	}
+	if opts.Bucket != "" && !slices.Contains(chartBuckets, opts.Bucket) {
+		return nil, fmt.Errorf("invalid bucket %s, use: %s", opts.Bucket, strings.Join(chartBuckets, ", "))
+	}
+	if !admin {
+		if err := s.checkUnmasked(tableName, opts.GroupBy, opts.Column); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, chartTimeout)
+	defer cancel()
+
+	columns, err := s.adapter.GetTableColumns(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	groupBy, err := s.chartColumn(tableName, columns, opts.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	series := &common.ChartSeries{
+		Table:     tableName,
+		GroupBy:   opts.GroupBy,
+		Aggregate: opts.Aggregate,
+		Bucket:    opts.Bucket,
+		Points:    []common.ChartPoint{},
+	}
+
+	var aggregate string
+	switch opts.Aggregate {
+	case "count":
+		aggregate, series.Label = "COUNT(*)", "count"
+	case "sum", "avg":
+		column, err := s.chartColumn(tableName, columns, opts.Column)
+		if err != nil {
+			return nil, err
+		}
+		if !isNumericColumnType(strings.ToLower(column.Type)) {
+			return nil, fmt.Errorf("%s of %s needs a numeric column, it is %s", opts.Aggregate, column.Name, column.Type)
+		}
+		aggregate = fmt.Sprintf("CAST(%s(%s) AS %s)", strings.ToUpper(opts.Aggregate), s.quoteIdentifier(column.Name), s.floatType())
+		series.Column = column.Name
+		series.Label = fmt.Sprintf("%s(%s)", opts.Aggregate, column.Name)
+	default:
+		return nil, fmt.Errorf("invalid aggregate %s, use: count, sum, avg", opts.Aggregate)
+	}
+
+	x := s.quoteIdentifier(groupBy.Name)
+	where, order := "", "2 DESC, 1"
+	if opts.Bucket != "" {
+		colType := strings.ToLower(groupBy.Type)
+		// SQLite has no date types, dates are usually TEXT
+		if !s.isSQLite() && !containsAny(colType, "date", "time") {
+			return nil, fmt.Errorf("%s is %s, time buckets need a date or time column", groupBy.Name, groupBy.Type)
+		}
+		where = fmt.Sprintf(" WHERE %s IS NOT NULL", x)
+		x, order = s.timeBucket(x, opts.Bucket), "1 DESC"
+	}
+
+	// One more row than asked tells whether there are more groups
+	result, err := s.adapter.ExecuteQuery(ctx, fmt.Sprintf("SELECT %s AS x, %s AS y FROM %s%s GROUP BY 1 ORDER BY %s LIMIT %d",
+		x, aggregate, s.quoteIdentifier(tableName), where, order, opts.Limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range result.Rows {
+		if len(series.Points) == opts.Limit {
+			series.Truncated = true
+			break
+		}
+		y, _ := profileFloat(row["y"])
+		series.Points = append(series.Points, common.ChartPoint{X: chartX(row["x"], opts.Bucket), Y: y})
+	}
+	if opts.Bucket != "" {
+		slices.Reverse(series.Points)
+	}
+	return series, nil
+}
+
+func (s *Service) chartColumn(tableName string, columns []types.SchemaColumn, name string) (types.SchemaColumn, error) {
+	if name == "" {
+		return types.SchemaColumn{}, fmt.Errorf("a column is required")
+	}
+	for _, col := range columns {
+		if col.Name == name && !s.isIgnoredColumn(tableName, name) {
+			return col, nil
+		}
+	}
+	return types.SchemaColumn{}, fmt.Errorf("column %s not found in %s", name, tableName)
+}
+
+// timeBucket truncates a date column to the start of its hour, day, week
+// (Monday), month or year
+func (s *Service) timeBucket(column, bucket string) string {
+	switch s.provider() {
+	case "mysql":
+		switch bucket {
+		case "hour":
+			return "DATE_FORMAT(" + column + ", '%Y-%m-%d %H:00:00')"
+		case "day":
+			return "DATE(" + column + ")"
+		case "week":
+			return "DATE(DATE_SUB(" + column + ", INTERVAL WEEKDAY(" + column + ") DAY))"
+		case "month":
+			return "DATE_FORMAT(" + column + ", '%Y-%m-01')"
+		}
+		return "DATE_FORMAT(" + column + ", '%Y-01-01')"
+	case "sqlite", "sqlite3":
+		switch bucket {
+		case "hour":
+			return "strftime('%Y-%m-%d %H:00:00', " + column + ")"
+		case "day":
+			return "date(" + column + ")"
+		case "week":
+			return "date(" + column + ", '-' || ((CAST(strftime('%w', " + column + ") AS INTEGER) + 6) % 7) || ' days')"
+		case "month":
+			return "strftime('%Y-%m-01', " + column + ")"
+		}
+		return "strftime('%Y-01-01', " + column + ")"
+	}
+	return fmt.Sprintf("date_trunc('%s', %s)", bucket, column)
+}
+
+// chartX formats time buckets the same way for every provider
+func chartX(value any, bucket string) any {
+	switch v := value.(type) {
+	case time.Time:
+		if bucket == "hour" {
+			return v.Format("2006-01-02 15:04:05")
+		}
+		return v.Format("2006-01-02")
+	case []byte:
+		return string(v)
+	}
+	return value
+}
@@ -0,0 +1,196 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// Kinds of columns the data editor has editors for
+const (
+	kindBoolean   = "boolean"
+	kindInteger   = "integer"
+	kindNumber    = "number"
+	kindDate      = "date"
+	kindTimestamp = "timestamp"
+	kindTime      = "time"
+	kindEnum      = "enum"
+	kindJSON      = "json"
+	kindUUID      = "uuid"
+	kindText      = "text"
+)
+
+// describeColumns sets the kind of each column, and the values an enum
+// column allows. SQLite reports booleans and dates by their storage class,
+// so their declared types are read instead; PostgreSQL enums are named
+// types whose values come from pg_enum.
+func (s *Service) describeColumns(ctx context.Context, tableName string, columns []common.ColumnInfo) {
+	declared := make(map[string]string)
+	if s.isSQLite() {
+		if result, err := s.adapter.ExecuteQuery(ctx, "SELECT name, type FROM pragma_table_info(?)", tableName); err == nil {
+			for _, row := range result.Rows {
+				declared[fmt.Sprint(row["name"])] = fmt.Sprint(row["type"])
+			}
+		}
+	}
+
+	var enums map[string][]string
+	if s.isPostgres() {
+		enums = make(map[string][]string)
+		if enumTypes, err := s.getEnumTypes(ctx); err == nil {
+			for _, enum := range enumTypes {
+				enums[enum.Name] = enum.Values
+			}
+		}
+	}
+
+	for i := range columns {
+		colType := columns[i].Type
+		if t, ok := declared[columns[i].Name]; ok && t != "" {
+			colType = t
+		}
+		if values, ok := enums[colType]; ok {
+			columns[i].Kind = kindEnum
+			columns[i].EnumValues = values
+			continue
+		}
+		if matches := inlineEnumRegex.FindStringSubmatch(strings.TrimSpace(colType)); matches != nil {
+			columns[i].Kind = kindEnum
+			columns[i].EnumValues = enumValues(matches[1])
+			continue
+		}
+		columns[i].Kind = columnKind(colType)
+		columns[i].TimeZone = s.isPostgres() && storesInstant(colType)
+	}
+}
+
+// columnKind returns the kind of a column from its type
+func columnKind(colType string) string {
+	colType = strings.ToLower(strings.TrimSpace(colType))
+	switch {
+	case strings.Contains(colType, "bool") || colType == "tinyint(1)" || colType == "bit(1)":
+		return kindBoolean
+	case containsAny(colType, "int", "serial") && !containsAny(colType, "interval", "point"):
+		return kindInteger
+	case isNumericColumnType(colType):
+		return kindNumber
+	case strings.Contains(colType, "uuid"):
+		return kindUUID
+	case strings.Contains(colType, "json"):
+		return kindJSON
+	case strings.HasPrefix(colType, "timestamp") || strings.HasPrefix(colType, "datetime"):
+		return kindTimestamp
+	case colType == "date":
+		return kindDate
+	case strings.HasPrefix(colType, "time"):
+		return kindTime
+	}
+	return kindText
+}
+
+// enumValues splits the quoted values of a MySQL ENUM type
+func enumValues(values string) []string {
+	var result []string
+	for _, value := range strings.Split(values, ",") {
+		value = strings.TrimSpace(value)
+		result = append(result, strings.ReplaceAll(strings.Trim(value, "'"), "''", "'"))
+	}
+	return result
+}
+
+// checkValue returns an InvalidValueError when a value doesn't fit the kind
+// of its column, so a bad edit is reported before reaching the database
+func checkValue(col common.ColumnInfo, value any) error {
+	invalid := func(format string, args ...any) error {
+		return &InvalidValueError{Column: col.Name, Message: fmt.Sprintf(format, args...)}
+	}
+	if value == nil {
+		if !col.Nullable && !col.PrimaryKey {
+			return invalid("can't be NULL")
+		}
+		return nil
+	}
+
+	text, isText := value.(string)
+	switch col.Kind {
+	case kindBoolean:
+		if _, ok := boolValue(value); !ok {
+			return invalid("%v isn't a boolean", value)
+		}
+	case kindInteger:
+		if _, err := strconv.ParseInt(strings.TrimSpace(fmt.Sprint(value)), 10, 64); err != nil {
+			return invalid("%v isn't an integer", value)
+		}
+	case kindNumber:
+		if _, err := strconv.ParseFloat(strings.TrimSpace(fmt.Sprint(value)), 64); err != nil {
+			return invalid("%v isn't a number", value)
+		}
+	case kindDate:
+		if _, err := time.Parse("2006-01-02", strings.TrimSpace(text)); !isText || err != nil {
+			return invalid("%v isn't a date, use YYYY-MM-DD", value)
+		}
+	case kindTimestamp:
+		if _, ok := value.(time.Time); ok {
+			break
+		}
+		if !isText || !isPasteTime(strings.TrimSpace(text)) {
+			return invalid("%v isn't a date and time, use YYYY-MM-DD HH:MM:SS", value)
+		}
+	case kindTime:
+		if !isText || !isPasteTime(strings.TrimSpace(text)) {
+			return invalid("%v isn't a time, use HH:MM:SS", value)
+		}
+	case kindEnum:
+		if !isText || !slices.Contains(col.EnumValues, text) {
+			return invalid("%v isn't one of %s", value, strings.Join(col.EnumValues, ", "))
+		}
+	case kindUUID:
+		if !isText || !uuidRegex.MatchString(strings.TrimSpace(text)) {
+			return invalid("%v isn't a UUID", value)
+		}
+	case kindJSON:
+		if isText && !json.Valid([]byte(text)) {
+			return invalid("isn't valid JSON")
+		}
+	}
+	return nil
+}
+
+// boolValue reads a boolean sent as true or false, 1 or 0, or as text
+func boolValue(value any) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case json.Number, float64, int64:
+		switch fmt.Sprint(v) {
+		case "1":
+			return true, true
+		case "0":
+			return false, true
+		}
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "t", "yes", "y", "1":
+			return true, true
+		case "false", "f", "no", "n", "0":
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// InvalidValueError rejects a value that doesn't fit its column
+type InvalidValueError struct {
+	Column  string
+	Message string
+}
+
+func (e *InvalidValueError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Column, e.Message)
+}
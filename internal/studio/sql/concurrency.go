@@ -0,0 +1,135 @@
+package sql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// RowConflict is a row someone else changed since it was read. Current is
+// nil when the row was deleted.
+type RowConflict struct {
+	RowID   string         `json:"row_id"`
+	Version string         `json:"version,omitempty"`
+	Current map[string]any `json:"current"`
+}
+
+// RowConflictError rejects a write to rows that changed since they were
+// read, with their current values so the user can merge
+type RowConflictError struct {
+	Conflicts []RowConflict
+}
+
+func (e *RowConflictError) Error() string {
+	return fmt.Sprintf("%d row(s) were changed by someone else since you loaded them", len(e.Conflicts))
+}
+
+// rowVersion hashes the values of a row, so a write can tell whether the
+// row changed since it was read. Any table gets one, with or without an
+// updated_at column.
+func rowVersion(row map[string]any) string {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	h := sha256.New()
+	for _, column := range columns {
+		fmt.Fprintf(h, "%s=%v\x00", column, row[column])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// currentRows reads the rows with the given primary key values, keyed by
+// the value. Versions are taken from rows read this way, so reading and
+// checking them hash the same representation.
+func (s *Service) currentRows(ctx context.Context, tableName, pkColumn string, ids []string) (map[string]map[string]any, error) {
+	rows := make(map[string]map[string]any, len(ids))
+	if len(ids) == 0 {
+		return rows, nil
+	}
+
+	// Bound in batches, as drivers limit the arguments of a query
+	const batch = 500
+	for start := 0; start < len(ids); start += batch {
+		end := min(start+batch, len(ids))
+		args := make([]any, 0, end-start)
+		for _, id := range ids[start:end] {
+			args = append(args, id)
+		}
+		result, err := s.adapter.ExecuteQuery(ctx, fmt.Sprintf("SELECT * FROM %s WHERE %s IN (%s)",
+			common.QuoteIdentifier(tableName), common.QuoteIdentifier(pkColumn), s.placeholders(len(args))), args...)
+		if err != nil {
+			return nil, err
+		}
+		s.stripIgnoredColumns(tableName, result.Rows)
+		for _, row := range result.Rows {
+			rows[exportText(row[pkColumn])] = row
+		}
+	}
+	return rows, nil
+}
+
+// rowVersions returns the versions of the given rows keyed by primary key
+func (s *Service) rowVersions(ctx context.Context, tableName, pkColumn string, page []map[string]any) (map[string]string, error) {
+	ids := make([]string, 0, len(page))
+	for _, row := range page {
+		if id, ok := row[pkColumn]; ok && id != nil {
+			ids = append(ids, exportText(id))
+		}
+	}
+	rows, err := s.currentRows(ctx, tableName, pkColumn, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string]string, len(rows))
+	for id, row := range rows {
+		versions[id] = rowVersion(row)
+	}
+	return versions, nil
+}
+
+// checkRowVersions returns a RowConflictError when any row no longer has
+// the version it was read with. Rows without an expected version aren't
+// checked.
+func (s *Service) checkRowVersions(ctx context.Context, tableName, pkColumn string, expected map[string]string) error {
+	var ids []string
+	for id, version := range expected {
+		if version != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	sort.Strings(ids)
+
+	rows, err := s.currentRows(ctx, tableName, pkColumn, ids)
+	if err != nil {
+		return fmt.Errorf("failed to check for concurrent changes: %w", err)
+	}
+
+	var conflicts []RowConflict
+	for _, id := range ids {
+		row, ok := rows[id]
+		if !ok {
+			conflicts = append(conflicts, RowConflict{RowID: id})
+			continue
+		}
+		if version := rowVersion(row); version != expected[id] {
+			exactRowValues([]map[string]any{row})
+			s.maskRows(tableName, []map[string]any{row})
+			conflicts = append(conflicts, RowConflict{RowID: id, Version: version, Current: row})
+		}
+	}
+	if len(conflicts) > 0 {
+		return &RowConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
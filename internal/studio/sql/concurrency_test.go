@@ -0,0 +1,50 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/database/sqlite"
+)
+
+// Row versions are read by bound primary key values, in batches, so quotes
+// and backslashes in them and pages of any size work
+func TestCurrentRowsBindsKeys(t *testing.T) {
+	ctx := context.Background()
+	adapter := sqlite.New()
+	if err := adapter.Connect(ctx, "sqlite://"+filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer adapter.Close()
+
+	ids := []string{"it's", `dir\`}
+	values := []string{`('it''s')`, `('dir\')`}
+	for i := range 1200 {
+		ids = append(ids, fmt.Sprintf("tag%d", i))
+		values = append(values, fmt.Sprintf("('tag%d')", i))
+	}
+	if err := adapter.ExecuteMigration(ctx, "CREATE TABLE tags (name TEXT PRIMARY KEY);\nINSERT INTO tags (name) VALUES "+strings.Join(values, ", ")+";"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	cfg.Database.Provider = "sqlite"
+	s := NewService(adapter, cfg)
+
+	rows, err := s.currentRows(ctx, "tags", "name", append(ids, "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(ids) {
+		t.Errorf("read %d rows, want %d", len(rows), len(ids))
+	}
+	for _, id := range []string{"it's", `dir\`, "tag1199"} {
+		if rows[id] == nil {
+			t.Errorf("row %q wasn't read", id)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	dbcommon "github.com/Lumos-Labs-HQ/flash/internal/database/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+var (
+	ddlStatementRegex = regexp.MustCompile(`(?i)^(CREATE|ALTER|DROP|RENAME|COMMENT\s+ON)\b`)
+	// The verb, kind and name of a DDL statement, e.g. CREATE TABLE users
+	ddlNameRegex       = regexp.MustCompile(`(?i)^(CREATE|ALTER|DROP)\s+(?:OR\s+REPLACE\s+)?(?:UNIQUE\s+)?(?:MATERIALIZED\s+)?(\w+)\s+(?:IF\s+(?:NOT\s+)?EXISTS\s+)?([\w."` + "`" + `]+)`)
+	migrationNameRegex = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// RecordsDDL reports whether DDL run from the SQL editor is recorded as a
+// migration unless the request says otherwise
+func (s *Service) RecordsDDL() bool {
+	return s.cfg != nil && s.cfg.Studio.RecordDDL
+}
+
+// IsDDL reports whether any statement of a script changes the schema
+func IsDDL(query string) bool {
+	for _, stmt := range dbcommon.ParseSQLStatements(query) {
+		if ddlStatementRegex.MatchString(strings.TrimSpace(stmt)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExecuteDDLAsMigration runs a script from the SQL editor as a new
+// migration: it's written to the migrations directory and recorded in the
+// migrations table in the same transaction it runs in, so flash apply
+// doesn't run it again and flash status shows it. Without a name, one is
+// made from the first DDL statement.
+func (s *Service) ExecuteDDLAsMigration(ctx context.Context, query, name, configPath string) (*common.TableData, error) {
+	if configPath == "" || s.cfg == nil {
+		return nil, fmt.Errorf("recording DDL as a migration needs a flash.config.json")
+	}
+	s.ensureCorrectSchema(ctx)
+
+	statements := dbcommon.ParseSQLStatements(query)
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("query is empty")
+	}
+	if name = migrationName(name); name == "" {
+		name = ddlMigrationName(statements)
+	}
+
+	up := make([]string, len(statements))
+	for i, stmt := range statements {
+		up[i] = strings.TrimSuffix(strings.TrimSpace(stmt), ";") + ";"
+	}
+	path, err := s.applyMigration(ctx, name, up, nil, configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &common.TableData{
+		Columns:   []common.ColumnInfo{},
+		Rows:      []map[string]any{},
+		Page:      1,
+		Migration: path,
+	}, nil
+}
+
+// ddlMigrationName names a migration after its first DDL statement, e.g.
+// create_table_users
+func ddlMigrationName(statements []string) string {
+	for _, stmt := range statements {
+		if m := ddlNameRegex.FindStringSubmatch(strings.TrimSpace(stmt)); m != nil {
+			table := m[3]
+			if i := strings.LastIndex(table, "."); i >= 0 {
+				table = table[i+1:]
+			}
+			if name := migrationName(m[1] + "_" + m[2] + "_" + table); name != "" {
+				return name
+			}
+		}
+	}
+	return "studio_sql"
+}
+
+// migrationName makes a name safe for a migration file name
+func migrationName(name string) string {
+	return strings.Trim(migrationNameRegex.ReplaceAllString(strings.ToLower(name), "_"), "_")
+}
@@ -0,0 +1,103 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// maxCascadeDepth bounds how far ON DELETE CASCADE chains are followed, so
+// self-referencing tables don't recurse forever
+const maxCascadeDepth = 8
+
+// PreviewDelete reports, without changing anything, how many rows in which
+// tables deleting the given rows would delete, set to NULL or default, or be
+// blocked by, following the ON DELETE action of every foreign key.
+func (s *Service) PreviewDelete(ctx context.Context, tableName string, rowIDs []string) (*common.DeleteImpact, error) {
+	s.ensureCorrectSchema(ctx)
+	if s.isIgnoredTable(tableName) {
+		return nil, fmt.Errorf("table %s is ignored", tableName)
+	}
+	if len(rowIDs) == 0 {
+		return nil, fmt.Errorf("no rows selected")
+	}
+
+	fks, primaryKeys, err := s.loadForeignKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	condition := s.rowCondition(primaryKeys, tableName, rowIDs...)
+	rows, err := s.countRows(ctx, tableName, condition)
+	if err != nil {
+		return nil, err
+	}
+
+	impact := &common.DeleteImpact{Table: tableName, Rows: rows, Effects: []common.DeleteEffect{}}
+	if rows > 0 {
+		if err := s.walkDeleteImpact(ctx, fks, impact, tableName, condition, []string{tableName}); err != nil {
+			return nil, err
+		}
+	}
+	return impact, nil
+}
+
+// walkDeleteImpact counts the rows referencing the rows of tableName that
+// match condition, and follows cascades into the tables they delete from
+func (s *Service) walkDeleteImpact(ctx context.Context, fks []foreignKey, impact *common.DeleteImpact, tableName, condition string, path []string) error {
+	for _, fk := range fks {
+		if fk.refTable != tableName {
+			continue
+		}
+
+		where := fmt.Sprintf("%s IN (SELECT %s FROM %s WHERE %s)",
+			s.quoteIdentifier(fk.column), s.quoteIdentifier(fk.refColumn), s.quoteIdentifier(tableName), condition)
+		rows, err := s.countRows(ctx, fk.table, where)
+		if err != nil {
+			return fmt.Errorf("failed to count %s rows referencing %s: %w", fk.table, tableName, err)
+		}
+		if rows == 0 {
+			continue
+		}
+
+		onDelete := fk.onDelete
+		if onDelete == "" {
+			onDelete = "NO ACTION"
+		}
+		childPath := append(append([]string(nil), path...), fk.table)
+		effect := common.DeleteEffect{
+			Table:           fk.table,
+			Column:          fk.column,
+			ReferencedTable: tableName,
+			OnDelete:        onDelete,
+			Rows:            rows,
+			Path:            childPath,
+		}
+
+		switch onDelete {
+		case "CASCADE":
+			effect.Action = "delete"
+		case "SET NULL":
+			effect.Action = "set null"
+		case "SET DEFAULT":
+			effect.Action = "set default"
+		default:
+			effect.Action = "restrict"
+			impact.Blocked = true
+		}
+		impact.Effects = append(impact.Effects, effect)
+
+		if effect.Action != "delete" {
+			continue
+		}
+		if len(childPath) > maxCascadeDepth {
+			impact.Truncated = true
+			continue
+		}
+		if err := s.walkDeleteImpact(ctx, fks, impact, fk.table, where, childPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
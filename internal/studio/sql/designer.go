@@ -0,0 +1,651 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+	"github.com/Lumos-Labs-HQ/flash/internal/utils"
+)
+
+// TableDesign is the whole definition of a table as the designer wants it.
+// A column with OldName renames an existing column. Indexes left out (nil)
+// stay as they are; an empty list drops every index of the table.
+type TableDesign struct {
+	Name    string         `json:"name"`
+	Columns []ColumnChange `json:"columns"`
+	Indexes []IndexDesign  `json:"indexes,omitempty"`
+}
+
+type IndexDesign struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique,omitempty"`
+}
+
+// DesignPreview is the migration turning the current table into the design.
+// A design with errors can't be applied; warnings are for review.
+type DesignPreview struct {
+	Table    string   `json:"table"`
+	Create   bool     `json:"create"`
+	SQL      string   `json:"sql"`
+	DownSQL  string   `json:"down_sql"`
+	Changes  []string `json:"changes"`
+	Warnings []string `json:"warnings"`
+	Errors   []string `json:"errors"`
+
+	up   []string
+	down []string
+}
+
+var designIdentifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// defaultCastRegex matches the casts postgres reports defaults with, as in
+// 'active'::character varying
+var defaultCastRegex = regexp.MustCompile(`::[A-Za-z_][\w ]*(\[\])?$`)
+
+// PreviewTableDesign validates a table design against the current schema
+// and returns the migration SQL that would apply it, without changing
+// anything.
+func (s *Service) PreviewTableDesign(ctx context.Context, design *TableDesign) (*DesignPreview, error) {
+	tables, err := s.currentTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.previewDesign(ctx, design, tables), nil
+}
+
+// currentTables reads the tables of the database by lowercased name
+func (s *Service) currentTables(ctx context.Context) (map[string]*types.SchemaTable, error) {
+	s.ensureCorrectSchema(ctx)
+
+	tables, err := s.adapter.GetCurrentSchema(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current schema: %w", err)
+	}
+	tablesByName := make(map[string]*types.SchemaTable, len(tables))
+	for i := range tables {
+		tablesByName[strings.ToLower(tables[i].Name)] = &tables[i]
+	}
+	return tablesByName, nil
+}
+
+// previewDesign plans a design against the given tables, which foreign keys
+// and index names are checked against
+func (s *Service) previewDesign(ctx context.Context, design *TableDesign, tablesByName map[string]*types.SchemaTable) *DesignPreview {
+	preview := &DesignPreview{
+		Table:    design.Name,
+		Changes:  []string{},
+		Warnings: []string{},
+		Errors:   []string{},
+	}
+	current := tablesByName[strings.ToLower(design.Name)]
+	preview.Create = current == nil
+
+	s.validateDesign(design, current, tablesByName, preview)
+	if len(preview.Errors) > 0 {
+		return preview
+	}
+
+	if current == nil {
+		s.planCreateTable(design, preview)
+	} else {
+		s.planAlterTable(ctx, design, current, preview)
+	}
+
+	preview.SQL = strings.Join(preview.up, "\n")
+	preview.DownSQL = strings.Join(preview.down, "\n")
+	return preview
+}
+
+// ApplyTableDesign applies a design as one migration and returns the path
+// of the migration file, empty when there is no flash config to write it to
+func (s *Service) ApplyTableDesign(ctx context.Context, design *TableDesign, configPath string, admin bool) (*DesignPreview, string, error) {
+	if err := s.checkSchemaAccess(admin); err != nil {
+		return nil, "", err
+	}
+	preview, err := s.PreviewTableDesign(ctx, design)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(preview.Errors) > 0 {
+		return preview, "", fmt.Errorf("table design has %d error(s): %s", len(preview.Errors), preview.Errors[0])
+	}
+	if len(preview.up) == 0 {
+		return preview, "", fmt.Errorf("table %s already matches the design", design.Name)
+	}
+
+	verb := "alter"
+	if preview.Create {
+		verb = "create"
+	}
+	path, err := s.applyMigration(ctx, fmt.Sprintf("%s_%s", verb, design.Name), preview.up, preview.down, configPath)
+	if err != nil {
+		return preview, "", err
+	}
+	return preview, path, nil
+}
+
+// applyMigration runs the statements in one transaction. With a flash
+// config they are also written to the migrations directory with their down
+// statements and recorded as applied, so flash apply doesn't run them again.
+func (s *Service) applyMigration(ctx context.Context, name string, up, down []string, configPath string) (string, error) {
+	if configPath == "" || s.cfg == nil {
+		if err := s.adapter.ExecuteMigration(ctx, strings.Join(up, "\n")); err != nil {
+			return "", fmt.Errorf("failed to apply schema change: %w", err)
+		}
+		return "", nil
+	}
+
+	if err := os.MkdirAll(s.cfg.MigrationsPath, 0755); err != nil {
+		return "", fmt.Errorf("failed to create migrations directory: %w", err)
+	}
+	if err := s.adapter.CreateMigrationsTable(ctx); err != nil {
+		return "", fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	fileUtils := &utils.FileUtils{}
+	filename := fileUtils.GenerateMigrationFilename(name)
+	path := filepath.Join(s.cfg.MigrationsPath, filename)
+	content := formatStudioMigration(name, up, down)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write migration file: %w", err)
+	}
+
+	// Same id and checksum as flash apply gives the file
+	id := strings.TrimSuffix(filename, ".sql")
+	checksum := fmt.Sprintf("%x", len(content))
+	if err := s.adapter.ExecuteAndRecordMigration(ctx, id, id, checksum, strings.Join(up, "\n")); err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to apply schema change: %w", err)
+	}
+
+	if err := s.syncSchemaFile(ctx, configPath); err != nil {
+		fmt.Printf("Warning: failed to sync schema file: %v\n", err)
+	}
+	return path, nil
+}
+
+// formatStudioMigration lays out a migration file the way flash migrate does
+func formatStudioMigration(name string, up, down []string) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("-- Migration: %s\n", name))
+	builder.WriteString(fmt.Sprintf("-- Created: %s\n", time.Now().Format("2006-01-02T15:04:05Z")))
+	builder.WriteString("-- Generated by Studio\n\n")
+
+	builder.WriteString("-- +migrate Up\n")
+	for _, stmt := range up {
+		builder.WriteString(stmt + "\n")
+	}
+
+	builder.WriteString("\n-- +migrate Down\n")
+	for _, stmt := range down {
+		builder.WriteString(stmt + "\n")
+	}
+	if len(down) == 0 {
+		builder.WriteString("-- Add rollback statements here\n")
+	}
+	return builder.String()
+}
+
+// validateDesign records in the preview every problem that would make the
+// migration fail or that the design can't express
+func (s *Service) validateDesign(design *TableDesign, current *types.SchemaTable, tables map[string]*types.SchemaTable, preview *DesignPreview) {
+	fail := func(format string, args ...any) {
+		preview.Errors = append(preview.Errors, fmt.Sprintf(format, args...))
+	}
+
+	if !designIdentifierRegex.MatchString(design.Name) {
+		fail("invalid table name %q", design.Name)
+		return
+	}
+	if s.isIgnoredTable(design.Name) {
+		fail("table %s is ignored", design.Name)
+		return
+	}
+	if len(design.Columns) == 0 {
+		fail("table %s needs at least one column", design.Name)
+		return
+	}
+
+	currentColumns := make(map[string]types.SchemaColumn)
+	if current != nil {
+		for _, col := range current.Columns {
+			currentColumns[strings.ToLower(col.Name)] = col
+		}
+	}
+
+	columns := make(map[string]bool, len(design.Columns))
+	renamed := make(map[string]bool)
+	primaryKeys := 0
+	for _, col := range design.Columns {
+		lower := strings.ToLower(col.Name)
+		if !designIdentifierRegex.MatchString(col.Name) {
+			fail("invalid column name %q", col.Name)
+			continue
+		}
+		if columns[lower] {
+			fail("column %s is defined more than once", col.Name)
+		}
+		columns[lower] = true
+		if strings.TrimSpace(col.Type) == "" {
+			fail("column %s has no type", col.Name)
+		}
+		if col.IsPrimary {
+			primaryKeys++
+		}
+
+		if from := sourceColumn(col, currentColumns); !strings.EqualFold(from, col.Name) {
+			if renamed[strings.ToLower(from)] {
+				fail("column %s is renamed more than once", from)
+			}
+			renamed[strings.ToLower(from)] = true
+		} else if col.OldName != "" && !strings.EqualFold(col.OldName, col.Name) && current != nil {
+			if _, ok := currentColumns[lower]; !ok {
+				fail("column %s is renamed from %s, which table %s doesn't have", col.Name, col.OldName, design.Name)
+			}
+		}
+
+		if col.ForeignKey != nil {
+			s.validateForeignKey(design, col, tables, fail)
+		}
+	}
+	if primaryKeys > 1 {
+		fail("table %s has %d primary key columns, the designer supports one", design.Name, primaryKeys)
+	}
+
+	// Index names are shared by every table of the database
+	indexNames := make(map[string]bool)
+	for _, index := range design.Indexes {
+		lower := strings.ToLower(index.Name)
+		if !designIdentifierRegex.MatchString(index.Name) {
+			fail("invalid index name %q", index.Name)
+			continue
+		}
+		if indexNames[lower] {
+			fail("index %s is defined more than once", index.Name)
+		}
+		indexNames[lower] = true
+		if len(index.Columns) == 0 {
+			fail("index %s has no columns", index.Name)
+		}
+		for _, column := range index.Columns {
+			if !columns[strings.ToLower(column)] {
+				fail("index %s is on column %s, which the design doesn't have", index.Name, column)
+			}
+		}
+		for _, table := range tables {
+			if strings.EqualFold(table.Name, design.Name) {
+				continue
+			}
+			for _, other := range table.Indexes {
+				if strings.EqualFold(other.Name, index.Name) {
+					fail("index name %s is already used by table %s", index.Name, table.Name)
+				}
+			}
+		}
+	}
+}
+
+func (s *Service) validateForeignKey(design *TableDesign, col ColumnChange, tables map[string]*types.SchemaTable, fail func(string, ...any)) {
+	fk := col.ForeignKey
+	if fk.Table == "" || fk.Column == "" {
+		fail("foreign key of column %s needs a table and a column", col.Name)
+		return
+	}
+
+	// A self-reference is checked against the design
+	if strings.EqualFold(fk.Table, design.Name) {
+		for _, other := range design.Columns {
+			if strings.EqualFold(other.Name, fk.Column) {
+				return
+			}
+		}
+		fail("column %s references %s.%s, which the design doesn't have", col.Name, fk.Table, fk.Column)
+		return
+	}
+
+	target := tables[strings.ToLower(fk.Table)]
+	if target == nil {
+		fail("column %s references table %s, which doesn't exist", col.Name, fk.Table)
+		return
+	}
+	for _, other := range target.Columns {
+		if strings.EqualFold(other.Name, fk.Column) {
+			if !other.IsPrimary && !other.IsUnique {
+				fail("column %s references %s.%s, which is neither a primary key nor unique", col.Name, fk.Table, fk.Column)
+			}
+			return
+		}
+	}
+	fail("column %s references %s.%s, which doesn't exist", col.Name, fk.Table, fk.Column)
+}
+
+func (s *Service) planCreateTable(design *TableDesign, preview *DesignPreview) {
+	table := types.SchemaTable{Name: design.Name}
+	for _, col := range design.Columns {
+		table.Columns = append(table.Columns, s.designColumn(col))
+	}
+	preview.up = append(preview.up, s.adapter.GenerateCreateTableSQL(table))
+	preview.Changes = append(preview.Changes, fmt.Sprintf("Create table %s with %d column(s)", design.Name, len(design.Columns)))
+
+	for _, index := range design.Indexes {
+		preview.up = append(preview.up, s.adapter.GenerateAddIndexSQL(designIndex(design.Name, index)))
+		preview.Changes = append(preview.Changes, fmt.Sprintf("Create index %s", index.Name))
+	}
+	preview.down = append(preview.down, fmt.Sprintf("DROP TABLE %s;", s.quoteIdentifier(design.Name)))
+}
+
+// planAlterTable diffs the design against the current table. Statements run
+// as: dropped indexes, renames, dropped, added and changed columns, new
+// indexes; the down statements undo them in reverse.
+func (s *Service) planAlterTable(ctx context.Context, design *TableDesign, current *types.SchemaTable, preview *DesignPreview) {
+	var downs [][]string
+	step := func(change string, up, down []string) {
+		preview.Changes = append(preview.Changes, change)
+		preview.up = append(preview.up, up...)
+		downs = append(downs, down)
+	}
+	fail := func(format string, args ...any) {
+		preview.Errors = append(preview.Errors, fmt.Sprintf(format, args...))
+	}
+
+	currentColumns := make(map[string]types.SchemaColumn, len(current.Columns))
+	for _, col := range current.Columns {
+		currentColumns[strings.ToLower(col.Name)] = col
+	}
+	rowCount, _ := s.adapter.GetTableRowCount(ctx, current.Name)
+
+	currentIndexes, _ := s.adapter.GetTableIndexes(ctx, current.Name)
+	currentIndexNames := make(map[string]types.SchemaIndex)
+	for _, index := range currentIndexes {
+		// SQLite backs UNIQUE constraints with indexes the table owns
+		if strings.HasPrefix(index.Name, "sqlite_autoindex_") {
+			continue
+		}
+		currentIndexNames[strings.ToLower(index.Name)] = index
+	}
+	designIndexNames := make(map[string]IndexDesign)
+	for _, index := range design.Indexes {
+		designIndexNames[strings.ToLower(index.Name)] = index
+	}
+
+	// Indexes are dropped first, so the columns they cover can change
+	if design.Indexes != nil {
+		for _, index := range currentIndexes {
+			wanted, ok := designIndexNames[strings.ToLower(index.Name)]
+			if _, tracked := currentIndexNames[strings.ToLower(index.Name)]; !tracked {
+				continue
+			}
+			if ok && sameIndex(wanted, index) {
+				continue
+			}
+			step(fmt.Sprintf("Drop index %s", index.Name),
+				[]string{s.adapter.GenerateDropIndexSQL(index)},
+				[]string{s.adapter.GenerateAddIndexSQL(index)})
+		}
+	}
+
+	// Columns of the design matched to the current ones
+	kept := make(map[string]bool)
+	for _, col := range design.Columns {
+		existing, ok := currentColumns[strings.ToLower(sourceColumn(col, currentColumns))]
+		if !ok {
+			continue
+		}
+		kept[strings.ToLower(existing.Name)] = true
+
+		if existing.Name != col.Name {
+			step(fmt.Sprintf("Rename column %s to %s", existing.Name, col.Name),
+				[]string{s.renameColumnSQL(current.Name, existing.Name, col.Name)},
+				[]string{s.renameColumnSQL(current.Name, col.Name, existing.Name)})
+		}
+	}
+
+	for _, col := range current.Columns {
+		if kept[strings.ToLower(col.Name)] || s.isIgnoredColumn(current.Name, col.Name) {
+			continue
+		}
+		if rowCount > 0 {
+			preview.Warnings = append(preview.Warnings,
+				fmt.Sprintf("dropping column %s deletes its data in %d row(s); the down migration restores the column, not the data", col.Name, rowCount))
+		}
+		step(fmt.Sprintf("Drop column %s", col.Name),
+			[]string{s.adapter.GenerateDropColumnSQL(current.Name, col.Name)},
+			s.addColumnSQL(current.Name, col))
+	}
+
+	for _, col := range design.Columns {
+		existing, ok := currentColumns[strings.ToLower(sourceColumn(col, currentColumns))]
+		wanted := s.designColumn(col)
+
+		if !ok {
+			if !wanted.Nullable && wanted.Default == "" && !wanted.IsAutoIncrement && rowCount > 0 {
+				fail("column %s is NOT NULL without a default, but table %s has %d row(s)", col.Name, current.Name, rowCount)
+			}
+			if s.isSQLite() && (wanted.IsPrimary || wanted.IsUnique) {
+				fail("SQLite can't add the PRIMARY KEY or UNIQUE column %s to an existing table", col.Name)
+			}
+			step(fmt.Sprintf("Add column %s %s", col.Name, wanted.Type),
+				s.addColumnSQL(current.Name, wanted),
+				[]string{s.adapter.GenerateDropColumnSQL(current.Name, col.Name)})
+			continue
+		}
+
+		existing.Name = col.Name
+		if existing.IsPrimary != wanted.IsPrimary || (existing.IsUnique != wanted.IsUnique && !wanted.IsPrimary) ||
+			!sameForeignKey(existing, wanted) {
+			fail("changing the primary key, unique or foreign key of column %s isn't supported; drop and add the column instead", col.Name)
+			continue
+		}
+		if !s.columnChanged(existing, wanted) {
+			continue
+		}
+		if s.isSQLite() {
+			fail("SQLite can't change the type, nullability or default of column %s without rebuilding table %s", col.Name, current.Name)
+			continue
+		}
+		if existing.Nullable && !wanted.Nullable && rowCount > 0 {
+			preview.Warnings = append(preview.Warnings,
+				fmt.Sprintf("column %s becomes NOT NULL; the migration fails if any of the %d row(s) has NULL in it", col.Name, rowCount))
+		}
+		step(fmt.Sprintf("Change column %s", col.Name),
+			s.modifyColumnSQL(current.Name, existing, wanted),
+			s.modifyColumnSQL(current.Name, wanted, existing))
+	}
+
+	for _, index := range design.Indexes {
+		if existing, ok := currentIndexNames[strings.ToLower(index.Name)]; ok && sameIndex(index, existing) {
+			continue
+		}
+		created := designIndex(current.Name, index)
+		step(fmt.Sprintf("Create index %s", index.Name),
+			[]string{s.adapter.GenerateAddIndexSQL(created)},
+			[]string{s.adapter.GenerateDropIndexSQL(created)})
+	}
+
+	for i := len(downs) - 1; i >= 0; i-- {
+		preview.down = append(preview.down, downs[i]...)
+	}
+}
+
+// designColumn converts a designer column to the schema form the adapters
+// generate SQL from
+func (s *Service) designColumn(col ColumnChange) types.SchemaColumn {
+	colType := col.Type
+	if col.AutoIncrement && s.isPostgres() {
+		switch strings.ToUpper(col.Type) {
+		case "INTEGER":
+			colType = "SERIAL"
+		case "BIGINT":
+			colType = "BIGSERIAL"
+		case "SMALLINT":
+			colType = "SMALLSERIAL"
+		}
+	}
+
+	column := types.SchemaColumn{
+		Name:            col.Name,
+		Type:            colType,
+		Nullable:        col.Nullable && !col.IsPrimary,
+		IsPrimary:       col.IsPrimary,
+		IsUnique:        col.Unique,
+		IsAutoIncrement: col.AutoIncrement,
+	}
+	if col.Default != "" && !col.AutoIncrement {
+		column.Default = sanitizeDefaultValue(col.Default, colType)
+	}
+	if col.ForeignKey != nil {
+		column.ForeignKeyTable = col.ForeignKey.Table
+		column.ForeignKeyColumn = col.ForeignKey.Column
+	}
+	return column
+}
+
+// addColumnSQL adds a column with its foreign key, which only the postgres
+// adapter writes inline
+func (s *Service) addColumnSQL(table string, col types.SchemaColumn) []string {
+	sql := s.adapter.GenerateAddColumnSQL(table, col)
+	if col.ForeignKeyTable == "" || s.isPostgres() {
+		return []string{sql}
+	}
+
+	reference := fmt.Sprintf("REFERENCES %s(%s)", s.quoteIdentifier(col.ForeignKeyTable), s.quoteIdentifier(col.ForeignKeyColumn))
+	if col.OnDeleteAction != "" {
+		reference += " ON DELETE " + col.OnDeleteAction
+	}
+	if col.OnUpdateAction != "" {
+		reference += " ON UPDATE " + col.OnUpdateAction
+	}
+	if s.isSQLite() {
+		return []string{strings.TrimSuffix(sql, ";") + " " + reference + ";"}
+	}
+	return []string{sql, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) %s;",
+		s.quoteIdentifier(table), s.quoteIdentifier(fmt.Sprintf("fk_%s_%s", table, col.Name)), s.quoteIdentifier(col.Name), reference)}
+}
+
+func (s *Service) renameColumnSQL(table, from, to string) string {
+	return fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s;", s.quoteIdentifier(table), s.quoteIdentifier(from), s.quoteIdentifier(to))
+}
+
+// modifyColumnSQL changes the type, nullability and default of a column
+// from one definition to the other
+func (s *Service) modifyColumnSQL(table string, from, to types.SchemaColumn) []string {
+	if !s.isPostgres() {
+		to.ForeignKeyTable, to.IsPrimary, to.IsUnique = "", false, false
+		return []string{fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s;",
+			s.quoteIdentifier(table), s.quoteIdentifier(to.Name), s.adapter.FormatColumnType(to))}
+	}
+
+	alter := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", s.quoteIdentifier(table), s.quoteIdentifier(to.Name))
+	var statements []string
+	if toType := serialBaseType(to.Type); !sameType(s.adapter.MapColumnType(serialBaseType(from.Type)), s.adapter.MapColumnType(toType)) {
+		statements = append(statements, fmt.Sprintf("%s TYPE %s USING %s::%s;", alter, toType, s.quoteIdentifier(to.Name), toType))
+	}
+	if from.Nullable != to.Nullable && !to.IsPrimary {
+		if to.Nullable {
+			statements = append(statements, alter+" DROP NOT NULL;")
+		} else {
+			statements = append(statements, alter+" SET NOT NULL;")
+		}
+	}
+	if !sameDefault(from.Default, to.Default) && !to.IsAutoIncrement {
+		if to.Default == "" {
+			statements = append(statements, alter+" DROP DEFAULT;")
+		} else {
+			statements = append(statements, fmt.Sprintf("%s SET DEFAULT %s;", alter, to.Default))
+		}
+	}
+	return statements
+}
+
+// columnChanged reports whether the design changes the type, nullability or
+// default of an existing column
+func (s *Service) columnChanged(existing, wanted types.SchemaColumn) bool {
+	if !sameType(s.adapter.MapColumnType(serialBaseType(existing.Type)), s.adapter.MapColumnType(serialBaseType(wanted.Type))) {
+		return true
+	}
+	if existing.Nullable != wanted.Nullable && !wanted.IsPrimary {
+		return true
+	}
+	if strings.Contains(strings.ToLower(existing.Default), "nextval") || wanted.IsAutoIncrement {
+		return false
+	}
+	return !sameDefault(existing.Default, wanted.Default)
+}
+
+// sourceColumn is the current column a design column is made from: the one
+// it's renamed from, or, once the rename is applied, the one of its name
+func sourceColumn(col ColumnChange, currentColumns map[string]types.SchemaColumn) string {
+	if col.OldName != "" {
+		if _, ok := currentColumns[strings.ToLower(col.OldName)]; ok {
+			return col.OldName
+		}
+	}
+	return col.Name
+}
+
+// serialBaseType is the integer type behind a postgres serial type, which
+// can only be used when a column is created
+func serialBaseType(colType string) string {
+	switch strings.ToUpper(colType) {
+	case "SERIAL":
+		return "INTEGER"
+	case "BIGSERIAL":
+		return "BIGINT"
+	case "SMALLSERIAL":
+		return "SMALLINT"
+	}
+	return colType
+}
+
+func designIndex(table string, index IndexDesign) types.SchemaIndex {
+	return types.SchemaIndex{Name: index.Name, Table: table, Columns: index.Columns, Unique: index.Unique}
+}
+
+func sameIndex(design IndexDesign, index types.SchemaIndex) bool {
+	if design.Unique != index.Unique || len(design.Columns) != len(index.Columns) {
+		return false
+	}
+	for i := range design.Columns {
+		if !strings.EqualFold(design.Columns[i], index.Columns[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameForeignKey(a, b types.SchemaColumn) bool {
+	return strings.EqualFold(a.ForeignKeyTable, b.ForeignKeyTable) && strings.EqualFold(a.ForeignKeyColumn, b.ForeignKeyColumn)
+}
+
+func sameType(a, b string) bool {
+	return strings.EqualFold(strings.Join(strings.Fields(a), " "), strings.Join(strings.Fields(b), " "))
+}
+
+// sameDefault compares defaults as the database reports them with the ones
+// the designer writes, ignoring casts, quotes and case. SQLite and MySQL
+// store booleans as 1 and 0.
+func sameDefault(a, b string) bool {
+	normalize := func(value string) string {
+		value = strings.TrimSpace(value)
+		for defaultCastRegex.MatchString(value) {
+			value = strings.TrimSpace(defaultCastRegex.ReplaceAllString(value, ""))
+		}
+		value = strings.Trim(value, "()")
+		value = strings.ToUpper(strings.Trim(value, "'"))
+		switch value {
+		case "TRUE":
+			return "1"
+		case "FALSE":
+			return "0"
+		}
+		return value
+	}
+	return normalize(a) == normalize(b)
+}
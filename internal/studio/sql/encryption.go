@@ -0,0 +1,59 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// ciphertextPrefix starts the values generated clients encrypted
+const ciphertextPrefix = "flash:v1:"
+
+// isEncryptedColumn reports whether generated clients encrypt a column of
+// a table, per encryption.columns
+func (s *Service) isEncryptedColumn(table, column string) bool {
+	return s.cfg != nil && s.cfg.Encryption.IsEncryptedColumn(table, column)
+}
+
+// markEncryptedColumns flags the encrypted columns of a table. Their cells
+// can't be edited, since studio would store the value unencrypted.
+func (s *Service) markEncryptedColumns(table string, columns []common.ColumnInfo) {
+	for i := range columns {
+		if s.isEncryptedColumn(table, columns[i].Name) {
+			columns[i].Encrypted = true
+			columns[i].ReadOnly = true
+		}
+	}
+}
+
+// describeEncryptedRows replaces the values of the encrypted columns of rows
+// of a table with the length of their ciphertext, which isn't worth
+// rendering. Values written before the column was encrypted are described
+// as unencrypted. NULL stays NULL.
+func (s *Service) describeEncryptedRows(table string, rows []map[string]any) {
+	if s.cfg == nil || !s.cfg.Encryption.Enabled() {
+		return
+	}
+	for _, row := range rows {
+		for column, value := range row {
+			if value == nil || !s.isEncryptedColumn(table, column) {
+				continue
+			}
+			var text string
+			switch v := value.(type) {
+			case string:
+				text = v
+			case []byte:
+				text = string(v)
+			default:
+				continue
+			}
+			if strings.HasPrefix(text, ciphertextPrefix) {
+				row[column] = fmt.Sprintf("encrypted, %d bytes", len(text))
+			} else {
+				row[column] = fmt.Sprintf("unencrypted, %d bytes", len(text))
+			}
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+)
+
+// Studio shows the length of encrypted values rather than their ciphertext,
+// and leaves other columns and NULLs alone
+func TestDescribeEncryptedRows(t *testing.T) {
+	tests := []struct {
+		column string
+		value  any
+		want   any
+	}{
+		{"ssn", "flash:v1:abcdefgh", "encrypted, 17 bytes"},
+		{"ssn", []byte("flash:v1:abc"), "encrypted, 12 bytes"},
+		{"ssn", "123-45-6789", "unencrypted, 11 bytes"},
+		{"ssn", nil, nil},
+		{"name", "flash:v1:abc", "flash:v1:abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.column, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Encryption.Columns = []string{"users.ssn"}
+			s := &Service{cfg: cfg}
+
+			rows := []map[string]any{{tt.column: tt.value}}
+			s.describeEncryptedRows("users", rows)
+			if got := rows[0][tt.column]; got != tt.want {
+				t.Errorf("%v shown as %#v, want %#v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/schema"
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+// EnumInfo is an enum with the columns that use it
+type EnumInfo struct {
+	Name    string      `json:"name"`
+	Values  []string    `json:"values"`
+	Columns []EnumUsage `json:"columns"`
+}
+
+type EnumUsage struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	Array  bool   `json:"array,omitempty"`
+}
+
+// EnumValueChange adds a value (Value, with Before or After to place it)
+// or renames one (From to To)
+type EnumValueChange struct {
+	Value  string `json:"value,omitempty"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+}
+
+// EnumChangeResult is the migration an enum change was applied as
+type EnumChangeResult struct {
+	Enum      *EnumInfo `json:"enum"`
+	SQL       string    `json:"sql"`
+	Migration string    `json:"migration,omitempty"`
+}
+
+// GetEnums lists the enums of the database and the columns of each
+func (s *Service) GetEnums(ctx context.Context) ([]*EnumInfo, error) {
+	if !s.isPostgres() {
+		return nil, fmt.Errorf("enum types are only supported on PostgreSQL")
+	}
+	s.ensureCorrectSchema(ctx)
+
+	enums, err := s.adapter.GetCurrentEnums(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read enums: %w", err)
+	}
+	tables, err := s.adapter.GetCurrentSchema(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current schema: %w", err)
+	}
+
+	result := make([]*EnumInfo, 0, len(enums))
+	for _, enum := range enums {
+		result = append(result, enumInfo(enum, tables))
+	}
+	return result, nil
+}
+
+// AddEnumValue adds a value to an enum, at the end or before or after an
+// existing value. The down migration creates the enum again without it,
+// once no row holds it.
+func (s *Service) AddEnumValue(ctx context.Context, enumName string, change *EnumValueChange, configPath string) (*EnumChangeResult, error) {
+	enum, tables, err := s.loadEnum(ctx, enumName)
+	if err != nil {
+		return nil, err
+	}
+
+	value := change.Value
+	switch {
+	case value == "":
+		return nil, fmt.Errorf("value is required")
+	case containsValue(enum.Values, value):
+		return nil, fmt.Errorf("enum %s already has value %s", enum.Name, value)
+	case change.Before != "" && change.After != "":
+		return nil, fmt.Errorf("give before or after, not both")
+	case change.Before != "" && !containsValue(enum.Values, change.Before):
+		return nil, fmt.Errorf("enum %s has no value %s", enum.Name, change.Before)
+	case change.After != "" && !containsValue(enum.Values, change.After):
+		return nil, fmt.Errorf("enum %s has no value %s", enum.Name, change.After)
+	}
+
+	add := types.EnumValueAdd{Value: value, Before: change.Before, After: change.After}
+	up := []string{schema.GenerateAddEnumValueSQL(enum.Name, add)}
+	values := insertValue(append([]string(nil), enum.Values...), add)
+	down := schema.GenerateChangeEnumValuesSQL(enum.Name, values, enum.Values, schema.EnumColumns(tables, enum.Name))
+
+	path, err := s.applyMigration(ctx, fmt.Sprintf("add_%s_value_%s", enum.Name, migrationName(value)), up, down, configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	enum.Values = values
+	return &EnumChangeResult{Enum: enumInfo(*enum, tables), SQL: strings.Join(up, "\n"), Migration: path}, nil
+}
+
+// RenameEnumValue renames a value of an enum. Rows keep it under the new
+// name.
+func (s *Service) RenameEnumValue(ctx context.Context, enumName string, change *EnumValueChange, configPath string) (*EnumChangeResult, error) {
+	enum, tables, err := s.loadEnum(ctx, enumName)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case change.From == "" || change.To == "":
+		return nil, fmt.Errorf("from and to are required")
+	case !containsValue(enum.Values, change.From):
+		return nil, fmt.Errorf("enum %s has no value %s", enum.Name, change.From)
+	case containsValue(enum.Values, change.To):
+		return nil, fmt.Errorf("enum %s already has value %s", enum.Name, change.To)
+	}
+
+	up := []string{schema.GenerateRenameEnumValueSQL(enum.Name, change.From, change.To)}
+	down := []string{schema.GenerateRenameEnumValueSQL(enum.Name, change.To, change.From)}
+
+	path, err := s.applyMigration(ctx, fmt.Sprintf("rename_%s_value_%s", enum.Name, migrationName(change.From)), up, down, configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, value := range enum.Values {
+		if value == change.From {
+			enum.Values[i] = change.To
+		}
+	}
+	return &EnumChangeResult{Enum: enumInfo(*enum, tables), SQL: strings.Join(up, "\n"), Migration: path}, nil
+}
+
+// loadEnum finds an enum and reads the tables its columns are in
+func (s *Service) loadEnum(ctx context.Context, name string) (*types.SchemaEnum, []types.SchemaTable, error) {
+	if !s.isPostgres() {
+		return nil, nil, fmt.Errorf("enum types are only supported on PostgreSQL")
+	}
+	s.ensureCorrectSchema(ctx)
+
+	enums, err := s.adapter.GetCurrentEnums(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read enums: %w", err)
+	}
+	for i := range enums {
+		if enums[i].Name == name {
+			tables, err := s.adapter.GetCurrentSchema(ctx)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read current schema: %w", err)
+			}
+			return &enums[i], tables, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("enum %s not found", name)
+}
+
+func enumInfo(enum types.SchemaEnum, tables []types.SchemaTable) *EnumInfo {
+	info := &EnumInfo{Name: enum.Name, Values: enum.Values, Columns: []EnumUsage{}}
+	for _, col := range schema.EnumColumns(tables, enum.Name) {
+		info.Columns = append(info.Columns, EnumUsage{Table: col.Table, Column: col.Column, Array: col.Array})
+	}
+	return info
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// insertValue places an added value where ADD VALUE puts it
+func insertValue(values []string, add types.EnumValueAdd) []string {
+	anchor, offset := add.Before, 0
+	if add.After != "" {
+		anchor, offset = add.After, 1
+	}
+	for i, value := range values {
+		if anchor != "" && value == anchor {
+			at := i + offset
+			return append(values[:at:at], append([]string{add.Value}, values[at:]...)...)
+		}
+	}
+	return append(values, add.Value)
+}
@@ -0,0 +1,136 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/state"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// FilterPreset is a named set of filters of a table, saved with the project
+// so it can be picked in the filter bar or named in an export. Presets are
+// kept in the project's state store.
+type FilterPreset struct {
+	Table     string          `json:"table"`
+	Name      string          `json:"name"`
+	Filters   []common.Filter `json:"filters"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// GetFilterPresets lists the filter presets of a table by name
+func (s *Service) GetFilterPresets(tableName string) ([]*FilterPreset, error) {
+	presets := []*FilterPreset{}
+	if !s.hasState() {
+		return presets, nil
+	}
+	st, err := s.openState()
+	if err != nil {
+		return nil, err
+	}
+	defer st.Close()
+
+	documents, err := st.List(state.KindFilterPresets)
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range documents {
+		var preset FilterPreset
+		if err := json.Unmarshal(doc.Value, &preset); err != nil {
+			return nil, fmt.Errorf("failed to parse filter preset %s: %w", doc.Key, err)
+		}
+		if preset.Table == tableName {
+			presets = append(presets, &preset)
+		}
+	}
+	slices.SortFunc(presets, func(a, b *FilterPreset) int { return strings.Compare(a.Name, b.Name) })
+	return presets, nil
+}
+
+// GetFilterPreset returns the filter preset of a table with the given name
+func (s *Service) GetFilterPreset(tableName, name string) (*FilterPreset, error) {
+	notFound := fmt.Errorf("filter preset '%s' not found for %s", name, tableName)
+	if !s.hasState() {
+		return nil, notFound
+	}
+	st, err := s.openState()
+	if err != nil {
+		return nil, err
+	}
+	defer st.Close()
+
+	var preset FilterPreset
+	found, err := st.Get(state.KindFilterPresets, state.FilterPresetKey(tableName, name), &preset)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, notFound
+	}
+	return &preset, nil
+}
+
+// SaveFilterPreset saves filters of a table under a name, replacing the
+// preset of that name. Every filter has to name a column of the table, an
+// operator the filter bar knows and values that operator takes.
+func (s *Service) SaveFilterPreset(ctx context.Context, tableName, name string, filters []common.Filter) (*FilterPreset, error) {
+	s.ensureCorrectSchema(ctx)
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("a filter preset needs a name")
+	}
+	if len(filters) == 0 {
+		return nil, fmt.Errorf("a filter preset needs at least one filter")
+	}
+	if s.isIgnoredTable(tableName) {
+		return nil, fmt.Errorf("table %s is ignored", tableName)
+	}
+
+	columns, _, err := s.tableColumns(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	for column := range columns {
+		if s.isIgnoredColumn(tableName, column) {
+			delete(columns, column)
+		}
+	}
+	// Building the clause checks the columns, operators and values
+	if _, _, err := s.buildWhereClause(ctx, filters, columns, nil); err != nil {
+		return nil, err
+	}
+
+	st, err := s.openState()
+	if err != nil {
+		return nil, err
+	}
+	defer st.Close()
+
+	preset := &FilterPreset{Table: tableName, Name: name, Filters: filters, UpdatedAt: time.Now().UTC()}
+	if err := st.Put(state.KindFilterPresets, state.FilterPresetKey(tableName, name), preset); err != nil {
+		return nil, err
+	}
+	return preset, nil
+}
+
+// DeleteFilterPreset removes a filter preset of a table
+func (s *Service) DeleteFilterPreset(tableName, name string) error {
+	st, err := s.openState()
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	deleted, err := st.Delete(state.KindFilterPresets, state.FilterPresetKey(tableName, name))
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("filter preset '%s' not found for %s", name, tableName)
+	}
+	return nil
+}
@@ -0,0 +1,377 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// whereBuilder builds a WHERE clause whose values are bound as parameters,
+// numbered in the order they are added
+type whereBuilder struct {
+	ctx  context.Context
+	s    *Service
+	loc  *time.Location
+	args []any
+	// unaccent is set once the unaccent extension has been found
+	unaccent bool
+}
+
+func (b *whereBuilder) bind(value any) string {
+	b.args = append(b.args, value)
+	return b.s.placeholder(len(b.args))
+}
+
+// buildWhereClause turns filters into a WHERE condition and the values it
+// binds. Filters are ANDed together; an "or" filter starts a new group.
+// Timestamps without an offset are taken as times in loc.
+func (s *Service) buildWhereClause(ctx context.Context, filters []common.Filter, columns map[string]common.ColumnInfo, loc *time.Location) (string, []any, error) {
+	if len(filters) == 0 {
+		return "", nil, nil
+	}
+
+	b := &whereBuilder{ctx: ctx, s: s, loc: loc}
+	var conditions []string
+	var currentGroup []string
+
+	for i, filter := range filters {
+		if filter.Column == "" {
+			continue
+		}
+		col, ok := columns[filter.Column]
+		if !ok {
+			return "", nil, &InvalidValueError{Column: filter.Column, Message: "no such column to filter by"}
+		}
+
+		condition, err := b.condition(filter, col)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if i == 0 || filter.Logic == "where" {
+			currentGroup = append(currentGroup, condition)
+		} else if filter.Logic == "and" {
+			currentGroup = append(currentGroup, condition)
+		} else if filter.Logic == "or" {
+			if len(currentGroup) > 0 {
+				conditions = append(conditions, "("+strings.Join(currentGroup, " AND ")+")")
+				currentGroup = []string{condition}
+			} else {
+				currentGroup = append(currentGroup, condition)
+			}
+		}
+	}
+
+	if len(currentGroup) > 0 {
+		conditions = append(conditions, "("+strings.Join(currentGroup, " AND ")+")")
+	}
+
+	if len(conditions) == 0 {
+		return "", nil, nil
+	}
+
+	return strings.Join(conditions, " OR "), b.args, nil
+}
+
+// condition returns the SQL condition of a filter. Text comparisons ignore
+// case; numbers, booleans, dates and times compare as their types.
+func (b *whereBuilder) condition(filter common.Filter, col common.ColumnInfo) (string, error) {
+	invalid := func(format string, args ...any) error {
+		return &InvalidValueError{Column: col.Name, Message: fmt.Sprintf(format, args...)}
+	}
+	quoted := b.s.quoteIdentifier(col.Name)
+	text := b.s.textCast(quoted)
+	if col.Kind == kindText {
+		// Text needs no cast, which leaves its indexes usable
+		text = quoted
+	}
+	typed := isTypedColumn(col)
+	if filter.IgnoreAccents && !typed {
+		if err := b.checkUnaccent(); err != nil {
+			return "", invalid("%v", err)
+		}
+	}
+
+	switch filter.Operator {
+	case "equals", "not_equals":
+		op := map[string]string{"equals": "=", "not_equals": "!="}[filter.Operator]
+		if typed || col.Kind == kindBoolean {
+			arg, err := b.arg(col, filter.Value)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s %s %s", quoted, op, b.bind(arg)), nil
+		}
+		return fmt.Sprintf("%s %s %s", b.fold(text, filter), op, b.fold(b.bind(filter.Value), filter)), nil
+	case "contains", "not_contains", "starts_with", "ends_with":
+		return b.like(text, filter), nil
+	case "gt", "lt", "gte", "lte":
+		op := map[string]string{"gt": ">", "lt": "<", "gte": ">=", "lte": "<="}[filter.Operator]
+		arg, err := b.arg(col, filter.Value)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s %s %s", quoted, op, b.bind(arg)), nil
+	case "in", "not_in":
+		values := common.FilterValues(filter)
+		if len(values) == 0 {
+			return "", invalid("%s needs at least one value", filter.Operator)
+		}
+		placeholders := make([]string, len(values))
+		for i, value := range values {
+			if typed || col.Kind == kindBoolean {
+				arg, err := b.arg(col, value)
+				if err != nil {
+					return "", err
+				}
+				placeholders[i] = b.bind(arg)
+			} else {
+				placeholders[i] = b.fold(b.bind(value), filter)
+			}
+		}
+		target := quoted
+		if !typed && col.Kind != kindBoolean {
+			target = b.fold(text, filter)
+		}
+		not := ""
+		if filter.Operator == "not_in" {
+			not = "NOT "
+		}
+		return fmt.Sprintf("%s %sIN (%s)", target, not, strings.Join(placeholders, ", ")), nil
+	case "between":
+		if !typed {
+			return "", invalid("between compares numbers, dates and times")
+		}
+		values := common.FilterValues(filter)
+		if len(values) != 2 {
+			return "", invalid("between needs two values, got %d", len(values))
+		}
+		from, err := b.arg(col, values[0])
+		if err != nil {
+			return "", err
+		}
+		to, err := b.arg(col, values[1])
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s BETWEEN %s AND %s", quoted, b.bind(from), b.bind(to)), nil
+	case "in_last", "not_in_last":
+		if col.Kind != kindDate && col.Kind != kindTimestamp {
+			return "", invalid("%s compares dates and timestamps", filter.Operator)
+		}
+		since, err := common.RelativeTime(filter.Value, b.now())
+		if err != nil {
+			return "", invalid("%v", err)
+		}
+		if filter.Operator == "not_in_last" {
+			return fmt.Sprintf("%s < %s", quoted, b.bind(b.timeArg(col, since))), nil
+		}
+		return fmt.Sprintf("%s BETWEEN %s AND %s", quoted, b.bind(b.timeArg(col, since)), b.bind(b.timeArg(col, b.now()))), nil
+	case "matches", "not_matches":
+		// SQLite's REGEXP is Go's, so a bad pattern can be caught here
+		if b.s.isSQLite() {
+			if _, err := regexp.Compile(filter.Value); err != nil {
+				return "", invalid("invalid regular expression: %v", err)
+			}
+		}
+		return b.regex(text, filter), nil
+	case "is_null":
+		return fmt.Sprintf("%s IS NULL", quoted), nil
+	case "is_not_null":
+		return fmt.Sprintf("%s IS NOT NULL", quoted), nil
+	case "is_empty":
+		return fmt.Sprintf("(%s IS NULL OR %s = '')", quoted, text), nil
+	case "is_not_empty":
+		return fmt.Sprintf("(%s IS NOT NULL AND %s != '')", quoted, text), nil
+	}
+	return "", invalid("unknown filter operator %q", filter.Operator)
+}
+
+// isTypedColumn reports whether a column is compared as its type rather
+// than as text: numbers, dates and times
+func isTypedColumn(col common.ColumnInfo) bool {
+	switch col.Kind {
+	case kindInteger, kindNumber, kindDate, kindTimestamp, kindTime:
+		return true
+	}
+	return false
+}
+
+// arg checks a filter value against its column and converts it to the
+// value bound for it, as for a row edit
+func (b *whereBuilder) arg(col common.ColumnInfo, value string) (any, error) {
+	value = strings.TrimSpace(value)
+	var v any = value
+	if col.Kind == kindInteger || col.Kind == kindNumber {
+		v = json.Number(value)
+	}
+	// A filter value is never NULL, whatever the column allows
+	col.Nullable = true
+	if err := checkValue(col, v); err != nil {
+		return nil, err
+	}
+	if col.TimeZone && b.loc != nil {
+		if t, ok := parseLocalTime(value, b.loc); ok {
+			return t, nil
+		}
+	}
+	return rowArg(col, v), nil
+}
+
+// now is the time relative date filters count back from, in the time zone
+// of the request
+func (b *whereBuilder) now() time.Time {
+	if b.loc != nil {
+		return time.Now().In(b.loc)
+	}
+	return time.Now()
+}
+
+// timeArg returns the value bound for a time compared with a column:
+// instants as they are, dates and wall clock timestamps as their text
+func (b *whereBuilder) timeArg(col common.ColumnInfo, t time.Time) any {
+	switch {
+	case col.Kind == kindDate:
+		return t.Format("2006-01-02")
+	case col.TimeZone:
+		return t.UTC()
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern with !, an escape
+// character every provider reads the same way
+func escapeLike(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+}
+
+// escapeGlob escapes the wildcards of a SQLite GLOB pattern, which has no
+// escape character, as one-character classes
+func escapeGlob(value string) string {
+	return strings.NewReplacer("*", "[*]", "?", "[?]", "[", "[[]").Replace(value)
+}
+
+// textCast casts a column to text for the configured provider
+func (s *Service) textCast(quoted string) string {
+	if s.provider() == "mysql" {
+		return fmt.Sprintf("CAST(%s AS CHAR)", quoted)
+	}
+	return fmt.Sprintf("CAST(%s AS TEXT)", quoted)
+}
+
+// fold applies the case and accent options of a filter to one side of a
+// text comparison. Case-sensitive comparisons of text columns keep the
+// column as it is, so its indexes serve them.
+func (b *whereBuilder) fold(expr string, filter common.Filter) string {
+	if filter.IgnoreAccents {
+		expr = fmt.Sprintf("unaccent(%s)", expr)
+	}
+	switch {
+	case !filter.CaseSensitive:
+		return fmt.Sprintf("LOWER(%s)", expr)
+	case b.s.provider() == "mysql":
+		// MySQL's usual collations ignore case, bytes don't
+		return fmt.Sprintf("CAST(%s AS BINARY)", expr)
+	}
+	return expr
+}
+
+// like returns the pattern match of the contains, starts with and ends with
+// operators: ILIKE on PostgreSQL, LIKE on SQLite, which ignores ASCII case
+// itself, and GLOB there when case matters
+func (b *whereBuilder) like(text string, filter common.Filter) string {
+	before, after := "%", "%"
+	switch filter.Operator {
+	case "starts_with":
+		before = ""
+	case "ends_with":
+		after = ""
+	}
+	not := ""
+	if filter.Operator == "not_contains" {
+		not = "NOT "
+	}
+
+	switch {
+	case b.s.isPostgres():
+		op := "ILIKE"
+		if filter.CaseSensitive {
+			op = "LIKE"
+		}
+		pattern := b.bind(before + escapeLike(filter.Value) + after)
+		if filter.IgnoreAccents {
+			text, pattern = fmt.Sprintf("unaccent(%s)", text), fmt.Sprintf("unaccent(%s)", pattern)
+		}
+		return fmt.Sprintf("%s %s%s %s ESCAPE '!'", text, not, op, pattern)
+	case b.s.isSQLite() && filter.CaseSensitive:
+		glob := strings.NewReplacer("%", "*").Replace
+		return fmt.Sprintf("%s %sGLOB %s", text, not, b.bind(glob(before)+escapeGlob(filter.Value)+glob(after)))
+	case b.s.isSQLite():
+		return fmt.Sprintf("%s %sLIKE %s ESCAPE '!'", text, not, b.bind(before+escapeLike(filter.Value)+after))
+	}
+	pattern := b.bind(before + escapeLike(filter.Value) + after)
+	return fmt.Sprintf("%s %sLIKE %s ESCAPE '!'", b.fold(text, filter), not, b.fold(pattern, filter))
+}
+
+// regex matches text against the regular expression of a filter, in the
+// syntax of the provider: POSIX on PostgreSQL, ICU on MySQL and Go's RE2 on
+// SQLite, whose REGEXP function flash provides
+func (b *whereBuilder) regex(text string, filter common.Filter) string {
+	pattern := b.bind(filter.Value)
+	not := filter.Operator == "not_matches"
+	switch {
+	case b.s.isPostgres():
+		op := "~*"
+		if filter.CaseSensitive {
+			op = "~"
+		}
+		if not {
+			op = "!" + op
+		}
+		if filter.IgnoreAccents {
+			text, pattern = fmt.Sprintf("unaccent(%s)", text), fmt.Sprintf("unaccent(%s)", pattern)
+		}
+		return fmt.Sprintf("%s %s %s", text, op, pattern)
+	case b.s.provider() == "mysql":
+		matchType := "'i'"
+		if filter.CaseSensitive {
+			matchType = "'c'"
+		}
+		if not {
+			return fmt.Sprintf("NOT REGEXP_LIKE(%s, %s, %s)", text, pattern, matchType)
+		}
+		return fmt.Sprintf("REGEXP_LIKE(%s, %s, %s)", text, pattern, matchType)
+	}
+	if !filter.CaseSensitive {
+		pattern = fmt.Sprintf("('(?i)' || %s)", pattern)
+	}
+	if not {
+		return fmt.Sprintf("%s NOT REGEXP %s", text, pattern)
+	}
+	return fmt.Sprintf("%s REGEXP %s", text, pattern)
+}
+
+// checkUnaccent fails unless accents can be ignored, which takes
+// PostgreSQL's unaccent extension
+func (b *whereBuilder) checkUnaccent() error {
+	if !b.s.isPostgres() {
+		return fmt.Errorf("ignoring accents needs PostgreSQL's unaccent extension")
+	}
+	if b.unaccent {
+		return nil
+	}
+	result, err := b.s.adapter.ExecuteQuery(b.ctx, "SELECT 1 FROM pg_extension WHERE extname = 'unaccent'")
+	if err != nil {
+		return fmt.Errorf("failed to look up the unaccent extension: %w", err)
+	}
+	if len(result.Rows) == 0 {
+		return fmt.Errorf("ignoring accents needs the unaccent extension, add it with CREATE EXTENSION unaccent")
+	}
+	b.unaccent = true
+	return nil
+}
@@ -0,0 +1,249 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/database/sqlite"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// Filters become conditions in each provider's SQL, with every value bound
+// rather than written into the query
+func TestWhereClause(t *testing.T) {
+	columns := map[string]common.ColumnInfo{
+		"name":  {Name: "name", Kind: kindText},
+		"age":   {Name: "age", Kind: kindInteger},
+		"email": {Name: "email", Kind: kindText},
+	}
+	tests := []struct {
+		name     string
+		provider string
+		filters  []common.Filter
+		where    string
+		args     string
+	}{
+		{
+			name:     "postgres equals",
+			provider: "postgresql",
+			filters:  []common.Filter{{Column: "name", Operator: "equals", Value: "Ada"}},
+			where:    `(LOWER("name") = LOWER($1))`,
+			args:     "[Ada]",
+		},
+		{
+			name:     "mysql case-sensitive equals",
+			provider: "mysql",
+			filters:  []common.Filter{{Column: "name", Operator: "equals", Value: "Ada", CaseSensitive: true}},
+			where:    "(CAST(`name` AS BINARY) = CAST(? AS BINARY))",
+			args:     "[Ada]",
+		},
+		{
+			name:     "postgres in",
+			provider: "postgresql",
+			filters:  []common.Filter{{Column: "age", Operator: "in", Values: []string{"1", " 2 ", ""}}},
+			where:    `("age" IN ($1, $2))`,
+			args:     "[1 2]",
+		},
+		{
+			name:     "mysql not in text",
+			provider: "mysql",
+			filters:  []common.Filter{{Column: "name", Operator: "not_in", Value: "Ada,Grace"}},
+			where:    "(LOWER(`name`) NOT IN (LOWER(?), LOWER(?)))",
+			args:     "[Ada Grace]",
+		},
+		{
+			name:     "between",
+			provider: "postgresql",
+			filters:  []common.Filter{{Column: "age", Operator: "between", Values: []string{"18", "65"}}},
+			where:    `("age" BETWEEN $1 AND $2)`,
+			args:     "[18 65]",
+		},
+		{
+			name:     "mysql contains wildcards",
+			provider: "mysql",
+			filters:  []common.Filter{{Column: "name", Operator: "contains", Value: "50%_!"}},
+			where:    "(LOWER(`name`) LIKE LOWER(?) ESCAPE '!')",
+			args:     "[%50!%!_!!%]",
+		},
+		{
+			name:     "postgres starts with",
+			provider: "postgresql",
+			filters:  []common.Filter{{Column: "name", Operator: "starts_with", Value: "A"}},
+			where:    `("name" ILIKE $1 ESCAPE '!')`,
+			args:     "[A%]",
+		},
+		{
+			name:     "sqlite case-sensitive ends with",
+			provider: "sqlite",
+			filters:  []common.Filter{{Column: "name", Operator: "ends_with", Value: "a*", CaseSensitive: true}},
+			where:    `("name" GLOB ?)`,
+			args:     "[*a[*]]",
+		},
+		{
+			name:     "postgres not matches",
+			provider: "postgresql",
+			filters:  []common.Filter{{Column: "email", Operator: "not_matches", Value: "^a.*@", CaseSensitive: true}},
+			where:    `("email" !~ $1)`,
+			args:     "[^a.*@]",
+		},
+		{
+			name:     "mysql matches",
+			provider: "mysql",
+			filters:  []common.Filter{{Column: "email", Operator: "matches", Value: "^a"}},
+			where:    "(REGEXP_LIKE(`email`, ?, 'i'))",
+			args:     "[^a]",
+		},
+		{
+			name:     "sqlite matches",
+			provider: "sqlite",
+			filters:  []common.Filter{{Column: "email", Operator: "matches", Value: "^a"}},
+			where:    `("email" REGEXP ('(?i)' || ?))`,
+			args:     "[^a]",
+		},
+		{
+			name:     "groups",
+			provider: "postgresql",
+			filters: []common.Filter{
+				{Column: "age", Operator: "gte", Value: "18"},
+				{Logic: "and", Column: "email", Operator: "is_not_null"},
+				{Logic: "or", Column: "name", Operator: "equals", Value: "Ada", CaseSensitive: true},
+			},
+			where: `("age" >= $1 AND "email" IS NOT NULL) OR ("name" = $2)`,
+			args:  "[18 Ada]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Database.Provider = tt.provider
+			s := &Service{cfg: cfg}
+
+			where, args, err := s.buildWhereClause(context.Background(), tt.filters, columns, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if where != tt.where {
+				t.Errorf("where %s, want %s", where, tt.where)
+			}
+			if got := fmt.Sprint(args); got != tt.args {
+				t.Errorf("args %s, want %s", got, tt.args)
+			}
+		})
+	}
+}
+
+// Filters that can't apply to their column are refused with the column
+// they name
+func TestWhereClauseInvalid(t *testing.T) {
+	columns := map[string]common.ColumnInfo{
+		"name": {Name: "name", Kind: kindText},
+		"age":  {Name: "age", Kind: kindInteger},
+	}
+	tests := []struct {
+		name   string
+		filter common.Filter
+	}{
+		{"unknown column", common.Filter{Column: "secret", Operator: "equals", Value: "x"}},
+		{"unknown operator", common.Filter{Column: "name", Operator: "sounds_like", Value: "x"}},
+		{"not a number", common.Filter{Column: "age", Operator: "gt", Value: "1; DROP TABLE users"}},
+		{"empty in", common.Filter{Column: "age", Operator: "in", Value: " , "}},
+		{"between text", common.Filter{Column: "name", Operator: "between", Values: []string{"a", "b"}}},
+		{"between one value", common.Filter{Column: "age", Operator: "between", Value: "1"}},
+		{"in last of text", common.Filter{Column: "name", Operator: "in_last", Value: "7 days"}},
+		{"bad regex", common.Filter{Column: "name", Operator: "matches", Value: "(unclosed"}},
+		{"accents on sqlite", common.Filter{Column: "name", Operator: "equals", Value: "e", IgnoreAccents: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Database.Provider = "sqlite"
+			s := &Service{cfg: cfg}
+
+			_, _, err := s.buildWhereClause(context.Background(), []common.Filter{tt.filter}, columns, nil)
+			var invalid *InvalidValueError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("error %v, want an InvalidValueError", err)
+			}
+			if invalid.Column != tt.filter.Column {
+				t.Errorf("error names column %q, want %q", invalid.Column, tt.filter.Column)
+			}
+		})
+	}
+}
+
+// The filters pick the rows they describe from a table
+func TestFilterRows(t *testing.T) {
+	ctx := context.Background()
+	adapter := sqlite.New()
+	if err := adapter.Connect(ctx, "sqlite://"+filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer adapter.Close()
+
+	now := time.Now().UTC()
+	day := func(ago int) string { return now.AddDate(0, 0, -ago).Format("2006-01-02 15:04:05") }
+	if err := adapter.ExecuteMigration(ctx, fmt.Sprintf(`CREATE TABLE users (
+  id INTEGER PRIMARY KEY,
+  name TEXT NOT NULL,
+  age INTEGER,
+  note TEXT,
+  created_at TIMESTAMP
+);
+INSERT INTO users (id, name, age, note, created_at) VALUES
+  (1, 'Ada', 36, '100%% sure', '%s'),
+  (2, 'ada', 17, NULL, '%s'),
+  (3, 'Grace', 85, 'a_b', '%s'),
+  (4, 'Linus', 54, '', '%s');`, day(1), day(3), day(30), day(400))); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	cfg.Database.Provider = "sqlite"
+	s := NewService(adapter, cfg)
+
+	tests := []struct {
+		name    string
+		filters []common.Filter
+		want    []string
+	}{
+		{"equals ignores case", []common.Filter{{Column: "name", Operator: "equals", Value: "ADA"}}, []string{"1", "2"}},
+		{"equals with case", []common.Filter{{Column: "name", Operator: "equals", Value: "Ada", CaseSensitive: true}}, []string{"1"}},
+		{"in", []common.Filter{{Column: "id", Operator: "in", Value: "1, 3, 9"}}, []string{"1", "3"}},
+		{"not in", []common.Filter{{Column: "name", Operator: "not_in", Values: []string{"ada", "grace"}}}, []string{"4"}},
+		{"between", []common.Filter{{Column: "age", Operator: "between", Values: []string{"17", "54"}}}, []string{"1", "2", "4"}},
+		{"in last", []common.Filter{{Column: "created_at", Operator: "in_last", Value: "7 days"}}, []string{"1", "2"}},
+		{"not in last", []common.Filter{{Column: "created_at", Operator: "not_in_last", Value: "1 year"}}, []string{"4"}},
+		{"contains percent", []common.Filter{{Column: "note", Operator: "contains", Value: "0%"}}, []string{"1"}},
+		{"contains underscore", []common.Filter{{Column: "note", Operator: "contains", Value: "_"}}, []string{"3"}},
+		{"matches", []common.Filter{{Column: "name", Operator: "matches", Value: "^(ada|linus)$"}}, []string{"1", "2", "4"}},
+		{"matches with case", []common.Filter{{Column: "name", Operator: "matches", Value: "^[A-Z]", CaseSensitive: true}}, []string{"1", "3", "4"}},
+		{"not matches", []common.Filter{{Column: "name", Operator: "not_matches", Value: "a"}}, []string{"4"}},
+		{"is empty", []common.Filter{{Column: "note", Operator: "is_empty"}}, []string{"2", "4"}},
+		{"or", []common.Filter{
+			{Column: "age", Operator: "lt", Value: "18"},
+			{Logic: "or", Column: "age", Operator: "gt", Value: "80"},
+		}, []string{"2", "3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := s.GetTableDataFiltered(ctx, "users", 1, 50, "", tt.filters, false, nil, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, row := range data.Rows {
+				ids = append(ids, fmt.Sprint(row["id"]))
+			}
+			slices.Sort(ids)
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("rows %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
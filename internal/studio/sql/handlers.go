@@ -1,9 +1,17 @@
 package sql
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Lumos-Labs-HQ/flash/internal/hooks"
+	"github.com/Lumos-Labs-HQ/flash/internal/schedule"
 	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
 )
 
@@ -16,7 +24,7 @@ func (s *Server) handlePreviewSchemaChange(w http.ResponseWriter, r *http.Reques
 
 	preview, err := s.service.PreviewSchemaChange(&change)
 	if err != nil {
-		common.JSONError(w, http.StatusInternalServerError, err.Error())
+		writeServiceError(w, err)
 		return
 	}
 	common.JSONRaw(w, preview)
@@ -34,8 +42,8 @@ func (s *Server) handleApplySchemaChange(w http.ResponseWriter, r *http.Request)
 		configPath = "./flash.config.json"
 	}
 
-	if err := s.service.ApplySchemaChange(&change, configPath); err != nil {
-		common.JSONError(w, http.StatusInternalServerError, err.Error())
+	if err := s.service.ApplySchemaChange(r.Context(), &change, configPath, s.isAdmin(r)); err != nil {
+		writeServiceError(w, err)
 		return
 	}
 
@@ -47,6 +55,196 @@ func (s *Server) handleApplySchemaChange(w http.ResponseWriter, r *http.Request)
 	common.JSONMap(w, common.Map{"success": true, "message": message})
 }
 
+func (s *Server) handlePreviewTableDesign(w http.ResponseWriter, r *http.Request) {
+	var design TableDesign
+	if err := common.ParseJSON(r, &design); err != nil {
+		common.JSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	preview, err := s.service.PreviewTableDesign(r.Context(), &design)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSONRaw(w, preview)
+}
+
+func (s *Server) handleApplyTableDesign(w http.ResponseWriter, r *http.Request) {
+	var design TableDesign
+	if err := common.ParseJSON(r, &design); err != nil {
+		common.JSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	preview, migration, err := s.service.ApplyTableDesign(r.Context(), &design, configPath(), s.isAdmin(r))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errSchemaNeedsAdmin) {
+			status = http.StatusForbidden
+		} else if preview != nil && len(preview.Errors) > 0 {
+			status = http.StatusBadRequest
+		}
+		common.JSONError(w, status, err.Error())
+		return
+	}
+
+	common.JSONMap(w, common.Map{"success": true, "migration": migration, "preview": preview})
+}
+
+func (s *Server) handleGetChangeset(w http.ResponseWriter, r *http.Request) {
+	changeset, err := s.service.GetChangeset(r.Context())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSONRaw(w, changeset)
+}
+
+func (s *Server) handleStageTableDesign(w http.ResponseWriter, r *http.Request) {
+	var design TableDesign
+	if err := common.ParseJSON(r, &design); err != nil {
+		common.JSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	changeset, err := s.service.StageTableDesign(r.Context(), &design, s.isAdmin(r))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errSchemaNeedsAdmin) {
+			status = http.StatusForbidden
+		}
+		common.JSONError(w, status, err.Error())
+		return
+	}
+	common.JSONRaw(w, changeset)
+}
+
+func (s *Server) handleStageColumnRename(w http.ResponseWriter, r *http.Request) {
+	var rename ColumnRename
+	if err := common.ParseJSON(r, &rename); err != nil {
+		common.JSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	changeset, err := s.service.StageColumnRename(r.Context(), &rename, s.isAdmin(r))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errSchemaNeedsAdmin) {
+			status = http.StatusForbidden
+		}
+		common.JSONError(w, status, err.Error())
+		return
+	}
+	common.JSONRaw(w, changeset)
+}
+
+func (s *Server) handleUnstageTable(w http.ResponseWriter, r *http.Request) {
+	changeset, err := s.service.UnstageTable(r.Context(), r.PathValue("table"))
+	if err != nil {
+		common.JSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	common.JSONRaw(w, changeset)
+}
+
+func (s *Server) handleDiscardChangeset(w http.ResponseWriter, r *http.Request) {
+	s.service.DiscardChangeset()
+	common.JSONMap(w, common.Map{"success": true})
+}
+
+func (s *Server) handleApplyChangeset(w http.ResponseWriter, r *http.Request) {
+	changeset, migration, err := s.service.ApplyChangeset(r.Context(), configPath(), s.isAdmin(r))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errSchemaNeedsAdmin) {
+			status = http.StatusForbidden
+		} else if changeset == nil || !changeset.Valid {
+			status = http.StatusBadRequest
+		}
+		common.JSONError(w, status, err.Error())
+		return
+	}
+
+	common.JSONMap(w, common.Map{"success": true, "migration": migration, "changeset": changeset})
+}
+
+func (s *Server) handleGetEnums(w http.ResponseWriter, r *http.Request) {
+	enums, err := s.service.GetEnums(r.Context())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, enums)
+}
+
+func (s *Server) handleAddEnumValue(w http.ResponseWriter, r *http.Request) {
+	var change EnumValueChange
+	if err := common.ParseJSON(r, &change); err != nil {
+		common.JSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	result, err := s.service.AddEnumValue(r.Context(), r.PathValue("name"), &change, configPath())
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.JSON(w, result)
+}
+
+func (s *Server) handleRenameEnumValue(w http.ResponseWriter, r *http.Request) {
+	var change EnumValueChange
+	if err := common.ParseJSON(r, &change); err != nil {
+		common.JSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	result, err := s.service.RenameEnumValue(r.Context(), r.PathValue("name"), &change, configPath())
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.JSON(w, result)
+}
+
+func (s *Server) handleGetUUIDDefaults(w http.ResponseWriter, r *http.Request) {
+	defaults, err := s.service.GetUUIDDefaults(r.Context())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, defaults)
+}
+
+func (s *Server) handleAddUUIDDefaults(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Columns []UUIDDefaultColumn `json:"columns"`
+	}
+	if r.ContentLength != 0 {
+		if err := common.ParseJSON(r, &req); err != nil {
+			common.JSONError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+	}
+
+	result, err := s.service.AddUUIDDefaults(r.Context(), req.Columns, configPath())
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.JSON(w, result)
+}
+
+// configPath is the flash config studio writes migrations next to, empty
+// when studio runs without one
+func configPath() string {
+	if _, err := os.Stat("./flash.config.json"); err == nil {
+		return "./flash.config.json"
+	}
+	return ""
+}
+
 func (s *Server) handleCheckConfig(w http.ResponseWriter, r *http.Request) {
 	exists := false
 	if _, err := os.Stat("./flash.config.json"); err == nil {
@@ -58,7 +256,7 @@ func (s *Server) handleCheckConfig(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleGetBranches(w http.ResponseWriter, r *http.Request) {
 	branches, current, err := s.service.GetBranches()
 	if err != nil {
-		common.JSONError(w, http.StatusInternalServerError, err.Error())
+		writeServiceError(w, err)
 		return
 	}
 	common.JSONMap(w, common.Map{"branches": branches, "current": current})
@@ -73,8 +271,8 @@ func (s *Server) handleSwitchBranch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.service.SwitchBranch(req.Branch); err != nil {
-		common.JSONError(w, http.StatusInternalServerError, err.Error())
+	if err := s.service.SwitchBranch(r.Context(), req.Branch); err != nil {
+		writeServiceError(w, err)
 		return
 	}
 
@@ -84,6 +282,35 @@ func (s *Server) handleSwitchBranch(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (s *Server) handleGetTenants(w http.ResponseWriter, r *http.Request) {
+	tenants, current, err := s.service.GetTenants()
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSONMap(w, common.Map{"tenants": tenants, "current": current})
+}
+
+func (s *Server) handleSwitchTenant(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Tenant string `json:"tenant"`
+	}
+	if err := common.ParseJSON(r, &req); err != nil {
+		common.JSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	if err := s.service.SwitchTenant(r.Context(), req.Tenant); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	common.JSONMap(w, common.Map{
+		"success": true,
+		"message": "Tenant switched. Please refresh the page to see changes.",
+	})
+}
+
 func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
 	exportTypeStr := r.PathValue("type")
 
@@ -100,18 +327,146 @@ func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data, err := s.service.ExportDatabase(exportType)
+	data, err := s.service.ExportDatabase(r.Context(), exportType, s.isAdmin(r))
 	if err != nil {
-		common.JSONError(w, http.StatusInternalServerError, err.Error())
+		writeServiceError(w, err)
 		return
 	}
 
 	common.JSON(w, data)
 }
 
+// handleExportResults downloads the results of an editor query. The query
+// is run again and its rows are written as they are read.
+func (s *Server) handleExportResults(w http.ResponseWriter, r *http.Request) {
+	var req ResultExport
+	if err := common.ParseJSON(r, &req); err != nil {
+		common.JSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	format, ok := exportFormats[req.Format]
+	if !ok {
+		common.JSONError(w, http.StatusBadRequest, "Invalid format. Use: csv, jsonl, or markdown")
+		return
+	}
+	if req.Offset < 0 || req.Limit < 0 {
+		common.JSONError(w, http.StatusBadRequest, "offset and limit can't be negative")
+		return
+	}
+
+	var args []any
+	if req.Params != nil {
+		var err error
+		if req.Query, args, err = s.service.BindParams(req.Query, req.Params); err != nil {
+			common.JSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	started := false
+	err := s.service.ExportResults(r.Context(), &req, args, s.isAdmin(r), func() io.Writer {
+		started = true
+		w.Header().Set("Content-Type", format.contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="query_results_%s.%s"`,
+			time.Now().Format("2006-01-02_15-04-05"), format.extension))
+		return w
+	})
+	if err != nil {
+		if errors.Is(err, errQueriesNeedAdmin) {
+			common.JSONError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if !started {
+			common.JSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		// Part of the file is sent already, drop the connection so the
+		// download fails rather than looking complete
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// handleExportTable downloads the rows of a table matching the filters or
+// the filter preset of the request
+func (s *Server) handleExportTable(w http.ResponseWriter, r *http.Request) {
+	tableName := r.PathValue("name")
+	formatName := common.Query(r, "format", "csv")
+	format, ok := exportFormats[formatName]
+	if !ok {
+		common.JSONError(w, http.StatusBadRequest, "Invalid format. Use: csv, jsonl, or markdown")
+		return
+	}
+	filters, err := s.requestFilters(r, tableName)
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	loc, err := requestLocation(r)
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	name := tableName
+	if preset := r.URL.Query().Get("preset"); preset != "" {
+		name += "_" + preset
+	}
+	started := false
+	err = s.service.ExportTable(r.Context(), tableName, filters, formatName, loc, s.isAdmin(r), func() io.Writer {
+		started = true
+		w.Header().Set("Content-Type", format.contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s_%s.%s"`,
+			strings.NewReplacer(`"`, "", "/", "_", " ", "_").Replace(name), time.Now().Format("2006-01-02_15-04-05"), format.extension))
+		return w
+	})
+	if err != nil {
+		if !started {
+			writeServiceError(w, err)
+			return
+		}
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (s *Server) handleGetFilterPresets(w http.ResponseWriter, r *http.Request) {
+	presets, err := s.service.GetFilterPresets(r.PathValue("name"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, presets)
+}
+
+func (s *Server) handleSaveFilterPreset(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Filters []common.Filter `json:"filters"`
+	}
+	if err := common.ParseJSON(r, &req); err != nil {
+		common.JSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	preset, err := s.service.SaveFilterPreset(r.Context(), r.PathValue("name"), r.PathValue("preset"), req.Filters)
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.JSON(w, preset)
+}
+
+func (s *Server) handleDeleteFilterPreset(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("preset")
+	if err := s.service.DeleteFilterPreset(r.PathValue("name"), name); err != nil {
+		common.JSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	common.JSONMessage(w, fmt.Sprintf("Deleted filter preset %s", name))
+}
+
 func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
 	var importData common.ExportData
-	if err := common.ParseJSON(r, &importData); err != nil {
+	if err := common.ParseJSONValues(r, &importData); err != nil {
 		common.JSONError(w, http.StatusBadRequest, "Invalid import data format")
 		return
 	}
@@ -121,9 +476,24 @@ func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := s.service.ImportDatabase(&importData)
+	event := hooks.Event{Command: "studio import"}
+	for _, table := range importData.Tables {
+		event.Tables = append(event.Tables, table.Name)
+	}
+	if s.service.cfg != nil {
+		if err := hooks.Pre(s.service.cfg, "import", event); err != nil {
+			common.JSONError(w, http.StatusPreconditionFailed, err.Error())
+			return
+		}
+	}
+
+	start := time.Now()
+	result, err := s.service.ImportDatabase(r.Context(), &importData, r.URL.Query().Get("verify") == "true")
+	if s.service.cfg != nil {
+		hooks.Post(s.service.cfg, "import", event, start, err)
+	}
 	if err != nil {
-		common.JSONError(w, http.StatusInternalServerError, err.Error())
+		writeServiceError(w, err)
 		return
 	}
 
@@ -133,3 +503,164 @@ func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
 		"result":  result,
 	})
 }
+
+func (s *Server) handleGetSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules, err := s.service.GetSchedules()
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, schedules)
+}
+
+func (s *Server) handleSaveSchedule(w http.ResponseWriter, r *http.Request) {
+	var sch schedule.Schedule
+	if err := common.ParseJSON(r, &sch); err != nil {
+		common.JSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	if err := s.service.SaveSchedule(&sch, s.isAdmin(r)); err != nil {
+		if errors.Is(err, errQueriesNeedAdmin) {
+			common.JSONError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.JSON(w, sch)
+}
+
+func (s *Server) handleDeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := s.service.DeleteSchedule(name); err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.JSONMessage(w, fmt.Sprintf("Deleted schedule %s", name))
+}
+
+// handleRunSchedule runs a schedule now and returns the run, failed or not
+func (s *Server) handleRunSchedule(w http.ResponseWriter, r *http.Request) {
+	run, err := s.service.RunSchedule(r.Context(), r.PathValue("name"), s.isAdmin(r))
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.JSON(w, run)
+}
+
+func (s *Server) handleGetScheduleRuns(w http.ResponseWriter, r *http.Request) {
+	runs, err := s.service.GetScheduleRuns(r.PathValue("name"), s.isAdmin(r))
+	if err != nil {
+		common.JSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	common.JSON(w, runs)
+}
+
+func (s *Server) handleGetMaterializedViews(w http.ResponseWriter, r *http.Request) {
+	views, err := s.service.GetMaterializedViews(r.Context())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, views)
+}
+
+func (s *Server) handleRefreshMaterializedView(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if r.ContentLength > 0 {
+		if err := common.ParseJSON(r, &req); err != nil {
+			common.JSONError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+	}
+
+	refresh, err := s.service.RefreshMaterializedView(r.Context(), r.PathValue("name"), req.Concurrently)
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.JSON(w, refresh)
+}
+
+func (s *Server) handleGetJournal(w http.ResponseWriter, r *http.Request) {
+	common.JSON(w, s.service.GetJournal())
+}
+
+func (s *Server) handleUndo(w http.ResponseWriter, r *http.Request) {
+	entry, err := s.service.Undo(r.Context())
+	if errors.Is(err, errNothingToUndo) {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, entry)
+}
+
+func (s *Server) handleRedo(w http.ResponseWriter, r *http.Request) {
+	entry, err := s.service.Redo(r.Context())
+	if errors.Is(err, errNothingToRedo) {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, entry)
+}
+
+func (s *Server) handlePasteRows(w http.ResponseWriter, r *http.Request) {
+	var req PasteRequest
+	if err := common.ParseJSON(r, &req); err != nil {
+		common.JSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	loc, err := requestLocation(r)
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := s.service.PasteRows(r.Context(), r.PathValue("name"), &req, loc)
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if result.Invalid > 0 {
+		common.JSONErrorData(w, http.StatusUnprocessableEntity,
+			fmt.Sprintf("%d of %d row(s) can't be inserted, nothing was inserted", result.Invalid, result.Rows), result)
+		return
+	}
+	common.JSON(w, result)
+}
+
+func (s *Server) handleStartChangeCapture(w http.ResponseWriter, r *http.Request) {
+	feed, err := s.service.StartChangeCapture(r.Context())
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.JSON(w, feed)
+}
+
+func (s *Server) handlePollChanges(w http.ResponseWriter, r *http.Request) {
+	after, _ := strconv.ParseInt(common.Query(r, "after", "0"), 10, 64)
+	feed, err := s.service.PollChanges(r.Context(), after, s.isAdmin(r))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, feed)
+}
+
+func (s *Server) handleStopChangeCapture(w http.ResponseWriter, r *http.Request) {
+	s.service.StopChangeCapture()
+	common.JSONMessage(w, "Change capture stopped")
+}
@@ -0,0 +1,91 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/dump"
+	"github.com/Lumos-Labs-HQ/flash/internal/hooks"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// dumpProgressInterval is how often a dump import reports its progress
+const dumpProgressInterval = 250 * time.Millisecond
+
+// ImportDump restores a plain SQL dump, as written by pg_dump, mysqldump or
+// sqlite3's .dump, with foreign key checks off so tables can be filled in
+// any order. A dump is SQL of the caller's own, so while columns are masked
+// it needs the admin role.
+func (s *Service) ImportDump(ctx context.Context, r io.Reader, onProgress func(dump.Progress), admin bool) (*dump.Result, error) {
+	if err := s.checkQueryAccess(admin); err != nil {
+		return nil, err
+	}
+	s.ensureCorrectSchema(ctx)
+
+	restoreFK := s.disableFKChecksIfNeeded(ctx)
+	defer restoreFK()
+
+	return dump.Restore(ctx, s.adapter, r, dump.Options{
+		Provider:   s.provider(),
+		OnProgress: onProgress,
+	})
+}
+
+// handleImportDump restores the dump sent as the request body, answering
+// with a line of JSON for its progress every so often and one for its
+// result at the end
+func (s *Server) handleImportDump(w http.ResponseWriter, r *http.Request) {
+	event := hooks.Event{Command: "studio import"}
+	if s.service.cfg != nil {
+		if err := hooks.Pre(s.service.cfg, "import", event); err != nil {
+			common.JSONError(w, http.StatusPreconditionFailed, err.Error())
+			return
+		}
+	}
+
+	// Progress is written while the body is still being read, which
+	// HTTP/1 servers don't allow by default
+	rc := http.NewResponseController(w)
+	_ = rc.EnableFullDuplex()
+	encoder := json.NewEncoder(w)
+	started := false
+	writeLine := func(line common.Map) {
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.Header().Set("Cache-Control", "no-cache")
+			started = true
+		}
+		_ = encoder.Encode(line)
+		_ = rc.Flush()
+	}
+
+	var last time.Time
+	onProgress := func(p dump.Progress) {
+		if time.Since(last) < dumpProgressInterval {
+			return
+		}
+		last = time.Now()
+		writeLine(common.Map{"progress": p})
+	}
+
+	start := time.Now()
+	result, err := s.service.ImportDump(r.Context(), r.Body, onProgress, s.isAdmin(r))
+	if s.service.cfg != nil {
+		hooks.Post(s.service.cfg, "import", event, start, err)
+	}
+	switch {
+	case errors.Is(err, errQueriesNeedAdmin):
+		common.JSONError(w, http.StatusForbidden, err.Error())
+	case err != nil && !started:
+		// Nothing ran yet, e.g. a dump of another database
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+	case err != nil:
+		writeLine(common.Map{"error": err.Error(), "result": result})
+	default:
+		writeLine(common.Map{"result": result})
+	}
+}
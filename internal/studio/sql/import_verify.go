@@ -0,0 +1,108 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/export"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// ExportVerifyError is an export file whose data doesn't match the row
+// counts and checksums written with it, found before anything is imported
+type ExportVerifyError struct {
+	Message string
+	Tables  []common.TableCheck
+}
+
+func (e *ExportVerifyError) Error() string {
+	if len(e.Tables) == 0 {
+		return e.Message
+	}
+	problems := make([]string, len(e.Tables))
+	for i, check := range e.Tables {
+		problems[i] = fmt.Sprintf("%s: %s", check.Table, check.Problem)
+	}
+	return fmt.Sprintf("%s: %s", e.Message, strings.Join(problems, "; "))
+}
+
+// verifyExport checks the data of each table of an export file against the
+// row count and checksum the export wrote for it
+func verifyExport(data *common.ExportData) error {
+	hasChecksums := false
+	for _, table := range data.Tables {
+		if table.Checksum != "" {
+			hasChecksums = true
+			break
+		}
+	}
+	if !hasChecksums {
+		return &ExportVerifyError{Message: "the file has no checksums to verify; export it again to get them"}
+	}
+
+	var failed []common.TableCheck
+	seen := make(map[string]bool, len(data.Tables))
+	for _, table := range data.Tables {
+		check := common.TableCheck{Table: table.Name, Expected: table.RowCount, Actual: len(table.Data)}
+		switch {
+		case seen[table.Name]:
+			check.Problem = "appears more than once in the file"
+		case table.Checksum == "" && len(table.Data) > 0:
+			check.Problem = "has rows but no checksum"
+		case table.Checksum == "":
+			continue
+		case len(table.Data) != table.RowCount:
+			check.Problem = fmt.Sprintf("has %d rows, the export wrote %d", len(table.Data), table.RowCount)
+		default:
+			checksum, err := export.Checksum(table.Data)
+			if err != nil {
+				check.Problem = err.Error()
+			} else if checksum != table.Checksum {
+				check.Problem = "rows differ from the ones exported"
+			}
+		}
+		seen[table.Name] = true
+		if check.Problem != "" {
+			failed = append(failed, check)
+		}
+	}
+
+	if len(failed) > 0 {
+		return &ExportVerifyError{Message: "the file failed verification", Tables: failed}
+	}
+	return nil
+}
+
+// verifyImport counts the rows of each table of an export file after an
+// import, listing the tables that didn't get all of their rows: ones the
+// import wrote fewer rows to, and ones that hold fewer rows than the file
+func (s *Service) verifyImport(ctx context.Context, tables []common.ExportTable, imported map[string]int) []common.TableCheck {
+	var incomplete []common.TableCheck
+	for _, table := range tables {
+		expected := len(table.Data)
+		if expected == 0 {
+			continue
+		}
+		check := common.TableCheck{Table: table.Name, Expected: expected, Actual: imported[table.Name]}
+		if check.Actual < expected {
+			check.Problem = fmt.Sprintf("imported %d of %d rows", check.Actual, expected)
+			incomplete = append(incomplete, check)
+			continue
+		}
+
+		count, err := s.adapter.GetTableRowCount(ctx, table.Name)
+		switch {
+		case err != nil:
+			check.Actual = 0
+			check.Problem = fmt.Sprintf("failed to count rows: %v", err)
+		case count < expected:
+			check.Actual = count
+			check.Problem = fmt.Sprintf("holds %d rows, fewer than the %d in the file", count, expected)
+		default:
+			continue
+		}
+		incomplete = append(incomplete, check)
+	}
+	return incomplete
+}
@@ -0,0 +1,399 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	dbcommon "github.com/Lumos-Labs-HQ/flash/internal/database/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// journalLimit is how many data edits studio remembers for undo
+const journalLimit = 50
+
+var (
+	errNothingToUndo = errors.New("nothing to undo")
+	errNothingToRedo = errors.New("nothing to redo")
+)
+
+// JournalEntry is a data edit made through studio. It keeps the rows it
+// changed as they were before and after it, keyed by primary key, so it can
+// be undone and redone: a row only in before was deleted, one only in after
+// was inserted.
+type JournalEntry struct {
+	ID          int       `json:"id"`
+	Table       string    `json:"table"`
+	Description string    `json:"description"`
+	Rows        int       `json:"rows"`
+	At          time.Time `json:"at"`
+	Undone      bool      `json:"undone"`
+
+	pkColumn string
+	before   map[string]map[string]any
+	after    map[string]map[string]any
+}
+
+// readRows reads the rows with the given primary key values, keyed by the
+// value, with every column. The journal needs the hidden columns too, to
+// put the rows back as they were.
+func (s *Service) readRows(ctx context.Context, tableName, pkColumn string, ids []string) (map[string]map[string]any, error) {
+	rows := make(map[string]map[string]any, len(ids))
+	const batch = 500
+	for start := 0; start < len(ids); start += batch {
+		end := min(start+batch, len(ids))
+		args := make([]any, 0, end-start)
+		for _, id := range ids[start:end] {
+			args = append(args, id)
+		}
+		result, err := s.adapter.ExecuteQuery(ctx, fmt.Sprintf("SELECT * FROM %s WHERE %s IN (%s)",
+			common.QuoteIdentifier(tableName), common.QuoteIdentifier(pkColumn), s.placeholders(len(args))), args...)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range result.Rows {
+			rows[exportText(row[pkColumn])] = row
+		}
+	}
+	return rows, nil
+}
+
+// journalEdit runs edit, which changes the rows of a table with the given
+// primary key values, and records what it changed for undo. The caller
+// holds writeMu. When the rows can't be read, e.g. the table has no primary
+// key, the edit runs without being recorded.
+func (s *Service) journalEdit(ctx context.Context, tableName, pkColumn, description string, ids []string, edit func() error) error {
+	before, err := s.readRows(ctx, tableName, pkColumn, ids)
+	if err != nil {
+		return edit()
+	}
+	editErr := edit()
+	// A failed edit may have changed some rows before failing
+	if after, err := s.readRows(ctx, tableName, pkColumn, ids); err == nil {
+		s.record(tableName, pkColumn, description, before, after)
+	}
+	return editErr
+}
+
+// journalInsert runs edit, which inserts a row with the given values, and
+// records the row for undo. Its key isn't known until it's inserted, so it
+// is found among the rows with those values. The caller holds writeMu.
+func (s *Service) journalInsert(ctx context.Context, tableName, pkColumn string, tableColumns map[string]common.ColumnInfo, data map[string]any, edit func() error) error {
+	existing, err := s.matchingKeys(ctx, tableName, pkColumn, tableColumns, data)
+	if err != nil {
+		return edit()
+	}
+	if err := edit(); err != nil {
+		return err
+	}
+
+	keys, err := s.matchingKeys(ctx, tableName, pkColumn, tableColumns, data)
+	if err != nil {
+		return nil
+	}
+	var inserted []string
+	for key := range keys {
+		if !existing[key] {
+			inserted = append(inserted, key)
+		}
+	}
+	if after, err := s.readRows(ctx, tableName, pkColumn, inserted); err == nil {
+		s.record(tableName, pkColumn, "Inserted a row", nil, after)
+	}
+	return nil
+}
+
+// matchingKeys returns the primary key values of the rows with the given
+// values
+func (s *Service) matchingKeys(ctx context.Context, tableName, pkColumn string, tableColumns map[string]common.ColumnInfo, data map[string]any) (map[string]bool, error) {
+	columns, values, err := rowValues(tableName, tableColumns, data)
+	if err != nil {
+		return nil, err
+	}
+	var conditions []string
+	var args []any
+	for i, column := range columns {
+		if values[i] == nil {
+			conditions = append(conditions, common.QuoteIdentifier(column)+" IS NULL")
+			continue
+		}
+		args = append(args, values[i])
+		conditions = append(conditions, common.QuoteIdentifier(column)+" = "+s.placeholder(len(args)))
+	}
+
+	result, err := s.adapter.ExecuteQuery(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE %s",
+		common.QuoteIdentifier(pkColumn), common.QuoteIdentifier(tableName), strings.Join(conditions, " AND ")), args...)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool, len(result.Rows))
+	for _, row := range result.Rows {
+		keys[exportText(row[pkColumn])] = true
+	}
+	return keys, nil
+}
+
+// record adds an edit to the journal, keeping only the rows it changed.
+// Edits that can be redone are dropped, as in an editor, since they may no
+// longer apply on top of the new one.
+func (s *Service) record(tableName, pkColumn, description string, before, after map[string]map[string]any) {
+	entry := &JournalEntry{
+		Table:       tableName,
+		Description: description,
+		At:          time.Now(),
+		pkColumn:    pkColumn,
+		before:      make(map[string]map[string]any),
+		after:       make(map[string]map[string]any),
+	}
+	for key, row := range before {
+		if other, ok := after[key]; !ok || rowVersion(row) != rowVersion(other) {
+			entry.before[key] = row
+		}
+	}
+	for key, row := range after {
+		if other, ok := before[key]; !ok || rowVersion(row) != rowVersion(other) {
+			entry.after[key] = row
+		}
+	}
+	entry.Rows = len(entry.before)
+	for key := range entry.after {
+		if _, ok := entry.before[key]; !ok {
+			entry.Rows++
+		}
+	}
+	if entry.Rows == 0 {
+		return
+	}
+
+	for len(s.journal) > 0 && s.journal[len(s.journal)-1].Undone {
+		s.journal = s.journal[:len(s.journal)-1]
+	}
+	s.journalSeq++
+	entry.ID = s.journalSeq
+	s.journal = append(s.journal, entry)
+	if len(s.journal) > journalLimit {
+		s.journal = s.journal[len(s.journal)-journalLimit:]
+	}
+}
+
+// GetJournal returns the recorded edits, newest first
+func (s *Service) GetJournal() []JournalEntry {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	entries := make([]JournalEntry, len(s.journal))
+	for i, entry := range s.journal {
+		entries[len(s.journal)-1-i] = *entry
+	}
+	return entries
+}
+
+// Undo reverts the latest edit that isn't undone yet. Rows changed since
+// the edit are left alone and come back in a RowConflictError.
+func (s *Service) Undo(ctx context.Context) (*JournalEntry, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	for i := len(s.journal) - 1; i >= 0; i-- {
+		if entry := s.journal[i]; !entry.Undone {
+			if err := s.restoreRows(ctx, entry, entry.after, entry.before); err != nil {
+				return nil, err
+			}
+			entry.Undone = true
+			return entry, nil
+		}
+	}
+	return nil, errNothingToUndo
+}
+
+// Redo makes the earliest undone edit again
+func (s *Service) Redo(ctx context.Context) (*JournalEntry, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	for _, entry := range s.journal {
+		if entry.Undone {
+			if err := s.restoreRows(ctx, entry, entry.before, entry.after); err != nil {
+				return nil, err
+			}
+			entry.Undone = false
+			return entry, nil
+		}
+	}
+	return nil, errNothingToRedo
+}
+
+// clearJournal forgets the recorded edits, once they no longer apply to
+// the database studio is connected to
+func (s *Service) clearJournal() {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.journal = nil
+}
+
+// restoreRows takes the rows of an entry from the state in from to the
+// state in to, in one transaction so they change all together or not at
+// all. It checks first that the rows are still in the from state. Updated
+// rows only get back the columns the edit changed, bound with the values
+// they were read as, so the other columns, BLOBs and NULLs stay as they are.
+func (s *Service) restoreRows(ctx context.Context, entry *JournalEntry, from, to map[string]map[string]any) error {
+	keys := make([]string, 0, len(entry.before)+len(entry.after))
+	for key := range entry.before {
+		keys = append(keys, key)
+	}
+	for key := range entry.after {
+		if _, ok := entry.before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	current, err := s.readRows(ctx, entry.Table, entry.pkColumn, keys)
+	if err != nil {
+		return fmt.Errorf("failed to read the rows of %s: %w", entry.Table, err)
+	}
+	var conflicts []RowConflict
+	for _, key := range keys {
+		expected, want := from[key]
+		row, has := current[key]
+		if want == has && (!has || rowVersion(row) == rowVersion(expected)) {
+			continue
+		}
+		conflict := RowConflict{RowID: key}
+		if has {
+			s.stripIgnoredColumns(entry.Table, []map[string]any{row})
+			conflict.Version = rowVersion(row)
+			exactRowValues([]map[string]any{row})
+			s.maskRows(entry.Table, []map[string]any{row})
+			conflict.Current = row
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	if len(conflicts) > 0 {
+		return &RowConflictError{Conflicts: conflicts}
+	}
+
+	schema, err := s.adapter.GetTableColumns(ctx, entry.Table)
+	if err != nil {
+		return fmt.Errorf("failed to read the columns of %s: %w", entry.Table, err)
+	}
+	// Some drivers read binary columns as text, which must be bound as
+	// bytes to be stored as they were
+	binary := make(map[string]bool, len(schema))
+	for _, col := range schema {
+		binary[col.Name] = containsAny(strings.ToLower(col.Type), "bytea", "blob", "binary")
+	}
+
+	var statements []dbcommon.Statement
+	for _, key := range keys {
+		row, keep := to[key]
+		old, exists := from[key]
+		switch {
+		case !keep:
+			statements = append(statements, s.journalStatement("DELETE FROM "+common.QuoteIdentifier(entry.Table), entry, nil, old))
+		case !exists:
+			statements = append(statements, s.journalInsertStatement(entry, row, binary))
+		default:
+			if statement, ok := s.journalUpdateStatement(entry, old, row, binary); ok {
+				statements = append(statements, statement)
+			}
+		}
+	}
+	if len(statements) == 0 {
+		return nil
+	}
+	if err := s.adapter.ExecuteStatements(ctx, statements); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", entry.Table, err)
+	}
+	return nil
+}
+
+// journalStatement returns query limited to the row of an entry by its
+// primary key, bound after args
+func (s *Service) journalStatement(query string, entry *JournalEntry, args []any, row map[string]any) dbcommon.Statement {
+	return dbcommon.Statement{
+		SQL:  fmt.Sprintf("%s WHERE %s = %s", query, common.QuoteIdentifier(entry.pkColumn), s.placeholder(len(args)+1)),
+		Args: append(args, row[entry.pkColumn]),
+	}
+}
+
+// journalInsertStatement returns the INSERT putting back a deleted row with
+// every column
+func (s *Service) journalInsertStatement(entry *JournalEntry, row map[string]any, binary map[string]bool) dbcommon.Statement {
+	columns := sortedColumns(row)
+	quoted := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	args := make([]any, len(columns))
+	for i, column := range columns {
+		quoted[i] = common.QuoteIdentifier(column)
+		placeholders[i] = s.placeholder(i + 1)
+		args[i] = s.journalArg(row[column], binary[column])
+	}
+	return dbcommon.Statement{
+		SQL: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", common.QuoteIdentifier(entry.Table),
+			strings.Join(quoted, ", "), strings.Join(placeholders, ", ")),
+		Args: args,
+	}
+}
+
+// journalUpdateStatement returns the UPDATE taking a row from old to row,
+// setting only the columns that differ. It reports false when none do.
+func (s *Service) journalUpdateStatement(entry *JournalEntry, old, row map[string]any, binary map[string]bool) (dbcommon.Statement, bool) {
+	var set []string
+	var args []any
+	for _, column := range sortedColumns(row) {
+		if column == entry.pkColumn || sameJournalValue(old[column], row[column]) {
+			continue
+		}
+		args = append(args, s.journalArg(row[column], binary[column]))
+		set = append(set, fmt.Sprintf("%s = %s", common.QuoteIdentifier(column), s.placeholder(len(args))))
+	}
+	if len(set) == 0 {
+		return dbcommon.Statement{}, false
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s", common.QuoteIdentifier(entry.Table), strings.Join(set, ", "))
+	return s.journalStatement(query, entry, args, row), true
+}
+
+// journalArg returns the value to bind to write back a value read from a
+// column. SQLite keeps times as text, which its driver reads as a time and
+// would write back in another format, so they are bound in the format of
+// SQLite's own CURRENT_TIMESTAMP.
+func (s *Service) journalArg(value any, binary bool) any {
+	switch v := value.(type) {
+	case string:
+		if binary {
+			return []byte(v)
+		}
+	case time.Time:
+		if s.isSQLite() {
+			if v.Location() == time.UTC {
+				return v.Format("2006-01-02 15:04:05.999999999")
+			}
+			return v.Format("2006-01-02 15:04:05.999999999-07:00")
+		}
+	}
+	return value
+}
+
+// sortedColumns returns the columns of a row in order
+func sortedColumns(row map[string]any) []string {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// sameJournalValue reports whether two values read from a column are the
+// same, telling NULL from empty text
+func sameJournalValue(a, b any) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return exportText(a) == exportText(b)
+}
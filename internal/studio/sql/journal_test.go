@@ -0,0 +1,126 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/database/sqlite"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// Undoing an edit puts back only the columns it changed, leaving timestamp
+// text, BLOBs and NULLs of the other columns exactly as they were
+func TestUndoRestoresOnlyChangedColumns(t *testing.T) {
+	ctx := context.Background()
+	adapter := sqlite.New()
+	if err := adapter.Connect(ctx, "sqlite://"+filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer adapter.Close()
+
+	if err := adapter.ExecuteMigration(ctx, `CREATE TABLE users (
+  id INTEGER PRIMARY KEY,
+  name TEXT,
+  note TEXT,
+  avatar BLOB,
+  created_at TIMESTAMP
+);
+INSERT INTO users (id, name, note, avatar, created_at) VALUES (1, 'Ada', NULL, X'00FF10', '2026-10-18 08:31:59');`); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	cfg.Database.Provider = "sqlite"
+	s := NewService(adapter, cfg)
+
+	before := readUser(t, s)
+	changes := []common.RowChange{{RowID: "1", Column: "name", Value: json.RawMessage(`"Grace"`), Action: "update"}}
+	if err := s.SaveChanges(ctx, "users", changes, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	if name := readUser(t, s)["name"]; name != "Grace" {
+		t.Fatalf("name after the edit is %v", name)
+	}
+	if _, err := s.Undo(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	after := readUser(t, s)
+	if after["name"] != "Ada" {
+		t.Errorf("name after undo is %v", after["name"])
+	}
+	if after["created_at"] != before["created_at"] {
+		t.Errorf("created_at changed from %v to %v", before["created_at"], after["created_at"])
+	}
+	if after["note"] != nil {
+		t.Errorf("note is %#v, want NULL", after["note"])
+	}
+	if after["avatar"] != "blob:00FF10" {
+		t.Errorf("avatar is %v", after["avatar"])
+	}
+
+	if _, err := s.Redo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if name := readUser(t, s)["name"]; name != "Grace" {
+		t.Errorf("name after redo is %v", name)
+	}
+
+	// A deleted row comes back with every column as it was
+	before = readUser(t, s)
+	if err := s.DeleteRows(ctx, "users", []string{"1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Undo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	after = readUser(t, s)
+	for _, column := range []string{"name", "note", "avatar", "created_at"} {
+		if after[column] != before[column] {
+			t.Errorf("%s of the restored row is %v, want %v", column, after[column], before[column])
+		}
+	}
+}
+
+func readUser(t *testing.T, s *Service) map[string]any {
+	t.Helper()
+	result, err := s.adapter.ExecuteQuery(context.Background(),
+		"SELECT name, note, typeof(avatar) || ':' || hex(avatar) AS avatar, typeof(created_at) || ':' || created_at AS created_at FROM users WHERE id = 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Rows) != 1 {
+		t.Fatalf("read %d rows", len(result.Rows))
+	}
+	return result.Rows[0]
+}
+
+// Primary key values are bound, so quotes and backslashes in them are read
+// as they are
+func TestReadRowsBindsKeys(t *testing.T) {
+	ctx := context.Background()
+	adapter := sqlite.New()
+	if err := adapter.Connect(ctx, "sqlite://"+filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer adapter.Close()
+	if err := adapter.ExecuteMigration(ctx, `CREATE TABLE tags (name TEXT PRIMARY KEY, note TEXT);
+INSERT INTO tags (name, note) VALUES ('it''s', 'quote'), ('dir\', 'backslash'), ('plain', 'plain');`); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	cfg.Database.Provider = "sqlite"
+	s := NewService(adapter, cfg)
+
+	rows, err := s.readRows(ctx, "tags", "name", []string{"it's", `dir\`, "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows["it's"]["note"] != "quote" || rows[`dir\`]["note"] != "backslash" {
+		t.Errorf("read %v", rows)
+	}
+}
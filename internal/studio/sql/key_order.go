@@ -0,0 +1,80 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	dbcommon "github.com/Lumos-Labs-HQ/flash/internal/database/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// keyOrderTTL is how long the key order of a table is reused before its
+// keys are sampled again
+const keyOrderTTL = 5 * time.Minute
+
+// keyOrderSample is how many keys of a table are sampled to tell whether
+// they are ULIDs or UUIDv7s
+const keyOrderSample = 20
+
+// cachedKeyOrder is the primary key of a table, how it sorts and when
+// that was found
+type cachedKeyOrder struct {
+	column string
+	order  dbcommon.KeyOrder
+	at     time.Time
+}
+
+// tableKeyOrder returns the primary key of a table and how its values
+// sort. Tables with no primary key, or one of several columns, have no
+// order.
+func (s *Service) tableKeyOrder(ctx context.Context, tableName string, columns []common.ColumnInfo) (string, dbcommon.KeyOrder) {
+	s.keyOrderMu.Lock()
+	cached, ok := s.keyOrderCache[tableName]
+	s.keyOrderMu.Unlock()
+	if ok && time.Since(cached.at) < keyOrderTTL {
+		return cached.column, cached.order
+	}
+
+	var primary []common.ColumnInfo
+	for _, col := range columns {
+		if col.PrimaryKey {
+			primary = append(primary, col)
+		}
+	}
+	cached = cachedKeyOrder{at: time.Now()}
+	if len(primary) == 1 {
+		key := primary[0]
+		var sample []any
+		query := fmt.Sprintf("SELECT %s AS k FROM %s LIMIT %d",
+			s.quoteIdentifier(key.Name), common.QuoteIdentifier(tableName), keyOrderSample)
+		if result, err := s.adapter.ExecuteQuery(ctx, query); err == nil {
+			for _, row := range result.Rows {
+				sample = append(sample, row["k"])
+			}
+		}
+		cached.column = key.Name
+		cached.order = dbcommon.DetectKeyOrder(key.Type, key.Default, sample)
+	}
+
+	s.keyOrderMu.Lock()
+	if s.keyOrderCache == nil {
+		s.keyOrderCache = make(map[string]cachedKeyOrder)
+	}
+	s.keyOrderCache[tableName] = cached
+	s.keyOrderMu.Unlock()
+	return cached.column, cached.order
+}
+
+// keyCursor converts the ?after= key of a page to the type of the key
+func keyCursor(after string, order dbcommon.KeyOrder) (any, error) {
+	if order != dbcommon.KeyOrderInteger {
+		return after, nil
+	}
+	n, err := strconv.ParseInt(after, 10, 64)
+	if err != nil {
+		return nil, &InvalidValueError{Column: "after", Message: "cursor must be an integer key"}
+	}
+	return n, nil
+}
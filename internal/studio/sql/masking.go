@@ -0,0 +1,165 @@
+package sql
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/schedule"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// maskedValue replaces the values of masked columns
+const maskedValue = "••••"
+
+// errQueriesNeedAdmin refuses SQL of a caller's own to callers without the
+// admin role while columns are masked
+var errQueriesNeedAdmin = errors.New("columns are masked, running SQL needs the admin role")
+
+// errSchemaNeedsAdmin refuses schema changes to callers without the admin
+// role while columns are masked
+var errSchemaNeedsAdmin = errors.New("columns are masked, changing the schema needs the admin role")
+
+// errHistoryNeedsAdmin refuses the query history and the state store to
+// callers without the admin role while columns are masked
+var errHistoryNeedsAdmin = errors.New("columns are masked, the query history needs the admin role")
+
+// IsAdminToken reports whether a token gives a request the admin role,
+// which sees masked columns
+func (s *Service) IsAdminToken(token string) bool {
+	if s.cfg == nil || token == "" {
+		return false
+	}
+	admin := s.cfg.Studio.AdminToken()
+	return admin != "" && subtle.ConstantTimeCompare([]byte(token), []byte(admin)) == 1
+}
+
+// masksColumns reports whether studio.masked_columns masks anything
+func (s *Service) masksColumns() bool {
+	return s.cfg != nil && len(s.cfg.Studio.MaskedColumns) > 0
+}
+
+// isMaskedColumn reports whether a column of a table is masked from
+// callers without the admin role
+func (s *Service) isMaskedColumn(table, column string) bool {
+	return s.cfg != nil && s.cfg.Studio.IsMaskedColumn(table, column)
+}
+
+// maskRows replaces the values of the masked columns of rows of a table.
+// NULL stays NULL: that a secret is missing is no secret.
+func (s *Service) maskRows(table string, rows []map[string]any) {
+	if !s.masksColumns() {
+		return
+	}
+	for _, row := range rows {
+		for column, value := range row {
+			if value != nil && s.isMaskedColumn(table, column) {
+				row[column] = maskedValue
+			}
+		}
+	}
+}
+
+// checkQueryAccess fails when a caller without the admin role runs SQL of
+// their own while columns are masked. The columns of a query can't be
+// traced to their tables, so an alias or an expression would give the
+// values away.
+func (s *Service) checkQueryAccess(admin bool) error {
+	if admin || !s.masksColumns() {
+		return nil
+	}
+	return errQueriesNeedAdmin
+}
+
+// checkSchemaAccess fails when a caller without the admin role changes the
+// schema while columns are masked. Raw SQL could copy a masked column to a
+// table of its own, and a rename would move it out from under its mask.
+func (s *Service) checkSchemaAccess(admin bool) error {
+	if admin || !s.masksColumns() {
+		return nil
+	}
+	return errSchemaNeedsAdmin
+}
+
+// checkHistoryAccess fails when a caller without the admin role reads the
+// query history or queries the state store while columns are masked. The
+// history holds the SQL the admin ran, with any secret written in it.
+func (s *Service) checkHistoryAccess(admin bool) error {
+	if admin || !s.masksColumns() {
+		return nil
+	}
+	return errHistoryNeedsAdmin
+}
+
+// masksColumnName reports whether schedule snapshots mask a column by its
+// name
+func (s *Service) masksColumnName(column string) bool {
+	return s.cfg != nil && s.cfg.Studio.MasksColumnName(column)
+}
+
+// maskRun returns a schedule run with the masked columns of its snapshot
+// masked, leaving the recorded run alone
+func (s *Service) maskRun(run *schedule.Run) *schedule.Run {
+	if run == nil || run.Snapshot == nil || !s.masksColumns() {
+		return run
+	}
+	masked := *run
+	snapshot := *run.Snapshot
+	snapshot.Rows = make([]map[string]any, len(run.Snapshot.Rows))
+	for i, row := range run.Snapshot.Rows {
+		snapshot.Rows[i] = make(map[string]any, len(row))
+		for column, value := range row {
+			if value != nil && s.masksColumnName(column) {
+				value = maskedValue
+			}
+			snapshot.Rows[i][column] = value
+		}
+	}
+	masked.Snapshot = &snapshot
+	return &masked
+}
+
+// checkUnmasked fails when a caller without the admin role names a masked
+// column, e.g. to filter or chart by it, which would give its values away
+func (s *Service) checkUnmasked(table string, columns ...string) error {
+	for _, column := range columns {
+		if column != "" && s.isMaskedColumn(table, column) {
+			return &MaskedColumnError{Table: table, Column: column}
+		}
+	}
+	return nil
+}
+
+// dropMaskedColumns removes the masked columns from rows of a table, for
+// exports
+func (s *Service) dropMaskedColumns(table string, rows []map[string]any) {
+	if !s.masksColumns() {
+		return
+	}
+	for _, row := range rows {
+		for column := range row {
+			if s.isMaskedColumn(table, column) {
+				delete(row, column)
+			}
+		}
+	}
+}
+
+// maskColumns flags the masked columns of a table, whose cells can't be
+// edited. New rows can still be given a value for them.
+func (s *Service) maskColumns(table string, columns []common.ColumnInfo) {
+	for i := range columns {
+		columns[i].Masked = s.isMaskedColumn(table, columns[i].Name)
+	}
+}
+
+// MaskedColumnError rejects a request that would read or change a masked
+// column without the admin role
+type MaskedColumnError struct {
+	Table  string
+	Column string
+}
+
+func (e *MaskedColumnError) Error() string {
+	return fmt.Sprintf("%s.%s is masked, it needs the admin role", e.Table, e.Column)
+}
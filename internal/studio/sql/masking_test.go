@@ -0,0 +1,191 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/database/sqlite"
+	"github.com/Lumos-Labs-HQ/flash/internal/dump"
+)
+
+// Queries can rename a masked column, so while columns are masked only the
+// admin role runs SQL of its own
+func TestMaskedColumnsAliasNeedsAdmin(t *testing.T) {
+	ctx := context.Background()
+	adapter := sqlite.New()
+	if err := adapter.Connect(ctx, "sqlite://"+filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer adapter.Close()
+	if err := adapter.ExecuteMigration(ctx, `CREATE TABLE users (id INTEGER PRIMARY KEY, secret TEXT);
+INSERT INTO users (id, secret) VALUES (1, 'hunter2');`); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	cfg.Database.Provider = "sqlite"
+	cfg.Studio.MaskedColumns = []string{"users.secret"}
+	s := NewService(adapter, cfg)
+
+	query := "select secret, secret as s from users"
+	if _, err := s.ExecuteSQL(ctx, query, false); !errors.Is(err, errQueriesNeedAdmin) {
+		t.Errorf("query without the admin role returned %v", err)
+	}
+	var out bytes.Buffer
+	export := &ResultExport{Query: query, Format: "csv"}
+	err := s.ExportResults(ctx, export, nil, false, func() io.Writer { return &out })
+	if !errors.Is(err, errQueriesNeedAdmin) {
+		t.Errorf("export without the admin role returned %v", err)
+	}
+	if strings.Contains(out.String(), "hunter2") {
+		t.Errorf("export gave the secret away: %s", out.String())
+	}
+
+	data, err := s.ExecuteSQL(ctx, query, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Rows) != 1 || data.Rows[0]["s"] != "hunter2" {
+		t.Errorf("admin query returned %v", data.Rows)
+	}
+
+	cfg.Studio.MaskedColumns = nil
+	if _, err := s.ExecuteSQL(ctx, query, false); err != nil {
+		t.Errorf("query without masked columns failed: %v", err)
+	}
+}
+
+// Schema changes could copy a masked column to a table of the caller's own
+// or rename it out from under its mask, so while columns are masked they
+// need the admin role too
+func TestMaskedColumnsSchemaChangesNeedAdmin(t *testing.T) {
+	ctx := context.Background()
+	adapter := sqlite.New()
+	if err := adapter.Connect(ctx, "sqlite://"+filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer adapter.Close()
+	if err := adapter.ExecuteMigration(ctx, `CREATE TABLE users (id INTEGER PRIMARY KEY, secret TEXT);
+INSERT INTO users (id, secret) VALUES (1, 'hunter2');`); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{}
+	cfg.Database.Provider = "sqlite"
+	cfg.Studio.MaskedColumns = []string{"users.secret"}
+	s := NewService(adapter, cfg)
+
+	leak := "CREATE TABLE leak AS SELECT secret FROM users"
+	renamed := &TableDesign{Name: "users", Columns: []ColumnChange{
+		{Name: "id", Type: "INTEGER", IsPrimary: true},
+		{Name: "plain", Type: "TEXT", Nullable: true, OldName: "secret"},
+	}}
+	tests := []struct {
+		name string
+		run  func() error
+		want error
+	}{
+		{"raw schema change", func() error {
+			return s.ApplySchemaChange(ctx, &SchemaChange{Type: "x", SQL: leak}, "", false)
+		}, errSchemaNeedsAdmin},
+		{"table design", func() error {
+			_, _, err := s.ApplyTableDesign(ctx, renamed, "", false)
+			return err
+		}, errSchemaNeedsAdmin},
+		{"staged design", func() error {
+			_, err := s.StageTableDesign(ctx, renamed, false)
+			return err
+		}, errSchemaNeedsAdmin},
+		{"staged rename", func() error {
+			_, err := s.StageColumnRename(ctx, &ColumnRename{Table: "users", Column: "secret", NewName: "plain"}, false)
+			return err
+		}, errSchemaNeedsAdmin},
+		{"staged changes", func() error {
+			_, _, err := s.ApplyChangeset(ctx, "", false)
+			return err
+		}, errSchemaNeedsAdmin},
+		{"dump import", func() error {
+			_, err := s.ImportDump(ctx, strings.NewReader(leak+";\n"), func(dump.Progress) {}, false)
+			return err
+		}, errQueriesNeedAdmin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); !errors.Is(err, tt.want) {
+				t.Errorf("without the admin role got %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	tables, err := adapter.GetAllTableNames(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range tables {
+		if table == "leak" {
+			t.Error("the masked column was copied to a new table")
+		}
+	}
+	if exists, err := adapter.CheckColumnExists(ctx, "users", "secret"); err != nil || !exists {
+		t.Errorf("the masked column was renamed (exists %v, err %v)", exists, err)
+	}
+
+	if err := s.ApplySchemaChange(ctx, &SchemaChange{Type: "x", SQL: leak}, "", true); err != nil {
+		t.Errorf("admin schema change failed: %v", err)
+	}
+}
+
+// The query history holds the SQL the admin ran, secrets written in it
+// included, so while columns are masked reading it needs the admin role
+func TestMaskedColumnsHistoryNeedsAdmin(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	adapter := sqlite.New()
+	if err := adapter.Connect(ctx, "sqlite://"+filepath.Join(dir, "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	defer adapter.Close()
+
+	cfg := &config.Config{MigrationsPath: filepath.Join(dir, "migrations")}
+	if err := os.MkdirAll(cfg.MigrationsPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Database.Provider = "sqlite"
+	cfg.Studio.MaskedColumns = []string{"users.secret"}
+	s := NewService(adapter, cfg)
+	s.RecordQuery("UPDATE users SET secret = 'hunter2'", time.Now(), nil)
+
+	tests := []struct {
+		name string
+		run  func(admin bool) (any, error)
+	}{
+		{"history", func(admin bool) (any, error) { return s.QueryHistory(10, admin) }},
+		{"state query", func(admin bool) (any, error) {
+			return s.QueryState(ctx, "SELECT * FROM query_history", admin)
+		}},
+		{"state view", func(admin bool) (any, error) { return s.GetStateView(ctx, "query_history", 1, 10, admin) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.run(false); !errors.Is(err, errHistoryNeedsAdmin) {
+				t.Errorf("without the admin role got %v", err)
+			}
+			data, err := tt.run(true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text := fmt.Sprint(data); !strings.Contains(text, "hunter2") {
+				t.Errorf("admin got %s", text)
+			}
+		})
+	}
+}
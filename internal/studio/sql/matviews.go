@@ -0,0 +1,43 @@
+package sql
+
+import (
+	"context"
+	"github.com/Lumos-Labs-HQ/flash/internal/matview"
+)
+
+// RefreshRequest asks for a materialized view refresh
+type RefreshRequest struct {
+	Concurrently bool `json:"concurrently"`
+}
+
+func (s *Service) matviews() (*matview.Service, error) {
+	migrationsPath := ""
+	if s.cfg != nil {
+		migrationsPath = s.cfg.MigrationsPath
+	}
+	return matview.NewService(s.adapter, migrationsPath)
+}
+
+// GetMaterializedViews lists the materialized views with their latest
+// refreshes, empty on databases without materialized views
+func (s *Service) GetMaterializedViews(ctx context.Context) ([]matview.View, error) {
+	s.ensureCorrectSchema(ctx)
+	if _, ok := s.adapter.(matview.Adapter); !ok {
+		return []matview.View{}, nil
+	}
+	service, err := s.matviews()
+	if err != nil {
+		return nil, err
+	}
+	return service.List(ctx)
+}
+
+// RefreshMaterializedView refreshes a materialized view
+func (s *Service) RefreshMaterializedView(ctx context.Context, name string, concurrently bool) (*matview.Refresh, error) {
+	s.ensureCorrectSchema(ctx)
+	service, err := s.matviews()
+	if err != nil {
+		return nil, err
+	}
+	return service.Refresh(ctx, name, concurrently, "studio")
+}
@@ -0,0 +1,159 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxSafeInteger is the largest integer a JSON number keeps exactly once
+// the browser reads it as a double: 2^53 - 1
+const maxSafeInteger = 1<<53 - 1
+
+// exactColumn reports whether a column holds numbers a JSON number could
+// lose digits of: 64-bit integers, which every SQLite integer is, and
+// decimals
+func (s *Service) exactColumn(col common.ColumnInfo) bool {
+	colType := strings.ToLower(col.Type)
+	switch col.Kind {
+	case kindInteger:
+		return s.isSQLite() || containsAny(colType, "bigint", "int8", "bigserial", "serial8")
+	case kindNumber:
+		return containsAny(colType, "numeric", "decimal")
+	}
+	return false
+}
+
+// exactNumbers sends the values of columns holding 64-bit integers or
+// decimals as text, and flags those columns
+func (s *Service) exactNumbers(rows []map[string]any, columns []common.ColumnInfo) {
+	for i := range columns {
+		if !s.exactColumn(columns[i]) {
+			continue
+		}
+		columns[i].ExactNumber = true
+		name := columns[i].Name
+		for _, row := range rows {
+			if text, ok := exactText(row[name]); ok {
+				row[name] = text
+			}
+		}
+	}
+}
+
+// exactResultNumbers does the same for query results by their values, as
+// not every driver reports column types: a column is sent as text when one
+// of its values would lose digits as a JSON number
+func exactResultNumbers(rows []map[string]any, columns []common.ColumnInfo) {
+	for i := range columns {
+		name := columns[i].Name
+		for _, row := range rows {
+			if losesDigits(row[name]) {
+				columns[i].ExactNumber = true
+				break
+			}
+		}
+		if !columns[i].ExactNumber {
+			continue
+		}
+		for _, row := range rows {
+			if text, ok := exactText(row[name]); ok {
+				row[name] = text
+			}
+		}
+	}
+}
+
+// exactRowValues turns the values of rows that would lose digits as JSON
+// numbers into text, and returns the columns it changed
+func exactRowValues(rows []map[string]any) []string {
+	changed := make(map[string]bool)
+	for _, row := range rows {
+		for name, value := range row {
+			if !losesDigits(value) {
+				continue
+			}
+			if text, ok := exactText(value); ok {
+				row[name] = text
+				changed[name] = true
+			}
+		}
+	}
+	columns := make([]string, 0, len(changed))
+	for name := range changed {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// losesDigits reports whether a value read from the database would come
+// out different after a trip through a JSON number in the browser
+func losesDigits(value any) bool {
+	switch v := value.(type) {
+	case int64:
+		return v > maxSafeInteger || v < -maxSafeInteger
+	case uint64:
+		return v > maxSafeInteger
+	case pgtype.Numeric:
+		return v.Valid
+	}
+	return false
+}
+
+// exactText returns the exact text of a number read from the database,
+// false for NULL and values that aren't numbers
+func exactText(value any) (string, bool) {
+	switch v := value.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case int, int8, int16, int32, uint, uint8, uint16, uint32:
+		return fmt.Sprint(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), true
+	case string:
+		return v, true // MySQL sends decimals as text already
+	case []byte:
+		return string(v), true
+	case pgtype.Numeric:
+		text, err := v.Value()
+		if err != nil || text == nil {
+			return "", false
+		}
+		return fmt.Sprint(text), true
+	}
+	return "", false
+}
+
+// exportExactNumbers writes the values of the 64-bit integer and decimal
+// columns of a table as text, so an export brings them back exactly, and
+// returns those columns
+func (s *Service) exportExactNumbers(ctx context.Context, tableName string, rows []map[string]any) []string {
+	tableColumns, _, err := s.tableColumns(ctx, tableName)
+	if err != nil {
+		return exactRowValues(rows)
+	}
+	columns := make([]common.ColumnInfo, 0, len(tableColumns))
+	for _, col := range tableColumns {
+		columns = append(columns, col)
+	}
+	s.exactNumbers(rows, columns)
+
+	var exact []string
+	for _, col := range columns {
+		if col.ExactNumber {
+			exact = append(exact, col.Name)
+		}
+	}
+	sort.Strings(exact)
+	return exact
+}
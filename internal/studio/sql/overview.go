@@ -0,0 +1,113 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/database"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// overviewMigrations is how many of the latest migrations the overview
+// lists as recent activity
+const overviewMigrations = 5
+
+// StoreOverview connects to a SQL database and returns its overview: its
+// size, its tables with their estimated row counts and the migrations
+// applied last. The connection is closed before returning.
+func StoreOverview(ctx context.Context, provider, url string) common.StoreOverview {
+	overview := common.StoreOverview{Provider: provider, ObjectKind: "tables", ItemKind: "rows", Activity: []common.StoreActivity{}}
+
+	adapter := database.NewAdapter(provider)
+	if err := adapter.Connect(ctx, url); err != nil {
+		overview.Error = fmt.Sprintf("failed to connect: %v", err)
+		return overview
+	}
+	defer adapter.Close()
+
+	s := &Service{adapter: adapter, cfg: &config.Config{
+		Database: config.Database{Provider: provider},
+		Studio:   config.Studio{RowCounts: rowCountsEstimated},
+	}}
+	s.overview(ctx, &overview)
+	return overview
+}
+
+// overview fills in the overview of the service's database
+func (s *Service) overview(ctx context.Context, overview *common.StoreOverview) {
+	start := time.Now()
+	if err := s.adapter.Ping(ctx); err != nil {
+		overview.Error = err.Error()
+		return
+	}
+	overview.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	overview.Healthy = true
+
+	var versionQuery, sizeQuery string
+	switch {
+	case s.isPostgres():
+		versionQuery = "SHOW server_version"
+		sizeQuery = "SELECT pg_database_size(current_database()) AS size"
+	case s.isSQLite():
+		versionQuery = "SELECT sqlite_version() AS version"
+		sizeQuery = "SELECT page_count * page_size AS size FROM pragma_page_count(), pragma_page_size()"
+	default:
+		versionQuery = "SELECT VERSION() AS version"
+		sizeQuery = "SELECT COALESCE(SUM(DATA_LENGTH + INDEX_LENGTH), 0) AS size FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE()"
+	}
+	if result, err := s.adapter.ExecuteQuery(ctx, versionQuery); err == nil && len(result.Rows) > 0 {
+		for _, value := range result.Rows[0] {
+			if b, ok := value.([]byte); ok {
+				value = string(b)
+			}
+			overview.Version = fmt.Sprint(value)
+		}
+	}
+	if result, err := s.adapter.ExecuteQuery(ctx, sizeQuery); err == nil && len(result.Rows) > 0 {
+		overview.SizeBytes = int64(profileInt(result.Rows[0]["size"]))
+	}
+
+	names, err := s.adapter.GetAllTableNames(ctx)
+	if err != nil {
+		overview.Error = fmt.Sprintf("failed to list tables: %v", err)
+		return
+	}
+	tables := make([]string, 0, len(names))
+	for _, table := range names {
+		if table != "_flash_migrations" {
+			tables = append(tables, table)
+		}
+	}
+	overview.Objects = len(tables)
+	if counts, estimated, err := s.rowCounts(ctx, tables); err == nil {
+		for _, table := range tables {
+			overview.Items += int64(counts[table])
+			overview.ItemsEstimated = overview.ItemsEstimated || estimated[table]
+		}
+	}
+
+	// A database without flash migrations has no migrations table
+	applied, err := s.adapter.GetAppliedMigrations(ctx)
+	if err != nil {
+		return
+	}
+	ids := make([]string, 0, len(applied))
+	for id, at := range applied {
+		if at != nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return applied[ids[i]].After(*applied[ids[j]]) })
+	if len(ids) > overviewMigrations {
+		ids = ids[:overviewMigrations]
+	}
+	for _, id := range ids {
+		overview.Activity = append(overview.Activity, common.StoreActivity{
+			Time:        applied[id].UTC().Format(time.RFC3339),
+			Description: "Applied migration " + id,
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package sql
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// BindParams rewrites the placeholders of a query to the ones the database
+// driver understands and returns the arguments to run it with, so values
+// are sent as real parameters instead of being spliced into the SQL.
+// params is a list for $N and ? placeholders or a map by name for :name
+// placeholders.
+func (s *Service) BindParams(query string, params any) (string, []any, error) {
+	list, _ := params.([]any)
+	values, _ := params.(map[string]any)
+
+	var (
+		args              []any
+		named, positional bool
+		maxIndex          = -1
+		numbers           = make(map[string]int) // $N given to each :name on PostgreSQL
+		bindErr           error
+	)
+	bound := s.scanPlaceholders(query, func(p placeholder) string {
+		if bindErr != nil {
+			return ""
+		}
+		named = named || p.Name != ""
+		positional = positional || p.Name == ""
+		if named && positional {
+			bindErr = fmt.Errorf("query mixes named and positional parameters")
+			return ""
+		}
+
+		if p.Name != "" {
+			value, ok := values[p.Name]
+			if !ok {
+				bindErr = fmt.Errorf("no value given for parameter :%s", p.Name)
+				return ""
+			}
+			if !s.isPostgres() {
+				args = append(args, bindValue(value))
+				return "?"
+			}
+			if _, ok := numbers[p.Name]; !ok {
+				args = append(args, bindValue(value))
+				numbers[p.Name] = len(args)
+			}
+			return "$" + strconv.Itoa(numbers[p.Name])
+		}
+
+		if p.Index < 0 || p.Index >= len(list) {
+			bindErr = fmt.Errorf("query uses parameter %d but only %d given", p.Index+1, len(list))
+			return ""
+		}
+		if s.isPostgres() {
+			maxIndex = max(maxIndex, p.Index)
+			return "$" + strconv.Itoa(p.Index+1)
+		}
+		args = append(args, bindValue(list[p.Index]))
+		return "?"
+	})
+	if bindErr != nil {
+		return "", nil, bindErr
+	}
+
+	// PostgreSQL numbers its parameters, so each is passed once
+	for i := 0; i <= maxIndex; i++ {
+		args = append(args, bindValue(list[i]))
+	}
+	return bound, args, nil
+}
+
+// placeholder returns the nth parameter placeholder, counting from 1, in the
+// syntax of the database
+func (s *Service) placeholder(n int) string {
+	if s.isPostgres() {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// placeholders returns the placeholders of n arguments from the first, for
+// an IN list
+func (s *Service) placeholders(n int) string {
+	list := make([]string, n)
+	for i := range list {
+		list[i] = s.placeholder(i + 1)
+	}
+	return strings.Join(list, ", ")
+}
+
+// bindValue converts a JSON value to one all drivers accept: whole numbers
+// become integers, arrays and objects their JSON text
+func bindValue(value any) any {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+	case []any, map[string]any:
+		return jsonLiteral(v)
+	}
+	return value
+}
+
+// QueryParamSignature lists the parameters of a query in the order they are
+// bound, for the editor to prompt for their values. When the query is one
+// of the project's named queries, its parameters are named and typed like
+// in the generated clients.
+func (s *Service) QueryParamSignature(query string) []common.QueryParam {
+	params := s.queryPlaceholders(query)
+	if len(params) == 0 || s.cfg == nil {
+		return params
+	}
+
+	queries, err := s.loadNamedQueries()
+	if err != nil {
+		return params
+	}
+	for _, named := range queries {
+		if normalizeSQL(named.SQL) == normalizeSQL(query) {
+			return s.namedQueryParams(named)
+		}
+	}
+	return params
+}
+
+// namedQueryParams are the placeholders of a named query with the names and
+// types the query parser gave them
+func (s *Service) namedQueryParams(query *parser.Query) []common.QueryParam {
+	params := s.queryPlaceholders(query.SQL)
+	for i := range params {
+		if params[i].Placeholder[0] == ':' || i >= len(query.Params) {
+			continue
+		}
+		params[i].Name = paramName(query.Params[i], i)
+		params[i].Type = query.Params[i].Type
+	}
+	return params
+}
+
+// queryPlaceholders lists the distinct placeholders of a query. Positional
+// parameters are listed up to the highest one used, since all of them have
+// to be given.
+func (s *Service) queryPlaceholders(query string) []common.QueryParam {
+	var (
+		named      []common.QueryParam
+		positional []common.QueryParam
+		seen       = make(map[string]bool)
+	)
+	s.scanPlaceholders(query, func(p placeholder) string {
+		if p.Name != "" {
+			if !seen[p.Name] {
+				seen[p.Name] = true
+				named = append(named, common.QueryParam{Name: p.Name, Placeholder: p.Text})
+			}
+			return p.Text
+		}
+		for len(positional) <= p.Index {
+			n := len(positional) + 1
+			positional = append(positional, common.QueryParam{Name: fmt.Sprintf("p%d", n), Placeholder: "$" + strconv.Itoa(n)})
+		}
+		positional[p.Index].Placeholder = p.Text
+		return p.Text
+	})
+	return append(positional, named...)
+}
+
+// normalizeSQL collapses whitespace and drops the trailing semicolon, so an
+// edited copy of a query still matches its source
+func normalizeSQL(query string) string {
+	return strings.TrimSuffix(strings.Join(strings.Fields(query), " "), ";")
+}
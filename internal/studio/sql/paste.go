@@ -0,0 +1,451 @@
+package sql
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+const (
+	// pasteBatch is how many rows go in one INSERT
+	pasteBatch = 200
+	// pasteMaxErrors caps the errors reported for one paste
+	pasteMaxErrors = 100
+)
+
+var (
+	typeLengthRegex = regexp.MustCompile(`^(var)?char(acter)?(\s+varying)?\s*\((\d+)\)`)
+	uuidRegex       = regexp.MustCompile(`(?i)^[0-9a-f]{8}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{12}$`)
+	inlineEnumRegex = regexp.MustCompile(`(?i)^enum\s*\((.*)\)$`)
+
+	// pasteTimeLayouts are the date and time formats a pasted value may use
+	pasteTimeLayouts = []string{
+		time.RFC3339Nano,
+		"2006-01-02",
+		"2006-01-02 15:04",
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04:05.999999999",
+		"2006-01-02 15:04:05Z07:00",
+		"2006-01-02 15:04:05.999999999Z07:00",
+		"2006-01-02 15:04:05-07",
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04:05.999999999",
+		"15:04",
+		"15:04:05",
+	}
+)
+
+// PasteRequest is a block of rows copied from a spreadsheet
+type PasteRequest struct {
+	Text string `json:"text"`
+	// Header says whether the first line names the columns. When unset, it
+	// does if every cell of it is a column of the table.
+	Header *bool `json:"header,omitempty"`
+	// DryRun checks the rows without inserting them
+	DryRun bool `json:"dry_run"`
+}
+
+// PasteError is a line of a paste that can't be inserted
+type PasteError struct {
+	Line    int    `json:"line"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// PasteResult is what a paste did, or would do on a dry run
+type PasteResult struct {
+	Columns  []string     `json:"columns"` // the columns the values went to, in order
+	Header   bool         `json:"header"`
+	Rows     int          `json:"rows"`
+	Invalid  int          `json:"invalid"` // rows with errors
+	Inserted int          `json:"inserted"`
+	Errors   []PasteError `json:"errors,omitempty"` // the first pasteMaxErrors
+}
+
+// PasteRows inserts rows pasted from a spreadsheet. The values go to the
+// columns a header line names, or else to the columns of the table in
+// order, leaving out auto-increment columns unless the rows have a value
+// for every column. Each value is checked against the type of its column
+// first; when any doesn't fit, nothing is inserted and the result has the
+// errors. The rows are inserted in batches, all in one transaction.
+// Timestamps without an offset are taken as times in loc.
+func (s *Service) PasteRows(ctx context.Context, tableName string, paste *PasteRequest, loc *time.Location) (*PasteResult, error) {
+	s.ensureCorrectSchema(ctx)
+	records, lines, err := parsePaste(paste.Text)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("nothing to paste")
+	}
+
+	schema, err := s.adapter.GetTableColumns(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	var columns []types.SchemaColumn
+	byName := make(map[string]types.SchemaColumn, len(schema))
+	for _, col := range schema {
+		key := strings.ToLower(col.Name)
+		if _, seen := byName[key]; seen || s.isIgnoredColumn(tableName, col.Name) {
+			continue
+		}
+		byName[key] = col
+		columns = append(columns, col)
+	}
+
+	// uuid keys studio generates, when studio.generate_uuids is set
+	var generated []string
+	if s.cfg != nil && s.cfg.Studio.GenerateUUIDs != "" {
+		for _, col := range columns {
+			if generatesUUID(col.IsPrimary, col.Type, col.Default) {
+				generated = append(generated, col.Name)
+			}
+		}
+	}
+
+	result := &PasteResult{Header: isPasteHeader(records[0], byName)}
+	if paste.Header != nil {
+		result.Header = *paste.Header
+	}
+	fail := func(line int, column, message string) {
+		if len(result.Errors) < pasteMaxErrors {
+			result.Errors = append(result.Errors, PasteError{Line: line, Column: column, Message: message})
+		}
+	}
+
+	var targets []types.SchemaColumn
+	if result.Header {
+		mapped := make(map[string]bool)
+		for _, name := range records[0] {
+			col, ok := byName[strings.ToLower(strings.TrimSpace(name))]
+			if !ok {
+				return nil, fmt.Errorf("%s has no column %q", tableName, name)
+			}
+			if mapped[col.Name] {
+				return nil, fmt.Errorf("the header names %s twice", col.Name)
+			}
+			mapped[col.Name] = true
+			targets = append(targets, col)
+		}
+		records, lines = records[1:], lines[1:]
+	} else {
+		targets = pasteColumns(columns, records, generated)
+	}
+	for _, col := range targets {
+		result.Columns = append(result.Columns, col.Name)
+	}
+	result.Rows = len(records)
+
+	// Rows that leave different columns to their defaults can't share an
+	// INSERT, since SQLite has no DEFAULT in VALUES
+	type insertGroup struct {
+		columns []string
+		rows    []string
+	}
+	var groups []*insertGroup
+	groupOf := make(map[string]*insertGroup)
+	var pastedKeys []string
+
+	for i, record := range records {
+		if len(record) > len(targets) {
+			fail(lines[i], "", fmt.Sprintf("%d values, but there are %d columns to paste into", len(record), len(targets)))
+			result.Invalid++
+			continue
+		}
+
+		var names, literals []string
+		given := make(map[string]bool)
+		valid := true
+		for j, value := range record {
+			col := targets[j]
+			if strings.TrimSpace(value) == "" && slices.Contains(generated, col.Name) {
+				continue
+			}
+			if loc != nil && s.isPostgres() && storesInstant(col.Type) {
+				if t, ok := parseLocalTime(strings.TrimSpace(value), loc); ok {
+					value = t.UTC().Format(time.RFC3339Nano)
+				}
+			}
+			literal, err := pasteLiteral(col, value)
+			if err != nil {
+				fail(lines[i], col.Name, err.Error())
+				valid = false
+				continue
+			}
+			if literal == "" {
+				continue
+			}
+			if col.IsPrimary {
+				pastedKeys = append(pastedKeys, strings.TrimSpace(value))
+			}
+			names = append(names, common.QuoteIdentifier(col.Name))
+			literals = append(literals, literal)
+			given[col.Name] = true
+		}
+		for _, name := range generated {
+			if given[name] {
+				continue
+			}
+			id, err := s.newUUID()
+			if err != nil {
+				return nil, err
+			}
+			pastedKeys = append(pastedKeys, id)
+			names = append(names, common.QuoteIdentifier(name))
+			literals = append(literals, "'"+id+"'")
+		}
+		if !valid {
+			result.Invalid++
+			continue
+		}
+
+		key := strings.Join(names, ",")
+		group, ok := groupOf[key]
+		if !ok {
+			group = &insertGroup{columns: names}
+			groupOf[key] = group
+			groups = append(groups, group)
+		}
+		group.rows = append(group.rows, "("+strings.Join(literals, ", ")+")")
+	}
+	if result.Invalid > 0 || paste.DryRun {
+		return result, nil
+	}
+
+	var statements []string
+	for _, group := range groups {
+		for start := 0; start < len(group.rows); start += pasteBatch {
+			end := min(start+pasteBatch, len(group.rows))
+			if len(group.columns) == 0 {
+				// Every value was left to its default
+				insert := fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", common.QuoteIdentifier(tableName))
+				if s.provider() == "mysql" {
+					insert = fmt.Sprintf("INSERT INTO %s () VALUES ()", common.QuoteIdentifier(tableName))
+				}
+				for range group.rows[start:end] {
+					statements = append(statements, insert)
+				}
+				continue
+			}
+			statements = append(statements, fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
+				common.QuoteIdentifier(tableName), strings.Join(group.columns, ", "), strings.Join(group.rows[start:end], ", ")))
+		}
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	err = s.journalPaste(ctx, tableName, columns, pastedKeys, fmt.Sprintf("Pasted %d row(s)", result.Rows), func() error {
+		return s.adapter.ExecuteMigration(ctx, strings.Join(statements, ";\n")+";")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert the pasted rows: %w", err)
+	}
+	result.Inserted = result.Rows
+	return result, nil
+}
+
+// journalPaste runs edit, which inserts pasted rows, and records the rows
+// for undo. They are the rows with the pasted primary keys and, when the
+// key is auto-increment, the rows past the largest key before the insert.
+// The caller holds writeMu.
+func (s *Service) journalPaste(ctx context.Context, tableName string, columns []types.SchemaColumn, pastedKeys []string, description string, edit func() error) error {
+	var pk types.SchemaColumn
+	for _, col := range columns {
+		if col.IsPrimary {
+			pk = col
+			break
+		}
+	}
+	if pk.Name == "" || (len(pastedKeys) == 0 && !pk.IsAutoIncrement) {
+		return edit()
+	}
+
+	table, column := common.QuoteIdentifier(tableName), common.QuoteIdentifier(pk.Name)
+	var largest any
+	if pk.IsAutoIncrement {
+		result, err := s.adapter.ExecuteQuery(ctx, fmt.Sprintf("SELECT MAX(%s) AS largest FROM %s", column, table))
+		if err != nil {
+			return edit()
+		}
+		if len(result.Rows) > 0 {
+			largest = result.Rows[0]["largest"]
+		}
+	}
+
+	if err := edit(); err != nil {
+		return err
+	}
+
+	after, err := s.readRows(ctx, tableName, pk.Name, pastedKeys)
+	if err != nil {
+		return nil
+	}
+	if pk.IsAutoIncrement {
+		query := fmt.Sprintf("SELECT * FROM %s", table)
+		var args []any
+		if largest != nil {
+			query += fmt.Sprintf(" WHERE %s > %s", column, s.placeholder(1))
+			args = append(args, largest)
+		}
+		result, err := s.adapter.ExecuteQuery(ctx, query, args...)
+		if err != nil {
+			return nil
+		}
+		for _, row := range result.Rows {
+			after[exportText(row[pk.Name])] = row
+		}
+	}
+	s.record(tableName, pk.Name, description, nil, after)
+	return nil
+}
+
+// parsePaste splits pasted text into rows of values, with the line each row
+// starts on. Spreadsheets copy cells separated by tabs; text without a tab
+// on its first line is taken as CSV. Blank rows are left out.
+func parsePaste(text string) ([][]string, []int, error) {
+	text = strings.TrimPrefix(text, "\ufeff")
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if first, _, _ := strings.Cut(text, "\n"); strings.Contains(first, "\t") {
+		reader.Comma = '\t'
+	}
+
+	var records [][]string
+	var lines []int
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read the pasted rows: %w", err)
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	return records, lines, nil
+}
+
+// isPasteHeader reports whether every cell of a row names a column
+func isPasteHeader(record []string, byName map[string]types.SchemaColumn) bool {
+	for _, cell := range record {
+		if _, ok := byName[strings.ToLower(strings.TrimSpace(cell))]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// pasteColumns returns the columns rows without a header go to: all of
+// them when a row has a value for each, else those that aren't
+// auto-increment or generated by studio
+func pasteColumns(columns []types.SchemaColumn, records [][]string, generated []string) []types.SchemaColumn {
+	for _, record := range records {
+		if len(record) >= len(columns) {
+			return columns
+		}
+	}
+	var targets []types.SchemaColumn
+	for _, col := range columns {
+		if !col.IsAutoIncrement && !slices.Contains(generated, col.Name) {
+			targets = append(targets, col)
+		}
+	}
+	return targets
+}
+
+// pasteLiteral checks a pasted value against the type of its column and
+// renders it as a SQL literal. An empty value is NULL, or left to the
+// default of the column when it has one, which gives an empty literal.
+func pasteLiteral(column types.SchemaColumn, value string) (string, error) {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		switch {
+		case column.Default != "" || column.IsAutoIncrement:
+			return "", nil
+		case column.Nullable:
+			return "NULL", nil
+		}
+		return "", fmt.Errorf("needs a value")
+	}
+
+	colType := strings.ToLower(strings.TrimSpace(column.Type))
+	switch {
+	case strings.Contains(colType, "bool") || colType == "tinyint(1)":
+		switch strings.ToLower(trimmed) {
+		case "true", "t", "yes", "y", "1":
+			return "TRUE", nil
+		case "false", "f", "no", "n", "0":
+			return "FALSE", nil
+		}
+		return "", fmt.Errorf("%q isn't a boolean", value)
+	case containsAny(colType, "int", "serial") && !containsAny(colType, "interval", "point"):
+		if _, err := strconv.ParseInt(trimmed, 10, 64); err != nil {
+			return "", fmt.Errorf("%q isn't an integer", value)
+		}
+		value = trimmed
+	case isNumericColumnType(colType):
+		if _, err := strconv.ParseFloat(trimmed, 64); err != nil {
+			return "", fmt.Errorf("%q isn't a number", value)
+		}
+		value = trimmed
+	case strings.Contains(colType, "uuid"):
+		if !uuidRegex.MatchString(trimmed) {
+			return "", fmt.Errorf("%q isn't a UUID", value)
+		}
+		value = trimmed
+	case strings.Contains(colType, "json"):
+		if !json.Valid([]byte(trimmed)) {
+			return "", fmt.Errorf("isn't valid JSON")
+		}
+	case containsAny(colType, "date", "time") && !strings.Contains(colType, "interval"):
+		if !isPasteTime(trimmed) {
+			return "", fmt.Errorf("%q isn't a date or time, use YYYY-MM-DD or YYYY-MM-DD HH:MM:SS", value)
+		}
+		value = trimmed
+	default:
+		if matches := inlineEnumRegex.FindStringSubmatch(strings.TrimSpace(column.Type)); matches != nil {
+			if !enumAllows(matches[1], value) {
+				return "", fmt.Errorf("%q isn't one of %s", value, matches[1])
+			}
+		}
+		if matches := typeLengthRegex.FindStringSubmatch(colType); matches != nil {
+			if limit, _ := strconv.Atoi(matches[4]); utf8.RuneCountInString(value) > limit {
+				return "", fmt.Errorf("is longer than %d characters", limit)
+			}
+		}
+	}
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'", nil
+}
+
+func isPasteTime(value string) bool {
+	for _, layout := range pasteTimeLayouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// enumAllows reports whether a value is one of the quoted values of a MySQL
+// ENUM type
+func enumAllows(values, value string) bool {
+	return slices.Contains(enumValues(values), value)
+}
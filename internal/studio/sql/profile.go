@@ -0,0 +1,314 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+const (
+	defaultProfileSampleSize = 10000
+	maxProfileSampleSize     = 100000
+	defaultProfileTopN       = 5
+	maxProfileTopN           = 50
+	maxProfileBudget         = 30 * time.Second
+	profileHistogramBuckets  = 10
+)
+
+// profileBudgets is how long profiling a table may take by default. SQLite
+// runs in-process and blocks the studio while it scans, so it gets less.
+var profileBudgets = map[string]time.Duration{
+	"postgresql": 5 * time.Second,
+	"postgres":   5 * time.Second,
+	"mysql":      5 * time.Second,
+	"sqlite":     2 * time.Second,
+	"sqlite3":    2 * time.Second,
+}
+
+// ProfileTable computes column statistics on a sample of up to sampleSize
+// rows. Columns are profiled one at a time until the time budget runs out;
+// the remaining columns are returned as skipped and the profile is marked
+// partial. A zero budget uses the provider's default. Masked columns aren't
+// profiled unless admin is set.
+func (s *Service) ProfileTable(ctx context.Context, tableName string, sampleSize, topN int, budget time.Duration, admin bool) (*common.TableProfile, error) {
+	s.ensureCorrectSchema(ctx)
+	if s.isIgnoredTable(tableName) {
+		return nil, fmt.Errorf("table %s is ignored", tableName)
+	}
+
+	if sampleSize <= 0 {
+		sampleSize = defaultProfileSampleSize
+	}
+	if sampleSize > maxProfileSampleSize {
+		sampleSize = maxProfileSampleSize
+	}
+	if topN <= 0 {
+		topN = defaultProfileTopN
+	}
+	if topN > maxProfileTopN {
+		topN = maxProfileTopN
+	}
+	if budget <= 0 {
+		if budget = profileBudgets[s.provider()]; budget == 0 {
+			budget = 5 * time.Second
+		}
+	}
+	if budget > maxProfileBudget {
+		budget = maxProfileBudget
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+
+	columns, err := s.adapter.GetTableColumns(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &common.TableProfile{Table: tableName, Columns: []common.ColumnProfile{}}
+	profile.RowCount, profile.RowCountEstimated, err = s.profileRowCount(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	profile.SampleSize = sampleSize
+	if profile.RowCount < sampleSize {
+		profile.SampleSize = profile.RowCount
+	}
+	profile.Sampled = profile.RowCount > sampleSize
+
+	seen := make(map[string]bool)
+	for _, col := range columns {
+		if seen[col.Name] || s.isIgnoredColumn(tableName, col.Name) {
+			continue
+		}
+		seen[col.Name] = true
+
+		column := common.ColumnProfile{Name: col.Name, Type: col.Type}
+		if !admin && s.isMaskedColumn(tableName, col.Name) {
+			column.Masked = true
+		} else if ctx.Err() != nil {
+			column.Skipped = true
+			profile.Partial = true
+		} else if err := s.profileColumn(ctx, tableName, profile, &column, topN); err != nil {
+			if ctx.Err() != nil {
+				column.Skipped = true
+				profile.Partial = true
+			} else {
+				column.Error = err.Error()
+			}
+		}
+		profile.Columns = append(profile.Columns, column)
+	}
+
+	profile.DurationMS = time.Since(start).Milliseconds()
+	return profile, nil
+}
+
+// profileRowCount returns the number of rows of a table. PostgreSQL uses the
+// planner's estimate when the table has been analyzed, since COUNT(*) on a
+// large table can take the whole budget.
+func (s *Service) profileRowCount(ctx context.Context, tableName string) (int, bool, error) {
+	if s.isPostgres() {
+		query := fmt.Sprintf("SELECT reltuples::bigint AS n FROM pg_class WHERE oid = to_regclass('%s')",
+			strings.ReplaceAll(common.QuoteIdentifier(tableName), "'", "''"))
+		if result, err := s.adapter.ExecuteQuery(ctx, query); err == nil && len(result.Rows) > 0 {
+			if n := profileInt(result.Rows[0]["n"]); n > 0 {
+				return n, true, nil
+			}
+		}
+	}
+
+	count, err := s.adapter.GetTableRowCount(ctx, tableName)
+	return count, false, err
+}
+
+// profileSample returns the FROM clause of the sample a column is profiled
+// on. PostgreSQL samples random pages with a fixed seed so every column
+// sees the same rows; other providers take the first rows of the table.
+func (s *Service) profileSample(tableName, column string, profile *common.TableProfile) string {
+	source := s.quoteIdentifier(tableName)
+	if profile.Sampled && s.isPostgres() && profile.RowCount > 0 {
+		// Oversample a little, pages hold a varying number of rows
+		percent := float64(profile.SampleSize) * 150 / float64(profile.RowCount)
+		if percent > 100 {
+			percent = 100
+		}
+		source += fmt.Sprintf(" TABLESAMPLE SYSTEM (%g) REPEATABLE (42)", percent)
+	}
+	return fmt.Sprintf("(SELECT %s AS v FROM %s LIMIT %d) sample", column, source, profile.SampleSize)
+}
+
+func (s *Service) profileColumn(ctx context.Context, tableName string, profile *common.TableProfile, column *common.ColumnProfile, topN int) error {
+	colType := strings.ToLower(column.Type)
+	binary := containsAny(colType, "bytea", "blob", "binary")
+	orderable := !binary && !containsAny(colType, "bool", "json", "xml", "uuid", "point", "polygon", "tsvector", "[]", "array")
+
+	expr := s.quoteIdentifier(column.Name)
+	// json and xml have no equality operator in PostgreSQL
+	if s.isPostgres() && containsAny(colType, "json", "xml") {
+		expr = fmt.Sprintf("CAST(%s AS TEXT)", expr)
+	}
+	sample := s.profileSample(tableName, expr, profile)
+
+	stats := "COUNT(*) AS total, COUNT(v) AS non_null"
+	if !binary {
+		stats += ", COUNT(DISTINCT v) AS distinct_count"
+	}
+	if orderable {
+		stats += ", MIN(v) AS min_value, MAX(v) AS max_value"
+	}
+	result, err := s.adapter.ExecuteQuery(ctx, fmt.Sprintf("SELECT %s FROM %s", stats, sample))
+	if err != nil {
+		return err
+	}
+	if len(result.Rows) == 0 {
+		return nil
+	}
+
+	row := result.Rows[0]
+	total := profileInt(row["total"])
+	column.NullCount = total - profileInt(row["non_null"])
+	if total > 0 {
+		column.NullFraction = float64(column.NullCount) / float64(total)
+	}
+	column.DistinctCount = profileInt(row["distinct_count"])
+	column.Min, column.Max = row["min_value"], row["max_value"]
+
+	if binary || column.NullCount == total {
+		return nil
+	}
+
+	top, err := s.adapter.ExecuteQuery(ctx, fmt.Sprintf(
+		"SELECT v AS value, COUNT(*) AS n FROM %s WHERE v IS NOT NULL GROUP BY v ORDER BY 2 DESC, 1 LIMIT %d", sample, topN))
+	if err != nil {
+		return err
+	}
+	for _, r := range top.Rows {
+		column.TopValues = append(column.TopValues, common.ValueCount{Value: r["value"], Count: profileInt(r["n"])})
+	}
+
+	if orderable && isNumericColumnType(colType) {
+		histogram, err := s.profileHistogram(ctx, sample)
+		if err != nil {
+			return err
+		}
+		column.Histogram = histogram
+	}
+	return nil
+}
+
+// profileHistogram splits the numeric values of the sample into equal-width
+// buckets between their minimum and maximum
+func (s *Service) profileHistogram(ctx context.Context, sample string) ([]common.HistogramBucket, error) {
+	floatType, bucketExpr := s.floatType(), "FLOOR((v - %g) / %g)"
+	if s.isSQLite() {
+		// FLOOR is only available when SQLite is built with math functions;
+		// the values are never below the minimum, so truncating is the same
+		bucketExpr = "CAST((v - %g) / %g AS INTEGER)"
+	}
+
+	bounds, err := s.adapter.ExecuteQuery(ctx, fmt.Sprintf(
+		"SELECT CAST(MIN(v) AS %s) AS lo, CAST(MAX(v) AS %s) AS hi FROM %s", floatType, floatType, sample))
+	if err != nil || len(bounds.Rows) == 0 {
+		return nil, err
+	}
+	lo, okLo := profileFloat(bounds.Rows[0]["lo"])
+	hi, okHi := profileFloat(bounds.Rows[0]["hi"])
+	if !okLo || !okHi || hi <= lo {
+		return nil, nil
+	}
+
+	width := (hi - lo) / profileHistogramBuckets
+	result, err := s.adapter.ExecuteQuery(ctx, fmt.Sprintf(
+		"SELECT "+bucketExpr+" AS bucket, COUNT(*) AS n FROM %s WHERE v IS NOT NULL GROUP BY 1", lo, width, sample))
+	if err != nil {
+		return nil, err
+	}
+
+	histogram := make([]common.HistogramBucket, profileHistogramBuckets)
+	for i := range histogram {
+		histogram[i].From = lo + float64(i)*width
+		histogram[i].To = lo + float64(i+1)*width
+	}
+	for _, row := range result.Rows {
+		bucket := profileInt(row["bucket"])
+		if bucket >= profileHistogramBuckets {
+			bucket = profileHistogramBuckets - 1 // the maximum itself
+		}
+		if bucket >= 0 {
+			histogram[bucket].Count += profileInt(row["n"])
+		}
+	}
+	return histogram, nil
+}
+
+// floatType is the type numbers are cast to for reading them as float64
+func (s *Service) floatType() string {
+	switch s.provider() {
+	case "mysql":
+		return "DOUBLE"
+	case "sqlite", "sqlite3":
+		return "REAL"
+	}
+	return "DOUBLE PRECISION"
+}
+
+// isNumericColumnType reports whether a lower-cased column type holds numbers
+func isNumericColumnType(colType string) bool {
+	return containsAny(colType, "int", "serial", "decimal", "numeric", "float", "double", "real", "money") &&
+		!containsAny(colType, "interval", "point")
+}
+
+func containsAny(s string, substrs ...string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func profileInt(value any) int {
+	switch v := value.(type) {
+	case int64:
+		return int(v)
+	case int32:
+		return int(v)
+	case int:
+		return v
+	case float64:
+		return int(v)
+	case []byte:
+		n, _ := strconv.Atoi(string(v))
+		return n
+	case string:
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+	return 0
+}
+
+func profileFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case []byte:
+		f, err := strconv.ParseFloat(string(v), 64)
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
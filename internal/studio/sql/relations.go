@@ -0,0 +1,223 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// foreignKey is a column that references a column of another table
+type foreignKey struct {
+	table     string
+	column    string
+	refTable  string
+	refColumn string
+	onDelete  string // CASCADE, SET NULL, SET DEFAULT, RESTRICT or NO ACTION
+}
+
+// loadForeignKeys returns the foreign keys between the tables studio shows,
+// and the primary key column of every table that has one
+func (s *Service) loadForeignKeys(ctx context.Context) ([]foreignKey, map[string]string, error) {
+	tables, err := s.adapter.GetCurrentSchema(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	primaryKeys := make(map[string]string)
+	var fks []foreignKey
+	for _, table := range tables {
+		if s.isIgnoredTable(table.Name) {
+			continue
+		}
+		for _, col := range table.Columns {
+			if col.IsPrimary && primaryKeys[table.Name] == "" {
+				primaryKeys[table.Name] = col.Name
+			}
+			if col.ForeignKeyTable == "" || s.isIgnoredTable(col.ForeignKeyTable) || s.isIgnoredColumn(table.Name, col.Name) {
+				continue
+			}
+			fks = append(fks, foreignKey{
+				table:     table.Name,
+				column:    col.Name,
+				refTable:  col.ForeignKeyTable,
+				refColumn: col.ForeignKeyColumn,
+				onDelete:  strings.ToUpper(col.OnDeleteAction),
+			})
+		}
+	}
+	return fks, primaryKeys, nil
+}
+
+// primaryKeyColumn returns the primary key of a table, falling back to id
+func primaryKeyColumn(primaryKeys map[string]string, table string) string {
+	if pk := primaryKeys[table]; pk != "" {
+		return pk
+	}
+	return "id"
+}
+
+// rowCondition returns the WHERE condition selecting rows by primary key
+func (s *Service) rowCondition(primaryKeys map[string]string, tableName string, rowIDs ...string) string {
+	values := make([]string, len(rowIDs))
+	for i, rowID := range rowIDs {
+		values[i] = "'" + strings.ReplaceAll(rowID, "'", "''") + "'"
+	}
+	return fmt.Sprintf("%s IN (%s)", s.quoteIdentifier(primaryKeyColumn(primaryKeys, tableName)), strings.Join(values, ", "))
+}
+
+// countRows counts the rows of a table matching a WHERE condition
+func (s *Service) countRows(ctx context.Context, tableName, where string) (int, error) {
+	result, err := s.adapter.ExecuteQuery(ctx, fmt.Sprintf("SELECT COUNT(*) AS count FROM %s WHERE %s", s.quoteIdentifier(tableName), where))
+	if err != nil {
+		return 0, err
+	}
+	if len(result.Rows) == 0 {
+		return 0, nil
+	}
+	return profileInt(result.Rows[0]["count"]), nil
+}
+
+// GetRowDetail returns a row with the rows its foreign keys point to and
+// the first page of rows referencing it from every table. Masked columns
+// are masked unless admin is set.
+func (s *Service) GetRowDetail(ctx context.Context, tableName, rowID string, limit int, admin bool) (*common.RowDetail, error) {
+	s.ensureCorrectSchema(ctx)
+	if s.isIgnoredTable(tableName) {
+		return nil, fmt.Errorf("table %s is ignored", tableName)
+	}
+
+	fks, primaryKeys, err := s.loadForeignKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	condition := s.rowCondition(primaryKeys, tableName, rowID)
+
+	result, err := s.adapter.ExecuteQuery(ctx, fmt.Sprintf("SELECT * FROM %s WHERE %s", s.quoteIdentifier(tableName), condition))
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Rows) == 0 {
+		return nil, fmt.Errorf("row %s not found in %s", rowID, tableName)
+	}
+	s.stripIgnoredColumns(tableName, result.Rows)
+	exactRowValues(result.Rows)
+	if !admin {
+		s.maskRows(tableName, result.Rows)
+	}
+	s.describeEncryptedRows(tableName, result.Rows)
+
+	detail := &common.RowDetail{
+		Table:    tableName,
+		Row:      result.Rows[0],
+		Parents:  []common.ParentRow{},
+		Children: []common.RelatedRowPage{},
+	}
+
+	for _, fk := range fks {
+		if fk.table != tableName {
+			continue
+		}
+		parent := common.ParentRow{Column: fk.column, Table: fk.refTable, ReferencedColumn: fk.refColumn}
+		if detail.Row[fk.column] != nil {
+			rows, err := s.adapter.ExecuteQuery(ctx, fmt.Sprintf("SELECT * FROM %s WHERE %s IN (SELECT %s FROM %s WHERE %s)",
+				s.quoteIdentifier(fk.refTable), s.quoteIdentifier(fk.refColumn),
+				s.quoteIdentifier(fk.column), s.quoteIdentifier(tableName), condition))
+			if err != nil {
+				return nil, fmt.Errorf("failed to load %s row referenced by %s: %w", fk.refTable, fk.column, err)
+			}
+			if len(rows.Rows) > 0 {
+				s.stripIgnoredColumns(fk.refTable, rows.Rows)
+				exactRowValues(rows.Rows)
+				if !admin {
+					s.maskRows(fk.refTable, rows.Rows)
+				}
+				s.describeEncryptedRows(fk.refTable, rows.Rows)
+				parent.Row = rows.Rows[0]
+			}
+		}
+		detail.Parents = append(detail.Parents, parent)
+	}
+
+	for _, fk := range fks {
+		if fk.refTable != tableName {
+			continue
+		}
+		page, err := s.relatedRows(ctx, fk, primaryKeys, condition, 1, limit, admin)
+		if err != nil {
+			return nil, err
+		}
+		detail.Children = append(detail.Children, *page)
+	}
+
+	return detail, nil
+}
+
+// GetRelatedRows returns one page of the rows of childTable whose
+// childColumn references the given row. Masked columns are masked unless
+// admin is set.
+func (s *Service) GetRelatedRows(ctx context.Context, tableName, rowID, childTable, childColumn string, page, limit int, admin bool) (*common.RelatedRowPage, error) {
+	s.ensureCorrectSchema(ctx)
+	if s.isIgnoredTable(tableName) {
+		return nil, fmt.Errorf("table %s is ignored", tableName)
+	}
+
+	fks, primaryKeys, err := s.loadForeignKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fk := range fks {
+		if fk.table == childTable && fk.column == childColumn && fk.refTable == tableName {
+			return s.relatedRows(ctx, fk, primaryKeys, s.rowCondition(primaryKeys, tableName, rowID), page, limit, admin)
+		}
+	}
+	return nil, fmt.Errorf("%s.%s does not reference %s", childTable, childColumn, tableName)
+}
+
+// relatedRows pages through the rows of fk.table that reference the row of
+// fk.refTable matching condition
+func (s *Service) relatedRows(ctx context.Context, fk foreignKey, primaryKeys map[string]string, condition string, page, limit int, admin bool) (*common.RelatedRowPage, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	where := fmt.Sprintf("%s IN (SELECT %s FROM %s WHERE %s)",
+		s.quoteIdentifier(fk.column), s.quoteIdentifier(fk.refColumn), s.quoteIdentifier(fk.refTable), condition)
+
+	total, err := s.countRows(ctx, fk.table, where)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count %s rows referencing %s: %w", fk.table, fk.refTable, err)
+	}
+
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s", s.quoteIdentifier(fk.table), where)
+	if pk := primaryKeys[fk.table]; pk != "" {
+		query += " ORDER BY " + s.quoteIdentifier(pk)
+	}
+	query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, (page-1)*limit)
+
+	result, err := s.adapter.ExecuteQuery(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s rows referencing %s: %w", fk.table, fk.refTable, err)
+	}
+	s.stripIgnoredColumns(fk.table, result.Rows)
+	exactRowValues(result.Rows)
+	if !admin {
+		s.maskRows(fk.table, result.Rows)
+	}
+	s.describeEncryptedRows(fk.table, result.Rows)
+
+	return &common.RelatedRowPage{
+		Table:            fk.table,
+		Column:           fk.column,
+		ReferencedColumn: fk.refColumn,
+		Rows:             result.Rows,
+		Total:            total,
+		Page:             page,
+		Limit:            limit,
+	}, nil
+}
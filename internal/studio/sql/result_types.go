@@ -0,0 +1,61 @@
+package sql
+
+import (
+	"strings"
+	"time"
+
+	dbcommon "github.com/Lumos-Labs-HQ/flash/internal/database/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// resultColumns describes the columns of query results by the types the
+// driver reports. Columns it has no type for, like expressions on SQLite,
+// take the type of their values.
+func (s *Service) resultColumns(result *dbcommon.QueryResult) []common.ColumnInfo {
+	columns := make([]common.ColumnInfo, len(result.Columns))
+	for i, name := range result.Columns {
+		colType := ""
+		if i < len(result.ColumnTypes) {
+			colType = result.ColumnTypes[i]
+		}
+		if colType == "" {
+			colType = valueType(result.Rows, name)
+		}
+		columns[i] = common.ColumnInfo{
+			Name:     name,
+			Type:     colType,
+			Kind:     resultKind(colType),
+			TimeZone: s.isPostgres() && storesInstant(colType),
+		}
+	}
+	return columns
+}
+
+// resultKind returns the kind of a result column of a type. Arrays are
+// shown as text whatever they hold.
+func resultKind(colType string) string {
+	if colType == "" || strings.HasSuffix(colType, "[]") {
+		return kindText
+	}
+	return columnKind(colType)
+}
+
+// valueType names the type of the first value of a column that isn't NULL
+func valueType(rows []map[string]any, name string) string {
+	for _, row := range rows {
+		switch row[name].(type) {
+		case nil:
+			continue
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			return "INTEGER"
+		case float32, float64:
+			return "REAL"
+		case bool:
+			return "BOOLEAN"
+		case time.Time:
+			return "TIMESTAMP"
+		}
+		return "TEXT"
+	}
+	return "TEXT"
+}
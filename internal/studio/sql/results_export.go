@@ -0,0 +1,417 @@
+package sql
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	dbcommon "github.com/Lumos-Labs-HQ/flash/internal/database/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// ResultExport is a query from the SQL editor to download the results of
+type ResultExport struct {
+	Query   string   `json:"query"`
+	Params  any      `json:"params"`  // as for POST /api/sql
+	Format  string   `json:"format"`  // csv, jsonl or markdown
+	Columns []string `json:"columns"` // all columns when empty
+	Offset  int      `json:"offset"`  // rows to skip, to continue an earlier export
+	Limit   int      `json:"limit"`   // row cap, no cap when 0
+}
+
+// resultWriter writes query results in a download format. The header
+// gives the kinds of the columns along with their names.
+type resultWriter interface {
+	WriteHeader(columns, kinds []string) error
+	WriteRow(values []any) error
+	Flush() error
+}
+
+// exportFormats are the download formats with their content type and file
+// extension
+var exportFormats = map[string]struct {
+	contentType string
+	extension   string
+}{
+	"csv":      {"text/csv; charset=utf-8", "csv"},
+	"jsonl":    {"application/x-ndjson", "jsonl"},
+	"markdown": {"text/markdown; charset=utf-8", "md"},
+}
+
+func newResultWriter(format string, w io.Writer) resultWriter {
+	switch format {
+	case "csv":
+		return &csvResultWriter{w: csv.NewWriter(w)}
+	case "jsonl":
+		return &jsonlResultWriter{w: w}
+	default:
+		return &markdownResultWriter{w: w}
+	}
+}
+
+// StreamSQL runs a query from the editor and passes its columns and their
+// database types to onColumns, then its rows to onRow as they are read, so
+// large results are never held in memory. Only queries that read rows can
+// be streamed: an export runs the query again.
+func (s *Service) StreamSQL(ctx context.Context, query string, args []any, onColumns func(columns, types []string) error, onRow func([]any) error) error {
+	s.ensureCorrectSchema(ctx)
+	if !isSelectStatement(query) {
+		return fmt.Errorf("only queries that return rows can be exported")
+	}
+
+	type QueryStreamer interface {
+		StreamQuery(ctx context.Context, query string, args []any, onColumns func(columns, types []string) error, onRow func([]any) error) error
+	}
+	if streamer, ok := s.adapter.(QueryStreamer); ok {
+		return streamer.StreamQuery(ctx, query, args, onColumns, onRow)
+	}
+
+	result, err := s.adapter.ExecuteQuery(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("query execution failed: %w", err)
+	}
+	if err := onColumns(result.Columns, result.ColumnTypes); err != nil {
+		return err
+	}
+	values := make([]any, len(result.Columns))
+	for _, row := range result.Rows {
+		for i, col := range result.Columns {
+			values[i] = row[col]
+		}
+		if err := onRow(values); err != nil {
+			if errors.Is(err, dbcommon.ErrStopRows) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportResults streams the results of an editor query to a result writer
+// for export.Format, keeping the selected columns and the rows between
+// Offset and Limit. start is called before anything is written, so errors
+// up to then can still be reported as such. While columns are masked it
+// needs admin.
+func (s *Service) ExportResults(ctx context.Context, export *ResultExport, args []any, admin bool, start func() io.Writer) error {
+	if err := s.checkQueryAccess(admin); err != nil {
+		return err
+	}
+	return s.streamExport(ctx, export, args, func(columns []string) ([]string, error) {
+		if len(export.Columns) > 0 {
+			return export.Columns, nil
+		}
+		return columns, nil
+	}, start)
+}
+
+// ExportTable streams the rows of a table matching filters to a result
+// writer for format, in primary key order. Soft-deleted rows are left out,
+// and so are masked columns unless admin is set.
+func (s *Service) ExportTable(ctx context.Context, tableName string, filters []common.Filter, format string, loc *time.Location, admin bool, start func() io.Writer) error {
+	s.ensureCorrectSchema(ctx)
+	if s.isIgnoredTable(tableName) {
+		return fmt.Errorf("table %s is ignored", tableName)
+	}
+	schema, err := s.adapter.GetTableColumns(ctx, tableName)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		for _, filter := range filters {
+			if err := s.checkUnmasked(tableName, filter.Column); err != nil {
+				return err
+			}
+		}
+	}
+
+	var columns []string
+	var orderBy string
+	var described []common.ColumnInfo
+	columnTypes := make(map[string]string)
+	for _, col := range schema {
+		if _, seen := columnTypes[col.Name]; seen || s.isIgnoredColumn(tableName, col.Name) {
+			continue
+		}
+		columnTypes[col.Name] = col.Type
+		described = append(described, common.ColumnInfo{Name: col.Name, Type: col.Type, Nullable: col.Nullable})
+		if col.IsPrimary && orderBy == "" {
+			orderBy = col.Name
+		}
+		if admin || !s.isMaskedColumn(tableName, col.Name) {
+			columns = append(columns, col.Name)
+		}
+	}
+
+	if len(columns) == 0 {
+		return fmt.Errorf("%s has no columns to export", tableName)
+	}
+
+	s.describeColumns(ctx, tableName, described)
+	filterColumns := make(map[string]common.ColumnInfo, len(described))
+	for _, col := range described {
+		filterColumns[col.Name] = col
+	}
+	where, args, err := s.buildWhereClause(ctx, filters, filterColumns, loc)
+	if err != nil {
+		return err
+	}
+	if softDeleteColumn := s.softDeleteColumn(columnTypes); softDeleteColumn != "" {
+		condition := fmt.Sprintf("%s IS NULL", common.QuoteIdentifier(softDeleteColumn))
+		if where != "" {
+			where = fmt.Sprintf("(%s) AND %s", where, condition)
+		} else {
+			where = condition
+		}
+	}
+
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = common.QuoteIdentifier(col)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(quoted, ", "), common.QuoteIdentifier(tableName))
+	if where != "" {
+		query += " WHERE " + where
+	}
+	if orderBy != "" {
+		query += " ORDER BY " + common.QuoteIdentifier(orderBy)
+	}
+
+	export := &ResultExport{Query: query, Format: format}
+	return s.streamExport(ctx, export, args, func(columns []string) ([]string, error) {
+		return columns, nil
+	}, start)
+}
+
+// streamExport runs the query of an export and writes its rows between
+// Offset and Limit, with the columns pick selects from those of the results
+func (s *Service) streamExport(ctx context.Context, export *ResultExport, args []any, pick func([]string) ([]string, error), start func() io.Writer) error {
+	var (
+		writer  resultWriter
+		indexes []int
+		kinds   []string
+		skipped int
+		written int
+	)
+
+	onColumns := func(columns, types []string) error {
+		selected, err := pick(columns)
+		if err != nil {
+			return err
+		}
+		for _, name := range selected {
+			i := slices.Index(columns, name)
+			if i < 0 {
+				return fmt.Errorf("column %s is not in the results", name)
+			}
+			indexes = append(indexes, i)
+			kind := kindText
+			if i < len(types) {
+				kind = resultKind(types[i])
+			}
+			kinds = append(kinds, kind)
+		}
+
+		writer = newResultWriter(export.Format, start())
+		return writer.WriteHeader(selected, kinds)
+	}
+
+	row := make([]any, 0, len(export.Columns))
+	onRow := func(values []any) error {
+		if skipped < export.Offset {
+			skipped++
+			return nil
+		}
+		row = row[:0]
+		for j, i := range indexes {
+			row = append(row, exportValue(kinds[j], values[i]))
+		}
+		if err := writer.WriteRow(row); err != nil {
+			return err
+		}
+		written++
+		if export.Limit > 0 && written >= export.Limit {
+			return dbcommon.ErrStopRows
+		}
+		return nil
+	}
+
+	if err := s.StreamSQL(ctx, export.Query, args, onColumns, onRow); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// exportValue writes the values of date columns as dates, which drivers
+// read as timestamps at midnight
+func exportValue(kind string, value any) any {
+	if t, ok := value.(time.Time); ok && kind == kindDate {
+		return t.Format("2006-01-02")
+	}
+	return value
+}
+
+// exportText renders a value as text for CSV and Markdown
+func exportText(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case [16]byte:
+		return formatUUID(v)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	}
+	// Arrays, JSON documents and driver types like numerics
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	var text string
+	if json.Unmarshal(data, &text) == nil {
+		return text
+	}
+	return string(data)
+}
+
+// exportJSON converts a value for a JSON document. Numbers a JSON number
+// could lose digits of are written as text.
+func exportJSON(value any) any {
+	if losesDigits(value) {
+		if text, ok := exactText(value); ok {
+			return text
+		}
+	}
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case [16]byte:
+		return formatUUID(v)
+	}
+	return value
+}
+
+// formatUUID formats the UUIDs PostgreSQL returns as 16 bytes
+func formatUUID(b [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+type csvResultWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func (c *csvResultWriter) WriteHeader(columns, kinds []string) error {
+	return c.w.Write(columns)
+}
+
+func (c *csvResultWriter) WriteRow(values []any) error {
+	c.record = c.record[:0]
+	for _, value := range values {
+		c.record = append(c.record, exportText(value))
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvResultWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonlResultWriter writes a JSON object per row, keys in column order
+type jsonlResultWriter struct {
+	w       io.Writer
+	columns [][]byte
+	buf     []byte
+}
+
+func (j *jsonlResultWriter) WriteHeader(columns, kinds []string) error {
+	for _, col := range columns {
+		key, _ := json.Marshal(col)
+		j.columns = append(j.columns, key)
+	}
+	return nil
+}
+
+func (j *jsonlResultWriter) WriteRow(values []any) error {
+	j.buf = append(j.buf[:0], '{')
+	for i, value := range values {
+		if i > 0 {
+			j.buf = append(j.buf, ',')
+		}
+		data, err := json.Marshal(exportJSON(value))
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", j.columns[i], err)
+		}
+		j.buf = append(j.buf, j.columns[i]...)
+		j.buf = append(j.buf, ':')
+		j.buf = append(j.buf, data...)
+	}
+	j.buf = append(j.buf, '}', '\n')
+	_, err := j.w.Write(j.buf)
+	return err
+}
+
+func (j *jsonlResultWriter) Flush() error {
+	return nil
+}
+
+type markdownResultWriter struct {
+	w io.Writer
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>")
+
+// WriteHeader writes the column names, with number columns aligned right
+func (m *markdownResultWriter) WriteHeader(columns, kinds []string) error {
+	cells := make([]any, len(columns))
+	for i, col := range columns {
+		cells[i] = col
+	}
+	if err := m.WriteRow(cells); err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString("|")
+	for _, kind := range kinds {
+		if kind == kindInteger || kind == kindNumber {
+			b.WriteString(" ---: |")
+		} else {
+			b.WriteString(" --- |")
+		}
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(m.w, b.String())
+	return err
+}
+
+func (m *markdownResultWriter) WriteRow(values []any) error {
+	var b strings.Builder
+	b.WriteString("|")
+	for _, value := range values {
+		b.WriteString(" ")
+		b.WriteString(markdownEscaper.Replace(exportText(value)))
+		b.WriteString(" |")
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(m.w, b.String())
+	return err
+}
+
+func (m *markdownResultWriter) Flush() error {
+	return nil
+}
@@ -0,0 +1,236 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// rowCountTTL is how long a row count is reused before the table is
+// counted again
+const rowCountTTL = 30 * time.Second
+
+// cachedRowCount is a row count and when it was made
+type cachedRowCount struct {
+	common.RowCount
+	at time.Time
+}
+
+// Modes of studio.row_counts
+const (
+	rowCountsExact     = "exact"
+	rowCountsEstimated = "estimated"
+)
+
+// estimatesRowCounts reports whether studio.row_counts asks for estimated
+// row counts
+func (s *Service) estimatesRowCounts() (bool, error) {
+	if s.cfg == nil {
+		return false, nil
+	}
+	switch strings.ToLower(s.cfg.Studio.RowCounts) {
+	case "", rowCountsExact:
+		return false, nil
+	case rowCountsEstimated:
+		return true, nil
+	}
+	return false, fmt.Errorf("studio.row_counts must be %s or %s, not %q", rowCountsExact, rowCountsEstimated, s.cfg.Studio.RowCounts)
+}
+
+// rowCounts returns the row counts of tables, and which of them are
+// estimates. Tables the database keeps no estimate for are counted.
+func (s *Service) rowCounts(ctx context.Context, tables []string) (map[string]int, map[string]bool, error) {
+	counts := make(map[string]int, len(tables))
+	estimated := make(map[string]bool)
+	if len(tables) == 0 {
+		return counts, estimated, nil
+	}
+
+	estimate, err := s.estimatesRowCounts()
+	if err != nil {
+		return nil, nil, err
+	}
+	remaining := tables
+	if estimate {
+		// Without estimates, the tables are counted instead
+		estimates, _ := s.estimatedRowCounts(ctx, tables)
+		remaining = nil
+		for _, table := range tables {
+			if n, ok := estimates[table]; ok {
+				counts[table] = n
+				estimated[table] = true
+			} else {
+				remaining = append(remaining, table)
+			}
+		}
+		if len(remaining) == 0 {
+			return counts, estimated, nil
+		}
+	}
+
+	exact, err := s.adapter.GetAllTableRowCounts(ctx, remaining)
+	if err != nil {
+		exact = make(map[string]int)
+		for _, table := range remaining {
+			count, _ := s.adapter.GetTableRowCount(ctx, table)
+			exact[table] = count
+		}
+	}
+	for _, table := range remaining {
+		counts[table] = exact[table]
+	}
+	return counts, estimated, nil
+}
+
+// estimatedRowCounts returns the row counts the database estimates for
+// tables, leaving out those it has no estimate for: PostgreSQL tables that
+// were never analyzed, MySQL views and SQLite tables without a rowid.
+// PostgreSQL and MySQL keep statistics; SQLite's largest rowid is the
+// number of rows inserted, which overcounts once rows are deleted.
+func (s *Service) estimatedRowCounts(ctx context.Context, tables []string) (map[string]int, error) {
+	estimates := make(map[string]int, len(tables))
+
+	if s.isSQLite() {
+		for _, table := range tables {
+			result, err := s.adapter.ExecuteQuery(ctx, fmt.Sprintf("SELECT MAX(rowid) AS n FROM %s", common.QuoteIdentifier(table)))
+			if err != nil || len(result.Rows) == 0 {
+				continue
+			}
+			estimates[table] = profileInt(result.Rows[0]["n"])
+		}
+		return estimates, nil
+	}
+
+	var query string
+	var args []any
+	switch s.provider() {
+	case "postgresql", "postgres":
+		// Partitioned tables have no statistics of their own, their
+		// partitions do. reltuples is -1, or 0 with pages in use before
+		// PostgreSQL 14, until a table is analyzed.
+		query = `SELECT c.relname AS table_name,
+			CASE
+				WHEN c.relkind = 'p' THEN COALESCE((SELECT SUM(GREATEST(p.reltuples, 0)) FROM pg_inherits i
+					JOIN pg_class p ON p.oid = i.inhrelid WHERE i.inhparent = c.oid), 0)
+				WHEN c.reltuples < 0 OR (c.reltuples = 0 AND c.relpages > 0) THEN -1
+				ELSE c.reltuples
+			END::bigint AS table_rows
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE c.relkind IN ('r', 'p') AND n.nspname = ANY(current_schemas(false)) AND c.relname = ANY($1)`
+		args = []any{tables}
+	case "mysql":
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(tables)), ", ")
+		query = `SELECT TABLE_NAME AS table_name, TABLE_ROWS AS table_rows
+			FROM information_schema.TABLES
+			WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE' AND TABLE_NAME IN (` + placeholders + `)`
+		for _, table := range tables {
+			args = append(args, table)
+		}
+	default:
+		return estimates, nil
+	}
+
+	result, err := s.adapter.ExecuteQuery(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate row counts: %w", err)
+	}
+	for _, row := range result.Rows {
+		// NULL, or -1 for a table that was never analyzed
+		n, err := strconv.ParseInt(fmt.Sprint(row["table_rows"]), 10, 64)
+		if err == nil && n >= 0 {
+			estimates[fmt.Sprint(row["table_name"])] = int(n)
+		}
+	}
+	return estimates, nil
+}
+
+// RowCounts returns the row counts of tables, for the tables the list
+// shows. Counts made in the last rowCountTTL are reused unless refresh is
+// set.
+func (s *Service) RowCounts(ctx context.Context, tables []string, refresh bool) (map[string]common.RowCount, error) {
+	s.ensureCorrectSchema(ctx)
+	tables = slices.DeleteFunc(slices.Clone(tables), s.isIgnoredTable)
+	return s.cachedRowCounts(ctx, tables, refresh)
+}
+
+// cachedRowCounts returns the row counts of tables, counting those without
+// a recent count
+func (s *Service) cachedRowCounts(ctx context.Context, tables []string, refresh bool) (map[string]common.RowCount, error) {
+	result := make(map[string]common.RowCount, len(tables))
+	var missing []string
+	s.rowCountMu.Lock()
+	for _, table := range tables {
+		if cached, ok := s.rowCountCache[table]; ok && !refresh && time.Since(cached.at) < rowCountTTL {
+			result[table] = cached.RowCount
+		} else {
+			missing = append(missing, table)
+		}
+	}
+	s.rowCountMu.Unlock()
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	counts, estimated, err := s.rowCounts(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range missing {
+		result[table] = common.RowCount{RowCount: counts[table], Estimated: estimated[table]}
+		s.cacheRowCount(table, result[table])
+	}
+	return result, nil
+}
+
+func (s *Service) cacheRowCount(table string, count common.RowCount) {
+	s.rowCountMu.Lock()
+	defer s.rowCountMu.Unlock()
+	if s.rowCountCache == nil {
+		s.rowCountCache = make(map[string]cachedRowCount)
+	}
+	s.rowCountCache[table] = cachedRowCount{RowCount: count, at: time.Now()}
+}
+
+// clearRowCounts forgets the cached row counts, once they no longer apply
+// to the database studio is connected to
+func (s *Service) clearRowCounts() {
+	s.rowCountMu.Lock()
+	defer s.rowCountMu.Unlock()
+	s.rowCountCache = nil
+}
+
+// CountRows counts the rows of a table exactly, for when the table list
+// only shows an estimate
+func (s *Service) CountRows(ctx context.Context, tableName string) (int, error) {
+	s.ensureCorrectSchema(ctx)
+	if s.isIgnoredTable(tableName) {
+		return 0, fmt.Errorf("table %s is ignored", tableName)
+	}
+	count, err := s.adapter.GetTableRowCount(ctx, tableName)
+	if err != nil {
+		return 0, err
+	}
+	s.cacheRowCount(tableName, common.RowCount{RowCount: count})
+	return count, nil
+}
+
+// tableTotal returns the number of rows of a table matching where, for
+// paging through them. With estimated row counts, an unfiltered table
+// takes its estimate, raised to cover the rows already seen.
+func (s *Service) tableTotal(ctx context.Context, tableName, where string, args []any, offset, rows int) (int, bool) {
+	if estimate, _ := s.estimatesRowCounts(); estimate && where == "" {
+		if estimates, err := s.estimatedRowCounts(ctx, []string{tableName}); err == nil {
+			if n, ok := estimates[tableName]; ok {
+				return max(n, offset+rows), true
+			}
+		}
+	}
+	total, _ := s.getFilteredRowCount(ctx, tableName, where, args)
+	return total, false
+}
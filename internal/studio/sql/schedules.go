@@ -0,0 +1,117 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/schedule"
+)
+
+// ScheduleStatus is a schedule with its latest run and when it runs next
+type ScheduleStatus struct {
+	*schedule.Schedule
+	LastRun *schedule.Run `json:"last_run,omitempty"`
+	NextRun *time.Time    `json:"next_run,omitempty"` // nil when disabled
+}
+
+func (s *Service) schedules() (*schedule.Runner, error) {
+	if s.scheduler == nil {
+		return nil, fmt.Errorf("schedules need a flash config")
+	}
+	return s.scheduler, nil
+}
+
+// GetSchedules lists the schedules of the project
+func (s *Service) GetSchedules() ([]ScheduleStatus, error) {
+	runner, err := s.schedules()
+	if err != nil {
+		return nil, err
+	}
+	store, err := runner.Metadata().Load()
+	if err != nil {
+		return nil, err
+	}
+	runs, err := runner.Metadata().LoadRuns()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := make([]ScheduleStatus, 0, len(store.Schedules))
+	for _, sch := range store.Schedules {
+		status := ScheduleStatus{Schedule: sch}
+		if scheduleRuns := runs.ScheduleRuns(sch.Name); len(scheduleRuns) > 0 {
+			status.LastRun = scheduleRuns[0]
+		}
+		if next := schedule.NextRun(sch, now); !next.IsZero() {
+			status.NextRun = &next
+		}
+		result = append(result, status)
+	}
+	return result, nil
+}
+
+// SaveSchedule creates a schedule, or replaces the one with its name.
+// While columns are masked it needs admin, since the schedule runs SQL of
+// the caller's own.
+func (s *Service) SaveSchedule(sch *schedule.Schedule, admin bool) error {
+	if err := s.checkQueryAccess(admin); err != nil {
+		return err
+	}
+	runner, err := s.schedules()
+	if err != nil {
+		return err
+	}
+	return runner.Save(sch)
+}
+
+// DeleteSchedule removes a schedule and its runs
+func (s *Service) DeleteSchedule(name string) error {
+	runner, err := s.schedules()
+	if err != nil {
+		return err
+	}
+	return runner.Remove(name)
+}
+
+// RunSchedule runs a schedule now, as the scheduler would. Unless admin is
+// set, masked columns of the snapshot are masked.
+func (s *Service) RunSchedule(ctx context.Context, name string, admin bool) (*schedule.Run, error) {
+	runner, err := s.schedules()
+	if err != nil {
+		return nil, err
+	}
+	run, err := runner.RunOnce(ctx, name)
+	if admin {
+		return run, err
+	}
+	return s.maskRun(run), err
+}
+
+// GetScheduleRuns returns the recorded runs of a schedule, latest first.
+// Unless admin is set, masked columns of the snapshots are masked.
+func (s *Service) GetScheduleRuns(name string, admin bool) ([]*schedule.Run, error) {
+	runner, err := s.schedules()
+	if err != nil {
+		return nil, err
+	}
+	store, err := runner.Metadata().Load()
+	if err != nil {
+		return nil, err
+	}
+	if store.GetSchedule(name) == nil {
+		return nil, fmt.Errorf("schedule '%s' not found", name)
+	}
+	runs, err := runner.Metadata().LoadRuns()
+	if err != nil {
+		return nil, err
+	}
+	scheduleRuns := runs.ScheduleRuns(name)
+	if !admin {
+		for i, run := range scheduleRuns {
+			scheduleRuns[i] = s.maskRun(run)
+		}
+	}
+	return scheduleRuns, nil
+}
@@ -1,12 +1,14 @@
 package sql
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/Lumos-Labs-HQ/flash/internal/schema"
 	"github.com/Lumos-Labs-HQ/flash/internal/types"
 )
 
@@ -62,16 +64,19 @@ func (s *Service) PreviewSchemaChange(change *SchemaChange) (*SchemaPreview, err
 	}, nil
 }
 
-func (s *Service) ApplySchemaChange(change *SchemaChange, configPath string) error {
+func (s *Service) ApplySchemaChange(ctx context.Context, change *SchemaChange, configPath string, admin bool) error {
+	if err := s.checkSchemaAccess(admin); err != nil {
+		return err
+	}
 	if change.Type == "add_column" {
-		exists, err := s.adapter.CheckColumnExists(s.ctx, change.Table, change.Column.Name)
+		exists, err := s.adapter.CheckColumnExists(ctx, change.Table, change.Column.Name)
 		if err == nil && exists {
 			return fmt.Errorf("column '%s' already exists in table '%s'", change.Column.Name, change.Table)
 		}
 	}
 
 	sql := s.generateSQL(change)
-	_, err := s.adapter.ExecuteQuery(s.ctx, sql)
+	_, err := s.adapter.ExecuteQuery(ctx, sql)
 	if err != nil {
 		return fmt.Errorf("failed to apply schema change: %w", err)
 	}
@@ -80,7 +85,7 @@ func (s *Service) ApplySchemaChange(change *SchemaChange, configPath string) err
 		if err := s.generateMigrationFile(change, sql, configPath); err != nil {
 			fmt.Printf("Warning: failed to generate migration: %v\n", err)
 		}
-		if err := s.syncSchemaFile(configPath); err != nil {
+		if err := s.syncSchemaFile(ctx, configPath); err != nil {
 			fmt.Printf("Warning: failed to sync schema file: %v\n", err)
 		}
 	}
@@ -372,19 +377,19 @@ func (s *Service) generateMigrationFile(change *SchemaChange, sql, configPath st
 	return os.WriteFile(path, []byte(content), 0644)
 }
 
-func (s *Service) syncSchemaFile(configPath string) error {
+func (s *Service) syncSchemaFile(ctx context.Context, configPath string) error {
 	schemaPath := "db/schema/schema.sql"
 	if configPath != "" {
 		dir := filepath.Dir(configPath)
 		schemaPath = filepath.Join(dir, "db/schema/schema.sql")
 	}
 
-	tables, err := s.adapter.PullCompleteSchema(s.ctx)
+	tables, err := s.adapter.PullCompleteSchema(ctx)
 	if err != nil {
 		return err
 	}
 
-	enums, _ := s.adapter.GetCurrentEnums(s.ctx)
+	enums, _ := s.adapter.GetCurrentEnums(ctx)
 	sql := s.generateSchemaSQL(tables, enums)
 	return os.WriteFile(schemaPath, []byte(sql), 0644)
 }
@@ -421,9 +426,18 @@ func (s *Service) generateSchemaSQL(tables []types.SchemaTable, enums []types.Sc
 				if col.OnDeleteAction != "" {
 					fkDef += fmt.Sprintf(" ON DELETE %s", col.OnDeleteAction)
 				}
+				if col.OnUpdateAction != "" && col.OnUpdateAction != "NO ACTION" {
+					fkDef += fmt.Sprintf(" ON UPDATE %s", col.OnUpdateAction)
+				}
+				if col.ForeignKeyDeferrable != "" {
+					fkDef += " " + col.ForeignKeyDeferrable
+				}
 				foreignKeys = append(foreignKeys, fkDef)
 			}
 		}
+		for _, constraint := range table.Constraints {
+			foreignKeys = append(foreignKeys, "  "+schema.DeclaredConstraint(constraint).Definition(func(name string) string { return "\"" + name + "\"" }))
+		}
 
 		for j, col := range table.Columns {
 			sql.WriteString(fmt.Sprintf("  \"%s\" %s", col.Name, col.Type))
@@ -433,6 +447,9 @@ func (s *Service) generateSchemaSQL(tables []types.SchemaTable, enums []types.Sc
 			}
 			if col.IsUnique && !col.IsPrimary {
 				sql.WriteString(" UNIQUE")
+				if col.UniqueDeferrable != "" {
+					sql.WriteString(" " + col.UniqueDeferrable)
+				}
 			}
 			if !col.Nullable && !col.IsPrimary {
 				sql.WriteString(" NOT NULL")
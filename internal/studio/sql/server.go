@@ -3,14 +3,18 @@ package sql
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Lumos-Labs-HQ/flash/internal/branch"
 	"github.com/Lumos-Labs-HQ/flash/internal/config"
 	"github.com/Lumos-Labs-HQ/flash/internal/database"
+	dbcommon "github.com/Lumos-Labs-HQ/flash/internal/database/common"
 	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
 )
 
@@ -48,7 +52,7 @@ func NewServer(cfg *config.Config, port int) *Server {
 	}
 
 	mux := http.NewServeMux()
-	tmpl := common.ParseTemplates(TemplatesFS)
+	tmpl := common.ParseTemplates(TemplatesFS, StaticFS)
 
 	server := &Server{
 		mux:     mux,
@@ -68,38 +72,106 @@ func (s *Server) setupRoutes() {
 	s.mux.HandleFunc("GET /{$}", s.handleIndex)
 	s.mux.HandleFunc("GET /schema", s.handleSchema)
 	s.mux.HandleFunc("GET /sql", s.handleSQL)
+	s.mux.HandleFunc("GET /changes", s.handleChanges)
 
 	// API routes
 	s.mux.HandleFunc("GET /api/tables", s.handleGetTables)
+	s.mux.HandleFunc("GET /api/table-counts", s.handleGetRowCounts)
 	s.mux.HandleFunc("GET /api/tables/{name}", s.handleGetTableData)
+	s.mux.HandleFunc("GET /api/tables/{name}/profile", s.handleProfileTable)
+	s.mux.HandleFunc("GET /api/tables/{name}/count", s.handleCountRows)
+	s.mux.HandleFunc("GET /api/tables/{name}/chart", s.handleChartData)
+	s.mux.HandleFunc("GET /api/tables/{name}/export", s.handleExportTable)
+	s.mux.HandleFunc("GET /api/tables/{name}/presets", s.handleGetFilterPresets)
+	s.mux.HandleFunc("PUT /api/tables/{name}/presets/{preset}", s.handleSaveFilterPreset)
+	s.mux.HandleFunc("DELETE /api/tables/{name}/presets/{preset}", s.handleDeleteFilterPreset)
 	s.mux.HandleFunc("GET /api/schema", s.handleGetSchema)
+	s.mux.HandleFunc("GET /api/schema/layout", s.handleGetSchemaLayout)
+	s.mux.HandleFunc("PUT /api/schema/layout", s.handleSaveSchemaLayout)
 	s.mux.HandleFunc("POST /api/tables/{name}/save", s.handleSaveChanges)
 	s.mux.HandleFunc("POST /api/tables/{name}/add", s.handleAddRow)
 	s.mux.HandleFunc("POST /api/tables/{name}/delete", s.handleDeleteRows)
+	s.mux.HandleFunc("POST /api/tables/{name}/delete/preview", s.handlePreviewDelete)
 	s.mux.HandleFunc("DELETE /api/tables/{name}/rows/{id}", s.handleDeleteRow)
+	s.mux.HandleFunc("GET /api/tables/{name}/rows/{id}", s.handleGetRowDetail)
+	s.mux.HandleFunc("GET /api/tables/{name}/rows/{id}/children", s.handleGetRelatedRows)
+	s.mux.HandleFunc("POST /api/tables/{name}/restore", s.handleRestoreRows)
 	s.mux.HandleFunc("POST /api/sql", s.handleExecuteSQL)
+	s.mux.HandleFunc("POST /api/sql/params", s.handleGetQueryParams)
+	s.mux.HandleFunc("POST /api/sql/export", s.handleExportResults)
+	s.mux.HandleFunc("GET /api/sql/history", s.handleGetQueryHistory)
+	s.mux.HandleFunc("DELETE /api/sql/history", s.handleClearQueryHistory)
+	s.mux.HandleFunc("GET /api/state/views", s.handleGetStateViews)
+	s.mux.HandleFunc("GET /api/state/views/{name}", s.handleGetStateView)
+
+	// Named queries API
+	s.mux.HandleFunc("GET /api/queries", s.handleGetNamedQueries)
+	s.mux.HandleFunc("GET /api/queries/{name}/snippets", s.handleGetQuerySnippets)
+
+	// Scheduled queries API
+	s.mux.HandleFunc("GET /api/schedules", s.handleGetSchedules)
+	s.mux.HandleFunc("POST /api/schedules", s.handleSaveSchedule)
+	s.mux.HandleFunc("DELETE /api/schedules/{name}", s.handleDeleteSchedule)
+	s.mux.HandleFunc("POST /api/schedules/{name}/run", s.handleRunSchedule)
+	s.mux.HandleFunc("GET /api/schedules/{name}/runs", s.handleGetScheduleRuns)
+
+	// Change capture API
+	s.mux.HandleFunc("GET /api/changes", s.handlePollChanges)
+	s.mux.HandleFunc("POST /api/changes/capture", s.handleStartChangeCapture)
+	s.mux.HandleFunc("DELETE /api/changes/capture", s.handleStopChangeCapture)
+
+	// Materialized views API
+	s.mux.HandleFunc("GET /api/matviews", s.handleGetMaterializedViews)
+	s.mux.HandleFunc("POST /api/matviews/{name}/refresh", s.handleRefreshMaterializedView)
 
 	// Schema Editor API
 	s.mux.HandleFunc("POST /api/schema/preview", s.handlePreviewSchemaChange)
 	s.mux.HandleFunc("POST /api/schema/apply", s.handleApplySchemaChange)
+	s.mux.HandleFunc("POST /api/schema/design/preview", s.handlePreviewTableDesign)
+	s.mux.HandleFunc("POST /api/schema/design/apply", s.handleApplyTableDesign)
+	s.mux.HandleFunc("GET /api/schema/changes", s.handleGetChangeset)
+	s.mux.HandleFunc("POST /api/schema/changes", s.handleStageTableDesign)
+	s.mux.HandleFunc("POST /api/schema/changes/rename", s.handleStageColumnRename)
+	s.mux.HandleFunc("POST /api/schema/changes/apply", s.handleApplyChangeset)
+	s.mux.HandleFunc("DELETE /api/schema/changes", s.handleDiscardChangeset)
+	s.mux.HandleFunc("DELETE /api/schema/changes/{table}", s.handleUnstageTable)
+	s.mux.HandleFunc("GET /api/enums", s.handleGetEnums)
+	s.mux.HandleFunc("POST /api/enums/{name}/values", s.handleAddEnumValue)
+	s.mux.HandleFunc("POST /api/enums/{name}/values/rename", s.handleRenameEnumValue)
+	s.mux.HandleFunc("GET /api/uuid-defaults", s.handleGetUUIDDefaults)
+	s.mux.HandleFunc("POST /api/uuid-defaults", s.handleAddUUIDDefaults)
 	s.mux.HandleFunc("GET /api/config/check", s.handleCheckConfig)
+	s.mux.HandleFunc("GET /api/admin", s.handleGetAdmin)
+	s.mux.HandleFunc("POST /api/admin", s.handleUnlockAdmin)
+	s.mux.HandleFunc("DELETE /api/admin", s.handleLockAdmin)
 	s.mux.HandleFunc("PUT /api/tables/{name}/rows/{id}", s.handleUpdateRow)
 	s.mux.HandleFunc("POST /api/tables/{name}/rows", s.handleInsertRow)
+	s.mux.HandleFunc("POST /api/tables/{name}/paste", s.handlePasteRows)
+
+	// Journal API, to undo and redo data edits
+	s.mux.HandleFunc("GET /api/journal", s.handleGetJournal)
+	s.mux.HandleFunc("POST /api/journal/undo", s.handleUndo)
+	s.mux.HandleFunc("POST /api/journal/redo", s.handleRedo)
 
 	// Branch API
 	s.mux.HandleFunc("GET /api/branches", s.handleGetBranches)
 	s.mux.HandleFunc("POST /api/branches/switch", s.handleSwitchBranch)
 
+	// Tenant API
+	s.mux.HandleFunc("GET /api/tenants", s.handleGetTenants)
+	s.mux.HandleFunc("POST /api/tenants/switch", s.handleSwitchTenant)
+
 	// Editor hints API (cached on client-side)
 	s.mux.HandleFunc("GET /api/editor/hints", s.handleGetEditorHints)
 
 	// Export/Import API
 	s.mux.HandleFunc("GET /api/export/{type}", s.handleExport)
 	s.mux.HandleFunc("POST /api/import", s.handleImport)
+	s.mux.HandleFunc("POST /api/import/dump", s.handleImportDump)
 }
 
-func (s *Server) Start(openBrowser bool) error {
-	return common.StartServer(s.mux, &s.port, "Studio", openBrowser)
+func (s *Server) Start(opts common.StartOptions) error {
+	return common.StartServer(s.mux, &s.port, "Studio", opts, s.service.adapter.Ping, s.service.adapter.Close)
 }
 
 // UI Handlers
@@ -115,47 +187,164 @@ func (s *Server) handleSQL(w http.ResponseWriter, r *http.Request) {
 	s.tmpl.ExecuteTemplate(w, "sql.html", common.Map{"Title": "SQL Editor - FlashORM Studio"})
 }
 
+func (s *Server) handleChanges(w http.ResponseWriter, r *http.Request) {
+	s.tmpl.ExecuteTemplate(w, "changes.html", common.Map{"Title": "Changes - FlashORM Studio"})
+}
+
 // API Handlers
 func (s *Server) handleGetTables(w http.ResponseWriter, r *http.Request) {
-	tables, err := s.service.GetTables()
+	offset, _ := strconv.Atoi(common.Query(r, "offset", "0"))
+	limit, _ := strconv.Atoi(common.Query(r, "limit", "0"))
+	tables, err := s.service.GetTables(r.Context(), TableQuery{
+		Search:  common.Query(r, "search", ""),
+		Offset:  offset,
+		Limit:   limit,
+		Counts:  common.Query(r, "counts", "true") != "false",
+		Refresh: common.Query(r, "refresh", "") == "true",
+	})
 	if err != nil {
-		common.JSONError(w, http.StatusInternalServerError, err.Error())
+		writeServiceError(w, err)
 		return
 	}
 	common.JSON(w, tables)
 }
 
+func (s *Server) handleGetRowCounts(w http.ResponseWriter, r *http.Request) {
+	counts, err := s.service.RowCounts(r.Context(), r.URL.Query()["tables"], common.Query(r, "refresh", "") == "true")
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, counts)
+}
+
+// requestFilters returns the filters of a request: the JSON encoded filters
+// parameter, or the filters of the filter preset the preset parameter names
+func (s *Server) requestFilters(r *http.Request, tableName string) ([]common.Filter, error) {
+	filtersJSON := r.URL.Query().Get("filters")
+	presetName := r.URL.Query().Get("preset")
+	if presetName != "" {
+		if filtersJSON != "" {
+			return nil, fmt.Errorf("use filters or preset, not both")
+		}
+		preset, err := s.service.GetFilterPreset(tableName, presetName)
+		if err != nil {
+			return nil, err
+		}
+		return preset.Filters, nil
+	}
+
+	var filters []common.Filter
+	if filtersJSON != "" {
+		if err := json.Unmarshal([]byte(filtersJSON), &filters); err != nil {
+			return nil, fmt.Errorf("Invalid filters format")
+		}
+	}
+	return filters, nil
+}
+
 func (s *Server) handleGetTableData(w http.ResponseWriter, r *http.Request) {
 	tableName := r.PathValue("name")
 	page, _ := strconv.Atoi(common.Query(r, "page", "1"))
 	limit, _ := strconv.Atoi(common.Query(r, "limit", "50"))
 
-	// Parse filters from query parameter (JSON encoded)
-	var filters []common.Filter
-	if filtersJSON := r.URL.Query().Get("filters"); filtersJSON != "" {
-		if err := json.Unmarshal([]byte(filtersJSON), &filters); err != nil {
-			common.JSONError(w, http.StatusBadRequest, "Invalid filters format")
-			return
-		}
+	filters, err := s.requestFilters(r, tableName)
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	data, err := s.service.GetTableDataFiltered(tableName, page, limit, filters)
+	includeDeleted := common.Query(r, "include_deleted", "false") == "true"
+	loc, err := requestLocation(r)
 	if err != nil {
-		common.JSONError(w, http.StatusInternalServerError, err.Error())
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	after := r.URL.Query().Get("after")
+	data, err := s.service.GetTableDataFiltered(r.Context(), tableName, page, limit, after, filters, includeDeleted, loc, s.isAdmin(r))
+	if err != nil {
+		writeServiceError(w, err)
 		return
 	}
 	common.JSON(w, data)
 }
 
+func (s *Server) handleProfileTable(w http.ResponseWriter, r *http.Request) {
+	tableName := r.PathValue("name")
+	sample, _ := strconv.Atoi(common.Query(r, "sample", "0"))
+	top, _ := strconv.Atoi(common.Query(r, "top", "0"))
+	budgetMS, _ := strconv.Atoi(common.Query(r, "budget_ms", "0"))
+
+	profile, err := s.service.ProfileTable(r.Context(), tableName, sample, top, time.Duration(budgetMS)*time.Millisecond, s.isAdmin(r))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, profile)
+}
+
+func (s *Server) handleCountRows(w http.ResponseWriter, r *http.Request) {
+	tableName := r.PathValue("name")
+	count, err := s.service.CountRows(r.Context(), tableName)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, map[string]any{"table": tableName, "row_count": count})
+}
+
+func (s *Server) handleChartData(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(common.Query(r, "limit", "0"))
+	series, err := s.service.ChartData(r.Context(), r.PathValue("name"), ChartOptions{
+		GroupBy:   common.Query(r, "group_by", ""),
+		Aggregate: common.Query(r, "agg", "count"),
+		Column:    common.Query(r, "column", ""),
+		Bucket:    common.Query(r, "bucket", ""),
+		Limit:     limit,
+	}, s.isAdmin(r))
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	common.JSON(w, series)
+}
+
 func (s *Server) handleGetSchema(w http.ResponseWriter, r *http.Request) {
-	schema, err := s.service.GetSchemaVisualization()
+	schema, err := s.service.GetSchemaVisualization(r.Context())
 	if err != nil {
-		common.JSONError(w, http.StatusInternalServerError, err.Error())
+		writeServiceError(w, err)
 		return
 	}
 	common.JSON(w, schema)
 }
 
+// handleGetSchemaLayout returns where the tables of the schema diagram were
+// placed, so the diagram looks as it was left
+func (s *Server) handleGetSchemaLayout(w http.ResponseWriter, r *http.Request) {
+	positions, err := s.service.GetSchemaLayout()
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, positions)
+}
+
+func (s *Server) handleSaveSchemaLayout(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Positions map[string]Position `json:"positions"`
+	}
+	if err := common.ParseJSON(r, &req); err != nil {
+		common.JSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	if err := s.service.SaveSchemaLayout(req.Positions); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSONMessage(w, "Layout saved")
+}
+
 func (s *Server) handleSaveChanges(w http.ResponseWriter, r *http.Request) {
 	tableName := r.PathValue("name")
 
@@ -165,8 +354,13 @@ func (s *Server) handleSaveChanges(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.service.SaveChanges(tableName, req.Changes); err != nil {
-		common.JSONError(w, http.StatusInternalServerError, err.Error())
+	loc, err := requestLocation(r)
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.service.SaveChanges(r.Context(), tableName, req.Changes, loc, s.isAdmin(r)); err != nil {
+		writeServiceError(w, err)
 		return
 	}
 	common.JSONMessage(w, "Changes saved successfully")
@@ -176,13 +370,18 @@ func (s *Server) handleAddRow(w http.ResponseWriter, r *http.Request) {
 	tableName := r.PathValue("name")
 
 	var req common.AddRowRequest
-	if err := common.ParseJSON(r, &req); err != nil {
+	if err := common.ParseJSONValues(r, &req); err != nil {
 		common.JSONError(w, http.StatusBadRequest, "Invalid request")
 		return
 	}
 
-	if err := s.service.AddRow(tableName, req.Data); err != nil {
-		common.JSONError(w, http.StatusInternalServerError, err.Error())
+	loc, err := requestLocation(r)
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.service.AddRow(r.Context(), tableName, req.Data, loc); err != nil {
+		writeServiceError(w, err)
 		return
 	}
 	common.JSONMessage(w, "Row added successfully")
@@ -192,13 +391,46 @@ func (s *Server) handleDeleteRow(w http.ResponseWriter, r *http.Request) {
 	tableName := r.PathValue("name")
 	rowID := r.PathValue("id")
 
-	if err := s.service.DeleteRow(tableName, rowID); err != nil {
-		common.JSONError(w, http.StatusInternalServerError, err.Error())
+	if err := s.service.DeleteRow(r.Context(), tableName, rowID); err != nil {
+		writeServiceError(w, err)
 		return
 	}
 	common.JSONMessage(w, "Row deleted successfully")
 }
 
+func (s *Server) handleGetRowDetail(w http.ResponseWriter, r *http.Request) {
+	tableName := r.PathValue("name")
+	rowID := r.PathValue("id")
+	limit, _ := strconv.Atoi(common.Query(r, "limit", "10"))
+
+	detail, err := s.service.GetRowDetail(r.Context(), tableName, rowID, limit, s.isAdmin(r))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, detail)
+}
+
+func (s *Server) handleGetRelatedRows(w http.ResponseWriter, r *http.Request) {
+	tableName := r.PathValue("name")
+	rowID := r.PathValue("id")
+	childTable := r.URL.Query().Get("table")
+	childColumn := r.URL.Query().Get("column")
+	if childTable == "" || childColumn == "" {
+		common.JSONError(w, http.StatusBadRequest, "table and column are required")
+		return
+	}
+	page, _ := strconv.Atoi(common.Query(r, "page", "1"))
+	limit, _ := strconv.Atoi(common.Query(r, "limit", "10"))
+
+	rows, err := s.service.GetRelatedRows(r.Context(), tableName, rowID, childTable, childColumn, page, limit, s.isAdmin(r))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, rows)
+}
+
 func (s *Server) handleDeleteRows(w http.ResponseWriter, r *http.Request) {
 	tableName := r.PathValue("name")
 
@@ -210,67 +442,313 @@ func (s *Server) handleDeleteRows(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.service.DeleteRows(tableName, req.RowIDs); err != nil {
-		common.JSONError(w, http.StatusInternalServerError, err.Error())
+	if err := s.service.DeleteRows(r.Context(), tableName, req.RowIDs); err != nil {
+		writeServiceError(w, err)
 		return
 	}
 	common.JSONMessage(w, fmt.Sprintf("Deleted %d row(s) successfully", len(req.RowIDs)))
 }
 
+func (s *Server) handlePreviewDelete(w http.ResponseWriter, r *http.Request) {
+	tableName := r.PathValue("name")
+
+	var req struct {
+		RowIDs []string `json:"row_ids"`
+	}
+	if err := common.ParseJSON(r, &req); err != nil {
+		common.JSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	impact, err := s.service.PreviewDelete(r.Context(), tableName, req.RowIDs)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, impact)
+}
+
+func (s *Server) handleRestoreRows(w http.ResponseWriter, r *http.Request) {
+	tableName := r.PathValue("name")
+
+	var req struct {
+		RowIDs []string `json:"row_ids"`
+	}
+	if err := common.ParseJSON(r, &req); err != nil {
+		common.JSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	if err := s.service.RestoreRows(r.Context(), tableName, req.RowIDs); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSONMessage(w, fmt.Sprintf("Restored %d row(s) successfully", len(req.RowIDs)))
+}
+
 func (s *Server) handleExecuteSQL(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Query string `json:"query"`
+		Query  string `json:"query"`
+		Params any    `json:"params"` // a list for $N and ?, a map for :name
+		// Source is "state" to query the views of the state store
+		Source string `json:"source"`
+		// RecordMigration overrides studio.record_ddl of the config
+		RecordMigration *bool  `json:"record_migration"`
+		MigrationName   string `json:"migration_name"`
 	}
 	if err := common.ParseJSON(r, &req); err != nil {
 		common.JSONError(w, http.StatusBadRequest, "Invalid request")
 		return
 	}
 
-	data, err := s.service.ExecuteSQL(req.Query)
+	if req.Source == "state" {
+		s.handleQueryState(w, r, req.Query, req.Params)
+		return
+	}
+
+	if err := s.service.checkQueryAccess(s.isAdmin(r)); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	record := s.service.RecordsDDL()
+	if req.RecordMigration != nil {
+		record = *req.RecordMigration
+	}
+	started := time.Now()
+	if record && req.Params == nil && IsDDL(req.Query) {
+		data, err := s.service.ExecuteDDLAsMigration(r.Context(), req.Query, req.MigrationName, configPath())
+		s.service.RecordQuery(req.Query, started, err)
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+		common.JSON(w, data)
+		return
+	}
+
+	query := req.Query
+	var args []any
+	if req.Params != nil {
+		var err error
+		if query, args, err = s.service.BindParams(query, req.Params); err != nil {
+			common.JSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	data, err := s.service.ExecuteSQL(r.Context(), query, s.isAdmin(r), args...)
+	s.service.RecordQuery(req.Query, started, err)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, data)
+}
+
+// handleGetQueryHistory returns the latest statements of the SQL editor,
+// newest first, up to ?limit=
+func (s *Server) handleGetQueryHistory(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	history, err := s.service.QueryHistory(limit, s.isAdmin(r))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, history)
+}
+
+// handleQueryState runs a statement of the SQL editor over the state store.
+// It isn't recorded, so querying the history doesn't change it.
+func (s *Server) handleQueryState(w http.ResponseWriter, r *http.Request, query string, params any) {
+	if params != nil {
+		common.JSONError(w, http.StatusBadRequest, "queries of the state store take no parameters")
+		return
+	}
+	data, err := s.service.QueryState(r.Context(), query, s.isAdmin(r))
 	if err != nil {
-		common.JSONError(w, http.StatusInternalServerError, err.Error())
+		writeServiceError(w, err)
 		return
 	}
 	common.JSON(w, data)
 }
 
+// handleGetStateViews lists the views of the state store with their columns
+func (s *Server) handleGetStateViews(w http.ResponseWriter, r *http.Request) {
+	views, err := s.service.StateViews(r.Context())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, views)
+}
+
+func (s *Server) handleGetStateView(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(common.Query(r, "page", "1"))
+	limit, _ := strconv.Atoi(common.Query(r, "limit", "50"))
+	data, err := s.service.GetStateView(r.Context(), r.PathValue("name"), page, limit, s.isAdmin(r))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, data)
+}
+
+func (s *Server) handleClearQueryHistory(w http.ResponseWriter, r *http.Request) {
+	if err := s.service.ClearQueryHistory(); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSONMessage(w, "Query history cleared")
+}
+
+// handleGetQueryParams returns the parameters of a query for the editor to
+// prompt for before running it
+func (s *Server) handleGetQueryParams(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query string `json:"query"`
+	}
+	if err := common.ParseJSON(r, &req); err != nil {
+		common.JSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	params := s.service.QueryParamSignature(req.Query)
+	if params == nil {
+		params = []common.QueryParam{}
+	}
+	common.JSON(w, params)
+}
+
+func (s *Server) handleGetNamedQueries(w http.ResponseWriter, r *http.Request) {
+	queries, err := s.service.GetNamedQueries()
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, queries)
+}
+
+func (s *Server) handleGetQuerySnippets(w http.ResponseWriter, r *http.Request) {
+	snippets, err := s.service.GetQuerySnippets(r.PathValue("name"), "http://"+r.Host)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	common.JSON(w, snippets)
+}
+
 func (s *Server) handleUpdateRow(w http.ResponseWriter, r *http.Request) {
 	table := r.PathValue("name")
 	id := r.PathValue("id")
 
 	var data map[string]interface{}
-	if err := common.ParseJSON(r, &data); err != nil {
+	if err := common.ParseJSONValues(r, &data); err != nil {
 		common.JSONError(w, http.StatusBadRequest, "Invalid request")
 		return
 	}
 
-	if err := s.service.UpdateRow(table, id, data); err != nil {
-		common.JSONError(w, http.StatusInternalServerError, err.Error())
+	// If-Match carries the version the row was read with
+	version := strings.Trim(strings.TrimPrefix(r.Header.Get("If-Match"), "W/"), `"`)
+	loc, err := requestLocation(r)
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.service.UpdateRow(r.Context(), table, id, data, version, loc, s.isAdmin(r)); err != nil {
+		writeServiceError(w, err)
 		return
 	}
 	common.JSONMap(w, common.Map{"success": true})
 }
 
+// writeServiceError answers a write that conflicts with someone else's with
+// a 409 and the current rows, a value that doesn't fit its column with a
+// 400, a masked column or SQL while columns are masked without the admin
+// role with a 403, other failures with a 500
+func writeServiceError(w http.ResponseWriter, err error) {
+	var conflict *RowConflictError
+	if errors.As(err, &conflict) {
+		common.JSONErrorData(w, http.StatusConflict, conflict.Error(), common.Map{"conflicts": conflict.Conflicts})
+		return
+	}
+	var invalid *InvalidValueError
+	if errors.As(err, &invalid) {
+		common.JSONErrorData(w, http.StatusBadRequest, invalid.Error(), common.Map{"column": invalid.Column})
+		return
+	}
+	var masked *MaskedColumnError
+	if errors.As(err, &masked) {
+		common.JSONErrorData(w, http.StatusForbidden, masked.Error(), common.Map{"column": masked.Column})
+		return
+	}
+	if errors.Is(err, errQueriesNeedAdmin) || errors.Is(err, errSchemaNeedsAdmin) || errors.Is(err, errHistoryNeedsAdmin) {
+		common.JSONError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	var unverified *ExportVerifyError
+	if errors.As(err, &unverified) {
+		common.JSONErrorData(w, http.StatusUnprocessableEntity, unverified.Error(), common.Map{"tables": unverified.Tables})
+		return
+	}
+	var dbErr *dbcommon.DatabaseError
+	if errors.As(err, &dbErr) {
+		common.JSONErrorData(w, databaseErrorStatus(err), err.Error(), common.Map{
+			"hint":       dbErr.Hint(),
+			"constraint": dbErr.Constraint,
+			"table":      dbErr.Table,
+			"column":     dbErr.Column,
+		})
+		return
+	}
+	if hint := dbcommon.Hint(err); hint != "" {
+		common.JSONErrorData(w, databaseErrorStatus(err), err.Error(), common.Map{"hint": hint})
+		return
+	}
+	common.JSONError(w, databaseErrorStatus(err), err.Error())
+}
+
+// databaseErrorStatus is the status of an error by its kind, 500 when it
+// has none
+func databaseErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, dbcommon.ErrConstraintViolation), errors.Is(err, dbcommon.ErrMigrationChecksum):
+		return http.StatusConflict
+	case errors.Is(err, dbcommon.ErrPermission):
+		return http.StatusForbidden
+	case errors.Is(err, dbcommon.ErrConnection):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, dbcommon.ErrUnsupported):
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
+
 func (s *Server) handleInsertRow(w http.ResponseWriter, r *http.Request) {
 	table := r.PathValue("name")
 
 	var data map[string]interface{}
-	if err := common.ParseJSON(r, &data); err != nil {
+	if err := common.ParseJSONValues(r, &data); err != nil {
 		common.JSONError(w, http.StatusBadRequest, "Invalid request")
 		return
 	}
 
-	if err := s.service.InsertRow(table, data); err != nil {
-		common.JSONError(w, http.StatusInternalServerError, err.Error())
+	loc, err := requestLocation(r)
+	if err != nil {
+		common.JSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.service.InsertRow(r.Context(), table, data, loc); err != nil {
+		writeServiceError(w, err)
 		return
 	}
 	common.JSONMap(w, common.Map{"success": true})
 }
 
 func (s *Server) handleGetEditorHints(w http.ResponseWriter, r *http.Request) {
-	hints, err := s.service.GetEditorHints()
+	hints, err := s.service.GetEditorHints(r.Context())
 	if err != nil {
-		common.JSONError(w, http.StatusInternalServerError, err.Error())
+		writeServiceError(w, err)
 		return
 	}
 	common.JSON(w, hints)
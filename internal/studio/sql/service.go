@@ -3,26 +3,54 @@ package sql
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Lumos-Labs-HQ/flash/internal/branch"
 	"github.com/Lumos-Labs-HQ/flash/internal/config"
 	"github.com/Lumos-Labs-HQ/flash/internal/database"
+	dbcommon "github.com/Lumos-Labs-HQ/flash/internal/database/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/export"
+	"github.com/Lumos-Labs-HQ/flash/internal/schedule"
 	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/tenant"
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
 )
 
 type Service struct {
 	adapter database.DatabaseAdapter
 	cfg     *config.Config
-	ctx     context.Context
+	tenant  string // active tenant, empty for the main database
+	// scheduler runs saved queries on demand, nil without a flash config
+	scheduler *schedule.Runner
+	// staged holds the table designs waiting to be applied as one migration
+	staged   []*TableDesign
+	stagedMu sync.Mutex
+	// writeMu keeps row writes from slipping in between another write's
+	// version check and its update, and guards the journal
+	writeMu sync.Mutex
+	// journal holds the latest data edits, oldest first, for undo
+	journal    []*JournalEntry
+	journalSeq int
+	// rowCountCache keeps recent row counts of tables by name
+	rowCountCache map[string]cachedRowCount
+	rowCountMu    sync.Mutex
+	// keyOrderCache keeps the primary key order of tables by name
+	keyOrderCache map[string]cachedKeyOrder
+	keyOrderMu    sync.Mutex
+	// changes is the running change capture, nil when there's none
+	changes   *changeCapture
+	changesMu sync.Mutex
 }
 
 func NewService(adapter database.DatabaseAdapter, cfg *config.Config) *Service {
-	return &Service{adapter: adapter, cfg: cfg, ctx: context.Background()}
+	scheduler, _ := schedule.NewRunner(cfg)
+	return &Service{adapter: adapter, cfg: cfg, scheduler: scheduler}
 }
 
-func (s *Service) ensureCorrectSchema() error {
+func (s *Service) ensureCorrectSchema(ctx context.Context) error {
 	if s.cfg == nil {
 		return nil
 	}
@@ -32,6 +60,11 @@ func (s *Service) ensureCorrectSchema() error {
 		return nil
 	}
 
+	// The connection already targets the tenant's schema
+	if s.tenant != "" {
+		return nil
+	}
+
 	// Skip if migrations path is not set or is default empty
 	if s.cfg.MigrationsPath == "" || s.cfg.MigrationsPath == "db/migrations" {
 		return nil
@@ -51,58 +84,139 @@ func (s *Service) ensureCorrectSchema() error {
 	switch s.cfg.Database.Provider {
 	case "postgresql", "postgres":
 		query := fmt.Sprintf("SET search_path TO %s, public", currentBranch.Schema)
-		_, err = s.adapter.ExecuteQuery(s.ctx, query)
+		_, err = s.adapter.ExecuteQuery(ctx, query)
 		return err
 	case "mysql", "sqlite", "sqlite3":
 		type DatabaseSwitcher interface {
 			SwitchDatabase(ctx context.Context, dbName string) error
 		}
 		if switcher, ok := s.adapter.(DatabaseSwitcher); ok {
-			return switcher.SwitchDatabase(s.ctx, currentBranch.Schema)
+			return switcher.SwitchDatabase(ctx, currentBranch.Schema)
 		}
 	}
 	return nil
 }
 
-func (s *Service) GetTables() ([]common.TableInfo, error) {
-	s.ensureCorrectSchema()
-	tables, err := s.adapter.GetAllTableNames(s.ctx)
+// TableQuery picks the page of tables GetTables lists
+type TableQuery struct {
+	Search  string // part of the name of a table or of one of its partitions
+	Offset  int
+	Limit   int  // every table when 0
+	Counts  bool // count rows now, rather than leave them to RowCounts
+	Refresh bool // count again rather than reuse recent counts
+}
+
+// GetTables lists the tables whose names match a search, a page at a time.
+// Counting the rows of every table up front is slow on schemas with
+// hundreds of them, so the counts can be fetched later, for the tables that
+// are shown.
+func (s *Service) GetTables(ctx context.Context, query TableQuery) (*common.TableList, error) {
+	s.ensureCorrectSchema(ctx)
+	tables, err := s.adapter.GetAllTableNames(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	result := make([]common.TableInfo, 0, len(tables))
 	targetTables := make([]string, 0, len(tables))
-
 	for _, table := range tables {
-		if table != "_flash_migrations" {
+		if table != "_flash_migrations" && !s.isIgnoredTable(table) {
 			targetTables = append(targetTables, table)
 		}
 	}
 
-	tableCounts, err := s.adapter.GetAllTableRowCounts(s.ctx, targetTables)
-	if err != nil {
-		tableCounts = make(map[string]int)
+	partitions := s.getPartitions(ctx, targetTables)
+	if search := strings.ToLower(strings.TrimSpace(query.Search)); search != "" {
+		matching := make([]string, 0, len(targetTables))
 		for _, table := range targetTables {
-			count, _ := s.adapter.GetTableRowCount(s.ctx, table)
-			tableCounts[table] = count
+			if strings.Contains(strings.ToLower(table), search) || slices.ContainsFunc(partitions[table], func(p common.PartitionInfo) bool {
+				return strings.Contains(strings.ToLower(p.Name), search)
+			}) {
+				matching = append(matching, table)
+			}
 		}
+		targetTables = matching
 	}
 
-	for _, table := range targetTables {
-		result = append(result, common.TableInfo{Name: table, RowCount: tableCounts[table]})
+	list := &common.TableList{Tables: []common.TableInfo{}, Total: len(targetTables), Offset: query.Offset, Limit: query.Limit}
+	page := targetTables[min(max(query.Offset, 0), len(targetTables)):]
+	if query.Limit > 0 && len(page) > query.Limit {
+		page = page[:query.Limit]
 	}
 
-	return result, nil
+	var counts map[string]common.RowCount
+	if query.Counts {
+		names := slices.Clone(page)
+		for _, table := range page {
+			for _, partition := range partitions[table] {
+				names = append(names, partition.Name)
+			}
+		}
+		if counts, err = s.cachedRowCounts(ctx, names, query.Refresh); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, table := range page {
+		info := common.TableInfo{Name: table, Partitions: partitions[table]}
+		if count, ok := counts[table]; ok {
+			info.RowCount, info.RowCountEstimated = &count.RowCount, count.Estimated
+		}
+		for i, partition := range info.Partitions {
+			if count, ok := counts[partition.Name]; ok {
+				info.Partitions[i].RowCount, info.Partitions[i].RowCountEstimated = &count.RowCount, count.Estimated
+			}
+		}
+		list.Tables = append(list.Tables, info)
+	}
+	return list, nil
+}
+
+// getPartitions returns the partitions of the partitioned tables among
+// tables. Partitioned tables count the rows of all their partitions, so the
+// partitions are listed under them instead of as tables of their own.
+func (s *Service) getPartitions(ctx context.Context, tables []string) map[string][]common.PartitionInfo {
+	type PartitionIntrospector interface {
+		GetCurrentPartitions(ctx context.Context) ([]types.SchemaPartition, error)
+	}
+	introspector, ok := s.adapter.(PartitionIntrospector)
+	if !ok {
+		return nil
+	}
+	partitions, err := introspector.GetCurrentPartitions(ctx)
+	if err != nil || len(partitions) == 0 {
+		return nil
+	}
+
+	parents := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		parents[table] = true
+	}
+	result := make(map[string][]common.PartitionInfo)
+	for _, partition := range partitions {
+		if parents[partition.Parent] {
+			result[partition.Parent] = append(result[partition.Parent], common.PartitionInfo{
+				Name:  partition.Name,
+				Bound: partition.Bound,
+			})
+		}
+	}
+	return result
 }
 
-func (s *Service) GetTableData(tableName string, page, limit int) (*common.TableData, error) {
-	return s.GetTableDataFiltered(tableName, page, limit, nil)
+func (s *Service) GetTableData(ctx context.Context, tableName string, page, limit int) (*common.TableData, error) {
+	return s.GetTableDataFiltered(ctx, tableName, page, limit, "", nil, false, nil, false)
 }
 
-func (s *Service) GetTableDataFiltered(tableName string, page, limit int, filters []common.Filter) (*common.TableData, error) {
-	s.ensureCorrectSchema()
-	schema, err := s.adapter.GetTableColumns(s.ctx, tableName)
+// GetTableDataFiltered returns one page of rows. When the table has the
+// configured soft-delete column, deleted rows are hidden unless includeDeleted is set.
+// Filter times without an offset are taken as times in loc.
+// Masked columns are masked unless admin is set.
+// Rows are in primary key order when the key sorts as rows were inserted
+// (integers, ULIDs and UUIDv7s); the page then starts after the key after,
+// when given, instead of at an offset.
+func (s *Service) GetTableDataFiltered(ctx context.Context, tableName string, page, limit int, after string, filters []common.Filter, includeDeleted bool, loc *time.Location, admin bool) (*common.TableData, error) {
+	s.ensureCorrectSchema(ctx)
+	schema, err := s.adapter.GetTableColumns(ctx, tableName)
 	if err != nil {
 		return nil, err
 	}
@@ -112,8 +226,8 @@ func (s *Service) GetTableDataFiltered(tableName string, page, limit int, filter
 	columns := make([]common.ColumnInfo, 0, len(schema))
 	columnTypes := make(map[string]string)
 	for _, col := range schema {
-		if seen[col.Name] {
-			continue // Skip duplicate column
+		if seen[col.Name] || s.isIgnoredColumn(tableName, col.Name) {
+			continue // Skip duplicate and ignored columns
 		}
 		seen[col.Name] = true
 		columns = append(columns, common.ColumnInfo{
@@ -125,63 +239,292 @@ func (s *Service) GetTableDataFiltered(tableName string, page, limit int, filter
 			AutoIncrement:    col.IsAutoIncrement,
 			ForeignKeyTable:  col.ForeignKeyTable,
 			ForeignKeyColumn: col.ForeignKeyColumn,
+			ReadOnly:         s.cfg != nil && s.cfg.Timestamps.IsTimestampColumn(col.Name),
 		})
 		columnTypes[col.Name] = col.Type
 	}
+	s.describeColumns(ctx, tableName, columns)
+	s.markEncryptedColumns(tableName, columns)
+	if s.cfg != nil && s.cfg.Studio.GenerateUUIDs != "" {
+		for i := range columns {
+			columns[i].GeneratedUUID = generatesUUID(columns[i].PrimaryKey, columns[i].Type, columns[i].Default)
+		}
+	}
+	if !admin {
+		s.maskColumns(tableName, columns)
+		// Filtering by a masked column would give its values away
+		for _, filter := range filters {
+			if err := s.checkUnmasked(tableName, filter.Column); err != nil {
+				return nil, err
+			}
+		}
+	}
 
 	offset := (page - 1) * limit
 
 	// Build WHERE clause from filters
-	whereClause := s.buildWhereClause(filters, columnTypes)
+	filterColumns := make(map[string]common.ColumnInfo, len(columns))
+	for _, col := range columns {
+		filterColumns[col.Name] = col
+	}
+	whereClause, whereArgs, err := s.buildWhereClause(ctx, filters, filterColumns, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	softDeleteColumn := s.softDeleteColumn(columnTypes)
+	if softDeleteColumn != "" && !includeDeleted {
+		condition := fmt.Sprintf("%s IS NULL", common.QuoteIdentifier(softDeleteColumn))
+		if whereClause != "" {
+			whereClause = fmt.Sprintf("(%s) AND %s", whereClause, condition)
+		} else {
+			whereClause = condition
+		}
+	}
 
-	rows, err := s.getRowsFiltered(tableName, limit, offset, whereClause)
+	// Keyset pagination reads the page by key, while the total still
+	// counts every row the filters match
+	keyColumn, keyOrder := s.tableKeyOrder(ctx, tableName, columns)
+	rowsWhere, rowsArgs, rowsOffset, orderBy := whereClause, whereArgs, offset, ""
+	if keyOrder.Ordered() {
+		orderBy = s.quoteIdentifier(keyColumn)
+		if after != "" {
+			cursor, err := keyCursor(after, keyOrder)
+			if err != nil {
+				return nil, err
+			}
+			rowsArgs = append(append([]any{}, whereArgs...), cursor)
+			condition := fmt.Sprintf("%s > %s", orderBy, s.placeholder(len(rowsArgs)))
+			if rowsWhere != "" {
+				rowsWhere = fmt.Sprintf("(%s) AND %s", rowsWhere, condition)
+			} else {
+				rowsWhere = condition
+			}
+			rowsOffset = 0
+		}
+	}
+
+	rows, err := s.getRowsFiltered(ctx, tableName, limit, rowsOffset, rowsWhere, rowsArgs, orderBy)
 	if err != nil {
 		return nil, err
 	}
+	var nextCursor string
+	if keyOrder.Ordered() && len(rows) == limit && (admin || !s.isMaskedColumn(tableName, keyColumn)) {
+		if key := rows[len(rows)-1][keyColumn]; key != nil {
+			nextCursor = dbcommon.KeyString(key)
+		}
+	}
+	s.stripIgnoredColumns(tableName, rows)
+	offsets := normalizeTimes(rows, columns)
+	s.exactNumbers(rows, columns)
+	if !admin {
+		s.maskRows(tableName, rows)
+	}
+	s.describeEncryptedRows(tableName, rows)
 
-	total, _ := s.getFilteredRowCount(tableName, whereClause)
+	total, totalEstimated := s.tableTotal(ctx, tableName, whereClause, whereArgs, offset, len(rows))
+
+	// Versions let edits of the rows detect that someone else changed them
+	var versions map[string]string
+	for _, col := range columns {
+		if col.PrimaryKey {
+			versions, _ = s.rowVersions(ctx, tableName, col.Name, rows)
+			break
+		}
+	}
 
 	return &common.TableData{
-		Columns: columns,
-		Rows:    rows,
-		Total:   total,
-		Page:    page,
-		Limit:   limit,
+		Columns:          columns,
+		Rows:             rows,
+		Total:            total,
+		TotalEstimated:   totalEstimated,
+		Page:             page,
+		Limit:            limit,
+		SoftDeleteColumn: softDeleteColumn,
+		RowVersions:      versions,
+		TimeOffsets:      offsets,
+		OrderColumn:      orderColumn(keyColumn, keyOrder),
+		KeyOrder:         string(keyOrder),
+		NextCursor:       nextCursor,
 	}, nil
 }
 
-func (s *Service) SaveChanges(tableName string, changes []common.RowChange) error {
-	s.ensureCorrectSchema()
-	schema, err := s.adapter.GetTableColumns(s.ctx, tableName)
+// orderColumn is the column rows are ordered by, "" when they aren't
+func orderColumn(keyColumn string, order dbcommon.KeyOrder) string {
+	if !order.Ordered() {
+		return ""
+	}
+	return keyColumn
+}
+
+// isIgnoredTable reports whether a table is managed outside of flash and
+// should be hidden from studio
+func (s *Service) isIgnoredTable(name string) bool {
+	return s.cfg != nil && s.cfg.Database.IsIgnoredTable(name)
+}
+
+// isIgnoredColumn reports whether a column is managed outside of flash and
+// should be hidden from studio
+func (s *Service) isIgnoredColumn(table, column string) bool {
+	return s.cfg != nil && s.cfg.Database.IsIgnoredColumn(table, column)
+}
+
+// stripIgnoredColumns removes ignored columns from fetched rows
+func (s *Service) stripIgnoredColumns(table string, rows []map[string]any) {
+	if s.cfg == nil || len(s.cfg.Database.IgnoreColumns) == 0 {
+		return
+	}
+	for _, row := range rows {
+		for column := range row {
+			if s.isIgnoredColumn(table, column) {
+				delete(row, column)
+			}
+		}
+	}
+}
+
+// provider returns the configured database provider, empty without a config
+func (s *Service) provider() string {
+	if s.cfg == nil {
+		return ""
+	}
+	return s.cfg.Database.Provider
+}
+
+func (s *Service) isPostgres() bool {
+	return s.provider() == "postgresql" || s.provider() == "postgres"
+}
+
+func (s *Service) isSQLite() bool {
+	return s.provider() == "sqlite" || s.provider() == "sqlite3"
+}
+
+// quoteIdentifier quotes an identifier for the configured provider
+func (s *Service) quoteIdentifier(name string) string {
+	if s.provider() == "mysql" {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return common.QuoteIdentifier(name)
+}
+
+// softDeleteColumn returns the configured soft-delete column if the table has it
+
+func (s *Service) softDeleteColumn(columnTypes map[string]string) string {
+	if s.cfg == nil || s.cfg.SoftDelete.Column == "" {
+		return ""
+	}
+	for name := range columnTypes {
+		if strings.EqualFold(name, s.cfg.SoftDelete.Column) {
+			return name
+		}
+	}
+	return ""
+}
+
+// RestoreRows clears the soft-delete column on the given rows
+func (s *Service) RestoreRows(ctx context.Context, tableName string, rowIDs []string) error {
+	s.ensureCorrectSchema(ctx)
+	schema, err := s.adapter.GetTableColumns(ctx, tableName)
 	if err != nil {
 		return err
 	}
 
 	pkColumn := "id"
+	columnTypes := make(map[string]string, len(schema))
 	for _, col := range schema {
-		if col.IsPrimary {
+		if col.IsPrimary && pkColumn == "id" {
 			pkColumn = col.Name
-			break
 		}
+		columnTypes[col.Name] = col.Type
 	}
 
-	for _, change := range changes {
-		if change.Action == "update" {
-			query := fmt.Sprintf("UPDATE %s SET %s = '%s' WHERE %s = '%s'",
-				common.QuoteIdentifier(tableName), common.QuoteIdentifier(change.Column),
-				change.Value, common.QuoteIdentifier(pkColumn), change.RowID)
+	softDeleteColumn := s.softDeleteColumn(columnTypes)
+	if softDeleteColumn == "" {
+		return fmt.Errorf("table %s has no soft-delete column", tableName)
+	}
 
-			if err := s.adapter.ExecuteMigration(s.ctx, query); err != nil {
-				return fmt.Errorf("failed to update %s.%s: %w", tableName, change.Column, err)
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.journalEdit(ctx, tableName, pkColumn, fmt.Sprintf("Restored %d row(s)", len(rowIDs)), rowIDs, func() error {
+		for _, rowID := range rowIDs {
+			escaped := strings.ReplaceAll(rowID, "'", "''")
+			query := fmt.Sprintf("UPDATE %s SET %s = NULL WHERE %s = '%s'",
+				common.QuoteIdentifier(tableName), common.QuoteIdentifier(softDeleteColumn),
+				common.QuoteIdentifier(pkColumn), escaped)
+			if err := s.adapter.ExecuteMigration(ctx, query); err != nil {
+				return fmt.Errorf("failed to restore row %s: %w", rowID, err)
 			}
 		}
+		return nil
+	})
+}
+
+// SaveChanges updates cells of a table, each row with one UPDATE and all
+// in one transaction. Values are bound with their JSON types, so NULL, an
+// empty string, numbers and booleans are stored as such. Changes that
+// carry the version their row was read with are rejected with a
+// RowConflictError, and none are saved, when any of those rows changed
+// since. Timestamps without an offset are taken as times in loc. Masked
+// columns can't be changed unless admin is set.
+func (s *Service) SaveChanges(ctx context.Context, tableName string, changes []common.RowChange, loc *time.Location, admin bool) error {
+	s.ensureCorrectSchema(ctx)
+	tableColumns, pkColumn, err := s.tableColumns(ctx, tableName)
+	if err != nil {
+		return err
 	}
-	return nil
+
+	expected := make(map[string]string)
+	values := make(map[string]map[string]any)
+	var rowIDs []string
+	for _, change := range changes {
+		if change.Action != "update" {
+			continue
+		}
+		if !admin {
+			if err := s.checkUnmasked(tableName, change.Column); err != nil {
+				return err
+			}
+		}
+		value, err := rowChangeValue(change)
+		if err != nil {
+			return err
+		}
+		if _, seen := values[change.RowID]; !seen {
+			rowIDs = append(rowIDs, change.RowID)
+			values[change.RowID] = make(map[string]any)
+		}
+		values[change.RowID][change.Column] = value
+		if change.Version != "" {
+			expected[change.RowID] = change.Version
+		}
+	}
+
+	statements := make([]dbcommon.Statement, 0, len(rowIDs))
+	for _, rowID := range rowIDs {
+		localTimes(tableColumns, values[rowID], loc)
+		statement, err := s.updateStatement(tableName, pkColumn, rowID, tableColumns, values[rowID])
+		if err != nil {
+			return err
+		}
+		statements = append(statements, statement)
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := s.checkRowVersions(ctx, tableName, pkColumn, expected); err != nil {
+		return err
+	}
+	return s.journalEdit(ctx, tableName, pkColumn, fmt.Sprintf("Edited %d row(s)", len(rowIDs)), rowIDs, func() error {
+		if err := s.adapter.ExecuteStatements(ctx, statements); err != nil {
+			return fmt.Errorf("failed to update %s: %w", tableName, err)
+		}
+		return nil
+	})
 }
 
-func (s *Service) DeleteRows(tableName string, rowIDs []string) error {
-	s.ensureCorrectSchema()
-	schema, err := s.adapter.GetTableColumns(s.ctx, tableName)
+func (s *Service) DeleteRows(ctx context.Context, tableName string, rowIDs []string) error {
+	s.ensureCorrectSchema(ctx)
+	schema, err := s.adapter.GetTableColumns(ctx, tableName)
 	if err != nil {
 		return err
 	}
@@ -194,50 +537,34 @@ func (s *Service) DeleteRows(tableName string, rowIDs []string) error {
 		}
 	}
 
-	for _, rowID := range rowIDs {
-		query := fmt.Sprintf("DELETE FROM %s WHERE %s = '%s'",
-			common.QuoteIdentifier(tableName), common.QuoteIdentifier(pkColumn), rowID)
-		if err := s.adapter.ExecuteMigration(s.ctx, query); err != nil {
-			return fmt.Errorf("failed to delete row %s: %w", rowID, err)
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.journalEdit(ctx, tableName, pkColumn, fmt.Sprintf("Deleted %d row(s)", len(rowIDs)), rowIDs, func() error {
+		for _, rowID := range rowIDs {
+			query := fmt.Sprintf("DELETE FROM %s WHERE %s = '%s'",
+				common.QuoteIdentifier(tableName), common.QuoteIdentifier(pkColumn), strings.ReplaceAll(rowID, "'", "''"))
+			if err := s.adapter.ExecuteMigration(ctx, query); err != nil {
+				return fmt.Errorf("failed to delete row %s: %w", rowID, err)
+			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
-func (s *Service) AddRow(tableName string, data map[string]any) error {
-	s.ensureCorrectSchema()
+func (s *Service) AddRow(ctx context.Context, tableName string, data map[string]any, loc *time.Location) error {
+	s.ensureCorrectSchema(ctx)
 	if len(data) == 0 {
 		return fmt.Errorf("no data provided")
 	}
-
-	columns := []string{}
-	values := []string{}
-
-	for col, val := range data {
-		columns = append(columns, common.QuoteIdentifier(col))
-		if val == nil {
-			values = append(values, "NULL")
-		} else {
-			strVal := fmt.Sprintf("%v", val)
-			escapedVal := strings.ReplaceAll(strVal, "'", "''")
-			values = append(values, fmt.Sprintf("'%s'", escapedVal))
-		}
-	}
-
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		common.QuoteIdentifier(tableName),
-		strings.Join(columns, ", "),
-		strings.Join(values, ", "))
-
-	return s.adapter.ExecuteMigration(s.ctx, query)
+	return s.insertRow(ctx, tableName, data, loc)
 }
 
-func (s *Service) DeleteRow(tableName, rowID string) error {
-	schema, err := s.adapter.GetTableColumns(s.ctx, tableName)
+func (s *Service) DeleteRow(ctx context.Context, tableName, rowID string) error {
+	schema, err := s.adapter.GetTableColumns(ctx, tableName)
 	if err != nil {
 		escaped := strings.ReplaceAll(rowID, "'", "''")
 		query := fmt.Sprintf("DELETE FROM %s WHERE id = '%s'", common.QuoteIdentifier(tableName), escaped)
-		return s.adapter.ExecuteMigration(s.ctx, query)
+		return s.adapter.ExecuteMigration(ctx, query)
 	}
 
 	pkColumn := "id"
@@ -251,19 +578,47 @@ func (s *Service) DeleteRow(tableName, rowID string) error {
 	escaped := strings.ReplaceAll(rowID, "'", "''")
 	query := fmt.Sprintf("DELETE FROM %s WHERE %s = '%s'",
 		common.QuoteIdentifier(tableName), common.QuoteIdentifier(pkColumn), escaped)
-	return s.adapter.ExecuteMigration(s.ctx, query)
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.journalEdit(ctx, tableName, pkColumn, "Deleted a row", []string{rowID}, func() error {
+		return s.adapter.ExecuteMigration(ctx, query)
+	})
+}
+
+// insertRow inserts a row with the given JSON values bound with their
+// types, recording the row for undo
+func (s *Service) insertRow(ctx context.Context, tableName string, data map[string]any, loc *time.Location) error {
+	tableColumns, pkColumn, err := s.tableColumns(ctx, tableName)
+	if err != nil {
+		return err
+	}
+	if err := s.fillUUIDs(tableColumns, data); err != nil {
+		return err
+	}
+	localTimes(tableColumns, data, loc)
+	statement, err := s.insertStatement(tableName, tableColumns, data)
+	if err != nil {
+		return err
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.journalInsert(ctx, tableName, pkColumn, tableColumns, data, func() error {
+		return s.adapter.ExecuteStatements(ctx, []dbcommon.Statement{statement})
+	})
 }
 
 
-func (s *Service) getFilteredRowCount(tableName, whereClause string) (int, error) {
+func (s *Service) getFilteredRowCount(ctx context.Context, tableName, whereClause string, args []any) (int, error) {
 	if whereClause == "" {
-		return s.adapter.GetTableRowCount(s.ctx, tableName)
+		return s.adapter.GetTableRowCount(ctx, tableName)
 	}
 
 	query := fmt.Sprintf("SELECT COUNT(*) as count FROM %s WHERE %s",
 		common.QuoteIdentifier(tableName), whereClause)
 
-	result, err := s.adapter.ExecuteQuery(s.ctx, query)
+	result, err := s.adapter.ExecuteQuery(ctx, query, args...)
 	if err != nil {
 		return 0, err
 	}
@@ -284,115 +639,16 @@ func (s *Service) getFilteredRowCount(tableName, whereClause string) (int, error
 	return 0, nil
 }
 
-func (s *Service) buildWhereClause(filters []common.Filter, columnTypes map[string]string) string {
-	if len(filters) == 0 {
-		return ""
-	}
 
-	var conditions []string
-	var currentGroup []string
-
-	for i, filter := range filters {
-		if filter.Column == "" {
-			continue
-		}
-
-		condition := s.buildFilterCondition(filter, columnTypes)
-		if condition == "" {
-			continue
-		}
-
-		if i == 0 || filter.Logic == "where" {
-			currentGroup = append(currentGroup, condition)
-		} else if filter.Logic == "and" {
-			currentGroup = append(currentGroup, condition)
-		} else if filter.Logic == "or" {
-			if len(currentGroup) > 0 {
-				conditions = append(conditions, "("+strings.Join(currentGroup, " AND ")+")")
-				currentGroup = []string{condition}
-			} else {
-				currentGroup = append(currentGroup, condition)
-			}
-		}
-	}
-
-	if len(currentGroup) > 0 {
-		conditions = append(conditions, "("+strings.Join(currentGroup, " AND ")+")")
-	}
-
-	if len(conditions) == 0 {
-		return ""
-	}
-
-	return strings.Join(conditions, " OR ")
-}
-
-func (s *Service) buildFilterCondition(filter common.Filter, columnTypes map[string]string) string {
-	col := common.QuoteIdentifier(filter.Column)
-	value := strings.ReplaceAll(filter.Value, "'", "''")
-
-	colType := strings.ToLower(columnTypes[filter.Column])
-	isNumeric := strings.Contains(colType, "int") || strings.Contains(colType, "serial") ||
-		strings.Contains(colType, "decimal") || strings.Contains(colType, "numeric") ||
-		strings.Contains(colType, "float") || strings.Contains(colType, "double") ||
-		strings.Contains(colType, "real") || strings.Contains(colType, "money")
-
-	switch filter.Operator {
-	case "equals":
-		if isNumeric {
-			return fmt.Sprintf("%s = %s", col, value)
-		}
-		return fmt.Sprintf("LOWER(CAST(%s AS TEXT)) = LOWER('%s')", col, value)
-	case "not_equals":
-		if isNumeric {
-			return fmt.Sprintf("%s != %s", col, value)
-		}
-		return fmt.Sprintf("LOWER(CAST(%s AS TEXT)) != LOWER('%s')", col, value)
-	case "contains":
-		return fmt.Sprintf("LOWER(CAST(%s AS TEXT)) LIKE LOWER('%%%s%%')", col, value)
-	case "not_contains":
-		return fmt.Sprintf("LOWER(CAST(%s AS TEXT)) NOT LIKE LOWER('%%%s%%')", col, value)
-	case "starts_with":
-		return fmt.Sprintf("LOWER(CAST(%s AS TEXT)) LIKE LOWER('%s%%')", col, value)
-	case "ends_with":
-		return fmt.Sprintf("LOWER(CAST(%s AS TEXT)) LIKE LOWER('%%%s')", col, value)
-	case "gt":
-		if isNumeric {
-			return fmt.Sprintf("%s > %s", col, value)
-		}
-		return fmt.Sprintf("%s > '%s'", col, value)
-	case "lt":
-		if isNumeric {
-			return fmt.Sprintf("%s < %s", col, value)
-		}
-		return fmt.Sprintf("%s < '%s'", col, value)
-	case "gte":
-		if isNumeric {
-			return fmt.Sprintf("%s >= %s", col, value)
-		}
-		return fmt.Sprintf("%s >= '%s'", col, value)
-	case "lte":
-		if isNumeric {
-			return fmt.Sprintf("%s <= %s", col, value)
-		}
-		return fmt.Sprintf("%s <= '%s'", col, value)
-	case "is_null":
-		return fmt.Sprintf("%s IS NULL", col)
-	case "is_not_null":
-		return fmt.Sprintf("%s IS NOT NULL", col)
-	case "is_empty":
-		return fmt.Sprintf("(%s IS NULL OR CAST(%s AS TEXT) = '')", col, col)
-	case "is_not_empty":
-		return fmt.Sprintf("(%s IS NOT NULL AND CAST(%s AS TEXT) != '')", col, col)
-	default:
-		return ""
-	}
-}
-
-
-func (s *Service) getRowsFiltered(tableName string, limit, offset int, whereClause string) ([]map[string]any, error) {
+func (s *Service) getRowsFiltered(ctx context.Context, tableName string, limit, offset int, whereClause string, args []any, orderBy string) ([]map[string]any, error) {
 	var query string
-	if whereClause != "" {
+	if orderBy != "" {
+		query = "SELECT * FROM " + common.QuoteIdentifier(tableName)
+		if whereClause != "" {
+			query += " WHERE " + whereClause
+		}
+		query += fmt.Sprintf(" ORDER BY %s LIMIT %d OFFSET %d", orderBy, limit, offset)
+	} else if whereClause != "" {
 		query = fmt.Sprintf("SELECT * FROM %s WHERE %s LIMIT %d OFFSET %d",
 			common.QuoteIdentifier(tableName), whereClause, limit, offset)
 	} else {
@@ -402,16 +658,20 @@ func (s *Service) getRowsFiltered(tableName string, limit, offset int, whereClau
 		}
 
 		if fetcher, ok := s.adapter.(PaginatedFetcher); ok {
-			return fetcher.GetTableDataPaginated(s.ctx, tableName, limit, offset)
+			return fetcher.GetTableDataPaginated(ctx, tableName, limit, offset)
 		}
 
 		query = fmt.Sprintf("SELECT * FROM %s LIMIT %d OFFSET %d",
 			common.QuoteIdentifier(tableName), limit, offset)
 	}
 
-	result, err := s.adapter.ExecuteQuery(s.ctx, query)
+	result, err := s.adapter.ExecuteQuery(ctx, query, args...)
 	if err != nil {
-		data, err := s.adapter.GetTableData(s.ctx, tableName)
+		// Unfiltered rows would pass for the rows a filter matched
+		if whereClause != "" {
+			return nil, fmt.Errorf("failed to filter %s: %w", tableName, err)
+		}
+		data, err := s.adapter.GetTableData(ctx, tableName)
 		if err != nil {
 			return nil, err
 		}
@@ -431,18 +691,25 @@ func (s *Service) getRowsFiltered(tableName string, limit, offset int, whereClau
 	return result.Rows, nil
 }
 
-func (s *Service) GetSchemaVisualization() (map[string]any, error) {
-	s.ensureCorrectSchema()
+func (s *Service) GetSchemaVisualization(ctx context.Context) (map[string]any, error) {
+	s.ensureCorrectSchema(ctx)
 
 	// Use a channel to load tables concurrently with timeout
-	ctx, cancel := context.WithTimeout(s.ctx, 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	tables, err := s.adapter.GetCurrentSchema(ctx)
+	allTables, err := s.adapter.GetCurrentSchema(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	tables := make([]types.SchemaTable, 0, len(allTables))
+	for _, table := range allTables {
+		if !s.isIgnoredTable(table.Name) {
+			tables = append(tables, table)
+		}
+	}
+
 	enums, _ := s.adapter.GetCurrentEnums(ctx)
 
 	nodes := make([]map[string]any, 0, len(tables))
@@ -464,14 +731,32 @@ func (s *Service) GetSchemaVisualization() (map[string]any, error) {
 			columns := make([]map[string]any, 0, len(table.Columns))
 			columnMap := make(map[string]bool, len(table.Columns))
 
+			// Columns of foreign keys over several columns are foreign too
+			inForeignKey := make(map[string]bool)
+			constraints := make([]map[string]any, 0, len(table.Constraints))
+			for _, constraint := range table.Constraints {
+				if constraint.Type == "FOREIGN KEY" {
+					for _, column := range constraint.Columns {
+						inForeignKey[column] = true
+					}
+				}
+				constraints = append(constraints, map[string]any{
+					"name":              constraint.Name,
+					"type":              constraint.Type,
+					"columns":           constraint.Columns,
+					"foreignKeyTable":   constraint.RefTable,
+					"foreignKeyColumns": constraint.RefColumns,
+				})
+			}
+
 			for _, col := range table.Columns {
-				if !columnMap[col.Name] {
+				if !columnMap[col.Name] && !s.isIgnoredColumn(table.Name, col.Name) {
 					columnMap[col.Name] = true
 					columns = append(columns, map[string]any{
 						"name":             col.Name,
 						"type":             col.Type,
 						"isPrimary":        col.IsPrimary,
-						"isForeign":        col.ForeignKeyTable != "",
+						"isForeign":        col.ForeignKeyTable != "" || inForeignKey[col.Name],
 						"nullable":         col.Nullable,
 						"default":          col.Default,
 						"foreignKeyTable":  col.ForeignKeyTable,
@@ -485,8 +770,9 @@ func (s *Service) GetSchemaVisualization() (map[string]any, error) {
 			nodes = append(nodes, map[string]any{
 				"id": nodeID,
 				"data": map[string]any{
-					"label":   table.Name,
-					"columns": columns,
+					"label":       table.Name,
+					"columns":     columns,
+					"constraints": constraints,
 				},
 				"position": map[string]int{
 					"x": 100 + (j%4)*300,
@@ -537,39 +823,74 @@ func (s *Service) GetSchemaVisualization() (map[string]any, error) {
 				}
 			}
 		}
+
+		// A foreign key over several columns is one edge, drawn between
+		// the first columns on each side
+		for _, constraint := range table.Constraints {
+			if constraint.Type != "FOREIGN KEY" || len(constraint.Columns) == 0 || len(constraint.RefColumns) == 0 {
+				continue
+			}
+			targetID, ok := nodeIndex[constraint.RefTable]
+			if !ok {
+				continue
+			}
+			edgeID := fmt.Sprintf("%s-%s-%s", sourceID, targetID, strings.Join(constraint.Columns, "-"))
+			if edgeMap[edgeID] {
+				continue
+			}
+			edgeMap[edgeID] = true
+			edges = append(edges, map[string]any{
+				"id":            edgeID,
+				"source":        sourceID,
+				"target":        targetID,
+				"label":         strings.Join(constraint.Columns, ", "),
+				"sourceHandle":  constraint.Columns[0],
+				"targetHandle":  constraint.RefColumns[0],
+				"columns":       constraint.Columns,
+				"targetColumns": constraint.RefColumns,
+			})
+		}
 	}
 
 	return map[string]any{"nodes": nodes, "edges": edges, "enums": enums}, nil
 }
 
-func (s *Service) ExecuteSQL(query string) (*common.TableData, error) {
-	s.ensureCorrectSchema()
-	query = strings.TrimSpace(query)
-
-	queryUpper := strings.ToUpper(query)
-
-	// Detect query type more comprehensively
-	isSelectQuery := strings.HasPrefix(queryUpper, "SELECT") ||
+// isSelectStatement reports whether a statement reads rows
+func isSelectStatement(query string) bool {
+	queryUpper := strings.ToUpper(strings.TrimSpace(query))
+	return strings.HasPrefix(queryUpper, "SELECT") ||
 		strings.HasPrefix(queryUpper, "SHOW") ||
 		strings.HasPrefix(queryUpper, "DESCRIBE") ||
 		strings.HasPrefix(queryUpper, "EXPLAIN") ||
 		strings.HasPrefix(queryUpper, "WITH") ||
 		strings.HasPrefix(queryUpper, "TABLE") ||
 		strings.HasPrefix(queryUpper, "VALUES")
+}
+
+// ExecuteSQL runs a statement from the editor. With args it has to be a
+// single statement, since they are bound by the driver. While columns are
+// masked it needs admin.
+func (s *Service) ExecuteSQL(ctx context.Context, query string, admin bool, args ...any) (*common.TableData, error) {
+	if err := s.checkQueryAccess(admin); err != nil {
+		return nil, err
+	}
+	s.ensureCorrectSchema(ctx)
+	query = strings.TrimSpace(query)
+
+	queryUpper := strings.ToUpper(query)
+	isSelectQuery := isSelectStatement(query)
 
 	// Handle SET statements - they may or may not return data depending on database
 	isSetStatement := strings.HasPrefix(queryUpper, "SET")
 
-	if isSelectQuery {
-		result, err := s.adapter.ExecuteQuery(s.ctx, query)
+	if isSelectQuery || len(args) > 0 {
+		result, err := s.adapter.ExecuteQuery(ctx, query, args...)
 		if err != nil {
 			return nil, fmt.Errorf("query execution failed: %w", err)
 		}
 
-		columns := make([]common.ColumnInfo, len(result.Columns))
-		for i, col := range result.Columns {
-			columns[i] = common.ColumnInfo{Name: col, Type: "TEXT"}
-		}
+		columns := s.resultColumns(result)
+		exactResultNumbers(result.Rows, columns)
 
 		return &common.TableData{
 			Columns: columns,
@@ -581,12 +902,10 @@ func (s *Service) ExecuteSQL(query string) (*common.TableData, error) {
 	}
 
 	if isSetStatement {
-		result, err := s.adapter.ExecuteQuery(s.ctx, query)
+		result, err := s.adapter.ExecuteQuery(ctx, query)
 		if err == nil && result != nil {
-			columns := make([]common.ColumnInfo, len(result.Columns))
-			for i, col := range result.Columns {
-				columns[i] = common.ColumnInfo{Name: col, Type: "TEXT"}
-			}
+			columns := s.resultColumns(result)
+			exactResultNumbers(result.Rows, columns)
 			return &common.TableData{
 				Columns: columns,
 				Rows:    result.Rows,
@@ -597,7 +916,7 @@ func (s *Service) ExecuteSQL(query string) (*common.TableData, error) {
 		}
 	}
 
-	if err := s.adapter.ExecuteMigration(s.ctx, query); err != nil {
+	if err := s.adapter.ExecuteMigration(ctx, query); err != nil {
 		return nil, fmt.Errorf("query execution failed: %w", err)
 	}
 
@@ -610,67 +929,50 @@ func (s *Service) ExecuteSQL(query string) (*common.TableData, error) {
 	}, nil
 }
 
-func (s *Service) UpdateRow(table string, id interface{}, data map[string]interface{}) error {
-	s.ensureCorrectSchema()
-
-	schema, err := s.adapter.GetTableColumns(s.ctx, table)
-	if err != nil {
-		return err
+// UpdateRow updates a row. With a version, the row is only updated when it
+// still has it; otherwise a RowConflictError comes back. Masked columns
+// can't be changed unless admin is set.
+func (s *Service) UpdateRow(ctx context.Context, table string, id interface{}, data map[string]interface{}, version string, loc *time.Location, admin bool) error {
+	s.ensureCorrectSchema(ctx)
+	if len(data) == 0 {
+		return fmt.Errorf("no data provided")
 	}
-
-	pkColumn := "id"
-	for _, col := range schema {
-		if col.IsPrimary {
-			pkColumn = col.Name
-			break
+	if !admin {
+		for column := range data {
+			if err := s.checkUnmasked(table, column); err != nil {
+				return err
+			}
 		}
 	}
 
-	var setClauses []string
-	for col, val := range data {
-		if val == nil {
-			setClauses = append(setClauses, fmt.Sprintf("%s = NULL", common.QuoteIdentifier(col)))
-		} else {
-			strVal := fmt.Sprintf("%v", val)
-			escapedVal := strings.ReplaceAll(strVal, "'", "''")
-			setClauses = append(setClauses, fmt.Sprintf("%s = '%s'", common.QuoteIdentifier(col), escapedVal))
-		}
+	tableColumns, pkColumn, err := s.tableColumns(ctx, table)
+	if err != nil {
+		return err
 	}
-
 	idStr := fmt.Sprintf("%v", id)
-	escapedId := strings.ReplaceAll(idStr, "'", "''")
-
-	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = '%s'",
-		common.QuoteIdentifier(table), strings.Join(setClauses, ", "),
-		common.QuoteIdentifier(pkColumn), escapedId)
+	localTimes(tableColumns, data, loc)
+	statement, err := s.updateStatement(table, pkColumn, idStr, tableColumns, data)
+	if err != nil {
+		return err
+	}
 
-	return s.adapter.ExecuteMigration(s.ctx, query)
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := s.checkRowVersions(ctx, table, pkColumn, map[string]string{idStr: version}); err != nil {
+		return err
+	}
+	return s.journalEdit(ctx, table, pkColumn, "Edited a row", []string{idStr}, func() error {
+		return s.adapter.ExecuteStatements(ctx, []dbcommon.Statement{statement})
+	})
 }
 
-func (s *Service) InsertRow(table string, data map[string]interface{}) error {
-	s.ensureCorrectSchema()
+func (s *Service) InsertRow(ctx context.Context, table string, data map[string]interface{}, loc *time.Location) error {
+	s.ensureCorrectSchema(ctx)
 
 	if len(data) == 0 {
 		return fmt.Errorf("no data provided")
 	}
-
-	var columns []string
-	var values []string
-	for col, val := range data {
-		columns = append(columns, common.QuoteIdentifier(col))
-		if val == nil {
-			values = append(values, "NULL")
-		} else {
-			strVal := fmt.Sprintf("%v", val)
-			escapedVal := strings.ReplaceAll(strVal, "'", "''")
-			values = append(values, fmt.Sprintf("'%s'", escapedVal))
-		}
-	}
-
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		common.QuoteIdentifier(table), strings.Join(columns, ", "), strings.Join(values, ", "))
-
-	return s.adapter.ExecuteMigration(s.ctx, query)
+	return s.insertRow(ctx, table, data, loc)
 }
 
 func (s *Service) GetBranches() ([]map[string]interface{}, string, error) {
@@ -703,7 +1005,7 @@ func (s *Service) GetBranches() ([]map[string]interface{}, string, error) {
 	return result, current, nil
 }
 
-func (s *Service) SwitchBranch(branchName string) error {
+func (s *Service) SwitchBranch(ctx context.Context, branchName string) error {
 	if s.cfg == nil {
 		return fmt.Errorf("no config loaded")
 	}
@@ -714,7 +1016,6 @@ func (s *Service) SwitchBranch(branchName string) error {
 	}
 	defer manager.Close()
 
-	ctx := context.Background()
 	if err := manager.SwitchBranch(ctx, branchName); err != nil {
 		return err
 	}
@@ -741,15 +1042,67 @@ func (s *Service) SwitchBranch(branchName string) error {
 		}
 	}
 
+	s.clearJournal()
+	s.clearRowCounts()
+	return nil
+}
+
+// GetTenants lists the project's tenants and the one studio is browsing
+func (s *Service) GetTenants() ([]*tenant.TenantMetadata, string, error) {
+	if s.cfg == nil {
+		return nil, "", fmt.Errorf("no config loaded")
+	}
+
+	store, err := tenant.NewMetadataManager(s.cfg.MigrationsPath).Load()
+	if err != nil {
+		return nil, "", err
+	}
+	return store.Tenants, s.tenant, nil
+}
+
+// SwitchTenant reconnects to a tenant's schema. An empty name returns to
+// the main database.
+func (s *Service) SwitchTenant(ctx context.Context, name string) error {
+	if s.cfg == nil {
+		return fmt.Errorf("no config loaded")
+	}
+
+	dbURL, err := s.cfg.GetDatabaseURL()
+	if err != nil {
+		return err
+	}
+
+	if name != "" {
+		store, err := tenant.NewMetadataManager(s.cfg.MigrationsPath).Load()
+		if err != nil {
+			return err
+		}
+		t := store.GetTenant(name)
+		if t == nil {
+			return fmt.Errorf("tenant '%s' not found", name)
+		}
+		dbURL, err = tenant.TenantURL(s.cfg.Database.Provider, dbURL, t.Schema)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.adapter.Close()
+	if err := s.adapter.Connect(ctx, dbURL); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	s.tenant = name
+	s.clearJournal()
+	s.clearRowCounts()
 	return nil
 }
 
 // GetEditorHints returns schema information optimized for editor autocomplete
 // This data should be cached on the client side to avoid repeated database calls
-func (s *Service) GetEditorHints() (map[string]any, error) {
-	s.ensureCorrectSchema()
+func (s *Service) GetEditorHints(ctx context.Context) (map[string]any, error) {
+	s.ensureCorrectSchema(ctx)
 
-	tables, err := s.adapter.GetAllTableNames(s.ctx)
+	tables, err := s.adapter.GetAllTableNames(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -758,11 +1111,11 @@ func (s *Service) GetEditorHints() (map[string]any, error) {
 	schema := make(map[string][]map[string]string)
 
 	for _, tableName := range tables {
-		if tableName == "_flash_migrations" {
+		if tableName == "_flash_migrations" || s.isIgnoredTable(tableName) {
 			continue
 		}
 
-		columns, err := s.adapter.GetTableColumns(s.ctx, tableName)
+		columns, err := s.adapter.GetTableColumns(ctx, tableName)
 		if err != nil {
 			// Skip tables we can't read columns from
 			schema[tableName] = []map[string]string{}
@@ -772,7 +1125,7 @@ func (s *Service) GetEditorHints() (map[string]any, error) {
 		cols := make([]map[string]string, 0, len(columns))
 		seen := make(map[string]bool)
 		for _, col := range columns {
-			if seen[col.Name] {
+			if seen[col.Name] || s.isIgnoredColumn(tableName, col.Name) {
 				continue
 			}
 			seen[col.Name] = true
@@ -937,19 +1290,27 @@ func (s *Service) getEnumTypes(ctx context.Context) ([]common.ExportEnumType, er
 	return enumTypes, nil
 }
 
-// ExportDatabase exports the database schema and/or data based on export type
-func (s *Service) ExportDatabase(exportType common.ExportType) (*common.ExportData, error) {
-	s.ensureCorrectSchema()
+// ExportDatabase exports the database schema and/or data based on export type.
+// Masked columns are left out of the data unless admin is set.
+func (s *Service) ExportDatabase(ctx context.Context, exportType common.ExportType, admin bool) (*common.ExportData, error) {
+	s.ensureCorrectSchema(ctx)
 
-	ctx, cancel := context.WithTimeout(s.ctx, 60*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
 	// Get all tables
-	tables, err := s.adapter.GetAllTableNames(ctx)
+	allTables, err := s.adapter.GetAllTableNames(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tables: %w", err)
 	}
 
+	tables := make([]string, 0, len(allTables))
+	for _, table := range allTables {
+		if !s.isIgnoredTable(table) {
+			tables = append(tables, table)
+		}
+	}
+
 	// Sort tables by dependency order (tables without FK first)
 	sortedTables, err := s.sortTablesByDependency(ctx, tables)
 	if err != nil {
@@ -1004,7 +1365,18 @@ func (s *Service) ExportDatabase(exportType common.ExportType) (*common.ExportDa
 			if err != nil {
 				return nil, fmt.Errorf("failed to get data for table %s: %w", tableName, err)
 			}
+			s.stripIgnoredColumns(tableName, data)
+			if !admin {
+				s.dropMaskedColumns(tableName, data)
+			}
+			exportTable.ExactNumbers = s.exportExactNumbers(ctx, tableName, data)
+			checksum, err := export.Checksum(data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to checksum table %s: %w", tableName, err)
+			}
 			exportTable.Data = data
+			exportTable.RowCount = len(data)
+			exportTable.Checksum = checksum
 		}
 
 		exportData.Tables = append(exportData.Tables, exportTable)
@@ -1024,7 +1396,7 @@ func (s *Service) getTableSchema(ctx context.Context, tableName string) (*common
 	seen := make(map[string]bool)
 
 	for _, col := range columns {
-		if seen[col.Name] {
+		if seen[col.Name] || s.isIgnoredColumn(tableName, col.Name) {
 			continue
 		}
 		seen[col.Name] = true
@@ -1239,8 +1611,17 @@ func (s *Service) createEnumType(ctx context.Context, enumType common.ExportEnum
 }
 
 // ImportDatabase imports data from an export file
-func (s *Service) ImportDatabase(importData *common.ExportData) (*common.ImportResult, error) {
-	s.ensureCorrectSchema()
+// ImportDatabase imports an export file. With verify, the data of the file
+// is checked against its row counts and checksums before anything is
+// written, and the tables are counted afterwards.
+func (s *Service) ImportDatabase(ctx context.Context, importData *common.ExportData, verify bool) (*common.ImportResult, error) {
+	s.ensureCorrectSchema(ctx)
+
+	if verify {
+		if err := verifyExport(importData); err != nil {
+			return nil, err
+		}
+	}
 
 	result := &common.ImportResult{
 		EnumTypesCreated: make([]string, 0),
@@ -1248,8 +1629,11 @@ func (s *Service) ImportDatabase(importData *common.ExportData) (*common.ImportR
 		TablesUpdated:    make([]string, 0),
 		Errors:           make([]string, 0),
 	}
+	if len(importData.Collections) > 0 {
+		result.Errors = append(result.Errors, fmt.Sprintf("%d MongoDB collection(s) skipped: import them into a MongoDB database", len(importData.Collections)))
+	}
 
-	ctx, cancel := context.WithTimeout(s.ctx, 120*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 120*time.Second)
 	defer cancel()
 
 	// Phase 0: Create ENUM types first (before tables)
@@ -1330,6 +1714,7 @@ func (s *Service) ImportDatabase(importData *common.ExportData) (*common.ImportR
 
 	// Phase 2: Disable FK checks (if enabled) and import data in dependency order
 	restoreFK := s.disableFKChecksIfNeeded(ctx)
+	imported := make(map[string]int, len(sortedTables))
 	for _, table := range sortedTables {
 		if len(table.Data) > 0 && existingTableMap[table.Name] {
 			inserted, updated, err := s.importTableData(ctx, table.Name, table.Data)
@@ -1338,6 +1723,7 @@ func (s *Service) ImportDatabase(importData *common.ExportData) (*common.ImportR
 			} else {
 				result.RowsInserted += inserted
 				result.RowsUpdated += updated
+				imported[table.Name] = inserted + updated
 			}
 		}
 	}
@@ -1362,6 +1748,11 @@ func (s *Service) ImportDatabase(importData *common.ExportData) (*common.ImportR
 		}
 	}
 
+	if verify {
+		result.Verified = true
+		result.Incomplete = s.verifyImport(ctx, sortedTables, imported)
+	}
+
 	return result, nil
 }
 
@@ -1464,13 +1855,32 @@ func (s *Service) importTableData(ctx context.Context, tableName string, data []
 	}
 
 	pkColumn := ""
+	generateKeys := false
 	for _, col := range columns {
 		if col.IsPrimary {
 			pkColumn = col.Name
+			generateKeys = generatesUUID(col.IsPrimary, col.Type, col.Default)
 			break
 		}
 	}
 
+	// Rows without a uuid key get one, when studio.generate_uuids is set
+	if generateKeys {
+		for _, row := range data {
+			if value, ok := row[pkColumn]; ok && value != nil && value != "" {
+				continue
+			}
+			id, err := s.newUUID()
+			if err != nil {
+				return 0, 0, err
+			}
+			if id == "" {
+				break
+			}
+			row[pkColumn] = id
+		}
+	}
+
 	// Batch-check which PKs already exist (single query instead of N queries)
 	existingPKs := make(map[string]bool)
 	if pkColumn != "" {
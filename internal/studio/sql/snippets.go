@@ -0,0 +1,371 @@
+package sql
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/parser"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/utils"
+)
+
+// loadNamedQueries parses the named queries of the project's queries folder
+func (s *Service) loadNamedQueries() ([]*parser.Query, error) {
+	if s.cfg == nil {
+		return nil, fmt.Errorf("named queries need a flash config")
+	}
+
+	schema, err := parser.NewSchemaParser(s.cfg).Parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	queries, err := parser.NewQueryParser(s.cfg).Parse(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse queries: %w", err)
+	}
+	return queries, nil
+}
+
+// GetNamedQueries lists the named queries of the project
+func (s *Service) GetNamedQueries() ([]common.NamedQuery, error) {
+	queries, err := s.loadNamedQueries()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]common.NamedQuery, 0, len(queries))
+	for _, query := range queries {
+		result = append(result, s.namedQuery(query))
+	}
+	return result, nil
+}
+
+// GetQuerySnippets renders ready-to-paste ways of running a named query: the
+// generated TypeScript and Go client calls, the database's command line
+// client and a curl against the studio SQL API at baseURL. Parameters are
+// filled with sample values to be replaced.
+func (s *Service) GetQuerySnippets(name, baseURL string) (*common.QuerySnippets, error) {
+	queries, err := s.loadNamedQueries()
+	if err != nil {
+		return nil, err
+	}
+
+	var query *parser.Query
+	for _, q := range queries {
+		if q.Name == name {
+			query = q
+			break
+		}
+	}
+	if query == nil {
+		return nil, fmt.Errorf("query %s not found", name)
+	}
+
+	samples := make([]any, len(query.Params))
+	for i, param := range query.Params {
+		samples[i] = sampleParamValue(param, i)
+	}
+
+	return &common.QuerySnippets{
+		NamedQuery: s.namedQuery(query),
+		TypeScript: s.typeScriptSnippet(query, samples),
+		Go:         goSnippet(query, samples),
+		CLI:        s.cliSnippet(query, samples),
+		Curl:       curlSnippet(query, samples, baseURL),
+	}, nil
+}
+
+func (s *Service) namedQuery(query *parser.Query) common.NamedQuery {
+	return common.NamedQuery{
+		Name:       query.Name,
+		Cmd:        query.Cmd,
+		SQL:        strings.TrimSpace(query.SQL),
+		Params:     s.namedQueryParams(query),
+		SourceFile: filepath.Base(query.SourceFile),
+	}
+}
+
+// paramName is the name the generated clients give a parameter
+func paramName(param *parser.Param, i int) string {
+	if param.Name == "" {
+		return fmt.Sprintf("p%d", i+1)
+	}
+	return param.Name
+}
+
+// sampleParamValue is a placeholder value of the parameter's type
+func sampleParamValue(param *parser.Param, i int) any {
+	paramType := strings.ToLower(param.Type)
+	switch {
+	case isNumericColumnType(paramType):
+		return 1
+	case strings.Contains(paramType, "bool"):
+		return true
+	}
+	return paramName(param, i)
+}
+
+func (s *Service) typeScriptSnippet(query *parser.Query, samples []any) string {
+	out := "flash_gen"
+	if s.cfg.Gen.JS.Out != "" {
+		out = filepath.ToSlash(filepath.Clean(s.cfg.Gen.JS.Out))
+	}
+	if !strings.HasPrefix(out, ".") && !strings.HasPrefix(out, "/") {
+		out = "./" + out
+	}
+
+	args := make([]string, len(samples))
+	for i, sample := range samples {
+		args[i] = jsonLiteral(sample)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "import { New } from '%s';\n\n", out)
+	b.WriteString("const db = New(pool);\n")
+	fmt.Fprintf(&b, "const result = await db.%s(%s);\n", utils.Uncapitalize(query.Name), strings.Join(args, ", "))
+	return b.String()
+}
+
+func goSnippet(query *parser.Query, samples []any) string {
+	methodName := utils.ToPascalCase(query.Name)
+
+	// The generated method takes a params struct past three parameters
+	var args string
+	if len(query.Params) > 3 {
+		fields := make([]string, len(query.Params))
+		for i, param := range query.Params {
+			fields[i] = fmt.Sprintf("\t%s: %s,", utils.ToPascalCase(param.Name), jsonLiteral(samples[i]))
+		}
+		args = fmt.Sprintf("flash_gen.%sParams{\n%s\n}", methodName, strings.Join(fields, "\n"))
+	} else {
+		values := make([]string, len(samples))
+		for i, sample := range samples {
+			values[i] = jsonLiteral(sample)
+		}
+		args = strings.Join(values, ", ")
+	}
+
+	assign := "err :="
+	switch cmd := strings.ToLower(query.Cmd); {
+	case cmd == ":one" || cmd == ":many":
+		assign = "result, err :="
+	case cmd == ":exec" || utils.IsModifyingQuery(query.SQL):
+	case cmd == ":execresult":
+		assign = "result, err :="
+	}
+
+	var b strings.Builder
+	b.WriteString("queries := flash_gen.New(db)\n")
+	fmt.Fprintf(&b, "%s queries.%s(%s)\n", assign, methodName, args)
+	b.WriteString("if err != nil {\n\treturn err\n}\n")
+	return b.String()
+}
+
+// cliSnippet runs the query with the database's own client, passing the
+// parameters as client variables so they stay easy to change
+func (s *Service) cliSnippet(query *parser.Query, samples []any) string {
+	sqlText := strings.TrimSuffix(strings.TrimSpace(query.SQL), ";")
+	names := make([]string, len(query.Params))
+	for i, param := range query.Params {
+		names[i] = paramName(param, i)
+	}
+	variable := func(format string) func(int) string {
+		return func(i int) string {
+			if i >= len(names) {
+				return "NULL"
+			}
+			return fmt.Sprintf(format, names[i])
+		}
+	}
+
+	var b strings.Builder
+	switch s.provider() {
+	case "mysql":
+		var sets []string
+		for i, sample := range samples {
+			sets = append(sets, fmt.Sprintf("SET @%s = %s;", names[i], sqlLiteral(sample)))
+		}
+		sets = append(sets, s.replacePlaceholders(sqlText, variable("@%s"))+";")
+		fmt.Fprintf(&b, "mysql -h \"$MYSQL_HOST\" -u \"$MYSQL_USER\" -p \"$MYSQL_DATABASE\" -e %s\n",
+			shellQuote(strings.Join(sets, "\n")))
+	case "sqlite", "sqlite3":
+		b.WriteString("sqlite3 " + shellQuote(s.sqlitePath()) + " <<'SQL'\n")
+		for i, sample := range samples {
+			fmt.Fprintf(&b, ".parameter set :%s %s\n", names[i], sqlLiteral(sample))
+		}
+		b.WriteString(s.replacePlaceholders(sqlText, variable(":%s")) + ";\nSQL\n")
+	default:
+		fmt.Fprintf(&b, "psql \"$%s\"", s.cfg.Database.URLEnv)
+		for i, sample := range samples {
+			fmt.Fprintf(&b, " -v %s=%s", names[i], shellQuote(fmt.Sprint(sample)))
+		}
+		// psql only interpolates variables in scripts, not in -c
+		b.WriteString(" <<'SQL'\n")
+		b.WriteString(s.replacePlaceholders(sqlText, func(i int) string {
+			if i >= len(samples) {
+				return "NULL"
+			}
+			if _, isString := samples[i].(string); isString {
+				return fmt.Sprintf(":'%s'", names[i])
+			}
+			return ":" + names[i]
+		}) + ";\nSQL\n")
+	}
+	return b.String()
+}
+
+// sqlitePath is the database file of a SQLite URL, which isn't a secret
+// worth hiding behind the environment variable the way server URLs are
+func (s *Service) sqlitePath() string {
+	url, err := s.cfg.GetDatabaseURL()
+	if err != nil || url == "" {
+		return "database.db"
+	}
+	for _, prefix := range []string{"sqlite3://", "sqlite://", "file:"} {
+		url = strings.TrimPrefix(url, prefix)
+	}
+	if i := strings.Index(url, "?"); i >= 0 {
+		url = url[:i]
+	}
+	return url
+}
+
+func curlSnippet(query *parser.Query, samples []any, baseURL string) string {
+	var body strings.Builder
+	encoder := json.NewEncoder(&body)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(map[string]any{
+		"query":  strings.TrimSpace(query.SQL),
+		"params": samples,
+	})
+	return fmt.Sprintf("curl -X POST %s/api/sql \\\n  -H 'Content-Type: application/json' \\\n  -d %s\n",
+		baseURL, shellQuote(strings.TrimSpace(body.String())))
+}
+
+// replacePlaceholders replaces the $N and ? placeholders outside of string
+// literals, quoted identifiers and comments with replace(index), where the
+// index is zero-based and ? placeholders are numbered in order. ? is an
+// operator in PostgreSQL, so only $N is a placeholder there. :name
+// placeholders are left as they are.
+func (s *Service) replacePlaceholders(query string, replace func(int) string) string {
+	return s.scanPlaceholders(query, func(p placeholder) string {
+		if p.Name != "" {
+			return p.Text
+		}
+		return replace(p.Index)
+	})
+}
+
+// placeholder is a query parameter, positional with a zero-based Index or
+// named
+type placeholder struct {
+	Index int
+	Name  string
+	Text  string // as written in the query
+}
+
+// scanPlaceholders replaces the $N, ? and :name placeholders outside of
+// string literals, quoted identifiers and comments with replace(placeholder)
+func (s *Service) scanPlaceholders(query string, replace func(placeholder) string) string {
+	var b strings.Builder
+	next := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := i + 1
+			for end < len(query) {
+				if query[end] == c {
+					if end+1 < len(query) && query[end+1] == c {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			if end >= len(query) {
+				end = len(query) - 1
+			}
+			b.WriteString(query[i : end+1])
+			i = end
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			b.WriteString(query[i : i+end])
+			i += end - 1
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i
+			} else {
+				end += 4
+			}
+			b.WriteString(query[i : i+end])
+			i += end - 1
+		case c == '$' && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9':
+			end := i + 1
+			for end < len(query) && query[end] >= '0' && query[end] <= '9' {
+				end++
+			}
+			n, _ := strconv.Atoi(query[i+1 : end])
+			b.WriteString(replace(placeholder{Index: n - 1, Text: query[i:end]}))
+			i = end - 1
+		case c == '?' && !s.isPostgres():
+			b.WriteString(replace(placeholder{Index: next, Text: "?"}))
+			next++
+		// :: is a PostgreSQL cast, not a placeholder
+		case c == ':' && i+1 < len(query) && isParamNameStart(query[i+1]) && (i == 0 || query[i-1] != ':'):
+			end := i + 1
+			for end < len(query) && (isParamNameStart(query[end]) || query[end] >= '0' && query[end] <= '9') {
+				end++
+			}
+			b.WriteString(replace(placeholder{Index: -1, Name: query[i+1 : end], Text: query[i:end]}))
+			i = end - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isParamNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// sqlLiteral renders a JSON value as a SQL literal
+func sqlLiteral(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	}
+	// Arrays and objects are passed as their JSON text
+	return "'" + strings.ReplaceAll(jsonLiteral(value), "'", "''") + "'"
+}
+
+func jsonLiteral(value any) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// shellQuote quotes a string for POSIX shells
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
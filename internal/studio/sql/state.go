@@ -0,0 +1,245 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/state"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// queryHistorySize is how many statements the query history keeps
+const queryHistorySize = 200
+
+// schemaLayout is the layout of the schema diagram in the state store
+const schemaLayout = "schema"
+
+// HistoryEntry is a statement run in the SQL editor
+type HistoryEntry struct {
+	Query      string    `json:"query"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	RanAt      time.Time `json:"ran_at"`
+}
+
+// Position is where a node of a diagram was placed
+type Position struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// openState opens the project's state store, which keeps the filter
+// presets, the query history and the diagram layouts
+func (s *Service) openState() (*state.Store, error) {
+	if s.cfg == nil || s.cfg.MigrationsPath == "" {
+		return nil, fmt.Errorf("saved studio state needs a flash config")
+	}
+	return state.Open(s.cfg.MigrationsPath)
+}
+
+// hasState tells whether the project has a state store to read, so reads
+// don't create one
+func (s *Service) hasState() bool {
+	return s.cfg != nil && s.cfg.MigrationsPath != "" && state.Exists(s.cfg.MigrationsPath)
+}
+
+// RecordQuery adds a statement of the SQL editor to the query history,
+// with the error it failed with. Studios without a flash config keep none.
+func (s *Service) RecordQuery(query string, started time.Time, err error) {
+	if s.cfg == nil || s.cfg.MigrationsPath == "" {
+		return
+	}
+	entry := HistoryEntry{
+		Query:      query,
+		Success:    err == nil,
+		DurationMS: time.Since(started).Milliseconds(),
+		RanAt:      started.UTC(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	st, openErr := s.openState()
+	if openErr == nil {
+		defer st.Close()
+		openErr = st.Append(state.KindQueryHistory, entry, queryHistorySize)
+	}
+	if openErr != nil {
+		fmt.Printf("Warning: failed to record query history: %v\n", openErr)
+	}
+}
+
+// QueryHistory returns the latest statements of the SQL editor, newest
+// first
+func (s *Service) QueryHistory(limit int, admin bool) ([]HistoryEntry, error) {
+	if err := s.checkHistoryAccess(admin); err != nil {
+		return nil, err
+	}
+	history := []HistoryEntry{}
+	if !s.hasState() {
+		return history, nil
+	}
+	st, err := s.openState()
+	if err != nil {
+		return nil, err
+	}
+	defer st.Close()
+
+	entries, err := st.Entries(state.KindQueryHistory, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		var entry HistoryEntry
+		if err := json.Unmarshal(e.Value, &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse query history: %w", err)
+		}
+		history = append(history, entry)
+	}
+	return history, nil
+}
+
+// ClearQueryHistory forgets the statements of the SQL editor
+func (s *Service) ClearQueryHistory() error {
+	if !s.hasState() {
+		return nil
+	}
+	st, err := s.openState()
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	return st.ClearEntries(state.KindQueryHistory)
+}
+
+// GetSchemaLayout returns where the tables of the schema diagram were
+// placed, by table
+func (s *Service) GetSchemaLayout() (map[string]Position, error) {
+	positions := map[string]Position{}
+	if !s.hasState() {
+		return positions, nil
+	}
+	st, err := s.openState()
+	if err != nil {
+		return nil, err
+	}
+	defer st.Close()
+
+	if _, err := st.Get(state.KindLayouts, schemaLayout, &positions); err != nil {
+		return nil, err
+	}
+	return positions, nil
+}
+
+// SaveSchemaLayout keeps where the tables of the schema diagram were placed
+func (s *Service) SaveSchemaLayout(positions map[string]Position) error {
+	st, err := s.openState()
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	return st.Put(state.KindLayouts, schemaLayout, positions)
+}
+
+// StateViews returns the read-only views of the state store, such as
+// query_history and query_stats, which the SQL editor can query
+func (s *Service) StateViews(ctx context.Context) ([]state.View, error) {
+	st, err := s.openState()
+	if err != nil {
+		return nil, err
+	}
+	defer st.Close()
+	return st.Views(ctx)
+}
+
+// QueryState runs a read-only statement of the SQL editor over the state
+// store instead of the database
+func (s *Service) QueryState(ctx context.Context, query string, admin bool) (*common.TableData, error) {
+	if err := s.checkHistoryAccess(admin); err != nil {
+		return nil, err
+	}
+	return s.queryState(ctx, query)
+}
+
+func (s *Service) queryState(ctx context.Context, query string, args ...any) (*common.TableData, error) {
+	st, err := s.openState()
+	if err != nil {
+		return nil, err
+	}
+	defer st.Close()
+
+	result, err := st.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query execution failed: %w", err)
+	}
+
+	columns := make([]common.ColumnInfo, len(result.Columns))
+	for i, name := range result.Columns {
+		columns[i] = common.ColumnInfo{Name: name, Type: result.Types[i], Nullable: true, ReadOnly: true}
+	}
+	rows := make([]map[string]any, len(result.Rows))
+	for i, values := range result.Rows {
+		rows[i] = make(map[string]any, len(values))
+		for j, v := range values {
+			rows[i][result.Columns[j]] = v
+		}
+	}
+	return &common.TableData{
+		Columns: columns,
+		Rows:    rows,
+		Total:   len(rows),
+		Page:    1,
+		Limit:   len(rows),
+	}, nil
+}
+
+// GetStateView returns a page of a view of the state store, newest rows
+// first when the view has an id
+func (s *Service) GetStateView(ctx context.Context, name string, page, limit int, admin bool) (*common.TableData, error) {
+	if err := s.checkHistoryAccess(admin); err != nil {
+		return nil, err
+	}
+	views, err := s.StateViews(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var view *state.View
+	for i := range views {
+		if views[i].Name == name {
+			view = &views[i]
+		}
+	}
+	if view == nil {
+		return nil, fmt.Errorf("state store has no view %q", name)
+	}
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 50
+	}
+
+	order := ""
+	for _, column := range view.Columns {
+		if column == "id" {
+			order = " ORDER BY id DESC"
+		}
+	}
+	data, err := s.queryState(ctx, fmt.Sprintf(`SELECT * FROM "%s"%s LIMIT ? OFFSET ?`, name, order), limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	count, err := s.queryState(ctx, fmt.Sprintf(`SELECT COUNT(*) AS n FROM "%s"`, name))
+	if err != nil {
+		return nil, err
+	}
+	if n, ok := count.Rows[0]["n"].(int64); ok {
+		data.Total = int(n)
+	}
+	data.Page = page
+	data.Limit = limit
+	return data, nil
+}
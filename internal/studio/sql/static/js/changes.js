@@ -0,0 +1,205 @@
+/**
+ * Change Capture Module
+ * Polls the studio's change capture and lists the rows the database
+ * writes, newest first
+ */
+
+const CHANGES_POLL_INTERVAL = 1000;
+// Most changes kept on the page
+const CHANGES_MAX = 500;
+
+let capturing = false;
+// Number of the latest change shown, the cursor of the next poll
+let changesCursor = 0;
+let changes = [];
+let pollTimer = null;
+let dropped = 0;
+
+async function toggleCapture() {
+    if (capturing) {
+        await stopCapture();
+    } else {
+        await startCapture();
+    }
+}
+
+async function startCapture() {
+    const btn = $('#capture-btn');
+    btn.disabled = true;
+    setChangesStatus('Creating replication slot…', false);
+    try {
+        const res = await apiCall('/api/changes/capture', { method: 'POST' });
+        const feed = res.data;
+        changesCursor = feed.last;
+        setCapturing(true, feed);
+        schedulePoll();
+    } catch (err) {
+        setCapturing(false);
+        showToast(err.message, 'error', 6000);
+    } finally {
+        btn.disabled = false;
+    }
+}
+
+async function stopCapture() {
+    clearTimeout(pollTimer);
+    try {
+        await apiCall('/api/changes/capture', { method: 'DELETE' });
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
+    setCapturing(false);
+}
+
+function schedulePoll() {
+    clearTimeout(pollTimer);
+    pollTimer = setTimeout(pollChanges, CHANGES_POLL_INTERVAL);
+}
+
+async function pollChanges() {
+    try {
+        const res = await apiCall('/api/changes?after=' + changesCursor);
+        const feed = res.data;
+        if (!feed.running) {
+            // Stopped from another page, or expired
+            if (capturing) {
+                setCapturing(false);
+                showToast('Change capture stopped', 'info');
+            }
+            return;
+        }
+        if (!capturing) {
+            setCapturing(true, feed);
+        }
+        addChanges(feed);
+        schedulePoll();
+    } catch (err) {
+        setCapturing(false);
+        showToast(err.message, 'error', 6000);
+    }
+}
+
+function addChanges(feed) {
+    dropped += feed.dropped || 0;
+    if (feed.changes.length > 0) {
+        changes = changes.concat(feed.changes);
+        if (changes.length > CHANGES_MAX) {
+            changes = changes.slice(changes.length - CHANGES_MAX);
+        }
+        renderChanges();
+    }
+    changesCursor = feed.last;
+}
+
+function setCapturing(running, feed) {
+    capturing = running;
+    const btn = $('#capture-btn');
+    if (running) {
+        btn.textContent = '■ Stop capture';
+        btn.className = 'btn btn-danger';
+        const since = feed && feed.started ? new Date(feed.started).toLocaleTimeString() : '';
+        setChangesStatus('Capturing with ' + (feed ? feed.plugin : '') + (since ? ' since ' + since : ''), true);
+    } else {
+        clearTimeout(pollTimer);
+        btn.textContent = '▶ Start capture';
+        btn.className = 'btn btn-primary';
+        setChangesStatus('Not capturing', false);
+    }
+}
+
+function setChangesStatus(text, live) {
+    const status = $('#changes-status');
+    status.textContent = text;
+    status.classList.toggle('live', live);
+}
+
+function clearChanges() {
+    changes = [];
+    dropped = 0;
+    renderChanges();
+}
+
+function renderChanges() {
+    const body = $('#changes-body');
+    const filter = $('#changes-filter').value.trim().toLowerCase();
+    const op = $('#changes-op').value;
+
+    const shown = changes.filter(function(change) {
+        if (op && change.op !== op) return false;
+        if (filter && (change.schema + '.' + change.table).toLowerCase().indexOf(filter) === -1) return false;
+        return true;
+    });
+
+    if (shown.length === 0) {
+        body.innerHTML = '<div class="empty-state"><span class="iconify" data-icon="mdi:pulse"></span><div>' +
+            (capturing ? 'Waiting for changes…' : 'No changes') + '</div></div>';
+        return;
+    }
+
+    let html = '';
+    if (dropped > 0) {
+        html += '<div class="change-notice">' + dropped + ' change(s) were written faster than this page read them and aren\'t shown</div>';
+    }
+    for (let i = shown.length - 1; i >= 0; i--) {
+        html += changeHtml(shown[i]);
+    }
+    body.innerHTML = html;
+}
+
+function changeHtml(change) {
+    const time = new Date(change.received).toLocaleTimeString();
+    let html = '<div class="change"><div class="change-head">' +
+        '<span class="change-op ' + escapeHtmlAttr(change.op) + '">' + escapeHtml(change.op.toUpperCase()) + '</span>' +
+        '<span class="change-table">' + escapeHtml(change.schema ? change.schema + '.' + change.table : change.table) + '</span>' +
+        '<span class="change-meta" title="Transaction and log position">xid ' + escapeHtml(change.xid) + ' · ' + escapeHtml(change.lsn) + ' · ' + escapeHtml(time) + '</span>' +
+        '</div>';
+
+    const before = change.before || null;
+    const after = change.after || null;
+    if (!before && !after) {
+        if (change.op === 'delete') {
+            html += '<div class="change-notice">The log has no key for this row; set <code>REPLICA IDENTITY</code> on the table to see it</div>';
+        }
+        return html + '</div>';
+    }
+
+    const columns = [];
+    [after, before].forEach(function(row) {
+        if (!row) return;
+        Object.keys(row).forEach(function(col) {
+            if (columns.indexOf(col) === -1) columns.push(col);
+        });
+    });
+
+    html += '<table class="change-rows"><tr><th>Column</th>';
+    if (before) html += '<th>Before</th>';
+    if (after) html += '<th>After</th>';
+    html += '</tr>';
+    columns.forEach(function(col) {
+        const changed = before && after && col in before && col in after &&
+            JSON.stringify(before[col]) !== JSON.stringify(after[col]);
+        html += '<tr' + (changed ? ' class="changed"' : '') + '><td>' + escapeHtml(col) + '</td>';
+        if (before) html += '<td>' + changeValueHtml(before, col) + '</td>';
+        if (after) html += '<td>' + changeValueHtml(after, col) + '</td>';
+        html += '</tr>';
+    });
+    return html + '</table></div>';
+}
+
+function changeValueHtml(row, col) {
+    if (!(col in row)) {
+        return '<span class="missing">—</span>';
+    }
+    const value = row[col];
+    if (value === null) {
+        return '<span class="null">NULL</span>';
+    }
+    return escapeHtml(typeof value === 'object' ? JSON.stringify(value) : String(value));
+}
+
+document.addEventListener('DOMContentLoaded', function() {
+    $('#changes-filter').addEventListener('input', renderChanges);
+    $('#changes-op').addEventListener('change', renderChanges);
+    // Join a capture another page started, with the changes it kept
+    pollChanges();
+});
@@ -0,0 +1,101 @@
+// Plain text area standing in for CodeMirror when it isn't loaded, in
+// offline mode or when the CDN is unreachable. It implements the part of
+// the CodeMirror API the SQL editor uses; hints and highlighting are off.
+(function () {
+    if (window.CodeMirror) {
+        return;
+    }
+
+    function Pos(line, ch) {
+        return { line: line, ch: ch };
+    }
+
+    const keyNames = { Enter: 'Enter', ArrowUp: 'Up', ArrowDown: 'Down', '/': '/', ' ': 'Space', F5: 'F5' };
+
+    function fromTextArea(textarea, options) {
+        options = options || {};
+        const handlers = {};
+
+        textarea.classList.add('editor-fallback');
+        textarea.spellcheck = false;
+
+        function lines() {
+            return textarea.value.split('\n');
+        }
+
+        function indexFromPos(pos) {
+            const all = lines();
+            let index = 0;
+            for (let i = 0; i < pos.line && i < all.length; i++) {
+                index += all[i].length + 1;
+            }
+            return index + Math.min(pos.ch, (all[pos.line] || '').length);
+        }
+
+        function posFromIndex(index) {
+            const before = textarea.value.slice(0, index).split('\n');
+            return Pos(before.length - 1, before[before.length - 1].length);
+        }
+
+        function emit(event) {
+            (handlers[event] || []).forEach(fn => fn(editor));
+        }
+
+        const editor = {
+            state: {},
+            getValue: () => textarea.value,
+            setValue: value => {
+                textarea.value = value;
+                emit('change');
+            },
+            lineCount: () => lines().length,
+            getLine: line => lines()[line],
+            getCursor: which => posFromIndex(which === 'from' ? textarea.selectionStart : textarea.selectionEnd),
+            setCursor: pos => {
+                const index = indexFromPos(pos);
+                textarea.setSelectionRange(index, index);
+            },
+            getSelection: () => textarea.value.slice(textarea.selectionStart, textarea.selectionEnd),
+            replaceRange: (text, from, to) => {
+                const start = indexFromPos(from);
+                const end = to ? indexFromPos(to) : start;
+                textarea.setRangeText(text, start, end, 'end');
+                emit('change');
+            },
+            indexFromPos: indexFromPos,
+            posFromIndex: posFromIndex,
+            getTokenAt: pos => {
+                const line = lines()[pos.line] || '';
+                const match = line.slice(0, pos.ch).match(/[\w.]*$/);
+                return { start: pos.ch - match[0].length, end: pos.ch, string: match[0], type: null };
+            },
+            showHint: () => {},
+            setOption: () => {},
+            focus: () => textarea.focus(),
+            on: (event, fn) => {
+                (handlers[event] = handlers[event] || []).push(fn);
+            },
+        };
+
+        textarea.addEventListener('input', () => emit('change'));
+        textarea.addEventListener('keydown', e => {
+            const name = keyNames[e.key];
+            if (!name) {
+                return;
+            }
+            const prefix = e.metaKey ? 'Cmd-' : e.ctrlKey ? 'Ctrl-' : '';
+            const action = (options.extraKeys || {})[prefix + name];
+            if (typeof action === 'function') {
+                e.preventDefault();
+                action(editor);
+            }
+        });
+
+        if (options.autofocus) {
+            textarea.focus();
+        }
+        return editor;
+    }
+
+    window.CodeMirror = { fromTextArea: fromTextArea, Pos: Pos };
+})();
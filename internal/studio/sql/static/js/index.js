@@ -1,5 +1,9 @@
 let filters = [];
 let currentColumns = [];
+// Filter operators that take no value
+const valuelessFilterOperators = ['is_null', 'is_not_null', 'is_empty', 'is_not_empty'];
+// Filter operators that compare text, whose case and accents can be ignored
+const textFilterOperators = ['equals', 'not_equals', 'contains', 'not_contains', 'starts_with', 'ends_with', 'in', 'not_in', 'matches', 'not_matches'];
 
 // This function just rebuilds the UI to show the active filters
 function restoreFilters(savedFilters) {
@@ -13,7 +17,7 @@ function restoreFilters(savedFilters) {
     // Rebuild filter rows from saved state (UI only)
     savedFilters.forEach((filter, index) => {
         const logic = index === 0 ? 'where' : filter.logic;
-        addFilterRow(logic, filter.column, filter.operator, filter.value);
+        addFilterRow(logic, filter.column, filter.operator, filter.value, filter.values, filter);
     });
 
     filters = savedFilters;
@@ -49,7 +53,8 @@ function getColumnType(columnName) {
     return 'text';
 }
 
-function addFilterRow(logic = 'where', column = '', operator = 'equals', value = '') {
+// flags holds the case_sensitive and ignore_accents options of the filter
+function addFilterRow(logic = 'where', column = '', operator = 'equals', value = '', values = [], flags = {}) {
     const row = document.createElement('div');
     row.className = 'filter-row';
 
@@ -61,10 +66,20 @@ function addFilterRow(logic = 'where', column = '', operator = 'equals', value =
         `<option value="${col.name}" ${col.name === column ? 'selected' : ''}>${col.name} (${col.type || 'text'})</option>`
     ).join('');
 
+    // Between takes its two values in two inputs, in and not in a comma
+    // separated list in one
+    values = values || [];
+    let value2 = '';
+    if (operator === 'between' && values.length) {
+        [value = '', value2 = ''] = values;
+    } else if (values.length) {
+        value = values.join(', ');
+    }
+
     row.innerHTML = `
         ${logicSelect}
         <select class="filter-column" onchange="updateFilterOperators(this)">${columnOptions}</select>
-        <select class="filter-operator">
+        <select class="filter-operator" onchange="updateFilterValueInputs(this.parentElement)">
             <option value="equals" ${operator === 'equals' ? 'selected' : ''}>equals</option>
             <option value="not_equals" ${operator === 'not_equals' ? 'selected' : ''}>not equals</option>
             <option value="contains" ${operator === 'contains' ? 'selected' : ''}>contains</option>
@@ -75,12 +90,22 @@ function addFilterRow(logic = 'where', column = '', operator = 'equals', value =
             <option value="lt" ${operator === 'lt' ? 'selected' : ''}>less than</option>
             <option value="gte" ${operator === 'gte' ? 'selected' : ''}>≥</option>
             <option value="lte" ${operator === 'lte' ? 'selected' : ''}>≤</option>
+            <option value="in" ${operator === 'in' ? 'selected' : ''}>in</option>
+            <option value="not_in" ${operator === 'not_in' ? 'selected' : ''}>not in</option>
+            <option value="between" ${operator === 'between' ? 'selected' : ''}>between</option>
+            <option value="in_last" ${operator === 'in_last' ? 'selected' : ''}>in the last</option>
+            <option value="not_in_last" ${operator === 'not_in_last' ? 'selected' : ''}>not in the last</option>
+            <option value="matches" ${operator === 'matches' ? 'selected' : ''}>matches regex</option>
+            <option value="not_matches" ${operator === 'not_matches' ? 'selected' : ''}>not matches regex</option>
             <option value="is_null" ${operator === 'is_null' ? 'selected' : ''}>is null</option>
             <option value="is_not_null" ${operator === 'is_not_null' ? 'selected' : ''}>is not null</option>
             <option value="is_empty" ${operator === 'is_empty' ? 'selected' : ''}>is empty</option>
             <option value="is_not_empty" ${operator === 'is_not_empty' ? 'selected' : ''}>is not empty</option>
         </select>
         <input type="text" class="filter-value" value="${escapeHtmlAttr(value)}" placeholder="Value">
+        <input type="text" class="filter-value2" value="${escapeHtmlAttr(value2)}" placeholder="To">
+        <label class="filter-flag" title="Match case"><input type="checkbox" class="filter-case" ${flags.case_sensitive ? 'checked' : ''}>Aa</label>
+        <label class="filter-flag" title="Ignore accents (PostgreSQL with the unaccent extension)"><input type="checkbox" class="filter-accents" ${flags.ignore_accents ? 'checked' : ''}>á=a</label>
         <button class="filter-remove" onclick="this.parentElement.remove(); updateFilterCount();">✕</button>
     `;
 
@@ -93,23 +118,35 @@ function addFilterRow(logic = 'where', column = '', operator = 'equals', value =
 
 // Update filter operators based on column type
 function updateFilterOperators(selectElement) {
-    const columnName = selectElement.value;
-    const colType = getColumnType(columnName);
-    const operatorSelect = selectElement.parentElement.querySelector('.filter-operator');
-    const valueInput = selectElement.parentElement.querySelector('.filter-value');
-
-    // Enable/disable value input based on operator
-    operatorSelect.addEventListener('change', function () {
-        const op = this.value;
-        if (op === 'is_null' || op === 'is_not_null' || op === 'is_empty' || op === 'is_not_empty') {
-            valueInput.disabled = true;
-            valueInput.value = '';
-            valueInput.placeholder = 'N/A';
-        } else {
-            valueInput.disabled = false;
-            valueInput.placeholder = 'Value';
-        }
-    });
+    updateFilterValueInputs(selectElement.parentElement);
+}
+
+// Fit the value inputs of a filter row to its operator
+function updateFilterValueInputs(row) {
+    const op = row.querySelector('.filter-operator').value;
+    const valueInput = row.querySelector('.filter-value');
+    const value2Input = row.querySelector('.filter-value2');
+
+    value2Input.style.display = op === 'between' ? '' : 'none';
+    // Case and accents only matter to text comparisons
+    const textOp = textFilterOperators.includes(op) && getColumnType(row.querySelector('.filter-column').value) !== 'number';
+    row.querySelectorAll('.filter-flag').forEach(flag => flag.style.display = textOp ? '' : 'none');
+    if (valuelessFilterOperators.includes(op)) {
+        valueInput.disabled = true;
+        valueInput.value = '';
+        valueInput.placeholder = 'N/A';
+        return;
+    }
+    valueInput.disabled = false;
+    valueInput.placeholder = {
+        in: 'a, b, c',
+        not_in: 'a, b, c',
+        between: 'From',
+        in_last: '7 days',
+        not_in_last: '7 days',
+        matches: 'Regular expression',
+        not_matches: 'Regular expression',
+    }[op] || 'Value';
 }
 
 function updateFilterCount() {
@@ -145,26 +182,7 @@ function clearFilters() {
 }
 
 function applyFilters() {
-    const rows = document.getElementById('filter-rows').children;
-    filters = [];
-
-    for (let row of rows) {
-        const logicSelect = row.querySelector('.filter-logic');
-        const logic = logicSelect ? logicSelect.value : 'where';
-        const column = row.querySelector('.filter-column').value;
-        const operator = row.querySelector('.filter-operator').value;
-        const value = row.querySelector('.filter-value').value;
-
-        // For null/empty checks, we don't need a value
-        if (operator === 'is_null' || operator === 'is_not_null' ||
-            operator === 'is_empty' || operator === 'is_not_empty') {
-            if (column) {
-                filters.push({ logic, column, operator, value: '' });
-            }
-        } else if (column && value !== '') {
-            filters.push({ logic, column, operator, value });
-        }
-    }
+    filters = currentFilterRows();
 
     toggleFilters();
 
@@ -183,6 +201,130 @@ function applyFilters() {
     }
 }
 
+// Filters read from the filter rows. Null and empty checks need no value;
+// other rows without one are left out. In and not in send their comma
+// separated list as values, between its two inputs.
+function currentFilterRows() {
+    const result = [];
+    for (let row of document.getElementById('filter-rows').children) {
+        const logicSelect = row.querySelector('.filter-logic');
+        const logic = logicSelect ? logicSelect.value : 'where';
+        const column = row.querySelector('.filter-column').value;
+        const operator = row.querySelector('.filter-operator').value;
+        const value = row.querySelector('.filter-value').value;
+        const flags = {};
+        // The flags are shown only where they apply
+        if (row.querySelector('.filter-flag').style.display !== 'none') {
+            if (row.querySelector('.filter-case').checked) flags.case_sensitive = true;
+            if (row.querySelector('.filter-accents').checked) flags.ignore_accents = true;
+        }
+        if (valuelessFilterOperators.includes(operator)) {
+            if (column) result.push({ logic, column, operator, value: '' });
+        } else if (operator === 'in' || operator === 'not_in') {
+            const values = value.split(',').map(v => v.trim()).filter(v => v !== '');
+            if (column && values.length) result.push({ logic, column, operator, value: '', values, ...flags });
+        } else if (operator === 'between') {
+            const value2 = row.querySelector('.filter-value2').value;
+            if (column && value !== '' && value2 !== '') {
+                result.push({ logic, column, operator, value: '', values: [value, value2] });
+            }
+        } else if (column && value !== '') {
+            result.push({ logic, column, operator, value, ...flags });
+        }
+    }
+    return result;
+}
+
+// Filter presets are named filter sets saved with the project per table
+let filterPresets = [];
+
+async function loadFilterPresets() {
+    const select = document.getElementById('filter-preset-select');
+    if (!select || !state.currentTable) return;
+    filterPresets = [];
+    try {
+        const res = await fetch(`/api/tables/${encodeURIComponent(state.currentTable)}/presets`);
+        const json = await res.json();
+        if (json.success) filterPresets = json.data || [];
+    } catch (error) {
+        console.error('Failed to load filter presets:', error);
+    }
+    select.innerHTML = '<option value="">Saved filters…</option>' +
+        filterPresets.map(p => `<option value="${escapeHtmlAttr(p.name)}">${escapeHtml(p.name)}</option>`).join('');
+    document.getElementById('filter-preset-delete').disabled = true;
+}
+
+function applyFilterPreset(name) {
+    document.getElementById('filter-preset-delete').disabled = !name;
+    const preset = filterPresets.find(p => p.name === name);
+    if (!preset) return;
+    restoreFilters(preset.filters);
+    applyFilters();
+}
+
+async function saveFilterPreset() {
+    const presetFilters = currentFilterRows();
+    if (presetFilters.length === 0) {
+        showToast('Add a filter to save first', 'error');
+        return;
+    }
+    const selected = document.getElementById('filter-preset-select').value;
+    const name = (prompt('Name of the saved filters', selected) || '').trim();
+    if (!name) return;
+    try {
+        const res = await fetch(`/api/tables/${encodeURIComponent(state.currentTable)}/presets/${encodeURIComponent(name)}`, {
+            method: 'PUT',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ filters: presetFilters })
+        });
+        const json = await res.json();
+        if (!json.success) {
+            showToast(json.message || 'Failed to save filters', 'error');
+            return;
+        }
+        showToast(`Saved filters "${name}"`, 'success');
+        await loadFilterPresets();
+        document.getElementById('filter-preset-select').value = name;
+        document.getElementById('filter-preset-delete').disabled = false;
+    } catch (error) {
+        showToast('Failed to save filters: ' + error.message, 'error');
+    }
+}
+
+async function deleteFilterPreset() {
+    const name = document.getElementById('filter-preset-select').value;
+    if (!name || !confirm(`Delete the saved filters "${name}"?`)) return;
+    try {
+        const res = await fetch(`/api/tables/${encodeURIComponent(state.currentTable)}/presets/${encodeURIComponent(name)}`, { method: 'DELETE' });
+        const json = await res.json();
+        if (!json.success) {
+            showToast(json.message || 'Failed to delete filters', 'error');
+            return;
+        }
+        showToast(`Deleted filters "${name}"`, 'success');
+        loadFilterPresets();
+    } catch (error) {
+        showToast('Failed to delete filters: ' + error.message, 'error');
+    }
+}
+
+// Download the rows matching the filter rows, by the name of the selected
+// preset while the rows are still the preset's
+function exportFilteredRows() {
+    if (!state.currentTable) return;
+    const rowFilters = currentFilterRows();
+    const preset = filterPresets.find(p => p.name === document.getElementById('filter-preset-select').value);
+    const filterKey = f => JSON.stringify(f.map(({ column, operator, value, values, case_sensitive, ignore_accents }, i) =>
+        [i ? f[i].logic : 'where', column, operator, value, values || [], !!case_sensitive, !!ignore_accents]));
+    let url = `/api/tables/${encodeURIComponent(state.currentTable)}/export?format=csv`;
+    if (preset && filterKey(preset.filters) === filterKey(rowFilters)) {
+        url += `&preset=${encodeURIComponent(preset.name)}`;
+    } else if (rowFilters.length > 0) {
+        url += `&filters=${encodeURIComponent(JSON.stringify(rowFilters))}`;
+    }
+    window.location.href = url;
+}
+
 function openSQLModal() {
     document.getElementById('sql-modal').classList.add('show');
 }
@@ -304,7 +446,7 @@ async function executeSQLQuery() {
     // Edit table - navigate to schema visualizer
     function editTableFromContext() {
         if (contextTableName) {
-            window.location.href = '/schema#edit-' + encodeURIComponent(contextTableName);
+            window.location.href = BASE_PATH + '/schema#edit-' + encodeURIComponent(contextTableName);
         }
     }
 
@@ -357,6 +499,7 @@ selectTable = async function (tableName) {
     if (state.data && state.data.columns) {
         currentColumns = state.data.columns;
     }
+    loadFilterPresets();
 };
 function filterIndexItems() {
     const query = document.getElementById('search-tables').value.toLowerCase();
@@ -368,7 +511,7 @@ function filterIndexItems() {
 }
 
 function showCreateTableForm() {
-    window.location.href = '/schema#create-table';
+    window.location.href = BASE_PATH + '/schema#create-table';
 }
 
 // Branch Management
@@ -423,9 +566,61 @@ async function switchBranch(branchName) {
     }
 }
 
-// Load branches on page load
+// Tenant Management
+async function loadTenants() {
+    try {
+        const response = await fetch('/api/tenants');
+        const data = await response.json();
+
+        const selector = document.getElementById('tenant-selector');
+        if (!selector || !data.tenants || data.tenants.length === 0) return;
+
+        selector.innerHTML = '';
+        selector.style.display = 'inline-block';
+
+        const mainOption = document.createElement('option');
+        mainOption.value = '';
+        mainOption.textContent = 'Main database';
+        selector.appendChild(mainOption);
+
+        data.tenants.forEach(tenant => {
+            const option = document.createElement('option');
+            option.value = tenant.name;
+            option.textContent = `Tenant: ${tenant.name}`;
+            if (tenant.name === data.current) {
+                option.selected = true;
+            }
+            selector.appendChild(option);
+        });
+    } catch (error) {
+        console.error('Failed to load tenants:', error);
+    }
+}
+
+async function switchTenant(tenantName) {
+    try {
+        const response = await fetch('/api/tenants/switch', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ tenant: tenantName })
+        });
+
+        if (response.ok) {
+            showToast(tenantName ? `Switched to tenant: ${tenantName}` : 'Switched to main database', 'success');
+            location.reload(); // Reload to show data from the selected tenant
+        } else {
+            showToast('Failed to switch tenant', 'error');
+        }
+    } catch (error) {
+        console.error('Failed to switch tenant:', error);
+        showToast('Failed to switch tenant', 'error');
+    }
+}
+
+// Load branches and tenants on page load
 document.addEventListener('DOMContentLoaded', () => {
     loadBranches();
+    loadTenants();
 });
 
 // Dropdown toggle
@@ -508,6 +703,12 @@ async function handleImportFile(event) {
     // Reset file input for future imports
     event.target.value = '';
 
+    // Anything but Flash's own JSON export is taken for a SQL dump
+    if (!file.name.toLowerCase().endsWith('.json')) {
+        confirmDumpImport(file);
+        return;
+    }
+
     try {
         const content = await file.text();
         let importData;
@@ -536,6 +737,7 @@ async function handleImportFile(event) {
         const hasSchema = importData.tables.some(t => t.schema);
         const hasData = importData.tables.some(t => t.data && t.data.length > 0);
         const enumCount = importData.enum_types ? importData.enum_types.length : 0;
+        const hasChecksums = importData.tables.some(t => t.checksum);
 
         let details = `<div style="margin-bottom: 16px;">
             <p><strong>File:</strong> ${file.name}</p>
@@ -545,6 +747,7 @@ async function handleImportFile(event) {
             <p><strong>Tables:</strong> ${tableCount}</p>
             <p><strong>Contains Schema:</strong> ${hasSchema ? 'Yes' : 'No'}</p>
             <p><strong>Contains Data:</strong> ${hasData ? 'Yes' : 'No'}</p>
+            <p><strong>Checksums:</strong> ${hasChecksums ? 'Yes' : 'No'}</p>
         </div>
         ${enumCount > 0 ? `<div style="background: #2a2a2a; padding: 12px; border-radius: 6px; margin-bottom: 16px;">
             <strong>Enum types to create:</strong>
@@ -558,11 +761,17 @@ async function handleImportFile(event) {
                 ${importData.tables.map(t => `<li>${t.name} ${t.data ? `(${t.data.length} rows)` : '(schema only)'}</li>`).join('')}
             </ul>
         </div>
+        ${hasChecksums ? `<label style="display: block; margin-bottom: 16px;">
+            <input type="checkbox" id="import-verify" checked>
+            Verify row counts and checksums before importing, and count the rows afterwards
+        </label>` : ''}
         <p style="color: #f59e0b;">This will create enum types, tables, and add missing columns/data.</p>`;
 
         showConfirm('Import Database', details, async () => {
-            await performImport(importData);
+            await performImport(importData, verifyBox !== null && verifyBox.checked);
         });
+        // The dialog is gone by the time it's confirmed, the checkbox isn't
+        const verifyBox = document.getElementById('import-verify');
 
     } catch (err) {
         console.error('Import failed:', err);
@@ -570,14 +779,123 @@ async function handleImportFile(event) {
     }
 }
 
+// List tables of an import that failed a check, with their row counts
+function tableChecksHtml(checks) {
+    return `<ul style="margin: 0; padding-left: 20px;">${checks.map(c =>
+        `<li><strong>${escapeHtml(c.table)}</strong>: ${escapeHtml(c.problem)}</li>`).join('')}</ul>`;
+}
+
+// Confirm restoring a SQL dump, from pg_dump, mysqldump or sqlite3's .dump
+function confirmDumpImport(file) {
+    const details = `<div style="margin-bottom: 16px;">
+            <p><strong>File:</strong> ${escapeHtml(file.name)}</p>
+            <p><strong>Size:</strong> ${formatBytes(file.size)}</p>
+        </div>
+        <p style="color: #f59e0b;">This runs every statement of the dump against the current database.
+        Statements that fail are listed at the end; the rest are kept.</p>`;
+
+    showConfirm('Restore SQL Dump', details, async () => {
+        await performDumpImport(file);
+    });
+}
+
+// Send a SQL dump and follow the progress the server streams back, a line
+// of JSON at a time
+async function performDumpImport(file) {
+    const importBtn = document.getElementById('import-btn');
+    const btnText = importBtn.querySelector('.btn-text');
+    importBtn.classList.add('loading');
+    showToast('Restoring dump...', 'info');
+
+    const showProgress = (p) => {
+        const percent = file.size > 0 ? Math.min(100, Math.round(p.bytes / file.size * 100)) : 100;
+        btnText.textContent = `Import ${percent}%`;
+    };
+
+    let result = null;
+    let error = null;
+    try {
+        const response = await fetch('/api/import/dump', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/sql' },
+            body: file
+        });
+
+        if (!(response.headers.get('Content-Type') || '').includes('ndjson')) {
+            const json = await response.json();
+            throw new Error(json.message || 'Import failed');
+        }
+
+        const reader = response.body.getReader();
+        const decoder = new TextDecoder();
+        let buffer = '';
+        const readLine = (line) => {
+            if (!line.trim()) return;
+            const msg = JSON.parse(line);
+            if (msg.progress) showProgress(msg.progress);
+            if (msg.result) result = msg.result;
+            if (msg.error) error = msg.error;
+        };
+        for (;;) {
+            const { done, value } = await reader.read();
+            if (done) break;
+            buffer += decoder.decode(value, { stream: true });
+            const lines = buffer.split('\n');
+            buffer = lines.pop();
+            lines.forEach(readLine);
+        }
+        readLine(buffer);
+    } catch (err) {
+        console.error('Dump import failed:', err);
+        error = err.message;
+    } finally {
+        importBtn.classList.remove('loading');
+        btnText.textContent = 'Import';
+    }
+
+    if (!result) {
+        showToast('Import failed: ' + (error || 'no result'), 'error', 5000);
+        return;
+    }
+
+    const summary = [
+        `Format: ${escapeHtml(result.format)}`,
+        `Statements run: ${result.statements}`,
+        `Rows copied: ${result.rows}`,
+        `Statements skipped: ${result.skipped}`
+    ];
+    if (result.failed > 0) {
+        summary.push(`<span style="color: #ef4444;">Statements failed: ${result.failed}</span>`);
+    }
+    if (result.stopped) {
+        summary.push('<span style="color: #ef4444;">Stopped after too many failures</span>');
+    }
+    if (error) {
+        summary.push(`<span style="color: #ef4444;">${escapeHtml(error)}</span>`);
+    }
+
+    let html = `<ul style="margin: 0; padding-left: 20px;">${summary.map(s => `<li>${s}</li>`).join('')}</ul>`;
+    const errors = result.errors || [];
+    if (errors.length > 0) {
+        html += `<div style="background: #2a2a2a; padding: 12px; border-radius: 6px; margin-top: 16px; max-height: 200px; overflow-y: auto;">
+            ${errors.slice(0, 20).map(e => `<p style="margin: 0 0 8px;"><strong>Line ${e.line}:</strong> ${escapeHtml(e.error)}<br><code>${escapeHtml(e.statement)}</code></p>`).join('')}
+            ${errors.length > 20 ? `<p style="margin: 0;">and ${errors.length - 20} more</p>` : ''}
+        </div>`;
+    }
+
+    showModal('Restore Complete', html, result.failed > 0 || error ? 'warning' : 'success');
+    refreshData();
+    loadTables(true);
+}
+
 // Perform the actual import
-async function performImport(importData) {
+async function performImport(importData, verify) {
     const importBtn = document.getElementById('import-btn');
     importBtn.classList.add('loading');
     showToast('Importing database...', 'info');
 
     try {
-        const response = await fetch('/api/import', {
+        const response = await fetch(verify ? '/api/import?verify=true' : '/api/import', {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
             body: JSON.stringify(importData)
@@ -586,6 +904,11 @@ async function performImport(importData) {
         const result = await response.json();
 
         if (!result.success) {
+            if (result.data && result.data.tables) {
+                // Nothing was imported: the file doesn't match its checksums
+                showModal('Verification Failed', tableChecksHtml(result.data.tables), 'error');
+                return;
+            }
             showToast(result.message || 'Import failed', 'error');
             return;
         }
@@ -616,15 +939,25 @@ async function performImport(importData) {
             summary.push(`<span style="color: #ef4444;">Errors: ${r.errors.length}</span>`);
         }
 
-        const summaryHtml = summary.length > 0
+        const incomplete = r.incomplete || [];
+        if (r.verified && incomplete.length === 0) {
+            summary.push('Row counts verified');
+        }
+
+        let summaryHtml = summary.length > 0
             ? `<ul style="margin: 0; padding-left: 20px;">${summary.map(s => `<li>${s}</li>`).join('')}</ul>`
             : 'No changes were made.';
+        if (incomplete.length > 0) {
+            summaryHtml += `<p style="margin: 16px 0 8px; color: #ef4444;"><strong>Tables that didn't fully import:</strong></p>` +
+                tableChecksHtml(incomplete);
+        }
 
-        showModal('Import Complete', summaryHtml, r.errors && r.errors.length > 0 ? 'warning' : 'success');
+        const warn = (r.errors && r.errors.length > 0) || incomplete.length > 0;
+        showModal('Import Complete', summaryHtml, warn ? 'warning' : 'success');
 
         // Refresh the data
         refreshData();
-        loadTables();
+        loadTables(true);
 
     } catch (err) {
         console.error('Import failed:', err);
@@ -74,6 +74,21 @@ function showConfirm(title, message, onConfirm) {
     };
 }
 
+// Input for a column of the add row form, picked by the kind of the column.
+// Lists start empty, which leaves the column to its default.
+function addRowField(col, placeholderText, attrs) {
+    const id = `field-${escapeHtmlAttr(col.name)}`;
+    if ((col.kind === 'boolean' || col.kind === 'enum') && !attrs.includes('readonly')) {
+        const values = col.kind === 'boolean' ? ['true', 'false'] : (col.enum_values || []);
+        const options = values.map(v => `<option value="${escapeHtmlAttr(v)}">${escapeHtml(v)}</option>`).join('');
+        return `<select class="form-input" id="${id}" ${attrs}><option value="">${escapeHtml(placeholderText)}</option>${options}</select>`;
+    }
+    const inputTypes = { date: 'date', timestamp: 'datetime-local', time: 'time' };
+    const type = inputTypes[col.kind] || 'text';
+    const step = type === 'datetime-local' || type === 'time' ? 'step="1"' : '';
+    return `<input type="${type}" ${step} class="form-input" id="${id}" placeholder="${escapeHtmlAttr(placeholderText)}" ${attrs} />`;
+}
+
 function showAddRowModal(columns, onSave) {
     const modal = document.createElement('div');
     modal.className = 'custom-modal show';
@@ -87,10 +102,11 @@ function showAddRowModal(columns, onSave) {
     
     const fields = uniqueColumns.map(col => {
         const isAutoIncrement = col.auto_increment === true;
+        const isReadOnly = isAutoIncrement || col.read_only === true;
         const hasDefault = col.default && col.default.trim() !== '';
-        const isRequired = !col.nullable && !isAutoIncrement && !hasDefault;
-        const readonlyAttr = isAutoIncrement ? 'readonly style="background: #1a1a1a; cursor: not-allowed;"' : '';
-        const placeholderText = isAutoIncrement ? 'Auto-generated' : hasDefault ? `Default: ${col.default}` : `Enter ${col.name}`;
+        const isRequired = !col.nullable && !isReadOnly && !hasDefault && !col.generated_uuid;
+        const readonlyAttr = isReadOnly ? 'readonly style="background: #1a1a1a; cursor: not-allowed;"' : '';
+        const placeholderText = isReadOnly ? 'Auto-generated' : hasDefault ? `Default: ${col.default}` : col.generated_uuid ? 'Generated if left empty' : `Enter ${col.name}`;
         
         return `
             <div class="form-group">
@@ -100,17 +116,11 @@ function showAddRowModal(columns, onSave) {
                     ${col.primary_key ? '<span class="schema-column-badge">PK</span>' : ''}
                     ${isRequired ? '<span style="color: #dc2626;">*</span>' : ''}
                 </label>
-                <input 
-                    type="text" 
-                    class="form-input" 
-                    id="field-${col.name}" 
-                    placeholder="${placeholderText}"
-                    ${readonlyAttr}
-                    ${isRequired ? 'required' : ''}
-                />
+                ${addRowField(col, placeholderText, `${readonlyAttr} ${isRequired ? 'required' : ''}`)}
                 ${isRequired ? '<div class="form-hint">Required field</div>' : ''}
-                ${isAutoIncrement ? '<div class="form-hint">This field will be auto-generated</div>' : ''}
-                ${hasDefault && !isAutoIncrement ? `<div class="form-hint">Optional - has default value</div>` : ''}
+                ${isReadOnly ? '<div class="form-hint">This field will be auto-generated</div>' : ''}
+                ${hasDefault && !isReadOnly ? `<div class="form-hint">Optional - has default value</div>` : ''}
+                ${col.generated_uuid ? '<div class="form-hint">Optional - studio generates a UUID</div>' : ''}
             </div>
         `;
     }).join('');
@@ -146,11 +156,11 @@ function showAddRowModal(columns, onSave) {
             if (!input) return;
             
             const value = input.value.trim();
-            const isAutoIncrement = col.auto_increment === true;
+            const isReadOnly = col.auto_increment === true || col.read_only === true;
             const hasDefault = col.default && col.default.trim() !== '';
-            const isRequired = !col.nullable && !isAutoIncrement && !hasDefault;
+            const isRequired = !col.nullable && !isReadOnly && !hasDefault && !col.generated_uuid;
             
-            if (isAutoIncrement) return;
+            if (isReadOnly) return;
             
             if (isRequired && !value) {
                 input.style.borderColor = '#dc2626';
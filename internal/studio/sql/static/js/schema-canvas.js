@@ -104,6 +104,7 @@ class SchemaCanvas {
                 this.edges = rawEdges;
 
                 this.resolveOverlaps();
+                await this.restoreLayout();
 
                 this.hideMessage();
 
@@ -117,6 +118,35 @@ class SchemaCanvas {
         }
     }
 
+    // Put the tables back where they were left, tables added since keep
+    // the computed position
+    async restoreLayout() {
+        try {
+            const res = await fetch('/api/schema/layout');
+            const data = await res.json();
+            if (!data.success || !data.data) return;
+            this.nodes.forEach(n => {
+                const saved = data.data[n.id];
+                if (saved) n.position = { x: saved.x, y: saved.y };
+            });
+        } catch (e) {
+            // The computed layout is used
+        }
+    }
+
+    // Keep where the tables are, for the next time the diagram is opened
+    saveLayout() {
+        const positions = {};
+        this.nodes.forEach(n => {
+            positions[n.id] = { x: Math.round(n.position.x), y: Math.round(n.position.y) };
+        });
+        fetch('/api/schema/layout', {
+            method: 'PUT',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ positions: positions })
+        }).catch(() => {});
+    }
+
     getNodeHeight(node) {
         const cols = node.data?.columns?.length || 0;
         return this.headerHeight + cols * this.rowHeight + this.nodePadding;
@@ -752,6 +782,9 @@ class SchemaCanvas {
 
         // Mouse up - listen on document to catch releases outside canvas
         const handleMouseUp = () => {
+            if (this.draggedNode && this.hasDragged) {
+                this.saveLayout();
+            }
             if (this.draggedNode || this.isPanning) {
                 this.draggedNode = null;
                 this.dragStartPos = null;
@@ -877,21 +910,7 @@ class SchemaCanvas {
         this.highlightedFields.clear();
 
         if (this.hoveredEdge) {
-            this.highlightedNodes.add(this.hoveredEdge.source);
-            this.highlightedNodes.add(this.hoveredEdge.target);
-
-            if (this.hoveredEdge.sourceHandle) {
-                if (!this.highlightedFields.has(this.hoveredEdge.source)) {
-                    this.highlightedFields.set(this.hoveredEdge.source, new Set());
-                }
-                this.highlightedFields.get(this.hoveredEdge.source).add(this.hoveredEdge.sourceHandle);
-            }
-            if (this.hoveredEdge.targetHandle) {
-                if (!this.highlightedFields.has(this.hoveredEdge.target)) {
-                    this.highlightedFields.set(this.hoveredEdge.target, new Set());
-                }
-                this.highlightedFields.get(this.hoveredEdge.target).add(this.hoveredEdge.targetHandle);
-            }
+            this.highlightEdgeFields(this.hoveredEdge);
             return;
         }
 
@@ -900,22 +919,24 @@ class SchemaCanvas {
         const connectedEdges = this.edges.filter(e =>
             e.source === this.hoveredNode.id || e.target === this.hoveredNode.id);
 
-        connectedEdges.forEach(e => {
-            this.highlightedNodes.add(e.source);
-            this.highlightedNodes.add(e.target);
+        connectedEdges.forEach(e => this.highlightEdgeFields(e));
+    }
 
-            if (e.sourceHandle) {
-                if (!this.highlightedFields.has(e.source)) {
-                    this.highlightedFields.set(e.source, new Set());
-                }
-                this.highlightedFields.get(e.source).add(e.sourceHandle);
-            }
-            if (e.targetHandle) {
-                if (!this.highlightedFields.has(e.target)) {
-                    this.highlightedFields.set(e.target, new Set());
-                }
-                this.highlightedFields.get(e.target).add(e.targetHandle);
+    // Foreign keys over several columns highlight all of their columns
+    highlightEdgeFields(e) {
+        this.highlightedNodes.add(e.source);
+        this.highlightedNodes.add(e.target);
+
+        const sides = [
+            [e.source, e.columns || (e.sourceHandle ? [e.sourceHandle] : [])],
+            [e.target, e.targetColumns || (e.targetHandle ? [e.targetHandle] : [])],
+        ];
+        sides.forEach(([node, fields]) => {
+            if (fields.length === 0) return;
+            if (!this.highlightedFields.has(node)) {
+                this.highlightedFields.set(node, new Set());
             }
+            fields.forEach(field => this.highlightedFields.get(node).add(field));
         });
     }
 
@@ -1019,6 +1040,7 @@ class SchemaCanvas {
 
         // Resolve any remaining overlaps
         this.resolveOverlaps();
+        this.saveLayout();
 
         this.fitView();
         this.render();
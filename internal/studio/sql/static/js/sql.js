@@ -5,8 +5,15 @@
 
 let editor;
 let currentResults = null;
+// Last query run, which results are exported
+let lastQuery = null;
 let queryHistory = [];
 let historyIndex = -1;
+// Last values entered for query parameters, by placeholder
+let paramValues = {};
+// Where queries run: 'database', or 'state' for the read-only views of the
+// project's state store, such as query_history and query_stats
+let querySource = 'database';
 
 // Storage key for SQL editor state
 const SQL_STORAGE_KEY = 'flashorm_sql_editor_state';
@@ -19,7 +26,9 @@ function saveSqlState() {
     const state = {
         content: editor ? editor.getValue() : '',
         queryHistory: queryHistory,
-        historyIndex: historyIndex
+        historyIndex: historyIndex,
+        paramValues: paramValues,
+        querySource: querySource
     };
     try {
         sessionStorage.setItem(SQL_STORAGE_KEY, JSON.stringify(state));
@@ -43,6 +52,13 @@ function restoreSqlState() {
             if (typeof state.historyIndex === 'number') {
                 historyIndex = state.historyIndex;
             }
+            if (state.paramValues) {
+                paramValues = state.paramValues;
+            }
+            if (state.querySource === 'state') {
+                document.getElementById('sql-source').value = 'state';
+                switchSource();
+            }
             return true;
         }
     } catch (e) {
@@ -91,6 +107,7 @@ function initializeEditor(mode) {
     // Try to restore previous state, otherwise use default content
     if (!restoreSqlState()) {
         editor.setValue(DEFAULT_CONTENT);
+        loadQueryHistory();
     }
 
     const lastLine = editor.lineCount() - 1;
@@ -146,6 +163,79 @@ function toggleComment() {
     }
 }
 
+// Load the statements of earlier sessions, which the project keeps, for
+// Ctrl-Up and Ctrl-Down
+async function loadQueryHistory() {
+    try {
+        const res = await fetch('/api/sql/history?limit=50');
+        const data = await res.json();
+        if (!data.success || queryHistory.length > 0) return;
+        queryHistory = data.data.map(entry => entry.query).reverse()
+            .filter((query, i, all) => i === 0 || all[i - 1] !== query);
+        historyIndex = queryHistory.length;
+    } catch (e) {
+        console.warn('Failed to load query history:', e);
+    }
+}
+
+// Switch between querying the database and the views of the state store
+function switchSource() {
+    querySource = document.getElementById('sql-source').value;
+    const panel = document.getElementById('state-views');
+    document.getElementById('params-panel').style.display = 'none';
+    if (querySource !== 'state') {
+        panel.style.display = 'none';
+        saveSqlState();
+        return;
+    }
+    panel.style.display = 'flex';
+    loadStateViews();
+    saveSqlState();
+}
+
+// List the views of the state store, each of which can be browsed
+async function loadStateViews() {
+    const panel = document.getElementById('state-views');
+    try {
+        const res = await fetch('/api/state/views');
+        const data = await res.json();
+        if (!data.success) {
+            panel.textContent = data.message;
+            return;
+        }
+        panel.innerHTML = '<span class="state-views-label">Views</span>';
+        data.data.forEach(view => {
+            const button = document.createElement('button');
+            button.className = 'state-view';
+            button.textContent = view.name;
+            button.title = view.columns.join(', ');
+            button.addEventListener('click', () => browseStateView(view.name));
+            panel.appendChild(button);
+        });
+    } catch (e) {
+        panel.textContent = 'Failed to load the state store views';
+    }
+}
+
+async function browseStateView(name) {
+    const startTime = Date.now();
+    try {
+        const res = await fetch(`/api/state/views/${encodeURIComponent(name)}?limit=100`);
+        const data = await res.json();
+        if (!data.success) {
+            displayError(data.message);
+            return;
+        }
+        currentResults = data.data;
+        displayResults(data.data, 'SELECT', Date.now() - startTime);
+        document.getElementById('export-btn').style.display = 'none';
+        document.getElementById('results-info').textContent =
+            `${name}: ${data.data.rows.length} of ${data.data.total} row${data.data.total !== 1 ? 's' : ''}`;
+    } catch (err) {
+        displayError(err.message);
+    }
+}
+
 // Navigate through query history
 function navigateHistory(direction) {
     if (queryHistory.length === 0) return;
@@ -173,6 +263,18 @@ async function runQuery() {
         return;
     }
 
+    // Queries of the state store take no parameters
+    let params = null;
+    if (querySource !== 'state') {
+        try {
+            params = await resolveQueryParams(cleanQuery);
+        } catch (err) {
+            displayError(err.message);
+            return;
+        }
+        if (params === undefined) return;
+    }
+
     // Add to history
     if (queryHistory[queryHistory.length - 1] !== query) {
         queryHistory.push(query);
@@ -189,7 +291,9 @@ async function runQuery() {
         const res = await fetch('/api/sql', {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
-            body: JSON.stringify({ query: cleanQuery })
+            body: JSON.stringify(querySource === 'state'
+                ? { query: cleanQuery, source: 'state' }
+                : params ? { query: cleanQuery, params: params } : { query: cleanQuery })
         });
 
         const data = await res.json();
@@ -197,15 +301,112 @@ async function runQuery() {
 
         if (data.success) {
             currentResults = data.data;
+            lastQuery = params ? { query: cleanQuery, params: params } : { query: cleanQuery };
             displayResults(data.data, cleanQuery, elapsed);
+            // Exports run against the database
+            if (querySource === 'state') {
+                document.getElementById('export-btn').style.display = 'none';
+            }
         } else {
-            displayError(data.message);
+            displayError(data.message, errorHint(data));
         }
     } catch (err) {
         displayError(err.message);
     }
 }
 
+// Get the parameter values of a query. Returns null when it has no
+// parameters, and undefined while the values are being prompted for: the
+// first run shows an input per parameter, the next one sends them.
+async function resolveQueryParams(query) {
+    const res = await fetch('/api/sql/params', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ query: query })
+    });
+    const data = await res.json();
+    if (!data.success) {
+        throw new Error(data.message);
+    }
+
+    const panel = document.getElementById('params-panel');
+    const signature = data.data;
+    if (signature.length === 0) {
+        panel.style.display = 'none';
+        panel.dataset.signature = '';
+        return null;
+    }
+
+    const key = signature.map(p => p.placeholder).join(',');
+    if (panel.style.display === 'none' || panel.dataset.signature !== key) {
+        showParamsPanel(panel, signature, key);
+        return undefined;
+    }
+
+    const named = signature.some(p => p.placeholder.startsWith(':'));
+    const values = named ? {} : [];
+    signature.forEach((param, i) => {
+        const raw = panel.querySelector(`input[data-index="${i}"]`).value;
+        paramValues[param.placeholder] = raw;
+        const value = parseParamValue(raw, param.type);
+        if (named) {
+            values[param.placeholder.slice(1)] = value;
+        } else {
+            values.push(value);
+        }
+    });
+    saveSqlState();
+    return values;
+}
+
+function showParamsPanel(panel, signature, key) {
+    const fields = signature.map((param, i) => {
+        // Named placeholders already show their name
+        const showName = !param.placeholder.startsWith(':') && param.name !== 'p' + (i + 1);
+        const label = `<code>${escapeHtml(param.placeholder)}</code>` + (showName ? ` ${escapeHtml(param.name)}` : '');
+        const type = param.type ? `<span class="param-type">${escapeHtml(param.type)}</span>` : '';
+        return `<label class="param-field">
+            <span class="param-label">${label}${type}</span>
+            <input type="text" data-index="${i}" value="${escapeHtmlAttr(paramValues[param.placeholder] ?? '')}" spellcheck="false">
+        </label>`;
+    }).join('');
+
+    panel.innerHTML = `<div class="params-header">
+            <span>Query parameters</span>
+            <span class="params-hint">Enter to run • NULL for a null value</span>
+        </div>
+        <div class="params-grid">${fields}</div>`;
+    panel.dataset.signature = key;
+    panel.style.display = 'block';
+
+    panel.querySelectorAll('input').forEach(input => {
+        input.addEventListener('keydown', e => {
+            if (e.key === 'Enter') {
+                e.preventDefault();
+                runQuery();
+            }
+        });
+    });
+    panel.querySelector('input').focus();
+
+    document.getElementById('results-info').textContent = 'Enter the query parameters, then run the query again';
+}
+
+// Convert an entered parameter value to the JSON type it is bound as
+function parseParamValue(raw, type) {
+    if (raw === 'NULL') return null;
+
+    const lowerType = (type || '').toLowerCase();
+    const isNumber = /^-?\d+(\.\d+)?$/.test(raw.trim());
+    if (/bool/.test(lowerType) || !lowerType) {
+        if (raw === 'true' || raw === 'false') return raw === 'true';
+    }
+    if (isNumber && (!lowerType || /int|serial|numeric|decimal|real|double|float/.test(lowerType))) {
+        return Number(raw);
+    }
+    return raw;
+}
+
 // Detect query type
 function getQueryType(query) {
     const upper = query.trim().toUpperCase();
@@ -226,12 +427,22 @@ function getQueryType(query) {
     return 'OTHER';
 }
 
-// Format value for display with proper type handling
-function formatCellValue(value) {
+// Format value for display with proper type handling. kind is the kind of
+// the column from its database type, when the driver reports one.
+function formatCellValue(value, exact = false, kind = '') {
     if (value === null || value === undefined) {
         return '<span class="cell-null">NULL</span>';
     }
 
+    if ((exact || kind === 'integer' || kind === 'number') && typeof value === 'string') {
+        return `<span class="cell-number">${escapeHtml(value)}</span>`;
+    }
+
+    // Drivers read dates as timestamps at midnight
+    if (kind === 'date' && typeof value === 'string' && /^\d{4}-\d{2}-\d{2}T00:00:00/.test(value)) {
+        return `<span class="cell-date">${escapeHtml(value.slice(0, 10))}</span>`;
+    }
+
     if (typeof value === 'boolean') {
         return `<span class="cell-bool">${value ? 'true' : 'false'}</span>`;
     }
@@ -287,6 +498,7 @@ function formatCellValue(value) {
 function displayResults(data, query, elapsed) {
     const resultsBody = document.getElementById('results-body');
     const queryType = getQueryType(query);
+    document.getElementById('export-menu').style.display = 'none';
 
     // Handle non-SELECT queries
     if (!data || !data.rows || data.rows.length === 0) {
@@ -317,6 +529,12 @@ function displayResults(data, query, elapsed) {
                 <div class="success-details">Execution time: ${elapsed}ms</div>
             </div>
         `;
+        if (data && data.migration) {
+            const recorded = document.createElement('div');
+            recorded.className = 'success-details';
+            recorded.textContent = `Recorded as migration ${data.migration}`;
+            resultsBody.querySelector('.success-message').appendChild(recorded);
+        }
         document.getElementById('export-btn').style.display = 'none';
         return;
     }
@@ -329,10 +547,17 @@ function displayResults(data, query, elapsed) {
         ? data.columns.map(col => col.name || col)
         : Object.keys(data.rows[0]);
 
+    // 64-bit integers and decimals that would lose digits come as text
+    const exactColumns = new Set((data.columns || []).filter(col => col.exact_number).map(col => col.name));
+    const columnInfo = new Map((data.columns || []).filter(col => col.name).map(col => [col.name, col]));
+    const kindOf = col => (columnInfo.get(col) || {}).kind || '';
+    const isNumeric = col => kindOf(col) === 'integer' || kindOf(col) === 'number';
+
     let html = '<table class="results-table"><thead><tr>';
     html += '<th class="row-num">#</th>';
     columns.forEach(col => {
-        html += `<th>${escapeHtml(col)}</th>`;
+        const type = (columnInfo.get(col) || {}).type || '';
+        html += `<th class="${isNumeric(col) ? 'cell-right' : ''}" title="${escapeHtmlAttr(type)}">${escapeHtml(col)}</th>`;
     });
     html += '</tr></thead><tbody>';
 
@@ -341,7 +566,7 @@ function displayResults(data, query, elapsed) {
         html += `<td class="row-num">${idx + 1}</td>`;
         columns.forEach(col => {
             const value = row[col];
-            html += `<td>${formatCellValue(value)}</td>`;
+            html += `<td class="${isNumeric(col) ? 'cell-right' : ''}">${formatCellValue(value, exactColumns.has(col), kindOf(col))}</td>`;
         });
         html += '</tr>';
     });
@@ -362,14 +587,15 @@ function displayResults(data, query, elapsed) {
     });
 }
 
-function displayError(message) {
+function displayError(message, hint) {
     document.getElementById('results-info').textContent = 'Query failed';
+    document.getElementById('export-menu').style.display = 'none';
     document.getElementById('results-body').innerHTML = `
         <div class="error-message">
             <div class="error-icon">✕</div>
             <div class="error-title">Query Error</div>
             <div class="error-text">${escapeHtml(message)}</div>
-            <div class="error-hint">Check your SQL syntax and try again</div>
+            <div class="error-hint">${escapeHtml(hint || 'Check your SQL syntax and try again')}</div>
         </div>
     `;
     document.getElementById('export-btn').style.display = 'none';
@@ -380,30 +606,58 @@ function clearEditor() {
     editor.focus();
 }
 
-function exportResults() {
-    if (!currentResults || !currentResults.rows) return;
+function toggleExportMenu() {
+    const menu = document.getElementById('export-menu');
+    if (menu.style.display !== 'none') {
+        menu.style.display = 'none';
+        return;
+    }
+
+    const columns = (currentResults.columns || []).map(col => col.name || col);
+    document.getElementById('export-columns').innerHTML = columns.map(col =>
+        `<label><input type="checkbox" value="${escapeHtmlAttr(col)}" checked> ${escapeHtml(col)}</label>`
+    ).join('');
+    menu.style.display = 'flex';
+}
+
+// Download the results of the last query. The server runs it again and
+// streams all of its rows, not only the ones shown.
+async function exportResults() {
+    if (!lastQuery) return;
 
-    const rows = currentResults.rows;
-    const columns = currentResults.columns && currentResults.columns.length > 0
-        ? currentResults.columns.map(col => col.name || col)
-        : Object.keys(rows[0]);
+    const format = document.getElementById('export-format').value;
+    const columns = Array.from(document.querySelectorAll('#export-columns input:checked')).map(input => input.value);
+    if (columns.length === 0) {
+        showToast('Select at least one column', 'error');
+        return;
+    }
+    const limit = parseInt(document.getElementById('export-limit').value, 10) || 0;
 
-    let csv = columns.join(',') + '\n';
-    rows.forEach(row => {
-        const values = columns.map(col => {
-            const val = row[col];
-            return val === null ? '' : `"${String(val).replace(/"/g, '""')}"`;
+    try {
+        const res = await fetch('/api/sql/export', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ ...lastQuery, format: format, columns: columns, limit: limit })
         });
-        csv += values.join(',') + '\n';
-    });
+        if (!res.ok) {
+            const data = await res.json();
+            showToast(data.message || 'Export failed', 'error');
+            return;
+        }
 
-    const blob = new Blob([csv], { type: 'text/csv' });
-    const url = URL.createObjectURL(blob);
-    const a = document.createElement('a');
-    a.href = url;
-    a.download = `query_results_${Date.now()}.csv`;
-    a.click();
-    URL.revokeObjectURL(url);
+        const disposition = res.headers.get('Content-Disposition') || '';
+        const match = disposition.match(/filename="([^"]+)"/);
+        const blob = await res.blob();
+        const url = URL.createObjectURL(blob);
+        const a = document.createElement('a');
+        a.href = url;
+        a.download = match ? match[1] : `query_results_${Date.now()}`;
+        a.click();
+        URL.revokeObjectURL(url);
+        document.getElementById('export-menu').style.display = 'none';
+    } catch (err) {
+        showToast(err.message, 'error');
+    }
 }
 
 function setupResize() {
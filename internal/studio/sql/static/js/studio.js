@@ -5,12 +5,20 @@ const state = {
     currentTable: null,
     data: null,
     changes: new Map(),
+    // Version of each edited row when its first edit was made
+    versions: new Map(),
     page: 1,
     limit: 50,
+    // Key each page starts after, by page, for tables ordered by a
+    // sortable primary key; pages without one are read by offset
+    cursors: {},
     tablesCache: null,
     foreignKeys: new Map(),
     filters: [],
+    includeDeleted: false,
     scrollPosition: 0,
+    // Time zone instants are shown and entered in, '' for the browser's
+    timeZone: '',
 
     // Persist state to sessionStorage
     save() {
@@ -19,9 +27,12 @@ const state = {
             page: this.page,
             limit: this.limit,
             filters: this.filters,
+            includeDeleted: this.includeDeleted,
             scrollPosition: window.scrollY || 0,
+            timeZone: this.timeZone,
             // Convert Map to array for JSON serialization
-            changes: Array.from(this.changes.entries())
+            changes: Array.from(this.changes.entries()),
+            versions: Array.from(this.versions.entries())
         };
         try {
             sessionStorage.setItem(STORAGE_KEY, JSON.stringify(toSave));
@@ -40,11 +51,16 @@ const state = {
                 this.page = parsed.page || 1;
                 this.limit = parsed.limit || 50;
                 this.filters = parsed.filters || [];
+                this.includeDeleted = parsed.includeDeleted || false;
                 this.scrollPosition = parsed.scrollPosition || 0;
+                this.timeZone = parsed.timeZone || '';
                 // Restore changes Map
                 if (parsed.changes && Array.isArray(parsed.changes)) {
                     this.changes = new Map(parsed.changes);
                 }
+                if (parsed.versions && Array.isArray(parsed.versions)) {
+                    this.versions = new Map(parsed.versions);
+                }
                 return true;
             }
         } catch (e) {
@@ -56,6 +72,7 @@ const state = {
     // Clear persisted state
     clear() {
         this.changes.clear();
+        this.versions.clear();
         this.filters = [];
         sessionStorage.removeItem(STORAGE_KEY);
     }
@@ -65,6 +82,7 @@ const state = {
 document.addEventListener('DOMContentLoaded', async () => {
     setupEventListeners();
     await loadTables();
+    loadMaterializedViews();
 
     // Restore previous state if available
     if (state.restore()) {
@@ -106,22 +124,154 @@ document.addEventListener('DOMContentLoaded', async () => {
 function setupEventListeners() {
     const saveBtn = document.getElementById('save-btn');
     const addBtn = document.getElementById('add-btn');
+    const pasteBtn = document.getElementById('paste-btn');
     const refreshBtn = document.getElementById('refresh-btn');
+    const undoBtn = document.getElementById('undo-btn');
+    const redoBtn = document.getElementById('redo-btn');
     const deleteSelectedBtn = document.getElementById('delete-selected-btn');
+    const restoreSelectedBtn = document.getElementById('restore-selected-btn');
+    const showDeletedBtn = document.getElementById('show-deleted-btn');
     const prevBtn = document.getElementById('prev-btn');
     const nextBtn = document.getElementById('next-btn');
     const searchTables = document.getElementById('search-tables');
+    const timeZoneSelect = document.getElementById('timezone-select');
 
     if (saveBtn) saveBtn.addEventListener('click', saveChanges);
     if (addBtn) addBtn.addEventListener('click', showAddRowDialog);
+    if (pasteBtn) pasteBtn.addEventListener('click', showPasteDialog);
     if (refreshBtn) refreshBtn.addEventListener('click', refreshData);
+    if (undoBtn) undoBtn.addEventListener('click', () => stepJournal('undo'));
+    if (redoBtn) redoBtn.addEventListener('click', () => stepJournal('redo'));
     if (deleteSelectedBtn) deleteSelectedBtn.addEventListener('click', deleteSelected);
+    if (restoreSelectedBtn) restoreSelectedBtn.addEventListener('click', restoreSelected);
+    if (showDeletedBtn) showDeletedBtn.addEventListener('click', toggleShowDeleted);
     if (prevBtn) prevBtn.addEventListener('click', () => changePage(-1));
     if (nextBtn) nextBtn.addEventListener('click', () => changePage(1));
     if (searchTables) searchTables.addEventListener('input', debounce(filterTables, 200));
+    if (timeZoneSelect) setupTimeZoneSelect(timeZoneSelect);
+    setupAdminButton();
     document.addEventListener('keydown', handleKeyDown);
 }
 
+// Show the lock button when columns are masked and an admin token can
+// unlock them. Masking is enforced by the server; this only asks for the token.
+async function setupAdminButton() {
+    const button = document.getElementById('admin-btn');
+    if (!button) return;
+    try {
+        const res = await fetch('/api/admin');
+        const json = await res.json();
+        if (!json.success || !json.data.masking || !json.data.available) return;
+        const admin = json.data.admin;
+        button.style.display = '';
+        button.title = admin ? 'Hide masked columns again' : 'Show masked columns';
+        button.innerHTML = `<span class="iconify" data-icon="${admin ? 'mdi:lock-open-variant' : 'mdi:lock'}"></span>`;
+        button.onclick = admin ? lockAdmin : showUnlockDialog;
+    } catch (error) {
+        console.error('Failed to check the admin role:', error);
+    }
+}
+
+function showUnlockDialog() {
+    const modal = document.createElement('div');
+    modal.className = 'custom-modal show';
+    modal.innerHTML = `
+        <div class="custom-modal-content">
+            <div class="custom-modal-header">
+                <div class="custom-modal-title">Show masked columns</div>
+                <button class="custom-modal-close" onclick="this.closest('.custom-modal').remove()">×</button>
+            </div>
+            <div class="custom-modal-body">
+                <div class="form-group">
+                    <input type="password" id="admin-token" class="form-input" autocomplete="off" placeholder="Admin token">
+                    <div class="form-hint">The token set in the environment variable named by studio.admin_token_env.</div>
+                </div>
+            </div>
+            <div class="custom-modal-footer">
+                <button class="btn btn-secondary" onclick="this.closest('.custom-modal').remove()">Cancel</button>
+                <button class="btn btn-primary" id="admin-unlock-btn">Unlock</button>
+            </div>
+        </div>
+    `;
+    document.body.appendChild(modal);
+    const input = document.getElementById('admin-token');
+    input.focus();
+
+    const unlock = async () => {
+        try {
+            const res = await fetch('/api/admin', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ token: input.value })
+            });
+            const json = await res.json();
+            if (!json.success) {
+                showToast(json.message || 'Invalid admin token', 'error');
+                return;
+            }
+            modal.remove();
+            showToast('Masked columns unlocked', 'success');
+            setupAdminButton();
+            refreshData();
+        } catch (error) {
+            showToast('Failed to unlock: ' + error.message, 'error');
+        }
+    };
+    document.getElementById('admin-unlock-btn').onclick = unlock;
+    input.addEventListener('keydown', (e) => { if (e.key === 'Enter') unlock(); });
+}
+
+async function lockAdmin() {
+    try {
+        await fetch('/api/admin', { method: 'DELETE' });
+        showToast('Masked columns hidden', 'success');
+        setupAdminButton();
+        refreshData();
+    } catch (error) {
+        showToast('Failed to lock: ' + error.message, 'error');
+    }
+}
+
+// Fill the time zone picker and show the table again in a zone once picked
+function setupTimeZoneSelect(select) {
+    const browserZone = Intl.DateTimeFormat().resolvedOptions().timeZone;
+    const zones = typeof Intl.supportedValuesOf === 'function' ? Intl.supportedValuesOf('timeZone') : [];
+    select.add(new Option(`Browser (${browserZone})`, ''));
+    select.add(new Option('UTC', 'UTC'));
+    zones.filter(zone => zone !== 'UTC').forEach(zone => select.add(new Option(zone, zone)));
+    select.value = state.timeZone;
+
+    select.addEventListener('change', () => {
+        state.timeZone = select.value;
+        state.save();
+        if (state.data) renderDataGrid(state.data);
+    });
+}
+
+// Time zone instants are shown and entered in
+function studioTimeZone() {
+    return state.timeZone || Intl.DateTimeFormat().resolvedOptions().timeZone;
+}
+
+// Headers of requests that write rows, naming the time zone timestamps
+// without an offset were entered in
+function writeHeaders() {
+    return { 'Content-Type': 'application/json', 'X-Time-Zone': studioTimeZone() };
+}
+
+// Show an instant sent in UTC as a date and time in the session's zone
+function zonedTime(value) {
+    const date = new Date(value);
+    if (isNaN(date)) return value;
+    const parts = new Intl.DateTimeFormat('en-CA', {
+        timeZone: studioTimeZone(),
+        year: 'numeric', month: '2-digit', day: '2-digit',
+        hour: '2-digit', minute: '2-digit', second: '2-digit', hourCycle: 'h23'
+    }).formatToParts(date);
+    const part = type => parts.find(p => p.type === type).value;
+    return `${part('year')}-${part('month')}-${part('day')} ${part('hour')}:${part('minute')}:${part('second')}`;
+}
+
 function debounce(func, wait) {
     let timeout;
     return function (...args) {
@@ -130,50 +280,257 @@ function debounce(func, wait) {
     };
 }
 
-// Load tables
-async function loadTables() {
+// The table list is loaded a page at a time, and the rows of a table are
+// counted once it scrolls into view
+const TABLES_PAGE_SIZE = 100;
+let tablesTotal = 0;
+let tablesRefresh = false;
+const pendingCounts = new Set();
+let countsTimer = null;
+const countObserver = 'IntersectionObserver' in window ? new IntersectionObserver(entries => {
+    entries.forEach(entry => {
+        if (entry.isIntersecting) {
+            countObserver.unobserve(entry.target);
+            if (entry.target.dataset.countTable) {
+                pendingCounts.add(entry.target.dataset.countTable);
+            } else {
+                loadTables(tablesRefresh, true);
+            }
+        }
+    });
+    clearTimeout(countsTimer);
+    countsTimer = setTimeout(fetchTableCounts, 100);
+}, { rootMargin: '200px' }) : null;
+
+// Load tables matching the search box. refresh counts rows again rather
+// than reuse recent counts; more appends the next page.
+async function loadTables(refresh = false, more = false) {
+    const search = document.getElementById('search-tables');
+    const offset = more && state.tablesCache ? state.tablesCache.length : 0;
+    const params = new URLSearchParams({
+        search: search ? search.value.trim() : '',
+        offset,
+        limit: TABLES_PAGE_SIZE,
+        counts: 'false',
+    });
     try {
-        const res = await fetch('/api/tables');
+        const res = await fetch(`/api/tables?${params}`);
         const json = await res.json();
 
         if (json.success) {
-            state.tablesCache = json.data;
-            renderTablesList(json.data);
+            state.tablesCache = more && state.tablesCache ? state.tablesCache.concat(json.data.tables) : json.data.tables;
+            tablesTotal = json.data.total;
+            tablesRefresh = refresh;
+            renderTablesList(state.tablesCache);
         }
     } catch (err) {
         console.error('Failed to load tables:', err);
     }
 }
 
+// Fetch the row counts of the tables that came into view
+async function fetchTableCounts() {
+    if (pendingCounts.size === 0) return;
+    const params = new URLSearchParams();
+    pendingCounts.forEach(name => params.append('tables', name));
+    pendingCounts.clear();
+    if (tablesRefresh) params.set('refresh', 'true');
+
+    try {
+        const res = await fetch(`/api/table-counts?${params}`);
+        const json = await res.json();
+        if (!json.success) throw new Error(json.message);
+        Object.entries(json.data).forEach(([name, count]) => setTableCount(name, count.row_count, count.row_count_estimated));
+    } catch (err) {
+        console.error('Failed to count rows:', err);
+    }
+}
+
 // Render tables
 function renderTablesList(tables) {
     const container = document.getElementById('tables-list');
+    if (countObserver) countObserver.disconnect();
 
     if (!tables || tables.length === 0) {
         container.innerHTML = '<div style="padding: 12px; color: #666; font-size: 12px;">No models found</div>';
         return;
     }
 
-    container.innerHTML = tables.map(table => `
+    const remaining = tablesTotal - tables.length;
+    container.innerHTML = tables.map(table => {
+        const partitions = table.partitions || [];
+        if (partitions.length === 0) {
+            return `
         <div class="table-item" data-table="${table.name}" onclick="selectTable('${table.name}')" title="${table.name}">
             <span class="table-item-name">${table.name}</span>
-            <span class="table-count">${table.row_count}</span>
+            ${tableCount(table)}
+        </div>
+    `;
+        }
+
+        // Partitioned tables count the rows of their partitions, which are
+        // listed under them
+        const expanded = expandedPartitions.has(table.name);
+        return `
+        <div class="table-item" data-table="${table.name}" onclick="selectTable('${table.name}')" title="${table.name} (${partitions.length} partitions)">
+            <span class="partition-toggle" onclick="togglePartitions(event, '${table.name}')">
+                <span class="iconify" data-icon="${expanded ? 'mdi:chevron-down' : 'mdi:chevron-right'}"></span>
+            </span>
+            <span class="table-item-name">${table.name}</span>
+            ${tableCount(table)}
+        </div>
+        <div class="partition-list" style="display: ${expanded ? 'block' : 'none'};">
+            ${partitions.map(partition => `
+            <div class="table-item partition-item" data-table="${partition.name}" onclick="selectTable('${partition.name}')" title="${escapeHtmlAttr(partition.name + ' ' + partition.bound)}">
+                <span class="table-item-name">${partition.name}</span>
+                ${tableCount(partition)}
+            </div>`).join('')}
         </div>
-    `).join('');
+    `;
+    }).join('') + (remaining > 0 ? `
+        <div class="tables-more" onclick="loadTables(tablesRefresh, true)">Show ${Math.min(remaining, TABLES_PAGE_SIZE)} more of ${remaining}</div>
+    ` : '');
+
+    container.querySelectorAll('.table-count[data-count-table], .tables-more').forEach(el => {
+        if (countObserver) {
+            countObserver.observe(el);
+        } else if (el.dataset.countTable) {
+            pendingCounts.add(el.dataset.countTable);
+        }
+    });
+    if (!countObserver) fetchTableCounts();
 }
 
-// Filter tables
-function filterTables(e) {
-    const search = e.target.value.toLowerCase();
-    if (!state.tablesCache) return;
+// Row counts read from the database's statistics are marked with ~ and
+// counted exactly when clicked
+function tableCount(table) {
+    if (table.row_count === null || table.row_count === undefined) {
+        return `<span class="table-count table-count-pending" data-count-table="${escapeHtmlAttr(table.name)}">...</span>`;
+    }
+    if (!table.row_count_estimated) {
+        return `<span class="table-count">${table.row_count}</span>`;
+    }
+    const compact = new Intl.NumberFormat(undefined, { notation: 'compact' }).format(table.row_count);
+    return `<span class="table-count table-count-estimated" title="About ${table.row_count} rows, click to count" onclick="countTableRows(event, '${table.name}')">~${compact}</span>`;
+}
 
-    if (!search) {
-        renderTablesList(state.tablesCache);
-        return;
+async function countTableRows(event, tableName) {
+    event.stopPropagation();
+    const el = event.currentTarget;
+    const label = el.textContent;
+    el.textContent = 'Counting...';
+    try {
+        const res = await fetch(`/api/tables/${encodeURIComponent(tableName)}/count`);
+        const json = await res.json();
+        if (!json.success) throw new Error(json.message);
+        const count = json.data.row_count;
+        setTableCount(tableName, count, false);
+
+        if (state.currentTable === tableName && state.data && state.data.total_estimated) {
+            state.data.total = count;
+            state.data.total_estimated = false;
+            updateRowCount(state.data);
+            updatePagination(state.data);
+        }
+    } catch (err) {
+        el.textContent = label;
+        showToast('Failed to count rows: ' + err.message, 'error');
+    }
+}
+
+// Show the row count of a table wherever the list has it
+function setTableCount(tableName, count, estimated) {
+    (state.tablesCache || []).flatMap(t => [t, ...(t.partitions || [])])
+        .filter(t => t.name === tableName)
+        .forEach(t => { t.row_count = count; t.row_count_estimated = !!estimated; });
+    document.querySelectorAll(`.table-item[data-table="${CSS.escape(tableName)}"] .table-count`).forEach(span => {
+        span.outerHTML = tableCount({ name: tableName, row_count: count, row_count_estimated: estimated });
+    });
+}
+
+const expandedPartitions = new Set();
+
+function togglePartitions(event, tableName) {
+    event.stopPropagation();
+    const expanded = !expandedPartitions.has(tableName);
+    if (expanded) {
+        expandedPartitions.add(tableName);
+    } else {
+        expandedPartitions.delete(tableName);
+    }
+
+    const item = event.currentTarget.closest('.table-item');
+    item.nextElementSibling.style.display = expanded ? 'block' : 'none';
+    event.currentTarget.innerHTML = `<span class="iconify" data-icon="${expanded ? 'mdi:chevron-down' : 'mdi:chevron-right'}"></span>`;
+}
+
+// Load materialized views, the section stays hidden when there are none
+async function loadMaterializedViews() {
+    try {
+        const json = await apiCall('/api/matviews');
+        renderMaterializedViews(json.data || []);
+    } catch (err) {
+        console.error('Failed to load materialized views:', err);
     }
+}
 
-    const filtered = state.tablesCache.filter(t => t.name.toLowerCase().includes(search));
-    renderTablesList(filtered);
+function renderMaterializedViews(views) {
+    const section = document.getElementById('matviews-section');
+    const container = document.getElementById('matviews-list');
+    if (!section || !container) return;
+
+    section.style.display = views.length > 0 ? '' : 'none';
+    container.innerHTML = views.map(view => {
+        const last = view.last_refresh;
+        let status = view.populated ? 'never refreshed by flash' : 'not populated';
+        if (last) {
+            status = `${last.success ? 'refreshed' : 'refresh failed'} ${new Date(last.started_at).toLocaleString()} (${last.duration_ms}ms)`;
+        }
+        const title = `${view.name}\n~${view.row_estimate} rows, ${formatBytes(view.size_bytes)}\n${status}\n\n${view.definition}`;
+        const name = escapeHtmlAttr(view.name);
+        const concurrent = view.populated && view.has_unique_index
+            ? `<button class="matview-refresh-btn" onclick="refreshMaterializedView('${name}', true)" title="Refresh concurrently (keeps the view readable)">
+                   <span class="iconify" data-icon="mdi:refresh-auto"></span>
+               </button>`
+            : '';
+        return `
+            <div class="table-item matview-item${last && !last.success ? ' matview-failed' : ''}" title="${escapeHtmlAttr(title)}">
+                <span class="table-item-name">${escapeHtml(view.name)}</span>
+                ${concurrent}
+                <button class="matview-refresh-btn" onclick="refreshMaterializedView('${name}', false)" title="Refresh">
+                    <span class="iconify" data-icon="mdi:refresh"></span>
+                </button>
+            </div>
+        `;
+    }).join('');
+}
+
+async function refreshMaterializedView(name, concurrently) {
+    showToast(`Refreshing ${name}...`, 'info');
+    try {
+        const json = await apiCall(`/api/matviews/${encodeURIComponent(name)}/refresh`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ concurrently })
+        });
+        showToast(`Refreshed ${name} in ${json.data.duration_ms}ms`, 'success');
+    } catch (err) {
+        showToast(err.message, 'error', 5000);
+    }
+    loadMaterializedViews();
+}
+
+function formatBytes(bytes) {
+    if (!bytes) return '0 B';
+    const units = ['B', 'KB', 'MB', 'GB', 'TB'];
+    const i = Math.min(Math.floor(Math.log(bytes) / Math.log(1024)), units.length - 1);
+    return `${(bytes / Math.pow(1024, i)).toFixed(i === 0 ? 0 : 1)} ${units[i]}`;
+}
+
+// Filter tables
+// The server searches the names, since the list only holds a page of them
+function filterTables() {
+    loadTables();
 }
 
 // Select table
@@ -181,6 +538,7 @@ async function selectTable(tableName) {
     state.currentTable = tableName;
     state.page = 1;
     state.changes.clear();
+    state.versions.clear();
 
     document.getElementById('current-table').textContent = tableName;
     document.getElementById('save-btn').style.display = 'none';
@@ -210,6 +568,9 @@ async function loadTableData() {
     try {
         // Build URL with filters
         let url = `/api/tables/${state.currentTable}?page=${state.page}&limit=${state.limit}`;
+        if (state.page > 1 && state.cursors[state.page] !== undefined) {
+            url += `&after=${encodeURIComponent(state.cursors[state.page])}`;
+        }
 
         // Add filters to request if any exist
         if (state.filters && state.filters.length > 0) {
@@ -217,14 +578,21 @@ async function loadTableData() {
             url += `&filters=${filtersJSON}`;
         }
 
+        if (state.includeDeleted) {
+            url += '&include_deleted=true';
+        }
+
         const res = await fetch(url);
         const json = await res.json();
 
         if (json.success) {
             state.data = json.data;
-            const rowCount = json.data.rows ? json.data.rows.length : 0;
-            const totalFiltered = json.data.total || 0;
-            document.getElementById('row-count').textContent = `${rowCount} of ${totalFiltered}`;
+            if (state.page === 1) state.cursors = {};
+            if (json.data.next_cursor) {
+                state.cursors[state.page + 1] = json.data.next_cursor;
+            }
+            updateRowCount(json.data);
+            updateSoftDeleteControls(json.data);
 
             // Deduplicate columns before setting global
             if (json.data.columns) {
@@ -324,9 +692,11 @@ function renderDataGrid(data) {
                     <tr>
                         <th><input type="checkbox" id="select-all" onchange="toggleSelectAll(this)"></th>
                         ${orderedCols.map(col => `
-                            <th title="${col.name}">
+                            <th title="${col.masked ? `${col.name} is masked` : col.encrypted ? `${col.name} is encrypted by the generated clients` : col.name}">
                                 ${col.name}
                                 <span class="type-badge">${col.type}</span>
+                                ${col.masked ? '<span class="type-badge masked-badge">masked</span>' : ''}
+                                ${col.encrypted ? '<span class="type-badge encrypted-badge">encrypted</span>' : ''}
                             </th>
                         `).join('')}
                     </tr>
@@ -345,30 +715,47 @@ function renderDataGrid(data) {
 // Render row
 function renderRow(row, idx, columns) {
     const rowId = row.id || idx;
+    const softDeleteColumn = state.data && state.data.soft_delete_column;
+    const isDeleted = softDeleteColumn && row[softDeleteColumn] !== null && row[softDeleteColumn] !== undefined;
 
     return `
-        <tr>
+        <tr${isDeleted ? ' class="row-deleted"' : ''}>
             <td>
                 <input type="checkbox" class="row-checkbox" data-row="${rowId}" style="cursor: pointer;" onchange="toggleRowSelection(this)">
             </td>
             ${columns.map(col => {
         const fk = state.foreignKeys.get(col.name);
-        const value = row[col.name];
-        const valueStr = String(value || '');
+        let value = row[col.name];
+        let valueStr = String(value || '');
+        if (col.time_zone && typeof value === 'string') {
+            // Instants come in UTC; the database session's offset is kept aside
+            const offsets = state.data.time_offsets && state.data.time_offsets[idx];
+            const offset = offsets && offsets[col.name];
+            valueStr = `${value}${offset ? ` (read with offset ${offset})` : ''}, shown in ${studioTimeZone()}`;
+            value = zonedTime(value);
+        }
 
         // FK cells have special click handler, others are editable
-        const cellClass = fk && value ? 'cell value-fk' : 'cell';
+        let cellClass = fk && value ? 'cell value-fk' : 'cell';
+        if (col.read_only) cellClass += ' cell-readonly';
+        // Masked values are hidden by the server; editing one would overwrite the secret
+        if (col.masked) cellClass += ' cell-readonly cell-masked';
+        // Encrypted values come as the length of their ciphertext
+        if (col.encrypted) cellClass += ' cell-encrypted';
         const onClick = fk && value ?
             `onclick="event.stopPropagation(); navigateToForeignKey('${fk.table}', '${fk.column}', '${value}'); return false;"` :
             `onclick="editCell(this)"`;
 
         const titleText = fk ? `Click to view ${fk.table}.${fk.column} = ${value}` : valueStr;
+        // Keys are identifiers rather than amounts, so they aren't grouped
+        const isNumber = !fk && !col.primary_key &&
+            (typeof value === 'number' || (col.exact_number && typeof value === 'string'));
 
         return `
                     <td class="${cellClass}" data-row="${rowId}" data-column="${col.name}" 
                         ${onClick} 
                         title="${titleText.replace(/"/g, '&quot;')}">
-                        ${formatValue(value, fk)}
+                        ${isNumber ? formatNumber(value) : formatValue(value, fk)}
                     </td>
                 `;
     }).join('')}
@@ -474,6 +861,60 @@ function toggleRowSelection(checkbox) {
 
     const anyChecked = document.querySelectorAll('.row-checkbox:checked').length > 0;
     document.getElementById('delete-selected-btn').style.display = anyChecked ? 'block' : 'none';
+
+    const anyDeletedChecked = document.querySelectorAll('tr.row-deleted .row-checkbox:checked').length > 0;
+    document.getElementById('restore-selected-btn').style.display = anyDeletedChecked ? 'block' : 'none';
+}
+
+// Show the soft-delete toggle only for tables with the configured column
+function updateSoftDeleteControls(data) {
+    const btn = document.getElementById('show-deleted-btn');
+    const restoreBtn = document.getElementById('restore-selected-btn');
+    if (!btn) return;
+
+    if (!data.soft_delete_column) {
+        btn.style.display = 'none';
+        if (restoreBtn) restoreBtn.style.display = 'none';
+        return;
+    }
+
+    btn.style.display = 'block';
+    btn.classList.toggle('active', state.includeDeleted);
+    btn.querySelector('.btn-text').textContent = state.includeDeleted ? 'Hide deleted' : 'Show deleted';
+}
+
+function toggleShowDeleted() {
+    state.includeDeleted = !state.includeDeleted;
+    state.page = 1;
+    state.save();
+    loadTableData();
+}
+
+// Restore selected soft-deleted rows
+async function restoreSelected() {
+    const checked = document.querySelectorAll('tr.row-deleted .row-checkbox:checked');
+    if (checked.length === 0) return;
+
+    const rowIds = Array.from(checked).map(cb => cb.dataset.row);
+
+    try {
+        const res = await fetch(`/api/tables/${state.currentTable}/restore`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ row_ids: rowIds })
+        });
+
+        const json = await res.json();
+
+        if (json.success) {
+            showModal('Success', json.message, 'success');
+            refreshData();
+        } else {
+            showModal('Error', json.message, 'error');
+        }
+    } catch (err) {
+        showModal('Error', 'Failed to restore: ' + err.message, 'error');
+    }
 }
 
 // Delete selected rows
@@ -579,6 +1020,27 @@ function formatValue(value, fk = null) {
     return `<span class="value-string" data-original-value="${escapeHtmlAttr(strValue)}">${escapeHtml(strValue)}</span>`;
 }
 
+// Format a number grouped the way the browser's locale writes it. 64-bit
+// integers and decimals come as text so no digits are lost; they are only
+// grouped when the browser formats them without rounding. The exact text
+// is kept for editing.
+function formatNumber(value) {
+    const text = String(value);
+    let shown = text;
+    const match = /^-?\d+(?:\.(\d+))?$/.exec(text);
+    if (match) {
+        const digits = match[1] ? match[1].length : 0;
+        try {
+            const grouped = new Intl.NumberFormat(undefined, {
+                minimumFractionDigits: digits,
+                maximumFractionDigits: digits,
+            }).format(text);
+            if (grouped.replace(/\D/g, '') === text.replace(/\D/g, '')) shown = grouped;
+        } catch { }
+    }
+    return `<span class="value-number" data-original-value="${escapeHtmlAttr(text)}">${escapeHtml(shown)}</span>`;
+}
+
 // Convert byte array to UUID string (8-4-4-4-12 format)
 function bytesToUuid(bytes) {
     if (!bytes || bytes.length !== 16) return '';
@@ -591,6 +1053,34 @@ function bytesToUuid(bytes) {
 function handleKeyDown(e) {
     if (e.key === 'ArrowLeft') collapseSidebar();
     if (e.key === 'ArrowRight') expandSidebar();
+
+    // Undo and redo saved edits, unless a field is being edited
+    const editing = e.target.closest && e.target.closest('input, textarea, select, [contenteditable]');
+    if ((e.ctrlKey || e.metaKey) && e.key.toLowerCase() === 'z' && !editing) {
+        e.preventDefault();
+        stepJournal(e.shiftKey ? 'redo' : 'undo');
+    }
+}
+
+// Undo or redo the latest saved data edit
+async function stepJournal(direction) {
+    try {
+        const res = await fetch(`/api/journal/${direction}`, { method: 'POST' });
+        const json = await res.json();
+
+        if (json.success) {
+            const verb = direction === 'undo' ? 'Undid' : 'Redid';
+            showToast(`${verb}: ${json.data.description} in ${json.data.table}`, 'success');
+            if (state.currentTable === json.data.table) refreshData();
+        } else if (res.status === 409) {
+            const rows = json.data.conflicts.map(c => escapeHtml(c.row_id)).join(', ');
+            showModal('Error', `${escapeHtml(json.message)}. Rows changed since: ${rows}`, 'error');
+        } else {
+            showToast(json.message, 'info');
+        }
+    } catch (err) {
+        showModal('Error', `Failed to ${direction}: ` + err.message, 'error');
+    }
 }
 
 function collapseSidebar() {
@@ -607,7 +1097,7 @@ function expandSidebar() {
 
 // Edit cell - Fixed to use original value, not truncated display text
 function editCell(cell) {
-    if (cell.querySelector('textarea') || cell.classList.contains('value-fk')) return;
+    if (cell.classList.contains('cell-editing') || cell.classList.contains('value-fk') || cell.classList.contains('cell-readonly')) return;
 
     const rowId = cell.dataset.row;
     const column = cell.dataset.column;
@@ -623,49 +1113,119 @@ function editCell(cell) {
         originalValue = cell.textContent.trim();
     }
 
-    // Handle NULL display
-    if (originalValue === 'NULL' || cell.querySelector('.value-null')) {
-        originalValue = '';
-    }
-
-    const textarea = document.createElement('textarea');
-    textarea.value = originalValue;
+    // NULL is kept apart from an empty string, which is a value of its own
+    const isNull = !!cell.querySelector('.value-null');
+    const col = columnInfo(column);
+    const editor = cellEditor(col, isNull ? '' : originalValue);
 
     // Store original for cancel operation
-    const storedOriginal = originalValue;
+    const storedOriginal = isNull ? null : originalValue;
+    let edited = false;
+    let done = false;
+    const finish = (value) => {
+        if (done) return;
+        done = true;
+        saveCell(cell, value, rowId, column, storedOriginal);
+    };
 
-    textarea.addEventListener('blur', () => saveCell(cell, textarea, rowId, column, storedOriginal));
-    textarea.addEventListener('keydown', (e) => {
-        // Ctrl+Enter or Cmd+Enter to save
-        if ((e.ctrlKey || e.metaKey) && e.key === 'Enter') {
+    const input = editor.element;
+    input.addEventListener('input', () => { edited = true; });
+    input.addEventListener('change', () => {
+        edited = true;
+        // Picking from a checkbox or a list is a complete edit
+        if (editor.commitOnChange) finish(editor.read());
+    });
+    input.addEventListener('blur', () => finish(edited ? editor.read() : storedOriginal));
+    input.addEventListener('keydown', (e) => {
+        // Ctrl+Enter or Cmd+Enter to save, Enter alone outside of a textarea
+        if (e.key === 'Enter' && (e.ctrlKey || e.metaKey || input.tagName !== 'TEXTAREA')) {
             e.preventDefault();
-            textarea.blur();
+            input.blur();
         }
         // Escape to cancel - restore to original value
         if (e.key === 'Escape') {
             e.preventDefault();
-            cell.innerHTML = formatValue(storedOriginal === '' ? null : storedOriginal);
-            cell.classList.remove('cell-editing');
+            finish(storedOriginal);
         }
     });
 
     cell.innerHTML = '';
-    cell.appendChild(textarea);
+    cell.appendChild(input);
+
+    if (col && col.nullable && !isNull) {
+        const nullBtn = document.createElement('button');
+        nullBtn.className = 'cell-null-btn';
+        nullBtn.textContent = 'Set NULL';
+        // Keep the editor from saving on blur before the click lands
+        nullBtn.addEventListener('mousedown', (e) => e.preventDefault());
+        nullBtn.addEventListener('click', (e) => {
+            e.stopPropagation();
+            finish(null);
+        });
+        cell.appendChild(nullBtn);
+    }
     cell.classList.add('cell-editing');
-    textarea.focus();
-    textarea.select();
+    input.focus();
+    if (input.select) input.select();
+}
+
+// Editor for a cell of a column, picked by the kind of the column: a
+// checkbox for booleans, a list for enums, date and time pickers, and a
+// textarea for anything else. read returns the edited value as text.
+function cellEditor(col, text) {
+    const kind = col ? col.kind : '';
+    if (kind === 'boolean') {
+        const input = document.createElement('input');
+        input.type = 'checkbox';
+        input.className = 'cell-checkbox';
+        input.checked = /^(true|t|yes|1)$/i.test(text.trim());
+        return { element: input, read: () => String(input.checked), commitOnChange: true };
+    }
+    if (kind === 'enum') {
+        const select = document.createElement('select');
+        select.className = 'cell-select';
+        const values = col.enum_values || [];
+        const options = values.includes(text) ? values : [text, ...values];
+        options.forEach(value => select.add(new Option(value, value)));
+        select.value = text;
+        return { element: select, read: () => select.value, commitOnChange: true };
+    }
+    const pickers = { date: 'date', timestamp: 'datetime-local', time: 'time' };
+    if (pickers[kind]) {
+        const input = document.createElement('input');
+        input.type = pickers[kind];
+        input.className = 'cell-picker';
+        if (kind !== 'date') input.step = '1';
+        input.value = pickerValue(kind, text);
+        return { element: input, read: () => input.value };
+    }
+    const textarea = document.createElement('textarea');
+    textarea.value = text;
+    return { element: textarea, read: () => textarea.value };
 }
 
-// Save cell - Updated to compare with original value and persist state
-function saveCell(cell, textarea, rowId, column, originalValue) {
-    const newValue = textarea.value;
+// Value of a date or time picker for a value read from the database,
+// e.g. 2024-05-01T10:30:00Z for a timestamp picker
+function pickerValue(kind, text) {
+    const value = text.trim();
+    if (kind === 'date') return value.slice(0, 10);
+    if (kind === 'time') return value.slice(0, 8);
+    return value.replace(' ', 'T').slice(0, 19);
+}
 
+// Save cell - Updated to compare with original value and persist state.
+// newValue is the text typed into the cell, or null for NULL.
+function saveCell(cell, newValue, rowId, column, originalValue) {
     // Compare with original value, not display text
     if (newValue !== originalValue) {
         if (!state.changes.has(rowId)) {
             state.changes.set(rowId, {});
+            const versions = state.data && state.data.row_versions;
+            if (versions && versions[rowId]) {
+                state.versions.set(rowId, versions[rowId]);
+            }
         }
-        state.changes.get(rowId)[column] = newValue;
+        state.changes.get(rowId)[column] = newValue === null ? null : typedValue(columnInfo(column), newValue);
 
         cell.classList.add('cell-dirty');
         document.getElementById('save-btn').style.display = 'block';
@@ -674,10 +1234,38 @@ function saveCell(cell, textarea, rowId, column, originalValue) {
         state.save();
     }
 
-    cell.innerHTML = formatValue(newValue === '' ? null : newValue);
+    cell.innerHTML = formatValue(newValue);
     cell.classList.remove('cell-editing');
 }
 
+// Column of the current table by name
+function columnInfo(name) {
+    return ((state.data && state.data.columns) || []).find(col => col.name === name);
+}
+
+// Turn text typed for a column into the JSON value sent for it: booleans
+// and numbers a double holds exactly keep their type, anything else is
+// sent as text, so decimals and big integers don't lose precision
+function typedValue(col, text) {
+    const kind = col ? col.kind : '';
+    const trimmed = text.trim();
+    if (kind === 'boolean') {
+        if (/^(true|t|yes|1)$/i.test(trimmed)) return true;
+        if (/^(false|f|no|0)$/i.test(trimmed)) return false;
+        return text;
+    }
+    if (kind === 'integer' && /^-?\d+$/.test(trimmed) && Number.isSafeInteger(Number(trimmed))) {
+        return Number(trimmed);
+    }
+    if (kind === 'number' && /float|double|real/i.test(col.type) && trimmed !== '' && Number.isFinite(Number(trimmed))) {
+        return Number(trimmed);
+    }
+    if (kind === 'timestamp') {
+        return trimmed.replace('T', ' ');
+    }
+    return text;
+}
+
 // Save changes
 async function saveChanges() {
     if (state.changes.size === 0) return;
@@ -693,7 +1281,9 @@ async function saveChanges() {
                 row_id: rowId,
                 column: colName,
                 value: value,
-                action: 'update'
+                null: value === null,
+                action: 'update',
+                version: state.versions.get(rowId)
             });
         });
     });
@@ -701,7 +1291,7 @@ async function saveChanges() {
     try {
         const res = await fetch(`/api/tables/${state.currentTable}/save`, {
             method: 'POST',
-            headers: { 'Content-Type': 'application/json' },
+            headers: writeHeaders(),
             body: JSON.stringify({ changes })
         });
 
@@ -709,10 +1299,13 @@ async function saveChanges() {
 
         if (json.success) {
             state.changes.clear();
+            state.versions.clear();
             showModal('Success', json.message, 'success');
             refreshData();
+        } else if (res.status === 409 && json.data && json.data.conflicts) {
+            showSaveConflicts(json.data.conflicts);
         } else {
-            showModal('Error', json.message, 'error');
+            showModal('Error', errorModalContent(json, 'Failed to save'), 'error');
         }
     } catch (err) {
         showModal('Error', 'Failed to save: ' + err.message, 'error');
@@ -722,6 +1315,90 @@ async function saveChanges() {
     }
 }
 
+// Show the rows someone else changed since they were loaded next to the
+// edits made to them, and let the user keep their edits or take the others'
+function showSaveConflicts(conflicts) {
+    const loaded = new Map();
+    ((state.data && state.data.rows) || []).forEach((row, idx) => loaded.set(String(row.id || idx), row));
+
+    const sections = conflicts.map(conflict => {
+        if (!conflict.current) {
+            return `<p><strong>Row ${escapeHtml(conflict.row_id)}</strong> was deleted.</p>`;
+        }
+        const edits = state.changes.get(conflict.row_id) || {};
+        const before = loaded.get(conflict.row_id) || {};
+        const rows = Object.keys(conflict.current)
+            .filter(col => col in edits || String(conflict.current[col]) !== String(before[col]))
+            .map(col => `
+                <tr>
+                    <td>${escapeHtml(col)}</td>
+                    <td>${!(col in edits) ? '<em>unchanged</em>' : edits[col] === null ? 'NULL' : escapeHtml(edits[col])}</td>
+                    <td>${conflict.current[col] === null ? 'NULL' : escapeHtml(conflict.current[col])}</td>
+                </tr>`).join('');
+        return `
+            <p><strong>Row ${escapeHtml(conflict.row_id)}</strong></p>
+            <table class="data-table" style="width: 100%; margin-bottom: 12px;">
+                <thead><tr><th>Column</th><th>Your edit</th><th>Now in the database</th></tr></thead>
+                <tbody>${rows}</tbody>
+            </table>`;
+    }).join('');
+
+    const modal = document.createElement('div');
+    modal.className = 'custom-modal show';
+    modal.innerHTML = `
+        <div class="custom-modal-content">
+            <div class="custom-modal-header">
+                <div class="custom-modal-title" style="color: #f59e0b">
+                    <span style="font-size: 24px; margin-right: 8px;">⚠</span>
+                    Changed by someone else
+                </div>
+                <button class="custom-modal-close" onclick="this.closest('.custom-modal').remove()">×</button>
+            </div>
+            <div class="custom-modal-body">
+                <p>These rows changed since you loaded them. Nothing was saved.</p>
+                ${sections}
+            </div>
+            <div class="custom-modal-footer">
+                <button class="btn btn-secondary" id="conflict-theirs-btn">Discard my edits</button>
+                <button class="btn btn-primary" id="conflict-mine-btn">Save my edits over theirs</button>
+            </div>
+        </div>
+    `;
+    document.body.appendChild(modal);
+
+    // Drops the edits of the rows, then saves the rest
+    document.getElementById('conflict-theirs-btn').onclick = () => {
+        modal.remove();
+        conflicts.forEach(conflict => {
+            state.changes.delete(conflict.row_id);
+            state.versions.delete(conflict.row_id);
+        });
+        state.save();
+        if (state.changes.size > 0) {
+            saveChanges();
+        } else {
+            document.getElementById('save-btn').style.display = 'none';
+            refreshData();
+        }
+    };
+
+    // Saves again against the versions the rows have now. Deleted rows
+    // can't be edited, so their edits are dropped.
+    document.getElementById('conflict-mine-btn').onclick = () => {
+        modal.remove();
+        conflicts.forEach(conflict => {
+            if (conflict.current) {
+                state.versions.set(conflict.row_id, conflict.version);
+            } else {
+                state.changes.delete(conflict.row_id);
+                state.versions.delete(conflict.row_id);
+            }
+        });
+        state.save();
+        saveChanges();
+    };
+}
+
 // Add row - Show modal with form
 // Add row - single unified function
 function addRow() {
@@ -729,10 +1406,13 @@ function addRow() {
 
     showAddRowModal(state.data.columns, async (data) => {
         if (!data || Object.keys(data).length === 0) return;
+        Object.keys(data).forEach(name => {
+            data[name] = typedValue(columnInfo(name), data[name]);
+        });
         try {
             const res = await fetch(`/api/tables/${state.currentTable}/add`, {
                 method: 'POST',
-                headers: { 'Content-Type': 'application/json' },
+                headers: writeHeaders(),
                 body: JSON.stringify({ data })
             });
             const json = await res.json();
@@ -740,7 +1420,7 @@ function addRow() {
                 showModal('Success', 'Row added successfully', 'success');
                 refreshData();
             } else {
-                showModal('Error', json.message || 'Failed to add row', 'error');
+                showModal('Error', errorModalContent(json, 'Failed to add row'), 'error');
             }
         } catch (err) {
             showModal('Error', err.message, 'error');
@@ -753,6 +1433,68 @@ function showAddRowDialog() {
     addRow();
 }
 
+// Paste rows - insert rows copied from a spreadsheet, checking them first
+function showPasteDialog() {
+    if (!state.currentTable) return;
+
+    const modal = document.createElement('div');
+    modal.className = 'custom-modal show';
+    modal.innerHTML = `
+        <div class="custom-modal-content">
+            <div class="custom-modal-header">
+                <div class="custom-modal-title">Paste rows into ${escapeHtml(state.currentTable)}</div>
+                <button class="custom-modal-close" onclick="this.closest('.custom-modal').remove()">×</button>
+            </div>
+            <div class="custom-modal-body">
+                <div class="form-group">
+                    <textarea id="paste-text" class="form-input" rows="10" style="font-family: monospace; white-space: pre;"
+                        placeholder="Paste cells from a spreadsheet, or CSV. Start with a header line to pick the columns."></textarea>
+                    <div class="form-hint">Without a header, values go to the columns in table order. Empty cells are NULL or the column default.</div>
+                </div>
+                <div id="paste-report"></div>
+            </div>
+            <div class="custom-modal-footer">
+                <button class="btn btn-secondary" id="paste-check-btn">Check</button>
+                <button class="btn btn-success" id="paste-insert-btn">Insert rows</button>
+            </div>
+        </div>
+    `;
+    document.body.appendChild(modal);
+    document.getElementById('paste-text').focus();
+
+    const report = document.getElementById('paste-report');
+    const send = async (dryRun) => {
+        const text = document.getElementById('paste-text').value;
+        if (!text.trim()) return;
+        try {
+            const res = await fetch(`/api/tables/${state.currentTable}/paste`, {
+                method: 'POST',
+                headers: writeHeaders(),
+                body: JSON.stringify({ text, dry_run: dryRun })
+            });
+            const json = await res.json();
+
+            if (json.success && !dryRun) {
+                modal.remove();
+                showToast(`Inserted ${json.data.inserted} row(s)`, 'success');
+                refreshData();
+            } else if (json.success) {
+                report.innerHTML = `<p style="color: #10b981;">${json.data.rows} row(s) are ready to insert into ${json.data.columns.map(escapeHtml).join(', ')}.</p>`;
+            } else if (json.data && json.data.errors) {
+                const items = json.data.errors.map(e =>
+                    `<li>Line ${e.line}${e.column ? ` (${escapeHtml(e.column)})` : ''}: ${escapeHtml(e.message)}</li>`).join('');
+                report.innerHTML = `<p style="color: #dc2626;">${escapeHtml(json.message)}</p><ul style="max-height: 200px; overflow: auto;">${items}</ul>`;
+            } else {
+                report.innerHTML = `<p style="color: #dc2626;">${escapeHtml(json.message)}</p>`;
+            }
+        } catch (err) {
+            report.innerHTML = `<p style="color: #dc2626;">Failed to paste: ${escapeHtml(err.message)}</p>`;
+        }
+    };
+    document.getElementById('paste-check-btn').onclick = () => send(true);
+    document.getElementById('paste-insert-btn').onclick = () => send(false);
+}
+
 // Delete row
 function deleteRow(rowId) {
     showConfirm('Confirm Delete', 'Delete this row?', async () => {
@@ -763,7 +1505,7 @@ function deleteRow(rowId) {
                 showModal('Success', 'Row deleted', 'success');
                 refreshData();
             } else {
-                showModal('Error', json.message || 'Failed to delete row', 'error');
+                showModal('Error', errorModalContent(json, 'Failed to delete row'), 'error');
             }
         } catch (err) {
             showModal('Error', err.message, 'error');
@@ -775,13 +1517,14 @@ function deleteRow(rowId) {
 function refreshData() {
     if (!state.currentTable) {
         // If no table is selected, just reload the tables list
-        loadTables();
+        loadTables(true);
         showToast('Tables list refreshed', 'success');
         return;
     }
 
     // Clear changes
     state.changes.clear();
+    state.versions.clear();
     document.getElementById('save-btn').style.display = 'none';
 
     // Clear dirty cells
@@ -827,6 +1570,17 @@ function changePage(delta) {
     loadTableData();
 }
 
+// Show how many rows the page has, of how many. An estimated total is
+// marked with ~ and counted exactly when clicked.
+function updateRowCount(data) {
+    const el = document.getElementById('row-count');
+    const rowCount = data.rows ? data.rows.length : 0;
+    el.textContent = `${rowCount} of ${data.total_estimated ? '~' : ''}${data.total || 0}`;
+    el.title = data.total_estimated ? 'Estimated, click to count' : '';
+    el.style.cursor = data.total_estimated ? 'pointer' : '';
+    el.onclick = data.total_estimated ? (e) => countTableRows(e, state.currentTable) : null;
+}
+
 function updatePagination(data) {
     const pagination = document.getElementById('pagination');
     const pageInfo = document.getElementById('page-info');
@@ -842,13 +1596,22 @@ function updatePagination(data) {
 
     const start = (data.page - 1) * data.limit + 1;
     const end = Math.min(data.page * data.limit, data.total);
-    pageInfo.textContent = `${start}-${end} of ${data.total}`;
+    pageInfo.textContent = `${start}-${end} of ${data.total_estimated ? '~' : ''}${data.total}`;
 
     prevBtn.disabled = data.page === 1;
-    nextBtn.disabled = end >= data.total;
+    // An estimate may fall short, a full page may have more after it
+    nextBtn.disabled = data.total_estimated ? data.rows.length < data.limit : end >= data.total;
 }
 
 // Modal system - Show a custom modal with title, content, and type
+// Body of the modal of a failed response, with the server's hint on how
+// to fix the error below the message
+function errorModalContent(json, fallback) {
+    const message = escapeHtml(json.message || fallback);
+    const hint = errorHint(json);
+    return hint ? `${message}<p class="custom-modal-hint">${escapeHtml(hint)}</p>` : message;
+}
+
 function showModal(title, content, type = 'info', blocking = false) {
     document.querySelectorAll('.custom-modal').forEach(m => m.remove());
 
@@ -0,0 +1,93 @@
+package sql
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// timeZoneHeader names the time zone timestamps were entered in, e.g.
+// Europe/Berlin. Studio sends the zone picked for the session.
+const timeZoneHeader = "X-Time-Zone"
+
+// requestLocation returns the time zone of a request, nil when it names
+// none
+func requestLocation(r *http.Request) (*time.Location, error) {
+	name := strings.TrimSpace(r.Header.Get(timeZoneHeader))
+	if name == "" {
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// storesInstant reports whether a PostgreSQL column holds a point in time
+// rather than a wall clock reading, i.e. is a timestamptz. Only those are
+// shown in the time zone of the session.
+func storesInstant(colType string) bool {
+	colType = strings.ToLower(colType)
+	return strings.Contains(colType, "timestamp with time zone") || colType == "timestamptz"
+}
+
+// normalizeTimes turns the instants in rows into ISO-8601 text in UTC and
+// returns the offset each had, by row, where it wasn't UTC. The database
+// session picks the offset, so it says nothing about the instant itself.
+func normalizeTimes(rows []map[string]any, columns []common.ColumnInfo) []map[string]string {
+	var offsets []map[string]string
+	for i, row := range rows {
+		for _, col := range columns {
+			t, ok := row[col.Name].(time.Time)
+			if !col.TimeZone || !ok {
+				continue
+			}
+			row[col.Name] = t.UTC().Format(time.RFC3339Nano)
+			if _, offset := t.Zone(); offset != 0 {
+				if offsets == nil {
+					offsets = make([]map[string]string, len(rows))
+				}
+				if offsets[i] == nil {
+					offsets[i] = make(map[string]string)
+				}
+				offsets[i][col.Name] = t.Format("-07:00")
+			}
+		}
+	}
+	return offsets
+}
+
+// localTimes reads text sent for columns that hold instants as a time in
+// loc, unless it carries an offset of its own
+func localTimes(tableColumns map[string]common.ColumnInfo, data map[string]any, loc *time.Location) {
+	if loc == nil {
+		return
+	}
+	for name, value := range data {
+		text, ok := value.(string)
+		if !ok || !tableColumns[name].TimeZone {
+			continue
+		}
+		if t, ok := parseLocalTime(strings.TrimSpace(text), loc); ok {
+			data[name] = t
+		}
+	}
+}
+
+// parseLocalTime parses a date and time in one of the formats a paste may
+// use, in loc when it has no offset
+func parseLocalTime(value string, loc *time.Location) (time.Time, bool) {
+	for _, layout := range pasteTimeLayouts {
+		if !strings.HasPrefix(layout, "2006") {
+			continue // a time without a date isn't an instant
+		}
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
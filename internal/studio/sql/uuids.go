@@ -0,0 +1,209 @@
+package sql
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// UUIDDefaultColumn is a uuid primary key column without a default
+type UUIDDefaultColumn struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+}
+
+// UUIDDefaults lists the uuid primary keys without a default and whether
+// gen_random_uuid() can give them one: built in since PostgreSQL 13, or
+// from the pgcrypto extension
+type UUIDDefaults struct {
+	Columns          []UUIDDefaultColumn `json:"columns"`
+	Available        bool                `json:"available"`
+	NeedsExtension   bool                `json:"needs_extension,omitempty"`
+	GenerateOnInsert string              `json:"generate_on_insert,omitempty"` // studio.generate_uuids
+}
+
+// UUIDDefaultsResult is the migration the defaults were added in
+type UUIDDefaultsResult struct {
+	Columns   []UUIDDefaultColumn `json:"columns"`
+	SQL       string              `json:"sql"`
+	Migration string              `json:"migration,omitempty"`
+}
+
+// generatesUUID reports whether studio fills in a column when a row comes
+// without it: a uuid primary key the database has no default for
+func generatesUUID(primary bool, colType, defaultValue string) bool {
+	return primary && defaultValue == "" && columnKind(colType) == kindUUID
+}
+
+// newUUID returns a UUID of the version set in studio.generate_uuids, or
+// "" when it isn't set
+func (s *Service) newUUID() (string, error) {
+	if s.cfg == nil {
+		return "", nil
+	}
+	switch strings.ToLower(s.cfg.Studio.GenerateUUIDs) {
+	case "":
+		return "", nil
+	case "v4", "4":
+		return newUUIDv4()
+	case "v7", "7":
+		return newUUIDv7(time.Now())
+	}
+	return "", fmt.Errorf("studio.generate_uuids must be v4 or v7, not %q", s.cfg.Studio.GenerateUUIDs)
+}
+
+// fillUUIDs sets the uuid primary key of a row the database won't generate
+// when it's missing, if studio.generate_uuids is set
+func (s *Service) fillUUIDs(tableColumns map[string]common.ColumnInfo, data map[string]any) error {
+	for name, col := range tableColumns {
+		if !generatesUUID(col.PrimaryKey, col.Type, col.Default) {
+			continue
+		}
+		if value, ok := data[name]; ok && value != nil && value != "" {
+			continue
+		}
+		id, err := s.newUUID()
+		if err != nil || id == "" {
+			return err
+		}
+		data[name] = id
+	}
+	return nil
+}
+
+// newUUIDv4 returns a random UUID
+func newUUIDv4() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUIDBytes(b), nil
+}
+
+// newUUIDv7 returns a UUID that starts with the time in milliseconds, so
+// keys generated later sort after earlier ones and index well
+func newUUIDv7(now time.Time) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[6:]); err != nil {
+		return "", err
+	}
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(now.UnixMilli()))
+	copy(b[:6], ms[2:])
+	b[6] = b[6]&0x0f | 0x70
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUIDBytes(b), nil
+}
+
+func formatUUIDBytes(b [16]byte) string {
+	h := hex.EncodeToString(b[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// GetUUIDDefaults lists the uuid primary keys without a default
+func (s *Service) GetUUIDDefaults(ctx context.Context) (*UUIDDefaults, error) {
+	if !s.isPostgres() {
+		return nil, fmt.Errorf("gen_random_uuid() defaults are only supported on PostgreSQL")
+	}
+	s.ensureCorrectSchema(ctx)
+
+	tables, err := s.adapter.GetCurrentSchema(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current schema: %w", err)
+	}
+	result := &UUIDDefaults{Columns: []UUIDDefaultColumn{}}
+	if s.cfg != nil {
+		result.GenerateOnInsert = s.cfg.Studio.GenerateUUIDs
+	}
+	for _, table := range tables {
+		if s.isIgnoredTable(table.Name) {
+			continue
+		}
+		for _, col := range table.Columns {
+			if generatesUUID(col.IsPrimary, col.Type, col.Default) {
+				result.Columns = append(result.Columns, UUIDDefaultColumn{Table: table.Name, Column: col.Name})
+			}
+		}
+	}
+
+	result.Available, result.NeedsExtension, err = s.genRandomUUIDAvailable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// genRandomUUIDAvailable reports whether gen_random_uuid() exists, or else
+// whether installing pgcrypto would add it
+func (s *Service) genRandomUUIDAvailable(ctx context.Context) (available, needsExtension bool, err error) {
+	result, err := s.adapter.ExecuteQuery(ctx, `SELECT
+		to_regproc('gen_random_uuid') IS NOT NULL AS builtin,
+		EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'pgcrypto') AS pgcrypto`)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to look for gen_random_uuid(): %w", err)
+	}
+	if len(result.Rows) == 0 {
+		return false, false, nil
+	}
+	builtin, _ := result.Rows[0]["builtin"].(bool)
+	pgcrypto, _ := result.Rows[0]["pgcrypto"].(bool)
+	if builtin {
+		return true, false, nil
+	}
+	return pgcrypto, pgcrypto, nil
+}
+
+// AddUUIDDefaults gives uuid primary keys a gen_random_uuid() default in a
+// migration, installing pgcrypto first when the function isn't built in.
+// Without columns, every uuid primary key without a default gets one.
+func (s *Service) AddUUIDDefaults(ctx context.Context, columns []UUIDDefaultColumn, configPath string) (*UUIDDefaultsResult, error) {
+	defaults, err := s.GetUUIDDefaults(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !defaults.Available {
+		return nil, fmt.Errorf("gen_random_uuid() isn't available: it needs PostgreSQL 13 or the pgcrypto extension")
+	}
+
+	missing := make(map[UUIDDefaultColumn]bool, len(defaults.Columns))
+	for _, col := range defaults.Columns {
+		missing[col] = true
+	}
+	if len(columns) == 0 {
+		columns = defaults.Columns
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("every uuid primary key already has a default")
+	}
+
+	var up, down []string
+	if defaults.NeedsExtension {
+		up = append(up, "CREATE EXTENSION IF NOT EXISTS pgcrypto;")
+	}
+	for _, col := range columns {
+		if !missing[col] {
+			return nil, fmt.Errorf("%s.%s isn't a uuid primary key without a default", col.Table, col.Column)
+		}
+		table, column := common.QuoteIdentifier(col.Table), common.QuoteIdentifier(col.Column)
+		up = append(up, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT gen_random_uuid();", table, column))
+		down = append(down, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT;", table, column))
+	}
+
+	name := "add_uuid_defaults"
+	if len(columns) == 1 {
+		name = fmt.Sprintf("add_%s_%s_uuid_default", columns[0].Table, columns[0].Column)
+	}
+	path, err := s.applyMigration(ctx, migrationName(name), up, down, configPath)
+	if err != nil {
+		return nil, err
+	}
+	return &UUIDDefaultsResult{Columns: columns, SQL: strings.Join(up, "\n"), Migration: path}, nil
+}
@@ -0,0 +1,177 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	dbcommon "github.com/Lumos-Labs-HQ/flash/internal/database/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
+)
+
+// rowChangeValue returns the value a change sets its column to, nil for
+// NULL. Numbers come back as json.Number, so they keep their precision.
+func rowChangeValue(change common.RowChange) (any, error) {
+	if change.Null {
+		return nil, nil
+	}
+	if len(change.Value) == 0 {
+		return nil, &InvalidValueError{Column: change.Column, Message: "has no value, send null to set it to NULL"}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(change.Value))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, &InvalidValueError{Column: change.Column, Message: "invalid value: " + err.Error()}
+	}
+	return value, nil
+}
+
+// tableColumns returns the columns of a table by name, with their kinds,
+// and its primary key column, id when it has none
+func (s *Service) tableColumns(ctx context.Context, tableName string) (map[string]common.ColumnInfo, string, error) {
+	schema, err := s.adapter.GetTableColumns(ctx, tableName)
+	if err != nil {
+		return nil, "", err
+	}
+	columns := make([]common.ColumnInfo, 0, len(schema))
+	pkColumn := ""
+	for _, col := range schema {
+		columns = append(columns, common.ColumnInfo{
+			Name:       col.Name,
+			Type:       col.Type,
+			Nullable:   col.Nullable,
+			PrimaryKey: col.IsPrimary,
+			Default:    col.Default,
+		})
+		if col.IsPrimary && pkColumn == "" {
+			pkColumn = col.Name
+		}
+	}
+	if pkColumn == "" {
+		pkColumn = "id"
+	}
+	s.describeColumns(ctx, tableName, columns)
+
+	byName := make(map[string]common.ColumnInfo, len(columns))
+	for _, col := range columns {
+		byName[col.Name] = col
+	}
+	return byName, pkColumn, nil
+}
+
+// rowArg converts a JSON value of a row edit to the Go value the driver
+// binds for a column. Numbers are sent as integers or floats when the
+// column holds those and as their exact text otherwise, booleans as 1 or 0
+// to numeric columns, and objects and arrays as JSON. Text sent to an
+// integer column is bound as an integer, and to a boolean one as a
+// boolean.
+func rowArg(col common.ColumnInfo, value any) any {
+	colType := strings.ToLower(col.Type)
+	isInteger := containsAny(colType, "int", "serial") && !containsAny(colType, "interval", "point")
+	if col.Kind == kindBoolean {
+		if b, ok := boolValue(value); ok {
+			value = b
+		}
+	}
+	switch v := value.(type) {
+	case json.Number:
+		if isInteger {
+			if n, err := v.Int64(); err == nil {
+				return n
+			}
+		}
+		if containsAny(colType, "float", "double", "real") {
+			if f, err := v.Float64(); err == nil {
+				return f
+			}
+		}
+		return v.String()
+	case float64:
+		return rowArg(col, json.Number(strconv.FormatFloat(v, 'f', -1, 64)))
+	case bool:
+		switch {
+		case strings.Contains(colType, "bool"):
+			return v
+		case isInteger || isNumericColumnType(colType):
+			if v {
+				return int64(1)
+			}
+			return int64(0)
+		}
+		return strconv.FormatBool(v)
+	case string:
+		if isInteger {
+			if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return n
+			}
+		}
+	case []any, map[string]any:
+		return jsonLiteral(v)
+	}
+	return value
+}
+
+// rowValues returns the columns of an edit in a stable order with their
+// values bound for their types, failing on columns the table doesn't have
+// and on values that don't fit their column
+func rowValues(tableName string, tableColumns map[string]common.ColumnInfo, data map[string]any) ([]string, []any, error) {
+	columns := make([]string, 0, len(data))
+	for column := range data {
+		if _, ok := tableColumns[column]; !ok {
+			return nil, nil, &InvalidValueError{Column: column, Message: fmt.Sprintf("%s has no such column", tableName)}
+		}
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	args := make([]any, len(columns))
+	for i, column := range columns {
+		if err := checkValue(tableColumns[column], data[column]); err != nil {
+			return nil, nil, err
+		}
+		args[i] = rowArg(tableColumns[column], data[column])
+	}
+	return columns, args, nil
+}
+
+// updateStatement returns the UPDATE that sets columns of the row with the
+// given primary key value
+func (s *Service) updateStatement(tableName, pkColumn, id string, tableColumns map[string]common.ColumnInfo, data map[string]any) (dbcommon.Statement, error) {
+	columns, args, err := rowValues(tableName, tableColumns, data)
+	if err != nil {
+		return dbcommon.Statement{}, err
+	}
+	set := make([]string, len(columns))
+	for i, column := range columns {
+		set[i] = fmt.Sprintf("%s = %s", common.QuoteIdentifier(column), s.placeholder(i+1))
+	}
+	return dbcommon.Statement{
+		SQL: fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s", common.QuoteIdentifier(tableName),
+			strings.Join(set, ", "), common.QuoteIdentifier(pkColumn), s.placeholder(len(args)+1)),
+		Args: append(args, id),
+	}, nil
+}
+
+// insertStatement returns the INSERT of a row
+func (s *Service) insertStatement(tableName string, tableColumns map[string]common.ColumnInfo, data map[string]any) (dbcommon.Statement, error) {
+	columns, args, err := rowValues(tableName, tableColumns, data)
+	if err != nil {
+		return dbcommon.Statement{}, err
+	}
+	quoted := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = common.QuoteIdentifier(column)
+		placeholders[i] = s.placeholder(i + 1)
+	}
+	return dbcommon.Statement{
+		SQL: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", common.QuoteIdentifier(tableName),
+			strings.Join(quoted, ", "), strings.Join(placeholders, ", ")),
+		Args: args,
+	}, nil
+}
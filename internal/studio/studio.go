@@ -2,12 +2,13 @@ package studio
 
 import (
 	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/studio/common"
 	"github.com/Lumos-Labs-HQ/flash/internal/studio/mongodb"
 	"github.com/Lumos-Labs-HQ/flash/internal/studio/sql"
 )
 
 type Server interface {
-	Start(openBrowser bool) error
+	Start(opts common.StartOptions) error
 }
 
 func New(cfg *config.Config, port int) Server {
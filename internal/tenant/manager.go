@@ -0,0 +1,211 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/database"
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
+	"github.com/Lumos-Labs-HQ/flash/internal/migrator"
+)
+
+var tenantNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+// MigrationResult is the outcome of applying pending migrations to one tenant
+type MigrationResult struct {
+	Tenant   string
+	Applied  int
+	Duration time.Duration
+	Err      error
+}
+
+// Manager creates tenants as a Postgres schema or MySQL database each and
+// keeps their migrations in step with the main database.
+type Manager struct {
+	adapter  database.DatabaseAdapter
+	metadata *MetadataManager
+	cfg      *config.Config
+	provider string
+	dbURL    string
+}
+
+func NewManager(cfg *config.Config) (*Manager, error) {
+	switch cfg.Database.Provider {
+	case "postgresql", "postgres", "mysql":
+	default:
+		return nil, fmt.Errorf("tenants are not supported for provider %s (use postgresql or mysql)", cfg.Database.Provider)
+	}
+
+	dbURL, err := cfg.GetDatabaseURL()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database URL: %w", err)
+	}
+
+	adapter := database.NewAdapter(cfg.Database.Provider)
+	if err := database.Require(adapter, cfg.Database.Provider, common.CapabilityMigrations); err != nil {
+		return nil, err
+	}
+	if err := adapter.Connect(context.Background(), dbURL); err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	return &Manager{
+		adapter:  adapter,
+		metadata: NewMetadataManager(cfg.MigrationsPath),
+		cfg:      cfg,
+		provider: cfg.Database.Provider,
+		dbURL:    dbURL,
+	}, nil
+}
+
+// CreateTenant creates the tenant's schema (or database) and records it.
+// Migrations are applied separately with MigrateTenant.
+func (m *Manager) CreateTenant(ctx context.Context, name string) (*TenantMetadata, error) {
+	if !tenantNameRegex.MatchString(name) {
+		return nil, fmt.Errorf("invalid tenant name '%s': use letters, digits and underscores, starting with a letter", name)
+	}
+
+	store, err := m.metadata.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	if store.GetTenant(name) != nil {
+		return nil, fmt.Errorf("tenant '%s' already exists", name)
+	}
+
+	tenant := &TenantMetadata{
+		Name:      name,
+		Schema:    SchemaName(name),
+		CreatedAt: time.Now(),
+	}
+
+	if err := m.adapter.CreateBranchSchema(ctx, tenant.Schema); err != nil {
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+
+	if err := store.AddTenant(tenant); err != nil {
+		return nil, err
+	}
+
+	if err := m.metadata.Save(store); err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+func (m *Manager) ListTenants() ([]*TenantMetadata, error) {
+	store, err := m.metadata.Load()
+	if err != nil {
+		return nil, err
+	}
+	return store.Tenants, nil
+}
+
+// MigrateTenant applies pending migrations to a single tenant
+func (m *Manager) MigrateTenant(ctx context.Context, tenant *TenantMetadata) MigrationResult {
+	start := time.Now()
+	result := MigrationResult{Tenant: tenant.Name}
+
+	tenantURL, err := TenantURL(m.provider, m.dbURL, tenant.Schema)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	mig, err := migrator.NewMigratorForURL(m.cfg, tenantURL)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer mig.Close()
+
+	result.Applied, result.Err = mig.ApplyPending(ctx)
+	result.Duration = time.Since(start)
+	return result
+}
+
+// MigrateAll applies pending migrations to every tenant, running up to
+// parallel tenants at once. Results are sorted by tenant name.
+func (m *Manager) MigrateAll(ctx context.Context, parallel int) ([]MigrationResult, error) {
+	tenants, err := m.ListTenants()
+	if err != nil {
+		return nil, err
+	}
+
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	results := make([]MigrationResult, len(tenants))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+
+	for i, tenant := range tenants {
+		wg.Add(1)
+		go func(i int, tenant *TenantMetadata) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = m.MigrateTenant(ctx, tenant)
+		}(i, tenant)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Tenant < results[j].Tenant
+	})
+	return results, nil
+}
+
+// SchemaName returns the schema (or MySQL database) that holds a tenant
+func SchemaName(tenantName string) string {
+	return fmt.Sprintf("flash_tenant_%s", tenantName)
+}
+
+// TenantURL rewrites a connection URL so it targets the tenant's schema:
+// Postgres gets a search_path (falling back to public, like branches),
+// MySQL gets the tenant database.
+func TenantURL(provider, dbURL, schema string) (string, error) {
+	switch provider {
+	case "postgresql", "postgres":
+		if !strings.Contains(dbURL, "://") {
+			// Keyword/value connection string
+			return fmt.Sprintf("%s search_path='%s,public'", dbURL, schema), nil
+		}
+		u, err := url.Parse(dbURL)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse database URL: %w", err)
+		}
+		query := u.Query()
+		query.Set("search_path", schema+",public")
+		u.RawQuery = query.Encode()
+		return u.String(), nil
+	case "mysql":
+		base, params := dbURL, ""
+		if qIdx := strings.Index(dbURL, "?"); qIdx >= 0 {
+			base, params = dbURL[:qIdx], dbURL[qIdx:]
+		}
+		idx := strings.LastIndex(base, "/")
+		if idx < 0 || idx < strings.Index(base, "://")+3 {
+			return "", fmt.Errorf("failed to parse database URL: missing database name")
+		}
+		return base[:idx+1] + schema + params, nil
+	default:
+		return "", fmt.Errorf("tenants are not supported for provider %s", provider)
+	}
+}
+
+func (m *Manager) Close() error {
+	if m.adapter != nil {
+		return m.adapter.Close()
+	}
+	return nil
+}
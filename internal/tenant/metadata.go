@@ -0,0 +1,81 @@
+package tenant
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type TenantMetadata struct {
+	Name      string    `json:"name"`
+	Schema    string    `json:"schema"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TenantStore struct {
+	Tenants []*TenantMetadata `json:"tenants"`
+}
+
+type MetadataManager struct {
+	filePath string
+	flashDir string
+}
+
+func NewMetadataManager(migrationsPath string) *MetadataManager {
+	flashDir := filepath.Join(migrationsPath, ".flash")
+
+	return &MetadataManager{
+		filePath: filepath.Join(flashDir, "tenants.json"),
+		flashDir: flashDir,
+	}
+}
+
+func (m *MetadataManager) Load() (*TenantStore, error) {
+	if _, err := os.Stat(m.filePath); os.IsNotExist(err) {
+		return &TenantStore{}, nil
+	}
+
+	data, err := os.ReadFile(m.filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenants file: %w", err)
+	}
+
+	var store TenantStore
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("failed to parse tenants file: %w", err)
+	}
+
+	return &store, nil
+}
+
+func (m *MetadataManager) Save(store *TenantStore) error {
+	if err := os.MkdirAll(m.flashDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directories: %w", err)
+	}
+
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal tenants: %w", err)
+	}
+
+	return os.WriteFile(m.filePath, data, 0644)
+}
+
+func (s *TenantStore) GetTenant(name string) *TenantMetadata {
+	for _, t := range s.Tenants {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+func (s *TenantStore) AddTenant(tenant *TenantMetadata) error {
+	if s.GetTenant(tenant.Name) != nil {
+		return fmt.Errorf("tenant '%s' already exists", tenant.Name)
+	}
+	s.Tenants = append(s.Tenants, tenant)
+	return nil
+}
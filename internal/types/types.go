@@ -1,6 +1,7 @@
 package types
 
 import (
+	"strings"
 	"time"
 )
 
@@ -13,6 +14,23 @@ type SchemaTable struct {
 	Name    string
 	Columns []SchemaColumn
 	Indexes []SchemaIndex
+	// Constraints are the foreign keys and unique constraints over more
+	// than one column; single-column ones are kept on the column
+	Constraints []SchemaConstraint
+
+	// PartitionBy is the partition key of a PostgreSQL partitioned table,
+	// e.g. "RANGE (created_at)"
+	PartitionBy string
+	// Partitions are filled by introspection; schema files declare them as
+	// separate CREATE TABLE ... PARTITION OF statements
+	Partitions []SchemaPartition
+}
+
+// SchemaPartition is a partition of a PostgreSQL partitioned table
+type SchemaPartition struct {
+	Name   string
+	Parent string
+	Bound  string // e.g. "FOR VALUES FROM ('2024-01-01') TO ('2025-01-01')" or "DEFAULT"
 }
 
 type SchemaColumn struct {
@@ -26,6 +44,55 @@ type SchemaColumn struct {
 	ForeignKeyTable  string
 	ForeignKeyColumn string
 	OnDeleteAction   string
+	OnUpdateAction   string
+	// DEFERRABLE or DEFERRABLE INITIALLY DEFERRED when the constraint is
+	// checked at the end of the transaction (PostgreSQL)
+	ForeignKeyDeferrable string
+	UniqueDeferrable     string
+}
+
+// SchemaConstraint is a FOREIGN KEY or UNIQUE constraint declared on the
+// table, e.g. FOREIGN KEY (order_id, line) REFERENCES order_lines (order_id, line)
+type SchemaConstraint struct {
+	Name       string // empty when the database is left to name it
+	Type       string // FOREIGN KEY or UNIQUE
+	Columns    []string
+	RefTable   string // the rest are for foreign keys, but Deferrable
+	RefColumns []string
+	OnDelete   string
+	OnUpdate   string
+	Deferrable string // as ForeignKeyDeferrable of SchemaColumn
+}
+
+// Definition writes the constraint the way CREATE TABLE and ADD CONSTRAINT
+// take it, with identifiers quoted by quote
+func (c SchemaConstraint) Definition(quote func(string) string) string {
+	quoteAll := func(names []string, sep string) string {
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			quoted[i] = quote(name)
+		}
+		return strings.Join(quoted, sep)
+	}
+
+	var parts []string
+	if c.Name != "" {
+		parts = append(parts, "CONSTRAINT "+quote(c.Name))
+	}
+	parts = append(parts, c.Type+" ("+quoteAll(c.Columns, ", ")+")")
+	if c.Type == "FOREIGN KEY" {
+		parts = append(parts, "REFERENCES "+quoteAll(strings.Split(c.RefTable, "."), ".")+" ("+quoteAll(c.RefColumns, ", ")+")")
+		if c.OnDelete != "" {
+			parts = append(parts, "ON DELETE "+c.OnDelete)
+		}
+		if c.OnUpdate != "" {
+			parts = append(parts, "ON UPDATE "+c.OnUpdate)
+		}
+	}
+	if c.Deferrable != "" {
+		parts = append(parts, c.Deferrable)
+	}
+	return strings.Join(parts, " ")
 }
 
 type SchemaIndex struct {
@@ -35,14 +102,100 @@ type SchemaIndex struct {
 	Unique  bool
 }
 
+// SchemaPolicy is a PostgreSQL row-level security policy
+type SchemaPolicy struct {
+	Name       string
+	Table      string
+	Command    string   // ALL, SELECT, INSERT, UPDATE or DELETE
+	Permissive string   // PERMISSIVE or RESTRICTIVE
+	Roles      []string // empty means PUBLIC
+	Using      string
+	WithCheck  string
+}
+
+// SchemaMaterializedView is a PostgreSQL materialized view
+type SchemaMaterializedView struct {
+	Name       string
+	Definition string // the query, without a trailing semicolon
+	NoData     bool   // created WITH NO DATA, left empty until refreshed
+	Indexes    []SchemaIndex
+}
+
+// MaterializedViewInfo describes the state of a materialized view in the
+// database
+type MaterializedViewInfo struct {
+	Name           string `json:"name"`
+	Populated      bool   `json:"populated"`
+	SizeBytes      int64  `json:"size_bytes"`
+	RowEstimate    int64  `json:"row_estimate"`
+	HasUniqueIndex bool   `json:"has_unique_index"` // needed to refresh concurrently
+	Definition     string `json:"definition"`
+}
+
 type SchemaDiff struct {
 	NewTables      []SchemaTable
 	DroppedTables  []string
 	ModifiedTables []TableDiff
 	NewIndexes     []SchemaIndex
 	DroppedIndexes []SchemaIndex // Changed from []string to include table name for MySQL DROP INDEX
+	RenamedIndexes []IndexRename
 	NewEnums       []SchemaEnum
 	DroppedEnums   []string
+	ModifiedEnums  []EnumDiff
+
+	RenamedTables []TableRename
+
+	// Row-level security (PostgreSQL only)
+	NewPolicies       []SchemaPolicy
+	DroppedPolicies   []SchemaPolicy
+	RLSEnabledTables  []string
+	RLSDisabledTables []string
+
+	// Materialized views (PostgreSQL only). A view whose definition changed
+	// is dropped and created again; index changes on other views are in
+	// NewIndexes and DroppedIndexes.
+	NewMaterializedViews     []SchemaMaterializedView
+	DroppedMaterializedViews []SchemaMaterializedView
+
+	// Partitions (PostgreSQL only), managed for the partitioned tables whose
+	// schema declares partitions
+	NewPartitions     []SchemaPartition
+	DroppedPartitions []SchemaPartition
+}
+
+// EnumDiff is an enum whose values changed. Values that are only added are
+// added in place; dropping or reordering values needs the type to be
+// created again and the columns of the type converted to it.
+type EnumDiff struct {
+	Name      string
+	OldValues []string
+	Values    []string
+	Added     []EnumValueAdd
+	Dropped   []string
+	Reordered bool
+	// Columns of the type in the schema files and in the database
+	Columns    []EnumColumn
+	OldColumns []EnumColumn
+}
+
+// Recreate reports whether the enum has to be created again
+func (d EnumDiff) Recreate() bool {
+	return len(d.Dropped) > 0 || d.Reordered
+}
+
+// EnumValueAdd is a value added to an enum, before or after an existing one
+type EnumValueAdd struct {
+	Value  string
+	Before string
+	After  string
+}
+
+// EnumColumn is a column whose type is an enum
+type EnumColumn struct {
+	Table   string
+	Column  string
+	Array   bool
+	Default string
 }
 
 type TableDiff struct {
@@ -50,13 +203,47 @@ type TableDiff struct {
 	NewColumns      []SchemaColumn
 	DroppedColumns  []SchemaColumn // Changed from []string to preserve column info for DOWN migration
 	ModifiedColumns []ColumnDiff
+	RenamedColumns  []ColumnRename
+
+	// A constraint that changed is dropped and added again
+	NewConstraints     []SchemaConstraint
+	DroppedConstraints []SchemaConstraint
+
+	// The table as the database has it and as the schema files declare it,
+	// which SQLite rebuilds the table from for changes it can't make in place
+	OldTable SchemaTable
+	Table    SchemaTable
+}
+
+// TableRename is a table whose name changed. Guessed renames were inferred
+// from identical columns rather than declared with -- flash:renamed-from.
+type TableRename struct {
+	From    string
+	To      SchemaTable
+	Guessed bool
+}
+
+// IndexRename is an index whose name changed while its definition didn't
+type IndexRename struct {
+	From SchemaIndex
+	To   SchemaIndex
+}
+
+// ColumnRename is a column whose name changed. Guessed renames were inferred
+// from a matching type rather than declared with -- flash:renamed-from.
+type ColumnRename struct {
+	From    SchemaColumn
+	To      SchemaColumn
+	Guessed bool
 }
 
 type ColumnDiff struct {
-	Name    string
-	OldType string
-	NewType string
-	Changes []string
+	Name       string
+	OldType    string
+	NewType    string
+	OldDefault string
+	NewDefault string
+	Changes    []string
 }
 
 type MigrationConflict struct {
@@ -97,6 +284,28 @@ type BackupData struct {
 	Version   string                 `json:"version"`
 	Tables    map[string]interface{} `json:"tables"`
 	Comment   string                 `json:"comment"`
+	// Checksums holds the row count and checksum of the data of each table,
+	// for an import to verify the file against
+	Checksums map[string]TableChecksum `json:"checksums,omitempty"`
+	// Since holds, for an incremental export, the watermark each table's
+	// rows were exported from. Tables without one were exported in full.
+	Since map[string]ExportWatermark `json:"since,omitempty"`
+}
+
+// ExportWatermark marks how far incremental exports of a table got: the
+// highest value of Column, an updated_at timestamp or an integer primary
+// key, when the table was last exported
+type ExportWatermark struct {
+	Column     string    `json:"column"`
+	Value      string    `json:"value"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+// TableChecksum is the row count and SHA-256 checksum of the exported rows
+// of a table
+type TableChecksum struct {
+	Rows     int    `json:"rows"`
+	Checksum string `json:"checksum"`
 }
 
 type MigrationStatusItem struct {
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Diagnostic is a problem found in a schema or query file, pointing at the
+// line and column it was found at
+type Diagnostic struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+	Snippet string // source line the column points into
+}
+
+// DisplayPath returns the path a diagnostic shows for a file: relative to
+// the working directory when the file is inside it, with the separators of
+// the OS
+func DisplayPath(path string) string {
+	if !filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(cwd, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
+}
+
+// NewDiagnostic creates a diagnostic at line and column of text, taking the
+// snippet from that line
+func NewDiagnostic(file, text string, line, column int, message string) *Diagnostic {
+	d := &Diagnostic{File: file, Line: line, Column: column, Message: message}
+	if lines := strings.Split(text, "\n"); line >= 1 && line <= len(lines) {
+		d.Snippet = strings.TrimRight(lines[line-1], "\r")
+	}
+	return d
+}
+
+// LocateDiagnostic creates a diagnostic at the first occurrence of needle
+// in text as a whole word, ignoring case. It falls back to the first
+// occurrence inside another word, then to the start of text.
+func LocateDiagnostic(file, text, needle, message string) *Diagnostic {
+	upperNeedle := strings.ToUpper(needle)
+	fallbackLine, fallbackCol := 1, 1
+	found := false
+
+	for i, line := range strings.Split(text, "\n") {
+		upperLine := strings.ToUpper(line)
+		for start := 0; start < len(upperLine); {
+			idx := strings.Index(upperLine[start:], upperNeedle)
+			if idx < 0 {
+				break
+			}
+			idx += start
+			end := idx + len(upperNeedle)
+			if (idx == 0 || !isWordByte(upperLine[idx-1])) && (end == len(upperLine) || !isWordByte(upperLine[end])) {
+				return NewDiagnostic(file, text, i+1, idx+1, message)
+			}
+			if !found {
+				fallbackLine, fallbackCol, found = i+1, idx+1, true
+			}
+			start = idx + 1
+		}
+	}
+	return NewDiagnostic(file, text, fallbackLine, fallbackCol, message)
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z'
+}
+
+func (d *Diagnostic) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message)
+	if d.Snippet != "" {
+		sb.WriteString("\n\t")
+		sb.WriteString(d.Snippet)
+		sb.WriteString("\n\t")
+		// Keep tabs so the caret lines up however wide they are shown
+		for i, ch := range d.Snippet {
+			if i >= d.Column-1 {
+				break
+			}
+			if ch == '\t' {
+				sb.WriteByte('\t')
+			} else {
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteByte('^')
+	}
+	return sb.String()
+}
+
+// Diagnostics is a list of problems reported together, so all of them can
+// be fixed in one go
+type Diagnostics []*Diagnostic
+
+func (ds Diagnostics) Error() string {
+	var sb strings.Builder
+	sb.WriteString("# package flash")
+	for _, d := range ds {
+		sb.WriteByte('\n')
+		sb.WriteString(d.Error())
+	}
+	if len(ds) > 1 {
+		fmt.Fprintf(&sb, "\n%d errors found", len(ds))
+	}
+	return sb.String()
+}
+
+// AsDiagnostics returns the diagnostics an error carries, if any
+func AsDiagnostics(err error) (Diagnostics, bool) {
+	var ds Diagnostics
+	if errors.As(err, &ds) {
+		return ds, true
+	}
+	var d *Diagnostic
+	if errors.As(err, &d) {
+		return Diagnostics{d}, true
+	}
+	return nil, false
+}
+
+// JoinDiagnostics combines errors into one. Diagnostics are merged into a
+// single list, in the order given, and any other errors are joined after
+// it. It returns nil when all errors are nil.
+func JoinDiagnostics(errs ...error) error {
+	var ds Diagnostics
+	var others []error
+
+	var collect func(err error)
+	collect = func(err error) {
+		switch e := err.(type) {
+		case nil:
+		case Diagnostics:
+			ds = append(ds, e...)
+		case *Diagnostic:
+			ds = append(ds, e)
+		case interface{ Unwrap() []error }:
+			for _, inner := range e.Unwrap() {
+				collect(inner)
+			}
+		default:
+			others = append(others, err)
+		}
+	}
+	for _, err := range errs {
+		collect(err)
+	}
+
+	switch {
+	case len(ds) == 0:
+		return errors.Join(others...)
+	case len(others) == 0:
+		return ds
+	default:
+		return errors.Join(append([]error{ds}, others...)...)
+	}
+}
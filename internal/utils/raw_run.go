@@ -8,6 +8,7 @@ import (
 
 	"github.com/Lumos-Labs-HQ/flash/internal/config"
 	"github.com/Lumos-Labs-HQ/flash/internal/database"
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
 	"github.com/spf13/cobra"
 )
 
@@ -56,6 +57,9 @@ func RunRaw(cmd *cobra.Command, args []string, queryFlag bool, fileFlag bool) er
 	}
 
 	adapter := database.NewAdapter(cfg.Database.Provider)
+	if err := database.Require(adapter, cfg.Database.Provider, common.CapabilityQueries); err != nil {
+		return err
+	}
 
 	dbURL, err := cfg.GetDatabaseURL()
 	if err != nil {
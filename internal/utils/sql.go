@@ -1,8 +1,6 @@
 package utils
 
 import (
-	"fmt"
-	"path/filepath"
 	"regexp"
 	"strings"
 )
@@ -285,20 +283,27 @@ func IsSQLKeyword(word string) bool {
 	return sqlKeywords[strings.ToUpper(word)]
 }
 
+// ValidateSchemaSyntax checks the CREATE TABLE statements of a schema file,
+// reporting every problem found as a Diagnostics error
 func ValidateSchemaSyntax(content, filePath string) error {
 	lines := strings.Split(content, "\n")
+	relPath := DisplayPath(filePath)
+	var diags Diagnostics
 
 	inCreateTable := false
-	tableStartLine := 0
+	tableStartLine, tableStartCol := 0, 0
 	parenDepth := 0
 
 	for lineNum, line := range lines {
 		lineNumber := lineNum + 1
 		trimmed := strings.TrimSpace(line)
 
-		if strings.Contains(strings.ToUpper(trimmed), "CREATE TABLE") {
+		if createIdx := strings.Index(strings.ToUpper(line), "CREATE TABLE"); createIdx >= 0 {
+			if inCreateTable && parenDepth > 0 {
+				diags = append(diags, NewDiagnostic(relPath, content, tableStartLine, tableStartCol, "syntax error: unclosed CREATE TABLE statement"))
+			}
 			inCreateTable = true
-			tableStartLine = lineNumber
+			tableStartLine, tableStartCol = lineNumber, createIdx+1
 			parenDepth = 0
 		}
 
@@ -318,8 +323,7 @@ func ValidateSchemaSyntax(content, filePath string) error {
 					continue
 				}
 				if strings.HasSuffix(prevLine, ",") {
-					relPath := filepath.Base(filePath)
-					return fmt.Errorf("# package FlashORM\n%s:%d:2: syntax error at or near \")\"", relPath, lineNumber)
+					diags = append(diags, NewDiagnostic(relPath, content, lineNumber, strings.Index(line, ")")+1, "syntax error at or near \")\""))
 				}
 				break
 			}
@@ -327,15 +331,18 @@ func ValidateSchemaSyntax(content, filePath string) error {
 		}
 
 		if parenDepth < 0 {
-			relPath := filepath.Base(filePath)
-			return fmt.Errorf("# package flash\n%s:%d:2: syntax error: unexpected ')'", relPath, lineNumber)
+			diags = append(diags, NewDiagnostic(relPath, content, lineNumber, strings.LastIndex(line, ")")+1, "syntax error: unexpected ')'"))
+			parenDepth = 0
+			inCreateTable = false
 		}
 	}
 
 	if inCreateTable && parenDepth > 0 {
-		relPath := filepath.Base(filePath)
-		return fmt.Errorf("# package flash\n%s:%d:2: syntax error: unclosed CREATE TABLE statement", relPath, tableStartLine)
+		diags = append(diags, NewDiagnostic(relPath, content, tableStartLine, tableStartCol, "syntax error: unclosed CREATE TABLE statement"))
 	}
 
+	if len(diags) > 0 {
+		return diags
+	}
 	return nil
 }
@@ -88,15 +88,18 @@ type tableAccessor interface {
 	GetTableNames() map[string]bool
 }
 
-// ValidateTableReferences checks if tables referenced in queries exist in the schema
+// ValidateTableReferences checks if tables referenced in queries exist in the schema,
+// reporting every missing table as a Diagnostics error
 // Uses type assertion for performance instead of reflection
-func ValidateTableReferences(sql string, schema interface{}, sourceFile string) error {
+// filePath is the query file shown in the diagnostics
+func ValidateTableReferences(sql string, schema interface{}, filePath string) error {
 	if schema == nil {
 		return nil
 	}
 
-	if sourceFile == "" {
-		sourceFile = "queries"
+	file := filePath
+	if file == "" {
+		file = "queries.sql"
 	}
 
 	// Try to extract table names using type assertion
@@ -119,6 +122,8 @@ func ValidateTableReferences(sql string, schema interface{}, sourceFile string)
 	matches := tablePatternRegex.FindAllStringSubmatch(sql, -1)
 
 	foundTableRefs := false
+	var diags Diagnostics
+	reported := make(map[string]bool)
 
 	for _, match := range matches {
 		if len(match) < 2 {
@@ -140,29 +145,20 @@ func ValidateTableReferences(sql string, schema interface{}, sourceFile string)
 		// Check if table exists in schema
 		tableExists := tableNames[strings.ToLower(tableName)]
 
-		if !tableExists {
-			lines := strings.Split(sql, "\n")
-			lineNum := 1
-			colPos := 1
-			upperTable := strings.ToUpper(tableName)
-
-			for i, line := range lines {
-				upperLine := strings.ToUpper(line)
-				if strings.Contains(upperLine, upperTable) {
-					lineNum = i + 1
-					colPos = strings.Index(upperLine, upperTable) + 1
-					break
-				}
-			}
-
-			return fmt.Errorf("# package flash\ndb\\queries\\%s.sql:%d:%d: relation \"%s\" does not exist", sourceFile, lineNum, colPos, tableName)
+		if !tableExists && !reported[strings.ToLower(tableName)] {
+			reported[strings.ToLower(tableName)] = true
+			diags = append(diags, LocateDiagnostic(file, sql, tableName,
+				fmt.Sprintf("relation \"%s\" does not exist", tableName)))
 		}
 	}
 
 	if foundTableRefs && len(tableNames) == 0 {
-		return fmt.Errorf("# package flash\ndb\\queries\\%s.sql:1:1: no tables found in schema, but query references tables", sourceFile)
+		return Diagnostics{NewDiagnostic(file, sql, 1, 1, "no tables found in schema, but query references tables")}
 	}
 
+	if len(diags) > 0 {
+		return diags
+	}
 	return nil
 }
 
@@ -230,14 +226,17 @@ func extractTableNamesViaReflection(schema interface{}) map[string]bool {
 	return tableNames
 }
 
-// ValidateColumnReferences checks if columns referenced in queries exist in the schema
-func ValidateColumnReferences(sql string, schema interface{}, sourceFile string) error {
+// ValidateColumnReferences checks if columns referenced in queries exist in the schema,
+// reporting every missing column as a Diagnostics error
+// filePath is the query file shown in the diagnostics
+func ValidateColumnReferences(sql string, schema interface{}, filePath string) error {
 	if schema == nil {
 		return nil
 	}
 
-	if sourceFile == "" {
-		sourceFile = "queries"
+	file := filePath
+	if file == "" {
+		file = "queries.sql"
 	}
 
 	sqlUpper := strings.ToUpper(sql)
@@ -321,6 +320,9 @@ func ValidateColumnReferences(sql string, schema interface{}, sourceFile string)
 		}
 	}
 
+	var diags Diagnostics
+	reported := make(map[string]bool)
+
 	columnRefs := columnRefPatternRegex.FindAllStringSubmatch(sql, -1)
 
 	for _, ref := range columnRefs {
@@ -347,19 +349,12 @@ func ValidateColumnReferences(sql string, schema interface{}, sourceFile string)
 
 		columnExists := table.columns[strings.ToLower(columnName)]
 
-		if !columnExists {
-			lines := strings.Split(sql, "\n")
-			lineNum := 0
-			colPos := 0
-			for i, line := range lines {
-				if strings.Contains(line, ref[0]) {
-					lineNum = i + 1
-					colPos = strings.Index(line, ref[0]) + len(tableOrAlias) + 1
-					break
-				}
-			}
-
-			return fmt.Errorf("# package flash\ndb\\queries\\%s.sql:%d:%d: column reference \"%s\" not found in table \"%s\"", sourceFile, lineNum, colPos, columnName, table.name)
+		if !columnExists && !reported[strings.ToLower(ref[0])] {
+			reported[strings.ToLower(ref[0])] = true
+			d := LocateDiagnostic(file, sql, ref[0],
+				fmt.Sprintf("column reference \"%s\" not found in table \"%s\"", columnName, table.name))
+			d.Column += len(tableOrAlias) + 1
+			diags = append(diags, d)
 		}
 	}
 
@@ -423,28 +418,18 @@ func ValidateColumnReferences(sql string, schema interface{}, sourceFile string)
 						continue
 					}
 
-					if !primaryTable.columns[colLower] {
-						lines := strings.Split(sql, "\n")
-						lineNum := 1
-						colPos := 1
-						upperCol := strings.ToUpper(colName)
-
-						for i, line := range lines {
-							upperLine := strings.ToUpper(line)
-							if strings.Contains(upperLine, upperCol) {
-								lineNum = i + 1
-								colPos = strings.Index(upperLine, upperCol) + 1
-								break
-							}
-						}
-
-						return fmt.Errorf("# package flash\ndb\\queries\\%s.sql:%d:%d: column \"%s\" does not exist in table \"%s\"",
-							sourceFile, lineNum, colPos, colName, primaryTable.name)
+					if !primaryTable.columns[colLower] && !reported[colLower] {
+						reported[colLower] = true
+						diags = append(diags, LocateDiagnostic(file, sql, colName,
+							fmt.Sprintf("column \"%s\" does not exist in table \"%s\"", colName, primaryTable.name)))
 					}
 				}
 			}
 		}
 	}
 
+	if len(diags) > 0 {
+		return diags
+	}
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/Lumos-Labs-HQ/flash/cmd"
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
 )
 
 func main() {
@@ -12,6 +13,9 @@ func main() {
 
 	if err := cmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if hint := common.Hint(err); hint != "" {
+			fmt.Fprintf(os.Stderr, "💡 %s\n", hint)
+		}
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,180 @@
+// Package flash runs Flash ORM migrations and schema introspection from Go
+// programs, e.g. to migrate on startup without shelling out to the flash
+// binary.
+//
+//	cfg, err := flash.LoadConfig("flash.config.json")
+//	if err != nil {
+//		return err
+//	}
+//	db, err := flash.Open(cfg)
+//	if err != nil {
+//		return err
+//	}
+//	defer db.Close()
+//
+//	result, err := db.Migrate(ctx, flash.MigrateOptions{})
+//
+// Hooks and notifications are left to the CLI.
+package flash
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/config"
+	"github.com/Lumos-Labs-HQ/flash/internal/export"
+	"github.com/Lumos-Labs-HQ/flash/internal/migrator"
+	"github.com/Lumos-Labs-HQ/flash/internal/types"
+)
+
+type (
+	// Config is the flash.config.json configuration
+	Config         = config.Config
+	DatabaseConfig = config.Database
+
+	Table  = types.SchemaTable
+	Column = types.SchemaColumn
+	Index  = types.SchemaIndex
+	Enum   = types.SchemaEnum
+
+	// Change is a change the next migration would make, see Client.Diff
+	Change = migrator.SchemaChange
+	// MigrationStatus is a migration with its status: applied, pending, or
+	// missing when it is applied but its file is gone
+	MigrationStatus = types.MigrationStatusItem
+)
+
+// LoadConfig reads a config file, flash.config.json when path is empty.
+// Paths in it are relative to the working directory.
+func LoadConfig(path string) (*Config, error) {
+	cfg, err := config.LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, nil
+}
+
+// Client is a connection to the database of a config
+type Client struct {
+	cfg      *Config
+	migrator *migrator.Migrator
+}
+
+// Open connects to the database in the environment variable the config
+// names. Settings left empty in cfg get their defaults.
+func Open(cfg *Config) (*Client, error) {
+	cfg.SetDefaults()
+	dbURL, err := cfg.GetDatabaseURL()
+	if err != nil {
+		return nil, err
+	}
+	return OpenURL(cfg, dbURL)
+}
+
+// OpenURL connects to dbURL instead of the URL in the environment
+func OpenURL(cfg *Config, dbURL string) (*Client, error) {
+	cfg.SetDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	m, err := migrator.NewMigratorForURL(cfg, dbURL)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{cfg: cfg, migrator: m}, nil
+}
+
+// Close closes the database connection
+func (c *Client) Close() error {
+	return c.migrator.Close()
+}
+
+// Config returns the config the client was opened with
+func (c *Client) Config() *Config {
+	return c.cfg
+}
+
+type MigrateOptions struct {
+	// DryRun returns the pending migrations without applying them
+	DryRun bool
+}
+
+type MigrateResult struct {
+	Applied []string `json:"applied"`
+	Pending []string `json:"pending"` // not applied, after a dry run or failure
+}
+
+// Migrate applies the pending migrations in order, each in its own
+// transaction. On failure the result holds the migrations applied before it.
+func (c *Client) Migrate(ctx context.Context, opts MigrateOptions) (*MigrateResult, error) {
+	pending, err := c.migrator.PendingMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return &MigrateResult{Applied: []string{}, Pending: pending}, nil
+	}
+
+	applied, err := c.migrator.ApplyPending(ctx)
+	return &MigrateResult{Applied: pending[:applied], Pending: pending[applied:]}, err
+}
+
+// Status returns every migration with its status
+func (c *Client) Status(ctx context.Context) ([]MigrationStatus, error) {
+	return c.migrator.MigrationStatuses(ctx)
+}
+
+// Diff compares the database with the schema files and returns the changes
+// a new migration would make, without writing one
+func (c *Client) Diff(ctx context.Context) ([]Change, error) {
+	return c.migrator.SchemaChanges(ctx)
+}
+
+// Schema returns the tables of the database, without the ones the config
+// ignores
+func (c *Client) Schema(ctx context.Context) ([]Table, error) {
+	tables, err := c.migrator.Adapter().GetCurrentSchema(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current schema: %w", err)
+	}
+	return slices.DeleteFunc(tables, func(table Table) bool {
+		return c.cfg.Database.IsIgnoredTable(table.Name)
+	}), nil
+}
+
+// Enums returns the enum types of the database
+func (c *Client) Enums(ctx context.Context) ([]Enum, error) {
+	return c.migrator.Adapter().GetCurrentEnums(ctx)
+}
+
+type ExportOptions struct {
+	// Tables limits the export to these tables, all tables when empty
+	Tables []string
+}
+
+// Export writes the data of the database to w as JSON, in the format of
+// flash export
+func (c *Client) Export(ctx context.Context, w io.Writer, opts ExportOptions) error {
+	data, err := export.CollectData(ctx, c.migrator.Adapter(), c.cfg.Database)
+	if err != nil {
+		return err
+	}
+	if len(opts.Tables) > 0 {
+		for name := range data.Tables {
+			if !slices.Contains(opts.Tables, name) {
+				delete(data.Tables, name)
+			}
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
@@ -0,0 +1,169 @@
+package flashtest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/database/common"
+)
+
+// LoadFixtures runs fixture files in a transaction, in order. Paths may be
+// glob patterns, whose matches are loaded sorted by name.
+//
+// A .sql file is run statement by statement. A .json file maps table names
+// to the rows to insert, with tables inserted in the order they appear:
+//
+//	{
+//		"users": [{"id": 1, "email": "ada@example.com"}],
+//		"posts": [{"id": 1, "user_id": 1, "title": "Hello"}]
+//	}
+//
+// Objects and arrays in a row are inserted as JSON text.
+func (db *DB) LoadFixtures(tb testing.TB, tx *sql.Tx, paths ...string) {
+	tb.Helper()
+
+	for _, pattern := range paths {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			tb.Fatalf("flashtest: invalid fixture pattern %s: %v", pattern, err)
+		}
+		if len(files) == 0 {
+			tb.Fatalf("flashtest: fixture %s not found", pattern)
+		}
+		sort.Strings(files)
+
+		for _, file := range files {
+			if err := db.loadFixture(tx, file); err != nil {
+				tb.Fatalf("flashtest: failed to load fixture %s: %v", file, err)
+			}
+		}
+	}
+}
+
+func (db *DB) loadFixture(tx *sql.Tx, file string) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".sql":
+		for _, stmt := range common.ParseSQLStatements(string(content)) {
+			if strings.TrimSpace(stmt) == "" {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("failed to execute statement '%s': %w", stmt, err)
+			}
+		}
+		return nil
+	case ".json":
+		tables, err := parseJSONFixture(content)
+		if err != nil {
+			return err
+		}
+		for _, table := range tables {
+			for i, row := range table.rows {
+				query, args := db.insertSQL(table.name, row)
+				if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+					return fmt.Errorf("failed to insert row %d into %s: %w", i+1, table.name, err)
+				}
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported fixture format, use .sql or .json")
+	}
+}
+
+type fixtureTable struct {
+	name string
+	rows []map[string]any
+}
+
+// parseJSONFixture reads the tables of a JSON fixture in file order, which
+// a map would lose
+func parseJSONFixture(content []byte) ([]fixtureTable, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	if tok, err := decoder.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("a JSON fixture must be an object of tables")
+	}
+
+	var tables []fixtureTable
+	for decoder.More() {
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse fixture: %w", err)
+		}
+		table := fixtureTable{name: tok.(string)}
+		if err := decoder.Decode(&table.rows); err != nil {
+			return nil, fmt.Errorf("rows of %s must be an array of objects: %w", table.name, err)
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+func (db *DB) insertSQL(table string, row map[string]any) (string, []any) {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	quoted := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	args := make([]any, len(columns))
+	for i, column := range columns {
+		quoted[i] = db.quoteIdentifier(column)
+		placeholders[i] = "?"
+		if db.Provider == "postgresql" || db.Provider == "postgres" {
+			placeholders[i] = fmt.Sprintf("$%d", i+1)
+		}
+		args[i] = fixtureValue(row[column])
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		db.quoteIdentifier(table), strings.Join(quoted, ", "), strings.Join(placeholders, ", ")), args
+}
+
+// quoteIdentifier quotes a table or column name, keeping a schema prefix
+func (db *DB) quoteIdentifier(name string) string {
+	quote := `"`
+	if db.Provider == "mysql" {
+		quote = "`"
+	}
+
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quote + strings.ReplaceAll(part, quote, quote+quote) + quote
+	}
+	return strings.Join(parts, ".")
+}
+
+func fixtureValue(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any, []any:
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return v
+	}
+}
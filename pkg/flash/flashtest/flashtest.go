@@ -0,0 +1,235 @@
+// Package flashtest gives Go tests a migrated database in which every test
+// runs in its own transaction, rolled back when the test ends, so tests
+// don't see each other's rows and need no cleanup.
+//
+//	func TestCreateUser(t *testing.T) {
+//		db := flashtest.Open(t, flashtest.Options{})
+//		tx := db.Tx(t, "testdata/users.json")
+//
+//		queries := flash_gen.New(tx)
+//		...
+//	}
+//
+// Open finds flash.config.json in the working directory or its parents and
+// connects to the URL in TEST_<url_env>, e.g. TEST_DATABASE_URL; tests are
+// skipped when it isn't set. Pending migrations are applied the first time
+// a test binary opens a database URL.
+//
+// The code under test must run its queries on the transaction it is given:
+// a transaction it begins and commits itself is not rolled back. SQLite
+// allows one writing transaction at a time, so tests on SQLite shouldn't
+// run in parallel.
+package flashtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Lumos-Labs-HQ/flash/internal/database/mysql"
+	"github.com/Lumos-Labs-HQ/flash/pkg/flash"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+type Options struct {
+	// ConfigPath is the config file, flash.config.json in the working
+	// directory or its closest parent when empty. Relative paths in it
+	// are resolved from the directory of the file.
+	ConfigPath string
+	// Config is used instead of a config file when set, with its paths as
+	// they are
+	Config *flash.Config
+	// URL is the test database, the TEST_<url_env> environment variable
+	// when empty
+	URL string
+}
+
+// DB is a migrated test database. It is shared by the tests of a test
+// binary and stays open until the binary exits.
+type DB struct {
+	*sql.DB
+	Provider string
+	URL      string
+}
+
+type openResult struct {
+	db  *DB
+	err error
+}
+
+var (
+	openMu sync.Mutex
+	opened = make(map[string]*openResult)
+)
+
+// Open returns the test database, applying the pending migrations the
+// first time the binary opens its URL. It skips the test when no URL is
+// set and fails it when the database can't be opened or migrated.
+func Open(tb testing.TB, opts Options) *DB {
+	tb.Helper()
+
+	cfg, dir, err := loadConfig(opts)
+	if err != nil {
+		tb.Fatalf("flashtest: %v", err)
+	}
+
+	dbURL := opts.URL
+	if dbURL == "" {
+		env := "TEST_" + cfg.Database.URLEnv
+		if dbURL = os.Getenv(env); dbURL == "" {
+			tb.Skipf("flashtest: %s is not set", env)
+		}
+	}
+	dbURL = resolveSQLitePath(cfg.Database.Provider, dbURL, dir)
+
+	openMu.Lock()
+	defer openMu.Unlock()
+
+	result, ok := opened[dbURL]
+	if !ok {
+		db, err := migrateAndOpen(cfg, dbURL)
+		result = &openResult{db: db, err: err}
+		opened[dbURL] = result
+	}
+	if result.err != nil {
+		tb.Fatalf("flashtest: %v", result.err)
+	}
+	return result.db
+}
+
+// Tx begins a transaction that is rolled back when the test ends and loads
+// the fixtures into it. It satisfies the DBTX interface of the generated
+// Go code.
+func (db *DB) Tx(tb testing.TB, fixtures ...string) *sql.Tx {
+	tb.Helper()
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		tb.Fatalf("flashtest: failed to begin transaction: %v", err)
+	}
+	tb.Cleanup(func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tb.Errorf("flashtest: failed to roll back transaction: %v", err)
+		}
+	})
+
+	db.LoadFixtures(tb, tx, fixtures...)
+	return tx
+}
+
+func migrateAndOpen(cfg *flash.Config, dbURL string) (*DB, error) {
+	ctx := context.Background()
+
+	client, err := flash.OpenURL(cfg, dbURL)
+	if err != nil {
+		return nil, err
+	}
+	_, err = client.Migrate(ctx, flash.MigrateOptions{})
+	client.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	sqlDB, err := openSQL(cfg.Database.Provider, dbURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to connect to test database: %w", err)
+	}
+	return &DB{DB: sqlDB, Provider: cfg.Database.Provider, URL: dbURL}, nil
+}
+
+// openSQL opens a database/sql connection with the driver the generated Go
+// code is used with for the provider
+func openSQL(provider, dbURL string) (*sql.DB, error) {
+	switch provider {
+	case "postgresql", "postgres":
+		pgConfig, err := pgx.ParseConfig(dbURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse connection URL: %w", err)
+		}
+		return stdlib.OpenDB(*pgConfig), nil
+	case "mysql":
+		return sql.Open("mysql", mysql.DSN(dbURL))
+	case "sqlite", "sqlite3":
+		path := strings.TrimPrefix(dbURL, "sqlite://")
+		if !strings.Contains(path, "?") {
+			path += "?_journal_mode=WAL&_busy_timeout=5000"
+		}
+		return sql.Open("sqlite3", path)
+	default:
+		return nil, fmt.Errorf("provider '%s' is not supported by flashtest", provider)
+	}
+}
+
+func loadConfig(opts Options) (*flash.Config, string, error) {
+	if opts.Config != nil {
+		return opts.Config, "", nil
+	}
+
+	path := opts.ConfigPath
+	if path == "" {
+		found, err := findConfig()
+		if err != nil {
+			return nil, "", err
+		}
+		path = found
+	}
+
+	cfg, err := flash.LoadConfig(path)
+	if err != nil {
+		return nil, "", err
+	}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, "", err
+	}
+	for _, p := range []*string{&cfg.SchemaPath, &cfg.SchemaDir, &cfg.Queries, &cfg.MigrationsPath, &cfg.ExportPath} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+	return cfg, dir, nil
+}
+
+// findConfig looks for flash.config.json from the working directory up,
+// as tests run in the directory of their package
+func findConfig() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, "flash.config.json")
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("flash.config.json not found in the working directory or its parents")
+		}
+		dir = parent
+	}
+}
+
+// resolveSQLitePath makes the file of a relative SQLite URL relative to the
+// directory of the config, like the CLI run from the project root
+func resolveSQLitePath(provider, dbURL, dir string) string {
+	if dir == "" || (provider != "sqlite" && provider != "sqlite3") {
+		return dbURL
+	}
+	path := strings.TrimPrefix(dbURL, "sqlite://")
+	if path == "" || filepath.IsAbs(path) || strings.HasPrefix(path, "file:") {
+		return dbURL
+	}
+	return "sqlite://" + filepath.Join(dir, path)
+}
@@ -99,59 +99,63 @@ func testDatabase(t *testing.T, db Database) {
 		testApply(t, testDir, db)
 	})
 
-	t.Run("04_Status", func(t *testing.T) {
+	t.Run("04_Migrate_Check", func(t *testing.T) {
+		testMigrateCheck(t, testDir, db)
+	})
+
+	t.Run("05_Status", func(t *testing.T) {
 		testStatus(t, testDir, db)
 	})
 
-	t.Run("05_Gen", func(t *testing.T) {
+	t.Run("06_Gen", func(t *testing.T) {
 		testGen(t, testDir, db)
 	})
 
-	t.Run("06_Pull", func(t *testing.T) {
+	t.Run("07_Pull", func(t *testing.T) {
 		testPull(t, testDir, db)
 	})
 
-	t.Run("07_Export_JSON", func(t *testing.T) {
+	t.Run("08_Export_JSON", func(t *testing.T) {
 		testExportJSON(t, testDir, db)
 	})
 
-	t.Run("08_Export_CSV", func(t *testing.T) {
+	t.Run("09_Export_CSV", func(t *testing.T) {
 		testExportCSV(t, testDir, db)
 	})
 
-	t.Run("09_Export_SQLite", func(t *testing.T) {
+	t.Run("10_Export_SQLite", func(t *testing.T) {
 		testExportSQLite(t, testDir, db)
 	})
 
-	t.Run("10_Raw", func(t *testing.T) {
+	t.Run("11_Raw", func(t *testing.T) {
 		testRaw(t, testDir, db)
 	})
 
-	t.Run("11_Branch_Create", func(t *testing.T) {
+	t.Run("12_Branch_Create", func(t *testing.T) {
 		testBranchCreate(t, testDir, db)
 	})
 
-	t.Run("12_Branch_List", func(t *testing.T) {
+	t.Run("13_Branch_List", func(t *testing.T) {
 		testBranchList(t, testDir, db)
 	})
 
-	t.Run("13_Branch_Checkout", func(t *testing.T) {
+	t.Run("14_Branch_Checkout", func(t *testing.T) {
 		testBranchCheckout(t, testDir, db)
 	})
 
-	t.Run("14_Branch_Diff", func(t *testing.T) {
+	t.Run("15_Branch_Diff", func(t *testing.T) {
 		testBranchDiff(t, testDir, db)
 	})
 
-	t.Run("15_Branch_Delete", func(t *testing.T) {
+	t.Run("16_Branch_Delete", func(t *testing.T) {
 		testBranchDelete(t, testDir, db)
 	})
 
-	t.Run("16_Studio", func(t *testing.T) {
+	t.Run("17_Studio", func(t *testing.T) {
 		testStudio(t, testDir, db)
 	})
 
-	t.Run("17_Reset", func(t *testing.T) {
+	t.Run("18_Reset", func(t *testing.T) {
 		testReset(t, testDir, db)
 	})
 }
@@ -221,6 +225,25 @@ func testApply(t *testing.T, testDir string, db Database) {
 	t.Logf("✅ Apply successful")
 }
 
+// testMigrateCheck checks that the migrations of a fresh project produce
+// its schema files exactly, so a CI gate on migrate check passes
+func testMigrateCheck(t *testing.T, testDir string, db Database) {
+	cmd := exec.Command(flashBinary, "migrate", "check")
+	cmd.Dir = testDir
+	output, err := cmd.CombinedOutput()
+
+	if err != nil {
+		t.Fatalf("Migrate check failed for %s: %v\nOutput: %s", db.Name, err, output)
+	}
+
+	outputStr := string(output)
+	if !strings.Contains(outputStr, `"ok": true`) || !strings.Contains(outputStr, `"drift": []`) {
+		t.Errorf("Migrate check reported problems on a clean schema: %s", output)
+	}
+
+	t.Logf("✅ Migrate check successful")
+}
+
 func testStatus(t *testing.T, testDir string, db Database) {
 	cmd := exec.Command(flashBinary, "status")
 	cmd.Dir = testDir